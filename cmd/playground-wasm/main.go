@@ -366,6 +366,8 @@ func getHooksForType(env *v1.EnvironmentBlockV1, nodeType graph.NodeType) []v1.H
 		return env.DatabaseUserHooks
 	case graph.NodeTypeNetworkPolicy:
 		return env.NetworkPolicyHooks
+	case graph.NodeTypeRouteAuth:
+		return env.RouteAuthHooks
 	default:
 		return nil
 	}
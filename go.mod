@@ -14,13 +14,17 @@ github.com/aws/aws-sdk-go-v2/config v1.32.7
 github.com/aws/aws-sdk-go-v2/credentials v1.19.7
 github.com/aws/aws-sdk-go-v2/service/s3 v1.96.0
 github.com/aws/aws-sdk-go-v2/service/secretsmanager v1.34.0
+github.com/aws/aws-sdk-go-v2/service/sts v1.41.6
 github.com/docker/docker v28.5.2+incompatible
 github.com/docker/go-connections v0.6.0
 github.com/go-git/go-git/v5 v5.16.4
 github.com/google/go-containerregistry v0.20.7
 github.com/google/uuid v1.6.0
 github.com/gorilla/websocket v1.5.3
+github.com/hashicorp/go-hclog v0.14.1
+github.com/hashicorp/go-plugin v1.6.3
 github.com/hashicorp/hcl/v2 v2.24.0
+github.com/jackc/pgx/v5 v5.7.6
 github.com/moby/go-archive v0.2.0
 github.com/spf13/cobra v1.10.2
 github.com/spf13/viper v1.21.0
@@ -28,6 +32,8 @@ github.com/stretchr/testify v1.11.1
 github.com/zclconf/go-cty v1.17.0
 golang.org/x/term v0.39.0
 google.golang.org/api v0.187.0
+google.golang.org/grpc v1.68.1
+google.golang.org/protobuf v1.36.3
 gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -57,7 +63,6 @@ github.com/aws/aws-sdk-go-v2/service/internal/s3shared v1.19.17 // indirect
 github.com/aws/aws-sdk-go-v2/service/signin v1.0.5 // indirect
 github.com/aws/aws-sdk-go-v2/service/sso v1.30.9 // indirect
 github.com/aws/aws-sdk-go-v2/service/ssooidc v1.35.13 // indirect
-github.com/aws/aws-sdk-go-v2/service/sts v1.41.6 // indirect
 github.com/aws/smithy-go v1.24.0 // indirect
 github.com/cenkalti/backoff/v4 v4.3.0 // indirect
 github.com/cespare/xxhash/v2 v2.3.0 // indirect
@@ -74,6 +79,7 @@ github.com/docker/distribution v2.8.3+incompatible // indirect
 github.com/docker/docker-credential-helpers v0.9.5 // indirect
 github.com/docker/go-units v0.5.0 // indirect
 github.com/emirpasic/gods v1.18.1 // indirect
+github.com/fatih/color v1.15.0 // indirect
 github.com/felixge/httpsnoop v1.0.4 // indirect
 github.com/fsnotify/fsnotify v1.9.0 // indirect
 github.com/go-git/gcfg v1.5.1-0.20230307220236-3a3c6141e376 // indirect
@@ -87,11 +93,17 @@ github.com/golang/protobuf v1.5.4 // indirect
 github.com/google/s2a-go v0.1.7 // indirect
 github.com/googleapis/enterprise-certificate-proxy v0.3.2 // indirect
 github.com/googleapis/gax-go/v2 v2.12.5 // indirect
+github.com/hashicorp/yamux v0.1.1 // indirect
 github.com/inconshreveable/mousetrap v1.1.0 // indirect
+github.com/jackc/pgpassfile v1.0.0 // indirect
+github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+github.com/jackc/puddle/v2 v2.2.2 // indirect
 github.com/jbenet/go-context v0.0.0-20150711004518-d14ea06fba99 // indirect
 github.com/kevinburke/ssh_config v1.2.0 // indirect
 github.com/klauspost/compress v1.18.3 // indirect
 github.com/kylelemons/godebug v1.1.0 // indirect
+github.com/mattn/go-colorable v0.1.13 // indirect
+github.com/mattn/go-isatty v0.0.17 // indirect
 github.com/mitchellh/go-homedir v1.1.0 // indirect
 github.com/mitchellh/go-wordwrap v1.0.1 // indirect
 github.com/moby/docker-image-spec v1.3.1 // indirect
@@ -101,6 +113,7 @@ github.com/moby/sys/user v0.4.0 // indirect
 github.com/moby/sys/userns v0.1.0 // indirect
 github.com/moby/term v0.5.2 // indirect
 github.com/morikuni/aec v1.1.0 // indirect
+github.com/oklog/run v1.0.0 // indirect
 github.com/opencontainers/go-digest v1.0.0 // indirect
 github.com/opencontainers/image-spec v1.1.1 // indirect
 github.com/pelletier/go-toml/v2 v2.2.4 // indirect
@@ -140,7 +153,5 @@ golang.org/x/tools v0.41.0 // indirect
 google.golang.org/genproto v0.0.0-20240624140628-dc46fd24d27d // indirect
 google.golang.org/genproto/googleapis/api v0.0.0-20241209162323-e6fa225c2576 // indirect
 google.golang.org/genproto/googleapis/rpc v0.0.0-20241209162323-e6fa225c2576 // indirect
-google.golang.org/grpc v1.68.1 // indirect
-google.golang.org/protobuf v1.36.3 // indirect
 gopkg.in/warnings.v0 v0.1.2 // indirect
 )
\ No newline at end of file
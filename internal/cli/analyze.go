@@ -0,0 +1,417 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/state/types"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Hook outputs that report runtime activity for a resource. Hooks that can
+// observe traffic or health checks publish RFC3339 timestamps under these
+// keys so that usage analysis can look past the last deploy time.
+const (
+	outputLastActiveAt  = "lastActiveAt"
+	outputLastHealthyAt = "lastHealthyAt"
+)
+
+// Usage recommendations.
+const (
+	recommendKeep        = "keep"
+	recommendDestroy     = "destroy"
+	recommendInvestigate = "investigate"
+)
+
+// environmentUsage summarizes activity for a deployed environment.
+type environmentUsage struct {
+	Name           string           `json:"name" yaml:"name"`
+	LastDeployed   time.Time        `json:"lastDeployed" yaml:"lastDeployed"`
+	LastActive     *time.Time       `json:"lastActive,omitempty" yaml:"lastActive,omitempty"`
+	Idle           bool             `json:"idle" yaml:"idle"`
+	Recommendation string           `json:"recommendation" yaml:"recommendation"`
+	Reasons        []string         `json:"reasons,omitempty" yaml:"reasons,omitempty"`
+	Components     []componentUsage `json:"components,omitempty" yaml:"components,omitempty"`
+}
+
+// componentUsage summarizes activity for a single deployed component.
+type componentUsage struct {
+	Name           string     `json:"name" yaml:"name"`
+	LastDeployed   time.Time  `json:"lastDeployed" yaml:"lastDeployed"`
+	LastActive     *time.Time `json:"lastActive,omitempty" yaml:"lastActive,omitempty"`
+	LastHealthy    *time.Time `json:"lastHealthy,omitempty" yaml:"lastHealthy,omitempty"`
+	Idle           bool       `json:"idle" yaml:"idle"`
+	Recommendation string     `json:"recommendation" yaml:"recommendation"`
+	Reasons        []string   `json:"reasons,omitempty" yaml:"reasons,omitempty"`
+}
+
+func newAnalyzeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "analyze",
+		Short: "Analyze deployed resources",
+		Long:  `Commands for analyzing deployed environments and suggesting cleanups.`,
+	}
+
+	cmd.AddCommand(newAnalyzeUsageCmd())
+
+	return cmd
+}
+
+func newAnalyzeUsageCmd() *cobra.Command {
+	var (
+		olderThan     string
+		destroyIdle   bool
+		autoApprove   bool
+		outputFormat  string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "usage [datacenter]",
+		Short: "Find idle environments and components",
+		Long: `Report environments and components that have not been deployed or used
+recently, with a recommendation for each.
+
+Activity is taken from the last deploy time recorded in state, and from
+runtime signals that datacenter hooks report as resource outputs:
+  lastActiveAt   RFC3339 time of the last observed traffic or activity
+  lastHealthyAt  RFC3339 time of the last passing health check
+
+An environment or component is idle when its most recent activity is older
+than --older-than. Resources in a failed state are flagged for investigation.
+
+With --destroy-idle, idle environments are destroyed after confirmation.
+
+The datacenter is resolved from the argument, CLDCTL_DATACENTER environment
+variable, or the default datacenter set in config.
+
+Examples:
+  cldctl analyze usage
+  cldctl analyze usage my-datacenter --older-than 14d
+  cldctl analyze usage my-datacenter -o json
+  cldctl analyze usage my-datacenter --destroy-idle --older-than 30d`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			threshold, err := parseAge(olderThan)
+			if err != nil {
+				return fmt.Errorf("invalid --older-than: %w", err)
+			}
+
+			dcName := ""
+			if len(args) > 0 {
+				dcName = args[0]
+			}
+			dc, err := resolveDatacenter(dcName)
+			if err != nil {
+				return err
+			}
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			envRefs, err := mgr.ListEnvironments(ctx, dc)
+			if err != nil {
+				return fmt.Errorf("failed to list environments: %w", err)
+			}
+
+			now := time.Now()
+			var report []environmentUsage
+			for _, ref := range envRefs {
+				env, err := mgr.GetEnvironment(ctx, dc, ref.Name)
+				if err != nil {
+					return fmt.Errorf("failed to get environment %s: %w", ref.Name, err)
+				}
+				report = append(report, analyzeEnvironmentUsage(env, now, threshold))
+			}
+
+			switch outputFormat {
+			case "json":
+				data, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				fmt.Println(string(data))
+			case "yaml":
+				data, err := yaml.Marshal(report)
+				if err != nil {
+					return fmt.Errorf("failed to marshal YAML: %w", err)
+				}
+				fmt.Println(string(data))
+			default:
+				printUsageReport(dc, report)
+			}
+
+			if !destroyIdle {
+				return nil
+			}
+
+			var idle []string
+			for _, env := range report {
+				if env.Idle {
+					idle = append(idle, env.Name)
+				}
+			}
+			if len(idle) == 0 {
+				fmt.Printf("\nNo environments idle for more than %s.\n", olderThan)
+				return nil
+			}
+
+			fmt.Printf("\nThe following environments will be destroyed:\n")
+			for _, name := range idle {
+				fmt.Printf("  - %s\n", name)
+			}
+
+			if !autoApprove {
+				fmt.Print("\nProceed with destroy? [y/N]: ")
+				var response string
+				_, _ = fmt.Scanln(&response)
+				if response != "y" && response != "Y" {
+					fmt.Println("Destroy cancelled.")
+					return nil
+				}
+			}
+
+			for _, name := range idle {
+				fmt.Printf("\n[destroy] Environment %s\n", name)
+				if err := destroyEnvironment(ctx, mgr, dc, name, os.Stdout); err != nil {
+					return fmt.Errorf("failed to destroy environment %s: %w", name, err)
+				}
+			}
+
+			fmt.Printf("\n[success] Destroyed %d idle environment(s)\n", len(idle))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&olderThan, "older-than", "30d", "Treat resources with no activity for this long as idle (e.g. 12h, 14d, 4w)")
+	cmd.Flags().BoolVar(&destroyIdle, "destroy-idle", false, "Destroy idle environments after confirmation")
+	cmd.Flags().BoolVar(&autoApprove, "auto-approve", false, "Skip confirmation prompt when destroying idle environments")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, json, yaml")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+// parseAge parses a duration that may also use day ("d") and week ("w")
+// units, which time.ParseDuration does not support.
+func parseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil || v < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// analyzeEnvironmentUsage reports on the activity of an environment and its
+// components as of now. Anything without activity newer than olderThan is
+// considered idle.
+func analyzeEnvironmentUsage(env *types.EnvironmentState, now time.Time, olderThan time.Duration) environmentUsage {
+	usage := environmentUsage{
+		Name:         env.Name,
+		LastDeployed: env.UpdatedAt,
+	}
+
+	if env.Status == types.EnvironmentStatusFailed {
+		usage.Reasons = append(usage.Reasons, statusReason("environment failed", env.StatusReason))
+	}
+
+	names := make([]string, 0, len(env.Components))
+	for name := range env.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		comp := analyzeComponentUsage(name, env.Components[name], now, olderThan)
+		usage.Components = append(usage.Components, comp)
+
+		if comp.LastDeployed.After(usage.LastDeployed) {
+			usage.LastDeployed = comp.LastDeployed
+		}
+		usage.LastActive = latest(usage.LastActive, comp.LastActive)
+		usage.LastActive = latest(usage.LastActive, comp.LastHealthy)
+	}
+
+	usage.Idle = now.Sub(lastActivity(usage.LastDeployed, usage.LastActive)) > olderThan
+	usage.Recommendation = recommendation(usage.Idle, len(usage.Reasons) > 0)
+	if usage.Idle {
+		usage.Reasons = append(usage.Reasons, fmt.Sprintf("no activity in %s", formatAge(olderThan)))
+	}
+	return usage
+}
+
+// analyzeComponentUsage reports on the activity of a single component.
+func analyzeComponentUsage(name string, comp *types.ComponentState, now time.Time, olderThan time.Duration) componentUsage {
+	usage := componentUsage{
+		Name:         name,
+		LastDeployed: comp.DeployedAt,
+	}
+	if comp.UpdatedAt.After(usage.LastDeployed) {
+		usage.LastDeployed = comp.UpdatedAt
+	}
+
+	if comp.Status == types.ResourceStatusFailed {
+		usage.Reasons = append(usage.Reasons, statusReason("component failed", comp.StatusReason))
+	}
+
+	visit := func(resources map[string]*types.ResourceState) {
+		keys := make([]string, 0, len(resources))
+		for key := range resources {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			res := resources[key]
+			if res == nil {
+				continue
+			}
+			usage.LastActive = latest(usage.LastActive, outputTime(res.Outputs, outputLastActiveAt))
+			usage.LastHealthy = latest(usage.LastHealthy, outputTime(res.Outputs, outputLastHealthyAt))
+			switch res.Status {
+			case types.ResourceStatusFailed:
+				usage.Reasons = append(usage.Reasons, statusReason(key+" failed", res.StatusReason))
+			case types.ResourceStatusUnknown:
+				usage.Reasons = append(usage.Reasons, statusReason(key+" partially created", res.StatusReason))
+			}
+		}
+	}
+
+	visit(comp.Resources)
+	instanceNames := make([]string, 0, len(comp.Instances))
+	for instName := range comp.Instances {
+		instanceNames = append(instanceNames, instName)
+	}
+	sort.Strings(instanceNames)
+	for _, instName := range instanceNames {
+		inst := comp.Instances[instName]
+		if inst.DeployedAt.After(usage.LastDeployed) {
+			usage.LastDeployed = inst.DeployedAt
+		}
+		visit(inst.Resources)
+	}
+
+	usage.Idle = now.Sub(lastActivity(usage.LastDeployed, latest(usage.LastActive, usage.LastHealthy))) > olderThan
+	usage.Recommendation = recommendation(usage.Idle, len(usage.Reasons) > 0)
+	if usage.Idle {
+		usage.Reasons = append(usage.Reasons, fmt.Sprintf("no activity in %s", formatAge(olderThan)))
+	}
+	return usage
+}
+
+// recommendation picks the suggested action for an analyzed item. Idle items
+// should be destroyed; active items with failures need a closer look.
+func recommendation(idle, failed bool) string {
+	switch {
+	case idle:
+		return recommendDestroy
+	case failed:
+		return recommendInvestigate
+	default:
+		return recommendKeep
+	}
+}
+
+func statusReason(prefix, reason string) string {
+	if reason == "" {
+		return prefix
+	}
+	return prefix + ": " + reason
+}
+
+// outputTime reads an RFC3339 timestamp from resource outputs.
+func outputTime(outputs map[string]interface{}, key string) *time.Time {
+	switch v := outputs[key].(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil
+		}
+		return &t
+	case time.Time:
+		return &v
+	}
+	return nil
+}
+
+func latest(a, b *time.Time) *time.Time {
+	if a == nil {
+		return b
+	}
+	if b == nil || a.After(*b) {
+		return a
+	}
+	return b
+}
+
+func lastActivity(deployed time.Time, active *time.Time) time.Time {
+	if active != nil && active.After(deployed) {
+		return *active
+	}
+	return deployed
+}
+
+// formatAge formats a duration in days when it is a whole number of days.
+func formatAge(d time.Duration) string {
+	if d >= 24*time.Hour && d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	}
+	return d.String()
+}
+
+func printUsageReport(dc string, report []environmentUsage) {
+	if len(report) == 0 {
+		fmt.Printf("No environments found in datacenter %q.\n", dc)
+		return
+	}
+
+	fmt.Printf("Datacenter: %s\n\n", dc)
+	fmt.Printf("%-24s %-16s %-16s %-12s %s\n", "NAME", "LAST DEPLOYED", "LAST ACTIVE", "RECOMMEND", "REASONS")
+	for _, env := range report {
+		printUsageRow(env.Name, env.LastDeployed, env.LastActive, env.Recommendation, env.Reasons)
+		for _, comp := range env.Components {
+			printUsageRow("  "+comp.Name, comp.LastDeployed, latest(comp.LastActive, comp.LastHealthy), comp.Recommendation, comp.Reasons)
+		}
+	}
+}
+
+func printUsageRow(name string, deployed time.Time, active *time.Time, recommend string, reasons []string) {
+	lastActive := "-"
+	if active != nil {
+		lastActive = formatTimeAgo(*active)
+	}
+	lastDeployed := "-"
+	if !deployed.IsZero() {
+		lastDeployed = formatTimeAgo(deployed)
+	}
+	fmt.Printf("%-24s %-16s %-16s %-12s %s\n",
+		truncateString(name, 24),
+		lastDeployed,
+		lastActive,
+		recommend,
+		strings.Join(reasons, "; "),
+	)
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "30d", want: 30 * 24 * time.Hour},
+		{in: "2w", want: 14 * 24 * time.Hour},
+		{in: "12h", want: 12 * time.Hour},
+		{in: "d", wantErr: true},
+		{in: "-1d", wantErr: true},
+		{in: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseAge(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseAge(%q) expected error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseAge(%q) unexpected error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseAge(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeEnvironmentUsage(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	old := now.Add(-60 * 24 * time.Hour)
+	recent := now.Add(-2 * 24 * time.Hour)
+
+	env := &types.EnvironmentState{
+		Name:      "preview-42",
+		UpdatedAt: old,
+		Status:    types.EnvironmentStatusReady,
+		Components: map[string]*types.ComponentState{
+			"api": {
+				DeployedAt: old,
+				UpdatedAt:  old,
+				Resources: map[string]*types.ResourceState{
+					"deployment.api": {
+						Status:  types.ResourceStatusReady,
+						Outputs: map[string]interface{}{"lastActiveAt": recent.Format(time.RFC3339)},
+					},
+				},
+			},
+			"worker": {
+				DeployedAt: old,
+				UpdatedAt:  old,
+				Resources: map[string]*types.ResourceState{
+					"deployment.worker": {Status: types.ResourceStatusReady},
+				},
+			},
+		},
+	}
+
+	usage := analyzeEnvironmentUsage(env, now, 30*24*time.Hour)
+
+	if usage.Idle {
+		t.Error("environment with recent runtime activity should not be idle")
+	}
+	if usage.LastActive == nil || !usage.LastActive.Equal(recent) {
+		t.Errorf("LastActive = %v, want %v", usage.LastActive, recent)
+	}
+	if len(usage.Components) != 2 {
+		t.Fatalf("expected 2 components, got %d", len(usage.Components))
+	}
+
+	api, worker := usage.Components[0], usage.Components[1]
+	if api.Idle || api.Recommendation != recommendKeep {
+		t.Errorf("api: idle=%v recommendation=%s, want active keep", api.Idle, api.Recommendation)
+	}
+	if !worker.Idle || worker.Recommendation != recommendDestroy {
+		t.Errorf("worker: idle=%v recommendation=%s, want idle destroy", worker.Idle, worker.Recommendation)
+	}
+}
+
+func TestAnalyzeEnvironmentUsage_IdleAndFailed(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	recent := now.Add(-24 * time.Hour)
+
+	env := &types.EnvironmentState{
+		Name:      "staging",
+		UpdatedAt: recent,
+		Components: map[string]*types.ComponentState{
+			"api": {
+				DeployedAt: recent,
+				Resources: map[string]*types.ResourceState{
+					"database.main": {
+						Status:       types.ResourceStatusFailed,
+						StatusReason: "connection refused",
+					},
+				},
+			},
+		},
+	}
+
+	usage := analyzeEnvironmentUsage(env, now, 30*24*time.Hour)
+	if usage.Idle {
+		t.Error("recently deployed environment should not be idle")
+	}
+	comp := usage.Components[0]
+	if comp.Recommendation != recommendInvestigate {
+		t.Errorf("recommendation = %s, want %s", comp.Recommendation, recommendInvestigate)
+	}
+	if len(comp.Reasons) != 1 || comp.Reasons[0] != "database.main failed: connection refused" {
+		t.Errorf("unexpected reasons: %v", comp.Reasons)
+	}
+
+	idle := analyzeEnvironmentUsage(env, now.Add(90*24*time.Hour), 30*24*time.Hour)
+	if !idle.Idle || idle.Recommendation != recommendDestroy {
+		t.Errorf("idle=%v recommendation=%s, want idle destroy", idle.Idle, idle.Recommendation)
+	}
+}
@@ -477,6 +477,7 @@ func printDatacenterModuleAddresses(dc datacenter.Datacenter) error {
 			printHookModuleAddresses("function", hooks.Function(), dcDir)
 			printHookModuleAddresses("service", hooks.Service(), dcDir)
 			printHookModuleAddresses("route", hooks.Route(), dcDir)
+			printHookModuleAddresses("routeAuth", hooks.RouteAuth(), dcDir)
 			printHookModuleAddresses("cronjob", hooks.Cronjob(), dcDir)
 			printHookModuleAddresses("encryptionKey", hooks.EncryptionKey(), dcDir)
 			printHookModuleAddresses("smtp", hooks.SMTP(), dcDir)
@@ -549,7 +550,7 @@ func printModuleAddresses(mod datacenter.Module, dcDir string) {
 	switch plugin {
 	case "native":
 		discoverNativeAddresses(modPath)
-	case "opentofu", "terraform":
+	case "opentofu", "terraform", "tofu":
 		discoverTerraformAddresses(modPath)
 	default:
 		fmt.Printf("    source: %s\n", modPath)
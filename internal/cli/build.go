@@ -552,8 +552,8 @@ If no tag is provided, the artifact is identified by its content digest
 
 				fmt.Printf("[success] Built %s (%s)\n", ref, buildResult.ModuleType)
 
-				// Push module image if --push flag is set (native modules are local-only)
-				if push && buildResult.ModuleType != "native" {
+				// Push module image if --push flag is set (host-executed modules are local-only)
+				if push && !isLocalOnlyPlugin(string(buildResult.ModuleType)) {
 					fmt.Printf("[push] Pushing module %s...\n", ref)
 					if err := moduleBuilder.Push(ctx, ref); err != nil {
 						return fmt.Errorf("failed to push module %s: %w", modulePath, err)
@@ -655,7 +655,7 @@ If no tag is provided, the artifact is identified by its content digest
 				// Print summary of all pushed artifacts
 				pushedModules := 0
 				for _, modInfo := range allModules {
-					if modInfo.plugin != "native" {
+					if !isLocalOnlyPlugin(modInfo.plugin) {
 						pushedModules++
 					}
 				}
@@ -663,7 +663,7 @@ If no tag is provided, the artifact is identified by its content digest
 				fmt.Printf("  %s\n", dcRef)
 				for modulePath, ref := range moduleArtifacts {
 					modInfo := allModules[modulePath]
-					if modInfo.plugin != "native" {
+					if !isLocalOnlyPlugin(modInfo.plugin) {
 						fmt.Printf("  %s\n", ref)
 					}
 				}
@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/labels"
+	"github.com/davidthor/cldctl/pkg/state"
+	"github.com/davidthor/cldctl/pkg/state/types"
+	"github.com/spf13/cobra"
+)
+
+// bulkFlags are the flags shared by commands that can operate on many
+// environments at once.
+type bulkFlags struct {
+	allEnvironments bool
+	selector        string
+	olderThan       string
+	concurrency     int
+}
+
+// addBulkFlags registers the environment selection flags. listOnly omits the
+// flags that only apply to commands that change environments.
+func addBulkFlags(cmd *cobra.Command, f *bulkFlags, listOnly bool) {
+	if listOnly {
+		f.concurrency = 1
+	} else {
+		cmd.Flags().BoolVar(&f.allEnvironments, "all-environments", false, "Operate on every environment in the datacenter")
+		cmd.Flags().IntVar(&f.concurrency, "concurrency", 1, "Number of environments to operate on at once")
+	}
+	cmd.Flags().StringVarP(&f.selector, "selector", "l", "", "Select environments by label selector (e.g. team=payments,tier!=batch)")
+	cmd.Flags().StringVar(&f.olderThan, "older-than", "", "Select environments created more than this long ago (e.g. 12h, 14d, 4w)")
+}
+
+// environmentSelection filters the environments of a datacenter.
+type environmentSelection struct {
+	labels    labels.Selector
+	olderThan time.Duration
+}
+
+// selection parses the flags. It reports false when no bulk flag was set and
+// the command should act on a single named environment instead.
+func (f *bulkFlags) selection() (environmentSelection, bool, error) {
+	if !f.allEnvironments && f.selector == "" && f.olderThan == "" {
+		return environmentSelection{}, false, nil
+	}
+	if f.concurrency < 1 {
+		return environmentSelection{}, false, fmt.Errorf("--concurrency must be at least 1")
+	}
+
+	var sel environmentSelection
+	var err error
+	if sel.labels, err = labels.ParseSelector(f.selector); err != nil {
+		return environmentSelection{}, false, err
+	}
+	if f.olderThan != "" {
+		if sel.olderThan, err = parseAge(f.olderThan); err != nil {
+			return environmentSelection{}, false, fmt.Errorf("invalid --older-than: %w", err)
+		}
+	}
+	return sel, true, nil
+}
+
+// matches reports whether an environment is selected as of now.
+func (s environmentSelection) matches(env *types.EnvironmentState, now time.Time) bool {
+	if !s.labels.Matches(env.Labels) {
+		return false
+	}
+	if s.olderThan > 0 && now.Sub(env.CreatedAt) < s.olderThan {
+		return false
+	}
+	return true
+}
+
+// selectEnvironments returns the selected environments of a datacenter,
+// sorted by name.
+func selectEnvironments(ctx context.Context, mgr state.Manager, dc string, sel environmentSelection) ([]*types.EnvironmentState, error) {
+	refs, err := mgr.ListEnvironments(ctx, dc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list environments: %w", err)
+	}
+
+	now := time.Now()
+	var envs []*types.EnvironmentState
+	for _, ref := range refs {
+		env, err := mgr.GetEnvironment(ctx, dc, ref.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get environment %s: %w", ref.Name, err)
+		}
+		if sel.matches(env, now) {
+			envs = append(envs, env)
+		}
+	}
+	sort.Slice(envs, func(i, j int) bool { return envs[i].Name < envs[j].Name })
+	return envs, nil
+}
+
+// printSelectedEnvironments prints the environments a bulk operation would
+// act on.
+func printSelectedEnvironments(w io.Writer, envs []*types.EnvironmentState) {
+	fmt.Fprintf(w, "%-24s %-14s %-12s %s\n", "NAME", "STATUS", "CREATED", "LABELS")
+	for _, env := range envs {
+		fmt.Fprintf(w, "%-24s %-14s %-12s %s\n", env.Name, env.Status, env.CreatedAt.Format("2006-01-02"), labels.Format(env.Labels))
+	}
+	fmt.Fprintf(w, "\n%d environment(s) selected.\n", len(envs))
+}
+
+// confirmBulk lists the selected environments and asks for confirmation
+// unless autoApprove is set. Without a terminal, approval must be explicit.
+func confirmBulk(action string, envs []*types.EnvironmentState, autoApprove bool) (bool, error) {
+	fmt.Printf("The following %d environment(s) will be %s:\n", len(envs), action)
+	for _, env := range envs {
+		fmt.Printf("  - %s\n", env.Name)
+	}
+	if autoApprove {
+		return true, nil
+	}
+	if !isInteractive() {
+		return false, fmt.Errorf("bulk operations require approval: re-run with --auto-approve")
+	}
+	fmt.Print("\nProceed? [y/N]: ")
+	var response string
+	_, _ = fmt.Scanln(&response)
+	response = strings.ToLower(strings.TrimSpace(response))
+	return response == "y" || response == "yes", nil
+}
+
+// runForEnvironments calls fn for each environment, at most concurrency at a
+// time, and keeps going when one fails. Output written by fn is prefixed with
+// the environment's name so that concurrent runs stay readable. It returns
+// the environments that failed, in order.
+func runForEnvironments(envs []string, concurrency int, w io.Writer, fn func(env string, out io.Writer) error) []string {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var mu sync.Mutex
+	errs := make([]error, len(envs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, env := range envs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, env string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			out := &prefixWriter{w: w, mu: &mu, prefix: "[" + env + "] "}
+			errs[i] = fn(env, out)
+			if errs[i] != nil {
+				fmt.Fprintf(out, "[error] %v\n", errs[i])
+			}
+			out.Flush()
+		}(i, env)
+	}
+	wg.Wait()
+
+	var failed []string
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, envs[i])
+		}
+	}
+	return failed
+}
+
+// bulkResult summarizes a bulk operation, returning an error if any
+// environment failed.
+func bulkResult(action string, total int, failed []string) error {
+	fmt.Println()
+	if len(failed) == 0 {
+		fmt.Printf("[success] %d environment(s) %s\n", total, action)
+		return nil
+	}
+	fmt.Printf("%d of %d environment(s) %s\n", total-len(failed), total, action)
+	return fmt.Errorf("failed for %d environment(s): %s", len(failed), strings.Join(failed, ", "))
+}
+
+// prefixWriter prefixes each complete line with a fixed string, writing
+// lines to a shared writer one at a time.
+type prefixWriter struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	prefix string
+	buf    bytes.Buffer
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf.Write(b)
+	for {
+		line, err := p.buf.ReadBytes('\n')
+		if err != nil {
+			// Keep the partial line for the next write
+			p.buf.Reset()
+			p.buf.Write(line)
+			return len(b), nil
+		}
+		p.mu.Lock()
+		_, werr := fmt.Fprintf(p.w, "%s%s", p.prefix, line)
+		p.mu.Unlock()
+		if werr != nil {
+			return len(b), werr
+		}
+	}
+}
+
+// Flush writes any trailing partial line.
+func (p *prefixWriter) Flush() {
+	if p.buf.Len() == 0 {
+		return
+	}
+	p.mu.Lock()
+	fmt.Fprintf(p.w, "%s%s\n", p.prefix, p.buf.String())
+	p.mu.Unlock()
+	p.buf.Reset()
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/labels"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func TestEnvironmentSelection_Matches(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	env := &types.EnvironmentState{
+		Name:      "pr-42",
+		Labels:    map[string]string{"team": "payments", "kind": "preview"},
+		CreatedAt: now.Add(-10 * 24 * time.Hour),
+	}
+
+	tests := []struct {
+		name string
+		sel  environmentSelection
+		want bool
+	}{
+		{"everything", environmentSelection{}, true},
+		{"matching labels", environmentSelection{labels: mustSelector(t, "team=payments,kind in (preview,review)")}, true},
+		{"different value", environmentSelection{labels: mustSelector(t, "team!=payments")}, false},
+		{"missing label", environmentSelection{labels: mustSelector(t, "owner")}, false},
+		{"old enough", environmentSelection{olderThan: 7 * 24 * time.Hour}, true},
+		{"too new", environmentSelection{olderThan: 14 * 24 * time.Hour}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.sel.matches(env, now); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func mustSelector(t *testing.T, s string) labels.Selector {
+	t.Helper()
+	sel, err := labels.ParseSelector(s)
+	if err != nil {
+		t.Fatalf("failed to parse selector %q: %v", s, err)
+	}
+	return sel
+}
+
+func TestBulkFlags_Selection(t *testing.T) {
+	if _, ok, err := (&bulkFlags{concurrency: 1}).selection(); ok || err != nil {
+		t.Errorf("expected no selection without bulk flags, got ok=%v err=%v", ok, err)
+	}
+	if _, ok, err := (&bulkFlags{allEnvironments: true, concurrency: 1}).selection(); !ok || err != nil {
+		t.Errorf("expected --all-environments to select, got ok=%v err=%v", ok, err)
+	}
+	if _, _, err := (&bulkFlags{allEnvironments: true}).selection(); err == nil {
+		t.Error("expected error for --concurrency 0")
+	}
+	if _, _, err := (&bulkFlags{olderThan: "soon", concurrency: 1}).selection(); err == nil {
+		t.Error("expected error for invalid --older-than")
+	}
+	if _, _, err := (&bulkFlags{selector: "team in (a", concurrency: 1}).selection(); err == nil {
+		t.Error("expected error for invalid --selector")
+	}
+}
+
+func TestRunForEnvironments(t *testing.T) {
+	var buf bytes.Buffer
+	failed := runForEnvironments([]string{"dev", "staging", "prod"}, 2, &buf, func(env string, out io.Writer) error {
+		fmt.Fprintf(out, "deploying\npartial")
+		if env == "staging" {
+			return fmt.Errorf("boom")
+		}
+		return nil
+	})
+
+	if len(failed) != 1 || failed[0] != "staging" {
+		t.Errorf("expected only staging to fail, got %v", failed)
+	}
+	out := buf.String()
+	for _, want := range []string{"[dev] deploying\n", "[staging] partial[error] boom\n", "[prod] partial\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
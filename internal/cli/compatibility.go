@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/engine"
+	"github.com/davidthor/cldctl/pkg/schema/component"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	"github.com/spf13/cobra"
+)
+
+// compatibilityReport is the result of checking a set of components against
+// a datacenter.
+type compatibilityReport struct {
+	Datacenter string                `json:"datacenter"`
+	Components []componentCompatible `json:"components"`
+}
+
+// componentCompatible is the result of checking one component.
+type componentCompatible struct {
+	Component  string                      `json:"component"`
+	Compatible bool                        `json:"compatible"`
+	Issues     []engine.CompatibilityIssue `json:"issues,omitempty"`
+}
+
+func newValidateCompatibilityCmd() *cobra.Command {
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:     "compatibility <datacenter> <component>...",
+		Aliases: []string{"compat"},
+		Short:   "Check that components can be deployed to a datacenter",
+		Long: `Check each component against a datacenter without deploying anything, and
+report the ones the datacenter can't run: capabilities the component
+requires that the datacenter lacks, resources no datacenter hook provisions
+(or that a hook rejects), and hook outputs the component reads that the
+datacenter's hooks don't declare.
+
+Run it against the whole component catalog before rolling out a datacenter
+upgrade. The command exits non-zero if any component is incompatible.
+
+The datacenter and components can be local paths or references to artifacts
+in the local cache (see 'cldctl pull').
+
+Examples:
+  cldctl validate compatibility ./my-datacenter ./api ./web
+  cldctl validate compatibility ghcr.io/myorg/dc:v2.0.0 ghcr.io/myorg/api:v1 ghcr.io/myorg/web:v3
+  cldctl validate compatibility ./my-datacenter ./api -o json`,
+		Args:         cobra.MinimumNArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dcRef := args[0]
+			dcFile, err := resolveDatacenterFile(dcRef)
+			if err != nil {
+				return fmt.Errorf("failed to resolve datacenter %q: %w", dcRef, err)
+			}
+			dc, err := datacenter.NewLoader().Load(dcFile)
+			if err != nil {
+				return fmt.Errorf("failed to load datacenter: %w", err)
+			}
+
+			report := compatibilityReport{Datacenter: dcRef}
+			loader := component.NewLoader()
+			for _, ref := range args[1:] {
+				compFile, err := resolveComponentFile(ref)
+				if err != nil {
+					return fmt.Errorf("failed to resolve component %q: %w", ref, err)
+				}
+				comp, err := loader.Load(compFile)
+				if err != nil {
+					return fmt.Errorf("failed to load component %s: %w", ref, err)
+				}
+
+				compName := deriveComponentName(ref, isLocalRef(ref))
+				issues, err := engine.CheckCompatibility(dcRef, dc, compName, comp)
+				if err != nil {
+					return fmt.Errorf("failed to check component %s: %w", ref, err)
+				}
+				report.Components = append(report.Components, componentCompatible{
+					Component:  ref,
+					Compatible: len(issues) == 0,
+					Issues:     issues,
+				})
+			}
+
+			switch outputFormat {
+			case "json":
+				data, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				fmt.Println(string(data))
+			default:
+				printCompatibilityReport(report)
+			}
+
+			if n := report.incompatible(); n > 0 {
+				return fmt.Errorf("%d of %d components are incompatible with datacenter %s", n, len(report.Components), dcRef)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, json")
+
+	return cmd
+}
+
+// incompatible returns the number of components with issues.
+func (r compatibilityReport) incompatible() int {
+	n := 0
+	for _, c := range r.Components {
+		if !c.Compatible {
+			n++
+		}
+	}
+	return n
+}
+
+// isLocalRef reports whether a component or datacenter reference is a
+// filesystem path rather than an artifact reference.
+func isLocalRef(ref string) bool {
+	return strings.HasPrefix(ref, ".") || strings.HasPrefix(ref, "/") || strings.HasPrefix(ref, "~")
+}
+
+func printCompatibilityReport(report compatibilityReport) {
+	fmt.Printf("Datacenter: %s\n\n", report.Datacenter)
+
+	width := len("COMPONENT")
+	for _, c := range report.Components {
+		if len(c.Component) > width {
+			width = len(c.Component)
+		}
+	}
+
+	fmt.Printf("%-*s  %-14s %s\n", width, "COMPONENT", "STATUS", "ISSUES")
+	for _, c := range report.Components {
+		status := "compatible"
+		if !c.Compatible {
+			status = "incompatible"
+		}
+		fmt.Printf("%-*s  %-14s %d\n", width, c.Component, status, len(c.Issues))
+	}
+
+	for _, c := range report.Components {
+		if c.Compatible {
+			continue
+		}
+		fmt.Printf("\n%s:\n", c.Component)
+		for _, issue := range c.Issues {
+			if issue.Resource != "" {
+				fmt.Printf("  - [%s] %s: %s\n", issue.Kind, issue.Resource, issue.Message)
+			} else {
+				fmt.Printf("  - [%s] %s\n", issue.Kind, issue.Message)
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateCompatibilityCmd(t *testing.T) {
+	dcDir := createTempDatacenter(t, `
+environment {
+  database {
+    when = element(split(":", node.inputs.type), 0) == "postgres"
+    module "postgres" {
+      build = "./modules/postgres"
+    }
+    outputs = {
+      host = module.postgres.host
+      port = module.postgres.port
+      url  = module.postgres.url
+    }
+  }
+}
+`)
+	postgres := createTempComponent(t, `
+databases:
+  main:
+    type: postgres:^16
+`)
+	redis := createTempComponent(t, `
+databases:
+  cache:
+    type: redis:^7
+`)
+
+	cmd := newValidateCompatibilityCmd()
+	cmd.SetArgs([]string{dcDir, postgres})
+	if err := cmd.Execute(); err != nil {
+		t.Errorf("expected compatible component to pass, got %v", err)
+	}
+
+	cmd = newValidateCompatibilityCmd()
+	cmd.SetArgs([]string{dcDir, postgres, redis})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 components are incompatible") {
+		t.Errorf("expected incompatible component to be reported, got %v", err)
+	}
+}
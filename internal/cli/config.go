@@ -1,10 +1,12 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 
+	"github.com/davidthor/cldctl/pkg/selfupdate"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -17,6 +19,10 @@ const (
 	EnvDefaultDatacenter = "CLDCTL_DATACENTER"
 )
 
+// errNoDatacenter is returned by resolveDatacenter when no datacenter is
+// configured anywhere.
+var errNoDatacenter = errors.New("no datacenter specified")
+
 func newConfigCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "config",
@@ -39,9 +45,20 @@ func newConfigSetCmd() *cobra.Command {
 
 Available keys:
   default-datacenter    The datacenter used when --datacenter/-d is not specified.
+  upgrade-channel       Release channel used by 'cldctl upgrade' (stable or edge).
+  slow-threshold        How long a resource may take to apply before deploys
+                        report it as still running (default 10m). A bare
+                        duration applies to all resource types and type=duration
+                        to one; "off" disables reports.
+  slow-webhook          URL that still running reports are also POSTed to.
+  test-datacenter       The datacenter 'cldctl test component' deploys into.
 
 Examples:
-  cldctl config set default-datacenter my-dc`,
+  cldctl config set default-datacenter my-dc
+  cldctl config set upgrade-channel edge
+  cldctl config set slow-threshold 15m,database=40m,route=off
+  cldctl config set slow-webhook https://hooks.example.com/cldctl
+  cldctl config set test-datacenter ci-dc`,
 		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			key := args[0]
@@ -53,8 +70,22 @@ Examples:
 			switch viperKey {
 			case ConfigKeyDefaultDatacenter:
 				// valid
+			case ConfigKeyUpgradeChannel:
+				if _, err := selfupdate.ParseChannel(value); err != nil {
+					return err
+				}
+			case ConfigKeySlowThreshold:
+				if _, err := parseSlowThresholds(value); err != nil {
+					return err
+				}
+			case ConfigKeySlowWebhook:
+				if err := validateSlowWebhook(value); err != nil {
+					return err
+				}
+			case ConfigKeyTestDatacenter:
+				// valid
 			default:
-				return fmt.Errorf("unknown configuration key %q\n\nAvailable keys:\n  default-datacenter", key)
+				return fmt.Errorf("unknown configuration key %q\n\nAvailable keys:\n  default-datacenter\n  upgrade-channel\n  slow-threshold\n  slow-webhook\n  test-datacenter", key)
 			}
 
 			viper.Set(viperKey, value)
@@ -104,11 +135,28 @@ func newConfigListCmd() *cobra.Command {
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			dc := viper.GetString(ConfigKeyDefaultDatacenter)
+			channel := viper.GetString(ConfigKeyUpgradeChannel)
+			slowThreshold := viper.GetString(ConfigKeySlowThreshold)
+			slowWebhook := viper.GetString(ConfigKeySlowWebhook)
+			testDC := viper.GetString(ConfigKeyTestDatacenter)
 
 			fmt.Println("Configuration:")
 			if dc != "" {
 				fmt.Printf("  default-datacenter = %s\n", dc)
-			} else {
+			}
+			if channel != "" {
+				fmt.Printf("  upgrade-channel = %s\n", channel)
+			}
+			if slowThreshold != "" {
+				fmt.Printf("  slow-threshold = %s\n", slowThreshold)
+			}
+			if slowWebhook != "" {
+				fmt.Printf("  slow-webhook = %s\n", slowWebhook)
+			}
+			if testDC != "" {
+				fmt.Printf("  test-datacenter = %s\n", testDC)
+			}
+			if dc == "" && channel == "" && slowThreshold == "" && slowWebhook == "" && testDC == "" {
 				fmt.Println("  (no values set)")
 			}
 
@@ -124,8 +172,9 @@ func newConfigListCmd() *cobra.Command {
 // Precedence (highest to lowest):
 //  1. --datacenter/-d flag (explicit)
 //  2. CLDCTL_DATACENTER environment variable
-//  3. default_datacenter from ~/.cldctl/config.yaml
-//  4. Error if none set
+//  3. The active context's datacenter
+//  4. default_datacenter from ~/.cldctl/config.yaml
+//  5. Error if none set
 func resolveDatacenter(flagValue string) (string, error) {
 	// 1. Explicit flag
 	if flagValue != "" {
@@ -137,19 +186,30 @@ func resolveDatacenter(flagValue string) (string, error) {
 		return envVal, nil
 	}
 
-	// 3. Config file default
+	// 3. Active context
+	cliCtx, _, err := activeContext()
+	if err != nil {
+		return "", err
+	}
+	if cliCtx != nil && cliCtx.Datacenter != "" {
+		return cliCtx.Datacenter, nil
+	}
+
+	// 4. Config file default
 	if configVal := viper.GetString(ConfigKeyDefaultDatacenter); configVal != "" {
 		return configVal, nil
 	}
 
-	// 4. Error
+	// 5. Error
 	return "", fmt.Errorf(
-		"no datacenter specified\n\n" +
-			"Specify a datacenter using one of:\n" +
-			"  --datacenter/-d flag\n" +
-			"  CLDCTL_DATACENTER environment variable\n" +
-			"  cldctl config set default-datacenter <name>\n\n" +
+		"%w\n\n"+
+			"Specify a datacenter using one of:\n"+
+			"  --datacenter/-d flag\n"+
+			"  CLDCTL_DATACENTER environment variable\n"+
+			"  cldctl context set <context> --datacenter <name>\n"+
+			"  cldctl config set default-datacenter <name>\n\n"+
 			"Deploying a datacenter automatically sets the default.",
+		errNoDatacenter,
 	)
 }
 
@@ -182,6 +242,14 @@ func normalizeConfigKey(key string) string {
 	switch key {
 	case "default-datacenter":
 		return ConfigKeyDefaultDatacenter
+	case "upgrade-channel":
+		return ConfigKeyUpgradeChannel
+	case "slow-threshold":
+		return ConfigKeySlowThreshold
+	case "slow-webhook":
+		return ConfigKeySlowWebhook
+	case "test-datacenter":
+		return ConfigKeyTestDatacenter
 	default:
 		return key
 	}
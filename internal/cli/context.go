@@ -0,0 +1,429 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EnvContext selects the active context, overriding the current context
+	// recorded in ~/.cldctl/contexts.yaml.
+	EnvContext = "CLDCTL_CONTEXT"
+)
+
+// contextName is the value of the global --context flag.
+var contextName string
+
+// cliContext is a named bundle of settings, similar to a kubeconfig context.
+// It lets users switch between, e.g., a laptop datacenter with local state
+// and a shared cloud datacenter with S3 state without repeating flags.
+type cliContext struct {
+	// Backend is the state backend type (local, s3, gcs, azurerm, postgres).
+	Backend string `yaml:"backend,omitempty"`
+
+	// BackendConfig is backend-specific configuration (path, bucket, ...).
+	BackendConfig map[string]string `yaml:"backend_config,omitempty"`
+
+	// Datacenter is the default datacenter for environment-scoped commands.
+	Datacenter string `yaml:"datacenter,omitempty"`
+
+	// DockerConfig is a Docker config directory holding registry credentials.
+	// It is exported as DOCKER_CONFIG so pushes and pulls use this context's
+	// registry auth.
+	DockerConfig string `yaml:"docker_config,omitempty"`
+
+	// Output is the default output format for commands that print tables.
+	Output string `yaml:"output,omitempty"`
+
+	// Tenant is the tenant commands act within in shared datacenters.
+	Tenant string `yaml:"tenant,omitempty"`
+
+	// Catalogs are the catalog indexes 'cldctl search' reads: URLs, OCI
+	// references, or files.
+	Catalogs []string `yaml:"catalogs,omitempty"`
+}
+
+// contextsFile is the on-disk format of ~/.cldctl/contexts.yaml.
+type contextsFile struct {
+	Current  string                 `yaml:"current,omitempty"`
+	Contexts map[string]*cliContext `yaml:"contexts,omitempty"`
+}
+
+// contextsPath returns the path of the contexts file.
+func contextsPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".cldctl", "contexts.yaml"), nil
+}
+
+// loadContexts reads the contexts file. A missing file yields an empty set.
+func loadContexts() (*contextsFile, error) {
+	path, err := contextsPath()
+	if err != nil {
+		return nil, err
+	}
+	cf := &contextsFile{Contexts: map[string]*cliContext{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cf, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, cf); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if cf.Contexts == nil {
+		cf.Contexts = map[string]*cliContext{}
+	}
+	return cf, nil
+}
+
+// saveContexts writes the contexts file. It may hold backend credentials, so
+// it is only readable by the owner.
+func saveContexts(cf *contextsFile) error {
+	path, err := contextsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	data, err := yaml.Marshal(cf)
+	if err != nil {
+		return fmt.Errorf("failed to encode contexts: %w", err)
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// activeContext returns the context selected by --context, CLDCTL_CONTEXT,
+// or the current context, in that order. It returns nil when no context is
+// selected. Explicitly naming a context that does not exist is an error.
+func activeContext() (*cliContext, string, error) {
+	name := contextName
+	explicit := name != ""
+	if name == "" {
+		name = os.Getenv(EnvContext)
+		explicit = name != ""
+	}
+
+	cf, err := loadContexts()
+	if err != nil {
+		return nil, "", err
+	}
+	if name == "" {
+		name = cf.Current
+	}
+	if name == "" {
+		return nil, "", nil
+	}
+
+	ctx, ok := cf.Contexts[name]
+	if !ok {
+		if explicit {
+			return nil, "", fmt.Errorf("context %q not found (see 'cldctl context list')", name)
+		}
+		// A stale current context should not break every command.
+		return nil, "", nil
+	}
+	return ctx, name, nil
+}
+
+// applyContextDefaults applies the active context's process-wide settings
+// before a command runs: registry auth via DOCKER_CONFIG, and the default
+// output format for commands whose --output flag defaults to "table".
+func applyContextDefaults(cmd *cobra.Command) error {
+	ctx, _, err := activeContext()
+	if err != nil || ctx == nil {
+		return err
+	}
+
+	if ctx.DockerConfig != "" && os.Getenv("DOCKER_CONFIG") == "" {
+		_ = os.Setenv("DOCKER_CONFIG", expandHome(ctx.DockerConfig))
+	}
+
+	if ctx.Output != "" {
+		if f := cmd.Flags().Lookup("output"); f != nil && !f.Changed && f.DefValue == "table" {
+			_ = f.Value.Set(ctx.Output)
+		}
+	}
+	return nil
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+func newContextCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "context",
+		Aliases: []string{"ctx"},
+		Short:   "Manage named contexts",
+		Long: `Manage named contexts stored in ~/.cldctl/contexts.yaml.
+
+A context bundles a state backend, default datacenter, tenant, registry
+credentials, catalogs, and output preferences. Switch between them with 'cldctl context use', or
+select one for a single command with the global --context flag (or the
+CLDCTL_CONTEXT environment variable).
+
+Explicit flags and CLDCTL_STATE_*/CLDCTL_DATACENTER environment variables
+always take precedence over the active context.`,
+	}
+
+	cmd.AddCommand(newContextListCmd())
+	cmd.AddCommand(newContextCurrentCmd())
+	cmd.AddCommand(newContextUseCmd())
+	cmd.AddCommand(newContextSetCmd())
+	cmd.AddCommand(newContextDeleteCmd())
+
+	return cmd
+}
+
+func newContextListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List contexts",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cf, err := loadContexts()
+			if err != nil {
+				return err
+			}
+			if len(cf.Contexts) == 0 {
+				fmt.Println("No contexts defined.")
+				fmt.Println()
+				fmt.Println("Create one with: cldctl context set <name> --backend <type> --datacenter <name>")
+				return nil
+			}
+
+			names := make([]string, 0, len(cf.Contexts))
+			for name := range cf.Contexts {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			fmt.Printf("%-9s %-20s %-10s %-20s %s\n", "CURRENT", "NAME", "BACKEND", "DATACENTER", "OUTPUT")
+			for _, name := range names {
+				c := cf.Contexts[name]
+				current := ""
+				if name == cf.Current {
+					current = "*"
+				}
+				fmt.Printf("%-9s %-20s %-10s %-20s %s\n",
+					current, truncateString(name, 20), valueOrDash(c.Backend), truncateString(valueOrDash(c.Datacenter), 20), valueOrDash(c.Output))
+			}
+			return nil
+		},
+	}
+}
+
+func newContextCurrentCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "current",
+		Short: "Show the active context",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, name, err := activeContext()
+			if err != nil {
+				return err
+			}
+			if name == "" {
+				fmt.Println("No context is active.")
+				return nil
+			}
+			fmt.Println(name)
+			return nil
+		},
+	}
+}
+
+func newContextUseCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "use <name>",
+		Short: "Switch the current context",
+		Long: `Switch the current context.
+
+Examples:
+  cldctl context use laptop
+  cldctl context use shared-aws`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cf, err := loadContexts()
+			if err != nil {
+				return err
+			}
+			name := args[0]
+			if _, ok := cf.Contexts[name]; !ok {
+				return fmt.Errorf("context %q not found (see 'cldctl context list')", name)
+			}
+			cf.Current = name
+			if err := saveContexts(cf); err != nil {
+				return fmt.Errorf("failed to save contexts: %w", err)
+			}
+			fmt.Printf("Switched to context %q\n", name)
+			return nil
+		},
+	}
+}
+
+func newContextSetCmd() *cobra.Command {
+	var (
+		backendType   string
+		backendConfig []string
+		datacenter    string
+		dockerConfig  string
+		output        string
+		catalogs      []string
+		tenant        string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "set <name>",
+		Short: "Create or update a context",
+		Long: `Create or update a context. Only the flags you pass are changed.
+
+Examples:
+  cldctl context set laptop --backend local --datacenter local
+  cldctl context set shared-aws --backend s3 \
+    --backend-config bucket=acme-cldctl-state --backend-config region=us-east-1 \
+    --datacenter aws-prod --docker-config ~/.docker-acme --output json
+  cldctl context set shared-aws --catalog ghcr.io/acme/catalog:latest
+  cldctl context set shared-aws --tenant payments`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cf, err := loadContexts()
+			if err != nil {
+				return err
+			}
+
+			name := args[0]
+			c, exists := cf.Contexts[name]
+			if !exists {
+				c = &cliContext{}
+				cf.Contexts[name] = c
+			}
+
+			flags := cmd.Flags()
+			if flags.Changed("backend") {
+				if c.Backend != backendType {
+					c.BackendConfig = nil
+				}
+				c.Backend = backendType
+			}
+			if flags.Changed("backend-config") {
+				if c.BackendConfig == nil {
+					c.BackendConfig = map[string]string{}
+				}
+				for _, kv := range backendConfig {
+					k, v, ok := strings.Cut(kv, "=")
+					if !ok {
+						return fmt.Errorf("invalid --backend-config %q (expected key=value)", kv)
+					}
+					c.BackendConfig[k] = v
+				}
+			}
+			if flags.Changed("datacenter") {
+				c.Datacenter = datacenter
+			}
+			if flags.Changed("docker-config") {
+				c.DockerConfig = dockerConfig
+			}
+			if flags.Changed("output") {
+				switch output {
+				case "", "table", "json", "yaml":
+				default:
+					return fmt.Errorf("unknown output format %q (use 'table', 'json', or 'yaml')", output)
+				}
+				c.Output = output
+			}
+			if flags.Changed("tenant") {
+				c.Tenant = tenant
+			}
+			if flags.Changed("catalog") {
+				// The list is replaced, and --catalog "" clears it
+				c.Catalogs = nil
+				for _, source := range catalogs {
+					if source != "" {
+						c.Catalogs = append(c.Catalogs, source)
+					}
+				}
+			}
+
+			// The first context becomes current so it takes effect immediately.
+			if cf.Current == "" {
+				cf.Current = name
+			}
+
+			if err := saveContexts(cf); err != nil {
+				return fmt.Errorf("failed to save contexts: %w", err)
+			}
+			if exists {
+				fmt.Printf("Updated context %q\n", name)
+			} else {
+				fmt.Printf("Created context %q\n", name)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type (local, s3, gcs, azurerm, postgres)")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value, repeatable)")
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Default datacenter")
+	cmd.Flags().StringVar(&dockerConfig, "docker-config", "", "Docker config directory with registry credentials")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Default output format: table, json, yaml")
+	cmd.Flags().StringVar(&tenant, "tenant", "", "Tenant to act within in shared datacenters")
+	cmd.Flags().StringArrayVar(&catalogs, "catalog", nil, "Catalog searched by 'cldctl search': URL, OCI reference, or file (repeatable; replaces the list)")
+
+	return cmd
+}
+
+func newContextDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "delete <name>",
+		Aliases: []string{"rm"},
+		Short:   "Delete a context",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cf, err := loadContexts()
+			if err != nil {
+				return err
+			}
+			name := args[0]
+			if _, ok := cf.Contexts[name]; !ok {
+				return fmt.Errorf("context %q not found", name)
+			}
+			delete(cf.Contexts, name)
+			if cf.Current == name {
+				cf.Current = ""
+			}
+			if err := saveContexts(cf); err != nil {
+				return fmt.Errorf("failed to save contexts: %w", err)
+			}
+			fmt.Printf("Deleted context %q\n", name)
+			return nil
+		},
+	}
+}
+
+func valueOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
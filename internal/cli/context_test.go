@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/state/types"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withContexts points the CLI at a temporary home directory containing the
+// given contexts file.
+func withContexts(t *testing.T, cf *contextsFile) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(EnvContext, "")
+	t.Setenv(EnvStateBackend, "")
+	t.Setenv(EnvDefaultDatacenter, "")
+	t.Setenv(EnvTenant, "")
+	contextName = ""
+	tenantName = ""
+	t.Cleanup(func() {
+		contextName = ""
+		tenantName = ""
+	})
+	if cf != nil {
+		require.NoError(t, saveContexts(cf))
+	}
+	return home
+}
+
+func TestActiveContext_Precedence(t *testing.T) {
+	withContexts(t, &contextsFile{
+		Current: "laptop",
+		Contexts: map[string]*cliContext{
+			"laptop": {Datacenter: "local"},
+			"shared": {Datacenter: "aws-prod"},
+			"ci":     {Datacenter: "aws-ci"},
+		},
+	})
+
+	_, name, err := activeContext()
+	require.NoError(t, err)
+	assert.Equal(t, "laptop", name)
+
+	t.Setenv(EnvContext, "ci")
+	_, name, err = activeContext()
+	require.NoError(t, err)
+	assert.Equal(t, "ci", name)
+
+	contextName = "shared"
+	c, name, err := activeContext()
+	require.NoError(t, err)
+	assert.Equal(t, "shared", name)
+	assert.Equal(t, "aws-prod", c.Datacenter)
+
+	contextName = "missing"
+	_, _, err = activeContext()
+	assert.Error(t, err)
+}
+
+func TestActiveContext_StaleCurrentIsIgnored(t *testing.T) {
+	withContexts(t, &contextsFile{Current: "gone"})
+
+	c, name, err := activeContext()
+	require.NoError(t, err)
+	assert.Nil(t, c)
+	assert.Empty(t, name)
+}
+
+func TestResolveDatacenter_FromContext(t *testing.T) {
+	withContexts(t, &contextsFile{
+		Current:  "shared",
+		Contexts: map[string]*cliContext{"shared": {Datacenter: "aws-prod"}},
+	})
+
+	dc, err := resolveDatacenter("")
+	require.NoError(t, err)
+	assert.Equal(t, "aws-prod", dc)
+
+	dc, err = resolveDatacenter("explicit")
+	require.NoError(t, err)
+	assert.Equal(t, "explicit", dc)
+
+	t.Setenv(EnvDefaultDatacenter, "from-env")
+	dc, err = resolveDatacenter("")
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", dc)
+}
+
+func TestCreateStateManagerWithConfig_FromContext(t *testing.T) {
+	home := withContexts(t, nil)
+	statePath := filepath.Join(home, "ctx-state")
+	require.NoError(t, saveContexts(&contextsFile{
+		Current: "laptop",
+		Contexts: map[string]*cliContext{
+			"laptop": {Backend: "local", BackendConfig: map[string]string{"path": statePath}},
+		},
+	}))
+
+	mgr, err := createStateManagerWithConfig("", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "local", mgr.Backend().Type())
+
+	_, err = os.Stat(statePath)
+	assert.NoError(t, err, "context backend config is applied")
+}
+
+func TestCreateStateManagerWithConfig_KeepsEncryptionKey(t *testing.T) {
+	home := withContexts(t, nil)
+	statePath := filepath.Join(home, "flag-state")
+	require.NoError(t, saveContexts(&contextsFile{
+		Current: "shared",
+		Contexts: map[string]*cliContext{
+			"shared": {Backend: "s3", BackendConfig: map[string]string{"bucket": "state", "encryption_key": "secret"}},
+		},
+	}))
+
+	// Choosing another backend drops the context's S3 config, but not its
+	// encryption key
+	mgr, err := createStateManagerWithConfig("local", []string{"path=" + statePath})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	env := &types.EnvironmentState{Name: "staging", Components: map[string]*types.ComponentState{
+		"api": {Name: "api", Resources: map[string]*types.ResourceState{
+			"database.main": {
+				Name:             "main",
+				Type:             "database",
+				Outputs:          map[string]interface{}{"password": "hunter22"},
+				SensitiveOutputs: []string{"password"},
+			},
+		}},
+	}}
+	require.NoError(t, mgr.SaveEnvironment(ctx, "dev", env))
+
+	data, err := os.ReadFile(filepath.Join(statePath, "datacenters", "dev", "environments", "staging", "environment.state.json"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "hunter22", "sensitive outputs are encrypted")
+}
+
+func TestApplyContextDefaults_Output(t *testing.T) {
+	withContexts(t, &contextsFile{
+		Current:  "ci",
+		Contexts: map[string]*cliContext{"ci": {Output: "json"}},
+	})
+
+	newCmd := func() (*cobra.Command, *string) {
+		var output string
+		cmd := &cobra.Command{Use: "test"}
+		cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table, json, yaml")
+		return cmd, &output
+	}
+
+	cmd, output := newCmd()
+	require.NoError(t, applyContextDefaults(cmd))
+	assert.Equal(t, "json", *output)
+
+	cmd, output = newCmd()
+	require.NoError(t, cmd.Flags().Set("output", "yaml"))
+	require.NoError(t, applyContextDefaults(cmd))
+	assert.Equal(t, "yaml", *output, "explicit flags win over the context")
+}
+
+func TestContextSetAndUse(t *testing.T) {
+	withContexts(t, nil)
+
+	set := newContextSetCmd()
+	set.SetArgs([]string{"shared", "--backend", "s3", "--backend-config", "bucket=acme", "--datacenter", "aws-prod"})
+	require.NoError(t, set.Execute())
+
+	set = newContextSetCmd()
+	set.SetArgs([]string{"laptop", "--backend", "local"})
+	require.NoError(t, set.Execute())
+
+	cf, err := loadContexts()
+	require.NoError(t, err)
+	assert.Equal(t, "shared", cf.Current, "first context becomes current")
+	assert.Equal(t, "acme", cf.Contexts["shared"].BackendConfig["bucket"])
+
+	use := newContextUseCmd()
+	use.SetArgs([]string{"laptop"})
+	require.NoError(t, use.Execute())
+
+	cf, err = loadContexts()
+	require.NoError(t, err)
+	assert.Equal(t, "laptop", cf.Current)
+
+	use = newContextUseCmd()
+	use.SetArgs([]string{"nope"})
+	assert.Error(t, use.Execute())
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/davidthor/cldctl/pkg/engine"
+	"github.com/davidthor/cldctl/pkg/labels"
 	"github.com/davidthor/cldctl/pkg/state/types"
 	"github.com/spf13/cobra"
 )
@@ -27,6 +28,8 @@ func newCreateEnvironmentCmd() *cobra.Command {
 	var (
 		datacenter    string
 		ifNotExists   bool
+		pin           bool
+		labelFlags    []string
 		backendType   string
 		backendConfig []string
 	)
@@ -37,14 +40,28 @@ func newCreateEnvironmentCmd() *cobra.Command {
 		Short:   "Create a new environment",
 		Long: `Create a new environment.
 
+Use --pin-datacenter to pin the environment to the datacenter version currently
+deployed, so later datacenter releases are only adopted with
+'cldctl update environment <name> --upgrade-datacenter'.
+
+Use --label to attach key=value labels, which bulk commands can select
+environments by (e.g. 'cldctl destroy environment -l team=payments').
+
 Examples:
   cldctl create environment staging -d my-datacenter
-  cldctl create environment production -d prod-dc --if-not-exists`,
+  cldctl create environment production -d prod-dc --if-not-exists
+  cldctl create environment production -d prod-dc --pin-datacenter
+  cldctl create environment pr-123 --label team=payments --label kind=preview`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			envName := args[0]
 			ctx := context.Background()
 
+			envLabels, err := labels.Parse(labelFlags)
+			if err != nil {
+				return err
+			}
+
 			// Resolve datacenter
 			dc, err := resolveDatacenter(datacenter)
 			if err != nil {
@@ -68,7 +85,7 @@ Examples:
 			}
 
 			// Verify datacenter exists
-			_, err = mgr.GetDatacenter(ctx, dc)
+			dcState, err := mgr.GetDatacenter(ctx, dc)
 			if err != nil {
 				return fmt.Errorf("datacenter %q not found: %w", dc, err)
 			}
@@ -88,6 +105,12 @@ Examples:
 				UpdatedAt:  time.Now(),
 				Components: make(map[string]*types.ComponentState),
 			}
+			if len(envLabels) > 0 {
+				envState.Labels = envLabels
+			}
+			if pin && dcState != nil {
+				envState.DatacenterVersion = dcState.Version
+			}
 
 			if err := mgr.SaveEnvironment(ctx, dc, envState); err != nil {
 				return fmt.Errorf("failed to save environment state: %w", err)
@@ -116,6 +139,8 @@ Examples:
 
 	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Datacenter to use (uses default if not set)")
 	cmd.Flags().BoolVar(&ifNotExists, "if-not-exists", false, "Don't error if environment already exists")
+	cmd.Flags().BoolVar(&pin, "pin-datacenter", false, "Pin the environment to the datacenter version currently deployed")
+	cmd.Flags().StringArrayVar(&labelFlags, "label", nil, "Set a label (key=value, repeatable)")
 	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
 	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
 
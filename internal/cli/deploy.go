@@ -3,8 +3,11 @@ package cli
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"syscall"
@@ -16,6 +19,7 @@ import (
 	"github.com/davidthor/cldctl/pkg/engine/planner"
 	"github.com/davidthor/cldctl/pkg/graph"
 	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/labels"
 	"github.com/davidthor/cldctl/pkg/oci"
 	"github.com/davidthor/cldctl/pkg/registry"
 	"github.com/davidthor/cldctl/pkg/schema/component"
@@ -26,12 +30,41 @@ import (
 )
 
 func newDeployCmd() *cobra.Command {
+	var (
+		planFile      string
+		logDir        string
+		backendType   string
+		backendConfig []string
+	)
+
 	cmd := &cobra.Command{
 		Use:   "deploy",
 		Short: "Deploy resources",
-		Long:  `Commands for deploying components and datacenters.`,
+		Long: `Commands for deploying components and datacenters.
+
+With --plan, execute a plan saved by 'cldctl plan environment --out'. The
+plan is refused if the environment's state changed since it was made, or if
+the changes it would make no longer match the saved ones, so that exactly
+the reviewed plan is applied.
+
+Examples:
+  cldctl plan environment production --out plan.cldplan
+  cldctl deploy --plan plan.cldplan`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if planFile == "" {
+				return cmd.Help()
+			}
+			return runDeployPlan(planFile, logDir, backendType, backendConfig)
+		},
 	}
 
+	cmd.Flags().StringVar(&planFile, "plan", "", "Execute a plan saved by 'cldctl plan environment --out'")
+	cmd.Flags().StringVar(&logDir, "log-dir", "", "Write each resource's full build and provisioning output to this directory")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
 	cmd.AddCommand(newDeployComponentCmd())
 	cmd.AddCommand(newDeployDatacenterCmd())
 
@@ -45,6 +78,8 @@ func newDeployComponentCmd() *cobra.Command {
 		variables         []string
 		varFile           string
 		autoApprove       bool
+		interactive       bool
+		dryRun            bool
 		importFile        string
 		targets           []string
 		backendType       string
@@ -53,6 +88,13 @@ func newDeployComponentCmd() *cobra.Command {
 		instanceWeight    int
 		routeSubdomains   []string
 		routePathPrefixes []string
+		allowURLChange    bool
+		takeOver          bool
+		logDir            string
+		planJSON          string
+		upgradeDatacenter bool
+		labelFlags        []string
+		bulk              bulkFlags
 	)
 
 	cmd := &cobra.Command{
@@ -81,16 +123,75 @@ Use --instance and --weight for progressive delivery (canary/blue-green deployme
 When --instance is specified, the component is deployed as a new weighted instance
 alongside existing instances, enabling gradual traffic shifting.
 
+Use --interactive to review the real execution plan before anything runs:
+expand individual changes to see their input diffs, and deselect deletes or
+replacements you are not ready to apply. Skipped changes are left untouched
+and reported as skipped.
+
+Use --dry-run to see exactly what would change without changing anything:
+every resource's hook modules are previewed by their IaC plugins (for
+example an OpenTofu plan) and the reported changes are printed. No resources
+are applied and no state is written.
+
+If the environment is pinned to an older datacenter version than the one
+deployed, the deploy is refused. Pass --upgrade-datacenter to move the
+environment to the deployed version as part of the deploy.
+
+If a previous deploy of the environment crashed partway through (its process
+stopped sending heartbeats), the deploy is refused. Pass --take-over to mark
+the resources it left in flight as unknown, re-apply them, and continue.
+
+Use --label to record key=value labels on the component, replacing any from
+an earlier deploy. Datacenter hooks receive the component's labels, merged
+over its environment's, as node.inputs.labels (e.g. to tag cloud resources).
+
+Instead of -e, deploy to many environments with --all-environments, a label
+selector (-l team=payments), and/or --older-than. The selected environments
+are listed and confirmed before deploying; with --dry-run only the list is
+printed. Use --concurrency to deploy to several environments at once. A
+failure in one environment doesn't stop the others.
+
 Examples:
   cldctl deploy component ghcr.io/myorg/myapp:v1.0.0 -e production
   cldctl deploy component myapp:latest -e staging -d my-dc
   cldctl deploy component ghcr.io/myorg/myapp:v1.0.0 -e production --var api_key=secret123
   cldctl deploy component myorg/stripe:latest -d my-dc --var key=sk_live_xxx
-  cldctl deploy component my-app:v2 -e production --instance canary --weight 10`,
+  cldctl deploy component my-app:v2 -e production --instance canary --weight 10
+  cldctl deploy component my-app:v2 -e production --interactive
+  cldctl deploy component my-app:v2 -e production --dry-run
+  cldctl deploy component my-app:v2 -e production --label team=payments
+  cldctl deploy component my-app:v2 -l team=payments --concurrency 3`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cmd.SilenceUsage = true
 
+			if interactive && autoApprove {
+				return fmt.Errorf("--interactive and --auto-approve cannot be used together")
+			}
+			if interactive && !isInteractive() {
+				return fmt.Errorf("--interactive requires a terminal")
+			}
+			if dryRun && (interactive || importFile != "") {
+				return fmt.Errorf("--dry-run cannot be combined with --interactive or --import-file")
+			}
+
+			sel, isBulk, err := bulk.selection()
+			if err != nil {
+				return err
+			}
+			compLabels, err := labels.Parse(labelFlags)
+			if err != nil {
+				return err
+			}
+			if isBulk {
+				if environment != "" {
+					return fmt.Errorf("--environment cannot be combined with --all-environments, --selector, or --older-than")
+				}
+				if interactive || importFile != "" || instanceName != "" || planJSON != "" {
+					return fmt.Errorf("--interactive, --import-file, --instance, and --plan-json can't be used when deploying to multiple environments")
+				}
+			}
+
 			imageRef := args[0]
 			ctx := context.Background()
 
@@ -107,13 +208,29 @@ Examples:
 			}
 
 			// If no environment specified, register as datacenter-level component
-			if environment == "" {
+			if environment == "" && !isBulk {
+				if dryRun {
+					return fmt.Errorf("--dry-run requires --environment")
+				}
 				return deployDatacenterComponent(ctx, mgr, dc, imageRef, variables, varFile)
 			}
 
-			// Verify environment exists
-			envState, err := mgr.GetEnvironment(ctx, dc, environment)
-			if err != nil {
+			// Verify environment exists, or select the environments to deploy to
+			var envState *types.EnvironmentState
+			var selected []*types.EnvironmentState
+			if isBulk {
+				if selected, err = selectEnvironments(ctx, mgr, dc, sel); err != nil {
+					return err
+				}
+				if len(selected) == 0 {
+					fmt.Printf("No environments in datacenter %q match the selection.\n", dc)
+					return nil
+				}
+				if dryRun {
+					printSelectedEnvironments(os.Stdout, selected)
+					return nil
+				}
+			} else if envState, err = mgr.GetEnvironment(ctx, dc, environment); err != nil {
 				return fmt.Errorf("environment %q not found in datacenter %q: %w", environment, dc, err)
 			}
 
@@ -140,75 +257,16 @@ Examples:
 			// Derive component name from image reference
 			componentName := deriveComponentName(imageRef, false)
 
-			// Resolve image: load from local cache or pull from remote
-			reg, err := registry.NewRegistry()
-			if err != nil {
-				return fmt.Errorf("failed to open local registry: %w", err)
+			// Only replace recorded labels when --label is given
+			var deployLabels map[string]map[string]string
+			if len(labelFlags) > 0 {
+				deployLabels = map[string]map[string]string{componentName: compLabels}
 			}
 
-			var componentPath string
-			entry, err := reg.Get(imageRef)
-			if err == nil && entry != nil && entry.CachePath != "" {
-				// Found in local cache — find the component file
-				compFile := findComponentFile(entry.CachePath)
-				if compFile != "" {
-					componentPath = compFile
-				}
-			}
-
-			if componentPath == "" {
-				// Not in local cache or cache is stale — pull from remote
-				fmt.Printf("[pull] Downloading %s...\n", imageRef)
-				client := oci.NewClient()
-
-				compDir, err := registry.CachePathForRef(imageRef)
-				if err != nil {
-					return fmt.Errorf("failed to compute cache path: %w", err)
-				}
-
-				os.RemoveAll(compDir)
-				if err := os.MkdirAll(compDir, 0755); err != nil {
-					return fmt.Errorf("failed to create cache directory: %w", err)
-				}
-
-				if err := client.Pull(ctx, imageRef, compDir); err != nil {
-					os.RemoveAll(compDir)
-					return fmt.Errorf("failed to pull component: %w", err)
-				}
-
-				// Calculate size
-				var totalSize int64
-				_ = filepath.Walk(compDir, func(_ string, info os.FileInfo, walkErr error) error {
-					if walkErr != nil {
-						return walkErr
-					}
-					if !info.IsDir() {
-						totalSize += info.Size()
-					}
-					return nil
-				})
-
-				// Register in local cache
-				repo, tagPortion := registry.ParseReference(imageRef)
-				compEntry := registry.ArtifactEntry{
-					Reference:  imageRef,
-					Repository: repo,
-					Tag:        tagPortion,
-					Type:       registry.TypeComponent,
-					Size:       totalSize,
-					CreatedAt:  time.Now(),
-					CachePath:  compDir,
-				}
-				if err := reg.Add(compEntry); err != nil {
-					return fmt.Errorf("failed to register component: %w", err)
-				}
-
-				compFile := findComponentFile(compDir)
-				if compFile == "" {
-					return fmt.Errorf("no cld.yml found in artifact %s", imageRef)
-				}
-				componentPath = compFile
-				fmt.Printf("[pull] Cached %s\n", imageRef)
+			// Resolve image: load from local cache or pull from remote
+			componentPath, err := ensureComponentImage(ctx, imageRef, os.Stdout)
+			if err != nil {
+				return err
 			}
 
 			// Load component from resolved cache path for variable prompts and plan display
@@ -250,12 +308,62 @@ Examples:
 			// Create the engine early so we can use it for dependency resolution
 			eng := createEngine(mgr)
 
+			slowNodes, err := slowNodeOptions()
+			if err != nil {
+				return err
+			}
+
 			// Convert vars to interface{} map
 			varsInterface := make(map[string]interface{})
 			for k, v := range vars {
 				varsInterface[k] = v
 			}
 
+			if isBulk {
+				routeOverrides, err := parseRouteFlags(routeSubdomains, routePathPrefixes)
+				if err != nil {
+					return err
+				}
+				ok, err := confirmBulk(fmt.Sprintf("deployed with %s", imageRef), selected, autoApprove)
+				if err != nil || !ok {
+					if err == nil {
+						fmt.Println("Deployment cancelled.")
+					}
+					return err
+				}
+
+				names := make([]string, len(selected))
+				for i, env := range selected {
+					names[i] = env.Name
+				}
+				fmt.Println()
+				failed := runForEnvironments(names, bulk.concurrency, os.Stdout, func(env string, out io.Writer) error {
+					// Each environment gets its own maps since deploys may run
+					// concurrently
+					envVars := make(map[string]interface{}, len(varsInterface))
+					for k, v := range varsInterface {
+						envVars[k] = v
+					}
+					return deployComponentToEnvironment(ctx, eng, engine.DeployOptions{
+						Environment:       env,
+						Datacenter:        dc,
+						Components:        map[string]string{componentName: componentPath},
+						Variables:         map[string]map[string]interface{}{componentName: envVars},
+						Routes:            map[string]map[string]engine.RouteOverride{componentName: routeOverrides},
+						Labels:            deployLabels,
+						SlowNodes:         slowNodes,
+						Output:            out,
+						AutoApprove:       true,
+						Parallelism:       defaultParallelism,
+						AllowURLChange:    allowURLChange,
+						TakeOver:          takeOver,
+						LogDir:            logDir,
+						UpgradeDatacenter: upgradeDatacenter,
+					})
+				})
+				return bulkResult("deployed", len(names), failed)
+			}
+
 			// Build initial component and variable maps
 			componentsMap := map[string]string{componentName: componentPath}
 			variablesMap := map[string]map[string]interface{}{componentName: varsInterface}
@@ -416,12 +524,11 @@ Examples:
 			fmt.Printf("Plan: %d to create, 0 to update, 0 to destroy\n", planCount)
 			fmt.Println()
 
-			// Handle targets filter
-			_ = targets
 			_ = envState
 
-			// Confirm unless --auto-approve is provided
-			if !autoApprove && isInteractive() {
+			// Confirm unless --auto-approve is provided. With --interactive the
+			// real plan is reviewed by the engine's Review callback instead.
+			if !autoApprove && !interactive && !dryRun && isInteractive() {
 				fmt.Print("Proceed with deployment? [Y/n]: ")
 				var response string
 				_, _ = fmt.Scanln(&response)
@@ -459,11 +566,21 @@ Examples:
 					status = StatusPending
 				}
 
+				progress.SetPhase(event.NodeID, string(event.Phase))
 				if event.Error != nil {
 					progress.SetError(event.NodeID, event.Error)
 				} else {
 					progress.UpdateStatus(event.NodeID, status, event.Message)
 				}
+
+				// Capture logs from failed resources for error diagnostics
+				if event.Logs != "" {
+					progress.SetLogs(event.NodeID, event.Logs)
+				}
+				if event.LogFile != "" {
+					progress.SetLogFile(event.NodeID, event.LogFile)
+				}
+
 				progress.PrintUpdate(event.NodeID)
 			}
 
@@ -521,25 +638,51 @@ Examples:
 
 			// Execute deployment using the engine
 			deployOpts := engine.DeployOptions{
-				Environment: environment,
-				Datacenter:  dc,
-				Components:  componentsMap,
-				Variables:   variablesMap,
-				Routes:      routesMap,
-				Output:      os.Stdout,
-				DryRun:      false,
-				AutoApprove: autoApprove,
-				Parallelism: defaultParallelism,
-				OnProgress:  onProgress,
-				OnPlan:      onPlan,
+				Environment:       environment,
+				Datacenter:        dc,
+				Components:        componentsMap,
+				Variables:         variablesMap,
+				Routes:            routesMap,
+				Labels:            deployLabels,
+				SlowNodes:         slowNodes,
+				Output:            os.Stdout,
+				DryRun:            dryRun,
+				AutoApprove:       autoApprove,
+				Parallelism:       defaultParallelism,
+				OnProgress:        onProgress,
+				OnPlan:            onPlan,
+				AllowURLChange:    allowURLChange,
+				TakeOver:          takeOver,
+				LogDir:            logDir,
+				UpgradeDatacenter: upgradeDatacenter,
+				Targets:           resolveTargets(componentName, targets),
 			}
 			if instancesMap != nil {
 				deployOpts.Instances = instancesMap
 			}
+			if interactive {
+				deployOpts.Review = func(plan *planner.Plan) (bool, error) {
+					return reviewPlan(os.Stdin, os.Stdout, plan)
+				}
+			}
 			result, err := eng.Deploy(ctx, deployOpts)
+			if err == nil && result.Cancelled {
+				fmt.Println("Deployment cancelled.")
+				return nil
+			}
+
 			// Always print the final progress summary so the user sees a clear
 			// success/failure report with resource counts and error details.
 			progress.PrintFinalSummary()
+			if result != nil {
+				printWarnings(os.Stdout, result.Warnings())
+			}
+
+			if planJSON != "" && result != nil && result.Plan != nil {
+				if writeErr := writePlanJSON(planJSON, result.Plan); writeErr != nil {
+					return writeErr
+				}
+			}
 
 			if err != nil {
 				return fmt.Errorf("deployment failed: %w", err)
@@ -552,6 +695,10 @@ Examples:
 				return fmt.Errorf("deployment failed")
 			}
 
+			if dryRun {
+				fmt.Println("Dry run complete. No changes were applied.")
+			}
+
 			return nil
 		},
 	}
@@ -561,18 +708,78 @@ Examples:
 	cmd.Flags().StringArrayVar(&variables, "var", nil, "Set variable (key=value)")
 	cmd.Flags().StringVar(&varFile, "var-file", "", "Load variables from file")
 	cmd.Flags().BoolVar(&autoApprove, "auto-approve", false, "Skip confirmation prompt")
+	cmd.Flags().BoolVar(&interactive, "interactive", false, "Review the plan and deselect individual deletes/replacements before executing")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Preview changes with each IaC plugin without applying them")
 	cmd.Flags().StringVar(&importFile, "import-file", "", "Import existing resources from mapping file during deploy")
-	cmd.Flags().StringArrayVar(&targets, "target", nil, "Target specific resource (repeatable)")
+	cmd.Flags().StringArrayVar(&targets, "target", nil, "Only deploy this resource and its dependencies (type/name or component/type/name, repeatable)")
 	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
 	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
 	cmd.Flags().StringVar(&instanceName, "instance", "", "Deploy as a named instance (for progressive delivery)")
 	cmd.Flags().IntVar(&instanceWeight, "weight", 10, "Traffic weight for the instance (0-100, used with --instance)")
 	cmd.Flags().StringArrayVar(&routeSubdomains, "route-subdomain", nil, "Set route subdomain (route=subdomain, repeatable)")
 	cmd.Flags().StringArrayVar(&routePathPrefixes, "route-path-prefix", nil, "Set route path prefix (route=/path, repeatable)")
+	cmd.Flags().BoolVar(&allowURLChange, "allow-url-change", false, "Allow published route URLs to change")
+	cmd.Flags().BoolVar(&takeOver, "take-over", false, "Take over from a deploy of the environment that crashed, re-applying the resources it left in flight")
+	cmd.Flags().StringVar(&logDir, "log-dir", "", "Write each resource's full build and provisioning output to this directory")
+	cmd.Flags().StringVar(&planJSON, "plan-json", "", "Write the execution plan as JSON to this file")
+	cmd.Flags().BoolVar(&upgradeDatacenter, "upgrade-datacenter", false, "Move an environment pinned to an older datacenter version to the deployed version")
+	cmd.Flags().StringArrayVar(&labelFlags, "label", nil, "Set a component label (key=value, repeatable)")
+	addBulkFlags(cmd, &bulk, false)
 
 	return cmd
 }
 
+// deployComponentToEnvironment deploys a component, along with any
+// dependencies missing from the environment, as one environment of a bulk
+// deploy. Nothing is prompted for, so dependencies with required variables
+// must already be deployed.
+func deployComponentToEnvironment(ctx context.Context, eng *engine.Engine, opts engine.DeployOptions) error {
+	deps, err := eng.ResolveDependencies(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to resolve dependencies: %w", err)
+	}
+	for _, dep := range deps {
+		if len(dep.MissingVariables) > 0 {
+			return fmt.Errorf("cannot auto-deploy dependency %q: missing required variables; deploy it to the environment first", dep.Name)
+		}
+		opts.Components[dep.Name] = dep.LocalPath
+	}
+
+	result, err := eng.Deploy(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("deployment failed: %w", err)
+	}
+	printWarnings(opts.Output, result.Warnings())
+	if !result.Success {
+		if result.Execution != nil && len(result.Execution.Errors) > 0 {
+			return fmt.Errorf("deployment failed with %d errors: %v", len(result.Execution.Errors), result.Execution.Errors[0])
+		}
+		return fmt.Errorf("deployment failed")
+	}
+	fmt.Fprintf(opts.Output, "[success] Deployed\n")
+	return nil
+}
+
+// resolveTargets turns --target flags into graph node IDs. Targets name a
+// resource of the deployed component as type/name (or type.name), or any
+// resource of the deploy as component/type/name.
+func resolveTargets(componentName string, targets []string) []string {
+	if len(targets) == 0 {
+		return nil
+	}
+	resolved := make([]string, 0, len(targets))
+	for _, target := range targets {
+		if !strings.Contains(target, "/") {
+			target = strings.Replace(target, ".", "/", 1)
+		}
+		if strings.Count(target, "/") == 1 {
+			target = componentName + "/" + target
+		}
+		resolved = append(resolved, target)
+	}
+	return resolved
+}
+
 // parseRouteFlags parses --route-subdomain and --route-path-prefix flags into a
 // map[string]engine.RouteOverride keyed by route name.
 func parseRouteFlags(subdomains, pathPrefixes []string) (map[string]engine.RouteOverride, error) {
@@ -623,24 +830,9 @@ func deployDatacenterComponent(ctx context.Context, mgr state.Manager, dc, image
 		componentSource = parts[1]
 	}
 
-	// Load variables from file if specified
-	vars := make(map[string]string)
-	if varFile != "" {
-		data, err := os.ReadFile(varFile)
-		if err != nil {
-			return fmt.Errorf("failed to read var file: %w", err)
-		}
-		if err := parseVarFile(data, vars); err != nil {
-			return fmt.Errorf("failed to parse var file: %w", err)
-		}
-	}
-
-	// Parse inline variables
-	for _, v := range variables {
-		parts := strings.SplitN(v, "=", 2)
-		if len(parts) == 2 {
-			vars[parts[0]] = parts[1]
-		}
+	vars, err := loadDatacenterVariables(varFile, variables)
+	if err != nil {
+		return err
 	}
 
 	// Save the datacenter component state
@@ -672,6 +864,8 @@ func newDeployDatacenterCmd() *cobra.Command {
 		varFile       string
 		autoApprove   bool
 		importFile    string
+		stageFlags    []string
+		upgrade       bool
 		backendType   string
 		backendConfig []string
 	)
@@ -695,6 +889,17 @@ NOT listed in the import file are provisioned normally.
 Use 'cldctl audit datacenter <image> --modules' to discover the IaC
 resource addresses you'll need for the import file.
 
+After root modules are provisioned, every environment in the datacenter is
+reconciled. Use --stage to roll the change out in order: each stage names
+environments or glob patterns, and cldctl asks for approval before moving on
+to the next stage. Environments matching no stage are reconciled last. The
+rollout stops at the first environment that fails to reconcile.
+
+Environments pinned to another datacenter version (see 'cldctl update
+environment --pin-datacenter') are skipped. Pass --upgrade-datacenter to
+reconcile and re-pin them as well, or upgrade them one at a time with
+'cldctl update environment <name> --upgrade-datacenter'.
+
 Arguments:
   name    Name for the deployed datacenter
   image   Datacenter image reference (e.g., my-dc:latest, davidthor/local-datacenter)
@@ -706,7 +911,11 @@ Examples:
 
   # Deploy with existing infrastructure imported atomically
   cldctl deploy datacenter prod-dc ghcr.io/myorg/dc:v1.0.0 \
-    --import-file import-datacenter.yml`,
+    --import-file import-datacenter.yml
+
+  # Reconcile dev environments first, then staging, then production
+  cldctl deploy datacenter prod-dc ghcr.io/myorg/dc:v1.1.0 \
+    --stage dev=dev-*,preview-* --stage staging=staging --stage prod=prod*`,
 		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cmd.SilenceUsage = true
@@ -721,92 +930,23 @@ Examples:
 				return fmt.Errorf("failed to create state manager: %w", err)
 			}
 
-			// Load variables from file if specified
-			vars := make(map[string]string)
-			if varFile != "" {
-				data, err := os.ReadFile(varFile)
-				if err != nil {
-					return fmt.Errorf("failed to read var file: %w", err)
-				}
-				if err := parseVarFile(data, vars); err != nil {
-					return fmt.Errorf("failed to parse var file: %w", err)
-				}
-			}
-
-			// Parse inline variables
-			for _, v := range variables {
-				parts := strings.SplitN(v, "=", 2)
-				if len(parts) == 2 {
-					vars[parts[0]] = parts[1]
-				}
+			vars, err := loadDatacenterVariables(varFile, variables)
+			if err != nil {
+				return err
 			}
 
-			// Resolve the image: check local cache first, then pull from remote
-			reg, err := registry.NewRegistry()
+			stages, err := parseRolloutStages(stageFlags)
 			if err != nil {
-				return fmt.Errorf("failed to open local registry: %w", err)
+				return err
 			}
 
-			entry, err := reg.Get(imageRef)
-			if err != nil || entry == nil || entry.CachePath == "" {
-				// Not in local cache — pull from remote
-				fmt.Printf("[pull] Downloading %s...\n", imageRef)
-				client := oci.NewClient()
-
-				dcDir, err := registry.CachePathForRef(imageRef)
-				if err != nil {
-					return fmt.Errorf("failed to compute cache path: %w", err)
-				}
-
-				os.RemoveAll(dcDir)
-				if err := os.MkdirAll(dcDir, 0755); err != nil {
-					return fmt.Errorf("failed to create cache directory: %w", err)
-				}
-
-				if err := client.Pull(ctx, imageRef, dcDir); err != nil {
-					os.RemoveAll(dcDir)
-					return fmt.Errorf("failed to pull datacenter: %w", err)
-				}
-
-				// Calculate size
-				var totalSize int64
-				_ = filepath.Walk(dcDir, func(_ string, info os.FileInfo, walkErr error) error {
-					if walkErr != nil {
-						return walkErr
-					}
-					if !info.IsDir() {
-						totalSize += info.Size()
-					}
-					return nil
-				})
-
-				// Register in local cache
-				repo, tagPortion := registry.ParseReference(imageRef)
-				dcEntry := registry.ArtifactEntry{
-					Reference:  imageRef,
-					Repository: repo,
-					Tag:        tagPortion,
-					Type:       registry.TypeDatacenter,
-					Size:       totalSize,
-					CreatedAt:  time.Now(),
-					CachePath:  dcDir,
-				}
-				if err := reg.Add(dcEntry); err != nil {
-					return fmt.Errorf("failed to register datacenter: %w", err)
-				}
-
-				fmt.Printf("[pull] Cached %s\n", imageRef)
-			} else {
-				// Verify cached content still exists on disk
-				dcFile := findDatacenterFile(entry.CachePath)
-				if dcFile == "" {
-					return fmt.Errorf("cached artifact for %s is missing from disk; try: cldctl pull datacenter %s", imageRef, imageRef)
-				}
+			if err := ensureDatacenterImage(ctx, imageRef); err != nil {
+				return err
 			}
 
 			// Generate and display the deployment plan
 			eng := createEngine(mgr)
-			dcPlan, err := eng.PlanDatacenter(ctx, dcName, imageRef)
+			dcPlan, err := eng.PlanDatacenter(ctx, dcName, imageRef, vars, false)
 			if err != nil {
 				return fmt.Errorf("failed to plan datacenter deployment: %w", err)
 			}
@@ -815,8 +955,12 @@ Examples:
 			eng.PrintDatacenterPlanSummary(os.Stdout, dcPlan)
 			fmt.Println()
 
-			// Confirm unless --auto-approve is provided
+			// Confirm unless --auto-approve is provided. Datacenter changes
+			// reconcile every environment, so never assume approval.
 			if !autoApprove {
+				if !isInteractive() {
+					return fmt.Errorf("datacenter deploy requires approval: review the plan with 'cldctl plan datacenter' and re-run with --auto-approve")
+				}
 				fmt.Print("Proceed? [Y/n]: ")
 				var response string
 				_, _ = fmt.Scanln(&response)
@@ -948,17 +1092,43 @@ Examples:
 			// Execute root-level modules and reconcile environments.
 			// Modules that were imported above already have state, so the
 			// engine updates them in-place rather than creating new resources.
-			dcResult, err := eng.DeployDatacenter(ctx, engine.DeployDatacenterOptions{
-				Datacenter:  dcName,
-				Output:      os.Stdout,
-				Parallelism: defaultParallelism,
-			})
+			dcOpts := engine.DeployDatacenterOptions{
+				Datacenter:        dcName,
+				Output:            os.Stdout,
+				Parallelism:       defaultParallelism,
+				Stages:            stages,
+				UpgradeDatacenter: upgrade,
+			}
+			if !autoApprove {
+				dcOpts.ApproveStage = func(stage engine.DatacenterRolloutStage, envs []string) (bool, error) {
+					fmt.Printf("\nReconcile stage %q (%s)? [Y/n]: ", stage.Name, strings.Join(envs, ", "))
+					var response string
+					_, _ = fmt.Scanln(&response)
+					response = strings.ToLower(strings.TrimSpace(response))
+					return response == "" || response == "y" || response == "yes", nil
+				}
+			}
+
+			dcResult, err := eng.DeployDatacenter(ctx, dcOpts)
 			if err != nil {
+				if dcResult != nil && dcResult.FailedEnvironment != "" {
+					printPendingEnvironments(dcResult.PendingEnvironments)
+					return fmt.Errorf("datacenter rollout stopped: %w", err)
+				}
 				return fmt.Errorf("failed to provision datacenter infrastructure: %w", err)
 			}
 			if !dcResult.Success {
 				return fmt.Errorf("datacenter infrastructure provisioning failed")
 			}
+			if len(dcResult.PinnedEnvironments) > 0 {
+				fmt.Printf("\n%d pinned environment(s) left on their datacenter version: %s\n", len(dcResult.PinnedEnvironments), strings.Join(dcResult.PinnedEnvironments, ", "))
+				fmt.Println("Upgrade them with 'cldctl update environment <name> --upgrade-datacenter'.")
+			}
+			if len(dcResult.PendingEnvironments) > 0 {
+				printPendingEnvironments(dcResult.PendingEnvironments)
+				fmt.Printf("Re-run 'cldctl deploy datacenter %s %s' to continue the rollout.\n", dcName, imageRef)
+				return nil
+			}
 
 			fmt.Printf("[success] Datacenter %q deployed from %s\n", dcName, imageRef)
 			fmt.Println()
@@ -972,12 +1142,46 @@ Examples:
 	cmd.Flags().StringVar(&varFile, "var-file", "", "Load variables from file")
 	cmd.Flags().BoolVar(&autoApprove, "auto-approve", false, "Skip confirmation prompt")
 	cmd.Flags().StringVar(&importFile, "import-file", "", "Import existing resources from mapping file during deploy")
+	cmd.Flags().StringArrayVar(&stageFlags, "stage", nil, "Reconcile environments in stages (name=env-or-glob,...; repeatable)")
+	cmd.Flags().BoolVar(&upgrade, "upgrade-datacenter", false, "Also reconcile environments pinned to another datacenter version, re-pinning them")
 	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
 	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
 
 	return cmd
 }
 
+// parseRolloutStages parses --stage flags of the form name=pattern[,pattern].
+func parseRolloutStages(flags []string) ([]engine.DatacenterRolloutStage, error) {
+	stages := make([]engine.DatacenterRolloutStage, 0, len(flags))
+	for _, flag := range flags {
+		name, patterns, ok := strings.Cut(flag, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.TrimSpace(patterns) == "" {
+			return nil, fmt.Errorf("invalid --stage %q: expected name=environment[,environment]", flag)
+		}
+		stage := engine.DatacenterRolloutStage{Name: name}
+		for _, pattern := range strings.Split(patterns, ",") {
+			pattern = strings.TrimSpace(pattern)
+			if pattern == "" {
+				continue
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid --stage %q: bad pattern %q", flag, pattern)
+			}
+			stage.Environments = append(stage.Environments, pattern)
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
+
+func printPendingEnvironments(envs []string) {
+	if len(envs) == 0 {
+		return
+	}
+	fmt.Printf("\n%d environment(s) not reconciled: %s\n", len(envs), strings.Join(envs, ", "))
+}
+
 // Helper functions used by deploy commands - these are duplicated from component.go
 // to avoid circular dependencies. In a future refactor, these could be moved to a
 // shared helpers package.
@@ -1159,6 +1363,178 @@ func promptForVariable(v component.Variable) (string, error) {
 	return strings.TrimSpace(input), nil
 }
 
+// ensureDatacenterImage makes sure a datacenter image is in the local cache,
+// pulling it from the remote registry if needed.
+func ensureDatacenterImage(ctx context.Context, imageRef string) error {
+	reg, err := registry.NewRegistry()
+	if err != nil {
+		return fmt.Errorf("failed to open local registry: %w", err)
+	}
+
+	entry, err := reg.Get(imageRef)
+	if err != nil || entry == nil || entry.CachePath == "" {
+		// Not in local cache — pull from remote
+		fmt.Printf("[pull] Downloading %s...\n", imageRef)
+		client := oci.NewClient()
+
+		dcDir, err := registry.CachePathForRef(imageRef)
+		if err != nil {
+			return fmt.Errorf("failed to compute cache path: %w", err)
+		}
+
+		os.RemoveAll(dcDir)
+		if err := os.MkdirAll(dcDir, 0755); err != nil {
+			return fmt.Errorf("failed to create cache directory: %w", err)
+		}
+
+		if err := client.Pull(ctx, imageRef, dcDir); err != nil {
+			os.RemoveAll(dcDir)
+			return fmt.Errorf("failed to pull datacenter: %w", err)
+		}
+
+		// Calculate size
+		var totalSize int64
+		_ = filepath.Walk(dcDir, func(_ string, info os.FileInfo, walkErr error) error {
+			if walkErr != nil {
+				return walkErr
+			}
+			if !info.IsDir() {
+				totalSize += info.Size()
+			}
+			return nil
+		})
+
+		// Register in local cache
+		repo, tagPortion := registry.ParseReference(imageRef)
+		dcEntry := registry.ArtifactEntry{
+			Reference:  imageRef,
+			Repository: repo,
+			Tag:        tagPortion,
+			Type:       registry.TypeDatacenter,
+			Size:       totalSize,
+			CreatedAt:  time.Now(),
+			CachePath:  dcDir,
+		}
+		if err := reg.Add(dcEntry); err != nil {
+			return fmt.Errorf("failed to register datacenter: %w", err)
+		}
+
+		fmt.Printf("[pull] Cached %s\n", imageRef)
+	} else {
+		// Verify cached content still exists on disk
+		dcFile := findDatacenterFile(entry.CachePath)
+		if dcFile == "" {
+			return fmt.Errorf("cached artifact for %s is missing from disk; try: cldctl pull datacenter %s", imageRef, imageRef)
+		}
+	}
+	return nil
+}
+
+// ensureComponentImage returns the component file of a component artifact,
+// pulling it into the local cache first if it isn't cached. Pull progress is
+// written to out.
+func ensureComponentImage(ctx context.Context, imageRef string, out io.Writer) (string, error) {
+	reg, err := registry.NewRegistry()
+	if err != nil {
+		return "", fmt.Errorf("failed to open local registry: %w", err)
+	}
+
+	entry, err := reg.Get(imageRef)
+	if err == nil && entry != nil && entry.CachePath != "" {
+		// Found in local cache — find the component file
+		if compFile := findComponentFile(entry.CachePath); compFile != "" {
+			return compFile, nil
+		}
+	}
+
+	// Not in local cache or cache is stale — pull from remote
+	return pullComponentImage(ctx, imageRef, out)
+}
+
+// pullComponentImage pulls a component artifact into the local cache,
+// replacing any cached copy, and returns its component file.
+func pullComponentImage(ctx context.Context, imageRef string, out io.Writer) (string, error) {
+	reg, err := registry.NewRegistry()
+	if err != nil {
+		return "", fmt.Errorf("failed to open local registry: %w", err)
+	}
+
+	fmt.Fprintf(out, "[pull] Downloading %s...\n", imageRef)
+	client := oci.NewClient()
+
+	compDir, err := registry.CachePathForRef(imageRef)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute cache path: %w", err)
+	}
+
+	os.RemoveAll(compDir)
+	if err := os.MkdirAll(compDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	if err := client.Pull(ctx, imageRef, compDir); err != nil {
+		os.RemoveAll(compDir)
+		return "", fmt.Errorf("failed to pull component: %w", err)
+	}
+
+	// Calculate size
+	var totalSize int64
+	_ = filepath.Walk(compDir, func(_ string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if !info.IsDir() {
+			totalSize += info.Size()
+		}
+		return nil
+	})
+
+	// Register in local cache
+	repo, tagPortion := registry.ParseReference(imageRef)
+	compEntry := registry.ArtifactEntry{
+		Reference:  imageRef,
+		Repository: repo,
+		Tag:        tagPortion,
+		Type:       registry.TypeComponent,
+		Size:       totalSize,
+		CreatedAt:  time.Now(),
+		CachePath:  compDir,
+	}
+	if err := reg.Add(compEntry); err != nil {
+		return "", fmt.Errorf("failed to register component: %w", err)
+	}
+
+	compFile := findComponentFile(compDir)
+	if compFile == "" {
+		return "", fmt.Errorf("no cld.yml found in artifact %s", imageRef)
+	}
+	fmt.Fprintf(out, "[pull] Cached %s\n", imageRef)
+	return compFile, nil
+}
+
+// loadDatacenterVariables reads variables from varFile, if set, and applies
+// inline key=value variables on top.
+func loadDatacenterVariables(varFile string, variables []string) (map[string]string, error) {
+	vars := make(map[string]string)
+	if varFile != "" {
+		data, err := os.ReadFile(varFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read var file: %w", err)
+		}
+		if err := parseVarFile(data, vars); err != nil {
+			return nil, fmt.Errorf("failed to parse var file: %w", err)
+		}
+	}
+
+	for _, v := range variables {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) == 2 {
+			vars[parts[0]] = parts[1]
+		}
+	}
+	return vars, nil
+}
+
 // parseVarFile parses a variable file with KEY=value format.
 func parseVarFile(data []byte, vars map[string]string) error {
 	lines := strings.Split(string(data), "\n")
@@ -1180,6 +1556,18 @@ func parseVarFile(data []byte, vars map[string]string) error {
 	return nil
 }
 
+// writePlanJSON writes an execution plan to path in its stable JSON form.
+func writePlanJSON(path string, plan *planner.Plan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal plan: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
+}
+
 // populateProgressFromPlan populates a progress table from the real execution plan,
 // using the actual dependency graph instead of a simplified approximation.
 func populateProgressFromPlan(progress *ProgressTable, plan *planner.Plan) {
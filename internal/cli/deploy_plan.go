@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/davidthor/cldctl/pkg/engine"
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+)
+
+// runDeployPlan executes a plan saved by 'cldctl plan environment --out'.
+func runDeployPlan(path, logDir, backendType string, backendConfig []string) error {
+	pf, err := engine.ReadPlanFile(path)
+	if err != nil {
+		return err
+	}
+
+	mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create state manager: %w", err)
+	}
+
+	fmt.Printf("Applying saved plan for environment %s in datacenter %s\n\n", pf.Environment, pf.Datacenter)
+
+	progress := NewProgressTable(os.Stdout)
+	result, err := createEngine(mgr).ApplyPlanFile(context.Background(), engine.ApplyPlanFileOptions{
+		PlanFile:    pf,
+		Output:      os.Stdout,
+		Parallelism: defaultParallelism,
+		OnPlan: func(plan *planner.Plan) {
+			populateProgressFromPlan(progress, plan)
+			progress.PrintInitial()
+		},
+		OnProgress: progressTableCallback(progress),
+		LogDir:     logDir,
+	})
+	if err != nil {
+		return fmt.Errorf("deployment failed: %w", err)
+	}
+
+	progress.PrintFinalSummary()
+	printWarnings(os.Stdout, result.Warnings())
+
+	if !result.Success {
+		if result.Execution != nil && len(result.Execution.Errors) > 0 {
+			return fmt.Errorf("deployment failed with %d errors: %v", len(result.Execution.Errors), result.Execution.Errors[0])
+		}
+		return fmt.Errorf("deployment failed")
+	}
+	return nil
+}
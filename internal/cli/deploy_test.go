@@ -2,6 +2,7 @@ package cli
 
 import (
 	"os"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -44,7 +45,7 @@ func TestDeployComponentCmd_Flags(t *testing.T) {
 	}
 
 	// Check optional flags
-	optionalFlags := []string{"var", "var-file", "auto-approve", "target", "backend", "backend-config"}
+	optionalFlags := []string{"var", "var-file", "auto-approve", "interactive", "dry-run", "target", "backend", "backend-config"}
 	for _, flagName := range optionalFlags {
 		if cmd.Flags().Lookup(flagName) == nil {
 			t.Errorf("expected --%s flag", flagName)
@@ -70,7 +71,7 @@ func TestDeployDatacenterCmd_Flags(t *testing.T) {
 	}
 
 	// Check optional flags
-	optionalFlags := []string{"var", "var-file", "auto-approve", "backend", "backend-config"}
+	optionalFlags := []string{"var", "var-file", "auto-approve", "stage", "backend", "backend-config"}
 	for _, flagName := range optionalFlags {
 		if cmd.Flags().Lookup(flagName) == nil {
 			t.Errorf("expected --%s flag", flagName)
@@ -83,6 +84,28 @@ func TestDeployDatacenterCmd_Flags(t *testing.T) {
 	}
 }
 
+func TestParseRolloutStages(t *testing.T) {
+	stages, err := parseRolloutStages([]string{"dev=dev-*, preview-*", "prod=production"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stages) != 2 {
+		t.Fatalf("expected 2 stages, got %d", len(stages))
+	}
+	if stages[0].Name != "dev" || len(stages[0].Environments) != 2 || stages[0].Environments[1] != "preview-*" {
+		t.Errorf("unexpected dev stage: %+v", stages[0])
+	}
+	if stages[1].Name != "prod" || len(stages[1].Environments) != 1 {
+		t.Errorf("unexpected prod stage: %+v", stages[1])
+	}
+
+	for _, invalid := range []string{"dev", "=dev-*", "dev=", "dev=[a"} {
+		if _, err := parseRolloutStages([]string{invalid}); err == nil {
+			t.Errorf("expected error for --stage %q", invalid)
+		}
+	}
+}
+
 func TestDeriveComponentName(t *testing.T) {
 	tests := []struct {
 		source      string
@@ -115,6 +138,17 @@ func TestDeriveComponentName(t *testing.T) {
 	}
 }
 
+func TestResolveTargets(t *testing.T) {
+	got := resolveTargets("web", []string{"deployment/api", "deployment.worker", "shared/database/main"})
+	want := []string{"web/deployment/api", "web/deployment/worker", "shared/database/main"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resolveTargets() = %v, want %v", got, want)
+	}
+	if got := resolveTargets("web", nil); got != nil {
+		t.Errorf("expected no targets, got %v", got)
+	}
+}
+
 func TestIsInteractive_CIEnvVars(t *testing.T) {
 	// Save original env vars
 	originalCI := os.Getenv("CI")
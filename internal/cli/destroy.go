@@ -3,7 +3,9 @@ package cli
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -45,7 +47,9 @@ component declaration when --environment is omitted.
 
 When -e is provided, the component and all its resources are destroyed
 in the target environment. If other components depend on it, the destroy
-is blocked unless --force is used.
+is blocked unless --force is used. The component's destroy webhooks are
+called first; if one fails, the destroy is also blocked unless --force is
+used.
 
 When -e is omitted, the component declaration is removed from the
 datacenter. This does not destroy any already-deployed instances of the
@@ -134,6 +138,7 @@ Examples:
 			if err != nil {
 				return fmt.Errorf("destroy failed: %w", err)
 			}
+			printWarnings(os.Stdout, result.Warnings())
 
 			if !result.Success {
 				if result.Execution != nil && len(result.Execution.Errors) > 0 {
@@ -157,7 +162,7 @@ Examples:
 	cmd.Flags().StringVarP(&environment, "environment", "e", "", "Target environment (omit to remove datacenter component)")
 	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
 	cmd.Flags().BoolVar(&autoApprove, "auto-approve", false, "Skip confirmation prompt")
-	cmd.Flags().BoolVar(&force, "force", false, "Force destroy even if other components depend on this one")
+	cmd.Flags().BoolVar(&force, "force", false, "Force destroy even if other components depend on this one or its destroy webhooks fail")
 	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
 	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
 
@@ -217,6 +222,12 @@ func newDestroyDatacenterCmd() *cobra.Command {
 
 WARNING: This will destroy all environments in the datacenter. Use with caution.
 
+The datacenter is torn down in the reverse of the order it was built: each
+environment's components and environment modules, then the datacenter's root
+modules (last declared first), and finally its state. If a step fails the
+teardown stops, keeping state for everything not yet destroyed; run the
+command again to resume.
+
 Examples:
   cldctl destroy datacenter my-dc
   cldctl destroy datacenter prod-dc --auto-approve`,
@@ -256,9 +267,18 @@ Examples:
 				fmt.Println()
 			}
 
-			moduleCount := len(dc.Modules)
-			fmt.Printf("This will destroy %d modules.\n", moduleCount)
-			fmt.Println()
+			if len(dc.Modules) > 0 {
+				fmt.Println("The following root modules will be destroyed:")
+				names := make([]string, 0, len(dc.Modules))
+				for name := range dc.Modules {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					fmt.Printf("  - %s\n", name)
+				}
+				fmt.Println()
+			}
 
 			// Confirm unless --auto-approve is provided
 			if !autoApprove {
@@ -274,25 +294,28 @@ Examples:
 
 			fmt.Println()
 
-			// Destroy environments first
-			for _, envRef := range envRefs {
-				fmt.Printf("[destroy] Destroying environment %q...\n", envRef.Name)
-				if err := mgr.DeleteEnvironment(ctx, dcName, envRef.Name); err != nil {
-					return fmt.Errorf("failed to destroy environment %q: %w", envRef.Name, err)
+			eng := createEngine(mgr)
+			result, err := eng.DestroyDatacenter(ctx, engine.DestroyDatacenterOptions{
+				Datacenter: dcName,
+				Output:     os.Stdout,
+			})
+
+			// Stop any containers left behind by environments that are gone
+			if result != nil {
+				for _, envName := range result.DestroyedEnvironments {
+					if cleanupErr := CleanupByEnvName(ctx, envName); cleanupErr != nil {
+						fmt.Printf("Warning: failed to cleanup containers for %q: %v\n", envName, cleanupErr)
+					}
 				}
 			}
 
-			fmt.Printf("[destroy] Destroying datacenter %q...\n", dcName)
-
-			// Delete datacenter state (this deletes everything under datacenters/<name>/)
-			if err := mgr.DeleteDatacenter(ctx, dcName); err != nil {
-				return fmt.Errorf("failed to delete datacenter state: %w", err)
+			if err != nil {
+				fmt.Println()
+				fmt.Printf("Run 'cldctl destroy datacenter %s' again to resume the teardown.\n", dcName)
+				return err
 			}
 
 			fmt.Printf("[success] Datacenter removed successfully\n")
-			fmt.Println()
-			fmt.Println("Note: Infrastructure created by datacenter hooks was destroyed")
-			fmt.Println("when the associated environments and components were removed.")
 
 			return nil
 		},
@@ -309,26 +332,46 @@ func newDestroyEnvironmentCmd() *cobra.Command {
 	var (
 		datacenter    string
 		autoApprove   bool
+		dryRun        bool
+		bulk          bulkFlags
 		backendType   string
 		backendConfig []string
 	)
 
 	cmd := &cobra.Command{
-		Use:     "environment <name>",
+		Use:     "environment [name]",
 		Aliases: []string{"env", "envs", "environments"},
 		Short:   "Destroy an environment",
 		Long: `Destroy an environment and all its resources.
 
 WARNING: This will destroy all components and resources in the environment. Use with caution.
 
+Instead of a name, select many environments with --all-environments, a label
+selector (-l team=payments), and/or --older-than. The selected environments
+are listed before anything is destroyed; --dry-run stops there. Use
+--concurrency to destroy several at once. A failure in one environment
+doesn't stop the others.
+
 Examples:
   cldctl destroy environment staging
-  cldctl destroy environment production --auto-approve`,
-		Args: cobra.ExactArgs(1),
+  cldctl destroy environment production --auto-approve
+  cldctl destroy environment -l team=payments --older-than 14d --dry-run
+  cldctl destroy environment --older-than 30d --concurrency 4 --auto-approve`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			envName := args[0]
 			ctx := context.Background()
 
+			sel, isBulk, err := bulk.selection()
+			if err != nil {
+				return err
+			}
+			if isBulk == (len(args) == 1) {
+				return fmt.Errorf("specify either an environment name or --all-environments, --selector, or --older-than")
+			}
+			if dryRun && !isBulk {
+				return fmt.Errorf("--dry-run requires --all-environments, --selector, or --older-than")
+			}
+
 			// Resolve datacenter
 			dc, err := resolveDatacenter(datacenter)
 			if err != nil {
@@ -341,6 +384,40 @@ Examples:
 				return fmt.Errorf("failed to create state manager: %w", err)
 			}
 
+			if isBulk {
+				envs, err := selectEnvironments(ctx, mgr, dc, sel)
+				if err != nil {
+					return err
+				}
+				if len(envs) == 0 {
+					fmt.Printf("No environments in datacenter %q match the selection.\n", dc)
+					return nil
+				}
+				if dryRun {
+					printSelectedEnvironments(os.Stdout, envs)
+					return nil
+				}
+				ok, err := confirmBulk("destroyed", envs, autoApprove)
+				if err != nil || !ok {
+					if err == nil {
+						fmt.Println("Destroy cancelled.")
+					}
+					return err
+				}
+
+				names := make([]string, len(envs))
+				for i, env := range envs {
+					names[i] = env.Name
+				}
+				fmt.Println()
+				failed := runForEnvironments(names, bulk.concurrency, os.Stdout, func(env string, out io.Writer) error {
+					return destroyEnvironment(ctx, mgr, dc, env, out)
+				})
+				return bulkResult("destroyed", len(names), failed)
+			}
+
+			envName := args[0]
+
 			// Get environment state
 			env, err := mgr.GetEnvironment(ctx, dc, envName)
 			if err != nil {
@@ -376,25 +453,8 @@ Examples:
 			}
 
 			fmt.Println()
-			fmt.Printf("[destroy] Destroying environment resources...\n")
-
-			// Use the engine to properly destroy all resources (components + env modules)
-			eng := createEngine(mgr)
-			if err := eng.DestroyEnvironment(ctx, dc, envName, os.Stdout, nil); err != nil {
-				fmt.Printf("[warning] Engine-based destroy encountered errors: %v\n", err)
-			}
-
-			// Also do Docker cleanup as a safety net for any orphaned containers
-			fmt.Printf("[destroy] Stopping any remaining containers...\n")
-			if err := CleanupByEnvName(ctx, envName); err != nil {
-				fmt.Printf("Warning: failed to cleanup containers: %v\n", err)
-			}
-
-			fmt.Printf("[destroy] Removing environment state...\n")
-
-			// Delete environment state
-			if err := mgr.DeleteEnvironment(ctx, dc, envName); err != nil {
-				return fmt.Errorf("failed to delete environment state: %w", err)
+			if err := destroyEnvironment(ctx, mgr, dc, envName, os.Stdout); err != nil {
+				return err
 			}
 
 			fmt.Printf("[success] Environment destroyed successfully\n")
@@ -405,8 +465,44 @@ Examples:
 
 	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
 	cmd.Flags().BoolVar(&autoApprove, "auto-approve", false, "Skip confirmation prompt")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the selected environments without destroying them")
+	addBulkFlags(cmd, &bulk, false)
 	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
 	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
 
 	return cmd
 }
+
+// destroyEnvironment destroys all resources of an environment and removes its
+// state, reporting progress to w. Engine errors are reported as warnings so
+// that state is still cleaned up for environments whose infrastructure is
+// already gone.
+func destroyEnvironment(ctx context.Context, mgr state.Manager, dc, envName string, w io.Writer) error {
+	ctx, unlock, err := engine.LockEnvironment(ctx, mgr, dc, envName, "destroy environment", false, w)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	fmt.Fprintf(w, "[destroy] Destroying environment resources...\n")
+
+	// Use the engine to properly destroy all resources (components + env modules)
+	eng := createEngine(mgr)
+	if err := eng.DestroyEnvironment(ctx, dc, envName, w, nil); err != nil {
+		fmt.Fprintf(w, "[warning] Engine-based destroy encountered errors: %v\n", err)
+	}
+
+	// Also do Docker cleanup as a safety net for any orphaned containers
+	fmt.Fprintf(w, "[destroy] Stopping any remaining containers...\n")
+	if err := CleanupByEnvName(ctx, envName); err != nil {
+		fmt.Fprintf(w, "Warning: failed to cleanup containers: %v\n", err)
+	}
+
+	fmt.Fprintf(w, "[destroy] Removing environment state...\n")
+
+	// Delete environment state
+	if err := mgr.DeleteEnvironment(ctx, dc, envName); err != nil {
+		return fmt.Errorf("failed to delete environment state: %w", err)
+	}
+	return nil
+}
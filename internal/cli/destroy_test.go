@@ -20,7 +20,7 @@ func TestNewDestroyCmd(t *testing.T) {
 	expectedCommands := []string{
 		"component <name>",
 		"datacenter <name>",
-		"environment <name>",
+		"environment [name]",
 	}
 
 	for _, expected := range expectedCommands {
@@ -80,12 +80,12 @@ func TestDestroyDatacenterCmd_Flags(t *testing.T) {
 func TestDestroyEnvironmentCmd_Flags(t *testing.T) {
 	cmd := newDestroyEnvironmentCmd()
 
-	if cmd.Use != "environment <name>" {
-		t.Errorf("expected use 'environment <name>', got '%s'", cmd.Use)
+	if cmd.Use != "environment [name]" {
+		t.Errorf("expected use 'environment [name]', got '%s'", cmd.Use)
 	}
 
 	// Check flags
-	flags := []string{"auto-approve", "backend", "backend-config"}
+	flags := []string{"auto-approve", "dry-run", "all-environments", "selector", "older-than", "concurrency", "backend", "backend-config"}
 	for _, flagName := range flags {
 		if cmd.Flags().Lookup(flagName) == nil {
 			t.Errorf("expected --%s flag", flagName)
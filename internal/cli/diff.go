@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/davidthor/cldctl/pkg/engine"
+	"github.com/davidthor/cldctl/pkg/schema/component"
+	"github.com/davidthor/cldctl/pkg/snapshot"
+	"github.com/spf13/cobra"
+)
+
+// componentDiff is the infrastructure impact of moving between two versions
+// of a component.
+type componentDiff struct {
+	From      string              `json:"from"`
+	To        string              `json:"to"`
+	Resources []snapshot.NodeDiff `json:"resources"`
+	Variables []variableDiff      `json:"variables"`
+}
+
+// variableDiff is a variable that was added, removed, or changed.
+type variableDiff struct {
+	Name       string                  `json:"name"`
+	Change     string                  `json:"change"`
+	Required   bool                    `json:"required,omitempty"`
+	Properties []snapshot.PropertyDiff `json:"properties,omitempty"`
+}
+
+func newDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compare versions of a resource",
+		Long:  `Commands for comparing versions of components and the state of environments.`,
+	}
+
+	cmd.AddCommand(newDiffComponentCmd())
+	cmd.AddCommand(newDiffEnvironmentCmd())
+
+	return cmd
+}
+
+func newDiffComponentCmd() *cobra.Command {
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:     "component <from> <to>",
+		Aliases: []string{"comp"},
+		Short:   "Show the infrastructure impact of a component version change",
+		Long: `Compare two versions of a component and report the resources and variables
+that were added, removed, or changed — for example to review a version bump
+in an environment file.
+
+Each version can be an OCI reference or a local path. References that aren't
+in the local cache are pulled. The resource graph of each version is built
+as it would be for a fresh environment, without a datacenter, and compared
+resource by resource.
+
+Examples:
+  cldctl diff component ghcr.io/myorg/app:1.4.0 ghcr.io/myorg/app:1.5.0
+  cldctl diff component ghcr.io/myorg/app:1.4.0 ./app
+  cldctl diff component ghcr.io/myorg/app:1.4.0 ghcr.io/myorg/app:1.5.0 -o json`,
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			fromRef, toRef := args[0], args[1]
+
+			// Both versions are graphed under one name so that their
+			// resources line up
+			name := deriveComponentName(toRef, isLocalRef(toRef))
+
+			fromComp, fromSnap, err := loadComponentVersion(ctx, fromRef, name)
+			if err != nil {
+				return err
+			}
+			toComp, toSnap, err := loadComponentVersion(ctx, toRef, name)
+			if err != nil {
+				return err
+			}
+
+			diff := componentDiff{
+				From:      fromRef,
+				To:        toRef,
+				Resources: snapshot.Diff(fromSnap, toSnap),
+				Variables: diffVariables(fromComp.Variables(), toComp.Variables()),
+			}
+
+			switch outputFormat {
+			case "json":
+				data, err := json.MarshalIndent(diff, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				fmt.Println(string(data))
+			default:
+				printComponentDiff(diff)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, json")
+
+	return cmd
+}
+
+// loadComponentVersion loads a component from a local path or artifact
+// reference and takes a snapshot of its graph.
+func loadComponentVersion(ctx context.Context, ref, name string) (component.Component, *snapshot.Snapshot, error) {
+	var compFile string
+	var err error
+	if isLocalRef(ref) {
+		compFile, err = resolveComponentFile(ref)
+	} else {
+		// Pull progress goes to stderr so that JSON output stays clean
+		compFile, err = ensureComponentImage(ctx, ref, os.Stderr)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve component %q: %w", ref, err)
+	}
+
+	comp, err := component.NewLoader().Load(compFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load component %s: %w", ref, err)
+	}
+
+	g, err := engine.BuildGraph("diff", "", nil, map[string]component.Component{name: comp})
+	if err != nil {
+		return nil, nil, err
+	}
+	snap := snapshot.New(g, nil, snapshot.Options{
+		ComponentDirs: map[string]string{name: filepath.Dir(compFile)},
+	})
+	return comp, snap, nil
+}
+
+// diffVariables compares the variable declarations of two component
+// versions, sorted by name.
+func diffVariables(before, after []component.Variable) []variableDiff {
+	prev := make(map[string]component.Variable, len(before))
+	for _, v := range before {
+		prev[v.Name()] = v
+	}
+	seen := make(map[string]bool, len(after))
+
+	diffs := []variableDiff{}
+	for _, v := range after {
+		seen[v.Name()] = true
+		old, ok := prev[v.Name()]
+		if !ok {
+			diffs = append(diffs, variableDiff{Name: v.Name(), Change: snapshot.NodeAdded, Required: v.Required()})
+			continue
+		}
+
+		var props []snapshot.PropertyDiff
+		add := func(path string, o, n interface{}) {
+			if fmt.Sprintf("%v", o) != fmt.Sprintf("%v", n) {
+				props = append(props, snapshot.PropertyDiff{Path: path, Old: o, New: n})
+			}
+		}
+		add("type", old.Type(), v.Type())
+		add("default", old.Default(), v.Default())
+		add("required", old.Required(), v.Required())
+		add("sensitive", old.Sensitive(), v.Sensitive())
+		add("enum", old.Enum(), v.Enum())
+		add("pattern", old.Pattern(), v.Pattern())
+		if len(props) > 0 {
+			diffs = append(diffs, variableDiff{Name: v.Name(), Change: snapshot.NodeChanged, Required: v.Required(), Properties: props})
+		}
+	}
+	for _, v := range before {
+		if !seen[v.Name()] {
+			diffs = append(diffs, variableDiff{Name: v.Name(), Change: snapshot.NodeRemoved})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Name < diffs[j].Name })
+	return diffs
+}
+
+// diffMarkers are the prefixes printed for each kind of change.
+var diffMarkers = map[string]string{
+	snapshot.NodeAdded:   "+",
+	snapshot.NodeRemoved: "-",
+	snapshot.NodeChanged: "~",
+}
+
+func printComponentDiff(diff componentDiff) {
+	fmt.Printf("Comparing %s -> %s\n", diff.From, diff.To)
+
+	if len(diff.Resources) == 0 && len(diff.Variables) == 0 {
+		fmt.Println()
+		fmt.Println("No changes to resources or variables.")
+		return
+	}
+
+	if len(diff.Resources) > 0 {
+		fmt.Println()
+		fmt.Println("Resources:")
+		for _, r := range diff.Resources {
+			fmt.Printf("  %s %s\n", diffMarkers[r.Change], r.ID)
+			printPropertyDiffs(r.Properties)
+		}
+	}
+
+	if len(diff.Variables) > 0 {
+		fmt.Println()
+		fmt.Println("Variables:")
+		for _, v := range diff.Variables {
+			suffix := ""
+			if v.Change == snapshot.NodeAdded && v.Required {
+				suffix = " (required)"
+			}
+			fmt.Printf("  %s %s%s\n", diffMarkers[v.Change], v.Name, suffix)
+			printPropertyDiffs(v.Properties)
+		}
+	}
+}
+
+func printPropertyDiffs(props []snapshot.PropertyDiff) {
+	for _, p := range props {
+		fmt.Printf("      %s: %s -> %s\n", p.Path, formatDiffValue(p.Old), formatDiffValue(p.New))
+	}
+}
+
+// formatDiffValue renders a value as compact JSON, or "(none)" when unset.
+func formatDiffValue(v interface{}) string {
+	if v == nil {
+		return "(none)"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
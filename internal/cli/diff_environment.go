@@ -0,0 +1,323 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/snapshot"
+	"github.com/davidthor/cldctl/pkg/state/types"
+	"github.com/spf13/cobra"
+)
+
+// sensitiveMask replaces the values of sensitive variables in diffs.
+const sensitiveMask = "<sensitive>"
+
+// environmentDiff is what differs between the deployed state of two
+// environments.
+type environmentDiff struct {
+	From       string                  `json:"from"`
+	To         string                  `json:"to"`
+	Variables  []snapshot.PropertyDiff `json:"variables"`
+	Components []envComponentDiff      `json:"components"`
+}
+
+// envComponentDiff is a component that is only deployed to one of the
+// environments, or is deployed differently to each.
+type envComponentDiff struct {
+	Name   string `json:"name"`
+	Change string `json:"change"`
+
+	// Source is the component's source in the environment it's deployed to,
+	// for added and removed components
+	Source string `json:"source,omitempty"`
+
+	// Properties are the differences in source, version, variables
+	// (variables.<name>), routes (routes.<name>), and resource counts
+	// (resources.<type>) of changed components
+	Properties []snapshot.PropertyDiff `json:"properties,omitempty"`
+}
+
+func newDiffEnvironmentCmd() *cobra.Command {
+	var (
+		datacenter    string
+		toDatacenter  string
+		outputFormat  string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "environment <from> <to>",
+		Aliases: []string{"env", "envs", "environments"},
+		Short:   "Show what's deployed differently to two environments",
+		Long: `Compare the deployed state of two environments: the components deployed to
+each, and for components deployed to both, their source and version,
+variables, routes, and the number of resources of each type. Variables set on
+the environments themselves are compared too.
+
+Values of variables whose names look sensitive (containing "secret",
+"password", or "key") are compared but not shown.
+
+Use --to-datacenter when the environments are in different datacenters.
+
+Examples:
+  cldctl diff environment staging production
+  cldctl diff environment staging production -d aws-staging --to-datacenter aws-prod
+  cldctl diff environment staging production -o json`,
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			fromName, toName := args[0], args[1]
+
+			fromDC, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+			toDC := fromDC
+			if toDatacenter != "" {
+				toDC = toDatacenter
+			}
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			fromEnv, err := mgr.GetEnvironment(ctx, fromDC, fromName)
+			if err != nil {
+				return fmt.Errorf("environment %q not found in datacenter %q: %w", fromName, fromDC, err)
+			}
+			toEnv, err := mgr.GetEnvironment(ctx, toDC, toName)
+			if err != nil {
+				return fmt.Errorf("environment %q not found in datacenter %q: %w", toName, toDC, err)
+			}
+
+			diff := diffEnvironments(fromEnv, toEnv)
+			if fromDC != toDC {
+				diff.From = fromDC + "/" + fromName
+				diff.To = toDC + "/" + toName
+			}
+
+			switch outputFormat {
+			case "json":
+				data, err := json.MarshalIndent(diff, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				fmt.Println(string(data))
+			default:
+				printEnvironmentDiff(diff)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Datacenter of the environments (uses default if not set)")
+	cmd.Flags().StringVar(&toDatacenter, "to-datacenter", "", "Datacenter of the <to> environment, if different")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, json")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+// diffEnvironments compares two environments' deployed state. Components
+// are sorted by name.
+func diffEnvironments(from, to *types.EnvironmentState) environmentDiff {
+	diff := environmentDiff{
+		From:       from.Name,
+		To:         to.Name,
+		Variables:  diffStringMaps("", from.Variables, to.Variables, true),
+		Components: []envComponentDiff{},
+	}
+	if diff.Variables == nil {
+		diff.Variables = []snapshot.PropertyDiff{}
+	}
+
+	for name, fc := range from.Components {
+		tc, ok := to.Components[name]
+		if !ok {
+			diff.Components = append(diff.Components, envComponentDiff{Name: name, Change: snapshot.NodeRemoved, Source: fc.Source})
+			continue
+		}
+		if props := diffComponentStates(fc, tc); len(props) > 0 {
+			diff.Components = append(diff.Components, envComponentDiff{Name: name, Change: snapshot.NodeChanged, Properties: props})
+		}
+	}
+	for name, tc := range to.Components {
+		if _, ok := from.Components[name]; !ok {
+			diff.Components = append(diff.Components, envComponentDiff{Name: name, Change: snapshot.NodeAdded, Source: tc.Source})
+		}
+	}
+
+	sort.Slice(diff.Components, func(i, j int) bool { return diff.Components[i].Name < diff.Components[j].Name })
+	return diff
+}
+
+// diffComponentStates compares how a component is deployed to two
+// environments.
+func diffComponentStates(from, to *types.ComponentState) []snapshot.PropertyDiff {
+	var props []snapshot.PropertyDiff
+	if from.Source != to.Source {
+		props = append(props, snapshot.PropertyDiff{Path: "source", Old: from.Source, New: to.Source})
+	}
+	if from.Version != to.Version {
+		props = append(props, snapshot.PropertyDiff{Path: "version", Old: from.Version, New: to.Version})
+	}
+	props = append(props, diffStringMaps("variables.", from.Variables, to.Variables, true)...)
+	props = append(props, diffStringMaps("routes.", routeAddresses(from.Routes), routeAddresses(to.Routes), false)...)
+	props = append(props, diffResourceCounts(resourceCounts(from), resourceCounts(to))...)
+	return props
+}
+
+// diffStringMaps compares two maps key by key, sorted by key, with each path
+// prefixed with prefix. When mask is set, the values of sensitive keys are
+// replaced with sensitiveMask.
+func diffStringMaps(prefix string, from, to map[string]string, mask bool) []snapshot.PropertyDiff {
+	keys := map[string]bool{}
+	for k := range from {
+		keys[k] = true
+	}
+	for k := range to {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var props []snapshot.PropertyDiff
+	for _, k := range sorted {
+		fv, inFrom := from[k]
+		tv, inTo := to[k]
+		if inFrom == inTo && fv == tv {
+			continue
+		}
+		p := snapshot.PropertyDiff{Path: prefix + k}
+		if inFrom {
+			p.Old = fv
+		}
+		if inTo {
+			p.New = tv
+		}
+		if mask && isSensitiveVariableName(k) {
+			if inFrom {
+				p.Old = sensitiveMask
+			}
+			if inTo {
+				p.New = sensitiveMask
+			}
+		}
+		props = append(props, p)
+	}
+	return props
+}
+
+// routeAddresses describes where each of a component's routes is
+// published, e.g. "api/v1 (+ api.example.com/v1)".
+func routeAddresses(routes map[string]*types.RouteState) map[string]string {
+	addrs := make(map[string]string, len(routes))
+	for name, rs := range routes {
+		addr := rs.Subdomain + rs.PathPrefix
+		if rs.Generated {
+			addr += " (generated)"
+		}
+		var hosts []string
+		for _, h := range rs.Hostnames {
+			host := h.Host
+			if host == "" {
+				host = h.Subdomain
+			}
+			hosts = append(hosts, host+h.PathPrefix)
+		}
+		if len(hosts) > 0 {
+			addr += " (+ " + strings.Join(hosts, ", ") + ")"
+		}
+		addrs[name] = addr
+	}
+	return addrs
+}
+
+// resourceCounts counts a component's resources by type, across its
+// instances.
+func resourceCounts(comp *types.ComponentState) map[string]int {
+	counts := map[string]int{}
+	for _, res := range comp.Resources {
+		counts[res.Type]++
+	}
+	for _, inst := range comp.Instances {
+		for _, res := range inst.Resources {
+			counts[res.Type]++
+		}
+	}
+	return counts
+}
+
+func diffResourceCounts(from, to map[string]int) []snapshot.PropertyDiff {
+	resTypes := map[string]bool{}
+	for t := range from {
+		resTypes[t] = true
+	}
+	for t := range to {
+		resTypes[t] = true
+	}
+	sorted := make([]string, 0, len(resTypes))
+	for t := range resTypes {
+		sorted = append(sorted, t)
+	}
+	sort.Strings(sorted)
+
+	var props []snapshot.PropertyDiff
+	for _, t := range sorted {
+		if from[t] != to[t] {
+			props = append(props, snapshot.PropertyDiff{Path: "resources." + t, Old: from[t], New: to[t]})
+		}
+	}
+	return props
+}
+
+// isSensitiveVariableName reports whether a variable's name suggests its
+// value is secret. Deployed state doesn't record which variables were
+// declared sensitive, so the name is all there is to go on.
+func isSensitiveVariableName(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.Contains(lower, "secret") ||
+		strings.Contains(lower, "password") ||
+		strings.Contains(lower, "key")
+}
+
+func printEnvironmentDiff(diff environmentDiff) {
+	fmt.Printf("Comparing %s -> %s\n", diff.From, diff.To)
+
+	if len(diff.Variables) == 0 && len(diff.Components) == 0 {
+		fmt.Println()
+		fmt.Println("No differences in components or variables.")
+		return
+	}
+
+	if len(diff.Variables) > 0 {
+		fmt.Println()
+		fmt.Println("Environment variables:")
+		for _, v := range diff.Variables {
+			fmt.Printf("  %s: %s -> %s\n", v.Path, formatDiffValue(v.Old), formatDiffValue(v.New))
+		}
+	}
+
+	if len(diff.Components) > 0 {
+		fmt.Println()
+		fmt.Println("Components:")
+		for _, c := range diff.Components {
+			if c.Source != "" {
+				fmt.Printf("  %s %s (%s)\n", diffMarkers[c.Change], c.Name, c.Source)
+			} else {
+				fmt.Printf("  %s %s\n", diffMarkers[c.Change], c.Name)
+			}
+			printPropertyDiffs(c.Properties)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/schema/component"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func TestDiffVariables(t *testing.T) {
+	load := func(yaml string) component.Component {
+		comp, err := component.NewLoader().LoadFromBytes([]byte(yaml), "/tmp/app/cld.yml")
+		if err != nil {
+			t.Fatalf("failed to load component: %v", err)
+		}
+		return comp
+	}
+	before := load(`
+variables:
+  tier:
+    default: small
+  legacy:
+    default: x
+  region:
+    required: true
+`)
+	after := load(`
+variables:
+  tier:
+    default: medium
+  api_key:
+    required: true
+    sensitive: true
+  region:
+    required: true
+`)
+
+	diffs := diffVariables(before.Variables(), after.Variables())
+
+	want := []string{
+		"api_key added true []",
+		"legacy removed false []",
+		"tier changed false [{default small medium}]",
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("expected %d diffs, got %+v", len(want), diffs)
+	}
+	for i, d := range diffs {
+		if got := fmt.Sprintf("%s %s %t %v", d.Name, d.Change, d.Required, d.Properties); got != want[i] {
+			t.Errorf("diff %d: expected %q, got %q", i, want[i], got)
+		}
+	}
+}
+
+func TestDiffComponentCmd_LocalPaths(t *testing.T) {
+	from := createTempComponent(t, `
+databases:
+  main:
+    type: postgres:^16
+`)
+	to := createTempComponent(t, `
+databases:
+  main:
+    type: postgres:^17
+`)
+
+	cmd := newDiffComponentCmd()
+	cmd.SetArgs([]string{from, to, "-o", "json"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("expected diff to succeed, got %v", err)
+	}
+}
+
+func TestDiffEnvironments(t *testing.T) {
+	from := &types.EnvironmentState{
+		Name:      "staging",
+		Variables: map[string]string{"region": "us-east-1", "api_key": "staging-key"},
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Source:    "ghcr.io/acme/api:1.5.0",
+				Version:   "1.5.0",
+				Variables: map[string]string{"log_level": "debug", "db_password": "a"},
+				Routes:    map[string]*types.RouteState{"main": {Subdomain: "api-staging", PathPrefix: "/"}},
+				Resources: map[string]*types.ResourceState{
+					"deployment.api":    {Type: "deployment", Name: "api"},
+					"deployment.worker": {Type: "deployment", Name: "worker"},
+				},
+			},
+			"preview-tools": {Source: "ghcr.io/acme/tools:latest"},
+			"web":           {Source: "ghcr.io/acme/web:2.0.0", Version: "2.0.0"},
+		},
+	}
+	to := &types.EnvironmentState{
+		Name:      "production",
+		Variables: map[string]string{"region": "us-west-2", "api_key": "prod-key"},
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Source:    "ghcr.io/acme/api:1.4.0",
+				Version:   "1.4.0",
+				Variables: map[string]string{"log_level": "info", "db_password": "b"},
+				Routes:    map[string]*types.RouteState{"main": {Subdomain: "api", PathPrefix: "/"}},
+				Resources: map[string]*types.ResourceState{
+					"deployment.api": {Type: "deployment", Name: "api"},
+				},
+			},
+			"billing": {Source: "ghcr.io/acme/billing:1.0.0"},
+			"web":     {Source: "ghcr.io/acme/web:2.0.0", Version: "2.0.0"},
+		},
+	}
+
+	diff := diffEnvironments(from, to)
+
+	wantVars := []string{
+		"api_key <sensitive> <sensitive>",
+		"region us-east-1 us-west-2",
+	}
+	if len(diff.Variables) != len(wantVars) {
+		t.Fatalf("expected %d variable diffs, got %+v", len(wantVars), diff.Variables)
+	}
+	for i, v := range diff.Variables {
+		if got := fmt.Sprintf("%s %v %v", v.Path, v.Old, v.New); got != wantVars[i] {
+			t.Errorf("variable diff %d: expected %q, got %q", i, wantVars[i], got)
+		}
+	}
+
+	var got []string
+	for _, c := range diff.Components {
+		got = append(got, c.Name+" "+c.Change)
+	}
+	want := []string{"api changed", "billing added", "preview-tools removed"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected components %v, got %v", want, got)
+	}
+
+	var props []string
+	for _, p := range diff.Components[0].Properties {
+		props = append(props, fmt.Sprintf("%s %v %v", p.Path, p.Old, p.New))
+	}
+	wantProps := []string{
+		"source ghcr.io/acme/api:1.5.0 ghcr.io/acme/api:1.4.0",
+		"version 1.5.0 1.4.0",
+		"variables.db_password <sensitive> <sensitive>",
+		"variables.log_level debug info",
+		"routes.main api-staging/ api/",
+		"resources.deployment 2 1",
+	}
+	if fmt.Sprint(props) != fmt.Sprint(wantProps) {
+		t.Errorf("expected api properties\n  %v\ngot\n  %v", wantProps, props)
+	}
+}
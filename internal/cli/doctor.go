@@ -0,0 +1,417 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/registry"
+	"github.com/davidthor/cldctl/pkg/selfupdate"
+	"github.com/davidthor/cldctl/pkg/state"
+	"github.com/docker/docker/client"
+	"github.com/spf13/cobra"
+)
+
+// checkStatus is the outcome of a single doctor check.
+type checkStatus string
+
+const (
+	checkPass checkStatus = "pass"
+	checkWarn checkStatus = "warn"
+	checkFail checkStatus = "fail"
+)
+
+// doctorCheck is the result of one host prerequisite check.
+type doctorCheck struct {
+	Name    string      `json:"name"`
+	Status  checkStatus `json:"status"`
+	Message string      `json:"message"`
+	Fix     string      `json:"fix,omitempty"`
+}
+
+// Free space thresholds for the artifact cache volume.
+const (
+	diskWarnBytes = 5 << 30
+	diskFailBytes = 1 << 30
+)
+
+// doctorTimeout bounds each network-bound check.
+const doctorTimeout = 5 * time.Second
+
+// pluginBinaries lists IaC plugins that shell out to an external tool, with
+// where to get it. Plugins are only needed by datacenters that use them, so a
+// missing binary is a warning rather than a failure.
+var pluginBinaries = []struct {
+	plugin  string
+	binary  string
+	install string
+}{
+	{"opentofu", "tofu or terraform", "https://opentofu.org/docs/intro/install/"},
+	{"pulumi", "pulumi", "https://www.pulumi.com/docs/install/"},
+	{"crossplane", "kubectl", "https://kubernetes.io/docs/tasks/tools/"},
+	{"ansible", "ansible-playbook", "https://docs.ansible.com/ansible/latest/installation_guide/"},
+}
+
+func newDoctorCmd() *cobra.Command {
+	var (
+		outputFormat  string
+		registries    []string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check host prerequisites",
+		Long: `Check that this machine has everything cldctl needs.
+
+Checks:
+  docker      Docker is installed and the daemon is reachable
+  disk        Free space on the volume holding the artifact cache
+  registry    Network access to OCI registries referenced by cached artifacts
+              (and any passed with --registry)
+  state       The state backend is reachable
+  version     The state was not written by a newer, incompatible cldctl
+  plugins     Binaries required by IaC plugins (tofu, pulumi, kubectl, ...)
+
+Each check reports pass, warn, or fail along with a suggested fix. The
+command exits non-zero if any check fails.
+
+Examples:
+  cldctl doctor
+  cldctl doctor --registry ghcr.io
+  cldctl doctor --backend s3 --backend-config bucket=my-state
+  cldctl doctor -o json`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			checks := runDoctorChecks(ctx, backendType, backendConfig, registries)
+
+			switch outputFormat {
+			case "json":
+				data, err := json.MarshalIndent(checks, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				fmt.Println(string(data))
+			case "table", "":
+				printDoctorChecks(checks)
+			default:
+				return fmt.Errorf("unknown output format %q (use 'table' or 'json')", outputFormat)
+			}
+
+			if failed := countChecks(checks, checkFail); failed > 0 {
+				return fmt.Errorf("%d check(s) failed", failed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, json")
+	cmd.Flags().StringArrayVar(&registries, "registry", nil, "Additional registry host to check (repeatable)")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+// runDoctorChecks runs every prerequisite check in display order.
+func runDoctorChecks(ctx context.Context, backendType string, backendConfig, registries []string) []doctorCheck {
+	var checks []doctorCheck
+
+	checks = append(checks, checkDocker(ctx))
+	checks = append(checks, checkDiskSpace())
+	checks = append(checks, checkRegistries(ctx, registries)...)
+	checks = append(checks, checkState(ctx, backendType, backendConfig)...)
+	checks = append(checks, checkPlugins()...)
+
+	return checks
+}
+
+func checkDocker(ctx context.Context) doctorCheck {
+	check := doctorCheck{Name: "docker"}
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		check.Status = checkFail
+		check.Message = fmt.Sprintf("failed to create Docker client: %v", err)
+		check.Fix = "Install Docker: https://docs.docker.com/get-docker/"
+		return check
+	}
+	defer cli.Close()
+
+	pingCtx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+
+	version, err := cli.ServerVersion(pingCtx)
+	if err != nil {
+		check.Status = checkFail
+		check.Message = fmt.Sprintf("Docker daemon is not reachable: %v", err)
+		check.Fix = "Start Docker Desktop or the docker service, or set DOCKER_HOST to a running daemon"
+		return check
+	}
+
+	check.Status = checkPass
+	check.Message = fmt.Sprintf("Docker %s (API %s)", version.Version, version.APIVersion)
+	return check
+}
+
+func checkDiskSpace() doctorCheck {
+	check := doctorCheck{Name: "disk"}
+
+	cachePath, err := registry.DefaultCachePath()
+	if err != nil {
+		check.Status = checkWarn
+		check.Message = fmt.Sprintf("could not locate the artifact cache: %v", err)
+		return check
+	}
+
+	free, err := diskFreeBytes(existingParent(cachePath))
+	if err != nil {
+		check.Status = checkWarn
+		check.Message = fmt.Sprintf("could not determine free space for %s: %v", cachePath, err)
+		return check
+	}
+
+	check.Message = fmt.Sprintf("%s free for %s", formatSize(int64(free)), cachePath)
+	switch {
+	case free < diskFailBytes:
+		check.Status = checkFail
+		check.Fix = "Free up disk space, or remove unused artifacts from ~/.cldctl/cache and unused Docker images (docker system prune)"
+	case free < diskWarnBytes:
+		check.Status = checkWarn
+		check.Fix = "Builds and image pulls may run out of space; consider running docker system prune"
+	default:
+		check.Status = checkPass
+	}
+	return check
+}
+
+// existingParent returns path or its nearest ancestor that exists, so free
+// space can be measured before the cache directory has been created.
+func existingParent(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+func checkRegistries(ctx context.Context, extra []string) []doctorCheck {
+	hosts := make(map[string]bool)
+	for _, h := range extra {
+		hosts[h] = true
+	}
+	if reg, err := registry.NewRegistry(); err == nil {
+		if entries, err := reg.List(); err == nil {
+			for _, entry := range entries {
+				if host := registryHost(entry.Repository); host != "" {
+					hosts[host] = true
+				}
+			}
+		}
+	}
+
+	if len(hosts) == 0 {
+		return []doctorCheck{{
+			Name:    "registry",
+			Status:  checkPass,
+			Message: "no remote registries referenced by cached artifacts",
+		}}
+	}
+
+	names := make([]string, 0, len(hosts))
+	for h := range hosts {
+		names = append(names, h)
+	}
+	sort.Strings(names)
+
+	checks := make([]doctorCheck, 0, len(names))
+	for _, host := range names {
+		checks = append(checks, checkRegistry(ctx, host))
+	}
+	return checks
+}
+
+// checkRegistry probes the registry's /v2/ endpoint. Any HTTP response,
+// including 401, means the registry is reachable.
+func checkRegistry(ctx context.Context, host string) doctorCheck {
+	check := doctorCheck{Name: "registry " + host}
+
+	scheme := "https"
+	if isLocalRegistry(host) {
+		scheme = "http"
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, fmt.Sprintf("%s://%s/v2/", scheme, host), nil)
+	if err != nil {
+		check.Status = checkWarn
+		check.Message = fmt.Sprintf("invalid registry host: %v", err)
+		return check
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		check.Status = checkWarn
+		check.Message = fmt.Sprintf("cannot reach registry: %v", err)
+		check.Fix = "Check your network connection, proxy settings (HTTPS_PROXY), and that the registry host is correct"
+		return check
+	}
+	resp.Body.Close()
+
+	check.Status = checkPass
+	check.Message = fmt.Sprintf("reachable (HTTP %d)", resp.StatusCode)
+	return check
+}
+
+// registryHost extracts the registry host from an OCI repository. References
+// without an explicit host resolve to Docker Hub.
+func registryHost(repository string) string {
+	if repository == "" || repository == "<none>" {
+		return ""
+	}
+	first, _, found := strings.Cut(repository, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		return first
+	}
+	return "registry-1.docker.io"
+}
+
+func isLocalRegistry(host string) bool {
+	name := host
+	if h, _, found := strings.Cut(host, ":"); found {
+		name = h
+	}
+	return name == "localhost" || strings.HasPrefix(name, "127.")
+}
+
+// checkState verifies the state backend is reachable and was not written by
+// a CLI with a newer state schema.
+func checkState(ctx context.Context, backendType string, backendConfig []string) []doctorCheck {
+	check := doctorCheck{Name: "state"}
+
+	mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+	if err != nil {
+		check.Status = checkFail
+		check.Message = fmt.Sprintf("failed to configure state backend: %v", err)
+		check.Fix = "Check --backend/--backend-config or the CLDCTL_STATE_* environment variables"
+		return []doctorCheck{check}
+	}
+
+	listCtx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+
+	b := mgr.Backend()
+	if _, err := b.List(listCtx, "datacenters/"); err != nil {
+		check.Status = checkFail
+		check.Message = fmt.Sprintf("%s backend is not reachable: %v", b.Type(), err)
+		check.Fix = "Check credentials and network access for the state backend"
+		return []doctorCheck{check}
+	}
+
+	check.Status = checkPass
+	check.Message = fmt.Sprintf("%s backend is reachable", b.Type())
+
+	return []doctorCheck{check, checkVersionSkew(listCtx, mgr)}
+}
+
+func checkVersionSkew(ctx context.Context, mgr state.Manager) doctorCheck {
+	check := doctorCheck{Name: "version"}
+
+	meta, err := state.ReadMetadata(ctx, mgr.Backend())
+	if err != nil {
+		check.Status = checkWarn
+		check.Message = fmt.Sprintf("could not read state metadata: %v", err)
+		return check
+	}
+	if meta == nil {
+		check.Status = checkPass
+		check.Message = fmt.Sprintf("cldctl %s; state has not recorded a CLI version yet", Version)
+		return check
+	}
+
+	switch {
+	case meta.SchemaVersion > state.SchemaVersion:
+		check.Status = checkFail
+		check.Message = fmt.Sprintf("state uses schema v%d (written by cldctl %s) but this CLI (%s) supports v%d",
+			meta.SchemaVersion, meta.CLIVersion, Version, state.SchemaVersion)
+		check.Fix = fmt.Sprintf("Upgrade cldctl to %s or newer before deploying", meta.CLIVersion)
+	case selfupdate.CompareVersions(meta.CLIVersion, Version) > 0:
+		check.Status = checkWarn
+		check.Message = fmt.Sprintf("state was last written by cldctl %s; this CLI is %s", meta.CLIVersion, Version)
+		check.Fix = "Upgrade cldctl so everyone sharing this state runs the same version"
+	default:
+		check.Status = checkPass
+		check.Message = fmt.Sprintf("cldctl %s; state schema v%d (last written by %s)", Version, meta.SchemaVersion, meta.CLIVersion)
+	}
+	return check
+}
+
+func checkPlugins() []doctorCheck {
+	registerExternalPlugins()
+
+	var checks []doctorCheck
+	for _, pb := range pluginBinaries {
+		check := doctorCheck{Name: "plugin " + pb.plugin}
+		if _, err := iac.DefaultRegistry.Get(pb.plugin); err != nil {
+			check.Status = checkWarn
+			check.Message = fmt.Sprintf("%s not found; only needed by datacenters that use the %s plugin", pb.binary, pb.plugin)
+			check.Fix = "Install " + pb.binary + ": " + pb.install
+		} else {
+			check.Status = checkPass
+			check.Message = pb.binary + " found"
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+func printDoctorChecks(checks []doctorCheck) {
+	for _, c := range checks {
+		fmt.Printf("%s %-22s %s\n", checkSymbol(c.Status), c.Name, c.Message)
+		if c.Fix != "" && c.Status != checkPass {
+			fmt.Printf("  %-22s → %s\n", "", c.Fix)
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("%d passed, %d warnings, %d failed\n",
+		countChecks(checks, checkPass), countChecks(checks, checkWarn), countChecks(checks, checkFail))
+}
+
+func checkSymbol(status checkStatus) string {
+	switch status {
+	case checkPass:
+		return "✓"
+	case checkWarn:
+		return "!"
+	default:
+		return "✗"
+	}
+}
+
+func countChecks(checks []doctorCheck, status checkStatus) int {
+	n := 0
+	for _, c := range checks {
+		if c.Status == status {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package cli
+
+import "errors"
+
+// diskFreeBytes is not implemented on this platform.
+func diskFreeBytes(path string) (uint64, error) {
+	return 0, errors.New("free space check is not supported on this platform")
+}
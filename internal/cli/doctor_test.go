@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"context"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/state"
+	"github.com/davidthor/cldctl/pkg/state/backend/local"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func TestNewDoctorCmd_Flags(t *testing.T) {
+	cmd := newDoctorCmd()
+
+	if cmd.Use != "doctor" {
+		t.Errorf("expected use 'doctor', got '%s'", cmd.Use)
+	}
+
+	for _, name := range []string{"output", "registry", "backend", "backend-config"} {
+		if cmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected flag '%s' to be defined", name)
+		}
+	}
+}
+
+func TestRegistryHost(t *testing.T) {
+	tests := []struct {
+		repository string
+		want       string
+	}{
+		{"ghcr.io/myorg/app", "ghcr.io"},
+		{"localhost:5000/app", "localhost:5000"},
+		{"localhost/app", "localhost"},
+		{"myorg/app", "registry-1.docker.io"},
+		{"postgres", "registry-1.docker.io"},
+		{"<none>", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := registryHost(tt.repository); got != tt.want {
+			t.Errorf("registryHost(%q) = %q, want %q", tt.repository, got, tt.want)
+		}
+	}
+}
+
+func TestCheckVersionSkew(t *testing.T) {
+	ctx := context.Background()
+
+	origVersion := Version
+	Version = "v1.2.0"
+	defer func() { Version = origVersion }()
+
+	newManager := func(t *testing.T, meta *types.StateMetadata) state.Manager {
+		t.Helper()
+		b, err := local.NewBackend(map[string]string{"path": t.TempDir()})
+		if err != nil {
+			t.Fatalf("failed to create backend: %v", err)
+		}
+		mgr := state.NewManager(b)
+		if meta != nil {
+			origWriter := state.WriterVersion
+			state.WriterVersion = meta.CLIVersion
+			defer func() { state.WriterVersion = origWriter }()
+			if err := state.WriteMetadata(ctx, b); err != nil {
+				t.Fatalf("failed to write metadata: %v", err)
+			}
+		}
+		return mgr
+	}
+
+	if got := checkVersionSkew(ctx, newManager(t, nil)); got.Status != checkPass {
+		t.Errorf("expected pass without metadata, got %s: %s", got.Status, got.Message)
+	}
+
+	same := checkVersionSkew(ctx, newManager(t, &types.StateMetadata{CLIVersion: "v1.2.0"}))
+	if same.Status != checkPass {
+		t.Errorf("expected pass for matching versions, got %s: %s", same.Status, same.Message)
+	}
+
+	newer := checkVersionSkew(ctx, newManager(t, &types.StateMetadata{CLIVersion: "v1.5.0"}))
+	if newer.Status != checkWarn || newer.Fix == "" {
+		t.Errorf("expected warning with fix for newer writer, got %+v", newer)
+	}
+}
+
+func TestCountChecks(t *testing.T) {
+	checks := []doctorCheck{
+		{Name: "a", Status: checkPass},
+		{Name: "b", Status: checkWarn},
+		{Name: "c", Status: checkFail},
+		{Name: "d", Status: checkPass},
+	}
+
+	if n := countChecks(checks, checkPass); n != 2 {
+		t.Errorf("expected 2 passed, got %d", n)
+	}
+	if n := countChecks(checks, checkFail); n != 1 {
+		t.Errorf("expected 1 failed, got %d", n)
+	}
+}
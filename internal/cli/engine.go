@@ -3,21 +3,36 @@ package cli
 import (
 	"github.com/davidthor/cldctl/pkg/engine"
 	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/iac/external"
 	"github.com/davidthor/cldctl/pkg/state"
 
 	// Import IaC plugins to trigger registration via init() functions
+	_ "github.com/davidthor/cldctl/pkg/iac/ansible"
 	_ "github.com/davidthor/cldctl/pkg/iac/container"
+	_ "github.com/davidthor/cldctl/pkg/iac/crossplane"
 	_ "github.com/davidthor/cldctl/pkg/iac/native"
 	_ "github.com/davidthor/cldctl/pkg/iac/opentofu"
 	_ "github.com/davidthor/cldctl/pkg/iac/pulumi"
 )
 
 // createEngine creates a new deployment engine with the given state manager.
-// The IaC plugins are automatically registered via init() functions from the
-// blank imports above.
+// The built-in IaC plugins are automatically registered via init() functions
+// from the blank imports above; external plugin binaries found in the plugin
+// directory (~/.cldctl/plugins or $CLDCTL_PLUGIN_DIR) are registered on top.
 func createEngine(stateManager state.Manager) *engine.Engine {
+	registerExternalPlugins()
 	return engine.NewEngine(stateManager, iac.DefaultRegistry)
 }
 
+// registerExternalPlugins registers out-of-process IaC plugins. Discovery
+// failures are non-fatal: built-in plugins remain available.
+func registerExternalPlugins() {
+	dir, err := external.DefaultDir()
+	if err != nil {
+		return
+	}
+	_, _ = external.RegisterDir(iac.DefaultRegistry, dir)
+}
+
 // defaultParallelism is the default number of parallel operations for deployments.
 const defaultParallelism = 10
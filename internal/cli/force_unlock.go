@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/engine"
+	"github.com/davidthor/cldctl/pkg/state"
+	"github.com/davidthor/cldctl/pkg/state/backend"
+	"github.com/spf13/cobra"
+)
+
+func newForceUnlockCmd() *cobra.Command {
+	var (
+		datacenter    string
+		autoApprove   bool
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "force-unlock <environment>",
+		Short: "Break the state lock on an environment",
+		Long: `Break the state lock on an environment, so that it can be deployed again
+after the process holding the lock died without releasing it.
+
+Deploys lock the environment they change, so that two deploys can't
+interleave their writes to its state. Only break a lock when you're certain
+the process holding it is no longer running: breaking the lock of a running
+deploy lets another deploy corrupt the environment's state.
+
+Examples:
+  cldctl force-unlock staging
+  cldctl force-unlock staging -d my-dc --auto-approve`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envName := args[0]
+
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			// Confirm unless --auto-approve is provided
+			if !autoApprove {
+				fmt.Printf("Break the lock on environment %q? Only do this if the process holding it is no longer running. [y/N]: ", envName)
+				var response string
+				_, _ = fmt.Scanln(&response)
+				response = strings.ToLower(strings.TrimSpace(response))
+				if response != "y" && response != "yes" {
+					fmt.Println("Unlock cancelled.")
+					return nil
+				}
+			}
+
+			info, err := mgr.ForceUnlock(context.Background(), state.LockScope{
+				Datacenter:  dc,
+				Environment: envName,
+			})
+			if errors.Is(err, backend.ErrNotFound) {
+				return fmt.Errorf("environment %q is not locked", envName)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to unlock environment %q: %w", envName, err)
+			}
+
+			fmt.Printf("[success] Broke the lock held by %s on environment %q\n", engine.DescribeLock(*info), envName)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
+	cmd.Flags().BoolVar(&autoApprove, "auto-approve", false, "Skip confirmation prompt")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"strings"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -94,10 +93,8 @@ Examples:
 					for key, value := range comp.Variables {
 						// Mask sensitive values
 						displayValue := value
-						if strings.Contains(strings.ToLower(key), "secret") ||
-							strings.Contains(strings.ToLower(key), "password") ||
-							strings.Contains(strings.ToLower(key), "key") {
-							displayValue = "<sensitive>"
+						if isSensitiveVariableName(key) {
+							displayValue = sensitiveMask
 						}
 						fmt.Printf("  %-16s = %q\n", key, displayValue)
 					}
@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/engine"
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/graph/query"
+	"github.com/davidthor/cldctl/pkg/schema/component"
+	"github.com/spf13/cobra"
+)
+
+func newGraphCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Inspect dependency graphs",
+		Long:  `Commands for inspecting the dependency graphs of environments and components.`,
+	}
+
+	cmd.AddCommand(newGraphQueryCmd())
+
+	return cmd
+}
+
+func newGraphQueryCmd() *cobra.Command {
+	var (
+		datacenter    string
+		expectNone    bool
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "query <environment|component> <query>",
+		Short: "Select nodes or edges of a dependency graph",
+		Long: `Select the nodes or edges of a dependency graph that match a query, and
+print them as JSON, so CI can assert invariants of an environment or a
+component.
+
+The first argument is a deployed environment, whose graph is rebuilt from
+the sources its components were last deployed from, or a component path or
+image. Paths must start with ".", "/" or "~".
+
+A query selects nodes or edges matching all of its comma-separated
+predicates:
+
+  nodes(type=database, inputs.type=~"^postgres")
+  edges(from.type=deployment, to.component!=api)
+
+Node fields are id, type, name, component, instance, depends_on,
+dependents and inputs.<path>. An edge runs from a node to a node it depends
+on, and its fields are those of either end, as from.<field> and to.<field>.
+Operators are = and != for equality, and =~ and !~ for regular expressions.
+Values are bare words or double-quoted strings. A list field matches if any
+of its elements does; a missing field matches only != and !~.
+
+Exit codes:
+  0  The query matched something (with --expect-none, nothing)
+  1  It didn't, or the graph couldn't be built
+  2  The query is invalid
+
+Examples:
+  cldctl graph query ./my-app 'nodes(type=database, inputs.type=~"postgres")'
+  cldctl graph query staging 'nodes(type=route, inputs.internal=false)' --expect-none
+  cldctl graph query ghcr.io/myorg/app:v1 'edges(from.type=deployment, to.type=database)'`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			target := args[0]
+			ctx := context.Background()
+
+			q, err := query.Parse(args[1])
+			if err != nil {
+				return &exitError{code: 2, err: err}
+			}
+
+			var g *graph.Graph
+			if isComponentRef(target) {
+				g, err = componentGraph(ctx, target)
+			} else {
+				g, err = environmentGraph(ctx, target, datacenter, backendType, backendConfig)
+			}
+			if err != nil {
+				return err
+			}
+
+			result := q.Run(g)
+			data, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal JSON: %w", err)
+			}
+			fmt.Println(string(data))
+
+			switch {
+			case expectNone && result.Count > 0:
+				return fmt.Errorf("expected no %s to match %s, found %d", result.Kind, q, result.Count)
+			case !expectNone && result.Count == 0:
+				return fmt.Errorf("expected %s to match %s, found none", result.Kind, q)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Datacenter the environment belongs to (uses default if not set)")
+	cmd.Flags().BoolVar(&expectNone, "expect-none", false, "Fail if the query matches anything, instead of if it matches nothing")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+// isComponentRef reports whether a graph query target is a component path or
+// image rather than an environment name.
+func isComponentRef(ref string) bool {
+	return isLocalRef(ref) || strings.ContainsAny(ref, "/:")
+}
+
+// componentGraph builds the graph of a single component, without a
+// datacenter's implicit resources.
+func componentGraph(ctx context.Context, ref string) (*graph.Graph, error) {
+	var compFile string
+	var err error
+	if isLocalRef(ref) {
+		compFile, err = resolveComponentFile(ref)
+	} else {
+		// Pull progress goes to stderr so that JSON output stays clean
+		compFile, err = ensureComponentImage(ctx, ref, os.Stderr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve component %q: %w", ref, err)
+	}
+
+	comp, err := component.NewLoader().Load(compFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load component %s: %w", ref, err)
+	}
+	name := deriveComponentName(ref, isLocalRef(ref))
+	return engine.BuildGraph("query", "", nil, map[string]component.Component{name: comp})
+}
+
+// environmentGraph rebuilds the graph of a deployed environment.
+func environmentGraph(ctx context.Context, envName, datacenter, backendType string, backendConfig []string) (*graph.Graph, error) {
+	dc, err := resolveDatacenter(datacenter)
+	if err != nil {
+		return nil, err
+	}
+
+	mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create state manager: %w", err)
+	}
+
+	return createEngine(mgr).EnvironmentGraph(ctx, dc, envName)
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGraphQueryCmd(t *testing.T) {
+	compDir := createTempComponent(t, `
+databases:
+  main:
+    type: postgres:^16
+deployments:
+  api:
+    image: api:latest
+services:
+  api:
+    deployment: api
+    port: 8080
+routes:
+  main:
+    type: http
+    service: api
+`)
+
+	run := func(args ...string) error {
+		cmd := newGraphQueryCmd()
+		cmd.SetArgs(append([]string{compDir}, args...))
+		return cmd.Execute()
+	}
+
+	if err := run(`nodes(type=database, inputs.type=~"postgres")`); err != nil {
+		t.Errorf("expected the database to match, got %v", err)
+	}
+	if err := run(`nodes(type=database, inputs.type=~"redis")`); err == nil || ExitCode(err) != 1 {
+		t.Errorf("expected exit code 1 without a match, got %v", err)
+	}
+	if err := run(`nodes(type=bucket)`, "--expect-none"); err != nil {
+		t.Errorf("expected --expect-none to pass without a match, got %v", err)
+	}
+	err := run(`nodes(type=route, inputs.internal=false)`, "--expect-none")
+	if err == nil || !strings.Contains(err.Error(), "found 1") || ExitCode(err) != 1 {
+		t.Errorf("expected --expect-none to fail on the public route, got %v", err)
+	}
+	if err := run(`nodes(color=red)`); err == nil || ExitCode(err) != 2 {
+		t.Errorf("expected exit code 2 for an invalid query, got %v", err)
+	}
+}
+
+func TestIsComponentRef(t *testing.T) {
+	for ref, want := range map[string]bool{
+		"staging":              false,
+		"preview-123":          false,
+		"./my-app":             true,
+		"/srv/my-app":          true,
+		"ghcr.io/myorg/app:v1": true,
+		"app:v1":               true,
+	} {
+		if got := isComponentRef(ref); got != want {
+			t.Errorf("isComponentRef(%q) = %v, want %v", ref, got, want)
+		}
+	}
+}
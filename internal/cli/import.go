@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -40,6 +42,7 @@ resource state in cldctl's state backend.`,
 	cmd.AddCommand(newImportComponentCmd())
 	cmd.AddCommand(newImportEnvironmentCmd())
 	cmd.AddCommand(newImportDatacenterCmd())
+	cmd.AddCommand(newImportModuleCmd())
 
 	return cmd
 }
@@ -609,3 +612,129 @@ Examples:
 
 	return cmd
 }
+
+func newImportModuleCmd() *cobra.Command {
+	var (
+		pluginName    string
+		stateFile     string
+		source        string
+		autoApprove   bool
+		force         bool
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "module <datacenter-name> <module-name>",
+		Short: "Adopt an existing module's state as a datacenter root module",
+		Long: `Adopt infrastructure that was applied outside cldctl as a datacenter root
+module by registering its existing IaC state. The module's outputs are read
+from the state, so hooks can reference them (module.<name>.<output>) without
+re-creating the infrastructure.
+
+If the datacenter declares a root module with the same name, it is adopted
+with that module's plugin, source, and inputs. Otherwise --plugin is required
+and the module is left alone by datacenter deploys. Pass --source with the
+module's code so 'cldctl destroy datacenter' can destroy it; without it, the
+module is only removed from state.
+
+Examples:
+  cldctl import module prod-dc vpc --plugin tofu --state ./vpc.tfstate
+  cldctl import module prod-dc vpc --plugin tofu --state ./vpc.tfstate \
+    --source ./infra/vpc`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			dcName := args[0]
+			moduleName := args[1]
+			ctx := context.Background()
+
+			state, err := os.ReadFile(stateFile)
+			if err != nil {
+				return fmt.Errorf("failed to read state file: %w", err)
+			}
+
+			// Record local module sources as absolute paths so destroy can
+			// find them from any directory
+			if source != "" {
+				if _, err := os.Stat(source); err == nil {
+					if abs, err := filepath.Abs(source); err == nil {
+						source = abs
+					}
+				}
+			}
+
+			// Create state manager and engine
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+			eng := createEngine(mgr)
+
+			// Show preview
+			fmt.Printf("Adopt plan for datacenter module:\n\n")
+			fmt.Printf("  Datacenter: %s\n", dcName)
+			fmt.Printf("  Module:     %s\n", moduleName)
+			if pluginName != "" {
+				fmt.Printf("  Plugin:     %s\n", pluginName)
+			}
+			fmt.Printf("  State:      %s\n", stateFile)
+			fmt.Println()
+
+			// Confirm
+			if !autoApprove && isInteractive() {
+				fmt.Print("Proceed with import? [y/N]: ")
+				var response string
+				_, _ = fmt.Scanln(&response)
+				response = strings.ToLower(strings.TrimSpace(response))
+				if response != "y" && response != "yes" {
+					fmt.Println("Import cancelled.")
+					return nil
+				}
+				fmt.Println()
+			}
+
+			result, err := eng.AdoptDatacenterModule(ctx, engine.AdoptDatacenterModuleOptions{
+				Datacenter: dcName,
+				Module:     moduleName,
+				Plugin:     pluginName,
+				Source:     source,
+				State:      state,
+				Output:     os.Stdout,
+				Force:      force,
+			})
+			if err != nil {
+				return fmt.Errorf("import failed: %w", err)
+			}
+
+			fmt.Println()
+			fmt.Printf("[success] Adopted module %q into datacenter %q\n", moduleName, dcName)
+			if len(result.Outputs) > 0 {
+				names := make([]string, 0, len(result.Outputs))
+				for name := range result.Outputs {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				fmt.Printf("\n  Outputs available to hooks:\n")
+				for _, name := range names {
+					fmt.Printf("    module.%s.%s\n", moduleName, name)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&pluginName, "plugin", "", "IaC plugin that manages the state (e.g., opentofu, tofu)")
+	cmd.Flags().StringVar(&stateFile, "state", "", "Path to the module's state file (required)")
+	cmd.Flags().StringVar(&source, "source", "", "Module source, used to destroy it (OCI reference or local path)")
+	cmd.Flags().BoolVar(&autoApprove, "auto-approve", false, "Skip confirmation prompt")
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite existing module state")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	_ = cmd.MarkFlagRequired("state")
+
+	return cmd
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/davidthor/cldctl/pkg/graph"
 	"github.com/davidthor/cldctl/pkg/resolver"
 	"github.com/davidthor/cldctl/pkg/schema/component"
+	"github.com/davidthor/cldctl/pkg/state"
 	"github.com/davidthor/cldctl/pkg/state/types"
 	"github.com/spf13/cobra"
 )
@@ -19,6 +20,8 @@ func newInspectCmd() *cobra.Command {
 	var (
 		datacenter    string
 		outputFormat  string
+		explain       string
+		showSecrets   bool
 		backendType   string
 		backendConfig []string
 	)
@@ -41,6 +44,15 @@ in the path:
 Resources can be qualified with type if the name is ambiguous:
   cldctl inspect staging/my-app/deployment/api
 
+To see where a resource's inputs come from, pass it to --explain as
+<component>/<resource>. Each expression in its inputs is listed with the
+resource, variable, or dependency it reads, and that resource's status:
+  cldctl inspect staging --explain my-app/api
+
+Outputs the datacenter's modules mark sensitive, such as database
+passwords, are shown as "(sensitive)", as are copies of them in resource
+inputs. Pass --show-secrets to show their values.
+
 To visualize a component's topology instead, use:
   cldctl inspect component ./my-app
 
@@ -60,6 +72,12 @@ Examples:
   # Disambiguate resources with the same name across types
   cldctl inspect staging/my-app/deployment/api
 
+  # Show which resources a deployment's environment variables come from
+  cldctl inspect staging --explain my-app/deployment/api
+
+  # Show a database's password
+  cldctl inspect staging/my-app/database/main --show-secrets
+
   # Output as JSON or YAML
   cldctl inspect staging/my-app/api -o json`,
 		Args:         cobra.MaximumNArgs(1),
@@ -96,6 +114,29 @@ Examples:
 				return fmt.Errorf("environment %q not found in datacenter %q: %w", envName, dc, err)
 			}
 
+			if explain != "" {
+				if len(parts) != 1 {
+					return fmt.Errorf("--explain takes the resource to explain; pass only the environment as the argument")
+				}
+				compName, resourceParts, err := resolveInspectPath(strings.Split(strings.Trim(explain, "/"), "/"), env.Components, envName)
+				if err != nil {
+					return err
+				}
+				res, err := findInspectResource(env.Components[compName], resourceParts, explain)
+				if err != nil {
+					return err
+				}
+				provenance, err := createEngine(mgr).ExplainResource(ctx, dc, envName, compName, res)
+				if err != nil {
+					return err
+				}
+				return inspectProvenance(res, provenance, envName, outputFormat)
+			}
+
+			if !showSecrets {
+				state.RedactEnvironment(env)
+			}
+
 			if len(parts) == 1 {
 				// Environment only
 				return inspectEnvironmentState(env, dc, outputFormat)
@@ -110,36 +151,23 @@ Examples:
 			}
 
 			comp := env.Components[compName]
-
-			switch len(resourceParts) {
-			case 0:
+			if len(resourceParts) == 0 {
 				// Component view
 				return inspectComponentState(comp, dc, envName, outputFormat)
+			}
 
-			case 1:
-				// Resource by name
-				res, err := findResource(comp.Resources, resourceParts[0], "")
-				if err != nil {
-					return err
-				}
-				return inspectResourceState(res, dc, envName, outputFormat)
-
-			case 2:
-				// Resource by type/name
-				res, err := findResource(comp.Resources, resourceParts[1], resourceParts[0])
-				if err != nil {
-					return err
-				}
-				return inspectResourceState(res, dc, envName, outputFormat)
-
-			default:
-				return fmt.Errorf("invalid path %q: too many segments after component name", args[0])
+			res, err := findInspectResource(comp, resourceParts, args[0])
+			if err != nil {
+				return err
 			}
+			return inspectResourceState(res, dc, envName, outputFormat)
 		},
 	}
 
 	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
 	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, json, yaml")
+	cmd.Flags().StringVar(&explain, "explain", "", "Show where a resource's inputs come from (<component>/<resource>)")
+	cmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Show the values of sensitive outputs")
 	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
 	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
 
@@ -276,6 +304,23 @@ func resolveInspectPath(remaining []string, components map[string]*types.Compone
 		strings.Join(remaining, "/"), envName, strings.Join(available, "\n  "))
 }
 
+// findInspectResource finds the resource the path segments after a
+// component name refer to: a name, or a type and a name.
+func findInspectResource(comp *types.ComponentState, resourceParts []string, path string) (*types.ResourceState, error) {
+	switch len(resourceParts) {
+	case 0:
+		return nil, fmt.Errorf("invalid path %q: expected a resource after the component name", path)
+	case 1:
+		// Resource by name
+		return findResource(comp.Resources, resourceParts[0], "")
+	case 2:
+		// Resource by type/name
+		return findResource(comp.Resources, resourceParts[1], resourceParts[0])
+	default:
+		return nil, fmt.Errorf("invalid path %q: too many segments after component name", path)
+	}
+}
+
 // formatResolveError formats resolution errors to show validation details
 func formatResolveError(err error) error {
 	return formatErrorWithDetails(err, "failed to resolve component")
@@ -476,6 +521,7 @@ func printNodesByType(nodesByType map[graph.NodeType][]*graph.Node) {
 		graph.NodeTypeBucket,
 		graph.NodeTypeEncryptionKey,
 		graph.NodeTypeSMTP,
+		graph.NodeTypeExternal,
 		graph.NodeTypeDockerBuild,
 		graph.NodeTypeDeployment,
 		graph.NodeTypeFunction,
@@ -490,6 +536,7 @@ func printNodesByType(nodesByType map[graph.NodeType][]*graph.Node) {
 		graph.NodeTypeBucket:        "[S3]",
 		graph.NodeTypeEncryptionKey: "[EK]",
 		graph.NodeTypeSMTP:          "[SM]",
+		graph.NodeTypeExternal:      "[EX]",
 		graph.NodeTypeDockerBuild:   "[BL]",
 		graph.NodeTypeDeployment:    "[DP]",
 		graph.NodeTypeFunction:      "[FN]",
@@ -505,6 +552,7 @@ func printNodesByType(nodesByType map[graph.NodeType][]*graph.Node) {
 		graph.NodeTypeBucket:        "Buckets",
 		graph.NodeTypeEncryptionKey: "Encryption Keys",
 		graph.NodeTypeSMTP:          "SMTP",
+		graph.NodeTypeExternal:      "External Services",
 		graph.NodeTypeDockerBuild:   "Docker Builds",
 		graph.NodeTypeDeployment:    "Deployments",
 		graph.NodeTypeFunction:      "Functions",
@@ -722,6 +770,7 @@ func formatNodeID(node *graph.Node) string {
 		graph.NodeTypeBucket:        "[S3]",
 		graph.NodeTypeEncryptionKey: "[EK]",
 		graph.NodeTypeSMTP:          "[SM]",
+		graph.NodeTypeExternal:      "[EX]",
 		graph.NodeTypeDockerBuild:   "[BL]",
 		graph.NodeTypeDeployment:    "[DP]",
 		graph.NodeTypeFunction:      "[FN]",
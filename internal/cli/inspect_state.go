@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/davidthor/cldctl/pkg/engine"
+	"github.com/davidthor/cldctl/pkg/engine/executor"
 	"github.com/davidthor/cldctl/pkg/state/types"
 	"gopkg.in/yaml.v3"
 )
@@ -32,6 +35,14 @@ func printEnvironmentStateTable(env *types.EnvironmentState, dc string) error {
 	if env.StatusReason != "" {
 		fmt.Printf("Reason:      %s\n", env.StatusReason)
 	}
+	if hb := env.Heartbeat; hb != nil {
+		if executor.HeartbeatStale(hb, time.Now()) {
+			fmt.Printf("Warning:     previous deploy appears to have crashed (no heartbeat from %s for %s)\n", hb.Owner, time.Since(hb.UpdatedAt).Round(time.Second))
+			fmt.Printf("             Re-deploy with --take-over to recover\n")
+		} else {
+			fmt.Printf("Deploy:      in progress by %s (started %s ago)\n", hb.Owner, time.Since(hb.StartedAt).Round(time.Second))
+		}
+	}
 
 	if len(env.Variables) > 0 {
 		fmt.Println()
@@ -149,6 +160,9 @@ func printComponentStateTable(comp *types.ComponentState, dc, envName string) er
 		fmt.Printf("  %-16s %-20s %-12s %s\n", "TYPE", "NAME", "STATUS", "DETAILS")
 		for _, e := range entries {
 			details := resourceSummary(e.res)
+			if e.res.Paused {
+				details = strings.TrimSpace("(paused) " + details)
+			}
 			fmt.Printf("  %-16s %-20s %-12s %s\n",
 				e.res.Type,
 				e.res.Name,
@@ -185,6 +199,15 @@ func printResourceStateTable(res *types.ResourceState, dc, envName string) error
 	if res.StatusReason != "" {
 		fmt.Printf("Reason:      %s\n", res.StatusReason)
 	}
+	if res.RootCause != "" {
+		fmt.Printf("Blocked by:  %s\n", res.RootCause)
+	}
+	if res.Paused {
+		fmt.Printf("Paused:      deploys leave this resource unchanged\n")
+		if res.PauseReason != "" {
+			fmt.Printf("             %s\n", res.PauseReason)
+		}
+	}
 
 	if res.Hook != "" {
 		fmt.Printf("Hook:        %s\n", res.Hook)
@@ -195,6 +218,9 @@ func printResourceStateTable(res *types.ResourceState, dc, envName string) error
 
 	fmt.Printf("Created:     %s\n", res.CreatedAt.Format("2006-01-02 15:04:05"))
 	fmt.Printf("Updated:     %s\n", res.UpdatedAt.Format("2006-01-02 15:04:05"))
+	if t := res.Timing; t != nil {
+		fmt.Printf("Last apply:  %s took %s (%s)\n", t.Action, formatStatSeconds(float64(t.DurationMS)/1000), t.StartedAt.Format("2006-01-02 15:04:05"))
+	}
 
 	// Separate environment variables from other inputs
 	envVars := extractEnvVars(res.Inputs)
@@ -235,6 +261,57 @@ func printResourceStateTable(res *types.ResourceState, dc, envName string) error
 	return nil
 }
 
+// inspectProvenance displays where a resource's inputs come from.
+func inspectProvenance(res *types.ResourceState, provenance []engine.InputProvenance, envName, outputFormat string) error {
+	switch outputFormat {
+	case "json":
+		return marshalJSON(provenance)
+	case "yaml":
+		return marshalYAML(provenance)
+	default:
+		return printProvenanceTable(res, provenance, envName)
+	}
+}
+
+func printProvenanceTable(res *types.ResourceState, provenance []engine.InputProvenance, envName string) error {
+	fmt.Printf("Resource:    %s/%s\n", res.Type, res.Name)
+	fmt.Printf("Component:   %s\n", res.Component)
+	fmt.Printf("Environment: %s\n", envName)
+	fmt.Println()
+
+	if len(provenance) == 0 {
+		fmt.Println("No inputs are read from expressions.")
+		fmt.Println()
+		return nil
+	}
+
+	fmt.Println("Inputs:")
+	fmt.Printf("  %-32s %-32s %-40s %s\n", "INPUT", "REFERENCE", "SOURCE", "STATUS")
+	for _, p := range provenance {
+		status := string(p.Status)
+		if p.Resource != "" && status == "" {
+			status = "not deployed"
+		}
+		fmt.Printf("  %-32s %-32s %-40s %s\n", p.Input, p.Reference, provenanceSource(p), status)
+	}
+	fmt.Println()
+	return nil
+}
+
+// provenanceSource describes what an input's reference reads.
+func provenanceSource(p engine.InputProvenance) string {
+	switch {
+	case p.Resource != "":
+		return p.Component + "/" + p.Resource
+	case p.Component != "":
+		return "component " + p.Component
+	case strings.HasPrefix(p.Reference, "variables."):
+		return "variable " + strings.TrimPrefix(p.Reference, "variables.")
+	default:
+		return "-"
+	}
+}
+
 // findResource locates a resource within a component's resource map.
 // If resourceType is empty, it matches by name only. If resourceType is set,
 // it matches by both type and name.
@@ -302,7 +379,10 @@ func findResource(resources map[string]*types.ResourceState, name, resourceType
 // resourceSummary returns a brief detail string for a resource (used in component table view).
 func resourceSummary(res *types.ResourceState) string {
 	// For failed resources, show the failure reason
-	if res.Status == types.ResourceStatusFailed && res.StatusReason != "" {
+	if (res.Status == types.ResourceStatusFailed || res.Status == types.ResourceStatusUnknown) && res.StatusReason != "" {
+		if res.RootCause != "" {
+			return fmt.Sprintf("%s (%s)", res.StatusReason, res.RootCause)
+		}
 		return res.StatusReason
 	}
 
@@ -3,6 +3,7 @@ package cli
 import (
 	"testing"
 
+	"github.com/davidthor/cldctl/pkg/engine"
 	"github.com/davidthor/cldctl/pkg/graph"
 	"github.com/davidthor/cldctl/pkg/resolver"
 	"github.com/davidthor/cldctl/pkg/state/types"
@@ -27,6 +28,7 @@ func TestNewInspectCmd(t *testing.T) {
 	// Should have state-related flags
 	assert.NotNil(t, cmd.Flags().Lookup("datacenter"))
 	assert.NotNil(t, cmd.Flags().Lookup("output"))
+	assert.NotNil(t, cmd.Flags().Lookup("explain"))
 	assert.NotNil(t, cmd.Flags().Lookup("backend"))
 	assert.NotNil(t, cmd.Flags().Lookup("backend-config"))
 
@@ -323,6 +325,41 @@ func TestExtractComponentName(t *testing.T) {
 	}
 }
 
+func TestProvenanceSource(t *testing.T) {
+	tests := []struct {
+		name string
+		p    engine.InputProvenance
+		want string
+	}{
+		{
+			name: "resource",
+			p:    engine.InputProvenance{Reference: "databases.main.url", Component: "my-app", Resource: "databaseUser/main--api"},
+			want: "my-app/databaseUser/main--api",
+		},
+		{
+			name: "dependency",
+			p:    engine.InputProvenance{Reference: "dependencies.auth.url", Component: "auth"},
+			want: "component auth",
+		},
+		{
+			name: "variable",
+			p:    engine.InputProvenance{Reference: "variables.log_level"},
+			want: "variable log_level",
+		},
+		{
+			name: "unresolved",
+			p:    engine.InputProvenance{Reference: "environment.name"},
+			want: "-",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, provenanceSource(tt.p))
+		})
+	}
+}
+
 func TestResolveInspectPath(t *testing.T) {
 	// Simulate components with slashes in their names
 	components := map[string]*types.ComponentState{
@@ -7,7 +7,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/davidthor/cldctl/pkg/labels"
 	"github.com/davidthor/cldctl/pkg/registry"
+	"github.com/davidthor/cldctl/pkg/state/types"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
@@ -32,6 +34,7 @@ func newListComponentCmd() *cobra.Command {
 		environment   string
 		datacenter    string
 		outputFormat  string
+		selector      string
 		backendType   string
 		backendConfig []string
 	)
@@ -46,10 +49,13 @@ Without the --environment flag, lists all locally built or pulled components
 (similar to 'docker images').
 
 With the --environment flag, lists all components deployed to that environment.
+Use -l to filter them by label selector; components inherit their
+environment's labels.
 
 Examples:
   cldctl list component                    # List local components
-  cldctl list component -e production      # List deployed components`,
+  cldctl list component -e production      # List deployed components
+  cldctl list component -e production -l team=payments`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
 
@@ -77,6 +83,18 @@ Examples:
 				return fmt.Errorf("failed to get environment: %w", err)
 			}
 
+			if selector != "" {
+				sel, err := labels.ParseSelector(selector)
+				if err != nil {
+					return err
+				}
+				for name, comp := range envState.Components {
+					if !sel.Matches(labels.Merge(envState.Labels, comp.Labels)) {
+						delete(envState.Components, name)
+					}
+				}
+			}
+
 			// Handle output format
 			switch outputFormat {
 			case "json":
@@ -121,6 +139,7 @@ Examples:
 	cmd.Flags().StringVarP(&environment, "environment", "e", "", "Target environment (lists deployed components)")
 	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
 	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, json, yaml")
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "Filter deployed components by label selector (e.g. team=payments)")
 	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
 	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
 
@@ -290,6 +309,7 @@ func newListEnvironmentCmd() *cobra.Command {
 	var (
 		datacenter    string
 		outputFormat  string
+		bulk          bulkFlags
 		backendType   string
 		backendConfig []string
 	)
@@ -303,10 +323,14 @@ func newListEnvironmentCmd() *cobra.Command {
 The datacenter is resolved from --datacenter/-d flag, CLDCTL_DATACENTER
 environment variable, or the default datacenter set in config.
 
+Filter with a label selector (-l team=payments) and/or --older-than to
+preview the environments a bulk deploy or destroy would select.
+
 Examples:
   cldctl list environment
   cldctl list environment -d my-datacenter
-  cldctl list environment -o json`,
+  cldctl list environment -o json
+  cldctl list environment -l team=payments --older-than 14d`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
 
@@ -328,6 +352,26 @@ Examples:
 				return fmt.Errorf("failed to list environments: %w", err)
 			}
 
+			sel, filtered, err := bulk.selection()
+			if err != nil {
+				return err
+			}
+			if filtered {
+				envs, err := selectEnvironments(ctx, mgr, dc, sel)
+				if err != nil {
+					return err
+				}
+				envRefs = envRefs[:0]
+				for _, env := range envs {
+					envRefs = append(envRefs, types.EnvironmentRef{
+						Name:       env.Name,
+						Datacenter: dc,
+						CreatedAt:  env.CreatedAt,
+						UpdatedAt:  env.UpdatedAt,
+					})
+				}
+			}
+
 			// Handle output format
 			switch outputFormat {
 			case "json":
@@ -350,16 +394,19 @@ Examples:
 				}
 
 				fmt.Printf("Datacenter: %s\n\n", dc)
-				fmt.Printf("%-16s %-12s %s\n", "NAME", "COMPONENTS", "CREATED")
+				fmt.Printf("%-16s %-14s %-12s %s\n", "NAME", "STATUS", "COMPONENTS", "CREATED")
 				for _, ref := range envRefs {
-					// Get full environment state for component count
+					// Get full environment state for status and component count
 					env, err := mgr.GetEnvironment(ctx, dc, ref.Name)
+					status := "unknown"
 					componentCount := 0
 					if err == nil {
+						status = string(env.Status)
 						componentCount = len(env.Components)
 					}
-					fmt.Printf("%-16s %-12d %s\n",
+					fmt.Printf("%-16s %-14s %-12d %s\n",
 						ref.Name,
+						status,
 						componentCount,
 						ref.CreatedAt.Format("2006-01-02"),
 					)
@@ -372,6 +419,7 @@ Examples:
 
 	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
 	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, json, yaml")
+	addBulkFlags(cmd, &bulk, true)
 	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
 	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
 
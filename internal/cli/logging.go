@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/davidthor/cldctl/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+// Logging flags, shared by every command.
+var (
+	verbosity       int
+	logLevel        string
+	debugSubsystems []string
+	logFile         string
+)
+
+func addLoggingFlags(cmd *cobra.Command) {
+	flags := cmd.PersistentFlags()
+	flags.CountVarP(&verbosity, "verbose", "v", "Log more detail (-v for info, -vv for debug)")
+	flags.StringVar(&logLevel, "log-level", "", "Lowest level to log: debug, info, warn, or error (overrides -v)")
+	flags.StringSliceVar(&debugSubsystems, "debug", nil, "Log these subsystems at debug level (all, build, engine, executor, expressions, plugins)")
+	flags.Lookup("debug").NoOptDefVal = logging.All
+	flags.StringVar(&logFile, "log-file", "", "Write logs to this file instead of stderr")
+}
+
+// configureLogging applies the logging flags. CLDCTL_DEBUG is still
+// honored, as --debug=all, when --debug isn't set.
+func configureLogging(cmd *cobra.Command) error {
+	opts := logging.Options{Debug: debugSubsystems}
+
+	switch {
+	case logLevel != "":
+		level, err := logging.ParseLevel(logLevel)
+		if err != nil {
+			return err
+		}
+		opts.Level = &level
+	case verbosity > 0:
+		level := slog.LevelInfo
+		if verbosity > 1 {
+			level = slog.LevelDebug
+		}
+		opts.Level = &level
+	}
+
+	if !cmd.Flags().Changed("debug") && os.Getenv("CLDCTL_DEBUG") != "" {
+		opts.Debug = []string{logging.All}
+	}
+
+	if logFile != "" {
+		f, err := os.OpenFile(expandHome(logFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		opts.Output = f
+	}
+
+	return logging.Configure(opts)
+}
@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"path"
 
+	componentv2 "github.com/davidthor/cldctl/pkg/schema/component/v2"
 	"github.com/davidthor/cldctl/pkg/state/backend"
 	"github.com/davidthor/cldctl/pkg/state/types"
 	"github.com/spf13/cobra"
@@ -21,6 +24,7 @@ func newMigrateCmd() *cobra.Command {
 	}
 
 	cmd.AddCommand(newMigrateStateCmd())
+	cmd.AddCommand(newMigrateComponentCmd())
 
 	return cmd
 }
@@ -218,6 +222,122 @@ then copies all files to the new path and removes the old files.`,
 	return cmd
 }
 
+func newMigrateComponentCmd() *cobra.Command {
+	var (
+		dryRun bool
+		backup bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "component [path...]",
+		Short: "Rewrite v1 component files as schema v2",
+		Long: `Rewrite v1 component files (cld.yml) using the v2 component schema.
+
+The migration edits the YAML in place, preserving comments and key order:
+  - version is set to v2
+  - deployments, functions, and cronjobs move under a workloads block
+  - every variable gets a type inferred from its default (string if none)
+  - the secret alias on variables is renamed to sensitive
+
+Each path may be a component file or a directory containing cld.yml or
+cld.yaml. With no arguments, the current directory is used. Files that already
+use v2 are left untouched.
+
+v1 files keep working without migration; they are upgraded in memory when
+loaded. If a component uses extends, migrate the base file as well.
+
+Examples:
+  cldctl migrate component
+  cldctl migrate component ./api ./worker/cld.yml
+  cldctl migrate component --dry-run`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = []string{"."}
+			}
+
+			var migrated, errCount int
+			for _, arg := range args {
+				path := arg
+				if info, err := os.Stat(arg); err == nil && info.IsDir() {
+					path = findComponentFile(arg)
+					if path == "" {
+						fmt.Printf("Error: no cld.yml or cld.yaml found in %s\n", arg)
+						errCount++
+						continue
+					}
+				}
+
+				changed, err := migrateComponentFile(path, dryRun, backup, os.Stdout)
+				if err != nil {
+					fmt.Printf("Error: %s: %v\n", path, err)
+					errCount++
+					continue
+				}
+				if !changed {
+					fmt.Printf("%s already uses schema v2\n", path)
+					continue
+				}
+				migrated++
+				if !dryRun {
+					fmt.Printf("Migrated %s to schema v2\n", path)
+				}
+			}
+
+			if errCount > 0 {
+				return fmt.Errorf("%d of %d components could not be migrated", errCount, len(args))
+			}
+			if dryRun && migrated > 0 {
+				fmt.Println("Dry run complete. No files were changed.")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the migrated files instead of writing them")
+	cmd.Flags().BoolVar(&backup, "backup", false, "Keep the original file with a .bak suffix")
+
+	return cmd
+}
+
+// migrateComponentFile rewrites a single component file as v2. It reports
+// false without error when the file already uses v2. In dry-run mode the
+// migrated document is written to out instead of the file.
+func migrateComponentFile(path string, dryRun, backup bool, out io.Writer) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	migrated, err := componentv2.Migrate(data)
+	if errors.Is(err, componentv2.ErrAlreadyV2) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if dryRun {
+		fmt.Fprintf(out, "# %s\n", path)
+		_, err := out.Write(migrated)
+		return true, err
+	}
+
+	if backup {
+		if err := os.WriteFile(path+".bak", data, info.Mode().Perm()); err != nil {
+			return false, fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, migrated, info.Mode().Perm()); err != nil {
+		return false, fmt.Errorf("failed to write file: %w", err)
+	}
+	return true, nil
+}
+
 // createBackend creates a raw backend from type and config flags.
 func createBackend(backendType string, backendConfigFlags []string) (backend.Backend, error) {
 	// Reuse the same config resolution logic as createStateManagerWithConfig
@@ -38,13 +38,14 @@ func (m *moduleBuilder) Build(ctx context.Context, sourceDir, plugin, tag string
 	switch plugin {
 	case "pulumi":
 		moduleType = container.ModuleTypePulumi
-	case "opentofu", "terraform":
+	case "opentofu", "terraform", "tofu":
 		moduleType = container.ModuleTypeOpenTofu
-	case "native":
-		// Native modules don't need containerization - they use Docker SDK directly
+	case "native", "crossplane", "ansible":
+		// Native, crossplane, and ansible modules don't need containerization -
+		// they run directly from the host against Docker, Kubernetes, or SSH
 		return &container.BuildResult{
 			Image:      tag,
-			ModuleType: "native",
+			ModuleType: container.ModuleType(plugin),
 		}, nil
 	default:
 		// Auto-detect from source
@@ -59,6 +60,17 @@ func (m *moduleBuilder) Build(ctx context.Context, sourceDir, plugin, tag string
 	})
 }
 
+// isLocalOnlyPlugin reports whether modules for the plugin are executed from
+// source on the host and therefore never pushed as container images.
+func isLocalOnlyPlugin(plugin string) bool {
+	switch plugin {
+	case "native", "crossplane", "ansible":
+		return true
+	default:
+		return false
+	}
+}
+
 // Push pushes a module container image to a remote registry using docker push.
 // This relies on the Docker CLI being authenticated (e.g., via docker login).
 func (m *moduleBuilder) Push(ctx context.Context, ref string) error {
@@ -120,6 +132,7 @@ func collectAllModules(dc datacenter.Datacenter, dcPath string) map[string]modul
 		collectHookModules(env.Hooks().DockerBuild(), modules, dcPath)
 		collectHookModules(env.Hooks().Observability(), modules, dcPath)
 		collectHookModules(env.Hooks().NetworkPolicy(), modules, dcPath)
+		collectHookModules(env.Hooks().RouteAuth(), modules, dcPath)
 	}
 
 	return modules
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/davidthor/cldctl/pkg/engine"
+	"github.com/davidthor/cldctl/pkg/schema/environment"
+	"github.com/davidthor/cldctl/pkg/state/types"
+	"github.com/spf13/cobra"
+)
+
+func newOutputCmd() *cobra.Command {
+	var (
+		datacenter    string
+		jsonOutput    bool
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "output <environment> [name]",
+		Short: "Show an environment's outputs",
+		Long: `Show the outputs declared in an environment file's outputs block, as
+resolved by the last deploy of the file.
+
+Without a name, all outputs are listed and sensitive values are masked. With
+a name, the raw value of that output is printed, so pipelines can capture it
+directly. --json prints values as JSON, including sensitive ones.
+
+Examples:
+  cldctl output preview-123
+  cldctl output preview-123 api_url
+  cldctl output preview-123 --json
+  API_URL=$(cldctl output preview-123 api_url)`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			envName := args[0]
+			ctx := context.Background()
+
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			env, err := mgr.GetEnvironment(ctx, dc, envName)
+			if err != nil {
+				return fmt.Errorf("environment %q not found in datacenter %q: %w", envName, dc, err)
+			}
+
+			if len(args) == 2 {
+				output, ok := env.Outputs[args[1]]
+				if !ok {
+					return fmt.Errorf("output %q not found in environment %q", args[1], envName)
+				}
+				if jsonOutput {
+					data, err := json.Marshal(output.Value)
+					if err != nil {
+						return fmt.Errorf("failed to marshal JSON: %w", err)
+					}
+					fmt.Println(string(data))
+					return nil
+				}
+				fmt.Println(formatOutputValue(output.Value))
+				return nil
+			}
+
+			if jsonOutput {
+				values := make(map[string]interface{}, len(env.Outputs))
+				for name, output := range env.Outputs {
+					values[name] = output.Value
+				}
+				data, err := json.MarshalIndent(values, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			if len(env.Outputs) == 0 {
+				fmt.Fprintf(os.Stderr, "Environment %q has no outputs. Declare them in the outputs block of its environment file.\n", envName)
+				return nil
+			}
+			printEnvironmentOutputs(os.Stdout, env.Outputs, "")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Datacenter the environment belongs to (uses default if not set)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print values as JSON, including sensitive values")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+// applyEnvironmentOutputs resolves an environment file's outputs after a
+// deploy and prints them. Outputs recorded by an earlier version of the file
+// are cleared when the file no longer declares any.
+func applyEnvironmentOutputs(ctx context.Context, eng *engine.Engine, dc string, env *types.EnvironmentState, outputs map[string]environment.Output) error {
+	if len(outputs) == 0 && len(env.Outputs) == 0 {
+		return nil
+	}
+
+	resolved, err := eng.ResolveEnvironmentOutputs(ctx, dc, env.Name, outputs)
+	if err != nil {
+		return fmt.Errorf("failed to resolve environment outputs: %w", err)
+	}
+	if len(resolved) > 0 {
+		fmt.Println()
+		fmt.Println("Outputs:")
+		printEnvironmentOutputs(os.Stdout, resolved, "  ")
+	}
+	return nil
+}
+
+// printEnvironmentOutputs prints outputs as sorted "name = value" lines,
+// masking sensitive values.
+func printEnvironmentOutputs(w io.Writer, outputs map[string]*types.EnvironmentOutput, indent string) {
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := "<sensitive>"
+		if !outputs[name].Sensitive {
+			value = formatOutputValue(outputs[name].Value)
+		}
+		fmt.Fprintf(w, "%s%s = %s\n", indent, name, value)
+	}
+}
+
+// formatOutputValue renders strings as-is and other values as JSON.
+func formatOutputValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
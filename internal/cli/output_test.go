@@ -0,0 +1,25 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func TestPrintEnvironmentOutputs(t *testing.T) {
+	var buf bytes.Buffer
+	printEnvironmentOutputs(&buf, map[string]*types.EnvironmentOutput{
+		"token":   {Value: "s3cret", Sensitive: true},
+		"api_url": {Value: "https://api.preview.example.com"},
+		"regions": {Value: []interface{}{"us-east-1", "eu-west-1"}},
+	}, "  ")
+
+	want := `  api_url = https://api.preview.example.com
+  regions = ["us-east-1","eu-west-1"]
+  token = <sensitive>
+`
+	if buf.String() != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
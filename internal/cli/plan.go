@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/davidthor/cldctl/pkg/engine"
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/graph/visual"
+	"github.com/spf13/cobra"
+)
+
+func newPlanCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Preview changes without applying them",
+		Long: `Commands for previewing what a deploy would change without applying it.
+
+Datacenter changes reconcile every environment in the datacenter, so review
+the plan before running 'cldctl deploy datacenter'.`,
+	}
+
+	cmd.AddCommand(newPlanDatacenterCmd())
+	cmd.AddCommand(newPlanEnvironmentCmd())
+
+	return cmd
+}
+
+func newPlanDatacenterCmd() *cobra.Command {
+	var (
+		variables     []string
+		varFile       string
+		refresh       bool
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "datacenter <name> <image>",
+		Aliases: []string{"dc"},
+		Short:   "Preview a datacenter upgrade",
+		Long: `Compare a datacenter image against the version currently deployed and
+report what deploying it would change:
+
+  - Root and environment modules to create, update, or delete
+  - Environment resources that would be provisioned by a different hook
+
+With --refresh, the infrastructure of each module is also read back and
+compared against its recorded state. Modules whose infrastructure drifted are
+planned to be updated, with the drifted resources listed. Only modules using
+the native, OpenTofu, or Pulumi plugins can be refreshed.
+
+Nothing is applied. Run 'cldctl deploy datacenter' with the same arguments to
+apply the changes.
+
+Arguments:
+  name    Name of the deployed datacenter
+  image   Datacenter image reference (e.g., my-dc:latest)
+
+Examples:
+  cldctl plan datacenter prod-dc ghcr.io/myorg/dc:v1.1.0
+  cldctl plan datacenter prod-dc ghcr.io/myorg/dc:v1.1.0 --var-file prod.vars
+  cldctl plan datacenter prod-dc ghcr.io/myorg/dc:v1.1.0 --refresh`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			dcName := args[0]
+			imageRef := args[1]
+			ctx := context.Background()
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			vars, err := loadDatacenterVariables(varFile, variables)
+			if err != nil {
+				return err
+			}
+
+			if err := ensureDatacenterImage(ctx, imageRef); err != nil {
+				return err
+			}
+
+			eng := createEngine(mgr)
+			dcPlan, err := eng.PlanDatacenter(ctx, dcName, imageRef, vars, refresh)
+			if err != nil {
+				return fmt.Errorf("failed to plan datacenter deployment: %w", err)
+			}
+
+			eng.PrintDatacenterPlanSummary(os.Stdout, dcPlan)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&variables, "var", nil, "Set variable (key=value)")
+	cmd.Flags().StringVar(&varFile, "var-file", "", "Load variables from file")
+	cmd.Flags().BoolVar(&refresh, "refresh", false, "Check modules for drift from their recorded state")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+func newPlanEnvironmentCmd() *cobra.Command {
+	var (
+		datacenter    string
+		refresh       bool
+		planJSON      string
+		planOut       string
+		outputFormat  string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "environment <name>",
+		Aliases: []string{"env"},
+		Short:   "Preview re-deploying an environment and check it for drift",
+		Long: `Plan re-deploying an environment's components from the sources and
+variables they were last deployed with, and report what would change.
+
+With --refresh, the infrastructure of each deployed resource is read back
+through its hook modules and compared against its recorded state. Resources
+whose infrastructure drifted are planned to be updated, with the drifted
+outputs and module resources listed. Drifted values aren't shown. Only
+modules using the native, OpenTofu, or Pulumi plugins can be refreshed.
+
+With --output waves, the changes are grouped into the waves they'd execute
+in: each wave only waits on earlier ones, and each change lists the changes
+it waits on. The critical path, the longest chain of changes that wait on
+each other, is estimated from how long each resource took to apply last
+time. Use it to predict how long a deploy takes and to spot changes that are
+serialized by expressions they don't need. --output mermaid renders the
+waves as a Mermaid flowchart.
+
+Nothing is applied or written to state. With --out, the plan is saved to a
+file that 'cldctl deploy --plan' executes later, for workflows that review a
+plan before applying it. Saved plans hold the components' variable values,
+so treat them as secrets.
+
+Examples:
+  cldctl plan environment staging --refresh
+  cldctl plan environment production -d prod-dc --refresh --plan-json drift.json
+  cldctl plan environment staging -o waves
+  cldctl plan environment staging -o mermaid > plan.mmd
+  cldctl plan environment production --out plan.cldplan`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envName := args[0]
+
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			var summary io.Writer
+			switch outputFormat {
+			case "summary":
+				summary = os.Stdout
+			case "waves", "mermaid":
+			default:
+				return fmt.Errorf("unknown output format %q (valid: summary, waves, mermaid)", outputFormat)
+			}
+
+			eng := createEngine(mgr)
+			planOpts := engine.PlanEnvironmentOptions{
+				Environment: envName,
+				Datacenter:  dc,
+				DetectDrift: refresh,
+				Output:      summary,
+			}
+			var plan *planner.Plan
+			var saved *engine.PlanFile
+			if planOut != "" {
+				plan, saved, err = eng.SavePlanEnvironment(context.Background(), planOpts)
+			} else {
+				plan, err = eng.PlanEnvironment(context.Background(), planOpts)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to plan environment: %w", err)
+			}
+
+			switch outputFormat {
+			case "waves":
+				fmt.Println()
+				plan.Waves().Print(os.Stdout)
+				printWarnings(os.Stdout, plan.Warnings)
+			case "mermaid":
+				fmt.Print(visual.RenderWavesMermaid(plan.Waves()))
+				printWarnings(os.Stderr, plan.Warnings)
+			default:
+				printWarnings(os.Stdout, plan.Warnings)
+			}
+
+			if planJSON != "" {
+				if err := writePlanJSON(planJSON, plan); err != nil {
+					return err
+				}
+			}
+			if saved != nil {
+				if err := engine.WritePlanFile(planOut, saved); err != nil {
+					return err
+				}
+				if outputFormat != "mermaid" {
+					fmt.Printf("Saved the plan to %s. Apply it with: cldctl deploy --plan %s\n", planOut, planOut)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
+	cmd.Flags().BoolVar(&refresh, "refresh", false, "Check deployed resources for drift from their recorded state")
+	cmd.Flags().StringVar(&planJSON, "plan-json", "", "Write the plan as JSON to this file")
+	cmd.Flags().StringVar(&planOut, "out", "", "Save the plan to this file for 'cldctl deploy --plan'")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "summary", "Output format: summary, waves, mermaid")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
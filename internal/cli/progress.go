@@ -9,6 +9,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/errors"
+	"github.com/davidthor/cldctl/pkg/logging"
 	"golang.org/x/term"
 )
 
@@ -45,6 +48,9 @@ type ResourceInfo struct {
 	EndTime      time.Time
 	Error        error
 	Message      string
+	// Phase is the stage the resource's plugin last reported while it was
+	// running (e.g. pulling, waiting-healthy).
+	Phase string
 	// MessageTime records when Message was last updated. Used to detect
 	// stale log lines and show a cleaner status for long-silent tasks.
 	MessageTime time.Time
@@ -52,6 +58,9 @@ type ResourceInfo struct {
 	InferredConfig map[string]string
 	// Logs stores captured output for debugging failures
 	Logs string
+	// LogFile is the path of the full captured output, when it was written
+	// to disk
+	LogFile string
 	// lastPrintedMsg tracks the last sub-status emitted in non-dynamic mode
 	// to avoid printing duplicate lines.
 	lastPrintedMsg string
@@ -88,9 +97,9 @@ type ProgressTable struct {
 
 // NewProgressTable creates a new progress table.
 // If the writer is a terminal, the table will render dynamically using ANSI
-// escape codes. Dynamic mode is disabled when debug mode is active
-// (CLDCTL_DEBUG) or a CI environment is detected, because interleaved debug
-// output or non-interactive terminals break the in-place redraw.
+// escape codes. Dynamic mode is disabled when info or debug logs are written
+// to stderr or a CI environment is detected, because interleaved log output
+// or non-interactive terminals break the in-place redraw.
 func NewProgressTable(w io.Writer) *ProgressTable {
 	dynamic := false
 	tw := 0
@@ -106,7 +115,7 @@ func NewProgressTable(w io.Writer) *ProgressTable {
 
 	// Disable dynamic rendering when debug output or CI would interfere.
 	if dynamic {
-		if os.Getenv("CLDCTL_DEBUG") != "" || os.Getenv("CI") != "" {
+		if logging.WritesToStderr() || os.Getenv("CI") != "" {
 			dynamic = false
 		}
 	}
@@ -201,6 +210,17 @@ func (p *ProgressTable) UpdateStatus(id string, status ResourceStatus, message s
 	}
 }
 
+// SetPhase records the phase a running resource is in. An empty phase
+// leaves the current one in place.
+func (p *ProgressTable) SetPhase(id string, phase string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if res, ok := p.resources[id]; ok && phase != "" {
+		res.Phase = phase
+	}
+}
+
 // SetError sets an error for a resource.
 func (p *ProgressTable) SetError(id string, err error) {
 	p.mu.Lock()
@@ -236,6 +256,16 @@ func (p *ProgressTable) SetLogs(id string, logs string) {
 	}
 }
 
+// SetLogFile stores the path of the full log for a resource.
+func (p *ProgressTable) SetLogFile(id string, path string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if res, ok := p.resources[id]; ok {
+		res.LogFile = path
+	}
+}
+
 // AppendLogs appends to the captured logs for a resource.
 func (p *ProgressTable) AppendLogs(id string, logs string) {
 	p.mu.Lock()
@@ -354,6 +384,9 @@ func (p *ProgressTable) PrintUpdate(id string) {
 				}
 			}
 			res.lastPrintedMsg = msg
+			if res.Phase != "" {
+				msg = "[" + res.Phase + "] " + msg
+			}
 			statusStr = fmt.Sprintf("  %s %s/%s %s", p.statusIcon(res.Status), res.Type, res.Name, msg)
 		} else {
 			if res.lastPrintedMsg == "" {
@@ -438,17 +471,21 @@ func (p *ProgressTable) PrintFinalSummary() {
 		}
 		fmt.Fprintf(p.writer, "  %s\n", strings.Join(parts, "  "))
 
-		// Separate root-cause failures from cascaded/stopped failures
+		// Separate root-cause failures from cascaded/stopped failures, and
+		// group the cascaded failures under the failure that blocked them
 		var rootFailures, cascadedFailures []*ResourceInfo
+		blocked := make(map[*ResourceInfo][]*ResourceInfo)
 		for _, id := range p.order {
 			res := p.resources[id]
 			if res.Status != StatusFailed {
 				continue
 			}
-			if p.isCascadedFailure(res) {
-				cascadedFailures = append(cascadedFailures, res)
-			} else {
+			if !p.isCascadedFailure(res) {
 				rootFailures = append(rootFailures, res)
+			} else if root := p.rootFailureLocked(res, map[*ResourceInfo]bool{}); root != nil {
+				blocked[root] = append(blocked[root], res)
+			} else {
+				cascadedFailures = append(cascadedFailures, res)
 			}
 		}
 
@@ -457,10 +494,14 @@ func (p *ProgressTable) PrintFinalSummary() {
 			fmt.Fprintln(p.writer, "\nErrors:")
 			for _, res := range rootFailures {
 				fmt.Fprintf(p.writer, "  ✗ %s/%s", res.Type, res.Name)
+				if res.Phase != "" {
+					fmt.Fprintf(p.writer, " (while %s)", res.Phase)
+				}
 				if res.Error != nil {
 					fmt.Fprintf(p.writer, ": %v", res.Error)
 				}
 				fmt.Fprintln(p.writer)
+				printRemediation(p.writer, "    ", errors.RemediationOf(res.Error))
 
 				// Show inferred configuration if available
 				if len(res.InferredConfig) > 0 {
@@ -491,10 +532,18 @@ func (p *ProgressTable) PrintFinalSummary() {
 						fmt.Fprintf(p.writer, "      %s\n", line)
 					}
 				}
+				if res.LogFile != "" {
+					fmt.Fprintf(p.writer, "    Full output: %s\n", res.LogFile)
+				}
+
+				// Show what this failure kept from being deployed
+				if deps := blocked[res]; len(deps) > 0 {
+					fmt.Fprintf(p.writer, "    Blocked %d resources: %s\n", len(deps), blockedList(deps))
+				}
 			}
 		}
 
-		// Show cascaded failures as a compact list
+		// Show the remaining cascaded failures as a compact count
 		if len(cascadedFailures) > 0 {
 			fmt.Fprintf(p.writer, "\nSkipped due to above errors: %d resources\n", len(cascadedFailures))
 		}
@@ -506,6 +555,36 @@ func (p *ProgressTable) PrintFinalSummary() {
 	}
 }
 
+// printRemediation prints the guidance an error carries for resolving it:
+// its code, the alternatives to use instead, and where to read more.
+func printRemediation(w io.Writer, indent string, r *errors.Remediation) {
+	if r.IsEmpty() {
+		return
+	}
+	if r.Code != "" {
+		fmt.Fprintf(w, "%sCode: %s\n", indent, r.Code)
+	}
+	if len(r.Alternatives) > 0 {
+		fmt.Fprintf(w, "%sAlternatives: %s\n", indent, strings.Join(r.Alternatives, ", "))
+	}
+	if r.DocsURL != "" {
+		fmt.Fprintf(w, "%sDocs: %s\n", indent, r.DocsURL)
+	}
+}
+
+// printWarnings prints the warnings of a command, after its summary, so that
+// degraded behavior is visible without debug output.
+func printWarnings(w io.Writer, warnings []planner.Warning) {
+	if len(warnings) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Warnings:\n")
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "  ! %s\n", warning)
+	}
+	fmt.Fprintln(w)
+}
+
 // ---------------------------------------------------------------------------
 // Dynamic table renderer (ANSI terminal)
 // ---------------------------------------------------------------------------
@@ -542,13 +621,25 @@ func (p *ProgressTable) renderTableLocked() {
 		}
 	}
 
+	// The phase column only appears once a plugin has reported a phase, and
+	// keeps its width from then on so rows don't shift as resources finish.
+	maxPhaseLen := 0
+	for _, id := range p.order {
+		if n := len(p.resources[id].Phase); n > maxPhaseLen {
+			maxPhaseLen = n
+		}
+	}
+
 	// ---- compute available width for status description ----
-	// Layout: "  {icon}  {label}  {desc}{deps}"
+	// Layout: "  {icon}  {label}  {phase}  {desc}{deps}"
 	// Icon is 1 visible char; spacing is 2+2+2 = 6 chars.
 	prefixWidth := 7 + maxLabelLen // 2 + 1(icon) + 2 + label + 2
 	if multiComp {
 		prefixWidth += maxCompLen + 2
 	}
+	if maxPhaseLen > 0 {
+		prefixWidth += maxPhaseLen + 2
+	}
 
 	// ---- render each resource row ----
 	for _, id := range p.order {
@@ -568,6 +659,14 @@ func (p *ProgressTable) renderTableLocked() {
 			desc = truncateAnsi(desc, maxDescVisible)
 		}
 
+		if maxPhaseLen > 0 {
+			phase := ""
+			if res.Status == StatusInProgress {
+				phase = res.Phase
+			}
+			label = fmt.Sprintf("%-*s  ", maxLabelLen, label) + colorYellow + fmt.Sprintf("%-*s", maxPhaseLen, phase) + colorReset
+		}
+
 		if multiComp {
 			compName := colorDim + fmt.Sprintf("%-*s", maxCompLen, res.Component) + colorReset
 			fmt.Fprintf(p.writer, "%s  %s  %s  %-*s  %s%s\n",
@@ -824,6 +923,45 @@ func (p *ProgressTable) isCascadedFailure(res *ResourceInfo) bool {
 		errMsg == "cancelled"
 }
 
+// rootFailureLocked returns the root-cause failure that a resource skipped
+// because of a failed dependency was blocked by, following dependencies that
+// were themselves skipped. Resources stopped or cancelled for another reason
+// have none. Caller MUST hold p.mu.
+func (p *ProgressTable) rootFailureLocked(res *ResourceInfo, visited map[*ResourceInfo]bool) *ResourceInfo {
+	if res.Error == nil || !strings.HasPrefix(res.Error.Error(), "dependenc") || visited[res] {
+		return nil
+	}
+	visited[res] = true
+	for _, depID := range res.Dependencies {
+		dep, ok := p.resources[depID]
+		if !ok || dep.Status != StatusFailed {
+			continue
+		}
+		if !p.isCascadedFailure(dep) {
+			return dep
+		}
+		if root := p.rootFailureLocked(dep, visited); root != nil {
+			return root
+		}
+	}
+	return nil
+}
+
+// blockedList formats the resources a failure blocked, listing the first few
+// by name.
+func blockedList(resources []*ResourceInfo) string {
+	const maxListed = 5
+	names := make([]string, 0, maxListed)
+	for i, res := range resources {
+		if i == maxListed {
+			names = append(names, fmt.Sprintf("and %d more", len(resources)-maxListed))
+			break
+		}
+		names = append(names, res.Type+"/"+res.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
 // ---------------------------------------------------------------------------
 // ANSI-aware string helpers
 // ---------------------------------------------------------------------------
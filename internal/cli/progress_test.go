@@ -2,9 +2,12 @@ package cli
 
 import (
 	"bytes"
+	"fmt"
 	"strings"
 	"testing"
 
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -54,6 +57,27 @@ func TestProgressTable_UpdateStatus(t *testing.T) {
 	assert.False(t, pt.resources["comp/database/main"].StartTime.IsZero())
 }
 
+func TestProgressTable_SetPhase(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pt := NewProgressTable(buf)
+
+	pt.AddResource("comp/deployment/api", "api", "deployment", "comp", nil)
+	pt.SetPhase("comp/deployment/api", "pulling")
+	pt.SetPhase("comp/deployment/api", "")
+	pt.UpdateStatus("comp/deployment/api", StatusInProgress, "pulling image")
+	assert.Equal(t, "pulling", pt.resources["comp/deployment/api"].Phase)
+
+	// Non-dynamic output prefixes sub-status lines with the phase
+	pt.PrintUpdate("comp/deployment/api")
+	assert.Contains(t, buf.String(), "deployment/api [pulling] pulling image")
+
+	// The failure summary says which phase the resource failed in
+	pt.SetError("comp/deployment/api", assert.AnError)
+	buf.Reset()
+	pt.PrintFinalSummary()
+	assert.Contains(t, buf.String(), "deployment/api (while pulling)")
+}
+
 func TestProgressTable_SetError(t *testing.T) {
 	buf := &bytes.Buffer{}
 	pt := NewProgressTable(buf)
@@ -205,6 +229,30 @@ func TestProgressTable_PrintFinalSummary_WithFailures(t *testing.T) {
 	assert.Contains(t, output, "deployment/api")
 }
 
+func TestProgressTable_PrintFinalSummary_GroupsCascadedFailures(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pt := NewProgressTable(buf)
+
+	pt.AddResource("comp/dockerBuild/api", "api", "dockerBuild", "comp", nil)
+	pt.AddResource("comp/deployment/api", "api", "deployment", "comp", []string{"comp/dockerBuild/api"})
+	pt.AddResource("comp/service/api", "api", "service", "comp", []string{"comp/deployment/api"})
+	pt.AddResource("comp/database/main", "main", "database", "comp", nil)
+	pt.AddResource("comp/deployment/worker", "worker", "deployment", "comp", nil)
+	pt.SetLogs("comp/dockerBuild/api", "ERROR: failed to solve")
+	pt.SetError("comp/dockerBuild/api", assert.AnError)
+	pt.SetError("comp/deployment/api", fmt.Errorf("dependency comp/dockerBuild/api failed"))
+	pt.SetError("comp/service/api", fmt.Errorf("dependency comp/deployment/api failed"))
+	pt.UpdateStatus("comp/database/main", StatusCompleted, "")
+	pt.SetError("comp/deployment/worker", fmt.Errorf("deployment stopped: a previous resource failed"))
+	pt.PrintFinalSummary()
+
+	output := buf.String()
+	assert.Equal(t, 1, strings.Count(output, "ERROR: failed to solve"))
+	assert.Contains(t, output, "Blocked 2 resources: deployment/api, service/api")
+	// Resources that weren't blocked by a dependency are only counted
+	assert.Contains(t, output, "Skipped due to above errors: 1 resources")
+}
+
 func TestProgressTable_PrintFinalSummary_WithInferredConfig(t *testing.T) {
 	buf := &bytes.Buffer{}
 	pt := NewProgressTable(buf)
@@ -232,6 +280,26 @@ func TestProgressTable_PrintFinalSummary_WithInferredConfig(t *testing.T) {
 	assert.Contains(t, output, "port: 3000")
 }
 
+func TestProgressTable_PrintFinalSummary_WithRemediation(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pt := NewProgressTable(buf)
+
+	pt.AddResource("comp/database/main", "main", "database", "comp", nil)
+	pt.SetError("comp/database/main", errors.DatacenterHookError("database", "comp", "main", "mysql is not supported").
+		WithRemediation(&errors.Remediation{
+			Code:         "MYSQL_UNSUPPORTED",
+			DocsURL:      "https://docs.example.com/databases",
+			Alternatives: []string{"postgres", "mariadb"},
+		}))
+	pt.PrintFinalSummary()
+
+	output := buf.String()
+	assert.Contains(t, output, "mysql is not supported")
+	assert.Contains(t, output, "Code: MYSQL_UNSUPPORTED")
+	assert.Contains(t, output, "Alternatives: postgres, mariadb")
+	assert.Contains(t, output, "Docs: https://docs.example.com/databases")
+}
+
 func TestProgressTable_PrintFinalSummary_WithLogs(t *testing.T) {
 	buf := &bytes.Buffer{}
 	pt := NewProgressTable(buf)
@@ -317,3 +385,18 @@ func TestResourceID(t *testing.T) {
 	id := resourceID("mycomp", "database", "main")
 	assert.Equal(t, "mycomp/database/main", id)
 }
+
+func TestPrintWarnings(t *testing.T) {
+	buf := &bytes.Buffer{}
+	printWarnings(buf, nil)
+	assert.Empty(t, buf.String())
+
+	printWarnings(buf, []planner.Warning{
+		{Resource: "comp/deployment/api", Message: "expression ${{ services.cache.url }} resolved to an empty string"},
+		{Message: "module db has no recorded IaC state"},
+	})
+	output := buf.String()
+	assert.Contains(t, output, "Warnings:")
+	assert.Contains(t, output, "! comp/deployment/api: expression ${{ services.cache.url }} resolved to an empty string")
+	assert.Contains(t, output, "! module db has no recorded IaC state")
+}
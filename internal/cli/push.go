@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/davidthor/cldctl/pkg/catalog"
 	"github.com/davidthor/cldctl/pkg/oci"
 	"github.com/davidthor/cldctl/pkg/schema/datacenter"
 	"github.com/spf13/cobra"
@@ -27,11 +28,12 @@ func newPushCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "push",
 		Short: "Push artifacts to registry",
-		Long:  `Commands for pushing component and datacenter artifacts to OCI registries.`,
+		Long:  `Commands for pushing component, datacenter, and catalog artifacts to OCI registries.`,
 	}
 
 	cmd.AddCommand(newPushComponentCmd())
 	cmd.AddCommand(newPushDatacenterCmd())
+	cmd.AddCommand(newPushCatalogCmd())
 
 	return cmd
 }
@@ -211,3 +213,88 @@ Examples:
 
 	return cmd
 }
+
+func newPushCatalogCmd() *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "catalog <index-file> <repo:tag>",
+		Short: "Push a catalog index to an OCI registry",
+		Long: `Validate a catalog index file and push it to an OCI registry, where
+'cldctl search' can read it.
+
+Point contexts at the pushed reference with
+'cldctl context set <context> --catalog <repo:tag>'.
+
+Examples:
+  cldctl push catalog ./catalog.yml ghcr.io/acme/catalog:latest
+  cldctl push catalog ./catalog.yml ghcr.io/acme/catalog:latest -y`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			indexFile, reference := args[0], args[1]
+
+			data, err := os.ReadFile(indexFile)
+			if err != nil {
+				return fmt.Errorf("failed to read catalog index: %w", err)
+			}
+			idx, err := catalog.Parse(data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", indexFile, err)
+			}
+
+			fmt.Printf("Pushing catalog artifact: %s (%d entries)\n", reference, len(idx.Entries))
+			fmt.Println()
+
+			// Confirm unless --yes is provided
+			if !yes {
+				fmt.Print("Proceed with push? [Y/n]: ")
+				var response string
+				_, _ = fmt.Scanln(&response)
+				response = strings.ToLower(strings.TrimSpace(response))
+				if response != "" && response != "y" && response != "yes" {
+					fmt.Println("Push cancelled.")
+					return nil
+				}
+			}
+
+			// The artifact holds the index alone, under the name readers look for
+			tmpDir, err := os.MkdirTemp("", "cldctl-push-catalog-*")
+			if err != nil {
+				return fmt.Errorf("failed to create temp dir: %w", err)
+			}
+			defer os.RemoveAll(tmpDir)
+
+			name := catalog.IndexFileNames[0]
+			if strings.EqualFold(filepath.Ext(indexFile), ".json") {
+				name = "catalog.json"
+			}
+			if err := os.WriteFile(filepath.Join(tmpDir, name), data, 0644); err != nil {
+				return fmt.Errorf("failed to stage catalog index: %w", err)
+			}
+
+			ctx := context.Background()
+			client := oci.NewClient()
+			artifact, err := client.BuildFromDirectory(ctx, tmpDir, oci.ArtifactTypeCatalog, map[string]interface{}{
+				"name":    idx.Name,
+				"version": idx.Version,
+				"entries": len(idx.Entries),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to build catalog artifact: %w", err)
+			}
+			artifact.Reference = reference
+
+			fmt.Printf("[push] Pushing %s...\n", reference)
+			if err := client.Push(ctx, artifact); err != nil {
+				return fmt.Errorf("failed to push catalog: %w", err)
+			}
+			fmt.Printf("[success] Pushed %s\n", reference)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Non-interactive mode")
+
+	return cmd
+}
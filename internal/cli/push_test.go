@@ -20,6 +20,7 @@ func TestNewPushCmd(t *testing.T) {
 	expectedCommands := []string{
 		"component <repo:tag>",
 		"datacenter <repo:tag>",
+		"catalog <index-file> <repo:tag>",
 	}
 
 	for _, expected := range expectedCommands {
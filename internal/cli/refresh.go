@@ -0,0 +1,316 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/engine"
+	"github.com/davidthor/cldctl/pkg/oci"
+	"github.com/davidthor/cldctl/pkg/registry"
+	"github.com/davidthor/cldctl/pkg/state"
+	"github.com/davidthor/cldctl/pkg/state/types"
+	"github.com/spf13/cobra"
+)
+
+// Statuses of a component in a refresh.
+const (
+	refreshUnchanged = "unchanged"
+	refreshChanged   = "changed" // dry run
+	refreshDeployed  = "deployed"
+	refreshFailed    = "failed"
+)
+
+// refreshTarget is a component deployed from a mutable OCI tag.
+type refreshTarget struct {
+	Name      string
+	Reference string
+	State     *types.ComponentState
+}
+
+// refreshResult records what happened to one component in a refresh.
+type refreshResult struct {
+	Component string `json:"component"`
+	Reference string `json:"reference"`
+	Status    string `json:"status"`
+	From      string `json:"from,omitempty"` // Previously deployed digest, if recorded
+	To        string `json:"to,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func newRefreshCmd() *cobra.Command {
+	var (
+		datacenter    string
+		every         time.Duration
+		dryRun        bool
+		outputFormat  string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "refresh <environment>",
+		Short: "Re-deploy components whose tags have moved",
+		Long: `Check the registry for new versions of the components in an environment
+and re-deploy the ones whose tag now points to a different artifact.
+
+Components deployed from a mutable tag (e.g. ghcr.io/myorg/api:main) are
+resolved to the digest the tag currently points to. Components whose digest
+changed since the last refresh are pulled and re-deployed with the variables
+they were deployed with; the rest are left alone. The deployed digest is
+recorded in the environment's state. The first refresh of a component
+re-deploys it, since the digest it was deployed from isn't known yet.
+
+Components deployed from a local path, pinned to a digest, or running
+multiple instances (see 'cldctl rollout') are not refreshed.
+
+Use --every to keep refreshing on a schedule, e.g. to keep a dev environment
+on the latest build of main. The command then runs until interrupted.
+
+Examples:
+  cldctl refresh dev
+  cldctl refresh dev --dry-run
+  cldctl refresh dev --every 10m
+  cldctl refresh dev -o json`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envName := args[0]
+
+			if every < 0 {
+				return fmt.Errorf("--every must be a positive duration")
+			}
+
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			// Deploy progress goes to stderr so that JSON output stays clean
+			progress := io.Writer(os.Stdout)
+			if outputFormat == "json" {
+				progress = os.Stderr
+			}
+
+			run := func() error {
+				results, err := refreshEnvironment(ctx, mgr, dc, envName, dryRun, progress)
+				if err != nil {
+					return err
+				}
+				switch outputFormat {
+				case "json":
+					data, err := json.MarshalIndent(results, "", "  ")
+					if err != nil {
+						return fmt.Errorf("failed to marshal JSON: %w", err)
+					}
+					fmt.Println(string(data))
+				default:
+					printRefreshResults(envName, results)
+				}
+				for _, r := range results {
+					if r.Status == refreshFailed {
+						return fmt.Errorf("failed to refresh component %q", r.Component)
+					}
+				}
+				return nil
+			}
+
+			if every == 0 {
+				return run()
+			}
+
+			ticker := time.NewTicker(every)
+			defer ticker.Stop()
+			for {
+				// A failed refresh is retried on the next tick
+				if err := run(); err != nil {
+					fmt.Fprintf(os.Stderr, "[refresh] %v\n", err)
+				}
+				fmt.Fprintf(progress, "[refresh] Next refresh at %s\n", time.Now().Add(every).Format(time.Kitchen))
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
+	cmd.Flags().DurationVar(&every, "every", 0, "Keep refreshing at this interval (e.g. 10m) until interrupted")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Report which components moved without re-deploying them")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, json")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+// refreshEnvironment re-deploys the components of an environment whose tags
+// have moved, and records the digests they were deployed from.
+func refreshEnvironment(ctx context.Context, mgr state.Manager, dc, envName string, dryRun bool, out io.Writer) ([]refreshResult, error) {
+	if !dryRun {
+		lockedCtx, unlock, err := engine.LockEnvironment(ctx, mgr, dc, envName, "refresh", false, out)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+		ctx = lockedCtx
+	}
+
+	env, err := mgr.GetEnvironment(ctx, dc, envName)
+	if err != nil {
+		return nil, fmt.Errorf("environment %q not found in datacenter %q: %w", envName, dc, err)
+	}
+
+	reg, err := registry.NewRegistry()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open local registry: %w", err)
+	}
+	entries, err := reg.ListByType(registry.TypeComponent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cached components: %w", err)
+	}
+
+	client := oci.NewClient()
+	eng := createEngine(mgr)
+
+	results := []refreshResult{}
+	for _, target := range refreshTargets(env, entries) {
+		result := refreshResult{Component: target.Name, Reference: target.Reference, From: target.State.Digest}
+
+		digest, err := client.Digest(ctx, target.Reference)
+		if err != nil {
+			result.Status = refreshFailed
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+		result.To = digest
+
+		switch {
+		case digest == target.State.Digest:
+			result.Status = refreshUnchanged
+		case dryRun:
+			result.Status = refreshChanged
+		default:
+			if err := redeployComponent(ctx, eng, mgr, dc, envName, target, digest, out); err != nil {
+				result.Status = refreshFailed
+				result.Error = err.Error()
+			} else {
+				result.Status = refreshDeployed
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// refreshTargets returns the components of an environment that were
+// deployed from a mutable OCI tag, sorted by name. Deployed components refer
+// to the cached copy of their artifact, so their references are found by
+// looking up the cache directory in the local registry.
+func refreshTargets(env *types.EnvironmentState, entries []registry.ArtifactEntry) []refreshTarget {
+	refs := make(map[string]string, len(entries))
+	for _, e := range entries {
+		if e.CachePath != "" {
+			refs[filepath.Clean(e.CachePath)] = e.Reference
+		}
+	}
+
+	var targets []refreshTarget
+	for _, name := range sortedComponentMapKeys(env.Components) {
+		cs := env.Components[name]
+		if cs == nil || cs.Source == "" || len(cs.Instances) > 0 {
+			continue
+		}
+		ref, ok := refs[filepath.Clean(filepath.Dir(cs.Source))]
+		if !ok {
+			ref, ok = refs[filepath.Clean(cs.Source)]
+		}
+		if !ok || strings.Contains(ref, "@") {
+			continue
+		}
+		targets = append(targets, refreshTarget{Name: name, Reference: ref, State: cs})
+	}
+	return targets
+}
+
+// redeployComponent pulls the latest artifact for a component and deploys it
+// with the variables it was deployed with, then records its digest.
+func redeployComponent(ctx context.Context, eng *engine.Engine, mgr state.Manager, dc, envName string, target refreshTarget, digest string, out io.Writer) error {
+	fmt.Fprintf(out, "[refresh] %s moved to %s, re-deploying...\n", target.Reference, shortDigest(digest))
+
+	compFile, err := pullComponentImage(ctx, target.Reference, out)
+	if err != nil {
+		return err
+	}
+
+	vars := make(map[string]interface{}, len(target.State.Variables))
+	for k, v := range target.State.Variables {
+		vars[k] = v
+	}
+	if err := deployComponentToEnvironment(ctx, eng, engine.DeployOptions{
+		Environment: envName,
+		Datacenter:  dc,
+		Components:  map[string]string{target.Name: compFile},
+		Variables:   map[string]map[string]interface{}{target.Name: vars},
+		Output:      out,
+		AutoApprove: true,
+		Parallelism: defaultParallelism,
+	}); err != nil {
+		return err
+	}
+
+	// The deploy rewrote the component's state, so record the digest on the
+	// saved copy
+	env, err := mgr.GetEnvironment(ctx, dc, envName)
+	if err != nil {
+		return fmt.Errorf("failed to read environment state: %w", err)
+	}
+	cs, ok := env.Components[target.Name]
+	if !ok {
+		return fmt.Errorf("component %q missing from environment state after deploy", target.Name)
+	}
+	cs.Digest = digest
+	if err := mgr.SaveEnvironment(ctx, dc, env); err != nil {
+		return fmt.Errorf("failed to record digest: %w", err)
+	}
+	return nil
+}
+
+func printRefreshResults(envName string, results []refreshResult) {
+	if len(results) == 0 {
+		fmt.Printf("No components in environment %q were deployed from a registry tag.\n", envName)
+		return
+	}
+
+	fmt.Printf("%-20s %-45s %-10s %s\n", "COMPONENT", "REFERENCE", "STATUS", "DIGEST")
+	for _, r := range results {
+		digest := shortDigest(r.To)
+		switch {
+		case r.Status == refreshFailed:
+			digest = r.Error
+		case r.Status != refreshUnchanged && r.From == "":
+			digest = "(unknown) -> " + shortDigest(r.To)
+		case r.Status != refreshUnchanged:
+			digest = shortDigest(r.From) + " -> " + shortDigest(r.To)
+		}
+		fmt.Printf("%-20s %-45s %-10s %s\n", r.Component, truncateString(r.Reference, 45), r.Status, digest)
+	}
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/registry"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func TestRefreshTargets(t *testing.T) {
+	env := &types.EnvironmentState{
+		Components: map[string]*types.ComponentState{
+			"api":    {Source: "/cache/api-main/cld.yml", Digest: "sha256:aaa"},
+			"worker": {Source: "/cache/worker-main/cld.yml"},
+			"pinned": {Source: "/cache/pinned/cld.yml"},
+			"local":  {Source: "/home/me/app/cld.yml"},
+			"canary": {
+				Source:    "/cache/canary-main/cld.yml",
+				Instances: map[string]*types.InstanceState{"stable": {Name: "stable"}},
+			},
+		},
+	}
+	entries := []registry.ArtifactEntry{
+		{Reference: "ghcr.io/org/api:main", CachePath: "/cache/api-main"},
+		{Reference: "ghcr.io/org/worker:main", CachePath: "/cache/worker-main/"},
+		{Reference: "ghcr.io/org/pinned@sha256:bbb", CachePath: "/cache/pinned"},
+		{Reference: "ghcr.io/org/canary:main", CachePath: "/cache/canary-main"},
+	}
+
+	targets := refreshTargets(env, entries)
+
+	want := map[string]string{
+		"api":    "ghcr.io/org/api:main",
+		"worker": "ghcr.io/org/worker:main",
+	}
+	if len(targets) != len(want) {
+		t.Fatalf("got %d targets, want %d: %+v", len(targets), len(want), targets)
+	}
+	for i, name := range []string{"api", "worker"} {
+		if targets[i].Name != name || targets[i].Reference != want[name] {
+			t.Errorf("targets[%d] = %s (%s), want %s (%s)", i, targets[i].Name, targets[i].Reference, name, want[name])
+		}
+	}
+	if targets[0].State.Digest != "sha256:aaa" {
+		t.Errorf("expected the recorded digest to be carried over, got %q", targets[0].State.Digest)
+	}
+}
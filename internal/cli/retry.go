@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/engine"
+	"github.com/davidthor/cldctl/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newRetryCmd() *cobra.Command {
+	var (
+		datacenter    string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "retry <environment>/<component>/<type>/<name>",
+		Short: "Re-apply a single deployed resource, such as one that failed",
+		Long: `Re-apply one resource of a deployed component without re-planning the
+environment. This is the fastest way to recover from a transient failure,
+such as a hook module that timed out.
+
+The component is rebuilt from the source and variables it was last deployed
+with, and the resource's expressions are resolved again against the outputs
+its dependencies recorded in state. Its dependencies must already be ready.
+Every other resource is left as it is, so resources skipped because this one
+failed are deployed by the next deploy.
+
+Examples:
+  cldctl retry staging/my-app/database/main
+  cldctl retry staging/my-app/deployment/api -d my-dc
+  cldctl retry staging/myorg/stripe/route/webhooks`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envName, compName, nodePath, err := parseRetryTarget(args[0])
+			if err != nil {
+				return err
+			}
+
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			result, err := createEngine(mgr).RetryNode(context.Background(), engine.RetryNodeOptions{
+				Environment: envName,
+				Datacenter:  dc,
+				Component:   compName,
+				NodePath:    nodePath,
+				Output:      os.Stdout,
+			})
+			if err != nil {
+				return err
+			}
+			printWarnings(os.Stdout, result.Execution.Warnings)
+			if !result.Success {
+				if len(result.Execution.Errors) > 0 {
+					printRemediation(os.Stdout, "  ", errors.RemediationOf(result.Execution.Errors[0]))
+					return fmt.Errorf("failed to retry %s: %v", result.NodeID, result.Execution.Errors[0])
+				}
+				return fmt.Errorf("failed to retry %s", result.NodeID)
+			}
+
+			fmt.Printf("[success] Retried %s (%s) in %s\n", result.NodeID, result.Action, result.Duration.Round(100*1e6))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+// parseRetryTarget splits an <environment>/<component>/<type>/<name>
+// argument into the environment, component, and type/name node path.
+// Component names may themselves contain slashes (e.g. myorg/stripe), so the
+// environment is the first segment and the node path the last two.
+func parseRetryTarget(target string) (envName, compName, nodePath string, err error) {
+	invalid := fmt.Errorf("invalid target %q: expected <environment>/<component>/<type>/<name>", target)
+
+	segments := strings.Split(target, "/")
+	if len(segments) < 4 {
+		return "", "", "", invalid
+	}
+	for _, s := range segments {
+		if s == "" {
+			return "", "", "", invalid
+		}
+	}
+	n := len(segments)
+	return segments[0], strings.Join(segments[1:n-2], "/"), segments[n-2] + "/" + segments[n-1], nil
+}
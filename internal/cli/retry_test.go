@@ -0,0 +1,23 @@
+package cli
+
+import "testing"
+
+func TestParseRetryTarget(t *testing.T) {
+	tests := []struct {
+		target, env, comp, node string
+	}{
+		{"staging/api/database/main", "staging", "api", "database/main"},
+		{"staging/myorg/stripe/route/webhooks", "staging", "myorg/stripe", "route/webhooks"},
+	}
+	for _, tt := range tests {
+		env, comp, node, err := parseRetryTarget(tt.target)
+		if err != nil || env != tt.env || comp != tt.comp || node != tt.node {
+			t.Errorf("parseRetryTarget(%q) = %q, %q, %q, %v", tt.target, env, comp, node, err)
+		}
+	}
+	for _, target := range []string{"staging/api/main", "/api/database/main", "staging/api/database/", "staging//database/main"} {
+		if _, _, _, err := parseRetryTarget(target); err == nil {
+			t.Errorf("parseRetryTarget(%q) should fail", target)
+		}
+	}
+}
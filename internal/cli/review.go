@@ -0,0 +1,247 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+)
+
+// defaultSkipReason is recorded when a change is skipped without a reason.
+const defaultSkipReason = "deselected during interactive review"
+
+// reviewPlan runs an interactive review of an execution plan. Users can
+// expand individual changes to see their input diffs and deselect deletes
+// and replacements, which are then executed as no-ops. It returns true when
+// the user approves the (possibly modified) plan.
+func reviewPlan(in io.Reader, out io.Writer, plan *planner.Plan) (bool, error) {
+	changes := reviewableChanges(plan)
+	if len(changes) == 0 {
+		return true, nil
+	}
+
+	scanner := bufio.NewScanner(in)
+	printReviewList(out, plan, changes)
+
+	for {
+		fmt.Fprint(out, "\nReview> ")
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return false, err
+			}
+			fmt.Fprintln(out)
+			return false, nil
+		}
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		cmd, args := strings.ToLower(fields[0]), fields[1:]
+		switch cmd {
+		case "a", "apply", "y", "yes":
+			return true, nil
+		case "q", "quit", "n", "no":
+			return false, nil
+		case "l", "list":
+			printReviewList(out, plan, changes)
+		case "?", "h", "help":
+			printReviewHelp(out)
+		case "s", "skip":
+			change, err := selectChange(changes, args)
+			if err != nil {
+				fmt.Fprintf(out, "Error: %v\n", err)
+				continue
+			}
+			reason := strings.Join(args[1:], " ")
+			if reason == "" {
+				reason = defaultSkipReason
+			}
+			if err := plan.Skip(change, reason); err != nil {
+				fmt.Fprintf(out, "Error: %v\n", err)
+				continue
+			}
+			fmt.Fprintf(out, "Skipping %s %s\n", change.SkippedAction, change.Node.ID)
+		case "u", "unskip":
+			change, err := selectChange(changes, args)
+			if err != nil {
+				fmt.Fprintf(out, "Error: %v\n", err)
+				continue
+			}
+			if !change.Skipped() {
+				fmt.Fprintf(out, "Error: %s is not skipped\n", change.Node.ID)
+				continue
+			}
+			plan.Unskip(change)
+			fmt.Fprintf(out, "Restored %s %s\n", change.Action, change.Node.ID)
+		default:
+			// A bare number expands that change.
+			if _, err := strconv.Atoi(cmd); err != nil {
+				fmt.Fprintf(out, "Unknown command %q (type ? for help)\n", fields[0])
+				continue
+			}
+			change, err := selectChange(changes, fields)
+			if err != nil {
+				fmt.Fprintf(out, "Error: %v\n", err)
+				continue
+			}
+			printChangeDetail(out, change)
+		}
+	}
+}
+
+// reviewableChanges returns the plan's changes that do something, plus any
+// that were already skipped.
+func reviewableChanges(plan *planner.Plan) []*planner.ResourceChange {
+	var changes []*planner.ResourceChange
+	for _, change := range plan.Changes {
+		if change.Action != planner.ActionNoop || change.Skipped() {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// selectChange resolves the 1-based change number in args[0].
+func selectChange(changes []*planner.ResourceChange, args []string) (*planner.ResourceChange, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("missing change number")
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 || n > len(changes) {
+		return nil, fmt.Errorf("no change %q (expected 1-%d)", args[0], len(changes))
+	}
+	return changes[n-1], nil
+}
+
+func printReviewList(out io.Writer, plan *planner.Plan, changes []*planner.ResourceChange) {
+	fmt.Fprintln(out, "Review changes:")
+	fmt.Fprintln(out)
+	for i, change := range changes {
+		action := change.Action
+		if change.Skipped() {
+			action = change.SkippedAction
+		}
+		line := fmt.Sprintf("  %3d. %s %-8s %s", i+1, actionSymbol(action), action, change.Node.ID)
+		if change.Skipped() {
+			line += fmt.Sprintf("  [skipped: %s]", change.SkipReason)
+		} else if n := len(change.PropertyChanges); n > 0 {
+			line += fmt.Sprintf("  (%d %s)", n, pluralize(n, "property", "properties"))
+		}
+		fmt.Fprintln(out, line)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "Plan: %d to create, %d to update, %d to delete", plan.ToCreate, plan.ToUpdate, plan.ToDelete)
+	if plan.Skipped > 0 {
+		fmt.Fprintf(out, ", %d skipped", plan.Skipped)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Enter a number to expand a change, 'skip <n> [reason]' to deselect a delete or replace, 'apply' to proceed, or '?' for help.")
+}
+
+func printReviewHelp(out io.Writer) {
+	fmt.Fprintln(out, "Commands:")
+	fmt.Fprintln(out, "  <n>                  Show the input diff for change n")
+	fmt.Fprintln(out, "  skip <n> [reason]    Deselect a delete or replace (s)")
+	fmt.Fprintln(out, "  unskip <n>           Restore a skipped change (u)")
+	fmt.Fprintln(out, "  list                 List the changes again (l)")
+	fmt.Fprintln(out, "  apply                Proceed with the selected changes (a, y)")
+	fmt.Fprintln(out, "  quit                 Cancel the deployment (q, n)")
+}
+
+// printChangeDetail prints why a change is planned and how its inputs differ
+// from the recorded state.
+func printChangeDetail(out io.Writer, change *planner.ResourceChange) {
+	action := change.Action
+	if change.Skipped() {
+		action = change.SkippedAction
+	}
+	fmt.Fprintf(out, "%s %s %s\n", actionSymbol(action), action, change.Node.ID)
+	if change.Reason != "" {
+		fmt.Fprintf(out, "  reason: %s\n", change.Reason)
+	}
+	if change.Skipped() {
+		fmt.Fprintf(out, "  skipped: %s\n", change.SkipReason)
+	}
+
+	switch action {
+	case planner.ActionCreate:
+		printInputs(out, "+", change.Node.Inputs)
+	case planner.ActionDelete:
+		if change.CurrentState != nil {
+			printInputs(out, "-", change.CurrentState.Inputs)
+		}
+	default:
+		if len(change.PropertyChanges) == 0 {
+			fmt.Fprintln(out, "  (no input changes)")
+			return
+		}
+		props := append([]planner.PropertyChange(nil), change.PropertyChanges...)
+		sort.Slice(props, func(i, j int) bool { return props[i].Path < props[j].Path })
+		for _, pc := range props {
+			switch {
+			case pc.OldValue == nil:
+				fmt.Fprintf(out, "  + %s = %s\n", pc.Path, formatReviewValue(pc.NewValue))
+			case pc.NewValue == nil:
+				fmt.Fprintf(out, "  - %s = %s\n", pc.Path, formatReviewValue(pc.OldValue))
+			default:
+				fmt.Fprintf(out, "  ~ %s: %s -> %s\n", pc.Path, formatReviewValue(pc.OldValue), formatReviewValue(pc.NewValue))
+			}
+		}
+	}
+}
+
+func printInputs(out io.Writer, symbol string, inputs map[string]interface{}) {
+	if len(inputs) == 0 {
+		fmt.Fprintln(out, "  (no inputs)")
+		return
+	}
+	keys := make([]string, 0, len(inputs))
+	for k := range inputs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(out, "  %s %s = %s\n", symbol, k, formatReviewValue(inputs[k]))
+	}
+}
+
+// formatReviewValue renders an input value on a single line, using JSON for
+// anything that is not a plain string.
+func formatReviewValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+func actionSymbol(action planner.Action) string {
+	switch action {
+	case planner.ActionCreate:
+		return "+"
+	case planner.ActionUpdate:
+		return "~"
+	case planner.ActionReplace:
+		return "±"
+	case planner.ActionDelete:
+		return "-"
+	}
+	return " "
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
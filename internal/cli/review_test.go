@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func reviewTestPlan() *planner.Plan {
+	create := graph.NewNode(graph.NodeTypeDeployment, "api", "main")
+	create.SetInput("image", "api:v2")
+
+	update := graph.NewNode(graph.NodeTypeService, "api", "main")
+
+	return &planner.Plan{
+		Changes: []*planner.ResourceChange{
+			{Node: create, Action: planner.ActionCreate, Reason: "resource does not exist"},
+			{
+				Node:   update,
+				Action: planner.ActionUpdate,
+				PropertyChanges: []planner.PropertyChange{
+					{Path: "port", OldValue: 8080, NewValue: 9090},
+				},
+			},
+			{
+				Node:   &graph.Node{ID: "api/database/legacy", Component: "api", Name: "legacy"},
+				Action: planner.ActionDelete,
+				CurrentState: &types.ResourceState{
+					Inputs: map[string]interface{}{"type": "postgres"},
+				},
+			},
+		},
+		ToCreate: 1,
+		ToUpdate: 1,
+		ToDelete: 1,
+	}
+}
+
+func TestReviewPlan_SkipDelete(t *testing.T) {
+	plan := reviewTestPlan()
+	var out bytes.Buffer
+
+	approved, err := reviewPlan(strings.NewReader("skip 3 keep the data\napply\n"), &out, plan)
+	if err != nil {
+		t.Fatalf("reviewPlan failed: %v", err)
+	}
+	if !approved {
+		t.Fatal("expected plan to be approved")
+	}
+
+	change := plan.Changes[2]
+	if change.Action != planner.ActionNoop {
+		t.Errorf("expected delete to become noop, got %s", change.Action)
+	}
+	if change.SkipReason != "keep the data" {
+		t.Errorf("expected skip reason to be recorded, got %q", change.SkipReason)
+	}
+	if plan.ToDelete != 0 || plan.Skipped != 1 {
+		t.Errorf("unexpected counts: delete=%d skipped=%d", plan.ToDelete, plan.Skipped)
+	}
+}
+
+func TestReviewPlan_CannotSkipCreate(t *testing.T) {
+	plan := reviewTestPlan()
+	var out bytes.Buffer
+
+	approved, err := reviewPlan(strings.NewReader("s 1\ny\n"), &out, plan)
+	if err != nil {
+		t.Fatalf("reviewPlan failed: %v", err)
+	}
+	if !approved {
+		t.Fatal("expected plan to be approved")
+	}
+	if plan.Changes[0].Action != planner.ActionCreate {
+		t.Errorf("expected create to be kept, got %s", plan.Changes[0].Action)
+	}
+	if !strings.Contains(out.String(), "only deletes and replacements can be skipped") {
+		t.Errorf("expected skip error in output, got:\n%s", out.String())
+	}
+}
+
+func TestReviewPlan_ExpandShowsDiff(t *testing.T) {
+	plan := reviewTestPlan()
+	var out bytes.Buffer
+
+	if _, err := reviewPlan(strings.NewReader("1\n2\n3\nq\n"), &out, plan); err != nil {
+		t.Fatalf("reviewPlan failed: %v", err)
+	}
+
+	for _, want := range []string{
+		`+ image = "api:v2"`,
+		"~ port: 8080 -> 9090",
+		`- type = "postgres"`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestReviewPlan_QuitAndEOFCancel(t *testing.T) {
+	for _, input := range []string{"q\n", ""} {
+		var out bytes.Buffer
+		approved, err := reviewPlan(strings.NewReader(input), &out, reviewTestPlan())
+		if err != nil {
+			t.Fatalf("reviewPlan failed: %v", err)
+		}
+		if approved {
+			t.Errorf("expected input %q to cancel", input)
+		}
+	}
+}
+
+func TestReviewPlan_UnskipRestores(t *testing.T) {
+	plan := reviewTestPlan()
+	var out bytes.Buffer
+
+	if _, err := reviewPlan(strings.NewReader("s 3\nu 3\na\n"), &out, plan); err != nil {
+		t.Fatalf("reviewPlan failed: %v", err)
+	}
+	if plan.Changes[2].Action != planner.ActionDelete {
+		t.Errorf("expected delete to be restored, got %s", plan.Changes[2].Action)
+	}
+	if plan.Skipped != 0 {
+		t.Errorf("expected no skipped changes, got %d", plan.Skipped)
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/davidthor/cldctl/pkg/engine"
 	"github.com/davidthor/cldctl/pkg/state/types"
 	"github.com/spf13/cobra"
 )
@@ -205,6 +207,12 @@ Examples:
 				return fmt.Errorf("failed to create state manager: %w", err)
 			}
 
+			ctx, unlock, err := engine.LockEnvironment(ctx, mgr, dc, environment, "rollout set-weight", false, os.Stdout)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
 			envState, err := mgr.GetEnvironment(ctx, dc, environment)
 			if err != nil {
 				return fmt.Errorf("environment %q not found in datacenter %q: %w", environment, dc, err)
@@ -322,6 +330,12 @@ Examples:
 				return fmt.Errorf("failed to create state manager: %w", err)
 			}
 
+			ctx, unlock, err := engine.LockEnvironment(ctx, mgr, dc, environment, "rollout promote", false, os.Stdout)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
 			envState, err := mgr.GetEnvironment(ctx, dc, environment)
 			if err != nil {
 				return fmt.Errorf("environment %q not found in datacenter %q: %w", environment, dc, err)
@@ -422,6 +436,12 @@ Examples:
 				return fmt.Errorf("failed to create state manager: %w", err)
 			}
 
+			ctx, unlock, err := engine.LockEnvironment(ctx, mgr, dc, environment, "rollout rollback", false, os.Stdout)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
 			envState, err := mgr.GetEnvironment(ctx, dc, environment)
 			if err != nil {
 				return fmt.Errorf("environment %q not found in datacenter %q: %w", environment, dc, err)
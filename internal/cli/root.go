@@ -2,8 +2,10 @@
 package cli
 
 import (
+	"errors"
 	"os"
 
+	"github.com/davidthor/cldctl/pkg/state"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
@@ -12,6 +14,7 @@ import (
 	_ "github.com/davidthor/cldctl/pkg/state/backend/gcs"
 	_ "github.com/davidthor/cldctl/pkg/state/backend/local"
 	_ "github.com/davidthor/cldctl/pkg/state/backend/s3"
+	_ "github.com/davidthor/cldctl/pkg/state/postgres"
 
 	// Import log query adapters to register them via init()
 	_ "github.com/davidthor/cldctl/pkg/logs/loki"
@@ -41,6 +44,12 @@ Examples:
   cldctl list environment
   cldctl destroy component my-app -e staging`,
 	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := configureLogging(cmd); err != nil {
+			return err
+		}
+		return applyContextDefaults(cmd)
+	},
 }
 
 // Execute runs the root command.
@@ -48,39 +57,80 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
+// exitError is an error that exits the CLI with a status other than 1.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// ExitCode returns the status the CLI should exit with for an error returned
+// by Execute.
+func ExitCode(err error) int {
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	return 1
+}
+
 func init() {
 	cobra.OnInitialize(initConfig)
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.cldctl/config.yaml)")
-	rootCmd.PersistentFlags().String("backend", "local", "State backend type (local, s3, gcs)")
+	rootCmd.PersistentFlags().String("backend", "local", "State backend type (local, s3, gcs, azurerm, postgres)")
 	rootCmd.PersistentFlags().StringArray("backend-config", nil, "Backend configuration (key=value)")
+	rootCmd.PersistentFlags().StringVar(&contextName, "context", "", "Named context to use for this command (overrides the current context)")
+	rootCmd.PersistentFlags().StringVar(&tenantName, "tenant", "", "Tenant to act within in shared datacenters (overrides the context's tenant)")
+	addLoggingFlags(rootCmd)
 
 	// Bind to viper
 	_ = viper.BindPFlag("backend", rootCmd.PersistentFlags().Lookup("backend"))
 	viper.SetEnvPrefix("CLDCTL")
 	viper.AutomaticEnv()
 
+	// Record the CLI version in state metadata so version skew is detectable
+	state.WriterVersion = Version
+
 	// Add action-based commands (new inverted syntax)
 	rootCmd.AddCommand(newBuildCmd())
+	rootCmd.AddCommand(newPlanCmd())
 	rootCmd.AddCommand(newDeployCmd())
 	rootCmd.AddCommand(newDestroyCmd())
 	rootCmd.AddCommand(newListCmd())
 	rootCmd.AddCommand(newGetCmd())
+	rootCmd.AddCommand(newOutputCmd())
 	rootCmd.AddCommand(newCreateCmd())
 	rootCmd.AddCommand(newUpdateCmd())
 	rootCmd.AddCommand(newTagCmd())
 	rootCmd.AddCommand(newPushCmd())
 	rootCmd.AddCommand(newPullCmd())
+	rootCmd.AddCommand(newSearchCmd())
 	rootCmd.AddCommand(newValidateCmd())
 	rootCmd.AddCommand(newInspectCmd())
+	rootCmd.AddCommand(newDiffCmd())
+	rootCmd.AddCommand(newRefreshCmd())
+	rootCmd.AddCommand(newSetCmd())
+	rootCmd.AddCommand(newRotateCmd())
+	rootCmd.AddCommand(newRetryCmd())
+	rootCmd.AddCommand(newForceUnlockCmd())
+	rootCmd.AddCommand(newStateCmd())
 
 	// Keep the up command and version command
 	rootCmd.AddCommand(newUpCmd())
 	rootCmd.AddCommand(newVersionCmd())
+	rootCmd.AddCommand(newUpgradeCmd())
 
 	// Configuration commands
 	rootCmd.AddCommand(newConfigCmd())
+	rootCmd.AddCommand(newContextCmd())
+	rootCmd.AddCommand(newTenantCmd())
+
+	// Host diagnostics
+	rootCmd.AddCommand(newDoctorCmd())
 
 	// Artifact cache commands
 	rootCmd.AddCommand(newImagesCmd())
@@ -101,6 +151,15 @@ func init() {
 	rootCmd.AddCommand(newLogsCmd())
 	rootCmd.AddCommand(newObservabilityCmd())
 
+	// Usage analysis
+	rootCmd.AddCommand(newAnalyzeCmd())
+	rootCmd.AddCommand(newStatsCmd())
+
+	// Acceptance testing
+	rootCmd.AddCommand(newTestCmd())
+	rootCmd.AddCommand(newSnapshotCmd())
+	rootCmd.AddCommand(newGraphCmd())
+
 	// Single-node execution (for CI workflows)
 	rootCmd.AddCommand(newApplyCmd())
 
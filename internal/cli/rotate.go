@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/engine"
+	"github.com/spf13/cobra"
+)
+
+func newRotateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Rotate credentials of deployed resources",
+		Long:  `Replace credentials of deployed resources with newly minted ones.`,
+	}
+
+	cmd.AddCommand(newRotateSecretCmd())
+
+	return cmd
+}
+
+func newRotateSecretCmd() *cobra.Command {
+	var (
+		datacenter    string
+		twoPhase      bool
+		dryRun        bool
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "secret <environment>/<component>/<key>",
+		Short: "Mint a new value for a component's encryption key",
+		Long: `Mint a new value for one of a deployed component's encryption keys, and
+re-apply the resources that use it.
+
+The datacenter's encryptionKey hook is run again to mint the new value. The
+resources whose expressions reference the key, such as deployments that read
+it from an environment variable, are then re-applied with it. Other
+resources are left as deployed.
+
+By default the old value is retired before the new one is minted, so
+consumers briefly hold a value that no longer works. With --two-phase, the
+new value is minted alongside the old one, and the old one is only retired
+once every consumer has been re-applied, for rotation without downtime.
+
+Examples:
+  cldctl rotate secret staging/api/session-secret --dry-run
+  cldctl rotate secret staging/api/session-secret
+  cldctl rotate secret production/api/webhook-signing --two-phase`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envName, compName, keyName, err := parseRotateTarget(args[0])
+			if err != nil {
+				return err
+			}
+
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			result, err := createEngine(mgr).RotateSecret(context.Background(), engine.RotateSecretOptions{
+				Environment: envName,
+				Datacenter:  dc,
+				Component:   compName,
+				Secret:      keyName,
+				TwoPhase:    twoPhase,
+				DryRun:      dryRun,
+				Output:      os.Stdout,
+			})
+			if err != nil {
+				return err
+			}
+			if dryRun {
+				return nil
+			}
+			if !result.Success {
+				if result.Execution != nil && len(result.Execution.Errors) > 0 {
+					return fmt.Errorf("failed to rotate secret %q: %v", keyName, result.Execution.Errors[0])
+				}
+				return fmt.Errorf("failed to rotate secret %q", keyName)
+			}
+
+			fmt.Printf("[success] Rotated %s on %s/%s (%d consumers re-applied)\n", keyName, envName, compName, len(result.Consumers))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
+	cmd.Flags().BoolVar(&twoPhase, "two-phase", false, "Keep the old value valid until every consumer uses the new one")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be re-applied without rotating")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+// parseRotateTarget splits an <environment>/<component>/<key> argument.
+// Component names may themselves contain slashes (e.g. myorg/stripe), so the
+// environment is the first segment and the key the last.
+func parseRotateTarget(target string) (envName, compName, keyName string, err error) {
+	first := strings.Index(target, "/")
+	last := strings.LastIndex(target, "/")
+	if first <= 0 || last == first || last == len(target)-1 || last == first+1 {
+		return "", "", "", fmt.Errorf("invalid target %q: expected <environment>/<component>/<key>", target)
+	}
+	return target[:first], target[first+1 : last], target[last+1:], nil
+}
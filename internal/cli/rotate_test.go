@@ -0,0 +1,23 @@
+package cli
+
+import "testing"
+
+func TestParseRotateTarget(t *testing.T) {
+	tests := []struct {
+		target, env, comp, key string
+	}{
+		{"staging/api/session", "staging", "api", "session"},
+		{"staging/myorg/stripe/signing", "staging", "myorg/stripe", "signing"},
+	}
+	for _, tt := range tests {
+		env, comp, key, err := parseRotateTarget(tt.target)
+		if err != nil || env != tt.env || comp != tt.comp || key != tt.key {
+			t.Errorf("parseRotateTarget(%q) = %q, %q, %q, %v", tt.target, env, comp, key, err)
+		}
+	}
+	for _, target := range []string{"staging/api", "/api/session", "staging/api/", "staging//session"} {
+		if _, _, _, err := parseRotateTarget(target); err == nil {
+			t.Errorf("parseRotateTarget(%q) should fail", target)
+		}
+	}
+}
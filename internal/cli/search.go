@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/davidthor/cldctl/pkg/catalog"
+	"github.com/davidthor/cldctl/pkg/oci"
+	"github.com/spf13/cobra"
+)
+
+// EnvCatalogToken is a bearer token sent when fetching catalogs over HTTPS.
+const EnvCatalogToken = "CLDCTL_CATALOG_TOKEN"
+
+func newSearchCmd() *cobra.Command {
+	var (
+		catalogs     []string
+		entryType    string
+		outputFormat string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "search [term]",
+		Short: "Search catalogs for components and datacenters",
+		Long: `Search catalog indexes for published components and datacenters.
+
+A catalog is an index file listing the artifacts an organization publishes,
+served over HTTPS, pushed to an OCI registry with 'cldctl push catalog', or
+kept on disk. The catalogs searched are the ones passed with --catalog, or
+otherwise the active context's (see 'cldctl context set --catalog').
+
+Names, keywords, descriptions, owners, and references are matched without
+regard to case, best matches first. Without a term, every entry is listed.
+HTTPS catalogs that require auth are sent the CLDCTL_CATALOG_TOKEN
+environment variable as a bearer token.
+
+Examples:
+  cldctl search postgres
+  cldctl search --type datacenter
+  cldctl search payments --catalog https://catalog.acme.internal/index.yml
+  cldctl search payments --catalog ghcr.io/acme/catalog:latest -o json`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			term := ""
+			if len(args) == 1 {
+				term = args[0]
+			}
+
+			switch catalog.EntryType(entryType) {
+			case "", catalog.TypeComponent, catalog.TypeDatacenter:
+			default:
+				return fmt.Errorf("unknown type %q (use 'component' or 'datacenter')", entryType)
+			}
+
+			sources, err := resolveCatalogs(catalogs)
+			if err != nil {
+				return err
+			}
+
+			client := catalog.NewClient(oci.NewClient())
+			client.Token = os.Getenv(EnvCatalogToken)
+			matches, err := searchCatalogs(cmd.Context(), client, sources, term, catalog.EntryType(entryType))
+			if err != nil {
+				return err
+			}
+
+			switch outputFormat {
+			case "json":
+				return marshalJSON(matches)
+			case "yaml":
+				return marshalYAML(matches)
+			default:
+				printSearchTable(matches, len(sources) > 1)
+				return nil
+			}
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&catalogs, "catalog", nil, "Catalog to search: URL, OCI reference, or file (repeatable; overrides the context's catalogs)")
+	cmd.Flags().StringVar(&entryType, "type", "", "Only list entries of this type: component, datacenter")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, json, yaml")
+
+	return cmd
+}
+
+// resolveCatalogs returns the catalogs to search: those passed with
+// --catalog, or the active context's.
+func resolveCatalogs(flagValues []string) ([]string, error) {
+	sources := flagValues
+	if len(sources) == 0 {
+		cliCtx, _, err := activeContext()
+		if err != nil {
+			return nil, err
+		}
+		if cliCtx != nil {
+			sources = cliCtx.Catalogs
+		}
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no catalogs configured\n\n" +
+			"Specify a catalog using one of:\n" +
+			"  --catalog flag\n" +
+			"  cldctl context set <context> --catalog <url|reference>")
+	}
+
+	expanded := make([]string, len(sources))
+	for i, s := range sources {
+		expanded[i] = expandHome(s)
+	}
+	return expanded, nil
+}
+
+// searchCatalogs searches every catalog and merges the results. A catalog
+// that can't be loaded is reported and skipped, unless none can be.
+func searchCatalogs(ctx context.Context, client *catalog.Client, sources []string, term string, entryType catalog.EntryType) ([]catalog.Match, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	matches := []catalog.Match{}
+	var lastErr error
+	loaded := 0
+	for _, source := range sources {
+		idx, err := client.Load(ctx, source)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping catalog %s: %v\n", source, err)
+			lastErr = err
+			continue
+		}
+		loaded++
+		matches = append(matches, catalog.Search(idx, source, term, entryType)...)
+	}
+	if loaded == 0 {
+		return nil, lastErr
+	}
+	catalog.SortMatches(matches)
+	return matches, nil
+}
+
+func printSearchTable(matches []catalog.Match, showCatalog bool) {
+	if len(matches) == 0 {
+		fmt.Println("No matching components or datacenters found.")
+		return
+	}
+
+	if showCatalog {
+		fmt.Printf("%-24s %-11s %-44s %-24s %s\n", "NAME", "TYPE", "REFERENCE", "CATALOG", "DESCRIPTION")
+	} else {
+		fmt.Printf("%-24s %-11s %-44s %s\n", "NAME", "TYPE", "REFERENCE", "DESCRIPTION")
+	}
+	for _, m := range matches {
+		name := truncateString(m.Name, 24)
+		ref := truncateString(m.Latest(), 44)
+		desc := truncateString(valueOrDash(m.Description), 60)
+		if showCatalog {
+			fmt.Printf("%-24s %-11s %-44s %-24s %s\n", name, m.Type, ref, truncateString(m.Catalog, 24), desc)
+		} else {
+			fmt.Printf("%-24s %-11s %-44s %s\n", name, m.Type, ref, desc)
+		}
+	}
+}
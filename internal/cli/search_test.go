@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/catalog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveCatalogs(t *testing.T) {
+	withContexts(t, &contextsFile{
+		Current: "acme",
+		Contexts: map[string]*cliContext{
+			"acme":  {Catalogs: []string{"ghcr.io/acme/catalog:latest"}},
+			"empty": {},
+		},
+	})
+
+	sources, err := resolveCatalogs(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ghcr.io/acme/catalog:latest"}, sources)
+
+	sources, err = resolveCatalogs([]string{"https://catalog.example.com/index.yml"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://catalog.example.com/index.yml"}, sources, "--catalog overrides the context")
+
+	contextName = "empty"
+	_, err = resolveCatalogs(nil)
+	assert.ErrorContains(t, err, "no catalogs configured")
+}
+
+func TestContextSet_Catalogs(t *testing.T) {
+	withContexts(t, nil)
+
+	set := newContextSetCmd()
+	set.SetArgs([]string{"acme", "--catalog", "ghcr.io/acme/catalog:latest", "--catalog", "https://catalog.acme.internal/index.yml"})
+	require.NoError(t, set.Execute())
+
+	cf, err := loadContexts()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ghcr.io/acme/catalog:latest", "https://catalog.acme.internal/index.yml"}, cf.Contexts["acme"].Catalogs)
+
+	set = newContextSetCmd()
+	set.SetArgs([]string{"acme", "--catalog", ""})
+	require.NoError(t, set.Execute())
+
+	cf, err = loadContexts()
+	require.NoError(t, err)
+	assert.Empty(t, cf.Contexts["acme"].Catalogs)
+}
+
+func TestSearchCatalogs_SkipsUnreadable(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "catalog.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+version: 1
+entries:
+  - name: payments
+    type: component
+    reference: ghcr.io/acme/payments
+`), 0644))
+
+	client := catalog.NewClient(nil)
+	matches, err := searchCatalogs(context.Background(), client, []string{path, filepath.Join(dir, "missing.yml")}, "pay", "")
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "payments", matches[0].Name)
+
+	_, err = searchCatalogs(context.Background(), client, []string{filepath.Join(dir, "missing.yml")}, "pay", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,245 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/engine"
+	"github.com/davidthor/cldctl/pkg/schema/component"
+	"github.com/davidthor/cldctl/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+func newSetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set",
+		Short: "Change settings of deployed resources",
+		Long:  `Change settings of resources that are already deployed.`,
+	}
+
+	cmd.AddCommand(newSetVarCmd())
+
+	return cmd
+}
+
+func newSetVarCmd() *cobra.Command {
+	var (
+		datacenter    string
+		apply         bool
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "var <environment>/<component> KEY=VALUE...",
+		Aliases: []string{"vars", "variable"},
+		Short:   "Change a deployed component's variables",
+		Long: `Change the variables of a component deployed to an environment, without
+re-running a full deploy.
+
+The component is re-planned from the source it was last deployed from, with
+the variables it was last deployed with updated with the given values. Other
+components in the environment aren't touched. Without --apply, the plan is
+shown and nothing changes.
+With --apply, the changes are applied and the new values are recorded in
+the environment's state, so later refreshes keep them.
+
+Values set this way are overwritten by the next deploy of the component, so
+also update the environment file to keep them.
+
+Examples:
+  cldctl set var staging/api LOG_LEVEL=debug
+  cldctl set var staging/api LOG_LEVEL=debug --apply
+  cldctl set var staging/api LOG_LEVEL=info FEATURE_X=true --apply`,
+		Args:         cobra.MinimumNArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			envName, compName, err := parseSetTarget(args[0])
+			if err != nil {
+				return err
+			}
+			values, err := parseAssignments(args[1:])
+			if err != nil {
+				return err
+			}
+
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			ctx := context.Background()
+			if apply {
+				lockedCtx, unlock, err := engine.LockEnvironment(ctx, mgr, dc, envName, "set var", false, os.Stdout)
+				if err != nil {
+					return err
+				}
+				defer unlock()
+				ctx = lockedCtx
+			}
+
+			env, err := mgr.GetEnvironment(ctx, dc, envName)
+			if err != nil {
+				return fmt.Errorf("environment %q not found in datacenter %q: %w", envName, dc, err)
+			}
+			cs := env.Components[compName]
+			if cs == nil {
+				return fmt.Errorf("component %q is not deployed to environment %q", compName, envName)
+			}
+			if cs.Source == "" {
+				return fmt.Errorf("component %q has no recorded source; deploy it again to change its variables", compName)
+			}
+			if len(cs.Instances) > 0 {
+				return fmt.Errorf("component %q runs multiple instances; use 'cldctl rollout' to change them", compName)
+			}
+
+			comp, err := component.NewLoader().Load(cs.Source)
+			if err != nil {
+				return fmt.Errorf("failed to load component %q from %s: %w", compName, cs.Source, err)
+			}
+			if err := checkDeclaredVariables(comp, values); err != nil {
+				return fmt.Errorf("component %q: %w", compName, err)
+			}
+
+			vars, changed := mergeVariables(cs.Variables, values)
+			if len(changed) == 0 {
+				fmt.Printf("Component %q already has these values. Nothing to do.\n", compName)
+				return nil
+			}
+
+			deployVars := make(map[string]interface{}, len(vars))
+			for k, v := range vars {
+				deployVars[k] = v
+			}
+
+			result, err := createEngine(mgr).Deploy(ctx, engine.DeployOptions{
+				Environment: envName,
+				Datacenter:  dc,
+				Components:  map[string]string{compName: cs.Source},
+				Variables:   map[string]map[string]interface{}{compName: deployVars},
+				Output:      os.Stdout,
+				DryRun:      !apply,
+				AutoApprove: true,
+				Parallelism: defaultParallelism,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to plan variable change: %w", err)
+			}
+			printWarnings(os.Stdout, result.Warnings())
+
+			if !apply {
+				fmt.Printf("\nChanged variables: %s\n", strings.Join(changed, ", "))
+				fmt.Println("Run again with --apply to apply these changes.")
+				return nil
+			}
+
+			if !result.Success {
+				if result.Execution != nil && len(result.Execution.Errors) > 0 {
+					return fmt.Errorf("failed to apply variable change: %v", result.Execution.Errors[0])
+				}
+				return fmt.Errorf("failed to apply variable change")
+			}
+			if err := recordComponentVariables(ctx, mgr, dc, envName, compName, vars); err != nil {
+				return err
+			}
+
+			fmt.Printf("[success] Updated %s on %s/%s\n", strings.Join(changed, ", "), envName, compName)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
+	cmd.Flags().BoolVar(&apply, "apply", false, "Apply the changes instead of only showing the plan")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+// parseSetTarget splits an <environment>/<component> argument. Component
+// names may themselves contain slashes (e.g. myorg/stripe), so only the
+// first one separates the environment.
+func parseSetTarget(target string) (envName, compName string, err error) {
+	parts := strings.SplitN(target, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid target %q: expected <environment>/<component>", target)
+	}
+	return parts[0], parts[1], nil
+}
+
+// parseAssignments parses KEY=VALUE arguments. Values may contain "=".
+func parseAssignments(args []string) (map[string]string, error) {
+	values := make(map[string]string, len(args))
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid assignment %q: expected KEY=VALUE", arg)
+		}
+		values[parts[0]] = parts[1]
+	}
+	return values, nil
+}
+
+// checkDeclaredVariables returns an error naming the values that don't
+// correspond to a variable the component declares.
+func checkDeclaredVariables(comp component.Component, values map[string]string) error {
+	declared := make(map[string]bool, len(comp.Variables()))
+	for _, v := range comp.Variables() {
+		declared[v.Name()] = true
+	}
+	var unknown []string
+	for name := range values {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("no variable named %s", strings.Join(unknown, ", "))
+}
+
+// mergeVariables returns current updated with values, along with the sorted
+// names of the variables whose values changed.
+func mergeVariables(current, values map[string]string) (map[string]string, []string) {
+	merged := make(map[string]string, len(current)+len(values))
+	for k, v := range current {
+		merged[k] = v
+	}
+	var changed []string
+	for k, v := range values {
+		if old, ok := current[k]; !ok || old != v {
+			changed = append(changed, k)
+		}
+		merged[k] = v
+	}
+	sort.Strings(changed)
+	return merged, changed
+}
+
+// recordComponentVariables records the variables a component was deployed
+// with on its saved state.
+func recordComponentVariables(ctx context.Context, mgr state.Manager, dc, envName, compName string, vars map[string]string) error {
+	env, err := mgr.GetEnvironment(ctx, dc, envName)
+	if err != nil {
+		return fmt.Errorf("failed to read environment state: %w", err)
+	}
+	cs, ok := env.Components[compName]
+	if !ok {
+		return fmt.Errorf("component %q missing from environment state after deploy", compName)
+	}
+	cs.Variables = vars
+	if err := mgr.SaveEnvironment(ctx, dc, env); err != nil {
+		return fmt.Errorf("failed to record variables: %w", err)
+	}
+	return nil
+}
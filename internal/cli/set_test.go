@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func TestParseSetTarget(t *testing.T) {
+	env, comp, err := parseSetTarget("staging/myorg/stripe")
+	if err != nil || env != "staging" || comp != "myorg/stripe" {
+		t.Errorf("parseSetTarget = %q, %q, %v", env, comp, err)
+	}
+	for _, target := range []string{"staging", "/api", "staging/"} {
+		if _, _, err := parseSetTarget(target); err == nil {
+			t.Errorf("parseSetTarget(%q) should fail", target)
+		}
+	}
+}
+
+func TestParseAssignments(t *testing.T) {
+	values, err := parseAssignments([]string{"LOG_LEVEL=debug", "DSN=postgres://u:p@host/db?sslmode=disable", "EMPTY="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["LOG_LEVEL"] != "debug" || values["DSN"] != "postgres://u:p@host/db?sslmode=disable" || values["EMPTY"] != "" {
+		t.Errorf("unexpected values: %v", values)
+	}
+	for _, arg := range []string{"LOG_LEVEL", "=debug"} {
+		if _, err := parseAssignments([]string{arg}); err == nil {
+			t.Errorf("parseAssignments(%q) should fail", arg)
+		}
+	}
+}
+
+func TestMergeVariables(t *testing.T) {
+	current := map[string]string{"LOG_LEVEL": "info", "REGION": "us-east-1"}
+	merged, changed := mergeVariables(current, map[string]string{"LOG_LEVEL": "debug", "REGION": "us-east-1", "FEATURE_X": "true"})
+	if fmt.Sprint(changed) != "[FEATURE_X LOG_LEVEL]" {
+		t.Errorf("changed = %v", changed)
+	}
+	if fmt.Sprint(merged) != "map[FEATURE_X:true LOG_LEVEL:debug REGION:us-east-1]" {
+		t.Errorf("merged = %v", merged)
+	}
+	if current["LOG_LEVEL"] != "info" {
+		t.Error("expected the current variables to be left unchanged")
+	}
+}
+
+func TestRecordComponentVariables(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := createStateManagerWithConfig("local", []string{"path=" + t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.SaveEnvironment(ctx, "dc", &types.EnvironmentState{
+		Name: "staging",
+		Components: map[string]*types.ComponentState{
+			"api": {Name: "api", Variables: map[string]string{"LOG_LEVEL": "info"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := recordComponentVariables(ctx, mgr, "dc", "staging", "api", map[string]string{"LOG_LEVEL": "debug"}); err != nil {
+		t.Fatalf("recordComponentVariables failed: %v", err)
+	}
+
+	env, err := mgr.GetEnvironment(ctx, "dc", "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := env.Components["api"].Variables["LOG_LEVEL"]; got != "debug" {
+		t.Errorf("LOG_LEVEL = %q, want the recorded value to be read back", got)
+	}
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/engine/executor"
+	"github.com/spf13/viper"
+)
+
+const (
+	// ConfigKeySlowThreshold is the config key for how long resources may
+	// take to apply before they're reported as still running.
+	ConfigKeySlowThreshold = "slow_threshold"
+
+	// ConfigKeySlowWebhook is the config key for the URL that slow resource
+	// notifications are POSTed to.
+	ConfigKeySlowWebhook = "slow_webhook"
+)
+
+// slowNodeOptions reads the slow resource notification settings from the
+// CLI config.
+func slowNodeOptions() (executor.SlowNodeOptions, error) {
+	thresholds, err := parseSlowThresholds(viper.GetString(ConfigKeySlowThreshold))
+	if err != nil {
+		return executor.SlowNodeOptions{}, fmt.Errorf("invalid slow-threshold config: %w", err)
+	}
+	return executor.SlowNodeOptions{
+		Thresholds: thresholds,
+		Webhook:    viper.GetString(ConfigKeySlowWebhook),
+	}, nil
+}
+
+// parseSlowThresholds parses a comma-separated list of thresholds. A bare
+// duration applies to all resource types, and type=duration to one type.
+// "off" disables notifications, e.g. "15m,database=40m,route=off".
+func parseSlowThresholds(s string) (map[string]time.Duration, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	thresholds := make(map[string]time.Duration)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		nodeType, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			nodeType, value = "*", entry
+		}
+		nodeType = strings.TrimSpace(nodeType)
+		value = strings.TrimSpace(value)
+		if nodeType == "" {
+			return nil, fmt.Errorf("invalid threshold %q: missing resource type", entry)
+		}
+
+		if value == "off" {
+			thresholds[nodeType] = -1
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid threshold %q: expected a positive duration (e.g. 10m) or off", entry)
+		}
+		thresholds[nodeType] = d
+	}
+	return thresholds, nil
+}
+
+// validateSlowWebhook checks that a webhook is an http(s) URL.
+func validateSlowWebhook(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid slow-webhook %q: expected an http or https URL", s)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSlowThresholds(t *testing.T) {
+	thresholds, err := parseSlowThresholds("15m, database=40m,route=off")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]time.Duration{"*": 15 * time.Minute, "database": 40 * time.Minute, "route": -1}
+	if len(thresholds) != len(want) {
+		t.Fatalf("got %v, want %v", thresholds, want)
+	}
+	for k, v := range want {
+		if thresholds[k] != v {
+			t.Errorf("threshold %q = %s, want %s", k, thresholds[k], v)
+		}
+	}
+
+	if thresholds, err := parseSlowThresholds(""); err != nil || thresholds != nil {
+		t.Errorf("expected no thresholds for an empty value, got %v, %v", thresholds, err)
+	}
+
+	for _, invalid := range []string{"ten minutes", "database=", "=10m", "0s", "database=-5m"} {
+		if _, err := parseSlowThresholds(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestValidateSlowWebhook(t *testing.T) {
+	if err := validateSlowWebhook("https://hooks.example.com/cldctl"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, invalid := range []string{"hooks.example.com", "ftp://example.com", "https://"} {
+		if err := validateSlowWebhook(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
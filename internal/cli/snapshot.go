@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/davidthor/cldctl/pkg/engine"
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/schema/component"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	"github.com/davidthor/cldctl/pkg/snapshot"
+	"github.com/spf13/cobra"
+)
+
+func newSnapshotCmd() *cobra.Command {
+	var (
+		dcRef   string
+		envName string
+		golden  string
+		update  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "snapshot [component...]",
+		Short: "Snapshot the graph and plan of components for golden tests",
+		Long: `Build the dependency graph components would have in a fresh environment,
+plan its creation, and write both as deterministic JSON. Nothing is deployed
+and no state is read.
+
+Commit the snapshot as a golden file and check it in CI to assert that a
+change does not alter the infrastructure graph: with --golden, the snapshot
+is compared against the file and the command fails if they differ. Pass
+--update to accept the change and rewrite the file.
+
+Paths inside a component's directory are written relative to it, so the
+snapshot is the same on every checkout. Components and the datacenter can be
+local paths or references to artifacts in the local cache. Without
+--datacenter, implicit resources that depend on datacenter hooks (database
+users, network policies) are left out.
+
+Examples:
+  cldctl snapshot ./my-app
+  cldctl snapshot ./my-app --datacenter ./my-datacenter --golden testdata/my-app.snapshot.json
+  cldctl snapshot ./api ./web --golden testdata/catalog.snapshot.json --update`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if update && golden == "" {
+				return fmt.Errorf("--update requires --golden")
+			}
+
+			refs := args
+			if len(refs) == 0 {
+				refs = []string{"."}
+			}
+
+			var dc datacenter.Datacenter
+			if dcRef != "" {
+				dcFile, err := resolveDatacenterFile(dcRef)
+				if err != nil {
+					return fmt.Errorf("failed to resolve datacenter %q: %w", dcRef, err)
+				}
+				dc, err = datacenter.NewLoader().Load(dcFile)
+				if err != nil {
+					return fmt.Errorf("failed to load datacenter: %w", err)
+				}
+			}
+
+			loader := component.NewLoader()
+			comps := make(map[string]component.Component, len(refs))
+			opts := snapshot.Options{ComponentDirs: make(map[string]string, len(refs))}
+			for _, ref := range refs {
+				compFile, err := resolveComponentFile(ref)
+				if err != nil {
+					return fmt.Errorf("failed to resolve component %q: %w", ref, err)
+				}
+				comp, err := loader.Load(compFile)
+				if err != nil {
+					return fmt.Errorf("failed to load component %s: %w", ref, err)
+				}
+				name := deriveComponentName(ref, isLocalRef(ref))
+				if _, exists := comps[name]; exists {
+					return fmt.Errorf("two components are named %q", name)
+				}
+				comps[name] = comp
+				opts.ComponentDirs[name] = filepath.Dir(compFile)
+			}
+
+			g, err := engine.BuildGraph(envName, dcRef, dc, comps)
+			if err != nil {
+				return err
+			}
+			plan, err := planner.NewPlanner().Plan(g, nil)
+			if err != nil {
+				return fmt.Errorf("failed to create plan: %w", err)
+			}
+			data, err := snapshot.New(g, plan, opts).Marshal()
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case golden == "":
+				_, err = os.Stdout.Write(data)
+				return err
+			case update:
+				if err := snapshot.Write(golden, data); err != nil {
+					return err
+				}
+				fmt.Printf("Updated %s\n", golden)
+				return nil
+			}
+
+			if err := snapshot.Compare(golden, data); err != nil {
+				var mismatch *snapshot.MismatchError
+				if errors.As(err, &mismatch) {
+					return fmt.Errorf("%w\nRe-run with --update if the change is intended", err)
+				}
+				return err
+			}
+			fmt.Printf("Snapshot matches %s\n", golden)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&dcRef, "datacenter", "d", "", "Datacenter path or image whose hooks decide implicit resources")
+	cmd.Flags().StringVarP(&envName, "environment", "e", "snapshot", "Environment name to build the graph for")
+	cmd.Flags().StringVar(&golden, "golden", "", "Golden file to compare the snapshot against")
+	cmd.Flags().BoolVar(&update, "update", false, "Rewrite the golden file instead of comparing against it")
+
+	return cmd
+}
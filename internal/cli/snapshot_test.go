@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotCmd_Golden(t *testing.T) {
+	compDir := createTempComponent(t, `
+databases:
+  main:
+    type: postgres:^16
+deployments:
+  api:
+    image: api:latest
+    environment:
+      DATABASE_URL: ${{ databases.main.url }}
+`)
+	golden := filepath.Join(t.TempDir(), "testdata", "app.snapshot.json")
+
+	run := func(args ...string) error {
+		cmd := newSnapshotCmd()
+		cmd.SetArgs(append([]string{compDir, "--golden", golden}, args...))
+		return cmd.Execute()
+	}
+
+	if err := run(); err == nil {
+		t.Error("expected an error before the golden file exists")
+	}
+	if err := run("--update"); err != nil {
+		t.Fatalf("--update failed: %v", err)
+	}
+	data, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("golden file not written: %v", err)
+	}
+	if strings.Contains(string(data), compDir) {
+		t.Errorf("expected component paths to be relative, got:\n%s", data)
+	}
+	if err := run(); err != nil {
+		t.Errorf("expected snapshot to match, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(compDir, "cld.yml"), []byte(`
+databases:
+  main:
+    type: postgres:^17
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("expected a mismatch after changing the component, got %v", err)
+	}
+}
+
+func TestSnapshotCmd_UpdateRequiresGolden(t *testing.T) {
+	cmd := newSnapshotCmd()
+	cmd.SetArgs([]string{"--update"})
+	if err := cmd.Execute(); err == nil {
+		t.Error("expected --update without --golden to fail")
+	}
+}
@@ -1,16 +1,21 @@
 package cli
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"strings"
 
+	"github.com/davidthor/cldctl/pkg/engine"
 	"github.com/davidthor/cldctl/pkg/state"
 	"github.com/davidthor/cldctl/pkg/state/backend"
+	"github.com/davidthor/cldctl/pkg/state/types"
+	"github.com/spf13/cobra"
 )
 
 // Environment variable names for state backend configuration.
 const (
-	// EnvStateBackend sets the state backend type (local, s3, gcs, azurerm).
+	// EnvStateBackend sets the state backend type (local, s3, gcs, azurerm, postgres).
 	EnvStateBackend = "CLDCTL_STATE_BACKEND"
 
 	// EnvStatePrefix is the prefix for backend-specific config environment variables.
@@ -24,14 +29,37 @@ const (
 // Configuration precedence (highest to lowest):
 //  1. CLI flags (--backend, --backend-config)
 //  2. Environment variables (CLDCTL_STATE_BACKEND, CLDCTL_STATE_*)
-//  3. Hardcoded defaults (local backend with ~/.cldctl/state)
+//  3. The active context (--context, CLDCTL_CONTEXT, or the current context)
+//  4. Hardcoded defaults (local backend with ~/.cldctl/state)
+//
+// Backend config from a lower level is dropped when a higher level selects a
+// different backend type, so e.g. a context's S3 bucket never leaks into a
+// local backend chosen with --backend. The state encryption key applies to
+// every backend, so it's kept.
+//
+// When a tenant is selected (see activeTenant), the manager is scoped to it.
 func createStateManagerWithConfig(backendType string, backendConfig []string) (state.Manager, error) {
 	// Start with hardcoded default
 	effectiveBackend := "local"
 	effectiveConfig := make(map[string]string)
 
+	// Apply the active context
+	cliCtx, _, err := activeContext()
+	if err != nil {
+		return nil, err
+	}
+	if cliCtx != nil && cliCtx.Backend != "" {
+		effectiveBackend = cliCtx.Backend
+		for k, v := range cliCtx.BackendConfig {
+			effectiveConfig[k] = v
+		}
+	}
+
 	// Apply environment variables
 	if envBackend := os.Getenv(EnvStateBackend); envBackend != "" {
+		if envBackend != effectiveBackend {
+			effectiveConfig = resetBackendConfig(effectiveConfig)
+		}
 		effectiveBackend = envBackend
 	}
 
@@ -49,6 +77,9 @@ func createStateManagerWithConfig(backendType string, backendConfig []string) (s
 
 	// Apply CLI flags (highest priority)
 	if backendType != "" {
+		if backendType != effectiveBackend {
+			effectiveConfig = resetBackendConfig(effectiveConfig)
+		}
 		effectiveBackend = backendType
 	}
 
@@ -64,5 +95,159 @@ func createStateManagerWithConfig(backendType string, backendConfig []string) (s
 		Config: effectiveConfig,
 	}
 
-	return state.NewManagerFromConfig(config)
+	mgr, err := state.NewManagerFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Scope the manager to the selected tenant
+	tenant, err := activeTenant()
+	if err != nil {
+		return nil, err
+	}
+	if tenant != "" {
+		mgr = state.WithTenant(mgr, tenant, currentIdentity())
+	}
+	return mgr, nil
+}
+
+// resetBackendConfig returns the config to use when a different backend type
+// is selected: none of it but the state encryption key.
+func resetBackendConfig(config map[string]string) map[string]string {
+	reset := make(map[string]string)
+	if key, ok := config[state.EncryptionKeyConfig]; ok {
+		reset[state.EncryptionKeyConfig] = key
+	}
+	return reset
+}
+
+func newStateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Modify recorded state",
+		Long:  `Modify how cldctl manages resources recorded in an environment's state.`,
+	}
+
+	cmd.AddCommand(newStatePauseCmd(true))
+	cmd.AddCommand(newStatePauseCmd(false))
+
+	return cmd
+}
+
+// newStatePauseCmd returns the pause command, or the unpause command when
+// pause is false.
+func newStatePauseCmd(pause bool) *cobra.Command {
+	var (
+		datacenter    string
+		reason        string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "pause <environment>/<component>/<resource>",
+		Short: "Stop deploys from changing a resource",
+		Long: `Mark a deployed resource as paused, so that deploys leave it alone until it
+is unpaused. Use it to change a resource by hand, for example during an
+incident, without the next deploy reverting the change.
+
+Paused resources are planned as unchanged, even if their configuration
+changed or they were removed from the component. They are still destroyed
+along with their environment or component.
+
+Resources can be qualified with their type if the name is ambiguous.
+
+Examples:
+  cldctl state pause production/api/deployment/api --reason "INC-123 scaled by hand"
+  cldctl state unpause production/api/deployment/api`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			res, err := setResourcePaused(context.Background(), mgr, dc, args[0], pause, reason)
+			if err != nil {
+				return err
+			}
+			if pause {
+				fmt.Printf("Paused %s/%s. Deploys will leave it unchanged until it's unpaused.\n", res.Type, res.Name)
+			} else {
+				fmt.Printf("Unpaused %s/%s. The next deploy will apply its configuration.\n", res.Type, res.Name)
+			}
+			return nil
+		},
+	}
+	if !pause {
+		cmd.Use = "unpause <environment>/<component>/<resource>"
+		cmd.Short = "Let deploys change a paused resource again"
+		cmd.Long = `Unpause a resource paused with 'cldctl state pause', so that the next
+deploy applies its configuration again, reverting changes made by hand.
+
+Example:
+  cldctl state unpause production/api/deployment/api`
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
+	if pause {
+		cmd.Flags().StringVar(&reason, "reason", "", "Why the resource is paused, shown by inspect")
+	}
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+// setResourcePaused pauses or unpauses the resource at an
+// <environment>/<component>/<resource> path and saves the environment.
+func setResourcePaused(ctx context.Context, mgr state.Manager, dc, path string, pause bool, reason string) (*types.ResourceState, error) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 3 {
+		return nil, fmt.Errorf("invalid path %q: expected <environment>/<component>/<resource>", path)
+	}
+	envName := parts[0]
+	ctx, unlock, err := engine.LockEnvironment(ctx, mgr, dc, envName, "pause", false, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	env, err := mgr.GetEnvironment(ctx, dc, envName)
+	if err != nil {
+		return nil, fmt.Errorf("environment %q not found in datacenter %q: %w", envName, dc, err)
+	}
+	compName, resourceParts, err := resolveInspectPath(parts[1:], env.Components, envName)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *types.ResourceState
+	switch len(resourceParts) {
+	case 1:
+		res, err = findResource(env.Components[compName].Resources, resourceParts[0], "")
+	case 2:
+		res, err = findResource(env.Components[compName].Resources, resourceParts[1], resourceParts[0])
+	default:
+		return nil, fmt.Errorf("invalid path %q: expected a resource after the component name", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res.Paused = pause
+	res.PauseReason = ""
+	if pause {
+		res.PauseReason = reason
+	}
+	if err := mgr.SaveEnvironment(ctx, dc, env); err != nil {
+		return nil, fmt.Errorf("failed to save environment state: %w", err)
+	}
+	return res, nil
 }
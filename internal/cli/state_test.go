@@ -1,10 +1,12 @@
 package cli
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/davidthor/cldctl/pkg/state/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -88,3 +90,38 @@ func TestCreateStateManagerWithConfig_CLIOverridesEnvVar(t *testing.T) {
 	_, err = os.Stat(cliPath)
 	assert.NoError(t, err)
 }
+
+func TestSetResourcePaused(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := createStateManagerWithConfig("local", []string{"path=" + t.TempDir()})
+	require.NoError(t, err)
+	require.NoError(t, mgr.SaveEnvironment(ctx, "dc", &types.EnvironmentState{
+		Name: "prod",
+		Components: map[string]*types.ComponentState{
+			"myorg/api": {Name: "myorg/api", Resources: map[string]*types.ResourceState{
+				"deployment.api": {Name: "api", Type: "deployment", Component: "myorg/api"},
+				"service.api":    {Name: "api", Type: "service", Component: "myorg/api"},
+			}},
+		},
+	}))
+
+	_, err = setResourcePaused(ctx, mgr, "dc", "prod/myorg/api/deployment/api", true, "INC-123")
+	require.NoError(t, err)
+	env, err := mgr.GetEnvironment(ctx, "dc", "prod")
+	require.NoError(t, err)
+	res := env.Components["myorg/api"].Resources["deployment.api"]
+	assert.True(t, res.Paused)
+	assert.Equal(t, "INC-123", res.PauseReason)
+	assert.False(t, env.Components["myorg/api"].Resources["service.api"].Paused, "only the named resource should be paused")
+
+	_, err = setResourcePaused(ctx, mgr, "dc", "prod/myorg/api/deployment/api", false, "")
+	require.NoError(t, err)
+	env, err = mgr.GetEnvironment(ctx, "dc", "prod")
+	require.NoError(t, err)
+	res = env.Components["myorg/api"].Resources["deployment.api"]
+	assert.False(t, res.Paused)
+	assert.Empty(t, res.PauseReason)
+
+	_, err = setResourcePaused(ctx, mgr, "dc", "prod/myorg/api", true, "")
+	assert.Error(t, err, "a path without a resource should be rejected")
+}
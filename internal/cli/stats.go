@@ -0,0 +1,269 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/state/types"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// deployStats reports how long resources in a datacenter took to apply.
+type deployStats struct {
+	Datacenter string           `json:"datacenter" yaml:"datacenter"`
+	Since      *time.Time       `json:"since,omitempty" yaml:"since,omitempty"`
+	Types      []typeStats      `json:"types" yaml:"types"`
+	Resources  []resourceTiming `json:"resources" yaml:"resources"`
+}
+
+// typeStats aggregates the apply times of one resource type and action.
+type typeStats struct {
+	Type       string  `json:"type" yaml:"type"`
+	Action     string  `json:"action" yaml:"action"`
+	Count      int     `json:"count" yaml:"count"`
+	P50Seconds float64 `json:"p50Seconds" yaml:"p50Seconds"`
+	P95Seconds float64 `json:"p95Seconds" yaml:"p95Seconds"`
+	MaxSeconds float64 `json:"maxSeconds" yaml:"maxSeconds"`
+}
+
+// resourceTiming is the last apply of a single resource.
+type resourceTiming struct {
+	Environment     string    `json:"environment" yaml:"environment"`
+	Component       string    `json:"component" yaml:"component"`
+	Instance        string    `json:"instance,omitempty" yaml:"instance,omitempty"`
+	Type            string    `json:"type" yaml:"type"`
+	Name            string    `json:"name" yaml:"name"`
+	Action          string    `json:"action" yaml:"action"`
+	StartedAt       time.Time `json:"startedAt" yaml:"startedAt"`
+	EndedAt         time.Time `json:"endedAt" yaml:"endedAt"`
+	DurationSeconds float64   `json:"durationSeconds" yaml:"durationSeconds"`
+}
+
+func newStatsCmd() *cobra.Command {
+	var (
+		since         string
+		outputFormat  string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "stats [datacenter]",
+		Short: "Report how long resources take to deploy",
+		Long: `Report the p50, p95, and maximum time resources took to apply across the
+environments of a datacenter, by resource type and action (create, update,
+or replace). Each resource contributes the timing of its last successful
+apply, as recorded in state.
+
+Use it to track deploy-time SLOs and to spot regressions after changing a
+datacenter or its modules: --since limits the report to applies that
+finished recently. With -o json or -o yaml, the timing of every resource is
+included as well.
+
+The datacenter is resolved from the argument, CLDCTL_DATACENTER environment
+variable, or the default datacenter set in config.
+
+Examples:
+  cldctl stats
+  cldctl stats my-datacenter --since 7d
+  cldctl stats my-datacenter -o json`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			var sinceTime *time.Time
+			if since != "" {
+				age, err := parseAge(since)
+				if err != nil {
+					return fmt.Errorf("invalid --since: %w", err)
+				}
+				t := time.Now().Add(-age)
+				sinceTime = &t
+			}
+
+			dcName := ""
+			if len(args) > 0 {
+				dcName = args[0]
+			}
+			dc, err := resolveDatacenter(dcName)
+			if err != nil {
+				return err
+			}
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			envRefs, err := mgr.ListEnvironments(ctx, dc)
+			if err != nil {
+				return fmt.Errorf("failed to list environments: %w", err)
+			}
+
+			var timings []resourceTiming
+			for _, ref := range envRefs {
+				env, err := mgr.GetEnvironment(ctx, dc, ref.Name)
+				if err != nil {
+					return fmt.Errorf("failed to get environment %s: %w", ref.Name, err)
+				}
+				timings = append(timings, collectResourceTimings(env, sinceTime)...)
+			}
+
+			stats := deployStats{
+				Datacenter: dc,
+				Since:      sinceTime,
+				Types:      aggregateTimings(timings),
+				Resources:  timings,
+			}
+
+			switch outputFormat {
+			case "json":
+				data, err := json.MarshalIndent(stats, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				fmt.Println(string(data))
+			case "yaml":
+				data, err := yaml.Marshal(stats)
+				if err != nil {
+					return fmt.Errorf("failed to marshal YAML: %w", err)
+				}
+				fmt.Println(string(data))
+			default:
+				printDeployStats(stats)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "", "Only include applies that finished within this long (e.g. 12h, 14d, 4w)")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, json, yaml")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+// collectResourceTimings returns the recorded apply timings of an
+// environment's resources, sorted by component, type, and name. Applies that
+// finished before since are left out.
+func collectResourceTimings(env *types.EnvironmentState, since *time.Time) []resourceTiming {
+	var timings []resourceTiming
+	add := func(compName, instance string, resources map[string]*types.ResourceState) {
+		for _, res := range resources {
+			t := res.Timing
+			if t == nil || (since != nil && t.EndedAt.Before(*since)) {
+				continue
+			}
+			timings = append(timings, resourceTiming{
+				Environment:     env.Name,
+				Component:       compName,
+				Instance:        instance,
+				Type:            res.Type,
+				Name:            res.Name,
+				Action:          t.Action,
+				StartedAt:       t.StartedAt,
+				EndedAt:         t.EndedAt,
+				DurationSeconds: float64(t.DurationMS) / 1000,
+			})
+		}
+	}
+	for compName, comp := range env.Components {
+		add(compName, "", comp.Resources)
+		for instName, inst := range comp.Instances {
+			add(compName, instName, inst.Resources)
+		}
+	}
+
+	sort.Slice(timings, func(i, j int) bool {
+		a, b := timings[i], timings[j]
+		if a.Component != b.Component {
+			return a.Component < b.Component
+		}
+		if a.Instance != b.Instance {
+			return a.Instance < b.Instance
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Name < b.Name
+	})
+	return timings
+}
+
+// aggregateTimings computes per type and action percentiles, sorted by type
+// and action.
+func aggregateTimings(timings []resourceTiming) []typeStats {
+	durations := make(map[[2]string][]float64)
+	for _, t := range timings {
+		key := [2]string{t.Type, t.Action}
+		durations[key] = append(durations[key], t.DurationSeconds)
+	}
+
+	stats := make([]typeStats, 0, len(durations))
+	for key, d := range durations {
+		sort.Float64s(d)
+		stats = append(stats, typeStats{
+			Type:       key[0],
+			Action:     key[1],
+			Count:      len(d),
+			P50Seconds: percentile(d, 50),
+			P95Seconds: percentile(d, 95),
+			MaxSeconds: d[len(d)-1],
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Type != stats[j].Type {
+			return stats[i].Type < stats[j].Type
+		}
+		return stats[i].Action < stats[j].Action
+	})
+	return stats
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func printDeployStats(stats deployStats) {
+	fmt.Printf("Datacenter: %s\n", stats.Datacenter)
+	if stats.Since != nil {
+		fmt.Printf("Since:      %s\n", stats.Since.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Println()
+
+	if len(stats.Types) == 0 {
+		fmt.Println("No resource timings recorded. Timings are recorded when resources are deployed.")
+		return
+	}
+
+	fmt.Printf("%-18s %-8s %-6s %-10s %-10s %s\n", "TYPE", "ACTION", "COUNT", "P50", "P95", "MAX")
+	for _, s := range stats.Types {
+		fmt.Printf("%-18s %-8s %-6d %-10s %-10s %s\n",
+			s.Type, s.Action, s.Count,
+			formatStatSeconds(s.P50Seconds), formatStatSeconds(s.P95Seconds), formatStatSeconds(s.MaxSeconds))
+	}
+}
+
+// formatStatSeconds renders seconds as a duration, to the second once it's
+// at least a second long.
+func formatStatSeconds(seconds float64) string {
+	d := time.Duration(seconds * float64(time.Second))
+	if d >= time.Second {
+		return d.Round(time.Second).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
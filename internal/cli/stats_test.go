@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func timedResource(typ, name, action string, ended time.Time, d time.Duration) *types.ResourceState {
+	return &types.ResourceState{
+		Name: name,
+		Type: typ,
+		Timing: &types.ResourceTiming{
+			Action:     action,
+			StartedAt:  ended.Add(-d),
+			EndedAt:    ended,
+			DurationMS: d.Milliseconds(),
+		},
+	}
+}
+
+func TestCollectResourceTimings(t *testing.T) {
+	now := time.Now()
+	env := &types.EnvironmentState{
+		Name: "staging",
+		Components: map[string]*types.ComponentState{
+			"app": {
+				Resources: map[string]*types.ResourceState{
+					"database.main":  timedResource("database", "main", "create", now, 12*time.Minute),
+					"deployment.api": timedResource("deployment", "api", "update", now.Add(-30*24*time.Hour), 40*time.Second),
+					"route.main":     {Name: "main", Type: "route"},
+				},
+				Instances: map[string]*types.InstanceState{
+					"canary": {Resources: map[string]*types.ResourceState{
+						"deployment.api": timedResource("deployment", "api", "create", now, 50*time.Second),
+					}},
+				},
+			},
+		},
+	}
+
+	timings := collectResourceTimings(env, nil)
+	if len(timings) != 3 {
+		t.Fatalf("expected 3 timings, got %+v", timings)
+	}
+	if timings[0].Type != "database" || timings[0].DurationSeconds != 720 || timings[0].Environment != "staging" {
+		t.Errorf("unexpected first timing: %+v", timings[0])
+	}
+	if timings[2].Instance != "canary" {
+		t.Errorf("expected instance timings to sort last, got %+v", timings[2])
+	}
+
+	since := now.Add(-7 * 24 * time.Hour)
+	if timings := collectResourceTimings(env, &since); len(timings) != 2 {
+		t.Errorf("expected old applies to be left out, got %+v", timings)
+	}
+}
+
+func TestAggregateTimings(t *testing.T) {
+	var timings []resourceTiming
+	for i := 1; i <= 20; i++ {
+		timings = append(timings, resourceTiming{Type: "database", Action: "create", DurationSeconds: float64(i * 60)})
+	}
+	timings = append(timings, resourceTiming{Type: "database", Action: "update", DurationSeconds: 5})
+
+	stats := aggregateTimings(timings)
+	if len(stats) != 2 {
+		t.Fatalf("expected stats per type and action, got %+v", stats)
+	}
+	create := stats[0]
+	if create.Action != "create" || create.Count != 20 || create.P50Seconds != 600 || create.P95Seconds != 1140 || create.MaxSeconds != 1200 {
+		t.Errorf("unexpected create stats: %+v", create)
+	}
+	update := stats[1]
+	if update.Count != 1 || update.P50Seconds != 5 || update.P95Seconds != 5 {
+		t.Errorf("unexpected update stats: %+v", update)
+	}
+}
+
+func TestFormatStatSeconds(t *testing.T) {
+	if got := formatStatSeconds(754.4); got != "12m34s" {
+		t.Errorf("formatStatSeconds(754.4) = %q", got)
+	}
+	if got := formatStatSeconds(0.25); got != "250ms" {
+		t.Errorf("formatStatSeconds(0.25) = %q", got)
+	}
+}
@@ -0,0 +1,377 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/state/backend"
+	"github.com/davidthor/cldctl/pkg/state/types"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// EnvTenant selects the tenant commands act within, overriding the
+	// active context's tenant.
+	EnvTenant = "CLDCTL_TENANT"
+
+	// EnvUser is the identity checked against tenant members. It defaults
+	// to the OS user name.
+	EnvUser = "CLDCTL_USER"
+)
+
+// tenantName is the value of the global --tenant flag.
+var tenantName string
+
+// activeTenant returns the tenant selected by --tenant, CLDCTL_TENANT, or
+// the active context, in that order, or "" when commands act on the whole
+// datacenter.
+func activeTenant() (string, error) {
+	if tenantName != "" {
+		return tenantName, nil
+	}
+	if name := os.Getenv(EnvTenant); name != "" {
+		return name, nil
+	}
+	cliCtx, _, err := activeContext()
+	if err != nil {
+		return "", err
+	}
+	if cliCtx != nil {
+		return cliCtx.Tenant, nil
+	}
+	return "", nil
+}
+
+// currentIdentity returns the identity checked against tenant members.
+func currentIdentity() string {
+	if id := os.Getenv(EnvUser); id != "" {
+		return id
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "unknown"
+}
+
+func newTenantCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tenant",
+		Short: "Manage tenants sharing a datacenter",
+		Long: `Manage tenants: teams sharing one datacenter.
+
+Each tenant owns the environments whose names start with its prefix. When a
+tenant is selected with --tenant, CLDCTL_TENANT, or 'cldctl context set
+--tenant', commands only see and change that tenant's environments, new
+environments must use its prefix and fit within its quota, and datacenter
+state is read-only.
+
+Members are identities (CLDCTL_USER, or the OS user name) with a role:
+viewers can read the tenant's environments, and deployers can also create,
+deploy to, and destroy them. A tenant without members lets anyone deploy.
+
+Tenants are enforced by cldctl. To stop teams from bypassing them, also give
+each team backend credentials limited to its environments' state
+(datacenters/<dc>/environments/<prefix>*).`,
+	}
+
+	cmd.AddCommand(newTenantListCmd())
+	cmd.AddCommand(newTenantSetCmd())
+	cmd.AddCommand(newTenantDeleteCmd())
+
+	return cmd
+}
+
+func newTenantListCmd() *cobra.Command {
+	var (
+		datacenter    string
+		outputFormat  string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List a datacenter's tenants",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			tenants, err := mgr.ListTenants(ctx, dc)
+			if err != nil {
+				return fmt.Errorf("failed to list tenants: %w", err)
+			}
+			sort.Slice(tenants, func(i, j int) bool { return tenants[i].Name < tenants[j].Name })
+
+			envRefs, err := mgr.ListEnvironments(ctx, dc)
+			if err != nil {
+				return fmt.Errorf("failed to list environments: %w", err)
+			}
+
+			switch outputFormat {
+			case "json":
+				return marshalJSON(tenants)
+			case "yaml":
+				return marshalYAML(tenants)
+			}
+
+			if len(tenants) == 0 {
+				fmt.Printf("No tenants defined in datacenter %q.\n", dc)
+				fmt.Println()
+				fmt.Println("Create one with: cldctl tenant set <name> --prefix <prefix>")
+				return nil
+			}
+
+			fmt.Printf("%-20s %-20s %-14s %-14s %s\n", "NAME", "PREFIX", "ENVIRONMENTS", "COMPONENTS", "MEMBERS")
+			for _, t := range tenants {
+				owned := 0
+				for _, ref := range envRefs {
+					if t.Owns(ref.Name) {
+						owned++
+					}
+				}
+				fmt.Printf("%-20s %-20s %-14s %-14s %s\n",
+					truncateString(t.Name, 20), truncateString(t.Prefix, 20),
+					quotaUsage(owned, t.Quota.MaxEnvironments), quotaUsage(-1, t.Quota.MaxComponents),
+					formatTenantMembers(t.Members))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Datacenter to use (uses default if not set)")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, json, yaml")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+func newTenantSetCmd() *cobra.Command {
+	var (
+		datacenter      string
+		prefix          string
+		maxEnvironments int
+		maxComponents   int
+		members         []string
+		removeMembers   []string
+		backendType     string
+		backendConfig   []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "set <name>",
+		Short: "Create or update a tenant",
+		Long: `Create or update a tenant in a datacenter. Only the flags you pass are
+changed. The prefix defaults to the tenant name followed by a dash, and can't
+overlap another tenant's.
+
+Quotas of 0 are unlimited. Lowering a quota below current usage doesn't affect
+existing environments and components, but nothing more can be added.
+
+Examples:
+  cldctl tenant set payments -d shared-dc
+  cldctl tenant set payments --prefix pay- --max-environments 10 --max-components 20
+  cldctl tenant set payments --member alice=deployer --member bob=viewer
+  cldctl tenant set payments --remove-member bob`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			name := args[0]
+
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+			if _, err := mgr.GetDatacenter(ctx, dc); err != nil {
+				return fmt.Errorf("datacenter %q not found: %w", dc, err)
+			}
+
+			tenant, err := mgr.GetTenant(ctx, dc, name)
+			exists := err == nil
+			if err != nil && !errors.Is(err, backend.ErrNotFound) {
+				return fmt.Errorf("failed to load tenant %q: %w", name, err)
+			}
+			if !exists {
+				tenant = &types.TenantConfig{Name: name, Prefix: name + "-", CreatedAt: time.Now()}
+			}
+
+			flags := cmd.Flags()
+			if flags.Changed("prefix") {
+				if prefix == "" {
+					return fmt.Errorf("--prefix can't be empty")
+				}
+				tenant.Prefix = prefix
+			}
+			if flags.Changed("max-environments") {
+				if maxEnvironments < 0 {
+					return fmt.Errorf("--max-environments can't be negative")
+				}
+				tenant.Quota.MaxEnvironments = maxEnvironments
+			}
+			if flags.Changed("max-components") {
+				if maxComponents < 0 {
+					return fmt.Errorf("--max-components can't be negative")
+				}
+				tenant.Quota.MaxComponents = maxComponents
+			}
+			for _, m := range members {
+				id, role, ok := strings.Cut(m, "=")
+				if !ok || id == "" {
+					return fmt.Errorf("invalid --member %q (expected identity=role)", m)
+				}
+				switch types.TenantRole(role) {
+				case types.TenantRoleViewer, types.TenantRoleDeployer:
+				default:
+					return fmt.Errorf("unknown role %q for %s (use 'viewer' or 'deployer')", role, id)
+				}
+				if tenant.Members == nil {
+					tenant.Members = map[string]types.TenantRole{}
+				}
+				tenant.Members[id] = types.TenantRole(role)
+			}
+			for _, id := range removeMembers {
+				delete(tenant.Members, id)
+			}
+
+			others, err := mgr.ListTenants(ctx, dc)
+			if err != nil {
+				return fmt.Errorf("failed to list tenants: %w", err)
+			}
+			if err := checkTenantPrefix(tenant, others); err != nil {
+				return err
+			}
+
+			tenant.UpdatedAt = time.Now()
+			if err := mgr.SaveTenant(ctx, dc, tenant); err != nil {
+				return fmt.Errorf("failed to save tenant: %w", err)
+			}
+			if exists {
+				fmt.Printf("Updated tenant %q in datacenter %q\n", name, dc)
+			} else {
+				fmt.Printf("Created tenant %q in datacenter %q (environments prefixed %q)\n", name, dc, tenant.Prefix)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Datacenter to use (uses default if not set)")
+	cmd.Flags().StringVar(&prefix, "prefix", "", "Name prefix of the tenant's environments (default \"<name>-\")")
+	cmd.Flags().IntVar(&maxEnvironments, "max-environments", 0, "Most environments the tenant can have (0 for unlimited)")
+	cmd.Flags().IntVar(&maxComponents, "max-components", 0, "Most components each environment can have (0 for unlimited)")
+	cmd.Flags().StringArrayVar(&members, "member", nil, "Member and role: identity=viewer|deployer (repeatable)")
+	cmd.Flags().StringArrayVar(&removeMembers, "remove-member", nil, "Identity to remove from the members (repeatable)")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+// checkTenantPrefix checks that a tenant's prefix doesn't overlap another
+// tenant's, which would let both see the same environments.
+func checkTenantPrefix(tenant *types.TenantConfig, others []*types.TenantConfig) error {
+	for _, o := range others {
+		if o.Name == tenant.Name {
+			continue
+		}
+		if strings.HasPrefix(tenant.Prefix, o.Prefix) || strings.HasPrefix(o.Prefix, tenant.Prefix) {
+			return fmt.Errorf("prefix %q overlaps tenant %q's prefix %q", tenant.Prefix, o.Name, o.Prefix)
+		}
+	}
+	return nil
+}
+
+func newTenantDeleteCmd() *cobra.Command {
+	var (
+		datacenter    string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "delete <name>",
+		Aliases: []string{"rm"},
+		Short:   "Delete a tenant",
+		Long: `Delete a tenant. Its environments are left in place, and can be managed
+by anyone not acting within a tenant.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			name := args[0]
+
+			dc, err := resolveDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+			if _, err := mgr.GetTenant(ctx, dc, name); err != nil {
+				return fmt.Errorf("tenant %q not found in datacenter %q", name, dc)
+			}
+			if err := mgr.DeleteTenant(ctx, dc, name); err != nil {
+				return fmt.Errorf("failed to delete tenant: %w", err)
+			}
+			fmt.Printf("Deleted tenant %q from datacenter %q\n", name, dc)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Datacenter to use (uses default if not set)")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+// quotaUsage formats usage against a quota, e.g. "3/10". A negative used
+// value shows only the limit.
+func quotaUsage(used, limit int) string {
+	switch {
+	case used < 0 && limit == 0:
+		return "unlimited"
+	case used < 0:
+		return "max " + strconv.Itoa(limit)
+	case limit == 0:
+		return strconv.Itoa(used)
+	default:
+		return fmt.Sprintf("%d/%d", used, limit)
+	}
+}
+
+func formatTenantMembers(members map[string]types.TenantRole) string {
+	if len(members) == 0 {
+		return "anyone"
+	}
+	ids := make([]string, 0, len(members))
+	for id := range members {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = id + "=" + string(members[id])
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"context"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/state/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantSet(t *testing.T) {
+	withContexts(t, nil)
+	t.Setenv(EnvStateBackend, "local")
+	t.Setenv("CLDCTL_STATE_PATH", t.TempDir())
+	ctx := context.Background()
+
+	mgr, err := createStateManagerWithConfig("", nil)
+	require.NoError(t, err)
+	require.NoError(t, mgr.SaveDatacenter(ctx, &types.DatacenterState{Name: "shared"}))
+	for _, name := range []string{"payments-prod", "search-prod"} {
+		require.NoError(t, mgr.SaveEnvironment(ctx, "shared", &types.EnvironmentState{Name: name, Datacenter: "shared"}))
+	}
+
+	set := newTenantSetCmd()
+	set.SetArgs([]string{"payments", "-d", "shared", "--max-environments", "3", "--member", "alice=deployer"})
+	require.NoError(t, set.Execute())
+
+	tenant, err := mgr.GetTenant(ctx, "shared", "payments")
+	require.NoError(t, err)
+	assert.Equal(t, "payments-", tenant.Prefix, "prefix defaults to the name")
+	assert.Equal(t, 3, tenant.Quota.MaxEnvironments)
+	assert.Equal(t, types.TenantRoleDeployer, tenant.Members["alice"])
+
+	set = newTenantSetCmd()
+	set.SetArgs([]string{"pay", "-d", "shared", "--prefix", "pay"})
+	assert.ErrorContains(t, set.Execute(), "overlaps tenant \"payments\"")
+
+	set = newTenantSetCmd()
+	set.SetArgs([]string{"payments", "-d", "shared", "--member", "bob=admin"})
+	assert.ErrorContains(t, set.Execute(), "unknown role")
+
+	// Selecting the tenant scopes the state manager to it
+	tenantName = "payments"
+	t.Setenv(EnvUser, "alice")
+	scoped, err := createStateManagerWithConfig("", nil)
+	require.NoError(t, err)
+	refs, err := scoped.ListEnvironments(ctx, "shared")
+	require.NoError(t, err)
+	require.Len(t, refs, 1)
+	assert.Equal(t, "payments-prod", refs[0].Name)
+}
+
+func TestActiveTenant_Precedence(t *testing.T) {
+	withContexts(t, &contextsFile{
+		Current:  "shared",
+		Contexts: map[string]*cliContext{"shared": {Tenant: "payments"}},
+	})
+
+	name, err := activeTenant()
+	require.NoError(t, err)
+	assert.Equal(t, "payments", name)
+
+	t.Setenv(EnvTenant, "search")
+	name, err = activeTenant()
+	require.NoError(t, err)
+	assert.Equal(t, "search", name)
+
+	tenantName = "billing"
+	name, err = activeTenant()
+	require.NoError(t, err)
+	assert.Equal(t, "billing", name)
+}
+
+func TestQuotaUsage(t *testing.T) {
+	assert.Equal(t, "3/10", quotaUsage(3, 10))
+	assert.Equal(t, "3", quotaUsage(3, 0))
+	assert.Equal(t, "max 20", quotaUsage(-1, 20))
+	assert.Equal(t, "unlimited", quotaUsage(-1, 0))
+}
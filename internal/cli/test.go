@@ -0,0 +1,599 @@
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/engine"
+	"github.com/davidthor/cldctl/pkg/engine/executor"
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/errors"
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/schema/component"
+	"github.com/davidthor/cldctl/pkg/state/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// ConfigKeyTestDatacenter is the config key for the datacenter that
+	// 'cldctl test component' deploys into.
+	ConfigKeyTestDatacenter = "test_datacenter"
+
+	// defaultTestTimeout bounds tests that don't declare a timeout.
+	defaultTestTimeout = 5 * time.Minute
+
+	// maxTestOutput is how much of a test's output is kept in memory for the
+	// report. The full output is written to --log-dir when set.
+	maxTestOutput = 64 << 10
+)
+
+// componentTestReport is the result of 'cldctl test component'.
+type componentTestReport struct {
+	Component   string       `json:"component"`
+	Environment string       `json:"environment"`
+	Datacenter  string       `json:"datacenter"`
+	Deployed    bool         `json:"deployed"`
+	DeployError string       `json:"deployError,omitempty"`
+	Tests       []testResult `json:"tests"`
+	Passed      bool         `json:"passed"`
+
+	// Warnings found while deploying the component
+	Warnings []planner.Warning `json:"warnings,omitempty"`
+
+	// DeployFailures are the resources that failed to deploy, not counting
+	// those skipped because a dependency failed
+	DeployFailures []deployFailure `json:"deployFailures,omitempty"`
+}
+
+// deployFailure is a resource that failed to deploy, with the remediation
+// its error carries (e.g. from a datacenter error hook).
+type deployFailure struct {
+	Resource     string   `json:"resource"`
+	Error        string   `json:"error"`
+	Code         string   `json:"code,omitempty"`
+	DocsURL      string   `json:"docsUrl,omitempty"`
+	Alternatives []string `json:"alternatives,omitempty"`
+}
+
+// testResult is the outcome of a single smoke test.
+type testResult struct {
+	Name            string  `json:"name"`
+	Passed          bool    `json:"passed"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	Error           string  `json:"error,omitempty"`
+	Output          string  `json:"output,omitempty"`
+	LogFile         string  `json:"logFile,omitempty"`
+}
+
+func newTestCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test",
+		Short: "Run acceptance tests",
+		Long:  `Run acceptance tests against throwaway deployments.`,
+	}
+
+	cmd.AddCommand(newTestComponentCmd())
+	cmd.AddCommand(newTestDatacenterCmd())
+
+	return cmd
+}
+
+func newTestComponentCmd() *cobra.Command {
+	var (
+		datacenter    string
+		name          string
+		variables     []string
+		varFile       string
+		keep          bool
+		logDir        string
+		reportFile    string
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "component [path]",
+		Aliases: []string{"comp"},
+		Short:   "Deploy a component to a throwaway environment and run its tests",
+		Long: `Deploy a component, with its dependencies, into a new environment on the
+test datacenter, run the smoke tests the component declares, and destroy the
+environment again: a one-command acceptance test for CI.
+
+Tests are declared in the component's tests block. Each test's command runs
+on this machine once the deploy succeeds. Its environment values may
+reference the deployed component (e.g. ${{ routes.main.url }},
+${{ outputs.api_url }}), and CLDCTL_DATACENTER, CLDCTL_ENVIRONMENT, and
+CLDCTL_COMPONENT are always set. A test passes when its command exits 0
+within its timeout (default 5m).
+
+The test datacenter is taken from --datacenter, the test-datacenter config
+key, or the default datacenter, in that order. The environment is named
+test-<component>-<random suffix> unless --name is set.
+
+The environment is destroyed whether or not the tests pass. Use --keep to
+leave it running for debugging. With --log-dir, each resource's deploy
+output and each test's output are written to that directory, and --report
+writes the results as JSON for CI systems to collect.
+
+Examples:
+  cldctl test component
+  cldctl test component ./my-app -d ci-datacenter
+  cldctl test component ./my-app --var api_key=test --log-dir ./test-logs
+  cldctl test component ./my-app --report test-results.json`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			path := "."
+			if len(args) > 0 {
+				path = args[0]
+			}
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				return fmt.Errorf("failed to resolve component path: %w", err)
+			}
+			componentDir := absPath
+			if info, err := os.Stat(absPath); err != nil {
+				return fmt.Errorf("failed to access path: %w", err)
+			} else if !info.IsDir() {
+				componentDir = filepath.Dir(absPath)
+			}
+			componentName := filepath.Base(componentDir)
+
+			dc, err := resolveTestDatacenter(datacenter)
+			if err != nil {
+				return err
+			}
+
+			cliVars := make(map[string]string)
+			if varFile != "" {
+				data, err := os.ReadFile(varFile)
+				if err != nil {
+					return fmt.Errorf("failed to read var file: %w", err)
+				}
+				if err := parseVarFile(data, cliVars); err != nil {
+					return fmt.Errorf("failed to parse var file: %w", err)
+				}
+			}
+			for _, v := range variables {
+				parts := strings.SplitN(v, "=", 2)
+				if len(parts) == 2 {
+					cliVars[parts[0]] = parts[1]
+				}
+			}
+
+			envName := name
+			if envName == "" {
+				if envName, err = testEnvName(componentName); err != nil {
+					return err
+				}
+			}
+
+			mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create state manager: %w", err)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			if _, err := mgr.GetDatacenter(ctx, dc); err != nil {
+				return fmt.Errorf("datacenter %q not found: %w", dc, err)
+			}
+			if _, err := mgr.GetEnvironment(ctx, dc, envName); err == nil {
+				return fmt.Errorf("environment %q already exists in datacenter %q; tests run in a new environment", envName, dc)
+			}
+
+			componentsMap, variablesMap, _, loadedComps, err := prepareComponentMode(ctx, absPath, envName, cliVars, dc, mgr)
+			if err != nil {
+				return err
+			}
+			tests := loadedComps[componentName].Tests()
+			sort.Slice(tests, func(i, j int) bool { return tests[i].Name() < tests[j].Name() })
+
+			fmt.Printf("Datacenter:  %s\n", dc)
+			fmt.Printf("Environment: %s\n", envName)
+			fmt.Println()
+
+			slowNodes, err := slowNodeOptions()
+			if err != nil {
+				return err
+			}
+
+			now := time.Now()
+			if err := mgr.SaveEnvironment(ctx, dc, &types.EnvironmentState{
+				Name:       envName,
+				Datacenter: dc,
+				Status:     types.EnvironmentStatusPending,
+				CreatedAt:  now,
+				UpdatedAt:  now,
+				Components: make(map[string]*types.ComponentState),
+			}); err != nil {
+				return fmt.Errorf("failed to create environment: %w", err)
+			}
+
+			// Destroy the environment however the run ends
+			if keep {
+				defer fmt.Printf("\nKept environment %s. To destroy it:\n  cldctl destroy environment %s -d %s\n", envName, envName, dc)
+			} else {
+				provisioned := true
+				defer makeCleanupFunc(&provisioned, mgr, envName, dc)()
+			}
+
+			report := &componentTestReport{
+				Component:   componentName,
+				Environment: envName,
+				Datacenter:  dc,
+			}
+
+			progress := NewProgressTable(os.Stdout)
+			result, err := createEngine(mgr).Deploy(ctx, engine.DeployOptions{
+				Environment: envName,
+				Datacenter:  dc,
+				Components:  componentsMap,
+				Variables:   variablesMap,
+				AutoApprove: true,
+				Parallelism: defaultParallelism,
+				OnPlan: func(plan *planner.Plan) {
+					populateProgressFromPlan(progress, plan)
+					progress.PrintInitial()
+					progress.StartTicker()
+				},
+				OnProgress: progressTableCallback(progress),
+				LogDir:     logDir,
+				SlowNodes:  slowNodes,
+			})
+			progress.StopTicker()
+			progress.PrintFinalSummary()
+			if result != nil {
+				report.Warnings = result.Warnings()
+				report.DeployFailures = deployFailures(result.Execution)
+				printWarnings(os.Stdout, report.Warnings)
+			}
+
+			switch {
+			case err != nil:
+				report.DeployError = err.Error()
+			case !result.Success:
+				report.DeployError = "deployment failed"
+			case ctx.Err() != nil:
+				report.DeployError = ctx.Err().Error()
+			default:
+				report.Deployed = true
+			}
+
+			if report.Deployed {
+				envState, err := mgr.GetEnvironment(ctx, dc, envName)
+				if err != nil {
+					return fmt.Errorf("failed to read environment: %w", err)
+				}
+				compState := envState.Components[componentName]
+				if compState == nil {
+					return fmt.Errorf("component %q not found in environment %q", componentName, envName)
+				}
+
+				if len(tests) > 0 {
+					fmt.Println("Tests:")
+				}
+				for _, test := range tests {
+					res := runComponentTest(ctx, test, envName, dc, compState, logDir)
+					report.Tests = append(report.Tests, res)
+					printTestResult(os.Stdout, res)
+				}
+			}
+
+			report.Passed = report.Deployed
+			failed := 0
+			for _, res := range report.Tests {
+				if !res.Passed {
+					report.Passed = false
+					failed++
+				}
+			}
+
+			if reportFile != "" {
+				data, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				if err := os.WriteFile(reportFile, data, 0644); err != nil {
+					return fmt.Errorf("failed to write report: %w", err)
+				}
+			}
+
+			switch {
+			case !report.Deployed:
+				return fmt.Errorf("deploy failed: %s", report.DeployError)
+			case failed > 0:
+				return fmt.Errorf("%d of %d tests failed", failed, len(report.Tests))
+			case len(tests) == 0:
+				fmt.Println("\nDeployed successfully. The component declares no tests.")
+			default:
+				fmt.Printf("\nAll %d tests passed.\n", len(report.Tests))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Datacenter to test on (uses test-datacenter config, then the default)")
+	cmd.Flags().StringVar(&name, "name", "", "Name of the test environment (default: test-<component>-<suffix>)")
+	cmd.Flags().StringArrayVar(&variables, "var", nil, "Set variable (key=value)")
+	cmd.Flags().StringVar(&varFile, "var-file", "", "Load variables from file")
+	cmd.Flags().BoolVar(&keep, "keep", false, "Keep the environment after the tests run")
+	cmd.Flags().StringVar(&logDir, "log-dir", "", "Write deploy and test output to this directory")
+	cmd.Flags().StringVar(&reportFile, "report", "", "Write the test results as JSON to this file")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+// resolveTestDatacenter resolves the datacenter tests deploy into: the flag,
+// then the test-datacenter config key, then the usual datacenter resolution.
+func resolveTestDatacenter(flagValue string) (string, error) {
+	if flagValue != "" {
+		return flagValue, nil
+	}
+	if configVal := viper.GetString(ConfigKeyTestDatacenter); configVal != "" {
+		return configVal, nil
+	}
+	return resolveDatacenter("")
+}
+
+// testEnvName generates a unique environment name for a test run.
+func testEnvName(componentName string) (string, error) {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to generate environment name: %w", err)
+	}
+	base := sanitizeEnvName(componentName)
+	if len(base) > 48 {
+		base = strings.TrimRight(base[:48], "-")
+	}
+	if base == "" {
+		base = "component"
+	}
+	return fmt.Sprintf("test-%s-%s", base, hex.EncodeToString(suffix)), nil
+}
+
+// progressTableCallback renders deploy progress events in the progress table.
+func progressTableCallback(progress *ProgressTable) executor.ProgressCallback {
+	return func(event executor.ProgressEvent) {
+		var status ResourceStatus
+		switch event.Status {
+		case "running":
+			status = StatusInProgress
+		case "completed":
+			status = StatusCompleted
+		case "failed":
+			status = StatusFailed
+		case "skipped":
+			status = StatusSkipped
+		default:
+			status = StatusPending
+		}
+
+		progress.SetPhase(event.NodeID, string(event.Phase))
+		if event.Error != nil {
+			progress.SetError(event.NodeID, event.Error)
+		} else {
+			progress.UpdateStatus(event.NodeID, status, event.Message)
+		}
+		if event.Logs != "" {
+			progress.SetLogs(event.NodeID, event.Logs)
+		}
+		if event.LogFile != "" {
+			progress.SetLogFile(event.NodeID, event.LogFile)
+		}
+		progress.PrintUpdate(event.NodeID)
+	}
+}
+
+// runComponentTest runs a single test's command against the deployed
+// component.
+func runComponentTest(ctx context.Context, test component.Test, envName, dc string, compState *types.ComponentState, logDir string) testResult {
+	res := testResult{Name: test.Name()}
+	start := time.Now()
+	defer func() { res.DurationSeconds = time.Since(start).Seconds() }()
+
+	timeout := defaultTestTimeout
+	if test.Timeout() != "" {
+		d, err := time.ParseDuration(test.Timeout())
+		if err != nil {
+			res.Error = fmt.Sprintf("invalid timeout %q", test.Timeout())
+			return res
+		}
+		timeout = d
+	}
+
+	env := []string{
+		"CLDCTL_DATACENTER=" + dc,
+		"CLDCTL_ENVIRONMENT=" + envName,
+		"CLDCTL_COMPONENT=" + compState.Name,
+	}
+	keys := make([]string, 0, len(test.Environment()))
+	for k := range test.Environment() {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		value, err := resolveTestExpressions(test.Environment()[k], envName, compState)
+		if err != nil {
+			res.Error = fmt.Sprintf("environment %s: %v", k, err)
+			return res
+		}
+		env = append(env, k+"="+value)
+	}
+
+	output := iac.NewTailBuffer(maxTestOutput)
+	var w io.Writer = output
+	if logDir != "" {
+		dir := filepath.Join(logDir, "tests")
+		if err := os.MkdirAll(dir, 0755); err == nil {
+			res.LogFile = filepath.Join(dir, sanitizeEnvName(test.Name())+".log")
+			if f, err := os.Create(res.LogFile); err == nil {
+				defer f.Close()
+				w = io.MultiWriter(output, f)
+			} else {
+				res.LogFile = ""
+			}
+		}
+	}
+
+	testCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	command := test.Command()
+	c := exec.CommandContext(testCtx, command[0], command[1:]...)
+	c.Env = append(os.Environ(), env...)
+	c.Stdout = w
+	c.Stderr = w
+	err := c.Run()
+
+	switch {
+	case testCtx.Err() == context.DeadlineExceeded:
+		res.Error = fmt.Sprintf("timed out after %s", timeout)
+	case err != nil:
+		res.Error = err.Error()
+	default:
+		res.Passed = true
+	}
+	if !res.Passed {
+		res.Output = output.String()
+	}
+	return res
+}
+
+// deployFailures returns the resources of an execution that failed on their
+// own, sorted by ID.
+func deployFailures(execution *executor.ExecutionResult) []deployFailure {
+	if execution == nil {
+		return nil
+	}
+	var failures []deployFailure
+	for id, res := range execution.NodeResults {
+		if res.Success || res.Error == nil || res.RootCause != "" {
+			continue
+		}
+		failure := deployFailure{Resource: id, Error: res.Error.Error()}
+		if r := errors.RemediationOf(res.Error); r != nil {
+			failure.Code = r.Code
+			failure.DocsURL = r.DocsURL
+			failure.Alternatives = r.Alternatives
+		}
+		failures = append(failures, failure)
+	}
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Resource < failures[j].Resource })
+	return failures
+}
+
+// printTestResult prints a test's outcome, with the tail of its output when
+// it failed.
+func printTestResult(w io.Writer, res testResult) {
+	duration := formatStatSeconds(res.DurationSeconds)
+	if res.Passed {
+		fmt.Fprintf(w, "  ● %s passed (%s)\n", res.Name, duration)
+		return
+	}
+	fmt.Fprintf(w, "  ✗ %s failed (%s): %s\n", res.Name, duration, res.Error)
+	if res.Output != "" {
+		lines := strings.Split(strings.TrimSpace(res.Output), "\n")
+		if len(lines) > 20 {
+			fmt.Fprintf(w, "      ... (%d lines truncated)\n", len(lines)-20)
+			lines = lines[len(lines)-20:]
+		}
+		for _, line := range lines {
+			fmt.Fprintf(w, "      %s\n", line)
+		}
+	}
+	if res.LogFile != "" {
+		fmt.Fprintf(w, "    Full output: %s\n", res.LogFile)
+	}
+}
+
+var testExpressionPattern = regexp.MustCompile(`\$\{\{\s*([A-Za-z]+)\.([A-Za-z0-9_-]+)(?:\.([A-Za-z0-9_-]+))?\s*\}\}`)
+
+// testResourceTypes maps the plural resource names used in expressions to
+// resource types in state.
+var testResourceTypes = map[string]string{
+	"databases":      "database",
+	"buckets":        "bucket",
+	"encryptionKeys": "encryptionKey",
+	"smtp":           "smtp",
+	"ports":          "port",
+	"deployments":    "deployment",
+	"functions":      "function",
+	"services":       "service",
+	"routes":         "route",
+}
+
+// resolveTestExpressions resolves ${{ }} references in a test's environment
+// value against the deployed component: environment.name, variables.<name>,
+// outputs.<name>, and <resources>.<name>.<output>.
+func resolveTestExpressions(value, envName string, compState *types.ComponentState) (string, error) {
+	var resolveErr error
+	resolved := testExpressionPattern.ReplaceAllStringFunc(value, func(expr string) string {
+		m := testExpressionPattern.FindStringSubmatch(expr)
+		kind, name, field := m[1], m[2], m[3]
+
+		var v interface{}
+		var ok bool
+		switch {
+		case kind == "environment" && name == "name" && field == "":
+			v, ok = envName, true
+		case kind == "variables" && field == "":
+			v, ok = compState.Variables[name]
+		case kind == "outputs" && field == "":
+			v, ok = compState.Outputs[name]
+		case testResourceTypes[kind] != "" && field != "":
+			if res := findTestResource(compState, testResourceTypes[kind], name); res != nil {
+				v, ok = res.Outputs[field]
+			}
+		}
+		if !ok {
+			if resolveErr == nil {
+				resolveErr = fmt.Errorf("unable to resolve %s", expr)
+			}
+			return expr
+		}
+		return formatOutputValue(v)
+	})
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+	return resolved, nil
+}
+
+// findTestResource looks up a resource of the component, falling back to
+// the first instance that has it when the component has instances.
+func findTestResource(compState *types.ComponentState, resourceType, name string) *types.ResourceState {
+	key := resourceType + "." + name
+	if res, ok := compState.Resources[key]; ok {
+		return res
+	}
+	instances := make([]string, 0, len(compState.Instances))
+	for inst := range compState.Instances {
+		instances = append(instances, inst)
+	}
+	sort.Strings(instances)
+	for _, inst := range instances {
+		if res, ok := compState.Instances[inst].Resources[key]; ok {
+			return res
+		}
+	}
+	return nil
+}
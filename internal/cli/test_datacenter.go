@@ -0,0 +1,260 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/engine"
+	"github.com/davidthor/cldctl/pkg/schema/component"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	"github.com/spf13/cobra"
+)
+
+// hookCoverageReport is the result of 'cldctl test datacenter'.
+type hookCoverageReport struct {
+	Datacenter string `json:"datacenter"`
+	Corpus     string `json:"corpus"`
+	Passed     bool   `json:"passed"`
+	*engine.HookCoverageReport
+}
+
+func newTestDatacenterCmd() *cobra.Command {
+	var (
+		corpus       string
+		envName      string
+		variables    []string
+		varFile      string
+		outputFormat string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "datacenter [path|image]",
+		Aliases: []string{"dc"},
+		Short:   "Check a datacenter's hooks against a corpus of sample components",
+		Long: `Match every resource of a directory of representative components against a
+datacenter's hooks, without deploying anything, and report which hooks fired
+and which resource combinations no hook handles: a safety net for datacenter
+refactors.
+
+Every cld.yml or cld.yaml under --corpus is loaded as a component and graphed
+on its own. Each resource is matched against the datacenter's hooks the way
+a deploy would, with the environment name from --environment and the
+datacenter variables from --var and --var-file. The report lists:
+
+  - every hook, with the resources it matched (hooks that never fire are
+    candidates for removal, or a sign the corpus is missing a case)
+  - resource types and variants (e.g. database postgres:^16) that no hook
+    handles
+  - resources an error hook rejects
+  - hook outputs the components read, or the resource type requires, that
+    the matching hook doesn't declare
+
+The command exits non-zero when any resource is uncovered or any output is
+missing. Rejections by error hooks are reported but don't fail the check.
+When clauses that can only be evaluated at deploy time are assumed to match.
+
+Examples:
+  cldctl test datacenter ./my-datacenter --corpus ./examples/
+  cldctl test datacenter ./my-datacenter --corpus ./examples/ -e production --var region=eu-west-1
+  cldctl test datacenter ghcr.io/myorg/dc:v2.0.0 --corpus ./catalog -o json`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dcRef := "."
+			if len(args) > 0 {
+				dcRef = args[0]
+			}
+			dcFile, err := resolveDatacenterFile(dcRef)
+			if err != nil {
+				return fmt.Errorf("failed to resolve datacenter %q: %w", dcRef, err)
+			}
+			dc, err := datacenter.NewLoader().Load(dcFile)
+			if err != nil {
+				return fmt.Errorf("failed to load datacenter: %w", err)
+			}
+
+			cliVars := make(map[string]string)
+			if varFile != "" {
+				data, err := os.ReadFile(varFile)
+				if err != nil {
+					return fmt.Errorf("failed to read var file: %w", err)
+				}
+				if err := parseVarFile(data, cliVars); err != nil {
+					return fmt.Errorf("failed to parse var file: %w", err)
+				}
+			}
+			for _, v := range variables {
+				parts := strings.SplitN(v, "=", 2)
+				if len(parts) == 2 {
+					cliVars[parts[0]] = parts[1]
+				}
+			}
+
+			comps, err := loadCorpus(corpus)
+			if err != nil {
+				return err
+			}
+			if len(comps) == 0 {
+				return fmt.Errorf("no components (cld.yml) found in %s", corpus)
+			}
+
+			coverage := engine.CheckHookCoverage(engine.HookCoverageOptions{
+				Datacenter:     dc,
+				DatacenterName: dcRef,
+				Environment:    envName,
+				Variables:      cliVars,
+				Components:     comps,
+			})
+			report := hookCoverageReport{
+				Datacenter:         dcRef,
+				Corpus:             corpus,
+				Passed:             coverage.Passed(),
+				HookCoverageReport: coverage,
+			}
+
+			switch outputFormat {
+			case "json":
+				data, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				fmt.Println(string(data))
+			default:
+				printHookCoverageReport(os.Stdout, report)
+			}
+
+			if !report.Passed {
+				return fmt.Errorf("hook coverage check failed: %d uncovered resource combinations, %d problems", len(coverage.Uncovered), len(coverage.Problems))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&corpus, "corpus", "", "Directory of sample components to match against the datacenter's hooks (required)")
+	cmd.Flags().StringVarP(&envName, "environment", "e", "", "Environment name hooks see (default: hook-coverage)")
+	cmd.Flags().StringArrayVar(&variables, "var", nil, "Set datacenter variable (key=value)")
+	cmd.Flags().StringVar(&varFile, "var-file", "", "Load datacenter variables from file")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, json")
+	_ = cmd.MarkFlagRequired("corpus")
+
+	return cmd
+}
+
+// loadCorpus loads every component file under dir, keyed by the name of its
+// directory. Components in directories with the same name are keyed by
+// their path relative to dir instead.
+func loadCorpus(dir string) (map[string]component.Component, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == "cld.yml" || d.Name() == "cld.yaml" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus: %w", err)
+	}
+
+	names := make(map[string][]string)
+	for _, file := range files {
+		name := filepath.Base(filepath.Dir(file))
+		if name == "." {
+			if abs, err := filepath.Abs(file); err == nil {
+				name = filepath.Base(filepath.Dir(abs))
+			}
+		}
+		names[name] = append(names[name], file)
+	}
+
+	loader := component.NewLoader()
+	comps := make(map[string]component.Component, len(files))
+	for name, paths := range names {
+		for _, file := range paths {
+			key := name
+			if len(paths) > 1 {
+				rel, err := filepath.Rel(dir, filepath.Dir(file))
+				if err != nil {
+					rel = filepath.Dir(file)
+				}
+				key = strings.ReplaceAll(filepath.ToSlash(rel), "/", "-")
+			}
+			comp, err := loader.Load(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load component %s: %w", file, err)
+			}
+			comps[key] = comp
+		}
+	}
+	return comps, nil
+}
+
+func printHookCoverageReport(w io.Writer, report hookCoverageReport) {
+	fmt.Fprintf(w, "Datacenter: %s\n", report.Datacenter)
+	fmt.Fprintf(w, "Corpus:     %s (%d components)\n\n", report.Corpus, len(report.Components))
+
+	width := len("HOOK")
+	for _, h := range report.Hooks {
+		if len(h.Hook) > width {
+			width = len(h.Hook)
+		}
+	}
+	fmt.Fprintf(w, "%-*s  %s\n", width, "HOOK", "RESOURCES")
+	var unused []string
+	for _, h := range report.Hooks {
+		fmt.Fprintf(w, "%-*s  %d\n", width, h.Hook, len(h.Resources))
+		if len(h.Resources) == 0 {
+			unused = append(unused, h.Hook)
+		}
+	}
+
+	if len(unused) > 0 {
+		fmt.Fprintln(w, "\nHooks no sample resource fired:")
+		for _, hook := range unused {
+			fmt.Fprintf(w, "  - %s\n", hook)
+		}
+	}
+
+	printCombinations := func(title string, combinations []engine.ResourceCombination) {
+		if len(combinations) == 0 {
+			return
+		}
+		fmt.Fprintf(w, "\n%s:\n", title)
+		for _, c := range combinations {
+			label := c.Type
+			if c.Variant != "" {
+				label += " " + c.Variant
+			}
+			resources := append([]string(nil), c.Resources...)
+			sort.Strings(resources)
+			fmt.Fprintf(w, "  - %s: %s (%s)\n", label, c.Reason, strings.Join(resources, ", "))
+		}
+	}
+	printCombinations("Uncovered resources", report.Uncovered)
+	printCombinations("Rejected by error hooks", report.Rejected)
+
+	if len(report.Problems) > 0 {
+		fmt.Fprintln(w, "\nProblems:")
+		for _, p := range report.Problems {
+			fmt.Fprintf(w, "  - %s: %s\n", p.Component, p.Message)
+		}
+	}
+
+	if report.Passed {
+		fmt.Fprintln(w, "\nEvery sample resource is handled by a hook.")
+	}
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestLoadCorpus(t *testing.T) {
+	corpus := t.TempDir()
+	for _, dir := range []string{"api", "web", "team-a/worker", "team-b/worker", ".hidden/api"} {
+		path := filepath.Join(corpus, dir)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(path, "cld.yml"), []byte("deployments:\n  main:\n    image: app:latest\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	comps, err := loadCorpus(corpus)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for name := range comps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	expected := "api,team-a-worker,team-b-worker,web"
+	if got := strings.Join(names, ","); got != expected {
+		t.Errorf("expected components %s, got %s", expected, got)
+	}
+}
+
+func TestTestDatacenterCmd(t *testing.T) {
+	dcDir := createTempDatacenter(t, `
+environment {
+  database {
+    when = element(split(":", node.inputs.type), 0) == "postgres"
+    module "postgres" {
+      build = "./modules/postgres"
+    }
+    outputs = {
+      host = module.postgres.host
+      port = module.postgres.port
+      url  = module.postgres.url
+    }
+  }
+}
+`)
+	corpus := t.TempDir()
+	writeComponent := func(name, content string) {
+		if err := os.MkdirAll(filepath.Join(corpus, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(corpus, name, "cld.yml"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeComponent("api", "databases:\n  main:\n    type: postgres:^16\n")
+
+	cmd := newTestDatacenterCmd()
+	cmd.SetArgs([]string{dcDir, "--corpus", corpus})
+	if err := cmd.Execute(); err != nil {
+		t.Errorf("expected covered corpus to pass, got %v", err)
+	}
+
+	writeComponent("cache", "databases:\n  main:\n    type: redis:^7\n")
+	cmd = newTestDatacenterCmd()
+	cmd.SetArgs([]string{dcDir, "--corpus", corpus})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "1 uncovered resource combinations") {
+		t.Errorf("expected the redis database to be uncovered, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/engine/executor"
+	"github.com/davidthor/cldctl/pkg/errors"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+type fakeTest struct {
+	name    string
+	command []string
+	env     map[string]string
+	timeout string
+}
+
+func (t fakeTest) Name() string                   { return t.name }
+func (t fakeTest) Command() []string              { return t.command }
+func (t fakeTest) Environment() map[string]string { return t.env }
+func (t fakeTest) Timeout() string                { return t.timeout }
+
+func testComponentState() *types.ComponentState {
+	return &types.ComponentState{
+		Name:      "api",
+		Variables: map[string]string{"region": "us-east-1"},
+		Outputs:   map[string]interface{}{"api_url": "https://api.example.com", "ports": []interface{}{80, 443}},
+		Resources: map[string]*types.ResourceState{
+			"route.main": {Name: "main", Type: "route", Outputs: map[string]interface{}{"url": "https://main.example.com"}},
+		},
+		Instances: map[string]*types.InstanceState{
+			"canary": {Resources: map[string]*types.ResourceState{
+				"service.web": {Name: "web", Type: "service", Outputs: map[string]interface{}{"port": 8080}},
+			}},
+		},
+	}
+}
+
+func TestResolveTestExpressions(t *testing.T) {
+	compState := testComponentState()
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"${{ routes.main.url }}/health", "https://main.example.com/health"},
+		{"${{ outputs.api_url }}", "https://api.example.com"},
+		{"${{ outputs.ports }}", "[80,443]"},
+		{"${{ variables.region }}", "us-east-1"},
+		{"${{ environment.name }}", "test-api-abc123"},
+		{"localhost:${{ services.web.port }}", "localhost:8080"},
+		{"plain value", "plain value"},
+	}
+	for _, tt := range tests {
+		got, err := resolveTestExpressions(tt.value, "test-api-abc123", compState)
+		if err != nil {
+			t.Errorf("resolveTestExpressions(%q) error: %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("resolveTestExpressions(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"${{ routes.missing.url }}", "${{ outputs.missing }}", "${{ dependencies.db.outputs }}"} {
+		if _, err := resolveTestExpressions(value, "test-api-abc123", compState); err == nil {
+			t.Errorf("resolveTestExpressions(%q) should fail", value)
+		}
+	}
+}
+
+func TestDeployFailures(t *testing.T) {
+	hookErr := errors.DatacenterHookError("database", "my-app", "main", "mysql is not supported").
+		WithRemediation(&errors.Remediation{Code: "MYSQL_UNSUPPORTED", Alternatives: []string{"postgres"}})
+	failures := deployFailures(&executor.ExecutionResult{
+		NodeResults: map[string]*executor.NodeResult{
+			"my-app/database/main":  {Error: hookErr},
+			"my-app/deployment/api": {Error: fmt.Errorf("dependency my-app/database/main failed"), RootCause: "my-app/database/main"},
+			"my-app/bucket/files":   {Success: true},
+		},
+	})
+
+	if len(failures) != 1 {
+		t.Fatalf("expected 1 failure, got %+v", failures)
+	}
+	f := failures[0]
+	if f.Resource != "my-app/database/main" || f.Code != "MYSQL_UNSUPPORTED" || len(f.Alternatives) != 1 {
+		t.Errorf("unexpected failure %+v", f)
+	}
+	if !strings.Contains(f.Error, "mysql is not supported") {
+		t.Errorf("expected the error message, got %q", f.Error)
+	}
+}
+
+func TestTestEnvName(t *testing.T) {
+	name, err := testEnvName("My_App")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^test-my-app-[0-9a-f]{6}$`).MatchString(name) {
+		t.Errorf("unexpected name %q", name)
+	}
+
+	long, _ := testEnvName(strings.Repeat("a", 80))
+	if len(long) > 63 {
+		t.Errorf("name %q is longer than 63 characters", long)
+	}
+}
+
+func TestRunComponentTest(t *testing.T) {
+	compState := testComponentState()
+	logDir := t.TempDir()
+
+	res := runComponentTest(context.Background(), fakeTest{
+		name:    "health",
+		command: []string{"sh", "-c", `test "$URL" = https://main.example.com && test "$CLDCTL_ENVIRONMENT" = test-env`},
+		env:     map[string]string{"URL": "${{ routes.main.url }}"},
+	}, "test-env", "ci", compState, "")
+	if !res.Passed {
+		t.Errorf("expected test to pass, got %+v", res)
+	}
+
+	res = runComponentTest(context.Background(), fakeTest{
+		name:    "failing",
+		command: []string{"sh", "-c", "echo boom; exit 3"},
+	}, "test-env", "ci", compState, logDir)
+	if res.Passed || !strings.Contains(res.Output, "boom") {
+		t.Errorf("expected failure with output, got %+v", res)
+	}
+	if data, err := os.ReadFile(filepath.Join(logDir, "tests", "failing.log")); err != nil || !strings.Contains(string(data), "boom") {
+		t.Errorf("expected output in log file, got %q (%v)", data, err)
+	}
+
+	res = runComponentTest(context.Background(), fakeTest{
+		name:    "slow",
+		command: []string{"sleep", "5"},
+		timeout: "50ms",
+	}, "test-env", "ci", compState, "")
+	if res.Passed || !strings.Contains(res.Error, "timed out") {
+		t.Errorf("expected timeout, got %+v", res)
+	}
+
+	res = runComponentTest(context.Background(), fakeTest{
+		name:    "unresolved",
+		command: []string{"true"},
+		env:     map[string]string{"URL": "${{ routes.missing.url }}"},
+	}, "test-env", "ci", compState, "")
+	if res.Passed || !strings.Contains(res.Error, "URL") {
+		t.Errorf("expected resolution error, got %+v", res)
+	}
+}
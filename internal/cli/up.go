@@ -2,9 +2,11 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"sort"
@@ -27,6 +29,16 @@ import (
 	_ "github.com/davidthor/cldctl/pkg/logs/loki"
 )
 
+const (
+	// defaultLocalDatacenter is the datacenter up deploys and uses when none
+	// is configured.
+	defaultLocalDatacenter = "local"
+
+	// defaultLocalDatacenterImage is the image defaultLocalDatacenter is
+	// deployed from.
+	defaultLocalDatacenterImage = "davidthor/local-datacenter"
+)
+
 // resourceID generates a unique ID for a resource.
 func resourceID(component, resourceType, name string) string {
 	return fmt.Sprintf("%s/%s/%s", component, resourceType, name)
@@ -52,6 +64,10 @@ func newUpCmd() *cobra.Command {
 		port              int
 		routeSubdomains   []string
 		routePathPrefixes []string
+		allowURLChange    bool
+		takeOver          bool
+		rerunBootstrap    bool
+		logDir            string
 	)
 
 	cmd := &cobra.Command{
@@ -68,13 +84,20 @@ You can specify either a component or an environment file:
 If neither flag is provided, the command auto-detects by looking for
 cld.yml or cldenv.yml in the current directory.
 
+If no datacenter is configured, up deploys a local datacenter named "local"
+from davidthor/local-datacenter on first use (reusing it afterwards) and
+makes it the default. Unless --name is set, the environment is named after
+the current git branch, so running 'cldctl up' with no arguments in a
+repository is enough to get started.
+
 The up command:
   1. Parses your cld.yml or cldenv.yml file
   2. Creates or uses an existing environment with the specified datacenter
   3. Provisions all required resources (databases, etc.) in parallel
   4. Builds and deploys your application(s)
-  5. Watches for file changes and auto-reloads (unless --detach)
-  6. Exposes routes for local access
+  5. Runs the environment file's bootstrap tasks that haven't run yet
+  6. Watches for file changes and auto-reloads (unless --detach)
+  7. Exposes routes for local access
 
 Examples:
   # Component mode (single component)
@@ -86,7 +109,10 @@ Examples:
   cldctl up -e ./envs/dev.yml -d my-datacenter
 
   # Auto-detect mode (looks for cld.yml or cldenv.yml in CWD)
-  cldctl up -d local`,
+  cldctl up -d local
+
+  # Zero-config: local datacenter, environment named after the git branch
+  cldctl up`,
 		Args:         cobra.NoArgs,
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -101,9 +127,13 @@ Examples:
 				return err
 			}
 
-			// Resolve datacenter
+			// Resolve datacenter. With none configured, fall back to a
+			// local datacenter that's deployed on first use.
 			dc, err := resolveDatacenter(datacenter)
-			if err != nil {
+			useLocal := errors.Is(err, errNoDatacenter)
+			if useLocal {
+				dc = defaultLocalDatacenter
+			} else if err != nil {
 				return err
 			}
 
@@ -137,24 +167,31 @@ Examples:
 
 			// Verify datacenter exists
 			_, err = mgr.GetDatacenter(ctx, dc)
-			if err != nil {
+			if err != nil && useLocal {
+				err = deployLocalDatacenter(ctx, mgr)
+			} else if err != nil {
 				return fmt.Errorf("datacenter %q not found: %w\nDeploy a datacenter first with: cldctl deploy datacenter <name> <path>", dc, err)
 			}
+			if err != nil {
+				return err
+			}
 
 			// Build component/variable maps and loaded components based on mode
 			var (
 				componentsMap map[string]string
 				variablesMap  map[string]map[string]interface{}
-				envRoutesMap  map[string]map[string]engine.RouteOverride
+				envOpts       engine.DeployOptions // environment-file overrides
 				envName       string
 				loadedComps   map[string]component.Component // for progress table
+				envConfig     environment.Environment
 			)
 
 			switch mode {
 			case upModeComponent:
 				componentsMap, variablesMap, envName, loadedComps, err = prepareComponentMode(ctx, resolvedPath, name, cliVars, dc, mgr)
 			case upModeEnvironment:
-				componentsMap, variablesMap, envRoutesMap, envName, loadedComps, err = prepareEnvironmentMode(resolvedPath, name, cliVars, dc)
+				envOpts, envName, loadedComps, envConfig, err = prepareEnvironmentMode(resolvedPath, name, cliVars, dc)
+				componentsMap, variablesMap = envOpts.Components, envOpts.Variables
 			}
 			if err != nil {
 				return err
@@ -238,6 +275,7 @@ Examples:
 					status = StatusPending
 				}
 
+				progress.SetPhase(event.NodeID, string(event.Phase))
 				if event.Error != nil {
 					progress.SetError(event.NodeID, event.Error)
 				} else {
@@ -248,6 +286,9 @@ Examples:
 				if event.Logs != "" {
 					progress.SetLogs(event.NodeID, event.Logs)
 				}
+				if event.LogFile != "" {
+					progress.SetLogFile(event.NodeID, event.LogFile)
+				}
 
 				progress.PrintUpdate(event.NodeID)
 			}
@@ -255,7 +296,7 @@ Examples:
 			// Build route overrides map from environment config and/or CLI flags.
 			// Environment routes are extracted by prepareEnvironmentMode; CLI flags
 			// (component mode only) override or supplement them.
-			routesMap := envRoutesMap
+			routesMap := envOpts.Routes
 			if mode == upModeComponent && (len(routeSubdomains) > 0 || len(routePathPrefixes) > 0) {
 				routeOverrides, routeErr := parseRouteFlags(routeSubdomains, routePathPrefixes)
 				if routeErr != nil {
@@ -273,19 +314,35 @@ Examples:
 				}
 			}
 
+			slowNodes, err := slowNodeOptions()
+			if err != nil {
+				return err
+			}
+
 			// Execute deployment
 			result, err := eng.Deploy(ctx, engine.DeployOptions{
-				Environment: envName,
-				Datacenter:  dc,
-				Components:  componentsMap,
-				Variables:   variablesMap,
-				Routes:      routesMap,
-				Output:      nil, // Suppress plan summary - progress table handles display
-				DryRun:      false,
-				AutoApprove: true,
-				Parallelism: defaultParallelism,
-				OnProgress:  onProgress,
-				OnPlan:      onPlan,
+				Environment:       envName,
+				Datacenter:        dc,
+				Components:        componentsMap,
+				Variables:         variablesMap,
+				Ports:             envOpts.Ports,
+				Routes:            routesMap,
+				Replicas:          envOpts.Replicas,
+				Scaling:           envOpts.Scaling,
+				Instances:         envOpts.Instances,
+				Distinct:          envOpts.Distinct,
+				InstanceSources:   envOpts.InstanceSources,
+				InstanceVariables: envOpts.InstanceVariables,
+				Output:            nil, // Suppress plan summary - progress table handles display
+				DryRun:            false,
+				AutoApprove:       true,
+				Parallelism:       defaultParallelism,
+				OnProgress:        onProgress,
+				OnPlan:            onPlan,
+				AllowURLChange:    allowURLChange,
+				LogDir:            logDir,
+				SlowNodes:         slowNodes,
+				TakeOver:          takeOver,
 			})
 
 			// Stop the background ticker before printing the final summary
@@ -295,6 +352,9 @@ Examples:
 			// Always print the final progress summary so the user sees a clear
 			// success/failure report with resource counts and error details.
 			progress.PrintFinalSummary()
+			if result != nil {
+				printWarnings(os.Stdout, result.Warnings())
+			}
 
 			if err != nil {
 				cleanupEnvironment()
@@ -315,6 +375,13 @@ Examples:
 				return ctx.Err()
 			}
 
+			if envConfig != nil {
+				if err := runEnvironmentBootstrap(ctx, eng, dc, envName, envConfig, rerunBootstrap); err != nil {
+					cleanupEnvironment()
+					return err
+				}
+			}
+
 			// Get route URLs from all deployed components
 			routeURLs := collectRouteURLs(ctx, mgr, dc, envName, componentsMap, loadedComps, port)
 
@@ -334,6 +401,17 @@ Examples:
 
 			}
 
+			if envConfig != nil && len(envConfig.Outputs()) > 0 {
+				outputs, err := eng.ResolveEnvironmentOutputs(ctx, dc, envName, envConfig.Outputs())
+				if err != nil {
+					fmt.Printf("\nWarning: failed to resolve environment outputs: %v\n", err)
+				} else {
+					fmt.Println()
+					fmt.Println("Outputs:")
+					printEnvironmentOutputs(os.Stdout, outputs, "  ")
+				}
+			}
+
 			if detach {
 				fmt.Println()
 				fmt.Println("Running in background. To stop:")
@@ -395,13 +473,17 @@ Examples:
 	cmd.Flags().StringVarP(&componentFile, "component", "c", "", "Path to component file or directory")
 	cmd.Flags().StringVarP(&envFile, "environment", "e", "", "Path to environment file")
 	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Datacenter to use for provisioning (uses default if not set)")
-	cmd.Flags().StringVarP(&name, "name", "n", "", "Environment name (default: auto-generated)")
+	cmd.Flags().StringVarP(&name, "name", "n", "", "Environment name (default: the git branch, or <dir>-dev)")
 	cmd.Flags().StringArrayVar(&variables, "var", nil, "Set a variable (key=value)")
 	cmd.Flags().StringVar(&varFile, "var-file", "", "Load variables from a file")
 	cmd.Flags().BoolVar(&detach, "detach", false, "Run in background (don't watch for changes)")
 	cmd.Flags().IntVar(&port, "port", 0, "Override the port for local access (default: 8080)")
 	cmd.Flags().StringArrayVar(&routeSubdomains, "route-subdomain", nil, "Set route subdomain (route=subdomain, repeatable; component mode only)")
 	cmd.Flags().StringArrayVar(&routePathPrefixes, "route-path-prefix", nil, "Set route path prefix (route=/path, repeatable; component mode only)")
+	cmd.Flags().BoolVar(&allowURLChange, "allow-url-change", false, "Allow published route URLs to change")
+	cmd.Flags().BoolVar(&takeOver, "take-over", false, "Take over from a deploy of the environment that crashed, re-applying the resources it left in flight")
+	cmd.Flags().BoolVar(&rerunBootstrap, "rerun-bootstrap", false, "Run the environment file's bootstrap tasks again, including those that have already run")
+	cmd.Flags().StringVar(&logDir, "log-dir", "", "Write each resource's full build and provisioning output to this directory")
 
 	return cmd
 }
@@ -492,8 +574,7 @@ func prepareComponentMode(
 	// Determine environment name
 	envName = nameFlag
 	if envName == "" {
-		dirName := filepath.Base(absDir)
-		envName = fmt.Sprintf("%s-dev", dirName)
+		envName = defaultUpEnvName(absDir)
 	}
 
 	// Build variable map: start with component defaults, then overlay CLI vars.
@@ -572,35 +653,37 @@ func prepareComponentMode(
 }
 
 // prepareEnvironmentMode loads an environment file, resolves variables,
-// and builds the component/variable/route maps needed for engine.Deploy.
+// and builds the deploy options (components, variables, and the environment's
+// port, route, scaling, and instance overrides) needed for engine.Deploy. It
+// also returns the loaded environment, whose bootstrap tasks and outputs are
+// run and resolved once deployed.
 func prepareEnvironmentMode(
 	resolvedPath string,
 	nameFlag string,
 	cliVars map[string]string,
 	dc string,
 ) (
-	componentsMap map[string]string,
-	variablesMap map[string]map[string]interface{},
-	envRoutesMap map[string]map[string]engine.RouteOverride,
+	envOpts engine.DeployOptions,
 	envName string,
 	loadedComps map[string]component.Component,
+	envConfig environment.Environment,
 	err error,
 ) {
 	// Load the environment file
 	envLoader := environment.NewLoader()
-	envConfig, err := envLoader.Load(resolvedPath)
+	envConfig, err = envLoader.Load(resolvedPath)
 	if err != nil {
-		return nil, nil, nil, "", nil, fmt.Errorf("failed to load environment config: %w", err)
+		return engine.DeployOptions{}, "", nil, nil, fmt.Errorf("failed to load environment config: %w", err)
 	}
 
-	// Determine environment name: --name flag > config file name > directory-based default
+	// Determine environment name: --name flag > config file name > git branch
+	// or directory-based default
 	envName = nameFlag
 	if envName == "" {
 		envName = envConfig.Name()
 	}
 	if envName == "" {
-		dirName := filepath.Base(filepath.Dir(resolvedPath))
-		envName = fmt.Sprintf("%s-dev", dirName)
+		envName = defaultUpEnvName(filepath.Dir(resolvedPath))
 	}
 
 	// Load dotenv file chain from the current working directory (consistent with
@@ -609,11 +692,11 @@ func prepareEnvironmentMode(
 	envDir := filepath.Dir(resolvedPath)
 	cwd, err := os.Getwd()
 	if err != nil {
-		return nil, nil, nil, "", nil, fmt.Errorf("failed to get working directory: %w", err)
+		return engine.DeployOptions{}, "", nil, nil, fmt.Errorf("failed to get working directory: %w", err)
 	}
 	dotenvVars, err := envfile.Load(cwd, envName)
 	if err != nil {
-		return nil, nil, nil, "", nil, fmt.Errorf("failed to load .env files: %w", err)
+		return engine.DeployOptions{}, "", nil, nil, fmt.Errorf("failed to load .env files: %w", err)
 	}
 
 	// Resolve environment-level variables and substitute expressions
@@ -622,59 +705,115 @@ func prepareEnvironmentMode(
 		DotenvVars: dotenvVars,
 		EnvName:    envName,
 	}); err != nil {
-		return nil, nil, nil, "", nil, fmt.Errorf("failed to resolve environment variables: %w", err)
+		return engine.DeployOptions{}, "", nil, nil, fmt.Errorf("failed to resolve environment variables: %w", err)
 	}
 
-	// Build component, variable, and route maps from the environment config
+	// Build deploy options from the environment config. Local component
+	// paths are resolved relative to the environment file directory.
 	envComponents := envConfig.Components()
-	componentsMap = make(map[string]string, len(envComponents))
-	variablesMap = make(map[string]map[string]interface{}, len(envComponents))
-	envRoutesMap = make(map[string]map[string]engine.RouteOverride)
+	envOpts.Components = make(map[string]string, len(envComponents))
+	envOpts.Variables = make(map[string]map[string]interface{}, len(envComponents))
 	loadedComps = make(map[string]component.Component, len(envComponents))
 
 	compLoader := component.NewLoader()
 
 	for compName, compConfig := range envComponents {
-		var source string
-		if compConfig.Path() != "" {
-			// Local path: resolve relative to the environment file directory
-			source = filepath.Join(envDir, compConfig.Path())
-		} else {
-			// OCI image reference
-			source = compConfig.Image()
-		}
-
-		componentsMap[compName] = source
-		variablesMap[compName] = compConfig.Variables()
-
-		// Extract route overrides (subdomain, pathPrefix) from the environment config
-		if routeConfigs := compConfig.Routes(); len(routeConfigs) > 0 {
-			routeMap := make(map[string]engine.RouteOverride, len(routeConfigs))
-			for routeName, rc := range routeConfigs {
-				if rc.Subdomain() != "" || rc.PathPrefix() != "" {
-					routeMap[routeName] = engine.RouteOverride{
-						Subdomain:  rc.Subdomain(),
-						PathPrefix: rc.PathPrefix(),
-					}
-				}
-			}
-			if len(routeMap) > 0 {
-				envRoutesMap[compName] = routeMap
-			}
-		}
+		envOpts.ApplyEnvironmentComponent(compName, compConfig, envDir)
 
 		// Load component for the progress table
 		// For local paths, load directly; for OCI references the engine handles pulling
-		if compConfig.Path() != "" {
+		if compConfig.Path() != "" && len(compConfig.Instances()) == 0 {
+			source := envOpts.Components[compName]
 			comp, err := compLoader.Load(source)
 			if err != nil {
-				return nil, nil, nil, "", nil, fmt.Errorf("failed to load component %q from %s: %w", compName, source, err)
+				return engine.DeployOptions{}, "", nil, nil, fmt.Errorf("failed to load component %q from %s: %w", compName, source, err)
 			}
 			loadedComps[compName] = comp
 		}
 	}
 
-	return componentsMap, variablesMap, envRoutesMap, envName, loadedComps, nil
+	return envOpts, envName, loadedComps, envConfig, nil
+}
+
+// defaultUpEnvName names the environment after the current git branch of dir,
+// falling back to "<dir>-dev" outside a repository or on a detached HEAD.
+func defaultUpEnvName(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--abbrev-ref", "HEAD").Output()
+	if err == nil {
+		if name := sanitizeEnvName(strings.TrimSpace(string(out))); name != "" && name != "head" {
+			return name
+		}
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		absDir = dir
+	}
+	return fmt.Sprintf("%s-dev", filepath.Base(absDir))
+}
+
+// sanitizeEnvName turns a branch name like "feature/Login_Page" into a valid
+// environment name ("feature-login-page"): lowercase alphanumerics separated
+// by single dashes, at most 63 characters.
+func sanitizeEnvName(branch string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(branch) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	name := b.String()
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return strings.TrimRight(name, "-")
+}
+
+// deployLocalDatacenter deploys the default local datacenter for up when no
+// datacenter is configured, and makes it the default for later commands.
+func deployLocalDatacenter(ctx context.Context, mgr state.Manager) error {
+	fmt.Printf("No datacenter configured; deploying %q from %s...\n", defaultLocalDatacenter, defaultLocalDatacenterImage)
+
+	if err := ensureDatacenterImage(ctx, defaultLocalDatacenterImage); err != nil {
+		return err
+	}
+
+	dcState := &types.DatacenterState{
+		Name:      defaultLocalDatacenter,
+		Version:   defaultLocalDatacenterImage,
+		Source:    defaultLocalDatacenterImage,
+		Variables: make(map[string]string),
+		Modules:   make(map[string]*types.ModuleState),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := mgr.SaveDatacenter(ctx, dcState); err != nil {
+		return fmt.Errorf("failed to save datacenter state: %w", err)
+	}
+
+	result, err := createEngine(mgr).DeployDatacenter(ctx, engine.DeployDatacenterOptions{
+		Datacenter:  defaultLocalDatacenter,
+		Output:      os.Stdout,
+		Parallelism: defaultParallelism,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to provision datacenter %q: %w", defaultLocalDatacenter, err)
+	}
+	if !result.Success {
+		return fmt.Errorf("datacenter %q provisioning failed", defaultLocalDatacenter)
+	}
+
+	if err := setDefaultDatacenter(defaultLocalDatacenter); err != nil {
+		fmt.Printf("Warning: failed to set default datacenter in config: %v\n", err)
+	} else {
+		fmt.Printf("[config] Default datacenter set to %q\n", defaultLocalDatacenter)
+	}
+	fmt.Println()
+	return nil
 }
 
 // makeCleanupFunc creates the cleanup function used during shutdown.
@@ -2,6 +2,8 @@ package cli
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -367,3 +369,33 @@ func TestUpCmd_NameFlag(t *testing.T) {
 		t.Errorf("expected empty default for --name, got '%s'", nameFlag.DefValue)
 	}
 }
+
+func TestSanitizeEnvName(t *testing.T) {
+	tests := []struct {
+		branch string
+		want   string
+	}{
+		{"main", "main"},
+		{"feature/Login_Page", "feature-login-page"},
+		{"fix//double--dash", "fix-double-dash"},
+		{"-leading/", "leading"},
+		{"release/v1.2", "release-v1-2"},
+		{"___", ""},
+		{strings.Repeat("a", 62) + "/b", strings.Repeat("a", 62)},
+	}
+	for _, tt := range tests {
+		if got := sanitizeEnvName(tt.branch); got != tt.want {
+			t.Errorf("sanitizeEnvName(%q) = %q, want %q", tt.branch, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultUpEnvName_OutsideRepository(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "my-app")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := defaultUpEnvName(dir); got != "my-app-dev" {
+		t.Errorf("expected my-app-dev outside a git repository, got %q", got)
+	}
+}
@@ -2,8 +2,11 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/davidthor/cldctl/pkg/engine"
@@ -29,12 +32,18 @@ func newUpdateCmd() *cobra.Command {
 
 func newUpdateEnvironmentCmd() *cobra.Command {
 	var (
-		datacenter    string
-		autoApprove   bool
-		variables     []string
-		varFile       string
-		backendType   string
-		backendConfig []string
+		datacenter        string
+		autoApprove       bool
+		allowURLChange    bool
+		takeOver          bool
+		rerunBootstrap    bool
+		pinDatacenter     bool
+		unpinDatacenter   bool
+		upgradeDatacenter bool
+		variables         []string
+		varFile           string
+		backendType       string
+		backendConfig     []string
 	)
 
 	cmd := &cobra.Command{
@@ -55,15 +64,29 @@ When providing a config file, the environment's components and configuration
 will be updated to match the file. Components not in the file will be removed,
 and new components will be deployed.
 
+Pinning:
+  --pin-datacenter pins the environment to the datacenter version currently
+  deployed. Deploying a new datacenter version then leaves the environment
+  untouched, and deploys to it are refused until it is upgraded.
+  --upgrade-datacenter moves a pinned environment to the deployed datacenter
+  version and reconciles its modules and components against it.
+  --unpin-datacenter makes the environment follow the deployed datacenter again.
+
 Examples:
   cldctl update environment staging --datacenter new-dc
   cldctl update environment staging environment.yml
-  cldctl update environment staging ./envs/staging.yml --auto-approve`,
+  cldctl update environment staging ./envs/staging.yml --auto-approve
+  cldctl update environment production --pin-datacenter
+  cldctl update environment production --upgrade-datacenter`,
 		Args: cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			envName := args[0]
 			ctx := context.Background()
 
+			if pinDatacenter && unpinDatacenter {
+				return fmt.Errorf("--pin-datacenter and --unpin-datacenter cannot be used together")
+			}
+
 			// Check if a config file was provided as second argument
 			configFile := ""
 			if len(args) > 1 {
@@ -82,6 +105,28 @@ Examples:
 				return fmt.Errorf("failed to create state manager: %w", err)
 			}
 
+			// Move a pinned environment to the deployed datacenter version
+			// before anything else is planned against it
+			if upgradeDatacenter {
+				fmt.Printf("[update] Upgrading environment %q to the deployed datacenter version...\n", envName)
+				if err := createEngine(mgr).UpgradeEnvironmentDatacenter(ctx, dc, envName, os.Stdout, nil, defaultParallelism); err != nil {
+					return fmt.Errorf("failed to upgrade environment %q: %w", envName, err)
+				}
+				fmt.Printf("[success] Environment %q upgraded\n", envName)
+			}
+
+			if pinDatacenter || unpinDatacenter {
+				version, err := createEngine(mgr).PinDatacenter(ctx, dc, envName, pinDatacenter)
+				if err != nil {
+					return err
+				}
+				if pinDatacenter {
+					fmt.Printf("[success] Environment %q pinned to datacenter version %s\n", envName, version)
+				} else {
+					fmt.Printf("[success] Environment %q now follows datacenter %q\n", envName, dc)
+				}
+			}
+
 			// Get environment state
 			env, err := mgr.GetEnvironment(ctx, dc, envName)
 			if err != nil {
@@ -108,19 +153,27 @@ Examples:
 					}
 				}
 
-				return applyEnvironmentConfig(ctx, mgr, dc, env, configFile, autoApprove, cliVars)
+				return applyEnvironmentConfig(ctx, mgr, dc, env, configFile, autoApprove, allowURLChange, takeOver, rerunBootstrap, cliVars)
 			}
 
 			// Otherwise, update individual settings
 			// Note: datacenter migration is not supported through this command
 			// since environments are now nested under datacenters
-			fmt.Println("No changes specified. Provide a config file to update environment components.")
+			if !upgradeDatacenter && !pinDatacenter && !unpinDatacenter {
+				fmt.Println("No changes specified. Provide a config file to update environment components.")
+			}
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVarP(&datacenter, "datacenter", "d", "", "Target datacenter (uses default if not set)")
 	cmd.Flags().BoolVar(&autoApprove, "auto-approve", false, "Skip confirmation prompt (when using config file)")
+	cmd.Flags().BoolVar(&allowURLChange, "allow-url-change", false, "Allow published route URLs to change (e.g., after renaming a component)")
+	cmd.Flags().BoolVar(&takeOver, "take-over", false, "Take over from a deploy of the environment that crashed, re-applying the resources it left in flight")
+	cmd.Flags().BoolVar(&rerunBootstrap, "rerun-bootstrap", false, "Run the environment file's bootstrap tasks again, including those that have already run")
+	cmd.Flags().BoolVar(&pinDatacenter, "pin-datacenter", false, "Pin the environment to the datacenter version currently deployed")
+	cmd.Flags().BoolVar(&unpinDatacenter, "unpin-datacenter", false, "Make the environment follow the deployed datacenter version")
+	cmd.Flags().BoolVar(&upgradeDatacenter, "upgrade-datacenter", false, "Move a pinned environment to the deployed datacenter version and reconcile it")
 	cmd.Flags().StringArrayVar(&variables, "var", nil, "Set an environment variable (key=value)")
 	cmd.Flags().StringVar(&varFile, "var-file", "", "Load variables from a file (KEY=value format)")
 	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type")
@@ -130,7 +183,7 @@ Examples:
 }
 
 // applyEnvironmentConfig applies an environment configuration file to an existing environment.
-func applyEnvironmentConfig(ctx context.Context, mgr state.Manager, dc string, env *types.EnvironmentState, configFile string, autoApprove bool, cliVars map[string]string) error {
+func applyEnvironmentConfig(ctx context.Context, mgr state.Manager, dc string, env *types.EnvironmentState, configFile string, autoApprove, allowURLChange, takeOver, rerunBootstrap bool, cliVars map[string]string) error {
 	// Load and validate the environment file
 	loader := environment.NewLoader()
 	envConfig, err := loader.Load(configFile)
@@ -196,6 +249,18 @@ func applyEnvironmentConfig(ctx context.Context, mgr state.Manager, dc string, e
 		}
 	}
 
+	// Renaming a component removes it and deploys it again under the new
+	// name, which moves its published routes to new generated URLs
+	if renames := likelyComponentRenames(toAdd, toRemove, newComponents, existingComponents); len(renames) > 0 && !allowURLChange {
+		var b strings.Builder
+		b.WriteString("the following changes look like component renames, which change published route URLs:")
+		for _, r := range renames {
+			fmt.Fprintf(&b, "\n  - %s -> %s", r[0], r[1])
+		}
+		b.WriteString("\nset explicit route subdomains in the environment file to keep the URLs, or rerun with --allow-url-change")
+		return errors.New(b.String())
+	}
+
 	// Display execution plan
 	fmt.Println("Execution Plan:")
 	fmt.Println()
@@ -229,7 +294,11 @@ func applyEnvironmentConfig(ctx context.Context, mgr state.Manager, dc string, e
 
 	if len(toAdd) == 0 && len(toUpdate) == 0 && len(toRemove) == 0 {
 		fmt.Println("  No changes detected.")
-		return nil
+		eng := createEngine(mgr)
+		if err := runEnvironmentBootstrap(ctx, eng, dc, env.Name, envConfig, rerunBootstrap); err != nil {
+			return err
+		}
+		return applyEnvironmentOutputs(ctx, eng, dc, env, envConfig.Outputs())
 	}
 
 	fmt.Printf("Plan: %d to deploy, %d to update, %d to remove\n", len(toAdd), len(toUpdate), len(toRemove))
@@ -266,10 +335,19 @@ func applyEnvironmentConfig(ctx context.Context, mgr state.Manager, dc string, e
 		fmt.Printf("[success] %d environment module(s) reconciled\n", len(envResult.ModuleOutputs))
 	}
 
+	slowNodes, err := slowNodeOptions()
+	if err != nil {
+		return err
+	}
+
 	// Create progress callback for component deployments
 	onProgress := func(event executor.ProgressEvent) {
 		switch event.Status {
 		case "running":
+			if strings.HasPrefix(event.Message, "still running") {
+				fmt.Printf("  [%s] %s: %s\n", event.NodeType, event.NodeName, event.Message)
+				return
+			}
 			fmt.Printf("  [%s] %s (%s): provisioning...\n", event.NodeType, event.NodeName, event.NodeID)
 		case "completed":
 			fmt.Printf("  [%s] %s: ready\n", event.NodeType, event.NodeName)
@@ -306,13 +384,11 @@ func applyEnvironmentConfig(ctx context.Context, mgr state.Manager, dc string, e
 		if result.Success {
 			fmt.Printf("  Removed %d resources\n", result.Execution.Deleted)
 		}
+		printWarnings(os.Stdout, result.Warnings())
 	}
 
 	// Add/update components
 	for name, comp := range newComponents {
-		// Convert variables from map[string]interface{} to map[string]interface{}
-		vars := comp.Variables()
-
 		_, isNew := existingComponents[name]
 		if isNew {
 			fmt.Printf("[update] Updating component %q...\n", name)
@@ -321,17 +397,20 @@ func applyEnvironmentConfig(ctx context.Context, mgr state.Manager, dc string, e
 		}
 
 		// Deploy the component
-		result, err := eng.Deploy(ctx, engine.DeployOptions{
-			Environment: env.Name,
-			Datacenter:  dc,
-			Components:  map[string]string{name: componentConfigSource(comp)},
-			Variables:   map[string]map[string]interface{}{name: vars},
-			Output:      os.Stdout,
-			DryRun:      false,
-			AutoApprove: true, // Already confirmed above
-			Parallelism: defaultParallelism,
-			OnProgress:  onProgress,
-		})
+		deployOpts := engine.DeployOptions{
+			Environment:    env.Name,
+			Datacenter:     dc,
+			Output:         os.Stdout,
+			DryRun:         false,
+			AutoApprove:    true, // Already confirmed above
+			Parallelism:    defaultParallelism,
+			OnProgress:     onProgress,
+			AllowURLChange: allowURLChange,
+			SlowNodes:      slowNodes,
+			TakeOver:       takeOver,
+		}
+		deployOpts.ApplyEnvironmentComponent(name, comp, "")
+		result, err := eng.Deploy(ctx, deployOpts)
 		if err != nil {
 			fmt.Printf("  Warning: failed to deploy component %q: %v\n", name, err)
 			updateErrors = append(updateErrors, err)
@@ -341,6 +420,7 @@ func applyEnvironmentConfig(ctx context.Context, mgr state.Manager, dc string, e
 		if result.Success && result.Execution != nil {
 			fmt.Printf("  Created: %d, Updated: %d\n", result.Execution.Created, result.Execution.Updated)
 		}
+		printWarnings(os.Stdout, result.Warnings())
 	}
 
 	// Report final status
@@ -349,14 +429,71 @@ func applyEnvironmentConfig(ctx context.Context, mgr state.Manager, dc string, e
 		return fmt.Errorf("environment update completed with errors")
 	}
 
+	if err := runEnvironmentBootstrap(ctx, eng, dc, env.Name, envConfig, rerunBootstrap); err != nil {
+		return err
+	}
+
+	// Resolve the environment file's outputs now that every component is
+	// deployed, so 'cldctl output' can read them
+	if err := applyEnvironmentOutputs(ctx, eng, dc, env, envConfig.Outputs()); err != nil {
+		return err
+	}
+
 	fmt.Printf("\n[success] Environment updated successfully\n")
 
 	return nil
 }
 
+// runEnvironmentBootstrap runs the environment file's bootstrap tasks that
+// haven't run in the environment yet, or all of them with rerun.
+func runEnvironmentBootstrap(ctx context.Context, eng *engine.Engine, dc, envName string, envConfig environment.Environment, rerun bool) error {
+	tasks := envConfig.Bootstrap()
+	if len(tasks) == 0 {
+		return nil
+	}
+	fmt.Println()
+	err := eng.RunBootstrap(ctx, engine.BootstrapOptions{
+		Datacenter:  dc,
+		Environment: envName,
+		Tasks:       tasks,
+		Dir:         filepath.Dir(envConfig.SourcePath()),
+		Rerun:       rerun,
+		Output:      os.Stdout,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to bootstrap environment: %w", err)
+	}
+	return nil
+}
+
+// likelyComponentRenames pairs removed components that published routes with
+// added components deployed from the same source. Each pair is
+// [old name, new name].
+func likelyComponentRenames(toAdd, toRemove []string, newComponents map[string]environment.ComponentConfig, existingComponents map[string]*types.ComponentState) [][2]string {
+	var renames [][2]string
+	for _, oldName := range toRemove {
+		old := existingComponents[oldName]
+		if old == nil || len(old.Routes) == 0 {
+			continue
+		}
+		for _, newName := range toAdd {
+			if componentConfigSource(newComponents[newName]) == old.Source {
+				renames = append(renames, [2]string{oldName, newName})
+				break
+			}
+		}
+	}
+	sort.Slice(renames, func(i, j int) bool { return renames[i][0] < renames[j][0] })
+	return renames
+}
+
 // componentConfigSource returns the display/reference string for a component config.
-// Returns the path if set, otherwise the image reference.
+// Returns the newest instance's source when instances declare one, then the
+// path if set, otherwise the image reference.
 func componentConfigSource(comp environment.ComponentConfig) string {
+	if instances := comp.Instances(); len(instances) > 0 && instances[0].Source() != "" {
+		return instances[0].Source()
+	}
 	if comp.Path() != "" {
 		return comp.Path()
 	}
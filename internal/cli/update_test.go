@@ -2,6 +2,9 @@ package cli
 
 import (
 	"testing"
+
+	"github.com/davidthor/cldctl/pkg/schema/environment"
+	"github.com/davidthor/cldctl/pkg/state/types"
 )
 
 func TestNewUpdateCmd(t *testing.T) {
@@ -36,7 +39,7 @@ func TestUpdateEnvironmentCmd_Flags(t *testing.T) {
 	}
 
 	// Check flags
-	flags := []string{"datacenter", "auto-approve", "backend", "backend-config"}
+	flags := []string{"datacenter", "auto-approve", "allow-url-change", "backend", "backend-config"}
 	for _, flagName := range flags {
 		if cmd.Flags().Lookup(flagName) == nil {
 			t.Errorf("expected --%s flag", flagName)
@@ -53,3 +56,30 @@ func TestUpdateEnvironmentCmd_Flags(t *testing.T) {
 		t.Error("expected alias 'env'")
 	}
 }
+
+func TestLikelyComponentRenames(t *testing.T) {
+	envConfig, err := environment.NewLoader().LoadFromBytes([]byte(`
+components:
+  storefront:
+    image: ghcr.io/org/shop:v1
+  worker:
+    image: ghcr.io/org/worker:v1
+`), "environment.yml")
+	if err != nil {
+		t.Fatalf("failed to load environment: %v", err)
+	}
+
+	existing := map[string]*types.ComponentState{
+		"shop": {
+			Source: "ghcr.io/org/shop:v1",
+			Routes: map[string]*types.RouteState{"main": {Subdomain: "brave-falcon", PathPrefix: "/", Generated: true}},
+		},
+		// No published routes, so renaming it changes no URLs
+		"jobs": {Source: "ghcr.io/org/worker:v1"},
+	}
+
+	renames := likelyComponentRenames([]string{"storefront", "worker"}, []string{"jobs", "shop"}, envConfig.Components(), existing)
+	if len(renames) != 1 || renames[0] != [2]string{"shop", "storefront"} {
+		t.Errorf("expected shop -> storefront, got %v", renames)
+	}
+}
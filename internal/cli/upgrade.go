@@ -0,0 +1,215 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/selfupdate"
+	"github.com/davidthor/cldctl/pkg/state"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ConfigKeyUpgradeChannel is the viper/config key for the default release channel.
+const ConfigKeyUpgradeChannel = "upgrade_channel"
+
+func newUpgradeCmd() *cobra.Command {
+	var (
+		channelName   string
+		targetVersion string
+		checkOnly     bool
+		force         bool
+		skipSignature bool
+		backendType   string
+		backendConfig []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade cldctl to the latest release",
+		Long: `Download and install the latest cldctl release for this platform.
+
+Releases are verified before anything is replaced: the release checksums file
+must carry a valid signature from the cldctl release key, and the downloaded
+binary must match its checksum.
+
+Before switching, the new binary is asked which state schema it supports and
+compared against the schema recorded in the state backend. An upgrade that
+could not read the existing state is refused unless --force is passed.
+
+Channels:
+  stable    Full releases only (default)
+  edge      Also includes pre-releases
+
+The default channel can be set with 'cldctl config set upgrade-channel edge'.
+
+Examples:
+  cldctl upgrade
+  cldctl upgrade --check
+  cldctl upgrade --channel edge
+  cldctl upgrade --version v0.9.2`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			channel, err := resolveUpgradeChannel(channelName)
+			if err != nil {
+				return err
+			}
+
+			client := selfupdate.NewClient()
+
+			var release *selfupdate.Release
+			if targetVersion != "" {
+				release, err = client.Find(ctx, targetVersion)
+			} else {
+				release, err = client.Latest(ctx, channel)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to find release: %w", err)
+			}
+
+			if targetVersion == "" && !force {
+				if !selfupdate.IsVersion(Version) {
+					return fmt.Errorf("this is a development build (%s); pass --force to replace it with %s", Version, release.Version)
+				}
+				if selfupdate.CompareVersions(release.Version, Version) <= 0 {
+					fmt.Printf("cldctl %s is already the latest %s release.\n", Version, channel)
+					return nil
+				}
+			}
+
+			if checkOnly {
+				fmt.Printf("cldctl %s is available (you have %s).\n", release.Version, Version)
+				return nil
+			}
+
+			fmt.Printf("Downloading cldctl %s...\n", release.Version)
+			binary, err := client.FetchBinary(ctx, release, selfupdate.FetchOptions{
+				PublicKey:     ReleasePublicKey,
+				SkipSignature: skipSignature,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to fetch release: %w", err)
+			}
+			if skipSignature {
+				fmt.Println("Warning: release signature was not verified (--insecure-skip-signature)")
+			} else {
+				fmt.Println("Verified release signature and checksum.")
+			}
+
+			if err := checkUpgradeCompatibility(ctx, binary, backendType, backendConfig, force); err != nil {
+				return err
+			}
+
+			exe, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("failed to locate the running cldctl binary: %w", err)
+			}
+			if err := selfupdate.Install(exe, binary); err != nil {
+				return err
+			}
+
+			fmt.Printf("Upgraded cldctl %s → %s\n", Version, release.Version)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&channelName, "channel", "", "Release channel: stable, edge (default from config, else stable)")
+	cmd.Flags().StringVar(&targetVersion, "version", "", "Install a specific release instead of the latest")
+	cmd.Flags().BoolVar(&checkOnly, "check", false, "Only report whether an upgrade is available")
+	cmd.Flags().BoolVar(&force, "force", false, "Install even if the release is not newer or the state compatibility check fails")
+	cmd.Flags().BoolVar(&skipSignature, "insecure-skip-signature", false, "Do not verify the release signature (checksums are still verified)")
+	cmd.Flags().StringVar(&backendType, "backend", "", "State backend type (for the compatibility check)")
+	cmd.Flags().StringArrayVar(&backendConfig, "backend-config", nil, "Backend configuration (key=value)")
+
+	return cmd
+}
+
+// resolveUpgradeChannel returns the channel from the flag, falling back to
+// the upgrade_channel config value and then stable.
+func resolveUpgradeChannel(flagValue string) (selfupdate.Channel, error) {
+	if flagValue == "" {
+		flagValue = viper.GetString(ConfigKeyUpgradeChannel)
+	}
+	return selfupdate.ParseChannel(flagValue)
+}
+
+// checkUpgradeCompatibility refuses an upgrade whose binary supports an older
+// state schema than the one recorded in the state backend, since it would
+// misread state written by a newer CLI.
+func checkUpgradeCompatibility(ctx context.Context, binary []byte, backendType string, backendConfig []string, force bool) error {
+	mgr, err := createStateManagerWithConfig(backendType, backendConfig)
+	if err != nil {
+		fmt.Printf("Warning: skipping state compatibility check: %v\n", err)
+		return nil
+	}
+	meta, err := state.ReadMetadata(ctx, mgr.Backend())
+	if err != nil {
+		fmt.Printf("Warning: skipping state compatibility check: %v\n", err)
+		return nil
+	}
+	if meta == nil {
+		return nil
+	}
+
+	info, err := binaryVersionInfo(ctx, binary)
+	if err != nil {
+		// Releases predating `version -o json` only understand the original schema.
+		info = &versionInfo{StateSchemaVersion: 1}
+	}
+
+	if info.StateSchemaVersion < meta.SchemaVersion {
+		msg := fmt.Sprintf("the new binary supports state schema v%d, but the %s state backend uses v%d (written by cldctl %s)",
+			info.StateSchemaVersion, mgr.Backend().Type(), meta.SchemaVersion, meta.CLIVersion)
+		if !force {
+			return fmt.Errorf("%s; pass --force to install anyway", msg)
+		}
+		fmt.Printf("Warning: %s\n", msg)
+		return nil
+	}
+	if info.StateSchemaVersion > meta.SchemaVersion {
+		fmt.Printf("Note: the new version writes state schema v%d; older cldctl versions sharing this state may need to upgrade too.\n",
+			info.StateSchemaVersion)
+	}
+	return nil
+}
+
+// binaryVersionInfo runs a downloaded binary with `version -o json`.
+func binaryVersionInfo(ctx context.Context, binary []byte) (*versionInfo, error) {
+	f, err := os.CreateTemp("", "cldctl-upgrade-*")
+	if err != nil {
+		return nil, err
+	}
+	path := f.Name()
+	defer os.Remove(path)
+
+	if _, err := f.Write(binary); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o755); err != nil {
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(runCtx, path, "version", "-o", "json").Output()
+	if err != nil {
+		return nil, err
+	}
+	var info versionInfo
+	if err := json.Unmarshal(out, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
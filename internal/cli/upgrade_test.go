@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/selfupdate"
+	"github.com/spf13/viper"
+)
+
+func TestNewUpgradeCmd_Flags(t *testing.T) {
+	cmd := newUpgradeCmd()
+
+	if cmd.Use != "upgrade" {
+		t.Errorf("expected use 'upgrade', got '%s'", cmd.Use)
+	}
+
+	for _, name := range []string{"channel", "version", "check", "force", "insecure-skip-signature", "backend", "backend-config"} {
+		if cmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected flag '%s' to be defined", name)
+		}
+	}
+}
+
+func TestNewVersionCmd_Flags(t *testing.T) {
+	cmd := newVersionCmd()
+
+	for _, name := range []string{"check", "channel", "output"} {
+		if cmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected flag '%s' to be defined", name)
+		}
+	}
+}
+
+func TestResolveUpgradeChannel(t *testing.T) {
+	defer viper.Set(ConfigKeyUpgradeChannel, "")
+
+	ch, err := resolveUpgradeChannel("")
+	if err != nil || ch != selfupdate.ChannelStable {
+		t.Errorf("expected stable by default, got %q (%v)", ch, err)
+	}
+
+	viper.Set(ConfigKeyUpgradeChannel, "edge")
+	ch, err = resolveUpgradeChannel("")
+	if err != nil || ch != selfupdate.ChannelEdge {
+		t.Errorf("expected edge from config, got %q (%v)", ch, err)
+	}
+
+	ch, err = resolveUpgradeChannel("stable")
+	if err != nil || ch != selfupdate.ChannelStable {
+		t.Errorf("expected flag to override config, got %q (%v)", ch, err)
+	}
+
+	if _, err := resolveUpgradeChannel("nightly"); err == nil {
+		t.Error("expected error for unknown channel")
+	}
+}
@@ -17,12 +17,14 @@ func newValidateCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "validate",
 		Short: "Validate configurations",
-		Long:  `Commands for validating component, datacenter, and environment configurations.`,
+		Long: `Commands for validating component, datacenter, and environment configurations,
+and the compatibility of components with a datacenter.`,
 	}
 
 	cmd.AddCommand(newValidateComponentCmd())
 	cmd.AddCommand(newValidateDatacenterCmd())
 	cmd.AddCommand(newValidateEnvironmentCmd())
+	cmd.AddCommand(newValidateCompatibilityCmd())
 
 	return cmd
 }
@@ -117,6 +119,9 @@ func newValidateDatacenterCmd() *cobra.Command {
 		Short:   "Validate a datacenter configuration",
 		Long: `Validate a datacenter configuration file without deploying.
 
+Warns about hooks that can never be selected because a hook tried before
+them always matches first, such as a catch-all inherited through extends.
+
 Examples:
   cldctl validate datacenter
   cldctl validate datacenter ./my-datacenter
@@ -150,11 +155,20 @@ Examples:
 			}
 
 			loader := datacenter.NewLoader()
-			if err := loader.Validate(dcFile); err != nil {
+			dc, err := loader.Load(dcFile)
+			if err != nil {
 				return formatValidationError(err)
 			}
 
 			fmt.Println("Datacenter configuration is valid!")
+			if caps := datacenter.Capabilities(dc); len(caps) > 0 {
+				fmt.Printf("Capabilities: %s\n", strings.Join(caps, ", "))
+			}
+			// Hooks shadowed by an earlier one are valid but almost always a
+			// mistake, e.g. a catch-all inherited through extends
+			for _, warning := range datacenter.LintHooks(dc) {
+				fmt.Printf("Warning: %s\n", warning)
+			}
 			return nil
 		},
 	}
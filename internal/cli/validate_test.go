@@ -25,6 +25,7 @@ func TestNewValidateCmd(t *testing.T) {
 		"component [path]",
 		"datacenter [path]",
 		"environment [path]",
+		"compatibility <datacenter> <component>...",
 	}
 
 	for _, expected := range expectedCommands {
@@ -1,8 +1,13 @@
 package cli
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"time"
 
+	"github.com/davidthor/cldctl/pkg/selfupdate"
+	"github.com/davidthor/cldctl/pkg/state"
 	"github.com/spf13/cobra"
 )
 
@@ -11,16 +16,132 @@ var (
 	Version   = "dev"
 	Commit    = "none"
 	BuildDate = "unknown"
+
+	// ReleasePublicKey is the base64 ed25519 public key release checksums are
+	// signed with. Release builds set it; development builds leave it empty
+	// and cannot verify upgrades without --insecure-skip-signature.
+	ReleasePublicKey = ""
 )
 
+// versionInfo is the machine-readable form of `cldctl version`. `cldctl
+// upgrade` runs the downloaded binary with `version -o json` to learn which
+// state schema it supports before installing it.
+type versionInfo struct {
+	Version            string `json:"version"`
+	Commit             string `json:"commit"`
+	BuildDate          string `json:"build_date"`
+	StateSchemaVersion int    `json:"state_schema_version"`
+}
+
 func newVersionCmd() *cobra.Command {
-	return &cobra.Command{
+	var (
+		outputFormat string
+		check        bool
+		channelName  string
+	)
+
+	cmd := &cobra.Command{
 		Use:   "version",
 		Short: "Print the version information",
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Printf("cldctl version %s\n", Version)
-			fmt.Printf("  commit: %s\n", Commit)
-			fmt.Printf("  built: %s\n", BuildDate)
+		Long: `Print the version information.
+
+With --check, also query the release feed for newer versions and list any
+fixes for deploy bugs that shipped after this version.
+
+Examples:
+  cldctl version
+  cldctl version --check
+  cldctl version --check --channel edge
+  cldctl version -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info := versionInfo{
+				Version:            Version,
+				Commit:             Commit,
+				BuildDate:          BuildDate,
+				StateSchemaVersion: state.SchemaVersion,
+			}
+
+			switch outputFormat {
+			case "json":
+				data, err := json.MarshalIndent(info, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				fmt.Println(string(data))
+			case "text", "":
+				fmt.Printf("cldctl version %s\n", Version)
+				fmt.Printf("  commit: %s\n", Commit)
+				fmt.Printf("  built: %s\n", BuildDate)
+			default:
+				return fmt.Errorf("unknown output format %q (use 'text' or 'json')", outputFormat)
+			}
+
+			if !check {
+				return nil
+			}
+
+			channel, err := resolveUpgradeChannel(channelName)
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+			defer cancel()
+
+			client := selfupdate.NewClient()
+			fmt.Println()
+
+			// Development builds have no version to compare against.
+			if !selfupdate.IsVersion(Version) {
+				latest, err := client.Latest(ctx, channel)
+				if err != nil {
+					return fmt.Errorf("failed to check for updates: %w", err)
+				}
+				fmt.Printf("This is a development build; the latest %s release is %s.\n", channel, latest.Version)
+				return nil
+			}
+
+			newer, err := client.Newer(ctx, Version, channel)
+			if err != nil {
+				return fmt.Errorf("failed to check for updates: %w", err)
+			}
+			printUpdateNotice(newer, channel)
+			return nil
 		},
 	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text, json")
+	cmd.Flags().BoolVar(&check, "check", false, "Check for a newer release")
+	cmd.Flags().StringVar(&channelName, "channel", "", "Release channel to check: stable, edge (default from config, else stable)")
+
+	return cmd
+}
+
+// printUpdateNotice reports the newest release and any deploy fixes shipped
+// in releases newer than the running CLI.
+func printUpdateNotice(newer []selfupdate.Release, channel selfupdate.Channel) {
+	if len(newer) == 0 {
+		fmt.Printf("cldctl is up to date (%s channel).\n", channel)
+		return
+	}
+
+	fmt.Printf("A newer version is available: %s (you have %s)\n", newer[0].Version, Version)
+
+	var fixes []string
+	for _, r := range newer {
+		for _, fix := range r.DeployFixes() {
+			fixes = append(fixes, fmt.Sprintf("%s: %s", r.Version, fix))
+		}
+	}
+	if len(fixes) > 0 {
+		fmt.Println()
+		fmt.Println("Newer releases fix known deploy bugs:")
+		for _, fix := range fixes {
+			fmt.Printf("  - %s\n", fix)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("Run 'cldctl upgrade' to install it.")
 }
@@ -61,3 +61,14 @@ variable "runtime" {
   type        = any
   default     = null
 }
+
+variable "files" {
+  description = "Files to mount into the container"
+  type = list(object({
+    mount_path = string
+    content    = string
+    mode       = optional(string)
+  }))
+  default  = []
+  nullable = false
+}
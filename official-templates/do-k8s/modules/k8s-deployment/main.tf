@@ -35,6 +35,25 @@ resource "local_file" "kubeconfig" {
   file_permission = "0600"
 }
 
+# Files are stored in a ConfigMap, one key per file
+resource "kubernetes_config_map_v1" "files" {
+  count = length(var.files) > 0 ? 1 : 0
+
+  metadata {
+    name      = "${local.name}-files"
+    namespace = var.namespace
+
+    labels = {
+      "app.kubernetes.io/name"       = local.name
+      "app.kubernetes.io/managed-by" = "cldctl"
+    }
+  }
+
+  data = { for i, f in var.files : "file-${i}" => f.content }
+
+  depends_on = [local_file.kubeconfig]
+}
+
 resource "kubernetes_deployment_v1" "deployment" {
   metadata {
     name      = local.name
@@ -61,9 +80,33 @@ resource "kubernetes_deployment_v1" "deployment" {
           "app.kubernetes.io/name"       = local.name
           "app.kubernetes.io/managed-by" = "cldctl"
         }
+
+        # Roll the pods when a file changes; subPath mounts aren't updated
+        annotations = length(var.files) > 0 ? {
+          "cldctl.io/files-hash" = sha256(jsonencode(var.files))
+        } : {}
       }
 
       spec {
+        dynamic "volume" {
+          for_each = length(var.files) > 0 ? [1] : []
+          content {
+            name = "files"
+            config_map {
+              name = kubernetes_config_map_v1.files[0].metadata[0].name
+
+              dynamic "items" {
+                for_each = var.files
+                content {
+                  key  = "file-${items.key}"
+                  path = "file-${items.key}"
+                  mode = items.value.mode
+                }
+              }
+            }
+          }
+        }
+
         container {
           name    = local.name
           image   = var.image
@@ -77,6 +120,16 @@ resource "kubernetes_deployment_v1" "deployment" {
             }
           }
 
+          dynamic "volume_mount" {
+            for_each = var.files
+            content {
+              name       = "files"
+              mount_path = volume_mount.value.mount_path
+              sub_path   = "file-${volume_mount.key}"
+              read_only  = true
+            }
+          }
+
           resources {
             requests = {
               cpu    = local.cpu_request
@@ -18,6 +18,23 @@ locals {
   replicas = coalesce(var.replicas, 1)
 }
 
+# Files are stored in a ConfigMap, one key per file
+resource "kubernetes_config_map_v1" "files" {
+  count = length(var.files) > 0 ? 1 : 0
+
+  metadata {
+    name      = "${var.name}-files"
+    namespace = var.namespace
+
+    labels = {
+      app        = var.name
+      managed-by = "cldctl"
+    }
+  }
+
+  data = { for i, f in var.files : "file-${i}" => f.content }
+}
+
 resource "kubernetes_deployment_v1" "main" {
   metadata {
     name      = var.name
@@ -44,9 +61,33 @@ resource "kubernetes_deployment_v1" "main" {
           app        = var.name
           managed-by = "cldctl"
         }
+
+        # Roll the pods when a file changes; subPath mounts aren't updated
+        annotations = length(var.files) > 0 ? {
+          "cldctl.io/files-hash" = sha256(jsonencode(var.files))
+        } : {}
       }
 
       spec {
+        dynamic "volume" {
+          for_each = length(var.files) > 0 ? [1] : []
+          content {
+            name = "files"
+            config_map {
+              name = kubernetes_config_map_v1.files[0].metadata[0].name
+
+              dynamic "items" {
+                for_each = var.files
+                content {
+                  key  = "file-${items.key}"
+                  path = "file-${items.key}"
+                  mode = items.value.mode
+                }
+              }
+            }
+          }
+        }
+
         container {
           name  = "main"
           image = var.image
@@ -66,6 +107,16 @@ resource "kubernetes_deployment_v1" "main" {
             }
           }
 
+          dynamic "volume_mount" {
+            for_each = var.files
+            content {
+              name       = "files"
+              mount_path = volume_mount.value.mount_path
+              sub_path   = "file-${volume_mount.key}"
+              read_only  = true
+            }
+          }
+
           resources {
             requests = {
               cpu    = coalesce(var.cpu, "250m")
@@ -58,3 +58,14 @@ variable "replicas" {
   type        = number
   default     = null
 }
+
+variable "files" {
+  description = "Files to mount into the container"
+  type = list(object({
+    mount_path = string
+    content    = string
+    mode       = optional(string)
+  }))
+  default  = []
+  nullable = false
+}
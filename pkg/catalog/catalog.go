@@ -0,0 +1,298 @@
+// Package catalog reads catalog indexes: files listing the components and
+// datacenters an organization publishes, so they can be discovered with
+// 'cldctl search' instead of by word of mouth.
+//
+// An index is a YAML (or JSON) file served over HTTPS, pushed to an OCI
+// registry with 'cldctl push catalog', or kept on disk. Each entry names an
+// artifact reference that can be passed straight to 'cldctl deploy' or
+// 'cldctl pull'.
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IndexVersion is the latest index format version this package reads.
+const IndexVersion = 1
+
+// IndexFileNames are the names an index file may have inside an OCI
+// artifact, in the order they're looked for.
+var IndexFileNames = []string{"catalog.yml", "catalog.yaml", "catalog.json"}
+
+// maxIndexSize bounds how much of an HTTPS response is read as an index.
+const maxIndexSize = 16 << 20
+
+// EntryType identifies what kind of artifact an entry describes.
+type EntryType string
+
+const (
+	// TypeComponent identifies a component entry.
+	TypeComponent EntryType = "component"
+
+	// TypeDatacenter identifies a datacenter entry.
+	TypeDatacenter EntryType = "datacenter"
+)
+
+// Index is a catalog index file.
+type Index struct {
+	// Version is the index format version
+	Version int `yaml:"version" json:"version"`
+
+	// Name is a display name for the catalog (e.g., "acme-internal")
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// Entries are the artifacts the catalog lists
+	Entries []Entry `yaml:"entries" json:"entries"`
+}
+
+// Entry describes one published component or datacenter.
+type Entry struct {
+	// Name is the artifact's name in the catalog
+	Name string `yaml:"name" json:"name"`
+
+	// Type is "component" or "datacenter"
+	Type EntryType `yaml:"type" json:"type"`
+
+	// Reference is the OCI repository the artifact is published to, without
+	// a tag (e.g., "ghcr.io/acme/payments")
+	Reference string `yaml:"reference" json:"reference"`
+
+	// Versions are the published tags, newest first
+	Versions []string `yaml:"versions,omitempty" json:"versions,omitempty"`
+
+	// Description is a one-line summary
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+
+	// Keywords are additional search terms
+	Keywords []string `yaml:"keywords,omitempty" json:"keywords,omitempty"`
+
+	// Owner is the team or person responsible for the artifact
+	Owner string `yaml:"owner,omitempty" json:"owner,omitempty"`
+
+	// Homepage links to the artifact's documentation or source
+	Homepage string `yaml:"homepage,omitempty" json:"homepage,omitempty"`
+}
+
+// Latest returns the entry's reference at its newest version, or the bare
+// reference if it lists no versions.
+func (e Entry) Latest() string {
+	if len(e.Versions) == 0 {
+		return e.Reference
+	}
+	return e.Reference + ":" + e.Versions[0]
+}
+
+// Parse decodes and validates an index file.
+func Parse(data []byte) (*Index, error) {
+	var idx Index
+	if err := yaml.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("failed to parse catalog index: %w", err)
+	}
+	if idx.Version == 0 {
+		return nil, fmt.Errorf("catalog index has no version")
+	}
+	if idx.Version > IndexVersion {
+		return nil, fmt.Errorf("catalog index version %d is newer than this cldctl supports (%d); upgrade cldctl", idx.Version, IndexVersion)
+	}
+	for i, e := range idx.Entries {
+		if e.Name == "" {
+			return nil, fmt.Errorf("catalog entry %d has no name", i)
+		}
+		if e.Reference == "" {
+			return nil, fmt.Errorf("catalog entry %q has no reference", e.Name)
+		}
+		switch e.Type {
+		case TypeComponent, TypeDatacenter:
+		default:
+			return nil, fmt.Errorf("catalog entry %q has unknown type %q (expected component or datacenter)", e.Name, e.Type)
+		}
+	}
+	return &idx, nil
+}
+
+// Puller pulls an OCI artifact's contents into a directory. oci.Client
+// implements it.
+type Puller interface {
+	Pull(ctx context.Context, reference string, destDir string) error
+}
+
+// Client loads catalog indexes from their sources.
+type Client struct {
+	HTTPClient *http.Client
+
+	// Token, if set, is sent as a bearer token when fetching HTTPS indexes
+	Token string
+
+	// OCI pulls indexes published as OCI artifacts
+	OCI Puller
+}
+
+// NewClient returns a client that pulls OCI indexes with puller.
+func NewClient(puller Puller) *Client {
+	return &Client{
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		OCI:        puller,
+	}
+}
+
+// Load reads the index at source: an http(s) URL, a path to a local file,
+// or otherwise an OCI artifact reference.
+func (c *Client) Load(ctx context.Context, source string) (*Index, error) {
+	var data []byte
+	var err error
+	switch {
+	case strings.HasPrefix(source, "https://"), strings.HasPrefix(source, "http://"):
+		data, err = c.fetch(ctx, source)
+	case isLocalPath(source):
+		data, err = os.ReadFile(source)
+	default:
+		data, err = c.pull(ctx, source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	idx, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	return idx, nil
+}
+
+func (c *Client) fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch catalog %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("catalog %s returned %d: %s", url, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxIndexSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog %s: %w", url, err)
+	}
+	if len(data) > maxIndexSize {
+		return nil, fmt.Errorf("catalog %s is larger than %d bytes", url, maxIndexSize)
+	}
+	return data, nil
+}
+
+func (c *Client) pull(ctx context.Context, reference string) ([]byte, error) {
+	if c.OCI == nil {
+		return nil, fmt.Errorf("cannot pull catalog %s: no OCI client configured", reference)
+	}
+	dir, err := os.MkdirTemp("", "cldctl-catalog-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := c.OCI.Pull(ctx, reference, dir); err != nil {
+		return nil, fmt.Errorf("failed to pull catalog %s: %w", reference, err)
+	}
+	for _, name := range IndexFileNames {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("catalog artifact %s has no %s", reference, strings.Join(IndexFileNames, ", "))
+}
+
+// isLocalPath reports whether a source names a file rather than an OCI
+// reference. Explicit paths always do; anything else only if it exists.
+func isLocalPath(source string) bool {
+	if strings.HasPrefix(source, "/") || strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") {
+		return true
+	}
+	info, err := os.Stat(source)
+	return err == nil && !info.IsDir()
+}
+
+// Match is an entry found by Search.
+type Match struct {
+	Entry `yaml:",inline"`
+
+	// Catalog is the source of the index the entry was found in
+	Catalog string `yaml:"catalog" json:"catalog"`
+
+	score int
+}
+
+// Search returns the entries in an index that match term, best matches
+// first: exact names, then names containing the term, then entries whose
+// keywords, description, or owner mention it. Matching is case-insensitive,
+// and an empty term matches every entry. A non-empty entryType limits the
+// results to that type.
+func Search(idx *Index, source, term string, entryType EntryType) []Match {
+	term = strings.ToLower(strings.TrimSpace(term))
+
+	var matches []Match
+	for _, e := range idx.Entries {
+		if entryType != "" && e.Type != entryType {
+			continue
+		}
+		if score := matchScore(e, term); score > 0 {
+			matches = append(matches, Match{Entry: e, Catalog: source, score: score})
+		}
+	}
+	SortMatches(matches)
+	return matches
+}
+
+// SortMatches orders matches best first, then by name.
+func SortMatches(matches []Match) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].Name < matches[j].Name
+	})
+}
+
+func matchScore(e Entry, term string) int {
+	name := strings.ToLower(e.Name)
+	switch {
+	case term == "":
+		return 1
+	case name == term:
+		return 4
+	case strings.Contains(name, term):
+		return 3
+	}
+	for _, k := range e.Keywords {
+		if strings.ToLower(k) == term {
+			return 2
+		}
+	}
+	for _, field := range append([]string{e.Description, e.Owner, e.Reference}, e.Keywords...) {
+		if strings.Contains(strings.ToLower(field), term) {
+			return 1
+		}
+	}
+	return 0
+}
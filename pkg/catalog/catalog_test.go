@@ -0,0 +1,173 @@
+package catalog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testIndex = `
+version: 1
+name: acme
+entries:
+  - name: payments
+    type: component
+    reference: ghcr.io/acme/payments
+    versions: [v2.1.0, v2.0.0]
+    description: Payment processing API
+    keywords: [billing]
+    owner: payments-team
+  - name: payments-worker
+    type: component
+    reference: ghcr.io/acme/payments-worker
+    description: Processes payment webhooks
+  - name: aws-prod
+    type: datacenter
+    reference: ghcr.io/acme/dc-aws
+    description: Production AWS datacenter with billing exports
+`
+
+func TestParse(t *testing.T) {
+	idx, err := Parse([]byte(testIndex))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if idx.Name != "acme" || len(idx.Entries) != 3 {
+		t.Fatalf("unexpected index: %+v", idx)
+	}
+	if got := idx.Entries[0].Latest(); got != "ghcr.io/acme/payments:v2.1.0" {
+		t.Errorf("Latest() = %q", got)
+	}
+	if got := idx.Entries[1].Latest(); got != "ghcr.io/acme/payments-worker" {
+		t.Errorf("Latest() without versions = %q", got)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"no version", `entries: []`, "no version"},
+		{"newer version", `version: 99`, "newer than this cldctl supports"},
+		{"missing reference", "version: 1\nentries:\n  - name: a\n    type: component", "has no reference"},
+		{"unknown type", "version: 1\nentries:\n  - name: a\n    type: module\n    reference: r", "unknown type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	idx, err := Parse([]byte(testIndex))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		term      string
+		entryType EntryType
+		want      []string
+	}{
+		{"exact name first", "payments", "", []string{"payments", "payments-worker"}},
+		{"keyword before description", "BILLING", "", []string{"payments", "aws-prod"}},
+		{"type filter", "billing", TypeDatacenter, []string{"aws-prod"}},
+		{"empty term lists all", "", "", []string{"aws-prod", "payments", "payments-worker"}},
+		{"no match", "kafka", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, m := range Search(idx, "acme", tt.term, tt.entryType) {
+				got = append(got, m.Name)
+				if m.Catalog != "acme" {
+					t.Errorf("Catalog = %q, want acme", m.Catalog)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Search(%q) = %v, want %v", tt.term, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_LoadHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(testIndex))
+	}))
+	defer srv.Close()
+
+	client := NewClient(nil)
+	if _, err := client.Load(context.Background(), srv.URL+"/index.yml"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected an unauthorized error, got %v", err)
+	}
+
+	client.Token = "s3cret"
+	idx, err := client.Load(context.Background(), srv.URL+"/index.yml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(idx.Entries) != 3 {
+		t.Errorf("expected 3 entries, got %d", len(idx.Entries))
+	}
+}
+
+// fakePuller writes an index file into the pull directory, as pulling an
+// artifact pushed with 'cldctl push catalog' does.
+type fakePuller struct {
+	files map[string]string
+}
+
+func (p *fakePuller) Pull(_ context.Context, _ string, destDir string) error {
+	for name, content := range p.files {
+		if err := os.WriteFile(filepath.Join(destDir, name), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestClient_LoadOCI(t *testing.T) {
+	client := NewClient(&fakePuller{files: map[string]string{"catalog.yaml": testIndex}})
+	idx, err := client.Load(context.Background(), "ghcr.io/acme/catalog:latest")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if idx.Name != "acme" {
+		t.Errorf("unexpected index name %q", idx.Name)
+	}
+
+	client = NewClient(&fakePuller{files: map[string]string{"README.md": "hi"}})
+	if _, err := client.Load(context.Background(), "ghcr.io/acme/not-a-catalog:latest"); err == nil || !strings.Contains(err.Error(), "has no catalog.yml") {
+		t.Errorf("expected a missing index error, got %v", err)
+	}
+}
+
+func TestClient_LoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.yml")
+	if err := os.WriteFile(path, []byte(testIndex), 0644); err != nil {
+		t.Fatal(err)
+	}
+	idx, err := NewClient(nil).Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(idx.Entries) != 3 {
+		t.Errorf("expected 3 entries, got %d", len(idx.Entries))
+	}
+}
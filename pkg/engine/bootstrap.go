@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/schema/environment"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// defaultBootstrapTimeout is how long a bootstrap task may run when it
+// doesn't set a timeout.
+const defaultBootstrapTimeout = 10 * time.Minute
+
+// BootstrapOptions configures RunBootstrap.
+type BootstrapOptions struct {
+	Datacenter  string
+	Environment string
+
+	// Tasks are the environment file's bootstrap tasks, with variables and
+	// locals already substituted (see environment.ResolveVariables)
+	Tasks []environment.BootstrapTask
+
+	// Dir is the environment file's directory, which task working
+	// directories are relative to
+	Dir string
+
+	// Rerun runs every task, including those that have run before
+	Rerun bool
+
+	// Output receives the tasks' output
+	Output io.Writer
+}
+
+// RunBootstrap runs an environment's bootstrap tasks that haven't run
+// before, in order, once its components are deployed. Each task that
+// succeeds is recorded in the environment's state so later deploys skip it.
+// The first task that fails stops the run, and it and the tasks after it
+// run again on the next deploy.
+func (e *Engine) RunBootstrap(ctx context.Context, opts BootstrapOptions) error {
+	if len(opts.Tasks) == 0 {
+		return nil
+	}
+
+	lockedCtx, unlock, err := e.lockEnvironment(ctx, opts.Datacenter, opts.Environment, "bootstrap", false, opts.Output)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	ctx = lockedCtx
+
+	envState, err := e.stateManager.GetEnvironment(ctx, opts.Datacenter, opts.Environment)
+	if err != nil {
+		return fmt.Errorf("environment %q not found in datacenter %q: %w", opts.Environment, opts.Datacenter, err)
+	}
+
+	output := opts.Output
+	if output == nil {
+		output = io.Discard
+	}
+
+	for _, task := range opts.Tasks {
+		if _, ran := envState.Bootstrap[task.Name()]; ran && !opts.Rerun {
+			continue
+		}
+
+		fmt.Fprintf(output, "Running bootstrap task %q...\n", task.Name())
+		if err := runBootstrapTask(ctx, task, envState, opts.Dir, output); err != nil {
+			return fmt.Errorf("bootstrap task %q failed: %w", task.Name(), err)
+		}
+
+		if envState.Bootstrap == nil {
+			envState.Bootstrap = make(map[string]*types.BootstrapTaskState)
+		}
+		envState.Bootstrap[task.Name()] = &types.BootstrapTaskState{CompletedAt: time.Now()}
+		envState.UpdatedAt = time.Now()
+		if err := e.stateManager.SaveEnvironment(ctx, opts.Datacenter, envState); err != nil {
+			return fmt.Errorf("failed to save environment state: %w", err)
+		}
+	}
+	return nil
+}
+
+// runBootstrapTask resolves a task's component output and route URL
+// references against the environment and runs its command.
+func runBootstrapTask(ctx context.Context, task environment.BootstrapTask, envState *types.EnvironmentState, dir string, output io.Writer) error {
+	args := make([]string, len(task.Command()))
+	for i, arg := range task.Command() {
+		value, err := resolveEnvironmentOutput(arg, envState)
+		if err != nil {
+			return fmt.Errorf("command: %w", err)
+		}
+		args[i] = fmt.Sprintf("%v", value)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("no command")
+	}
+
+	env := os.Environ()
+	for key, expr := range task.Environment() {
+		value, err := resolveEnvironmentOutput(expr, envState)
+		if err != nil {
+			return fmt.Errorf("environment.%s: %w", key, err)
+		}
+		env = append(env, fmt.Sprintf("%s=%v", key, value))
+	}
+
+	timeout := defaultBootstrapTimeout
+	if task.Timeout() != "" {
+		d, err := time.ParseDuration(task.Timeout())
+		if err != nil {
+			return fmt.Errorf("invalid timeout %q: %w", task.Timeout(), err)
+		}
+		timeout = d
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	workDir := dir
+	if wd := task.WorkingDirectory(); wd != "" {
+		if filepath.IsAbs(wd) || dir == "" {
+			workDir = wd
+		} else {
+			workDir = filepath.Join(dir, wd)
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = workDir
+	cmd.Env = env
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("timed out after %v", timeout)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/schema/component"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+)
+
+// newGraphBuilder returns a graph builder for an environment of dc.
+//
+// Instead of simply toggling implicit nodes on/off based on hook presence,
+// it creates filters that evaluate each hook's when-clause against the
+// prospective node's inputs. This ensures nodes are only created when a
+// matching hook exists — e.g. a databaseUser hook scoped to postgres won't
+// generate nodes for redis databases.
+func newGraphBuilder(envName, dcName string, dc datacenter.Datacenter) *graph.Builder {
+	builder := graph.NewBuilder(envName, dcName)
+	if dc == nil {
+		return builder
+	}
+	if env := dc.Environment(); env != nil {
+		exports := make([]string, 0, len(env.Exports()))
+		for name := range env.Exports() {
+			exports = append(exports, name)
+		}
+		builder.SetPlatformExports(exports)
+		if hooks := env.Hooks(); hooks != nil {
+			if dbUserHooks := hooks.DatabaseUser(); len(dbUserHooks) > 0 {
+				builder.SetDatabaseUserFilter(makeHookFilter(dbUserHooks))
+			}
+			if npHooks := hooks.NetworkPolicy(); len(npHooks) > 0 {
+				builder.SetNetworkPolicyFilter(makeHookFilter(npHooks))
+			}
+		}
+	}
+	return builder
+}
+
+// BuildGraph builds the dependency graph comps would have when deployed
+// together to an environment of dc, keyed by component name, without
+// loading state or executing anything. Components that declare instances
+// get their default instances. dc may be nil, in which case no implicit
+// nodes are created.
+func BuildGraph(envName, dcName string, dc datacenter.Datacenter, comps map[string]component.Component) (*graph.Graph, error) {
+	builder := newGraphBuilder(envName, dcName, dc)
+
+	names := make([]string, 0, len(comps))
+	for name := range comps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		comp := comps[name]
+		if len(comp.Instances()) > 0 {
+			var instances []graph.InstanceInfo
+			for _, inst := range comp.Instances() {
+				instances = append(instances, graph.InstanceInfo{Name: inst.Name(), Weight: inst.Weight()})
+			}
+			if err := builder.AddComponentWithInstances(name, comp, instances, comp.Distinct()); err != nil {
+				return nil, fmt.Errorf("failed to add component %s to graph with instances: %w", name, err)
+			}
+			continue
+		}
+		if err := builder.AddComponent(name, comp); err != nil {
+			return nil, fmt.Errorf("failed to add component %s to graph: %w", name, err)
+		}
+	}
+
+	return builder.Build(), nil
+}
+
+// EnvironmentGraph rebuilds the dependency graph of a deployed environment
+// from the sources its components were last deployed from, along with the
+// datacenter's hooks. Components running several instances get the
+// instances they're deployed with.
+func (e *Engine) EnvironmentGraph(ctx context.Context, dcName, envName string) (*graph.Graph, error) {
+	envState, err := e.stateManager.GetEnvironment(ctx, dcName, envName)
+	if err != nil {
+		return nil, fmt.Errorf("environment %s not found in datacenter %s", envName, dcName)
+	}
+
+	// Without the datacenter, no implicit nodes (e.g. databaseUser) are created
+	var dc datacenter.Datacenter
+	if dcState, err := e.stateManager.GetDatacenter(ctx, dcName); err == nil && dcState != nil && dcState.Version != "" {
+		if loaded, err := e.loadDatacenterConfig(dcState.Version); err == nil {
+			dc = loaded
+		}
+	}
+
+	names := make([]string, 0, len(envState.Components))
+	for name := range envState.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	builder := newGraphBuilder(envName, dcName, dc)
+	for _, name := range names {
+		compState := envState.Components[name]
+		if len(compState.Instances) == 0 {
+			if compState.Source == "" {
+				return nil, fmt.Errorf("component %q has no recorded source; deploy it again to build its graph", name)
+			}
+			comp, err := e.compLoader.Load(compState.Source)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load component %s: %w", name, err)
+			}
+			if err := builder.AddComponent(name, comp); err != nil {
+				return nil, fmt.Errorf("failed to add component %s to graph: %w", name, err)
+			}
+			continue
+		}
+
+		instNames := make([]string, 0, len(compState.Instances))
+		for instName := range compState.Instances {
+			instNames = append(instNames, instName)
+		}
+		sort.Strings(instNames)
+
+		var shared component.Component
+		instances := make([]graph.InstanceInfo, 0, len(instNames))
+		for _, instName := range instNames {
+			inst := compState.Instances[instName]
+			source := inst.Source
+			if source == "" {
+				source = compState.Source
+			}
+			if source == "" {
+				return nil, fmt.Errorf("instance %q of component %q has no recorded source; deploy it again to build its graph", instName, name)
+			}
+			comp, err := e.compLoader.Load(source)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load component %s instance %s: %w", name, instName, err)
+			}
+			if shared == nil {
+				shared = comp
+			}
+			instances = append(instances, graph.InstanceInfo{Name: instName, Weight: inst.Weight, Component: comp})
+		}
+		if err := builder.AddComponentWithInstances(name, shared, instances, shared.Distinct()); err != nil {
+			return nil, fmt.Errorf("failed to add component %s to graph with instances: %w", name, err)
+		}
+	}
+
+	return builder.Build(), nil
+}
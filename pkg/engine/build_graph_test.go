@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func TestEnvironmentGraph(t *testing.T) {
+	ctx := context.Background()
+	compFile := filepath.Join(t.TempDir(), "cld.yml")
+	if err := os.WriteFile(compFile, []byte(`
+databases:
+  main:
+    type: postgres:^16
+deployments:
+  api:
+    image: api:latest
+    environment:
+      DATABASE_URL: ${{ databases.main.url }}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := newMockStateManager()
+	_ = mgr.SaveEnvironment(ctx, "dc", &types.EnvironmentState{
+		Name: "staging",
+		Components: map[string]*types.ComponentState{
+			"api": {Name: "api", Source: compFile},
+		},
+	})
+	eng := NewEngine(mgr, nil)
+
+	g, err := eng.EnvironmentGraph(ctx, "dc", "staging")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deploy := g.GetNode("api/deployment/api")
+	if deploy == nil || g.GetNode("api/database/main") == nil {
+		t.Fatalf("expected the component's resources in the graph, got %v", g.Nodes)
+	}
+	found := false
+	for _, dep := range deploy.DependsOn {
+		found = found || dep == "api/database/main"
+	}
+	if !found {
+		t.Errorf("expected the deployment to depend on the database, got %v", deploy.DependsOn)
+	}
+
+	mgr.environments["staging"].Components["web"] = &types.ComponentState{Name: "web"}
+	if _, err := eng.EnvironmentGraph(ctx, "dc", "staging"); err == nil || !strings.Contains(err.Error(), "no recorded source") {
+		t.Errorf("expected a component without a source to be refused, got %v", err)
+	}
+}
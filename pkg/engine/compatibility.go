@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/schema/component"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	dcv1 "github.com/davidthor/cldctl/pkg/schema/datacenter/v1"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// Kinds of compatibility issue.
+const (
+	// CompatibilityCapability means the datacenter lacks a capability the
+	// component declares in requires.
+	CompatibilityCapability = "capability"
+	// CompatibilityHook means no datacenter hook would provision one of the
+	// component's resources, or the matching hook rejects it.
+	CompatibilityHook = "hook"
+	// CompatibilityOutput means the component reads a hook output the
+	// datacenter does not declare.
+	CompatibilityOutput = "output"
+)
+
+// CompatibilityIssue is a reason a component would fail to deploy to a
+// datacenter.
+type CompatibilityIssue struct {
+	Kind string `json:"kind"`
+	// Resource is the ID of the affected node, or empty for issues with the
+	// component as a whole.
+	Resource string `json:"resource,omitempty"`
+	Message  string `json:"message"`
+}
+
+// compatibilityEnvironment names the environment the graph is built for.
+// Nothing is deployed to it.
+const compatibilityEnvironment = "compatibility-check"
+
+// CheckCompatibility reports the reasons comp could not be deployed to dc
+// without executing anything: capabilities the datacenter lacks, resources
+// no hook would provision, and hook outputs the component consumes but the
+// datacenter does not declare. It lets platform teams validate a datacenter
+// against a catalog of components before rolling it out. Hooks whose when
+// clause can't be evaluated before deploy are assumed to match. The error is
+// only set when the component can't be turned into a graph.
+func CheckCompatibility(dcName string, dc datacenter.Datacenter, compName string, comp component.Component) ([]CompatibilityIssue, error) {
+	var issues []CompatibilityIssue
+
+	if missing := datacenter.MissingCapabilities(dc, comp.Requires()); len(missing) > 0 {
+		issues = append(issues, CompatibilityIssue{
+			Kind:    CompatibilityCapability,
+			Message: fmt.Sprintf("missing %s support", strings.Join(missing, ", ")),
+		})
+	}
+
+	var hooks datacenter.Hooks
+	if env := dc.Environment(); env != nil {
+		hooks = env.Hooks()
+	}
+	builder := newGraphBuilder(compatibilityEnvironment, dcName, dc)
+	if err := builder.AddComponent(compName, comp); err != nil {
+		return nil, fmt.Errorf("failed to add component %s to graph: %w", compName, err)
+	}
+	g := builder.Build()
+
+	ids := make([]string, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if msg := checkNodeHook(hooks, g.Nodes[id]); msg != "" {
+			issues = append(issues, CompatibilityIssue{Kind: CompatibilityHook, Resource: id, Message: msg})
+		}
+	}
+
+	var contractErr *HookOutputContractError
+	if err := checkHookOutputContracts(g, dc); errors.As(err, &contractErr) {
+		for _, m := range contractErr.Mismatches {
+			issues = append(issues, CompatibilityIssue{
+				Kind:     CompatibilityOutput,
+				Resource: m.Resource,
+				Message:  fmt.Sprintf("%s reads ${{ %s }}, but the %s hook does not declare output %q", m.Consumer, m.Expression, m.Hook, m.Output),
+			})
+		}
+	}
+
+	return issues, nil
+}
+
+// checkNodeHook describes why no hook would provision node, or returns ""
+// when one would. Node types the executor handles without a hook (ports,
+// external services, and implicit nodes that fall back to a default) are
+// not checked.
+func checkNodeHook(hooks datacenter.Hooks, node *graph.Node) string {
+	switch node.Type {
+	case graph.NodeTypePort, graph.NodeTypeExternal, graph.NodeTypeSecret,
+		graph.NodeTypeDatabaseUser, graph.NodeTypeNetworkPolicy:
+		return ""
+	}
+
+	var candidates []datacenter.Hook
+	if hooks != nil {
+		candidates = hooksForType(hooks, node.Type)
+	}
+	if len(candidates) == 0 {
+		return fmt.Sprintf("the datacenter defines no %s hook", node.Type)
+	}
+
+	for _, h := range candidates {
+		// Fallthrough hooks don't provision the resource on their own
+		if h.Fallthrough() {
+			continue
+		}
+		matched, ok := evaluateStaticWhen(h.When(), node)
+		if !ok {
+			return ""
+		}
+		if !matched {
+			continue
+		}
+		if msg := h.Error(); msg != "" {
+			return fmt.Sprintf("rejected by the %s hook: %s", node.Type, msg)
+		}
+		return ""
+	}
+	return fmt.Sprintf("no %s hook matches the resource", node.Type)
+}
+
+// evaluateStaticWhen evaluates a hook's when clause against a node's identity
+// and the inputs known before deploy. Unlike evaluateHookWhen, ok is also false when the
+// clause can't be evaluated, e.g. because it reads an input that is only
+// resolved at deploy time.
+func evaluateStaticWhen(when string, node *graph.Node) (matched, ok bool) {
+	if when == "" {
+		return true, true
+	}
+
+	expr, diags := hclsyntax.ParseExpression([]byte(when), "when.hcl", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return false, false
+	}
+
+	eval := dcv1.NewEvaluator()
+	eval.SetNodeContext(string(node.Type), node.Name, node.Component, node.Inputs)
+
+	result, err := eval.EvaluateWhen(expr)
+	if err != nil {
+		return false, false
+	}
+	return result, true
+}
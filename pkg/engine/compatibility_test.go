@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/schema/component"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+)
+
+func TestCheckCompatibility(t *testing.T) {
+	dc, err := datacenter.NewLoader().LoadFromBytes([]byte(`
+environment {
+  database {
+    when  = node.inputs.type == "redis"
+    error = "redis is not offered"
+  }
+
+  database {
+    module "postgres" {
+      build = "./modules/postgres"
+    }
+    outputs = {
+      host = module.postgres.host
+      port = module.postgres.port
+      url  = module.postgres.url
+    }
+  }
+
+  deployment {
+    module "deployment" {
+      build = "./modules/deployment"
+    }
+    outputs = {
+      id = module.deployment.id
+    }
+  }
+}
+`), "/tmp/dc/datacenter.dc")
+	if err != nil {
+		t.Fatalf("failed to load datacenter: %v", err)
+	}
+	loadComp := func(yaml string) component.Component {
+		comp, err := component.NewLoader().LoadFromBytes([]byte(yaml), "/tmp/app/cld.yml")
+		if err != nil {
+			t.Fatalf("failed to load component: %v", err)
+		}
+		return comp
+	}
+
+	t.Run("compatible", func(t *testing.T) {
+		issues, err := CheckCompatibility("dc", dc, "app", loadComp(`
+databases:
+  main:
+    type: postgres:^16
+deployments:
+  api:
+    image: api:latest
+    environment:
+      DATABASE_URL: ${{ databases.main.url }}
+`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(issues) != 0 {
+			t.Errorf("expected no issues, got %+v", issues)
+		}
+	})
+
+	t.Run("incompatible", func(t *testing.T) {
+		issues, err := CheckCompatibility("dc", dc, "app", loadComp(`
+requires: [smtp]
+databases:
+  main:
+    type: postgres:^16
+  cache:
+    type: redis
+deployments:
+  api:
+    image: api:latest
+    environment:
+      POOL_URL: ${{ databases.main.poolUrl }}
+services:
+  api:
+    deployment: api
+    port: 8080
+`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := []CompatibilityIssue{
+			{Kind: CompatibilityCapability, Message: "missing smtp support"},
+			{Kind: CompatibilityHook, Resource: "app/database/cache", Message: "rejected by the database hook: redis is not offered"},
+			{Kind: CompatibilityHook, Resource: "app/service/api", Message: "the datacenter defines no service hook"},
+			{Kind: CompatibilityOutput, Resource: "app/database/main"},
+		}
+		if len(issues) != len(want) {
+			t.Fatalf("expected %d issues, got %+v", len(want), issues)
+		}
+		for i, w := range want {
+			got := issues[i]
+			if got.Kind != w.Kind || got.Resource != w.Resource || (w.Message != "" && got.Message != w.Message) {
+				t.Errorf("issue %d: expected %+v, got %+v", i, w, got)
+			}
+		}
+	})
+}
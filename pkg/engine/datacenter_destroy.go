@@ -0,0 +1,200 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/engine/executor"
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// DestroyDatacenterOptions configures a datacenter teardown.
+type DestroyDatacenterOptions struct {
+	// Datacenter name
+	Datacenter string
+
+	// Output writer for progress
+	Output io.Writer
+
+	// OnProgress is called when an environment or root module changes status
+	OnProgress executor.ProgressCallback
+}
+
+// DestroyDatacenterResult contains the results of a datacenter teardown.
+type DestroyDatacenterResult struct {
+	Success  bool
+	Duration time.Duration
+
+	// DestroyedEnvironments lists the environments torn down, in order.
+	DestroyedEnvironments []string
+
+	// DestroyedModules lists the root modules destroyed, in order.
+	DestroyedModules []string
+}
+
+// DestroyDatacenter tears down a datacenter in the reverse of the order it was
+// built: every environment (components, then environment modules), then the
+// root modules, and finally the datacenter's state. Each environment and root
+// module is removed from state as soon as it is destroyed, so after a failure
+// the teardown stops and can be resumed by running it again.
+func (e *Engine) DestroyDatacenter(ctx context.Context, opts DestroyDatacenterOptions) (*DestroyDatacenterResult, error) {
+	startTime := time.Now()
+	result := &DestroyDatacenterResult{}
+	fail := func(err error) (*DestroyDatacenterResult, error) {
+		result.Duration = time.Since(startTime)
+		return result, err
+	}
+
+	dcState, err := e.stateManager.GetDatacenter(ctx, opts.Datacenter)
+	if err != nil {
+		return nil, fmt.Errorf("datacenter %q not found: %w", opts.Datacenter, err)
+	}
+
+	// The configuration orders root modules and provides sandbox settings.
+	// Teardown works from stored state alone if it can't be loaded.
+	var dc datacenter.Datacenter
+	if dcState.Version != "" {
+		if dc, err = e.loadDatacenterConfig(dcState.Version); err != nil && opts.Output != nil {
+			fmt.Fprintf(opts.Output, "  [warning] Could not load datacenter config: %v\n", err)
+		}
+	}
+
+	// Phase 1: Environments
+	envs, err := e.stateManager.ListEnvironments(ctx, opts.Datacenter)
+	if err != nil {
+		return fail(fmt.Errorf("failed to list environments: %w", err))
+	}
+	for _, envRef := range envs {
+		nodeID := "env/" + envRef.Name
+		reportDestroyProgress(opts.OnProgress, nodeID, envRef.Name, "environment", "running", "Destroying environment...", nil)
+		if opts.Output != nil {
+			fmt.Fprintf(opts.Output, "[destroy] Destroying environment %q...\n", envRef.Name)
+		}
+
+		if err := e.destroyDatacenterEnvironment(ctx, opts, envRef.Name); err != nil {
+			reportDestroyProgress(opts.OnProgress, nodeID, envRef.Name, "environment", "failed", "", err)
+			return fail(err)
+		}
+
+		result.DestroyedEnvironments = append(result.DestroyedEnvironments, envRef.Name)
+		reportDestroyProgress(opts.OnProgress, nodeID, envRef.Name, "environment", "completed", "Environment destroyed", nil)
+	}
+
+	// Phase 2: Root modules, in reverse declaration order
+	var rootModules []datacenter.Module
+	if dc != nil {
+		rootModules = dc.Modules()
+	}
+	order := moduleDestroyOrder(rootModules, dcState.Modules)
+	if len(order) > 0 && opts.Output != nil {
+		fmt.Fprintf(opts.Output, "[destroy] Destroying %d root module(s)...\n", len(order))
+	}
+	for _, modName := range order {
+		nodeID := "root/module/" + modName
+		reportDestroyProgress(opts.OnProgress, nodeID, modName, "module", "running", "Destroying root module...", nil)
+
+		if err := e.destroyModule(ctx, dc, modName, dcState.Modules[modName]); err != nil {
+			dcState.Modules[modName].StatusReason = err.Error()
+			dcState.UpdatedAt = time.Now()
+			_ = e.stateManager.SaveDatacenter(ctx, dcState)
+
+			reportDestroyProgress(opts.OnProgress, nodeID, modName, "module", "failed", "", err)
+			return fail(fmt.Errorf("failed to destroy root module %s: %w", modName, err))
+		}
+
+		delete(dcState.Modules, modName)
+		dcState.UpdatedAt = time.Now()
+		if err := e.stateManager.SaveDatacenter(ctx, dcState); err != nil {
+			return fail(fmt.Errorf("failed to save datacenter state: %w", err))
+		}
+
+		result.DestroyedModules = append(result.DestroyedModules, modName)
+		reportDestroyProgress(opts.OnProgress, nodeID, modName, "module", "completed", "Root module destroyed", nil)
+		if opts.Output != nil {
+			fmt.Fprintf(opts.Output, "  [success] Module %q destroyed\n", modName)
+		}
+	}
+
+	// Phase 3: Purge the datacenter's state, including its component
+	// registrations
+	if err := e.stateManager.DeleteDatacenter(ctx, opts.Datacenter); err != nil {
+		return fail(fmt.Errorf("failed to delete datacenter state: %w", err))
+	}
+
+	result.Success = true
+	result.Duration = time.Since(startTime)
+	return result, nil
+}
+
+// destroyDatacenterEnvironment destroys an environment of a datacenter being
+// torn down and deletes its state, holding its lock throughout.
+func (e *Engine) destroyDatacenterEnvironment(ctx context.Context, opts DestroyDatacenterOptions, envName string) error {
+	ctx, unlock, err := e.lockEnvironment(ctx, opts.Datacenter, envName, "destroy datacenter", false, opts.Output)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := e.DestroyEnvironment(ctx, opts.Datacenter, envName, opts.Output, opts.OnProgress); err != nil {
+		return fmt.Errorf("failed to destroy environment %q: %w", envName, err)
+	}
+	if err := e.stateManager.DeleteEnvironment(ctx, opts.Datacenter, envName); err != nil {
+		return fmt.Errorf("failed to delete environment state %q: %w", envName, err)
+	}
+	return nil
+}
+
+// destroyModule destroys a datacenter- or environment-level module from its
+// stored state. Modules that never applied have nothing to destroy, and
+// modules adopted without their source can only be forgotten.
+func (e *Engine) destroyModule(ctx context.Context, dc datacenter.Datacenter, modName string, modState *types.ModuleState) error {
+	if modState == nil || modState.Plugin == "" || (modState.Status == types.ModuleStatusFailed && modState.IaCState == nil) {
+		return nil
+	}
+	if modState.Adopted && modState.Source == "" {
+		return nil
+	}
+
+	plugin, err := e.iacRegistry.Get(modState.Plugin)
+	if err != nil {
+		return fmt.Errorf("could not get plugin %q: %w", modState.Plugin, err)
+	}
+	credEnv, err := executor.CredentialEnvironment(ctx, modState.Credentials, executor.CredentialSessionName(modName), nil)
+	if err != nil {
+		return fmt.Errorf("could not scope credentials: %w", err)
+	}
+	sandbox, err := executor.ModuleSandbox(dc, modName, modState.Plugin, plugin)
+	if err != nil {
+		return err
+	}
+
+	runOpts := iac.RunOptions{
+		ModuleSource: modState.Source,
+		Inputs:       modState.Inputs,
+		Environment:  credEnv,
+		Sandbox:      sandbox,
+	}
+	if modState.IaCState != nil {
+		runOpts.StateReader = bytes.NewReader(modState.IaCState)
+	}
+	return plugin.Destroy(ctx, runOpts)
+}
+
+func reportDestroyProgress(onProgress executor.ProgressCallback, nodeID, name, nodeType, status, message string, err error) {
+	if onProgress == nil {
+		return
+	}
+	onProgress(executor.ProgressEvent{
+		NodeID:   nodeID,
+		NodeName: name,
+		NodeType: nodeType,
+		Status:   status,
+		Message:  message,
+		Error:    err,
+	})
+}
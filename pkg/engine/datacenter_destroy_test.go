@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// destroyRecorder is an IaC plugin that records the modules it destroys and
+// fails for module sources listed in failOn.
+type destroyRecorder struct {
+	destroyed []string
+	failOn    map[string]bool
+}
+
+func (p *destroyRecorder) Name() string { return "recorder" }
+
+func (p *destroyRecorder) Preview(ctx context.Context, opts iac.RunOptions) (*iac.PreviewResult, error) {
+	return &iac.PreviewResult{}, nil
+}
+
+func (p *destroyRecorder) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResult, error) {
+	return &iac.ApplyResult{}, nil
+}
+
+func (p *destroyRecorder) Destroy(ctx context.Context, opts iac.RunOptions) error {
+	if p.failOn[opts.ModuleSource] {
+		return errors.New("resource still in use")
+	}
+	if opts.StateReader == nil {
+		return fmt.Errorf("module %s destroyed without its IaC state", opts.ModuleSource)
+	}
+	p.destroyed = append(p.destroyed, opts.ModuleSource)
+	return nil
+}
+
+func (p *destroyRecorder) Refresh(ctx context.Context, opts iac.RunOptions) (*iac.RefreshResult, error) {
+	return &iac.RefreshResult{}, nil
+}
+
+func (p *destroyRecorder) Import(ctx context.Context, opts iac.ImportOptions) (*iac.ImportResult, error) {
+	return &iac.ImportResult{}, nil
+}
+
+func TestDestroyDatacenter_ResumesAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dcFile := filepath.Join(dir, "datacenter.dc")
+	if err := os.WriteFile(dcFile, []byte(`
+module "network" {
+  plugin = "recorder"
+  build  = "./modules/network"
+}
+
+module "cluster" {
+  plugin = "recorder"
+  build  = "./modules/cluster"
+  inputs = {
+    vpc = module.network.vpc_id
+  }
+}
+
+environment {
+  module "namespace" {
+    plugin = "recorder"
+    build  = "./modules/namespace"
+  }
+}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	moduleState := func(name string) *types.ModuleState {
+		return &types.ModuleState{Name: name, Plugin: "recorder", Source: name, IaCState: []byte("{}"), Status: types.ModuleStatusReady}
+	}
+
+	mgr := newMockStateManager()
+	mgr.datacenter = &types.DatacenterState{
+		Name:    "prod-dc",
+		Version: dcFile,
+		Modules: map[string]*types.ModuleState{
+			"network": moduleState("network"),
+			"cluster": moduleState("cluster"),
+		},
+	}
+	_ = mgr.SaveEnvironment(ctx, "prod-dc", &types.EnvironmentState{
+		Name:       "staging",
+		Datacenter: "prod-dc",
+		Modules:    map[string]*types.ModuleState{"namespace": moduleState("namespace")},
+	})
+
+	plugin := &destroyRecorder{failOn: map[string]bool{"network": true}}
+	registry := iac.NewRegistry()
+	registry.Register("recorder", func() (iac.Plugin, error) { return plugin, nil })
+	eng := NewEngine(mgr, registry)
+
+	result, err := eng.DestroyDatacenter(ctx, DestroyDatacenterOptions{Datacenter: "prod-dc"})
+	if err == nil {
+		t.Fatal("expected teardown to stop when a root module fails to destroy")
+	}
+	if fmt.Sprint(result.DestroyedEnvironments) != "[staging]" || fmt.Sprint(result.DestroyedModules) != "[cluster]" {
+		t.Errorf("unexpected partial result: environments %v, modules %v", result.DestroyedEnvironments, result.DestroyedModules)
+	}
+	if _, ok := mgr.environments["staging"]; ok {
+		t.Error("expected destroyed environment state to be deleted")
+	}
+	if mgr.datacenter == nil || len(mgr.datacenter.Modules) != 1 || mgr.datacenter.Modules["network"] == nil {
+		t.Fatalf("expected only the failed module to remain in state, got %+v", mgr.datacenter)
+	}
+
+	// Resume once the blocking resource is gone
+	plugin.failOn = nil
+	result, err = eng.DestroyDatacenter(ctx, DestroyDatacenterOptions{Datacenter: "prod-dc"})
+	if err != nil {
+		t.Fatalf("expected resumed teardown to succeed, got %v", err)
+	}
+	if !result.Success || fmt.Sprint(result.DestroyedModules) != "[network]" {
+		t.Errorf("unexpected resumed result: %+v", result)
+	}
+	if fmt.Sprint(plugin.destroyed) != "[namespace cluster network]" {
+		t.Errorf("expected reverse-order teardown, got %v", plugin.destroyed)
+	}
+	if mgr.datacenter != nil {
+		t.Error("expected datacenter state to be purged")
+	}
+}
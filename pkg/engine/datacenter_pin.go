@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/davidthor/cldctl/pkg/engine/executor"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// datacenterPinned reports whether an environment is pinned to a datacenter
+// version other than the one currently deployed.
+func datacenterPinned(envState *types.EnvironmentState, dcState *types.DatacenterState) bool {
+	return envState != nil && dcState != nil &&
+		envState.DatacenterVersion != "" && envState.DatacenterVersion != dcState.Version
+}
+
+// environmentDatacenterVersion returns the datacenter version an environment
+// was provisioned with: its pin, or the deployed version when unpinned.
+func environmentDatacenterVersion(envState *types.EnvironmentState, dcState *types.DatacenterState) string {
+	if envState != nil && envState.DatacenterVersion != "" {
+		return envState.DatacenterVersion
+	}
+	return dcState.Version
+}
+
+// checkDatacenterPin refuses to plan an environment pinned to a different
+// datacenter version than the one deployed, since its resources would mix
+// hooks and modules from both versions. With upgrade set, the environment is
+// re-pinned to the deployed version instead. Environments that don't exist
+// yet are never pinned.
+func (e *Engine) checkDatacenterPin(ctx context.Context, dcName string, dcState *types.DatacenterState, envName string, upgrade, dryRun bool) error {
+	envState, err := e.stateManager.GetEnvironment(ctx, dcName, envName)
+	if err != nil || !datacenterPinned(envState, dcState) {
+		return nil
+	}
+	if !upgrade {
+		return fmt.Errorf("environment %q is pinned to datacenter version %s, but datacenter %q is at %s; re-run with --upgrade-datacenter to adopt it",
+			envName, envState.DatacenterVersion, dcName, dcState.Version)
+	}
+	if dryRun {
+		return nil
+	}
+	envState.DatacenterVersion = dcState.Version
+	if err := e.stateManager.SaveEnvironment(ctx, dcName, envState); err != nil {
+		return fmt.Errorf("failed to re-pin environment %q: %w", envName, err)
+	}
+	return nil
+}
+
+// PinDatacenter pins an environment to the datacenter version currently
+// deployed, or unpins it when pin is false. A pinned environment isn't
+// reconciled when a new datacenter version is deployed, and deploys to it are
+// refused until it is upgraded. It returns the pinned version.
+func (e *Engine) PinDatacenter(ctx context.Context, dcName, envName string, pin bool) (string, error) {
+	lockedCtx, unlock, err := e.lockEnvironment(ctx, dcName, envName, "pin datacenter", false, nil)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	ctx = lockedCtx
+
+	dcState, err := e.stateManager.GetDatacenter(ctx, dcName)
+	if err != nil {
+		return "", fmt.Errorf("datacenter %q not found: %w", dcName, err)
+	}
+	envState, err := e.stateManager.GetEnvironment(ctx, dcName, envName)
+	if err != nil {
+		return "", fmt.Errorf("environment %q not found in datacenter %q: %w", envName, dcName, err)
+	}
+
+	if !pin {
+		envState.DatacenterVersion = ""
+	} else if envState.DatacenterVersion == "" {
+		envState.DatacenterVersion = dcState.Version
+	} else if envState.DatacenterVersion != dcState.Version {
+		return "", fmt.Errorf("environment %q is already pinned to datacenter version %s; use --upgrade-datacenter to move it to %s",
+			envName, envState.DatacenterVersion, dcState.Version)
+	}
+
+	if err := e.stateManager.SaveEnvironment(ctx, dcName, envState); err != nil {
+		return "", fmt.Errorf("failed to save environment %q: %w", envName, err)
+	}
+	return envState.DatacenterVersion, nil
+}
+
+// UpgradeEnvironmentDatacenter re-pins an environment to the datacenter
+// version currently deployed and reconciles its modules and components
+// against it, as a datacenter deploy would.
+func (e *Engine) UpgradeEnvironmentDatacenter(ctx context.Context, dcName, envName string, output io.Writer, onProgress executor.ProgressCallback, parallelism int) error {
+	return e.reconcileEnvironment(ctx, DeployDatacenterOptions{
+		Datacenter:        dcName,
+		Output:            output,
+		OnProgress:        onProgress,
+		Parallelism:       parallelism,
+		UpgradeDatacenter: true,
+	}, envName)
+}
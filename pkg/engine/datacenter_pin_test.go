@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func TestCheckDatacenterPin(t *testing.T) {
+	ctx := context.Background()
+	mgr := newMockStateManager()
+	dcState := &types.DatacenterState{Name: "prod-dc", Version: "ghcr.io/myorg/dc:v2"}
+	eng := NewEngine(mgr, nil)
+
+	_ = mgr.SaveEnvironment(ctx, "prod-dc", &types.EnvironmentState{Name: "staging", Datacenter: "prod-dc"})
+	_ = mgr.SaveEnvironment(ctx, "prod-dc", &types.EnvironmentState{Name: "production", Datacenter: "prod-dc", DatacenterVersion: "ghcr.io/myorg/dc:v1"})
+
+	// Unpinned and new environments follow the datacenter
+	if err := eng.checkDatacenterPin(ctx, "prod-dc", dcState, "staging", false, false); err != nil {
+		t.Errorf("expected unpinned environment to be allowed, got %v", err)
+	}
+	if err := eng.checkDatacenterPin(ctx, "prod-dc", dcState, "preview", false, false); err != nil {
+		t.Errorf("expected new environment to be allowed, got %v", err)
+	}
+
+	err := eng.checkDatacenterPin(ctx, "prod-dc", dcState, "production", false, false)
+	if err == nil || !strings.Contains(err.Error(), "--upgrade-datacenter") {
+		t.Fatalf("expected mixed-version deploy to be refused, got %v", err)
+	}
+
+	// A dry run doesn't re-pin
+	if err := eng.checkDatacenterPin(ctx, "prod-dc", dcState, "production", true, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := mgr.environments["production"].DatacenterVersion; got != "ghcr.io/myorg/dc:v1" {
+		t.Errorf("expected dry run to keep the pin, got %s", got)
+	}
+
+	if err := eng.checkDatacenterPin(ctx, "prod-dc", dcState, "production", true, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := mgr.environments["production"].DatacenterVersion; got != "ghcr.io/myorg/dc:v2" {
+		t.Errorf("expected environment to be re-pinned to v2, got %s", got)
+	}
+}
+
+func TestPinDatacenter(t *testing.T) {
+	ctx := context.Background()
+	mgr := newMockStateManager()
+	mgr.datacenter = &types.DatacenterState{Name: "prod-dc", Version: "ghcr.io/myorg/dc:v1"}
+	eng := NewEngine(mgr, nil)
+	_ = mgr.SaveEnvironment(ctx, "prod-dc", &types.EnvironmentState{Name: "production", Datacenter: "prod-dc"})
+
+	version, err := eng.PinDatacenter(ctx, "prod-dc", "production", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if version != "ghcr.io/myorg/dc:v1" || mgr.environments["production"].DatacenterVersion != version {
+		t.Errorf("expected environment pinned to v1, got %q", version)
+	}
+
+	// Pinning again after a new release must go through an upgrade
+	mgr.datacenter.Version = "ghcr.io/myorg/dc:v2"
+	if _, err := eng.PinDatacenter(ctx, "prod-dc", "production", true); err == nil {
+		t.Error("expected re-pinning to a new version to be refused")
+	}
+
+	if _, err := eng.PinDatacenter(ctx, "prod-dc", "production", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := mgr.environments["production"].DatacenterVersion; got != "" {
+		t.Errorf("expected environment to be unpinned, got %q", got)
+	}
+}
+
+func TestPrintDatacenterPlanSummary_PinnedEnvironment(t *testing.T) {
+	eng := NewEngine(newMockStateManager(), nil)
+	var buf strings.Builder
+	eng.PrintDatacenterPlanSummary(&buf, &DatacenterPlan{
+		Datacenter: "prod-dc",
+		EnvironmentReconciliations: []EnvironmentReconciliation{
+			{Name: "staging", ComponentCount: 2},
+			{Name: "production", ComponentCount: 5, PinnedVersion: "ghcr.io/myorg/dc:v1"},
+		},
+	})
+	out := buf.String()
+	if !strings.Contains(out, "= production (pinned to ghcr.io/myorg/dc:v1") {
+		t.Errorf("expected pinned environment to be marked, got:\n%s", out)
+	}
+	if !strings.Contains(out, "1 environment(s) to reconcile") {
+		t.Errorf("expected pinned environment to be excluded from the count, got:\n%s", out)
+	}
+}
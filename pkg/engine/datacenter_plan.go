@@ -0,0 +1,381 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/davidthor/cldctl/pkg/engine/executor"
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// ResourceHookChange describes an environment resource that the new
+// datacenter version would provision with a different hook.
+type ResourceHookChange struct {
+	Component string
+	Type      string
+	Name      string
+	// From and To describe the hooks before and after the change (e.g.,
+	// "database (when ...)"). "none" means no hook provisions the resource.
+	From string
+	To   string
+}
+
+// planModuleChange compares a datacenter or environment module against the
+// state it was last applied with. oldDC and oldMod describe the datacenter
+// version that state came from; oldMod is nil when that version didn't
+// declare the module or can't be loaded.
+func planModuleChange(newDC datacenter.Datacenter, mod datacenter.Module, desiredInputs map[string]interface{}, oldDC datacenter.Datacenter, oldMod datacenter.Module, existing *types.ModuleState, digests dirDigests) DatacenterModuleChange {
+	change := DatacenterModuleChange{
+		Name:   mod.Name(),
+		Plugin: modulePlugin(mod),
+	}
+
+	if existing == nil {
+		change.Action = "create"
+		return change
+	}
+
+	change.InputChanges = planner.NewPlanner().CompareInputs(desiredInputs, existing.Inputs)
+	if len(change.InputChanges) > 0 || change.Plugin != existing.Plugin || moduleCodeChanged(oldDC, oldMod, newDC, mod, digests) {
+		change.Action = "update"
+	} else {
+		change.Action = "noop"
+	}
+	return change
+}
+
+// refreshModuleChanges refreshes the recorded state of planned modules with
+// the plugins that can read back real infrastructure (see
+// iac.RefreshesState). Modules whose infrastructure drifted from their state
+// are planned to be updated, since applying them restores it. Nothing is
+// written to state.
+func (e *Engine) refreshModuleChanges(ctx context.Context, changes []DatacenterModuleChange, states map[string]*types.ModuleState) error {
+	for i := range changes {
+		change := &changes[i]
+		ms := states[change.Name]
+		if change.Action == "create" || change.Action == "delete" || ms == nil || len(ms.IaCState) == 0 {
+			continue
+		}
+		plugin, err := e.iacRegistry.Get(ms.Plugin)
+		if err != nil || !iac.RefreshesState(plugin) {
+			continue
+		}
+		credEnv, err := executor.CredentialEnvironment(ctx, ms.Credentials, executor.CredentialSessionName(change.Name), nil)
+		if err != nil {
+			return fmt.Errorf("module %s: %w", change.Name, err)
+		}
+		result, err := plugin.Refresh(ctx, iac.RunOptions{
+			ModuleSource: ms.Source,
+			Inputs:       ms.Inputs,
+			Environment:  credEnv,
+			StateReader:  bytes.NewReader(ms.IaCState),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to refresh module %s: %w", change.Name, err)
+		}
+		change.Drifts = result.Drifts
+		if len(change.Drifts) > 0 && change.Action == "noop" {
+			change.Action = "update"
+		}
+	}
+	return nil
+}
+
+// planEnvironmentModules plans the environment modules of one environment,
+// evaluating inputs the way DeployEnvironment does.
+func planEnvironmentModules(dc, oldDC datacenter.Datacenter, envState *types.EnvironmentState, dcState *types.DatacenterState, dcVars map[string]interface{}, digests dirDigests) []DatacenterModuleChange {
+	var modules []datacenter.Module
+	if dc.Environment() != nil {
+		modules = dc.Environment().Modules()
+	}
+	var oldModules []datacenter.Module
+	if oldDC != nil && oldDC.Environment() != nil {
+		oldModules = oldDC.Environment().Modules()
+	}
+
+	rootOutputs := make(map[string]map[string]interface{})
+	if dcState != nil {
+		for name, mod := range dcState.Modules {
+			if mod != nil && mod.Outputs != nil {
+				rootOutputs[name] = mod.Outputs
+			}
+		}
+	}
+	extras := map[string]string{
+		"environment.name": envState.Name,
+	}
+	for name, outputs := range environmentModuleOutputs(envState) {
+		for out, val := range outputs {
+			extras["environment.module."+name+"."+out] = fmt.Sprintf("%v", val)
+		}
+	}
+
+	var changes []DatacenterModuleChange
+	for _, mod := range modules {
+		desiredInputs := make(map[string]interface{})
+		for inputName, exprStr := range mod.Inputs() {
+			desiredInputs[inputName] = evaluateModuleExpression(exprStr, dcVars, rootOutputs, extras)
+		}
+		changes = append(changes, planModuleChange(dc, mod, desiredInputs, oldDC, findModule(oldModules, mod.Name()), envState.Modules[mod.Name()], digests))
+	}
+	return append(changes, removedModules(modules, envState.Modules)...)
+}
+
+// findModule returns the module named name, or nil.
+func findModule(modules []datacenter.Module, name string) datacenter.Module {
+	for _, mod := range modules {
+		if mod.Name() == name {
+			return mod
+		}
+	}
+	return nil
+}
+
+// removedModules returns a delete change for each module in states that
+// declared doesn't include, sorted by name. Adopted modules were never
+// declared, so they aren't deleted.
+func removedModules(declared []datacenter.Module, states map[string]*types.ModuleState) []DatacenterModuleChange {
+	names := make(map[string]bool, len(declared))
+	for _, mod := range declared {
+		names[mod.Name()] = true
+	}
+
+	var changes []DatacenterModuleChange
+	for name, ms := range states {
+		if names[name] || ms == nil || ms.Adopted {
+			continue
+		}
+		changes = append(changes, DatacenterModuleChange{
+			Name:   name,
+			Plugin: ms.Plugin,
+			Action: "delete",
+		})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+// moduleCodeChanged reports whether a module's code differs between two
+// datacenter versions: a different OCI source, or different files in its
+// build directory. A module with no previous version is treated as changed.
+func moduleCodeChanged(oldDC datacenter.Datacenter, oldMod datacenter.Module, newDC datacenter.Datacenter, newMod datacenter.Module, digests dirDigests) bool {
+	if oldDC == nil || oldMod == nil {
+		return true
+	}
+	if oldMod.Source() != "" || newMod.Source() != "" {
+		return oldMod.Source() != newMod.Source()
+	}
+
+	oldDigest, err := digests.digest(modulePath(oldDC, oldMod))
+	if err != nil {
+		return true
+	}
+	newDigest, err := digests.digest(modulePath(newDC, newMod))
+	if err != nil {
+		return true
+	}
+	return oldDigest != newDigest
+}
+
+// modulePath resolves a module's build directory or source reference.
+func modulePath(dc datacenter.Datacenter, mod datacenter.Module) string {
+	path := mod.Build()
+	if path == "" {
+		return mod.Source()
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(dc.SourcePath()), path)
+	}
+	return path
+}
+
+func modulePlugin(mod datacenter.Module) string {
+	if mod.Plugin() == "" {
+		return "native"
+	}
+	return mod.Plugin()
+}
+
+// dirDigests memoizes the digests of module directories, which many hooks
+// and resources share.
+type dirDigests map[string]string
+
+// digest hashes the relative paths and contents of every file under dir.
+func (d dirDigests) digest(dir string) (string, error) {
+	if sum, ok := d[dir]; ok {
+		return sum, nil
+	}
+	h := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fmt.Fprintf(h, "%s\x00", filepath.ToSlash(rel))
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	d[dir] = hex.EncodeToString(h.Sum(nil))
+	return d[dir], nil
+}
+
+// planHookChanges returns the resources of an environment that newDC would
+// provision with a different hook than oldDC: one whose when clause selects
+// another hook, or whose matching hook's modules or outputs changed. Nothing
+// is reported when the old datacenter version can't be loaded.
+func planHookChanges(oldDC, newDC datacenter.Datacenter, envState *types.EnvironmentState, digests dirDigests) []ResourceHookChange {
+	if oldDC == nil || envState == nil {
+		return nil
+	}
+	var changes []ResourceHookChange
+
+	compNames := make([]string, 0, len(envState.Components))
+	for name := range envState.Components {
+		compNames = append(compNames, name)
+	}
+	sort.Strings(compNames)
+
+	for _, compName := range compNames {
+		compState := envState.Components[compName]
+		if compState == nil {
+			continue
+		}
+		resources := make(map[string]*types.ResourceState)
+		for key, rs := range compState.Resources {
+			resources[key] = rs
+		}
+		for instName, inst := range compState.Instances {
+			if inst == nil {
+				continue
+			}
+			for key, rs := range inst.Resources {
+				resources[instName+"/"+key] = rs
+			}
+		}
+
+		keys := make([]string, 0, len(resources))
+		for key := range resources {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			rs := resources[key]
+			if rs == nil || rs.Type == "" {
+				continue
+			}
+			nodeType := graph.NodeType(rs.Type)
+			oldLabel, oldPrint := planHook(oldDC, nodeType, rs.Inputs, digests)
+			newLabel, newPrint := planHook(newDC, nodeType, rs.Inputs, digests)
+			if oldPrint == newPrint {
+				continue
+			}
+			changes = append(changes, ResourceHookChange{
+				Component: compName,
+				Type:      rs.Type,
+				Name:      rs.Name,
+				From:      oldLabel,
+				To:        newLabel,
+			})
+		}
+	}
+	return changes
+}
+
+// planHook describes the hook dc selects for a resource with the given
+// inputs, and returns a fingerprint of its definition.
+func planHook(dc datacenter.Datacenter, nodeType graph.NodeType, inputs map[string]interface{}, digests dirDigests) (label, fingerprint string) {
+	if dc == nil || dc.Environment() == nil || dc.Environment().Hooks() == nil {
+		return "none", ""
+	}
+	hooks := hooksForType(dc.Environment().Hooks(), nodeType)
+	h, i := datacenter.MatchHook(hooks, func(h datacenter.Hook) bool {
+		matched, ok := evaluateHookWhen(h.When(), inputs)
+		return ok && matched
+	})
+	if h == nil {
+		return "none", ""
+	}
+	label = string(nodeType)
+	switch {
+	case h.When() != "":
+		label += fmt.Sprintf(" (when %s)", h.When())
+	case len(hooks) > 1:
+		label += fmt.Sprintf("[%d]", i)
+	}
+	if h.Error() != "" {
+		label += " error"
+	}
+	return label, hookFingerprint(dc, h, digests)
+}
+
+// hookFingerprint serializes the parts of a hook that determine how it
+// provisions a resource, with module code reduced to a digest.
+func hookFingerprint(dc datacenter.Datacenter, hook datacenter.Hook, digests dirDigests) string {
+	type moduleFingerprint struct {
+		Name   string
+		Plugin string
+		Code   string
+		Inputs map[string]string
+		When   string
+	}
+	fp := struct {
+		When          string
+		Error         string
+		Modules       []moduleFingerprint
+		Outputs       map[string]string
+		NestedOutputs map[string]map[string]string
+	}{
+		When:          hook.When(),
+		Error:         hook.Error(),
+		Outputs:       hook.Outputs(),
+		NestedOutputs: hook.NestedOutputs(),
+	}
+	for _, mod := range hook.Modules() {
+		code := mod.Source()
+		if code == "" {
+			digest, err := digests.digest(modulePath(dc, mod))
+			if err != nil {
+				// Unreadable code can't be compared; fall back to the path
+				digest = mod.Build()
+			}
+			code = digest
+		}
+		fp.Modules = append(fp.Modules, moduleFingerprint{
+			Name:   mod.Name(),
+			Plugin: modulePlugin(mod),
+			Code:   code,
+			Inputs: mod.Inputs(),
+			When:   mod.When(),
+		})
+	}
+	data, _ := json.Marshal(fp)
+	return string(data)
+}
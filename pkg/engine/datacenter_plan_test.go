@@ -0,0 +1,279 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func loadPlanDatacenter(t *testing.T, dir, content string) datacenter.Datacenter {
+	t.Helper()
+	dc, err := datacenter.NewLoader().LoadFromBytes([]byte(content), filepath.Join(dir, "datacenter.dc"))
+	if err != nil {
+		t.Fatalf("failed to load datacenter: %v", err)
+	}
+	return dc
+}
+
+func TestPlanHookChanges(t *testing.T) {
+	dir := t.TempDir()
+	oldDC := loadPlanDatacenter(t, dir, `
+environment {
+  database {
+    module "db" {
+      source = "ghcr.io/myorg/postgres:v1"
+    }
+    outputs = {
+      host = module.db.host
+      port = module.db.port
+      url  = module.db.url
+    }
+  }
+  bucket {
+    module "bucket" {
+      source = "ghcr.io/myorg/bucket:v1"
+    }
+    outputs = {
+      endpoint        = module.bucket.endpoint
+      bucket          = module.bucket.name
+      accessKeyId     = module.bucket.access_key_id
+      secretAccessKey = module.bucket.secret_access_key
+    }
+  }
+}
+`)
+	newDC := loadPlanDatacenter(t, dir, `
+environment {
+  database {
+    when = node.inputs.type == "postgres"
+    module "db" {
+      source = "ghcr.io/myorg/postgres:v2"
+    }
+    outputs = {
+      host = module.db.host
+      port = module.db.port
+      url  = module.db.url
+    }
+  }
+  database {
+    error = "Only postgres databases are supported"
+  }
+  bucket {
+    module "bucket" {
+      source = "ghcr.io/myorg/bucket:v1"
+    }
+    outputs = {
+      endpoint        = module.bucket.endpoint
+      bucket          = module.bucket.name
+      accessKeyId     = module.bucket.access_key_id
+      secretAccessKey = module.bucket.secret_access_key
+    }
+  }
+}
+`)
+
+	envState := &types.EnvironmentState{
+		Name: "production",
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Name: "api",
+				Resources: map[string]*types.ResourceState{
+					"database.main":    {Type: "database", Name: "main", Inputs: map[string]interface{}{"type": "postgres"}},
+					"database.cache":   {Type: "database", Name: "cache", Inputs: map[string]interface{}{"type": "redis"}},
+					"bucket.uploads":   {Type: "bucket", Name: "uploads"},
+					"deployment.api":   {Type: "deployment", Name: "api"},
+					"database.pending": nil,
+				},
+			},
+		},
+	}
+
+	changes := planHookChanges(oldDC, newDC, envState, dirDigests{})
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 hook changes, got %d: %+v", len(changes), changes)
+	}
+
+	// Sorted by resource key: database.cache, then database.main
+	if changes[0].Name != "cache" || changes[0].From != "database" || changes[0].To != "database[1] error" {
+		t.Errorf("unexpected change for cache: %+v", changes[0])
+	}
+	if changes[1].Name != "main" || changes[1].To != `database (when node.inputs.type == "postgres")` {
+		t.Errorf("unexpected change for main: %+v", changes[1])
+	}
+
+	if got := planHookChanges(nil, newDC, envState, dirDigests{}); got != nil {
+		t.Errorf("expected no hook changes without a previous datacenter, got %+v", got)
+	}
+}
+
+func TestPlanModuleChange_BuildDirectory(t *testing.T) {
+	dir := t.TempDir()
+	moduleDir := filepath.Join(dir, "modules", "vpc")
+	if err := os.MkdirAll(moduleDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(moduleDir, "main.tf"), []byte("# v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dc := loadPlanDatacenter(t, dir, `
+module "vpc" {
+  plugin = "opentofu"
+  build  = "./modules/vpc"
+  inputs = {
+    cidr = "10.0.0.0/16"
+  }
+}
+`)
+	mod := dc.Modules()[0]
+	inputs := map[string]interface{}{"cidr": "10.0.0.0/16"}
+	existing := &types.ModuleState{Name: "vpc", Plugin: "opentofu", Inputs: inputs}
+
+	if got := planModuleChange(dc, mod, inputs, nil, nil, nil, dirDigests{}); got.Action != "create" {
+		t.Errorf("expected create for a new module, got %s", got.Action)
+	}
+	if got := planModuleChange(dc, mod, inputs, dc, mod, existing, dirDigests{}); got.Action != "noop" {
+		t.Errorf("expected noop for unchanged module, got %s", got.Action)
+	}
+
+	changed := map[string]interface{}{"cidr": "10.1.0.0/16"}
+	got := planModuleChange(dc, mod, changed, dc, mod, existing, dirDigests{})
+	if got.Action != "update" || len(got.InputChanges) != 1 {
+		t.Errorf("expected update with one input change, got %s with %d", got.Action, len(got.InputChanges))
+	}
+
+	// Same declaration, different code on disk
+	newDir := filepath.Join(t.TempDir(), "dc")
+	if err := os.MkdirAll(filepath.Join(newDir, "modules", "vpc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(newDir, "modules", "vpc", "main.tf"), []byte("# v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	newDC := loadPlanDatacenter(t, newDir, `
+module "vpc" {
+  plugin = "opentofu"
+  build  = "./modules/vpc"
+  inputs = {
+    cidr = "10.0.0.0/16"
+  }
+}
+`)
+	if got := planModuleChange(newDC, newDC.Modules()[0], inputs, dc, mod, existing, dirDigests{}); got.Action != "update" {
+		t.Errorf("expected update when module code changed, got %s", got.Action)
+	}
+}
+
+func TestRemovedModules(t *testing.T) {
+	dc := loadPlanDatacenter(t, t.TempDir(), `
+module "vpc" {
+  build = "./modules/vpc"
+}
+`)
+	changes := removedModules(dc.Modules(), map[string]*types.ModuleState{
+		"vpc":   {Name: "vpc"},
+		"redis": {Name: "redis", Plugin: "opentofu"},
+		"dns":   {Name: "dns", Plugin: "pulumi"},
+		"vault": {Name: "vault", Plugin: "opentofu", Adopted: true},
+	})
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 deletes, got %+v", changes)
+	}
+	if changes[0].Name != "dns" || changes[1].Name != "redis" || changes[1].Action != "delete" || changes[1].Plugin != "opentofu" {
+		t.Errorf("unexpected delete changes: %+v", changes)
+	}
+}
+
+func TestPrintDatacenterPlanSummary_EnvironmentChanges(t *testing.T) {
+	eng := NewEngine(newMockStateManager(), nil)
+	plan := &DatacenterPlan{
+		Datacenter: "prod-dc",
+		EnvironmentReconciliations: []EnvironmentReconciliation{{
+			Name:           "production",
+			ComponentCount: 1,
+			ModuleChanges: []DatacenterModuleChange{
+				{Name: "dns", Plugin: "opentofu", Action: "create"},
+				{Name: "otel", Action: "noop"},
+			},
+			HookChanges: []ResourceHookChange{
+				{Component: "api", Type: "database", Name: "main", From: "database", To: "database"},
+			},
+		}},
+	}
+
+	var buf bytes.Buffer
+	eng.PrintDatacenterPlanSummary(&buf, plan)
+	out := buf.String()
+
+	for _, want := range []string{
+		"+ module/dns (opentofu)",
+		"~ api/database.main: database hook changed",
+		"Plan: 1 module(s) to create, 0 to update, 0 to delete, 1 environment(s) to reconcile, 1 resource(s) with changed hooks",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "module/otel") {
+		t.Errorf("expected unchanged environment modules to be omitted, got:\n%s", out)
+	}
+}
+
+func TestRefreshModuleChanges(t *testing.T) {
+	drifted := &prober{drifts: []iac.ResourceDrift{
+		{ResourceID: "aws_security_group.default", Diffs: []iac.PropertyDiff{{Path: "ingress"}, {Path: "tags"}}},
+		{ResourceID: "aws_route53_record.api", Diffs: []iac.PropertyDiff{{Path: iac.DriftPathExists, OldValue: true, NewValue: false}}},
+	}}
+	registry := iac.NewRegistry()
+	registry.Register("drifted", func() (iac.Plugin, error) { return drifted, nil })
+	registry.Register("steady", func() (iac.Plugin, error) { return &prober{}, nil })
+	registry.Register("recorder", func() (iac.Plugin, error) { return &hookRecorder{}, nil })
+	eng := NewEngine(newMockStateManager(), registry)
+
+	state := func(plugin string) *types.ModuleState {
+		return &types.ModuleState{Plugin: plugin, IaCState: []byte("{}")}
+	}
+	changes := []DatacenterModuleChange{
+		{Name: "vpc", Action: "noop"},
+		{Name: "dns", Action: "noop"},
+		{Name: "cache", Action: "noop"},
+		{Name: "queue", Action: "create"},
+	}
+	states := map[string]*types.ModuleState{
+		"vpc":   state("drifted"),
+		"dns":   state("steady"),
+		"cache": state("recorder"),
+		"queue": state("drifted"),
+	}
+	if err := eng.refreshModuleChanges(context.Background(), changes, states); err != nil {
+		t.Fatalf("refreshModuleChanges failed: %v", err)
+	}
+
+	want := map[string]string{"vpc": "update", "dns": "noop", "cache": "noop", "queue": "create"}
+	for _, c := range changes {
+		if c.Action != want[c.Name] {
+			t.Errorf("module %s action = %s, want %s", c.Name, c.Action, want[c.Name])
+		}
+	}
+	if len(changes[3].Drifts) != 0 {
+		t.Errorf("expected a module being created not to be refreshed, got %v", changes[3].Drifts)
+	}
+
+	var buf bytes.Buffer
+	printDatacenterModuleChange(&buf, "  ", changes[0])
+	for _, line := range []string{
+		"! aws_security_group.default drifted: ingress, tags",
+		"! aws_route53_record.api no longer exists",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expected %q in output:\n%s", line, buf.String())
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"path"
+)
+
+// DatacenterRolloutStage groups environments that a datacenter deploy
+// reconciles together, e.g. all development environments before staging.
+type DatacenterRolloutStage struct {
+	Name string
+
+	// Environments holds environment names or glob patterns (e.g., "dev-*").
+	// An environment belongs to the first stage with a matching pattern.
+	Environments []string
+}
+
+// rolloutStage is a DatacenterRolloutStage resolved to concrete environments.
+type rolloutStage struct {
+	DatacenterRolloutStage
+	environments []string
+}
+
+// rolloutStages assigns environments to stages, preserving the order of envs
+// within each stage. Stages that match no environment are dropped, and
+// unmatched environments form a final stage.
+func rolloutStages(stages []DatacenterRolloutStage, envs []string) []rolloutStage {
+	resolved := make([]rolloutStage, len(stages))
+	for i, stage := range stages {
+		resolved[i].DatacenterRolloutStage = stage
+	}
+
+	var unmatched []string
+	for _, env := range envs {
+		matched := false
+		for i, stage := range stages {
+			if matchesEnvironment(stage.Environments, env) {
+				resolved[i].environments = append(resolved[i].environments, env)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			unmatched = append(unmatched, env)
+		}
+	}
+
+	var result []rolloutStage
+	for _, stage := range resolved {
+		if len(stage.environments) > 0 {
+			result = append(result, stage)
+		}
+	}
+	if len(unmatched) > 0 {
+		name := ""
+		if len(stages) > 0 {
+			name = "remaining"
+		}
+		result = append(result, rolloutStage{
+			DatacenterRolloutStage: DatacenterRolloutStage{Name: name},
+			environments:           unmatched,
+		})
+	}
+	return result
+}
+
+func matchesEnvironment(patterns []string, env string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, env); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// stageEnvironments returns the environments of all stages in rollout order.
+func stageEnvironments(stages []rolloutStage) []string {
+	var envs []string
+	for _, stage := range stages {
+		envs = append(envs, stage.environments...)
+	}
+	return envs
+}
+
+// reconcileEnvironment re-deploys an environment's modules and components
+// against the current datacenter version.
+func (e *Engine) reconcileEnvironment(ctx context.Context, opts DeployDatacenterOptions, envName string) error {
+	lockedCtx, unlock, err := e.lockEnvironment(ctx, opts.Datacenter, envName, "reconcile", false, opts.Output)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	ctx = lockedCtx
+
+	envState, err := e.stateManager.GetEnvironment(ctx, opts.Datacenter, envName)
+	if err != nil {
+		return fmt.Errorf("could not load environment: %w", err)
+	}
+
+	// Re-deploy environment-scoped modules
+	if _, err := e.DeployEnvironment(ctx, DeployEnvironmentOptions{
+		Datacenter:        opts.Datacenter,
+		Environment:       envName,
+		Output:            opts.Output,
+		OnProgress:        opts.OnProgress,
+		Parallelism:       opts.Parallelism,
+		UpgradeDatacenter: opts.UpgradeDatacenter,
+	}); err != nil {
+		return fmt.Errorf("failed to reconcile environment modules: %w", err)
+	}
+
+	// Re-deploy each component with force update
+	components := make(map[string]string)
+	variables := make(map[string]map[string]interface{})
+	for compName, compState := range envState.Components {
+		if compState.Source != "" {
+			components[compName] = compState.Source
+		}
+		if compState.Variables != nil {
+			vars := make(map[string]interface{})
+			for k, v := range compState.Variables {
+				vars[k] = v
+			}
+			variables[compName] = vars
+		}
+	}
+	if len(components) == 0 {
+		return nil
+	}
+
+	if opts.Output != nil {
+		fmt.Fprintf(opts.Output, "  Reconciling %d component(s) in %q...\n", len(components), envName)
+	}
+
+	deployResult, err := e.Deploy(ctx, DeployOptions{
+		Environment:       envName,
+		Datacenter:        opts.Datacenter,
+		Components:        components,
+		Variables:         variables,
+		Output:            opts.Output,
+		Parallelism:       opts.Parallelism,
+		AutoApprove:       true,
+		OnProgress:        opts.OnProgress,
+		ForceUpdate:       true,
+		UpgradeDatacenter: opts.UpgradeDatacenter,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reconcile components: %w", err)
+	}
+	if !deployResult.Success {
+		return fmt.Errorf("failed to reconcile components")
+	}
+
+	if opts.Output != nil {
+		fmt.Fprintf(opts.Output, "  [success] Components in %q reconciled\n", envName)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRolloutStages(t *testing.T) {
+	envs := []string{"prod-us", "dev-alice", "staging", "sandbox", "dev-bob", "prod-eu"}
+	stages := rolloutStages([]DatacenterRolloutStage{
+		{Name: "dev", Environments: []string{"dev-*"}},
+		{Name: "qa", Environments: []string{"qa"}},
+		{Name: "staging", Environments: []string{"staging"}},
+		{Name: "prod", Environments: []string{"prod-*", "dev-*"}},
+	}, envs)
+
+	var got []string
+	for _, stage := range stages {
+		got = append(got, fmt.Sprintf("%s%v", stage.Name, stage.environments))
+	}
+	// qa matches nothing and is dropped; dev-* environments belong to the
+	// first stage that matches them; sandbox runs last
+	want := []string{"dev[dev-alice dev-bob]", "staging[staging]", "prod[prod-us prod-eu]", "remaining[sandbox]"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected stages %v, got %v", want, got)
+	}
+
+	order := stageEnvironments(stages)
+	if fmt.Sprint(order) != "[dev-alice dev-bob staging prod-us prod-eu sandbox]" {
+		t.Errorf("unexpected rollout order %v", order)
+	}
+}
+
+func TestRolloutStages_NoStages(t *testing.T) {
+	stages := rolloutStages(nil, []string{"dev", "prod"})
+	if len(stages) != 1 || stages[0].Name != "" || len(stages[0].environments) != 2 {
+		t.Errorf("expected a single unnamed stage with every environment, got %+v", stages)
+	}
+}
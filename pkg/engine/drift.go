@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/davidthor/cldctl/pkg/engine/executor"
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// PlanEnvironmentOptions configures PlanEnvironment.
+type PlanEnvironmentOptions struct {
+	// Environment name
+	Environment string
+
+	// Datacenter name
+	Datacenter string
+
+	// DetectDrift reads back the infrastructure of deployed resources and
+	// plans those that drifted from their state to be updated
+	DetectDrift bool
+
+	// Output writer for the plan summary
+	Output io.Writer
+}
+
+// PlanEnvironment plans re-deploying an environment's components from the
+// sources and variables they were last deployed with, without executing
+// anything. Besides drift, the plan shows what changed in the datacenter or
+// the components' sources since they were deployed.
+func (e *Engine) PlanEnvironment(ctx context.Context, opts PlanEnvironmentOptions) (*planner.Plan, error) {
+	envState, err := e.stateManager.GetEnvironment(ctx, opts.Datacenter, opts.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("environment %s not found in datacenter %s", opts.Environment, opts.Datacenter)
+	}
+
+	components, variables, err := recordedComponents(envState)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := e.Deploy(ctx, DeployOptions{
+		Environment: opts.Environment,
+		Datacenter:  opts.Datacenter,
+		Components:  components,
+		Variables:   variables,
+		Output:      opts.Output,
+		PlanOnly:    true,
+		DetectDrift: opts.DetectDrift,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Plan, nil
+}
+
+// recordedComponents returns the sources and variables an environment's
+// components were last deployed with.
+func recordedComponents(envState *types.EnvironmentState) (map[string]string, map[string]map[string]interface{}, error) {
+	components := make(map[string]string)
+	variables := make(map[string]map[string]interface{})
+	for compName, compState := range envState.Components {
+		if compState.Source == "" {
+			return nil, nil, fmt.Errorf("component %q has no recorded source; deploy it again to plan the environment", compName)
+		}
+		components[compName] = compState.Source
+		if compState.Variables != nil {
+			vars := make(map[string]interface{}, len(compState.Variables))
+			for k, v := range compState.Variables {
+				vars[k] = v
+			}
+			variables[compName] = vars
+		}
+	}
+	return components, variables, nil
+}
+
+// driftDetector returns a planner.DriftDetector that refreshes the hook
+// modules of deployed resources with the plugins that can read back real
+// infrastructure (see iac.RefreshesState). A resource drifted when a plugin
+// reports drifted resources, or when a module output read back differs from
+// the one recorded and the resource's recorded outputs carry that value.
+// Nothing is written to state.
+func (e *Engine) driftDetector(ctx context.Context) planner.DriftDetector {
+	return func(node *graph.Node, current *types.ResourceState) (*planner.Drift, error) {
+		names := make([]string, 0, len(current.ModuleStates))
+		for name := range current.ModuleStates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		drift := &planner.Drift{}
+		for _, name := range names {
+			ms := current.ModuleStates[name]
+			if ms == nil || len(ms.IaCState) == 0 {
+				continue
+			}
+			plugin, err := e.iacRegistry.Get(ms.Plugin)
+			if err != nil || !iac.RefreshesState(plugin) {
+				continue
+			}
+			credEnv, err := executor.CredentialEnvironment(ctx, ms.Credentials, executor.CredentialSessionName(name), nil)
+			if err != nil {
+				return nil, fmt.Errorf("module %s: %w", name, err)
+			}
+			result, err := plugin.Refresh(ctx, iac.RunOptions{
+				ModuleSource: ms.Source,
+				Inputs:       ms.Inputs,
+				Environment:  credEnv,
+				StateReader:  bytes.NewReader(ms.IaCState),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to refresh module %s: %w", name, err)
+			}
+
+			if len(result.Drifts) > 0 {
+				drift.Modules = append(drift.Modules, planner.ModuleDrift{Module: name, Plugin: ms.Plugin, Resources: result.Drifts})
+			}
+			drift.Outputs = append(drift.Outputs, driftedOutputs(current.Outputs, ms.Outputs, result.Outputs)...)
+		}
+
+		if drift.IsEmpty() {
+			return nil, nil
+		}
+		sort.Slice(drift.Outputs, func(i, j int) bool { return drift.Outputs[i].Path < drift.Outputs[j].Path })
+		return drift, nil
+	}
+}
+
+// driftedOutputs returns the resource outputs that carry a module output
+// whose value changed since it was recorded. Hook outputs are usually module
+// outputs passed through, so a recorded resource output equal to the
+// module's recorded value is taken to come from it.
+func driftedOutputs(resourceOutputs, recorded map[string]interface{}, refreshed map[string]iac.OutputValue) []planner.PropertyChange {
+	var changes []planner.PropertyChange
+	for key, value := range refreshed {
+		old, ok := recorded[key]
+		if !ok || reflect.DeepEqual(old, value.Value) {
+			continue
+		}
+		for path, out := range resourceOutputs {
+			if reflect.DeepEqual(out, old) {
+				changes = append(changes, planner.PropertyChange{Path: path, OldValue: old, NewValue: value.Value})
+			}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func TestDriftDetector(t *testing.T) {
+	registry := iac.NewRegistry()
+	registry.Register("prober", func() (iac.Plugin, error) {
+		return &prober{drifts: []iac.ResourceDrift{{
+			ResourceID:   "aws_db_instance.main",
+			ResourceType: "aws_db_instance",
+			Diffs:        []iac.PropertyDiff{{Path: "engine_version"}},
+		}}}, nil
+	})
+	registry.Register("recorder", func() (iac.Plugin, error) { return &hookRecorder{}, nil })
+	detect := NewEngine(newMockStateManager(), registry).driftDetector(context.Background())
+
+	node := graph.NewNode(graph.NodeTypeDatabase, "app", "main")
+	drift, err := detect(node, &types.ResourceState{
+		Outputs: map[string]interface{}{"id": "recorded-id", "port": 5432},
+		ModuleStates: map[string]*types.ModuleState{
+			"db": {Name: "db", Plugin: "prober", IaCState: []byte("recorded"), Outputs: map[string]interface{}{"id": "recorded-id"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if drift == nil || len(drift.Modules) != 1 || drift.Modules[0].Module != "db" {
+		t.Fatalf("expected the module's drifted resources, got %+v", drift)
+	}
+	if len(drift.Outputs) != 1 || drift.Outputs[0].Path != "id" || drift.Outputs[0].NewValue != "refreshed-id" {
+		t.Errorf("expected the output carrying the changed module output to drift, got %+v", drift.Outputs)
+	}
+
+	// Plugins that can't read back infrastructure report no drift
+	drift, err = detect(node, &types.ResourceState{
+		ModuleStates: map[string]*types.ModuleState{
+			"db": {Name: "db", Plugin: "recorder", IaCState: []byte("recorded")},
+		},
+	})
+	if err != nil || drift != nil {
+		t.Errorf("expected no drift, got %+v, %v", drift, err)
+	}
+}
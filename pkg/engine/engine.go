@@ -18,6 +18,7 @@ import (
 	"github.com/davidthor/cldctl/pkg/engine/planner"
 	"github.com/davidthor/cldctl/pkg/graph"
 	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/logging"
 	"github.com/davidthor/cldctl/pkg/oci"
 	"github.com/davidthor/cldctl/pkg/registry"
 	"github.com/davidthor/cldctl/pkg/schema/component"
@@ -28,6 +29,9 @@ import (
 	"github.com/davidthor/cldctl/pkg/state/types"
 )
 
+// engineLog is the logger for the engine's diagnostic output.
+var engineLog = logging.Logger(logging.Engine)
+
 // OCIClient defines the interface for OCI registry operations needed by the engine.
 type OCIClient interface {
 	Pull(ctx context.Context, reference string, destDir string) error
@@ -73,6 +77,21 @@ type DeployDatacenterOptions struct {
 
 	// DryRun only plans without executing
 	DryRun bool
+
+	// Stages orders environment reconciliation. Each stage is reconciled in
+	// turn; environments matching no stage are reconciled in a final stage.
+	// When empty, all environments are reconciled as a single stage.
+	Stages []DatacenterRolloutStage
+
+	// ApproveStage is called before each stage after the first. Returning
+	// false pauses the rollout, leaving later stages unreconciled. When nil,
+	// every stage proceeds.
+	ApproveStage func(stage DatacenterRolloutStage, environments []string) (bool, error)
+
+	// UpgradeDatacenter reconciles environments pinned to an older datacenter
+	// version too, re-pinning them to the deployed version. Otherwise they
+	// are skipped.
+	UpgradeDatacenter bool
 }
 
 // DeployDatacenterResult contains the results of a datacenter deployment.
@@ -81,6 +100,20 @@ type DeployDatacenterResult struct {
 	Duration time.Duration
 	// Outputs from root-level modules keyed by module name
 	ModuleOutputs map[string]map[string]interface{}
+
+	// ReconciledEnvironments lists the environments reconciled, in order.
+	ReconciledEnvironments []string
+
+	// FailedEnvironment is the environment whose failure stopped the rollout.
+	FailedEnvironment string
+
+	// PendingEnvironments lists environments left unreconciled because the
+	// rollout failed or was paused.
+	PendingEnvironments []string
+
+	// PinnedEnvironments lists environments skipped because they are pinned
+	// to another datacenter version.
+	PinnedEnvironments []string
 }
 
 // DatacenterPlan describes planned changes for a datacenter deployment.
@@ -98,13 +131,17 @@ type DatacenterPlan struct {
 	EnvironmentReconciliations []EnvironmentReconciliation
 }
 
-// DatacenterModuleChange describes a planned change to a root-level module.
+// DatacenterModuleChange describes a planned change to a root-level or
+// environment module.
 type DatacenterModuleChange struct {
 	Name   string
 	Plugin string
-	Action string // "create", "update", "noop"
+	Action string // "create", "update", "delete", "noop"
 	// InputChanges lists property-level changes (for updates).
 	InputChanges []planner.PropertyChange
+	// Drifts lists the module's resources that no longer match its state,
+	// when the plan was made with refresh.
+	Drifts []iac.ResourceDrift
 }
 
 // DatacenterComponentChange describes a planned change to a datacenter-level component registration.
@@ -118,6 +155,18 @@ type DatacenterComponentChange struct {
 type EnvironmentReconciliation struct {
 	Name           string
 	ComponentCount int
+
+	// ModuleChanges lists planned changes to environment modules.
+	ModuleChanges []DatacenterModuleChange
+
+	// HookChanges lists resources that will be re-evaluated with a
+	// different hook.
+	HookChanges []ResourceHookChange
+
+	// PinnedVersion is the datacenter version the environment is pinned to
+	// when it differs from the planned one. Pinned environments are only
+	// reconciled with --upgrade-datacenter.
+	PinnedVersion string
 }
 
 // HasChanges returns true if the plan includes any actionable changes.
@@ -154,6 +203,10 @@ type DeployEnvironmentOptions struct {
 
 	// DryRun only plans without executing
 	DryRun bool
+
+	// UpgradeDatacenter re-pins an environment pinned to an older datacenter
+	// version to the deployed version. Otherwise such environments are refused.
+	UpgradeDatacenter bool
 }
 
 // DeployEnvironmentResult contains the results of an environment module deployment.
@@ -168,6 +221,32 @@ type DeployEnvironmentResult struct {
 type RouteOverride struct {
 	Subdomain  string
 	PathPrefix string
+
+	// Hostnames are additional host and path combinations the route is
+	// published at. Subdomains and hosts may start with a "*." wildcard.
+	Hostnames []RouteHostname
+}
+
+// RouteHostname is one host and path combination a route matches. Exactly
+// one of Subdomain and Host is set. An empty PathPrefix inherits the
+// route's path prefix.
+type RouteHostname struct {
+	Subdomain  string
+	Host       string
+	PathPrefix string
+}
+
+// executorOverride converts the override to the executor's representation.
+func (ro RouteOverride) executorOverride() executor.RouteOverride {
+	out := executor.RouteOverride{Subdomain: ro.Subdomain, PathPrefix: ro.PathPrefix}
+	for _, h := range ro.Hostnames {
+		out.Hostnames = append(out.Hostnames, executor.RouteHostname{
+			Subdomain:  h.Subdomain,
+			Host:       h.Host,
+			PathPrefix: h.PathPrefix,
+		})
+	}
+	return out
 }
 
 // DeployOptions configures a deployment operation.
@@ -196,12 +275,24 @@ type DeployOptions struct {
 	// DryRun only plans without executing
 	DryRun bool
 
+	// PlanOnly returns the plan without executing it, not even as a preview
+	PlanOnly bool
+
+	// DetectDrift reads back the infrastructure of deployed resources while
+	// planning, and plans those that drifted from their state to be updated
+	// (see planner.PlanOptions.DetectDrift)
+	DetectDrift bool
+
 	// AutoApprove skips confirmation
 	AutoApprove bool
 
 	// Parallelism for parallel execution
 	Parallelism int
 
+	// BuildParallelism caps how many docker builds run at once (defaults to
+	// executor.DefaultBuildParallelism)
+	BuildParallelism int
+
 	// OnProgress is called when resource status changes
 	OnProgress executor.ProgressCallback
 
@@ -210,6 +301,21 @@ type DeployOptions struct {
 	// progress table with real dependency information from the graph).
 	OnPlan func(plan *planner.Plan)
 
+	// Review is called with a non-empty plan before OnPlan and execution.
+	// It may deselect destructive changes with plan.Skip. Returning false
+	// cancels the deployment.
+	Review func(plan *planner.Plan) (bool, error)
+
+	// Targets restricts the deploy to the resources with these node IDs
+	// ("component/type/name") and the resources they depend on. Other
+	// resources are neither updated nor deleted.
+	Targets []string
+
+	// VerifyPlan is called with the plan before it's reviewed or executed.
+	// Returning an error fails the deployment without executing anything,
+	// e.g. when the plan differs from a saved one.
+	VerifyPlan func(plan *planner.Plan) error
+
 	// ForceUpdate converts Noop actions to Update, used when datacenter config
 	// changes and all resources need re-evaluation against new hooks.
 	ForceUpdate bool
@@ -220,16 +326,86 @@ type DeployOptions struct {
 
 	// Distinct maps component name to resource patterns that should be per-instance.
 	Distinct map[string][]string
+
+	// InstanceSources maps component name to instance name to the component
+	// source for that instance. Instances whose source differs from
+	// Components are built from their own component definition.
+	InstanceSources map[string]map[string]string
+
+	// InstanceVariables maps component name to instance name to variable
+	// overrides layered over Variables for that instance.
+	InstanceVariables map[string]map[string]map[string]interface{}
+
+	// Replicas maps component name to a replica count applied to all of its
+	// deployments. Per-deployment Scaling takes precedence.
+	Replicas map[string]int
+
+	// Scaling maps component name to deployment name to scaling overrides.
+	Scaling map[string]map[string]ScalingOverride
+
+	// AllowURLChange permits routes to move away from the address they were
+	// previously published at (e.g., after a subdomain override changes).
+	AllowURLChange bool
+
+	// LogDir, when set, is a directory that each resource's plugin output is
+	// streamed to. See executor.Options.LogDir.
+	LogDir string
+
+	// UpgradeDatacenter re-pins an environment pinned to an older datacenter
+	// version to the deployed version. Otherwise deploys to such environments
+	// are refused.
+	UpgradeDatacenter bool
+
+	// Labels maps component name to the labels to record for it, replacing
+	// those from an earlier deploy. Components without an entry keep their
+	// recorded labels.
+	Labels map[string]map[string]string
+
+	// SlowNodes configures the notifications sent while a resource takes a
+	// long time to apply.
+	SlowNodes executor.SlowNodeOptions
+
+	// TakeOver proceeds even though another deploy of the environment looks
+	// to be in progress, breaking its state lock and reconciling the
+	// resources it left in flight. It's meant for deploys whose process died.
+	TakeOver bool
 }
 
 // DeployResult contains the results of a deployment.
 type DeployResult struct {
 	Success   bool
+	Cancelled bool
 	Plan      *planner.Plan
 	Execution *executor.ExecutionResult
 	Duration  time.Duration
 }
 
+// Warnings returns the warnings found while planning and executing the
+// deployment.
+func (r *DeployResult) Warnings() []planner.Warning {
+	return mergeWarnings(r.Plan, r.Execution)
+}
+
+// mergeWarnings returns a plan's warnings followed by its execution's,
+// without duplicates. Either may be nil.
+func mergeWarnings(plan *planner.Plan, execution *executor.ExecutionResult) []planner.Warning {
+	var all, out []planner.Warning
+	if plan != nil {
+		all = append(all, plan.Warnings...)
+	}
+	if execution != nil {
+		all = append(all, execution.Warnings...)
+	}
+	seen := make(map[planner.Warning]bool, len(all))
+	for _, w := range all {
+		if !seen[w] {
+			seen[w] = true
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
 // Deploy deploys components to an environment.
 func (e *Engine) Deploy(ctx context.Context, opts DeployOptions) (*DeployResult, error) {
 	startTime := time.Now()
@@ -255,44 +431,87 @@ func (e *Engine) Deploy(ctx context.Context, opts DeployOptions) (*DeployResult,
 		return nil, fmt.Errorf("failed to load datacenter configuration: %w", err)
 	}
 
-	// Build dependency graph
-	builder := graph.NewBuilder(opts.Environment, opts.Datacenter)
+	if !opts.DryRun && !opts.PlanOnly {
+		lockedCtx, unlock, err := e.lockEnvironment(ctx, opts.Datacenter, opts.Environment, "deploy", opts.TakeOver, opts.Output)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+		ctx = lockedCtx
+	}
 
-	// Configure implicit graph nodes. Instead of simply toggling them on/off
-	// based on hook presence, we create filters that evaluate each hook's
-	// when-clause against the prospective node's inputs. This ensures nodes
-	// are only created when a matching hook exists — e.g. a databaseUser hook
-	// scoped to postgres won't generate nodes for redis databases.
-	if env := dc.Environment(); env != nil {
-		if hooks := env.Hooks(); hooks != nil {
-			if dbUserHooks := hooks.DatabaseUser(); len(dbUserHooks) > 0 {
-				builder.SetDatabaseUserFilter(makeHookFilter(dbUserHooks))
-			}
-			if npHooks := hooks.NetworkPolicy(); len(npHooks) > 0 {
-				builder.SetNetworkPolicyFilter(makeHookFilter(npHooks))
-			}
+	if err := e.checkDatacenterPin(ctx, opts.Datacenter, dcState, opts.Environment, opts.UpgradeDatacenter, opts.DryRun || opts.PlanOnly); err != nil {
+		return nil, err
+	}
+
+	if !opts.DryRun && !opts.PlanOnly {
+		if err := e.checkInFlightDeploy(ctx, opts.Datacenter, opts.Environment, opts.TakeOver, opts.Output); err != nil {
+			return nil, err
 		}
 	}
 
+	// Build dependency graph, with implicit nodes filtered by the
+	// datacenter's hooks
+	builder := newGraphBuilder(opts.Environment, opts.Datacenter, dc)
+
+	comps := make(map[string]component.Component, len(opts.Components))
 	for compName, compPath := range opts.Components {
 		// Load component
 		comp, err := e.compLoader.Load(compPath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load component %s: %w", compName, err)
 		}
+		comps[compName] = comp
 
-		// Check if this component has instances configured
-		if opts.Instances != nil {
-			if instances, ok := opts.Instances[compName]; ok && len(instances) > 0 {
-				var distinct []string
-				if opts.Distinct != nil {
-					distinct = opts.Distinct[compName]
+		// Fail fast if the datacenter can't provision what the component
+		// says it needs, rather than partway through the deploy
+		if err := checkCapabilities(opts.Datacenter, dc, compName, comp); err != nil {
+			return nil, err
+		}
+
+		// Reject provided variable values that violate their declarations
+		// before anything is planned
+		if err := component.ValidateVariableValues(comp, opts.Variables[compName]); err != nil {
+			return nil, fmt.Errorf("component %s: %w", compName, err)
+		}
+
+		// Check if this component has instances configured. The environment's
+		// instances take precedence over the defaults declared by the component.
+		instances, distinct := opts.Instances[compName], opts.Distinct[compName]
+		if len(instances) == 0 {
+			for _, inst := range comp.Instances() {
+				instances = append(instances, graph.InstanceInfo{Name: inst.Name(), Weight: inst.Weight()})
+			}
+			distinct = comp.Distinct()
+		}
+		if len(instances) > 0 {
+			instances = append([]graph.InstanceInfo(nil), instances...)
+			for i, inst := range instances {
+				instComp := comp
+				if source := opts.InstanceSources[compName][inst.Name]; source != "" && source != compPath {
+					instComp, err = e.compLoader.Load(source)
+					if err != nil {
+						return nil, fmt.Errorf("failed to load instance %s of component %s: %w", inst.Name, compName, err)
+					}
+					instances[i].Component = instComp
 				}
-				if err := builder.AddComponentWithInstances(compName, comp, instances, distinct); err != nil {
-					return nil, fmt.Errorf("failed to add component %s to graph with instances: %w", compName, err)
+				if overrides := opts.InstanceVariables[compName][inst.Name]; len(overrides) > 0 || instComp != comp {
+					vars := make(map[string]interface{}, len(opts.Variables[compName])+len(overrides))
+					for k, v := range opts.Variables[compName] {
+						vars[k] = v
+					}
+					for k, v := range overrides {
+						vars[k] = v
+					}
+					if err := component.ValidateVariableValues(instComp, vars); err != nil {
+						return nil, fmt.Errorf("component %s instance %s: %w", compName, inst.Name, err)
+					}
 				}
-				continue
 			}
+			if err := builder.AddComponentWithInstances(compName, comp, instances, distinct); err != nil {
+				return nil, fmt.Errorf("failed to add component %s to graph with instances: %w", compName, err)
+			}
+			continue
 		}
 
 		// Add to graph - component name comes from the deployment mapping
@@ -301,14 +520,63 @@ func (e *Engine) Deploy(ctx context.Context, opts DeployOptions) (*DeployResult,
 		}
 	}
 
-	g := builder.Build()
-
 	// Get current state
 	currentState, _ := e.stateManager.GetEnvironment(ctx, opts.Datacenter, opts.Environment)
 
+	// Environment files can pass one component's outputs into another's
+	// variables (${{ components.<name>.outputs.<key> }}). Components deployed
+	// together are ordered so the referenced one finishes first; others must
+	// already be deployed to the environment.
+	for compName := range opts.Components {
+		for _, ref := range executor.ReferencedComponents(opts.Variables[compName]) {
+			if _, ok := opts.Components[ref]; ok {
+				if err := builder.AddComponentOrdering(compName, ref); err != nil {
+					return nil, fmt.Errorf("component %s: %w", compName, err)
+				}
+				continue
+			}
+			if currentState == nil || currentState.Components[ref] == nil {
+				return nil, fmt.Errorf("component %s references outputs of component %q, which is not deployed to environment %s", compName, ref, opts.Environment)
+			}
+		}
+	}
+
+	g := builder.Build()
+
+	if err := applyScalingOverrides(g, opts.Replicas, opts.Scaling); err != nil {
+		return nil, err
+	}
+	compLabels := componentLabels(currentState, opts.Components, opts.Labels)
+	applyLabels(g, environmentLabels(currentState), compLabels)
+
+	// Fail before anything is applied if two components would be published
+	// at the same address, or if a published route would move without the
+	// caller opting in
+	routes := plannedRoutes(g, opts.Environment, opts.Routes, currentState)
+	if err := checkRouteConflicts(g, opts.Environment, routes, currentState); err != nil {
+		return nil, err
+	}
+	if !opts.AllowURLChange {
+		if err := checkURLChanges(opts.Environment, routes, currentState); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkRouteAuthHooks(g, dc); err != nil {
+		return nil, err
+	}
+	if err := checkHookOutputContracts(g, dc); err != nil {
+		return nil, err
+	}
+
 	// Create plan
 	planOpts := planner.PlanOptions{
 		ForceUpdate: opts.ForceUpdate,
+		Targets:     opts.Targets,
+		ReplaceOn: replaceOnHints(dc, opts.Environment, opts.Datacenter, environmentLabels(currentState),
+			datacenter.ResolveVariables(dc, dcState.Variables, opts.Environment)),
+	}
+	if opts.DetectDrift {
+		planOpts.DetectDrift = e.driftDetector(ctx)
 	}
 	p := planner.NewPlannerWithOptions(planOpts)
 	plan, err := p.Plan(g, currentState)
@@ -318,6 +586,25 @@ func (e *Engine) Deploy(ctx context.Context, opts DeployOptions) (*DeployResult,
 
 	result.Plan = plan
 
+	if opts.VerifyPlan != nil {
+		if err := opts.VerifyPlan(plan); err != nil {
+			return nil, err
+		}
+	}
+
+	// Let the caller review the plan and deselect destructive changes
+	if opts.Review != nil && !opts.DryRun && !opts.PlanOnly && !plan.IsEmpty() {
+		approved, err := opts.Review(plan)
+		if err != nil {
+			return nil, fmt.Errorf("plan review failed: %w", err)
+		}
+		if !approved {
+			result.Cancelled = true
+			result.Duration = time.Since(startTime)
+			return result, nil
+		}
+	}
+
 	// Notify caller about the plan before execution begins
 	if opts.OnPlan != nil {
 		opts.OnPlan(plan)
@@ -328,24 +615,15 @@ func (e *Engine) Deploy(ctx context.Context, opts DeployOptions) (*DeployResult,
 		e.printPlanSummary(opts.Output, plan)
 	}
 
-	// If dry run or no changes, return here
-	if opts.DryRun || plan.IsEmpty() {
-		result.Success = plan.IsEmpty() || opts.DryRun
+	// If no changes, or only planning, return here
+	if plan.IsEmpty() || opts.PlanOnly {
+		result.Success = true
 		result.Duration = time.Since(startTime)
 		return result, nil
 	}
 
 	// Build datacenter variables map
-	dcVars := make(map[string]interface{})
-	for k, v := range dcState.Variables {
-		dcVars[k] = v
-	}
-	// Fill in defaults from the schema for any unset variables
-	for _, v := range dc.Variables() {
-		if _, ok := dcVars[v.Name()]; !ok && v.Default() != nil {
-			dcVars[v.Name()] = v.Default()
-		}
-	}
+	dcVars := datacenter.ResolveVariables(dc, dcState.Variables, opts.Environment)
 
 	// Build component routes map for the executor
 	var componentRoutes map[string]map[string]executor.RouteOverride
@@ -354,32 +632,49 @@ func (e *Engine) Deploy(ctx context.Context, opts DeployOptions) (*DeployResult,
 		for compName, routes := range opts.Routes {
 			m := make(map[string]executor.RouteOverride, len(routes))
 			for routeName, ro := range routes {
-				m[routeName] = executor.RouteOverride{
-					Subdomain:  ro.Subdomain,
-					PathPrefix: ro.PathPrefix,
-				}
+				m[routeName] = ro.executorOverride()
 			}
 			componentRoutes[compName] = m
 		}
 	}
 
-	// Execute plan
+	// Execute plan. A dry run executes it in preview mode: plugins report
+	// what they would change without applying anything or writing state.
 	execOpts := executor.Options{
 		Parallelism:         opts.Parallelism,
+		BuildParallelism:    opts.BuildParallelism,
 		Output:              opts.Output,
-		DryRun:              false,
+		DryRun:              opts.DryRun,
 		StopOnError:         true,
 		OnProgress:          opts.OnProgress,
+		SlowNodes:           opts.SlowNodes,
 		Datacenter:          dc,
 		DatacenterVariables: dcVars,
+		EnvironmentModules:  environmentModuleOutputs(currentState),
+		EnvironmentLabels:   environmentLabels(currentState),
 		ComponentSources:    opts.Components,
 		ComponentVariables:  opts.Variables,
+		ComponentLabels:     compLabels,
 		ComponentPorts:      opts.Ports,
 		ComponentRoutes:     componentRoutes,
+		InstanceSources:     opts.InstanceSources,
+		InstanceVariables:   opts.InstanceVariables,
+		LogDir:              opts.LogDir,
 	}
 
 	exec := executor.NewExecutor(e.stateManager, e.iacRegistry, execOpts)
 
+	// Check credentials and run the hooks' validate modules before anything
+	// is built or applied
+	if !opts.DryRun {
+		if err := exec.Preflight(ctx, plan); err != nil {
+			return nil, err
+		}
+	}
+
+	engineLog.Info("executing plan", "environment", opts.Environment, "datacenter", opts.Datacenter,
+		"create", plan.ToCreate, "update", plan.ToUpdate, "delete", plan.ToDelete, "unchanged", plan.NoChange)
+
 	var execResult *executor.ExecutionResult
 	if opts.Parallelism > 1 {
 		execResult, err = exec.ExecuteParallel(ctx, plan, g)
@@ -391,6 +686,25 @@ func (e *Engine) Deploy(ctx context.Context, opts DeployOptions) (*DeployResult,
 		return nil, fmt.Errorf("execution failed: %w", err)
 	}
 
+	if opts.DryRun && opts.Output != nil {
+		printPlanPreview(opts.Output, plan)
+	}
+
+	// Call the components' deploy webhooks, then run the datacenter's
+	// on_deploy modules, once every resource is in place
+	if execResult.Success && !opts.DryRun {
+		if err := e.runDeployWebhooks(ctx, opts.Datacenter, opts.Environment, comps, opts.Output); err != nil {
+			execResult.Success = false
+			execResult.Errors = append(execResult.Errors, err)
+		}
+	}
+	if execResult.Success && !opts.DryRun {
+		if err := e.runOnDeploy(ctx, dc, dcState, opts.Datacenter, opts.Environment, opts.Output, opts.OnProgress); err != nil {
+			execResult.Success = false
+			execResult.Errors = append(execResult.Errors, err)
+		}
+	}
+
 	result.Execution = execResult
 	result.Success = execResult.Success
 	result.Duration = time.Since(startTime)
@@ -441,6 +755,13 @@ type ApplyNodeResult struct {
 func (e *Engine) ApplyNode(ctx context.Context, opts ApplyNodeOptions) (*ApplyNodeResult, error) {
 	startTime := time.Now()
 
+	lockedCtx, unlock, err := e.lockEnvironment(ctx, opts.Datacenter, opts.Environment, "apply", false, opts.Output)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	ctx = lockedCtx
+
 	// Parse node path (type/name)
 	parts := strings.SplitN(opts.NodePath, "/", 2)
 	if len(parts) != 2 {
@@ -465,24 +786,20 @@ func (e *Engine) ApplyNode(ctx context.Context, opts ApplyNodeOptions) (*ApplyNo
 		return nil, fmt.Errorf("failed to load datacenter configuration: %w", err)
 	}
 
-	// Build the full graph (including implicit nodes from datacenter hooks)
-	builder := graph.NewBuilder(opts.Environment, opts.Datacenter)
-
-	if env := dc.Environment(); env != nil {
-		if hooks := env.Hooks(); hooks != nil {
-			if dbUserHooks := hooks.DatabaseUser(); len(dbUserHooks) > 0 {
-				builder.SetDatabaseUserFilter(makeHookFilter(dbUserHooks))
-			}
-			if npHooks := hooks.NetworkPolicy(); len(npHooks) > 0 {
-				builder.SetNetworkPolicyFilter(makeHookFilter(npHooks))
-			}
-		}
+	if err := e.checkDatacenterPin(ctx, opts.Datacenter, dcState, opts.Environment, false, false); err != nil {
+		return nil, err
 	}
 
+	// Build the full graph (including implicit nodes from datacenter hooks)
+	builder := newGraphBuilder(opts.Environment, opts.Datacenter, dc)
+
 	comp, err := e.compLoader.Load(opts.ComponentPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load component: %w", err)
 	}
+	if err := checkCapabilities(opts.Datacenter, dc, opts.ComponentName, comp); err != nil {
+		return nil, err
+	}
 
 	if err := builder.AddComponent(opts.ComponentName, comp); err != nil {
 		return nil, fmt.Errorf("failed to add component to graph: %w", err)
@@ -518,6 +835,17 @@ func (e *Engine) ApplyNode(ctx context.Context, opts ApplyNodeOptions) (*ApplyNo
 		}
 	}
 
+	// Check for the routeAuth proxy in front of a target route
+	for _, n := range g.GetNodesByType(graph.NodeTypeRouteAuth) {
+		if n.Component == opts.ComponentName {
+			for _, depID := range targetNode.DependsOn {
+				if depID == n.ID {
+					nodesToExecute[n.ID] = true
+				}
+			}
+		}
+	}
+
 	// Check for implicit networkPolicy nodes that depend on the target
 	for _, n := range g.GetNodesByType(graph.NodeTypeNetworkPolicy) {
 		if n.Component == opts.ComponentName {
@@ -538,12 +866,22 @@ func (e *Engine) ApplyNode(ctx context.Context, opts ApplyNodeOptions) (*ApplyNo
 			_ = filteredGraph.AddNode(node)
 		}
 	}
+	filteredGraph.InjectionExclusions = g.InjectionExclusions
+	if err := checkRouteAuthHooks(filteredGraph, dc); err != nil {
+		return nil, err
+	}
+	if err := checkHookOutputContracts(filteredGraph, dc); err != nil {
+		return nil, err
+	}
 
 	// Get current state
 	currentState, _ := e.stateManager.GetEnvironment(ctx, opts.Datacenter, opts.Environment)
 
 	// Create plan for the filtered graph
-	p := planner.NewPlanner()
+	p := planner.NewPlannerWithOptions(planner.PlanOptions{
+		ReplaceOn: replaceOnHints(dc, opts.Environment, opts.Datacenter, environmentLabels(currentState),
+			datacenter.ResolveVariables(dc, dcState.Variables, opts.Environment)),
+	})
 	plan, err := p.Plan(filteredGraph, currentState)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create plan: %w", err)
@@ -559,15 +897,7 @@ func (e *Engine) ApplyNode(ctx context.Context, opts ApplyNodeOptions) (*ApplyNo
 	}
 
 	// Build datacenter variables map
-	dcVars := make(map[string]interface{})
-	for k, v := range dcState.Variables {
-		dcVars[k] = v
-	}
-	for _, v := range dc.Variables() {
-		if _, ok := dcVars[v.Name()]; !ok && v.Default() != nil {
-			dcVars[v.Name()] = v.Default()
-		}
-	}
+	dcVars := datacenter.ResolveVariables(dc, dcState.Variables, opts.Environment)
 
 	// Build component variables
 	compVars := map[string]map[string]interface{}{
@@ -583,6 +913,7 @@ func (e *Engine) ApplyNode(ctx context.Context, opts ApplyNodeOptions) (*ApplyNo
 		OnProgress:          opts.OnProgress,
 		Datacenter:          dc,
 		DatacenterVariables: dcVars,
+		EnvironmentModules:  environmentModuleOutputs(currentState),
 		ComponentSources:    map[string]string{opts.ComponentName: opts.ComponentPath},
 		ComponentVariables:  compVars,
 	}
@@ -822,12 +1153,27 @@ type DestroyResult struct {
 	Duration  time.Duration
 }
 
+// Warnings returns the warnings found while planning and executing the
+// destroy.
+func (r *DestroyResult) Warnings() []planner.Warning {
+	return mergeWarnings(r.Plan, r.Execution)
+}
+
 // Destroy destroys an environment.
 func (e *Engine) Destroy(ctx context.Context, opts DestroyOptions) (*DestroyResult, error) {
 	startTime := time.Now()
 
 	result := &DestroyResult{}
 
+	if !opts.DryRun {
+		lockedCtx, unlock, err := e.lockEnvironment(ctx, opts.Datacenter, opts.Environment, "destroy", false, opts.Output)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+		ctx = lockedCtx
+	}
+
 	// Get current state
 	currentState, err := e.stateManager.GetEnvironment(ctx, opts.Datacenter, opts.Environment)
 	if err != nil {
@@ -946,6 +1292,15 @@ func (e *Engine) DestroyComponent(ctx context.Context, opts DestroyComponentOpti
 
 	result := &DestroyResult{}
 
+	if !opts.DryRun {
+		lockedCtx, unlock, err := e.lockEnvironment(ctx, opts.Datacenter, opts.Environment, "destroy component", false, opts.Output)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+		ctx = lockedCtx
+	}
+
 	// Get current environment state
 	currentState, err := e.stateManager.GetEnvironment(ctx, opts.Datacenter, opts.Environment)
 	if err != nil {
@@ -1001,6 +1356,17 @@ func (e *Engine) DestroyComponent(ctx context.Context, opts DestroyComponentOpti
 		return result, nil
 	}
 
+	// Let the component deregister before anything is torn down. A failed
+	// webhook stops the destroy unless it's forced.
+	if err := callWebhooks(ctx, WebhookEventDestroy, opts.Datacenter, currentState, compState, opts.Output); err != nil {
+		if !opts.Force {
+			return nil, fmt.Errorf("%w\nUse --force to destroy the component anyway", err)
+		}
+		if opts.Output != nil {
+			fmt.Fprintf(opts.Output, "Warning: %v\n", err)
+		}
+	}
+
 	// Execute plan
 	execOpts := executor.Options{
 		Parallelism: 1,
@@ -1042,45 +1408,8 @@ func (e *Engine) DeployFromEnvironmentFile(ctx context.Context, envPath string,
 	// Note: Name and Datacenter are CLI parameters, not part of the config file
 	// They must be provided via opts.Environment and opts.Datacenter
 
-	// Build components map from environment
-	if opts.Components == nil {
-		opts.Components = make(map[string]string)
-	}
-	if opts.Variables == nil {
-		opts.Variables = make(map[string]map[string]interface{})
-	}
-	if opts.Ports == nil {
-		opts.Ports = make(map[string]map[string]int)
-	}
-	if opts.Routes == nil {
-		opts.Routes = make(map[string]map[string]RouteOverride)
-	}
-
 	for name, compConfig := range env.Components() {
-		if compConfig.Path() != "" {
-			opts.Components[name] = compConfig.Path()
-		} else {
-			opts.Components[name] = compConfig.Image()
-		}
-		opts.Variables[name] = compConfig.Variables()
-		if compConfig.Ports() != nil {
-			opts.Ports[name] = compConfig.Ports()
-		}
-		// Copy route configs (subdomain/pathPrefix) from environment file
-		if routeConfigs := compConfig.Routes(); len(routeConfigs) > 0 {
-			routeMap := make(map[string]RouteOverride, len(routeConfigs))
-			for routeName, rc := range routeConfigs {
-				if rc.Subdomain() != "" || rc.PathPrefix() != "" {
-					routeMap[routeName] = RouteOverride{
-						Subdomain:  rc.Subdomain(),
-						PathPrefix: rc.PathPrefix(),
-					}
-				}
-			}
-			if len(routeMap) > 0 {
-				opts.Routes[name] = routeMap
-			}
-		}
+		opts.ApplyEnvironmentComponent(name, compConfig, "")
 	}
 
 	return e.Deploy(ctx, opts)
@@ -1099,6 +1428,10 @@ func (e *Engine) printPlanSummary(w io.Writer, plan *planner.Plan) {
 
 	fmt.Fprintf(w, "Changes:\n")
 	for _, change := range plan.Changes {
+		if change.Skipped() {
+			fmt.Fprintf(w, "  ! %s (skipped %s: %s)\n", change.Node.ID, change.SkippedAction, change.SkipReason)
+			continue
+		}
 		if change.Action == planner.ActionNoop {
 			continue
 		}
@@ -1121,10 +1454,73 @@ func (e *Engine) printPlanSummary(w io.Writer, plan *planner.Plan) {
 		}
 
 		fmt.Fprintf(w, "  %s %s\n", actionSymbol, nodeID)
+		printDrift(w, "  ", change.Drift)
 	}
 
-	fmt.Fprintf(w, "\nSummary: %d to create, %d to update, %d to delete, %d unchanged\n",
+	fmt.Fprintf(w, "\nSummary: %d to create, %d to update, %d to delete, %d unchanged",
 		plan.ToCreate, plan.ToUpdate, plan.ToDelete, plan.NoChange)
+	if plan.Skipped > 0 {
+		fmt.Fprintf(w, " (%d skipped)", plan.Skipped)
+	}
+	if plan.Drifted > 0 {
+		fmt.Fprintf(w, " (%d drifted)", plan.Drifted)
+	}
+	fmt.Fprintln(w)
+}
+
+// printPlanPreview prints the IaC-level changes each plugin reported during a
+// dry run, grouped by resource.
+func printPlanPreview(w io.Writer, plan *planner.Plan) {
+	fmt.Fprintf(w, "\nPreview:\n")
+
+	printed := false
+	for _, change := range plan.Changes {
+		if len(change.Preview) == 0 {
+			continue
+		}
+		printed = true
+
+		fmt.Fprintf(w, "\n  %s (%s)\n", change.Node.ID, change.Action)
+		for _, mp := range change.Preview {
+			fmt.Fprintf(w, "    module %s (%s)\n", mp.Module, mp.Plugin)
+			if mp.Error != "" {
+				fmt.Fprintf(w, "      error: %s\n", mp.Error)
+				continue
+			}
+			if len(mp.Changes) == 0 {
+				fmt.Fprintf(w, "      no changes\n")
+				continue
+			}
+			for _, rc := range mp.Changes {
+				fmt.Fprintf(w, "      %s %s %s\n", previewSymbol(rc.Action), rc.ResourceType, rc.ResourceID)
+				for _, d := range rc.Diff {
+					oldVal, newVal := d.OldValue, d.NewValue
+					if d.Sensitive {
+						oldVal, newVal = "(sensitive)", "(sensitive)"
+					}
+					fmt.Fprintf(w, "          %s: %v -> %v\n", d.Path, oldVal, newVal)
+				}
+			}
+		}
+	}
+
+	if !printed {
+		fmt.Fprintf(w, "  No plugin-level changes reported.\n")
+	}
+}
+
+func previewSymbol(action iac.ChangeAction) string {
+	switch action {
+	case iac.ActionCreate:
+		return "+"
+	case iac.ActionUpdate:
+		return "~"
+	case iac.ActionDelete:
+		return "-"
+	case iac.ActionReplace:
+		return "±"
+	}
+	return " "
 }
 
 func (e *Engine) printDestroyPlanSummary(w io.Writer, plan *planner.Plan) {
@@ -1150,16 +1546,20 @@ func (e *Engine) printDestroyPlanSummary(w io.Writer, plan *planner.Plan) {
 }
 
 // PlanDatacenter generates a plan showing what changes a datacenter deployment will make.
-// It compares the incoming datacenter configuration against the current state to determine
-// which modules will be created, updated, or remain unchanged.
-func (e *Engine) PlanDatacenter(ctx context.Context, datacenter, imageRef string) (*DatacenterPlan, error) {
+// It compares the incoming datacenter configuration against the current state and the
+// datacenter version that is deployed to determine which root and environment modules
+// will be created, updated, deleted, or remain unchanged, and which environment
+// resources will be re-evaluated with a different hook. variables override the
+// datacenter's stored variables. With refresh, modules are also checked for
+// drift from their recorded state (see refreshModuleChanges).
+func (e *Engine) PlanDatacenter(ctx context.Context, dcName, imageRef string, variables map[string]string, refresh bool) (*DatacenterPlan, error) {
 	plan := &DatacenterPlan{
-		Datacenter: datacenter,
+		Datacenter: dcName,
 		Image:      imageRef,
 	}
 
 	// Load existing datacenter state (may not exist for first deployment)
-	dcState, _ := e.stateManager.GetDatacenter(ctx, datacenter)
+	dcState, _ := e.stateManager.GetDatacenter(ctx, dcName)
 
 	// Load the datacenter configuration from the image/path
 	dc, err := e.loadDatacenterConfig(imageRef)
@@ -1167,6 +1567,14 @@ func (e *Engine) PlanDatacenter(ctx context.Context, datacenter, imageRef string
 		return nil, fmt.Errorf("failed to load datacenter configuration: %w", err)
 	}
 
+	// Load the deployed version to compare module code and hooks against. It
+	// may no longer be available (e.g., a local path that moved).
+	var oldDC datacenter.Datacenter
+	if dcState != nil && dcState.Version != "" {
+		oldDC, _ = e.loadDatacenterConfig(dcState.Version)
+	}
+	digests := make(dirDigests)
+
 	// Build datacenter variables map (used to evaluate module inputs)
 	dcVars := make(map[string]interface{})
 	if dcState != nil {
@@ -1174,6 +1582,9 @@ func (e *Engine) PlanDatacenter(ctx context.Context, datacenter, imageRef string
 			dcVars[k] = v
 		}
 	}
+	for k, v := range variables {
+		dcVars[k] = v
+	}
 	for _, v := range dc.Variables() {
 		if _, ok := dcVars[v.Name()]; !ok && v.Default() != nil {
 			dcVars[v.Name()] = v.Default()
@@ -1187,40 +1598,29 @@ func (e *Engine) PlanDatacenter(ctx context.Context, datacenter, imageRef string
 			continue // Skip conditional modules (would need runtime evaluation)
 		}
 
-		modName := mod.Name()
-		pluginName := mod.Plugin()
-		if pluginName == "" {
-			pluginName = "native"
-		}
-
 		// Build desired inputs
 		desiredInputs := make(map[string]interface{})
 		for inputName, exprStr := range mod.Inputs() {
 			desiredInputs[inputName] = evaluateModuleExpression(exprStr, dcVars, nil, nil)
 		}
 
-		change := DatacenterModuleChange{
-			Name:   modName,
-			Plugin: pluginName,
+		var existing *types.ModuleState
+		if dcState != nil {
+			existing = dcState.Modules[mod.Name()]
 		}
-
-		if dcState == nil || dcState.Modules == nil || dcState.Modules[modName] == nil {
-			// Module doesn't exist yet → create
-			change.Action = "create"
-		} else {
-			// Module exists → compare inputs to detect changes
-			existing := dcState.Modules[modName]
-			p := planner.NewPlanner()
-			inputChanges := p.CompareInputs(desiredInputs, existing.Inputs)
-			if len(inputChanges) > 0 {
-				change.Action = "update"
-				change.InputChanges = inputChanges
-			} else {
-				change.Action = "update" // Always re-apply for idempotency
+		var oldMod datacenter.Module
+		if oldDC != nil {
+			oldMod = findModule(oldDC.Modules(), mod.Name())
+		}
+		plan.ModuleChanges = append(plan.ModuleChanges, planModuleChange(dc, mod, desiredInputs, oldDC, oldMod, existing, digests))
+	}
+	if dcState != nil {
+		plan.ModuleChanges = append(plan.ModuleChanges, removedModules(rootModules, dcState.Modules)...)
+		if refresh {
+			if err := e.refreshModuleChanges(ctx, plan.ModuleChanges, dcState.Modules); err != nil {
+				return nil, err
 			}
 		}
-
-		plan.ModuleChanges = append(plan.ModuleChanges, change)
 	}
 
 	// Plan datacenter-level component registrations
@@ -1235,18 +1635,38 @@ func (e *Engine) PlanDatacenter(ctx context.Context, datacenter, imageRef string
 	}
 
 	// Plan environment reconciliation
-	envs, err := e.stateManager.ListEnvironments(ctx, datacenter)
+	envs, err := e.stateManager.ListEnvironments(ctx, dcName)
 	if err == nil && len(envs) > 0 {
 		for _, envRef := range envs {
-			envState, err := e.stateManager.GetEnvironment(ctx, datacenter, envRef.Name)
+			envState, err := e.stateManager.GetEnvironment(ctx, dcName, envRef.Name)
 			if err != nil {
 				continue
 			}
 
-			compCount := len(envState.Components)
+			// Pinned environments are compared against the version they were
+			// provisioned with
+			envOldDC := oldDC
+			pinned := ""
+			if envState.DatacenterVersion != "" && envState.DatacenterVersion != imageRef {
+				pinned = envState.DatacenterVersion
+				if dcState == nil || pinned != dcState.Version {
+					envOldDC, _ = e.loadDatacenterConfig(pinned)
+				}
+			}
+
+			moduleChanges := planEnvironmentModules(dc, envOldDC, envState, dcState, dcVars, digests)
+			if refresh {
+				if err := e.refreshModuleChanges(ctx, moduleChanges, envState.Modules); err != nil {
+					return nil, fmt.Errorf("environment %s: %w", envRef.Name, err)
+				}
+			}
+
 			plan.EnvironmentReconciliations = append(plan.EnvironmentReconciliations, EnvironmentReconciliation{
 				Name:           envRef.Name,
-				ComponentCount: compCount,
+				ComponentCount: len(envState.Components),
+				ModuleChanges:  moduleChanges,
+				HookChanges:    planHookChanges(envOldDC, dc, envState, digests),
+				PinnedVersion:  pinned,
 			})
 		}
 	}
@@ -1265,23 +1685,7 @@ func (e *Engine) PrintDatacenterPlanSummary(w io.Writer, plan *DatacenterPlan) {
 	if len(plan.ModuleChanges) > 0 {
 		fmt.Fprintf(w, "Root modules:\n")
 		for _, m := range plan.ModuleChanges {
-			symbol := actionSymbol(m.Action)
-			label := fmt.Sprintf("module/%s", m.Name)
-			if m.Plugin != "" && m.Plugin != "native" {
-				label += fmt.Sprintf(" (%s)", m.Plugin)
-			}
-			fmt.Fprintf(w, "  %s %s\n", symbol, label)
-
-			// Show input-level changes for updates
-			for _, ic := range m.InputChanges {
-				if ic.OldValue == nil {
-					fmt.Fprintf(w, "      + %s = %v\n", ic.Path, ic.NewValue)
-				} else if ic.NewValue == nil {
-					fmt.Fprintf(w, "      - %s = %v\n", ic.Path, ic.OldValue)
-				} else {
-					fmt.Fprintf(w, "      ~ %s: %v → %v\n", ic.Path, ic.OldValue, ic.NewValue)
-				}
-			}
+			printDatacenterModuleChange(w, "  ", m)
 		}
 		fmt.Fprintln(w)
 	}
@@ -1299,27 +1703,120 @@ func (e *Engine) PrintDatacenterPlanSummary(w io.Writer, plan *DatacenterPlan) {
 	if len(plan.EnvironmentReconciliations) > 0 {
 		fmt.Fprintf(w, "Environments to reconcile:\n")
 		for _, env := range plan.EnvironmentReconciliations {
-			if env.ComponentCount > 0 {
+			switch {
+			case env.PinnedVersion != "":
+				fmt.Fprintf(w, "  = %s (pinned to %s; reconciled only with --upgrade-datacenter)\n", env.Name, env.PinnedVersion)
+			case env.ComponentCount > 0:
 				fmt.Fprintf(w, "  ~ %s (%d component(s) will be re-deployed)\n", env.Name, env.ComponentCount)
-			} else {
+			default:
 				fmt.Fprintf(w, "  ~ %s\n", env.Name)
 			}
+			for _, m := range env.ModuleChanges {
+				if m.Action != "noop" {
+					printDatacenterModuleChange(w, "      ", m)
+				}
+			}
+			for _, h := range env.HookChanges {
+				if h.From == h.To {
+					fmt.Fprintf(w, "      ~ %s/%s.%s: %s hook changed\n", h.Component, h.Type, h.Name, h.To)
+				} else {
+					fmt.Fprintf(w, "      ~ %s/%s.%s: %s → %s\n", h.Component, h.Type, h.Name, h.From, h.To)
+				}
+			}
 		}
 		fmt.Fprintln(w)
 	}
 
 	// Summary line
-	var creates, updates int
-	for _, m := range plan.ModuleChanges {
-		switch m.Action {
-		case "create":
-			creates++
-		case "update":
-			updates++
+	var creates, updates, deletes, hookChanges, pinned int
+	count := func(changes []DatacenterModuleChange) {
+		for _, m := range changes {
+			switch m.Action {
+			case "create":
+				creates++
+			case "update":
+				updates++
+			case "delete":
+				deletes++
+			}
+		}
+	}
+	count(plan.ModuleChanges)
+	for _, env := range plan.EnvironmentReconciliations {
+		if env.PinnedVersion != "" {
+			pinned++
+			continue
+		}
+		count(env.ModuleChanges)
+		hookChanges += len(env.HookChanges)
+	}
+	fmt.Fprintf(w, "Plan: %d module(s) to create, %d to update, %d to delete, %d environment(s) to reconcile, %d resource(s) with changed hooks\n",
+		creates, updates, deletes, len(plan.EnvironmentReconciliations)-pinned, hookChanges)
+	if pinned > 0 {
+		fmt.Fprintf(w, "%d environment(s) pinned to another datacenter version will be skipped\n", pinned)
+	}
+}
+
+// printDatacenterModuleChange writes one module change and, for updates,
+// its input-level changes.
+func printDatacenterModuleChange(w io.Writer, indent string, m DatacenterModuleChange) {
+	label := fmt.Sprintf("module/%s", m.Name)
+	if m.Plugin != "" && m.Plugin != "native" {
+		label += fmt.Sprintf(" (%s)", m.Plugin)
+	}
+	fmt.Fprintf(w, "%s%s %s\n", indent, actionSymbol(m.Action), label)
+
+	for _, ic := range m.InputChanges {
+		if ic.OldValue == nil {
+			fmt.Fprintf(w, "%s    + %s = %v\n", indent, ic.Path, ic.NewValue)
+		} else if ic.NewValue == nil {
+			fmt.Fprintf(w, "%s    - %s = %v\n", indent, ic.Path, ic.OldValue)
+		} else {
+			fmt.Fprintf(w, "%s    ~ %s: %v → %v\n", indent, ic.Path, ic.OldValue, ic.NewValue)
+		}
+	}
+
+	printResourceDrifts(w, indent, m.Drifts)
+}
+
+// printDrift prints how a resource drifted from its state.
+func printDrift(w io.Writer, indent string, d *planner.Drift) {
+	if d.IsEmpty() {
+		return
+	}
+	// Drifted values aren't shown, since outputs may be secret
+	if len(d.Outputs) > 0 {
+		paths := make([]string, 0, len(d.Outputs))
+		for _, pc := range d.Outputs {
+			paths = append(paths, pc.Path)
+		}
+		fmt.Fprintf(w, "%s    ! outputs drifted: %s\n", indent, strings.Join(paths, ", "))
+	}
+	for _, md := range d.Modules {
+		fmt.Fprintf(w, "%s    module %s (%s)\n", indent, md.Module, md.Plugin)
+		printResourceDrifts(w, indent+"  ", md.Resources)
+	}
+}
+
+// printResourceDrifts prints the resources of a module that drifted from its
+// state.
+func printResourceDrifts(w io.Writer, indent string, drifts []iac.ResourceDrift) {
+	// Drifted values aren't shown, since resource attributes may be secret
+	for _, d := range drifts {
+		var paths []string
+		for _, diff := range d.Diffs {
+			if diff.Path == iac.DriftPathExists {
+				paths = nil
+				break
+			}
+			paths = append(paths, diff.Path)
+		}
+		if paths == nil {
+			fmt.Fprintf(w, "%s    ! %s no longer exists\n", indent, d.ResourceID)
+		} else {
+			fmt.Fprintf(w, "%s    ! %s drifted: %s\n", indent, d.ResourceID, strings.Join(paths, ", "))
 		}
 	}
-	fmt.Fprintf(w, "Plan: %d module(s) to create, %d to update, %d environment(s) to reconcile\n",
-		creates, updates, len(plan.EnvironmentReconciliations))
 }
 
 // actionSymbol returns a single-character symbol for a change action.
@@ -1458,10 +1955,24 @@ func (e *Engine) DeployDatacenter(ctx context.Context, opts DeployDatacenterOpti
 			// Check for existing state (for updates)
 			existingMod := dcState.Modules[modName]
 
+			creds, err := resolveModuleCredentials(mod, dcVars, nil, nil)
+			if err != nil {
+				return nil, fmt.Errorf("root module %s: %w", modName, err)
+			}
+			credEnv, err := executor.CredentialEnvironment(ctx, creds, executor.CredentialSessionName(modName), nil)
+			if err != nil {
+				return nil, fmt.Errorf("root module %s: %w", modName, err)
+			}
+			sandbox, err := executor.ModuleSandbox(dc, modName, pluginName, plugin)
+			if err != nil {
+				return nil, err
+			}
+
 			runOpts := iac.RunOptions{
 				ModuleSource: modulePath,
 				Inputs:       inputs,
-				Environment:  map[string]string{},
+				Environment:  credEnv,
+				Sandbox:      sandbox,
 			}
 			if existingMod != nil && existingMod.IaCState != nil {
 				// TODO: Pass existing state via StateReader for incremental updates
@@ -1511,14 +2022,16 @@ func (e *Engine) DeployDatacenter(ctx context.Context, opts DeployDatacenterOpti
 			result.ModuleOutputs[modName] = outputs
 
 			dcState.Modules[modName] = &types.ModuleState{
-				Name:      modName,
-				Plugin:    pluginName,
-				Source:    modulePath,
-				Inputs:    inputs,
-				Outputs:   outputs,
-				IaCState:  applyResult.State,
-				Status:    types.ModuleStatusReady,
-				UpdatedAt: time.Now(),
+				Name:             modName,
+				Plugin:           pluginName,
+				Source:           modulePath,
+				Inputs:           inputs,
+				Outputs:          outputs,
+				SensitiveOutputs: iac.SensitiveOutputs(applyResult.Outputs),
+				IaCState:         applyResult.State,
+				Credentials:      creds,
+				Status:           types.ModuleStatusReady,
+				UpdatedAt:        time.Now(),
 			}
 			dcState.UpdatedAt = time.Now()
 			_ = e.stateManager.SaveDatacenter(ctx, dcState)
@@ -1539,81 +2052,57 @@ func (e *Engine) DeployDatacenter(ctx context.Context, opts DeployDatacenterOpti
 		}
 	}
 
-	// Phase 2: Reconcile existing environments
+	// Phase 2: Reconcile existing environments, stage by stage
 	envs, err := e.stateManager.ListEnvironments(ctx, opts.Datacenter)
 	if err == nil && len(envs) > 0 {
-		if opts.Output != nil {
-			fmt.Fprintf(opts.Output, "\nReconciling %d environment(s)...\n", len(envs))
-		}
-
+		envNames := make([]string, 0, len(envs))
 		for _, envRef := range envs {
-			envState, err := e.stateManager.GetEnvironment(ctx, opts.Datacenter, envRef.Name)
-			if err != nil {
+			// Environments pinned to another datacenter version keep it until
+			// they are explicitly upgraded
+			if envState, err := e.stateManager.GetEnvironment(ctx, opts.Datacenter, envRef.Name); err == nil && datacenterPinned(envState, dcState) && !opts.UpgradeDatacenter {
+				result.PinnedEnvironments = append(result.PinnedEnvironments, envRef.Name)
 				if opts.Output != nil {
-					fmt.Fprintf(opts.Output, "  [warning] Could not load environment %q: %v\n", envRef.Name, err)
+					fmt.Fprintf(opts.Output, "\nSkipping environment %q: pinned to datacenter version %s\n", envRef.Name, envState.DatacenterVersion)
 				}
 				continue
 			}
+			envNames = append(envNames, envRef.Name)
+		}
+		stages := rolloutStages(opts.Stages, envNames)
+		order := stageEnvironments(stages)
 
-			// Re-deploy environment-scoped modules
-			envResult, err := e.DeployEnvironment(ctx, DeployEnvironmentOptions{
-				Datacenter:  opts.Datacenter,
-				Environment: envRef.Name,
-				Output:      opts.Output,
-				OnProgress:  opts.OnProgress,
-				Parallelism: opts.Parallelism,
-			})
-			if err != nil {
-				if opts.Output != nil {
-					fmt.Fprintf(opts.Output, "  [warning] Failed to reconcile env modules for %q: %v\n", envRef.Name, err)
-				}
-			}
-			_ = envResult
-
-			// Re-deploy each component with force update
-			if len(envState.Components) > 0 {
-				components := make(map[string]string)
-				variables := make(map[string]map[string]interface{})
+		if opts.Output != nil && len(envNames) > 0 {
+			fmt.Fprintf(opts.Output, "\nReconciling %d environment(s)...\n", len(envNames))
+		}
 
-				for compName, compState := range envState.Components {
-					if compState.Source != "" {
-						components[compName] = compState.Source
-					}
-					if compState.Variables != nil {
-						vars := make(map[string]interface{})
-						for k, v := range compState.Variables {
-							vars[k] = v
-						}
-						variables[compName] = vars
-					}
+		for i, stage := range stages {
+			if i > 0 && opts.ApproveStage != nil {
+				approved, err := opts.ApproveStage(stage.DatacenterRolloutStage, stage.environments)
+				if err != nil {
+					result.Duration = time.Since(startTime)
+					return result, err
 				}
-
-				if len(components) > 0 {
+				if !approved {
+					result.PendingEnvironments = order[len(result.ReconciledEnvironments):]
 					if opts.Output != nil {
-						fmt.Fprintf(opts.Output, "  Reconciling %d component(s) in %q...\n", len(components), envRef.Name)
+						fmt.Fprintf(opts.Output, "\nRollout paused before stage %q; %d environment(s) not reconciled\n", stage.Name, len(result.PendingEnvironments))
 					}
+					break
+				}
+			}
 
-					deployResult, err := e.Deploy(ctx, DeployOptions{
-						Environment: envRef.Name,
-						Datacenter:  opts.Datacenter,
-						Components:  components,
-						Variables:   variables,
-						Output:      opts.Output,
-						Parallelism: opts.Parallelism,
-						AutoApprove: true,
-						OnProgress:  opts.OnProgress,
-						ForceUpdate: true,
-					})
-					if err != nil {
-						if opts.Output != nil {
-							fmt.Fprintf(opts.Output, "  [warning] Failed to reconcile components in %q: %v\n", envRef.Name, err)
-						}
-					} else if deployResult.Success {
-						if opts.Output != nil {
-							fmt.Fprintf(opts.Output, "  [success] Components in %q reconciled\n", envRef.Name)
-						}
-					}
+			if opts.Output != nil && stage.Name != "" {
+				fmt.Fprintf(opts.Output, "\nStage %q: %s\n", stage.Name, strings.Join(stage.environments, ", "))
+			}
+
+			for _, envName := range stage.environments {
+				if err := e.reconcileEnvironment(ctx, opts, envName); err != nil {
+					result.FailedEnvironment = envName
+					result.PendingEnvironments = order[len(result.ReconciledEnvironments)+1:]
+					result.Duration = time.Since(startTime)
+					return result, fmt.Errorf("failed to reconcile environment %q: %w", envName, err)
 				}
+				result.ReconciledEnvironments = append(result.ReconciledEnvironments, envName)
 			}
 		}
 	}
@@ -1648,6 +2137,19 @@ func (e *Engine) DeployEnvironment(ctx context.Context, opts DeployEnvironmentOp
 		return nil, fmt.Errorf("failed to load datacenter configuration: %w", err)
 	}
 
+	if !opts.DryRun {
+		lockedCtx, unlock, err := e.lockEnvironment(ctx, opts.Datacenter, opts.Environment, "deploy environment", false, opts.Output)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+		ctx = lockedCtx
+	}
+
+	if err := e.checkDatacenterPin(ctx, opts.Datacenter, dcState, opts.Environment, opts.UpgradeDatacenter, opts.DryRun); err != nil {
+		return nil, err
+	}
+
 	envBlock := dc.Environment()
 	if envBlock == nil {
 		result.Success = true
@@ -1663,15 +2165,7 @@ func (e *Engine) DeployEnvironment(ctx context.Context, opts DeployEnvironmentOp
 	}
 
 	// Build datacenter variables map
-	dcVars := make(map[string]interface{})
-	for k, v := range dcState.Variables {
-		dcVars[k] = v
-	}
-	for _, v := range dc.Variables() {
-		if _, ok := dcVars[v.Name()]; !ok && v.Default() != nil {
-			dcVars[v.Name()] = v.Default()
-		}
-	}
+	dcVars := datacenter.ResolveVariables(dc, dcState.Variables, opts.Environment)
 
 	// Collect root module outputs for cross-module references
 	rootOutputs := make(map[string]map[string]interface{})
@@ -1698,6 +2192,12 @@ func (e *Engine) DeployEnvironment(ctx context.Context, opts DeployEnvironmentOp
 		fmt.Fprintf(opts.Output, "  Provisioning %d environment module(s) for %q...\n", len(envModules), opts.Environment)
 	}
 
+	// Modules are provisioned in declaration order, so each can reference the
+	// outputs of those before it as environment.module.<name>.<output>
+	extras := map[string]string{
+		"environment.name": opts.Environment,
+	}
+
 	for _, mod := range envModules {
 		modName := mod.Name()
 
@@ -1724,9 +2224,7 @@ func (e *Engine) DeployEnvironment(ctx context.Context, opts DeployEnvironmentOp
 		// Build module inputs by substituting variable and environment references
 		inputs := make(map[string]interface{})
 		for inputName, exprStr := range mod.Inputs() {
-			inputs[inputName] = evaluateModuleExpression(exprStr, dcVars, rootOutputs, map[string]string{
-				"environment.name": opts.Environment,
-			})
+			inputs[inputName] = evaluateModuleExpression(exprStr, dcVars, rootOutputs, extras)
 		}
 
 		// Get IaC plugin
@@ -1739,10 +2237,24 @@ func (e *Engine) DeployEnvironment(ctx context.Context, opts DeployEnvironmentOp
 			return nil, fmt.Errorf("failed to get IaC plugin %q for module %s: %w", pluginName, modName, err)
 		}
 
+		creds, err := resolveModuleCredentials(mod, dcVars, rootOutputs, extras)
+		if err != nil {
+			return nil, fmt.Errorf("environment module %s: %w", modName, err)
+		}
+		credEnv, err := executor.CredentialEnvironment(ctx, creds, executor.CredentialSessionName(modName), nil)
+		if err != nil {
+			return nil, fmt.Errorf("environment module %s: %w", modName, err)
+		}
+		sandbox, err := executor.ModuleSandbox(dc, modName, pluginName, plugin)
+		if err != nil {
+			return nil, err
+		}
+
 		runOpts := iac.RunOptions{
 			ModuleSource: modulePath,
 			Inputs:       inputs,
-			Environment:  map[string]string{},
+			Environment:  credEnv,
+			Sandbox:      sandbox,
 		}
 
 		// Mark as applying
@@ -1784,18 +2296,21 @@ func (e *Engine) DeployEnvironment(ctx context.Context, opts DeployEnvironmentOp
 		outputs := make(map[string]interface{})
 		for name, out := range applyResult.Outputs {
 			outputs[name] = out.Value
+			extras["environment.module."+modName+"."+name] = fmt.Sprintf("%v", out.Value)
 		}
 		result.ModuleOutputs[modName] = outputs
 
 		envState.Modules[modName] = &types.ModuleState{
-			Name:      modName,
-			Plugin:    pluginName,
-			Source:    modulePath,
-			Inputs:    inputs,
-			Outputs:   outputs,
-			IaCState:  applyResult.State,
-			Status:    types.ModuleStatusReady,
-			UpdatedAt: time.Now(),
+			Name:             modName,
+			Plugin:           pluginName,
+			Source:           modulePath,
+			Inputs:           inputs,
+			Outputs:          outputs,
+			SensitiveOutputs: iac.SensitiveOutputs(applyResult.Outputs),
+			IaCState:         applyResult.State,
+			Credentials:      creds,
+			Status:           types.ModuleStatusReady,
+			UpdatedAt:        time.Now(),
 		}
 		envState.UpdatedAt = time.Now()
 		_ = e.stateManager.SaveEnvironment(ctx, opts.Datacenter, envState)
@@ -1822,7 +2337,16 @@ func (e *Engine) DeployEnvironment(ctx context.Context, opts DeployEnvironmentOp
 
 // DestroyEnvironment destroys environment-scoped modules and all component
 // resources for an environment using the engine. Called by `destroy environment`.
+// The datacenter's on_destroy modules run first, and what they and the
+// on_deploy modules created is destroyed last.
 func (e *Engine) DestroyEnvironment(ctx context.Context, datacenterName, envName string, output io.Writer, onProgress executor.ProgressCallback) error {
+	lockedCtx, unlock, err := e.lockEnvironment(ctx, datacenterName, envName, "destroy environment", false, output)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	ctx = lockedCtx
+
 	// Load datacenter state
 	dcState, err := e.stateManager.GetDatacenter(ctx, datacenterName)
 	if err != nil {
@@ -1835,8 +2359,9 @@ func (e *Engine) DestroyEnvironment(ctx context.Context, datacenterName, envName
 		return fmt.Errorf("environment %q not found: %w", envName, err)
 	}
 
-	// Load datacenter config for plugin resolution
-	dc, err := e.loadDatacenterConfig(dcState.Version)
+	// Load the datacenter version the environment was provisioned with for
+	// plugin resolution
+	dc, err := e.loadDatacenterConfig(environmentDatacenterVersion(envState, dcState))
 	if err != nil {
 		// If we can't load the config, we can still try destroying from stored state
 		if output != nil {
@@ -1844,6 +2369,21 @@ func (e *Engine) DestroyEnvironment(ctx context.Context, datacenterName, envName
 		}
 	}
 
+	var failed []string
+
+	// Run the datacenter's on_destroy modules while the environment is still
+	// intact. Their resources are destroyed once the environment is gone.
+	onDestroy := make(map[string]*types.ModuleState)
+	if dc != nil && dc.Environment() != nil && len(dc.Environment().OnDestroy()) > 0 {
+		r := e.newEnvironmentHookRun(dc, dcState, envState, output, onProgress)
+		if err := r.run(ctx, "on_destroy", dc.Environment().OnDestroy(), onDestroy, func() {}); err != nil {
+			failed = append(failed, "on_destroy hooks")
+			if output != nil {
+				fmt.Fprintf(output, "  [warning] %v\n", err)
+			}
+		}
+	}
+
 	// Phase 1: Destroy all component resources using eng.DestroyComponent
 	if envState.Components != nil {
 		for compName := range envState.Components {
@@ -1859,6 +2399,7 @@ func (e *Engine) DestroyEnvironment(ctx context.Context, datacenterName, envName
 				AutoApprove: true,
 			})
 			if err != nil {
+				failed = append(failed, "component "+compName)
 				if output != nil {
 					fmt.Fprintf(output, "  [warning] Failed to destroy component %q: %v\n", compName, err)
 				}
@@ -1866,49 +2407,147 @@ func (e *Engine) DestroyEnvironment(ctx context.Context, datacenterName, envName
 		}
 	}
 
-	// Phase 2: Destroy environment-scoped modules (in reverse order)
+	// Phase 2: Destroy environment-scoped modules (in reverse order). Each
+	// destroyed module is dropped from state so a retry skips it.
 	if len(envState.Modules) > 0 {
 		if output != nil {
 			fmt.Fprintf(output, "  Destroying %d environment module(s)...\n", len(envState.Modules))
 		}
 
-		for modName, modState := range envState.Modules {
-			if modState.Status == types.ModuleStatusFailed || modState.Plugin == "" {
-				continue
-			}
+		// DestroyComponent saves the environment, so work from the latest copy
+		if latest, err := e.stateManager.GetEnvironment(ctx, datacenterName, envName); err == nil {
+			envState = latest
+		}
 
-			pluginName := modState.Plugin
-			plugin, err := e.iacRegistry.Get(pluginName)
-			if err != nil {
+		for _, modName := range environmentModuleDestroyOrder(dc, envState.Modules) {
+			nodeID := fmt.Sprintf("env/%s/module/%s", envName, modName)
+			reportDestroyProgress(onProgress, nodeID, modName, "module", "running", "Destroying environment module...", nil)
+
+			if err := e.destroyModule(ctx, dc, modName, envState.Modules[modName]); err != nil {
+				failed = append(failed, "module "+modName)
+				reportDestroyProgress(onProgress, nodeID, modName, "module", "failed", "", err)
 				if output != nil {
-					fmt.Fprintf(output, "  [warning] Could not get plugin %q for module %s: %v\n", pluginName, modName, err)
+					fmt.Fprintf(output, "  [warning] Failed to destroy module %s: %v\n", modName, err)
 				}
 				continue
 			}
 
-			runOpts := iac.RunOptions{
-				ModuleSource: modState.Source,
-				Inputs:       modState.Inputs,
-				Environment:  map[string]string{},
-			}
+			delete(envState.Modules, modName)
+			envState.UpdatedAt = time.Now()
+			_ = e.stateManager.SaveEnvironment(ctx, datacenterName, envState)
 
-			if err := plugin.Destroy(ctx, runOpts); err != nil {
-				if output != nil {
-					fmt.Fprintf(output, "  [warning] Failed to destroy module %s: %v\n", modName, err)
-				}
-			} else {
-				if output != nil {
-					fmt.Fprintf(output, "  [success] Module %q destroyed\n", modName)
-				}
+			reportDestroyProgress(onProgress, nodeID, modName, "module", "completed", "Environment module destroyed", nil)
+			if output != nil {
+				fmt.Fprintf(output, "  [success] Module %q destroyed\n", modName)
 			}
 		}
 	}
 
-	_ = dc // Used for future expression-aware destroy
+	// Phase 3: Destroy what the environment's lifecycle hooks created
+	var onDeployDeclarations, onDestroyDeclarations []datacenter.Module
+	if dc != nil && dc.Environment() != nil {
+		onDeployDeclarations = dc.Environment().OnDeploy()
+		onDestroyDeclarations = dc.Environment().OnDestroy()
+	}
+	if latest, err := e.stateManager.GetEnvironment(ctx, datacenterName, envName); err == nil {
+		envState = latest
+	}
+	failed = append(failed, e.destroyEnvironmentHookModules(ctx, dc, envName, "on_deploy", onDeployDeclarations, envState.OnDeploy, func() {
+		envState.UpdatedAt = time.Now()
+		_ = e.stateManager.SaveEnvironment(ctx, datacenterName, envState)
+	}, output, onProgress)...)
+	failed = append(failed, e.destroyEnvironmentHookModules(ctx, dc, envName, "on_destroy", onDestroyDeclarations, onDestroy, func() {}, output, onProgress)...)
 
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to destroy %s", strings.Join(failed, ", "))
+	}
 	return nil
 }
 
+// environmentModuleDestroyOrder returns the names of an environment's modules
+// in the order to destroy them. See moduleDestroyOrder.
+func environmentModuleDestroyOrder(dc datacenter.Datacenter, modules map[string]*types.ModuleState) []string {
+	var declarations []datacenter.Module
+	if dc != nil && dc.Environment() != nil {
+		declarations = dc.Environment().Modules()
+	}
+	return moduleDestroyOrder(declarations, modules)
+}
+
+// moduleDestroyOrder returns the names of the modules in state in the order to
+// destroy them: the reverse of their declaration order, so a module is
+// destroyed before the modules it references. Modules no longer declared are
+// destroyed first, sorted by name.
+func moduleDestroyOrder(declarations []datacenter.Module, modules map[string]*types.ModuleState) []string {
+	var declared []string
+	isDeclared := make(map[string]bool)
+	for _, mod := range declarations {
+		if _, ok := modules[mod.Name()]; ok {
+			declared = append(declared, mod.Name())
+			isDeclared[mod.Name()] = true
+		}
+	}
+
+	var order []string
+	for name := range modules {
+		if !isDeclared[name] {
+			order = append(order, name)
+		}
+	}
+	sort.Strings(order)
+	for i := len(declared) - 1; i >= 0; i-- {
+		order = append(order, declared[i])
+	}
+	return order
+}
+
+// environmentModuleOutputs returns the outputs of an environment's ready
+// environment-scoped modules, keyed by module name, for hooks to reference.
+func environmentModuleOutputs(envState *types.EnvironmentState) map[string]map[string]interface{} {
+	outputs := make(map[string]map[string]interface{})
+	if envState == nil {
+		return outputs
+	}
+	for name, mod := range envState.Modules {
+		if mod != nil && mod.Status == types.ModuleStatusReady {
+			outputs[name] = mod.Outputs
+		}
+	}
+	return outputs
+}
+
+// resolveModuleCredentials resolves a datacenter- or environment-level
+// module's credentials block. It returns nil when the module has none.
+func resolveModuleCredentials(mod datacenter.Module, dcVars map[string]interface{}, moduleOutputs map[string]map[string]interface{}, extras map[string]string) (*types.ModuleCredentials, error) {
+	c := mod.Credentials()
+	if c == nil {
+		return nil, nil
+	}
+	resolve := func(field, expr string) (string, error) {
+		if expr == "" {
+			return "", nil
+		}
+		s, _ := evaluateModuleExpression(expr, dcVars, moduleOutputs, extras).(string)
+		if s == "" || strings.Contains(s, "${") {
+			return "", fmt.Errorf("credentials %s %q did not resolve to a value", field, expr)
+		}
+		return s, nil
+	}
+
+	creds := &types.ModuleCredentials{}
+	var err error
+	if creds.RoleARN, err = resolve("role_arn", c.RoleARN()); err != nil {
+		return nil, err
+	}
+	if creds.ServiceAccount, err = resolve("service_account", c.ServiceAccount()); err != nil {
+		return nil, err
+	}
+	if creds.EnvSet, err = resolve("env_set", c.EnvSet()); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
 // evaluateModuleExpression evaluates a simple expression string used in datacenter
 // module inputs. Supports ${variable.*}, ${environment.name}, and ${module.*.*} references.
 func evaluateModuleExpression(expr string, dcVars map[string]interface{}, moduleOutputs map[string]map[string]interface{}, extras map[string]string) interface{} {
@@ -2124,11 +2763,7 @@ func (e *Engine) ResolveDependencies(ctx context.Context, opts DeployOptions) ([
 		// Fill in defaults from the schema for any unset variables
 		if dcState.Version != "" {
 			if dc, err := e.loadDatacenterConfig(dcState.Version); err == nil && dc != nil {
-				for _, v := range dc.Variables() {
-					if _, ok := dcVars[v.Name()]; !ok && v.Default() != nil {
-						dcVars[v.Name()] = v.Default()
-					}
-				}
+				dcVars = datacenter.ResolveVariables(dc, dcState.Variables, opts.Environment)
 			}
 		}
 		dcModuleOutputs = make(map[string]map[string]interface{})
@@ -2287,26 +2922,96 @@ func (e *Engine) ResolveDependencies(ctx context.Context, opts DeployOptions) ([
 func makeHookFilter(hooks []datacenter.Hook) graph.ImplicitNodeFilter {
 	return func(inputs map[string]interface{}) bool {
 		for _, hook := range hooks {
-			when := hook.When()
-			if when == "" {
-				return true // catch-all hook — always matches
-			}
-
-			// Parse the when expression as HCL and evaluate against inputs.
-			expr, diags := hclsyntax.ParseExpression([]byte(when), "when.hcl", hcl.Pos{Line: 1, Column: 1})
-			if diags.HasErrors() {
+			matched, ok := evaluateHookWhen(hook.When(), inputs)
+			if !ok {
 				// If we can't parse the expression, conservatively assume it matches
 				// so we don't accidentally drop nodes that should exist.
 				return true
 			}
-
-			eval := dcv1.NewEvaluator()
-			eval.SetNodeContext("", "", "", inputs)
-
-			if result, err := eval.EvaluateWhen(expr); err == nil && result {
+			if matched {
 				return true
 			}
 		}
 		return false
 	}
 }
+
+// evaluateEnvironmentWhen evaluates a hook's when clause against a node with
+// the environment context the executor gives it at deploy time: the node's
+// identity and inputs, the environment's name and labels, and datacenter
+// variables. ok is false when the clause can't be evaluated before deploy.
+func evaluateEnvironmentWhen(when string, node *graph.Node, envName, dcName string, labels map[string]string, vars map[string]interface{}) (matched, ok bool) {
+	if when == "" {
+		return true, true
+	}
+	expr, diags := hclsyntax.ParseExpression([]byte(when), "when.hcl", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return false, false
+	}
+	eval := dcv1.NewEvaluator()
+	eval.SetNodeContext(string(node.Type), node.Name, node.Component, node.Inputs)
+	eval.SetEnvironmentContext(envName, dcName, "", "")
+	eval.SetEnvironmentLabels(labels)
+	eval.SetVariables(vars)
+	result, err := eval.EvaluateWhen(expr)
+	if err != nil {
+		return false, false
+	}
+	return result, true
+}
+
+// replaceOnHints returns the planner's ReplaceOn option for an environment
+// of dc: the inputs that the hook provisioning a node declares in
+// replace_on. Nodes whose hook can't be determined before deploy get no
+// hints, so they are updated in place.
+func replaceOnHints(dc datacenter.Datacenter, envName, dcName string, labels map[string]string, vars map[string]interface{}) func(*graph.Node) []string {
+	if dc == nil || dc.Environment() == nil || dc.Environment().Hooks() == nil {
+		return nil
+	}
+	hooks := dc.Environment().Hooks()
+	return func(node *graph.Node) []string {
+		unknown := false
+		hook, _ := datacenter.MatchHook(hooksForType(hooks, node.Type), func(h datacenter.Hook) bool {
+			matched, ok := evaluateEnvironmentWhen(h.When(), node, envName, dcName, labels, vars)
+			if !ok {
+				unknown = true
+			}
+			return matched
+		})
+		if unknown || hook == nil {
+			return nil
+		}
+		return hook.ReplaceOn()
+	}
+}
+
+// checkCapabilities fails when the datacenter lacks a capability the
+// component requires.
+func checkCapabilities(dcName string, dc datacenter.Datacenter, compName string, comp component.Component) error {
+	missing := datacenter.MissingCapabilities(dc, comp.Requires())
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("datacenter %s cannot satisfy component %s: missing %s support", dcName, compName, strings.Join(missing, ", "))
+}
+
+// evaluateHookWhen evaluates a hook's when-clause against node inputs. An
+// empty clause is a catch-all and always matches. ok is false when the
+// clause can't be parsed.
+func evaluateHookWhen(when string, inputs map[string]interface{}) (matched, ok bool) {
+	if when == "" {
+		return true, true
+	}
+
+	// Parse the when expression as HCL and evaluate against inputs.
+	expr, diags := hclsyntax.ParseExpression([]byte(when), "when.hcl", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return false, false
+	}
+
+	eval := dcv1.NewEvaluator()
+	eval.SetNodeContext("", "", "", inputs)
+
+	result, err := eval.EvaluateWhen(expr)
+	return err == nil && result, true
+}
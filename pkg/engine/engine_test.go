@@ -11,7 +11,10 @@ import (
 
 	"github.com/davidthor/cldctl/pkg/engine/executor"
 	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/graph"
 	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/schema/component"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
 	"github.com/davidthor/cldctl/pkg/state"
 	"github.com/davidthor/cldctl/pkg/state/backend"
 	"github.com/davidthor/cldctl/pkg/state/types"
@@ -20,8 +23,12 @@ import (
 // mockStateManager implements state.Manager for testing
 type mockStateManager struct {
 	environments map[string]*types.EnvironmentState
+	datacenter   *types.DatacenterState
 	saveErr      error
 	getErr       error
+
+	// heldLock is a lock held by another process
+	heldLock *backend.LockInfo
 }
 
 func newMockStateManager() *mockStateManager {
@@ -58,8 +65,10 @@ func (m *mockStateManager) SaveEnvironment(ctx context.Context, datacenter strin
 
 func (m *mockStateManager) ListEnvironments(ctx context.Context, datacenter string) ([]types.EnvironmentRef, error) {
 	var refs []types.EnvironmentRef
+	seen := make(map[string]bool)
 	for _, env := range m.environments {
-		if env.Datacenter == datacenter {
+		if env.Datacenter == datacenter && !seen[env.Name] {
+			seen[env.Name] = true
 			refs = append(refs, types.EnvironmentRef{Name: env.Name, Datacenter: env.Datacenter})
 		}
 	}
@@ -74,7 +83,7 @@ func (m *mockStateManager) DeleteEnvironment(ctx context.Context, datacenter, na
 }
 
 func (m *mockStateManager) GetDatacenter(ctx context.Context, name string) (*types.DatacenterState, error) {
-	return nil, nil
+	return m.datacenter, nil
 }
 
 func (m *mockStateManager) SaveDatacenter(ctx context.Context, s *types.DatacenterState) error {
@@ -82,6 +91,7 @@ func (m *mockStateManager) SaveDatacenter(ctx context.Context, s *types.Datacent
 }
 
 func (m *mockStateManager) DeleteDatacenter(ctx context.Context, name string) error {
+	m.datacenter = nil
 	return nil
 }
 
@@ -129,10 +139,54 @@ func (m *mockStateManager) ListDatacenterComponents(ctx context.Context, dc stri
 	return nil, nil
 }
 
+func (m *mockStateManager) GetTenant(ctx context.Context, dc, name string) (*types.TenantConfig, error) {
+	return nil, fmt.Errorf("not found")
+}
+
+func (m *mockStateManager) SaveTenant(ctx context.Context, dc string, s *types.TenantConfig) error {
+	return nil
+}
+
+func (m *mockStateManager) DeleteTenant(ctx context.Context, dc, name string) error {
+	return nil
+}
+
+func (m *mockStateManager) ListTenants(ctx context.Context, dc string) ([]*types.TenantConfig, error) {
+	return nil, nil
+}
+
+func (m *mockStateManager) GetSharedResource(ctx context.Context, dc, key string) (*types.SharedResourceState, error) {
+	return nil, fmt.Errorf("not found")
+}
+
+func (m *mockStateManager) SaveSharedResource(ctx context.Context, dc string, s *types.SharedResourceState) error {
+	return nil
+}
+
+func (m *mockStateManager) DeleteSharedResource(ctx context.Context, dc, key string) error {
+	return nil
+}
+
+func (m *mockStateManager) ListSharedResources(ctx context.Context, dc string) ([]*types.SharedResourceState, error) {
+	return nil, nil
+}
+
 func (m *mockStateManager) Lock(ctx context.Context, scope state.LockScope) (backend.Lock, error) {
+	if m.heldLock != nil {
+		return nil, &backend.LockError{Info: *m.heldLock, Err: backend.ErrLocked}
+	}
 	return nil, nil
 }
 
+func (m *mockStateManager) ForceUnlock(ctx context.Context, scope state.LockScope) (*backend.LockInfo, error) {
+	if m.heldLock == nil {
+		return nil, backend.ErrNotFound
+	}
+	info := m.heldLock
+	m.heldLock = nil
+	return info, nil
+}
+
 func (m *mockStateManager) Backend() backend.Backend {
 	return nil
 }
@@ -318,6 +372,54 @@ func TestPrintPlanSummary(t *testing.T) {
 	})
 }
 
+func TestPrintPlanPreview(t *testing.T) {
+	var buf bytes.Buffer
+	plan := &planner.Plan{
+		Changes: []*planner.ResourceChange{
+			{
+				Action: planner.ActionUpdate,
+				Node:   &graph.Node{ID: "api/deployment/main"},
+				Preview: []planner.ModulePreview{
+					{
+						Module: "container",
+						Plugin: "native",
+						Changes: []iac.ResourceChange{
+							{
+								ResourceID:   "api-main",
+								ResourceType: "docker_container",
+								Action:       iac.ActionReplace,
+								Diff: []iac.PropertyDiff{
+									{Path: "image", OldValue: "api:v1", NewValue: "api:v2"},
+									{Path: "env.TOKEN", OldValue: "a", NewValue: "b", Sensitive: true},
+								},
+							},
+						},
+					},
+				},
+			},
+			{Action: planner.ActionNoop, Node: &graph.Node{ID: "api/service/main"}},
+		},
+	}
+
+	printPlanPreview(&buf, plan)
+
+	output := buf.String()
+	for _, want := range []string{
+		"api/deployment/main (update)",
+		"module container (native)",
+		"± docker_container api-main",
+		"image: api:v1 -> api:v2",
+		"env.TOKEN: (sensitive) -> (sensitive)",
+	} {
+		if !bytes.Contains([]byte(output), []byte(want)) {
+			t.Errorf("Expected %q in output, got: %s", want, output)
+		}
+	}
+	if bytes.Contains([]byte(output), []byte("api/service/main")) {
+		t.Errorf("Expected changes without a preview to be omitted, got: %s", output)
+	}
+}
+
 func TestPrintDestroyPlanSummary(t *testing.T) {
 	sm := newMockStateManager()
 	registry := iac.DefaultRegistry
@@ -815,3 +917,75 @@ func TestDestroyComponent_ForceOverridesDependents(t *testing.T) {
 		t.Error("expected success for forced dry run")
 	}
 }
+
+func TestCheckCapabilities(t *testing.T) {
+	dc, err := datacenter.NewLoader().LoadFromBytes([]byte(`
+environment {
+  database {
+    when = element(split(":", node.inputs.type), 0) == "postgres"
+    module "postgres" {
+      build = "./modules/postgres"
+    }
+    outputs = {
+      host = module.postgres.host
+      port = module.postgres.port
+      url  = module.postgres.url
+    }
+  }
+}
+`), "/tmp/dc/datacenter.dc")
+	if err != nil {
+		t.Fatalf("failed to load datacenter: %v", err)
+	}
+	loadComp := func(yaml string) component.Component {
+		comp, err := component.NewLoader().LoadFromBytes([]byte(yaml), "/tmp/app/cld.yml")
+		if err != nil {
+			t.Fatalf("failed to load component: %v", err)
+		}
+		return comp
+	}
+
+	if err := checkCapabilities("local", dc, "app", loadComp("requires: [postgres]\n")); err != nil {
+		t.Errorf("expected supported requirements to pass, got %v", err)
+	}
+
+	err = checkCapabilities("local", dc, "app", loadComp("requires: [postgres, smtp, cronjobs]\n"))
+	want := "datacenter local cannot satisfy component app: missing smtp, cronjob support"
+	if err == nil || err.Error() != want {
+		t.Errorf("expected %q, got %v", want, err)
+	}
+}
+
+func TestEnvironmentModuleDestroyOrder(t *testing.T) {
+	dc, err := datacenter.NewLoader().LoadFromBytes([]byte(`
+environment {
+  module "network" {
+    build = "./modules/network"
+  }
+  module "ingress" {
+    build = "./modules/ingress"
+    inputs = {
+      vpc = environment.module.network.vpc_id
+    }
+  }
+  module "otel" {
+    build = "./modules/otel"
+  }
+}
+`), "/tmp/dc/datacenter.dc")
+	if err != nil {
+		t.Fatalf("failed to load datacenter: %v", err)
+	}
+
+	modules := map[string]*types.ModuleState{
+		"network": {Name: "network"},
+		"ingress": {Name: "ingress"},
+		"legacy":  {Name: "legacy"},
+	}
+
+	got := environmentModuleDestroyOrder(dc, modules)
+	want := []string{"legacy", "ingress", "network"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected destroy order %v, got %v", want, got)
+	}
+}
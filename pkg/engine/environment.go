@@ -0,0 +1,325 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/schema/environment"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// environmentOutputRefPattern matches the component references allowed in
+// environment outputs: ${{ components.<name>.outputs.<key> }} and
+// ${{ components.<name>.routes.<route>.url }}.
+var environmentOutputRefPattern = regexp.MustCompile(`\$\{\{\s*components\.([^\s.}]+)\.(?:outputs\.([^\s.}]+)|routes\.([^\s.}]+)\.url)\s*\}\}`)
+
+// ScalingOverride holds environment-level scaling for a single deployment.
+// Zero values leave the component's own setting in place.
+type ScalingOverride struct {
+	Replicas    int
+	CPU         string
+	Memory      string
+	MinReplicas int
+	MaxReplicas int
+}
+
+// ApplyEnvironmentComponent adds a component declared in an environment file
+// to the deploy options: its source and variables along with the
+// environment's port, route, replica, scaling, and instance overrides.
+// Relative local paths are resolved against baseDir when it is set.
+func (opts *DeployOptions) ApplyEnvironmentComponent(name string, cfg environment.ComponentConfig, baseDir string) {
+	resolve := func(path string) string {
+		if baseDir == "" || path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(baseDir, path)
+	}
+
+	source := cfg.Image()
+	if cfg.Path() != "" {
+		source = resolve(cfg.Path())
+	}
+
+	if opts.Components == nil {
+		opts.Components = make(map[string]string)
+	}
+	if opts.Variables == nil {
+		opts.Variables = make(map[string]map[string]interface{})
+	}
+	opts.Variables[name] = cfg.Variables()
+
+	if ports := cfg.Ports(); len(ports) > 0 {
+		if opts.Ports == nil {
+			opts.Ports = make(map[string]map[string]int)
+		}
+		opts.Ports[name] = ports
+	}
+
+	// Copy route configs (subdomain/pathPrefix/hostnames) from environment file
+	if routeConfigs := cfg.Routes(); len(routeConfigs) > 0 {
+		routeMap := make(map[string]RouteOverride, len(routeConfigs))
+		for routeName, rc := range routeConfigs {
+			ro := RouteOverride{
+				Subdomain:  rc.Subdomain(),
+				PathPrefix: rc.PathPrefix(),
+			}
+			for _, h := range rc.Hostnames() {
+				ro.Hostnames = append(ro.Hostnames, RouteHostname{
+					Subdomain:  h.Subdomain(),
+					Host:       h.Host(),
+					PathPrefix: h.PathPrefix(),
+				})
+			}
+			if ro.Subdomain != "" || ro.PathPrefix != "" || len(ro.Hostnames) > 0 {
+				routeMap[routeName] = ro
+			}
+		}
+		if len(routeMap) > 0 {
+			if opts.Routes == nil {
+				opts.Routes = make(map[string]map[string]RouteOverride)
+			}
+			opts.Routes[name] = routeMap
+		}
+	}
+
+	if replicas := cfg.Replicas(); replicas > 0 {
+		if opts.Replicas == nil {
+			opts.Replicas = make(map[string]int)
+		}
+		opts.Replicas[name] = replicas
+	}
+
+	if scaling := cfg.Scaling(); len(scaling) > 0 {
+		scalingMap := make(map[string]ScalingOverride, len(scaling))
+		for deployName, s := range scaling {
+			scalingMap[deployName] = ScalingOverride{
+				Replicas:    s.Replicas(),
+				CPU:         s.CPU(),
+				Memory:      s.Memory(),
+				MinReplicas: s.MinReplicas(),
+				MaxReplicas: s.MaxReplicas(),
+			}
+		}
+		if opts.Scaling == nil {
+			opts.Scaling = make(map[string]map[string]ScalingOverride)
+		}
+		opts.Scaling[name] = scalingMap
+	}
+
+	// Instances: the first (newest) instance's source defines the shared
+	// resources, so it becomes the component's source. Instances without a
+	// source of their own use the top-level path or image.
+	if instances := cfg.Instances(); len(instances) > 0 {
+		infos := make([]graph.InstanceInfo, len(instances))
+		sources := make(map[string]string, len(instances))
+		instanceVars := make(map[string]map[string]interface{})
+		for i, inst := range instances {
+			infos[i] = graph.InstanceInfo{Name: inst.Name(), Weight: inst.Weight()}
+			instSource := source
+			if inst.Source() != "" {
+				instSource = inst.Source()
+				if isLocalPath(instSource) {
+					instSource = resolve(instSource)
+				}
+			}
+			sources[inst.Name()] = instSource
+			if len(inst.Variables()) > 0 {
+				instanceVars[inst.Name()] = inst.Variables()
+			}
+		}
+		source = sources[instances[0].Name()]
+
+		if opts.Instances == nil {
+			opts.Instances = make(map[string][]graph.InstanceInfo)
+		}
+		opts.Instances[name] = infos
+		if opts.InstanceSources == nil {
+			opts.InstanceSources = make(map[string]map[string]string)
+		}
+		opts.InstanceSources[name] = sources
+		if len(instanceVars) > 0 {
+			if opts.InstanceVariables == nil {
+				opts.InstanceVariables = make(map[string]map[string]map[string]interface{})
+			}
+			opts.InstanceVariables[name] = instanceVars
+		}
+		if distinct := cfg.Distinct(); len(distinct) > 0 {
+			if opts.Distinct == nil {
+				opts.Distinct = make(map[string][]string)
+			}
+			opts.Distinct[name] = distinct
+		}
+	}
+
+	opts.Components[name] = source
+}
+
+// applyScalingOverrides sets environment-level replica and resource
+// overrides on deployment nodes before planning, so changing them in the
+// environment file updates the affected deployments. Component-wide replicas
+// apply to every deployment; per-deployment scaling takes precedence.
+func applyScalingOverrides(g *graph.Graph, replicas map[string]int, scaling map[string]map[string]ScalingOverride) error {
+	found := make(map[string]map[string]bool)
+	for _, node := range g.Nodes {
+		if node.Type != graph.NodeTypeDeployment {
+			continue
+		}
+		if r := replicas[node.Component]; r > 0 {
+			node.SetInput("replicas", r)
+		}
+		s, ok := scaling[node.Component][node.Name]
+		if !ok {
+			continue
+		}
+		if found[node.Component] == nil {
+			found[node.Component] = make(map[string]bool)
+		}
+		found[node.Component][node.Name] = true
+
+		if s.Replicas > 0 {
+			node.SetInput("replicas", s.Replicas)
+		}
+		if s.CPU != "" {
+			node.SetInput("cpu", s.CPU)
+		}
+		if s.Memory != "" {
+			node.SetInput("memory", s.Memory)
+		}
+		if s.MinReplicas > 0 {
+			node.SetInput("minReplicas", s.MinReplicas)
+		}
+		if s.MaxReplicas > 0 {
+			node.SetInput("maxReplicas", s.MaxReplicas)
+		}
+	}
+
+	// Report scaling for deployments the component doesn't declare rather
+	// than silently ignoring it
+	var unknown []string
+	for compName, deployments := range scaling {
+		for deployName := range deployments {
+			if !found[compName][deployName] {
+				unknown = append(unknown, fmt.Sprintf("%s.%s", compName, deployName))
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("scaling configured for unknown deployments: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// ResolveEnvironmentOutputs evaluates an environment file's outputs against
+// the deployed environment and records them in its state, replacing any
+// outputs from a previous deploy. Variables and locals are expected to have
+// been substituted already (see environment.ResolveVariables).
+func (e *Engine) ResolveEnvironmentOutputs(ctx context.Context, dcName, envName string, outputs map[string]environment.Output) (map[string]*types.EnvironmentOutput, error) {
+	lockedCtx, unlock, err := e.lockEnvironment(ctx, dcName, envName, "resolve outputs", false, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	ctx = lockedCtx
+
+	envState, err := e.stateManager.GetEnvironment(ctx, dcName, envName)
+	if err != nil {
+		return nil, fmt.Errorf("environment %q not found in datacenter %q: %w", envName, dcName, err)
+	}
+
+	resolved := make(map[string]*types.EnvironmentOutput, len(outputs))
+	for name, output := range outputs {
+		value, err := resolveEnvironmentOutput(output.Value(), envState)
+		if err != nil {
+			return nil, fmt.Errorf("outputs.%s: %w", name, err)
+		}
+		resolved[name] = &types.EnvironmentOutput{
+			Value:       value,
+			Description: output.Description(),
+			Sensitive:   output.Sensitive(),
+		}
+	}
+
+	if len(resolved) == 0 {
+		resolved = nil
+	}
+	envState.Outputs = resolved
+	envState.UpdatedAt = time.Now()
+	if err := e.stateManager.SaveEnvironment(ctx, dcName, envState); err != nil {
+		return nil, fmt.Errorf("failed to save environment state: %w", err)
+	}
+	return resolved, nil
+}
+
+// resolveEnvironmentOutput substitutes component output and route URL
+// references in an output expression. A value that is exactly one reference
+// keeps the referenced value's type.
+func resolveEnvironmentOutput(expr string, envState *types.EnvironmentState) (interface{}, error) {
+	if m := environmentOutputRefPattern.FindStringSubmatch(expr); m != nil && m[0] == strings.TrimSpace(expr) {
+		return environmentReference(m, envState)
+	}
+
+	var resolveErr error
+	value := environmentOutputRefPattern.ReplaceAllStringFunc(expr, func(match string) string {
+		v, err := environmentReference(environmentOutputRefPattern.FindStringSubmatch(match), envState)
+		if err != nil {
+			if resolveErr == nil {
+				resolveErr = err
+			}
+			return ""
+		}
+		return fmt.Sprintf("%v", v)
+	})
+	if resolveErr != nil {
+		return nil, resolveErr
+	}
+	return value, nil
+}
+
+// environmentReference looks up one environmentOutputRefPattern match in the
+// environment's state.
+func environmentReference(m []string, envState *types.EnvironmentState) (interface{}, error) {
+	compName, outputKey, routeName := m[1], m[2], m[3]
+	comp := envState.Components[compName]
+	if comp == nil {
+		return nil, fmt.Errorf("component %q is not deployed", compName)
+	}
+
+	if outputKey != "" {
+		value, ok := comp.Outputs[outputKey]
+		if !ok {
+			return nil, fmt.Errorf("component %q has no output %q", compName, outputKey)
+		}
+		return value, nil
+	}
+
+	route := comp.Resources["route."+routeName]
+	if route == nil {
+		// In multi-instance mode, routes may be per-instance
+		instanceNames := make([]string, 0, len(comp.Instances))
+		for name := range comp.Instances {
+			instanceNames = append(instanceNames, name)
+		}
+		sort.Strings(instanceNames)
+		for _, name := range instanceNames {
+			if r := comp.Instances[name].Resources["route."+routeName]; r != nil {
+				route = r
+				break
+			}
+		}
+	}
+	if route == nil {
+		return nil, fmt.Errorf("component %q has no route %q", compName, routeName)
+	}
+	url, ok := route.Outputs["url"]
+	if !ok {
+		return nil, fmt.Errorf("route %q of component %q has no URL", routeName, compName)
+	}
+	return url, nil
+}
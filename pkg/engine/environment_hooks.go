@@ -0,0 +1,358 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/engine/executor"
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// environmentHookRun carries what the modules of an on_deploy or on_destroy
+// block are evaluated against.
+type environmentHookRun struct {
+	registry    *iac.Registry
+	dc          datacenter.Datacenter
+	envName     string
+	dcVars      map[string]interface{}
+	rootOutputs map[string]map[string]interface{}
+	context     map[string]interface{}
+	output      io.Writer
+	onProgress  executor.ProgressCallback
+}
+
+// newEnvironmentHookRun prepares a run of environment lifecycle hooks
+// against the current state of an environment.
+func (e *Engine) newEnvironmentHookRun(dc datacenter.Datacenter, dcState *types.DatacenterState, envState *types.EnvironmentState, output io.Writer, onProgress executor.ProgressCallback) *environmentHookRun {
+	dcVars := datacenter.ResolveVariables(dc, dcState.Variables, envState.Name)
+
+	rootOutputs := make(map[string]map[string]interface{})
+	for name, mod := range dcState.Modules {
+		if mod.Outputs != nil {
+			rootOutputs[name] = mod.Outputs
+		}
+	}
+
+	return &environmentHookRun{
+		registry:    e.iacRegistry,
+		dc:          dc,
+		envName:     envState.Name,
+		dcVars:      dcVars,
+		rootOutputs: rootOutputs,
+		context:     environmentHookContext(envState),
+		output:      output,
+		onProgress:  onProgress,
+	}
+}
+
+// environmentHookContext returns the environment object lifecycle hook
+// modules can reference in their inputs:
+//
+//	environment.name
+//	environment.labels.<key>
+//	environment.module.<name>.<output>
+//	environment.components.<name>.outputs.<key>
+//	environment.components.<name>.routes.<route>.url
+//
+// Any level can be passed as a whole, e.g. environment.components.
+func environmentHookContext(envState *types.EnvironmentState) map[string]interface{} {
+	labels := make(map[string]interface{}, len(envState.Labels))
+	for k, v := range envState.Labels {
+		labels[k] = v
+	}
+
+	modules := make(map[string]interface{})
+	for name, outputs := range environmentModuleOutputs(envState) {
+		modules[name] = outputs
+	}
+
+	components := make(map[string]interface{}, len(envState.Components))
+	for name, comp := range envState.Components {
+		if comp == nil {
+			continue
+		}
+		outputs := make(map[string]interface{}, len(comp.Outputs))
+		for k, v := range comp.Outputs {
+			outputs[k] = v
+		}
+		components[name] = map[string]interface{}{
+			"outputs": outputs,
+			"routes":  componentRouteURLs(comp),
+		}
+	}
+
+	return map[string]interface{}{
+		"name":       envState.Name,
+		"labels":     labels,
+		"module":     modules,
+		"components": components,
+	}
+}
+
+// componentRouteURLs returns the URL of each of a component's routes. In
+// multi-instance mode, routes may be per-instance; the first instance by
+// name that has a route provides its URL.
+func componentRouteURLs(comp *types.ComponentState) map[string]interface{} {
+	routes := make(map[string]interface{})
+	add := func(resources map[string]*types.ResourceState) {
+		for key, rs := range resources {
+			if rs == nil || rs.Type != "route" {
+				continue
+			}
+			name := strings.TrimPrefix(key, "route.")
+			if _, ok := routes[name]; ok {
+				continue
+			}
+			if url, ok := rs.Outputs["url"]; ok {
+				routes[name] = map[string]interface{}{"url": url}
+			}
+		}
+	}
+
+	add(comp.Resources)
+	instanceNames := make([]string, 0, len(comp.Instances))
+	for name := range comp.Instances {
+		instanceNames = append(instanceNames, name)
+	}
+	sort.Strings(instanceNames)
+	for _, name := range instanceNames {
+		add(comp.Instances[name].Resources)
+	}
+	return routes
+}
+
+// evaluate evaluates a lifecycle hook module's input expression. Direct
+// references into the environment object keep their structure; anything
+// else is evaluated like an environment module's inputs.
+func (r *environmentHookRun) evaluate(expr string) interface{} {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "environment.") && !strings.Contains(expr, "${") {
+		if v, ok := lookupContextPath(r.context, strings.Split(expr[len("environment."):], ".")); ok {
+			return v
+		}
+	}
+	return evaluateModuleExpression(expr, r.dcVars, r.rootOutputs, r.extras())
+}
+
+// extras flattens the environment object's scalar values for string
+// interpolation, e.g. "${environment.components.api.routes.main.url}/health".
+func (r *environmentHookRun) extras() map[string]string {
+	extras := make(map[string]string)
+	var flatten func(prefix string, v interface{})
+	flatten = func(prefix string, v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for k, child := range v {
+				flatten(prefix+"."+k, child)
+			}
+		case nil:
+		default:
+			extras[prefix] = fmt.Sprintf("%v", v)
+		}
+	}
+	flatten("environment", r.context)
+	return extras
+}
+
+// lookupContextPath walks a nested map along path.
+func lookupContextPath(ctx map[string]interface{}, path []string) (interface{}, bool) {
+	var current interface{} = ctx
+	for _, key := range path {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// apply applies one lifecycle hook module. prior is the module's state from
+// an earlier run, if any, so the module updates what it created then rather
+// than creating it again.
+func (r *environmentHookRun) apply(ctx context.Context, block string, mod datacenter.Module, prior *types.ModuleState) (*types.ModuleState, error) {
+	modName := mod.Name()
+
+	modulePath := mod.Build()
+	if modulePath == "" {
+		modulePath = mod.Source()
+	}
+	if modulePath != "" && !filepath.IsAbs(modulePath) {
+		modulePath = filepath.Join(filepath.Dir(r.dc.SourcePath()), modulePath)
+	}
+
+	inputs := make(map[string]interface{})
+	for inputName, exprStr := range mod.Inputs() {
+		inputs[inputName] = r.evaluate(exprStr)
+	}
+
+	pluginName := mod.Plugin()
+	if pluginName == "" {
+		pluginName = "native"
+	}
+	plugin, err := r.registry.Get(pluginName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IaC plugin %q for %s module %s: %w", pluginName, block, modName, err)
+	}
+
+	creds, err := resolveModuleCredentials(mod, r.dcVars, r.rootOutputs, r.extras())
+	if err != nil {
+		return nil, fmt.Errorf("%s module %s: %w", block, modName, err)
+	}
+	credEnv, err := executor.CredentialEnvironment(ctx, creds, executor.CredentialSessionName(modName), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s module %s: %w", block, modName, err)
+	}
+	sandbox, err := executor.ModuleSandbox(r.dc, modName, pluginName, plugin)
+	if err != nil {
+		return nil, err
+	}
+
+	runOpts := iac.RunOptions{
+		ModuleSource: modulePath,
+		Inputs:       inputs,
+		Environment:  credEnv,
+		Sandbox:      sandbox,
+	}
+	if prior != nil && prior.IaCState != nil {
+		runOpts.StateReader = bytes.NewReader(prior.IaCState)
+	}
+
+	modState := &types.ModuleState{
+		Name:        modName,
+		Plugin:      pluginName,
+		Source:      modulePath,
+		Inputs:      inputs,
+		Credentials: creds,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if prior != nil && !prior.CreatedAt.IsZero() {
+		modState.CreatedAt = prior.CreatedAt
+	}
+
+	applyResult, err := plugin.Apply(ctx, runOpts)
+	if err != nil {
+		modState.Status = types.ModuleStatusFailed
+		modState.StatusReason = err.Error()
+		if prior != nil {
+			// Keep what the last successful run created so it can still be
+			// destroyed
+			modState.IaCState = prior.IaCState
+		}
+		return modState, fmt.Errorf("failed to apply %s module %s: %w", block, modName, err)
+	}
+
+	modState.Outputs = make(map[string]interface{}, len(applyResult.Outputs))
+	for name, out := range applyResult.Outputs {
+		modState.Outputs[name] = out.Value
+	}
+	modState.SensitiveOutputs = iac.SensitiveOutputs(applyResult.Outputs)
+	modState.IaCState = applyResult.State
+	modState.Status = types.ModuleStatusReady
+	return modState, nil
+}
+
+// runEnvironmentHooks applies the modules of an on_deploy or on_destroy
+// block in declaration order, updating states as each finishes. It stops at
+// the first module that fails.
+func (r *environmentHookRun) run(ctx context.Context, block string, modules []datacenter.Module, states map[string]*types.ModuleState, save func()) error {
+	if r.output != nil {
+		fmt.Fprintf(r.output, "  Running %d %s module(s) for %q...\n", len(modules), block, r.envName)
+	}
+
+	for _, mod := range modules {
+		modName := mod.Name()
+		nodeID := fmt.Sprintf("env/%s/%s/%s", r.envName, block, modName)
+		r.report(nodeID, modName, "running", fmt.Sprintf("Running %s module...", block), nil)
+
+		modState, err := r.apply(ctx, block, mod, states[modName])
+		if modState != nil {
+			states[modName] = modState
+			save()
+		}
+		if err != nil {
+			r.report(nodeID, modName, "failed", "", err)
+			return err
+		}
+
+		r.report(nodeID, modName, "completed", fmt.Sprintf("%s module ran", block), nil)
+		if r.output != nil {
+			fmt.Fprintf(r.output, "    [success] Module %q ran\n", modName)
+		}
+	}
+	return nil
+}
+
+func (r *environmentHookRun) report(nodeID, name, status, message string, err error) {
+	if r.onProgress == nil {
+		return
+	}
+	r.onProgress(executor.ProgressEvent{
+		NodeID:   nodeID,
+		NodeName: name,
+		NodeType: "module",
+		Status:   status,
+		Message:  message,
+		Error:    err,
+	})
+}
+
+// runOnDeploy runs the datacenter's on_deploy modules against the
+// environment as it stands after a deploy, recording their state in the
+// environment so they can be destroyed with it.
+func (e *Engine) runOnDeploy(ctx context.Context, dc datacenter.Datacenter, dcState *types.DatacenterState, dcName, envName string, output io.Writer, onProgress executor.ProgressCallback) error {
+	if dc.Environment() == nil || len(dc.Environment().OnDeploy()) == 0 {
+		return nil
+	}
+
+	envState, err := e.stateManager.GetEnvironment(ctx, dcName, envName)
+	if err != nil {
+		return fmt.Errorf("failed to read environment state: %w", err)
+	}
+	if envState.OnDeploy == nil {
+		envState.OnDeploy = make(map[string]*types.ModuleState)
+	}
+
+	r := e.newEnvironmentHookRun(dc, dcState, envState, output, onProgress)
+	return r.run(ctx, "on_deploy", dc.Environment().OnDeploy(), envState.OnDeploy, func() {
+		envState.UpdatedAt = time.Now()
+		_ = e.stateManager.SaveEnvironment(ctx, dcName, envState)
+	})
+}
+
+// destroyEnvironmentHookModules destroys the modules of an on_deploy or
+// on_destroy block from their recorded states, in reverse declaration order.
+// Each destroyed module is dropped from states and save is called, so a
+// retry skips it. It returns the names of the modules that failed.
+func (e *Engine) destroyEnvironmentHookModules(ctx context.Context, dc datacenter.Datacenter, envName, block string, declarations []datacenter.Module, states map[string]*types.ModuleState, save func(), output io.Writer, onProgress executor.ProgressCallback) []string {
+	var failed []string
+	for _, modName := range moduleDestroyOrder(declarations, states) {
+		nodeID := fmt.Sprintf("env/%s/%s/%s", envName, block, modName)
+		reportDestroyProgress(onProgress, nodeID, modName, "module", "running", fmt.Sprintf("Destroying %s module...", block), nil)
+
+		if err := e.destroyModule(ctx, dc, modName, states[modName]); err != nil {
+			failed = append(failed, fmt.Sprintf("%s module %s", block, modName))
+			reportDestroyProgress(onProgress, nodeID, modName, "module", "failed", "", err)
+			if output != nil {
+				fmt.Fprintf(output, "  [warning] Failed to destroy %s module %s: %v\n", block, modName, err)
+			}
+			continue
+		}
+
+		delete(states, modName)
+		save()
+		reportDestroyProgress(onProgress, nodeID, modName, "module", "completed", fmt.Sprintf("%s module destroyed", block), nil)
+	}
+	return failed
+}
@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// hookRecorder is an IaC plugin that records the inputs of each module it
+// applies and the modules it destroys.
+type hookRecorder struct {
+	events  []string
+	applied map[string]map[string]interface{}
+	priorOf map[string]bool
+}
+
+func (p *hookRecorder) Name() string { return "recorder" }
+
+func (p *hookRecorder) Preview(ctx context.Context, opts iac.RunOptions) (*iac.PreviewResult, error) {
+	return &iac.PreviewResult{}, nil
+}
+
+func (p *hookRecorder) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResult, error) {
+	name := filepath.Base(opts.ModuleSource)
+	p.events = append(p.events, "apply "+name)
+	p.applied[name] = opts.Inputs
+	p.priorOf[name] = opts.StateReader != nil
+	return &iac.ApplyResult{
+		Outputs: map[string]iac.OutputValue{"id": {Value: name + "-id"}},
+		State:   []byte(name),
+	}, nil
+}
+
+func (p *hookRecorder) Destroy(ctx context.Context, opts iac.RunOptions) error {
+	p.events = append(p.events, "destroy "+filepath.Base(opts.ModuleSource))
+	return nil
+}
+
+func (p *hookRecorder) Refresh(ctx context.Context, opts iac.RunOptions) (*iac.RefreshResult, error) {
+	return &iac.RefreshResult{}, nil
+}
+
+func (p *hookRecorder) Import(ctx context.Context, opts iac.ImportOptions) (*iac.ImportResult, error) {
+	return &iac.ImportResult{}, nil
+}
+
+const environmentHooksTestDatacenter = `
+environment {
+  on_deploy {
+    module "warm" {
+      plugin = "recorder"
+      build  = "./modules/warm"
+      inputs = {
+        url        = environment.components.api.routes.main.url
+        health     = "${environment.components.api.routes.main.url}/health"
+        components = environment.components
+        env        = environment.name
+        team       = environment.labels.team
+      }
+    }
+  }
+
+  on_destroy {
+    module "notify" {
+      plugin = "recorder"
+      build  = "./modules/notify"
+      inputs = {
+        env = environment.name
+      }
+    }
+  }
+}
+`
+
+func newEnvironmentHooksTestEngine(t *testing.T) (*Engine, *mockStateManager, *hookRecorder) {
+	t.Helper()
+	dcFile := filepath.Join(t.TempDir(), "datacenter.dc")
+	if err := os.WriteFile(dcFile, []byte(environmentHooksTestDatacenter), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := newMockStateManager()
+	mgr.datacenter = &types.DatacenterState{Name: "dc", Version: dcFile}
+
+	plugin := &hookRecorder{applied: map[string]map[string]interface{}{}, priorOf: map[string]bool{}}
+	registry := iac.NewRegistry()
+	registry.Register("recorder", func() (iac.Plugin, error) { return plugin, nil })
+	return NewEngine(mgr, registry), mgr, plugin
+}
+
+func TestRunOnDeploy(t *testing.T) {
+	ctx := context.Background()
+	eng, mgr, plugin := newEnvironmentHooksTestEngine(t)
+	_ = mgr.SaveEnvironment(ctx, "dc", &types.EnvironmentState{
+		Name:   "staging",
+		Labels: map[string]string{"team": "payments"},
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Name:    "api",
+				Outputs: map[string]interface{}{"version": "1.2.0"},
+				Resources: map[string]*types.ResourceState{
+					"route.main": {Name: "main", Type: "route", Outputs: map[string]interface{}{"url": "https://api.example.com"}},
+				},
+			},
+		},
+	})
+
+	dc, err := eng.loadDatacenterConfig(mgr.datacenter.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.runOnDeploy(ctx, dc, mgr.datacenter, "dc", "staging", nil, nil); err != nil {
+		t.Fatalf("runOnDeploy failed: %v", err)
+	}
+
+	inputs := plugin.applied["warm"]
+	if inputs["url"] != "https://api.example.com" || inputs["health"] != "https://api.example.com/health" {
+		t.Errorf("unexpected route inputs: url %v, health %v", inputs["url"], inputs["health"])
+	}
+	if inputs["env"] != "staging" || inputs["team"] != "payments" {
+		t.Errorf("unexpected environment inputs: env %v, team %v", inputs["env"], inputs["team"])
+	}
+	components, ok := inputs["components"].(map[string]interface{})
+	if !ok || fmt.Sprint(components["api"].(map[string]interface{})["outputs"]) != "map[version:1.2.0]" {
+		t.Errorf("expected environment.components to be passed whole, got %v", inputs["components"])
+	}
+
+	modState := mgr.environments["staging"].OnDeploy["warm"]
+	if modState == nil || modState.Status != types.ModuleStatusReady || string(modState.IaCState) != "warm" {
+		t.Fatalf("expected the module's state to be recorded, got %+v", modState)
+	}
+
+	// Later deploys update what the module created
+	if err := eng.runOnDeploy(ctx, dc, mgr.datacenter, "dc", "staging", nil, nil); err != nil {
+		t.Fatalf("runOnDeploy failed: %v", err)
+	}
+	if !plugin.priorOf["warm"] {
+		t.Error("expected the second run to be given the module's state")
+	}
+}
+
+func TestDestroyEnvironment_LifecycleHooks(t *testing.T) {
+	ctx := context.Background()
+	eng, mgr, plugin := newEnvironmentHooksTestEngine(t)
+	_ = mgr.SaveEnvironment(ctx, "dc", &types.EnvironmentState{
+		Name: "staging",
+		Modules: map[string]*types.ModuleState{
+			"namespace": {Name: "namespace", Plugin: "recorder", Source: "/modules/namespace", IaCState: []byte("{}"), Status: types.ModuleStatusReady},
+		},
+		OnDeploy: map[string]*types.ModuleState{
+			"warm": {Name: "warm", Plugin: "recorder", Source: "/modules/warm", IaCState: []byte("{}"), Status: types.ModuleStatusReady},
+		},
+	})
+
+	if err := eng.DestroyEnvironment(ctx, "dc", "staging", nil, nil); err != nil {
+		t.Fatalf("DestroyEnvironment failed: %v", err)
+	}
+
+	want := "[apply notify destroy namespace destroy warm destroy notify]"
+	if got := fmt.Sprint(plugin.events); got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+	if plugin.applied["notify"]["env"] != "staging" {
+		t.Errorf("expected on_destroy inputs to be resolved, got %v", plugin.applied["notify"])
+	}
+	if env := mgr.environments["staging"]; env != nil && len(env.OnDeploy) != 0 {
+		t.Errorf("expected on_deploy state to be removed, got %v", env.OnDeploy)
+	}
+}
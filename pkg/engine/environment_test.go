@@ -0,0 +1,285 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/schema/environment"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func TestApplyEnvironmentComponent(t *testing.T) {
+	env, err := environment.NewLoader().LoadFromBytes([]byte(`
+components:
+  api:
+    path: ./api
+    replicas: 2
+    variables:
+      log_level: info
+    ports:
+      http: 8080
+    routes:
+      main:
+        subdomain: api
+    scaling:
+      worker:
+        replicas: 4
+        memory: 1Gi
+  web:
+    instances:
+      - name: canary
+        source: ghcr.io/org/web:v2
+        weight: 10
+        variables:
+          beta: "true"
+      - name: stable
+        source: ghcr.io/org/web:v1
+        weight: 90
+    distinct:
+      - database.main
+`), "/envs/staging/environment.yml")
+	if err != nil {
+		t.Fatalf("failed to load environment: %v", err)
+	}
+
+	var opts DeployOptions
+	for name, cfg := range env.Components() {
+		opts.ApplyEnvironmentComponent(name, cfg, "/envs/staging")
+	}
+
+	if got := opts.Components["api"]; got != filepath.Join("/envs/staging", "api") {
+		t.Errorf("expected path relative to the environment file, got %q", got)
+	}
+	if opts.Variables["api"]["log_level"] != "info" {
+		t.Errorf("unexpected variables: %v", opts.Variables["api"])
+	}
+	if opts.Ports["api"]["http"] != 8080 {
+		t.Errorf("unexpected ports: %v", opts.Ports["api"])
+	}
+	if opts.Routes["api"]["main"].Subdomain != "api" {
+		t.Errorf("unexpected routes: %v", opts.Routes["api"])
+	}
+	if opts.Replicas["api"] != 2 {
+		t.Errorf("expected component replicas 2, got %d", opts.Replicas["api"])
+	}
+	if s := opts.Scaling["api"]["worker"]; s.Replicas != 4 || s.Memory != "1Gi" {
+		t.Errorf("unexpected scaling: %+v", s)
+	}
+
+	// The newest instance's source becomes the component source
+	if got := opts.Components["web"]; got != "ghcr.io/org/web:v2" {
+		t.Errorf("expected newest instance source, got %q", got)
+	}
+	instances := opts.Instances["web"]
+	if len(instances) != 2 || instances[0].Name != "canary" || instances[1].Weight != 90 {
+		t.Errorf("unexpected instances: %+v", instances)
+	}
+	if opts.InstanceSources["web"]["stable"] != "ghcr.io/org/web:v1" {
+		t.Errorf("unexpected instance sources: %v", opts.InstanceSources["web"])
+	}
+	if opts.InstanceVariables["web"]["canary"]["beta"] != "true" {
+		t.Errorf("unexpected instance variables: %v", opts.InstanceVariables["web"])
+	}
+	if len(opts.Distinct["web"]) != 1 || opts.Distinct["web"][0] != "database.main" {
+		t.Errorf("unexpected distinct: %v", opts.Distinct["web"])
+	}
+}
+
+func TestApplyScalingOverrides(t *testing.T) {
+	g := graph.NewGraph("env", "dc")
+	api := graph.NewNode(graph.NodeTypeDeployment, "app", "api")
+	api.SetInput("replicas", 1)
+	worker := graph.NewNode(graph.NodeTypeDeployment, "app", "worker")
+	worker.SetInput("replicas", 1)
+	worker.SetInput("cpu", "100m")
+	_ = g.AddNode(api)
+	_ = g.AddNode(worker)
+
+	err := applyScalingOverrides(g,
+		map[string]int{"app": 3},
+		map[string]map[string]ScalingOverride{
+			"app": {"worker": {Replicas: 5, CPU: "500m", MinReplicas: 2, MaxReplicas: 10}},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if api.Inputs["replicas"] != 3 {
+		t.Errorf("expected component replicas on api, got %v", api.Inputs["replicas"])
+	}
+	if worker.Inputs["replicas"] != 5 || worker.Inputs["cpu"] != "500m" {
+		t.Errorf("expected per-deployment scaling to win, got %v", worker.Inputs)
+	}
+	if worker.Inputs["minReplicas"] != 2 || worker.Inputs["maxReplicas"] != 10 {
+		t.Errorf("expected autoscaling bounds, got %v", worker.Inputs)
+	}
+
+	err = applyScalingOverrides(g, nil, map[string]map[string]ScalingOverride{
+		"app": {"missing": {Replicas: 2}},
+	})
+	if err == nil {
+		t.Error("expected error for scaling an unknown deployment")
+	}
+}
+
+func TestResolveEnvironmentOutputs(t *testing.T) {
+	ctx := context.Background()
+	env, err := environment.NewLoader().LoadFromBytes([]byte(`
+components:
+  api:
+    image: ghcr.io/org/api:v1
+outputs:
+  api_url:
+    value: ${{ components.api.routes.main.url }}
+  health_url:
+    value: ${{ components.api.routes.main.url }}/healthz
+  replicas:
+    value: ${{ components.api.outputs.replicas }}
+  token:
+    value: ${{ components.api.outputs.token }}
+    sensitive: true
+`), "environment.yml")
+	if err != nil {
+		t.Fatalf("failed to load environment: %v", err)
+	}
+
+	mgr := newMockStateManager()
+	_ = mgr.SaveEnvironment(ctx, "dc", &types.EnvironmentState{
+		Name:       "preview",
+		Datacenter: "dc",
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Name:    "api",
+				Outputs: map[string]interface{}{"replicas": 2, "token": "s3cret"},
+				Resources: map[string]*types.ResourceState{
+					"route.main": {Type: "route", Name: "main", Outputs: map[string]interface{}{"url": "https://api.preview.example.com"}},
+				},
+			},
+		},
+	})
+	eng := NewEngine(mgr, nil)
+
+	outputs, err := eng.ResolveEnvironmentOutputs(ctx, "dc", "preview", env.Outputs())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if outputs["api_url"].Value != "https://api.preview.example.com" {
+		t.Errorf("unexpected api_url: %v", outputs["api_url"].Value)
+	}
+	if outputs["health_url"].Value != "https://api.preview.example.com/healthz" {
+		t.Errorf("unexpected health_url: %v", outputs["health_url"].Value)
+	}
+	if outputs["replicas"].Value != 2 {
+		t.Errorf("expected replicas to keep its type, got %#v", outputs["replicas"].Value)
+	}
+	if !outputs["token"].Sensitive {
+		t.Error("expected token to be sensitive")
+	}
+	if saved := mgr.environments["dc/preview"].Outputs; len(saved) != 4 {
+		t.Errorf("expected outputs to be saved to state, got %v", saved)
+	}
+
+	missing, err := environment.NewLoader().LoadFromBytes([]byte(`
+components:
+  api:
+    image: ghcr.io/org/api:v1
+outputs:
+  admin_url:
+    value: ${{ components.api.routes.admin.url }}
+`), "environment.yml")
+	if err != nil {
+		t.Fatalf("failed to load environment: %v", err)
+	}
+	_, err = eng.ResolveEnvironmentOutputs(ctx, "dc", "preview", missing.Outputs())
+	if err == nil || !strings.Contains(err.Error(), `outputs.admin_url: component "api" has no route "admin"`) {
+		t.Errorf("expected missing route error, got %v", err)
+	}
+}
+
+func TestRunBootstrap(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	env, err := environment.NewLoader().LoadFromBytes([]byte(`
+components:
+  api:
+    image: ghcr.io/org/api:v1
+bootstrap:
+  - name: create-admin
+    command: ["sh", "-c", "echo \"$API_URL\" >> ran.txt"]
+    environment:
+      API_URL: ${{ components.api.routes.main.url }}
+  - name: register-tenant
+    command: ["sh", "-c", "echo tenant >> ran.txt"]
+`), filepath.Join(dir, "environment.yml"))
+	if err != nil {
+		t.Fatalf("failed to load environment: %v", err)
+	}
+
+	mgr := newMockStateManager()
+	_ = mgr.SaveEnvironment(ctx, "dc", &types.EnvironmentState{
+		Name:       "preview",
+		Datacenter: "dc",
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Name: "api",
+				Resources: map[string]*types.ResourceState{
+					"route.main": {Type: "route", Name: "main", Outputs: map[string]interface{}{"url": "https://api.preview.example.com"}},
+				},
+			},
+		},
+	})
+	eng := NewEngine(mgr, nil)
+	opts := BootstrapOptions{Datacenter: "dc", Environment: "preview", Tasks: env.Bootstrap(), Dir: dir}
+
+	// Tasks run once; later deploys skip them unless asked to rerun them
+	for i := 0; i < 2; i++ {
+		if err := eng.RunBootstrap(ctx, opts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	ran, _ := os.ReadFile(filepath.Join(dir, "ran.txt"))
+	if string(ran) != "https://api.preview.example.com\ntenant\n" {
+		t.Errorf("expected each task to run once in order, got %q", ran)
+	}
+	if saved := mgr.environments["dc/preview"].Bootstrap; len(saved) != 2 || saved["create-admin"].CompletedAt.IsZero() {
+		t.Errorf("expected the tasks to be recorded in state, got %v", saved)
+	}
+
+	opts.Rerun = true
+	if err := eng.RunBootstrap(ctx, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ran, _ = os.ReadFile(filepath.Join(dir, "ran.txt"))
+	if strings.Count(string(ran), "tenant") != 2 {
+		t.Errorf("expected the tasks to run again, got %q", ran)
+	}
+}
+
+func TestRunBootstrap_Failure(t *testing.T) {
+	ctx := context.Background()
+	env, err := environment.NewLoader().LoadFromBytes([]byte(`
+bootstrap:
+  - name: seed
+    command: ["sh", "-c", "exit 3"]
+`), "environment.yml")
+	if err != nil {
+		t.Fatalf("failed to load environment: %v", err)
+	}
+
+	mgr := newMockStateManager()
+	_ = mgr.SaveEnvironment(ctx, "dc", &types.EnvironmentState{Name: "preview", Datacenter: "dc"})
+	eng := NewEngine(mgr, nil)
+
+	err = eng.RunBootstrap(ctx, BootstrapOptions{Datacenter: "dc", Environment: "preview", Tasks: env.Bootstrap()})
+	if err == nil || !strings.Contains(err.Error(), `bootstrap task "seed" failed: exit status 3`) {
+		t.Errorf("expected the task's failure, got %v", err)
+	}
+	if saved := mgr.environments["dc/preview"].Bootstrap; len(saved) != 0 {
+		t.Errorf("expected a failed task not to be recorded, got %v", saved)
+	}
+}
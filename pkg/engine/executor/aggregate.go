@@ -0,0 +1,384 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// aggregateBatch collects the resources of an aggregate hook that are being
+// deployed together.
+//
+// Aggregate hooks run their modules once for all of the resources they match
+// in an environment. The modules receive every matching resource in a "nodes"
+// input, and return outputs keyed by node ID, which are handed back to each
+// resource. In a parallel deploy, the members launched while a batch is open
+// wait for each other, and the last one to get to its hook runs the modules
+// for all of them. Resources that aren't part of the deploy are included with
+// the inputs they were last applied with, so the modules always see the whole
+// environment.
+type aggregateBatch struct {
+	key      string
+	hook     datacenter.Hook
+	expected int // Members launched that haven't joined or left yet
+	members  []*graph.Node
+	done     chan struct{}
+
+	// Set once the batch has run: module outputs of each member (node ID ->
+	// module name -> outputs)
+	outputs map[string]map[string]map[string]interface{}
+	err     error
+}
+
+// aggregates tracks the open aggregate batches of an execution.
+type aggregates struct {
+	mu      sync.Mutex
+	open    map[string]*aggregateBatch // Aggregate key -> batch accepting members
+	batchOf map[string]*aggregateBatch // Node ID -> batch the node is expected to join
+
+	// run serializes applies of aggregate modules, which share state
+	run sync.Mutex
+}
+
+// aggregateKey identifies the aggregate hook that handles resources of a
+// type, by the names of its modules, which stay the same as hooks are
+// reordered or added.
+func aggregateKey(nodeType graph.NodeType, hook datacenter.Hook) string {
+	names := make([]string, 0, len(hook.Modules()))
+	for _, m := range hook.Modules() {
+		names = append(names, m.Name())
+	}
+	return string(nodeType) + ":" + strings.Join(names, ",")
+}
+
+// expectAggregate registers a node that is about to be launched with the
+// open batch of its aggregate hook, if it is handled by one, and reports
+// whether it was. Members of a batch wait for each other, so they must not
+// hold a slot of the parallelism limit while they do.
+func (e *Executor) expectAggregate(node *graph.Node) bool {
+	if e.options.DryRun || e.options.Datacenter == nil {
+		return false
+	}
+	hook, err := e.matchHook(node)
+	if err != nil || hook == nil || !hook.Aggregate() {
+		return false
+	}
+	key := aggregateKey(node.Type, hook)
+
+	e.aggregates.mu.Lock()
+	defer e.aggregates.mu.Unlock()
+	if e.aggregates.open == nil {
+		e.aggregates.open = make(map[string]*aggregateBatch)
+		e.aggregates.batchOf = make(map[string]*aggregateBatch)
+	}
+	b := e.aggregates.open[key]
+	if b == nil {
+		b = &aggregateBatch{key: key, done: make(chan struct{})}
+		e.aggregates.open[key] = b
+	}
+	b.expected++
+	e.aggregates.batchOf[node.ID] = b
+	return true
+}
+
+// joinAggregate adds a resource to its batch and returns the outputs of the
+// hook's modules for it once the batch has run. A resource that wasn't
+// expected (e.g. in a sequential deploy) runs in a batch of its own, along
+// with the members recorded in state.
+func (e *Executor) joinAggregate(ctx context.Context, node *graph.Node, hook datacenter.Hook, logBuf io.Writer, onProgress func(string)) (map[string]map[string]interface{}, error) {
+	key := aggregateKey(node.Type, hook)
+
+	a := &e.aggregates
+	a.mu.Lock()
+	b := a.batchOf[node.ID]
+	if b != nil && b.key != key {
+		// The resolved inputs matched a different hook than expected
+		a.mu.Unlock()
+		e.leaveAggregate(ctx, node)
+		a.mu.Lock()
+		b = nil
+	}
+	if b == nil {
+		b = &aggregateBatch{key: key, expected: 1, done: make(chan struct{})}
+	}
+	delete(a.batchOf, node.ID)
+	b.hook = hook
+	b.members = append(b.members, node)
+	b.expected--
+	last := b.expected == 0
+	if last && a.open[key] == b {
+		delete(a.open, key)
+	}
+	a.mu.Unlock()
+
+	if last {
+		e.runAggregate(ctx, b, logBuf, onProgress)
+	} else {
+		if onProgress != nil {
+			onProgress("waiting for the other resources of its aggregate hook")
+		}
+		select {
+		case <-b.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.outputs[node.ID], nil
+}
+
+// leaveAggregate removes a node that finished without joining its batch,
+// e.g. because it failed first, and runs the batch for the remaining members
+// if they were only waiting on it. It is a no-op for nodes that joined.
+func (e *Executor) leaveAggregate(ctx context.Context, node *graph.Node) {
+	a := &e.aggregates
+	a.mu.Lock()
+	b := a.batchOf[node.ID]
+	if b == nil {
+		a.mu.Unlock()
+		return
+	}
+	delete(a.batchOf, node.ID)
+	b.expected--
+	last := b.expected == 0
+	if last && a.open[b.key] == b {
+		delete(a.open, b.key)
+	}
+	a.mu.Unlock()
+
+	if !last {
+		return
+	}
+	if len(b.members) == 0 {
+		close(b.done)
+		return
+	}
+	e.runAggregate(ctx, b, io.Discard, nil)
+}
+
+// runAggregate applies the hook's modules for a batch and wakes its members.
+func (e *Executor) runAggregate(ctx context.Context, b *aggregateBatch, logBuf io.Writer, onProgress func(string)) {
+	e.aggregates.run.Lock()
+	defer e.aggregates.run.Unlock()
+	b.outputs, b.err = e.applyAggregate(ctx, b.key, b.hook, b.members, logBuf, onProgress)
+	close(b.done)
+}
+
+// applyAggregate applies the modules of an aggregate hook for the given
+// resources and those recorded in state, and records the result in state.
+// It returns the module outputs of each resource, by node ID.
+func (e *Executor) applyAggregate(ctx context.Context, key string, hook datacenter.Hook, joined []*graph.Node, logBuf io.Writer, onProgress func(string)) (map[string]map[string]map[string]interface{}, error) {
+	envState := e.envState
+	envName := envState.Name
+
+	e.stateMu.Lock()
+	prior := envState.Aggregates[key]
+	members := make(map[string]*graph.Node)
+	var priorModules map[string]*types.ModuleState
+	if prior != nil {
+		priorModules = prior.ModuleStates
+		for id, m := range prior.Members {
+			members[id] = &graph.Node{
+				ID:        id,
+				Type:      graph.NodeType(m.Type),
+				Name:      m.Name,
+				Component: m.Component,
+				Inputs:    m.Inputs,
+			}
+		}
+	}
+	e.stateMu.Unlock()
+	for _, n := range joined {
+		members[n.ID] = n
+	}
+	ids := make([]string, 0, len(members))
+	for id := range members {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	// Hook-level expressions (module when conditions, credentials) are
+	// evaluated without a resource, since the modules run for all of them
+	hookNode := &graph.Node{Type: joined[0].Type}
+	dcDir := filepath.Dir(e.options.Datacenter.SourcePath())
+
+	outputs := make(map[string]map[string]map[string]interface{}, len(ids))
+	for _, id := range ids {
+		outputs[id] = make(map[string]map[string]interface{})
+	}
+	moduleStates := make(map[string]*types.ModuleState)
+
+	var applyErr error
+	for _, module := range hook.Modules() {
+		if when := module.When(); when != "" && !e.evaluateWhenCondition(when, hookNode) {
+			continue
+		}
+
+		modulePath := module.Build()
+		if modulePath == "" {
+			modulePath = module.Source()
+		}
+		if modulePath == "" {
+			applyErr = fmt.Errorf("module %s has no build or source path", module.Name())
+			break
+		}
+		if !filepath.IsAbs(modulePath) {
+			modulePath = filepath.Join(dcDir, modulePath)
+		}
+
+		// Each resource's entry is built from its own inputs, and from the
+		// outputs earlier modules returned for it
+		nodes := make([]interface{}, 0, len(ids))
+		for _, id := range ids {
+			n := members[id]
+			nodes = append(nodes, map[string]interface{}{
+				"id":        id,
+				"component": n.Component,
+				"name":      n.Name,
+				"inputs":    e.buildModuleInputsWithCrossRef(module, n, envName, outputs[id]),
+			})
+		}
+		inputs := map[string]interface{}{"nodes": nodes}
+
+		contract, err := iac.LoadModuleContract(modulePath)
+		if err != nil {
+			applyErr = fmt.Errorf("module %s: %w", module.Name(), err)
+			break
+		}
+		if err := contract.ValidateInputs(module.Name(), inputs); err != nil {
+			applyErr = err
+			break
+		}
+
+		var modOutputs map[string]interface{}
+		cacheKey := e.moduleCacheKey(module, modulePath, inputs, nil, hookNode, envName)
+		if cached := cachedModuleState(&types.ResourceState{ModuleStates: priorModules}, module.Name(), cacheKey); cached != nil {
+			if onProgress != nil {
+				onProgress(fmt.Sprintf("module %s unchanged, reusing cached outputs", module.Name()))
+			}
+			moduleStates[module.Name()] = cached
+			modOutputs = cached.Outputs
+		} else {
+			executorLog.Debug("executing aggregate module", "aggregate", key, "module", module.Name(), "resources", len(ids))
+			ms, err := e.applyHookModule(ctx, hook, module, modulePath, contract, inputs, hookNode, envName, logBuf, onProgress)
+			if ms != nil {
+				moduleStates[module.Name()] = ms
+			}
+			if err != nil {
+				applyErr = err
+				break
+			}
+			ms.CacheKey = cacheKey
+			modOutputs = ms.Outputs
+		}
+
+		for _, id := range ids {
+			outputs[id][module.Name()] = memberOutputs(modOutputs, id)
+		}
+	}
+
+	// Record the modules' state, keeping that of modules that didn't run so
+	// that their resources stay destroyable
+	e.stateMu.Lock()
+	if envState.Aggregates == nil {
+		envState.Aggregates = make(map[string]*types.AggregateState)
+	}
+	agg := envState.Aggregates[key]
+	if agg == nil {
+		agg = &types.AggregateState{}
+		envState.Aggregates[key] = agg
+	}
+	if applyErr != nil {
+		if agg.ModuleStates == nil {
+			agg.ModuleStates = make(map[string]*types.ModuleState)
+		}
+		for name, ms := range moduleStates {
+			agg.ModuleStates[name] = ms
+		}
+	} else {
+		agg.ModuleStates = moduleStates
+		agg.Members = make(map[string]*types.AggregateMember, len(ids))
+		for _, id := range ids {
+			n := members[id]
+			agg.Members[id] = &types.AggregateMember{
+				Component: n.Component,
+				Type:      string(n.Type),
+				Name:      n.Name,
+				Inputs:    n.Inputs,
+			}
+		}
+	}
+	agg.UpdatedAt = time.Now()
+	e.saveStateLocked(envState)
+	e.stateMu.Unlock()
+
+	if applyErr != nil {
+		return nil, fmt.Errorf("aggregate %s hook: %w", joined[0].Type, applyErr)
+	}
+	return outputs, nil
+}
+
+// memberOutputs picks a resource's outputs out of the outputs of an
+// aggregate module. Outputs that are maps holding a value for the node ID are
+// per-resource; any other output is shared by all resources.
+func memberOutputs(outputs map[string]interface{}, id string) map[string]interface{} {
+	result := make(map[string]interface{}, len(outputs))
+	for name, value := range outputs {
+		if byNode, ok := value.(map[string]interface{}); ok {
+			if v, ok := byNode[id]; ok {
+				result[name] = v
+				continue
+			}
+		}
+		result[name] = value
+	}
+	return result
+}
+
+// destroyAggregateMember removes a destroyed resource from its aggregate
+// hook. The hook's modules are destroyed along with its last resource;
+// otherwise they keep running for the remaining resources, and are applied
+// without the removed one on the next deploy.
+func (e *Executor) destroyAggregateMember(ctx context.Context, key string, node *graph.Node, envState *types.EnvironmentState) error {
+	e.stateMu.Lock()
+	agg := envState.Aggregates[key]
+	if agg == nil {
+		e.stateMu.Unlock()
+		return nil
+	}
+	delete(agg.Members, node.ID)
+	if len(agg.Members) > 0 {
+		e.saveStateLocked(envState)
+		e.stateMu.Unlock()
+		return nil
+	}
+	moduleStates := agg.ModuleStates
+	e.stateMu.Unlock()
+
+	var hook datacenter.Hook
+	if e.options.Datacenter != nil {
+		hook, _ = e.matchHook(node)
+	}
+	if err := e.destroyModuleStates(ctx, moduleStates, moduleSandbox(e.options.Datacenter, hook)); err != nil {
+		return err
+	}
+
+	e.stateMu.Lock()
+	delete(envState.Aggregates, key)
+	e.saveStateLocked(envState)
+	e.stateMu.Unlock()
+	return nil
+}
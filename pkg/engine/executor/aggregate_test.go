@@ -0,0 +1,196 @@
+package executor
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/iac"
+)
+
+const aggregateTestDatacenter = `
+environment {
+  deployment {
+    aggregate = true
+    module "proxy" {
+      plugin = "native"
+      build  = "./modules/proxy"
+      inputs = {
+        image = node.inputs.image
+      }
+    }
+    outputs = {
+      id      = module.proxy.id
+      address = module.proxy.address
+    }
+  }
+}
+`
+
+// aggregatePlugin records the resources each apply was given, and returns
+// an id per resource along with a shared address.
+type aggregatePlugin struct {
+	mu        sync.Mutex
+	applies   [][]string
+	destroyed int
+}
+
+func (p *aggregatePlugin) Name() string { return "native" }
+
+func (p *aggregatePlugin) Preview(ctx context.Context, opts iac.RunOptions) (*iac.PreviewResult, error) {
+	return &iac.PreviewResult{}, nil
+}
+
+func (p *aggregatePlugin) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var ids []string
+	byNode := make(map[string]interface{})
+	for _, n := range opts.Inputs["nodes"].([]interface{}) {
+		entry := n.(map[string]interface{})
+		id := entry["id"].(string)
+		ids = append(ids, id)
+		byNode[id] = "proxy-" + entry["inputs"].(map[string]interface{})["image"].(string)
+	}
+	p.applies = append(p.applies, ids)
+	return &iac.ApplyResult{
+		Outputs: map[string]iac.OutputValue{
+			"id":      {Value: byNode},
+			"address": {Value: "10.0.0.1"},
+		},
+		State: []byte("proxy-state"),
+	}, nil
+}
+
+func (p *aggregatePlugin) Destroy(ctx context.Context, opts iac.RunOptions) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.destroyed++
+	return nil
+}
+
+func (p *aggregatePlugin) Refresh(ctx context.Context, opts iac.RunOptions) (*iac.RefreshResult, error) {
+	return &iac.RefreshResult{}, nil
+}
+
+func (p *aggregatePlugin) Import(ctx context.Context, opts iac.ImportOptions) (*iac.ImportResult, error) {
+	return &iac.ImportResult{}, nil
+}
+
+func TestExecuteParallel_AggregateHook(t *testing.T) {
+	plugin := &aggregatePlugin{}
+	registry := iac.NewRegistry()
+	registry.Register("native", func() (iac.Plugin, error) { return plugin, nil })
+
+	sm := newMockStateManager()
+	opts := DefaultOptions()
+	opts.Datacenter = loadHCLDatacenter(t, aggregateTestDatacenter)
+	// Fewer slots than members: waiting members must not hold them
+	opts.Parallelism = 1
+
+	g := graph.NewGraph("test", "dc")
+	plan := &planner.Plan{Environment: "test", Datacenter: "dc", ToCreate: 3}
+	var nodes []*graph.Node
+	for _, name := range []string{"api", "web", "worker"} {
+		node := graph.NewNode(graph.NodeTypeDeployment, name, "main")
+		node.SetInput("image", name)
+		_ = g.AddNode(node)
+		nodes = append(nodes, node)
+		plan.Changes = append(plan.Changes, &planner.ResourceChange{Node: node, Action: planner.ActionCreate})
+	}
+
+	result, err := NewExecutor(sm, registry, opts).ExecuteParallel(context.Background(), plan, g)
+	if err != nil {
+		t.Fatalf("ExecuteParallel failed: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("expected success, got %v", result.Errors)
+	}
+
+	if len(plugin.applies) != 1 || len(plugin.applies[0]) != 3 {
+		t.Fatalf("expected one apply with all 3 deployments, got %v", plugin.applies)
+	}
+	for _, node := range nodes {
+		outputs := result.NodeResults[node.ID].Outputs
+		if want := "proxy-" + node.Component; outputs["id"] != want {
+			t.Errorf("%s: id = %v, want %s", node.ID, outputs["id"], want)
+		}
+		if outputs["address"] != "10.0.0.1" {
+			t.Errorf("%s: expected the shared address, got %v", node.ID, outputs["address"])
+		}
+	}
+
+	env := sm.environments["test"]
+	key := "deployment:proxy"
+	if agg := env.Aggregates[key]; agg == nil || len(agg.Members) != 3 || agg.ModuleStates["proxy"] == nil {
+		t.Fatalf("expected aggregate state with 3 members, got %+v", env.Aggregates)
+	}
+	if rs := env.Components["api"].Resources["deployment.main"]; rs.Aggregate != key || len(rs.ModuleStates) != 0 || len(rs.IaCState) != 0 {
+		t.Errorf("expected the resource to refer to its aggregate, got %+v", rs)
+	}
+
+	// Updating one deployment re-applies the modules with all of them
+	nodes[0].SetInput("image", "api-v2")
+	update := &planner.Plan{Environment: "test", Datacenter: "dc", ToUpdate: 1, Changes: []*planner.ResourceChange{
+		{Node: nodes[0], Action: planner.ActionUpdate},
+	}}
+	result, err = NewExecutor(sm, registry, opts).Execute(context.Background(), update, g)
+	if err != nil || !result.Success {
+		t.Fatalf("Execute failed: %v %v", err, result.Errors)
+	}
+	if len(plugin.applies) != 2 || len(plugin.applies[1]) != 3 {
+		t.Fatalf("expected the update to apply all 3 deployments, got %v", plugin.applies)
+	}
+	if got := result.NodeResults[nodes[0].ID].Outputs["id"]; got != "proxy-api-v2" {
+		t.Errorf("id = %v, want proxy-api-v2", got)
+	}
+
+	// The modules are only destroyed with the last deployment
+	destroy := func(node *graph.Node) {
+		t.Helper()
+		current := env.Components[node.Component].Resources[resourceKey(node)]
+		plan := &planner.Plan{Environment: "test", Datacenter: "dc", ToDelete: 1, Changes: []*planner.ResourceChange{
+			{Node: node, Action: planner.ActionDelete, CurrentState: current},
+		}}
+		result, err := NewExecutor(sm, registry, DefaultOptions()).Execute(context.Background(), plan, g)
+		if err != nil || !result.Success {
+			t.Fatalf("destroy of %s failed: %v %v", node.ID, err, result.Errors)
+		}
+	}
+	destroy(nodes[0])
+	destroy(nodes[1])
+	if plugin.destroyed != 0 {
+		t.Fatalf("expected the modules to stay while deployments remain, got %d destroys", plugin.destroyed)
+	}
+	if len(env.Aggregates[key].Members) != 1 {
+		t.Errorf("expected 1 remaining member, got %v", env.Aggregates[key].Members)
+	}
+	destroy(nodes[2])
+	if plugin.destroyed != 1 {
+		t.Errorf("expected the modules to be destroyed with the last deployment, got %d destroys", plugin.destroyed)
+	}
+	if _, ok := env.Aggregates[key]; ok {
+		t.Error("expected the aggregate to be removed from state")
+	}
+}
+
+func TestMemberOutputs(t *testing.T) {
+	outputs := map[string]interface{}{
+		"url":     map[string]interface{}{"api/route/main": "http://api.local", "web/route/main": "http://web.local"},
+		"address": "10.0.0.1",
+		"config":  map[string]interface{}{"workers": 4},
+	}
+	got := memberOutputs(outputs, "api/route/main")
+	if got["url"] != "http://api.local" {
+		t.Errorf("url = %v, want the resource's own value", got["url"])
+	}
+	if got["address"] != "10.0.0.1" {
+		t.Errorf("address = %v, want the shared value", got["address"])
+	}
+	if cfg, ok := got["config"].(map[string]interface{}); !ok || cfg["workers"] != 4 {
+		t.Errorf("config = %v, want maps without the node ID to be shared", got["config"])
+	}
+}
@@ -0,0 +1,71 @@
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// moduleCacheKey returns the key identifying the result of a cacheable
+// module, or "" when the module isn't cacheable or its key can't be resolved.
+// A module with a cache_key expression is keyed on the expression's value;
+// one without is keyed on its inputs. Either way the module source is part
+// of the key, so pointing a hook at a different module never reuses outputs.
+func (e *Executor) moduleCacheKey(module datacenter.Module, modulePath string, inputs map[string]interface{}, moduleOutputs map[string]map[string]interface{}, node *graph.Node, envName string) string {
+	if !module.Cacheable() {
+		return ""
+	}
+
+	var value interface{} = inputs
+	if expr := module.CacheKey(); expr != "" {
+		dcVars := e.options.DatacenterVariables
+		if dcVars == nil {
+			dcVars = make(map[string]interface{})
+		}
+		resolved := e.resolveCrossModuleRefs(expr, moduleOutputs, node, envName, dcVars)
+		value = e.evaluateInputExpression(resolved, node, envName, dcVars)
+		if value == nil {
+			return ""
+		}
+		if s, ok := value.(string); ok && (s == "" || strings.Contains(s, "${") || strings.HasPrefix(s, "module.")) {
+			return ""
+		}
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s", modulePath, data)))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// cachedModuleState returns the module state a previous apply recorded under
+// cacheKey, or nil when there is no reusable result.
+func cachedModuleState(prior *types.ResourceState, moduleName, cacheKey string) *types.ModuleState {
+	if prior == nil || cacheKey == "" {
+		return nil
+	}
+	ms := prior.ModuleStates[moduleName]
+	if ms == nil || ms.CacheKey != cacheKey || ms.Status != types.ModuleStatusReady {
+		return nil
+	}
+	cached := *ms
+	return &cached
+}
+
+// hasCacheKey reports whether any of the module states is cached.
+func hasCacheKey(moduleStates map[string]*types.ModuleState) bool {
+	for _, ms := range moduleStates {
+		if ms.CacheKey != "" {
+			return true
+		}
+	}
+	return false
+}
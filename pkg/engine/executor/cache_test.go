@@ -0,0 +1,109 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+const cacheTestDatacenter = `
+environment {
+  deployment {
+    module "lookup" {
+      plugin    = "lookup"
+      build     = "./modules/lookup"
+      cacheable = true
+      cache_key = node.inputs.image
+      inputs = {
+        image = node.inputs.image
+      }
+    }
+    module "container" {
+      plugin = "native"
+      build  = "./modules/container"
+      inputs = {
+        digest = module.lookup.digest
+      }
+    }
+    outputs = {
+      id = module.container.id
+    }
+  }
+}
+`
+
+func TestExecuteHookModules_Cacheable(t *testing.T) {
+	lookup := &mockPlugin{name: "lookup", outputs: map[string]iac.OutputValue{
+		"digest": {Value: "sha256:abc"},
+	}}
+	container := &mockPlugin{name: "native", outputs: map[string]iac.OutputValue{
+		"id": {Value: "api-main"},
+	}}
+	registry := iac.NewRegistry()
+	registry.Register("lookup", func() (iac.Plugin, error) { return lookup, nil })
+	registry.Register("native", func() (iac.Plugin, error) { return container, nil })
+
+	opts := DefaultOptions()
+	opts.Datacenter = loadHCLDatacenter(t, cacheTestDatacenter)
+	exec := NewExecutor(newMockStateManager(), registry, opts)
+
+	node := graph.NewNode(graph.NodeTypeDeployment, "api", "main")
+	node.SetInput("image", "api:v1")
+
+	apply := func(prior *types.ResourceState) *types.ResourceState {
+		t.Helper()
+		lookup.applied, container.applied = false, false
+		result, err := exec.executeHookModules(context.Background(), node, "test", nil, prior, io.Discard, nil)
+		if err != nil {
+			t.Fatalf("executeHookModules failed: %v", err)
+		}
+		rs := &types.ResourceState{Status: types.ResourceStatusReady, Outputs: result.Outputs}
+		setModuleStates(rs, result.ModuleStates)
+		return rs
+	}
+
+	first := apply(nil)
+	if !lookup.applied || !container.applied {
+		t.Fatal("expected both modules to apply on the first run")
+	}
+	if first.ModuleStates["lookup"].CacheKey == "" {
+		t.Fatal("expected cacheable module to record a cache key")
+	}
+	if first.ModuleStates["container"].CacheKey != "" {
+		t.Error("expected non-cacheable module to record no cache key")
+	}
+
+	// Unchanged key: the lookup is skipped and its outputs still feed the
+	// container module
+	second := apply(first)
+	if lookup.applied {
+		t.Error("expected cached module to be skipped")
+	}
+	if !container.applied {
+		t.Error("expected non-cacheable module to apply")
+	}
+	if got := second.ModuleStates["container"].Inputs["digest"]; got != "sha256:abc" {
+		t.Errorf("expected cached digest to be passed on, got %v", got)
+	}
+
+	// A changed key applies the module again
+	node.SetInput("image", "api:v2")
+	apply(second)
+	if !lookup.applied {
+		t.Error("expected module to apply after its cache key changed")
+	}
+}
+
+func TestSetModuleStates_SingleCachedModule(t *testing.T) {
+	rs := &types.ResourceState{}
+	setModuleStates(rs, map[string]*types.ModuleState{
+		"build": {Name: "build", IaCState: []byte("state"), CacheKey: "sha256:abc"},
+	})
+	if rs.ModuleStates["build"] == nil {
+		t.Error("expected a cached single module to keep its per-module state")
+	}
+}
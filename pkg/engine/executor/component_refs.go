@@ -0,0 +1,92 @@
+package executor
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// componentOutputRefPattern matches ${{ components.<name>.outputs.<key> }},
+// which environment files use to pass one component's outputs into another
+// component's variables. Component names may contain slashes (repo paths).
+var componentOutputRefPattern = regexp.MustCompile(`\$\{\{\s*components\.([^\s.}]+)\.outputs\.([^\s.}]+)\s*\}\}`)
+
+// ReferencedComponents returns the sorted, de-duplicated names of the
+// components whose outputs are referenced by the given variable values.
+func ReferencedComponents(vars map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, v := range vars {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		for _, m := range componentOutputRefPattern.FindAllStringSubmatch(s, -1) {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				names = append(names, m[1])
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// componentVariables returns a component's deployment variables with
+// references to other components' outputs resolved. The engine orders
+// components so that referenced components deployed in the same run finish
+// first; components deployed earlier are read from environment state.
+func (e *Executor) componentVariables(compName string) map[string]interface{} {
+	vars := e.options.ComponentVariables[compName]
+	if len(vars) == 0 {
+		return map[string]interface{}{}
+	}
+
+	resolved := make(map[string]interface{}, len(vars))
+	for k, v := range vars {
+		s, ok := v.(string)
+		if !ok || !strings.Contains(s, "${{") {
+			resolved[k] = v
+			continue
+		}
+
+		// A value that is exactly one reference keeps the output's type
+		if m := componentOutputRefPattern.FindStringSubmatch(s); m != nil && m[0] == strings.TrimSpace(s) {
+			if out, ok := e.componentOutput(m[1], m[2]); ok {
+				resolved[k] = out
+				continue
+			}
+		}
+
+		resolved[k] = componentOutputRefPattern.ReplaceAllStringFunc(s, func(match string) string {
+			m := componentOutputRefPattern.FindStringSubmatch(match)
+			if out, ok := e.componentOutput(m[1], m[2]); ok {
+				return fmt.Sprintf("%v", out)
+			}
+			return ""
+		})
+	}
+	return resolved
+}
+
+// componentOutput looks up a declared output of another component: first by
+// evaluating its output expression against this run's graph, then from the
+// outputs recorded in environment state by a previous deploy.
+func (e *Executor) componentOutput(compName, key string) (interface{}, bool) {
+	if e.graph != nil {
+		if exprs, ok := e.graph.ComponentOutputExprs[compName]; ok {
+			if _, ok := exprs[key]; ok {
+				out, ok := e.resolveComponentOutputs(compName, exprs)[key]
+				return out, ok
+			}
+		}
+	}
+	if e.envState != nil {
+		if comp, ok := e.envState.Components[compName]; ok && comp.Outputs != nil {
+			out, ok := comp.Outputs[key]
+			return out, ok
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,62 @@
+package executor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func TestReferencedComponents(t *testing.T) {
+	got := ReferencedComponents(map[string]interface{}{
+		"issuer":   "${{ components.auth.outputs.issuer }}",
+		"audience": "https://${{ components.auth.outputs.domain }}/${{ components.org/api.outputs.path }}",
+		"replicas": 3,
+		"plain":    "${{ variables.region }}",
+	})
+	want := []string{"auth", "org/api"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestComponentVariables_ResolvesComponentOutputs(t *testing.T) {
+	exec := NewExecutor(newMockStateManager(), newTestRegistry(), Options{
+		ComponentVariables: map[string]map[string]interface{}{
+			"auth": {"issuer": "https://auth.example.com"},
+			"api": {
+				"issuer":   "${{ components.auth.outputs.issuer }}",
+				"callback": "${{ components.auth.outputs.issuer }}/callback",
+				"billing":  "${{ components.billing.outputs.url }}",
+				"missing":  "${{ components.auth.outputs.nope }}",
+				"replicas": 2,
+			},
+		},
+	})
+
+	g := graph.NewGraph("env", "dc")
+	g.ComponentOutputExprs = map[string]map[string]string{
+		"auth": {"issuer": "${{ variables.issuer }}"},
+	}
+	exec.graph = g
+
+	// billing was deployed by a previous run
+	exec.envState = &types.EnvironmentState{
+		Components: map[string]*types.ComponentState{
+			"billing": {Outputs: map[string]interface{}{"url": "https://billing.internal"}},
+		},
+	}
+
+	vars := exec.componentVariables("api")
+	want := map[string]interface{}{
+		"issuer":   "https://auth.example.com",
+		"callback": "https://auth.example.com/callback",
+		"billing":  "https://billing.internal",
+		"missing":  "",
+		"replicas": 2,
+	}
+	if !reflect.DeepEqual(vars, want) {
+		t.Errorf("expected %v, got %v", want, vars)
+	}
+}
@@ -0,0 +1,171 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// credentialSetPrefix prefixes the process environment variables that make
+// up a named credential set: CLDCTL_CREDENTIALS_<SET>_<VAR> is passed to the
+// module as <VAR>.
+const credentialSetPrefix = "CLDCTL_CREDENTIALS_"
+
+// AWSCredentials are temporary AWS credentials obtained by assuming a role.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// RoleAssumer exchanges the CLI's own AWS credentials for credentials of
+// roleARN.
+type RoleAssumer func(ctx context.Context, roleARN, sessionName string) (*AWSCredentials, error)
+
+// assumeAWSRole assumes roleARN with STS using the default credential chain.
+func assumeAWSRole(ctx context.Context, roleARN, sessionName string) (*AWSCredentials, error) {
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	out, err := sts.NewFromConfig(awsCfg).AssumeRole(ctx, &sts.AssumeRoleInput{
+		RoleArn:         aws.String(roleARN),
+		RoleSessionName: aws.String(sessionName),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out.Credentials == nil {
+		return nil, fmt.Errorf("STS returned no credentials")
+	}
+	return &AWSCredentials{
+		AccessKeyID:     aws.ToString(out.Credentials.AccessKeyId),
+		SecretAccessKey: aws.ToString(out.Credentials.SecretAccessKey),
+		SessionToken:    aws.ToString(out.Credentials.SessionToken),
+	}, nil
+}
+
+// resolveModuleCredentials merges a module's credentials over its hook's,
+// field by field, and resolves variable references. It returns nil when
+// neither configures credentials, so the module runs with the CLI's own.
+func (e *Executor) resolveModuleCredentials(hook datacenter.Hook, module datacenter.Module, node *graph.Node, envName string) (*types.ModuleCredentials, error) {
+	var layers []datacenter.Credentials
+	if c := hook.Credentials(); c != nil {
+		layers = append(layers, c)
+	}
+	if c := module.Credentials(); c != nil {
+		layers = append(layers, c)
+	}
+	if len(layers) == 0 {
+		return nil, nil
+	}
+
+	var roleARN, serviceAccount, envSet string
+	for _, c := range layers {
+		if c.RoleARN() != "" {
+			roleARN = c.RoleARN()
+		}
+		if c.ServiceAccount() != "" {
+			serviceAccount = c.ServiceAccount()
+		}
+		if c.EnvSet() != "" {
+			envSet = c.EnvSet()
+		}
+	}
+
+	resolve := func(field, expr string) (string, error) {
+		if expr == "" {
+			return "", nil
+		}
+		val := e.evaluateInputExpression(expr, node, envName, e.options.DatacenterVariables)
+		s, _ := val.(string)
+		if s == "" || strings.Contains(s, "${") {
+			return "", fmt.Errorf("credentials %s %q did not resolve to a value", field, expr)
+		}
+		return s, nil
+	}
+
+	creds := &types.ModuleCredentials{}
+	var err error
+	if creds.RoleARN, err = resolve("role_arn", roleARN); err != nil {
+		return nil, err
+	}
+	if creds.ServiceAccount, err = resolve("service_account", serviceAccount); err != nil {
+		return nil, err
+	}
+	if creds.EnvSet, err = resolve("env_set", envSet); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// credentialEnvironment returns the environment variables that scope a
+// module's cloud credentials.
+func (e *Executor) credentialEnvironment(ctx context.Context, creds *types.ModuleCredentials, sessionName string) (map[string]string, error) {
+	return CredentialEnvironment(ctx, creds, sessionName, e.options.AssumeRole)
+}
+
+// CredentialEnvironment returns the environment variables that scope a
+// module's cloud credentials. They are layered over the CLI's environment
+// by the IaC plugin, so they take precedence over its own credentials.
+// A nil assume assumes roles with AWS STS.
+func CredentialEnvironment(ctx context.Context, creds *types.ModuleCredentials, sessionName string, assume RoleAssumer) (map[string]string, error) {
+	env := map[string]string{}
+	if creds == nil {
+		return env, nil
+	}
+
+	if creds.EnvSet != "" {
+		setPrefix := credentialSetPrefix + strings.ToUpper(strings.ReplaceAll(creds.EnvSet, "-", "_")) + "_"
+		for _, kv := range os.Environ() {
+			key, value, ok := strings.Cut(kv, "=")
+			if !ok || !strings.HasPrefix(key, setPrefix) || len(key) == len(setPrefix) {
+				continue
+			}
+			env[key[len(setPrefix):]] = value
+		}
+		if len(env) == 0 {
+			return nil, fmt.Errorf("credential set %q is empty: set %s<VAR> environment variables", creds.EnvSet, setPrefix)
+		}
+	}
+
+	if creds.ServiceAccount != "" {
+		env["GOOGLE_IMPERSONATE_SERVICE_ACCOUNT"] = creds.ServiceAccount
+		env["CLOUDSDK_AUTH_IMPERSONATE_SERVICE_ACCOUNT"] = creds.ServiceAccount
+	}
+
+	if creds.RoleARN != "" {
+		if assume == nil {
+			assume = assumeAWSRole
+		}
+		awsCreds, err := assume(ctx, creds.RoleARN, sessionName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to assume role %s: %w", creds.RoleARN, err)
+		}
+		env["AWS_ACCESS_KEY_ID"] = awsCreds.AccessKeyID
+		env["AWS_SECRET_ACCESS_KEY"] = awsCreds.SecretAccessKey
+		env["AWS_SESSION_TOKEN"] = awsCreds.SessionToken
+	}
+
+	return env, nil
+}
+
+// CredentialSessionName names the STS session a module runs in so CloudTrail
+// entries can be traced back to the module. STS limits names to 64
+// characters of [\w+=,.@-].
+func CredentialSessionName(moduleName string) string {
+	name := "cldctl-" + sanitizeResourceName(moduleName)
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
@@ -0,0 +1,121 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+const credentialsTestDatacenter = `
+environment {
+  database {
+    credentials {
+      role_arn = variable.database_role_arn
+      env_set  = "aws-prod"
+    }
+
+    module "postgres" {
+      build = "./modules/postgres"
+
+      credentials {
+        role_arn = "arn:aws:iam::123456789012:role/rds-admin"
+      }
+    }
+
+    module "dns" {
+      build = "./modules/dns"
+    }
+
+    outputs = {
+      host = module.postgres.host
+      port = module.postgres.port
+      url  = module.postgres.url
+    }
+  }
+}
+`
+
+func TestResolveModuleCredentials(t *testing.T) {
+	dc, err := datacenter.NewLoader().LoadFromBytes([]byte(credentialsTestDatacenter), "datacenter.dc")
+	if err != nil {
+		t.Fatalf("failed to load datacenter: %v", err)
+	}
+	exec := &Executor{options: Options{
+		Datacenter:          dc,
+		DatacenterVariables: map[string]interface{}{"database_role_arn": "arn:aws:iam::123456789012:role/db"},
+	}}
+	hook := dc.Environment().Hooks().Database()[0]
+	node := graph.NewNode(graph.NodeTypeDatabase, "my-app", "main")
+
+	// Module fields override the hook's; unset fields are inherited
+	creds, err := exec.resolveModuleCredentials(hook, hook.Modules()[0], node, "staging")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds.RoleARN != "arn:aws:iam::123456789012:role/rds-admin" || creds.EnvSet != "aws-prod" {
+		t.Errorf("expected module role and hook env set, got %+v", creds)
+	}
+
+	creds, err = exec.resolveModuleCredentials(hook, hook.Modules()[1], node, "staging")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds.RoleARN != "arn:aws:iam::123456789012:role/db" {
+		t.Errorf("expected hook role from variable, got %+v", creds)
+	}
+
+	exec.options.DatacenterVariables = nil
+	if _, err := exec.resolveModuleCredentials(hook, hook.Modules()[1], node, "staging"); err == nil {
+		t.Error("expected error for an unresolved role_arn variable")
+	}
+}
+
+func TestCredentialEnvironment(t *testing.T) {
+	t.Setenv("CLDCTL_CREDENTIALS_AWS_PROD_AWS_REGION", "eu-west-1")
+	t.Setenv("CLDCTL_CREDENTIALS_AWS_PROD_AWS_ACCESS_KEY_ID", "AKIABASE")
+
+	var assumed string
+	exec := &Executor{options: Options{
+		AssumeRole: func(ctx context.Context, roleARN, sessionName string) (*AWSCredentials, error) {
+			assumed = roleARN + " " + sessionName
+			return &AWSCredentials{AccessKeyID: "AKIAROLE", SecretAccessKey: "secret", SessionToken: "token"}, nil
+		},
+	}}
+
+	env, err := exec.credentialEnvironment(context.Background(), &types.ModuleCredentials{
+		RoleARN:        "arn:aws:iam::123456789012:role/db",
+		ServiceAccount: "deployer@project.iam.gserviceaccount.com",
+		EnvSet:         "aws-prod",
+	}, CredentialSessionName("postgres"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if assumed != "arn:aws:iam::123456789012:role/db cldctl-postgres" {
+		t.Errorf("expected role to be assumed for the module session, got %q", assumed)
+	}
+	for key, want := range map[string]string{
+		"AWS_REGION":                         "eu-west-1",
+		"AWS_ACCESS_KEY_ID":                  "AKIAROLE", // the assumed role wins over the env set
+		"AWS_SECRET_ACCESS_KEY":              "secret",
+		"AWS_SESSION_TOKEN":                  "token",
+		"GOOGLE_IMPERSONATE_SERVICE_ACCOUNT": "deployer@project.iam.gserviceaccount.com",
+	} {
+		if env[key] != want {
+			t.Errorf("expected %s=%q, got %q", key, want, env[key])
+		}
+	}
+
+	if env, err := exec.credentialEnvironment(context.Background(), nil, "cldctl-postgres"); err != nil || len(env) != 0 {
+		t.Errorf("expected no environment without credentials, got %v, %v", env, err)
+	}
+
+	_, err = exec.credentialEnvironment(context.Background(), &types.ModuleCredentials{EnvSet: "missing"}, "cldctl-postgres")
+	if err == nil || !strings.Contains(err.Error(), "CLDCTL_CREDENTIALS_MISSING_") {
+		t.Errorf("expected error naming the empty credential set, got %v", err)
+	}
+}
@@ -4,9 +4,10 @@ package executor
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"os"
+	"log/slog"
 	"path/filepath"
 	"regexp"
 	"sort"
@@ -15,11 +16,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/davidthor/cldctl/pkg/engine/expression"
 	"github.com/davidthor/cldctl/pkg/engine/planner"
 	arcerrors "github.com/davidthor/cldctl/pkg/errors"
 	"github.com/davidthor/cldctl/pkg/graph"
 	"github.com/davidthor/cldctl/pkg/iac"
-	"github.com/davidthor/cldctl/pkg/names"
+	"github.com/davidthor/cldctl/pkg/logging"
 	"github.com/davidthor/cldctl/pkg/schema/datacenter"
 	v1 "github.com/davidthor/cldctl/pkg/schema/datacenter/v1"
 	"github.com/davidthor/cldctl/pkg/state"
@@ -28,6 +30,12 @@ import (
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 )
 
+// Loggers for the executor's debug output.
+var (
+	executorLog    = logging.Logger(logging.Executor)
+	expressionsLog = logging.Logger(logging.Expressions)
+)
+
 // ExecutionResult contains the results of an execution.
 type ExecutionResult struct {
 	Success     bool
@@ -38,6 +46,10 @@ type ExecutionResult struct {
 	Failed      int
 	Errors      []error
 	NodeResults map[string]*NodeResult
+
+	// Warnings are conditions that didn't fail the execution but degraded
+	// it, such as expressions that resolved to empty strings
+	Warnings []planner.Warning
 }
 
 // NodeResult contains the result of executing a single node.
@@ -48,6 +60,10 @@ type NodeResult struct {
 	Duration time.Duration
 	Error    error
 	Outputs  map[string]interface{}
+
+	// RootCause is the node ID of the failed resource that kept this node
+	// from being applied, for nodes skipped because a dependency failed
+	RootCause string
 }
 
 // ProgressEvent represents a progress update during execution.
@@ -56,11 +72,22 @@ type ProgressEvent struct {
 	NodeName string
 	NodeType string
 	Status   string // "pending", "running", "completed", "failed", "skipped"
-	Message  string
-	Error    error
+	// Phase is the stage a running node's plugin last reported (e.g.
+	// pulling, waiting-healthy); empty if it hasn't reported one.
+	Phase   iac.Phase
+	Message string
+	Error   error
 	// Logs contains captured stdout/stderr output from the resource execution.
-	// Populated on failure for error diagnostics.
+	// Populated on failure for error diagnostics. Only the most recent output
+	// is included; see LogFile for the full log.
 	Logs string
+	// LogFile is the path of a file holding the full output, set when the
+	// output was written to disk (LogDir is set or the output was too large
+	// to keep in memory).
+	LogFile string
+	// Remediation is the guidance a failed node's error carries, such as the
+	// code, docs link, and alternatives of the error hook that rejected it.
+	Remediation *arcerrors.Remediation
 }
 
 // ProgressCallback is called when resource status changes.
@@ -71,6 +98,10 @@ type Options struct {
 	// Parallelism is the max number of concurrent operations
 	Parallelism int
 
+	// BuildParallelism is the max number of docker builds run at once. Builds
+	// also count toward Parallelism. Defaults to DefaultBuildParallelism.
+	BuildParallelism int
+
 	// Output writer for progress
 	Output io.Writer
 
@@ -83,6 +114,10 @@ type Options struct {
 	// OnProgress is called when resource status changes
 	OnProgress ProgressCallback
 
+	// SlowNodes configures the notifications sent while a node takes a long
+	// time to apply.
+	SlowNodes SlowNodeOptions
+
 	// Datacenter is the parsed datacenter configuration (required for hook execution)
 	Datacenter datacenter.Datacenter
 
@@ -106,19 +141,63 @@ type Options struct {
 	// Environment-level route overrides (subdomain, pathPrefix) are injected
 	// into route node inputs by buildModuleInputs.
 	ComponentRoutes map[string]map[string]RouteOverride
+
+	// InstanceSources maps component name to instance name to the source the
+	// instance was deployed from. Recorded in InstanceState.Source.
+	InstanceSources map[string]map[string]string
+
+	// InstanceVariables maps component name to instance name to variable
+	// overrides. They are layered over ComponentVariables when resolving
+	// expressions for that instance's resources.
+	InstanceVariables map[string]map[string]map[string]interface{}
+
+	// LogDir, when set, is a directory that each node's plugin output is
+	// streamed to as <node-id>.log. Otherwise output is kept in memory and
+	// spilled to a temporary file only when it grows large.
+	LogDir string
+
+	// AssumeRole obtains credentials for modules whose datacenter hook sets
+	// credentials.role_arn. Defaults to assuming the role with AWS STS.
+	AssumeRole RoleAssumer
+
+	// EnvironmentModules maps each provisioned environment-scoped module to
+	// its outputs. Hooks read them as environment.module.<name>.<output>.
+	EnvironmentModules map[string]map[string]interface{}
+
+	// EnvironmentLabels are the environment's labels. Hooks read them as
+	// environment.labels.<key>.
+	EnvironmentLabels map[string]string
+
+	// ComponentLabels maps component name to the labels recorded in its
+	// ComponentState.
+	ComponentLabels map[string]map[string]string
 }
 
 // RouteOverride holds environment-level overrides for a single route.
 type RouteOverride struct {
 	Subdomain  string
 	PathPrefix string
+
+	// Hostnames are additional host and path combinations the route is
+	// published at. Subdomains and hosts may start with a "*." wildcard.
+	Hostnames []RouteHostname
+}
+
+// RouteHostname is one host and path combination a route matches. Exactly
+// one of Subdomain and Host is set. An empty PathPrefix inherits the
+// route's path prefix.
+type RouteHostname struct {
+	Subdomain  string
+	Host       string
+	PathPrefix string
 }
 
 // DefaultOptions returns default executor options.
 func DefaultOptions() Options {
 	return Options{
-		Parallelism: 10,
-		StopOnError: true,
+		Parallelism:      10,
+		BuildParallelism: DefaultBuildParallelism,
+		StopOnError:      true,
 	}
 }
 
@@ -130,6 +209,27 @@ type Executor struct {
 	graph          *graph.Graph // Store reference to graph for service port lookups
 	stateMu        sync.Mutex   // Protects concurrent access to environment state
 	datacenterName string       // Set at execution start for incremental state saves
+
+	// envState is the environment being executed. Set at execution start so
+	// port allocation can consult and update the environment's port registry.
+	envState *types.EnvironmentState
+
+	// publishedRoutes is a snapshot of the route addresses recorded in state
+	// when execution started (component name -> route name -> address).
+	publishedRoutes map[string]map[string]types.RouteState
+
+	// phases holds the phase each running node is in (node ID -> iac.Phase).
+	phases sync.Map
+
+	// aggregates batches the resources of aggregate hooks being deployed.
+	aggregates aggregates
+
+	// sharedLocks holds a mutex per shared hook key, serializing uses of
+	// each hook (see lockShared).
+	sharedLocks sync.Map
+
+	// warnings collects the execution's warnings (see warn).
+	warnings warnings
 }
 
 // saveStateLocked flushes the in-memory environment state to the backend so that
@@ -138,7 +238,15 @@ type Executor struct {
 // saves complete even when the deployment context has been cancelled.
 func (e *Executor) saveStateLocked(envState *types.EnvironmentState) {
 	saveCtx := context.Background()
-	_ = e.stateManager.SaveEnvironment(saveCtx, e.datacenterName, envState)
+	_ = e.saveState(saveCtx, envState)
+}
+
+// saveState persists the environment state. Dry runs never write state.
+func (e *Executor) saveState(ctx context.Context, envState *types.EnvironmentState) error {
+	if e.options.DryRun {
+		return nil
+	}
+	return e.stateManager.SaveEnvironment(ctx, e.datacenterName, envState)
 }
 
 // NewExecutor creates a new executor.
@@ -146,6 +254,9 @@ func NewExecutor(stateManager state.Manager, iacRegistry *iac.Registry, options
 	if options.Parallelism <= 0 {
 		options.Parallelism = 10
 	}
+	if options.BuildParallelism <= 0 {
+		options.BuildParallelism = DefaultBuildParallelism
+	}
 	return &Executor{
 		stateManager: stateManager,
 		iacRegistry:  iacRegistry,
@@ -168,6 +279,7 @@ func (e *Executor) newComponentState(componentName string) *types.ComponentState
 	if e.options.ComponentSources != nil {
 		cs.Source = e.options.ComponentSources[componentName]
 	}
+	cs.Labels = e.options.ComponentLabels[componentName]
 	if e.options.ComponentVariables != nil {
 		if vars, ok := e.options.ComponentVariables[componentName]; ok {
 			strVars := make(map[string]string, len(vars))
@@ -180,6 +292,44 @@ func (e *Executor) newComponentState(componentName string) *types.ComponentState
 	return cs
 }
 
+// recordComponentLabels updates the labels of components already in state
+// to the ones being deployed.
+func (e *Executor) recordComponentLabels(envState *types.EnvironmentState) {
+	for compName, l := range e.options.ComponentLabels {
+		if cs := envState.Components[compName]; cs != nil {
+			cs.Labels = l
+		}
+	}
+}
+
+// recordTiming records how long a successful apply took on the resource's
+// state.
+func (e *Executor) recordTiming(change *planner.ResourceChange, envState *types.EnvironmentState, result *NodeResult, startTime time.Time) {
+	if e.options.DryRun || !result.Success || change.Node == nil {
+		return
+	}
+	switch change.Action {
+	case planner.ActionCreate, planner.ActionUpdate, planner.ActionReplace:
+	default:
+		return
+	}
+
+	e.stateMu.Lock()
+	defer e.stateMu.Unlock()
+	compState := envState.Components[change.Node.Component]
+	if compState == nil {
+		return
+	}
+	if res := e.getResourceMap(compState, change.Node)[resourceKey(change.Node)]; res != nil {
+		res.Timing = &types.ResourceTiming{
+			Action:     string(change.Action),
+			StartedAt:  startTime,
+			EndedAt:    startTime.Add(result.Duration),
+			DurationMS: result.Duration.Milliseconds(),
+		}
+	}
+}
+
 // resourceKey returns the type-qualified key for storing a resource in state.
 // Format: "type.name" (e.g., "deployment.api", "database.main").
 // This prevents collisions when different resource types share the same name.
@@ -208,6 +358,7 @@ func (e *Executor) getResourceMap(compState *types.ComponentState, node *graph.N
 	if !ok {
 		inst = &types.InstanceState{
 			Name:       node.Instance.Name,
+			Source:     e.options.InstanceSources[node.Component][node.Instance.Name],
 			Weight:     node.Instance.Weight,
 			Resources:  make(map[string]*types.ResourceState),
 			DeployedAt: time.Now(),
@@ -231,7 +382,7 @@ func computeComponentStatuses(envState *types.EnvironmentState) {
 		anyFailed := false
 		for _, res := range comp.Resources {
 			switch res.Status {
-			case types.ResourceStatusFailed:
+			case types.ResourceStatusFailed, types.ResourceStatusUnknown:
 				anyFailed = true
 				allReady = false
 			case types.ResourceStatusReady:
@@ -266,68 +417,8 @@ func (e *Executor) resolveAndStoreComponentOutputs(envState *types.EnvironmentSt
 		return
 	}
 
-	exprPattern := regexp.MustCompile(`\$\{\{\s*([^}]+)\s*\}\}`)
-
 	for compName, outputExprs := range e.graph.ComponentOutputExprs {
-		// Get component variables
-		compVars := make(map[string]interface{})
-		if e.options.ComponentVariables != nil {
-			if vars, ok := e.options.ComponentVariables[compName]; ok {
-				compVars = vars
-			}
-		}
-
-		resolved := make(map[string]interface{}, len(outputExprs))
-		for outName, expr := range outputExprs {
-			val := exprPattern.ReplaceAllStringFunc(expr, func(match string) string {
-				inner := match[3 : len(match)-2]
-				inner = strings.TrimSpace(inner)
-				parts := strings.Split(inner, ".")
-
-				if len(parts) < 2 {
-					return match
-				}
-
-				switch parts[0] {
-				case "variables":
-					varName := parts[1]
-					if v, ok := compVars[varName]; ok {
-						return fmt.Sprintf("%v", v)
-					}
-					return ""
-
-				case "databases", "services", "buckets", "routes", "ports":
-					// Look up resource output from graph
-					if len(parts) < 3 {
-						return ""
-					}
-					var nodeType graph.NodeType
-					switch parts[0] {
-					case "databases":
-						nodeType = graph.NodeTypeDatabase
-					case "services":
-						nodeType = graph.NodeTypeService
-					case "buckets":
-						nodeType = graph.NodeTypeBucket
-					case "routes":
-						nodeType = graph.NodeTypeRoute
-					case "ports":
-						nodeType = graph.NodeTypePort
-					}
-					nodeID := fmt.Sprintf("%s/%s/%s", compName, nodeType, parts[1])
-					if n, ok := e.graph.Nodes[nodeID]; ok && n.Outputs != nil {
-						if v, ok := n.Outputs[parts[2]]; ok {
-							return fmt.Sprintf("%v", v)
-						}
-					}
-					return ""
-
-				default:
-					return ""
-				}
-			})
-			resolved[outName] = val
-		}
+		resolved := e.resolveComponentOutputs(compName, outputExprs)
 
 		// Store on the ComponentState
 		if envState.Components != nil {
@@ -347,6 +438,90 @@ func (e *Executor) resolveAndStoreComponentOutputs(envState *types.EnvironmentSt
 	}
 }
 
+// resolveComponentOutputs evaluates a component's output expressions against
+// its variables and the outputs of its resource nodes in the graph.
+func (e *Executor) resolveComponentOutputs(compName string, outputExprs map[string]string) map[string]interface{} {
+	exprPattern := regexp.MustCompile(`\$\{\{\s*([^}]+)\s*\}\}`)
+
+	compVars := e.componentVariables(compName)
+
+	resolveRef := func(ref string) string {
+		parts := strings.Split(ref, ".")
+		if len(parts) < 2 {
+			return ""
+		}
+
+		switch parts[0] {
+		case "variables":
+			varName := parts[1]
+			if v, ok := compVars[varName]; ok {
+				return fmt.Sprintf("%v", v)
+			}
+			return ""
+
+		case "platform":
+			envName := ""
+			if e.envState != nil {
+				envName = e.envState.Name
+			}
+			if v, ok := e.platformExport(parts[1], envName); ok {
+				return fmt.Sprintf("%v", v)
+			}
+			return ""
+
+		case "databases", "services", "buckets", "routes", "ports", "external":
+			// Look up resource output from graph
+			if len(parts) < 3 {
+				return ""
+			}
+			var nodeType graph.NodeType
+			switch parts[0] {
+			case "databases":
+				nodeType = graph.NodeTypeDatabase
+			case "services":
+				nodeType = graph.NodeTypeService
+			case "buckets":
+				nodeType = graph.NodeTypeBucket
+			case "routes":
+				nodeType = graph.NodeTypeRoute
+			case "ports":
+				nodeType = graph.NodeTypePort
+			case "external":
+				nodeType = graph.NodeTypeExternal
+			}
+			nodeID := fmt.Sprintf("%s/%s/%s", compName, nodeType, parts[1])
+			if n, ok := e.graph.Nodes[nodeID]; ok && n.Outputs != nil {
+				if v, ok := n.Outputs[parts[2]]; ok {
+					return fmt.Sprintf("%v", v)
+				}
+			}
+			return ""
+
+		default:
+			return ""
+		}
+	}
+
+	resolved := make(map[string]interface{}, len(outputExprs))
+	for outName, expr := range outputExprs {
+		val := exprPattern.ReplaceAllStringFunc(expr, func(match string) string {
+			inner := match[3 : len(match)-2]
+			inner = strings.TrimSpace(inner)
+
+			if expression.IsComputed(inner) {
+				value, _ := expression.Compute(inner, resolveRef)
+				return value
+			}
+			if len(strings.Split(inner, ".")) < 2 {
+				return match
+			}
+			return resolveRef(inner)
+		})
+		resolved[outName] = val
+	}
+	return resolved
+}
+
 // Execute runs an execution plan.
 func (e *Executor) Execute(ctx context.Context, plan *planner.Plan, g *graph.Graph) (*ExecutionResult, error) {
 	startTime := time.Now()
@@ -365,6 +540,9 @@ func (e *Executor) Execute(ctx context.Context, plan *planner.Plan, g *graph.Gra
 		return result, nil
 	}
 
+	e.resetWarnings()
+	defer func() { result.Warnings = e.collectWarnings(result) }()
+
 	// Get or create environment state
 	envState, err := e.stateManager.GetEnvironment(ctx, plan.Datacenter, plan.Environment)
 	if err != nil {
@@ -382,11 +560,16 @@ func (e *Executor) Execute(ctx context.Context, plan *planner.Plan, g *graph.Gra
 	if envState.Components == nil {
 		envState.Components = make(map[string]*types.ComponentState)
 	}
+	e.envState = envState
+	e.snapshotPublishedRoutes(envState)
+	e.recordComponentLabels(envState)
+	stopHeartbeat := e.startHeartbeat(envState)
+	defer stopHeartbeat()
 
 	// Mark as provisioning and flush so that inspect can see progress immediately
 	envState.Status = types.EnvironmentStatusProvisioning
 	envState.UpdatedAt = time.Now()
-	_ = e.stateManager.SaveEnvironment(ctx, plan.Datacenter, envState)
+	_ = e.saveState(ctx, envState)
 
 	// Execute changes in order
 	for _, change := range plan.Changes {
@@ -399,11 +582,13 @@ func (e *Executor) Execute(ctx context.Context, plan *planner.Plan, g *graph.Gra
 		// Check if dependencies are satisfied
 		if change.Node != nil && !e.areDependenciesSatisfied(change.Node, g, result) {
 			depErr := e.buildDependencyError(change.Node, result)
+			rootCause := upstreamRootCause(change.Node, result)
 			nodeResult := &NodeResult{
-				NodeID:  change.Node.ID,
-				Action:  change.Action,
-				Success: false,
-				Error:   depErr,
+				NodeID:    change.Node.ID,
+				Action:    change.Action,
+				Success:   false,
+				Error:     depErr,
+				RootCause: rootCause,
 			}
 			result.NodeResults[change.Node.ID] = nodeResult
 			result.Failed++
@@ -431,7 +616,8 @@ func (e *Executor) Execute(ctx context.Context, plan *planner.Plan, g *graph.Gra
 				Name:         change.Node.Name,
 				Type:         string(change.Node.Type),
 				Status:       types.ResourceStatusFailed,
-				StatusReason: depErr.Error(),
+				StatusReason: types.StatusReasonUpstreamFailure,
+				RootCause:    rootCause,
 				Inputs:       change.Node.Inputs,
 				UpdatedAt:    time.Now(),
 			}
@@ -517,6 +703,7 @@ func (e *Executor) Execute(ctx context.Context, plan *planner.Plan, g *graph.Gra
 	e.resolveAndStoreComponentOutputs(envState)
 
 	// Update environment status
+	stopHeartbeat()
 	if result.Success {
 		envState.Status = types.EnvironmentStatusReady
 	} else {
@@ -525,7 +712,7 @@ func (e *Executor) Execute(ctx context.Context, plan *planner.Plan, g *graph.Gra
 	envState.UpdatedAt = time.Now()
 
 	// Save state
-	if err := e.stateManager.SaveEnvironment(ctx, plan.Datacenter, envState); err != nil {
+	if err := e.saveState(ctx, envState); err != nil {
 		result.Errors = append(result.Errors, fmt.Errorf("failed to save state: %w", err))
 	}
 
@@ -548,6 +735,27 @@ func (e *Executor) buildDependencyError(node *graph.Node, result *ExecutionResul
 	return fmt.Errorf("dependencies failed: %s", strings.Join(failedDeps, ", "))
 }
 
+// upstreamRootCause returns the failed resource that kept a node from being
+// applied: its first failed dependency, or the resource that dependency was
+// itself skipped because of.
+func upstreamRootCause(node *graph.Node, result *ExecutionResult) string {
+	for _, depID := range node.DependsOn {
+		if depResult, exists := result.NodeResults[depID]; !exists || !depResult.Success {
+			return rootCauseOf(depID, result)
+		}
+	}
+	return ""
+}
+
+// rootCauseOf returns the root cause of a failed node: the node itself, or
+// the node it was skipped because of.
+func rootCauseOf(nodeID string, result *ExecutionResult) string {
+	if depResult, exists := result.NodeResults[nodeID]; exists && depResult.RootCause != "" {
+		return depResult.RootCause
+	}
+	return nodeID
+}
+
 func (e *Executor) areDependenciesSatisfied(node *graph.Node, g *graph.Graph, result *ExecutionResult) bool {
 	for _, depID := range node.DependsOn {
 		depResult, exists := result.NodeResults[depID]
@@ -572,10 +780,11 @@ func (e *Executor) executeChange(ctx context.Context, change *planner.ResourceCh
 		result.NodeID = change.Node.ID
 	}
 
-	// Create a per-node log buffer to capture plugin output (build logs, process
+	// Create a per-node log to capture plugin output (build logs, process
 	// output, etc.). On failure the captured output is included in the progress
-	// event so the caller can display it for error diagnostics.
-	var logBuf bytes.Buffer
+	// event so the caller can display it for error diagnostics. Memory use is
+	// bounded: large output is spilled to disk.
+	logBuf := newNodeLog(result.NodeID, e.options.LogDir)
 
 	// Notify progress: starting
 	if e.options.OnProgress != nil && change.Node != nil {
@@ -588,18 +797,25 @@ func (e *Executor) executeChange(ctx context.Context, change *planner.ResourceCh
 		})
 	}
 
-	// Handle dry run
-	if e.options.DryRun {
-		result.Success = true
-		result.Duration = time.Since(startTime)
-		return result
+	// Report nodes that take a long time so that slow isn't mistaken for stuck
+	stopWatch := func() {}
+	if change.Node != nil && change.Action != planner.ActionNoop {
+		stopWatch = e.watchSlowNode(change.Node, string(change.Action), envState.Name)
 	}
 
 	switch change.Action {
 	case planner.ActionCreate, planner.ActionUpdate, planner.ActionReplace:
-		result = e.executeApply(ctx, change, envState, &logBuf)
+		if e.options.DryRun {
+			result = e.executePreview(ctx, change, envState, logBuf)
+		} else {
+			result = e.executeApply(ctx, change, envState, logBuf)
+		}
 	case planner.ActionDelete:
-		result = e.executeDestroy(ctx, change, envState)
+		if e.options.DryRun {
+			result.Success = true
+		} else {
+			result = e.executeDestroy(ctx, change, envState)
+		}
 	case planner.ActionNoop:
 		result.Success = true
 		// Load existing outputs from state so downstream expression resolution works.
@@ -608,7 +824,13 @@ func (e *Executor) executeChange(ctx context.Context, change *planner.ResourceCh
 		}
 	}
 
+	stopWatch()
+	e.phases.Delete(result.NodeID)
 	result.Duration = time.Since(startTime)
+	e.recordTiming(change, envState, result, startTime)
+
+	// Keep the full log on failure so it can be inspected after the run.
+	logFile := logBuf.Close(!result.Success)
 
 	// Notify progress: completed or failed.
 	// If the context was cancelled (StopOnError), report "cancelled" instead
@@ -618,6 +840,10 @@ func (e *Executor) executeChange(ctx context.Context, change *planner.ResourceCh
 		msg := ""
 		progressErr := result.Error
 		capturedLogs := ""
+		if change.Skipped() {
+			status = "skipped"
+			msg = change.SkipReason
+		}
 		if !result.Success {
 			status = "failed"
 			if ctx.Err() != nil {
@@ -627,23 +853,25 @@ func (e *Executor) executeChange(ctx context.Context, change *planner.ResourceCh
 				msg = result.Error.Error()
 			}
 			// Include captured logs on failure for error diagnostics
-			capturedLogs = logBuf.String()
+			capturedLogs = logBuf.Tail()
 		}
 		e.options.OnProgress(ProgressEvent{
-			NodeID:   change.Node.ID,
-			NodeName: change.Node.Name,
-			NodeType: string(change.Node.Type),
-			Status:   status,
-			Message:  msg,
-			Error:    progressErr,
-			Logs:     capturedLogs,
+			NodeID:      change.Node.ID,
+			NodeName:    change.Node.Name,
+			NodeType:    string(change.Node.Type),
+			Status:      status,
+			Message:     msg,
+			Error:       progressErr,
+			Logs:        capturedLogs,
+			LogFile:     logFile,
+			Remediation: arcerrors.RemediationOf(progressErr),
 		})
 	}
 
 	return result
 }
 
-func (e *Executor) executeApply(ctx context.Context, change *planner.ResourceChange, envState *types.EnvironmentState, logBuf *bytes.Buffer) *NodeResult {
+func (e *Executor) executeApply(ctx context.Context, change *planner.ResourceChange, envState *types.EnvironmentState, logBuf io.Writer) *NodeResult {
 	result := &NodeResult{
 		NodeID: change.Node.ID,
 		Action: change.Action,
@@ -654,11 +882,16 @@ func (e *Executor) executeApply(ctx context.Context, change *planner.ResourceCha
 	// inspect shows resolved values even while the resource is still provisioning.
 	e.resolveComponentExpressions(change.Node, envState)
 
+	// Add datacenter environment injections (proxies, CA bundles, region
+	// settings) to workloads the datacenter targets and the component has
+	// not opted out of.
+	e.applyEnvironmentInjections(change.Node, envState.Name)
+
 	// Dump the resolved node configuration when debug mode is active so
 	// operators can inspect resource inputs even if the environment is
 	// auto-cleaned after a failure (where `inspect` would not be available).
-	if os.Getenv("CLDCTL_DEBUG") != "" {
-		debugDumpNodeConfig(change)
+	if w := logging.Writer(logging.Executor); w != nil {
+		debugDumpNodeConfig(w, change)
 	}
 
 	// Port nodes use a special allocation flow: env override > datacenter hook > built-in fallback
@@ -677,6 +910,12 @@ func (e *Executor) executeApply(ctx context.Context, change *planner.ResourceCha
 		return e.executeNetworkPolicyNoop(ctx, change, envState)
 	}
 
+	// External services are declared, not provisioned: their outputs come
+	// straight from the component's configuration.
+	if change.Node.Type == graph.NodeTypeExternal {
+		return e.executeExternalService(ctx, change, envState)
+	}
+
 	// For databaseUser nodes, inject the parent database's resolved host and port
 	// into the node inputs so the datacenter hook can forward them to the module.
 	// The database node has already completed (it's a dependency), so its outputs
@@ -686,6 +925,42 @@ func (e *Executor) executeApply(ctx context.Context, change *planner.ResourceCha
 		e.injectParentDatabaseOutputs(change.Node)
 	}
 
+	// routeAuth proxies need the upstream port, and routes behind them need
+	// the proxy endpoint to send traffic to.
+	switch change.Node.Type {
+	case graph.NodeTypeRouteAuth:
+		e.injectRouteAuthUpstream(change.Node)
+	case graph.NodeTypeRoute:
+		e.injectRouteAuthOutputs(change.Node)
+	}
+
+	// A replaced resource's modules are destroyed, with the inputs they
+	// were applied with, before being applied again from scratch
+	if prior := change.CurrentState; change.Action == planner.ActionReplace && prior != nil && prior.Aggregate == "" && prior.Shared == "" {
+		replaced := *change.Node
+		if prior.Inputs != nil {
+			replaced.Inputs = prior.Inputs
+		}
+		if onProgress := e.options.OnProgress; onProgress != nil {
+			onProgress(ProgressEvent{
+				NodeID:   change.Node.ID,
+				NodeName: change.Node.Name,
+				NodeType: string(change.Node.Type),
+				Status:   "running",
+				Message:  "destroying the resource to replace it",
+			})
+		}
+		if err := e.destroyResourceModules(ctx, &replaced, prior); err != nil {
+			result.Error = fmt.Errorf("failed to replace resource: destroy failed: %w", err)
+			result.Success = false
+			return result
+		}
+		// What the resource's state recorded no longer exists
+		replacement := *change
+		replacement.CurrentState = nil
+		change = &replacement
+	}
+
 	// Lock for state initialization
 	e.stateMu.Lock()
 
@@ -715,27 +990,37 @@ func (e *Executor) executeApply(ctx context.Context, change *planner.ResourceCha
 		Inputs:    change.Node.Inputs,
 		UpdatedAt: time.Now(),
 	}
+	// Keep the prior IaC state so that a deploy taking over from this one,
+	// should this process die mid-apply, can still reconcile the resource
+	if change.CurrentState != nil {
+		resMap[resourceKey(change.Node)].IaCState = change.CurrentState.IaCState
+		resMap[resourceKey(change.Node)].ModuleStates = change.CurrentState.ModuleStates
+	}
 	e.saveStateLocked(envState)
 
 	e.stateMu.Unlock()
 
 	// Build a sub-status progress callback that forwards messages from the
 	// IaC plugin back to the CLI progress table as intermediate "running" events.
-	var hookOnProgress func(string)
-	if e.options.OnProgress != nil && change.Node != nil {
-		hookOnProgress = func(msg string) {
+	// Phases the plugin reports are tracked even without a progress callback
+	// so that they're recorded in the node log.
+	hookOnProgress := func(msg string) {
+		phase, msg := iac.ParseProgress(msg)
+		phase = e.setPhase(change.Node.ID, phase, logBuf)
+		if e.options.OnProgress != nil {
 			e.options.OnProgress(ProgressEvent{
 				NodeID:   change.Node.ID,
 				NodeName: change.Node.Name,
 				NodeType: string(change.Node.Type),
 				Status:   "running",
+				Phase:    phase,
 				Message:  msg,
 			})
 		}
 	}
 
 	// Find the matching hook from datacenter and execute all its modules
-	hookResult, err := e.executeHookModules(ctx, change.Node, envState.Name, compState, logBuf, hookOnProgress)
+	hookResult, err := e.executeHookModules(ctx, change.Node, envState.Name, compState, change.CurrentState, logBuf, hookOnProgress)
 	if err != nil {
 		result.Error = fmt.Errorf("failed to execute hook: %w", err)
 		result.Success = false
@@ -743,15 +1028,37 @@ func (e *Executor) executeApply(ctx context.Context, change *planner.ResourceCha
 		// Update resource state to failed (lock for state update)
 		e.stateMu.Lock()
 		resMap := e.getResourceMap(compState, change.Node)
-		resMap[resourceKey(change.Node)] = &types.ResourceState{
-			Component:    change.Node.Component,
-			Name:         change.Node.Name,
-			Type:         string(change.Node.Type),
-			Status:       types.ResourceStatusFailed,
-			StatusReason: result.Error.Error(),
-			Inputs:       change.Node.Inputs,
-			UpdatedAt:    time.Now(),
-		}
+		resMap[resourceKey(change.Node)] = e.failedResourceState(ctx, change, hookResult, result.Error)
+		e.saveStateLocked(envState)
+		e.stateMu.Unlock()
+
+		return result
+	}
+
+	// Dependents aren't applied until the resource passes its readiness
+	// gate. The gate may reference the resource's own outputs.
+	change.Node.Outputs = hookResult.Outputs
+	if err := e.awaitReadiness(ctx, change.Node, envState, hookOnProgress); err != nil {
+		result.Error = fmt.Errorf("readiness check failed: %w", err)
+		result.Success = false
+
+		e.stateMu.Lock()
+		rs := &types.ResourceState{
+			Component:        change.Node.Component,
+			Name:             change.Node.Name,
+			Type:             string(change.Node.Type),
+			Status:           types.ResourceStatusFailed,
+			StatusReason:     result.Error.Error(),
+			Inputs:           change.Node.Inputs,
+			Outputs:          hookResult.Outputs,
+			SensitiveOutputs: hookResult.Sensitive,
+			Aggregate:        hookResult.Aggregate,
+			Shared:           hookResult.Shared,
+			UpdatedAt:        time.Now(),
+		}
+		setModuleStates(rs, hookResult.ModuleStates)
+		resMap := e.getResourceMap(compState, change.Node)
+		resMap[resourceKey(change.Node)] = rs
 		e.saveStateLocked(envState)
 		e.stateMu.Unlock()
 
@@ -764,42 +1071,100 @@ func (e *Executor) executeApply(ctx context.Context, change *planner.ResourceCha
 	// Update resource state including IaC state for cleanup (lock for state update)
 	e.stateMu.Lock()
 	resourceState := &types.ResourceState{
-		Component: change.Node.Component,
-		Name:      change.Node.Name,
-		Type:      string(change.Node.Type),
-		Status:    types.ResourceStatusReady,
-		Inputs:    change.Node.Inputs,
-		Outputs:   hookResult.Outputs,
-		UpdatedAt: time.Now(),
-	}
-	// For single-module hooks, store IaC state in the legacy field for backward compatibility.
-	// For multi-module hooks, store per-module states.
-	if len(hookResult.ModuleStates) == 1 {
-		for _, ms := range hookResult.ModuleStates {
-			resourceState.IaCState = ms.IaCState
-		}
-	} else if len(hookResult.ModuleStates) > 1 {
-		resourceState.ModuleStates = hookResult.ModuleStates
-	}
+		Component:        change.Node.Component,
+		Name:             change.Node.Name,
+		Type:             string(change.Node.Type),
+		Status:           types.ResourceStatusReady,
+		Inputs:           change.Node.Inputs,
+		Outputs:          hookResult.Outputs,
+		SensitiveOutputs: hookResult.Sensitive,
+		Aggregate:        hookResult.Aggregate,
+		Shared:           hookResult.Shared,
+		UpdatedAt:        time.Now(),
+	}
+	setModuleStates(resourceState, hookResult.ModuleStates)
 	resMapFinal := e.getResourceMap(compState, change.Node)
 	resMapFinal[resourceKey(change.Node)] = resourceState
+	e.recordRouteLocked(compState, change.Node, envState.Name)
 	e.saveStateLocked(envState)
 	e.stateMu.Unlock()
 
 	return result
 }
 
-// hookExecutionResult contains the combined results of executing all modules in a hook.
-type hookExecutionResult struct {
-	Outputs      map[string]interface{}
-	ModuleStates map[string]*types.ModuleState
+// setModuleStates records the IaC state of a hook's modules on a resource.
+// For single-module hooks, IaC state is stored in the legacy field for
+// backward compatibility. For multi-module hooks, and for cached modules whose
+// cache key must survive to the next apply, per-module states are stored.
+func setModuleStates(rs *types.ResourceState, moduleStates map[string]*types.ModuleState) {
+	if len(moduleStates) == 1 && !hasCacheKey(moduleStates) {
+		for _, ms := range moduleStates {
+			rs.IaCState = ms.IaCState
+		}
+	} else if len(moduleStates) > 0 {
+		rs.ModuleStates = moduleStates
+	}
 }
 
-// executeHookModules finds the matching hook, executes ALL its modules (not just the first),
-// allows cross-module references in inputs, evaluates hook outputs including nested objects,
-// and auto-populates read/write fallback outputs for database hooks.
-// onProgress (may be nil) forwards sub-status messages from plugins to the caller.
-func (e *Executor) executeHookModules(ctx context.Context, node *graph.Node, envName string, compState *types.ComponentState, logBuf *bytes.Buffer, onProgress func(string)) (*hookExecutionResult, error) {
+// failedResourceState builds the state recorded for a node whose hook failed.
+//
+// Infrastructure that a failed or cancelled apply left behind is not dropped:
+// the IaC state of modules that completed, and of leftovers reported by a
+// plugin through iac.PartialApplyError, is kept so a later destroy can clean
+// it up. Such resources, and any whose apply was cancelled, are marked
+// unknown rather than failed. When nothing new was recorded, the state of the
+// previous deploy is carried forward so it remains tracked.
+func (e *Executor) failedResourceState(ctx context.Context, change *planner.ResourceChange, hookResult *hookExecutionResult, err error) *types.ResourceState {
+	rs := &types.ResourceState{
+		Component:    change.Node.Component,
+		Name:         change.Node.Name,
+		Type:         string(change.Node.Type),
+		Status:       types.ResourceStatusFailed,
+		StatusReason: err.Error(),
+		Inputs:       change.Node.Inputs,
+		UpdatedAt:    time.Now(),
+	}
+
+	var partial map[string]*types.ModuleState
+	if hookResult != nil {
+		for name, ms := range hookResult.ModuleStates {
+			if len(ms.IaCState) > 0 {
+				if partial == nil {
+					partial = make(map[string]*types.ModuleState)
+				}
+				partial[name] = ms
+			}
+		}
+	}
+
+	switch {
+	case len(partial) > 0:
+		setModuleStates(rs, partial)
+		rs.Status = types.ResourceStatusUnknown
+	case change.CurrentState != nil:
+		rs.IaCState = change.CurrentState.IaCState
+		rs.ModuleStates = change.CurrentState.ModuleStates
+	}
+	// Keep the resource in its aggregate or shared hook so that destroying
+	// it is accounted for
+	switch {
+	case hookResult != nil && (hookResult.Aggregate != "" || hookResult.Shared != ""):
+		rs.Aggregate = hookResult.Aggregate
+		rs.Shared = hookResult.Shared
+	case change.CurrentState != nil:
+		rs.Aggregate = change.CurrentState.Aggregate
+		rs.Shared = change.CurrentState.Shared
+	}
+	if ctx.Err() != nil {
+		rs.Status = types.ResourceStatusUnknown
+	}
+	return rs
+}
+
+// matchHook returns the first datacenter hook whose 'when' condition matches
+// the node, combined with any matching fallthrough hooks tried before it.
+// Error hooks are reported as a DatacenterHookError.
+func (e *Executor) matchHook(node *graph.Node) (datacenter.Hook, error) {
 	dc := e.options.Datacenter
 	if dc == nil {
 		return nil, fmt.Errorf("no datacenter configuration provided")
@@ -812,29 +1177,51 @@ func (e *Executor) executeHookModules(ctx context.Context, node *graph.Node, env
 	}
 
 	// Find the first matching hook based on 'when' condition
-	var matchedHook datacenter.Hook
-	for _, hook := range hooks {
-		when := hook.When()
-		matches := e.evaluateWhenCondition(when, node.Inputs)
-		if matches {
-			matchedHook = hook
-			break
-		}
-	}
+	matchedHook, _ := datacenter.MatchHook(hooks, func(hook datacenter.Hook) bool {
+		return e.evaluateWhenCondition(hook.When(), node)
+	})
 
 	if matchedHook == nil {
 		return nil, fmt.Errorf("no matching hook found for %s (inputs: %v)", node.Type, node.Inputs)
 	}
 
+	// Modules of fallthrough hooks share the resource's state with the
+	// modules of the hook they fall through to, keyed by module name
+	seen := make(map[string]bool)
+	for _, module := range matchedHook.Modules() {
+		if seen[module.Name()] {
+			return nil, fmt.Errorf("module %q is defined by more than one hook matching %s %s/%s; modules of fallthrough hooks must have unique names", module.Name(), node.Type, node.Component, node.Name)
+		}
+		seen[module.Name()] = true
+	}
+
 	// Check if the matched hook is an error hook (rejects the resource)
-	if errMsg := matchedHook.Error(); errMsg != "" {
-		evaluatedMsg := e.evaluateErrorMessage(errMsg, node.Inputs)
-		return nil, arcerrors.DatacenterHookError(
-			string(node.Type),
-			node.Component,
-			node.Name,
-			evaluatedMsg,
-		)
+	if matchedHook.Error() != "" {
+		return nil, e.hookRejection(matchedHook, node)
+	}
+
+	return matchedHook, nil
+}
+
+// hookExecutionResult contains the combined results of executing all modules in a hook.
+type hookExecutionResult struct {
+	Outputs      map[string]interface{}
+	Sensitive    []string // Dot paths of the sensitive outputs
+	ModuleStates map[string]*types.ModuleState
+	Aggregate    string // Key of the aggregate hook that ran, if any
+	Shared       string // Key of the shared hook whose modules were used, if any
+}
+
+// executeHookModules finds the matching hook, executes ALL its modules (not just the first),
+// allows cross-module references in inputs, evaluates hook outputs including nested objects,
+// and auto-populates read/write fallback outputs for database hooks.
+// prior (may be nil) is the resource's state from the last apply; cacheable
+// modules whose cache key is unchanged reuse their outputs from it.
+// onProgress (may be nil) forwards sub-status messages from plugins to the caller.
+func (e *Executor) executeHookModules(ctx context.Context, node *graph.Node, envName string, compState *types.ComponentState, prior *types.ResourceState, logBuf io.Writer, onProgress func(string)) (*hookExecutionResult, error) {
+	matchedHook, err := e.matchHook(node)
+	if err != nil {
+		return nil, err
 	}
 
 	modules := matchedHook.Modules()
@@ -842,8 +1229,75 @@ func (e *Executor) executeHookModules(ctx context.Context, node *graph.Node, env
 		return nil, fmt.Errorf("hook has no modules defined for %s", node.Type)
 	}
 
+	// Environment modules are provisioned with the environment, before any
+	// component. Fail clearly rather than pass unresolved references along.
+	for _, name := range datacenter.HookEnvironmentModules(matchedHook) {
+		if _, ok := e.options.EnvironmentModules[name]; !ok {
+			return nil, fmt.Errorf("hook for %s references environment module %q, which is not provisioned in environment %q", node.Type, name, envName)
+		}
+	}
+
+	// Aggregate hooks run their modules once for all of the resources they
+	// match, and the resource gets its share of their outputs
+	if matchedHook.Aggregate() {
+		key := aggregateKey(node.Type, matchedHook)
+		moduleOutputs, err := e.joinAggregate(ctx, node, matchedHook, logBuf, onProgress)
+		if err != nil {
+			return &hookExecutionResult{Aggregate: key}, err
+		}
+		e.stateMu.Lock()
+		var sensitive map[string]bool
+		if agg := e.envState.Aggregates[key]; agg != nil {
+			sensitive = sensitiveModuleOutputs(agg.ModuleStates)
+		}
+		e.stateMu.Unlock()
+		outputs, sensitivePaths, err := e.hookOutputs(node, matchedHook, moduleOutputs, sensitive, envName)
+		if err != nil {
+			return &hookExecutionResult{Aggregate: key}, err
+		}
+		return &hookExecutionResult{Outputs: outputs, Sensitive: sensitivePaths, Aggregate: key}, nil
+	}
+
+	// Shared hooks provision their modules once for the datacenter, and the
+	// resource reuses their outputs
+	if matchedHook.Shared() {
+		key := sharedKey(node.Type, matchedHook)
+		moduleOutputs, moduleStates, err := e.useShared(ctx, key, node, matchedHook, envName, logBuf, onProgress)
+		if err != nil {
+			return &hookExecutionResult{Shared: key}, err
+		}
+		outputs, sensitivePaths, err := e.hookOutputs(node, matchedHook, moduleOutputs, sensitiveModuleOutputs(moduleStates), envName)
+		if err != nil {
+			return &hookExecutionResult{Shared: key}, err
+		}
+		return &hookExecutionResult{Outputs: outputs, Sensitive: sensitivePaths, Shared: key}, nil
+	}
+
+	moduleOutputs, moduleStates, err := e.applyHookModules(ctx, matchedHook, node, envName, prior, logBuf, onProgress)
+	if err != nil {
+		return &hookExecutionResult{ModuleStates: moduleStates}, err
+	}
+
+	outputs, sensitivePaths, err := e.hookOutputs(node, matchedHook, moduleOutputs, sensitiveModuleOutputs(moduleStates), envName)
+	if err != nil {
+		return &hookExecutionResult{ModuleStates: moduleStates}, err
+	}
+
+	return &hookExecutionResult{
+		Outputs:      outputs,
+		Sensitive:    sensitivePaths,
+		ModuleStates: moduleStates,
+	}, nil
+}
+
+// applyHookModules applies each of a hook's modules for a resource in turn,
+// resolving cross-module references (module.<name>.<output>) from the
+// outputs of earlier modules. It returns the outputs of each module, and the
+// state of each module that may have created resources, which is set even
+// when a module fails so that they remain destroyable.
+func (e *Executor) applyHookModules(ctx context.Context, matchedHook datacenter.Hook, node *graph.Node, envName string, prior *types.ResourceState, logBuf io.Writer, onProgress func(string)) (map[string]map[string]interface{}, map[string]*types.ModuleState, error) {
 	// Resolve datacenter path for module paths
-	dcPath := dc.SourcePath()
+	dcPath := e.options.Datacenter.SourcePath()
 	dcDir := filepath.Dir(dcPath)
 
 	// Execute all modules, accumulating outputs for cross-module references
@@ -851,10 +1305,10 @@ func (e *Executor) executeHookModules(ctx context.Context, node *graph.Node, env
 	moduleOutputs := make(map[string]map[string]interface{})
 	moduleStates := make(map[string]*types.ModuleState)
 
-	for _, module := range modules {
+	for _, module := range matchedHook.Modules() {
 		// Check module's when condition (if any)
 		moduleWhen := module.When()
-		if moduleWhen != "" && !e.evaluateWhenCondition(moduleWhen, node.Inputs) {
+		if moduleWhen != "" && !e.evaluateWhenCondition(moduleWhen, node) {
 			continue
 		}
 
@@ -864,73 +1318,59 @@ func (e *Executor) executeHookModules(ctx context.Context, node *graph.Node, env
 			modulePath = module.Source()
 		}
 
-		if os.Getenv("CLDCTL_DEBUG") != "" && e.options.Output != nil {
-			fmt.Fprintf(e.options.Output, "  [debug] Node %s: executing module %s (dcDir=%s, build=%q, source=%q)\n",
-				node.ID, module.Name(), dcDir, module.Build(), module.Source())
-		}
+		executorLog.Debug("executing module", "node", node.ID, "module", module.Name(),
+			"dc_dir", dcDir, "build", module.Build(), "source", module.Source())
 
 		if modulePath != "" && !filepath.IsAbs(modulePath) {
 			modulePath = filepath.Join(dcDir, modulePath)
 		}
 
 		if modulePath == "" {
-			return nil, fmt.Errorf("module %s has no build or source path", module.Name())
+			return nil, moduleStates, fmt.Errorf("module %s has no build or source path", module.Name())
 		}
 
 		// Build module inputs, resolving cross-module references (module.<name>.<output>)
 		inputs := e.buildModuleInputsWithCrossRef(module, node, envName, moduleOutputs)
 
-		// Get IaC plugin
-		pluginName := module.Plugin()
-		if pluginName == "" {
-			pluginName = "native"
-		}
-		plugin, err := e.iacRegistry.Get(pluginName)
+		// Check inputs against the module's contract before handing them to
+		// the IaC tool, whose own errors are much harder to trace back
+		contract, err := iac.LoadModuleContract(modulePath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get IaC plugin %q: %w", pluginName, err)
+			return nil, moduleStates, fmt.Errorf("module %s: %w", module.Name(), err)
 		}
-
-		// Execute — pipe plugin output into the per-node log buffer so it can be
-		// included in error diagnostics instead of being printed to stdout.
-		runOpts := iac.RunOptions{
-			ModuleSource: modulePath,
-			Inputs:       inputs,
-			Environment:  map[string]string{},
-			Stdout:       logBuf,
-			Stderr:       logBuf,
-			OnProgress:   onProgress,
+		if err := contract.ValidateInputs(module.Name(), inputs); err != nil {
+			return nil, moduleStates, err
 		}
 
-		applyResult, err := plugin.Apply(ctx, runOpts)
-		if err != nil {
-			// Log resource configuration on failure for debugging
-			if os.Getenv("CLDCTL_DEBUG") != "" {
-				fmt.Fprintf(os.Stderr, "\n[debug] Resource %s/%s module %s failed — configuration:\n", node.Type, node.Name, module.Name())
-				fmt.Fprintf(os.Stderr, "  Component:        %s\n", node.Component)
-				fmt.Fprintf(os.Stderr, "  Module:           %s (plugin: %s)\n", modulePath, pluginName)
+		// Skip cacheable modules whose result is unchanged since the last apply
+		cacheKey := e.moduleCacheKey(module, modulePath, inputs, moduleOutputs, node, envName)
+		if cached := cachedModuleState(prior, module.Name(), cacheKey); cached != nil {
+			if onProgress != nil {
+				onProgress(fmt.Sprintf("module %s unchanged, reusing cached outputs", module.Name()))
 			}
-			return nil, fmt.Errorf("module %s apply failed: %w", module.Name(), err)
+			moduleOutputs[module.Name()] = cached.Outputs
+			moduleStates[module.Name()] = cached
+			continue
 		}
 
-		// Collect module outputs
-		modOutputs := make(map[string]interface{})
-		for name, out := range applyResult.Outputs {
-			modOutputs[name] = out.Value
+		ms, err := e.applyHookModule(ctx, matchedHook, module, modulePath, contract, inputs, node, envName, logBuf, onProgress)
+		if ms != nil {
+			moduleStates[module.Name()] = ms
 		}
-		moduleOutputs[module.Name()] = modOutputs
-
-		// Track per-module state
-		moduleStates[module.Name()] = &types.ModuleState{
-			Name:     module.Name(),
-			Plugin:   pluginName,
-			Source:   modulePath,
-			Inputs:   inputs,
-			Outputs:  modOutputs,
-			IaCState: applyResult.State,
-			Status:   types.ModuleStatusReady,
+		if err != nil {
+			return nil, moduleStates, err
 		}
+		moduleOutputs[module.Name()] = ms.Outputs
+		ms.CacheKey = cacheKey
 	}
 
+	return moduleOutputs, moduleStates, nil
+}
+
+// hookOutputs evaluates a hook's outputs for a resource from the outputs of
+// its modules. It also returns the paths of the outputs that are derived from
+// the sensitive module outputs (see sensitiveModuleOutputs).
+func (e *Executor) hookOutputs(node *graph.Node, matchedHook datacenter.Hook, moduleOutputs map[string]map[string]interface{}, sensitive map[string]bool, envName string) (map[string]interface{}, []string, error) {
 	// Evaluate hook-level outputs using module outputs
 	outputs := e.evaluateHookOutputs(matchedHook, moduleOutputs, node, envName)
 
@@ -942,14 +1382,101 @@ func (e *Executor) executeHookModules(ctx context.Context, node *graph.Node, env
 	// Validate that the hook produced all required outputs for this resource type.
 	// Missing outputs lead to unresolved ${{ }} expressions downstream which are
 	// very difficult to diagnose, so we fail early with a clear message.
-	if err := validateHookOutputs(node.Type, outputs); err != nil {
-		return nil, fmt.Errorf("datacenter hook for %s/%s produced incomplete outputs: %w", node.Type, node.Name, err)
+	if err := validateHookOutputs(node.Type, matchedHook, outputs); err != nil {
+		return nil, nil, fmt.Errorf("datacenter hook for %s/%s produced incomplete outputs: %w", node.Type, node.Name, err)
 	}
+	return outputs, hookSensitiveOutputs(matchedHook, sensitive, outputs), nil
+}
 
-	return &hookExecutionResult{
-		Outputs:      outputs,
-		ModuleStates: moduleStates,
-	}, nil
+// applyHookModule applies one module of a hook, with the credentials and
+// sandbox of the hook that declares it, and checks its outputs against the
+// module's contract. The returned state is set whenever the module may have
+// created resources, even if it failed, so that they remain destroyable.
+func (e *Executor) applyHookModule(ctx context.Context, hook datacenter.Hook, module datacenter.Module, modulePath string, contract *iac.ModuleContract, inputs map[string]interface{}, node *graph.Node, envName string, logBuf io.Writer, onProgress func(string)) (*types.ModuleState, error) {
+	pluginName := module.Plugin()
+	if pluginName == "" {
+		pluginName = "native"
+	}
+	plugin, err := e.iacRegistry.Get(pluginName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IaC plugin %q: %w", pluginName, err)
+	}
+	// A module runs with its own hook's sandbox and credentials, which
+	// differ from the matched hook's for modules of fallthrough hooks
+	moduleHook := datacenter.ModuleHook(hook, module)
+	sandbox := moduleSandbox(e.options.Datacenter, moduleHook)
+	if err := checkSandbox(module.Name(), pluginName, plugin, sandbox); err != nil {
+		return nil, err
+	}
+
+	// Scope the module's cloud credentials to those its hook configures
+	creds, err := e.resolveModuleCredentials(moduleHook, module, node, envName)
+	if err != nil {
+		return nil, fmt.Errorf("module %s: %w", module.Name(), err)
+	}
+	credEnv, err := e.credentialEnvironment(ctx, creds, CredentialSessionName(module.Name()))
+	if err != nil {
+		return nil, fmt.Errorf("module %s: %w", module.Name(), err)
+	}
+
+	// Execute — pipe plugin output into the per-node log buffer so it can be
+	// included in error diagnostics instead of being printed to stdout.
+	runOpts := iac.RunOptions{
+		ModuleSource: modulePath,
+		Inputs:       inputs,
+		Environment:  credEnv,
+		Sandbox:      sandbox,
+		Stdout:       logBuf,
+		Stderr:       logBuf,
+		OnProgress:   onProgress,
+	}
+
+	applyResult, err := plugin.Apply(ctx, runOpts)
+	if err != nil {
+		// Log resource configuration on failure for debugging
+		executorLog.Debug("module failed", "node", node.ID, "module", module.Name(),
+			"path", modulePath, "plugin", pluginName, "error", err)
+		// Keep track of anything the plugin could not roll back.
+		var partial *iac.PartialApplyError
+		if errors.As(err, &partial) {
+			return &types.ModuleState{
+				Name:         module.Name(),
+				Plugin:       pluginName,
+				Source:       modulePath,
+				Inputs:       inputs,
+				IaCState:     partial.State,
+				Credentials:  creds,
+				Status:       types.ModuleStatusFailed,
+				StatusReason: err.Error(),
+			}, fmt.Errorf("module %s apply failed: %w", module.Name(), err)
+		}
+		return nil, fmt.Errorf("module %s apply failed: %w", module.Name(), err)
+	}
+
+	// Collect module outputs
+	modOutputs := make(map[string]interface{})
+	for name, out := range applyResult.Outputs {
+		modOutputs[name] = out.Value
+	}
+
+	ms := &types.ModuleState{
+		Name:             module.Name(),
+		Plugin:           pluginName,
+		Source:           modulePath,
+		Inputs:           inputs,
+		Outputs:          modOutputs,
+		SensitiveOutputs: iac.SensitiveOutputs(applyResult.Outputs),
+		IaCState:         applyResult.State,
+		Credentials:      creds,
+		Status:           types.ModuleStatusReady,
+	}
+
+	// The module's resources are tracked above, so a contract violation
+	// still leaves them destroyable
+	if err := contract.ValidateOutputs(module.Name(), modOutputs); err != nil {
+		return ms, err
+	}
+	return ms, nil
 }
 
 // buildModuleInputsWithCrossRef builds inputs for a module, resolving cross-module references
@@ -995,8 +1522,11 @@ func (e *Executor) resolveCrossModuleRefs(expr string, moduleOutputs map[string]
 		return expr
 	}
 
+	// Resolve ${environment.module.<name>.<output>} first so it isn't
+	// mistaken for a reference to a module of the hook
+	result := e.interpolateEnvironmentModules(expr)
+
 	// Handle ${module.<name>.<output>} interpolation patterns
-	result := expr
 	for modName, modOutputs := range moduleOutputs {
 		for outName, outVal := range modOutputs {
 			ref := fmt.Sprintf("module.%s.%s", modName, outName)
@@ -1017,6 +1547,50 @@ func (e *Executor) resolveCrossModuleRefs(expr string, moduleOutputs map[string]
 	return result
 }
 
+// interpolateEnvironmentModules replaces ${environment.module.<name>.<output>}
+// references with the outputs of provisioned environment modules.
+func (e *Executor) interpolateEnvironmentModules(expr string) string {
+	if !strings.Contains(expr, "${environment.module.") {
+		return expr
+	}
+	result := expr
+	for modName, modOutputs := range e.options.EnvironmentModules {
+		for outName, outVal := range modOutputs {
+			ref := fmt.Sprintf("${environment.module.%s.%s}", modName, outName)
+			result = strings.ReplaceAll(result, ref, fmt.Sprintf("%v", outVal))
+		}
+	}
+	return result
+}
+
+// platformExport evaluates a value the datacenter exports to components as
+// ${{ platform.<name> }}. It reports false if the datacenter doesn't export
+// the name, or if the export refers to an environment module that isn't
+// provisioned.
+func (e *Executor) platformExport(name, envName string) (interface{}, bool) {
+	dc := e.options.Datacenter
+	if dc == nil || dc.Environment() == nil {
+		return nil, false
+	}
+	expr, ok := dc.Environment().Exports()[name]
+	if !ok {
+		return nil, false
+	}
+	dcVars := e.options.DatacenterVariables
+	if dcVars == nil {
+		dcVars = make(map[string]interface{})
+	}
+	// Exports can't refer to a resource, so they're evaluated without one
+	value := e.evaluateInputExpression(expr, &graph.Node{}, envName, dcVars)
+	if value == nil {
+		return nil, false
+	}
+	if s, ok := value.(string); ok && strings.Contains(s, "${") {
+		return nil, false
+	}
+	return value, true
+}
+
 // evaluateHookOutputs evaluates the hook's output expressions using accumulated module outputs.
 // For expressions like "module.postgres.url", it looks up the value from moduleOutputs.
 // Also handles nested output objects (e.g., read = { ... }, write = { ... }).
@@ -1062,6 +1636,10 @@ func (e *Executor) resolveOutputExpression(exprStr string, moduleOutputs map[str
 
 	// If fully resolved (no more module. references), return the value
 	if resolved != exprStr {
+		if strings.Contains(resolved, "${") {
+			// Interpolate any remaining node, environment or variable references
+			return e.evaluateInputExpression(resolved, node, envName, dcVars)
+		}
 		return resolved
 	}
 
@@ -1104,44 +1682,20 @@ func (e *Executor) autoPopulateDatabaseEndpoints(outputs map[string]interface{},
 	}
 }
 
-// requiredHookOutputs maps each resource type to the output keys that the
-// datacenter hook MUST produce at deploy time. Missing outputs cause unresolved
-// ${{ }} expressions downstream, so we validate eagerly and fail with a clear
-// message.
-//
-// This mirrors internal.RequiredHookOutputs but uses graph.NodeType keys and
-// operates on resolved map[string]interface{} values (vs expression strings at
-// build time).
-var requiredHookOutputs = map[graph.NodeType][]string{
-	graph.NodeTypeDatabase:      {"host", "port", "url"},
-	graph.NodeTypeBucket:        {"endpoint", "bucket", "accessKeyId", "secretAccessKey"},
-	graph.NodeTypeSMTP:          {"host", "port", "username", "password"},
-	graph.NodeTypeDeployment:    {"id"},
-	graph.NodeTypeFunction:      {"id", "endpoint"},
-	graph.NodeTypeService:       {"host", "port", "url"},
-	graph.NodeTypeRoute:         {"url", "host", "port"},
-	graph.NodeTypeTask:          {"id", "status"},
-	graph.NodeTypeObservability: {"endpoint", "protocol"},
-	graph.NodeTypeDatabaseUser:  {"host", "port", "url"},
-	// database: username and password are optional (not all engines require
-	// credentials, e.g., Redis).
-	// encryptionKey: outputs vary by algorithm (RSA vs symmetric) — validated separately if needed.
-	// port: hook is optional (engine has built-in fallback), so no required outputs here.
-	// networkPolicy: no outputs (fire-and-forget leaf node).
-}
-
-// validateHookOutputs checks that the hook outputs contain all required keys
-// for the given resource type. Returns a descriptive error listing the missing
-// keys, or nil if all required outputs are present.
-func validateHookOutputs(nodeType graph.NodeType, outputs map[string]interface{}) error {
-	required, ok := requiredHookOutputs[nodeType]
-	if !ok {
-		return nil // No required outputs defined for this type
+// validateHookOutputs checks that the hook outputs contain every output the
+// hook is required to produce: the built-in contract for the resource type
+// plus any outputs the hook guarantees. Missing outputs cause unresolved
+// ${{ }} expressions downstream, so we validate eagerly and return a
+// descriptive error listing the missing keys, or nil if all are present.
+func validateHookOutputs(nodeType graph.NodeType, hook datacenter.Hook, outputs map[string]interface{}) error {
+	required := datacenter.RequiredHookOutputs(string(nodeType), hook)
+	if len(required) == 0 {
+		return nil
 	}
 
 	var missing []string
 	for _, key := range required {
-		if _, exists := outputs[key]; !exists {
+		if !hasOutput(outputs, key) {
 			missing = append(missing, key)
 		}
 	}
@@ -1169,6 +1723,18 @@ func validateHookOutputs(nodeType graph.NodeType, outputs map[string]interface{}
 	return nil
 }
 
+// hasOutput reports whether outputs contains key. A dot path such as
+// "read.host" looks inside a nested output object.
+func hasOutput(outputs map[string]interface{}, key string) bool {
+	if parent, child, ok := strings.Cut(key, "."); ok {
+		nested, _ := outputs[parent].(map[string]interface{})
+		_, exists := nested[child]
+		return exists
+	}
+	_, exists := outputs[key]
+	return exists
+}
+
 // findMatchingHook finds the matching datacenter hook for a node and returns the module path, inputs, and plugin name.
 // NOTE: This method is retained for backward compatibility with single-module execution paths
 // (e.g., port allocation). For multi-module execution, use executeHookModules instead.
@@ -1185,29 +1751,17 @@ func (e *Executor) findMatchingHook(node *graph.Node, envName string) (modulePat
 	}
 
 	// Find the first matching hook based on 'when' condition
-	var matchedHook datacenter.Hook
-	for _, hook := range hooks {
-		when := hook.When()
-		matches := e.evaluateWhenCondition(when, node.Inputs)
-		if matches {
-			matchedHook = hook
-			break
-		}
-	}
+	matchedHook, _ := datacenter.MatchHook(hooks, func(hook datacenter.Hook) bool {
+		return e.evaluateWhenCondition(hook.When(), node)
+	})
 
 	if matchedHook == nil {
 		return "", nil, "", fmt.Errorf("no matching hook found for %s (inputs: %v)", node.Type, node.Inputs)
 	}
 
 	// Check if the matched hook is an error hook (rejects the resource)
-	if errMsg := matchedHook.Error(); errMsg != "" {
-		evaluatedMsg := e.evaluateErrorMessage(errMsg, node.Inputs)
-		return "", nil, "", arcerrors.DatacenterHookError(
-			string(node.Type),
-			node.Component,
-			node.Name,
-			evaluatedMsg,
-		)
+	if matchedHook.Error() != "" {
+		return "", nil, "", e.hookRejection(matchedHook, node)
 	}
 
 	// Get the first module from the hook
@@ -1226,11 +1780,8 @@ func (e *Executor) findMatchingHook(node *graph.Node, envName string) (modulePat
 		modulePath = module.Source()
 	}
 
-	// Debug output for troubleshooting (only when env var is set)
-	if os.Getenv("CLDCTL_DEBUG") != "" && e.options.Output != nil {
-		fmt.Fprintf(e.options.Output, "  [debug] Node %s: dcPath=%s, dcDir=%s, moduleName=%s, moduleBuild=%q, moduleSource=%q\n",
-			node.ID, dcPath, dcDir, module.Name(), module.Build(), module.Source())
-	}
+	executorLog.Debug("resolving module path", "node", node.ID, "dc_path", dcPath, "dc_dir", dcDir,
+		"module", module.Name(), "build", module.Build(), "source", module.Source())
 
 	if modulePath != "" && !filepath.IsAbs(modulePath) {
 		modulePath = filepath.Join(dcDir, modulePath)
@@ -1289,6 +1840,8 @@ func (e *Executor) getHooksForType(nodeType graph.NodeType) []datacenter.Hook {
 		return hooks.DatabaseUser()
 	case graph.NodeTypeNetworkPolicy:
 		return hooks.NetworkPolicy()
+	case graph.NodeTypeRouteAuth:
+		return hooks.RouteAuth()
 	default:
 		return nil
 	}
@@ -1299,52 +1852,65 @@ func (e *Executor) getHooksForType(nodeType graph.NodeType) []datacenter.Hook {
 // implicit node types (databaseUser, networkPolicy) to decide whether to execute
 // the hook pipeline or fall back to default behavior.
 func (e *Executor) hasMatchingHook(node *graph.Node) bool {
-	hooks := e.getHooksForType(node.Type)
-	for _, hook := range hooks {
-		if e.evaluateWhenCondition(hook.When(), node.Inputs) {
-			return true
-		}
-	}
-	return false
+	hook, _ := datacenter.MatchHook(e.getHooksForType(node.Type), func(hook datacenter.Hook) bool {
+		return e.evaluateWhenCondition(hook.When(), node)
+	})
+	return hook != nil
 }
 
-// evaluateWhenCondition evaluates a 'when' condition string against node inputs.
+// evaluateWhenCondition evaluates a 'when' condition string against a node.
 // It first attempts full HCL expression evaluation via the v1 Evaluator. If that
 // fails (e.g. due to an unparseable expression), it falls back to simplified
 // string-based matching for common patterns.
-func (e *Executor) evaluateWhenCondition(when string, inputs map[string]interface{}) bool {
+func (e *Executor) evaluateWhenCondition(when string, node *graph.Node) bool {
 	if when == "" {
 		return true // No condition means always match
 	}
 
 	// Try full HCL expression evaluation first
-	if result, err := e.evaluateWhenHCL(when, inputs); err == nil {
+	result, err := e.evaluateWhenHCL(when, node)
+	if err == nil {
 		return result
 	}
 
 	// Fall back to simplified string matching for patterns that can't be parsed as HCL
-	return e.evaluateWhenStringFallback(when, inputs)
+	e.warn(node, "when condition %q could not be evaluated, so it was matched as a string instead: %v", when, err)
+	return e.evaluateWhenStringFallback(when, node.Inputs)
 }
 
 // evaluateWhenHCL parses the when string as an HCL expression and evaluates it
 // with the full v1 Evaluator context (node inputs, environment, variables, etc.).
-func (e *Executor) evaluateWhenHCL(when string, inputs map[string]interface{}) (bool, error) {
+func (e *Executor) evaluateWhenHCL(when string, node *graph.Node) (bool, error) {
 	expr, diags := hclsyntax.ParseExpression([]byte(when), "when.hcl", hcl.Pos{Line: 1, Column: 1})
 	if diags.HasErrors() {
 		return false, fmt.Errorf("failed to parse when expression: %s", diags.Error())
 	}
 
+	return e.hookEvaluator(node).EvaluateWhen(expr)
+}
+
+// hookEvaluator returns an evaluator for a hook's when condition and error
+// message. Hooks can match on the node's identity (node.type, node.name,
+// node.component), its inputs (including instance context injected by
+// buildModuleInputs) and labels, the environment's name and labels, and
+// datacenter variables.
+func (e *Executor) hookEvaluator(node *graph.Node) *v1.Evaluator {
 	eval := v1.NewEvaluator()
+	if node == nil {
+		node = &graph.Node{}
+	}
+	eval.SetNodeContext(string(node.Type), node.Name, node.Component, node.Inputs)
 
-	// Set node context with inputs (including instance context injected by buildModuleInputs)
-	eval.SetNodeContext("", "", "", inputs)
+	if e.graph != nil {
+		eval.SetEnvironmentContext(e.graph.Environment, e.graph.Datacenter, "", "")
+	}
+	eval.SetEnvironmentLabels(e.options.EnvironmentLabels)
 
 	// Set datacenter variables if available
 	if e.options.DatacenterVariables != nil {
 		eval.SetVariables(e.options.DatacenterVariables)
 	}
-
-	return eval.EvaluateWhen(expr)
+	return eval
 }
 
 // evaluateWhenStringFallback provides legacy string-based matching for when conditions
@@ -1383,24 +1949,36 @@ func (e *Executor) evaluateWhenStringFallback(when string, inputs map[string]int
 	return true
 }
 
+// hookRejection returns the DatacenterHookError of an error hook that matched
+// the node, with the hook's code, docs link, and alternatives as remediation.
+func (e *Executor) hookRejection(hook datacenter.Hook, node *graph.Node) error {
+	var alternatives []string
+	for _, alt := range hook.ErrorAlternatives() {
+		alternatives = append(alternatives, e.evaluateErrorMessage(alt, node))
+	}
+	return arcerrors.DatacenterHookError(
+		string(node.Type),
+		node.Component,
+		node.Name,
+		e.evaluateErrorMessage(hook.Error(), node),
+	).WithRemediation(&arcerrors.Remediation{
+		Code:         hook.ErrorCode(),
+		DocsURL:      e.evaluateErrorMessage(hook.ErrorDocsURL(), node),
+		Alternatives: alternatives,
+	})
+}
+
 // evaluateErrorMessage evaluates a hook error message, resolving any HCL interpolations
-// like ${node.inputs.type} using the node's inputs. Falls back to the raw string
-// if HCL evaluation fails.
-func (e *Executor) evaluateErrorMessage(errorMsg string, inputs map[string]interface{}) string {
+// like ${node.inputs.type} or ${node.component} against the node. Falls back to
+// the raw string if HCL evaluation fails.
+func (e *Executor) evaluateErrorMessage(errorMsg string, node *graph.Node) string {
 	// Try full HCL template evaluation first
 	expr, diags := hclsyntax.ParseTemplate([]byte(errorMsg), "error.hcl", hcl.Pos{Line: 1, Column: 1})
 	if diags.HasErrors() {
 		return errorMsg // Return raw string if it can't be parsed as a template
 	}
 
-	eval := v1.NewEvaluator()
-	eval.SetNodeContext("", "", "", inputs)
-
-	if e.options.DatacenterVariables != nil {
-		eval.SetVariables(e.options.DatacenterVariables)
-	}
-
-	evaluated, err := eval.EvaluateErrorMessage(expr)
+	evaluated, err := e.hookEvaluator(node).EvaluateErrorMessage(expr)
 	if err != nil {
 		return errorMsg // Return raw string if evaluation fails
 	}
@@ -1619,8 +2197,8 @@ func (e *Executor) buildModuleInputs(module datacenter.Module, node *graph.Node,
 		inputs["name"] = standardName
 		inputs["database"] = node.Name
 		inputs["network"] = networkName
-		// Assign a port based on hash of the name (for local dev)
-		inputs["port"] = 5432 + hashCode(standardName)%100
+		// Assign a host port from the environment's port registry (for local dev)
+		inputs["port"] = e.allocatePort(node, 5432+hashCode(standardName)%100, 5432, 5432+portSearchWindow)
 		if v := extractVersionFromType(node.Inputs["type"]); v != "" {
 			inputs["version"] = v
 		}
@@ -1628,8 +2206,8 @@ func (e *Executor) buildModuleInputs(module datacenter.Module, node *graph.Node,
 	case "redis":
 		inputs["name"] = standardName
 		inputs["network"] = networkName
-		// Assign a port based on hash of the name (for local dev)
-		inputs["port"] = 6379 + hashCode(standardName)%100
+		// Assign a host port from the environment's port registry (for local dev)
+		inputs["port"] = e.allocatePort(node, 6379+hashCode(standardName)%100, 6379, 6379+portSearchWindow)
 		if v := extractVersionFromType(node.Inputs["type"]); v != "" {
 			inputs["version"] = v
 		}
@@ -1657,56 +2235,16 @@ func (e *Executor) buildModuleInputs(module datacenter.Module, node *graph.Node,
 
 		// Inject subdomain and path_prefix from environment route config or generate defaults.
 		// Priority: environment-level override > deterministic default.
-		subdomain := ""
-		pathPrefix := "/"
-		if e.options.ComponentRoutes != nil {
-			if compRoutes, ok := e.options.ComponentRoutes[node.Component]; ok {
-				if ro, ok := compRoutes[node.Name]; ok {
-					subdomain = ro.Subdomain
-					pathPrefix = ro.PathPrefix
-				}
-			}
-		}
-		if subdomain == "" {
-			subdomain = names.Generate(envName, node.Component, node.Name)
-		}
-		if pathPrefix == "" {
-			pathPrefix = "/"
-		}
+		subdomain, pathPrefix, _ := e.routeAddress(node, envName)
 		setIfMissing(inputs, "subdomain", subdomain)
 		setIfMissing(inputs, "path_prefix", pathPrefix)
+		setIfMissing(inputs, "hostnames", hostnameInputs(RouteHostnames(e.options.ComponentRoutes[node.Component][node.Name], subdomain, pathPrefix)))
 
 		// Resolve upstream port for the route's target service/function.
 		// This allows datacenter route hooks to know the upstream endpoint
 		// without needing to reference other node outputs.
-		if target, ok := node.Inputs["target"].(string); ok && target != "" {
-			var upstreamPort int
-			targetType, _ := node.Inputs["targetType"].(string)
-			switch targetType {
-			case "service":
-				// Look up the service node's declared port from the component schema.
-				// The service node may execute in parallel with this route, so its
-				// port input may still be an unresolved expression. Handle both
-				// resolved (int/float64) and unresolved (${{ }}) cases.
-				svcNodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeService, target)
-				if svcNode, ok := e.graph.Nodes[svcNodeID]; ok {
-					upstreamPort = toIntSafe(svcNode.Inputs["port"])
-					if upstreamPort == 0 {
-						if portStr, ok := svcNode.Inputs["port"].(string); ok && strings.Contains(portStr, "${{") {
-							upstreamPort = e.resolvePortFromExpression(portStr, node.Component)
-						}
-					}
-				}
-			case "function":
-				// Look up the function node and resolve its port (from schema or associated service)
-				fnNodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeFunction, target)
-				if fnNode, ok := e.graph.Nodes[fnNodeID]; ok {
-					upstreamPort = e.resolvePortForWorkload(fnNode)
-				}
-			}
-			if upstreamPort > 0 {
-				setIfMissing(inputs, "upstream_port", upstreamPort)
-			}
+		if upstreamPort := e.routeUpstreamPort(node); upstreamPort > 0 {
+			setIfMissing(inputs, "upstream_port", upstreamPort)
 		}
 
 	case "build":
@@ -1763,6 +2301,9 @@ func (e *Executor) buildModuleInputs(module datacenter.Module, node *graph.Node,
 		setIfMissing(inputs, "cpu", node.Inputs["cpu"])
 		setIfMissing(inputs, "memory", node.Inputs["memory"])
 		setIfMissing(inputs, "liveness_probe", node.Inputs["liveness_probe"])
+		setIfMissing(inputs, "strategy", node.Inputs["strategy"])
+		setIfMissing(inputs, "lifecycle", node.Inputs["lifecycle"])
+		setIfMissing(inputs, "files", node.Inputs["files"])
 
 		// Resolve port for readiness check (but do NOT inject PORT into environment --
 		// applications opt in via the ports resource and ${{ ports.<name>.port }})
@@ -2003,13 +2544,14 @@ func otelSetIfMissing(env map[string]string, key, value string) {
 }
 
 // debugDumpNodeConfig writes a human-readable dump of the node's resolved
-// configuration to stderr. This is only called when CLDCTL_DEBUG is set.
-func debugDumpNodeConfig(change *planner.ResourceChange) {
+// configuration to w. This is only called when the executor logs at debug
+// level.
+func debugDumpNodeConfig(w io.Writer, change *planner.ResourceChange) {
 	node := change.Node
-	fmt.Fprintf(os.Stderr, "\n[debug] ─── %s %s/%s/%s ───\n", change.Action, node.Component, node.Type, node.Name)
+	fmt.Fprintf(w, "\n[debug] ─── %s %s/%s/%s ───\n", change.Action, node.Component, node.Type, node.Name)
 
 	if len(node.Inputs) == 0 {
-		fmt.Fprintf(os.Stderr, "[debug]   (no inputs)\n")
+		fmt.Fprintf(w, "[debug]   (no inputs)\n")
 		return
 	}
 
@@ -2027,33 +2569,33 @@ func debugDumpNodeConfig(change *planner.ResourceChange) {
 			if len(val) == 0 {
 				continue
 			}
-			fmt.Fprintf(os.Stderr, "[debug]   %s:\n", k)
+			fmt.Fprintf(w, "[debug]   %s:\n", k)
 			subKeys := make([]string, 0, len(val))
 			for sk := range val {
 				subKeys = append(subKeys, sk)
 			}
 			sort.Strings(subKeys)
 			for _, sk := range subKeys {
-				fmt.Fprintf(os.Stderr, "[debug]     %s: %s\n", sk, val[sk])
+				fmt.Fprintf(w, "[debug]     %s: %s\n", sk, val[sk])
 			}
 		case map[string]interface{}:
 			if len(val) == 0 {
 				continue
 			}
-			fmt.Fprintf(os.Stderr, "[debug]   %s:\n", k)
+			fmt.Fprintf(w, "[debug]   %s:\n", k)
 			subKeys := make([]string, 0, len(val))
 			for sk := range val {
 				subKeys = append(subKeys, sk)
 			}
 			sort.Strings(subKeys)
 			for _, sk := range subKeys {
-				fmt.Fprintf(os.Stderr, "[debug]     %s: %v\n", sk, val[sk])
+				fmt.Fprintf(w, "[debug]     %s: %v\n", sk, val[sk])
 			}
 		default:
 			if v == nil {
 				continue
 			}
-			fmt.Fprintf(os.Stderr, "[debug]   %s: %v\n", k, v)
+			fmt.Fprintf(w, "[debug]   %s: %v\n", k, v)
 		}
 	}
 }
@@ -2101,7 +2643,9 @@ func sanitizeResourceName(name string) string {
 // executePortAllocation handles port node allocation with a three-tier priority:
 // 1. Environment override (from ComponentPorts)
 // 2. Datacenter port hook (if defined)
-// 3. Built-in deterministic hash-based fallback
+// 3. Built-in allocator (hash-based preferred port, skipping ports in use)
+//
+// Every allocated port is recorded in the environment's port registry.
 func (e *Executor) executePortAllocation(ctx context.Context, change *planner.ResourceChange, envState *types.EnvironmentState) *NodeResult {
 	result := &NodeResult{
 		NodeID: change.Node.ID,
@@ -2164,9 +2708,12 @@ func (e *Executor) executePortAllocation(ctx context.Context, change *planner.Re
 		}
 	}
 
-	// Priority 3: Built-in deterministic hash fallback
-	if !allocated {
-		allocatedPort = stablePortForNode(envState.Name, componentName, portName)
+	// Priority 3: Built-in allocator with conflict detection
+	if allocated {
+		e.reservePort(change.Node, allocatedPort)
+	} else {
+		preferred := stablePortForNode(envState.Name, componentName, portName)
+		allocatedPort = e.allocatePort(change.Node, preferred, dynamicPortMin, dynamicPortMax)
 	}
 
 	// Save to state
@@ -2203,6 +2750,80 @@ func (e *Executor) executePortAllocation(ctx context.Context, change *planner.Re
 	return result
 }
 
+// routeUpstreamPort resolves the port of a route's target service or function.
+// It is used for both route and routeAuth nodes, which share the same target.
+func (e *Executor) routeUpstreamPort(node *graph.Node) int {
+	target, ok := node.Inputs["target"].(string)
+	if !ok || target == "" {
+		return 0
+	}
+	var upstreamPort int
+	targetType, _ := node.Inputs["targetType"].(string)
+	switch targetType {
+	case "service":
+		// Look up the service node's declared port from the component schema.
+		// The service node may execute in parallel with this route, so its
+		// port input may still be an unresolved expression. Handle both
+		// resolved (int/float64) and unresolved (${{ }}) cases.
+		svcNodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeService, target)
+		if svcNode, ok := e.graph.Nodes[svcNodeID]; ok {
+			upstreamPort = toIntSafe(svcNode.Inputs["port"])
+			if upstreamPort == 0 {
+				if portStr, ok := svcNode.Inputs["port"].(string); ok && strings.Contains(portStr, "${{") {
+					upstreamPort = e.resolvePortFromExpression(portStr, node.Component)
+				}
+			}
+		}
+	case "function":
+		// Look up the function node and resolve its port (from schema or associated service)
+		fnNodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeFunction, target)
+		if fnNode, ok := e.graph.Nodes[fnNodeID]; ok {
+			upstreamPort = e.resolvePortForWorkload(fnNode)
+		}
+	}
+	return upstreamPort
+}
+
+// injectRouteAuthUpstream sets the upstream port on a routeAuth node so the
+// datacenter hook knows where the proxy forwards authenticated requests.
+func (e *Executor) injectRouteAuthUpstream(node *graph.Node) {
+	if e.graph == nil {
+		return
+	}
+	if upstreamPort := e.routeUpstreamPort(node); upstreamPort > 0 {
+		node.SetInput("upstreamPort", upstreamPort)
+	}
+}
+
+// injectRouteAuthOutputs copies the proxy endpoint of a route's routeAuth node
+// into the route's auth input, so the route hook sends traffic to the proxy
+// instead of the upstream. The routeAuth node is a dependency of the route, so
+// it has already completed.
+func (e *Executor) injectRouteAuthOutputs(node *graph.Node) {
+	if e.graph == nil {
+		return
+	}
+	auth, ok := node.Inputs["auth"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	authNodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeRouteAuth, node.Name)
+	authNode := e.graph.GetNode(authNodeID)
+	if authNode == nil || authNode.Outputs == nil {
+		return
+	}
+	merged := make(map[string]interface{}, len(auth)+3)
+	for k, v := range auth {
+		merged[k] = v
+	}
+	for _, key := range []string{"url", "host", "port"} {
+		if v, ok := authNode.Outputs[key]; ok {
+			merged[key] = v
+		}
+	}
+	node.SetInput("auth", merged)
+}
+
 // injectParentDatabaseOutputs copies the parent database node's host and port
 // outputs into the databaseUser node's inputs. This bridges the gap between the
 // database (which knows its actual allocated port) and the databaseUser hook
@@ -2327,7 +2948,7 @@ func stablePortForNode(envName, componentName, portName string) int {
 	key := fmt.Sprintf("%s/%s/%s", envName, componentName, portName)
 	h := hashCode(key)
 	// Map into range [10000, 60000) -- avoids privileged ports and common dev ports
-	return 10000 + (h % 50000)
+	return dynamicPortMin + (h % (dynamicPortMax - dynamicPortMin))
 }
 
 func hashCode(s string) int {
@@ -2346,6 +2967,7 @@ func hashCode(s string) int {
 //   - ${{ builds.api.image }}      → dockerBuild node output
 //   - ${{ databases.main.url }}    → database node output
 //   - ${{ services.api.host }}     → service node output
+//   - ${{ external.stripe.api_key }} → external service node output
 //   - ${{ observability.endpoint }} → observability node output
 //
 // Also recurses into nested maps (e.g., environment map) to resolve expressions there.
@@ -2354,12 +2976,68 @@ func (e *Executor) resolveComponentExpressions(node *graph.Node, envState *types
 	if e.graph == nil {
 		return
 	}
+	resolveStr := e.expressionResolver(node, envState)
+
+	for key, value := range node.Inputs {
+		switch v := value.(type) {
+		case string:
+			node.Inputs[key] = resolveStr(v)
+		case map[string]string:
+			resolved := make(map[string]string, len(v))
+			for k, val := range v {
+				resolved[k] = resolveStr(val)
+			}
+			node.Inputs[key] = resolved
+		case map[string]interface{}:
+			resolved := make(map[string]interface{}, len(v))
+			for k, val := range v {
+				if s, ok := val.(string); ok {
+					resolved[k] = resolveStr(s)
+				} else {
+					resolved[k] = val
+				}
+			}
+			node.Inputs[key] = resolved
+		case []interface{}:
+			// Lists of maps, such as a deployment's files
+			resolved := make([]interface{}, len(v))
+			for i, item := range v {
+				m, ok := item.(map[string]interface{})
+				if !ok {
+					resolved[i] = item
+					continue
+				}
+				entry := make(map[string]interface{}, len(m))
+				for k, val := range m {
+					if s, ok := val.(string); ok {
+						entry[k] = resolveStr(s)
+					} else {
+						entry[k] = val
+					}
+				}
+				resolved[i] = entry
+			}
+			node.Inputs[key] = resolved
+		}
+	}
+}
 
+// expressionResolver returns a function that resolves the ${{ }} expressions
+// in a string from the outputs of the graph's nodes, as seen from node.
+// Expressions that can't be resolved resolve to "".
+func (e *Executor) expressionResolver(node *graph.Node, envState *types.EnvironmentState) func(string) string {
 	// Resolve component variables from executor options
-	compVars := make(map[string]interface{})
-	if e.options.ComponentVariables != nil {
-		if vars, ok := e.options.ComponentVariables[node.Component]; ok {
-			compVars = vars
+	compVars := e.componentVariables(node.Component)
+	if node.Instance != nil {
+		if overrides := e.options.InstanceVariables[node.Component][node.Instance.Name]; len(overrides) > 0 {
+			merged := make(map[string]interface{}, len(compVars)+len(overrides))
+			for k, v := range compVars {
+				merged[k] = v
+			}
+			for k, v := range overrides {
+				merged[k] = v
+			}
+			compVars = merged
 		}
 	}
 
@@ -2374,374 +3052,387 @@ func (e *Executor) resolveComponentExpressions(node *graph.Node, envState *types
 				continue
 			}
 			funcName := fields[0]
-			switch funcName {
-			case "default":
-				if len(fields) >= 2 && value == "" {
-					value = strings.Trim(fields[1], `"'`)
-				}
-			}
-		}
-		return value
-	}
-
-	resolveStr := func(strVal string) string {
-		if !strings.Contains(strVal, "${{") {
-			return strVal
-		}
-		return exprPattern.ReplaceAllStringFunc(strVal, func(match string) string {
-			inner := match[3 : len(match)-2]
-			inner = strings.TrimSpace(inner)
-
-			// Split pipe functions from the reference path.
-			// E.g., "smtp.email.username | default 'unused'" → ref="smtp.email.username", pipes=["default 'unused'"]
-			pipeParts := strings.Split(inner, "|")
-			refStr := strings.TrimSpace(pipeParts[0])
-			var pipeFuncs []string
-			for _, p := range pipeParts[1:] {
-				pipeFuncs = append(pipeFuncs, strings.TrimSpace(p))
+			switch funcName {
+			case "default":
+				if len(fields) >= 2 && value == "" {
+					value = strings.Trim(fields[1], `"'`)
+				}
 			}
+		}
+		return value
+	}
 
-			parts := strings.Split(refStr, ".")
+	// resolveRef resolves a single reference such as "databases.main.url".
+	// References that can't be resolved resolve to "".
+	resolveRef := func(refStr string) string {
+		parts := strings.Split(refStr, ".")
 
-			if len(parts) < 2 {
-				return match // Malformed expression — preserve as-is
+		// debugUnresolved emits a debug-level warning when an expression cannot
+		// be resolved. The expression resolves to "" so applications receive an
+		// empty string instead of a literal "${{ ... }}" value.
+		debugUnresolved := func(reason string) string {
+			expressionsLog.Debug("unresolved expression", "expression", refStr, "node", node.ID, "reason", reason)
+			e.warn(node, "expression ${{ %s }} resolved to an empty string: %s", refStr, reason)
+			return ""
+		}
+
+		if len(parts) < 2 {
+			return debugUnresolved("malformed expression")
+		}
+
+		resourceType := parts[0]
+		switch resourceType {
+		case "builds":
+			if len(parts) < 3 {
+				return debugUnresolved("malformed builds expression (expected builds.<name>.<output>)")
+			}
+			buildNodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeDockerBuild, parts[1])
+			buildNode, ok := e.graph.Nodes[buildNodeID]
+			if !ok || buildNode.Outputs == nil {
+				return debugUnresolved(fmt.Sprintf("build %q not found or has no outputs", parts[1]))
+			}
+			if val, ok := buildNode.Outputs[parts[2]]; ok {
+				return fmt.Sprintf("%v", val)
 			}
+			return debugUnresolved(fmt.Sprintf("build %q has no output %q", parts[1], parts[2]))
 
-			// debugUnresolved emits a debug-level warning when an expression cannot
-			// be resolved. The expression resolves to "" so applications receive an
-			// empty string instead of a literal "${{ ... }}" value.
-			debugUnresolved := func(reason string) string {
-				if os.Getenv("CLDCTL_DEBUG") != "" {
-					fmt.Fprintf(os.Stderr, "[debug] unresolved expression %s in %s/%s: %s\n",
-						refStr, node.Type, node.Name, reason)
+		case "databases":
+			if len(parts) < 3 {
+				return debugUnresolved("malformed databases expression (expected databases.<name>.<output>)")
+			}
+			dbName := parts[1]
+
+			// Resolve the parent database node (always needed as a fallback source).
+			dbNodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeDatabase, dbName)
+			dbNode, dbOK := e.graph.Nodes[dbNodeID]
+
+			// If the datacenter defines a databaseUser hook, resolve through the
+			// interposed databaseUser node first for per-consumer credentials.
+			dbUserNodeID := fmt.Sprintf("%s/%s/%s--%s", node.Component, graph.NodeTypeDatabaseUser, dbName, node.Name)
+			depNode, ok := e.graph.Nodes[dbUserNodeID]
+			if !ok || depNode == nil || depNode.Outputs == nil {
+				// No databaseUser node — resolve directly from the database node
+				if !dbOK || dbNode.Outputs == nil {
+					return debugUnresolved(fmt.Sprintf("database %q not found or has no outputs", dbName))
 				}
-				return ""
+				depNode = dbNode
 			}
-
-			// Resolve the reference, then apply any pipe functions.
-			resolved := func() string {
-
-				resourceType := parts[0]
-				switch resourceType {
-				case "builds":
-					if len(parts) < 3 {
-						return debugUnresolved("malformed builds expression (expected builds.<name>.<output>)")
-					}
-					buildNodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeDockerBuild, parts[1])
-					buildNode, ok := e.graph.Nodes[buildNodeID]
-					if !ok || buildNode.Outputs == nil {
-						return debugUnresolved(fmt.Sprintf("build %q not found or has no outputs", parts[1]))
-					}
-					if val, ok := buildNode.Outputs[parts[2]]; ok {
-						return fmt.Sprintf("%v", val)
-					}
-					return debugUnresolved(fmt.Sprintf("build %q has no output %q", parts[1], parts[2]))
-
-				case "databases":
-					if len(parts) < 3 {
-						return debugUnresolved("malformed databases expression (expected databases.<name>.<output>)")
-					}
-					dbName := parts[1]
-
-					// Resolve the parent database node (always needed as a fallback source).
-					dbNodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeDatabase, dbName)
-					dbNode, dbOK := e.graph.Nodes[dbNodeID]
-
-					// If the datacenter defines a databaseUser hook, resolve through the
-					// interposed databaseUser node first for per-consumer credentials.
-					dbUserNodeID := fmt.Sprintf("%s/%s/%s--%s", node.Component, graph.NodeTypeDatabaseUser, dbName, node.Name)
-					depNode, ok := e.graph.Nodes[dbUserNodeID]
-					if !ok || depNode == nil || depNode.Outputs == nil {
-						// No databaseUser node — resolve directly from the database node
-						if !dbOK || dbNode.Outputs == nil {
-							return debugUnresolved(fmt.Sprintf("database %q not found or has no outputs", dbName))
-						}
-						depNode = dbNode
-					}
-					// Handle read/write sub-objects: databases.<name>.read.<prop> / databases.<name>.write.<prop>
-					if (parts[2] == "read" || parts[2] == "write") && len(parts) >= 4 {
-						if nested, ok := depNode.Outputs[parts[2]]; ok {
-							if nestedMap, ok := nested.(map[string]interface{}); ok {
-								if val, ok := nestedMap[parts[3]]; ok {
-									return fmt.Sprintf("%v", val)
-								}
-							}
-						}
-						// Fallback to top-level output when read/write is not explicitly set
-						if val, ok := depNode.Outputs[parts[3]]; ok {
+			// Handle read/write sub-objects: databases.<name>.read.<prop> / databases.<name>.write.<prop>
+			if (parts[2] == "read" || parts[2] == "write") && len(parts) >= 4 {
+				if nested, ok := depNode.Outputs[parts[2]]; ok {
+					if nestedMap, ok := nested.(map[string]interface{}); ok {
+						if val, ok := nestedMap[parts[3]]; ok {
 							return fmt.Sprintf("%v", val)
 						}
-						// Per-field fallback: if the databaseUser node doesn't have this field,
-						// try the parent database node (e.g., host/port come from the database).
-						if depNode.Type == graph.NodeTypeDatabaseUser && dbOK && dbNode.Outputs != nil {
-							if nested, ok := dbNode.Outputs[parts[2]]; ok {
-								if nestedMap, ok := nested.(map[string]interface{}); ok {
-									if val, ok := nestedMap[parts[3]]; ok {
-										return fmt.Sprintf("%v", val)
-									}
-								}
-							}
-							if val, ok := dbNode.Outputs[parts[3]]; ok {
+					}
+				}
+				// Fallback to top-level output when read/write is not explicitly set
+				if val, ok := depNode.Outputs[parts[3]]; ok {
+					return fmt.Sprintf("%v", val)
+				}
+				// Per-field fallback: if the databaseUser node doesn't have this field,
+				// try the parent database node (e.g., host/port come from the database).
+				if depNode.Type == graph.NodeTypeDatabaseUser && dbOK && dbNode.Outputs != nil {
+					if nested, ok := dbNode.Outputs[parts[2]]; ok {
+						if nestedMap, ok := nested.(map[string]interface{}); ok {
+							if val, ok := nestedMap[parts[3]]; ok {
 								return fmt.Sprintf("%v", val)
 							}
 						}
-						return debugUnresolved(fmt.Sprintf("database %q has no output %s.%s", dbName, parts[2], parts[3]))
 					}
-					if val, ok := depNode.Outputs[parts[2]]; ok {
+					if val, ok := dbNode.Outputs[parts[3]]; ok {
 						return fmt.Sprintf("%v", val)
 					}
-					// Per-field fallback: if the databaseUser node doesn't have this field,
-					// try the parent database node (e.g., host/port come from the database
-					// while username/password/url come from the databaseUser hook).
-					if depNode.Type == graph.NodeTypeDatabaseUser && dbOK && dbNode.Outputs != nil {
-						if val, ok := dbNode.Outputs[parts[2]]; ok {
-							return fmt.Sprintf("%v", val)
-						}
-					}
-					return debugUnresolved(fmt.Sprintf("database %q has no output %q", dbName, parts[2]))
+				}
+				return debugUnresolved(fmt.Sprintf("database %q has no output %s.%s", dbName, parts[2], parts[3]))
+			}
+			if val, ok := depNode.Outputs[parts[2]]; ok {
+				return fmt.Sprintf("%v", val)
+			}
+			// Per-field fallback: if the databaseUser node doesn't have this field,
+			// try the parent database node (e.g., host/port come from the database
+			// while username/password/url come from the databaseUser hook).
+			if depNode.Type == graph.NodeTypeDatabaseUser && dbOK && dbNode.Outputs != nil {
+				if val, ok := dbNode.Outputs[parts[2]]; ok {
+					return fmt.Sprintf("%v", val)
+				}
+			}
+			return debugUnresolved(fmt.Sprintf("database %q has no output %q", dbName, parts[2]))
 
-				case "services":
-					if len(parts) < 3 {
-						return debugUnresolved("malformed services expression (expected services.<name>.<output>)")
-					}
-					nodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeService, parts[1])
-					depNode, ok := e.graph.Nodes[nodeID]
-					if !ok || depNode.Outputs == nil {
-						return debugUnresolved(fmt.Sprintf("service %q not found or has no outputs", parts[1]))
-					}
-					if val, ok := depNode.Outputs[parts[2]]; ok {
-						return fmt.Sprintf("%v", val)
-					}
-					return debugUnresolved(fmt.Sprintf("service %q has no output %q", parts[1], parts[2]))
+		case "services":
+			if len(parts) < 3 {
+				return debugUnresolved("malformed services expression (expected services.<name>.<output>)")
+			}
+			nodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeService, parts[1])
+			depNode, ok := e.graph.Nodes[nodeID]
+			if !ok || depNode.Outputs == nil {
+				return debugUnresolved(fmt.Sprintf("service %q not found or has no outputs", parts[1]))
+			}
+			if val, ok := depNode.Outputs[parts[2]]; ok {
+				return fmt.Sprintf("%v", val)
+			}
+			return debugUnresolved(fmt.Sprintf("service %q has no output %q", parts[1], parts[2]))
 
-				case "buckets":
-					if len(parts) < 3 {
-						return debugUnresolved("malformed buckets expression (expected buckets.<name>.<output>)")
-					}
-					nodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeBucket, parts[1])
-					depNode, ok := e.graph.Nodes[nodeID]
-					if !ok || depNode.Outputs == nil {
-						return debugUnresolved(fmt.Sprintf("bucket %q not found or has no outputs", parts[1]))
-					}
-					if val, ok := depNode.Outputs[parts[2]]; ok {
-						return fmt.Sprintf("%v", val)
-					}
-					return debugUnresolved(fmt.Sprintf("bucket %q has no output %q", parts[1], parts[2]))
+		case "buckets":
+			if len(parts) < 3 {
+				return debugUnresolved("malformed buckets expression (expected buckets.<name>.<output>)")
+			}
+			nodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeBucket, parts[1])
+			depNode, ok := e.graph.Nodes[nodeID]
+			if !ok || depNode.Outputs == nil {
+				return debugUnresolved(fmt.Sprintf("bucket %q not found or has no outputs", parts[1]))
+			}
+			if val, ok := depNode.Outputs[parts[2]]; ok {
+				return fmt.Sprintf("%v", val)
+			}
+			return debugUnresolved(fmt.Sprintf("bucket %q has no output %q", parts[1], parts[2]))
 
-				case "routes":
-					if len(parts) < 3 {
-						return debugUnresolved("malformed routes expression (expected routes.<name>.<output>)")
-					}
-					nodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeRoute, parts[1])
-					depNode, ok := e.graph.Nodes[nodeID]
-					if !ok || depNode.Outputs == nil {
-						return debugUnresolved(fmt.Sprintf("route %q not found or has no outputs", parts[1]))
-					}
-					if val, ok := depNode.Outputs[parts[2]]; ok {
-						return fmt.Sprintf("%v", val)
-					}
-					return debugUnresolved(fmt.Sprintf("route %q has no output %q", parts[1], parts[2]))
+		case "routes":
+			if len(parts) < 3 {
+				return debugUnresolved("malformed routes expression (expected routes.<name>.<output>)")
+			}
+			nodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeRoute, parts[1])
+			depNode, ok := e.graph.Nodes[nodeID]
+			if !ok || depNode.Outputs == nil {
+				return debugUnresolved(fmt.Sprintf("route %q not found or has no outputs", parts[1]))
+			}
+			if val, ok := depNode.Outputs[parts[2]]; ok {
+				return fmt.Sprintf("%v", val)
+			}
+			return debugUnresolved(fmt.Sprintf("route %q has no output %q", parts[1], parts[2]))
 
-				case "ports":
-					if len(parts) < 3 {
-						return debugUnresolved("malformed ports expression (expected ports.<name>.<output>)")
-					}
-					nodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypePort, parts[1])
-					depNode, ok := e.graph.Nodes[nodeID]
-					if !ok || depNode.Outputs == nil {
-						return debugUnresolved(fmt.Sprintf("port %q not found or has no outputs", parts[1]))
-					}
-					if val, ok := depNode.Outputs[parts[2]]; ok {
-						return fmt.Sprintf("%v", val)
-					}
-					return debugUnresolved(fmt.Sprintf("port %q has no output %q", parts[1], parts[2]))
-
-				case "observability":
-					// observability is a singleton per component
-					obsNodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeObservability, "observability")
-					obsNode, ok := e.graph.Nodes[obsNodeID]
-					if !ok || obsNode.Outputs == nil {
-						return "" // No observability hook — resolve silently
-					}
-					prop := parts[1]
-					if val, ok := obsNode.Outputs[prop]; ok {
-						return fmt.Sprintf("%v", val)
-					}
-					return "" // Unknown observability property — resolve silently
+		case "ports":
+			if len(parts) < 3 {
+				return debugUnresolved("malformed ports expression (expected ports.<name>.<output>)")
+			}
+			nodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypePort, parts[1])
+			depNode, ok := e.graph.Nodes[nodeID]
+			if !ok || depNode.Outputs == nil {
+				return debugUnresolved(fmt.Sprintf("port %q not found or has no outputs", parts[1]))
+			}
+			if val, ok := depNode.Outputs[parts[2]]; ok {
+				return fmt.Sprintf("%v", val)
+			}
+			return debugUnresolved(fmt.Sprintf("port %q has no output %q", parts[1], parts[2]))
 
-				case "variables":
-					// Resolve from component deployment variables
-					if len(parts) < 2 {
-						return debugUnresolved("malformed variables expression (expected variables.<name>)")
-					}
-					varName := parts[1]
-					if val, ok := compVars[varName]; ok {
-						return fmt.Sprintf("%v", val)
-					}
-					// Fallback: check if stored as variables_<name> in node inputs
-					if val, ok := node.Inputs["variables_"+varName]; ok {
-						return fmt.Sprintf("%v", val)
-					}
-					return debugUnresolved(fmt.Sprintf("variable %q not provided", varName))
+		case "platform":
+			envName := ""
+			if envState != nil {
+				envName = envState.Name
+			}
+			if val, ok := e.platformExport(parts[1], envName); ok {
+				return fmt.Sprintf("%v", val)
+			}
+			return debugUnresolved(fmt.Sprintf("platform export %q not exported by the datacenter or not provisioned", parts[1]))
+
+		case "observability":
+			// observability is a singleton per component
+			obsNodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeObservability, "observability")
+			obsNode, ok := e.graph.Nodes[obsNodeID]
+			if !ok || obsNode.Outputs == nil {
+				return "" // No observability hook — resolve silently
+			}
+			prop := parts[1]
+			if val, ok := obsNode.Outputs[prop]; ok {
+				return fmt.Sprintf("%v", val)
+			}
+			return "" // Unknown observability property — resolve silently
 
-				case "encryptionKeys":
-					if len(parts) < 3 {
-						return debugUnresolved("malformed encryptionKeys expression (expected encryptionKeys.<name>.<output>)")
-					}
-					nodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeEncryptionKey, parts[1])
-					depNode, ok := e.graph.Nodes[nodeID]
-					if !ok || depNode.Outputs == nil {
-						return debugUnresolved(fmt.Sprintf("encryptionKey %q not found or has no outputs", parts[1]))
-					}
-					if val, ok := depNode.Outputs[parts[2]]; ok {
-						return fmt.Sprintf("%v", val)
-					}
-					return debugUnresolved(fmt.Sprintf("encryptionKey %q has no output %q", parts[1], parts[2]))
+		case "variables":
+			// Resolve from component deployment variables
+			if len(parts) < 2 {
+				return debugUnresolved("malformed variables expression (expected variables.<name>)")
+			}
+			varName := parts[1]
+			if val, ok := compVars[varName]; ok {
+				return fmt.Sprintf("%v", val)
+			}
+			// Fallback: check if stored as variables_<name> in node inputs
+			if val, ok := node.Inputs["variables_"+varName]; ok {
+				return fmt.Sprintf("%v", val)
+			}
+			return debugUnresolved(fmt.Sprintf("variable %q not provided", varName))
 
-				case "smtp":
-					if len(parts) < 3 {
-						return debugUnresolved("malformed smtp expression (expected smtp.<name>.<output>)")
-					}
-					nodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeSMTP, parts[1])
-					depNode, ok := e.graph.Nodes[nodeID]
-					if !ok || depNode.Outputs == nil {
-						return debugUnresolved(fmt.Sprintf("smtp %q not found or has no outputs", parts[1]))
-					}
-					if val, ok := depNode.Outputs[parts[2]]; ok {
-						return fmt.Sprintf("%v", val)
-					}
-					return debugUnresolved(fmt.Sprintf("smtp %q has no output %q", parts[1], parts[2]))
-
-				case "dependencies":
-					// Resolve cross-component dependency outputs.
-					// Format: dependencies.<depAlias>.outputs.<outputKey>
-					// or:     dependencies.<depAlias>.<outputKey>
-					if len(parts) < 3 {
-						return debugUnresolved("malformed dependencies expression (expected dependencies.<name>.outputs.<key>)")
-					}
-					depAlias := parts[1]
-
-					// Resolve the dependency alias to the actual component name.
-					// E.g., "clerk" → "questra/clerk" via DependencyTargets.
-					targetComp := depAlias
-					if e.graph.DependencyTargets != nil {
-						if targets, ok := e.graph.DependencyTargets[node.Component]; ok {
-							if tc, ok := targets[depAlias]; ok {
-								targetComp = tc
-							}
-						}
-					}
+		case "encryptionKeys":
+			if len(parts) < 3 {
+				return debugUnresolved("malformed encryptionKeys expression (expected encryptionKeys.<name>.<output>)")
+			}
+			nodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeEncryptionKey, parts[1])
+			depNode, ok := e.graph.Nodes[nodeID]
+			if !ok || depNode.Outputs == nil {
+				return debugUnresolved(fmt.Sprintf("encryptionKey %q not found or has no outputs", parts[1]))
+			}
+			if val, ok := depNode.Outputs[parts[2]]; ok {
+				return fmt.Sprintf("%v", val)
+			}
+			return debugUnresolved(fmt.Sprintf("encryptionKey %q has no output %q", parts[1], parts[2]))
 
-					// Determine the output key (handle both with and without "outputs" segment)
-					var outputKey string
-					if len(parts) >= 4 && parts[2] == "outputs" {
-						outputKey = parts[3]
-					} else {
-						outputKey = parts[2]
+		case "smtp":
+			if len(parts) < 3 {
+				return debugUnresolved("malformed smtp expression (expected smtp.<name>.<output>)")
+			}
+			nodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeSMTP, parts[1])
+			depNode, ok := e.graph.Nodes[nodeID]
+			if !ok || depNode.Outputs == nil {
+				return debugUnresolved(fmt.Sprintf("smtp %q not found or has no outputs", parts[1]))
+			}
+			if val, ok := depNode.Outputs[parts[2]]; ok {
+				return fmt.Sprintf("%v", val)
+			}
+			return debugUnresolved(fmt.Sprintf("smtp %q has no output %q", parts[1], parts[2]))
+
+		case "external":
+			if len(parts) < 3 {
+				return debugUnresolved("malformed external expression (expected external.<name>.<output>)")
+			}
+			nodeID := fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeExternal, parts[1])
+			depNode, ok := e.graph.Nodes[nodeID]
+			if !ok || depNode.Outputs == nil {
+				return debugUnresolved(fmt.Sprintf("external service %q not found or has no outputs", parts[1]))
+			}
+			if val, ok := depNode.Outputs[parts[2]]; ok {
+				return fmt.Sprintf("%v", val)
+			}
+			return debugUnresolved(fmt.Sprintf("external service %q has no output %q", parts[1], parts[2]))
+
+		case "dependencies":
+			// Resolve cross-component dependency outputs.
+			// Format: dependencies.<depAlias>.outputs.<outputKey>
+			// or:     dependencies.<depAlias>.<outputKey>
+			if len(parts) < 3 {
+				return debugUnresolved("malformed dependencies expression (expected dependencies.<name>.outputs.<key>)")
+			}
+			depAlias := parts[1]
+
+			// Resolve the dependency alias to the actual component name.
+			// E.g., "clerk" → "questra/clerk" via DependencyTargets.
+			targetComp := depAlias
+			if e.graph.DependencyTargets != nil {
+				if targets, ok := e.graph.DependencyTargets[node.Component]; ok {
+					if tc, ok := targets[depAlias]; ok {
+						targetComp = tc
 					}
+				}
+			}
 
-					// Try 1: look up component-level outputs from the graph
-					// (for pass-through components with outputs but no resources,
-					// resolved during the current session)
-					if e.graph.ComponentOutputExprs != nil {
-						if outExprs, ok := e.graph.ComponentOutputExprs[targetComp]; ok {
-							if exprStr, ok := outExprs[outputKey]; ok {
-								// Resolve the output expression inline using the
-								// dependency component's variables
-								depVars := make(map[string]interface{})
-								if e.options.ComponentVariables != nil {
-									if vars, ok := e.options.ComponentVariables[targetComp]; ok {
-										depVars = vars
-									}
+			// Determine the output key (handle both with and without "outputs" segment)
+			var outputKey string
+			if len(parts) >= 4 && parts[2] == "outputs" {
+				outputKey = parts[3]
+			} else {
+				outputKey = parts[2]
+			}
+
+			// Try 1: look up component-level outputs from the graph
+			// (for pass-through components with outputs but no resources,
+			// resolved during the current session)
+			if e.graph.ComponentOutputExprs != nil {
+				if outExprs, ok := e.graph.ComponentOutputExprs[targetComp]; ok {
+					if exprStr, ok := outExprs[outputKey]; ok {
+						// Resolve the output expression inline using the
+						// dependency component's variables
+						depVars := e.componentVariables(targetComp)
+						resolved := exprPattern.ReplaceAllStringFunc(exprStr, func(m string) string {
+							innerM := m[3 : len(m)-2]
+							innerM = strings.TrimSpace(innerM)
+							mParts := strings.Split(innerM, ".")
+							if len(mParts) >= 2 && mParts[0] == "variables" {
+								if v, ok := depVars[mParts[1]]; ok {
+									return fmt.Sprintf("%v", v)
 								}
-								resolved := exprPattern.ReplaceAllStringFunc(exprStr, func(m string) string {
-									innerM := m[3 : len(m)-2]
-									innerM = strings.TrimSpace(innerM)
-									mParts := strings.Split(innerM, ".")
-									if len(mParts) >= 2 && mParts[0] == "variables" {
-										if v, ok := depVars[mParts[1]]; ok {
-											return fmt.Sprintf("%v", v)
-										}
-									}
-									return ""
-								})
-								return resolved
 							}
-						}
+							return ""
+						})
+						return resolved
 					}
+				}
+			}
 
-					// Try 2: look up component-level outputs from environment state
-					// (for components deployed in a previous session)
-					if envState != nil {
-						if depComp, ok := envState.Components[targetComp]; ok {
-							// Check component-level outputs first
-							if depComp.Outputs != nil {
-								if val, ok := depComp.Outputs[outputKey]; ok {
-									return fmt.Sprintf("%v", val)
-								}
-							}
-							// Fall back to resource-level outputs
-							for _, res := range depComp.Resources {
-								if res.Outputs != nil {
-									if val, ok := res.Outputs[outputKey]; ok {
-										return fmt.Sprintf("%v", val)
-									}
-								}
-							}
+			// Try 2: look up component-level outputs from environment state
+			// (for components deployed in a previous session)
+			if envState != nil {
+				if depComp, ok := envState.Components[targetComp]; ok {
+					// Check component-level outputs first
+					if depComp.Outputs != nil {
+						if val, ok := depComp.Outputs[outputKey]; ok {
+							return fmt.Sprintf("%v", val)
 						}
 					}
-
-					// Try 3: look up resource-level outputs from graph nodes
-					// (for components deployed in the same session with resources)
-					for _, graphNode := range e.graph.Nodes {
-						if graphNode.Component == targetComp && graphNode.Outputs != nil {
-							if val, ok := graphNode.Outputs[outputKey]; ok {
+					// Fall back to resource-level outputs
+					for _, res := range depComp.Resources {
+						if res.Outputs != nil {
+							if val, ok := res.Outputs[outputKey]; ok {
 								return fmt.Sprintf("%v", val)
 							}
 						}
 					}
+				}
+			}
 
-					// Dependency not found or doesn't expose this output key.
-					// For optional dependencies this is expected — resolve silently to "".
-					// For required dependencies emit a debug warning.
-					isOptional := e.graph.OptionalDependencies != nil &&
-						e.graph.OptionalDependencies[node.Component] != nil &&
-						e.graph.OptionalDependencies[node.Component][depAlias]
-					if isOptional {
-						return "" // Silently resolve optional dep to empty string
+			// Try 3: look up resource-level outputs from graph nodes
+			// (for components deployed in the same session with resources)
+			for _, graphNode := range e.graph.Nodes {
+				if graphNode.Component == targetComp && graphNode.Outputs != nil {
+					if val, ok := graphNode.Outputs[outputKey]; ok {
+						return fmt.Sprintf("%v", val)
 					}
-					return debugUnresolved(fmt.Sprintf("dependency %q (component %q) output %q not available", depAlias, targetComp, outputKey))
-
-				default:
-					return debugUnresolved(fmt.Sprintf("unknown expression prefix %q", resourceType))
 				}
+			}
 
-			}() // end resolved func
-			return applyPipeFuncs(resolved, pipeFuncs)
-		})
+			// Dependency not found or doesn't expose this output key.
+			// For optional dependencies this is expected — resolve silently to "".
+			// For required dependencies emit a debug warning.
+			isOptional := e.graph.OptionalDependencies != nil &&
+				e.graph.OptionalDependencies[node.Component] != nil &&
+				e.graph.OptionalDependencies[node.Component][depAlias]
+			if isOptional {
+				// Expected, but the resource still gets an empty value
+				e.warn(node, "optional dependency %q doesn't provide output %q, so ${{ %s }} resolved to an empty string", depAlias, outputKey, refStr)
+				return ""
+			}
+			return debugUnresolved(fmt.Sprintf("dependency %q (component %q) output %q not available", depAlias, targetComp, outputKey))
+
+		default:
+			return debugUnresolved(fmt.Sprintf("unknown expression prefix %q", resourceType))
+		}
 	}
 
-	for key, value := range node.Inputs {
-		switch v := value.(type) {
-		case string:
-			node.Inputs[key] = resolveStr(v)
-		case map[string]string:
-			resolved := make(map[string]string, len(v))
-			for k, val := range v {
-				resolved[k] = resolveStr(val)
+	return func(strVal string) string {
+		if !strings.Contains(strVal, "${{") {
+			return strVal
+		}
+		return exprPattern.ReplaceAllStringFunc(strVal, func(match string) string {
+			inner := match[3 : len(match)-2]
+			inner = strings.TrimSpace(inner)
+
+			// Split pipe functions from the reference path.
+			// E.g., "smtp.email.username | default 'unused'" → ref="smtp.email.username", pipes=["default 'unused'"]
+			pipeParts := strings.Split(inner, "|")
+			refStr := strings.TrimSpace(pipeParts[0])
+			var pipeFuncs []string
+			for _, p := range pipeParts[1:] {
+				pipeFuncs = append(pipeFuncs, strings.TrimSpace(p))
 			}
-			node.Inputs[key] = resolved
-		case map[string]interface{}:
-			resolved := make(map[string]interface{}, len(v))
-			for k, val := range v {
-				if s, ok := val.(string); ok {
-					resolved[k] = resolveStr(s)
-				} else {
-					resolved[k] = val
+
+			// Computed expressions (e.g. "ports.http.port + 1") resolve each
+			// reference they read and are then evaluated.
+			if expression.IsComputed(refStr) {
+				value, err := expression.Compute(refStr, resolveRef)
+				if err != nil {
+					expressionsLog.Debug("unresolved expression", "expression", refStr, "node", node.ID, "reason", err)
+					e.warn(node, "expression ${{ %s }} resolved to an empty string: %v", refStr, err)
+					value = ""
 				}
+				return applyPipeFuncs(value, pipeFuncs)
 			}
-			node.Inputs[key] = resolved
-		}
+
+			if len(strings.Split(refStr, ".")) < 2 {
+				return match // Malformed expression — preserve as-is
+			}
+
+			// Resolve the reference, then apply any pipe functions.
+			return applyPipeFuncs(resolveRef(refStr), pipeFuncs)
+		})
 	}
 }
 
@@ -2920,8 +3611,13 @@ func (e *Executor) evaluateInputExpression(expr string, node *graph.Node, envNam
 	// Handle string interpolation ${...}
 	if strings.Contains(expr, "${") {
 		result := expr
-		// Replace ${environment.name}
+		// Replace ${environment.name}, ${environment.labels.*}, and
+		// ${environment.module.*}
 		result = strings.ReplaceAll(result, "${environment.name}", envName)
+		for k, v := range e.options.EnvironmentLabels {
+			result = strings.ReplaceAll(result, "${environment.labels."+k+"}", v)
+		}
+		result = e.interpolateEnvironmentModules(result)
 		// Replace ${node.name}
 		result = strings.ReplaceAll(result, "${node.name}", node.Name)
 		// Replace ${node.component} - sanitize for use in resource names
@@ -2992,6 +3688,19 @@ func (e *Executor) evaluateInputExpression(expr string, node *graph.Node, envNam
 		}
 		return nil
 	}
+	if hasPrefix(expr, "environment.module.") {
+		name, output, _ := strings.Cut(expr[19:], ".") // len("environment.module.")
+		if val, ok := e.options.EnvironmentModules[name][output]; ok {
+			return val
+		}
+		return nil
+	}
+	if hasPrefix(expr, "environment.labels.") {
+		if val, ok := e.options.EnvironmentLabels[expr[19:]]; ok { // len("environment.labels.")
+			return val
+		}
+		return nil
+	}
 	if hasPrefix(expr, "environment.name") {
 		return envName
 	}
@@ -3287,18 +3996,61 @@ func (e *Executor) executeDestroy(ctx context.Context, change *planner.ResourceC
 
 	e.stateMu.Unlock()
 
+	// The modules of aggregate hooks are shared with the hook's other
+	// resources, and only destroyed along with the last of them
+	if resourceState != nil && resourceState.Aggregate != "" {
+		if err := e.destroyAggregateMember(ctx, resourceState.Aggregate, change.Node, envState); err != nil {
+			result.Error = fmt.Errorf("destroy failed: %w", err)
+			result.Success = false
+			return result
+		}
+		return e.removeDestroyedResource(change, envState, compState, result)
+	}
+
+	// The modules of shared hooks are used by other environments too, and
+	// only destroyed along with the last resource that uses them
+	if resourceState != nil && resourceState.Shared != "" {
+		if err := e.releaseShared(ctx, resourceState.Shared, change.Node, envState.Name); err != nil {
+			result.Error = fmt.Errorf("destroy failed: %w", err)
+			result.Success = false
+			return result
+		}
+		return e.removeDestroyedResource(change, envState, compState, result)
+	}
+
+	if err := e.destroyResourceModules(ctx, change.Node, resourceState); err != nil {
+		result.Error = fmt.Errorf("destroy failed: %w", err)
+		result.Success = false
+		return result
+	}
+
+	return e.removeDestroyedResource(change, envState, compState, result)
+}
+
+// destroyResourceModules destroys what the hook modules of a resource
+// created, from the IaC state recorded for them. resourceState may be nil.
+func (e *Executor) destroyResourceModules(ctx context.Context, node *graph.Node, resourceState *types.ResourceState) error {
+	// Multi-module hooks (and partially applied ones) track state per module.
+	if resourceState != nil && len(resourceState.ModuleStates) > 0 {
+		// Destroy in the same sandbox the modules were applied in
+		var hook datacenter.Hook
+		if node != nil {
+			hook, _ = e.matchHook(node)
+		}
+		sandbox := moduleSandbox(e.options.Datacenter, hook)
+		return e.destroyModuleStates(ctx, resourceState.ModuleStates, sandbox)
+	}
+
 	// Get IaC plugin
 	plugin, err := e.iacRegistry.Get("native")
 	if err != nil {
-		result.Error = fmt.Errorf("failed to get IaC plugin: %w", err)
-		result.Success = false
-		return result
+		return fmt.Errorf("failed to get IaC plugin: %w", err)
 	}
 
 	// Build run options with state reader if we have stored state
 	runOpts := iac.RunOptions{
-		ModulePath: string(change.Node.Type),
-		Inputs:     change.Node.Inputs,
+		ModulePath: string(node.Type),
+		Inputs:     node.Inputs,
 	}
 
 	// Pass the stored IaC state so the plugin knows what to destroy
@@ -3306,13 +4058,56 @@ func (e *Executor) executeDestroy(ctx context.Context, change *planner.ResourceC
 		runOpts.StateReader = bytes.NewReader(resourceState.IaCState)
 	}
 
-	// Execute destroy
-	if err := plugin.Destroy(ctx, runOpts); err != nil {
-		result.Error = fmt.Errorf("destroy failed: %w", err)
-		result.Success = false
-		return result
+	return plugin.Destroy(ctx, runOpts)
+}
+
+// destroyModuleStates destroys each module of a resource with the plugin
+// that applied it, in reverse module name order.
+func (e *Executor) destroyModuleStates(ctx context.Context, moduleStates map[string]*types.ModuleState, sandbox *iac.Sandbox) error {
+	names := make([]string, 0, len(moduleStates))
+	for name := range moduleStates {
+		names = append(names, name)
 	}
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
 
+	for _, name := range names {
+		ms := moduleStates[name]
+		if ms == nil || len(ms.IaCState) == 0 {
+			if ms != nil && ms.Status == types.ModuleStatusReady {
+				e.warn(nil, "module %s has no recorded IaC state, so nothing was destroyed for it", name)
+			}
+			continue
+		}
+		pluginName := ms.Plugin
+		if pluginName == "" {
+			pluginName = "native"
+		}
+		plugin, err := e.iacRegistry.Get(pluginName)
+		if err != nil {
+			return fmt.Errorf("failed to get IaC plugin %q: %w", pluginName, err)
+		}
+		if err := checkSandbox(name, pluginName, plugin, sandbox); err != nil {
+			return err
+		}
+		credEnv, err := e.credentialEnvironment(ctx, ms.Credentials, CredentialSessionName(name))
+		if err != nil {
+			return fmt.Errorf("module %s: %w", name, err)
+		}
+		if err := plugin.Destroy(ctx, iac.RunOptions{
+			ModuleSource: ms.Source,
+			Inputs:       ms.Inputs,
+			Environment:  credEnv,
+			Sandbox:      sandbox,
+			StateReader:  bytes.NewReader(ms.IaCState),
+		}); err != nil {
+			return fmt.Errorf("module %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// removeDestroyedResource removes a destroyed node's resource from state.
+func (e *Executor) removeDestroyedResource(change *planner.ResourceChange, envState *types.EnvironmentState, compState *types.ComponentState, result *NodeResult) *NodeResult {
 	result.Success = true
 
 	// Lock for state cleanup
@@ -3326,6 +4121,12 @@ func (e *Executor) executeDestroy(ctx context.Context, change *planner.ResourceC
 		delete(compState.Resources, change.Node.Name)
 	}
 
+	// Free any host port held by the resource
+	releasePortLocked(envState, change.Node)
+	if change.Node.Type == graph.NodeTypeRoute {
+		delete(compState.Routes, change.Node.Name)
+	}
+
 	// If component has no more resources, remove it
 	if len(compState.Resources) == 0 {
 		delete(envState.Components, change.Node.Component)
@@ -3341,7 +4142,9 @@ func (e *Executor) executeDestroy(ctx context.Context, change *planner.ResourceC
 // Uses a reactive approach: nodes start as soon as their specific dependencies
 // complete, rather than waiting for an entire batch to finish. This prevents
 // fast-completing nodes (like routes) from being blocked by slow nodes (like
-// docker builds) when they share the same dependency level.
+// docker builds) when they share the same dependency level. When more nodes
+// are ready than can run, cheap node types are started first and builds are
+// limited separately (see slots).
 func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *graph.Graph) (*ExecutionResult, error) {
 	startTime := time.Now()
 
@@ -3359,6 +4162,9 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 		return result, nil
 	}
 
+	e.resetWarnings()
+	defer func() { result.Warnings = e.collectWarnings(result) }()
+
 	// Get or create environment state
 	envState, err := e.stateManager.GetEnvironment(ctx, plan.Datacenter, plan.Environment)
 	if err != nil {
@@ -3375,11 +4181,16 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 	if envState.Components == nil {
 		envState.Components = make(map[string]*types.ComponentState)
 	}
+	e.envState = envState
+	e.snapshotPublishedRoutes(envState)
+	e.recordComponentLabels(envState)
+	stopHeartbeat := e.startHeartbeat(envState)
+	defer stopHeartbeat()
 
 	// Mark as provisioning and flush so that inspect can see progress immediately
 	envState.Status = types.EnvironmentStatusProvisioning
 	envState.UpdatedAt = time.Now()
-	_ = e.stateManager.SaveEnvironment(ctx, plan.Datacenter, envState)
+	_ = e.saveState(ctx, envState)
 
 	// Create a derived context so StopOnError can cancel in-flight operations
 	// (e.g., Docker builds, image pulls) for fast termination and cleanup.
@@ -3388,13 +4199,18 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 
 	// Concurrency control
 	var mu sync.Mutex
-	sem := make(chan struct{}, e.options.Parallelism)
+	nodeSlots := newSlots(e.options.Parallelism, e.options.BuildParallelism)
 	var wg sync.WaitGroup
 
-	// Track node states
+	// Track node states. order preserves the plan's ordering so that nodes
+	// are visited, launched, and reported in the same order on every run.
 	pending := make(map[string]*planner.ResourceChange)
+	var order []string
 	for _, change := range plan.Changes {
 		if change.Node != nil {
+			if _, dup := pending[change.Node.ID]; !dup {
+				order = append(order, change.Node.ID)
+			}
 			pending[change.Node.ID] = change
 		}
 	}
@@ -3407,10 +4223,9 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 	nodeFinished := make(chan struct{}, len(pending))
 
 	// Debug: show all nodes and their dependencies
-	if os.Getenv("CLDCTL_DEBUG") != "" {
-		fmt.Fprintf(os.Stderr, "[debug] All nodes and dependencies:\n")
-		for id, change := range pending {
-			fmt.Fprintf(os.Stderr, "[debug]   %s -> %v\n", id, change.Node.DependsOn)
+	if logging.Enabled(logging.Executor, slog.LevelDebug) {
+		for _, id := range order {
+			executorLog.Debug("planned node", "node", id, "depends_on", pending[id].Node.DependsOn)
 		}
 	}
 
@@ -3430,18 +4245,21 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 		// First pass: cascade failures to nodes whose dependencies failed
 		for {
 			cascaded := false
-			for id, change := range pending {
-				if inFlight[id] {
+			for _, id := range order {
+				change, ok := pending[id]
+				if !ok || inFlight[id] {
 					continue
 				}
 				for _, depID := range change.Node.DependsOn {
 					if failed[depID] {
 						depErr := fmt.Errorf("dependency %s failed", depID)
+						rootCause := rootCauseOf(depID, result)
 						result.NodeResults[id] = &NodeResult{
-							NodeID:  id,
-							Action:  change.Action,
-							Success: false,
-							Error:   depErr,
+							NodeID:    id,
+							Action:    change.Action,
+							Success:   false,
+							Error:     depErr,
+							RootCause: rootCause,
 						}
 						result.Failed++
 						result.Success = false
@@ -3472,7 +4290,8 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 							Name:         change.Node.Name,
 							Type:         string(change.Node.Type),
 							Status:       types.ResourceStatusFailed,
-							StatusReason: depErr.Error(),
+							StatusReason: types.StatusReasonUpstreamFailure,
+							RootCause:    rootCause,
 							Inputs:       change.Node.Inputs,
 							UpdatedAt:    time.Now(),
 						}
@@ -3503,8 +4322,9 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 		// Mark all remaining pending (non-in-flight) nodes as failed so the
 		// executor terminates quickly once in-flight goroutines finish.
 		if e.options.StopOnError && len(failed) > 0 {
-			for id, change := range pending {
-				if inFlight[id] {
+			for _, id := range order {
+				change, ok := pending[id]
+				if !ok || inFlight[id] {
 					continue
 				}
 				stopErr := fmt.Errorf("deployment stopped: a previous resource failed")
@@ -3564,8 +4384,9 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 		}
 
 		// Second pass: find and launch ready nodes
-		for id, change := range pending {
-			if inFlight[id] {
+		for _, id := range order {
+			change, ok := pending[id]
+			if !ok || inFlight[id] {
 				continue
 			}
 
@@ -3580,43 +4401,47 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 			if isReady {
 				inFlight[id] = true
 
-				if os.Getenv("CLDCTL_DEBUG") != "" {
-					fmt.Fprintf(os.Stderr, "[debug] Launching %s (deps satisfied)\n", id)
+				// Members of an aggregate batch wait for each other, so they
+				// don't take a slot while they do
+				aggregate := false
+				switch change.Action {
+				case planner.ActionCreate, planner.ActionUpdate, planner.ActionReplace:
+					aggregate = e.expectAggregate(change.Node)
 				}
 
+				executorLog.Debug("launching node, dependencies satisfied", "node", id)
+
 				wg.Add(1)
 
-				go func(c *planner.ResourceChange) {
-					// Acquire semaphore (limits concurrency)
-					select {
-					case sem <- struct{}{}:
-						// Got semaphore
-					case <-execCtx.Done():
-						// Context cancelled (user interrupt or StopOnError)
-						wg.Done()
-						mu.Lock()
-						delete(inFlight, c.Node.ID)
-						failed[c.Node.ID] = true
-						result.NodeResults[c.Node.ID] = &NodeResult{
-							NodeID:  c.Node.ID,
-							Action:  c.Action,
-							Success: false,
-							Error:   fmt.Errorf("cancelled"),
+				go func(c *planner.ResourceChange, aggregate bool) {
+					if !aggregate {
+						// Acquire a slot (limits concurrency)
+						if err := nodeSlots.acquire(execCtx, c.Node.Type); err != nil {
+							// Context cancelled (user interrupt or StopOnError)
+							wg.Done()
+							mu.Lock()
+							delete(inFlight, c.Node.ID)
+							failed[c.Node.ID] = true
+							result.NodeResults[c.Node.ID] = &NodeResult{
+								NodeID:  c.Node.ID,
+								Action:  c.Action,
+								Success: false,
+								Error:   fmt.Errorf("cancelled"),
+							}
+							result.Failed++
+							result.Success = false
+							mu.Unlock()
+							nodeFinished <- struct{}{}
+							return
 						}
-						result.Failed++
-						result.Success = false
-						mu.Unlock()
-						nodeFinished <- struct{}{}
-						return
+						defer nodeSlots.release(c.Node.Type)
 					}
-					defer func() { <-sem }()
 					defer wg.Done()
 
-					if os.Getenv("CLDCTL_DEBUG") != "" {
-						fmt.Fprintf(os.Stderr, "[debug] Goroutine started for %s, calling executeChange\n", c.Node.ID)
-					}
+					executorLog.Debug("executing node", "node", c.Node.ID)
 
 					nodeResult := e.executeChange(execCtx, c, envState)
+					e.leaveAggregate(execCtx, c.Node)
 
 					// If this node failed because StopOnError cancelled the
 					// execution context (not a user Ctrl+C), use a clean error.
@@ -3624,9 +4449,8 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 						nodeResult.Error = fmt.Errorf("cancelled")
 					}
 
-					if os.Getenv("CLDCTL_DEBUG") != "" {
-						fmt.Fprintf(os.Stderr, "[debug] executeChange completed for %s, success=%v\n", c.Node.ID, nodeResult.Success)
-					}
+					executorLog.Info("executed node", "node", c.Node.ID, "action", c.Action,
+						"success", nodeResult.Success, "duration", nodeResult.Duration)
 
 					mu.Lock()
 					result.NodeResults[c.Node.ID] = nodeResult
@@ -3678,7 +4502,7 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 
 					// Signal completion for the drain loop
 					nodeFinished <- struct{}{}
-				}(change)
+				}(change, aggregate)
 			}
 		}
 	}
@@ -3715,21 +4539,23 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 		// Still save state and return
 		computeComponentStatuses(envState)
 		e.resolveAndStoreComponentOutputs(envState)
+		stopHeartbeat()
 		envState.Status = types.EnvironmentStatusFailed
 		envState.UpdatedAt = time.Now()
-		_ = e.stateManager.SaveEnvironment(ctx, plan.Datacenter, envState)
+		_ = e.saveState(ctx, envState)
 		result.Duration = time.Since(startTime)
 		return result, nil
 	}
 
 	// Check for stuck nodes (dependency cycle or unresolvable deps)
 	if len(pending) > 0 {
-		if os.Getenv("CLDCTL_DEBUG") != "" {
-			fmt.Fprintf(os.Stderr, "[debug] Deadlock detected! Pending nodes:\n")
-			for id, change := range pending {
-				fmt.Fprintf(os.Stderr, "[debug]   %s depends on: %v\n", id, change.Node.DependsOn)
+		if logging.Enabled(logging.Executor, slog.LevelDebug) {
+			for _, id := range order {
+				if change, ok := pending[id]; ok {
+					executorLog.Debug("deadlock: node still pending", "node", id, "depends_on", change.Node.DependsOn)
+				}
 			}
-			fmt.Fprintf(os.Stderr, "[debug] Completed nodes: %v\n", completed)
+			executorLog.Debug("deadlock: completed nodes", "nodes", completed)
 		}
 		// Mark remaining nodes as failed
 		for id, change := range pending {
@@ -3756,6 +4582,7 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 	e.resolveAndStoreComponentOutputs(envState)
 
 	// Update environment status
+	stopHeartbeat()
 	if result.Success {
 		envState.Status = types.EnvironmentStatusReady
 	} else {
@@ -3764,7 +4591,7 @@ func (e *Executor) ExecuteParallel(ctx context.Context, plan *planner.Plan, g *g
 	envState.UpdatedAt = time.Now()
 
 	// Save state
-	if err := e.stateManager.SaveEnvironment(ctx, plan.Datacenter, envState); err != nil {
+	if err := e.saveState(ctx, envState); err != nil {
 		result.Errors = append(result.Errors, fmt.Errorf("failed to save state: %w", err))
 	}
 
@@ -3,12 +3,15 @@ package executor
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/davidthor/cldctl/pkg/engine/planner"
+	arcerrors "github.com/davidthor/cldctl/pkg/errors"
 	"github.com/davidthor/cldctl/pkg/graph"
 	"github.com/davidthor/cldctl/pkg/iac"
 	"github.com/davidthor/cldctl/pkg/schema/datacenter"
@@ -20,6 +23,7 @@ import (
 // mockStateManager implements state.Manager for testing
 type mockStateManager struct {
 	environments map[string]*types.EnvironmentState
+	shared       map[string]*types.SharedResourceState
 	saveErr      error
 	getErr       error
 }
@@ -121,10 +125,54 @@ func (m *mockStateManager) ListDatacenterComponents(ctx context.Context, dc stri
 	return nil, nil
 }
 
+func (m *mockStateManager) GetTenant(ctx context.Context, dc, name string) (*types.TenantConfig, error) {
+	return nil, fmt.Errorf("not found")
+}
+
+func (m *mockStateManager) SaveTenant(ctx context.Context, dc string, s *types.TenantConfig) error {
+	return nil
+}
+
+func (m *mockStateManager) DeleteTenant(ctx context.Context, dc, name string) error {
+	return nil
+}
+
+func (m *mockStateManager) ListTenants(ctx context.Context, dc string) ([]*types.TenantConfig, error) {
+	return nil, nil
+}
+
+func (m *mockStateManager) GetSharedResource(ctx context.Context, dc, key string) (*types.SharedResourceState, error) {
+	if s, ok := m.shared[dc+"/"+key]; ok {
+		return s, nil
+	}
+	return nil, backend.ErrNotFound
+}
+
+func (m *mockStateManager) SaveSharedResource(ctx context.Context, dc string, s *types.SharedResourceState) error {
+	if m.shared == nil {
+		m.shared = make(map[string]*types.SharedResourceState)
+	}
+	m.shared[dc+"/"+s.Key] = s
+	return nil
+}
+
+func (m *mockStateManager) DeleteSharedResource(ctx context.Context, dc, key string) error {
+	delete(m.shared, dc+"/"+key)
+	return nil
+}
+
+func (m *mockStateManager) ListSharedResources(ctx context.Context, dc string) ([]*types.SharedResourceState, error) {
+	return nil, nil
+}
+
 func (m *mockStateManager) Lock(ctx context.Context, scope state.LockScope) (backend.Lock, error) {
 	return nil, nil
 }
 
+func (m *mockStateManager) ForceUnlock(ctx context.Context, scope state.LockScope) (*backend.LockInfo, error) {
+	return nil, backend.ErrNotFound
+}
+
 func (m *mockStateManager) Backend() backend.Backend {
 	return nil
 }
@@ -135,6 +183,8 @@ type mockPlugin struct {
 	applyErr   error
 	destroyErr error
 	outputs    map[string]iac.OutputValue
+	preview    *iac.PreviewResult
+	applied    bool
 }
 
 func (p *mockPlugin) Name() string {
@@ -142,10 +192,14 @@ func (p *mockPlugin) Name() string {
 }
 
 func (p *mockPlugin) Preview(ctx context.Context, opts iac.RunOptions) (*iac.PreviewResult, error) {
+	if p.preview != nil {
+		return p.preview, nil
+	}
 	return &iac.PreviewResult{}, nil
 }
 
 func (p *mockPlugin) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResult, error) {
+	p.applied = true
 	if p.applyErr != nil {
 		return nil, p.applyErr
 	}
@@ -245,6 +299,9 @@ func TestNewExecutor_ParallelismDefault(t *testing.T) {
 	if exec.options.Parallelism != 10 {
 		t.Errorf("Parallelism should default to 10, got %d", exec.options.Parallelism)
 	}
+	if exec.options.BuildParallelism != DefaultBuildParallelism {
+		t.Errorf("BuildParallelism should default to %d, got %d", DefaultBuildParallelism, exec.options.BuildParallelism)
+	}
 }
 
 func TestExecute_EmptyPlan(t *testing.T) {
@@ -280,29 +337,53 @@ func TestExecute_DryRun(t *testing.T) {
 	registry := newTestRegistry()
 
 	// Register a mock plugin (overrides any existing)
+	plugin := &mockPlugin{
+		name: "native",
+		preview: &iac.PreviewResult{
+			Changes: []iac.ResourceChange{
+				{ResourceID: "api-main", ResourceType: "docker_container", Action: iac.ActionCreate},
+			},
+		},
+	}
 	registry.Register("native", func() (iac.Plugin, error) {
-		return &mockPlugin{name: "native"}, nil
+		return plugin, nil
 	})
 
 	opts := DefaultOptions()
 	opts.DryRun = true
+	opts.Datacenter = loadHCLDatacenter(t, `
+environment {
+  deployment {
+    module "container" {
+      plugin = "native"
+      build  = "./modules/container"
+      inputs = {
+        image = node.inputs.image
+      }
+    }
+    outputs = {
+      id = module.container.id
+    }
+  }
+}
+`)
 
 	exec := NewExecutor(sm, registry, opts)
 
 	node := graph.NewNode(graph.NodeTypeDeployment, "api", "main")
+	node.SetInput("image", "api:v1")
 	g := graph.NewGraph("test", "dc")
 	_ = g.AddNode(node)
 
+	change := &planner.ResourceChange{
+		Node:   node,
+		Action: planner.ActionCreate,
+	}
 	plan := &planner.Plan{
 		Environment: "test",
 		Datacenter:  "dc",
 		ToCreate:    1,
-		Changes: []*planner.ResourceChange{
-			{
-				Node:   node,
-				Action: planner.ActionCreate,
-			},
-		},
+		Changes:     []*planner.ResourceChange{change},
 	}
 
 	result, err := exec.Execute(context.Background(), plan, g)
@@ -311,13 +392,39 @@ func TestExecute_DryRun(t *testing.T) {
 	}
 
 	if !result.Success {
-		t.Error("Dry run should succeed")
+		t.Fatalf("Dry run should succeed: %v", result.Errors)
 	}
 
-	// In dry run mode, changes are simulated
+	// In dry run mode, changes are previewed rather than applied
 	if result.Created != 1 {
 		t.Errorf("Created: got %d, want %d", result.Created, 1)
 	}
+	if plugin.applied {
+		t.Error("Dry run should not call Apply")
+	}
+	if len(change.Preview) != 1 {
+		t.Fatalf("Preview: got %d module previews, want 1", len(change.Preview))
+	}
+	if mp := change.Preview[0]; mp.Module != "container" || len(mp.Changes) != 1 || mp.Changes[0].ResourceID != "api-main" {
+		t.Errorf("unexpected module preview: %+v", mp)
+	}
+	if _, saved := sm.environments["test"]; saved {
+		t.Error("Dry run should not write state")
+	}
+}
+
+// loadHCLDatacenter writes HCL to a temp file and loads it as a datacenter.
+func loadHCLDatacenter(t *testing.T, content string) datacenter.Datacenter {
+	t.Helper()
+	tmpFile := filepath.Join(t.TempDir(), "datacenter.dc")
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	dc, err := datacenter.NewLoader().Load(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to load datacenter: %v", err)
+	}
+	return dc
 }
 
 func TestExecute_ContextCancellation(t *testing.T) {
@@ -517,7 +624,7 @@ func TestEvaluateWhenCondition_EqualityMatch(t *testing.T) {
 	}
 	when := `element(split(":", node.inputs.type), 0) == "postgres"`
 
-	result := exec.evaluateWhenCondition(when, inputs)
+	result := exec.evaluateWhenCondition(when, &graph.Node{Inputs: inputs})
 	if !result {
 		t.Error("should match postgres type via HCL evaluation")
 	}
@@ -532,7 +639,7 @@ func TestEvaluateWhenCondition_EqualityNoMatch(t *testing.T) {
 	}
 	when := `element(split(":", node.inputs.type), 0) == "postgres"`
 
-	result := exec.evaluateWhenCondition(when, inputs)
+	result := exec.evaluateWhenCondition(when, &graph.Node{Inputs: inputs})
 	if result {
 		t.Error("should not match mysql type when looking for postgres")
 	}
@@ -547,7 +654,7 @@ func TestEvaluateWhenCondition_NotNullTrue(t *testing.T) {
 	}
 	when := `node.inputs.image != null`
 
-	result := exec.evaluateWhenCondition(when, inputs)
+	result := exec.evaluateWhenCondition(when, &graph.Node{Inputs: inputs})
 	if !result {
 		t.Error("should return true when image is set")
 	}
@@ -562,7 +669,7 @@ func TestEvaluateWhenCondition_NotNullFalse(t *testing.T) {
 	}
 	when := `node.inputs.image != null`
 
-	result := exec.evaluateWhenCondition(when, inputs)
+	result := exec.evaluateWhenCondition(when, &graph.Node{Inputs: inputs})
 	if result {
 		t.Error("should return false when image is nil")
 	}
@@ -579,7 +686,7 @@ func TestEvaluateWhenCondition_EqualNullTrue(t *testing.T) {
 	}
 	when := `node.inputs.image == null`
 
-	result := exec.evaluateWhenCondition(when, inputs)
+	result := exec.evaluateWhenCondition(when, &graph.Node{Inputs: inputs})
 	if !result {
 		t.Error("should return true when image is not in inputs (missing key is null)")
 	}
@@ -594,7 +701,7 @@ func TestEvaluateWhenCondition_EqualNullFalse(t *testing.T) {
 	}
 	when := `node.inputs.image == null`
 
-	result := exec.evaluateWhenCondition(when, inputs)
+	result := exec.evaluateWhenCondition(when, &graph.Node{Inputs: inputs})
 	if result {
 		t.Error("should return false when image is set")
 	}
@@ -610,7 +717,7 @@ func TestEvaluateWhenCondition_EqualNullNilValue(t *testing.T) {
 	}
 	when := `node.inputs.image == null`
 
-	result := exec.evaluateWhenCondition(when, inputs)
+	result := exec.evaluateWhenCondition(when, &graph.Node{Inputs: inputs})
 	if !result {
 		t.Error("should return true when image is explicitly nil")
 	}
@@ -627,7 +734,7 @@ func TestEvaluateWhenCondition_HCLExpression(t *testing.T) {
 	}
 	when := `node.inputs.image != null`
 
-	result := exec.evaluateWhenCondition(when, inputs)
+	result := exec.evaluateWhenCondition(when, &graph.Node{Inputs: inputs})
 	if !result {
 		t.Error("HCL expression should match when image is set")
 	}
@@ -642,7 +749,7 @@ func TestEvaluateWhenCondition_RedisType(t *testing.T) {
 	}
 	when := `element(split(":", node.inputs.type), 0) == "redis"`
 
-	result := exec.evaluateWhenCondition(when, inputs)
+	result := exec.evaluateWhenCondition(when, &graph.Node{Inputs: inputs})
 	if !result {
 		t.Error("should match redis type")
 	}
@@ -661,7 +768,7 @@ func TestEvaluateWhenCondition_WithVariables(t *testing.T) {
 	}
 	when := `element(split(":", node.inputs.type), 0) == "postgres"`
 
-	result := exec.evaluateWhenCondition(when, inputs)
+	result := exec.evaluateWhenCondition(when, &graph.Node{Inputs: inputs})
 	if !result {
 		t.Error("should still match with variables set")
 	}
@@ -678,12 +785,60 @@ func TestEvaluateWhenCondition_FallbackOnInvalidHCL(t *testing.T) {
 	when := `node.inputs.image != null`
 
 	// Should succeed via HCL evaluation path
-	result := exec.evaluateWhenCondition(when, inputs)
+	result := exec.evaluateWhenCondition(when, &graph.Node{Inputs: inputs})
 	if !result {
 		t.Error("should evaluate successfully")
 	}
 }
 
+func TestEvaluateWhenCondition_ComponentIdentity(t *testing.T) {
+	sm := newMockStateManager()
+	exec := NewExecutor(sm, newTestRegistry(), DefaultOptions())
+
+	when := `startswith(node.component, "team-payments/") && node.type == "database"`
+
+	payments := &graph.Node{Type: graph.NodeTypeDatabase, Name: "main", Component: "team-payments/ledger"}
+	if !exec.evaluateWhenCondition(when, payments) {
+		t.Error("should match a database of a team-payments component")
+	}
+
+	other := &graph.Node{Type: graph.NodeTypeDatabase, Name: "main", Component: "team-search/indexer"}
+	if exec.evaluateWhenCondition(when, other) {
+		t.Error("should not match a database of another team's component")
+	}
+}
+
+func TestEvaluateWhenCondition_Labels(t *testing.T) {
+	sm := newMockStateManager()
+	opts := DefaultOptions()
+	opts.EnvironmentLabels = map[string]string{"tier": "production"}
+	exec := NewExecutor(sm, newTestRegistry(), opts)
+
+	node := &graph.Node{
+		Type:      graph.NodeTypeDatabase,
+		Component: "api",
+		Inputs: map[string]interface{}{
+			"labels": map[string]interface{}{"team": "payments"},
+		},
+	}
+
+	tests := []struct {
+		when string
+		want bool
+	}{
+		{`environment.labels.tier == "production"`, true},
+		{`environment.labels.tier == "staging"`, false},
+		{`node.labels.team == "payments"`, true},
+		{`lookup(environment.labels, "region", "") == ""`, true},
+		{`lookup(node.labels, "cost-center", "none") == "none"`, true},
+	}
+	for _, tt := range tests {
+		if got := exec.evaluateWhenCondition(tt.when, node); got != tt.want {
+			t.Errorf("evaluateWhenCondition(%q) = %v, want %v", tt.when, got, tt.want)
+		}
+	}
+}
+
 func TestEvaluateErrorMessage_SimpleLiteral(t *testing.T) {
 	sm := newMockStateManager()
 	exec := NewExecutor(sm, newTestRegistry(), DefaultOptions())
@@ -705,7 +860,7 @@ func TestEvaluateErrorMessage_WithInterpolation(t *testing.T) {
 	}
 	msg := `Unsupported database type: ${node.inputs.type}`
 
-	result := exec.evaluateErrorMessage(msg, inputs)
+	result := exec.evaluateErrorMessage(msg, &graph.Node{Inputs: inputs})
 
 	expected := "Unsupported database type: mongodb"
 	if result != expected {
@@ -726,6 +881,42 @@ func TestEvaluateErrorMessage_FallbackOnInvalidTemplate(t *testing.T) {
 	}
 }
 
+func TestHookRejection(t *testing.T) {
+	sm := newMockStateManager()
+	exec := NewExecutor(sm, newTestRegistry(), DefaultOptions())
+
+	hook := &mockHook{
+		errorMsg:          "${node.inputs.type} is not supported",
+		errorCode:         "MYSQL_UNSUPPORTED",
+		errorDocsURL:      "https://docs.example.com/databases",
+		errorAlternatives: []string{"postgres", "mariadb"},
+	}
+	node := &graph.Node{Type: graph.NodeTypeDatabase, Component: "my-app", Name: "main", Inputs: map[string]interface{}{"type": "mysql"}}
+
+	err := exec.hookRejection(hook, node)
+	if !arcerrors.Is(err, arcerrors.ErrCodeDatacenterHook) {
+		t.Fatalf("expected a datacenter hook error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "mysql is not supported") {
+		t.Errorf("expected the evaluated message, got %q", err.Error())
+	}
+
+	remediation := arcerrors.RemediationOf(err)
+	if remediation == nil {
+		t.Fatal("expected remediation")
+	}
+	if remediation.Code != "MYSQL_UNSUPPORTED" || remediation.DocsURL != "https://docs.example.com/databases" {
+		t.Errorf("unexpected remediation %+v", remediation)
+	}
+	if len(remediation.Alternatives) != 2 || remediation.Alternatives[0] != "postgres" {
+		t.Errorf("expected alternatives [postgres mariadb], got %v", remediation.Alternatives)
+	}
+
+	if arcerrors.RemediationOf(exec.hookRejection(&mockHook{errorMsg: "no"}, node)) != nil {
+		t.Error("expected no remediation for a hook with only a message")
+	}
+}
+
 func TestExtractVersionFromType(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -877,12 +1068,12 @@ func TestExecute_CascadedFailure_ResolvesExpressions(t *testing.T) {
 		t.Fatal("expected deployment resource state to be saved")
 	}
 
-	// Check status reason includes dependency info
-	if resState.StatusReason == "" {
-		t.Error("expected StatusReason to be set for cascaded failure")
+	// Check the cascaded failure is marked as skipped and names the task
+	if resState.StatusReason != types.StatusReasonUpstreamFailure {
+		t.Errorf("expected StatusReason %q, got: %s", types.StatusReasonUpstreamFailure, resState.StatusReason)
 	}
-	if !contains(resState.StatusReason, taskNode.ID) {
-		t.Errorf("StatusReason should reference failed task, got: %s", resState.StatusReason)
+	if resState.RootCause != taskNode.ID {
+		t.Errorf("RootCause should reference failed task, got: %s", resState.RootCause)
 	}
 
 	// Check that the DATABASE_URL expression was resolved
@@ -910,6 +1101,64 @@ func TestExecute_CascadedFailure_ResolvesExpressions(t *testing.T) {
 	}
 }
 
+func TestExecute_CascadedFailure_RootCause(t *testing.T) {
+	// A chain where the first node fails: every node after it names the
+	// first as its root cause, not its direct dependency
+	newPlan := func() (*planner.Plan, *graph.Graph, *graph.Node, *graph.Node) {
+		g := graph.NewGraph("test-env", "test-dc")
+		taskNode := graph.NewNode(graph.NodeTypeTask, "my-app", "migration")
+		_ = g.AddNode(taskNode)
+		deployNode := graph.NewNode(graph.NodeTypeDeployment, "my-app", "api")
+		deployNode.AddDependency(taskNode.ID)
+		_ = g.AddNode(deployNode)
+		serviceNode := graph.NewNode(graph.NodeTypeService, "my-app", "api")
+		serviceNode.AddDependency(deployNode.ID)
+		_ = g.AddNode(serviceNode)
+
+		plan := &planner.Plan{
+			Environment: "test-env",
+			Datacenter:  "test-dc",
+			ToCreate:    3,
+			Changes: []*planner.ResourceChange{
+				{Node: taskNode, Action: planner.ActionCreate},
+				{Node: deployNode, Action: planner.ActionCreate},
+				{Node: serviceNode, Action: planner.ActionCreate},
+			},
+		}
+		return plan, g, taskNode, serviceNode
+	}
+
+	for _, parallel := range []bool{false, true} {
+		sm := newMockStateManager()
+		opts := DefaultOptions()
+		opts.StopOnError = false
+		exec := NewExecutor(sm, newTestRegistry(), opts)
+
+		plan, g, taskNode, serviceNode := newPlan()
+		var result *ExecutionResult
+		var err error
+		if parallel {
+			result, err = exec.ExecuteParallel(context.Background(), plan, g)
+		} else {
+			result, err = exec.Execute(context.Background(), plan, g)
+		}
+		if err != nil {
+			t.Fatalf("Execute returned error: %v", err)
+		}
+
+		if result.NodeResults[taskNode.ID].RootCause != "" {
+			t.Errorf("parallel=%v: the failed task should have no root cause", parallel)
+		}
+		if got := result.NodeResults[serviceNode.ID].RootCause; got != taskNode.ID {
+			t.Errorf("parallel=%v: service RootCause = %q, want %q", parallel, got, taskNode.ID)
+		}
+		resState := sm.environments["test-dc/test-env"].Components["my-app"].Resources["service.api"]
+		if resState == nil || resState.RootCause != taskNode.ID || resState.StatusReason != types.StatusReasonUpstreamFailure {
+			t.Errorf("parallel=%v: expected the service's state to be marked as skipped because of the task, got %+v", parallel, resState)
+		}
+	}
+}
+
 func TestAutoPopulateDatabaseEndpoints(t *testing.T) {
 	exec := &Executor{}
 
@@ -1014,14 +1263,66 @@ type mockHook struct {
 	when          string
 	outputs       map[string]string
 	nestedOutputs map[string]map[string]string
+	guarantees    []string
 	errorMsg      string
+
+	errorCode         string
+	errorDocsURL      string
+	errorAlternatives []string
 }
 
 func (h *mockHook) When() string                                { return h.when }
 func (h *mockHook) Modules() []datacenter.Module                { return nil }
+func (h *mockHook) Validate() []datacenter.Module               { return nil }
 func (h *mockHook) Outputs() map[string]string                  { return h.outputs }
 func (h *mockHook) NestedOutputs() map[string]map[string]string { return h.nestedOutputs }
+func (h *mockHook) Guarantees() []string                        { return h.guarantees }
+func (h *mockHook) ReplaceOn() []string                         { return nil }
 func (h *mockHook) Error() string                               { return h.errorMsg }
+func (h *mockHook) ErrorCode() string                           { return h.errorCode }
+func (h *mockHook) ErrorDocsURL() string                        { return h.errorDocsURL }
+func (h *mockHook) ErrorAlternatives() []string                 { return h.errorAlternatives }
+func (h *mockHook) Credentials() datacenter.Credentials         { return nil }
+func (h *mockHook) Sandbox() datacenter.Sandbox                 { return nil }
+func (h *mockHook) Priority() int                               { return 0 }
+func (h *mockHook) Fallthrough() bool                           { return false }
+func (h *mockHook) Aggregate() bool                             { return false }
+func (h *mockHook) Shared() bool                                { return false }
+
+func TestValidateHookOutputs(t *testing.T) {
+	full := map[string]interface{}{"host": "db", "port": 5432, "url": "postgres://db"}
+
+	if err := validateHookOutputs(graph.NodeTypeDatabase, &mockHook{}, full); err != nil {
+		t.Errorf("expected built-in outputs to satisfy the contract, got %v", err)
+	}
+	if err := validateHookOutputs(graph.NodeTypeDatabase, &mockHook{}, map[string]interface{}{"url": "postgres://db"}); err == nil {
+		t.Error("expected error for missing host and port")
+	}
+	// databaseUser host/port fall back to the parent database
+	if err := validateHookOutputs(graph.NodeTypeDatabaseUser, &mockHook{}, map[string]interface{}{"url": "postgres://db"}); err != nil {
+		t.Errorf("expected url alone to satisfy the databaseUser contract, got %v", err)
+	}
+
+	hook := &mockHook{guarantees: []string{"poolUrl", "read.host"}}
+	err := validateHookOutputs(graph.NodeTypeDatabase, hook, full)
+	if err == nil {
+		t.Fatal("expected error for missing guaranteed outputs")
+	}
+	if !strings.Contains(err.Error(), "poolUrl, read.host") {
+		t.Errorf("expected missing guaranteed outputs in error, got %v", err)
+	}
+
+	withGuarantees := map[string]interface{}{
+		"host":    "db",
+		"port":    5432,
+		"url":     "postgres://db",
+		"poolUrl": "postgres://pool",
+		"read":    map[string]interface{}{"host": "replica"},
+	}
+	if err := validateHookOutputs(graph.NodeTypeDatabase, hook, withGuarantees); err != nil {
+		t.Errorf("expected guaranteed outputs to be satisfied, got %v", err)
+	}
+}
 
 func TestBuildDependencyError(t *testing.T) {
 	exec := &Executor{}
@@ -1262,3 +1563,301 @@ func TestGetHooksForType_NoHooksDefined(t *testing.T) {
 		t.Errorf("expected 0 networkPolicy hooks, got %d", len(npHooks))
 	}
 }
+
+func TestFailedResourceState_KeepsPartialModuleState(t *testing.T) {
+	exec := NewExecutor(newMockStateManager(), newTestRegistry(), DefaultOptions())
+	change := &planner.ResourceChange{
+		Node: &graph.Node{Type: graph.NodeTypeDeployment, Name: "api", Component: "app"},
+	}
+	hookResult := &hookExecutionResult{
+		ModuleStates: map[string]*types.ModuleState{
+			"container": {Name: "container", Plugin: "native", IaCState: []byte(`{"resources":{}}`)},
+		},
+	}
+
+	rs := exec.failedResourceState(context.Background(), change, hookResult, fmt.Errorf("boom"))
+
+	if rs.Status != types.ResourceStatusUnknown {
+		t.Errorf("Status = %s, want %s", rs.Status, types.ResourceStatusUnknown)
+	}
+	if string(rs.IaCState) != `{"resources":{}}` {
+		t.Errorf("IaCState = %s, want partial module state", rs.IaCState)
+	}
+	if rs.StatusReason != "boom" {
+		t.Errorf("StatusReason = %q", rs.StatusReason)
+	}
+}
+
+func TestFailedResourceState_CarriesPriorState(t *testing.T) {
+	exec := NewExecutor(newMockStateManager(), newTestRegistry(), DefaultOptions())
+	change := &planner.ResourceChange{
+		Node:         &graph.Node{Type: graph.NodeTypeDeployment, Name: "api", Component: "app"},
+		CurrentState: &types.ResourceState{IaCState: []byte("prior")},
+	}
+
+	rs := exec.failedResourceState(context.Background(), change, nil, fmt.Errorf("boom"))
+
+	if rs.Status != types.ResourceStatusFailed {
+		t.Errorf("Status = %s, want %s", rs.Status, types.ResourceStatusFailed)
+	}
+	if string(rs.IaCState) != "prior" {
+		t.Errorf("IaCState = %q, want prior state carried forward", rs.IaCState)
+	}
+}
+
+func TestFailedResourceState_CancelledIsUnknown(t *testing.T) {
+	exec := NewExecutor(newMockStateManager(), newTestRegistry(), DefaultOptions())
+	change := &planner.ResourceChange{
+		Node: &graph.Node{Type: graph.NodeTypeDeployment, Name: "api", Component: "app"},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rs := exec.failedResourceState(ctx, change, nil, ctx.Err())
+
+	if rs.Status != types.ResourceStatusUnknown {
+		t.Errorf("Status = %s, want %s", rs.Status, types.ResourceStatusUnknown)
+	}
+}
+
+func TestExecuteHookModules_EnvironmentModuleRefs(t *testing.T) {
+	plugin := &mockPlugin{name: "native", outputs: map[string]iac.OutputValue{
+		"host": {Value: "api.example.com"},
+	}}
+	registry := iac.NewRegistry()
+	registry.Register("native", func() (iac.Plugin, error) { return plugin, nil })
+
+	opts := DefaultOptions()
+	opts.Datacenter = loadHCLDatacenter(t, `
+environment {
+  module "ingress" {
+    build = "./modules/ingress"
+  }
+
+  route {
+    module "route" {
+      plugin = "native"
+      build  = "./modules/route"
+      inputs = {
+        ingress_class = environment.module.ingress.class
+        gateway       = "${environment.module.ingress.namespace}/gateway"
+      }
+    }
+    outputs = {
+      url  = "https://${module.route.host}:${environment.module.ingress.port}"
+      host = module.route.host
+      port = environment.module.ingress.port
+    }
+  }
+}
+`)
+	opts.EnvironmentModules = map[string]map[string]interface{}{
+		"ingress": {"class": "nginx", "namespace": "ingress-system", "port": 8443},
+	}
+	exec := NewExecutor(newMockStateManager(), registry, opts)
+
+	node := graph.NewNode(graph.NodeTypeRoute, "api", "main")
+	result, err := exec.executeHookModules(context.Background(), node, "test", nil, nil, io.Discard, nil)
+	if err != nil {
+		t.Fatalf("executeHookModules failed: %v", err)
+	}
+
+	inputs := result.ModuleStates["route"].Inputs
+	if inputs["ingress_class"] != "nginx" {
+		t.Errorf("expected ingress_class nginx, got %v", inputs["ingress_class"])
+	}
+	if inputs["gateway"] != "ingress-system/gateway" {
+		t.Errorf("expected gateway ingress-system/gateway, got %v", inputs["gateway"])
+	}
+	if result.Outputs["url"] != "https://api.example.com:8443" {
+		t.Errorf("expected url https://api.example.com:8443, got %v", result.Outputs["url"])
+	}
+	if result.Outputs["port"] != 8443 {
+		t.Errorf("expected port 8443, got %v", result.Outputs["port"])
+	}
+
+	// A hook can't run before the environment modules it references exist
+	exec.options.EnvironmentModules = nil
+	_, err = exec.executeHookModules(context.Background(), node, "test", nil, nil, io.Discard, nil)
+	if err == nil || !strings.Contains(err.Error(), `environment module "ingress"`) {
+		t.Errorf("expected error for unprovisioned environment module, got %v", err)
+	}
+}
+
+func TestPlatformExport(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Datacenter = loadHCLDatacenter(t, `
+environment {
+  module "network" {
+    build = "./modules/network"
+  }
+  exports {
+    vpc_id = environment.module.network.vpc_id
+    subnet = "${environment.module.network.vpc_id}/private"
+  }
+}
+`)
+	opts.EnvironmentModules = map[string]map[string]interface{}{
+		"network": {"vpc_id": "vpc-123"},
+	}
+	exec := NewExecutor(newMockStateManager(), iac.NewRegistry(), opts)
+
+	if v, ok := exec.platformExport("vpc_id", "test"); !ok || v != "vpc-123" {
+		t.Errorf("expected vpc_id vpc-123, got %v %v", v, ok)
+	}
+	if v, ok := exec.platformExport("subnet", "test"); !ok || v != "vpc-123/private" {
+		t.Errorf("expected subnet vpc-123/private, got %v %v", v, ok)
+	}
+	if _, ok := exec.platformExport("region", "test"); ok {
+		t.Error("expected a name the datacenter doesn't export to be unresolved")
+	}
+
+	// Exports of environment modules that aren't provisioned are unresolved
+	exec.options.EnvironmentModules = nil
+	if v, ok := exec.platformExport("vpc_id", "test"); ok {
+		t.Errorf("expected vpc_id to be unresolved, got %v", v)
+	}
+}
+
+func TestExecuteHookModules_Labels(t *testing.T) {
+	plugin := &mockPlugin{name: "native"}
+	registry := iac.NewRegistry()
+	registry.Register("native", func() (iac.Plugin, error) { return plugin, nil })
+
+	opts := DefaultOptions()
+	opts.Datacenter = loadHCLDatacenter(t, `
+environment {
+  deployment {
+    module "deployment" {
+      plugin = "native"
+      build  = "./modules/deployment"
+      inputs = {
+        team = environment.labels.team
+        name = "${environment.labels.team}-${node.name}"
+        tags = node.inputs.labels
+      }
+    }
+    outputs = {
+      id = node.name
+    }
+  }
+}
+`)
+	opts.EnvironmentLabels = map[string]string{"team": "payments"}
+	exec := NewExecutor(newMockStateManager(), registry, opts)
+
+	node := graph.NewNode(graph.NodeTypeDeployment, "app", "api")
+	node.SetInput("labels", map[string]interface{}{"team": "payments", "tier": "web"})
+	result, err := exec.executeHookModules(context.Background(), node, "test", nil, nil, io.Discard, nil)
+	if err != nil {
+		t.Fatalf("executeHookModules failed: %v", err)
+	}
+
+	inputs := result.ModuleStates["deployment"].Inputs
+	if inputs["team"] != "payments" {
+		t.Errorf("expected team payments, got %v", inputs["team"])
+	}
+	if inputs["name"] != "payments-api" {
+		t.Errorf("expected name payments-api, got %v", inputs["name"])
+	}
+	tags, ok := inputs["tags"].(map[string]interface{})
+	if !ok || tags["tier"] != "web" {
+		t.Errorf("expected tags to carry the node's labels, got %v", inputs["tags"])
+	}
+}
+
+func TestRecordTiming(t *testing.T) {
+	exec := NewExecutor(newMockStateManager(), newTestRegistry(), DefaultOptions())
+	node := graph.NewNode(graph.NodeTypeDatabase, "app", "main")
+	envState := &types.EnvironmentState{
+		Components: map[string]*types.ComponentState{
+			"app": {Name: "app", Resources: map[string]*types.ResourceState{
+				resourceKey(node): {Name: "main", Status: types.ResourceStatusReady},
+			}},
+		},
+	}
+	start := time.Now().Add(-90 * time.Second)
+
+	change := &planner.ResourceChange{Node: node, Action: planner.ActionCreate}
+	exec.recordTiming(change, envState, &NodeResult{Success: true, Duration: 90 * time.Second}, start)
+
+	timing := envState.Components["app"].Resources[resourceKey(node)].Timing
+	if timing == nil {
+		t.Fatal("expected timing to be recorded")
+	}
+	if timing.Action != "create" || timing.DurationMS != 90000 || !timing.EndedAt.Equal(start.Add(90*time.Second)) {
+		t.Errorf("unexpected timing: %+v", timing)
+	}
+
+	// Failed applies and noops keep the last successful timing
+	exec.recordTiming(change, envState, &NodeResult{Success: false, Duration: time.Second}, time.Now())
+	exec.recordTiming(&planner.ResourceChange{Node: node, Action: planner.ActionNoop}, envState, &NodeResult{Success: true}, time.Now())
+	if got := envState.Components["app"].Resources[resourceKey(node)].Timing; got != timing {
+		t.Errorf("expected timing to be unchanged, got %+v", got)
+	}
+}
+
+func TestResolveComponentExpressions_Computed(t *testing.T) {
+	g := graph.NewGraph("test-env", "test-dc")
+
+	portNode := graph.NewNode(graph.NodeTypePort, "my-app", "http")
+	portNode.SetOutput("port", 8080)
+	portNode.State = graph.NodeStateCompleted
+	_ = g.AddNode(portNode)
+
+	deployNode := graph.NewNode(graph.NodeTypeDeployment, "my-app", "api")
+	deployNode.SetInput("environment", map[string]string{
+		"METRICS_PORT": "${{ ports.http.port + 1 }}",
+		"STACK":        `${{ format("%s-%s", variables.region, upper(variables.tier)) }}`,
+		"WORKERS":      "${{ max(variables.workers * 2, 4) }}",
+		"PORT":         "${{ ports.http.port }}",
+	})
+	_ = g.AddNode(deployNode)
+
+	executor := &Executor{
+		graph: g,
+		options: Options{
+			ComponentVariables: map[string]map[string]interface{}{
+				"my-app": {"region": "eu-west-1", "tier": "prod", "workers": 3},
+			},
+		},
+	}
+	executor.resolveComponentExpressions(deployNode, nil)
+
+	env := deployNode.Inputs["environment"].(map[string]string)
+	want := map[string]string{
+		"METRICS_PORT": "8081",
+		"STACK":        "eu-west-1-PROD",
+		"WORKERS":      "6",
+		"PORT":         "8080",
+	}
+	for k, v := range want {
+		if env[k] != v {
+			t.Errorf("%s = %q, want %q", k, env[k], v)
+		}
+	}
+}
+
+func TestResolveComponentOutputs_Computed(t *testing.T) {
+	g := graph.NewGraph("test-env", "test-dc")
+
+	portNode := graph.NewNode(graph.NodeTypePort, "my-app", "http")
+	portNode.SetOutput("port", 8080)
+	_ = g.AddNode(portNode)
+
+	executor := &Executor{
+		graph: g,
+		options: Options{
+			ComponentVariables: map[string]map[string]interface{}{
+				"my-app": {"host": "api.local"},
+			},
+		},
+	}
+	outputs := executor.resolveComponentOutputs("my-app", map[string]string{
+		"url": `${{ format("http://%s:%d", variables.host, ports.http.port) }}`,
+	})
+
+	if outputs["url"] != "http://api.local:8080" {
+		t.Errorf("url = %v, want http://api.local:8080", outputs["url"])
+	}
+}
@@ -0,0 +1,85 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// executeExternalService completes an external service node. External
+// services aren't provisioned, so no datacenter hook runs: the node's outputs
+// are its endpoint plus each credential resolved from the component's
+// variables.
+func (e *Executor) executeExternalService(ctx context.Context, change *planner.ResourceChange, envState *types.EnvironmentState) *NodeResult {
+	result := &NodeResult{
+		NodeID: change.Node.ID,
+		Action: change.Action,
+	}
+
+	outputs, err := externalServiceOutputs(change.Node)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	// Save to state
+	e.stateMu.Lock()
+	if envState.Components == nil {
+		envState.Components = make(map[string]*types.ComponentState)
+	}
+	compState := envState.Components[change.Node.Component]
+	if compState == nil {
+		compState = e.newComponentState(change.Node.Component)
+		envState.Components[change.Node.Component] = compState
+	}
+	resMap := e.getResourceMap(compState, change.Node)
+	resMap[resourceKey(change.Node)] = &types.ResourceState{
+		Component: change.Node.Component,
+		Name:      change.Node.Name,
+		Type:      string(change.Node.Type),
+		Status:    types.ResourceStatusReady,
+		Inputs:    change.Node.Inputs,
+		Outputs:   outputs,
+		UpdatedAt: time.Now(),
+	}
+	e.saveStateLocked(envState)
+	e.stateMu.Unlock()
+
+	result.Success = true
+	result.Outputs = outputs
+	return result
+}
+
+// externalServiceOutputs builds an external service node's outputs from its
+// resolved inputs. Every declared credential is required: a credential whose
+// variable wasn't provided is an error rather than an empty value in the
+// workloads that consume it.
+func externalServiceOutputs(node *graph.Node) (map[string]interface{}, error) {
+	outputs := map[string]interface{}{
+		"url":      node.Inputs["url"],
+		"host":     node.Inputs["host"],
+		"port":     node.Inputs["port"],
+		"protocol": node.Inputs["protocol"],
+	}
+
+	credentials, _ := node.Inputs["credentials"].(map[string]string)
+	var missing []string
+	for name, value := range credentials {
+		if value == "" || strings.Contains(value, "${{") {
+			missing = append(missing, name)
+			continue
+		}
+		outputs[name] = value
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("external service %q is missing required credentials: %s (set the variables they reference)", node.Name, strings.Join(missing, ", "))
+	}
+	return outputs, nil
+}
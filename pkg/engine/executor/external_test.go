@@ -0,0 +1,120 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func newExternalTestNode() *graph.Node {
+	node := graph.NewNode(graph.NodeTypeExternal, "my-app", "stripe")
+	node.SetInput("url", "https://api.stripe.com")
+	node.SetInput("host", "api.stripe.com")
+	node.SetInput("port", "443")
+	node.SetInput("protocol", "https")
+	node.SetInput("credentials", map[string]string{
+		"api_key":        "${{ variables.stripe_key }}",
+		"webhook_secret": "${{ variables.stripe_webhook_secret }}",
+	})
+	return node
+}
+
+func TestExecuteExternalService(t *testing.T) {
+	g := graph.NewGraph("test-env", "test-dc")
+	extNode := newExternalTestNode()
+	_ = g.AddNode(extNode)
+
+	exec := NewExecutor(newMockStateManager(), newTestRegistry(), Options{
+		Parallelism: 1,
+		ComponentVariables: map[string]map[string]interface{}{
+			"my-app": {"stripe_key": "sk_test_123", "stripe_webhook_secret": "whsec_456"},
+		},
+	})
+	exec.graph = g
+	exec.datacenterName = "test-dc"
+
+	envState := &types.EnvironmentState{
+		Name:       "test-env",
+		Components: make(map[string]*types.ComponentState),
+	}
+
+	result := exec.executeApply(context.Background(), &planner.ResourceChange{
+		Node:   extNode,
+		Action: planner.ActionCreate,
+	}, envState, nil)
+
+	if !result.Success {
+		t.Fatalf("expected external service to succeed, got %v", result.Error)
+	}
+	for key, want := range map[string]string{
+		"url":            "https://api.stripe.com",
+		"host":           "api.stripe.com",
+		"port":           "443",
+		"api_key":        "sk_test_123",
+		"webhook_secret": "whsec_456",
+	} {
+		if result.Outputs[key] != want {
+			t.Errorf("expected output %s %q, got %v", key, want, result.Outputs[key])
+		}
+	}
+
+	resState := envState.Components["my-app"].Resources["external.stripe"]
+	if resState == nil {
+		t.Fatal("expected resource state to be created")
+	}
+	if resState.Status != types.ResourceStatusReady {
+		t.Errorf("expected status Ready, got %s", resState.Status)
+	}
+
+	// Workloads read credentials through external.<name>.<credential>
+	extNode.Outputs = result.Outputs
+	extNode.State = graph.NodeStateCompleted
+	deployNode := graph.NewNode(graph.NodeTypeDeployment, "my-app", "api")
+	deployNode.SetInput("environment", map[string]string{
+		"STRIPE_API_KEY": "${{ external.stripe.api_key }}",
+		"STRIPE_HOST":    "${{ external.stripe.host }}",
+	})
+	_ = g.AddNode(deployNode)
+
+	exec.resolveComponentExpressions(deployNode, envState)
+
+	env := getEnvironmentMap(deployNode.Inputs["environment"])
+	assertEnvVar(t, env, "STRIPE_API_KEY", "sk_test_123")
+	assertEnvVar(t, env, "STRIPE_HOST", "api.stripe.com")
+}
+
+func TestExecuteExternalService_MissingCredentials(t *testing.T) {
+	g := graph.NewGraph("test-env", "test-dc")
+	extNode := newExternalTestNode()
+	_ = g.AddNode(extNode)
+
+	exec := NewExecutor(newMockStateManager(), newTestRegistry(), Options{
+		Parallelism: 1,
+		ComponentVariables: map[string]map[string]interface{}{
+			"my-app": {"stripe_key": "sk_test_123"},
+		},
+	})
+	exec.graph = g
+	exec.datacenterName = "test-dc"
+
+	envState := &types.EnvironmentState{
+		Name:       "test-env",
+		Components: make(map[string]*types.ComponentState),
+	}
+
+	result := exec.executeApply(context.Background(), &planner.ResourceChange{
+		Node:   extNode,
+		Action: planner.ActionCreate,
+	}, envState, nil)
+
+	if result.Success {
+		t.Fatal("expected external service with a missing credential to fail")
+	}
+	if !strings.Contains(result.Error.Error(), "webhook_secret") {
+		t.Errorf("expected error to name the missing credential, got %v", result.Error)
+	}
+}
@@ -0,0 +1,133 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// HeartbeatTimeout is how long a deploy's heartbeat may go without being
+// refreshed before the deploy is presumed to have crashed.
+const HeartbeatTimeout = 2 * time.Minute
+
+// heartbeatInterval is how often a running deploy refreshes its heartbeat.
+var heartbeatInterval = 15 * time.Second
+
+// interruptedReason is recorded on resources left in flight by a deploy
+// that crashed.
+const interruptedReason = "interrupted: the deploy applying it stopped before it finished"
+
+// HeartbeatStale reports whether a heartbeat has stopped being refreshed,
+// meaning the process running the deploy likely died.
+func HeartbeatStale(hb *types.DeployHeartbeat, now time.Time) bool {
+	return hb != nil && now.Sub(hb.UpdatedAt) > HeartbeatTimeout
+}
+
+// heartbeatOwner identifies this process in heartbeats.
+func heartbeatOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+// startHeartbeat records on envState that this process is executing against
+// it and refreshes the record until the returned function is called, which
+// clears it. The heartbeat is saved with the next state flush; stop may be
+// called more than once.
+func (e *Executor) startHeartbeat(envState *types.EnvironmentState) (stop func()) {
+	if e.options.DryRun {
+		return func() {}
+	}
+
+	now := time.Now()
+	envState.Heartbeat = &types.DeployHeartbeat{
+		Owner:     heartbeatOwner(),
+		StartedAt: now,
+		UpdatedAt: now,
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				e.stateMu.Lock()
+				if envState.Heartbeat != nil {
+					envState.Heartbeat.UpdatedAt = time.Now()
+					e.saveStateLocked(envState)
+				}
+				e.stateMu.Unlock()
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+			e.stateMu.Lock()
+			envState.Heartbeat = nil
+			e.stateMu.Unlock()
+		})
+	}
+}
+
+// ReconcileInterrupted takes over an environment whose deploy crashed:
+// resources it left in flight are marked unknown, so that the next deploy
+// re-applies them, and the heartbeat is cleared. It returns what
+// MarkInterrupted does.
+func ReconcileInterrupted(envState *types.EnvironmentState) ([]*types.ResourceState, int) {
+	marked, modules := MarkInterrupted(envState)
+	envState.Heartbeat = nil
+	envState.Status = types.EnvironmentStatusFailed
+	envState.UpdatedAt = time.Now()
+	return marked, modules
+}
+
+// MarkInterrupted marks the resources of envState that are recorded as in
+// flight (pending, provisioning or deleting) as unknown, and environment
+// modules recorded as applying as failed. It returns the resources marked and
+// the number of modules. It must only be called when no deploy is running
+// against the environment, as then anything in flight was left that way by a
+// run that stopped early.
+func MarkInterrupted(envState *types.EnvironmentState) (marked []*types.ResourceState, modules int) {
+	mark := func(resources map[string]*types.ResourceState) {
+		for _, res := range resources {
+			switch res.Status {
+			case types.ResourceStatusPending, types.ResourceStatusProvisioning, types.ResourceStatusDeleting:
+				res.Status = types.ResourceStatusUnknown
+				res.StatusReason = interruptedReason
+				res.UpdatedAt = time.Now()
+				marked = append(marked, res)
+			}
+		}
+	}
+	for _, comp := range envState.Components {
+		mark(comp.Resources)
+		for _, inst := range comp.Instances {
+			mark(inst.Resources)
+		}
+	}
+	for _, mod := range envState.Modules {
+		if mod.Status == types.ModuleStatusApplying {
+			mod.Status = types.ModuleStatusFailed
+			mod.StatusReason = interruptedReason
+			mod.UpdatedAt = time.Now()
+			modules++
+		}
+	}
+	computeComponentStatuses(envState)
+	return marked, modules
+}
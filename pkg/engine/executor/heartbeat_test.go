@@ -0,0 +1,107 @@
+package executor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func TestStartHeartbeat(t *testing.T) {
+	defer func(d time.Duration) { heartbeatInterval = d }(heartbeatInterval)
+	heartbeatInterval = 10 * time.Millisecond
+
+	mgr := newMockStateManager()
+	exec := NewExecutor(mgr, newTestRegistry(), DefaultOptions())
+	exec.datacenterName = "dc"
+	envState := &types.EnvironmentState{Name: "staging"}
+
+	stop := exec.startHeartbeat(envState)
+	if envState.Heartbeat == nil || envState.Heartbeat.Owner == "" {
+		t.Fatalf("expected heartbeat to be recorded, got %+v", envState.Heartbeat)
+	}
+	started := envState.Heartbeat.StartedAt
+
+	time.Sleep(35 * time.Millisecond)
+	exec.stateMu.Lock()
+	saved := mgr.environments["dc/staging"]
+	refreshed := saved != nil && saved.Heartbeat != nil && saved.Heartbeat.UpdatedAt.After(started)
+	exec.stateMu.Unlock()
+	if !refreshed {
+		t.Error("expected the refreshed heartbeat to be saved")
+	}
+
+	stop()
+	stop()
+	if envState.Heartbeat != nil {
+		t.Errorf("expected heartbeat to be cleared, got %+v", envState.Heartbeat)
+	}
+}
+
+func TestStartHeartbeat_DryRun(t *testing.T) {
+	opts := DefaultOptions()
+	opts.DryRun = true
+	exec := NewExecutor(newMockStateManager(), newTestRegistry(), opts)
+	envState := &types.EnvironmentState{Name: "staging"}
+
+	stop := exec.startHeartbeat(envState)
+	defer stop()
+	if envState.Heartbeat != nil {
+		t.Errorf("expected no heartbeat for a dry run, got %+v", envState.Heartbeat)
+	}
+}
+
+func TestHeartbeatStale(t *testing.T) {
+	now := time.Now()
+	if HeartbeatStale(nil, now) {
+		t.Error("expected no heartbeat not to be stale")
+	}
+	if HeartbeatStale(&types.DeployHeartbeat{UpdatedAt: now.Add(-30 * time.Second)}, now) {
+		t.Error("expected a recent heartbeat not to be stale")
+	}
+	if !HeartbeatStale(&types.DeployHeartbeat{UpdatedAt: now.Add(-HeartbeatTimeout - time.Second)}, now) {
+		t.Error("expected an old heartbeat to be stale")
+	}
+}
+
+func TestReconcileInterrupted(t *testing.T) {
+	envState := &types.EnvironmentState{
+		Name:      "staging",
+		Status:    types.EnvironmentStatusProvisioning,
+		Heartbeat: &types.DeployHeartbeat{Owner: "ci:123"},
+		Components: map[string]*types.ComponentState{
+			"app": {
+				Name: "app",
+				Resources: map[string]*types.ResourceState{
+					"database.main":  {Name: "main", Status: types.ResourceStatusReady},
+					"deployment.api": {Name: "api", Status: types.ResourceStatusProvisioning},
+				},
+				Instances: map[string]*types.InstanceState{
+					"canary": {Resources: map[string]*types.ResourceState{
+						"deployment.api": {Name: "api", Status: types.ResourceStatusDeleting},
+					}},
+				},
+			},
+		},
+	}
+
+	if marked, _ := ReconcileInterrupted(envState); len(marked) != 2 {
+		t.Errorf("marked %d resources, want 2", len(marked))
+	}
+
+	app := envState.Components["app"]
+	if app.Resources["database.main"].Status != types.ResourceStatusReady {
+		t.Errorf("expected ready resource to be left alone, got %s", app.Resources["database.main"].Status)
+	}
+	for _, res := range []*types.ResourceState{app.Resources["deployment.api"], app.Instances["canary"].Resources["deployment.api"]} {
+		if res.Status != types.ResourceStatusUnknown || res.StatusReason != interruptedReason {
+			t.Errorf("expected in-flight resource to be marked unknown, got %s (%s)", res.Status, res.StatusReason)
+		}
+	}
+	if app.Status != types.ResourceStatusFailed {
+		t.Errorf("component status = %s, want failed", app.Status)
+	}
+	if envState.Status != types.EnvironmentStatusFailed || envState.Heartbeat != nil {
+		t.Errorf("expected failed environment without a heartbeat, got %s, %+v", envState.Status, envState.Heartbeat)
+	}
+}
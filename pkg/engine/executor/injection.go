@@ -0,0 +1,154 @@
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	v1 "github.com/davidthor/cldctl/pkg/schema/datacenter/v1"
+)
+
+// applyEnvironmentInjections adds the datacenter's environment injections to
+// a workload node's environment. Injections apply in declaration order; a
+// variable the component declares itself, or that an earlier injection set,
+// is never overwritten. Components opt out of injections by name through the
+// graph's InjectionExclusions.
+func (e *Executor) applyEnvironmentInjections(node *graph.Node, envName string) {
+	if !graph.IsWorkloadType(node.Type) {
+		return
+	}
+	dc := e.options.Datacenter
+	if dc == nil || dc.Environment() == nil {
+		return
+	}
+	injections := dc.Environment().Injections()
+	if len(injections) == 0 {
+		return
+	}
+
+	var excluded []string
+	if e.graph != nil {
+		excluded = e.graph.InjectionExclusions[node.Component]
+	}
+
+	dcVars := e.options.DatacenterVariables
+	if dcVars == nil {
+		dcVars = make(map[string]interface{})
+	}
+	obsOutputs := e.observabilityOutputs(node)
+
+	env := getEnvironmentMap(node.Inputs["environment"])
+	injected := false
+	for _, inj := range injections {
+		if isInjectionExcluded(excluded, inj.Name()) {
+			continue
+		}
+		if !e.injectionMatches(inj.When(), node, envName) {
+			continue
+		}
+
+		keys := make([]string, 0, len(inj.Environment()))
+		for k := range inj.Environment() {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if _, exists := env[key]; exists {
+				continue
+			}
+			expr, ok := resolveObservabilityRefs(inj.Environment()[key], obsOutputs)
+			if !ok {
+				continue
+			}
+			value := e.evaluateInputExpression(expr, node, envName, dcVars)
+			if value == nil || value == "" {
+				continue
+			}
+			env[key] = fmt.Sprintf("%v", value)
+			injected = true
+		}
+	}
+
+	if injected {
+		node.Inputs["environment"] = env
+	}
+}
+
+// injectionMatches evaluates an injection's when condition against the
+// workload node. Unlike hook conditions, a condition that cannot be evaluated
+// does not match: injecting proxy or credential settings into a workload the
+// operator did not intend is worse than leaving them out.
+func (e *Executor) injectionMatches(when string, node *graph.Node, envName string) bool {
+	if when == "" {
+		return true
+	}
+
+	expr, diags := hclsyntax.ParseExpression([]byte(when), "inject.hcl", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return false
+	}
+
+	eval := v1.NewEvaluator()
+	eval.SetNodeContext(string(node.Type), node.Name, node.Component, node.Inputs)
+	datacenterName := ""
+	if e.graph != nil {
+		datacenterName = e.graph.Datacenter
+	}
+	eval.SetEnvironmentContext(envName, datacenterName, "", "")
+	if e.options.DatacenterVariables != nil {
+		eval.SetVariables(e.options.DatacenterVariables)
+	}
+
+	matched, err := eval.EvaluateWhen(expr)
+	return err == nil && matched
+}
+
+// observabilityOutputs returns the outputs of the component's completed
+// observability node, or nil if the component has none.
+func (e *Executor) observabilityOutputs(node *graph.Node) map[string]interface{} {
+	if e.graph == nil {
+		return nil
+	}
+	obsNode := e.graph.GetNode(fmt.Sprintf("%s/%s/%s", node.Component, graph.NodeTypeObservability, "observability"))
+	if obsNode == nil || obsNode.State != graph.NodeStateCompleted {
+		return nil
+	}
+	return obsNode.Outputs
+}
+
+// resolveObservabilityRefs substitutes observability.<output> references,
+// both interpolated and bare, with the component's observability outputs.
+// ok is false if a reference is left unresolved, for example because the
+// component has no observability configured.
+func resolveObservabilityRefs(expr string, outputs map[string]interface{}) (string, bool) {
+	if !strings.Contains(expr, "observability.") {
+		return expr, true
+	}
+	for name, value := range outputs {
+		ref := "observability." + name
+		if strings.TrimSpace(expr) == ref {
+			return fmt.Sprintf("%v", value), true
+		}
+		expr = strings.ReplaceAll(expr, "${"+ref+"}", fmt.Sprintf("%v", value))
+	}
+	trimmed := strings.TrimSpace(expr)
+	if strings.Contains(expr, "${observability.") || (strings.HasPrefix(trimmed, "observability.") && !strings.ContainsAny(trimmed, " /:")) {
+		return expr, false
+	}
+	return expr, true
+}
+
+// isInjectionExcluded reports whether name is in a component's exclusion
+// list. "*" excludes every injection.
+func isInjectionExcluded(excluded []string, name string) bool {
+	for _, ex := range excluded {
+		if ex == "*" || ex == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+)
+
+const injectionTestDatacenter = `
+environment {
+  inject "proxy" {
+    when = node.type == "deployment"
+    environment = {
+      HTTPS_PROXY = "http://proxy.internal:3128"
+      NO_PROXY    = "localhost,${node.name}"
+      LOG_LEVEL   = "debug"
+    }
+  }
+
+  inject "region" {
+    environment = {
+      AWS_REGION = variable.region
+      UNSET      = variable.missing
+    }
+  }
+
+  inject "telemetry" {
+    environment = {
+      COLLECTOR_URL = observability.endpoint
+    }
+  }
+}
+`
+
+func newInjectionTestExecutor(t *testing.T) (*Executor, *graph.Graph) {
+	t.Helper()
+	dc, err := datacenter.NewLoader().LoadFromBytes([]byte(injectionTestDatacenter), "datacenter.dc")
+	if err != nil {
+		t.Fatalf("failed to load datacenter: %v", err)
+	}
+	g := graph.NewGraph("staging", "test-dc")
+	return &Executor{
+		graph: g,
+		options: Options{
+			Datacenter:          dc,
+			DatacenterVariables: map[string]interface{}{"region": "eu-west-1"},
+		},
+	}, g
+}
+
+func TestApplyEnvironmentInjections_MatchingWorkload(t *testing.T) {
+	executor, g := newInjectionTestExecutor(t)
+
+	node := graph.NewNode(graph.NodeTypeDeployment, "my-app", "api")
+	node.SetInput("environment", map[string]string{"LOG_LEVEL": "info"})
+	_ = g.AddNode(node)
+
+	executor.applyEnvironmentInjections(node, "staging")
+
+	env := getEnvironmentMap(node.Inputs["environment"])
+	assertEnvVar(t, env, "HTTPS_PROXY", "http://proxy.internal:3128")
+	assertEnvVar(t, env, "NO_PROXY", "localhost,api")
+	assertEnvVar(t, env, "AWS_REGION", "eu-west-1")
+	// Component-declared values are never overwritten
+	assertEnvVar(t, env, "LOG_LEVEL", "info")
+	// Values that resolve to nothing are skipped
+	if _, ok := env["UNSET"]; ok {
+		t.Error("expected UNSET to be skipped")
+	}
+	// Observability references are skipped without an observability node
+	if _, ok := env["COLLECTOR_URL"]; ok {
+		t.Error("expected COLLECTOR_URL to be skipped")
+	}
+}
+
+func TestApplyEnvironmentInjections_WhenConditionNotMatched(t *testing.T) {
+	executor, g := newInjectionTestExecutor(t)
+
+	node := graph.NewNode(graph.NodeTypeFunction, "my-app", "web")
+	_ = g.AddNode(node)
+
+	executor.applyEnvironmentInjections(node, "staging")
+
+	env := getEnvironmentMap(node.Inputs["environment"])
+	if _, ok := env["HTTPS_PROXY"]; ok {
+		t.Error("expected proxy injection to be skipped for functions")
+	}
+	assertEnvVar(t, env, "AWS_REGION", "eu-west-1")
+}
+
+func TestApplyEnvironmentInjections_NonWorkload(t *testing.T) {
+	executor, g := newInjectionTestExecutor(t)
+
+	node := graph.NewNode(graph.NodeTypeDatabase, "my-app", "main")
+	_ = g.AddNode(node)
+
+	executor.applyEnvironmentInjections(node, "staging")
+
+	if _, ok := node.Inputs["environment"]; ok {
+		t.Error("expected no environment on non-workload nodes")
+	}
+}
+
+func TestApplyEnvironmentInjections_ComponentExclusions(t *testing.T) {
+	executor, g := newInjectionTestExecutor(t)
+	g.InjectionExclusions = map[string][]string{
+		"my-app":   {"proxy"},
+		"isolated": {"*"},
+	}
+
+	node := graph.NewNode(graph.NodeTypeDeployment, "my-app", "api")
+	_ = g.AddNode(node)
+	isolated := graph.NewNode(graph.NodeTypeDeployment, "isolated", "api")
+	_ = g.AddNode(isolated)
+
+	executor.applyEnvironmentInjections(node, "staging")
+	executor.applyEnvironmentInjections(isolated, "staging")
+
+	env := getEnvironmentMap(node.Inputs["environment"])
+	if _, ok := env["HTTPS_PROXY"]; ok {
+		t.Error("expected excluded proxy injection to be skipped")
+	}
+	assertEnvVar(t, env, "AWS_REGION", "eu-west-1")
+
+	if len(getEnvironmentMap(isolated.Inputs["environment"])) != 0 {
+		t.Error("expected no injections for a component that opted out of all")
+	}
+}
+
+func TestApplyEnvironmentInjections_ObservabilityOutputs(t *testing.T) {
+	executor, g := newInjectionTestExecutor(t)
+
+	obsNode := graph.NewNode(graph.NodeTypeObservability, "my-app", "observability")
+	obsNode.State = graph.NodeStateCompleted
+	obsNode.Outputs = map[string]interface{}{"endpoint": "http://otel-collector:4318"}
+	_ = g.AddNode(obsNode)
+
+	node := graph.NewNode(graph.NodeTypeCronjob, "my-app", "cleanup")
+	_ = g.AddNode(node)
+
+	executor.applyEnvironmentInjections(node, "staging")
+
+	env := getEnvironmentMap(node.Inputs["environment"])
+	assertEnvVar(t, env, "COLLECTOR_URL", "http://otel-collector:4318")
+}
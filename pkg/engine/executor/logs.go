@@ -0,0 +1,118 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+)
+
+// maxNodeLogMemory is how much plugin output a node keeps in memory. Output
+// beyond this is spilled to a file on disk, and only the tail stays in
+// memory for progress events.
+const maxNodeLogMemory = 256 << 10
+
+var unsafeLogNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// nodeLog captures plugin output for a single node with bounded memory.
+//
+// Output is held in memory until it outgrows maxNodeLogMemory, then written
+// to a temporary file. When the executor has a LogDir, output is instead
+// streamed straight to a file in that directory as it is produced. Either way
+// only the last maxNodeLogMemory bytes are kept in memory.
+type nodeLog struct {
+	nodeID string
+	dir    string // LogDir; empty to spill to a temporary file
+
+	mu   sync.Mutex
+	tail *iac.TailBuffer
+	size int
+	file *os.File
+	err  error
+}
+
+func newNodeLog(nodeID, dir string) *nodeLog {
+	return &nodeLog{
+		nodeID: nodeID,
+		dir:    dir,
+		tail:   iac.NewTailBuffer(maxNodeLogMemory),
+	}
+}
+
+// Write records p. Plugins write stdout and stderr from separate goroutines,
+// so writes are serialized.
+func (l *nodeLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil && l.err == nil && (l.dir != "" || l.size+len(p) > maxNodeLogMemory) {
+		l.openFile()
+	}
+	if l.file != nil {
+		if _, err := l.file.Write(p); err != nil {
+			// Keep the in-memory tail rather than failing the plugin.
+			l.err = err
+			_ = l.file.Close()
+			l.file = nil
+		}
+	}
+
+	_, _ = l.tail.Write(p)
+	l.size += len(p)
+	return len(p), nil
+}
+
+// openFile creates the log file and writes everything captured so far to it.
+// Until the first spill the tail holds the complete output.
+func (l *nodeLog) openFile() {
+	var (
+		f   *os.File
+		err error
+	)
+	name := unsafeLogNameChars.ReplaceAllString(l.nodeID, "_")
+	if l.dir != "" {
+		if err = os.MkdirAll(l.dir, 0755); err == nil {
+			f, err = os.Create(filepath.Join(l.dir, name+".log"))
+		}
+	} else {
+		f, err = os.CreateTemp("", "cldctl-"+name+"-*.log")
+	}
+	if err != nil {
+		l.err = fmt.Errorf("failed to create log file: %w", err)
+		return
+	}
+	if _, err := f.Write(l.tail.Bytes()); err != nil {
+		l.err = err
+		_ = f.Close()
+		return
+	}
+	l.file = f
+}
+
+// Tail returns the most recent output, at most maxNodeLogMemory bytes.
+func (l *nodeLog) Tail() string {
+	return l.tail.String()
+}
+
+// Close closes the log file and returns its path. Temporary spill files are
+// removed unless keep is set; files in LogDir are always kept.
+func (l *nodeLog) Close(keep bool) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return ""
+	}
+	path := l.file.Name()
+	_ = l.file.Close()
+	l.file = nil
+
+	if l.dir == "" && !keep {
+		_ = os.Remove(path)
+		return ""
+	}
+	return path
+}
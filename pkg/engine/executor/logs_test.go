@@ -0,0 +1,80 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNodeLog_SmallOutputStaysInMemory(t *testing.T) {
+	l := newNodeLog("app/deployment/api", "")
+	_, _ = l.Write([]byte("building...\n"))
+
+	if got := l.Tail(); got != "building...\n" {
+		t.Errorf("Tail() = %q", got)
+	}
+	if path := l.Close(true); path != "" {
+		t.Errorf("expected no log file for small output, got %s", path)
+	}
+}
+
+func TestNodeLog_SpillsLargeOutputToDisk(t *testing.T) {
+	l := newNodeLog("app/deployment/api", "")
+	line := strings.Repeat("x", 1023) + "\n"
+	for i := 0; i < (maxNodeLogMemory/len(line))*2; i++ {
+		_, _ = l.Write([]byte(line))
+	}
+
+	if n := len(l.Tail()); n > maxNodeLogMemory {
+		t.Errorf("tail holds %d bytes, want at most %d", n, maxNodeLogMemory)
+	}
+
+	path := l.Close(true)
+	if path == "" {
+		t.Fatal("expected large output to be spilled to a file")
+	}
+	defer os.Remove(path)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat log file: %v", err)
+	}
+	if want := int64(maxNodeLogMemory * 2); info.Size() != want {
+		t.Errorf("log file has %d bytes, want %d", info.Size(), want)
+	}
+}
+
+func TestNodeLog_RemovesSpillFileOnSuccess(t *testing.T) {
+	l := newNodeLog("app/deployment/api", "")
+	_, _ = l.Write(make([]byte, maxNodeLogMemory+1))
+
+	l.mu.Lock()
+	path := l.file.Name()
+	l.mu.Unlock()
+
+	if got := l.Close(false); got != "" {
+		t.Errorf("Close(false) = %q, want empty", got)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected spill file %s to be removed", path)
+	}
+}
+
+func TestNodeLog_StreamsToLogDir(t *testing.T) {
+	dir := t.TempDir()
+	l := newNodeLog("app/deployment/api", dir)
+	_, _ = l.Write([]byte("hello\n"))
+
+	path := l.Close(false)
+	if want := filepath.Join(dir, "app_deployment_api.log"); path != want {
+		t.Errorf("Close() = %q, want %q", path, want)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log file: %v", err)
+	}
+	if string(data) != "hello\n" {
+		t.Errorf("log file = %q", data)
+	}
+}
@@ -0,0 +1,32 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+)
+
+// setPhase records the phase a node's plugin reported and notes the change
+// in the node's log, so that the log shows how long each phase took. It
+// returns the node's current phase.
+func (e *Executor) setPhase(nodeID string, phase iac.Phase, logBuf io.Writer) iac.Phase {
+	if phase == "" {
+		return e.phase(nodeID)
+	}
+	prev, loaded := e.phases.Swap(nodeID, phase)
+	if (!loaded || prev.(iac.Phase) != phase) && logBuf != nil {
+		fmt.Fprintf(logBuf, "[phase] %s %s\n", time.Now().Format(time.RFC3339), phase)
+	}
+	return phase
+}
+
+// phase returns the phase a node is in, or "" if its plugin hasn't reported
+// one.
+func (e *Executor) phase(nodeID string) iac.Phase {
+	if p, ok := e.phases.Load(nodeID); ok {
+		return p.(iac.Phase)
+	}
+	return ""
+}
@@ -0,0 +1,71 @@
+package executor
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/iac"
+)
+
+func TestSetPhase_RecordsChangesInLog(t *testing.T) {
+	exec := NewExecutor(newMockStateManager(), newTestRegistry(), DefaultOptions())
+	var log bytes.Buffer
+
+	if got := exec.setPhase("app/service/api", "", &log); got != "" {
+		t.Errorf("phase before any report = %q, want empty", got)
+	}
+	exec.setPhase("app/service/api", iac.PhasePulling, &log)
+	exec.setPhase("app/service/api", iac.PhasePulling, &log)
+	if got := exec.setPhase("app/service/api", "", &log); got != iac.PhasePulling {
+		t.Errorf("message without a phase should keep the current one, got %q", got)
+	}
+	exec.setPhase("app/service/api", iac.PhaseWaitingHealthy, &log)
+
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected one log line per phase change, got %q", log.String())
+	}
+	if !strings.HasPrefix(lines[0], "[phase] ") || !strings.HasSuffix(lines[0], " pulling") {
+		t.Errorf("unexpected log line %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], " waiting-healthy") {
+		t.Errorf("unexpected log line %q", lines[1])
+	}
+	if got := exec.phase("app/service/api"); got != iac.PhaseWaitingHealthy {
+		t.Errorf("phase() = %q, want waiting-healthy", got)
+	}
+}
+
+func TestWatchSlowNode_ReportsPhase(t *testing.T) {
+	var mu sync.Mutex
+	var events []ProgressEvent
+	opts := DefaultOptions()
+	opts.SlowNodes = SlowNodeOptions{Thresholds: map[string]time.Duration{"*": 10 * time.Millisecond}}
+	opts.OnProgress = func(event ProgressEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+	exec := NewExecutor(newMockStateManager(), newTestRegistry(), opts)
+
+	node := graph.NewNode(graph.NodeTypeService, "app", "api")
+	exec.setPhase(node.ID, iac.PhaseWaitingHealthy, nil)
+	stop := exec.watchSlowNode(node, "create", "staging")
+	time.Sleep(25 * time.Millisecond)
+	stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) == 0 {
+		t.Fatal("expected still running events")
+	}
+	for _, event := range events {
+		if event.Phase != iac.PhaseWaitingHealthy || !strings.HasPrefix(event.Message, "still running (waiting-healthy), ") {
+			t.Errorf("unexpected event: %+v", event)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package executor
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// Range used by the built-in allocator for port nodes. Avoids privileged
+// ports and common dev ports.
+const (
+	dynamicPortMin = 10000
+	dynamicPortMax = 60000
+)
+
+// portSearchWindow bounds how far past a resource's base port the allocator
+// searches for a free port (e.g. 5432-6431 for databases).
+const portSearchWindow = 1000
+
+// hostPortAvailable reports whether a TCP port can currently be bound on the
+// host. It is a variable so tests can simulate ports held by other processes.
+var hostPortAvailable = func(port int) bool {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return false
+	}
+	_ = l.Close()
+	return true
+}
+
+// allocatePort returns the host port for a node and records it in the
+// environment's port registry. Resolution order:
+//
+//  1. The node's existing registry entry (allocations are sticky)
+//  2. The port recorded in the node's resource state by a previous deploy, so
+//     environments created before the registry keep their ports
+//  3. The first port starting at preferred, wrapping within [min, max), that
+//     is not allocated to another resource and can be bound on the host
+//
+// If no port in the range is free, preferred is returned unrecorded and the
+// resource will fail to bind with a clear error from the runtime.
+func (e *Executor) allocatePort(node *graph.Node, preferred, min, max int) int {
+	e.stateMu.Lock()
+	defer e.stateMu.Unlock()
+
+	envState := e.envState
+	if envState == nil {
+		return preferred
+	}
+	if envState.Ports == nil {
+		envState.Ports = make(map[string]int)
+	}
+
+	if port, ok := envState.Ports[node.ID]; ok {
+		return port
+	}
+
+	taken := e.allocatedPorts(envState, node.ID)
+
+	if port := priorPort(envState, node); port > 0 && !taken[port] {
+		envState.Ports[node.ID] = port
+		return port
+	}
+
+	span := max - min
+	if span <= 0 {
+		return preferred
+	}
+	for i := 0; i < span; i++ {
+		port := min + ((preferred-min)+i)%span
+		if taken[port] || !hostPortAvailable(port) {
+			continue
+		}
+		envState.Ports[node.ID] = port
+		return port
+	}
+	return preferred
+}
+
+// reservePort records an explicitly chosen port (an environment override or a
+// datacenter hook result) so the allocator never hands it to another resource.
+func (e *Executor) reservePort(node *graph.Node, port int) {
+	e.stateMu.Lock()
+	defer e.stateMu.Unlock()
+
+	if e.envState == nil {
+		return
+	}
+	if e.envState.Ports == nil {
+		e.envState.Ports = make(map[string]int)
+	}
+	e.envState.Ports[node.ID] = port
+}
+
+// releasePortLocked removes a node's allocation from the registry.
+// MUST be called while holding e.stateMu.
+func releasePortLocked(envState *types.EnvironmentState, node *graph.Node) {
+	delete(envState.Ports, node.ID)
+}
+
+// allocatedPorts returns every port held by a resource other than owner,
+// including environment port overrides that may not have been applied yet.
+func (e *Executor) allocatedPorts(envState *types.EnvironmentState, owner string) map[int]bool {
+	taken := make(map[int]bool)
+	for id, port := range envState.Ports {
+		if id != owner {
+			taken[port] = true
+		}
+	}
+	for _, ports := range e.options.ComponentPorts {
+		for _, port := range ports {
+			taken[port] = true
+		}
+	}
+	return taken
+}
+
+// priorPort returns the "port" output recorded for a node by a previous
+// deploy, or 0 if there is none.
+func priorPort(envState *types.EnvironmentState, node *graph.Node) int {
+	compState := envState.Components[node.Component]
+	if compState == nil {
+		return 0
+	}
+	res := compState.Resources[resourceKey(node)]
+	if res == nil || res.Outputs == nil {
+		return 0
+	}
+	switch v := res.Outputs["port"].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	case string:
+		p, _ := strconv.Atoi(v)
+		return p
+	}
+	return 0
+}
@@ -2,6 +2,9 @@ package executor
 
 import (
 	"testing"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/state/types"
 )
 
 func TestStablePortForNode_Deterministic(t *testing.T) {
@@ -54,3 +57,126 @@ func TestStablePortForNode_Range(t *testing.T) {
 		}
 	}
 }
+
+func withHostPorts(t *testing.T, inUse ...int) {
+	t.Helper()
+	busy := make(map[int]bool, len(inUse))
+	for _, p := range inUse {
+		busy[p] = true
+	}
+	orig := hostPortAvailable
+	hostPortAvailable = func(port int) bool { return !busy[port] }
+	t.Cleanup(func() { hostPortAvailable = orig })
+}
+
+func portNode(component, name string) *graph.Node {
+	return &graph.Node{
+		ID:        component + "/port/" + name,
+		Type:      graph.NodeTypePort,
+		Component: component,
+		Name:      name,
+	}
+}
+
+func TestAllocatePort_RecordsAndIsSticky(t *testing.T) {
+	withHostPorts(t)
+	e := &Executor{envState: &types.EnvironmentState{Name: "dev"}}
+	node := portNode("my-app", "api")
+
+	port := e.allocatePort(node, 12345, dynamicPortMin, dynamicPortMax)
+	if port != 12345 {
+		t.Fatalf("expected preferred port 12345, got %d", port)
+	}
+	if e.envState.Ports[node.ID] != 12345 {
+		t.Errorf("expected allocation recorded in state, got %v", e.envState.Ports)
+	}
+
+	// Once allocated, the port is reused even if it is now bound on the host
+	// (typically by the resource itself).
+	withHostPorts(t, 12345)
+	if again := e.allocatePort(node, 20000, dynamicPortMin, dynamicPortMax); again != 12345 {
+		t.Errorf("expected sticky port 12345, got %d", again)
+	}
+}
+
+func TestAllocatePort_ResolvesCollisions(t *testing.T) {
+	withHostPorts(t)
+	e := &Executor{envState: &types.EnvironmentState{
+		Name:  "dev",
+		Ports: map[string]int{"other/port/web": 12345},
+	}}
+
+	port := e.allocatePort(portNode("my-app", "api"), 12345, dynamicPortMin, dynamicPortMax)
+	if port != 12346 {
+		t.Errorf("expected next free port 12346, got %d", port)
+	}
+}
+
+func TestAllocatePort_SkipsPortsInUseOnHost(t *testing.T) {
+	withHostPorts(t, 5432, 5433)
+	e := &Executor{envState: &types.EnvironmentState{Name: "dev"}}
+
+	port := e.allocatePort(portNode("my-app", "main"), 5432, 5432, 5432+portSearchWindow)
+	if port != 5434 {
+		t.Errorf("expected 5434, got %d", port)
+	}
+}
+
+func TestAllocatePort_WrapsWithinRange(t *testing.T) {
+	withHostPorts(t, 59999)
+	e := &Executor{envState: &types.EnvironmentState{Name: "dev"}}
+
+	port := e.allocatePort(portNode("my-app", "api"), 59999, dynamicPortMin, dynamicPortMax)
+	if port != dynamicPortMin {
+		t.Errorf("expected wrap to %d, got %d", dynamicPortMin, port)
+	}
+}
+
+func TestAllocatePort_AvoidsEnvironmentOverrides(t *testing.T) {
+	withHostPorts(t)
+	e := &Executor{
+		envState: &types.EnvironmentState{Name: "dev"},
+		options: Options{ComponentPorts: map[string]map[string]int{
+			"other": {"web": 12345},
+		}},
+	}
+
+	port := e.allocatePort(portNode("my-app", "api"), 12345, dynamicPortMin, dynamicPortMax)
+	if port == 12345 {
+		t.Error("expected allocator to avoid a port reserved by an environment override")
+	}
+}
+
+func TestAllocatePort_AdoptsPriorDeployPort(t *testing.T) {
+	withHostPorts(t, 15000)
+	node := portNode("my-app", "api")
+	e := &Executor{envState: &types.EnvironmentState{
+		Name: "dev",
+		Components: map[string]*types.ComponentState{
+			"my-app": {Resources: map[string]*types.ResourceState{
+				resourceKey(node): {Outputs: map[string]interface{}{"port": float64(15000)}},
+			}},
+		},
+	}}
+
+	if port := e.allocatePort(node, 20000, dynamicPortMin, dynamicPortMax); port != 15000 {
+		t.Errorf("expected previously deployed port 15000, got %d", port)
+	}
+}
+
+func TestReleasePort(t *testing.T) {
+	withHostPorts(t)
+	e := &Executor{envState: &types.EnvironmentState{Name: "dev"}}
+	api := portNode("my-app", "api")
+	web := portNode("my-app", "web")
+
+	e.allocatePort(api, 12345, dynamicPortMin, dynamicPortMax)
+	releasePortLocked(e.envState, api)
+
+	if _, ok := e.envState.Ports[api.ID]; ok {
+		t.Fatal("expected allocation to be released")
+	}
+	if port := e.allocatePort(web, 12345, dynamicPortMin, dynamicPortMax); port != 12345 {
+		t.Errorf("expected released port to be reusable, got %d", port)
+	}
+}
@@ -0,0 +1,172 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// Preflight checks, before a plan is executed, that the hooks of the
+// resources it changes can run, so that problems such as expired cloud
+// credentials are reported up front rather than after the builds and
+// resources ahead of the first failing module. For each resource, the
+// credentials of its hook's modules are resolved (assuming any roles) and
+// checked by plugins that implement iac.CredentialChecker, and the hook's
+// validate modules are previewed. Each distinct check runs once.
+//
+// Resources whose hook can't be matched are left for execution to report.
+func (e *Executor) Preflight(ctx context.Context, plan *planner.Plan) error {
+	if e.options.Datacenter == nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var failures []string
+	fail := func(nodeID, moduleName string, err error) {
+		msg := fmt.Sprintf("%s: module %s: %v", nodeID, moduleName, err)
+		if !seen[msg] {
+			seen[msg] = true
+			failures = append(failures, msg)
+		}
+	}
+
+	for _, change := range plan.Changes {
+		if change.Node == nil || change.Action == planner.ActionNoop {
+			continue
+		}
+		node := change.Node
+		hook, err := e.matchHook(node)
+		if err != nil {
+			continue
+		}
+
+		for _, module := range hook.Modules() {
+			if when := module.When(); when != "" && !e.evaluateWhenCondition(when, node) {
+				continue
+			}
+			pluginName := module.Plugin()
+			if pluginName == "" {
+				pluginName = "native"
+			}
+			plugin, err := e.iacRegistry.Get(pluginName)
+			if err != nil {
+				fail(node.ID, module.Name(), err)
+				continue
+			}
+			creds, credEnv, err := e.preflightCredentials(ctx, hook, module, change, plan.Environment)
+			if err != nil {
+				fail(node.ID, module.Name(), err)
+				continue
+			}
+			if _, ok := plugin.(iac.CredentialChecker); !ok {
+				continue
+			}
+			key := checkKey("credentials", pluginName, creds)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if err := iac.CheckCredentials(ctx, plugin, credEnv); err != nil {
+				fail(node.ID, module.Name(), fmt.Errorf("credential check failed: %w", err))
+			}
+		}
+
+		for _, module := range hook.Validate() {
+			if when := module.When(); when != "" && !e.evaluateWhenCondition(when, node) {
+				continue
+			}
+			if err := e.runValidateModule(ctx, hook, module, change, plan.Environment, seen); err != nil {
+				fail(node.ID, module.Name(), err)
+			}
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("preflight checks failed; nothing was deployed:\n  %s", strings.Join(failures, "\n  "))
+}
+
+// preflightCredentials resolves the credentials a hook module runs with and
+// the environment that scopes them.
+func (e *Executor) preflightCredentials(ctx context.Context, hook datacenter.Hook, module datacenter.Module, change *planner.ResourceChange, envName string) (*types.ModuleCredentials, map[string]string, error) {
+	creds, err := e.resolveModuleCredentials(datacenter.ModuleHook(hook, module), module, change.Node, envName)
+	if err != nil {
+		return nil, nil, err
+	}
+	credEnv, err := e.credentialEnvironment(ctx, creds, CredentialSessionName(module.Name()))
+	if err != nil {
+		return nil, nil, err
+	}
+	return creds, credEnv, nil
+}
+
+// runValidateModule previews one of a hook's validate modules for a
+// resource, unless an identical preview already ran.
+func (e *Executor) runValidateModule(ctx context.Context, hook datacenter.Hook, module datacenter.Module, change *planner.ResourceChange, envName string, seen map[string]bool) error {
+	node := change.Node
+
+	modulePath := module.Build()
+	if modulePath == "" {
+		modulePath = module.Source()
+	}
+	if modulePath == "" {
+		return fmt.Errorf("validate module has no build or source path")
+	}
+	if !filepath.IsAbs(modulePath) {
+		modulePath = filepath.Join(filepath.Dir(e.options.Datacenter.SourcePath()), modulePath)
+	}
+
+	pluginName := module.Plugin()
+	if pluginName == "" {
+		pluginName = "native"
+	}
+	plugin, err := e.iacRegistry.Get(pluginName)
+	if err != nil {
+		return err
+	}
+	moduleHook := datacenter.ModuleHook(hook, module)
+	sandbox := moduleSandbox(e.options.Datacenter, moduleHook)
+	if err := checkSandbox(module.Name(), pluginName, plugin, sandbox); err != nil {
+		return err
+	}
+
+	creds, credEnv, err := e.preflightCredentials(ctx, hook, module, change, envName)
+	if err != nil {
+		return err
+	}
+	inputs := e.buildModuleInputsWithCrossRef(module, node, envName, map[string]map[string]interface{}{})
+
+	key := checkKey("validate", modulePath, pluginName, creds, inputs)
+	if seen[key] {
+		return nil
+	}
+	seen[key] = true
+
+	if _, err := plugin.Preview(ctx, iac.RunOptions{
+		ModuleSource: modulePath,
+		Inputs:       inputs,
+		Environment:  credEnv,
+		Sandbox:      sandbox,
+		Stdout:       io.Discard,
+		Stderr:       io.Discard,
+	}); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	return nil
+}
+
+// checkKey identifies a preflight check by what it runs with, so checks
+// shared by many resources run once.
+func checkKey(parts ...interface{}) string {
+	data, _ := json.Marshal(parts)
+	return string(data)
+}
@@ -0,0 +1,118 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/iac"
+)
+
+const preflightTestDatacenter = `
+environment {
+  database {
+    validate "account" {
+      plugin = "checker"
+      build  = "./modules/account"
+      inputs = {
+        engine = node.inputs.type
+      }
+    }
+    module "db" {
+      plugin = "cloud"
+      build  = "./modules/db"
+    }
+    outputs = {
+      host = module.db.host
+      port = module.db.port
+      url  = module.db.url
+    }
+  }
+}
+`
+
+// checkingPlugin is a plugin whose credential check and previews can fail.
+type checkingPlugin struct {
+	mockPlugin
+	credsErr   error
+	previewErr error
+	checks     int
+	previews   int
+}
+
+func (p *checkingPlugin) CheckCredentials(ctx context.Context, env map[string]string) error {
+	p.checks++
+	return p.credsErr
+}
+
+func (p *checkingPlugin) Preview(ctx context.Context, opts iac.RunOptions) (*iac.PreviewResult, error) {
+	p.previews++
+	if p.previewErr != nil {
+		return nil, p.previewErr
+	}
+	return &iac.PreviewResult{}, nil
+}
+
+func TestPreflight(t *testing.T) {
+	newExecutor := func(checker, cloud *checkingPlugin) *Executor {
+		registry := iac.NewRegistry()
+		registry.Register("checker", func() (iac.Plugin, error) { return checker, nil })
+		registry.Register("cloud", func() (iac.Plugin, error) { return cloud, nil })
+		opts := DefaultOptions()
+		opts.Datacenter = loadHCLDatacenter(t, preflightTestDatacenter)
+		return NewExecutor(newMockStateManager(), registry, opts)
+	}
+
+	plan := &planner.Plan{Environment: "test"}
+	for _, name := range []string{"main", "cache"} {
+		node := graph.NewNode(graph.NodeTypeDatabase, "api", name)
+		node.SetInput("type", "postgres")
+		plan.Changes = append(plan.Changes, &planner.ResourceChange{Node: node, Action: planner.ActionCreate})
+	}
+	plan.Changes = append(plan.Changes, &planner.ResourceChange{
+		Node:   graph.NewNode(graph.NodeTypeDatabase, "api", "unchanged"),
+		Action: planner.ActionNoop,
+	})
+
+	t.Run("passes", func(t *testing.T) {
+		checker, cloud := &checkingPlugin{}, &checkingPlugin{}
+		if err := newExecutor(checker, cloud).Preflight(context.Background(), plan); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// Identical checks for the two databases run once
+		if checker.previews != 1 {
+			t.Errorf("expected the validate module to be previewed once, got %d", checker.previews)
+		}
+		if cloud.checks != 1 {
+			t.Errorf("expected credentials to be checked once, got %d", cloud.checks)
+		}
+		if cloud.previews != 0 {
+			t.Error("expected hook modules not to be previewed")
+		}
+	})
+
+	t.Run("expired credentials", func(t *testing.T) {
+		checker, cloud := &checkingPlugin{}, &checkingPlugin{credsErr: errors.New("the security token has expired")}
+		err := newExecutor(checker, cloud).Preflight(context.Background(), plan)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if !strings.Contains(err.Error(), "module db: credential check failed: the security token has expired") {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if strings.Contains(err.Error(), "unchanged") {
+			t.Errorf("expected unchanged resources to be skipped: %v", err)
+		}
+	})
+
+	t.Run("validate module fails", func(t *testing.T) {
+		checker, cloud := &checkingPlugin{previewErr: errors.New("no access to account")}, &checkingPlugin{}
+		err := newExecutor(checker, cloud).Preflight(context.Background(), plan)
+		if err == nil || !strings.Contains(err.Error(), "module account: validation failed: no access to account") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
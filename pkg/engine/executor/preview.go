@@ -0,0 +1,201 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// executePreview is the dry-run counterpart of executeApply. It resolves the
+// node's inputs and asks each matching hook module's plugin what it would
+// change, recording the result on change.Preview. Nothing is applied and no
+// state is written.
+//
+// Module outputs are not known until apply, so downstream nodes see the
+// outputs recorded by the previous deploy (if any). Inputs that reference
+// outputs of resources that do not exist yet stay unresolved in the preview.
+func (e *Executor) executePreview(ctx context.Context, change *planner.ResourceChange, envState *types.EnvironmentState, logBuf io.Writer) *NodeResult {
+	result := &NodeResult{
+		NodeID: change.Node.ID,
+		Action: change.Action,
+	}
+
+	e.resolveComponentExpressions(change.Node, envState)
+
+	if change.CurrentState != nil {
+		result.Outputs = change.CurrentState.Outputs
+	}
+
+	switch {
+	case change.Node.Type == graph.NodeTypePort:
+		result.Outputs = map[string]interface{}{"port": e.previewPort(change, envState)}
+		result.Success = true
+		return result
+	case change.Node.Type == graph.NodeTypeDatabaseUser && !e.hasMatchingHook(change.Node):
+		result.Success = true
+		return result
+	case change.Node.Type == graph.NodeTypeNetworkPolicy && !e.hasMatchingHook(change.Node):
+		result.Success = true
+		return result
+	case change.Node.Type == graph.NodeTypeExternal:
+		outputs, err := externalServiceOutputs(change.Node)
+		if err != nil {
+			result.Error = err
+			return result
+		}
+		result.Outputs = outputs
+		result.Success = true
+		return result
+	}
+
+	previews, err := e.previewHookModules(ctx, change, envState.Name, logBuf)
+	change.Preview = previews
+	if err != nil {
+		result.Error = fmt.Errorf("failed to preview hook: %w", err)
+		return result
+	}
+
+	for _, p := range previews {
+		if p.Error != "" {
+			result.Error = fmt.Errorf("module %s preview failed: %s", p.Module, p.Error)
+			return result
+		}
+	}
+
+	result.Success = true
+	return result
+}
+
+// previewHookModules calls Preview on every module of the node's matching
+// hook, passing each module's previously recorded IaC state.
+func (e *Executor) previewHookModules(ctx context.Context, change *planner.ResourceChange, envName string, logBuf io.Writer) ([]planner.ModulePreview, error) {
+	node := change.Node
+	matchedHook, err := e.matchHook(node)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := matchedHook.Modules()
+	if len(modules) == 0 {
+		return nil, fmt.Errorf("hook has no modules defined for %s", node.Type)
+	}
+
+	// The modules of aggregate hooks take every resource they match at once,
+	// so there is nothing to preview for a single one. Those of shared hooks
+	// are provisioned once for the datacenter, and usually already are.
+	if matchedHook.Aggregate() || matchedHook.Shared() {
+		return nil, nil
+	}
+
+	dcDir := filepath.Dir(e.options.Datacenter.SourcePath())
+
+	// Module outputs are unknown during preview, so cross-module references
+	// are left unresolved.
+	moduleOutputs := make(map[string]map[string]interface{})
+
+	var previews []planner.ModulePreview
+	for _, module := range modules {
+		if when := module.When(); when != "" && !e.evaluateWhenCondition(when, node) {
+			continue
+		}
+
+		modulePath := module.Build()
+		if modulePath == "" {
+			modulePath = module.Source()
+		}
+		if modulePath == "" {
+			return previews, fmt.Errorf("module %s has no build or source path", module.Name())
+		}
+		if !filepath.IsAbs(modulePath) {
+			modulePath = filepath.Join(dcDir, modulePath)
+		}
+
+		pluginName := module.Plugin()
+		if pluginName == "" {
+			pluginName = "native"
+		}
+		preview := planner.ModulePreview{
+			Module: module.Name(),
+			Plugin: pluginName,
+		}
+
+		plugin, err := e.iacRegistry.Get(pluginName)
+		if err != nil {
+			return previews, fmt.Errorf("failed to get IaC plugin %q: %w", pluginName, err)
+		}
+		moduleHook := datacenter.ModuleHook(matchedHook, module)
+		sandbox := moduleSandbox(e.options.Datacenter, moduleHook)
+		if err := checkSandbox(module.Name(), pluginName, plugin, sandbox); err != nil {
+			return previews, err
+		}
+
+		creds, err := e.resolveModuleCredentials(moduleHook, module, node, envName)
+		if err != nil {
+			return previews, fmt.Errorf("module %s: %w", module.Name(), err)
+		}
+		credEnv, err := e.credentialEnvironment(ctx, creds, CredentialSessionName(module.Name()))
+		if err != nil {
+			return previews, fmt.Errorf("module %s: %w", module.Name(), err)
+		}
+
+		runOpts := iac.RunOptions{
+			ModuleSource: modulePath,
+			Inputs:       e.buildModuleInputsWithCrossRef(module, node, envName, moduleOutputs),
+			Environment:  credEnv,
+			Sandbox:      sandbox,
+			Stdout:       logBuf,
+			Stderr:       logBuf,
+		}
+		if prior := priorModuleState(change.CurrentState, module.Name()); len(prior) > 0 {
+			runOpts.StateReader = bytes.NewReader(prior)
+		}
+
+		previewResult, err := plugin.Preview(ctx, runOpts)
+		if err != nil {
+			preview.Error = err.Error()
+		} else if previewResult != nil {
+			preview.Changes = previewResult.Changes
+		}
+		previews = append(previews, preview)
+	}
+
+	return previews, nil
+}
+
+// priorModuleState returns the IaC state a module recorded on the last apply.
+// Single-module hooks store it in the resource's legacy IaCState field.
+func priorModuleState(res *types.ResourceState, moduleName string) []byte {
+	if res == nil {
+		return nil
+	}
+	if ms, ok := res.ModuleStates[moduleName]; ok && ms != nil {
+		return ms.IaCState
+	}
+	return res.IaCState
+}
+
+// previewPort reports the port a port node would get without reserving it:
+// the environment override, the port recorded by a previous deploy, or the
+// built-in allocator's preferred port.
+func (e *Executor) previewPort(change *planner.ResourceChange, envState *types.EnvironmentState) int {
+	if compPorts, ok := e.options.ComponentPorts[change.Node.Component]; ok {
+		if port, ok := compPorts[change.Node.Name]; ok {
+			return port
+		}
+	}
+	if port, ok := envState.Ports[change.Node.ID]; ok {
+		return port
+	}
+	if port := priorPort(envState, change.Node); port > 0 {
+		return port
+	}
+	return stablePortForNode(envState.Name, change.Node.Component, change.Node.Name)
+}
@@ -0,0 +1,133 @@
+package executor
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+)
+
+// DefaultBuildParallelism is the max number of docker builds run at once
+// when no limit is configured.
+const DefaultBuildParallelism = 4
+
+// nodePriority returns the order in which ready nodes of a type are given a
+// slot when more are ready than can run: lower goes first. Nodes that
+// complete almost instantly (ports, secrets, policies) go ahead of resources
+// that take a while to provision, so they aren't left waiting behind builds
+// and the nodes that depend on them can start sooner.
+func nodePriority(t graph.NodeType) int {
+	switch t {
+	case graph.NodeTypePort, graph.NodeTypeSecret, graph.NodeTypeEncryptionKey,
+		graph.NodeTypeDatabaseUser, graph.NodeTypeNetworkPolicy, graph.NodeTypeRouteAuth,
+		graph.NodeTypeObservability, graph.NodeTypeExternal:
+		return 0
+	case graph.NodeTypeDockerBuild:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// slots limits how many nodes run at once in ExecuteParallel. Waiting nodes
+// are given slots in priority order (see nodePriority), then in the order
+// they started waiting. Docker builds are also limited separately, so a
+// burst of builds can't take every slot.
+type slots struct {
+	mu         sync.Mutex
+	limit      int
+	buildLimit int
+	running    int
+	builds     int
+	seq        int
+	waiting    []*slotWaiter // Sorted by priority, then seq
+}
+
+type slotWaiter struct {
+	priority int
+	seq      int
+	build    bool
+	granted  chan struct{}
+}
+
+func newSlots(limit, buildLimit int) *slots {
+	return &slots{limit: limit, buildLimit: buildLimit}
+}
+
+// acquire blocks until a node of the given type is given a slot, or ctx is
+// done. Each successful acquire must be paired with a release.
+func (s *slots) acquire(ctx context.Context, nodeType graph.NodeType) error {
+	w := &slotWaiter{
+		priority: nodePriority(nodeType),
+		build:    nodeType == graph.NodeTypeDockerBuild,
+		granted:  make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	w.seq = s.seq
+	s.seq++
+	i := sort.Search(len(s.waiting), func(i int) bool { return s.waiting[i].priority > w.priority })
+	s.waiting = append(s.waiting, nil)
+	copy(s.waiting[i+1:], s.waiting[i:])
+	s.waiting[i] = w
+	s.dispatchLocked()
+	s.mu.Unlock()
+
+	select {
+	case <-w.granted:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		select {
+		case <-w.granted:
+			// Given a slot just as ctx was done; hand it on
+			s.releaseLocked(w.build)
+		default:
+			for i, other := range s.waiting {
+				if other == w {
+					s.waiting = append(s.waiting[:i], s.waiting[i+1:]...)
+					break
+				}
+			}
+		}
+		return ctx.Err()
+	}
+}
+
+// release frees the slot held by a node of the given type.
+func (s *slots) release(nodeType graph.NodeType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releaseLocked(nodeType == graph.NodeTypeDockerBuild)
+}
+
+func (s *slots) releaseLocked(build bool) {
+	s.running--
+	if build {
+		s.builds--
+	}
+	s.dispatchLocked()
+}
+
+// dispatchLocked gives free slots to waiting nodes. Builds over the build
+// limit are passed over, leaving their slots to other nodes.
+func (s *slots) dispatchLocked() {
+	kept := s.waiting[:0]
+	for _, w := range s.waiting {
+		if s.running >= s.limit || (w.build && s.builds >= s.buildLimit) {
+			kept = append(kept, w)
+			continue
+		}
+		s.running++
+		if w.build {
+			s.builds++
+		}
+		close(w.granted)
+	}
+	for i := len(kept); i < len(s.waiting); i++ {
+		s.waiting[i] = nil
+	}
+	s.waiting = kept
+}
@@ -0,0 +1,117 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+)
+
+// waitFor queues an acquire of a slot for nodeType, returning once it's
+// waiting. nodeType is sent to granted once it's given a slot.
+func waitFor(t *testing.T, s *slots, nodeType graph.NodeType, granted chan<- graph.NodeType) {
+	t.Helper()
+	s.mu.Lock()
+	waiting := len(s.waiting)
+	s.mu.Unlock()
+
+	go func() {
+		if err := s.acquire(context.Background(), nodeType); err == nil {
+			granted <- nodeType
+		}
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		n := len(s.waiting)
+		s.mu.Unlock()
+		if n > waiting {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("%s never started waiting", nodeType)
+}
+
+func TestSlots_PriorityOrder(t *testing.T) {
+	s := newSlots(1, 1)
+	if err := s.acquire(context.Background(), graph.NodeTypeDatabase); err != nil {
+		t.Fatal(err)
+	}
+
+	queued := []graph.NodeType{graph.NodeTypeDockerBuild, graph.NodeTypeDeployment, graph.NodeTypeSecret, graph.NodeTypePort}
+	granted := make(chan graph.NodeType, len(queued))
+	for _, nodeType := range queued {
+		waitFor(t, s, nodeType, granted)
+	}
+
+	held := graph.NodeTypeDatabase
+	var order []graph.NodeType
+	for range queued {
+		s.release(held)
+		select {
+		case held = <-granted:
+			order = append(order, held)
+		case <-time.After(time.Second):
+			t.Fatalf("no node was given the freed slot; order so far %v", order)
+		}
+	}
+
+	want := "[secret port deployment dockerBuild]"
+	if got := fmt.Sprint(order); got != want {
+		t.Errorf("order = %s, want %s", got, want)
+	}
+}
+
+func TestSlots_BuildLimit(t *testing.T) {
+	s := newSlots(3, 1)
+	if err := s.acquire(context.Background(), graph.NodeTypeDockerBuild); err != nil {
+		t.Fatal(err)
+	}
+	build := make(chan graph.NodeType, 1)
+	waitFor(t, s, graph.NodeTypeDockerBuild, build)
+
+	// A build over the limit doesn't hold up other nodes
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.acquire(ctx, graph.NodeTypeDeployment); err != nil {
+		t.Fatalf("expected the deployment to get a slot past the waiting build: %v", err)
+	}
+
+	select {
+	case <-build:
+		t.Fatal("expected the second build to wait for the first")
+	default:
+	}
+	s.release(graph.NodeTypeDockerBuild)
+	select {
+	case <-build:
+	case <-time.After(time.Second):
+		t.Fatal("expected the second build to start once the first finished")
+	}
+}
+
+func TestSlots_Cancelled(t *testing.T) {
+	s := newSlots(1, 1)
+	if err := s.acquire(context.Background(), graph.NodeTypeDeployment); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.acquire(ctx, graph.NodeTypeRoute); err == nil {
+		t.Fatal("expected a cancelled acquire to fail")
+	}
+	if len(s.waiting) != 0 {
+		t.Errorf("expected the cancelled node to stop waiting, got %d waiting", len(s.waiting))
+	}
+
+	// The held slot is still handed on
+	s.release(graph.NodeTypeDeployment)
+	if err := s.acquire(context.Background(), graph.NodeTypeRoute); err != nil {
+		t.Fatal(err)
+	}
+}
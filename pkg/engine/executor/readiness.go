@@ -0,0 +1,139 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// Readiness gate defaults, used when a gate doesn't set them.
+const (
+	defaultReadinessInterval = 2 * time.Second
+	defaultReadinessTimeout  = 60 * time.Second
+
+	// readinessProbeTimeout bounds a single HTTP or TCP check.
+	readinessProbeTimeout = 5 * time.Second
+)
+
+// awaitReadiness runs a node's readiness gate, if it has one, until it passes
+// or its timeout runs out. Nodes that depend on the resource aren't applied
+// until the gate passes. The gate's expressions are resolved against the
+// graph, including the node's own outputs, so they must be set on the node
+// before this is called.
+func (e *Executor) awaitReadiness(ctx context.Context, node *graph.Node, envState *types.EnvironmentState, onProgress func(string)) error {
+	gate := node.Readiness
+	if gate == nil {
+		return nil
+	}
+
+	resolve := e.expressionResolver(node, envState)
+	var (
+		desc  string
+		check func(context.Context) error
+	)
+	switch {
+	case gate.HTTP != "":
+		url := resolve(gate.HTTP)
+		desc = "GET " + url
+		check = func(ctx context.Context) error { return checkHTTP(ctx, url) }
+	case gate.TCP != "":
+		addr := resolve(gate.TCP)
+		desc = "connect to " + addr
+		check = func(ctx context.Context) error { return checkTCP(ctx, addr) }
+	case len(gate.Command) > 0:
+		args := make([]string, len(gate.Command))
+		for i, arg := range gate.Command {
+			args[i] = resolve(arg)
+		}
+		desc = strings.Join(args, " ")
+		check = func(ctx context.Context) error { return checkCommand(ctx, args) }
+	default:
+		return nil
+	}
+
+	interval := parseGateDuration(gate.Interval, defaultReadinessInterval)
+	timeout := parseGateDuration(gate.Timeout, defaultReadinessTimeout)
+
+	if onProgress != nil {
+		onProgress(iac.PhaseMessage(iac.PhaseWaitingHealthy, fmt.Sprintf("waiting for readiness check: %s", desc)))
+	}
+
+	deadline := time.Now().Add(timeout)
+	var lastErr error
+	for {
+		if lastErr = check(ctx); lastErr == nil {
+			return nil
+		}
+		if time.Now().Add(interval).After(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+	return fmt.Errorf("%s did not pass within %v: %w", desc, timeout, lastErr)
+}
+
+func parseGateDuration(s string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
+
+// checkHTTP checks that a GET of url returns a 2xx status.
+func checkHTTP(ctx context.Context, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, readinessProbeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("returned %s", resp.Status)
+	}
+	return nil
+}
+
+// checkTCP checks that addr (host:port) accepts connections.
+func checkTCP(ctx context.Context, addr string) error {
+	ctx, cancel := context.WithTimeout(ctx, readinessProbeTimeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// checkCommand checks that a command exits 0. The command runs where cldctl
+// runs, not inside the resource.
+func checkCommand(ctx context.Context, args []string) error {
+	out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if output := strings.TrimSpace(string(out)); output != "" {
+		return errors.New(err.Error() + ": " + output)
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+package executor
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+)
+
+func TestAwaitReadiness_HTTPSelfReference(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/healthz" || calls < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	g := graph.NewGraph("test", "dc")
+	node := graph.NewNode(graph.NodeTypeService, "api", "main")
+	node.Outputs = map[string]interface{}{"url": srv.URL}
+	node.Readiness = &graph.Readiness{HTTP: "${{ services.main.url }}/healthz", Interval: "10ms", Timeout: "5s"}
+	_ = g.AddNode(node)
+
+	exec := &Executor{graph: g}
+	var progress []string
+	if err := exec.awaitReadiness(context.Background(), node, nil, func(msg string) { progress = append(progress, msg) }); err != nil {
+		t.Fatalf("expected the gate to pass, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected the gate to be retried until it passed, got %d calls", calls)
+	}
+	if len(progress) != 1 || !strings.Contains(progress[0], srv.URL+"/healthz") {
+		t.Errorf("expected progress naming the resolved URL, got %v", progress)
+	}
+}
+
+func TestAwaitReadiness_TCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	g := graph.NewGraph("test", "dc")
+	node := graph.NewNode(graph.NodeTypeDatabase, "api", "main")
+	node.Readiness = &graph.Readiness{TCP: ln.Addr().String()}
+	_ = g.AddNode(node)
+
+	exec := &Executor{graph: g}
+	if err := exec.awaitReadiness(context.Background(), node, nil, nil); err != nil {
+		t.Fatalf("expected the gate to pass, got %v", err)
+	}
+}
+
+func TestAwaitReadiness_CommandTimesOut(t *testing.T) {
+	g := graph.NewGraph("test", "dc")
+	node := graph.NewNode(graph.NodeTypeDeployment, "api", "main")
+	node.Readiness = &graph.Readiness{Command: []string{"sh", "-c", "echo not yet; exit 1"}, Interval: "10ms", Timeout: "50ms"}
+	_ = g.AddNode(node)
+
+	exec := &Executor{graph: g}
+	err := exec.awaitReadiness(context.Background(), node, nil, nil)
+	if err == nil {
+		t.Fatal("expected the gate to fail")
+	}
+	if !strings.Contains(err.Error(), "did not pass within 50ms") || !strings.Contains(err.Error(), "not yet") {
+		t.Errorf("expected the timeout and the command's output in the error, got %v", err)
+	}
+}
+
+func TestAwaitReadiness_NoGate(t *testing.T) {
+	exec := &Executor{graph: graph.NewGraph("test", "dc")}
+	if err := exec.awaitReadiness(context.Background(), graph.NewNode(graph.NodeTypeService, "api", "main"), nil, nil); err != nil {
+		t.Errorf("expected no error without a gate, got %v", err)
+	}
+}
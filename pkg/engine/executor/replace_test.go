@@ -0,0 +1,124 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+const replaceTestDatacenter = `
+environment {
+  deployment {
+    replace_on = ["image"]
+    module "network" {
+      plugin = "cloud"
+      build  = "./modules/network"
+    }
+    module "vm" {
+      plugin = "cloud"
+      build  = "./modules/vm"
+      inputs = {
+        image = node.inputs.image
+      }
+    }
+    outputs = {
+      id = module.vm.id
+    }
+  }
+}
+`
+
+// orderingPlugin records the applies and destroys it runs, in order.
+type orderingPlugin struct {
+	mockPlugin
+	calls []string
+}
+
+func (p *orderingPlugin) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResult, error) {
+	p.calls = append(p.calls, "apply "+opts.ModuleSource[strings.LastIndex(opts.ModuleSource, "/")+1:])
+	return &iac.ApplyResult{
+		Outputs: map[string]iac.OutputValue{"id": {Value: "vm-2"}},
+		State:   []byte("new"),
+	}, nil
+}
+
+func (p *orderingPlugin) Destroy(ctx context.Context, opts iac.RunOptions) error {
+	p.calls = append(p.calls, "destroy "+opts.ModuleSource+" "+opts.Inputs["image"].(string))
+	return p.destroyErr
+}
+
+func TestExecuteApply_Replace(t *testing.T) {
+	run := func(plugin *orderingPlugin) (*NodeResult, *types.EnvironmentState) {
+		registry := iac.NewRegistry()
+		registry.Register("cloud", func() (iac.Plugin, error) { return plugin, nil })
+		opts := DefaultOptions()
+		opts.Datacenter = loadHCLDatacenter(t, replaceTestDatacenter)
+		exec := NewExecutor(newMockStateManager(), registry, opts)
+
+		prior := &types.ResourceState{
+			Component: "api",
+			Name:      "main",
+			Type:      "deployment",
+			Status:    types.ResourceStatusReady,
+			Inputs:    map[string]interface{}{"image": "api:v1"},
+			ModuleStates: map[string]*types.ModuleState{
+				"network": {Plugin: "cloud", Source: "network", Inputs: map[string]interface{}{"image": ""}, IaCState: []byte("old")},
+				"vm":      {Plugin: "cloud", Source: "vm", Inputs: map[string]interface{}{"image": "api:v1"}, IaCState: []byte("old")},
+			},
+		}
+		envState := &types.EnvironmentState{
+			Name: "test",
+			Components: map[string]*types.ComponentState{
+				"api": {Name: "api", Resources: map[string]*types.ResourceState{"deployment.main": prior}},
+			},
+		}
+
+		node := graph.NewNode(graph.NodeTypeDeployment, "api", "main")
+		node.SetInput("image", "api:v2")
+		result := exec.executeApply(context.Background(), &planner.ResourceChange{
+			Node:         node,
+			Action:       planner.ActionReplace,
+			CurrentState: prior,
+		}, envState, nil)
+		return result, envState
+	}
+
+	t.Run("destroys before applying", func(t *testing.T) {
+		plugin := &orderingPlugin{}
+		result, envState := run(plugin)
+		if !result.Success {
+			t.Fatalf("expected the replacement to succeed, got %v", result.Error)
+		}
+		expected := []string{"destroy vm api:v1", "destroy network ", "apply network", "apply vm"}
+		if strings.Join(plugin.calls, "|") != strings.Join(expected, "|") {
+			t.Errorf("expected calls %v, got %v", expected, plugin.calls)
+		}
+		rs := envState.Components["api"].Resources["deployment.main"]
+		if string(rs.ModuleStates["vm"].IaCState) != "new" {
+			t.Errorf("expected the new module state to be recorded, got %q", rs.ModuleStates["vm"].IaCState)
+		}
+	})
+
+	t.Run("destroy fails", func(t *testing.T) {
+		plugin := &orderingPlugin{mockPlugin: mockPlugin{destroyErr: errors.New("still in use")}}
+		result, envState := run(plugin)
+		if result.Success || !strings.Contains(result.Error.Error(), "failed to replace resource: destroy failed") {
+			t.Fatalf("expected the replacement to fail, got %v", result.Error)
+		}
+		for _, call := range plugin.calls {
+			if strings.HasPrefix(call, "apply") {
+				t.Errorf("expected nothing to be applied, got %v", plugin.calls)
+			}
+		}
+		rs := envState.Components["api"].Resources["deployment.main"]
+		if string(rs.ModuleStates["vm"].IaCState) != "old" {
+			t.Error("expected the resource's state to be left as it was")
+		}
+	})
+}
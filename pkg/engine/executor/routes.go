@@ -0,0 +1,125 @@
+package executor
+
+import (
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/names"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// RouteAddress returns the subdomain and path prefix a route is published
+// at, and whether the subdomain was generated. The subdomain is the
+// environment's override when set, otherwise the generated subdomain the
+// route was previously published at (so published URLs stay stable),
+// otherwise one generated from the environment, component, and route names.
+// The path prefix defaults to the root path.
+func RouteAddress(envName, component, route string, override RouteOverride, published *types.RouteState) (subdomain, pathPrefix string, generated bool) {
+	subdomain = override.Subdomain
+	if subdomain == "" {
+		generated = true
+		if published != nil && published.Generated && published.Subdomain != "" {
+			subdomain = published.Subdomain
+		} else {
+			subdomain = names.Generate(envName, component, route)
+		}
+	}
+	pathPrefix = override.PathPrefix
+	if pathPrefix == "" {
+		pathPrefix = "/"
+	}
+	return subdomain, pathPrefix, generated
+}
+
+// RouteHostnames returns the host and path combinations a route is
+// published at. Configured hostnames inherit the route's path prefix when
+// they don't set their own; without any, the route matches its subdomain and
+// path prefix only.
+func RouteHostnames(override RouteOverride, subdomain, pathPrefix string) []types.RouteHostname {
+	if len(override.Hostnames) == 0 {
+		return []types.RouteHostname{{Subdomain: subdomain, PathPrefix: pathPrefix}}
+	}
+	hostnames := make([]types.RouteHostname, len(override.Hostnames))
+	for i, h := range override.Hostnames {
+		hostnames[i] = types.RouteHostname{Subdomain: h.Subdomain, Host: h.Host, PathPrefix: h.PathPrefix}
+		if hostnames[i].PathPrefix == "" {
+			hostnames[i].PathPrefix = pathPrefix
+		}
+	}
+	return hostnames
+}
+
+// hostnameInputs converts route hostnames into the structured rules passed
+// to route hooks as node.inputs.hostnames.
+func hostnameInputs(hostnames []types.RouteHostname) []interface{} {
+	rules := make([]interface{}, len(hostnames))
+	for i, h := range hostnames {
+		wildcard := h.Subdomain == "*" || strings.HasPrefix(h.Subdomain, "*.") || strings.HasPrefix(h.Host, "*.")
+		rules[i] = map[string]interface{}{
+			"subdomain":   h.Subdomain,
+			"host":        h.Host,
+			"path_prefix": h.PathPrefix,
+			"wildcard":    wildcard,
+		}
+	}
+	return rules
+}
+
+// PublishedRoute returns the address a route was last published at, or nil
+// if the environment has no record of it.
+func PublishedRoute(envState *types.EnvironmentState, component, route string) *types.RouteState {
+	if envState == nil {
+		return nil
+	}
+	comp := envState.Components[component]
+	if comp == nil {
+		return nil
+	}
+	return comp.Routes[route]
+}
+
+// snapshotPublishedRoutes copies the route addresses recorded in state at
+// the start of execution, so route nodes can be resolved without holding
+// stateMu while other nodes update state.
+func (e *Executor) snapshotPublishedRoutes(envState *types.EnvironmentState) {
+	e.publishedRoutes = make(map[string]map[string]types.RouteState)
+	for compName, comp := range envState.Components {
+		if comp == nil || len(comp.Routes) == 0 {
+			continue
+		}
+		routes := make(map[string]types.RouteState, len(comp.Routes))
+		for name, rs := range comp.Routes {
+			if rs != nil {
+				routes[name] = *rs
+			}
+		}
+		e.publishedRoutes[compName] = routes
+	}
+}
+
+// routeAddress returns the address of a route node, applying the
+// environment's route overrides from the executor options.
+func (e *Executor) routeAddress(node *graph.Node, envName string) (subdomain, pathPrefix string, generated bool) {
+	var published *types.RouteState
+	if rs, ok := e.publishedRoutes[node.Component][node.Name]; ok {
+		published = &rs
+	}
+	return RouteAddress(envName, node.Component, node.Name, e.options.ComponentRoutes[node.Component][node.Name], published)
+}
+
+// recordRouteLocked stores the address a route node was published at in its
+// component's state. Callers must hold stateMu.
+func (e *Executor) recordRouteLocked(compState *types.ComponentState, node *graph.Node, envName string) {
+	if node.Type != graph.NodeTypeRoute {
+		return
+	}
+	subdomain, pathPrefix, generated := e.routeAddress(node, envName)
+	if compState.Routes == nil {
+		compState.Routes = make(map[string]*types.RouteState)
+	}
+	rs := &types.RouteState{Subdomain: subdomain, PathPrefix: pathPrefix, Generated: generated}
+	if override := e.options.ComponentRoutes[node.Component][node.Name]; len(override.Hostnames) > 0 {
+		rs.Hostnames = RouteHostnames(override, subdomain, pathPrefix)
+	}
+	compState.Routes[node.Name] = rs
+}
@@ -0,0 +1,110 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/names"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func TestRecordRouteLocked(t *testing.T) {
+	exec := NewExecutor(newMockStateManager(), newTestRegistry(), Options{
+		ComponentRoutes: map[string]map[string]RouteOverride{
+			"api": {"admin": {Subdomain: "admin", PathPrefix: "/ops"}},
+		},
+	})
+	compState := &types.ComponentState{Name: "api"}
+
+	exec.recordRouteLocked(compState, graph.NewNode(graph.NodeTypeRoute, "api", "main"), "staging")
+	exec.recordRouteLocked(compState, graph.NewNode(graph.NodeTypeRoute, "api", "admin"), "staging")
+	exec.recordRouteLocked(compState, graph.NewNode(graph.NodeTypeService, "api", "main"), "staging")
+
+	if len(compState.Routes) != 2 {
+		t.Fatalf("expected two recorded routes, got %v", compState.Routes)
+	}
+	if rs := compState.Routes["main"]; rs.Subdomain != names.Generate("staging", "api", "main") || rs.PathPrefix != "/" || !rs.Generated {
+		t.Errorf("expected generated address for main, got %+v", rs)
+	}
+	if rs := compState.Routes["admin"]; rs.Subdomain != "admin" || rs.PathPrefix != "/ops" || rs.Generated {
+		t.Errorf("expected overridden address for admin, got %+v", rs)
+	}
+}
+
+func TestRouteAddress_ReusesPublishedGeneratedSubdomain(t *testing.T) {
+	published := &types.RouteState{Subdomain: "brave-falcon", PathPrefix: "/", Generated: true}
+
+	subdomain, pathPrefix, generated := RouteAddress("staging", "api", "main", RouteOverride{}, published)
+	if subdomain != "brave-falcon" || pathPrefix != "/" || !generated {
+		t.Errorf("expected published subdomain, got %s %s %v", subdomain, pathPrefix, generated)
+	}
+
+	subdomain, _, generated = RouteAddress("staging", "api", "main", RouteOverride{Subdomain: "app"}, published)
+	if subdomain != "app" || generated {
+		t.Errorf("expected override to win, got %s %v", subdomain, generated)
+	}
+}
+
+func TestRouteHostnames(t *testing.T) {
+	hostnames := RouteHostnames(RouteOverride{}, "brave-falcon", "/")
+	if len(hostnames) != 1 || hostnames[0] != (types.RouteHostname{Subdomain: "brave-falcon", PathPrefix: "/"}) {
+		t.Errorf("expected the route address as the only hostname, got %+v", hostnames)
+	}
+
+	override := RouteOverride{
+		PathPrefix: "/api",
+		Hostnames: []RouteHostname{
+			{Subdomain: "*.preview"},
+			{Host: "api.example.com", PathPrefix: "/v2"},
+		},
+	}
+	hostnames = RouteHostnames(override, "brave-falcon", "/api")
+	want := []types.RouteHostname{
+		{Subdomain: "*.preview", PathPrefix: "/api"},
+		{Host: "api.example.com", PathPrefix: "/v2"},
+	}
+	if len(hostnames) != len(want) {
+		t.Fatalf("expected %d hostnames, got %+v", len(want), hostnames)
+	}
+	for i := range want {
+		if hostnames[i] != want[i] {
+			t.Errorf("hostname %d: expected %+v, got %+v", i, want[i], hostnames[i])
+		}
+	}
+
+	rules := hostnameInputs(hostnames)
+	first := rules[0].(map[string]interface{})
+	if first["wildcard"] != true || first["subdomain"] != "*.preview" || first["path_prefix"] != "/api" {
+		t.Errorf("unexpected rule for wildcard subdomain: %v", first)
+	}
+	second := rules[1].(map[string]interface{})
+	if second["wildcard"] != false || second["host"] != "api.example.com" {
+		t.Errorf("unexpected rule for explicit host: %v", second)
+	}
+}
+
+func TestInjectRouteAuthOutputs(t *testing.T) {
+	exec := NewExecutor(newMockStateManager(), newTestRegistry(), Options{})
+
+	route := graph.NewNode(graph.NodeTypeRoute, "admin", "main")
+	route.SetInput("auth", map[string]interface{}{"provider": "github"})
+	authNode := graph.NewNode(graph.NodeTypeRouteAuth, "admin", "main")
+	authNode.Outputs = map[string]interface{}{"url": "http://proxy:4180", "host": "proxy", "port": 4180}
+	public := graph.NewNode(graph.NodeTypeRoute, "admin", "public")
+
+	exec.graph = graph.NewGraph("staging", "dc")
+	_ = exec.graph.AddNode(route)
+	_ = exec.graph.AddNode(authNode)
+	_ = exec.graph.AddNode(public)
+
+	exec.injectRouteAuthOutputs(route)
+	auth := route.Inputs["auth"].(map[string]interface{})
+	if auth["provider"] != "github" || auth["host"] != "proxy" || auth["port"] != 4180 || auth["url"] != "http://proxy:4180" {
+		t.Errorf("expected proxy endpoint merged into auth input, got %v", auth)
+	}
+
+	exec.injectRouteAuthOutputs(public)
+	if _, ok := public.Inputs["auth"]; ok {
+		t.Errorf("expected route without auth to be left alone, got %v", public.Inputs["auth"])
+	}
+}
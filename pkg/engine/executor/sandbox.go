@@ -0,0 +1,57 @@
+package executor
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+)
+
+// moduleSandbox returns the sandbox a hook's modules run in: the hook's own
+// sandbox block if it has one, otherwise the datacenter's. hook may be nil
+// for datacenter- and environment-level modules.
+func moduleSandbox(dc datacenter.Datacenter, hook datacenter.Hook) *iac.Sandbox {
+	if dc == nil {
+		return nil
+	}
+	s := dc.Sandbox()
+	if hook != nil && hook.Sandbox() != nil {
+		s = hook.Sandbox()
+	}
+	if s == nil {
+		return nil
+	}
+
+	sandbox := &iac.Sandbox{
+		NoNetwork:      s.NoNetwork(),
+		ReadOnlyRoot:   s.ReadOnlyRoot(),
+		Memory:         s.Memory(),
+		CPUs:           s.CPUs(),
+		PidsLimit:      s.PidsLimit(),
+		SeccompProfile: s.SeccompProfile(),
+	}
+	if sandbox.SeccompProfile != "" && !filepath.IsAbs(sandbox.SeccompProfile) {
+		sandbox.SeccompProfile = filepath.Join(filepath.Dir(dc.SourcePath()), sandbox.SeccompProfile)
+	}
+	return sandbox
+}
+
+// checkSandbox refuses to run a sandboxed module with a plugin that can't
+// enforce the sandbox, rather than silently running it unrestricted.
+func checkSandbox(moduleName, pluginName string, plugin iac.Plugin, sandbox *iac.Sandbox) error {
+	if sandbox == nil || iac.EnforcesSandbox(plugin) {
+		return nil
+	}
+	return fmt.Errorf("module %s: plugin %q cannot enforce the datacenter sandbox; use a container-based plugin", moduleName, pluginName)
+}
+
+// ModuleSandbox returns the datacenter sandbox for datacenter- and
+// environment-level modules, checking that plugin can enforce it.
+func ModuleSandbox(dc datacenter.Datacenter, moduleName, pluginName string, plugin iac.Plugin) (*iac.Sandbox, error) {
+	sandbox := moduleSandbox(dc, nil)
+	if err := checkSandbox(moduleName, pluginName, plugin, sandbox); err != nil {
+		return nil, err
+	}
+	return sandbox, nil
+}
@@ -0,0 +1,80 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+)
+
+const sandboxTestDatacenter = `
+sandbox {
+  network         = false
+  seccomp_profile = "./seccomp.json"
+}
+
+environment {
+  database {
+    sandbox {
+      memory = "1g"
+    }
+    module "postgres" {
+      build = "./modules/postgres"
+    }
+    outputs = {
+      host = module.postgres.host
+      port = module.postgres.port
+      url  = module.postgres.url
+    }
+  }
+
+  bucket {
+    module "s3" {
+      build = "./modules/s3"
+    }
+    outputs = {
+      endpoint        = module.s3.endpoint
+      bucket          = module.s3.bucket
+      accessKeyId     = module.s3.accessKeyId
+      secretAccessKey = module.s3.secretAccessKey
+    }
+  }
+}
+`
+
+// sandboxedMockPlugin is a mockPlugin that enforces sandboxes.
+type sandboxedMockPlugin struct {
+	mockPlugin
+}
+
+func (p *sandboxedMockPlugin) EnforcesSandbox() bool { return true }
+
+func TestModuleSandbox(t *testing.T) {
+	dc, err := datacenter.NewLoader().LoadFromBytes([]byte(sandboxTestDatacenter), "/dc/datacenter.dc")
+	if err != nil {
+		t.Fatalf("failed to load datacenter: %v", err)
+	}
+	hooks := dc.Environment().Hooks()
+
+	// Hooks without a sandbox block use the datacenter's, with the seccomp
+	// profile resolved relative to the datacenter file
+	sandbox := moduleSandbox(dc, hooks.Bucket()[0])
+	if sandbox == nil || !sandbox.NoNetwork || sandbox.SeccompProfile != "/dc/seccomp.json" {
+		t.Errorf("expected datacenter sandbox, got %+v", sandbox)
+	}
+
+	// A hook's sandbox block replaces the datacenter's entirely
+	sandbox = moduleSandbox(dc, hooks.Database()[0])
+	if sandbox == nil || sandbox.NoNetwork || sandbox.Memory != 1<<30 {
+		t.Errorf("expected hook sandbox, got %+v", sandbox)
+	}
+
+	if err := checkSandbox("postgres", "opentofu", &mockPlugin{}, sandbox); err == nil {
+		t.Error("expected error for a plugin that can't enforce the sandbox")
+	}
+	if err := checkSandbox("postgres", "container", &sandboxedMockPlugin{}, sandbox); err != nil {
+		t.Errorf("expected container plugin to be allowed, got %v", err)
+	}
+	if err := checkSandbox("postgres", "opentofu", &mockPlugin{}, nil); err != nil {
+		t.Errorf("expected no error without a sandbox, got %v", err)
+	}
+}
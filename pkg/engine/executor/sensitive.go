@@ -0,0 +1,87 @@
+package executor
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// moduleOutputRefPattern matches module.<name>.<output> references, but not
+// references to environment modules (environment.module.<name>.<output>).
+var moduleOutputRefPattern = regexp.MustCompile(`(?:^|[^.\w])(module\.[\w-]+\.[\w-]+)`)
+
+// sensitiveModuleOutputs returns the sensitive outputs of a hook's modules,
+// as the module.<name>.<output> references hook outputs read them by.
+func sensitiveModuleOutputs(moduleStates map[string]*types.ModuleState) map[string]bool {
+	refs := make(map[string]bool)
+	for name, ms := range moduleStates {
+		if ms == nil {
+			continue
+		}
+		for _, out := range ms.SensitiveOutputs {
+			refs["module."+name+"."+out] = true
+		}
+	}
+	return refs
+}
+
+// hookSensitiveOutputs returns the dot paths of the evaluated hook outputs
+// whose expressions reference a sensitive module output, sorted. The read
+// and write endpoints auto-populated for databases are as sensitive as the
+// top-level outputs they mirror.
+func hookSensitiveOutputs(hook datacenter.Hook, sensitive map[string]bool, outputs map[string]interface{}) []string {
+	if len(sensitive) == 0 {
+		return nil
+	}
+	references := func(expr string) bool {
+		for _, m := range moduleOutputRefPattern.FindAllStringSubmatch(expr, -1) {
+			if sensitive[m[1]] {
+				return true
+			}
+		}
+		return false
+	}
+
+	flat := hook.Outputs()
+	nested := hook.NestedOutputs()
+	isSensitive := make(map[string]bool)
+	for name, expr := range flat {
+		if _, ok := outputs[name]; ok && references(expr) {
+			isSensitive[name] = true
+		}
+	}
+	for name, fields := range nested {
+		values, _ := outputs[name].(map[string]interface{})
+		for field, expr := range fields {
+			if _, ok := values[field]; ok && references(expr) {
+				isSensitive[name+"."+field] = true
+			}
+		}
+	}
+	for _, endpoint := range []string{"read", "write"} {
+		if _, declared := flat[endpoint]; declared {
+			continue
+		}
+		if _, declared := nested[endpoint]; declared {
+			continue
+		}
+		values, _ := outputs[endpoint].(map[string]interface{})
+		for field := range values {
+			if isSensitive[field] {
+				isSensitive[endpoint+"."+field] = true
+			}
+		}
+	}
+
+	if len(isSensitive) == 0 {
+		return nil
+	}
+	paths := make([]string, 0, len(isSensitive))
+	for p := range isSensitive {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,95 @@
+package executor
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+const sensitiveTestDatacenter = `
+environment {
+  database {
+    module "db" {
+      plugin = "cloud"
+      build  = "./modules/db"
+    }
+    outputs = {
+      host     = module.db.host
+      port     = module.db.port
+      username = module.db.username
+      password = module.db.password
+      url      = "postgres://${module.db.username}:${module.db.password}@${module.db.host}:${module.db.port}/app"
+    }
+  }
+}
+`
+
+func TestExecuteApply_SensitiveOutputs(t *testing.T) {
+	plugin := &mockPlugin{outputs: map[string]iac.OutputValue{
+		"host":     {Value: "db.internal"},
+		"port":     {Value: 5432},
+		"username": {Value: "app"},
+		"password": {Value: "hunter22", Sensitive: true},
+	}}
+	registry := iac.NewRegistry()
+	registry.Register("cloud", func() (iac.Plugin, error) { return plugin, nil })
+	opts := DefaultOptions()
+	opts.Datacenter = loadHCLDatacenter(t, sensitiveTestDatacenter)
+	exec := NewExecutor(newMockStateManager(), registry, opts)
+
+	envState := &types.EnvironmentState{
+		Name:       "test",
+		Components: map[string]*types.ComponentState{"api": {Name: "api"}},
+	}
+	node := graph.NewNode(graph.NodeTypeDatabase, "api", "main")
+	node.SetInput("type", "postgres:16")
+	result := exec.executeApply(context.Background(), &planner.ResourceChange{
+		Node:   node,
+		Action: planner.ActionCreate,
+	}, envState, nil)
+	if !result.Success {
+		t.Fatalf("expected the apply to succeed, got %v", result.Error)
+	}
+
+	rs := envState.Components["api"].Resources["database.main"]
+	expected := []string{"password", "read.password", "read.url", "url", "write.password", "write.url"}
+	if !reflect.DeepEqual(rs.SensitiveOutputs, expected) {
+		t.Errorf("expected sensitive outputs %v, got %v", expected, rs.SensitiveOutputs)
+	}
+	if rs.Outputs["password"] != "hunter22" {
+		t.Errorf("expected outputs to keep their values, got %v", rs.Outputs)
+	}
+}
+
+func TestHookSensitiveOutputs(t *testing.T) {
+	hook := &mockHook{
+		outputs: map[string]string{
+			"host":   "module.db.host",
+			"secret": "${environment.module.db.password}",
+		},
+		nestedOutputs: map[string]map[string]string{
+			"write": {"password": "module.db.password", "host": "module.db.host"},
+		},
+	}
+	outputs := map[string]interface{}{
+		"host":   "db.internal",
+		"secret": "s3cret",
+		"write":  map[string]interface{}{"password": "hunter22", "host": "db.internal"},
+	}
+	sensitive := sensitiveModuleOutputs(map[string]*types.ModuleState{
+		"db": {SensitiveOutputs: []string{"password"}},
+	})
+
+	paths := hookSensitiveOutputs(hook, sensitive, outputs)
+	if !reflect.DeepEqual(paths, []string{"write.password"}) {
+		t.Errorf("expected only write.password to be sensitive, got %v", paths)
+	}
+	if paths := hookSensitiveOutputs(hook, nil, outputs); paths != nil {
+		t.Errorf("expected no sensitive outputs without sensitive module outputs, got %v", paths)
+	}
+}
@@ -0,0 +1,204 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	"github.com/davidthor/cldctl/pkg/state/backend"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// Shared hooks provision their modules once for a datacenter, for the first
+// resource they match in any environment. Every later resource they match,
+// in that environment or another, reuses the modules' outputs and adds a
+// reference to them in the datacenter's state. Destroying a resource removes
+// its reference, and the modules are destroyed with the last one.
+
+// sharedLockRetry is how long to wait before retrying a shared hook's lock
+// while another deploy holds it.
+var sharedLockRetry = time.Second
+
+// sharedKey identifies the shared hook that handles resources of a type, by
+// the names of its modules, which stay the same as hooks are reordered or
+// added. The key names the hook's state file, so it only uses characters
+// that are safe in paths.
+func sharedKey(nodeType graph.NodeType, hook datacenter.Hook) string {
+	names := make([]string, 0, len(hook.Modules()))
+	for _, m := range hook.Modules() {
+		names = append(names, m.Name())
+	}
+	return string(nodeType) + "-" + strings.Join(names, "-")
+}
+
+// sharedReferenceKey identifies a resource's reference to a shared hook's
+// modules.
+func sharedReferenceKey(envName string, node *graph.Node) string {
+	return envName + "/" + node.ID
+}
+
+// lockShared serializes uses of a shared hook, within this execution and,
+// through the state backend, with deploys of other environments. Uses of
+// different hooks don't wait for each other. It returns a function that
+// releases the lock.
+func (e *Executor) lockShared(ctx context.Context, key string) (func(), error) {
+	value, _ := e.sharedLocks.LoadOrStore(key, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+
+	b := e.stateManager.Backend()
+	if b == nil {
+		return mu.Unlock, nil
+	}
+	lockPath := path.Join("datacenters", e.datacenterName, "shared", key)
+	info := backend.LockInfo{Operation: "shared " + key}
+	for {
+		lock, err := b.Lock(ctx, lockPath, info)
+		if err == nil {
+			stopRenewing := backend.KeepAlive(lock)
+			return func() {
+				stopRenewing()
+				_ = lock.Unlock(context.Background())
+				mu.Unlock()
+			}, nil
+		}
+		if !errors.Is(err, backend.ErrLocked) {
+			mu.Unlock()
+			return nil, fmt.Errorf("failed to lock shared resource %s: %w", key, err)
+		}
+		select {
+		case <-time.After(sharedLockRetry):
+		case <-ctx.Done():
+			mu.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// useShared returns the outputs and states of a shared hook's modules for a
+// resource, applying the modules if they haven't been provisioned yet, and
+// records the resource's reference to them. The reference is recorded even if the
+// modules fail, so that destroying the resource cleans up after them.
+func (e *Executor) useShared(ctx context.Context, key string, node *graph.Node, hook datacenter.Hook, envName string, logBuf io.Writer, onProgress func(string)) (map[string]map[string]interface{}, map[string]*types.ModuleState, error) {
+	unlock, err := e.lockShared(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer unlock()
+
+	shared, err := e.stateManager.GetSharedResource(ctx, e.datacenterName, key)
+	if err != nil && !errors.Is(err, backend.ErrNotFound) {
+		return nil, nil, fmt.Errorf("failed to load shared resource %s: %w", key, err)
+	}
+	if shared == nil {
+		shared = &types.SharedResourceState{Key: key, CreatedAt: time.Now()}
+	}
+
+	var moduleOutputs map[string]map[string]interface{}
+	var applyErr error
+	if sharedProvisioned(shared) {
+		if onProgress != nil {
+			onProgress(fmt.Sprintf("reusing shared %s", key))
+		}
+		moduleOutputs = make(map[string]map[string]interface{}, len(shared.ModuleStates))
+		for name, ms := range shared.ModuleStates {
+			moduleOutputs[name] = ms.Outputs
+		}
+	} else {
+		var moduleStates map[string]*types.ModuleState
+		prior := &types.ResourceState{ModuleStates: shared.ModuleStates}
+		moduleOutputs, moduleStates, applyErr = e.applyHookModules(ctx, hook, node, envName, prior, logBuf, onProgress)
+		if applyErr != nil {
+			// Keep the state of modules that didn't run, so that their
+			// resources stay destroyable
+			if shared.ModuleStates == nil {
+				shared.ModuleStates = make(map[string]*types.ModuleState)
+			}
+			for name, ms := range moduleStates {
+				shared.ModuleStates[name] = ms
+			}
+		} else {
+			shared.ModuleStates = moduleStates
+		}
+	}
+
+	if shared.References == nil {
+		shared.References = make(map[string]*types.SharedReference)
+	}
+	refKey := sharedReferenceKey(envName, node)
+	if _, ok := shared.References[refKey]; !ok {
+		shared.References[refKey] = &types.SharedReference{
+			Environment: envName,
+			Component:   node.Component,
+			Type:        string(node.Type),
+			Name:        node.Name,
+			AddedAt:     time.Now(),
+		}
+	}
+	shared.UpdatedAt = time.Now()
+	if !e.options.DryRun {
+		if err := e.stateManager.SaveSharedResource(context.Background(), e.datacenterName, shared); err != nil && applyErr == nil {
+			applyErr = fmt.Errorf("failed to save shared resource %s: %w", key, err)
+		}
+	}
+
+	if applyErr != nil {
+		return nil, nil, fmt.Errorf("shared %s hook: %w", node.Type, applyErr)
+	}
+	return moduleOutputs, shared.ModuleStates, nil
+}
+
+// sharedProvisioned reports whether a shared hook's modules were applied
+// successfully, so that their outputs can be reused.
+func sharedProvisioned(shared *types.SharedResourceState) bool {
+	if len(shared.ModuleStates) == 0 {
+		return false
+	}
+	for _, ms := range shared.ModuleStates {
+		if ms.Status != types.ModuleStatusReady {
+			return false
+		}
+	}
+	return true
+}
+
+// releaseShared removes a destroyed resource's reference to a shared hook's
+// modules, and destroys the modules if it was the last one. If destroying
+// them fails, the reference is kept so that the destroy can be retried.
+func (e *Executor) releaseShared(ctx context.Context, key string, node *graph.Node, envName string) error {
+	unlock, err := e.lockShared(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	shared, err := e.stateManager.GetSharedResource(ctx, e.datacenterName, key)
+	if errors.Is(err, backend.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load shared resource %s: %w", key, err)
+	}
+
+	delete(shared.References, sharedReferenceKey(envName, node))
+	if len(shared.References) > 0 {
+		shared.UpdatedAt = time.Now()
+		return e.stateManager.SaveSharedResource(ctx, e.datacenterName, shared)
+	}
+
+	var hook datacenter.Hook
+	if e.options.Datacenter != nil {
+		hook, _ = e.matchHook(node)
+	}
+	if err := e.destroyModuleStates(ctx, shared.ModuleStates, moduleSandbox(e.options.Datacenter, hook)); err != nil {
+		return err
+	}
+	return e.stateManager.DeleteSharedResource(ctx, e.datacenterName, key)
+}
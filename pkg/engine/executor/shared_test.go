@@ -0,0 +1,186 @@
+package executor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/iac"
+)
+
+const sharedTestDatacenter = `
+environment {
+  deployment {
+    shared = true
+    module "relay" {
+      plugin = "native"
+      build  = "./modules/relay"
+    }
+    outputs = {
+      id      = module.relay.id
+      address = module.relay.address
+    }
+  }
+}
+`
+
+// sharedPlugin counts the applies and destroys of a shared module.
+type sharedPlugin struct {
+	mu        sync.Mutex
+	applied   int
+	destroyed int
+}
+
+func (p *sharedPlugin) Name() string { return "native" }
+
+func (p *sharedPlugin) Preview(ctx context.Context, opts iac.RunOptions) (*iac.PreviewResult, error) {
+	return &iac.PreviewResult{}, nil
+}
+
+func (p *sharedPlugin) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.applied++
+	return &iac.ApplyResult{
+		Outputs: map[string]iac.OutputValue{
+			"id":      {Value: "relay-1"},
+			"address": {Value: "relay.internal:25"},
+		},
+		State: []byte("relay-state"),
+	}, nil
+}
+
+func (p *sharedPlugin) Destroy(ctx context.Context, opts iac.RunOptions) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.destroyed++
+	return nil
+}
+
+func (p *sharedPlugin) Refresh(ctx context.Context, opts iac.RunOptions) (*iac.RefreshResult, error) {
+	return &iac.RefreshResult{}, nil
+}
+
+func (p *sharedPlugin) Import(ctx context.Context, opts iac.ImportOptions) (*iac.ImportResult, error) {
+	return &iac.ImportResult{}, nil
+}
+
+func TestExecute_SharedHook(t *testing.T) {
+	plugin := &sharedPlugin{}
+	registry := iac.NewRegistry()
+	registry.Register("native", func() (iac.Plugin, error) { return plugin, nil })
+
+	sm := newMockStateManager()
+	opts := DefaultOptions()
+	opts.Datacenter = loadHCLDatacenter(t, sharedTestDatacenter)
+
+	node := graph.NewNode(graph.NodeTypeDeployment, "api", "main")
+	g := graph.NewGraph("test", "dc")
+	_ = g.AddNode(node)
+
+	deploy := func(env string) {
+		t.Helper()
+		plan := &planner.Plan{Environment: env, Datacenter: "dc", ToCreate: 1, Changes: []*planner.ResourceChange{
+			{Node: node, Action: planner.ActionCreate},
+		}}
+		result, err := NewExecutor(sm, registry, opts).Execute(context.Background(), plan, g)
+		if err != nil || !result.Success {
+			t.Fatalf("deploy to %s failed: %v %v", env, err, result.Errors)
+		}
+		if got := result.NodeResults[node.ID].Outputs["address"]; got != "relay.internal:25" {
+			t.Errorf("%s: address = %v, want the shared module's output", env, got)
+		}
+	}
+	destroy := func(env string) {
+		t.Helper()
+		current := sm.environments[env].Components["api"].Resources[resourceKey(node)]
+		plan := &planner.Plan{Environment: env, Datacenter: "dc", ToDelete: 1, Changes: []*planner.ResourceChange{
+			{Node: node, Action: planner.ActionDelete, CurrentState: current},
+		}}
+		result, err := NewExecutor(sm, registry, opts).Execute(context.Background(), plan, g)
+		if err != nil || !result.Success {
+			t.Fatalf("destroy in %s failed: %v %v", env, err, result.Errors)
+		}
+	}
+
+	// The first environment provisions the modules, and later ones reuse them
+	deploy("staging")
+	deploy("preview")
+	if plugin.applied != 1 {
+		t.Fatalf("expected the shared module to be applied once, got %d applies", plugin.applied)
+	}
+
+	key := "deployment-relay"
+	shared := sm.shared["dc/"+key]
+	if shared == nil || len(shared.References) != 2 || shared.ModuleStates["relay"] == nil {
+		t.Fatalf("expected shared state with 2 references, got %+v", shared)
+	}
+	if rs := sm.environments["staging"].Components["api"].Resources["deployment.main"]; rs.Shared != key || len(rs.ModuleStates) != 0 {
+		t.Errorf("expected the resource to refer to its shared hook, got %+v", rs)
+	}
+
+	// The modules are only destroyed with the last reference
+	destroy("staging")
+	if plugin.destroyed != 0 {
+		t.Fatalf("expected the modules to stay while preview uses them, got %d destroys", plugin.destroyed)
+	}
+	if refs := sm.shared["dc/"+key].References; len(refs) != 1 || refs["preview/"+node.ID] == nil {
+		t.Errorf("expected only preview's reference to remain, got %v", refs)
+	}
+	destroy("preview")
+	if plugin.destroyed != 1 {
+		t.Errorf("expected the modules to be destroyed with the last reference, got %d destroys", plugin.destroyed)
+	}
+	if _, ok := sm.shared["dc/"+key]; ok {
+		t.Error("expected the shared state to be removed")
+	}
+}
+
+func TestLockShared_PerKey(t *testing.T) {
+	e := NewExecutor(newMockStateManager(), iac.NewRegistry(), DefaultOptions())
+
+	unlock, err := e.lockShared(context.Background(), "deployment-relay")
+	if err != nil {
+		t.Fatalf("lock failed: %v", err)
+	}
+
+	// Another hook's key isn't held up by the first
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	done := make(chan func(), 1)
+	go func() {
+		unlockOther, err := e.lockShared(ctx, "database-postgres")
+		if err != nil {
+			t.Errorf("lock of another key failed: %v", err)
+		}
+		done <- unlockOther
+	}()
+	select {
+	case unlockOther := <-done:
+		if unlockOther != nil {
+			unlockOther()
+		}
+	case <-ctx.Done():
+		t.Fatal("locking another key waited for the first")
+	}
+
+	// The same key waits until it is released
+	acquired := make(chan struct{})
+	go func() {
+		unlockAgain, err := e.lockShared(context.Background(), "deployment-relay")
+		if err == nil {
+			unlockAgain()
+		}
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("expected the same key to wait for its holder")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	<-acquired
+}
@@ -0,0 +1,151 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+)
+
+// DefaultSlowNodeThreshold is how long a node may run before it's reported
+// as still running when no threshold is configured for its type.
+const DefaultSlowNodeThreshold = 10 * time.Minute
+
+// slowNodeWebhookTimeout bounds each webhook request so that an unreachable
+// endpoint can't hold up a deploy.
+const slowNodeWebhookTimeout = 10 * time.Second
+
+// SlowNodeOptions configures the notifications sent while a node takes a
+// long time to apply, so that users can tell a slow resource (e.g. a 20
+// minute database creation) from a wedged one.
+type SlowNodeOptions struct {
+	// Thresholds maps node type to how long its nodes may run before they're
+	// reported as still running, and again each time that much more time
+	// passes. The "*" entry applies to types without their own entry, and
+	// DefaultSlowNodeThreshold applies when neither is set. A negative
+	// threshold disables notifications.
+	Thresholds map[string]time.Duration
+
+	// Webhook, when set, is a URL that each notification is also POSTed to
+	// as JSON (see SlowNodeNotification).
+	Webhook string
+}
+
+// SlowNodeNotification is the JSON body POSTed to SlowNodeOptions.Webhook.
+type SlowNodeNotification struct {
+	Datacenter     string `json:"datacenter"`
+	Environment    string `json:"environment"`
+	Component      string `json:"component"`
+	NodeID         string `json:"node_id"`
+	NodeType       string `json:"node_type"`
+	Action         string `json:"action"`
+	Phase          string `json:"phase,omitempty"`
+	ElapsedSeconds int    `json:"elapsed_seconds"`
+	Message        string `json:"message"`
+}
+
+// threshold returns the threshold for a node type, or 0 if notifications
+// are disabled for it.
+func (o SlowNodeOptions) threshold(nodeType string) time.Duration {
+	d, ok := o.Thresholds[nodeType]
+	if !ok {
+		d, ok = o.Thresholds["*"]
+	}
+	if !ok {
+		d = DefaultSlowNodeThreshold
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// watchSlowNode reports the node as still running each time its threshold
+// elapses until the returned function is called. Notifications go to
+// OnProgress and, if configured, the webhook.
+func (e *Executor) watchSlowNode(node *graph.Node, action, envName string) (stop func()) {
+	threshold := e.options.SlowNodes.threshold(string(node.Type))
+	if threshold == 0 || e.options.DryRun || (e.options.OnProgress == nil && e.options.SlowNodes.Webhook == "") {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		start := time.Now()
+		ticker := time.NewTicker(threshold)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				elapsed := time.Since(start).Round(time.Second)
+				phase := e.phase(node.ID)
+				msg := fmt.Sprintf("still running, %s elapsed", elapsed)
+				if phase != "" {
+					msg = fmt.Sprintf("still running (%s), %s elapsed", phase, elapsed)
+				}
+				if e.options.OnProgress != nil {
+					e.options.OnProgress(ProgressEvent{
+						NodeID:   node.ID,
+						NodeName: node.Name,
+						NodeType: string(node.Type),
+						Status:   "running",
+						Phase:    phase,
+						Message:  msg,
+					})
+				}
+				if e.options.SlowNodes.Webhook != "" {
+					go e.notifySlowNode(SlowNodeNotification{
+						Datacenter:     e.datacenterName,
+						Environment:    envName,
+						Component:      node.Component,
+						NodeID:         node.ID,
+						NodeType:       string(node.Type),
+						Action:         action,
+						Phase:          string(phase),
+						ElapsedSeconds: int(elapsed.Seconds()),
+						Message:        fmt.Sprintf("%s %s", node.ID, msg),
+					})
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// notifySlowNode POSTs a notification to the webhook. Failures are reported
+// on the output but don't affect the deploy.
+func (e *Executor) notifySlowNode(n SlowNodeNotification) {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), slowNodeWebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.options.SlowNodes.Webhook, bytes.NewReader(body))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		var resp *http.Response
+		if resp, err = http.DefaultClient.Do(req); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				err = fmt.Errorf("unexpected status %s", resp.Status)
+			}
+		}
+	}
+	if err != nil && e.options.Output != nil {
+		fmt.Fprintf(e.options.Output, "  [warning] slow node webhook for %s failed: %v\n", n.NodeID, err)
+	}
+}
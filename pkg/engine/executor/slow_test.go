@@ -0,0 +1,101 @@
+package executor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+)
+
+func TestSlowNodeOptions_Threshold(t *testing.T) {
+	opts := SlowNodeOptions{Thresholds: map[string]time.Duration{
+		"*":        5 * time.Minute,
+		"database": 30 * time.Minute,
+		"route":    -1,
+	}}
+	if got := opts.threshold("database"); got != 30*time.Minute {
+		t.Errorf("database threshold = %s, want 30m", got)
+	}
+	if got := opts.threshold("deployment"); got != 5*time.Minute {
+		t.Errorf("deployment threshold = %s, want the 5m default entry", got)
+	}
+	if got := opts.threshold("route"); got != 0 {
+		t.Errorf("route threshold = %s, want disabled", got)
+	}
+	if got := (SlowNodeOptions{}).threshold("deployment"); got != DefaultSlowNodeThreshold {
+		t.Errorf("unconfigured threshold = %s, want %s", got, DefaultSlowNodeThreshold)
+	}
+}
+
+func TestWatchSlowNode(t *testing.T) {
+	notifications := make(chan SlowNodeNotification, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n SlowNodeNotification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Errorf("failed to decode notification: %v", err)
+		}
+		notifications <- n
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var events []ProgressEvent
+	opts := DefaultOptions()
+	opts.SlowNodes = SlowNodeOptions{
+		Thresholds: map[string]time.Duration{"database": 10 * time.Millisecond, "*": -1},
+		Webhook:    server.URL,
+	}
+	opts.OnProgress = func(event ProgressEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+	exec := NewExecutor(newMockStateManager(), newTestRegistry(), opts)
+
+	// Nodes without a threshold aren't watched
+	stop := exec.watchSlowNode(graph.NewNode(graph.NodeTypeDeployment, "app", "api"), "create", "staging")
+	time.Sleep(30 * time.Millisecond)
+	stop()
+	if len(events) != 0 {
+		t.Fatalf("expected no events for an unwatched node type, got %v", events)
+	}
+
+	node := graph.NewNode(graph.NodeTypeDatabase, "app", "main")
+	stop = exec.watchSlowNode(node, "create", "staging")
+	time.Sleep(35 * time.Millisecond)
+	stop()
+
+	mu.Lock()
+	if len(events) == 0 {
+		t.Fatal("expected still running events")
+	}
+	for _, event := range events {
+		if event.NodeID != node.ID || event.Status != "running" || !strings.HasPrefix(event.Message, "still running, ") {
+			t.Errorf("unexpected event: %+v", event)
+		}
+	}
+	count := len(events)
+	mu.Unlock()
+
+	select {
+	case n := <-notifications:
+		if n.Environment != "staging" || n.NodeID != node.ID || n.NodeType != "database" || n.Action != "create" {
+			t.Errorf("unexpected notification: %+v", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a webhook notification")
+	}
+
+	// No events once the node is done
+	time.Sleep(30 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != count {
+		t.Errorf("expected no events after stop, got %d more", len(events)-count)
+	}
+}
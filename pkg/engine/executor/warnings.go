@@ -0,0 +1,63 @@
+package executor
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/graph"
+)
+
+// warnings collects the warnings found during an execution. The same
+// warning is only recorded once, since expressions are resolved more than
+// once for a node.
+type warnings struct {
+	mu   sync.Mutex
+	list []planner.Warning
+	seen map[planner.Warning]bool
+}
+
+// warn records a warning about a node. The node may be nil for warnings
+// that aren't about a resource.
+func (e *Executor) warn(node *graph.Node, format string, args ...interface{}) {
+	w := planner.Warning{Message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		w.Resource = node.ID
+	}
+
+	e.warnings.mu.Lock()
+	defer e.warnings.mu.Unlock()
+	if e.warnings.seen == nil {
+		e.warnings.seen = make(map[planner.Warning]bool)
+	}
+	if e.warnings.seen[w] {
+		return
+	}
+	e.warnings.seen[w] = true
+	e.warnings.list = append(e.warnings.list, w)
+}
+
+// resetWarnings clears the warnings of a previous execution.
+func (e *Executor) resetWarnings() {
+	e.warnings.mu.Lock()
+	defer e.warnings.mu.Unlock()
+	e.warnings.list = nil
+	e.warnings.seen = nil
+}
+
+// collectWarnings returns the execution's warnings. Warnings about nodes
+// skipped because a dependency failed are left out: they're about the
+// missing outputs of the failed dependency, which is reported already.
+func (e *Executor) collectWarnings(result *ExecutionResult) []planner.Warning {
+	e.warnings.mu.Lock()
+	defer e.warnings.mu.Unlock()
+
+	var out []planner.Warning
+	for _, w := range e.warnings.list {
+		if nr := result.NodeResults[w.Resource]; nr != nil && nr.RootCause != "" {
+			continue
+		}
+		out = append(out, w)
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+)
+
+func TestWarnings_UnresolvedExpression(t *testing.T) {
+	g := graph.NewGraph("test", "dc")
+	api := graph.NewNode(graph.NodeTypeDeployment, "app", "api")
+	worker := graph.NewNode(graph.NodeTypeDeployment, "app", "worker")
+	_ = g.AddNode(api)
+	_ = g.AddNode(worker)
+
+	exec := &Executor{graph: g}
+	for i := 0; i < 2; i++ {
+		if got := exec.expressionResolver(api, nil)("${{ services.missing.url }}"); got != "" {
+			t.Fatalf("expected the expression to resolve to an empty string, got %q", got)
+		}
+	}
+	exec.expressionResolver(worker, nil)("${{ services.missing.url }}")
+
+	result := &ExecutionResult{NodeResults: map[string]*NodeResult{
+		api.ID:    {NodeID: api.ID, Success: true},
+		worker.ID: {NodeID: worker.ID, RootCause: "app/dockerBuild/worker"},
+	}}
+	warnings := exec.collectWarnings(result)
+
+	// Repeated warnings are recorded once, and warnings about skipped nodes
+	// are left out
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %v", warnings)
+	}
+	if warnings[0].Resource != api.ID || !strings.Contains(warnings[0].Message, "services.missing.url") {
+		t.Errorf("expected a warning naming the expression, got %v", warnings[0])
+	}
+}
+
+func TestWarnings_WhenConditionFallback(t *testing.T) {
+	node := graph.NewNode(graph.NodeTypeDatabase, "app", "main")
+	node.Inputs = map[string]interface{}{"type": "postgres"}
+
+	exec := &Executor{graph: graph.NewGraph("test", "dc")}
+	exec.evaluateWhenCondition(`node.inputs.type == "postgres" &&`, node)
+	warnings := exec.collectWarnings(&ExecutionResult{})
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Message, "matched as a string") {
+		t.Errorf("expected a warning about the fallback, got %v", warnings)
+	}
+}
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// InputProvenance describes where one of a deployed resource's inputs came
+// from: the reference in its expression, and the resource or component the
+// value was read from.
+type InputProvenance struct {
+	// Input is the dotted path of the input (e.g., "environment.DATABASE_URL")
+	Input string `json:"input"`
+
+	// Reference is what the input's expression reads (e.g., "databases.main.url")
+	Reference string `json:"reference"`
+
+	// Component is the component the value was read from, for resource and
+	// dependency references
+	Component string `json:"component,omitempty"`
+
+	// Resource is the type/name of the resource the value was read from, or
+	// empty when it isn't read from a resource (variables, dependency outputs)
+	Resource string `json:"resource,omitempty"`
+
+	// Status is the deployed status of Resource, or empty if it isn't deployed
+	Status types.ResourceStatus `json:"status,omitempty"`
+}
+
+// ExplainResource returns where each expression in a deployed resource's
+// inputs reads its value from. The component is rebuilt from the source it
+// was last deployed from, so the references are the ones its expressions
+// actually make, not every node it's ordered after.
+func (e *Engine) ExplainResource(ctx context.Context, dcName, envName, compName string, res *types.ResourceState) ([]InputProvenance, error) {
+	envState, err := e.stateManager.GetEnvironment(ctx, dcName, envName)
+	if err != nil {
+		return nil, fmt.Errorf("environment %s not found in datacenter %s", envName, dcName)
+	}
+	compState := envState.Components[compName]
+	if compState == nil {
+		return nil, fmt.Errorf("component %q is not deployed to environment %q", compName, envName)
+	}
+	if compState.Source == "" {
+		return nil, fmt.Errorf("component %q has no recorded source; deploy it again to explain its resources", compName)
+	}
+	if len(compState.Instances) > 0 {
+		return nil, fmt.Errorf("component %q runs multiple instances, whose resources can't be explained", compName)
+	}
+
+	comp, err := e.compLoader.Load(compState.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load component %s: %w", compName, err)
+	}
+
+	// Without the datacenter, no implicit nodes (e.g. databaseUser) are
+	// created, so references resolve to the resources they name
+	var dc datacenter.Datacenter
+	if dcState, err := e.stateManager.GetDatacenter(ctx, dcName); err == nil && dcState.Version != "" {
+		if loaded, err := e.loadDatacenterConfig(dcState.Version); err == nil {
+			dc = loaded
+		}
+	}
+
+	builder := newGraphBuilder(envName, dcName, dc)
+	if err := builder.AddComponent(compName, comp); err != nil {
+		return nil, fmt.Errorf("failed to add component %s to graph: %w", compName, err)
+	}
+	g := builder.Build()
+
+	node := g.GetNode(graph.NewNode(graph.NodeType(res.Type), compName, res.Name).ID)
+	if node == nil {
+		return nil, fmt.Errorf("%s %q is no longer declared by component %s", res.Type, res.Name, compName)
+	}
+
+	sources := g.InputSources(node.ID)
+	provenance := make([]InputProvenance, 0, len(sources))
+	for _, s := range sources {
+		p := InputProvenance{Input: s.Input, Reference: s.Reference, Component: s.Component}
+		if dep := g.GetNode(s.NodeID); dep != nil {
+			p.Component = dep.Component
+			p.Resource = string(dep.Type) + "/" + dep.Name
+			if depComp := envState.Components[dep.Component]; depComp != nil {
+				if rs := depComp.Resources[string(dep.Type)+"."+dep.Name]; rs != nil {
+					p.Status = rs.Status
+				}
+			}
+		}
+		provenance = append(provenance, p)
+	}
+	return provenance, nil
+}
@@ -0,0 +1,157 @@
+package expression
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// plainReferencePattern matches a bare reference such as "databases.main.url",
+// "builds.api-build.image" or "dependents.*.routes.*.url". Anything else
+// inside ${{ }} is a computed expression.
+var plainReferencePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*(\.[A-Za-z0-9_*-]+|\[[^\]]*\])*$`)
+
+// computedFunctions are the functions available to computed expressions.
+var computedFunctions = map[string]function.Function{
+	"format": stdlib.FormatFunc,
+	"upper":  stdlib.UpperFunc,
+	"lower":  stdlib.LowerFunc,
+	"min":    stdlib.MinFunc,
+	"max":    stdlib.MaxFunc,
+}
+
+// IsComputed reports whether the content of a ${{ }} expression, without
+// pipe functions, is a computed expression like "ports.http.port + 1" or
+// `format("%s-%s", variables.region, variables.tier)` rather than a single
+// reference.
+func IsComputed(content string) bool {
+	return !plainReferencePattern.MatchString(strings.TrimSpace(content))
+}
+
+// References returns the dotted paths of the references a computed
+// expression reads, in the order they appear. Names that aren't valid
+// identifiers are written with index syntax in computed expressions
+// (ports["my-port"].port), and are returned as ordinary path segments
+// ("ports.my-port.port").
+func References(content string) ([]string, error) {
+	expr, err := parseComputed(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []string
+	seen := make(map[string]bool)
+	for _, traversal := range expr.Variables() {
+		ref := traversalPath(traversal)
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+// Compute evaluates a computed expression, calling resolve for the value of
+// each reference it reads. Values that look like numbers can be used in
+// arithmetic. Whole numbers are rendered without a decimal point.
+func Compute(content string, resolve func(ref string) string) (string, error) {
+	expr, err := parseComputed(content)
+	if err != nil {
+		return "", err
+	}
+
+	tree := make(map[string]interface{})
+	for _, traversal := range expr.Variables() {
+		path := strings.Split(traversalPath(traversal), ".")
+		node := tree
+		for i, key := range path {
+			if i == len(path)-1 {
+				if _, isMap := node[key].(map[string]interface{}); !isMap {
+					node[key] = resolve(strings.Join(path, "."))
+				}
+				break
+			}
+			child, ok := node[key].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				node[key] = child
+			}
+			node = child
+		}
+	}
+
+	vars := make(map[string]cty.Value, len(tree))
+	for k, v := range tree {
+		vars[k] = toCtyValue(v)
+	}
+
+	val, diags := expr.Value(&hcl.EvalContext{Variables: vars, Functions: computedFunctions})
+	if diags.HasErrors() {
+		return "", fmt.Errorf("failed to evaluate %q: %s", content, diags.Error())
+	}
+	return formatValue(val)
+}
+
+func parseComputed(content string) (hclsyntax.Expression, error) {
+	expr, diags := hclsyntax.ParseExpression([]byte(strings.TrimSpace(content)), "expression", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("invalid expression %q: %s", content, diags.Error())
+	}
+	return expr, nil
+}
+
+// traversalPath renders a traversal as a dotted reference path.
+func traversalPath(traversal hcl.Traversal) string {
+	parts := []string{traversal.RootName()}
+	for _, step := range traversal[1:] {
+		switch s := step.(type) {
+		case hcl.TraverseAttr:
+			parts = append(parts, s.Name)
+		case hcl.TraverseIndex:
+			if s.Key.Type() == cty.String {
+				parts = append(parts, s.Key.AsString())
+			} else if s.Key.Type() == cty.Number {
+				parts = append(parts, s.Key.AsBigFloat().Text('f', -1))
+			}
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func toCtyValue(v interface{}) cty.Value {
+	if m, ok := v.(map[string]interface{}); ok {
+		attrs := make(map[string]cty.Value, len(m))
+		for k, child := range m {
+			attrs[k] = toCtyValue(child)
+		}
+		return cty.ObjectVal(attrs)
+	}
+	return cty.StringVal(fmt.Sprintf("%v", v))
+}
+
+// formatValue renders the result of a computed expression as a string.
+func formatValue(val cty.Value) (string, error) {
+	if val.IsNull() || !val.IsKnown() {
+		return "", nil
+	}
+	switch val.Type() {
+	case cty.String:
+		return val.AsString(), nil
+	case cty.Number:
+		bf := val.AsBigFloat()
+		if bf.IsInt() {
+			return bf.Text('f', 0), nil
+		}
+		return bf.Text('f', -1), nil
+	case cty.Bool:
+		return strconv.FormatBool(val.True()), nil
+	}
+	return "", fmt.Errorf("expression produced a %s, expected a string, number, or bool", val.Type().FriendlyName())
+}
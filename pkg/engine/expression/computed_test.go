@@ -0,0 +1,83 @@
+package expression
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestIsComputed(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"databases.main.url", false},
+		{"builds.api-build.image", false},
+		{"dependents.*.routes.*.url", false},
+		{"ports.http.port + 1", true},
+		{`format("%s-%s", variables.region, variables.tier)`, true},
+		{`ports["my-port"].port * 2`, true},
+	}
+	for _, tt := range tests {
+		if got := IsComputed(tt.content); got != tt.want {
+			t.Errorf("IsComputed(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestReferences(t *testing.T) {
+	refs, err := References(`format("%s-%s-%d", variables.region, variables.tier, ports["my-port"].port + 1)`)
+	if err != nil {
+		t.Fatalf("References() error: %v", err)
+	}
+	want := "[variables.region variables.tier ports.my-port.port]"
+	if got := fmt.Sprint(refs); got != want {
+		t.Errorf("References() = %s, want %s", got, want)
+	}
+
+	if _, err := References("ports.http.port +"); err == nil {
+		t.Error("expected an error for an incomplete expression")
+	}
+}
+
+func TestCompute(t *testing.T) {
+	values := map[string]string{
+		"ports.http.port":    "8080",
+		"ports.my-port.port": "9000",
+		"variables.region":   "us-east-1",
+		"variables.tier":     "gold",
+		"variables.replicas": "3",
+	}
+	resolve := func(ref string) string { return values[ref] }
+
+	tests := []struct {
+		content string
+		want    string
+		wantErr bool
+	}{
+		{content: "ports.http.port + 1", want: "8081"},
+		{content: `ports["my-port"].port - ports.http.port`, want: "920"},
+		{content: "variables.replicas * 2", want: "6"},
+		{content: "variables.replicas / 2", want: "1.5"},
+		{content: `format("%s-%s", variables.region, variables.tier)`, want: "us-east-1-gold"},
+		{content: `format("%s:%d", upper(variables.tier), ports.http.port + 1)`, want: "GOLD:8081"},
+		{content: "max(variables.replicas, 5)", want: "5"},
+		{content: "variables.region + 1", wantErr: true},
+		{content: "unknown(variables.region)", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Compute(tt.content, resolve)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Compute(%q) = %q, want error", tt.content, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Compute(%q) error: %v", tt.content, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Compute(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
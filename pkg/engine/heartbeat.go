@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/engine/executor"
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// checkInFlightDeploy refuses to deploy over another deploy of the same
+// environment. A deploy whose heartbeat went stale most likely crashed; with
+// takeOver its in-flight resources are reconciled and the deploy proceeds.
+// When no deploy is running, resources that an earlier run left in flight
+// without a heartbeat are reconciled the same way.
+func (e *Engine) checkInFlightDeploy(ctx context.Context, dc, envName string, takeOver bool, w io.Writer) error {
+	envState, err := e.stateManager.GetEnvironment(ctx, dc, envName)
+	if err != nil {
+		return nil
+	}
+	if envState.Heartbeat == nil {
+		return e.reconcileStuckResources(ctx, dc, envState, w)
+	}
+
+	hb := envState.Heartbeat
+	now := time.Now()
+	if !takeOver {
+		if executor.HeartbeatStale(hb, now) {
+			return fmt.Errorf("previous deploy of environment %q appears to have crashed: %s stopped sending heartbeats %s ago\n"+
+				"Re-run with --take-over to reconcile the resources it left in flight and continue",
+				envName, hb.Owner, now.Sub(hb.UpdatedAt).Round(time.Second))
+		}
+		return fmt.Errorf("environment %q is being deployed by %s (started %s ago)\n"+
+			"Wait for it to finish, or re-run with --take-over if that process is no longer running",
+			envName, hb.Owner, now.Sub(hb.StartedAt).Round(time.Second))
+	}
+
+	marked, _ := executor.ReconcileInterrupted(envState)
+	refreshed := e.probeInterrupted(ctx, marked)
+	if err := e.stateManager.SaveEnvironment(ctx, dc, envState); err != nil {
+		return fmt.Errorf("failed to take over environment %q: %w", envName, err)
+	}
+	if w != nil {
+		fmt.Fprintf(w, "Took over environment %q from %s: %d in-flight resource(s) will be re-applied", envName, hb.Owner, len(marked))
+		if refreshed > 0 {
+			fmt.Fprintf(w, " (%d refreshed from their infrastructure)", refreshed)
+		}
+		fmt.Fprint(w, "\n\n")
+	}
+	return nil
+}
+
+// reconcileStuckResources normalizes resources recorded as in flight in an
+// environment no deploy is running against, such as those left by a run
+// killed before it recorded a heartbeat. Left alone, they would be shown as
+// still provisioning and planned as if their last apply had finished.
+func (e *Engine) reconcileStuckResources(ctx context.Context, dc string, envState *types.EnvironmentState, w io.Writer) error {
+	marked, modules := executor.MarkInterrupted(envState)
+	if len(marked) == 0 && modules == 0 {
+		return nil
+	}
+
+	refreshed := e.probeInterrupted(ctx, marked)
+	envState.Status = types.EnvironmentStatusFailed
+	envState.UpdatedAt = time.Now()
+	if err := e.stateManager.SaveEnvironment(ctx, dc, envState); err != nil {
+		return fmt.Errorf("failed to reconcile environment %q: %w", envState.Name, err)
+	}
+	if w != nil && len(marked) > 0 {
+		fmt.Fprintf(w, "Found %d resource(s) left in flight by an earlier run; they will be re-applied", len(marked))
+		if refreshed > 0 {
+			fmt.Fprintf(w, " (%d refreshed from their infrastructure)", refreshed)
+		}
+		fmt.Fprint(w, "\n\n")
+	}
+	return nil
+}
+
+// probeInterrupted refreshes the recorded IaC state of resources left in
+// flight with the plugins that can read back real infrastructure (see
+// iac.RefreshesState), so they're re-applied from what actually exists
+// rather than from what was last recorded. A failed probe is noted in the
+// resource's status reason. It returns the number of resources refreshed.
+func (e *Engine) probeInterrupted(ctx context.Context, resources []*types.ResourceState) int {
+	refreshed := 0
+	for _, res := range resources {
+		names := make([]string, 0, len(res.ModuleStates))
+		for name := range res.ModuleStates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		probed := false
+		var failed []string
+		for _, name := range names {
+			ms := res.ModuleStates[name]
+			if ms == nil || len(ms.IaCState) == 0 {
+				continue
+			}
+			plugin, err := e.iacRegistry.Get(ms.Plugin)
+			if err != nil || !iac.RefreshesState(plugin) {
+				continue
+			}
+			credEnv, err := executor.CredentialEnvironment(ctx, ms.Credentials, executor.CredentialSessionName(name), nil)
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", name, err))
+				continue
+			}
+			result, err := plugin.Refresh(ctx, iac.RunOptions{
+				ModuleSource: ms.Source,
+				Inputs:       ms.Inputs,
+				Environment:  credEnv,
+				StateReader:  bytes.NewReader(ms.IaCState),
+			})
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", name, err))
+				continue
+			}
+			if len(result.State) > 0 {
+				ms.IaCState = result.State
+				ms.UpdatedAt = time.Now()
+			}
+			if len(result.Outputs) > 0 {
+				ms.Outputs = make(map[string]interface{}, len(result.Outputs))
+				for k, v := range result.Outputs {
+					ms.Outputs[k] = v.Value
+				}
+			}
+			probed = true
+		}
+
+		if len(failed) > 0 {
+			res.StatusReason += fmt.Sprintf(" (refresh failed: %s)", strings.Join(failed, "; "))
+		} else if probed {
+			refreshed++
+		}
+	}
+	return refreshed
+}
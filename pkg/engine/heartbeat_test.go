@@ -0,0 +1,160 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func TestCheckInFlightDeploy(t *testing.T) {
+	ctx := context.Background()
+	mgr := newMockStateManager()
+	eng := NewEngine(mgr, nil)
+
+	now := time.Now()
+	_ = mgr.SaveEnvironment(ctx, "dc", &types.EnvironmentState{Name: "idle", Status: types.EnvironmentStatusReady})
+	_ = mgr.SaveEnvironment(ctx, "dc", &types.EnvironmentState{
+		Name:      "running",
+		Status:    types.EnvironmentStatusProvisioning,
+		Heartbeat: &types.DeployHeartbeat{Owner: "ci:1", StartedAt: now.Add(-time.Minute), UpdatedAt: now},
+	})
+	_ = mgr.SaveEnvironment(ctx, "dc", &types.EnvironmentState{
+		Name:      "crashed",
+		Status:    types.EnvironmentStatusProvisioning,
+		Heartbeat: &types.DeployHeartbeat{Owner: "ci:2", StartedAt: now.Add(-time.Hour), UpdatedAt: now.Add(-time.Hour)},
+		Components: map[string]*types.ComponentState{
+			"app": {Name: "app", Resources: map[string]*types.ResourceState{
+				"database.main": {Name: "main", Status: types.ResourceStatusProvisioning},
+			}},
+		},
+	})
+
+	if err := eng.checkInFlightDeploy(ctx, "dc", "idle", false, nil); err != nil {
+		t.Errorf("expected idle environment to be allowed, got %v", err)
+	}
+	if err := eng.checkInFlightDeploy(ctx, "dc", "new", false, nil); err != nil {
+		t.Errorf("expected new environment to be allowed, got %v", err)
+	}
+
+	err := eng.checkInFlightDeploy(ctx, "dc", "running", false, nil)
+	if err == nil || !strings.Contains(err.Error(), "is being deployed by ci:1") {
+		t.Errorf("expected in-progress deploy to be refused, got %v", err)
+	}
+
+	err = eng.checkInFlightDeploy(ctx, "dc", "crashed", false, nil)
+	if err == nil || !strings.Contains(err.Error(), "appears to have crashed") || !strings.Contains(err.Error(), "--take-over") {
+		t.Fatalf("expected crashed deploy to be reported, got %v", err)
+	}
+
+	var out strings.Builder
+	if err := eng.checkInFlightDeploy(ctx, "dc", "crashed", true, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	env := mgr.environments["crashed"]
+	if env.Heartbeat != nil || env.Status != types.EnvironmentStatusFailed {
+		t.Errorf("expected take-over to clear the heartbeat and fail the environment, got %s, %+v", env.Status, env.Heartbeat)
+	}
+	if got := env.Components["app"].Resources["database.main"].Status; got != types.ResourceStatusUnknown {
+		t.Errorf("expected in-flight resource to be marked unknown, got %s", got)
+	}
+	if !strings.Contains(out.String(), "1 in-flight resource(s)") {
+		t.Errorf("unexpected take-over output: %q", out.String())
+	}
+}
+
+// prober is an IaC plugin whose Refresh reads back infrastructure.
+type prober struct {
+	hookRecorder
+	err    error
+	drifts []iac.ResourceDrift
+}
+
+func (p *prober) RefreshesState() bool { return true }
+
+func (p *prober) Refresh(ctx context.Context, opts iac.RunOptions) (*iac.RefreshResult, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &iac.RefreshResult{
+		State:   []byte("refreshed"),
+		Drifts:  p.drifts,
+		Outputs: map[string]iac.OutputValue{"id": {Value: "refreshed-id"}},
+	}, nil
+}
+
+func TestCheckInFlightDeploy_StuckWithoutHeartbeat(t *testing.T) {
+	ctx := context.Background()
+	mgr := newMockStateManager()
+	registry := iac.NewRegistry()
+	registry.Register("prober", func() (iac.Plugin, error) { return &prober{}, nil })
+	registry.Register("broken", func() (iac.Plugin, error) { return &prober{err: errors.New("no credentials")}, nil })
+	registry.Register("recorder", func() (iac.Plugin, error) { return &hookRecorder{}, nil })
+	eng := NewEngine(mgr, registry)
+
+	stuck := func(plugin string) *types.ResourceState {
+		return &types.ResourceState{
+			Status: types.ResourceStatusProvisioning,
+			ModuleStates: map[string]*types.ModuleState{
+				"m": {Name: "m", Plugin: plugin, IaCState: []byte("recorded")},
+			},
+		}
+	}
+	_ = mgr.SaveEnvironment(ctx, "dc", &types.EnvironmentState{
+		Name:   "staging",
+		Status: types.EnvironmentStatusProvisioning,
+		Components: map[string]*types.ComponentState{
+			"app": {Name: "app", Resources: map[string]*types.ResourceState{
+				"database.main":     stuck("prober"),
+				"bucket.uploads":    stuck("broken"),
+				"deployment.api":    stuck("recorder"),
+				"deployment.worker": {Status: types.ResourceStatusReady},
+			}},
+		},
+	})
+
+	var out strings.Builder
+	if err := eng.checkInFlightDeploy(ctx, "dc", "staging", false, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	env := mgr.environments["staging"]
+	resources := env.Components["app"].Resources
+	for key, res := range resources {
+		want := types.ResourceStatusUnknown
+		if key == "deployment.worker" {
+			want = types.ResourceStatusReady
+		}
+		if res.Status != want {
+			t.Errorf("%s status = %s, want %s", key, res.Status, want)
+		}
+	}
+	if got := string(resources["database.main"].ModuleStates["m"].IaCState); got != "refreshed" {
+		t.Errorf("expected the probed resource's state to be refreshed, got %q", got)
+	}
+	if got := resources["database.main"].ModuleStates["m"].Outputs["id"]; got != "refreshed-id" {
+		t.Errorf("expected the probed resource's outputs to be refreshed, got %v", got)
+	}
+	if got := string(resources["deployment.api"].ModuleStates["m"].IaCState); got != "recorded" {
+		t.Errorf("expected a plugin that can't probe to leave the state alone, got %q", got)
+	}
+	if reason := resources["bucket.uploads"].StatusReason; !strings.Contains(reason, "refresh failed: m: no credentials") {
+		t.Errorf("expected the failed probe to be noted, got %q", reason)
+	}
+	if env.Status != types.EnvironmentStatusFailed {
+		t.Errorf("environment status = %s, want failed", env.Status)
+	}
+	if !strings.Contains(out.String(), "3 resource(s) left in flight") || !strings.Contains(out.String(), "1 refreshed") {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+
+	// Nothing left in flight, so nothing more to do
+	out.Reset()
+	if err := eng.checkInFlightDeploy(ctx, "dc", "staging", false, &out); err != nil || out.Len() != 0 {
+		t.Errorf("expected a second check to find nothing, got %v, %q", err, out.String())
+	}
+}
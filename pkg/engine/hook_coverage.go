@@ -0,0 +1,308 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/schema/component"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+)
+
+// fallbackNodeTypes are the resource types the executor handles without a
+// hook when none matches, so they are never reported as uncovered.
+var fallbackNodeTypes = map[graph.NodeType]bool{
+	graph.NodeTypePort:          true,
+	graph.NodeTypeSecret:        true,
+	graph.NodeTypeDatabaseUser:  true,
+	graph.NodeTypeNetworkPolicy: true,
+	graph.NodeTypeExternal:      true,
+}
+
+// hookNodeTypes are the resource types provisioned by hooks, in the order
+// coverage reports list them.
+var hookNodeTypes = []graph.NodeType{
+	graph.NodeTypeDatabase,
+	graph.NodeTypeDatabaseUser,
+	graph.NodeTypeTask,
+	graph.NodeTypeBucket,
+	graph.NodeTypeEncryptionKey,
+	graph.NodeTypeSMTP,
+	graph.NodeTypeDockerBuild,
+	graph.NodeTypeDeployment,
+	graph.NodeTypeFunction,
+	graph.NodeTypeCronjob,
+	graph.NodeTypeService,
+	graph.NodeTypeRoute,
+	graph.NodeTypeRouteAuth,
+	graph.NodeTypePort,
+	graph.NodeTypeObservability,
+	graph.NodeTypeNetworkPolicy,
+}
+
+// coverageEnvironment names the environment the graphs are built for when
+// none is given. Nothing is deployed to it.
+const coverageEnvironment = "hook-coverage"
+
+// HookCoverageOptions configures CheckHookCoverage.
+type HookCoverageOptions struct {
+	// Datacenter is the datacenter whose hooks are checked
+	Datacenter datacenter.Datacenter
+
+	// DatacenterName is the name hooks see as environment.datacenter
+	DatacenterName string
+
+	// Environment is the environment name hooks see as environment.name,
+	// and that selects per-environment variable defaults
+	Environment string
+
+	// Variables are datacenter variable values, resolved like a deploy would
+	Variables map[string]string
+
+	// Components are the sample components, keyed by name. Each is checked
+	// in a graph of its own.
+	Components map[string]component.Component
+}
+
+// HookCoverageReport is the result of matching the resources of a corpus of
+// sample components against a datacenter's hooks.
+type HookCoverageReport struct {
+	Components []string `json:"components"`
+
+	// Hooks lists every hook of the datacenter with the resources it matched
+	Hooks []HookCoverage `json:"hooks"`
+
+	// Uncovered are resource combinations that no hook handles
+	Uncovered []ResourceCombination `json:"uncovered,omitempty"`
+
+	// Rejected are resource combinations an error hook rejects on purpose
+	Rejected []ResourceCombination `json:"rejected,omitempty"`
+
+	// Problems are failed checks other than coverage: components that
+	// can't be graphed, missing capabilities, and hook outputs that
+	// components consume or the resource type requires but the matching
+	// hooks don't declare
+	Problems []CoverageProblem `json:"problems,omitempty"`
+}
+
+// Passed reports whether every resource is handled by a hook that declares
+// the outputs it needs to.
+func (r *HookCoverageReport) Passed() bool {
+	return len(r.Uncovered) == 0 && len(r.Problems) == 0
+}
+
+// HookCoverage is a datacenter hook and the resources it matched.
+type HookCoverage struct {
+	// Hook describes the hook (e.g., "database[0] (when node.inputs.type == \"postgres\")")
+	Hook string `json:"hook"`
+	Type string `json:"type"`
+
+	// Resources are the IDs of the nodes whose modules the hook runs
+	Resources []string `json:"resources,omitempty"`
+}
+
+// ResourceCombination groups resources of one type (and, for resources with
+// a type input such as databases, one variant) that share a result.
+type ResourceCombination struct {
+	Type      string   `json:"type"`
+	Variant   string   `json:"variant,omitempty"`
+	Reason    string   `json:"reason"`
+	Resources []string `json:"resources"`
+}
+
+// CoverageProblem is a failed check for a sample component.
+type CoverageProblem struct {
+	Component string `json:"component"`
+	Message   string `json:"message"`
+}
+
+// CheckHookCoverage matches every resource of a corpus of sample components
+// against the datacenter's hooks, the way the executor would at deploy
+// time, and validates the outputs of the hooks that match. It deploys
+// nothing; it is a safety net for datacenter refactors that shows which
+// hooks fire and which resource combinations are left without one.
+func CheckHookCoverage(opts HookCoverageOptions) *HookCoverageReport {
+	dc := opts.Datacenter
+	envName := opts.Environment
+	if envName == "" {
+		envName = coverageEnvironment
+	}
+	vars := datacenter.ResolveVariables(dc, opts.Variables, envName)
+
+	var hooks datacenter.Hooks
+	if env := dc.Environment(); env != nil {
+		hooks = env.Hooks()
+	}
+
+	report := &HookCoverageReport{}
+	fired := make(map[string]map[int][]string)
+	uncovered := make(map[string]*ResourceCombination)
+	rejected := make(map[string]*ResourceCombination)
+	addCombination := func(into map[string]*ResourceCombination, n *graph.Node, reason string) {
+		variant, _ := n.Inputs["type"].(string)
+		key := string(n.Type) + "\x00" + variant + "\x00" + reason
+		c, ok := into[key]
+		if !ok {
+			c = &ResourceCombination{Type: string(n.Type), Variant: variant, Reason: reason}
+			into[key] = c
+		}
+		c.Resources = append(c.Resources, n.ID)
+	}
+
+	for name := range opts.Components {
+		report.Components = append(report.Components, name)
+	}
+	sort.Strings(report.Components)
+
+	for _, name := range report.Components {
+		comp := opts.Components[name]
+		problem := func(format string, args ...interface{}) {
+			report.Problems = append(report.Problems, CoverageProblem{Component: name, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if err := checkCapabilities(opts.DatacenterName, dc, name, comp); err != nil {
+			problem("%v", err)
+		}
+		g, err := BuildGraph(envName, opts.DatacenterName, dc, map[string]component.Component{name: comp})
+		if err != nil {
+			problem("%v", err)
+			continue
+		}
+		if err := checkHookOutputContracts(g, dc); err != nil {
+			var contractErr *HookOutputContractError
+			if errors.As(err, &contractErr) {
+				for _, m := range contractErr.Mismatches {
+					problem("%s: ${{ %s }}: %s hook for %s does not declare output %q", m.Consumer, m.Expression, m.Hook, m.Resource, m.Output)
+				}
+			} else {
+				problem("%v", err)
+			}
+		}
+
+		ids := make([]string, 0, len(g.Nodes))
+		for id := range g.Nodes {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			n := g.Nodes[id]
+			var typeHooks []datacenter.Hook
+			if hooks != nil {
+				typeHooks = hooksForType(hooks, n.Type)
+			}
+			if len(typeHooks) == 0 {
+				if !fallbackNodeTypes[n.Type] {
+					addCombination(uncovered, n, "no hooks defined for this type")
+				}
+				continue
+			}
+
+			var matched []int
+			hook, _ := datacenter.MatchHook(typeHooks, func(h datacenter.Hook) bool {
+				// Like CheckCompatibility, assume a clause that can't be
+				// evaluated before deploy matches
+				if matched, ok := evaluateEnvironmentWhen(h.When(), n, envName, opts.DatacenterName, nil, vars); ok && !matched {
+					return false
+				}
+				matched = append(matched, indexOfHook(typeHooks, h))
+				return true
+			})
+			if hook == nil {
+				if !fallbackNodeTypes[n.Type] {
+					addCombination(uncovered, n, "no hook matches")
+				}
+				continue
+			}
+
+			if fired[string(n.Type)] == nil {
+				fired[string(n.Type)] = make(map[int][]string)
+			}
+			for _, i := range matched {
+				fired[string(n.Type)][i] = append(fired[string(n.Type)][i], n.ID)
+			}
+
+			if hook.Error() != "" {
+				addCombination(rejected, n, hook.Error())
+				continue
+			}
+			if missing := missingRequiredOutputs(string(n.Type), hook); len(missing) > 0 {
+				problem("%s: the matching %s hook is missing required outputs: %s", n.ID, n.Type, strings.Join(missing, ", "))
+			}
+		}
+	}
+
+	if hooks != nil {
+		for _, nodeType := range hookNodeTypes {
+			typeHooks := hooksForType(hooks, nodeType)
+			for i, h := range typeHooks {
+				report.Hooks = append(report.Hooks, HookCoverage{
+					Hook:      coverageHookLabel(string(nodeType), typeHooks, i, h),
+					Type:      string(nodeType),
+					Resources: fired[string(nodeType)][i],
+				})
+			}
+		}
+	}
+	report.Uncovered = sortedCombinations(uncovered)
+	report.Rejected = sortedCombinations(rejected)
+	return report
+}
+
+// missingRequiredOutputs returns the outputs a matching hook (or chain of
+// fallthrough hooks) must declare for hookType but doesn't.
+func missingRequiredOutputs(hookType string, hook datacenter.Hook) []string {
+	// Hooks without modules (e.g., partial hooks of an extended
+	// datacenter) produce no outputs to check.
+	if len(hook.Modules()) == 0 {
+		return nil
+	}
+	var missing []string
+	for _, key := range datacenter.RequiredHookOutputs(hookType, hook) {
+		if !datacenter.HookDeclaresOutput(hook, key) {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+func indexOfHook(hooks []datacenter.Hook, h datacenter.Hook) int {
+	for i, candidate := range hooks {
+		if candidate == h {
+			return i
+		}
+	}
+	return -1
+}
+
+func coverageHookLabel(hookType string, hooks []datacenter.Hook, i int, h datacenter.Hook) string {
+	switch {
+	case h.When() != "":
+		return fmt.Sprintf("%s[%d] (when %s)", hookType, i, h.When())
+	case len(hooks) > 1:
+		return fmt.Sprintf("%s[%d]", hookType, i)
+	}
+	return hookType
+}
+
+func sortedCombinations(m map[string]*ResourceCombination) []ResourceCombination {
+	result := make([]ResourceCombination, 0, len(m))
+	for _, c := range m {
+		result = append(result, *c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Type != result[j].Type {
+			return result[i].Type < result[j].Type
+		}
+		if result[i].Variant != result[j].Variant {
+			return result[i].Variant < result[j].Variant
+		}
+		return result[i].Reason < result[j].Reason
+	})
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
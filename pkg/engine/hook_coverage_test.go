@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/schema/component"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+)
+
+func TestCheckHookCoverage(t *testing.T) {
+	dc, err := datacenter.NewLoader().LoadFromBytes([]byte(`
+environment {
+  database {
+    when  = node.inputs.type == "redis"
+    error = "redis is not offered"
+  }
+
+  database {
+    when = node.inputs.type == "postgres:^16"
+    module "postgres" {
+      build = "./modules/postgres"
+    }
+    outputs = {
+      host = module.postgres.host
+      port = module.postgres.port
+      url  = module.postgres.url
+    }
+  }
+
+  deployment {
+    module "deployment" {
+      build = "./modules/deployment"
+    }
+    outputs = {
+      id = module.deployment.id
+    }
+  }
+
+  route {
+    module "route" {
+      build = "./modules/route"
+    }
+    outputs = {
+      url  = module.route.url
+      host = module.route.host
+      port = module.route.port
+    }
+  }
+}
+`), "/tmp/dc/datacenter.dc")
+	if err != nil {
+		t.Fatalf("failed to load datacenter: %v", err)
+	}
+	loadComp := func(yaml string) component.Component {
+		comp, err := component.NewLoader().LoadFromBytes([]byte(yaml), "/tmp/app/cld.yml")
+		if err != nil {
+			t.Fatalf("failed to load component: %v", err)
+		}
+		return comp
+	}
+
+	t.Run("covered", func(t *testing.T) {
+		report := CheckHookCoverage(HookCoverageOptions{
+			Datacenter: dc,
+			Components: map[string]component.Component{"api": loadComp(`
+databases:
+  main:
+    type: postgres:^16
+deployments:
+  api:
+    image: api:latest
+    environment:
+      DATABASE_URL: ${{ databases.main.url }}
+`)},
+		})
+		if !report.Passed() {
+			t.Fatalf("expected the corpus to pass, got %+v", report)
+		}
+
+		fired := make(map[string][]string)
+		for _, h := range report.Hooks {
+			fired[h.Hook] = h.Resources
+		}
+		expected := map[string][]string{
+			`database[0] (when node.inputs.type == "redis")`:        nil,
+			`database[1] (when node.inputs.type == "postgres:^16")`: {"api/database/main"},
+			"deployment": {"api/deployment/api"},
+			"route":      nil,
+		}
+		if !reflect.DeepEqual(fired, expected) {
+			t.Errorf("expected hooks %v, got %v", expected, fired)
+		}
+	})
+
+	t.Run("gaps", func(t *testing.T) {
+		report := CheckHookCoverage(HookCoverageOptions{
+			Datacenter: dc,
+			Components: map[string]component.Component{
+				"api": loadComp(`
+databases:
+  main:
+    type: postgres:^16
+  cache:
+    type: redis
+deployments:
+  api:
+    image: api:latest
+    environment:
+      DATABASE_USER: ${{ databases.main.username }}
+`),
+				"worker": loadComp(`
+databases:
+  jobs:
+    type: mysql:^8
+cronjobs:
+  cleanup:
+    image: worker:latest
+    schedule: "0 * * * *"
+`),
+			},
+		})
+		if report.Passed() {
+			t.Fatal("expected the corpus to fail")
+		}
+		if !reflect.DeepEqual(report.Components, []string{"api", "worker"}) {
+			t.Errorf("unexpected components: %v", report.Components)
+		}
+
+		expectedUncovered := []ResourceCombination{
+			{Type: "cronjob", Reason: "no hooks defined for this type", Resources: []string{"worker/cronjob/cleanup"}},
+			{Type: "database", Variant: "mysql:^8", Reason: "no hook matches", Resources: []string{"worker/database/jobs"}},
+		}
+		if !reflect.DeepEqual(report.Uncovered, expectedUncovered) {
+			t.Errorf("expected uncovered %+v, got %+v", expectedUncovered, report.Uncovered)
+		}
+		expectedRejected := []ResourceCombination{
+			{Type: "database", Variant: "redis", Reason: "redis is not offered", Resources: []string{"api/database/cache"}},
+		}
+		if !reflect.DeepEqual(report.Rejected, expectedRejected) {
+			t.Errorf("expected rejected %+v, got %+v", expectedRejected, report.Rejected)
+		}
+
+		if len(report.Problems) != 1 || report.Problems[0].Component != "api" ||
+			!strings.Contains(report.Problems[0].Message, `does not declare output "username"`) {
+			t.Errorf("expected a problem for the undeclared username output, got %+v", report.Problems)
+		}
+	})
+}
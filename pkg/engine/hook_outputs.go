@@ -0,0 +1,268 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/engine/expression"
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+)
+
+var hookOutputExprPattern = regexp.MustCompile(`\$\{\{\s*([^}]+)\s*\}\}`)
+
+// consumedOutputTypes maps component expression roots to the resource types
+// whose hook outputs they read. Builds, ports and observability are
+// resolved with engine fallbacks and are not checked.
+var consumedOutputTypes = map[string]graph.NodeType{
+	"databases":      graph.NodeTypeDatabase,
+	"buckets":        graph.NodeTypeBucket,
+	"encryptionKeys": graph.NodeTypeEncryptionKey,
+	"smtp":           graph.NodeTypeSMTP,
+	"services":       graph.NodeTypeService,
+	"routes":         graph.NodeTypeRoute,
+}
+
+// HookOutputMismatch is a component expression that reads an output the
+// datacenter hook for the referenced resource does not declare.
+type HookOutputMismatch struct {
+	// Consumer is where the expression appears (e.g.,
+	// "app/deployment/api environment.POOL_URL").
+	Consumer string
+	// Expression is the reference without the ${{ }} delimiters.
+	Expression string
+	// Resource is the ID of the referenced node.
+	Resource string
+	// Hook describes the matching hook (e.g., "database (when ...)").
+	Hook string
+	// Output is the output the expression reads ("read.host" for nested outputs).
+	Output string
+}
+
+// HookOutputContractError is returned when components consume hook outputs
+// that the datacenter does not provide. It is raised at plan time so the
+// problem surfaces before anything is applied, rather than as an empty
+// value in a running workload.
+type HookOutputContractError struct {
+	Mismatches []HookOutputMismatch
+}
+
+func (e *HookOutputContractError) Error() string {
+	var b strings.Builder
+	b.WriteString("components consume hook outputs the datacenter does not provide:")
+	for _, m := range e.Mismatches {
+		fmt.Fprintf(&b, "\n  - %s: ${{ %s }}: %s hook for %s does not declare output %q", m.Consumer, m.Expression, m.Hook, m.Resource, m.Output)
+	}
+	b.WriteString("\nAdd the output to the hook's outputs block (and list it in guarantees to enforce it at deploy time)")
+	return b.String()
+}
+
+// checkHookOutputContracts verifies that every hook output consumed through
+// a component expression is declared by the datacenter hook that will
+// provision the referenced resource. Hooks whose outputs can't be known
+// statically (no declared outputs, or a when clause that can't be evaluated
+// yet) are skipped; the executor validates their outputs at deploy time.
+func checkHookOutputContracts(g *graph.Graph, dc datacenter.Datacenter) error {
+	if dc == nil || dc.Environment() == nil || dc.Environment().Hooks() == nil {
+		return nil
+	}
+	hooks := dc.Environment().Hooks()
+
+	// Index nodes by component/type/name so instance-qualified nodes are
+	// found by the unqualified names used in expressions.
+	byName := make(map[string]*graph.Node)
+	ids := make([]string, 0, len(g.Nodes))
+	for id, n := range g.Nodes {
+		ids = append(ids, id)
+		key := n.Component + "/" + string(n.Type) + "/" + n.Name
+		if existing, ok := byName[key]; !ok || n.ID < existing.ID {
+			byName[key] = n
+		}
+	}
+	sort.Strings(ids)
+
+	var mismatches []HookOutputMismatch
+	check := func(consumer, component, consumerName, value string) {
+		for _, match := range hookOutputExprPattern.FindAllStringSubmatch(value, -1) {
+			expr := strings.TrimSpace(match[1])
+			refPart, pipes, hasPipe := strings.Cut(expr, "|")
+			// A default makes a missing output intentional.
+			if hasPipe && strings.Contains(pipes, "default") {
+				continue
+			}
+			// Computed expressions read every reference they contain
+			refs := []string{strings.TrimSpace(refPart)}
+			if expression.IsComputed(refPart) {
+				var err error
+				if refs, err = expression.References(refPart); err != nil {
+					continue
+				}
+			}
+			for _, ref := range refs {
+				parts := strings.Split(ref, ".")
+				nodeType, ok := consumedOutputTypes[parts[0]]
+				if !ok || len(parts) < 3 {
+					continue
+				}
+				target := byName[component+"/"+string(nodeType)+"/"+parts[1]]
+				if target == nil {
+					continue
+				}
+
+				output := parts[2]
+				if len(parts) >= 4 {
+					output = parts[2] + "." + parts[3]
+				}
+
+				hook, label, known := matchingHook(hooksForType(hooks, nodeType), target.Inputs)
+				if !known || providesOutput(hook, nodeType, output) {
+					continue
+				}
+				// Per-consumer database credentials come from an interposed
+				// databaseUser node, falling back field by field to the database.
+				if nodeType == graph.NodeTypeDatabase {
+					userKey := component + "/" + string(graph.NodeTypeDatabaseUser) + "/" + parts[1] + "--" + consumerName
+					if user := byName[userKey]; user != nil {
+						userHook, _, userKnown := matchingHook(hooks.DatabaseUser(), user.Inputs)
+						if !userKnown || providesOutput(userHook, graph.NodeTypeDatabase, output) {
+							continue
+						}
+					}
+				}
+
+				mismatches = append(mismatches, HookOutputMismatch{
+					Consumer:   consumer,
+					Expression: expr,
+					Resource:   target.ID,
+					Hook:       string(nodeType) + label,
+					Output:     output,
+				})
+			}
+		}
+	}
+
+	for _, id := range ids {
+		n := g.Nodes[id]
+		if env, ok := n.Inputs["environment"].(map[string]string); ok {
+			keys := make([]string, 0, len(env))
+			for k := range env {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				check(id+" environment."+k, n.Component, n.Name, env[k])
+			}
+		}
+		for _, field := range []string{"image", "port"} {
+			if v, ok := n.Inputs[field].(string); ok {
+				check(id+" "+field, n.Component, n.Name, v)
+			}
+		}
+	}
+
+	compNames := make([]string, 0, len(g.ComponentOutputExprs))
+	for name := range g.ComponentOutputExprs {
+		compNames = append(compNames, name)
+	}
+	sort.Strings(compNames)
+	for _, compName := range compNames {
+		outputs := g.ComponentOutputExprs[compName]
+		keys := make([]string, 0, len(outputs))
+		for k := range outputs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			check(compName+" outputs."+k, compName, "", outputs[k])
+		}
+	}
+
+	if len(mismatches) == 0 {
+		return nil
+	}
+	return &HookOutputContractError{Mismatches: mismatches}
+}
+
+// providesOutput reports whether hook declares output. Database read/write
+// endpoints fall back to the top-level value when not set explicitly.
+func providesOutput(hook datacenter.Hook, nodeType graph.NodeType, output string) bool {
+	// Outputs are only known statically when the hook declares some.
+	if len(hook.Outputs()) == 0 && len(hook.NestedOutputs()) == 0 {
+		return true
+	}
+	if datacenter.HookDeclaresOutput(hook, output) {
+		return true
+	}
+	if nodeType == graph.NodeTypeDatabase {
+		if parent, child, ok := strings.Cut(output, "."); ok && (parent == "read" || parent == "write") {
+			return datacenter.HookDeclaresOutput(hook, child)
+		}
+	}
+	return false
+}
+
+// matchingHook returns the hook the executor would select for a node with
+// the given inputs, along with a label for error messages. known is false
+// when no hook matches, the matching hook rejects the resource, or a when
+// clause can't be evaluated before deploy.
+func matchingHook(hooks []datacenter.Hook, inputs map[string]interface{}) (hook datacenter.Hook, label string, known bool) {
+	unknown := false
+	h, i := datacenter.MatchHook(hooks, func(h datacenter.Hook) bool {
+		matched, ok := evaluateHookWhen(h.When(), inputs)
+		if !ok {
+			unknown = true
+		}
+		return matched
+	})
+	if unknown || h == nil || h.Error() != "" {
+		return nil, "", false
+	}
+	switch {
+	case h.When() != "":
+		label = fmt.Sprintf(" (when %s)", h.When())
+	case len(hooks) > 1:
+		label = fmt.Sprintf("[%d]", i)
+	}
+	return h, label, true
+}
+
+// hooksForType returns the datacenter hooks that provision nodeType.
+func hooksForType(hooks datacenter.Hooks, nodeType graph.NodeType) []datacenter.Hook {
+	switch nodeType {
+	case graph.NodeTypeDatabase:
+		return hooks.Database()
+	case graph.NodeTypeBucket:
+		return hooks.Bucket()
+	case graph.NodeTypeEncryptionKey:
+		return hooks.EncryptionKey()
+	case graph.NodeTypeSMTP:
+		return hooks.SMTP()
+	case graph.NodeTypeService:
+		return hooks.Service()
+	case graph.NodeTypeRoute:
+		return hooks.Route()
+	case graph.NodeTypeDeployment:
+		return hooks.Deployment()
+	case graph.NodeTypeFunction:
+		return hooks.Function()
+	case graph.NodeTypeCronjob:
+		return hooks.Cronjob()
+	case graph.NodeTypeDockerBuild:
+		return hooks.DockerBuild()
+	case graph.NodeTypeTask:
+		return hooks.Task()
+	case graph.NodeTypeObservability:
+		return hooks.Observability()
+	case graph.NodeTypePort:
+		return hooks.Port()
+	case graph.NodeTypeDatabaseUser:
+		return hooks.DatabaseUser()
+	case graph.NodeTypeNetworkPolicy:
+		return hooks.NetworkPolicy()
+	case graph.NodeTypeRouteAuth:
+		return hooks.RouteAuth()
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package engine
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+)
+
+func loadContractDatacenter(t *testing.T) datacenter.Datacenter {
+	t.Helper()
+	dc, err := datacenter.NewLoader().LoadFromBytes([]byte(`
+environment {
+  database {
+    when = node.inputs.type == "redis"
+    module "redis" {
+      build = "./redis"
+    }
+    outputs = {
+      host = module.redis.host
+      port = module.redis.port
+      url  = module.redis.url
+    }
+  }
+
+  database {
+    guarantees = ["poolUrl"]
+    module "postgres" {
+      build = "./postgres"
+    }
+    outputs = {
+      host     = module.postgres.host
+      port     = module.postgres.port
+      url      = module.postgres.url
+      username = module.postgres.username
+      password = module.postgres.password
+      poolUrl  = module.postgres.pool_url
+    }
+  }
+}
+`), "/tmp/dc/datacenter.dc")
+	if err != nil {
+		t.Fatalf("failed to load datacenter: %v", err)
+	}
+	return dc
+}
+
+func contractGraph(env map[string]string) *graph.Graph {
+	g := graph.NewGraph("staging", "dc")
+	pg := graph.NewNode(graph.NodeTypeDatabase, "app", "main")
+	pg.SetInput("type", "postgres:^16")
+	cache := graph.NewNode(graph.NodeTypeDatabase, "app", "cache")
+	cache.SetInput("type", "redis")
+	api := graph.NewNode(graph.NodeTypeDeployment, "app", "api")
+	api.SetInput("environment", env)
+	_ = g.AddNode(pg)
+	_ = g.AddNode(cache)
+	_ = g.AddNode(api)
+	return g
+}
+
+func TestCheckHookOutputContracts(t *testing.T) {
+	dc := loadContractDatacenter(t)
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{
+			name: "built-in and guaranteed outputs",
+			env: map[string]string{
+				"DATABASE_URL": "${{ databases.main.url }}",
+				"POOL_URL":     "${{ databases.main.poolUrl }}",
+				"READ_HOST":    "${{ databases.main.read.host }}",
+				"REDIS_URL":    "${{ databases.cache.url }}",
+			},
+		},
+		{
+			name: "output not declared by the matching hook",
+			env: map[string]string{
+				"REDIS_PASSWORD": "${{ databases.cache.password }}",
+			},
+			wantErr: `app/deployment/api environment.REDIS_PASSWORD: ${{ databases.cache.password }}: database (when node.inputs.type == "redis") hook for app/database/cache does not declare output "password"`,
+		},
+		{
+			name: "nested output missing at both levels",
+			env: map[string]string{
+				"READ_USER": "${{ databases.cache.read.username }}",
+			},
+			wantErr: `does not declare output "read.username"`,
+		},
+		{
+			name: "default makes missing output optional",
+			env: map[string]string{
+				"REDIS_PASSWORD": "${{ databases.cache.password | default '' }}",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkHookOutputContracts(contractGraph(tt.env), dc)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var contractErr *HookOutputContractError
+			if !errors.As(err, &contractErr) {
+				t.Fatalf("expected HookOutputContractError, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got:\n%v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckHookOutputContracts_DatabaseUserOutputs(t *testing.T) {
+	dc, err := datacenter.NewLoader().LoadFromBytes([]byte(`
+environment {
+  database {
+    module "postgres" {
+      build = "./postgres"
+    }
+    outputs = {
+      host = module.postgres.host
+      port = module.postgres.port
+      url  = module.postgres.url
+    }
+  }
+
+  databaseUser {
+    module "user" {
+      build = "./user"
+    }
+    outputs = {
+      url      = module.user.url
+      username = module.user.username
+    }
+  }
+}
+`), "/tmp/dc/datacenter.dc")
+	if err != nil {
+		t.Fatalf("failed to load datacenter: %v", err)
+	}
+
+	g := contractGraph(map[string]string{
+		"DB_USER": "${{ databases.main.username }}",
+		"DB_HOST": "${{ databases.main.host }}",
+	})
+	_ = g.AddNode(graph.NewNode(graph.NodeTypeDatabaseUser, "app", "main--api"))
+
+	if err := checkHookOutputContracts(g, dc); err != nil {
+		t.Errorf("expected databaseUser outputs to satisfy the contract, got %v", err)
+	}
+
+	g = contractGraph(map[string]string{"DB_PASSWORD": "${{ databases.main.password }}"})
+	_ = g.AddNode(graph.NewNode(graph.NodeTypeDatabaseUser, "app", "main--api"))
+	if err := checkHookOutputContracts(g, dc); err == nil {
+		t.Error("expected error when neither the database nor databaseUser hook declares password")
+	}
+}
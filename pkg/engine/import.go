@@ -139,6 +139,33 @@ type ImportDatacenterModuleResult struct {
 	Outputs           map[string]interface{}
 }
 
+// AdoptDatacenterModuleOptions configures adopting a module that was applied
+// outside cldctl as a datacenter root module.
+type AdoptDatacenterModuleOptions struct {
+	// Datacenter name
+	Datacenter string
+
+	// Module name. Hooks reference the module's outputs by this name.
+	Module string
+
+	// Plugin that manages the module's state (e.g., "opentofu"). Defaults to
+	// the plugin of the datacenter's module with the same name, if declared.
+	Plugin string
+
+	// Source is the OCI reference or local path of the module's code, used to
+	// destroy it. Ignored for modules declared in the datacenter.
+	Source string
+
+	// State is the module's serialized IaC state (e.g., a .tfstate file)
+	State []byte
+
+	// Output writer for progress
+	Output io.Writer
+
+	// Force overwrites existing module state
+	Force bool
+}
+
 // ImportEnvironmentModuleOptions configures an environment-scoped module import.
 type ImportEnvironmentModuleOptions struct {
 	// Datacenter name
@@ -170,6 +197,13 @@ type ImportEnvironmentModuleResult struct {
 
 // ImportResource imports a single existing cloud resource into cldctl state.
 func (e *Engine) ImportResource(ctx context.Context, opts ImportResourceOptions) (*ImportResourceResult, error) {
+	lockedCtx, unlock, err := e.lockEnvironment(ctx, opts.Datacenter, opts.Environment, "import", false, opts.Output)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	ctx = lockedCtx
+
 	result := &ImportResourceResult{
 		ResourceKey: opts.ResourceKey,
 	}
@@ -327,6 +361,14 @@ func (e *Engine) ImportResource(ctx context.Context, opts ImportResourceOptions)
 // ImportComponent imports all resources for a component from existing cloud infrastructure.
 func (e *Engine) ImportComponent(ctx context.Context, opts ImportComponentOptions) (*ImportComponentResult, error) {
 	startTime := time.Now()
+
+	lockedCtx, unlock, err := e.lockEnvironment(ctx, opts.Datacenter, opts.Environment, "import", false, opts.Output)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	ctx = lockedCtx
+
 	result := &ImportComponentResult{
 		Component: opts.Component,
 	}
@@ -434,6 +476,14 @@ func (e *Engine) ImportComponent(ctx context.Context, opts ImportComponentOption
 // ImportEnvironment imports multiple components into an environment from existing infrastructure.
 func (e *Engine) ImportEnvironment(ctx context.Context, opts ImportEnvironmentOptions) (*ImportEnvironmentResult, error) {
 	startTime := time.Now()
+
+	lockedCtx, unlock, err := e.lockEnvironment(ctx, opts.Datacenter, opts.Environment, "import", false, opts.Output)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	ctx = lockedCtx
+
 	result := &ImportEnvironmentResult{}
 
 	// Ensure environment exists (create if it doesn't)
@@ -636,10 +686,133 @@ func (e *Engine) ImportDatacenterModule(ctx context.Context, opts ImportDatacent
 	return result, nil
 }
 
+// AdoptDatacenterModule registers an existing module's state as a datacenter
+// root module, reading its outputs from the state so hooks can reference them
+// without re-creating the infrastructure. A module declared in the datacenter
+// is adopted with its declared plugin, source, and inputs; any other module is
+// recorded as adopted and left alone by datacenter deploys.
+func (e *Engine) AdoptDatacenterModule(ctx context.Context, opts AdoptDatacenterModuleOptions) (*ImportDatacenterModuleResult, error) {
+	dcState, err := e.stateManager.GetDatacenter(ctx, opts.Datacenter)
+	if err != nil {
+		return nil, fmt.Errorf("datacenter %q not found: %w", opts.Datacenter, err)
+	}
+
+	if !opts.Force && dcState.Modules != nil {
+		if existing, ok := dcState.Modules[opts.Module]; ok && existing.Status == types.ModuleStatusReady {
+			return nil, fmt.Errorf("module %q already has state (use --force to overwrite)", opts.Module)
+		}
+	}
+
+	modState := &types.ModuleState{
+		Name:      opts.Module,
+		Plugin:    opts.Plugin,
+		Source:    opts.Source,
+		IaCState:  opts.State,
+		Status:    types.ModuleStatusReady,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	var declared datacenter.Module
+	var dc datacenter.Datacenter
+	if dcState.Version != "" {
+		if dc, err = e.loadDatacenterConfig(dcState.Version); err != nil {
+			return nil, fmt.Errorf("failed to load datacenter configuration: %w", err)
+		}
+		for _, mod := range dc.Modules() {
+			if mod.Name() == opts.Module {
+				declared = mod
+				break
+			}
+		}
+	}
+
+	if declared != nil {
+		pluginName := declared.Plugin()
+		if pluginName == "" {
+			pluginName = "native"
+		}
+		if opts.Plugin != "" && opts.Plugin != pluginName {
+			return nil, fmt.Errorf("module %q is declared with plugin %q, not %q", opts.Module, pluginName, opts.Plugin)
+		}
+
+		modulePath := declared.Build()
+		if modulePath == "" {
+			modulePath = declared.Source()
+		}
+		if modulePath != "" && !filepath.IsAbs(modulePath) {
+			modulePath = filepath.Join(filepath.Dir(dc.SourcePath()), modulePath)
+		}
+
+		dcVars := make(map[string]interface{})
+		for k, v := range dcState.Variables {
+			dcVars[k] = v
+		}
+		inputs := make(map[string]interface{})
+		for inputName, exprStr := range declared.Inputs() {
+			inputs[inputName] = evaluateModuleExpression(exprStr, dcVars, nil, nil)
+		}
+
+		modState.Plugin = pluginName
+		modState.Source = modulePath
+		modState.Inputs = inputs
+	} else {
+		if opts.Plugin == "" {
+			return nil, fmt.Errorf("module %q is not declared in the datacenter; specify its plugin", opts.Module)
+		}
+		modState.Adopted = true
+	}
+
+	plugin, err := e.iacRegistry.Get(modState.Plugin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IaC plugin %q: %w", modState.Plugin, err)
+	}
+	reader, ok := plugin.(iac.StateOutputReader)
+	if !ok {
+		return nil, fmt.Errorf("plugin %q can't read outputs from existing state", modState.Plugin)
+	}
+	stateOutputs, err := reader.OutputsFromState(opts.State)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outputs for module %q: %w", opts.Module, err)
+	}
+
+	outputs := make(map[string]interface{}, len(stateOutputs))
+	for name, out := range stateOutputs {
+		outputs[name] = out.Value
+	}
+	modState.Outputs = outputs
+
+	if dcState.Modules == nil {
+		dcState.Modules = make(map[string]*types.ModuleState)
+	}
+	dcState.Modules[opts.Module] = modState
+	dcState.UpdatedAt = time.Now()
+	if err := e.stateManager.SaveDatacenter(ctx, dcState); err != nil {
+		return nil, fmt.Errorf("failed to save datacenter state: %w", err)
+	}
+
+	if opts.Output != nil {
+		fmt.Fprintf(opts.Output, "  [success] Module %q adopted (%d output(s))\n", opts.Module, len(outputs))
+	}
+
+	return &ImportDatacenterModuleResult{
+		Success: true,
+		Module:  opts.Module,
+		Outputs: outputs,
+	}, nil
+}
+
 // ImportEnvironmentModule imports existing infrastructure into an environment-scoped module's state.
 // Environment-scoped modules are defined inside the `environment {}` block of a datacenter
 // configuration (outside hooks). They are per-environment shared resources like VPCs or namespaces.
 func (e *Engine) ImportEnvironmentModule(ctx context.Context, opts ImportEnvironmentModuleOptions) (*ImportEnvironmentModuleResult, error) {
+	lockedCtx, unlock, err := e.lockEnvironment(ctx, opts.Datacenter, opts.Environment, "import", false, opts.Output)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	ctx = lockedCtx
+
 	result := &ImportEnvironmentModuleResult{
 		Module: opts.Module,
 	}
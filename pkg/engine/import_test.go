@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// stateReaderPlugin reads outputs from a flat JSON object of output values.
+type stateReaderPlugin struct {
+	destroyRecorder
+}
+
+func (p *stateReaderPlugin) OutputsFromState(state []byte) (map[string]iac.OutputValue, error) {
+	var values map[string]interface{}
+	if err := json.Unmarshal(state, &values); err != nil {
+		return nil, err
+	}
+	outputs := make(map[string]iac.OutputValue, len(values))
+	for k, v := range values {
+		outputs[k] = iac.OutputValue{Value: v}
+	}
+	return outputs, nil
+}
+
+func TestAdoptDatacenterModule(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dcFile := filepath.Join(dir, "datacenter.dc")
+	if err := os.WriteFile(dcFile, []byte(`
+module "vpc" {
+  plugin = "reader"
+  build  = "./modules/vpc"
+}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := newMockStateManager()
+	mgr.datacenter = &types.DatacenterState{Name: "prod-dc", Version: dcFile}
+
+	registry := iac.NewRegistry()
+	registry.Register("reader", func() (iac.Plugin, error) { return &stateReaderPlugin{}, nil })
+	registry.Register("recorder", func() (iac.Plugin, error) { return &destroyRecorder{}, nil })
+	eng := NewEngine(mgr, registry)
+
+	// Declared module: plugin and source come from the datacenter
+	state := []byte(`{"vpc_id": "vpc-0abc123"}`)
+	result, err := eng.AdoptDatacenterModule(ctx, AdoptDatacenterModuleOptions{
+		Datacenter: "prod-dc",
+		Module:     "vpc",
+		State:      state,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Outputs["vpc_id"] != "vpc-0abc123" {
+		t.Errorf("expected vpc_id output, got %v", result.Outputs)
+	}
+	vpc := mgr.datacenter.Modules["vpc"]
+	if vpc.Plugin != "reader" || vpc.Source != filepath.Join(dir, "modules", "vpc") || vpc.Adopted || string(vpc.IaCState) != string(state) {
+		t.Errorf("unexpected module state: %+v", vpc)
+	}
+
+	if _, err := eng.AdoptDatacenterModule(ctx, AdoptDatacenterModuleOptions{Datacenter: "prod-dc", Module: "vpc", State: state}); err == nil {
+		t.Error("expected error adopting over existing module state without force")
+	}
+	if _, err := eng.AdoptDatacenterModule(ctx, AdoptDatacenterModuleOptions{Datacenter: "prod-dc", Module: "vpc", Plugin: "opentofu", State: state, Force: true}); err == nil {
+		t.Error("expected error when the plugin doesn't match the declaration")
+	}
+
+	// Undeclared module: recorded as adopted
+	if _, err := eng.AdoptDatacenterModule(ctx, AdoptDatacenterModuleOptions{Datacenter: "prod-dc", Module: "dns", State: state}); err == nil {
+		t.Error("expected error adopting an undeclared module without a plugin")
+	}
+	if _, err := eng.AdoptDatacenterModule(ctx, AdoptDatacenterModuleOptions{Datacenter: "prod-dc", Module: "dns", Plugin: "reader", State: []byte(`{"zone_id": "Z123"}`)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dns := mgr.datacenter.Modules["dns"]; !dns.Adopted || dns.Outputs["zone_id"] != "Z123" {
+		t.Errorf("unexpected adopted module state: %+v", dns)
+	}
+
+	_, err = eng.AdoptDatacenterModule(ctx, AdoptDatacenterModuleOptions{Datacenter: "prod-dc", Module: "cdn", Plugin: "recorder", State: state})
+	if err == nil || !strings.Contains(err.Error(), "can't read outputs") {
+		t.Errorf("expected error for a plugin that can't read state, got %v", err)
+	}
+}
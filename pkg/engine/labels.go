@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/labels"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// componentLabels returns the labels to record for each deployed component:
+// the ones passed to the deploy, or else the ones recorded by an earlier
+// deploy.
+func componentLabels(envState *types.EnvironmentState, components map[string]string, deployLabels map[string]map[string]string) map[string]map[string]string {
+	result := make(map[string]map[string]string)
+	for compName := range components {
+		if l, ok := deployLabels[compName]; ok {
+			result[compName] = l
+		} else if envState != nil && envState.Components[compName] != nil && envState.Components[compName].Labels != nil {
+			result[compName] = envState.Components[compName].Labels
+		}
+	}
+	return result
+}
+
+// applyLabels passes each node its component's labels, merged over the
+// environment's, as the "labels" input. Hooks read them as node.inputs.labels,
+// e.g. to tag cloud resources, and changing labels updates the resources.
+func applyLabels(g *graph.Graph, envLabels map[string]string, compLabels map[string]map[string]string) {
+	for _, node := range g.Nodes {
+		merged := labels.Merge(envLabels, compLabels[node.Component])
+		if len(merged) == 0 {
+			continue
+		}
+		input := make(map[string]interface{}, len(merged))
+		for k, v := range merged {
+			input[k] = v
+		}
+		node.SetInput("labels", input)
+	}
+}
+
+// environmentLabels returns an environment's labels, tolerating environments
+// that don't exist yet.
+func environmentLabels(envState *types.EnvironmentState) map[string]string {
+	if envState == nil {
+		return nil
+	}
+	return envState.Labels
+}
@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func TestComponentLabels(t *testing.T) {
+	current := &types.EnvironmentState{
+		Components: map[string]*types.ComponentState{
+			"api": {Name: "api", Labels: map[string]string{"team": "payments"}},
+			"web": {Name: "web", Labels: map[string]string{"team": "growth"}},
+		},
+	}
+	components := map[string]string{"api": "./api", "web": "./web", "worker": "./worker"}
+	deployLabels := map[string]map[string]string{"web": {"team": "search"}}
+
+	got := componentLabels(current, components, deployLabels)
+	if got["api"]["team"] != "payments" {
+		t.Errorf("expected recorded labels to be kept for api, got %v", got["api"])
+	}
+	if got["web"]["team"] != "search" {
+		t.Errorf("expected deploy labels to replace recorded ones for web, got %v", got["web"])
+	}
+	if _, ok := got["worker"]; ok {
+		t.Errorf("expected no labels for worker, got %v", got["worker"])
+	}
+
+	if got := componentLabels(nil, components, nil); len(got) != 0 {
+		t.Errorf("expected no labels for a new environment, got %v", got)
+	}
+}
+
+func TestApplyLabels(t *testing.T) {
+	g := graph.NewGraph("staging", "dc")
+	api := graph.NewNode(graph.NodeTypeDeployment, "api", "main")
+	web := graph.NewNode(graph.NodeTypeDeployment, "web", "main")
+	_ = g.AddNode(api)
+	_ = g.AddNode(web)
+
+	applyLabels(g,
+		map[string]string{"team": "platform", "env": "staging"},
+		map[string]map[string]string{"api": {"team": "payments"}},
+	)
+
+	apiLabels, ok := api.Inputs["labels"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected labels input on api, got %v", api.Inputs["labels"])
+	}
+	if apiLabels["team"] != "payments" || apiLabels["env"] != "staging" {
+		t.Errorf("expected component labels merged over environment labels, got %v", apiLabels)
+	}
+	webLabels, _ := web.Inputs["labels"].(map[string]interface{})
+	if webLabels["team"] != "platform" {
+		t.Errorf("expected environment labels on web, got %v", webLabels)
+	}
+
+	unlabeled := graph.NewGraph("staging", "dc")
+	node := graph.NewNode(graph.NodeTypeDeployment, "api", "main")
+	_ = unlabeled.AddNode(node)
+	applyLabels(unlabeled, nil, nil)
+	if _, ok := node.Inputs["labels"]; ok {
+		t.Error("expected no labels input when nothing is labeled")
+	}
+}
@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/state"
+	"github.com/davidthor/cldctl/pkg/state/backend"
+)
+
+// heldLocksKey is the context key for the environments whose lock the
+// operation in progress holds.
+type heldLocksKey struct{}
+
+// lockEnvironment takes the state lock on an environment for an operation.
+// See LockEnvironment.
+func (e *Engine) lockEnvironment(ctx context.Context, dc, envName, operation string, takeOver bool, w io.Writer) (context.Context, func(), error) {
+	return LockEnvironment(ctx, e.stateManager, dc, envName, operation, takeOver, w)
+}
+
+// LockEnvironment takes the state lock on an environment for an operation,
+// so that concurrent deploys, destroys and other changes can't interleave
+// their writes to its state. It returns a context recording that the lock is
+// held, so that operations run with it don't try to take it again, and a
+// function that releases the lock.
+//
+// The lock is renewed in the background until it is released. With takeOver,
+// a lock held by another process is broken first: taking over presumes that
+// process is no longer running.
+func LockEnvironment(ctx context.Context, mgr state.Manager, dc, envName, operation string, takeOver bool, w io.Writer) (context.Context, func(), error) {
+	key := dc + "/" + envName
+	held, _ := ctx.Value(heldLocksKey{}).(map[string]bool)
+	if held[key] {
+		return ctx, func() {}, nil
+	}
+
+	scope := state.LockScope{
+		Datacenter:  dc,
+		Environment: envName,
+		Operation:   operation,
+		Who:         lockHolder(),
+	}
+
+	lock, err := mgr.Lock(ctx, scope)
+	var lockErr *backend.LockError
+	if takeOver && errors.As(err, &lockErr) {
+		broken, unlockErr := mgr.ForceUnlock(ctx, scope)
+		if unlockErr != nil && !errors.Is(unlockErr, backend.ErrNotFound) {
+			return nil, nil, fmt.Errorf("failed to break the lock on environment %q: %w", envName, unlockErr)
+		}
+		if broken != nil && w != nil {
+			fmt.Fprintf(w, "Broke the lock held by %s on environment %q\n", DescribeLock(*broken), envName)
+		}
+		lock, err = mgr.Lock(ctx, scope)
+	}
+	if errors.As(err, &lockErr) {
+		return nil, nil, fmt.Errorf("environment %q is locked by %s\n"+
+			"Wait for it to finish. If that process is no longer running, re-run with --take-over, or run 'cldctl force-unlock %s -d %s'",
+			envName, DescribeLock(lockErr.Info), envName, dc)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to lock environment %q: %w", envName, err)
+	}
+
+	nested := make(map[string]bool, len(held)+1)
+	for k := range held {
+		nested[k] = true
+	}
+	nested[key] = true
+	ctx = context.WithValue(ctx, heldLocksKey{}, nested)
+
+	if lock == nil {
+		return ctx, func() {}, nil
+	}
+	// Locks expire unless renewed, so that one left by a process that died
+	// can be taken over; keep this one alive for as long as it's held.
+	stopRenewing := backend.KeepAlive(lock)
+	return ctx, func() {
+		stopRenewing()
+		_ = lock.Unlock(context.Background())
+	}, nil
+}
+
+// DescribeLock describes who holds a lock, for what, and since when.
+func DescribeLock(info backend.LockInfo) string {
+	desc := info.Who
+	if desc == "" {
+		desc = "another process"
+	}
+	if info.PID != 0 {
+		desc += fmt.Sprintf(" (pid %d on %s)", info.PID, info.Host)
+	}
+	if info.Operation != "" {
+		desc += " for " + info.Operation
+	}
+	if !info.Created.IsZero() {
+		desc += fmt.Sprintf(" since %s", info.Created.Local().Format(time.RFC3339))
+	}
+	return desc
+}
+
+// lockHolder identifies the user taking a lock.
+func lockHolder() string {
+	if id := os.Getenv("CLDCTL_USER"); id != "" {
+		return id
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "unknown"
+}
@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/state"
+	"github.com/davidthor/cldctl/pkg/state/backend"
+	"github.com/davidthor/cldctl/pkg/state/backend/local"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func TestLockEnvironment(t *testing.T) {
+	ctx := context.Background()
+	mgr := newMockStateManager()
+	eng := NewEngine(mgr, nil)
+
+	_, unlock, err := eng.lockEnvironment(ctx, "dc", "staging", "deploy", false, nil)
+	if err != nil {
+		t.Fatalf("expected an unlocked environment to be locked, got %v", err)
+	}
+	unlock()
+
+	held := &backend.LockInfo{Who: "ci-job-456", Operation: "deploy", Host: "runner-1", PID: 4242, Created: time.Now()}
+	mgr.heldLock = held
+	_, _, err = eng.lockEnvironment(ctx, "dc", "staging", "deploy", false, nil)
+	if err == nil {
+		t.Fatal("expected a locked environment to be refused")
+	}
+	for _, want := range []string{"ci-job-456 (pid 4242 on runner-1) for deploy", "cldctl force-unlock staging -d dc"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to contain %q, got %v", want, err)
+		}
+	}
+
+	var out strings.Builder
+	_, unlock, err = eng.lockEnvironment(ctx, "dc", "staging", "deploy", true, &out)
+	if err != nil {
+		t.Fatalf("expected take-over to break the lock, got %v", err)
+	}
+	unlock()
+	if mgr.heldLock != nil {
+		t.Error("expected the held lock to be broken")
+	}
+	if !strings.Contains(out.String(), "Broke the lock held by ci-job-456") {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
+
+// pausingStateManager pauses the first environment read after it's armed,
+// which the engine makes with the environment's lock held.
+type pausingStateManager struct {
+	state.Manager
+	armed   bool
+	paused  chan struct{}
+	release chan struct{}
+}
+
+func (m *pausingStateManager) GetEnvironment(ctx context.Context, dc, name string) (*types.EnvironmentState, error) {
+	if m.armed {
+		m.armed = false
+		close(m.paused)
+		<-m.release
+	}
+	return m.Manager.GetEnvironment(ctx, dc, name)
+}
+
+func TestDeployAndDestroyAreSerialized(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	b, err := local.NewBackend(map[string]string{"path": filepath.Join(dir, "state")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dcFile := filepath.Join(dir, "datacenter.hcl")
+	if err := os.WriteFile(dcFile, []byte(minimalDatacenterHCL), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := &pausingStateManager{Manager: state.NewManager(b), paused: make(chan struct{}), release: make(chan struct{})}
+	if err := mgr.SaveDatacenter(ctx, &types.DatacenterState{Name: "dc", Version: dcFile}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.SaveEnvironment(ctx, "dc", &types.EnvironmentState{Name: "staging", Datacenter: "dc", Components: map[string]*types.ComponentState{}}); err != nil {
+		t.Fatal(err)
+	}
+	eng := NewEngine(mgr, nil)
+
+	// Start a deploy and hold it partway through, with the lock taken.
+	mgr.armed = true
+	deployed := make(chan error, 1)
+	go func() {
+		_, err := eng.Deploy(ctx, DeployOptions{Environment: "staging", Datacenter: "dc", Output: io.Discard, AutoApprove: true})
+		deployed <- err
+	}()
+	<-mgr.paused
+
+	destroy := func() error {
+		_, err := eng.Destroy(ctx, DestroyOptions{Environment: "staging", Datacenter: "dc", Output: io.Discard, AutoApprove: true})
+		return err
+	}
+	if err := destroy(); err == nil || !strings.Contains(err.Error(), `environment "staging" is locked by`) {
+		t.Fatalf("expected destroy to be refused while the deploy runs, got %v", err)
+	}
+	if err := eng.DestroyEnvironment(ctx, "dc", "staging", io.Discard, nil); err == nil || !strings.Contains(err.Error(), "is locked by") {
+		t.Fatalf("expected environment destroy to be refused while the deploy runs, got %v", err)
+	}
+
+	close(mgr.release)
+	if err := <-deployed; err != nil && strings.Contains(err.Error(), "is locked by") {
+		t.Fatalf("expected the deploy to keep its lock, got %v", err)
+	}
+
+	// With the deploy done, its lock is released.
+	if err := destroy(); err != nil && strings.Contains(err.Error(), "is locked by") {
+		t.Fatalf("expected destroy to run once the deploy finished, got %v", err)
+	}
+}
@@ -0,0 +1,364 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/engine/executor"
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// PlanFileVersion is the version of the plan file format written by
+// WritePlanFile. Files of other versions are refused.
+const PlanFileVersion = 2
+
+// PlanFile is a saved environment plan. It records the inputs the plan was
+// made from, digests of the state and the sources it was made against, and
+// the planned changes, so that ApplyPlanFile can execute exactly the plan
+// that was reviewed.
+//
+// Plan files hold the components' variable values, which may be secret.
+type PlanFile struct {
+	Version     int    `json:"version"`
+	Environment string `json:"environment"`
+	Datacenter  string `json:"datacenter"`
+
+	// StateDigest is the digest of the datacenter and environment state the
+	// plan was made against (see stateDigest)
+	StateDigest string `json:"state_digest"`
+
+	// DetectDrift records whether the plan checked resources for drift
+	DetectDrift bool `json:"detect_drift,omitempty"`
+
+	// Components maps component names to the sources they're deployed from
+	Components map[string]string `json:"components"`
+
+	// Variables maps component names to their variable values
+	Variables map[string]map[string]interface{} `json:"variables,omitempty"`
+
+	// Sources maps the directories the components and the datacenter are
+	// loaded from to digests of their contents (see sourceDigests)
+	Sources map[string]string `json:"sources"`
+
+	// Inputs maps the IDs of the planned resources to digests of their
+	// inputs (see plannedInputs), which their actions alone don't capture
+	Inputs map[string]string `json:"inputs"`
+
+	// Plan is the plan in its JSON form (see planner.Plan.MarshalJSON)
+	Plan json.RawMessage `json:"plan"`
+}
+
+// plannedAction is the part of a change in a plan's JSON form that has to
+// match when a saved plan is applied.
+type plannedAction struct {
+	ID            string         `json:"id"`
+	Action        planner.Action `json:"action"`
+	SkippedAction planner.Action `json:"skipped_action,omitempty"`
+}
+
+// WritePlanFile writes a saved plan to path.
+func WritePlanFile(path string, pf *PlanFile) error {
+	data, err := json.MarshalIndent(pf, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal plan: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write plan file: %w", err)
+	}
+	return nil
+}
+
+// ReadPlanFile reads a plan written by WritePlanFile.
+func ReadPlanFile(path string) (*PlanFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan file: %w", err)
+	}
+	var pf PlanFile
+	if err := json.Unmarshal(data, &pf); err != nil {
+		return nil, fmt.Errorf("failed to parse plan file %s: %w", path, err)
+	}
+	if pf.Version != PlanFileVersion {
+		return nil, fmt.Errorf("plan file %s has version %d, but this version of cldctl reads version %d; plan again", path, pf.Version, PlanFileVersion)
+	}
+	if pf.Environment == "" || pf.Datacenter == "" || len(pf.Plan) == 0 {
+		return nil, fmt.Errorf("plan file %s is incomplete", path)
+	}
+	return &pf, nil
+}
+
+// SavePlanEnvironment plans an environment like PlanEnvironment and returns
+// the plan along with a PlanFile that ApplyPlanFile can execute later.
+func (e *Engine) SavePlanEnvironment(ctx context.Context, opts PlanEnvironmentOptions) (*planner.Plan, *PlanFile, error) {
+	digest, err := e.stateDigest(ctx, opts.Datacenter, opts.Environment)
+	if err != nil {
+		return nil, nil, err
+	}
+	envState, err := e.stateManager.GetEnvironment(ctx, opts.Datacenter, opts.Environment)
+	if err != nil {
+		return nil, nil, fmt.Errorf("environment %s not found in datacenter %s", opts.Environment, opts.Datacenter)
+	}
+	components, variables, err := recordedComponents(envState)
+	if err != nil {
+		return nil, nil, err
+	}
+	sources, err := e.sourceDigests(ctx, opts.Datacenter, components)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plan, err := e.PlanEnvironment(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	planJSON, err := json.Marshal(plan)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal plan: %w", err)
+	}
+	inputs, err := plannedInputs(plan)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return plan, &PlanFile{
+		Version:     PlanFileVersion,
+		Environment: opts.Environment,
+		Datacenter:  opts.Datacenter,
+		StateDigest: digest,
+		DetectDrift: opts.DetectDrift,
+		Components:  components,
+		Variables:   variables,
+		Sources:     sources,
+		Inputs:      inputs,
+		Plan:        planJSON,
+	}, nil
+}
+
+// ApplyPlanFileOptions configures ApplyPlanFile.
+type ApplyPlanFileOptions struct {
+	// PlanFile is the saved plan to execute
+	PlanFile *PlanFile
+
+	// Output writer for progress
+	Output io.Writer
+
+	// Parallelism for parallel execution
+	Parallelism int
+
+	// OnProgress is called when resource status changes
+	OnProgress executor.ProgressCallback
+
+	// OnPlan is called with the plan before it's executed
+	OnPlan func(plan *planner.Plan)
+
+	// LogDir, when set, is a directory that each resource's plugin output is
+	// streamed to. See executor.Options.LogDir.
+	LogDir string
+}
+
+// ApplyPlanFile executes a saved plan. The plan is refused if the state or
+// the sources of the components or the datacenter have changed since it was
+// made, and nothing is executed unless re-planning from the saved inputs
+// yields exactly the saved changes with the same inputs, so that what runs is
+// what was reviewed.
+func (e *Engine) ApplyPlanFile(ctx context.Context, opts ApplyPlanFileOptions) (*DeployResult, error) {
+	pf := opts.PlanFile
+
+	// Check the state with the environment locked, so that nothing can
+	// change it before the deploy, which runs with the same lock
+	ctx, unlock, err := e.lockEnvironment(ctx, pf.Datacenter, pf.Environment, "deploy", false, opts.Output)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	digest, err := e.stateDigest(ctx, pf.Datacenter, pf.Environment)
+	if err != nil {
+		return nil, err
+	}
+	if digest != pf.StateDigest {
+		return nil, fmt.Errorf("the state of environment %s has changed since the plan was made; plan again", pf.Environment)
+	}
+
+	var saved struct {
+		Changes []plannedAction `json:"changes"`
+	}
+	if err := json.Unmarshal(pf.Plan, &saved); err != nil {
+		return nil, fmt.Errorf("failed to parse saved plan: %w", err)
+	}
+
+	return e.Deploy(ctx, DeployOptions{
+		Environment: pf.Environment,
+		Datacenter:  pf.Datacenter,
+		Components:  pf.Components,
+		Variables:   pf.Variables,
+		Output:      opts.Output,
+		DetectDrift: pf.DetectDrift,
+		AutoApprove: true,
+		Parallelism: opts.Parallelism,
+		OnProgress:  opts.OnProgress,
+		OnPlan:      opts.OnPlan,
+		LogDir:      opts.LogDir,
+		VerifyPlan: func(plan *planner.Plan) error {
+			if err := comparePlannedActions(saved.Changes, pf.Inputs, plan); err != nil {
+				return err
+			}
+			// The components have been loaded by now: check they were
+			// loaded from the sources that were planned
+			return e.checkSourceDigests(ctx, pf)
+		},
+	})
+}
+
+// comparePlannedActions returns an error describing how a plan's changes,
+// or the inputs of the resources they change, differ from the saved ones.
+func comparePlannedActions(saved []plannedAction, savedInputs map[string]string, plan *planner.Plan) error {
+	want := make(map[string]plannedAction, len(saved))
+	for _, c := range saved {
+		want[c.ID] = c
+	}
+	inputs, err := plannedInputs(plan)
+	if err != nil {
+		return err
+	}
+
+	var diffs []string
+	seen := make(map[string]bool, len(plan.Changes))
+	for _, c := range plan.Changes {
+		if c.Node == nil {
+			continue
+		}
+		id := c.Node.ID
+		seen[id] = true
+		w, ok := want[id]
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("%s is now planned to %s", id, c.Action))
+		case w.Action != c.Action || w.SkippedAction != c.SkippedAction:
+			diffs = append(diffs, fmt.Sprintf("%s was planned to %s, now %s", id, describeAction(w.Action, w.SkippedAction), describeAction(c.Action, c.SkippedAction)))
+		case inputs[id] != savedInputs[id]:
+			diffs = append(diffs, fmt.Sprintf("%s has different inputs than when it was planned", id))
+		}
+	}
+	for _, c := range saved {
+		if !seen[c.ID] {
+			diffs = append(diffs, fmt.Sprintf("%s is no longer in the plan", c.ID))
+		}
+	}
+	if len(diffs) == 0 {
+		return nil
+	}
+	sort.Strings(diffs)
+	return fmt.Errorf("the plan no longer matches the saved plan; plan again:\n  %s", strings.Join(diffs, "\n  "))
+}
+
+func describeAction(action, skipped planner.Action) string {
+	if skipped != "" {
+		return fmt.Sprintf("%s (skipped)", skipped)
+	}
+	return string(action)
+}
+
+// plannedInputs hashes the inputs of the resources a plan changes, keyed by
+// node ID.
+func plannedInputs(plan *planner.Plan) (map[string]string, error) {
+	inputs := make(map[string]string, len(plan.Changes))
+	for _, c := range plan.Changes {
+		if c.Node == nil {
+			continue
+		}
+		data, err := json.Marshal(c.Node.Inputs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash the inputs of %s: %w", c.Node.ID, err)
+		}
+		sum := sha256.Sum256(data)
+		inputs[c.Node.ID] = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	return inputs, nil
+}
+
+// sourceDigests hashes the contents of the directories a datacenter's
+// configuration and the components' sources are loaded from, keyed by
+// directory. Refreshing a component or re-pulling the datacenter replaces
+// these contents without changing the recorded sources.
+func (e *Engine) sourceDigests(ctx context.Context, dcName string, components map[string]string) (map[string]string, error) {
+	dcState, err := e.stateManager.GetDatacenter(ctx, dcName)
+	if err != nil {
+		return nil, fmt.Errorf("datacenter %q not found: %w", dcName, err)
+	}
+	dc, err := e.loadDatacenterConfig(dcState.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load datacenter configuration: %w", err)
+	}
+
+	dirs := []string{filepath.Dir(dc.SourcePath())}
+	for _, source := range components {
+		dirs = append(dirs, filepath.Dir(source))
+	}
+	digests := make(dirDigests)
+	sources := make(map[string]string, len(dirs))
+	for _, dir := range dirs {
+		sum, err := digests.digest(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", dir, err)
+		}
+		sources[dir] = "sha256:" + sum
+	}
+	return sources, nil
+}
+
+// checkSourceDigests returns an error naming the sources of a saved plan
+// whose contents have changed since it was made.
+func (e *Engine) checkSourceDigests(ctx context.Context, pf *PlanFile) error {
+	sources, err := e.sourceDigests(ctx, pf.Datacenter, pf.Components)
+	if err != nil {
+		return err
+	}
+	var changed []string
+	for dir, sum := range pf.Sources {
+		if sources[dir] != sum {
+			changed = append(changed, dir)
+		}
+	}
+	for dir := range sources {
+		if _, ok := pf.Sources[dir]; !ok {
+			changed = append(changed, dir)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	sort.Strings(changed)
+	return fmt.Errorf("sources have changed since the plan was made; plan again:\n  %s", strings.Join(changed, "\n  "))
+}
+
+// stateDigest hashes the recorded state of a datacenter and one of its
+// environments. Any deploy, refresh, or state edit changes it.
+func (e *Engine) stateDigest(ctx context.Context, dcName, envName string) (string, error) {
+	dcState, err := e.stateManager.GetDatacenter(ctx, dcName)
+	if err != nil {
+		return "", fmt.Errorf("datacenter %q not found: %w", dcName, err)
+	}
+	envState, err := e.stateManager.GetEnvironment(ctx, dcName, envName)
+	if err != nil {
+		return "", fmt.Errorf("environment %s not found in datacenter %s", envName, dcName)
+	}
+	data, err := json.Marshal(struct {
+		Datacenter  *types.DatacenterState  `json:"datacenter"`
+		Environment *types.EnvironmentState `json:"environment"`
+	}{dcState, envState})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash state: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+)
+
+// savedPlanTestEngine returns a rotate test engine along with a saved plan
+// of its environment that has been written and read back.
+func savedPlanTestEngine(t *testing.T) (*Engine, *mockStateManager, *rotator, *PlanFile) {
+	t.Helper()
+	eng, mgr, plugin := newRotateTestEngine(t)
+
+	plan, pf, err := eng.SavePlanEnvironment(context.Background(), PlanEnvironmentOptions{Environment: "staging", Datacenter: "dc"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plan.IsEmpty() {
+		t.Fatal("expected a plan with changes")
+	}
+	if pf.Components["app"] == "" || !strings.HasPrefix(pf.StateDigest, "sha256:") || len(pf.Sources) == 0 || len(pf.Inputs) == 0 {
+		t.Fatalf("expected the plan's inputs and state digest to be recorded, got %+v", pf)
+	}
+
+	path := filepath.Join(t.TempDir(), "plan.cldplan")
+	if err := WritePlanFile(path, pf); err != nil {
+		t.Fatal(err)
+	}
+	pf, err = ReadPlanFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error reading the plan back: %v", err)
+	}
+	return eng, mgr, plugin, pf
+}
+
+func TestApplyPlanFile(t *testing.T) {
+	ctx := context.Background()
+	eng, _, plugin, pf := savedPlanTestEngine(t)
+	eng.iacRegistry.Register("native", func() (iac.Plugin, error) { return &hookRecorder{}, nil })
+
+	result, err := eng.ApplyPlanFile(ctx, ApplyPlanFileOptions{PlanFile: pf, Parallelism: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("expected the plan to apply, got %+v", result.Execution.Errors)
+	}
+	if len(plugin.events) == 0 {
+		t.Error("expected the plan to be executed")
+	}
+}
+
+func TestApplyPlanFile_StateChanged(t *testing.T) {
+	ctx := context.Background()
+	eng, mgr, plugin, pf := savedPlanTestEngine(t)
+
+	envState, _ := mgr.GetEnvironment(ctx, "dc", "staging")
+	envState.Components["app"].Variables = map[string]string{"changed": "true"}
+	_ = mgr.SaveEnvironment(ctx, "dc", envState)
+
+	_, err := eng.ApplyPlanFile(ctx, ApplyPlanFileOptions{PlanFile: pf})
+	if err == nil || !strings.Contains(err.Error(), "has changed since the plan was made") {
+		t.Fatalf("expected the changed state to be refused, got %v", err)
+	}
+	if len(plugin.events) != 0 {
+		t.Errorf("expected nothing to be executed, got %v", plugin.events)
+	}
+}
+
+func TestApplyPlanFile_PlanChanged(t *testing.T) {
+	eng, _, plugin, pf := savedPlanTestEngine(t)
+
+	var saved map[string]interface{}
+	if err := json.Unmarshal(pf.Plan, &saved); err != nil {
+		t.Fatal(err)
+	}
+	change := saved["changes"].([]interface{})[0].(map[string]interface{})
+	change["action"] = "delete"
+	pf.Plan, _ = json.Marshal(saved)
+
+	_, err := eng.ApplyPlanFile(context.Background(), ApplyPlanFileOptions{PlanFile: pf})
+	if err == nil || !strings.Contains(err.Error(), "no longer matches") || !strings.Contains(err.Error(), "was planned to delete") {
+		t.Fatalf("expected the plan mismatch to be refused, got %v", err)
+	}
+	if len(plugin.events) != 0 {
+		t.Errorf("expected nothing to be executed, got %v", plugin.events)
+	}
+}
+
+func TestApplyPlanFile_SourceChanged(t *testing.T) {
+	eng, _, plugin, pf := savedPlanTestEngine(t)
+
+	// A refresh replaces a component's files in place
+	f, err := os.OpenFile(pf.Components["app"], os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("\n# refreshed\n")
+	f.Close()
+
+	_, err = eng.ApplyPlanFile(context.Background(), ApplyPlanFileOptions{PlanFile: pf})
+	if err == nil || !strings.Contains(err.Error(), "sources have changed") || !strings.Contains(err.Error(), filepath.Dir(pf.Components["app"])) {
+		t.Fatalf("expected the changed source to be refused, got %v", err)
+	}
+	if len(plugin.events) != 0 {
+		t.Errorf("expected nothing to be executed, got %v", plugin.events)
+	}
+}
+
+func TestApplyPlanFile_InputsChanged(t *testing.T) {
+	eng, _, plugin, pf := savedPlanTestEngine(t)
+
+	pf.Inputs["app/deployment/api"] = "sha256:0000"
+
+	_, err := eng.ApplyPlanFile(context.Background(), ApplyPlanFileOptions{PlanFile: pf})
+	if err == nil || !strings.Contains(err.Error(), "app/deployment/api has different inputs") {
+		t.Fatalf("expected the changed inputs to be refused, got %v", err)
+	}
+	if len(plugin.events) != 0 {
+		t.Errorf("expected nothing to be executed, got %v", plugin.events)
+	}
+}
+
+func TestReadPlanFile_Version(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.cldplan")
+	if err := WritePlanFile(path, &PlanFile{Version: PlanFileVersion + 1, Environment: "staging", Datacenter: "dc", Plan: []byte("{}")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadPlanFile(path); err == nil || !strings.Contains(err.Error(), "version") {
+		t.Errorf("expected plans of another version to be refused, got %v", err)
+	}
+}
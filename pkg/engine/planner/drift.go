@@ -0,0 +1,64 @@
+package planner
+
+import (
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// DriftDetector reads back the infrastructure of a deployed resource and
+// reports how it differs from the resource's recorded state. It returns nil
+// when nothing drifted, or when the resource's infrastructure can't be read
+// back.
+type DriftDetector func(node *graph.Node, current *types.ResourceState) (*Drift, error)
+
+// Drift describes how a resource's infrastructure differs from its recorded
+// state.
+type Drift struct {
+	// Outputs are the resource's outputs whose values, as read back, differ
+	// from those recorded
+	Outputs []PropertyChange
+
+	// Modules are the hook modules whose resources drifted
+	Modules []ModuleDrift
+}
+
+// ModuleDrift lists the resources of one hook module that drifted.
+type ModuleDrift struct {
+	Module    string
+	Plugin    string
+	Resources []iac.ResourceDrift
+}
+
+// IsEmpty reports whether nothing drifted.
+func (d *Drift) IsEmpty() bool {
+	return d == nil || (len(d.Outputs) == 0 && len(d.Modules) == 0)
+}
+
+// detectDrift checks a planned change's resource for drift. Resources that
+// drifted are re-applied, since applying them restores what their state
+// records; a failed check is reported as a warning.
+func (p *Planner) detectDrift(plan *Plan, change *ResourceChange) {
+	if change.CurrentState == nil || (change.Action != ActionNoop && change.Action != ActionUpdate) {
+		return
+	}
+
+	drift, err := p.options.DetectDrift(change.Node, change.CurrentState)
+	if err != nil {
+		plan.Warnings = append(plan.Warnings, Warning{
+			Resource: change.Node.ID,
+			Message:  "could not check for drift: " + err.Error(),
+		})
+		return
+	}
+	if drift.IsEmpty() {
+		return
+	}
+
+	change.Drift = drift
+	plan.Drifted++
+	if change.Action == ActionNoop {
+		change.Action = ActionUpdate
+		change.Reason = "infrastructure drifted from state"
+	}
+}
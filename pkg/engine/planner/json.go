@@ -0,0 +1,192 @@
+package planner
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+)
+
+// planJSON is the JSON form of a Plan. Fields are emitted in a fixed order,
+// lists are sorted, and maps are encoded with sorted keys, so the same plan
+// always produces the same bytes and plan files can be diffed in CI.
+type planJSON struct {
+	Environment string       `json:"environment"`
+	Datacenter  string       `json:"datacenter"`
+	Summary     summaryJSON  `json:"summary"`
+	Changes     []changeJSON `json:"changes"`
+	Warnings    []Warning    `json:"warnings,omitempty"`
+}
+
+type summaryJSON struct {
+	Create    int `json:"create"`
+	Update    int `json:"update"`
+	Delete    int `json:"delete"`
+	Unchanged int `json:"unchanged"`
+	Skipped   int `json:"skipped"`
+	Drifted   int `json:"drifted,omitempty"`
+}
+
+type changeJSON struct {
+	ID              string               `json:"id"`
+	Component       string               `json:"component"`
+	Type            string               `json:"type"`
+	Name            string               `json:"name"`
+	Instance        string               `json:"instance,omitempty"`
+	Action          Action               `json:"action"`
+	Reason          string               `json:"reason,omitempty"`
+	SkippedAction   Action               `json:"skipped_action,omitempty"`
+	SkipReason      string               `json:"skip_reason,omitempty"`
+	DependsOn       []string             `json:"depends_on,omitempty"`
+	PropertyChanges []propertyChangeJSON `json:"property_changes,omitempty"`
+	Preview         []modulePreviewJSON  `json:"preview,omitempty"`
+	Drift           *driftJSON           `json:"drift,omitempty"`
+}
+
+type propertyChangeJSON struct {
+	Path     string      `json:"path"`
+	OldValue interface{} `json:"old_value"`
+	NewValue interface{} `json:"new_value"`
+}
+
+type modulePreviewJSON struct {
+	Module  string                  `json:"module"`
+	Plugin  string                  `json:"plugin"`
+	Error   string                  `json:"error,omitempty"`
+	Changes []previewResourceChange `json:"changes,omitempty"`
+}
+
+type previewResourceChange struct {
+	ResourceID   string               `json:"resource_id"`
+	ResourceType string               `json:"resource_type"`
+	Action       iac.ChangeAction     `json:"action"`
+	Diff         []propertyChangeJSON `json:"diff,omitempty"`
+}
+
+// driftJSON is the JSON form of a Drift. Drifted values are left out, since
+// resource attributes and outputs may be secret.
+type driftJSON struct {
+	Outputs []string          `json:"outputs,omitempty"`
+	Modules []moduleDriftJSON `json:"modules,omitempty"`
+}
+
+type moduleDriftJSON struct {
+	Module    string              `json:"module"`
+	Plugin    string              `json:"plugin"`
+	Resources []driftResourceJSON `json:"resources"`
+}
+
+type driftResourceJSON struct {
+	ResourceID   string   `json:"resource_id"`
+	ResourceType string   `json:"resource_type"`
+	Paths        []string `json:"paths"`
+}
+
+// MarshalJSON encodes the plan in a stable, documented format. Changes keep
+// their execution order, which the planner makes deterministic.
+func (p *Plan) MarshalJSON() ([]byte, error) {
+	out := planJSON{
+		Environment: p.Environment,
+		Datacenter:  p.Datacenter,
+		Summary: summaryJSON{
+			Create:    p.ToCreate,
+			Update:    p.ToUpdate,
+			Delete:    p.ToDelete,
+			Unchanged: p.NoChange,
+			Skipped:   p.Skipped,
+			Drifted:   p.Drifted,
+		},
+		Changes:  make([]changeJSON, 0, len(p.Changes)),
+		Warnings: p.Warnings,
+	}
+
+	for _, change := range p.Changes {
+		c := changeJSON{
+			Action:        change.Action,
+			Reason:        change.Reason,
+			SkippedAction: change.SkippedAction,
+			SkipReason:    change.SkipReason,
+		}
+		if node := change.Node; node != nil {
+			c.ID = node.ID
+			c.Component = node.Component
+			c.Type = string(node.Type)
+			c.Name = node.Name
+			if node.Instance != nil {
+				c.Instance = node.Instance.Name
+			}
+			if len(node.DependsOn) > 0 {
+				c.DependsOn = append([]string(nil), node.DependsOn...)
+				sort.Strings(c.DependsOn)
+			}
+		}
+		// Deletions of resources no longer in the graph carry their type
+		// only in state.
+		if c.Type == "" && change.CurrentState != nil {
+			c.Type = change.CurrentState.Type
+		}
+		for _, pc := range change.PropertyChanges {
+			c.PropertyChanges = append(c.PropertyChanges, propertyChangeJSON{
+				Path:     pc.Path,
+				OldValue: pc.OldValue,
+				NewValue: pc.NewValue,
+			})
+		}
+		sort.SliceStable(c.PropertyChanges, func(i, j int) bool {
+			return c.PropertyChanges[i].Path < c.PropertyChanges[j].Path
+		})
+		for _, mp := range change.Preview {
+			c.Preview = append(c.Preview, previewJSON(mp))
+		}
+		if !change.Drift.IsEmpty() {
+			c.Drift = driftToJSON(change.Drift)
+		}
+		out.Changes = append(out.Changes, c)
+	}
+
+	return json.Marshal(out)
+}
+
+func previewJSON(mp ModulePreview) modulePreviewJSON {
+	out := modulePreviewJSON{
+		Module: mp.Module,
+		Plugin: mp.Plugin,
+		Error:  mp.Error,
+	}
+	for _, rc := range mp.Changes {
+		prc := previewResourceChange{
+			ResourceID:   rc.ResourceID,
+			ResourceType: rc.ResourceType,
+			Action:       rc.Action,
+		}
+		for _, d := range rc.Diff {
+			oldVal, newVal := d.OldValue, d.NewValue
+			if d.Sensitive {
+				oldVal, newVal = "(sensitive)", "(sensitive)"
+			}
+			prc.Diff = append(prc.Diff, propertyChangeJSON{Path: d.Path, OldValue: oldVal, NewValue: newVal})
+		}
+		out.Changes = append(out.Changes, prc)
+	}
+	return out
+}
+
+func driftToJSON(d *Drift) *driftJSON {
+	out := &driftJSON{}
+	for _, pc := range d.Outputs {
+		out.Outputs = append(out.Outputs, pc.Path)
+	}
+	sort.Strings(out.Outputs)
+	for _, md := range d.Modules {
+		mdj := moduleDriftJSON{Module: md.Module, Plugin: md.Plugin}
+		for _, rd := range md.Resources {
+			rj := driftResourceJSON{ResourceID: rd.ResourceID, ResourceType: rd.ResourceType, Paths: []string{}}
+			for _, diff := range rd.Diffs {
+				rj.Paths = append(rj.Paths, diff.Path)
+			}
+			mdj.Resources = append(mdj.Resources, rj)
+		}
+		out.Modules = append(out.Modules, mdj)
+	}
+	return out
+}
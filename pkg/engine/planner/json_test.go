@@ -0,0 +1,115 @@
+package planner
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// goldenPlan builds a plan that exercises creates, updates, no-ops and
+// deletions of resources across several components.
+func goldenPlan(t *testing.T) *Plan {
+	t.Helper()
+
+	g := graph.NewGraph("staging", "local")
+
+	db := graph.NewNode(graph.NodeTypeDatabase, "api", "main")
+	db.SetInput("type", "postgres")
+	_ = g.AddNode(db)
+
+	api := graph.NewNode(graph.NodeTypeDeployment, "api", "api")
+	api.SetInput("image", "api:v2")
+	api.SetInput("replicas", 2)
+	api.SetInput("command", []string{"serve", "--port", "8080"})
+	_ = g.AddNode(api)
+	_ = g.AddEdge(api.ID, db.ID)
+
+	web := graph.NewNode(graph.NodeTypeDeployment, "web", "web")
+	web.SetInput("image", "web:v1")
+	_ = g.AddNode(web)
+	_ = g.AddEdge(web.ID, api.ID)
+
+	route := graph.NewNode(graph.NodeTypeRoute, "web", "main")
+	route.SetInput("subdomain", "app")
+	_ = g.AddNode(route)
+	_ = g.AddEdge(route.ID, web.ID)
+
+	current := &types.EnvironmentState{
+		Name: "staging",
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Resources: map[string]*types.ResourceState{
+					"database/main": {
+						Component: "api", Type: "database", Name: "main",
+						Inputs: map[string]interface{}{"type": "postgres"},
+					},
+					"deployment/api": {
+						Component: "api", Type: "deployment", Name: "api",
+						Inputs: map[string]interface{}{"image": "api:v1", "debug": true},
+					},
+					"bucket/uploads": {Component: "api", Type: "bucket", Name: "uploads"},
+					"bucket/assets":  {Component: "api", Type: "bucket", Name: "assets"},
+				},
+			},
+			"worker": {
+				Resources: map[string]*types.ResourceState{
+					"deployment/worker": {Component: "worker", Type: "deployment", Name: "worker"},
+					"cronjob/cleanup":   {Component: "worker", Type: "cronjob", Name: "cleanup"},
+				},
+			},
+		},
+	}
+
+	plan, err := NewPlanner().Plan(g, current)
+	if err != nil {
+		t.Fatalf("Plan() error: %v", err)
+	}
+	return plan
+}
+
+func TestPlan_JSONGolden(t *testing.T) {
+	got, err := json.MarshalIndent(goldenPlan(t), "", "  ")
+	if err != nil {
+		t.Fatalf("marshal plan: %v", err)
+	}
+	got = append(got, '\n')
+
+	golden := filepath.Join("testdata", "plan.golden.json")
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatalf("write golden file: %v", err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("plan JSON does not match %s (run with -update to accept changes)\n got:\n%s", golden, got)
+	}
+}
+
+func TestPlan_JSONStableAcrossRuns(t *testing.T) {
+	first, err := json.Marshal(goldenPlan(t))
+	if err != nil {
+		t.Fatalf("marshal plan: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		next, err := json.Marshal(goldenPlan(t))
+		if err != nil {
+			t.Fatalf("marshal plan: %v", err)
+		}
+		if !bytes.Equal(first, next) {
+			t.Fatalf("plan JSON changed between runs:\n%s\n%s", first, next)
+		}
+	}
+}
@@ -3,8 +3,11 @@ package planner
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/iac"
 	"github.com/davidthor/cldctl/pkg/state/types"
 )
 
@@ -35,6 +38,52 @@ type ResourceChange struct {
 
 	// Property changes (for updates)
 	PropertyChanges []PropertyChange
+
+	// SkipReason records why the change was deselected during plan review.
+	// Skipped changes are executed as Noop.
+	SkipReason string
+
+	// SkippedAction is the action the change had before it was skipped.
+	SkippedAction Action
+
+	// Preview holds the IaC-level changes reported by each hook module's
+	// plugin during a dry run. Empty outside of dry runs.
+	Preview []ModulePreview
+
+	// Drift is how the resource's infrastructure differs from its state,
+	// when planned with PlanOptions.DetectDrift
+	Drift *Drift
+}
+
+// ModulePreview is the preview reported by one hook module.
+type ModulePreview struct {
+	// Module is the hook module name
+	Module string
+
+	// Plugin is the IaC plugin that produced the preview
+	Plugin string
+
+	// Changes are the resources the module would create, update, or delete
+	Changes []iac.ResourceChange
+
+	// Error is set when the module could not be previewed
+	Error string
+}
+
+// Skippable reports whether the change may be deselected during review.
+// Only destructive changes can be skipped; creates and updates are required
+// to converge the environment on its configuration.
+func (c *ResourceChange) Skippable() bool {
+	switch c.Action {
+	case ActionDelete, ActionReplace:
+		return true
+	}
+	return false
+}
+
+// Skipped reports whether the change was deselected during review.
+func (c *ResourceChange) Skipped() bool {
+	return c.SkippedAction != ""
 }
 
 // PropertyChange describes a change to a property.
@@ -44,6 +93,22 @@ type PropertyChange struct {
 	NewValue interface{}
 }
 
+// Warning reports a condition that didn't stop a command but degraded what
+// it did, such as an expression that couldn't be resolved.
+type Warning struct {
+	// Resource is the ID of the graph node the warning is about, if any
+	Resource string `json:"resource,omitempty"`
+
+	Message string `json:"message"`
+}
+
+func (w Warning) String() string {
+	if w.Resource == "" {
+		return w.Message
+	}
+	return w.Resource + ": " + w.Message
+}
+
 // Plan represents an execution plan.
 type Plan struct {
 	// Environment being modified
@@ -60,6 +125,17 @@ type Plan struct {
 	ToUpdate int
 	ToDelete int
 	NoChange int
+
+	// Skipped counts changes deselected during review. They are also
+	// counted in NoChange.
+	Skipped int
+
+	// Drifted counts resources whose infrastructure drifted from their
+	// state. They are also counted in ToUpdate.
+	Drifted int
+
+	// Warnings found while planning
+	Warnings []Warning
 }
 
 // IsEmpty returns true if there are no changes.
@@ -67,11 +143,74 @@ func (p *Plan) IsEmpty() bool {
 	return p.ToCreate == 0 && p.ToUpdate == 0 && p.ToDelete == 0
 }
 
+// Skip deselects a delete or replace so it is executed as a Noop, recording
+// the reason on the change.
+func (p *Plan) Skip(change *ResourceChange, reason string) error {
+	if change.Skipped() {
+		change.SkipReason = reason
+		return nil
+	}
+	if !change.Skippable() {
+		return fmt.Errorf("cannot skip %s of %s: only deletes and replacements can be skipped", change.Action, change.Node.ID)
+	}
+
+	p.count(change.Action, -1)
+	change.SkippedAction = change.Action
+	change.SkipReason = reason
+	change.Action = ActionNoop
+	p.count(ActionNoop, 1)
+	p.Skipped++
+	return nil
+}
+
+// Unskip restores a change previously deselected with Skip.
+func (p *Plan) Unskip(change *ResourceChange) {
+	if !change.Skipped() {
+		return
+	}
+
+	p.count(ActionNoop, -1)
+	change.Action = change.SkippedAction
+	change.SkippedAction = ""
+	change.SkipReason = ""
+	p.count(change.Action, 1)
+	p.Skipped--
+}
+
+func (p *Plan) count(action Action, delta int) {
+	switch action {
+	case ActionCreate:
+		p.ToCreate += delta
+	case ActionUpdate, ActionReplace:
+		p.ToUpdate += delta
+	case ActionDelete:
+		p.ToDelete += delta
+	case ActionNoop:
+		p.NoChange += delta
+	}
+}
+
 // PlanOptions configures planning behavior.
 type PlanOptions struct {
 	// ForceUpdate converts Noop actions to Update, used when datacenter config
 	// changes and all resources need re-evaluation against new hooks.
 	ForceUpdate bool
+
+	// DetectDrift, when set, reads back the infrastructure of deployed
+	// resources. Resources that drifted from their state are planned to be
+	// updated, with the drift recorded on their change.
+	DetectDrift DriftDetector
+
+	// Targets, when set, restricts the plan to the graph nodes with these
+	// IDs and the nodes they transitively depend on. Resources outside that
+	// set are left as they are: neither updated nor deleted.
+	Targets []string
+
+	// ReplaceOn, when set, returns the inputs of a node whose changes can't
+	// be applied in place, as declared by the hook that provisions it ("*"
+	// for every input). A change to one of them plans the resource to be
+	// replaced instead of updated.
+	ReplaceOn func(node *graph.Node) []string
 }
 
 // Planner generates execution plans.
@@ -102,13 +241,26 @@ func (p *Planner) Plan(g *graph.Graph, currentState *types.EnvironmentState) (*P
 		return nil, err
 	}
 
+	var targeted map[string]bool
+	if len(p.options.Targets) > 0 {
+		targeted, err = TargetedNodes(g, p.options.Targets)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Track which resources exist in current state
 	existingResources := make(map[string]*types.ResourceState)
+	// Paused resources are keyed by the ID of their graph node
+	paused := make(map[string]*types.ResourceState)
 	if currentState != nil {
 		for compName, compState := range currentState.Components {
 			for resName, resState := range compState.Resources {
 				key := compName + "/" + resName
 				existingResources[key] = resState
+				if resState.Paused {
+					paused[compName+"/"+resState.Type+"/"+resState.Name] = resState
+				}
 			}
 		}
 	}
@@ -116,7 +268,27 @@ func (p *Planner) Plan(g *graph.Graph, currentState *types.EnvironmentState) (*P
 	// Plan changes for each node
 	processedIDs := make(map[string]bool)
 	for _, node := range sortedNodes {
+		if targeted != nil && !targeted[node.ID] {
+			continue
+		}
 		change := p.planNodeChange(node, existingResources)
+		if resState := paused[node.ID]; resState != nil {
+			// Paused resources are being managed by hand
+			if changes := p.CompareInputs(node.Inputs, resState.Inputs); len(changes) > 0 {
+				plan.Warnings = append(plan.Warnings, Warning{
+					Resource: node.ID,
+					Message:  fmt.Sprintf("resource is paused; %d configuration change(s) won't be applied until it's unpaused", len(changes)),
+				})
+			}
+			change = &ResourceChange{
+				Node:         node,
+				Action:       ActionNoop,
+				CurrentState: resState,
+				Reason:       "resource is paused",
+			}
+		} else if p.options.DetectDrift != nil {
+			p.detectDrift(plan, change)
+		}
 		plan.Changes = append(plan.Changes, change)
 		processedIDs[node.ID] = true
 
@@ -132,27 +304,86 @@ func (p *Planner) Plan(g *graph.Graph, currentState *types.EnvironmentState) (*P
 		}
 	}
 
-	// Plan deletions for resources that exist but aren't in the graph
-	for key, resState := range existingResources {
-		if !processedIDs[key] {
-			change := &ResourceChange{
-				Node: &graph.Node{
-					ID:        key,
-					Component: resState.Component,
-					Name:      resState.Name,
-				},
-				Action:       ActionDelete,
-				CurrentState: resState,
-				Reason:       "resource no longer defined",
-			}
+	// Plan deletions for resources that exist but aren't in the graph, in a
+	// stable order so plan output doesn't vary between runs. A targeted plan
+	// deletes nothing.
+	var removed []string
+	for key := range existingResources {
+		if targeted == nil && !processedIDs[key] {
+			removed = append(removed, key)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool {
+		a, b := existingResources[removed[i]], existingResources[removed[j]]
+		if a.Component != b.Component {
+			return a.Component < b.Component
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return removed[i] < removed[j]
+	})
+	for _, key := range removed {
+		resState := existingResources[key]
+		change := &ResourceChange{
+			Node: &graph.Node{
+				ID:        key,
+				Component: resState.Component,
+				Name:      resState.Name,
+			},
+			Action:       ActionDelete,
+			CurrentState: resState,
+			Reason:       "resource no longer defined",
+		}
+		if resState.Paused {
+			change.Action = ActionNoop
+			change.Reason = "resource is paused"
 			plan.Changes = append(plan.Changes, change)
-			plan.ToDelete++
+			plan.NoChange++
+			continue
 		}
+		plan.Changes = append(plan.Changes, change)
+		plan.ToDelete++
 	}
 
 	return plan, nil
 }
 
+// TargetedNodes returns the IDs of the target nodes and of every node they
+// transitively depend on, so that a plan restricted to them is complete. It
+// fails if a target isn't in the graph.
+func TargetedNodes(g *graph.Graph, targets []string) (map[string]bool, error) {
+	selected := make(map[string]bool)
+	var visit func(id string)
+	visit = func(id string) {
+		if selected[id] {
+			return
+		}
+		selected[id] = true
+		if node := g.GetNode(id); node != nil {
+			for _, depID := range node.DependsOn {
+				visit(depID)
+			}
+		}
+	}
+
+	for _, target := range targets {
+		if g.GetNode(target) == nil {
+			var available []string
+			for id := range g.Nodes {
+				available = append(available, id)
+			}
+			sort.Strings(available)
+			return nil, fmt.Errorf("target %q not found in the graph\n\nAvailable resources:\n  %s", target, strings.Join(available, "\n  "))
+		}
+		visit(target)
+	}
+	return selected, nil
+}
+
 // PlanDestroy creates a plan to destroy all resources.
 // Tasks (e.g., database migrations) are excluded because they are one-time
 // operations with no persistent resources to tear down — the underlying
@@ -221,12 +452,24 @@ func (p *Planner) planNodeChange(node *graph.Node, existingResources map[string]
 		return change
 	}
 
+	// A resource whose apply was interrupted may not match its recorded
+	// inputs, so it's re-applied even if they haven't changed
+	if existing.Status == types.ResourceStatusUnknown {
+		change.Action = ActionUpdate
+		change.Reason = "previous apply did not finish"
+		return change
+	}
+
 	// Compare inputs to detect changes
 	changes := p.CompareInputs(node.Inputs, existing.Inputs)
 	if len(changes) > 0 {
 		change.Action = ActionUpdate
 		change.PropertyChanges = changes
 		change.Reason = "resource configuration changed"
+		if forced := p.forcesReplacement(node, changes); len(forced) > 0 {
+			change.Action = ActionReplace
+			change.Reason = fmt.Sprintf("changing %s requires replacement", strings.Join(forced, ", "))
+		}
 		return change
 	}
 
@@ -243,6 +486,28 @@ func (p *Planner) planNodeChange(node *graph.Node, existingResources map[string]
 	return change
 }
 
+// forcesReplacement returns the paths of changes that the hook provisioning
+// node can't apply in place.
+func (p *Planner) forcesReplacement(node *graph.Node, changes []PropertyChange) []string {
+	if p.options.ReplaceOn == nil {
+		return nil
+	}
+	replaceOn := p.options.ReplaceOn(node)
+	if len(replaceOn) == 0 {
+		return nil
+	}
+	var forced []string
+	for _, c := range changes {
+		for _, input := range replaceOn {
+			if input == "*" || input == c.Path {
+				forced = append(forced, c.Path)
+				break
+			}
+		}
+	}
+	return forced
+}
+
 // CompareInputs compares desired inputs against current inputs and returns
 // a list of property-level changes. Useful for detecting configuration drift.
 func (p *Planner) CompareInputs(desired, current map[string]interface{}) []PropertyChange {
@@ -277,6 +542,9 @@ func (p *Planner) CompareInputs(desired, current map[string]interface{}) []Prope
 		}
 	}
 
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
 	return changes
 }
 
@@ -1,6 +1,9 @@
 package planner
 
 import (
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/davidthor/cldctl/pkg/graph"
@@ -180,6 +183,94 @@ func TestPlan_NoChanges(t *testing.T) {
 	}
 }
 
+func TestPlan_InterruptedResourceIsReapplied(t *testing.T) {
+	p := NewPlanner()
+
+	g := graph.NewGraph("test-env", "test-dc")
+	node := graph.NewNode(graph.NodeTypeDatabase, "api", "postgres")
+	node.SetInput("type", "postgres")
+	_ = g.AddNode(node)
+
+	currentState := &types.EnvironmentState{
+		Name: "test-env",
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Name: "api",
+				Resources: map[string]*types.ResourceState{
+					"database/postgres": {
+						Name:      "postgres",
+						Type:      string(graph.NodeTypeDatabase),
+						Component: "api",
+						Inputs:    map[string]interface{}{"type": "postgres"},
+						Status:    types.ResourceStatusUnknown,
+					},
+				},
+			},
+		},
+	}
+
+	plan, err := p.Plan(g, currentState)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if plan.ToUpdate != 1 {
+		t.Errorf("ToUpdate: got %d, want %d", plan.ToUpdate, 1)
+	}
+}
+
+func TestPlan_PausedResources(t *testing.T) {
+	p := NewPlannerWithOptions(PlanOptions{ForceUpdate: true})
+
+	g := graph.NewGraph("test-env", "test-dc")
+	node := graph.NewNode(graph.NodeTypeDeployment, "api", "main")
+	node.SetInput("replicas", 2)
+	_ = g.AddNode(node)
+
+	currentState := &types.EnvironmentState{
+		Name: "test-env",
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Name: "api",
+				Resources: map[string]*types.ResourceState{
+					"deployment.main": {
+						Name:      "main",
+						Type:      string(graph.NodeTypeDeployment),
+						Component: "api",
+						Inputs:    map[string]interface{}{"replicas": 8},
+						Status:    types.ResourceStatusReady,
+						Paused:    true,
+					},
+					"deployment.legacy": {
+						Name:      "legacy",
+						Type:      string(graph.NodeTypeDeployment),
+						Component: "api",
+						Status:    types.ResourceStatusReady,
+						Paused:    true,
+					},
+				},
+			},
+		},
+	}
+
+	plan, err := p.Plan(g, currentState)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if !plan.IsEmpty() {
+		t.Fatalf("expected paused resources to be left unchanged, got %d to update, %d to delete", plan.ToUpdate, plan.ToDelete)
+	}
+	for _, c := range plan.Changes {
+		if c.Reason != "resource is paused" {
+			t.Errorf("%s: Reason = %q", c.Node.ID, c.Reason)
+		}
+	}
+
+	// The change that isn't applied is called out
+	if len(plan.Warnings) != 1 || plan.Warnings[0].Resource != node.ID {
+		t.Errorf("expected a warning about the paused resource's pending change, got %v", plan.Warnings)
+	}
+}
+
 func TestPlan_Updates(t *testing.T) {
 	p := NewPlanner()
 
@@ -290,6 +381,154 @@ func TestPlan_Deletions(t *testing.T) {
 	}
 }
 
+func TestPlan_Targets(t *testing.T) {
+	g := graph.NewGraph("test-env", "test-dc")
+	db := graph.NewNode(graph.NodeTypeDatabase, "api", "main")
+	api := graph.NewNode(graph.NodeTypeDeployment, "api", "api")
+	worker := graph.NewNode(graph.NodeTypeDeployment, "api", "worker")
+	route := graph.NewNode(graph.NodeTypeRoute, "api", "public")
+	for _, n := range []*graph.Node{db, api, worker, route} {
+		_ = g.AddNode(n)
+	}
+	_ = g.AddEdge(api.ID, db.ID)
+	_ = g.AddEdge(worker.ID, db.ID)
+	_ = g.AddEdge(route.ID, api.ID)
+
+	currentState := &types.EnvironmentState{
+		Name: "test-env",
+		Components: map[string]*types.ComponentState{
+			"api": {
+				Name: "api",
+				Resources: map[string]*types.ResourceState{
+					"old-resource": {
+						Name:      "old-resource",
+						Type:      string(graph.NodeTypeDeployment),
+						Component: "api",
+					},
+				},
+			},
+		},
+	}
+
+	p := NewPlannerWithOptions(PlanOptions{Targets: []string{api.ID}})
+	plan, err := p.Plan(g, currentState)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	var planned []string
+	for _, c := range plan.Changes {
+		planned = append(planned, c.Node.ID)
+	}
+	// The target and its dependency, but not its dependents, siblings, or
+	// the removed resource
+	want := []string{db.ID, api.ID}
+	if !reflect.DeepEqual(planned, want) {
+		t.Errorf("planned %v, want %v", planned, want)
+	}
+	if plan.ToCreate != 2 || plan.ToDelete != 0 {
+		t.Errorf("got %d to create and %d to delete, want 2 and 0", plan.ToCreate, plan.ToDelete)
+	}
+
+	p = NewPlannerWithOptions(PlanOptions{Targets: []string{"api/deployment/missing"}})
+	if _, err := p.Plan(g, currentState); err == nil || !strings.Contains(err.Error(), `target "api/deployment/missing" not found`) {
+		t.Errorf("expected an unknown target error, got %v", err)
+	}
+}
+
+func TestPlan_ReplaceOn(t *testing.T) {
+	g := graph.NewGraph("test-env", "test-dc")
+	db := graph.NewNode(graph.NodeTypeDatabase, "api", "main")
+	db.SetInput("type", "postgres:^16")
+	db.SetInput("size", "large")
+	_ = g.AddNode(db)
+
+	plan := func(replaceOn []string, current map[string]interface{}) *ResourceChange {
+		t.Helper()
+		currentState := &types.EnvironmentState{
+			Components: map[string]*types.ComponentState{
+				"api": {Resources: map[string]*types.ResourceState{
+					"database/main": {Component: "api", Name: "main", Type: "database", Inputs: current},
+				}},
+			},
+		}
+		p := NewPlannerWithOptions(PlanOptions{ReplaceOn: func(n *graph.Node) []string {
+			if n.ID != db.ID {
+				t.Errorf("unexpected node %s", n.ID)
+			}
+			return replaceOn
+		}})
+		result, err := p.Plan(g, currentState)
+		if err != nil {
+			t.Fatalf("Plan failed: %v", err)
+		}
+		return result.Changes[0]
+	}
+
+	change := plan([]string{"type"}, map[string]interface{}{"type": "postgres:^15", "size": "large"})
+	if change.Action != ActionReplace || change.Reason != "changing type requires replacement" {
+		t.Errorf("expected a replacement for the type change, got %s (%s)", change.Action, change.Reason)
+	}
+
+	if change := plan([]string{"type"}, map[string]interface{}{"type": "postgres:^16", "size": "small"}); change.Action != ActionUpdate {
+		t.Errorf("expected other changes to update in place, got %s", change.Action)
+	}
+
+	if change := plan([]string{"*"}, map[string]interface{}{"type": "postgres:^16", "size": "small"}); change.Action != ActionReplace {
+		t.Errorf("expected * to replace on any change, got %s", change.Action)
+	}
+
+	if change := plan([]string{"*"}, map[string]interface{}{"type": "postgres:^16", "size": "large"}); change.Action != ActionNoop {
+		t.Errorf("expected an unchanged resource not to be replaced, got %s", change.Action)
+	}
+}
+
+func TestPlan_SkipDelete(t *testing.T) {
+	node := graph.NewNode(graph.NodeTypeDatabase, "api", "old")
+	change := &ResourceChange{Node: node, Action: ActionDelete}
+	plan := &Plan{Changes: []*ResourceChange{change}, ToDelete: 1}
+
+	if err := plan.Skip(change, "keep data"); err != nil {
+		t.Fatalf("Skip failed: %v", err)
+	}
+	if change.Action != ActionNoop {
+		t.Errorf("Action: got %s, want %s", change.Action, ActionNoop)
+	}
+	if change.SkipReason != "keep data" {
+		t.Errorf("SkipReason: got %q", change.SkipReason)
+	}
+	if plan.ToDelete != 0 || plan.NoChange != 1 || plan.Skipped != 1 {
+		t.Errorf("counts: delete=%d noop=%d skipped=%d", plan.ToDelete, plan.NoChange, plan.Skipped)
+	}
+	if !plan.IsEmpty() {
+		t.Error("expected plan to be empty after skipping its only change")
+	}
+
+	plan.Unskip(change)
+	if change.Action != ActionDelete {
+		t.Errorf("Action after Unskip: got %s, want %s", change.Action, ActionDelete)
+	}
+	if change.SkipReason != "" {
+		t.Errorf("SkipReason after Unskip: got %q", change.SkipReason)
+	}
+	if plan.ToDelete != 1 || plan.NoChange != 0 || plan.Skipped != 0 {
+		t.Errorf("counts after Unskip: delete=%d noop=%d skipped=%d", plan.ToDelete, plan.NoChange, plan.Skipped)
+	}
+}
+
+func TestPlan_SkipRejectsNonDestructive(t *testing.T) {
+	node := graph.NewNode(graph.NodeTypeDeployment, "api", "main")
+	change := &ResourceChange{Node: node, Action: ActionCreate}
+	plan := &Plan{Changes: []*ResourceChange{change}, ToCreate: 1}
+
+	if err := plan.Skip(change, "nope"); err == nil {
+		t.Fatal("expected error skipping a create")
+	}
+	if change.Action != ActionCreate || plan.ToCreate != 1 {
+		t.Errorf("plan was modified: action=%s create=%d", change.Action, plan.ToCreate)
+	}
+}
+
 func TestPlanDestroy(t *testing.T) {
 	p := NewPlanner()
 
@@ -536,3 +775,55 @@ func TestPropertyChange(t *testing.T) {
 		t.Errorf("NewValue: got %v", change.NewValue)
 	}
 }
+
+func TestPlan_DetectDrift(t *testing.T) {
+	g := graph.NewGraph("test-env", "test-dc")
+	for _, name := range []string{"main", "cache", "broken"} {
+		node := graph.NewNode(graph.NodeTypeDatabase, "api", name)
+		node.SetInput("type", "postgres")
+		_ = g.AddNode(node)
+	}
+
+	resources := make(map[string]*types.ResourceState)
+	for _, name := range []string{"main", "cache", "broken"} {
+		resources["database/"+name] = &types.ResourceState{
+			Name:      name,
+			Type:      string(graph.NodeTypeDatabase),
+			Component: "api",
+			Inputs:    map[string]interface{}{"type": "postgres"},
+			Status:    types.ResourceStatusReady,
+		}
+	}
+	currentState := &types.EnvironmentState{
+		Name:       "test-env",
+		Components: map[string]*types.ComponentState{"api": {Name: "api", Resources: resources}},
+	}
+
+	p := NewPlannerWithOptions(PlanOptions{
+		DetectDrift: func(node *graph.Node, current *types.ResourceState) (*Drift, error) {
+			switch node.Name {
+			case "main":
+				return &Drift{Outputs: []PropertyChange{{Path: "host", OldValue: "a", NewValue: "b"}}}, nil
+			case "broken":
+				return nil, errors.New("no credentials")
+			}
+			return nil, nil
+		},
+	})
+	plan, err := p.Plan(g, currentState)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	if plan.ToUpdate != 1 || plan.Drifted != 1 || plan.NoChange != 2 {
+		t.Fatalf("expected only the drifted resource to be updated, got %d to update, %d drifted, %d unchanged", plan.ToUpdate, plan.Drifted, plan.NoChange)
+	}
+	for _, c := range plan.Changes {
+		if c.Node.Name == "main" && (c.Action != ActionUpdate || c.Drift == nil || c.Reason != "infrastructure drifted from state") {
+			t.Errorf("expected the drifted resource to be updated, got %s (%s)", c.Action, c.Reason)
+		}
+	}
+	if len(plan.Warnings) != 1 || plan.Warnings[0].Resource != "api/database/broken" {
+		t.Errorf("expected a warning for the failed check, got %v", plan.Warnings)
+	}
+}
@@ -0,0 +1,210 @@
+package planner
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Wave is a group of changes that can run at the same time: each one only
+// waits on changes in earlier waves.
+type Wave struct {
+	Changes []WaveChange
+
+	// Estimate is how long the wave's slowest change took the last time it
+	// was applied, or zero if none of them has been applied before
+	Estimate time.Duration
+}
+
+// WaveChange is a change in a wave.
+type WaveChange struct {
+	*ResourceChange
+
+	// After lists the IDs of the changes this one waits on directly.
+	// Unchanged resources in between don't wait, so they're skipped over.
+	After []string
+
+	// Estimate is how long the resource took the last time it was applied,
+	// or zero if it hasn't been applied before
+	Estimate time.Duration
+}
+
+// Schedule is a plan's changes grouped into waves.
+type Schedule struct {
+	Waves []Wave
+
+	// CriticalPath lists the IDs of the longest chain of changes that wait
+	// on each other, which bounds how fast the plan can be executed. Chains
+	// are measured by their estimates, then by their length.
+	CriticalPath []string
+
+	// Estimate is the estimated duration of the critical path
+	Estimate time.Duration
+}
+
+// Waves groups the plan's changes into waves by their dependencies, to show
+// what runs in parallel and what blocks what. Unchanged and skipped
+// resources don't run, so they aren't in any wave.
+func (p *Plan) Waves() *Schedule {
+	changes := make(map[string]*ResourceChange, len(p.Changes))
+	for _, c := range p.Changes {
+		if c.Node != nil {
+			changes[c.Node.ID] = c
+		}
+	}
+	runs := func(id string) bool {
+		c := changes[id]
+		return c != nil && c.Action != ActionNoop
+	}
+
+	// after returns the changes a node waits on, looking through unchanged
+	// dependencies to the changes they wait on
+	afterMemo := make(map[string][]string)
+	var after func(id string) []string
+	after = func(id string) []string {
+		if deps, ok := afterMemo[id]; ok {
+			return deps
+		}
+		afterMemo[id] = nil
+		seen := make(map[string]bool)
+		var deps []string
+		for _, dep := range changes[id].Node.DependsOn {
+			if changes[dep] == nil {
+				continue
+			}
+			found := []string{dep}
+			if !runs(dep) {
+				found = after(dep)
+			}
+			for _, d := range found {
+				if !seen[d] {
+					seen[d] = true
+					deps = append(deps, d)
+				}
+			}
+		}
+		sort.Strings(deps)
+		afterMemo[id] = deps
+		return deps
+	}
+
+	level := make(map[string]int)
+	finish := make(map[string]time.Duration)
+	critical := make(map[string]string)
+	var place func(id string) int
+	place = func(id string) int {
+		if l, ok := level[id]; ok {
+			return l
+		}
+		level[id] = 0
+		l := 0
+		var longest time.Duration
+		for _, dep := range after(id) {
+			if dl := place(dep); dl > l {
+				l = dl
+			}
+			if f := finish[dep]; critical[id] == "" || f > longest || (f == longest && level[dep] > level[critical[id]]) {
+				longest = f
+				critical[id] = dep
+			}
+		}
+		level[id] = l + 1
+		finish[id] = longest + estimate(changes[id])
+		return l + 1
+	}
+
+	schedule := &Schedule{}
+	var last string
+	for _, c := range p.Changes {
+		if c.Node == nil || !runs(c.Node.ID) {
+			continue
+		}
+		id := c.Node.ID
+		l := place(id)
+		for len(schedule.Waves) < l {
+			schedule.Waves = append(schedule.Waves, Wave{})
+		}
+		wave := &schedule.Waves[l-1]
+		wc := WaveChange{ResourceChange: c, After: after(id), Estimate: estimate(c)}
+		wave.Changes = append(wave.Changes, wc)
+		if wc.Estimate > wave.Estimate {
+			wave.Estimate = wc.Estimate
+		}
+		if last == "" || finish[id] > finish[last] || (finish[id] == finish[last] && level[id] > level[last]) {
+			last = id
+		}
+	}
+
+	for id := last; id != ""; id = critical[id] {
+		schedule.CriticalPath = append([]string{id}, schedule.CriticalPath...)
+	}
+	if last != "" {
+		schedule.Estimate = finish[last]
+	}
+	return schedule
+}
+
+// estimate returns how long a change's resource took the last time it was
+// applied.
+func estimate(c *ResourceChange) time.Duration {
+	if c.CurrentState == nil || c.CurrentState.Timing == nil {
+		return 0
+	}
+	return time.Duration(c.CurrentState.Timing.DurationMS) * time.Millisecond
+}
+
+// Print writes the schedule as text: each wave's changes, what each one
+// waits on, and the critical path.
+func (s *Schedule) Print(w io.Writer) {
+	if len(s.Waves) == 0 {
+		fmt.Fprintln(w, "No changes required.")
+		return
+	}
+
+	changes := 0
+	for i, wave := range s.Waves {
+		changes += len(wave.Changes)
+		fmt.Fprintf(w, "Wave %d (%d change(s)", i+1, len(wave.Changes))
+		if wave.Estimate > 0 {
+			fmt.Fprintf(w, ", ~%s", wave.Estimate.Round(time.Second))
+		}
+		fmt.Fprintln(w, ")")
+		for _, c := range wave.Changes {
+			fmt.Fprintf(w, "  %s %s", actionSymbol(c.Action), c.Node.ID)
+			if len(c.After) > 0 {
+				fmt.Fprintf(w, "  after %s", strings.Join(c.After, ", "))
+			}
+			if c.Estimate > 0 {
+				fmt.Fprintf(w, "  ~%s", c.Estimate.Round(time.Second))
+			}
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(s.CriticalPath) > 1 {
+		fmt.Fprint(w, "Critical path")
+		if s.Estimate > 0 {
+			fmt.Fprintf(w, " (~%s)", s.Estimate.Round(time.Second))
+		}
+		fmt.Fprintf(w, ": %s\n", strings.Join(s.CriticalPath, " → "))
+	}
+	fmt.Fprintf(w, "%d change(s) in %d wave(s)\n", changes, len(s.Waves))
+}
+
+// actionSymbol returns the symbol plan output shows for an action.
+func actionSymbol(action Action) string {
+	switch action {
+	case ActionCreate:
+		return "+"
+	case ActionUpdate:
+		return "~"
+	case ActionDelete:
+		return "-"
+	case ActionReplace:
+		return "±"
+	}
+	return " "
+}
@@ -0,0 +1,83 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// wavesTestPlan plans a database and a build that both block a deployment
+// through an unchanged service, and a route after the deployment.
+func wavesTestPlan() *Plan {
+	db := graph.NewNode(graph.NodeTypeDatabase, "app", "main")
+	build := graph.NewNode(graph.NodeTypeDockerBuild, "app", "api")
+	svc := graph.NewNode(graph.NodeTypeService, "app", "db-proxy")
+	svc.DependsOn = []string{db.ID}
+	api := graph.NewNode(graph.NodeTypeDeployment, "app", "api")
+	api.DependsOn = []string{svc.ID, build.ID}
+	route := graph.NewNode(graph.NodeTypeRoute, "app", "main")
+	route.DependsOn = []string{api.ID}
+
+	timed := func(d time.Duration) *types.ResourceState {
+		return &types.ResourceState{Timing: &types.ResourceTiming{DurationMS: d.Milliseconds()}}
+	}
+	return &Plan{
+		Changes: []*ResourceChange{
+			{Node: db, Action: ActionUpdate, CurrentState: timed(3 * time.Minute)},
+			{Node: build, Action: ActionCreate},
+			{Node: svc, Action: ActionNoop},
+			{Node: api, Action: ActionUpdate, CurrentState: timed(time.Minute)},
+			{Node: route, Action: ActionCreate},
+		},
+	}
+}
+
+func TestPlan_Waves(t *testing.T) {
+	s := wavesTestPlan().Waves()
+
+	var got []string
+	for _, wave := range s.Waves {
+		var ids []string
+		for _, c := range wave.Changes {
+			ids = append(ids, c.Node.ID+" after "+fmt.Sprint(c.After))
+		}
+		got = append(got, strings.Join(ids, ", "))
+	}
+	want := []string{
+		"app/database/main after [], app/dockerBuild/api after []",
+		"app/deployment/api after [app/database/main app/dockerBuild/api]",
+		"app/route/main after [app/deployment/api]",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("waves = %q, want %q", got, want)
+	}
+
+	// The unchanged service doesn't run, but the deployment still waits on
+	// the database behind it, which is the slowest chain
+	if path := strings.Join(s.CriticalPath, " "); path != "app/database/main app/deployment/api app/route/main" {
+		t.Errorf("critical path = %s", path)
+	}
+	if s.Estimate != 4*time.Minute || s.Waves[0].Estimate != 3*time.Minute {
+		t.Errorf("expected estimates from the last applies, got %s overall and %s for the first wave", s.Estimate, s.Waves[0].Estimate)
+	}
+}
+
+func TestSchedule_Print(t *testing.T) {
+	var out strings.Builder
+	wavesTestPlan().Waves().Print(&out)
+
+	for _, want := range []string{
+		"Wave 1 (2 change(s), ~3m0s)\n  ~ app/database/main  ~3m0s\n  + app/dockerBuild/api\n",
+		"  ~ app/deployment/api  after app/database/main, app/dockerBuild/api  ~1m0s\n",
+		"Critical path (~4m0s): app/database/main → app/deployment/api → app/route/main\n",
+		"4 change(s) in 3 wave(s)\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/engine/executor"
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// RetryNodeOptions configures the retry of a single resource.
+type RetryNodeOptions struct {
+	// Environment name
+	Environment string
+
+	// Datacenter name
+	Datacenter string
+
+	// Component is the name of the deployed component that owns the resource
+	Component string
+
+	// NodePath identifies the resource as "type/name" (e.g., "database/main")
+	NodePath string
+
+	// Output writer for progress
+	Output io.Writer
+
+	// OnProgress is called when resource status changes
+	OnProgress executor.ProgressCallback
+}
+
+// RetryNodeResult contains the result of a retry.
+type RetryNodeResult struct {
+	Success bool
+	NodeID  string
+	Action  planner.Action
+
+	Execution *executor.ExecutionResult
+	Duration  time.Duration
+}
+
+// RetryNode re-applies one resource of a deployed component, such as one
+// whose hook module failed transiently, without re-planning the environment.
+// The component is rebuilt from the source and variables it was last
+// deployed with, and the resource's expressions are resolved again against
+// the outputs its dependencies have recorded in state. Every other resource
+// is left as it is, so resources skipped because this one failed are only
+// deployed by the next deploy.
+func (e *Engine) RetryNode(ctx context.Context, opts RetryNodeOptions) (*RetryNodeResult, error) {
+	startTime := time.Now()
+
+	lockedCtx, unlock, err := e.lockEnvironment(ctx, opts.Datacenter, opts.Environment, "retry", false, opts.Output)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	ctx = lockedCtx
+
+	parts := strings.SplitN(opts.NodePath, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid node path %q: expected format type/name (e.g., database/main)", opts.NodePath)
+	}
+
+	dcState, err := e.stateManager.GetDatacenter(ctx, opts.Datacenter)
+	if err != nil {
+		return nil, fmt.Errorf("datacenter %q not found: %w", opts.Datacenter, err)
+	}
+	if dcState.Version == "" {
+		return nil, fmt.Errorf("datacenter %q has no source path configured", opts.Datacenter)
+	}
+	dc, err := e.loadDatacenterConfig(dcState.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load datacenter configuration: %w", err)
+	}
+	if err := e.checkDatacenterPin(ctx, opts.Datacenter, dcState, opts.Environment, false, false); err != nil {
+		return nil, err
+	}
+	if err := e.checkInFlightDeploy(ctx, opts.Datacenter, opts.Environment, false, opts.Output); err != nil {
+		return nil, err
+	}
+
+	envState, err := e.stateManager.GetEnvironment(ctx, opts.Datacenter, opts.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("environment %s not found in datacenter %s", opts.Environment, opts.Datacenter)
+	}
+	compState := envState.Components[opts.Component]
+	if compState == nil {
+		return nil, fmt.Errorf("component %q is not deployed to environment %q", opts.Component, opts.Environment)
+	}
+	if compState.Source == "" {
+		return nil, fmt.Errorf("component %q has no recorded source; deploy it again instead", opts.Component)
+	}
+	if len(compState.Instances) > 0 {
+		return nil, fmt.Errorf("component %q runs multiple instances, whose resources can't be retried", opts.Component)
+	}
+
+	comp, err := e.compLoader.Load(compState.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load component %s: %w", opts.Component, err)
+	}
+	builder := newGraphBuilder(opts.Environment, opts.Datacenter, dc)
+	if err := builder.AddComponent(opts.Component, comp); err != nil {
+		return nil, fmt.Errorf("failed to add component %s to graph: %w", opts.Component, err)
+	}
+	g := builder.Build()
+	applyLabels(g, environmentLabels(envState), componentLabels(envState, map[string]string{opts.Component: compState.Source}, nil))
+	if err := checkRouteAuthHooks(g, dc); err != nil {
+		return nil, err
+	}
+	if err := checkHookOutputContracts(g, dc); err != nil {
+		return nil, err
+	}
+
+	target := g.GetNode(graph.NewNode(graph.NodeType(parts[0]), opts.Component, parts[1]).ID)
+	if target == nil {
+		var available []string
+		for _, n := range g.GetNodesByComponent(opts.Component) {
+			available = append(available, fmt.Sprintf("%s/%s", n.Type, n.Name))
+		}
+		sort.Strings(available)
+		return nil, fmt.Errorf("node %q not found in component %q\n\nAvailable nodes:\n  %s",
+			opts.NodePath, opts.Component, strings.Join(available, "\n  "))
+	}
+
+	plan, retried, err := planRetry(g, compState, target)
+	if err != nil {
+		return nil, err
+	}
+
+	dcVars := datacenter.ResolveVariables(dc, dcState.Variables, opts.Environment)
+	compVars := make(map[string]interface{}, len(compState.Variables))
+	for k, v := range compState.Variables {
+		compVars[k] = v
+	}
+
+	execOpts := executor.Options{
+		Parallelism:         1,
+		Output:              opts.Output,
+		StopOnError:         true,
+		OnProgress:          opts.OnProgress,
+		Datacenter:          dc,
+		DatacenterVariables: dcVars,
+		EnvironmentModules:  environmentModuleOutputs(envState),
+		EnvironmentLabels:   environmentLabels(envState),
+		ComponentSources:    map[string]string{opts.Component: compState.Source},
+		ComponentVariables:  map[string]map[string]interface{}{opts.Component: compVars},
+	}
+
+	execResult, err := executor.NewExecutor(e.stateManager, e.iacRegistry, execOpts).Execute(ctx, plan, g)
+	if err != nil {
+		return nil, fmt.Errorf("execution failed: %w", err)
+	}
+
+	return &RetryNodeResult{
+		Success:   execResult.Success,
+		NodeID:    target.ID,
+		Action:    retried.Action,
+		Execution: execResult,
+		Duration:  time.Since(startTime),
+	}, nil
+}
+
+// planRetry plans the retry of a node, returning the plan and the node's
+// change: it's re-applied, and every other node of the component is left as
+// deployed. The node's dependencies must already be ready, since their
+// outputs are read from state.
+func planRetry(g *graph.Graph, compState *types.ComponentState, target *graph.Node) (*planner.Plan, *planner.ResourceChange, error) {
+	current := compState.Resources[string(target.Type)+"."+target.Name]
+	if current != nil && current.Paused {
+		return nil, nil, fmt.Errorf("%s is paused; unpause it before retrying", target.ID)
+	}
+	for _, depID := range target.DependsOn {
+		dep := g.GetNode(depID)
+		if dep == nil {
+			continue
+		}
+		rs := compState.Resources[string(dep.Type)+"."+dep.Name]
+		if rs == nil || rs.Status != types.ResourceStatusReady {
+			return nil, nil, fmt.Errorf("%s depends on %s, which is not ready; retry it first or deploy component %s", target.ID, dep.ID, target.Component)
+		}
+	}
+
+	sorted, err := g.TopologicalSort()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plan := &planner.Plan{Environment: g.Environment, Datacenter: g.Datacenter}
+	var retried *planner.ResourceChange
+	for _, node := range sorted {
+		rs := compState.Resources[string(node.Type)+"."+node.Name]
+		change := &planner.ResourceChange{Node: node, CurrentState: rs}
+		switch {
+		case node.ID != target.ID:
+			change.Action = planner.ActionNoop
+			change.Reason = "not retried"
+			plan.NoChange++
+		case rs == nil:
+			retried = change
+			change.Action = planner.ActionCreate
+			change.Reason = "retrying resource"
+			plan.ToCreate++
+		default:
+			retried = change
+			change.Action = planner.ActionUpdate
+			change.Reason = "retrying resource"
+			plan.ToUpdate++
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+	return plan, retried, nil
+}
@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func TestRetryNode(t *testing.T) {
+	eng, mgr, plugin := newRotateTestEngine(t)
+	api := mgr.environments["staging"].Components["app"].Resources["deployment.api"]
+	api.Status = types.ResourceStatusFailed
+	api.StatusReason = "apply failed: connection reset"
+
+	result, err := eng.RetryNode(context.Background(), RetryNodeOptions{
+		Environment: "staging",
+		Datacenter:  "dc",
+		Component:   "app",
+		NodePath:    "deployment/api",
+	})
+	if err != nil {
+		t.Fatalf("RetryNode failed: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("expected success, got %v", result.Execution.Errors)
+	}
+	if result.NodeID != "app/deployment/api" || result.Action != planner.ActionUpdate {
+		t.Errorf("expected an update of app/deployment/api, got %s of %s", result.Action, result.NodeID)
+	}
+
+	// Only the retried node is applied, with its expressions resolved from
+	// the recorded outputs of its dependencies
+	if got := fmt.Sprint(plugin.events); got != "[apply api]" {
+		t.Errorf("events = %s, want [apply api]", got)
+	}
+	if env := fmt.Sprint(plugin.applied["api"]["environment"]); env != "map[SESSION_KEY:key-0]" {
+		t.Errorf("expected the recorded key to be used, got %s", env)
+	}
+
+	retried := mgr.environments["staging"].Components["app"].Resources["deployment.api"]
+	if retried == nil || retried.Status != types.ResourceStatusReady {
+		t.Errorf("expected the retried resource to be ready, got %+v", retried)
+	}
+}
+
+func TestRetryNode_DependencyNotReady(t *testing.T) {
+	eng, mgr, plugin := newRotateTestEngine(t)
+	mgr.environments["staging"].Components["app"].Resources["encryptionKey.session"].Status = types.ResourceStatusFailed
+
+	_, err := eng.RetryNode(context.Background(), RetryNodeOptions{
+		Environment: "staging",
+		Datacenter:  "dc",
+		Component:   "app",
+		NodePath:    "deployment/api",
+	})
+	if err == nil || !strings.Contains(err.Error(), "app/encryptionKey/session") {
+		t.Errorf("expected an error naming the dependency, got %v", err)
+	}
+	if len(plugin.events) != 0 {
+		t.Errorf("expected nothing to be applied, got %v", plugin.events)
+	}
+
+	if _, err := eng.RetryNode(context.Background(), RetryNodeOptions{
+		Environment: "staging",
+		Datacenter:  "dc",
+		Component:   "app",
+		NodePath:    "deployment/missing",
+	}); err == nil {
+		t.Error("expected an error for an undeclared node")
+	}
+}
@@ -0,0 +1,314 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/engine/executor"
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// RotateSecretOptions configures a secret rotation.
+type RotateSecretOptions struct {
+	// Environment name
+	Environment string
+
+	// Datacenter name
+	Datacenter string
+
+	// Component is the name of the deployed component that owns the secret
+	Component string
+
+	// Secret is the name of the component's encryption key to rotate
+	Secret string
+
+	// TwoPhase keeps the old value valid until every consumer has been
+	// re-applied with the new one, instead of retiring it first
+	TwoPhase bool
+
+	// DryRun only plans the rotation without executing it
+	DryRun bool
+
+	// Output writer for progress
+	Output io.Writer
+
+	// OnProgress is called when resource status changes
+	OnProgress executor.ProgressCallback
+}
+
+// RotateSecretResult contains the results of a secret rotation.
+type RotateSecretResult struct {
+	Success bool
+	Plan    *planner.Plan
+
+	// Consumers are the IDs of the nodes re-applied with the new value
+	Consumers []string
+
+	Execution *executor.ExecutionResult
+	Duration  time.Duration
+}
+
+// RotateSecret mints a new value for one of a deployed component's secrets by
+// re-running the datacenter hook that created it, then re-applies the
+// resources whose expressions consume it.
+//
+// By default the old value is retired before the new one is minted, so
+// consumers are briefly left holding a value that no longer works. In
+// two-phase mode the new value is minted alongside the old one, and the old
+// one is only retired once every consumer has been re-applied.
+func (e *Engine) RotateSecret(ctx context.Context, opts RotateSecretOptions) (*RotateSecretResult, error) {
+	startTime := time.Now()
+
+	if !opts.DryRun {
+		lockedCtx, unlock, err := e.lockEnvironment(ctx, opts.Datacenter, opts.Environment, "rotate secret", false, opts.Output)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+		ctx = lockedCtx
+	}
+
+	dcState, err := e.stateManager.GetDatacenter(ctx, opts.Datacenter)
+	if err != nil {
+		return nil, fmt.Errorf("datacenter %q not found: %w", opts.Datacenter, err)
+	}
+	if dcState.Version == "" {
+		return nil, fmt.Errorf("datacenter %q has no source path configured", opts.Datacenter)
+	}
+	dc, err := e.loadDatacenterConfig(dcState.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load datacenter configuration: %w", err)
+	}
+	if err := e.checkDatacenterPin(ctx, opts.Datacenter, dcState, opts.Environment, false, opts.DryRun); err != nil {
+		return nil, err
+	}
+	if !opts.DryRun {
+		if err := e.checkInFlightDeploy(ctx, opts.Datacenter, opts.Environment, false, opts.Output); err != nil {
+			return nil, err
+		}
+	}
+
+	envState, err := e.stateManager.GetEnvironment(ctx, opts.Datacenter, opts.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("environment %s not found in datacenter %s", opts.Environment, opts.Datacenter)
+	}
+	compState := envState.Components[opts.Component]
+	if compState == nil {
+		return nil, fmt.Errorf("component %q is not deployed to environment %q", opts.Component, opts.Environment)
+	}
+	if compState.Source == "" {
+		return nil, fmt.Errorf("component %q has no recorded source; deploy it again to rotate its secrets", opts.Component)
+	}
+	if len(compState.Instances) > 0 {
+		return nil, fmt.Errorf("component %q runs multiple instances, whose secrets can't be rotated", opts.Component)
+	}
+
+	comp, err := e.compLoader.Load(compState.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load component %s: %w", opts.Component, err)
+	}
+	builder := newGraphBuilder(opts.Environment, opts.Datacenter, dc)
+	if err := builder.AddComponent(opts.Component, comp); err != nil {
+		return nil, fmt.Errorf("failed to add component %s to graph: %w", opts.Component, err)
+	}
+	g := builder.Build()
+	applyLabels(g, environmentLabels(envState), componentLabels(envState, map[string]string{opts.Component: compState.Source}, nil))
+
+	secret := g.GetNode(graph.NewNode(graph.NodeTypeEncryptionKey, opts.Component, opts.Secret).ID)
+	if secret == nil {
+		return nil, fmt.Errorf("component %q has no encryption key named %q", opts.Component, opts.Secret)
+	}
+	previous := compState.Resources[string(secret.Type)+"."+secret.Name]
+	if previous == nil {
+		return nil, fmt.Errorf("secret %q of component %q has not been deployed", opts.Secret, opts.Component)
+	}
+
+	plan, consumers, err := planRotation(g, compState, secret)
+	if err != nil {
+		return nil, err
+	}
+	result := &RotateSecretResult{Plan: plan, Consumers: consumers}
+
+	if opts.Output != nil {
+		e.printPlanSummary(opts.Output, plan)
+	}
+	if opts.DryRun {
+		result.Success = true
+		result.Duration = time.Since(startTime)
+		return result, nil
+	}
+
+	dcVars := datacenter.ResolveVariables(dc, dcState.Variables, opts.Environment)
+	compVars := make(map[string]interface{}, len(compState.Variables))
+	for k, v := range compState.Variables {
+		compVars[k] = v
+	}
+
+	execOpts := executor.Options{
+		Parallelism:         1,
+		Output:              opts.Output,
+		StopOnError:         true,
+		OnProgress:          opts.OnProgress,
+		Datacenter:          dc,
+		DatacenterVariables: dcVars,
+		EnvironmentModules:  environmentModuleOutputs(envState),
+		EnvironmentLabels:   environmentLabels(envState),
+		ComponentSources:    map[string]string{opts.Component: compState.Source},
+		ComponentVariables:  map[string]map[string]interface{}{opts.Component: compVars},
+	}
+
+	// Retiring the old value destroys the resources its hook created, using
+	// the state recorded when it was minted
+	retire := func() error {
+		node := graph.NewNode(secret.Type, secret.Component, secret.Name)
+		node.Inputs = previous.Inputs
+		retireGraph := graph.NewGraph(opts.Environment, opts.Datacenter)
+		_ = retireGraph.AddNode(node)
+		retirePlan := &planner.Plan{
+			Environment: opts.Environment,
+			Datacenter:  opts.Datacenter,
+			ToDelete:    1,
+			Changes: []*planner.ResourceChange{
+				{Node: node, Action: planner.ActionDelete, CurrentState: previous, Reason: "retiring rotated secret"},
+			},
+		}
+		retireOpts := execOpts
+		retireOpts.OnProgress = nil
+		execResult, err := executor.NewExecutor(e.stateManager, e.iacRegistry, retireOpts).Execute(ctx, retirePlan, retireGraph)
+		if err != nil {
+			return err
+		}
+		if !execResult.Success {
+			return fmt.Errorf("failed to retire the old value of secret %s: %v", opts.Secret, execResult.Errors)
+		}
+		return nil
+	}
+
+	if !opts.TwoPhase {
+		if err := retire(); err != nil {
+			return nil, err
+		}
+	}
+
+	execResult, err := executor.NewExecutor(e.stateManager, e.iacRegistry, execOpts).Execute(ctx, plan, g)
+	if err != nil {
+		return nil, fmt.Errorf("execution failed: %w", err)
+	}
+	result.Execution = execResult
+
+	if execResult.Success && opts.TwoPhase {
+		if err := e.retireKeepingReplacement(ctx, opts, secret, previous, retire); err != nil {
+			execResult.Success = false
+			execResult.Errors = append(execResult.Errors, err)
+		}
+	}
+
+	result.Success = execResult.Success
+	result.Duration = time.Since(startTime)
+	return result, nil
+}
+
+// retireKeepingReplacement retires the old value of a secret after a
+// two-phase rotation. Destroying a resource works from, and then removes, the
+// state recorded for it, so the old value's state stands in for the new
+// one's while it's retired, and the new one's is put back afterwards.
+func (e *Engine) retireKeepingReplacement(ctx context.Context, opts RotateSecretOptions, secret *graph.Node, previous *types.ResourceState, retire func() error) error {
+	key := string(secret.Type) + "." + secret.Name
+	swap := func(rs *types.ResourceState) (*types.ResourceState, error) {
+		envState, err := e.stateManager.GetEnvironment(ctx, opts.Datacenter, opts.Environment)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read environment state: %w", err)
+		}
+		compState := envState.Components[opts.Component]
+		if compState == nil {
+			return nil, fmt.Errorf("component %q missing from environment state", opts.Component)
+		}
+		if compState.Resources == nil {
+			compState.Resources = make(map[string]*types.ResourceState)
+		}
+		current := compState.Resources[key]
+		compState.Resources[key] = rs
+		if err := e.stateManager.SaveEnvironment(ctx, opts.Datacenter, envState); err != nil {
+			return nil, fmt.Errorf("failed to save state of secret %s: %w", opts.Secret, err)
+		}
+		return current, nil
+	}
+
+	replacement, err := swap(previous)
+	if err != nil {
+		return err
+	}
+	retireErr := retire()
+	if _, err := swap(replacement); err != nil {
+		return err
+	}
+	return retireErr
+}
+
+// planRotation plans the rotation of a secret: its node is replaced, and the
+// nodes that reference it in their expressions are updated with the new
+// value. Every other node of the component is left as deployed, and must
+// already be, since its outputs are read from state.
+func planRotation(g *graph.Graph, compState *types.ComponentState, secret *graph.Node) (*planner.Plan, []string, error) {
+	consumers := make(map[string]bool, len(secret.DependedOnBy))
+	for _, id := range secret.DependedOnBy {
+		if node := g.GetNode(id); node != nil && node.Type != graph.NodeTypeTask {
+			consumers[id] = true
+		}
+	}
+
+	sorted, err := g.TopologicalSort()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plan := &planner.Plan{Environment: g.Environment, Datacenter: g.Datacenter}
+	for _, node := range sorted {
+		current := compState.Resources[string(node.Type)+"."+node.Name]
+		change := &planner.ResourceChange{Node: node, CurrentState: current}
+		switch {
+		case node.ID == secret.ID:
+			// No prior state, so the hook mints a new value rather than
+			// reusing what it created before
+			change.CurrentState = nil
+			change.Action = planner.ActionReplace
+			change.Reason = "rotating secret"
+			plan.ToUpdate++
+		case node.Type == graph.NodeTypeTask:
+			// Tasks such as migrations aren't re-run
+			change.Action = planner.ActionNoop
+			change.Reason = "task is not re-run"
+			plan.NoChange++
+		case current == nil:
+			return nil, nil, fmt.Errorf("%s is not deployed; deploy component %s before rotating its secrets", node.ID, node.Component)
+		case consumers[node.ID] && current.Paused:
+			// Paused resources are being managed by hand
+			delete(consumers, node.ID)
+			change.Action = planner.ActionNoop
+			change.Reason = "resource is paused"
+			plan.NoChange++
+		case consumers[node.ID]:
+			change.Action = planner.ActionUpdate
+			change.Reason = "consumes rotated secret"
+			plan.ToUpdate++
+		default:
+			change.Action = planner.ActionNoop
+			change.Reason = "resource is up to date"
+			plan.NoChange++
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	ids := make([]string, 0, len(consumers))
+	for id := range consumers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return plan, ids, nil
+}
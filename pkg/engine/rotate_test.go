@@ -0,0 +1,220 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+const rotateTestDatacenter = `
+environment {
+  encryptionKey {
+    module "keygen" {
+      plugin = "rotator"
+      build  = "./modules/keygen"
+    }
+    outputs = {
+      key = module.keygen.value
+    }
+  }
+
+  deployment {
+    module "deploy" {
+      plugin = "rotator"
+      build  = "./modules/deploy"
+      inputs = {
+        name        = node.name
+        environment = node.inputs.environment
+      }
+    }
+    outputs = {
+      id = module.deploy.value
+    }
+  }
+}
+`
+
+const rotateTestComponent = `
+encryptionKeys:
+  session:
+    type: symmetric
+    bytes: 32
+deployments:
+  api:
+    image: api:latest
+    environment:
+      SESSION_KEY: ${{ encryptionKeys.session.key }}
+  worker:
+    image: worker:latest
+`
+
+// rotator is an IaC plugin that mints a new value on each keygen apply, and
+// records the order of applies and destroys.
+type rotator struct {
+	events  []string
+	minted  int
+	applied map[string]map[string]interface{}
+}
+
+func (p *rotator) Name() string { return "rotator" }
+
+func (p *rotator) Preview(ctx context.Context, opts iac.RunOptions) (*iac.PreviewResult, error) {
+	return &iac.PreviewResult{}, nil
+}
+
+func (p *rotator) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResult, error) {
+	name := filepath.Base(opts.ModuleSource)
+	value := fmt.Sprint(opts.Inputs["name"])
+	if name == "keygen" {
+		p.minted++
+		value = fmt.Sprintf("key-%d", p.minted)
+	}
+	p.events = append(p.events, "apply "+value)
+	p.applied[value] = opts.Inputs
+	return &iac.ApplyResult{
+		Outputs: map[string]iac.OutputValue{"value": {Value: value}},
+		State:   []byte(value),
+	}, nil
+}
+
+func (p *rotator) Destroy(ctx context.Context, opts iac.RunOptions) error {
+	state, _ := io.ReadAll(opts.StateReader)
+	p.events = append(p.events, "destroy "+string(state))
+	return nil
+}
+
+func (p *rotator) Refresh(ctx context.Context, opts iac.RunOptions) (*iac.RefreshResult, error) {
+	return &iac.RefreshResult{}, nil
+}
+
+func (p *rotator) Import(ctx context.Context, opts iac.ImportOptions) (*iac.ImportResult, error) {
+	return &iac.ImportResult{}, nil
+}
+
+func newRotateTestEngine(t *testing.T) (*Engine, *mockStateManager, *rotator) {
+	t.Helper()
+	dir := t.TempDir()
+	dcFile := filepath.Join(dir, "datacenter.dc")
+	compFile := filepath.Join(dir, "cld.yml")
+	if err := os.WriteFile(dcFile, []byte(rotateTestDatacenter), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(compFile, []byte(rotateTestComponent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := newMockStateManager()
+	mgr.datacenter = &types.DatacenterState{Name: "dc", Version: dcFile}
+	deployed := func(resType, name, value string) *types.ResourceState {
+		return &types.ResourceState{
+			Component: "app",
+			Name:      name,
+			Type:      resType,
+			Status:    types.ResourceStatusReady,
+			Outputs:   map[string]interface{}{"key": value, "id": value},
+			ModuleStates: map[string]*types.ModuleState{
+				"m": {Name: "m", Plugin: "rotator", Source: "/modules/m", IaCState: []byte(value), Status: types.ModuleStatusReady},
+			},
+		}
+	}
+	_ = mgr.SaveEnvironment(context.Background(), "dc", &types.EnvironmentState{
+		Name:       "staging",
+		Datacenter: "dc",
+		Components: map[string]*types.ComponentState{
+			"app": {
+				Name:   "app",
+				Source: compFile,
+				Resources: map[string]*types.ResourceState{
+					"encryptionKey.session": deployed("encryptionKey", "session", "key-0"),
+					"deployment.api":        deployed("deployment", "api", "api"),
+					"deployment.worker":     deployed("deployment", "worker", "worker"),
+				},
+			},
+		},
+	})
+
+	plugin := &rotator{applied: map[string]map[string]interface{}{}}
+	registry := iac.NewRegistry()
+	registry.Register("rotator", func() (iac.Plugin, error) { return plugin, nil })
+	return NewEngine(mgr, registry), mgr, plugin
+}
+
+func TestRotateSecret(t *testing.T) {
+	tests := []struct {
+		name     string
+		twoPhase bool
+		want     string
+	}{
+		{"retires old value first", false, "[destroy key-0 apply key-1 apply api]"},
+		{"two-phase retires old value last", true, "[apply key-1 apply api destroy key-0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eng, mgr, plugin := newRotateTestEngine(t)
+			result, err := eng.RotateSecret(context.Background(), RotateSecretOptions{
+				Environment: "staging",
+				Datacenter:  "dc",
+				Component:   "app",
+				Secret:      "session",
+				TwoPhase:    tt.twoPhase,
+			})
+			if err != nil {
+				t.Fatalf("RotateSecret failed: %v", err)
+			}
+			if !result.Success {
+				t.Fatalf("expected success, got %v", result.Execution.Errors)
+			}
+
+			if got := fmt.Sprint(plugin.events); got != tt.want {
+				t.Errorf("events = %s, want %s", got, tt.want)
+			}
+			if fmt.Sprint(result.Consumers) != "[app/deployment/api]" {
+				t.Errorf("consumers = %v, want only the deployment that references the key", result.Consumers)
+			}
+			if env := fmt.Sprint(plugin.applied["api"]["environment"]); env != "map[SESSION_KEY:key-1]" {
+				t.Errorf("expected the consumer to get the new value, got %s", env)
+			}
+
+			key := mgr.environments["staging"].Components["app"].Resources["encryptionKey.session"]
+			if key == nil || key.Outputs["key"] != "key-1" {
+				t.Errorf("expected the new value to be recorded, got %+v", key)
+			}
+		})
+	}
+}
+
+func TestRotateSecret_DryRun(t *testing.T) {
+	eng, _, plugin := newRotateTestEngine(t)
+	result, err := eng.RotateSecret(context.Background(), RotateSecretOptions{
+		Environment: "staging",
+		Datacenter:  "dc",
+		Component:   "app",
+		Secret:      "session",
+		DryRun:      true,
+	})
+	if err != nil {
+		t.Fatalf("RotateSecret failed: %v", err)
+	}
+	if len(plugin.events) != 0 {
+		t.Errorf("expected a dry run not to apply anything, got %v", plugin.events)
+	}
+	if result.Plan.ToUpdate != 2 || result.Plan.NoChange != 1 {
+		t.Errorf("expected the key and its consumer to be planned, got %d to update, %d unchanged", result.Plan.ToUpdate, result.Plan.NoChange)
+	}
+
+	if _, err := eng.RotateSecret(context.Background(), RotateSecretOptions{
+		Environment: "staging",
+		Datacenter:  "dc",
+		Component:   "app",
+		Secret:      "missing",
+		DryRun:      true,
+	}); err == nil {
+		t.Error("expected an error for an undeclared key")
+	}
+}
@@ -0,0 +1,233 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/engine/executor"
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// RouteConflict describes routes of different components that would be
+// published at the same subdomain (or explicit host) and path prefix.
+type RouteConflict struct {
+	Subdomain  string
+	Host       string
+	PathPrefix string
+
+	// Routes are the claiming routes as "<component>/<route>"
+	Routes []string
+}
+
+// RouteConflictError is returned when routes in an environment collide.
+type RouteConflictError struct {
+	Environment string
+	Conflicts   []RouteConflict
+}
+
+func (e *RouteConflictError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "conflicting routes in environment %s:", e.Environment)
+	for _, c := range e.Conflicts {
+		if c.Host != "" {
+			fmt.Fprintf(&b, "\n  - host %q, path %s: claimed by %s", c.Host, c.PathPrefix, strings.Join(c.Routes, ", "))
+		} else {
+			fmt.Fprintf(&b, "\n  - subdomain %q, path %s: claimed by %s", c.Subdomain, c.PathPrefix, strings.Join(c.Routes, ", "))
+		}
+	}
+	b.WriteString("\nset a distinct subdomain or pathPrefix for these routes in the environment file")
+	return b.String()
+}
+
+// URLChange describes a published route whose address would change.
+type URLChange struct {
+	// Route is the route as "<component>/<route>"
+	Route string
+	From  string
+	To    string
+}
+
+// URLChangeError is returned when a deploy would move routes away from the
+// addresses they were published at and DeployOptions.AllowURLChange is not
+// set.
+type URLChangeError struct {
+	Environment string
+	Changes     []URLChange
+}
+
+func (e *URLChangeError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "deploying would change published route URLs in environment %s:", e.Environment)
+	for _, c := range e.Changes {
+		fmt.Fprintf(&b, "\n  - %s: %s -> %s", c.Route, c.From, c.To)
+	}
+	b.WriteString("\nrerun with --allow-url-change to proceed")
+	return b.String()
+}
+
+// plannedRoutes resolves the address of every route node in the graph the
+// same way the executor does: environment overrides, then the generated
+// subdomain recorded in state, then a newly generated subdomain.
+func plannedRoutes(g *graph.Graph, envName string, overrides map[string]map[string]RouteOverride, current *types.EnvironmentState) map[string]map[string]types.RouteState {
+	planned := make(map[string]map[string]types.RouteState)
+	for _, node := range g.Nodes {
+		if node.Type != graph.NodeTypeRoute {
+			continue
+		}
+		ro := overrides[node.Component][node.Name].executorOverride()
+		subdomain, pathPrefix, generated := executor.RouteAddress(envName, node.Component, node.Name, ro, executor.PublishedRoute(current, node.Component, node.Name))
+		if planned[node.Component] == nil {
+			planned[node.Component] = make(map[string]types.RouteState)
+		}
+		rs := types.RouteState{Subdomain: subdomain, PathPrefix: pathPrefix, Generated: generated}
+		if len(ro.Hostnames) > 0 {
+			rs.Hostnames = executor.RouteHostnames(ro, subdomain, pathPrefix)
+		}
+		planned[node.Component][node.Name] = rs
+	}
+	return planned
+}
+
+// checkRouteConflicts verifies that no two routes in the environment are
+// published at the same address. Every hostname of a route is an address it
+// claims; wildcards only collide with the identical wildcard. Routes of
+// components that are deployed but not part of this run are taken from the
+// addresses recorded in state.
+func checkRouteConflicts(g *graph.Graph, envName string, planned map[string]map[string]types.RouteState, current *types.EnvironmentState) error {
+	claims := make(map[string]*RouteConflict)
+	claim := func(subdomain, host, pathPrefix, owner string) {
+		pathPrefix = normalizePathPrefix(pathPrefix)
+		key := "subdomain:" + strings.ToLower(subdomain) + pathPrefix
+		if host != "" {
+			key = "host:" + strings.ToLower(host) + pathPrefix
+		}
+		c, ok := claims[key]
+		if !ok {
+			c = &RouteConflict{Subdomain: subdomain, Host: host, PathPrefix: pathPrefix}
+			claims[key] = c
+		}
+		// A route may list the same address as its subdomain and a hostname
+		for _, r := range c.Routes {
+			if r == owner {
+				return
+			}
+		}
+		c.Routes = append(c.Routes, owner)
+	}
+	claimRoute := func(rs types.RouteState, owner string) {
+		claim(rs.Subdomain, "", rs.PathPrefix, owner)
+		for _, h := range rs.Hostnames {
+			claim(h.Subdomain, h.Host, h.PathPrefix, owner)
+		}
+	}
+
+	for compName, routes := range planned {
+		for routeName, rs := range routes {
+			claimRoute(rs, compName+"/"+routeName)
+		}
+	}
+
+	if current != nil {
+		deploying := make(map[string]bool)
+		for _, node := range g.Nodes {
+			deploying[node.Component] = true
+		}
+		for compName, comp := range current.Components {
+			if deploying[compName] || comp == nil {
+				continue
+			}
+			for routeName, rs := range comp.Routes {
+				if rs == nil {
+					continue
+				}
+				claimRoute(*rs, compName+"/"+routeName)
+			}
+		}
+	}
+
+	var conflicts []RouteConflict
+	for _, c := range claims {
+		if len(c.Routes) > 1 {
+			sort.Strings(c.Routes)
+			conflicts = append(conflicts, *c)
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Host != conflicts[j].Host {
+			return conflicts[i].Host < conflicts[j].Host
+		}
+		if conflicts[i].Subdomain != conflicts[j].Subdomain {
+			return conflicts[i].Subdomain < conflicts[j].Subdomain
+		}
+		return conflicts[i].PathPrefix < conflicts[j].PathPrefix
+	})
+	return &RouteConflictError{Environment: envName, Conflicts: conflicts}
+}
+
+// checkURLChanges reports routes whose planned address differs from the
+// address they were previously published at.
+func checkURLChanges(envName string, planned map[string]map[string]types.RouteState, current *types.EnvironmentState) error {
+	var changes []URLChange
+	for compName, routes := range planned {
+		for routeName, rs := range routes {
+			published := executor.PublishedRoute(current, compName, routeName)
+			if published == nil {
+				continue
+			}
+			from := formatRouteAddress(published.Subdomain, published.PathPrefix)
+			to := formatRouteAddress(rs.Subdomain, rs.PathPrefix)
+			if !strings.EqualFold(from, to) {
+				changes = append(changes, URLChange{Route: compName + "/" + routeName, From: from, To: to})
+			}
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Route < changes[j].Route })
+	return &URLChangeError{Environment: envName, Changes: changes}
+}
+
+// normalizePathPrefix makes equivalent prefixes compare equal ("api",
+// "/api", and "/api/" are the same prefix).
+func normalizePathPrefix(p string) string {
+	return "/" + strings.Trim(p, "/")
+}
+
+// formatRouteAddress renders a route address as "<subdomain>/<path>".
+func formatRouteAddress(subdomain, pathPrefix string) string {
+	return subdomain + normalizePathPrefix(pathPrefix)
+}
+
+// checkRouteAuthHooks fails when a route requires authentication but no
+// routeAuth hook in the datacenter matches it. Protected routes are never
+// published without their proxy.
+func checkRouteAuthHooks(g *graph.Graph, dc datacenter.Datacenter) error {
+	authNodes := g.GetNodesByType(graph.NodeTypeRouteAuth)
+	if len(authNodes) == 0 {
+		return nil
+	}
+	var hooks []datacenter.Hook
+	if env := dc.Environment(); env != nil && env.Hooks() != nil {
+		hooks = env.Hooks().RouteAuth()
+	}
+	matches := makeHookFilter(hooks)
+
+	var unsupported []string
+	for _, n := range authNodes {
+		if !matches(n.Inputs) {
+			unsupported = append(unsupported, n.Component+"/"+n.Name)
+		}
+	}
+	if len(unsupported) == 0 {
+		return nil
+	}
+	sort.Strings(unsupported)
+	return fmt.Errorf("routes require authentication but the datacenter has no matching routeAuth hook: %s", strings.Join(unsupported, ", "))
+}
@@ -0,0 +1,251 @@
+package engine
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/davidthor/cldctl/pkg/names"
+	"github.com/davidthor/cldctl/pkg/schema/datacenter"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func routeGraph(routes ...[2]string) *graph.Graph {
+	g := graph.NewGraph("staging", "dc")
+	for _, r := range routes {
+		_ = g.AddNode(graph.NewNode(graph.NodeTypeRoute, r[0], r[1]))
+	}
+	return g
+}
+
+func TestCheckRouteConflicts(t *testing.T) {
+	tests := []struct {
+		name      string
+		graph     *graph.Graph
+		overrides map[string]map[string]RouteOverride
+		current   *types.EnvironmentState
+		want      []string // expected claimants of the single conflict, or nil
+	}{
+		{
+			name:  "generated subdomains do not collide",
+			graph: routeGraph([2]string{"api", "main"}, [2]string{"web", "main"}),
+		},
+		{
+			name:  "same subdomain and path",
+			graph: routeGraph([2]string{"api", "main"}, [2]string{"web", "main"}),
+			overrides: map[string]map[string]RouteOverride{
+				"api": {"main": {Subdomain: "app"}},
+				"web": {"main": {Subdomain: "app", PathPrefix: "/"}},
+			},
+			want: []string{"api/main", "web/main"},
+		},
+		{
+			name:  "same subdomain with different paths",
+			graph: routeGraph([2]string{"api", "main"}, [2]string{"web", "main"}),
+			overrides: map[string]map[string]RouteOverride{
+				"api": {"main": {Subdomain: "app", PathPrefix: "/api"}},
+				"web": {"main": {Subdomain: "app"}},
+			},
+		},
+		{
+			name:  "equivalent path prefixes",
+			graph: routeGraph([2]string{"api", "main"}, [2]string{"web", "main"}),
+			overrides: map[string]map[string]RouteOverride{
+				"api": {"main": {Subdomain: "app", PathPrefix: "/api/"}},
+				"web": {"main": {Subdomain: "APP", PathPrefix: "api"}},
+			},
+			want: []string{"api/main", "web/main"},
+		},
+		{
+			name:  "override collides with another component's generated name",
+			graph: routeGraph([2]string{"api", "main"}, [2]string{"web", "main"}),
+			overrides: map[string]map[string]RouteOverride{
+				"api": {"main": {Subdomain: names.Generate("staging", "web", "main")}},
+			},
+			want: []string{"api/main", "web/main"},
+		},
+		{
+			name:  "same explicit host and path",
+			graph: routeGraph([2]string{"api", "main"}, [2]string{"web", "main"}),
+			overrides: map[string]map[string]RouteOverride{
+				"api": {"main": {Hostnames: []RouteHostname{{Host: "app.example.com", PathPrefix: "/api"}}}},
+				"web": {"main": {PathPrefix: "/api", Hostnames: []RouteHostname{{Host: "www.example.com"}, {Host: "App.example.com"}}}},
+			},
+			want: []string{"api/main", "web/main"},
+		},
+		{
+			name:  "wildcard and specific host share a path",
+			graph: routeGraph([2]string{"api", "main"}, [2]string{"web", "main"}),
+			overrides: map[string]map[string]RouteOverride{
+				"api": {"main": {Hostnames: []RouteHostname{{Subdomain: "*.preview"}}}},
+				"web": {"main": {Hostnames: []RouteHostname{{Subdomain: "docs.preview"}}}},
+			},
+		},
+		{
+			name:  "hostname repeats the route's own subdomain",
+			graph: routeGraph([2]string{"api", "main"}),
+			overrides: map[string]map[string]RouteOverride{
+				"api": {"main": {Subdomain: "app", Hostnames: []RouteHostname{{Subdomain: "app"}, {Subdomain: "www"}}}},
+			},
+		},
+		{
+			name:  "collides with a deployed component",
+			graph: routeGraph([2]string{"api", "main"}),
+			overrides: map[string]map[string]RouteOverride{
+				"api": {"main": {Subdomain: "app"}},
+			},
+			current: &types.EnvironmentState{Components: map[string]*types.ComponentState{
+				"web": {Routes: map[string]*types.RouteState{"public": {Subdomain: "app", PathPrefix: "/"}}},
+			}},
+			want: []string{"api/main", "web/public"},
+		},
+		{
+			name:  "redeployed component ignores its own recorded routes",
+			graph: routeGraph([2]string{"api", "main"}),
+			overrides: map[string]map[string]RouteOverride{
+				"api": {"main": {Subdomain: "app"}},
+			},
+			current: &types.EnvironmentState{Components: map[string]*types.ComponentState{
+				"api": {Routes: map[string]*types.RouteState{"main": {Subdomain: "old", PathPrefix: "/"}}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			planned := plannedRoutes(tt.graph, "staging", tt.overrides, tt.current)
+			err := checkRouteConflicts(tt.graph, "staging", planned, tt.current)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var conflictErr *RouteConflictError
+			if !errors.As(err, &conflictErr) {
+				t.Fatalf("expected RouteConflictError, got %v", err)
+			}
+			if len(conflictErr.Conflicts) != 1 {
+				t.Fatalf("expected one conflict, got %+v", conflictErr.Conflicts)
+			}
+			if got := strings.Join(conflictErr.Conflicts[0].Routes, ","); got != strings.Join(tt.want, ",") {
+				t.Errorf("expected claimants %v, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPlannedRoutes_ReusesPublishedGeneratedSubdomain(t *testing.T) {
+	g := routeGraph([2]string{"api", "main"}, [2]string{"api", "admin"})
+	current := &types.EnvironmentState{Components: map[string]*types.ComponentState{
+		"api": {Routes: map[string]*types.RouteState{
+			"main":  {Subdomain: "brave-falcon", PathPrefix: "/", Generated: true},
+			"admin": {Subdomain: "ops", PathPrefix: "/"},
+		}},
+	}}
+
+	planned := plannedRoutes(g, "staging", nil, current)
+
+	if rs := planned["api"]["main"]; rs.Subdomain != "brave-falcon" || !rs.Generated {
+		t.Errorf("expected published generated subdomain to be reused, got %+v", rs)
+	}
+	// An override that was removed falls back to a generated subdomain
+	if rs := planned["api"]["admin"]; rs.Subdomain != names.Generate("staging", "api", "admin") {
+		t.Errorf("expected generated subdomain once the override is gone, got %+v", rs)
+	}
+}
+
+func TestCheckURLChanges(t *testing.T) {
+	g := routeGraph([2]string{"api", "main"}, [2]string{"api", "docs"}, [2]string{"api", "new"})
+	current := &types.EnvironmentState{Components: map[string]*types.ComponentState{
+		"api": {Routes: map[string]*types.RouteState{
+			"main": {Subdomain: "app", PathPrefix: "/"},
+			"docs": {Subdomain: "app", PathPrefix: "/docs"},
+		}},
+	}}
+	overrides := map[string]map[string]RouteOverride{
+		"api": {
+			"main": {Subdomain: "web"},
+			"docs": {Subdomain: "app", PathPrefix: "/docs/"},
+		},
+	}
+
+	err := checkURLChanges("staging", plannedRoutes(g, "staging", overrides, current), current)
+
+	var changeErr *URLChangeError
+	if !errors.As(err, &changeErr) {
+		t.Fatalf("expected URLChangeError, got %v", err)
+	}
+	if len(changeErr.Changes) != 1 {
+		t.Fatalf("expected one change, got %+v", changeErr.Changes)
+	}
+	if c := changeErr.Changes[0]; c.Route != "api/main" || c.From != "app/" || c.To != "web/" {
+		t.Errorf("unexpected change: %+v", c)
+	}
+	if !strings.Contains(err.Error(), "--allow-url-change") {
+		t.Errorf("expected error to mention --allow-url-change, got %q", err.Error())
+	}
+}
+
+func TestCheckRouteAuthHooks(t *testing.T) {
+	authGraph := func(provider string) *graph.Graph {
+		g := routeGraph([2]string{"admin", "main"})
+		node := graph.NewNode(graph.NodeTypeRouteAuth, "admin", "main")
+		node.SetInput("provider", provider)
+		_ = g.AddNode(node)
+		return g
+	}
+	loadDC := func(t *testing.T, hcl string) datacenter.Datacenter {
+		t.Helper()
+		dc, err := datacenter.NewLoader().LoadFromBytes([]byte(hcl), "/tmp/dc/datacenter.dc")
+		if err != nil {
+			t.Fatalf("failed to load datacenter: %v", err)
+		}
+		return dc
+	}
+
+	noHooks := loadDC(t, `
+environment {
+  route {
+    module "ingress" {
+      build = "./ingress"
+    }
+    outputs = {
+      url  = "http://localhost"
+      host = "localhost"
+      port = 80
+    }
+  }
+}
+`)
+	githubOnly := loadDC(t, `
+environment {
+  routeAuth {
+    when = node.inputs.provider == "github"
+    module "proxy" {
+      build = "./proxy"
+    }
+    outputs = {
+      url  = "http://proxy"
+      host = "proxy"
+      port = 4180
+    }
+  }
+}
+`)
+
+	if err := checkRouteAuthHooks(routeGraph([2]string{"web", "main"}), noHooks); err != nil {
+		t.Errorf("expected no error without protected routes, got %v", err)
+	}
+	if err := checkRouteAuthHooks(authGraph("github"), noHooks); err == nil || !strings.Contains(err.Error(), "admin/main") {
+		t.Errorf("expected missing hook error naming admin/main, got %v", err)
+	}
+	if err := checkRouteAuthHooks(authGraph("github"), githubOnly); err != nil {
+		t.Errorf("expected matching hook to pass, got %v", err)
+	}
+	if err := checkRouteAuthHooks(authGraph("google"), githubOnly); err == nil {
+		t.Error("expected error when no hook matches the provider")
+	}
+}
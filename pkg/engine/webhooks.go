@@ -0,0 +1,265 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/schema/component"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// Component lifecycle webhook events.
+const (
+	WebhookEventDeploy  = "deploy"
+	WebhookEventDestroy = "destroy"
+)
+
+// defaultWebhookTimeout bounds a webhook request when the component doesn't
+// set a timeout.
+const defaultWebhookTimeout = 10 * time.Second
+
+// Headers sent with each webhook request. The signature is the hex-encoded
+// HMAC-SHA256 of the request body, keyed with the webhook's secret.
+const (
+	WebhookEventHeader     = "X-Cldctl-Event"
+	WebhookSignatureHeader = "X-Cldctl-Signature"
+)
+
+// WebhookPayload is the JSON body POSTed to a component's lifecycle webhooks.
+type WebhookPayload struct {
+	Event       string             `json:"event"`
+	Timestamp   time.Time          `json:"timestamp"`
+	Datacenter  string             `json:"datacenter"`
+	Environment WebhookEnvironment `json:"environment"`
+	Component   WebhookComponent   `json:"component"`
+}
+
+// WebhookEnvironment describes the environment in a WebhookPayload.
+type WebhookEnvironment struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// WebhookComponent describes the component in a WebhookPayload.
+type WebhookComponent struct {
+	Name    string                 `json:"name"`
+	Outputs map[string]interface{} `json:"outputs,omitempty"`
+	Routes  map[string]string      `json:"routes,omitempty"` // Route name to URL
+}
+
+// componentWebhooks returns the webhooks a component declares, to record in
+// its state.
+func componentWebhooks(comp component.Component) map[string]*types.WebhookState {
+	if len(comp.Webhooks()) == 0 {
+		return nil
+	}
+	webhooks := make(map[string]*types.WebhookState, len(comp.Webhooks()))
+	for _, w := range comp.Webhooks() {
+		webhooks[w.Name()] = &types.WebhookState{
+			URL:     w.URL(),
+			Events:  w.Events(),
+			Secret:  w.Secret(),
+			Timeout: w.Timeout(),
+		}
+	}
+	return webhooks
+}
+
+// runDeployWebhooks records the webhooks of each deployed component in its
+// state, then calls those subscribed to the deploy event.
+func (e *Engine) runDeployWebhooks(ctx context.Context, dcName, envName string, comps map[string]component.Component, output io.Writer) error {
+	envState, err := e.stateManager.GetEnvironment(ctx, dcName, envName)
+	if err != nil {
+		return fmt.Errorf("failed to read environment state: %w", err)
+	}
+
+	names := make([]string, 0, len(comps))
+	for name := range comps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var failed []string
+	for _, name := range names {
+		compState := envState.Components[name]
+		if compState == nil {
+			continue
+		}
+		webhooks := componentWebhooks(comps[name])
+		if len(webhooks) == 0 && len(compState.Webhooks) == 0 {
+			continue
+		}
+		compState.Webhooks = webhooks
+		if err := e.stateManager.SaveEnvironment(ctx, dcName, envState); err != nil {
+			return fmt.Errorf("failed to record webhooks of component %s: %w", name, err)
+		}
+		if err := callWebhooks(ctx, WebhookEventDeploy, dcName, envState, compState, output); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%s", strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+// callWebhooks calls the component's webhooks that subscribe to event, in
+// name order. Every webhook is called even if an earlier one fails; the
+// returned error lists the ones that did.
+func callWebhooks(ctx context.Context, event, dcName string, envState *types.EnvironmentState, compState *types.ComponentState, output io.Writer) error {
+	names := make([]string, 0, len(compState.Webhooks))
+	for name, w := range compState.Webhooks {
+		for _, e := range w.Events {
+			if e == event {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+
+	payload := WebhookPayload{
+		Event:      event,
+		Timestamp:  time.Now().UTC(),
+		Datacenter: dcName,
+		Environment: WebhookEnvironment{
+			Name:   envState.Name,
+			Labels: envState.Labels,
+		},
+		Component: WebhookComponent{
+			Name:    compState.Name,
+			Outputs: compState.Outputs,
+			Routes:  make(map[string]string),
+		},
+	}
+	for route, v := range componentRouteURLs(compState) {
+		if r, ok := v.(map[string]interface{}); ok {
+			payload.Component.Routes[route] = fmt.Sprintf("%v", r["url"])
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	var failed []string
+	for _, name := range names {
+		if err := callWebhook(ctx, event, envState.Name, compState, compState.Webhooks[name], body); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		if output != nil {
+			fmt.Fprintf(output, "  [webhook] %s: called %s webhook %q\n", compState.Name, event, name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%s webhooks of component %s failed: %s", event, compState.Name, strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// callWebhook POSTs body to a webhook, signed with its secret if it has one.
+func callWebhook(ctx context.Context, event, envName string, compState *types.ComponentState, w *types.WebhookState, body []byte) error {
+	url, err := resolveWebhookExpression(w.URL, envName, compState)
+	if err != nil {
+		return fmt.Errorf("url: %w", err)
+	}
+	secret, err := resolveWebhookExpression(w.Secret, envName, compState)
+	if err != nil {
+		return fmt.Errorf("secret: %w", err)
+	}
+
+	timeout := defaultWebhookTimeout
+	if w.Timeout != "" {
+		if d, err := time.ParseDuration(w.Timeout); err == nil && d > 0 {
+			timeout = d
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, event)
+	if secret != "" {
+		req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhookPayload(body, secret))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// SignWebhookPayload returns the hex-encoded HMAC-SHA256 of a webhook body,
+// as sent in the signature header, so receivers can verify requests.
+func SignWebhookPayload(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookExpressionPattern matches the ${{ }} references webhook URLs and
+// secrets can use.
+var webhookExpressionPattern = regexp.MustCompile(`\$\{\{\s*([A-Za-z]+)\.([A-Za-z0-9_-]+)(?:\.([A-Za-z0-9_-]+))?\s*\}\}`)
+
+// resolveWebhookExpression resolves ${{ }} references in a webhook's URL or
+// secret against the deployed component: environment.name,
+// variables.<name>, outputs.<name>, and routes.<name>.url.
+func resolveWebhookExpression(value, envName string, compState *types.ComponentState) (string, error) {
+	routes := componentRouteURLs(compState)
+
+	var resolveErr error
+	resolved := webhookExpressionPattern.ReplaceAllStringFunc(value, func(expr string) string {
+		m := webhookExpressionPattern.FindStringSubmatch(expr)
+		kind, name, field := m[1], m[2], m[3]
+
+		var v interface{}
+		var ok bool
+		switch {
+		case kind == "environment" && name == "name" && field == "":
+			v, ok = envName, true
+		case kind == "variables" && field == "":
+			v, ok = compState.Variables[name]
+		case kind == "outputs" && field == "":
+			v, ok = compState.Outputs[name]
+		case kind == "routes" && field == "url":
+			if route, found := routes[name].(map[string]interface{}); found {
+				v, ok = route["url"]
+			}
+		}
+		if !ok {
+			if resolveErr == nil {
+				resolveErr = fmt.Errorf("unable to resolve %s", expr)
+			}
+			return expr
+		}
+		return fmt.Sprintf("%v", v)
+	})
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+	return resolved, nil
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func TestCallWebhooks(t *testing.T) {
+	type request struct {
+		path, event, signature string
+		body                   []byte
+	}
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, request{r.URL.Path, r.Header.Get(WebhookEventHeader), r.Header.Get(WebhookSignatureHeader), body})
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	envState := &types.EnvironmentState{Name: "staging", Labels: map[string]string{"team": "payments"}}
+	compState := &types.ComponentState{
+		Name:      "api",
+		Variables: map[string]string{"license_secret": "s3cret"},
+		Outputs:   map[string]interface{}{"version": "1.2.0"},
+		Resources: map[string]*types.ResourceState{
+			"route.main": {Name: "main", Type: "route", Outputs: map[string]interface{}{"url": "https://api.example.com"}},
+		},
+		Webhooks: map[string]*types.WebhookState{
+			"license": {
+				URL:    server.URL + "/register/${{ environment.name }}",
+				Events: []string{WebhookEventDeploy, WebhookEventDestroy},
+				Secret: "${{ variables.license_secret }}",
+			},
+			"audit": {URL: server.URL + "/audit", Events: []string{WebhookEventDestroy}},
+		},
+	}
+
+	if err := callWebhooks(context.Background(), WebhookEventDeploy, "dc", envState, compState, nil); err != nil {
+		t.Fatalf("callWebhooks failed: %v", err)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("expected only the deploy webhook to be called, got %d requests", len(requests))
+	}
+	req := requests[0]
+	if req.path != "/register/staging" || req.event != WebhookEventDeploy {
+		t.Errorf("unexpected request: path %s, event %s", req.path, req.event)
+	}
+	if want := "sha256=" + SignWebhookPayload(req.body, "s3cret"); req.signature != want {
+		t.Errorf("signature = %q, want %q", req.signature, want)
+	}
+
+	var payload WebhookPayload
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Environment.Name != "staging" || payload.Environment.Labels["team"] != "payments" || payload.Datacenter != "dc" {
+		t.Errorf("unexpected environment in payload: %+v", payload)
+	}
+	if payload.Component.Outputs["version"] != "1.2.0" || payload.Component.Routes["main"] != "https://api.example.com" {
+		t.Errorf("unexpected component in payload: %+v", payload.Component)
+	}
+
+	// Unsigned webhooks send no signature, and failures are reported after
+	// every webhook is called
+	requests = nil
+	compState.Webhooks["audit"].URL = server.URL + "/fail"
+	err := callWebhooks(context.Background(), WebhookEventDestroy, "dc", envState, compState, nil)
+	if err == nil || !strings.Contains(err.Error(), "audit: unexpected status 500") {
+		t.Fatalf("expected the failed webhook to be reported, got %v", err)
+	}
+	if len(requests) != 2 || requests[0].signature != "" {
+		t.Errorf("expected both destroy webhooks to be called, the first unsigned, got %+v", requests)
+	}
+}
+
+func TestResolveWebhookExpression(t *testing.T) {
+	compState := &types.ComponentState{
+		Variables: map[string]string{"region": "us-east-1"},
+		Outputs:   map[string]interface{}{"id": "abc"},
+		Resources: map[string]*types.ResourceState{
+			"route.main": {Name: "main", Type: "route", Outputs: map[string]interface{}{"url": "https://api.example.com"}},
+		},
+	}
+
+	got, err := resolveWebhookExpression("https://hooks.example.com/${{ environment.name }}/${{ variables.region }}/${{ outputs.id }}?url=${{ routes.main.url }}", "staging", compState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://hooks.example.com/staging/us-east-1/abc?url=https://api.example.com"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := resolveWebhookExpression("${{ variables.missing }}", "staging", compState); err == nil {
+		t.Error("expected an unresolvable reference to fail")
+	}
+}
@@ -50,6 +50,15 @@ func Load(dir string, envName string) (map[string]string, error) {
 	return result, nil
 }
 
+// Parse parses the KEY=value lines of a single dotenv file.
+func Parse(data []byte) (map[string]string, error) {
+	vars := make(map[string]string)
+	if err := parseEnvFile(data, vars); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
 // parseEnvFile parses KEY=value lines from raw file bytes into the provided map.
 // Supports comments (#), empty lines, optional quoting, and export prefixes.
 func parseEnvFile(data []byte, vars map[string]string) error {
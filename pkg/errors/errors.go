@@ -2,6 +2,7 @@
 package errors
 
 import (
+	"errors"
 	"fmt"
 	"time"
 )
@@ -32,6 +33,23 @@ type Error struct {
 	Message string
 	Cause   error
 	Details map[string]interface{}
+
+	// Remediation tells the user how to resolve the error, if known
+	Remediation *Remediation
+}
+
+// Remediation is guidance for resolving an error: a code identifying the
+// condition, a link to documentation about it, and alternatives to use
+// instead. Any of them may be empty.
+type Remediation struct {
+	Code         string
+	DocsURL      string
+	Alternatives []string
+}
+
+// IsEmpty reports whether the remediation offers no guidance.
+func (r *Remediation) IsEmpty() bool {
+	return r == nil || (r.Code == "" && r.DocsURL == "" && len(r.Alternatives) == 0)
 }
 
 func (e *Error) Error() string {
@@ -78,6 +96,31 @@ func (e *Error) WithDetail(key string, value interface{}) *Error {
 	return e
 }
 
+// WithRemediation attaches remediation to an error. Empty remediation is
+// ignored.
+func (e *Error) WithRemediation(r *Remediation) *Error {
+	if !r.IsEmpty() {
+		e.Remediation = r
+	}
+	return e
+}
+
+// RemediationOf returns the remediation of the first *Error in err's chain
+// that has one, or nil.
+func RemediationOf(err error) *Remediation {
+	for err != nil {
+		var e *Error
+		if !errors.As(err, &e) {
+			return nil
+		}
+		if e.Remediation != nil {
+			return e.Remediation
+		}
+		err = e.Cause
+	}
+	return nil
+}
+
 // ValidationError creates a validation error
 func ValidationError(message string, details map[string]interface{}) *Error {
 	return &Error{
@@ -2,6 +2,7 @@ package errors
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -120,3 +121,22 @@ func TestParseError(t *testing.T) {
 		t.Error("expected file detail")
 	}
 }
+
+func TestRemediationOf(t *testing.T) {
+	remediation := &Remediation{Code: "MYSQL_UNSUPPORTED", Alternatives: []string{"postgres", "mariadb"}}
+	hookErr := DatacenterHookError("database", "my-app", "main", "mysql is not supported").WithRemediation(remediation)
+
+	if got := RemediationOf(fmt.Errorf("failed to apply: %w", hookErr)); got != remediation {
+		t.Errorf("expected remediation to be found through wrapping, got %v", got)
+	}
+	if got := RemediationOf(Wrap(ErrCodeIaC, "apply failed", hookErr)); got != remediation {
+		t.Errorf("expected remediation to be found through the cause, got %v", got)
+	}
+	if got := RemediationOf(errors.New("plain")); got != nil {
+		t.Errorf("expected no remediation, got %v", got)
+	}
+
+	if DatacenterHookError("database", "my-app", "main", "x").WithRemediation(&Remediation{}).Remediation != nil {
+		t.Error("expected empty remediation to be ignored")
+	}
+}
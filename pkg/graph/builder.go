@@ -2,10 +2,14 @@ package graph
 
 import (
 	"fmt"
+	"net/url"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/davidthor/cldctl/pkg/engine/expression"
 	"github.com/davidthor/cldctl/pkg/schema/component"
 )
 
@@ -29,6 +33,10 @@ type Builder struct {
 	// networkPolicy node should be created for a specific workload+service pair.
 	// If nil, no networkPolicy nodes are created.
 	networkPolicyFilter ImplicitNodeFilter
+
+	// platformExports, when non-nil, are the names the datacenter exports to
+	// components as ${{ platform.<name> }}. If nil, any name is accepted.
+	platformExports map[string]bool
 }
 
 // NewBuilder creates a new graph builder.
@@ -67,9 +75,22 @@ func (b *Builder) SetNetworkPolicyFilter(fn ImplicitNodeFilter) {
 	b.networkPolicyFilter = fn
 }
 
+// SetPlatformExports sets the names the datacenter exports to components, so
+// that references to anything else are rejected as the graph is built.
+func (b *Builder) SetPlatformExports(names []string) {
+	b.platformExports = make(map[string]bool, len(names))
+	for _, name := range names {
+		b.platformExports[name] = true
+	}
+}
+
 // AddComponent adds a component's resources to the graph.
 // The componentName is provided externally since component specs no longer contain names.
 func (b *Builder) AddComponent(componentName string, comp component.Component) error {
+	if err := validateReferences(componentName, comp, b.platformExports); err != nil {
+		return err
+	}
+
 	// Record inter-component dependencies.
 	// Required (non-optional) dependencies create hard edges for destroy protection
 	// and execution ordering. Optional dependencies are tracked separately so the
@@ -127,6 +148,9 @@ func (b *Builder) AddComponent(componentName string, comp component.Component) e
 		b.graph.ComponentOutputExprs[componentName] = outMap
 	}
 
+	// Record datacenter environment injection opt-outs for the executor.
+	b.recordInjectionExclusions(componentName, comp)
+
 	// Get the component's base directory for resolving relative paths
 	// This is crucial for OCI-pulled components where build contexts need to be
 	// resolved relative to the extracted artifact location
@@ -141,6 +165,7 @@ func (b *Builder) AddComponent(componentName string, comp component.Component) e
 			typeStr = typeStr + ":" + db.Version()
 		}
 		node.SetInput("type", typeStr)
+		node.Readiness = readinessGate(db.Readiness())
 
 		// Add migration node if migrations defined
 		if db.Migrations() != nil {
@@ -235,6 +260,11 @@ func (b *Builder) AddComponent(componentName string, comp component.Component) e
 		_ = b.graph.AddNode(node)
 	}
 
+	// Add external services
+	for _, ext := range comp.External() {
+		_ = b.graph.AddNode(newExternalNode(componentName, ext))
+	}
+
 	// Add ports (no dependencies - they are depended on by workloads/services via expressions)
 	for _, p := range comp.Ports() {
 		node := NewNode(NodeTypePort, componentName, p.Name())
@@ -246,6 +276,7 @@ func (b *Builder) AddComponent(componentName string, comp component.Component) e
 	// Add deployments
 	for _, deploy := range comp.Deployments() {
 		node := NewNode(NodeTypeDeployment, componentName, deploy.Name())
+		node.Readiness = readinessGate(deploy.Readiness())
 
 		if deploy.Image() != "" {
 			node.SetInput("image", deploy.Image())
@@ -271,10 +302,19 @@ func (b *Builder) AddComponent(componentName string, comp component.Component) e
 		}
 		node.SetInput("command", deploy.Command())
 		node.SetInput("entrypoint", deploy.Entrypoint())
-		node.SetInput("environment", deploy.Environment())
+		node.SetInput("environment", workloadEnvironment(deploy.EnvFrom(), deploy.Environment()))
 		node.SetInput("cpu", deploy.CPU())
 		node.SetInput("memory", deploy.Memory())
 		node.SetInput("replicas", deploy.Replicas())
+		if strategyMap := strategyToMap(deploy.Strategy()); strategyMap != nil {
+			node.SetInput("strategy", strategyMap)
+		}
+		if lifecycleMap := lifecycleToMap(deploy.Lifecycle()); lifecycleMap != nil {
+			node.SetInput("lifecycle", lifecycleMap)
+		}
+		if files := filesToList(deploy.Files()); files != nil {
+			node.SetInput("files", files)
+		}
 		if probeMap := probeToMap(deploy.LivenessProbe()); probeMap != nil {
 			node.SetInput("liveness_probe", probeMap)
 		}
@@ -293,9 +333,10 @@ func (b *Builder) AddComponent(componentName string, comp component.Component) e
 	// Add functions
 	for _, fn := range comp.Functions() {
 		node := NewNode(NodeTypeFunction, componentName, fn.Name())
+		node.Readiness = readinessGate(fn.Readiness())
 
 		// Set common fields
-		node.SetInput("environment", fn.Environment())
+		node.SetInput("environment", workloadEnvironment(fn.EnvFrom(), fn.Environment()))
 		node.SetInput("cpu", fn.CPU())
 		node.SetInput("memory", fn.Memory())
 		node.SetInput("timeout", fn.Timeout())
@@ -345,6 +386,7 @@ func (b *Builder) AddComponent(componentName string, comp component.Component) e
 	// that routes to pods matching a selector. The pods don't need to exist yet.
 	for _, svc := range comp.Services() {
 		node := NewNode(NodeTypeService, componentName, svc.Name())
+		node.Readiness = readinessGate(svc.Readiness())
 		node.SetInput("port", svc.Port())
 		node.SetInput("protocol", svc.Protocol())
 
@@ -426,6 +468,10 @@ func (b *Builder) AddComponent(componentName string, comp component.Component) e
 				}
 			}
 		}
+
+		if route.Auth() != nil {
+			b.addRouteAuthNode(componentName, node, route.Auth())
+		}
 	}
 
 	// Add observability node if component has observability configured.
@@ -479,13 +525,16 @@ func (b *Builder) AddComponent(componentName string, comp component.Component) e
 		if node == nil {
 			continue
 		}
-		for _, value := range deploy.Environment() {
+		for _, value := range workloadEnvironment(deploy.EnvFrom(), deploy.Environment()) {
 			b.addEnvDependencies(componentName, node, value)
 		}
 		// Scan image field for expressions like ${{ builds.api.image }}
 		if deploy.Image() != "" {
 			b.addEnvDependencies(componentName, node, deploy.Image())
 		}
+		for _, f := range deploy.Files() {
+			b.addEnvDependencies(componentName, node, f.Content())
+		}
 		// Make workload depend on observability node so OTel config is resolved first
 		if obsNodeID != "" {
 			obsNode := b.graph.GetNode(obsNodeID)
@@ -502,7 +551,7 @@ func (b *Builder) AddComponent(componentName string, comp component.Component) e
 		if node == nil {
 			continue
 		}
-		for _, value := range fn.Environment() {
+		for _, value := range workloadEnvironment(fn.EnvFrom(), fn.Environment()) {
 			b.addEnvDependencies(componentName, node, value)
 		}
 		// Scan port field for expression dependencies (e.g., ${{ ports.web.port }})
@@ -550,6 +599,13 @@ func (b *Builder) AddComponent(componentName string, comp component.Component) e
 		}
 	}
 
+	// Scan readiness gates for expression dependencies
+	for _, node := range b.gatedNodes(componentName) {
+		for _, value := range node.Readiness.Values() {
+			b.addEnvDependencies(componentName, node, value)
+		}
+	}
+
 	// Scan migration task environment variables for expression dependencies.
 	// Migration tasks may reference resources beyond their parent database
 	// (e.g., ${{ databases.redis.url }}) and need those nodes complete before running.
@@ -586,15 +642,37 @@ func (b *Builder) AddComponent(componentName string, comp component.Component) e
 	return nil
 }
 
+// recordInjectionExclusions stores the datacenter environment injections a
+// component opts out of so the executor can skip them for its workloads.
+func (b *Builder) recordInjectionExclusions(componentName string, comp component.Component) {
+	inj := comp.Injection()
+	if inj == nil {
+		return
+	}
+	exclude := inj.Exclude()
+	if inj.Disabled() {
+		exclude = []string{"*"}
+	}
+	if len(exclude) == 0 {
+		return
+	}
+	if b.graph.InjectionExclusions == nil {
+		b.graph.InjectionExclusions = make(map[string][]string)
+	}
+	b.graph.InjectionExclusions[componentName] = exclude
+}
+
 // addEnvDependencies parses an environment variable value and adds dependencies
 // with proper bidirectional relationships.
 // When a workload references a database, a databaseUser node is interposed.
-// When a workload references a service, a networkPolicy leaf node is created.
+// When a workload references a service or external service, a networkPolicy
+// leaf node is created.
 func (b *Builder) addEnvDependencies(componentName string, node *Node, value string) {
 	deps := extractDependencies(value)
 	for _, dep := range deps {
-		depNodeID := b.resolveDepReference(componentName, dep)
-		if depNodeID == "" {
+		depNodeID := referenceNodeID(componentName, dep)
+		// A readiness gate may read its own resource's outputs
+		if depNodeID == "" || depNodeID == node.ID {
 			continue
 		}
 		// Only add dependency if target node exists
@@ -635,8 +713,9 @@ func (b *Builder) addEnvDependencies(componentName string, node *Node, value str
 
 		// Implicit networkPolicy creation: when a workload references a service
 		// and the datacenter defines a matching networkPolicy hook, create a
-		// networkPolicy leaf node capturing the from/to relationship.
-		if depNode.Type == NodeTypeService && IsWorkloadType(node.Type) && b.shouldCreateNetworkPolicy(node, depNode) {
+		// networkPolicy leaf node capturing the from/to relationship. References
+		// to external services produce egress policies the same way.
+		if (depNode.Type == NodeTypeService || depNode.Type == NodeTypeExternal) && IsWorkloadType(node.Type) && b.shouldCreateNetworkPolicy(node, depNode) {
 			b.getOrCreateNetworkPolicyNode(componentName, node, depNode)
 		}
 
@@ -677,16 +756,20 @@ func (b *Builder) shouldCreateDatabaseUser(dbNode, consumerNode *Node) bool {
 }
 
 // shouldCreateNetworkPolicy checks whether a networkPolicy implicit node should
-// be created for the given workload→service pair.
-func (b *Builder) shouldCreateNetworkPolicy(fromNode, toServiceNode *Node) bool {
+// be created for the given workload→service or workload→external pair.
+func (b *Builder) shouldCreateNetworkPolicy(fromNode, toNode *Node) bool {
 	if b.networkPolicyFilter == nil {
 		return false
 	}
 	inputs := map[string]interface{}{
 		"from":     fromNode.Name,
 		"fromType": string(fromNode.Type),
-		"to":       toServiceNode.Name,
-		"toType":   string(toServiceNode.Type),
+		"to":       toNode.Name,
+		"toType":   string(toNode.Type),
+	}
+	if toNode.Type == NodeTypeExternal {
+		inputs["host"] = toNode.Inputs["host"]
+		inputs["port"] = toNode.Inputs["port"]
 	}
 	return b.networkPolicyFilter(inputs)
 }
@@ -723,9 +806,13 @@ func (b *Builder) getOrCreateDatabaseUserNode(componentName string, dbNode, cons
 // getOrCreateNetworkPolicyNode returns (or creates) a networkPolicy leaf node for the
 // given workload-to-service relationship. The networkPolicy depends on both the workload
 // and the service. Nothing depends on it — it is a fire-and-forget side-effect node.
-// Naming convention: {fromWorkload}--{toService}
+// Naming convention: {fromWorkload}--{toService}, or {fromWorkload}--external-{name}
+// for egress to an external service.
 func (b *Builder) getOrCreateNetworkPolicyNode(componentName string, fromNode, toServiceNode *Node) *Node {
 	npName := fromNode.Name + "--" + toServiceNode.Name
+	if toServiceNode.Type == NodeTypeExternal {
+		npName = fromNode.Name + "--external-" + toServiceNode.Name
+	}
 	npID := fmt.Sprintf("%s/%s/%s", componentName, NodeTypeNetworkPolicy, npName)
 
 	existing := b.graph.GetNode(npID)
@@ -738,6 +825,7 @@ func (b *Builder) getOrCreateNetworkPolicyNode(componentName string, fromNode, t
 	npNode.SetInput("fromType", string(fromNode.Type))
 	npNode.SetInput("fromComponent", componentName)
 	npNode.SetInput("to", toServiceNode.Name)
+	npNode.SetInput("toType", string(toServiceNode.Type))
 	npNode.SetInput("toComponent", toServiceNode.Component)
 
 	// Extract port from the service node inputs
@@ -745,6 +833,12 @@ func (b *Builder) getOrCreateNetworkPolicyNode(componentName string, fromNode, t
 		npNode.SetInput("port", port)
 	}
 
+	// Egress to an external service carries the destination endpoint
+	if toServiceNode.Type == NodeTypeExternal {
+		npNode.SetInput("host", toServiceNode.Inputs["host"])
+		npNode.SetInput("protocol", toServiceNode.Inputs["protocol"])
+	}
+
 	// networkPolicy depends on both the workload and the service
 	npNode.AddDependency(fromNode.ID)
 	fromNode.AddDependent(npNode.ID)
@@ -755,10 +849,65 @@ func (b *Builder) getOrCreateNetworkPolicyNode(componentName string, fromNode, t
 	return npNode
 }
 
+// RouteAuthIdentityHeaders are the request headers a routeAuth proxy sets
+// with the authenticated caller's identity before forwarding to the upstream.
+// Keys are the identity fields passed to the hook as
+// node.inputs.identityHeaders; values are the header names applications read.
+var RouteAuthIdentityHeaders = map[string]string{
+	"user":   "X-Auth-Request-User",
+	"email":  "X-Auth-Request-Email",
+	"groups": "X-Auth-Request-Groups",
+}
+
+// addRouteAuthNode interposes a routeAuth node between a route and its
+// upstream. The route depends on the routeAuth node so the proxy exists before
+// the route points traffic at it, and the routeAuth node inherits the route's
+// port dependencies so it can resolve the upstream port.
+// Naming convention: the routeAuth node shares the route's name.
+func (b *Builder) addRouteAuthNode(componentName string, routeNode *Node, auth component.RouteAuth) *Node {
+	authNode := NewNode(NodeTypeRouteAuth, componentName, routeNode.Name)
+	authNode.SetInput("route", routeNode.Name)
+	authNode.SetInput("component", componentName)
+	authNode.SetInput("provider", auth.Provider())
+	authNode.SetInput("config", auth.Config())
+	authNode.SetInput("target", routeNode.Inputs["target"])
+	authNode.SetInput("targetType", routeNode.Inputs["targetType"])
+	authNode.SetInput("identityHeaders", RouteAuthIdentityHeaders)
+	_ = b.graph.AddNode(authNode)
+
+	for _, depID := range routeNode.DependsOn {
+		if dep := b.graph.GetNode(depID); dep != nil && dep.Type == NodeTypePort {
+			_ = b.graph.AddEdge(authNode.ID, depID)
+		}
+	}
+
+	routeNode.SetInput("auth", map[string]interface{}{
+		"provider": auth.Provider(),
+		"config":   auth.Config(),
+	})
+	_ = b.graph.AddEdge(routeNode.ID, authNode.ID)
+	return authNode
+}
+
 // InstanceInfo describes a component instance for multi-instance graph building.
 type InstanceInfo struct {
 	Name   string
 	Weight int
+
+	// Component optionally overrides the component definition used for this
+	// instance's per-instance resources (e.g., a canary built from a newer
+	// image). Shared resources always use the component passed to
+	// AddComponentWithInstances.
+	Component component.Component
+}
+
+// component returns the instance's component definition, falling back to the
+// shared one.
+func (i InstanceInfo) component(shared component.Component) component.Component {
+	if i.Component != nil {
+		return i.Component
+	}
+	return shared
 }
 
 // AddComponentWithInstances adds a component's resources to the graph in multi-instance mode.
@@ -766,6 +915,10 @@ type InstanceInfo struct {
 // Shared resource types create a single node that derives inputs from the newest (first) instance.
 // The `distinct` list promotes specific shared resources to per-instance.
 func (b *Builder) AddComponentWithInstances(componentName string, comp component.Component, instances []InstanceInfo, distinct []string) error {
+	if err := validateReferences(componentName, comp, b.platformExports); err != nil {
+		return err
+	}
+
 	// Build a set of distinct resource patterns for quick lookup
 	distinctSet := make(map[string]bool)
 	for _, d := range distinct {
@@ -826,12 +979,14 @@ func (b *Builder) AddComponentWithInstances(componentName string, comp component
 		b.graph.ComponentOutputExprs[componentName] = outMap
 	}
 
+	b.recordInjectionExclusions(componentName, comp)
+
 	compDir := filepath.Dir(comp.SourcePath())
 
 	// Convert instances to NodeInstance for shared node metadata
 	nodeInstances := make([]NodeInstance, len(instances))
 	for i, inst := range instances {
-		nodeInstances[i] = NodeInstance(inst)
+		nodeInstances[i] = NodeInstance{Name: inst.Name, Weight: inst.Weight}
 	}
 
 	// === SHARED RESOURCES ===
@@ -848,6 +1003,7 @@ func (b *Builder) AddComponentWithInstances(componentName string, comp component
 					typeStr = typeStr + ":" + db.Version()
 				}
 				node.SetInput("type", typeStr)
+				node.Readiness = readinessGate(db.Readiness())
 				node.Instances = nodeInstances
 				_ = b.graph.AddNode(node)
 			}
@@ -858,6 +1014,7 @@ func (b *Builder) AddComponentWithInstances(componentName string, comp component
 				typeStr = typeStr + ":" + db.Version()
 			}
 			node.SetInput("type", typeStr)
+			node.Readiness = readinessGate(db.Readiness())
 			node.Instances = nodeInstances
 			_ = b.graph.AddNode(node)
 
@@ -917,6 +1074,13 @@ func (b *Builder) AddComponentWithInstances(componentName string, comp component
 		_ = b.graph.AddNode(node)
 	}
 
+	// Add external services (shared)
+	for _, ext := range comp.External() {
+		node := newExternalNode(componentName, ext)
+		node.Instances = nodeInstances
+		_ = b.graph.AddNode(node)
+	}
+
 	// Add observability (shared)
 	var obsNodeID string
 	if comp.Observability() != nil {
@@ -934,26 +1098,29 @@ func (b *Builder) AddComponentWithInstances(componentName string, comp component
 	// These are duplicated for each instance.
 
 	for _, inst := range instances {
+		instComp := inst.component(comp)
+		instDir := filepath.Dir(instComp.SourcePath())
 		// Add builds per instance
-		for _, build := range comp.Builds() {
+		for _, build := range instComp.Builds() {
 			buildNode := NewInstanceNode(NodeTypeDockerBuild, componentName, inst.Name, inst.Weight, build.Name())
-			buildNode.SetInput("context", resolveBuildContext(compDir, build.Context()))
-			buildNode.SetInput("dockerfile", resolveBuildContext(compDir, build.Dockerfile()))
+			buildNode.SetInput("context", resolveBuildContext(instDir, build.Context()))
+			buildNode.SetInput("dockerfile", resolveBuildContext(instDir, build.Dockerfile()))
 			buildNode.SetInput("target", build.Target())
 			buildNode.SetInput("args", build.Args())
 			_ = b.graph.AddNode(buildNode)
 		}
 
 		// Add ports per instance
-		for _, p := range comp.Ports() {
+		for _, p := range instComp.Ports() {
 			node := NewInstanceNode(NodeTypePort, componentName, inst.Name, inst.Weight, p.Name())
 			node.SetInput("description", p.Description())
 			_ = b.graph.AddNode(node)
 		}
 
 		// Add deployments per instance
-		for _, deploy := range comp.Deployments() {
+		for _, deploy := range instComp.Deployments() {
 			node := NewInstanceNode(NodeTypeDeployment, componentName, inst.Name, inst.Weight, deploy.Name())
+			node.Readiness = readinessGate(deploy.Readiness())
 			if deploy.Image() != "" {
 				node.SetInput("image", deploy.Image())
 			}
@@ -978,32 +1145,42 @@ func (b *Builder) AddComponentWithInstances(componentName string, comp component
 			}
 			node.SetInput("command", deploy.Command())
 			node.SetInput("entrypoint", deploy.Entrypoint())
-			node.SetInput("environment", deploy.Environment())
+			node.SetInput("environment", workloadEnvironment(deploy.EnvFrom(), deploy.Environment()))
 			node.SetInput("cpu", deploy.CPU())
 			node.SetInput("memory", deploy.Memory())
 			node.SetInput("replicas", deploy.Replicas())
+			if strategyMap := strategyToMap(deploy.Strategy()); strategyMap != nil {
+				node.SetInput("strategy", strategyMap)
+			}
+			if lifecycleMap := lifecycleToMap(deploy.Lifecycle()); lifecycleMap != nil {
+				node.SetInput("lifecycle", lifecycleMap)
+			}
+			if files := filesToList(deploy.Files()); files != nil {
+				node.SetInput("files", files)
+			}
 			if probeMap := probeToMap(deploy.LivenessProbe()); probeMap != nil {
 				node.SetInput("liveness_probe", probeMap)
 			}
 			if deploy.WorkingDirectory() != "" {
-				node.SetInput("workingDirectory", resolveBuildContext(compDir, deploy.WorkingDirectory()))
+				node.SetInput("workingDirectory", resolveBuildContext(instDir, deploy.WorkingDirectory()))
 			} else {
-				node.SetInput("workingDirectory", compDir)
+				node.SetInput("workingDirectory", instDir)
 			}
 			_ = b.graph.AddNode(node)
 		}
 
 		// Add functions per instance
-		for _, fn := range comp.Functions() {
+		for _, fn := range instComp.Functions() {
 			node := NewInstanceNode(NodeTypeFunction, componentName, inst.Name, inst.Weight, fn.Name())
-			node.SetInput("environment", fn.Environment())
+			node.Readiness = readinessGate(fn.Readiness())
+			node.SetInput("environment", workloadEnvironment(fn.EnvFrom(), fn.Environment()))
 			node.SetInput("cpu", fn.CPU())
 			node.SetInput("memory", fn.Memory())
 			node.SetInput("timeout", fn.Timeout())
 			node.SetInput("port", fn.Port())
 			if fn.IsSourceBased() {
 				src := fn.Src()
-				node.SetInput("srcPath", resolveBuildContext(compDir, src.Path()))
+				node.SetInput("srcPath", resolveBuildContext(instDir, src.Path()))
 				node.SetInput("language", src.Language())
 				node.SetInput("runtime", src.Runtime())
 				node.SetInput("framework", src.Framework())
@@ -1020,8 +1197,8 @@ func (b *Builder) AddComponentWithInstances(componentName string, comp component
 				}
 				if container.Build() != nil {
 					buildNode := NewInstanceNode(NodeTypeDockerBuild, componentName, inst.Name, inst.Weight, fn.Name()+"-build")
-					buildNode.SetInput("context", resolveBuildContext(compDir, container.Build().Context()))
-					buildNode.SetInput("dockerfile", resolveBuildContext(compDir, container.Build().Dockerfile()))
+					buildNode.SetInput("context", resolveBuildContext(instDir, container.Build().Context()))
+					buildNode.SetInput("dockerfile", resolveBuildContext(instDir, container.Build().Dockerfile()))
 					buildNode.SetInput("args", container.Build().Args())
 					node.AddDependency(buildNode.ID)
 					buildNode.AddDependent(node.ID)
@@ -1032,8 +1209,9 @@ func (b *Builder) AddComponentWithInstances(componentName string, comp component
 		}
 
 		// Add services per instance
-		for _, svc := range comp.Services() {
+		for _, svc := range instComp.Services() {
 			node := NewInstanceNode(NodeTypeService, componentName, inst.Name, inst.Weight, svc.Name())
+			node.Readiness = readinessGate(svc.Readiness())
 			node.SetInput("port", svc.Port())
 			node.SetInput("protocol", svc.Protocol())
 			if svc.Deployment() != "" {
@@ -1044,7 +1222,7 @@ func (b *Builder) AddComponentWithInstances(componentName string, comp component
 		}
 
 		// Add cronjobs per instance
-		for _, cron := range comp.Cronjobs() {
+		for _, cron := range instComp.Cronjobs() {
 			node := NewInstanceNode(NodeTypeCronjob, componentName, inst.Name, inst.Weight, cron.Name())
 			if cron.Image() != "" {
 				node.SetInput("image", cron.Image())
@@ -1056,8 +1234,8 @@ func (b *Builder) AddComponentWithInstances(componentName string, comp component
 			node.SetInput("memory", cron.Memory())
 			if cron.Build() != nil {
 				buildNode := NewInstanceNode(NodeTypeDockerBuild, componentName, inst.Name, inst.Weight, cron.Name()+"-build")
-				buildNode.SetInput("context", resolveBuildContext(compDir, cron.Build().Context()))
-				buildNode.SetInput("dockerfile", resolveBuildContext(compDir, cron.Build().Dockerfile()))
+				buildNode.SetInput("context", resolveBuildContext(instDir, cron.Build().Context()))
+				buildNode.SetInput("dockerfile", resolveBuildContext(instDir, cron.Build().Dockerfile()))
 				buildNode.SetInput("args", cron.Build().Args())
 				node.AddDependency(buildNode.ID)
 				buildNode.AddDependent(node.ID)
@@ -1098,22 +1276,31 @@ func (b *Builder) AddComponentWithInstances(componentName string, comp component
 		}
 
 		_ = b.graph.AddNode(node)
+
+		if route.Auth() != nil {
+			authNode := b.addRouteAuthNode(componentName, node, route.Auth())
+			authNode.Instances = nodeInstances
+		}
 	}
 
 	// === Second pass: wire dependencies ===
 	for _, inst := range instances {
-		for _, deploy := range comp.Deployments() {
+		instComp := inst.component(comp)
+		for _, deploy := range instComp.Deployments() {
 			nodeID := fmt.Sprintf("%s/%s/%s/%s", componentName, inst.Name, NodeTypeDeployment, deploy.Name())
 			node := b.graph.GetNode(nodeID)
 			if node == nil {
 				continue
 			}
-			for _, value := range deploy.Environment() {
+			for _, value := range workloadEnvironment(deploy.EnvFrom(), deploy.Environment()) {
 				b.addInstanceEnvDependencies(componentName, inst.Name, node, value)
 			}
 			if deploy.Image() != "" {
 				b.addInstanceEnvDependencies(componentName, inst.Name, node, deploy.Image())
 			}
+			for _, f := range deploy.Files() {
+				b.addInstanceEnvDependencies(componentName, inst.Name, node, f.Content())
+			}
 			if obsNodeID != "" {
 				obsNode := b.graph.GetNode(obsNodeID)
 				if obsNode != nil {
@@ -1123,13 +1310,13 @@ func (b *Builder) AddComponentWithInstances(componentName string, comp component
 			}
 		}
 
-		for _, fn := range comp.Functions() {
+		for _, fn := range instComp.Functions() {
 			nodeID := fmt.Sprintf("%s/%s/%s/%s", componentName, inst.Name, NodeTypeFunction, fn.Name())
 			node := b.graph.GetNode(nodeID)
 			if node == nil {
 				continue
 			}
-			for _, value := range fn.Environment() {
+			for _, value := range workloadEnvironment(fn.EnvFrom(), fn.Environment()) {
 				b.addInstanceEnvDependencies(componentName, inst.Name, node, value)
 			}
 			// Scan port field for expression dependencies (e.g., ${{ ports.web.port }})
@@ -1145,7 +1332,7 @@ func (b *Builder) AddComponentWithInstances(componentName string, comp component
 			}
 		}
 
-		for _, cron := range comp.Cronjobs() {
+		for _, cron := range instComp.Cronjobs() {
 			nodeID := fmt.Sprintf("%s/%s/%s/%s", componentName, inst.Name, NodeTypeCronjob, cron.Name())
 			node := b.graph.GetNode(nodeID)
 			if node == nil {
@@ -1164,7 +1351,7 @@ func (b *Builder) AddComponentWithInstances(componentName string, comp component
 		}
 
 		// Scan service port fields for dependencies
-		for _, svc := range comp.Services() {
+		for _, svc := range instComp.Services() {
 			nodeID := fmt.Sprintf("%s/%s/%s/%s", componentName, inst.Name, NodeTypeService, svc.Name())
 			node := b.graph.GetNode(nodeID)
 			if node == nil {
@@ -1176,9 +1363,33 @@ func (b *Builder) AddComponentWithInstances(componentName string, comp component
 		}
 	}
 
+	// Scan readiness gates for dependencies
+	for _, node := range b.gatedNodes(componentName) {
+		for _, value := range node.Readiness.Values() {
+			if node.Instance != nil {
+				b.addInstanceEnvDependencies(componentName, node.Instance.Name, node, value)
+			} else {
+				b.addEnvDependencies(componentName, node, value)
+			}
+		}
+	}
+
 	return nil
 }
 
+// gatedNodes returns a component's nodes that have a readiness gate, in ID
+// order.
+func (b *Builder) gatedNodes(componentName string) []*Node {
+	var nodes []*Node
+	for _, node := range b.graph.GetNodesByComponent(componentName) {
+		if node.Readiness != nil {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
+
 // addInstanceEnvDependencies resolves dependencies for instance-qualified nodes.
 // It first looks for per-instance dependencies (instance-qualified IDs),
 // then falls back to shared resources (non-instance-qualified IDs).
@@ -1187,14 +1398,14 @@ func (b *Builder) addInstanceEnvDependencies(componentName, instanceName string,
 	deps := extractDependencies(value)
 	for _, dep := range deps {
 		// First try instance-qualified ID
-		depNodeID := b.resolveInstanceDepReference(componentName, instanceName, dep)
+		depNodeID := referenceInstanceNodeID(componentName, instanceName, dep)
 		if depNodeID == "" {
 			continue
 		}
 		depNode := b.graph.GetNode(depNodeID)
 		if depNode == nil {
 			// Fall back to shared (non-instance-qualified) ID
-			depNodeID = b.resolveDepReference(componentName, dep)
+			depNodeID = referenceNodeID(componentName, dep)
 			if depNodeID == "" {
 				continue
 			}
@@ -1203,6 +1414,9 @@ func (b *Builder) addInstanceEnvDependencies(componentName, instanceName string,
 				continue
 			}
 		}
+		if depNodeID == node.ID {
+			continue
+		}
 
 		// Implicit databaseUser interposition for multi-instance mode
 		if depNode.Type == NodeTypeDatabase && IsWorkloadType(node.Type) && b.shouldCreateDatabaseUser(depNode, node) {
@@ -1224,7 +1438,7 @@ func (b *Builder) addInstanceEnvDependencies(componentName, instanceName string,
 		depNode.AddDependent(node.ID)
 
 		// Implicit networkPolicy creation for multi-instance mode
-		if depNode.Type == NodeTypeService && IsWorkloadType(node.Type) && b.shouldCreateNetworkPolicy(node, depNode) {
+		if (depNode.Type == NodeTypeService || depNode.Type == NodeTypeExternal) && IsWorkloadType(node.Type) && b.shouldCreateNetworkPolicy(node, depNode) {
 			b.getOrCreateNetworkPolicyNode(componentName, node, depNode)
 		}
 
@@ -1239,8 +1453,8 @@ func (b *Builder) addInstanceEnvDependencies(componentName, instanceName string,
 	}
 }
 
-// resolveInstanceDepReference converts a reference to an instance-qualified node ID.
-func (b *Builder) resolveInstanceDepReference(componentName, instanceName, ref string) string {
+// referenceInstanceNodeID converts a reference to an instance-qualified node ID.
+func referenceInstanceNodeID(componentName, instanceName, ref string) string {
 	parts := strings.Split(ref, ".")
 	if len(parts) < 2 {
 		return ""
@@ -1269,6 +1483,8 @@ func (b *Builder) resolveInstanceDepReference(componentName, instanceName, ref s
 		nodeType = NodeTypeDockerBuild
 	case "ports":
 		nodeType = NodeTypePort
+	case "external":
+		nodeType = NodeTypeExternal
 	case "observability":
 		return fmt.Sprintf("%s/%s/%s", componentName, NodeTypeObservability, "observability")
 	default:
@@ -1283,12 +1499,54 @@ func (b *Builder) resolveInstanceDepReference(componentName, instanceName, ref s
 	return fmt.Sprintf("%s/%s/%s", componentName, nodeType, resourceName)
 }
 
+// AddComponentOrdering makes every node of the dependent component wait for
+// every node of the dependency component. It is used when an environment
+// passes one component's outputs into another component's variables, which
+// does not show up as a dependency in either component's schema.
+func (b *Builder) AddComponentOrdering(dependent, dependency string) error {
+	if dependent == dependency {
+		return fmt.Errorf("component %s cannot depend on its own outputs", dependent)
+	}
+
+	var dependentIDs, dependencyIDs []string
+	for id, node := range b.graph.Nodes {
+		switch node.Component {
+		case dependent:
+			dependentIDs = append(dependentIDs, id)
+		case dependency:
+			dependencyIDs = append(dependencyIDs, id)
+		}
+	}
+
+	for _, from := range dependentIDs {
+		for _, to := range dependencyIDs {
+			if err := b.graph.AddEdge(from, to); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Record the relationship so the dependency is not destroyed first
+	if b.graph.ComponentDependencies == nil {
+		b.graph.ComponentDependencies = make(map[string][]string)
+	}
+	for _, existing := range b.graph.ComponentDependencies[dependent] {
+		if existing == dependency {
+			return nil
+		}
+	}
+	b.graph.ComponentDependencies[dependent] = append(b.graph.ComponentDependencies[dependent], dependency)
+	return nil
+}
+
 // Build returns the completed graph.
 func (b *Builder) Build() *Graph {
 	return b.graph
 }
 
-// extractDependencies finds ${{ }} references in a string
+// extractDependencies finds ${{ }} references in a string. A computed
+// expression (e.g. ${{ ports.http.port + 1 }}) contributes each reference it
+// reads.
 func extractDependencies(value string) []string {
 	re := regexp.MustCompile(`\$\{\{\s*([^}]+)\s*\}\}`)
 	matches := re.FindAllStringSubmatch(value, -1)
@@ -1296,14 +1554,22 @@ func extractDependencies(value string) []string {
 	var deps []string
 	for _, match := range matches {
 		if len(match) > 1 {
-			deps = append(deps, strings.TrimSpace(match[1]))
+			content := strings.TrimSpace(match[1])
+			ref := strings.SplitN(content, "|", 2)[0]
+			if expression.IsComputed(ref) {
+				if refs, err := expression.References(ref); err == nil {
+					deps = append(deps, refs...)
+					continue
+				}
+			}
+			deps = append(deps, content)
 		}
 	}
 	return deps
 }
 
-// resolveDepReference converts a reference like "databases.main.url" to a node ID
-func (b *Builder) resolveDepReference(componentName, ref string) string {
+// referenceNodeID converts a reference like "databases.main.url" to a node ID
+func referenceNodeID(componentName, ref string) string {
 	parts := strings.Split(ref, ".")
 	if len(parts) < 2 {
 		return ""
@@ -1332,6 +1598,8 @@ func (b *Builder) resolveDepReference(componentName, ref string) string {
 		nodeType = NodeTypeDockerBuild
 	case "ports":
 		nodeType = NodeTypePort
+	case "external":
+		nodeType = NodeTypeExternal
 	case "observability":
 		// Observability is a singleton per component, always named "observability"
 		return fmt.Sprintf("%s/%s/%s", componentName, NodeTypeObservability, "observability")
@@ -1342,6 +1610,43 @@ func (b *Builder) resolveDepReference(componentName, ref string) string {
 	return fmt.Sprintf("%s/%s/%s", componentName, nodeType, resourceName)
 }
 
+// newExternalNode creates the node for an external service. The endpoint's
+// host, port, and protocol are split out of the URL so network policy hooks
+// can generate egress rules without parsing it themselves.
+func newExternalNode(componentName string, ext component.ExternalService) *Node {
+	node := NewNode(NodeTypeExternal, componentName, ext.Name())
+	node.SetInput("url", ext.URL())
+	node.SetInput("description", ext.Description())
+	node.SetInput("credentials", ext.Credentials())
+
+	host, port, protocol := externalEndpoint(ext.URL())
+	node.SetInput("host", host)
+	node.SetInput("port", port)
+	node.SetInput("protocol", protocol)
+	return node
+}
+
+// externalEndpoint splits an external service URL into host, port, and
+// protocol. The port defaults from the scheme when the URL doesn't set one.
+func externalEndpoint(rawURL string) (host, port, protocol string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", ""
+	}
+	protocol = u.Scheme
+	host = u.Hostname()
+	port = u.Port()
+	if port == "" {
+		switch protocol {
+		case "https", "wss":
+			port = "443"
+		case "http", "ws":
+			port = "80"
+		}
+	}
+	return host, port, protocol
+}
+
 // probeToMap converts a Probe interface to a map[string]interface{} suitable for
 // passing through the expression evaluator. Returns nil if the probe is nil.
 func probeToMap(p component.Probe) map[string]interface{} {
@@ -1362,6 +1667,103 @@ func probeToMap(p component.Probe) map[string]interface{} {
 	return m
 }
 
+// strategyToMap converts a deployment strategy to the map passed to hooks.
+// Counts are passed as numbers and percentages as strings, the way
+// Kubernetes and ECS accept them. Unset amounts are left out.
+func strategyToMap(s component.Strategy) map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+	m := map[string]interface{}{
+		"type":              s.Type(),
+		"min_ready_seconds": s.MinReadySeconds(),
+	}
+	for key, value := range map[string]string{"max_surge": s.MaxSurge(), "max_unavailable": s.MaxUnavailable()} {
+		if value == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(value); err == nil {
+			m[key] = n
+		} else {
+			m[key] = value
+		}
+	}
+	return m
+}
+
+// lifecycleToMap converts a deployment's lifecycle hooks to the map passed to
+// hooks. Unset hooks and a default grace period are left out.
+func lifecycleToMap(l component.Lifecycle) map[string]interface{} {
+	if l == nil {
+		return nil
+	}
+	m := make(map[string]interface{})
+	if len(l.PostStart()) > 0 {
+		m["post_start"] = l.PostStart()
+	}
+	if len(l.PreStop()) > 0 {
+		m["pre_stop"] = l.PreStop()
+	}
+	if l.TerminationGracePeriodSeconds() > 0 {
+		m["termination_grace_period_seconds"] = l.TerminationGracePeriodSeconds()
+	}
+	return m
+}
+
+// readinessGate converts a resource's readiness gate to the one the executor
+// runs for its node.
+func readinessGate(r component.Readiness) *Readiness {
+	if r == nil {
+		return nil
+	}
+	return &Readiness{
+		HTTP:     r.HTTP(),
+		TCP:      r.TCP(),
+		Command:  r.Command(),
+		Interval: r.Interval(),
+		Timeout:  r.Timeout(),
+	}
+}
+
+// filesToList converts the files a deployment mounts to the list passed to
+// hooks, one map per file with its mount_path, content, and mode if set.
+func filesToList(files []component.File) []interface{} {
+	if len(files) == 0 {
+		return nil
+	}
+	result := make([]interface{}, 0, len(files))
+	for _, f := range files {
+		m := map[string]interface{}{
+			"mount_path": f.MountPath(),
+			"content":    f.Content(),
+		}
+		if f.Mode() != "" {
+			m["mode"] = f.Mode()
+		}
+		result = append(result, m)
+	}
+	return result
+}
+
+// workloadEnvironment merges a workload's env files with its environment
+// block. Files are applied in the order they are listed, so a later file
+// overrides an earlier one, and the environment block overrides them all.
+func workloadEnvironment(files []component.EnvFile, env map[string]string) map[string]string {
+	if len(files) == 0 {
+		return env
+	}
+	merged := make(map[string]string)
+	for _, f := range files {
+		for k, v := range f.Environment() {
+			merged[k] = v
+		}
+	}
+	for k, v := range env {
+		merged[k] = v
+	}
+	return merged
+}
+
 // resolveBuildContext resolves a build context path to an absolute path.
 // This is important for OCI-pulled components where relative paths need to be
 // resolved relative to the extracted artifact location, not the current working directory.
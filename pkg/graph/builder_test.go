@@ -1,6 +1,8 @@
 package graph
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/davidthor/cldctl/pkg/schema/component"
@@ -886,6 +888,79 @@ deployments:
 	}
 }
 
+func TestBuilder_ExternalService(t *testing.T) {
+	builder := NewBuilder("test-env", "test-dc")
+	builder.EnableImplicitNodes(false, true)
+
+	comp := loadComponent(t, `
+variables:
+  stripe_key:
+    sensitive: true
+
+external:
+  stripe:
+    url: https://api.stripe.com
+    credentials:
+      api_key: "${{ variables.stripe_key }}"
+
+deployments:
+  api:
+    image: api:latest
+    environment:
+      STRIPE_API_KEY: "${{ external.stripe.api_key }}"
+`)
+
+	err := builder.AddComponent("my-app", comp)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	g := builder.Build()
+
+	extNode := g.GetNode("my-app/external/stripe")
+	if extNode == nil {
+		t.Fatal("expected external node to exist")
+	}
+	if extNode.Inputs["host"] != "api.stripe.com" {
+		t.Errorf("expected 'host' input 'api.stripe.com', got %v", extNode.Inputs["host"])
+	}
+	if extNode.Inputs["port"] != "443" {
+		t.Errorf("expected 'port' input '443', got %v", extNode.Inputs["port"])
+	}
+	if extNode.Inputs["protocol"] != "https" {
+		t.Errorf("expected 'protocol' input 'https', got %v", extNode.Inputs["protocol"])
+	}
+
+	deployNode := g.GetNode("my-app/deployment/api")
+	hasDep := false
+	for _, dep := range deployNode.DependsOn {
+		if dep == extNode.ID {
+			hasDep = true
+		}
+	}
+	if !hasDep {
+		t.Error("expected api deployment to depend on the external service")
+	}
+
+	// Referencing the external service generates an egress networkPolicy
+	npNode := g.GetNode("my-app/networkPolicy/api--external-stripe")
+	if npNode == nil {
+		t.Fatal("expected egress networkPolicy node to exist")
+	}
+	if npNode.Inputs["toType"] != "external" {
+		t.Errorf("expected 'toType' input 'external', got %v", npNode.Inputs["toType"])
+	}
+	if npNode.Inputs["host"] != "api.stripe.com" {
+		t.Errorf("expected 'host' input 'api.stripe.com', got %v", npNode.Inputs["host"])
+	}
+	if npNode.Inputs["port"] != "443" {
+		t.Errorf("expected 'port' input '443', got %v", npNode.Inputs["port"])
+	}
+	if len(npNode.DependedOnBy) != 0 {
+		t.Errorf("expected no dependents on networkPolicy, got %v", npNode.DependedOnBy)
+	}
+}
+
 func TestBuilder_TopologicalSort_WithImplicitNodes(t *testing.T) {
 	builder := NewBuilder("test-env", "test-dc")
 	builder.EnableImplicitNodes(true, true)
@@ -1653,3 +1728,471 @@ deployments:
 		t.Error("deployment should NOT depend on smtp/transactional (not referenced in env)")
 	}
 }
+
+func TestBuilder_AddComponentOrdering(t *testing.T) {
+	builder := NewBuilder("test-env", "test-dc")
+
+	auth := loadComponent(t, `
+deployments:
+  auth:
+    image: auth:latest
+`)
+	api := loadComponent(t, `
+deployments:
+  api:
+    image: api:latest
+`)
+	if err := builder.AddComponent("auth", auth); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := builder.AddComponent("api", api); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := builder.AddComponentOrdering("api", "auth"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := builder.AddComponentOrdering("api", "api"); err == nil {
+		t.Error("expected error for self-ordering")
+	}
+
+	g := builder.Build()
+	apiNode := g.GetNode("api/deployment/api")
+	found := false
+	for _, dep := range apiNode.DependsOn {
+		if dep == "auth/deployment/auth" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected api deployment to depend on auth deployment, got %v", apiNode.DependsOn)
+	}
+	if deps := g.ComponentDependencies["api"]; len(deps) != 1 || deps[0] != "auth" {
+		t.Errorf("expected api to record a dependency on auth, got %v", deps)
+	}
+
+	sorted, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pos := map[string]int{}
+	for i, n := range sorted {
+		pos[n.ID] = i
+	}
+	if pos["auth/deployment/auth"] > pos["api/deployment/api"] {
+		t.Error("expected auth to be ordered before api")
+	}
+}
+
+func TestBuilder_AddComponentWithInstances_PerInstanceComponent(t *testing.T) {
+	builder := NewBuilder("test-env", "test-dc")
+
+	stable := loadComponent(t, `
+databases:
+  main:
+    type: postgres:^16
+deployments:
+  api:
+    image: my-app:v1
+`)
+	canary := loadComponent(t, `
+databases:
+  main:
+    type: postgres:^16
+deployments:
+  api:
+    image: my-app:v2
+`)
+
+	instances := []InstanceInfo{
+		{Name: "canary", Weight: 10, Component: canary},
+		{Name: "stable", Weight: 90},
+	}
+	if err := builder.AddComponentWithInstances("my-app", stable, instances, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	g := builder.Build()
+	for id, want := range map[string]string{
+		"my-app/canary/deployment/api": "my-app:v2",
+		"my-app/stable/deployment/api": "my-app:v1",
+	} {
+		node := g.GetNode(id)
+		if node == nil {
+			t.Fatalf("expected node %s", id)
+		}
+		if node.Inputs["image"] != want {
+			t.Errorf("%s: expected image %s, got %v", id, want, node.Inputs["image"])
+		}
+	}
+	if g.GetNode("my-app/database/main") == nil {
+		t.Error("expected the database to stay shared")
+	}
+}
+
+func TestBuilder_RouteAuthNode_InterposedBeforeRoute(t *testing.T) {
+	builder := NewBuilder("test-env", "test-dc")
+
+	comp := loadComponent(t, `
+ports:
+  admin:
+    description: admin port
+
+deployments:
+  admin:
+    image: admin:latest
+
+services:
+  admin:
+    deployment: admin
+    port: ${{ ports.admin.port }}
+
+routes:
+  admin:
+    type: http
+    service: admin
+    auth:
+      provider: github
+  public:
+    type: http
+    service: admin
+`)
+
+	if err := builder.AddComponent("my-app", comp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g := builder.Build()
+
+	authNodes := g.GetNodesByType(NodeTypeRouteAuth)
+	if len(authNodes) != 1 {
+		t.Fatalf("expected 1 routeAuth node, got %d", len(authNodes))
+	}
+	authNode := authNodes[0]
+	if authNode.ID != "my-app/routeAuth/admin" {
+		t.Errorf("unexpected routeAuth node ID %s", authNode.ID)
+	}
+	if authNode.Inputs["provider"] != "github" || authNode.Inputs["target"] != "admin" || authNode.Inputs["targetType"] != "service" {
+		t.Errorf("unexpected routeAuth inputs: %v", authNode.Inputs)
+	}
+	if _, ok := authNode.Inputs["identityHeaders"].(map[string]string); !ok {
+		t.Errorf("expected identityHeaders input, got %v", authNode.Inputs["identityHeaders"])
+	}
+
+	routeNode := g.GetNode("my-app/route/admin")
+	hasAuthDep := false
+	for _, dep := range routeNode.DependsOn {
+		if dep == authNode.ID {
+			hasAuthDep = true
+		}
+	}
+	if !hasAuthDep {
+		t.Error("expected route to depend on its routeAuth node")
+	}
+	if _, ok := routeNode.Inputs["auth"].(map[string]interface{}); !ok {
+		t.Errorf("expected auth input on route, got %v", routeNode.Inputs["auth"])
+	}
+
+	hasPortDep := false
+	for _, dep := range authNode.DependsOn {
+		if dep == "my-app/port/admin" {
+			hasPortDep = true
+		}
+	}
+	if !hasPortDep {
+		t.Error("expected routeAuth node to inherit the route's port dependency")
+	}
+
+	if _, ok := g.GetNode("my-app/route/public").Inputs["auth"]; ok {
+		t.Error("expected public route to have no auth input")
+	}
+}
+
+func TestBuilder_InjectionExclusions(t *testing.T) {
+	builder := NewBuilder("test-env", "test-dc")
+
+	excluding := loadComponent(t, `
+deployments:
+  api:
+    image: nginx:latest
+injection:
+  exclude: [proxy]
+`)
+	optedOut := loadComponent(t, `
+deployments:
+  api:
+    image: nginx:latest
+injection: false
+`)
+	plain := loadComponent(t, `
+deployments:
+  api:
+    image: nginx:latest
+`)
+
+	for name, comp := range map[string]component.Component{"excluding": excluding, "opted-out": optedOut, "plain": plain} {
+		if err := builder.AddComponent(name, comp); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	g := builder.Build()
+
+	if got := g.InjectionExclusions["excluding"]; len(got) != 1 || got[0] != "proxy" {
+		t.Errorf("expected [proxy], got %v", got)
+	}
+	if got := g.InjectionExclusions["opted-out"]; len(got) != 1 || got[0] != "*" {
+		t.Errorf("expected [*], got %v", got)
+	}
+	if _, ok := g.InjectionExclusions["plain"]; ok {
+		t.Error("expected no exclusions for a component without injection settings")
+	}
+}
+
+func TestBuilder_EnvFrom_MergedWithPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"base.env": "DATABASE_URL=${{ databases.main.url }}\nLOG_LEVEL=info\nREGION=us-east-1\n",
+		"prod.env": "LOG_LEVEL=warn\nREGION=eu-west-1\n",
+		"cld.yml": `
+databases:
+  main:
+    type: postgres:^16
+deployments:
+  api:
+    image: api:latest
+    envFrom:
+      - base.env
+      - prod.env
+    environment:
+      REGION: ap-south-1
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	comp, err := component.NewLoader().Load(filepath.Join(dir, "cld.yml"))
+	if err != nil {
+		t.Fatalf("failed to load component: %v", err)
+	}
+
+	builder := NewBuilder("test-env", "test-dc")
+	if err := builder.AddComponent("app", comp); err != nil {
+		t.Fatalf("AddComponent failed: %v", err)
+	}
+	g := builder.Build()
+
+	deployNode := g.GetNode("app/deployment/api")
+	if deployNode == nil {
+		t.Fatal("expected deployment node")
+	}
+	env, ok := deployNode.Inputs["environment"].(map[string]string)
+	if !ok {
+		t.Fatalf("expected environment to be map[string]string, got %T", deployNode.Inputs["environment"])
+	}
+
+	want := map[string]string{
+		"DATABASE_URL": "${{ databases.main.url }}", // only in base.env
+		"LOG_LEVEL":    "warn",                      // prod.env overrides base.env
+		"REGION":       "ap-south-1",                // environment overrides both files
+	}
+	for k, v := range want {
+		if env[k] != v {
+			t.Errorf("%s = %q, want %q", k, env[k], v)
+		}
+	}
+
+	dependsOnDB := false
+	for _, dep := range deployNode.DependsOn {
+		if dep == "app/database/main" {
+			dependsOnDB = true
+		}
+	}
+	if !dependsOnDB {
+		t.Errorf("expected deployment to depend on the database referenced from base.env, got %v", deployNode.DependsOn)
+	}
+}
+
+func TestBuilder_DeploymentStrategyInput(t *testing.T) {
+	comp, err := component.NewLoader().LoadFromBytes([]byte(`
+deployments:
+  api:
+    image: api:latest
+    strategy:
+      type: rolling
+      maxSurge: "25%"
+      maxUnavailable: 0
+      minReadySeconds: 10
+  worker:
+    image: worker:latest
+`), "/tmp/cld.yml")
+	if err != nil {
+		t.Fatalf("failed to load component: %v", err)
+	}
+
+	builder := NewBuilder("test-env", "test-dc")
+	if err := builder.AddComponent("app", comp); err != nil {
+		t.Fatalf("AddComponent failed: %v", err)
+	}
+	g := builder.Build()
+
+	strategy, ok := g.GetNode("app/deployment/api").Inputs["strategy"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected strategy input, got %T", g.GetNode("app/deployment/api").Inputs["strategy"])
+	}
+	want := map[string]interface{}{
+		"type":              "rolling",
+		"max_surge":         "25%",
+		"max_unavailable":   0,
+		"min_ready_seconds": 10,
+	}
+	for k, v := range want {
+		if strategy[k] != v {
+			t.Errorf("strategy.%s = %#v, want %#v", k, strategy[k], v)
+		}
+	}
+
+	if _, ok := g.GetNode("app/deployment/worker").Inputs["strategy"]; ok {
+		t.Error("expected no strategy input for a deployment without a strategy")
+	}
+}
+
+func TestBuilder_DeploymentFilesInput(t *testing.T) {
+	comp, err := component.NewLoader().LoadFromBytes([]byte(`
+databases:
+  main:
+    type: postgres:^16
+deployments:
+  api:
+    image: api:latest
+    files:
+      - mount_path: /etc/app/config.json
+        content: '{"db": "${{ databases.main.url }}"}'
+        mode: "0600"
+`), "/tmp/cld.yml")
+	if err != nil {
+		t.Fatalf("failed to load component: %v", err)
+	}
+
+	builder := NewBuilder("test-env", "test-dc")
+	if err := builder.AddComponent("app", comp); err != nil {
+		t.Fatalf("AddComponent failed: %v", err)
+	}
+	g := builder.Build()
+
+	node := g.GetNode("app/deployment/api")
+	files, ok := node.Inputs["files"].([]interface{})
+	if !ok || len(files) != 1 {
+		t.Fatalf("expected one file input, got %#v", node.Inputs["files"])
+	}
+	file := files[0].(map[string]interface{})
+	if file["mount_path"] != "/etc/app/config.json" || file["mode"] != "0600" {
+		t.Errorf("unexpected file input %#v", file)
+	}
+	if file["content"] != `{"db": "${{ databases.main.url }}"}` {
+		t.Errorf("content = %#v, want the expression left for the executor", file["content"])
+	}
+
+	// Expressions in file content make the deployment wait for what they read
+	found := false
+	for _, dep := range node.DependsOn {
+		if dep == "app/database/main" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected the deployment to depend on the database, got %v", node.DependsOn)
+	}
+}
+
+func TestBuilder_ReadinessGate(t *testing.T) {
+	comp, err := component.NewLoader().LoadFromBytes([]byte(`
+databases:
+  main:
+    type: postgres:^16
+deployments:
+  api:
+    image: api:latest
+services:
+  api:
+    deployment: api
+    port: 8080
+    readiness:
+      command: ["pg_isready", "-d", "${{ databases.main.url }}"]
+      timeout: 2m
+  web:
+    deployment: api
+    port: 8080
+    readiness:
+      http: ${{ services.web.url }}/healthz
+`), "/tmp/cld.yml")
+	if err != nil {
+		t.Fatalf("failed to load component: %v", err)
+	}
+
+	builder := NewBuilder("test-env", "test-dc")
+	if err := builder.AddComponent("app", comp); err != nil {
+		t.Fatalf("AddComponent failed: %v", err)
+	}
+	g := builder.Build()
+
+	api := g.GetNode("app/service/api")
+	if api.Readiness == nil || api.Readiness.Timeout != "2m" || len(api.Readiness.Command) != 3 {
+		t.Fatalf("unexpected readiness gate %#v", api.Readiness)
+	}
+	if _, ok := api.Inputs["readiness"]; ok {
+		t.Error("the readiness gate should not be passed to hooks")
+	}
+	found := false
+	for _, dep := range api.DependsOn {
+		if dep == "app/database/main" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected the service to depend on the database its gate reads, got %v", api.DependsOn)
+	}
+
+	// A gate that reads the resource's own outputs doesn't make it depend on itself
+	web := g.GetNode("app/service/web")
+	for _, dep := range web.DependsOn {
+		if dep == web.ID {
+			t.Errorf("service depends on itself: %v", web.DependsOn)
+		}
+	}
+}
+
+func TestBuilder_DeploymentLifecycleInput(t *testing.T) {
+	comp, err := component.NewLoader().LoadFromBytes([]byte(`
+deployments:
+  api:
+    image: api:latest
+    lifecycle:
+      preStop: ["sleep", "5"]
+      terminationGracePeriodSeconds: 45
+`), "/tmp/cld.yml")
+	if err != nil {
+		t.Fatalf("failed to load component: %v", err)
+	}
+
+	builder := NewBuilder("test-env", "test-dc")
+	if err := builder.AddComponent("app", comp); err != nil {
+		t.Fatalf("AddComponent failed: %v", err)
+	}
+	g := builder.Build()
+
+	lifecycle, ok := g.GetNode("app/deployment/api").Inputs["lifecycle"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected lifecycle input, got %T", g.GetNode("app/deployment/api").Inputs["lifecycle"])
+	}
+	if got := lifecycle["termination_grace_period_seconds"]; got != 45 {
+		t.Errorf("termination_grace_period_seconds = %#v, want 45", got)
+	}
+	if got, _ := lifecycle["pre_stop"].([]string); len(got) != 2 || got[0] != "sleep" {
+		t.Errorf("pre_stop = %#v, want [sleep 5]", lifecycle["pre_stop"])
+	}
+	if _, ok := lifecycle["post_start"]; ok {
+		t.Error("expected unset post_start to be left out")
+	}
+}
@@ -38,6 +38,10 @@ type Graph struct {
 	// Used by the expression resolver to map ${{ dependencies.clerk.* }} to
 	// the actual component name in the graph.
 	DependencyTargets map[string]map[string]string
+
+	// InjectionExclusions maps component names to the datacenter environment
+	// injections their workloads opt out of. "*" excludes every injection.
+	InjectionExclusions map[string][]string
 }
 
 // NewGraph creates a new empty graph.
@@ -25,6 +25,8 @@ const (
 	NodeTypePort          NodeType = "port"
 	NodeTypeDatabaseUser  NodeType = "databaseUser"
 	NodeTypeNetworkPolicy NodeType = "networkPolicy"
+	NodeTypeRouteAuth     NodeType = "routeAuth"
+	NodeTypeExternal      NodeType = "external"
 )
 
 // NodeInstance holds instance context for per-instance nodes in progressive delivery.
@@ -72,6 +74,30 @@ type Node struct {
 	// Instances holds all instance metadata for shared nodes (e.g., routes).
 	// This lets shared hooks see all instances and their weights for traffic splitting.
 	Instances []NodeInstance
+
+	// Readiness is the gate the executor runs once the node is applied, or
+	// nil when the resource doesn't declare one.
+	Readiness *Readiness
+}
+
+// Readiness is a check the executor runs once a node has been applied, which
+// must pass before the nodes that depend on it run. Exactly one of HTTP, TCP,
+// and Command is set. They may contain expressions, which are resolved once
+// the node has its outputs.
+type Readiness struct {
+	HTTP     string   // URL that must respond with a 2xx status
+	TCP      string   // host:port that must accept connections
+	Command  []string // Command that must exit successfully
+	Interval string   // Time between checks; empty for the default
+	Timeout  string   // How long the check has to pass; empty for the default
+}
+
+// Values returns the fields of the check that may contain expressions.
+func (r *Readiness) Values() []string {
+	if r == nil {
+		return nil
+	}
+	return append([]string{r.HTTP, r.TCP}, r.Command...)
 }
 
 // NodeState tracks the execution state of a node.
@@ -0,0 +1,120 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// InputSource records where a value in a node's inputs comes from: a
+// reference in one of its expressions, and what that reference reads.
+type InputSource struct {
+	// Input is the dotted path of the input holding the expression (e.g.,
+	// "environment.DATABASE_URL").
+	Input string
+	// Reference is the reference the expression reads, without pipe
+	// functions (e.g., "databases.main.url").
+	Reference string
+	// NodeID is the node whose outputs the reference is resolved from, or
+	// empty if it doesn't read a node (variables, dependency outputs, ...).
+	NodeID string
+	// Component is the component whose outputs a dependencies.* reference
+	// reads, or empty for other references.
+	Component string
+}
+
+// InputSources returns the sources of the expressions in a node's inputs,
+// sorted by input and then reference. Unlike the node's DependsOn edges,
+// which also order it after implicit nodes and tasks, these are only the
+// references its values are actually read from.
+//
+// A workload's database references are resolved from the databaseUser node
+// interposed for it, when there is one.
+func (g *Graph) InputSources(nodeID string) []InputSource {
+	node := g.GetNode(nodeID)
+	if node == nil {
+		return nil
+	}
+
+	var sources []InputSource
+	var walk func(path string, v interface{})
+	walk = func(path string, v interface{}) {
+		switch val := v.(type) {
+		case string:
+			for _, dep := range extractDependencies(val) {
+				ref := strings.TrimSpace(strings.SplitN(dep, "|", 2)[0])
+				sources = append(sources, g.inputSource(node, path, ref))
+			}
+		case map[string]interface{}:
+			for k, item := range val {
+				walk(joinInputPath(path, k), item)
+			}
+		case map[string]string:
+			for k, item := range val {
+				walk(joinInputPath(path, k), item)
+			}
+		case []interface{}:
+			for i, item := range val {
+				walk(fmt.Sprintf("%s[%d]", path, i), item)
+			}
+		case []string:
+			for i, item := range val {
+				walk(fmt.Sprintf("%s[%d]", path, i), item)
+			}
+		}
+	}
+	for k, v := range node.Inputs {
+		walk(k, v)
+	}
+
+	sort.Slice(sources, func(i, j int) bool {
+		if sources[i].Input != sources[j].Input {
+			return sources[i].Input < sources[j].Input
+		}
+		return sources[i].Reference < sources[j].Reference
+	})
+	return sources
+}
+
+// inputSource resolves a reference in one of node's inputs to what it reads.
+func (g *Graph) inputSource(node *Node, input, ref string) InputSource {
+	source := InputSource{Input: input, Reference: ref}
+
+	if parts := strings.SplitN(ref, ".", 3); parts[0] == "dependencies" && len(parts) > 1 {
+		source.Component = parts[1]
+		if target, ok := g.DependencyTargets[node.Component][parts[1]]; ok {
+			source.Component = target
+		}
+		return source
+	}
+
+	id := referenceNodeID(node.Component, ref)
+	if node.Instance != nil {
+		if instanceID := referenceInstanceNodeID(node.Component, node.Instance.Name, ref); g.GetNode(instanceID) != nil {
+			id = instanceID
+		}
+	}
+	dep := g.GetNode(id)
+	if dep == nil {
+		return source
+	}
+	source.NodeID = dep.ID
+
+	if dep.Type == NodeTypeDatabase && IsWorkloadType(node.Type) {
+		userID := fmt.Sprintf("%s/%s/%s", node.Component, NodeTypeDatabaseUser, dep.Name+"--"+node.Name)
+		for _, d := range node.DependsOn {
+			if d == userID {
+				source.NodeID = userID
+				break
+			}
+		}
+	}
+	return source
+}
+
+func joinInputPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,58 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGraph_InputSources(t *testing.T) {
+	builder := NewBuilder("test-env", "test-dc")
+	builder.EnableImplicitNodes(true, false)
+
+	comp := loadComponent(t, `
+databases:
+  main:
+    type: postgres:^16
+
+deployments:
+  api:
+    image: api:latest
+  web:
+    image: web:latest
+    environment:
+      DATABASE_URL: "${{ databases.main.url }}"
+      API_URL: "http://${{ services.api.host }}:${{ services.api.port }}"
+      LOG_LEVEL: "${{ variables.log_level | default('info') }}"
+      STATIC: "plain"
+
+services:
+  api:
+    deployment: api
+    port: 8080
+
+variables:
+  log_level:
+    default: info
+`)
+
+	if err := builder.AddComponent("my-app", comp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g := builder.Build()
+
+	got := g.InputSources("my-app/deployment/web")
+	want := []InputSource{
+		{Input: "environment.API_URL", Reference: "services.api.host", NodeID: "my-app/service/api"},
+		{Input: "environment.API_URL", Reference: "services.api.port", NodeID: "my-app/service/api"},
+		// Read from the user interposed for the deployment, not the database
+		{Input: "environment.DATABASE_URL", Reference: "databases.main.url", NodeID: "my-app/databaseUser/main--web"},
+		{Input: "environment.LOG_LEVEL", Reference: "variables.log_level"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("InputSources() =\n  %+v\nwant\n  %+v", got, want)
+	}
+
+	if sources := g.InputSources("my-app/deployment/missing"); sources != nil {
+		t.Errorf("expected no sources for an unknown node, got %+v", sources)
+	}
+}
@@ -0,0 +1,463 @@
+// Package query implements a small selector language over the nodes and
+// edges of a dependency graph, so that invariants of an environment or a
+// component can be asserted in CI.
+//
+// A query selects nodes or edges matching every one of its predicates:
+//
+//	nodes(type=database, inputs.type=~"^postgres")
+//	edges(from.type=deployment, to.component!=api)
+//
+// Node fields are id, type, name, component, instance, depends_on,
+// dependents, and inputs.<path>. Edges run from a node to a node it depends
+// on; their fields are those of either end, as from.<field> and to.<field>.
+// The operators are = and != for equality, and =~ and !~ for regular
+// expression matches. Values are bare words or double-quoted strings. A
+// field holding a list matches if any of its elements does.
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+)
+
+// Kind is what a query selects.
+type Kind string
+
+const (
+	KindNodes Kind = "nodes"
+	KindEdges Kind = "edges"
+)
+
+// Operator compares a field with a predicate's value.
+type Operator string
+
+const (
+	OpEqual    Operator = "="
+	OpNotEqual Operator = "!="
+	OpMatch    Operator = "=~"
+	OpNotMatch Operator = "!~"
+)
+
+// Query is a parsed query.
+type Query struct {
+	Kind       Kind
+	Predicates []Predicate
+
+	source string
+}
+
+// Predicate is a condition on a field of a node or edge.
+type Predicate struct {
+	Field []string // e.g. ["inputs", "type"]
+	Op    Operator
+	Value string
+
+	re *regexp.Regexp
+}
+
+// SyntaxError is returned for a query that can't be parsed.
+type SyntaxError struct {
+	Offset int // byte offset into the query
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid query at offset %d: %s", e.Offset, e.Msg)
+}
+
+// nodeFields are the fields of a node; inputs takes a path.
+var nodeFields = map[string]bool{
+	"id":         true,
+	"type":       true,
+	"name":       true,
+	"component":  true,
+	"instance":   true,
+	"depends_on": true,
+	"dependents": true,
+	"inputs":     true,
+}
+
+// Parse parses a query.
+func Parse(s string) (*Query, error) {
+	p := &parser{s: s}
+	q, err := p.query()
+	if err != nil {
+		return nil, err
+	}
+	q.source = s
+	return q, nil
+}
+
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.s) && strings.ContainsRune(" \t\r\n", rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+// consume skips whitespace and reports whether the input continues with tok,
+// consuming it if so.
+func (p *parser) consume(tok string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.s[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func (p *parser) ident() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && isWordChar(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+func (p *parser) query() (*Query, error) {
+	kind := Kind(p.ident())
+	if kind != KindNodes && kind != KindEdges {
+		return nil, &SyntaxError{Offset: 0, Msg: fmt.Sprintf("expected nodes(...) or edges(...), got %q", kind)}
+	}
+	if !p.consume("(") {
+		return nil, p.errorf("expected ( after %s", kind)
+	}
+
+	q := &Query{Kind: kind}
+	if !p.consume(")") {
+		for {
+			pred, err := p.predicate(kind)
+			if err != nil {
+				return nil, err
+			}
+			q.Predicates = append(q.Predicates, pred)
+			if p.consume(")") {
+				break
+			}
+			if !p.consume(",") {
+				return nil, p.errorf("expected , or )")
+			}
+		}
+	}
+
+	p.skipSpace()
+	if p.pos != len(p.s) {
+		return nil, p.errorf("unexpected %q after the query", p.s[p.pos:])
+	}
+	return q, nil
+}
+
+func (p *parser) predicate(kind Kind) (Predicate, error) {
+	p.skipSpace()
+	start := p.pos
+	field := []string{p.ident()}
+	for p.pos < len(p.s) && p.s[p.pos] == '.' {
+		p.pos++
+		part := p.ident()
+		if part == "" {
+			return Predicate{}, p.errorf("expected a field name after .")
+		}
+		field = append(field, part)
+	}
+	if field[0] == "" {
+		return Predicate{}, p.errorf("expected a field")
+	}
+	if err := checkField(kind, field); err != nil {
+		return Predicate{}, &SyntaxError{Offset: start, Msg: err.Error()}
+	}
+
+	var op Operator
+	for _, candidate := range []Operator{OpNotEqual, OpMatch, OpNotMatch, OpEqual} {
+		if p.consume(string(candidate)) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return Predicate{}, p.errorf("expected =, !=, =~ or !~ after %s", strings.Join(field, "."))
+	}
+
+	value, err := p.value()
+	if err != nil {
+		return Predicate{}, err
+	}
+	pred := Predicate{Field: field, Op: op, Value: value}
+	if op == OpMatch || op == OpNotMatch {
+		pred.re, err = regexp.Compile(value)
+		if err != nil {
+			return Predicate{}, p.errorf("invalid regular expression %q: %v", value, err)
+		}
+	}
+	return pred, nil
+}
+
+// value reads a double-quoted string, or a bare word that may also contain
+// dots, slashes and colons (e.g. postgres:16).
+func (p *parser) value() (string, error) {
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == '"' {
+		end := p.pos + 1
+		for end < len(p.s) && p.s[end] != '"' {
+			if p.s[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(p.s) {
+			return "", p.errorf("unterminated string")
+		}
+		value, err := strconv.Unquote(p.s[p.pos : end+1])
+		if err != nil {
+			return "", p.errorf("invalid string %s", p.s[p.pos:end+1])
+		}
+		p.pos = end + 1
+		return value, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.s) && (isWordChar(p.s[p.pos]) || strings.ContainsRune("./:", rune(p.s[p.pos]))) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("expected a value")
+	}
+	return p.s[start:p.pos], nil
+}
+
+// checkField checks that a field exists for the kind of query.
+func checkField(kind Kind, field []string) error {
+	if kind == KindEdges {
+		if field[0] != "from" && field[0] != "to" {
+			return fmt.Errorf("edge fields start with from. or to., got %q", strings.Join(field, "."))
+		}
+		if len(field) == 1 {
+			return fmt.Errorf("expected a node field after %s.", field[0])
+		}
+		field = field[1:]
+	}
+	if !nodeFields[field[0]] {
+		return fmt.Errorf("unknown field %q", strings.Join(field, "."))
+	}
+	if field[0] == "inputs" && len(field) == 1 {
+		return fmt.Errorf("expected an input name after inputs.")
+	}
+	if field[0] != "inputs" && len(field) > 1 {
+		return fmt.Errorf("field %s has no %q", field[0], strings.Join(field[1:], "."))
+	}
+	return nil
+}
+
+// String returns the query as it was parsed.
+func (q *Query) String() string {
+	return q.source
+}
+
+// Result is what a query selected.
+type Result struct {
+	Query string      `json:"query"`
+	Kind  Kind        `json:"kind"`
+	Count int         `json:"count"`
+	Nodes []NodeMatch `json:"nodes,omitempty"`
+	Edges []EdgeMatch `json:"edges,omitempty"`
+}
+
+// NodeMatch is a node a query selected.
+type NodeMatch struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	Component string                 `json:"component"`
+	Name      string                 `json:"name"`
+	Instance  string                 `json:"instance,omitempty"`
+	Inputs    map[string]interface{} `json:"inputs,omitempty"`
+}
+
+// EdgeMatch is an edge a query selected: From depends on To.
+type EdgeMatch struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Run runs the query against a graph. Matches are sorted by node ID.
+func (q *Query) Run(g *graph.Graph) *Result {
+	result := &Result{Query: q.source, Kind: q.Kind}
+
+	ids := make([]string, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		node := g.Nodes[id]
+		if q.Kind == KindNodes {
+			if q.matches(func(field []string) (interface{}, bool) { return nodeField(node, field) }) {
+				result.Nodes = append(result.Nodes, nodeMatch(node))
+			}
+			continue
+		}
+
+		deps := append([]string(nil), node.DependsOn...)
+		sort.Strings(deps)
+		for _, depID := range deps {
+			dep := g.Nodes[depID]
+			if dep == nil {
+				continue
+			}
+			lookup := func(field []string) (interface{}, bool) {
+				if field[0] == "from" {
+					return nodeField(node, field[1:])
+				}
+				return nodeField(dep, field[1:])
+			}
+			if q.matches(lookup) {
+				result.Edges = append(result.Edges, EdgeMatch{From: node.ID, To: dep.ID})
+			}
+		}
+	}
+
+	result.Count = len(result.Nodes) + len(result.Edges)
+	return result
+}
+
+func (q *Query) matches(lookup func(field []string) (interface{}, bool)) bool {
+	for _, pred := range q.Predicates {
+		value, ok := lookup(pred.Field)
+		if !pred.matches(value, ok) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether a field's value satisfies the predicate. found is
+// false for fields the node doesn't have, which only != and !~ match.
+func (pred Predicate) matches(value interface{}, found bool) bool {
+	matched := false
+	if found {
+		for _, v := range elements(value) {
+			s := stringify(v)
+			if pred.re != nil && pred.re.MatchString(s) || pred.re == nil && s == pred.Value {
+				matched = true
+				break
+			}
+		}
+	}
+	if pred.Op == OpNotEqual || pred.Op == OpNotMatch {
+		return !matched
+	}
+	return matched
+}
+
+// elements returns the elements of a list value, or the value itself.
+func elements(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case []string:
+		items := make([]interface{}, len(v))
+		for i, s := range v {
+			items[i] = s
+		}
+		return items
+	default:
+		return []interface{}{value}
+	}
+}
+
+func stringify(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// nodeField returns the value of a node's field.
+func nodeField(node *graph.Node, field []string) (interface{}, bool) {
+	switch field[0] {
+	case "id":
+		return node.ID, true
+	case "type":
+		return string(node.Type), true
+	case "name":
+		return node.Name, true
+	case "component":
+		return node.Component, true
+	case "instance":
+		if node.Instance == nil {
+			return nil, false
+		}
+		return node.Instance.Name, true
+	case "depends_on":
+		return node.DependsOn, true
+	case "dependents":
+		return node.DependedOnBy, true
+	case "inputs":
+		return lookupPath(node.Inputs, field[1:])
+	}
+	return nil, false
+}
+
+// lookupPath returns the value at a path of nested maps and lists.
+func lookupPath(value interface{}, path []string) (interface{}, bool) {
+	for _, key := range path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case map[string]string:
+			next, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func nodeMatch(node *graph.Node) NodeMatch {
+	m := NodeMatch{
+		ID:        node.ID,
+		Type:      string(node.Type),
+		Component: node.Component,
+		Name:      node.Name,
+		Inputs:    node.Inputs,
+	}
+	if node.Instance != nil {
+		m.Instance = node.Instance.Name
+	}
+	return m
+}
@@ -0,0 +1,135 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildTestGraph() *graph.Graph {
+	g := graph.NewGraph("staging", "my-dc")
+
+	db := graph.NewNode(graph.NodeTypeDatabase, "api", "main")
+	db.SetInput("type", "postgres:16")
+	cache := graph.NewNode(graph.NodeTypeDatabase, "api", "cache")
+	cache.SetInput("type", "redis:7")
+	deploy := graph.NewNode(graph.NodeTypeDeployment, "api", "api")
+	deploy.SetInput("environment", map[string]interface{}{"LOG_LEVEL": "info"})
+	deploy.SetInput("ports", []interface{}{8080, 9090})
+	publicRoute := graph.NewNode(graph.NodeTypeRoute, "api", "public")
+	publicRoute.SetInput("internal", false)
+	adminRoute := graph.NewNode(graph.NodeTypeRoute, "admin", "main")
+	adminRoute.SetInput("internal", true)
+
+	for _, n := range []*graph.Node{db, cache, deploy, publicRoute, adminRoute} {
+		_ = g.AddNode(n)
+	}
+	_ = g.AddEdge(deploy.ID, db.ID)
+	_ = g.AddEdge(deploy.ID, cache.ID)
+	_ = g.AddEdge(publicRoute.ID, deploy.ID)
+	_ = g.AddEdge(adminRoute.ID, deploy.ID)
+	return g
+}
+
+func nodeIDs(r *Result) []string {
+	var ids []string
+	for _, n := range r.Nodes {
+		ids = append(ids, n.ID)
+	}
+	return ids
+}
+
+func TestRun_Nodes(t *testing.T) {
+	g := buildTestGraph()
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{`nodes()`, []string{"admin/route/main", "api/database/cache", "api/database/main", "api/deployment/api", "api/route/public"}},
+		{`nodes(type=database)`, []string{"api/database/cache", "api/database/main"}},
+		{`nodes(type=database, inputs.type=~"postgres")`, []string{"api/database/main"}},
+		{`nodes(type = database, inputs.type !~ "^postgres")`, []string{"api/database/cache"}},
+		{`nodes(type=route, inputs.internal=false)`, []string{"api/route/public"}},
+		{`nodes(component!=api)`, []string{"admin/route/main"}},
+		{`nodes(inputs.environment.LOG_LEVEL=info)`, []string{"api/deployment/api"}},
+		{`nodes(inputs.ports=9090)`, []string{"api/deployment/api"}},
+		{`nodes(inputs.ports.0=8080)`, []string{"api/deployment/api"}},
+		{`nodes(depends_on=api/database/main)`, []string{"api/deployment/api"}},
+		{`nodes(dependents="admin/route/main")`, []string{"api/deployment/api"}},
+		{`nodes(type=deployment, inputs.replicas=3)`, nil},
+		{`nodes(type=deployment, inputs.replicas!=3)`, []string{"api/deployment/api"}},
+		{`nodes(instance=canary)`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := Parse(tt.query)
+			require.NoError(t, err)
+			result := q.Run(g)
+			assert.Equal(t, tt.want, nodeIDs(result))
+			assert.Equal(t, len(tt.want), result.Count)
+			assert.Equal(t, tt.query, result.Query)
+		})
+	}
+}
+
+func TestRun_Edges(t *testing.T) {
+	q, err := Parse(`edges(from.type=route, from.inputs.internal=false, to.component=api)`)
+	require.NoError(t, err)
+
+	result := q.Run(buildTestGraph())
+	assert.Equal(t, []EdgeMatch{{From: "api/route/public", To: "api/deployment/api"}}, result.Edges)
+	assert.Equal(t, 1, result.Count)
+	assert.Empty(t, result.Nodes)
+
+	q, err = Parse(`edges(to.type=database)`)
+	require.NoError(t, err)
+	assert.Equal(t, 2, q.Run(buildTestGraph()).Count)
+}
+
+func TestResult_JSON(t *testing.T) {
+	q, err := Parse(`nodes(type=route, inputs.internal=false)`)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(q.Run(buildTestGraph()))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"query": "nodes(type=route, inputs.internal=false)",
+		"kind": "nodes",
+		"count": 1,
+		"nodes": [{"id": "api/route/public", "type": "route", "component": "api", "name": "public", "inputs": {"internal": false}}]
+	}`, string(data))
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		query  string
+		offset int
+	}{
+		{`resources(type=database)`, 0},
+		{`nodes`, 5},
+		{`nodes(type=database`, 19},
+		{`nodes(type=database) extra`, 21},
+		{`nodes(color=red)`, 6},
+		{`nodes(inputs=x)`, 6},
+		{`nodes(type.name=x)`, 6},
+		{`nodes(type>3)`, 10},
+		{`nodes(type=)`, 11},
+		{`nodes(type="database)`, 11},
+		{`nodes(name=~"(")`, 15},
+		{`edges(type=route)`, 6},
+		{`edges(from=route)`, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := Parse(tt.query)
+			var syntaxErr *SyntaxError
+			require.True(t, errors.As(err, &syntaxErr), "expected a syntax error, got %v", err)
+			assert.Equal(t, tt.offset, syntaxErr.Offset)
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/schema/component"
+)
+
+// ReferenceError describes an expression that references something the
+// component does not declare, such as ${{ services.ap.url }} when the only
+// service is named "api".
+type ReferenceError struct {
+	// Component is the name of the component containing the expression.
+	Component string
+	// Location is the YAML path of the field holding the expression
+	// (e.g., "deployments.api.environment.API_URL").
+	Location string
+	// Expression is the offending reference without the ${{ }} delimiters.
+	Expression string
+	// Message explains what could not be resolved.
+	Message string
+}
+
+func (e ReferenceError) Error() string {
+	return fmt.Sprintf("%s: ${{ %s }}: %s", e.Location, e.Expression, e.Message)
+}
+
+// ReferenceErrors is returned by the builder when a component contains one or
+// more unresolvable expression references.
+type ReferenceErrors []ReferenceError
+
+func (e ReferenceErrors) Error() string {
+	if len(e) == 0 {
+		return "no reference errors"
+	}
+	msgs := make([]string, len(e))
+	for i, re := range e {
+		msgs[i] = re.Error()
+	}
+	return fmt.Sprintf("component %q has invalid references:\n  %s", e[0].Component, strings.Join(msgs, "\n  "))
+}
+
+// referenceScope holds the names a component's expressions may refer to,
+// keyed by expression root (e.g., "databases" → {"main": true}).
+type referenceScope map[string]map[string]bool
+
+// referenceKinds maps expression roots to the singular noun used in errors.
+var referenceKinds = map[string]string{
+	"builds":         "build",
+	"databases":      "database",
+	"buckets":        "bucket",
+	"encryptionKeys": "encryption key",
+	"smtp":           "SMTP connection",
+	"external":       "external service",
+	"services":       "service",
+	"routes":         "route",
+	"functions":      "function",
+	"ports":          "port",
+	"dependencies":   "dependency",
+	"platform":       "platform export",
+}
+
+func newReferenceScope(comp component.Component, platformExports map[string]bool) referenceScope {
+	scope := make(referenceScope)
+	add := func(root, name string) {
+		if scope[root] == nil {
+			scope[root] = make(map[string]bool)
+		}
+		scope[root][name] = true
+	}
+
+	for _, build := range comp.Builds() {
+		add("builds", build.Name())
+	}
+	for _, db := range comp.Databases() {
+		add("databases", db.Name())
+	}
+	for _, bucket := range comp.Buckets() {
+		add("buckets", bucket.Name())
+	}
+	for _, ek := range comp.EncryptionKeys() {
+		add("encryptionKeys", ek.Name())
+	}
+	for _, smtp := range comp.SMTP() {
+		add("smtp", smtp.Name())
+	}
+	for _, ext := range comp.External() {
+		add("external", ext.Name())
+	}
+	for _, p := range comp.Ports() {
+		add("ports", p.Name())
+	}
+	for _, svc := range comp.Services() {
+		add("services", svc.Name())
+	}
+	for _, route := range comp.Routes() {
+		add("routes", route.Name())
+	}
+	for _, fn := range comp.Functions() {
+		add("functions", fn.Name())
+		// Container functions with a build get an implicit "<name>-build" node.
+		if fn.IsContainerBased() && fn.Container().Build() != nil {
+			add("builds", fn.Name()+"-build")
+		}
+	}
+	for _, cron := range comp.Cronjobs() {
+		if cron.Build() != nil {
+			add("builds", cron.Name()+"-build")
+		}
+	}
+	for _, dep := range comp.Dependencies() {
+		add("dependencies", dep.Name())
+	}
+	if platformExports != nil {
+		scope["platform"] = platformExports
+	}
+	return scope
+}
+
+// validateReferences checks every expression in the fields the builder scans
+// for dependencies, plus component outputs, against the resources and
+// dependencies the component declares. Without this check a typo such as
+// ${{ services.ap.url }} only surfaces at execution time as an empty value.
+// platformExports are the datacenter's exports, or nil if they aren't known.
+func validateReferences(componentName string, comp component.Component, platformExports map[string]bool) error {
+	scope := newReferenceScope(comp, platformExports)
+	hasObservability := comp.Observability() != nil
+
+	var errs ReferenceErrors
+	check := func(location, value string) {
+		for _, dep := range extractDependencies(value) {
+			ref := strings.TrimSpace(strings.SplitN(dep, "|", 2)[0])
+			if msg := scope.check(ref, hasObservability); msg != "" {
+				errs = append(errs, ReferenceError{
+					Component:  componentName,
+					Location:   location,
+					Expression: dep,
+					Message:    msg,
+				})
+			}
+		}
+	}
+	checkEnv := func(prefix string, env map[string]string) {
+		keys := make([]string, 0, len(env))
+		for k := range env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			check(prefix+".environment."+k, env[k])
+		}
+	}
+	checkEnvFrom := func(prefix string, files []component.EnvFile) {
+		for _, f := range files {
+			env := f.Environment()
+			keys := make([]string, 0, len(env))
+			for k := range env {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				check(prefix+".envFrom["+f.Path()+"]."+k, env[k])
+			}
+		}
+	}
+
+	checkReadiness := func(location string, r component.Readiness) {
+		if r == nil {
+			return
+		}
+		check(location+".readiness.http", r.HTTP())
+		check(location+".readiness.tcp", r.TCP())
+		for _, arg := range r.Command() {
+			check(location+".readiness.command", arg)
+		}
+	}
+
+	for _, db := range comp.Databases() {
+		if db.Migrations() != nil {
+			checkEnv("databases."+db.Name()+".migrations", db.Migrations().Environment())
+		}
+		checkReadiness("databases."+db.Name(), db.Readiness())
+	}
+	for _, deploy := range comp.Deployments() {
+		check("deployments."+deploy.Name()+".image", deploy.Image())
+		checkEnv("deployments."+deploy.Name(), deploy.Environment())
+		checkEnvFrom("deployments."+deploy.Name(), deploy.EnvFrom())
+		for _, f := range deploy.Files() {
+			check("deployments."+deploy.Name()+".files["+f.MountPath()+"]", f.Content())
+		}
+		checkReadiness("deployments."+deploy.Name(), deploy.Readiness())
+	}
+	for _, fn := range comp.Functions() {
+		check("functions."+fn.Name()+".port", fn.Port())
+		checkEnv("functions."+fn.Name(), fn.Environment())
+		checkEnvFrom("functions."+fn.Name(), fn.EnvFrom())
+		checkReadiness("functions."+fn.Name(), fn.Readiness())
+	}
+	for _, svc := range comp.Services() {
+		check("services."+svc.Name()+".port", svc.Port())
+		checkReadiness("services."+svc.Name(), svc.Readiness())
+	}
+	for _, cron := range comp.Cronjobs() {
+		checkEnv("cronjobs."+cron.Name(), cron.Environment())
+	}
+	for _, out := range comp.Outputs() {
+		check("outputs."+out.Name()+".value", out.Value())
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// check returns a message describing why ref cannot be resolved, or "" if
+// it refers to something in scope. Roots that are not backed by named
+// resources (variables, dependents) are not checked here.
+func (s referenceScope) check(ref string, hasObservability bool) string {
+	parts := strings.Split(ref, ".")
+	root := parts[0]
+
+	switch root {
+	case "variables", "dependents":
+		return ""
+	case "observability":
+		if !hasObservability {
+			return "observability is not configured for this component"
+		}
+		return ""
+	}
+
+	kind, ok := referenceKinds[root]
+	if !ok {
+		return fmt.Sprintf("unknown reference type %q", root)
+	}
+	if len(parts) < 2 || parts[1] == "" {
+		return fmt.Sprintf("missing %s name", kind)
+	}
+	name := parts[1]
+	if s[root][name] {
+		return ""
+	}
+	if root == "platform" {
+		if _, known := s[root]; !known {
+			return ""
+		}
+		return fmt.Sprintf("references %s %q, which the datacenter doesn't export%s", kind, name, suggestName(name, s[root]))
+	}
+	return fmt.Sprintf("references unknown %s %q%s", kind, name, suggestName(name, s[root]))
+}
+
+// suggestName returns a "did you mean" hint for the closest declared name.
+func suggestName(name string, declared map[string]bool) string {
+	best, bestDist := "", -1
+	for candidate := range declared {
+		d := levenshtein(name, candidate)
+		if bestDist < 0 || d < bestDist || (d == bestDist && candidate < best) {
+			best, bestDist = candidate, d
+		}
+	}
+	if best == "" || bestDist > len(name)/2+1 {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
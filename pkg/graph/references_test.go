@@ -0,0 +1,269 @@
+package graph
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBuilder_AddComponent_UnknownServiceReference(t *testing.T) {
+	builder := NewBuilder("test-env", "test-dc")
+
+	comp := loadComponent(t, `
+deployments:
+  api:
+    image: api:latest
+  web:
+    image: web:latest
+    environment:
+      API_URL: ${{ services.ap.url }}
+
+services:
+  api:
+    deployment: api
+    port: 8080
+`)
+
+	err := builder.AddComponent("app", comp)
+	if err == nil {
+		t.Fatal("expected error for reference to unknown service")
+	}
+
+	var refErrs ReferenceErrors
+	if !errors.As(err, &refErrs) {
+		t.Fatalf("expected ReferenceErrors, got %T: %v", err, err)
+	}
+	if len(refErrs) != 1 {
+		t.Fatalf("expected 1 reference error, got %d: %v", len(refErrs), err)
+	}
+	re := refErrs[0]
+	if re.Component != "app" {
+		t.Errorf("expected component app, got %q", re.Component)
+	}
+	if re.Location != "deployments.web.environment.API_URL" {
+		t.Errorf("unexpected location %q", re.Location)
+	}
+	if re.Expression != "services.ap.url" {
+		t.Errorf("unexpected expression %q", re.Expression)
+	}
+	if !strings.Contains(err.Error(), `unknown service "ap"`) || !strings.Contains(err.Error(), `did you mean "api"`) {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestBuilder_AddComponent_ReferenceValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "valid references",
+			yaml: `
+databases:
+  main:
+    type: postgres:^16
+    migrations:
+      image: migrate:latest
+      environment:
+        DATABASE_URL: ${{ databases.main.url }}
+ports:
+  api:
+    description: API port
+deployments:
+  api:
+    image: api:latest
+    environment:
+      DATABASE_URL: ${{ databases.main.url }}
+      LOG_LEVEL: ${{ variables.log_level }}
+      AUTH_URL: ${{ dependencies.auth.url }}
+      PORT: ${{ ports.api.port }}
+services:
+  api:
+    deployment: api
+    port: ${{ ports.api.port }}
+dependencies:
+  auth: ghcr.io/myorg/auth:v1
+variables:
+  log_level:
+    default: info
+outputs:
+  url:
+    value: ${{ services.api.url }}
+`,
+		},
+		{
+			name: "unknown database in migration environment",
+			yaml: `
+databases:
+  main:
+    type: postgres:^16
+    migrations:
+      image: migrate:latest
+      environment:
+        CACHE_URL: ${{ databases.cache.url }}
+`,
+			wantErr: `databases.main.migrations.environment.CACHE_URL: ${{ databases.cache.url }}: references unknown database "cache"`,
+		},
+		{
+			name: "undeclared dependency",
+			yaml: `
+deployments:
+  api:
+    image: api:latest
+    environment:
+      AUTH_URL: ${{ dependencies.auth.url }}
+`,
+			wantErr: `references unknown dependency "auth"`,
+		},
+		{
+			name: "unknown reference type",
+			yaml: `
+deployments:
+  api:
+    image: api:latest
+    environment:
+      DB: ${{ database.main.url }}
+`,
+			wantErr: `unknown reference type "database"`,
+		},
+		{
+			name: "observability not configured",
+			yaml: `
+deployments:
+  api:
+    image: api:latest
+    environment:
+      OTEL_ENDPOINT: ${{ observability.endpoint }}
+`,
+			wantErr: "observability is not configured",
+		},
+		{
+			name: "unknown output reference",
+			yaml: `
+buckets:
+  uploads:
+    type: s3
+outputs:
+  bucket:
+    value: ${{ buckets.upload.endpoint }}
+`,
+			wantErr: `outputs.bucket.value: ${{ buckets.upload.endpoint }}: references unknown bucket "upload"`,
+		},
+		{
+			name: "unknown external service",
+			yaml: `
+variables:
+  stripe_key:
+    sensitive: true
+external:
+  stripe:
+    url: https://api.stripe.com
+    credentials:
+      api_key: ${{ variables.stripe_key }}
+deployments:
+  api:
+    image: api:latest
+    environment:
+      STRIPE_API_KEY: ${{ external.strip.api_key }}
+`,
+			wantErr: `references unknown external service "strip" (did you mean "stripe"?)`,
+		},
+		{
+			name: "computed expressions",
+			yaml: `
+ports:
+  api:
+    description: API port
+deployments:
+  api:
+    image: api:latest
+    environment:
+      METRICS_PORT: ${{ ports.api.port + 1 }}
+      STACK: ${{ format("%s-%s", variables.region, upper(variables.tier)) }}
+variables:
+  region:
+    default: us-east-1
+  tier:
+    default: prod
+`,
+		},
+		{
+			name: "unknown port in computed expression",
+			yaml: `
+ports:
+  api:
+    description: API port
+deployments:
+  api:
+    image: api:latest
+    environment:
+      METRICS_PORT: ${{ ports.metrics.port + 1 }}
+`,
+			wantErr: `references unknown port "metrics"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder("test-env", "test-dc")
+			err := builder.AddComponent("app", loadComponent(t, tt.yaml))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error containing %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestBuilder_AddComponent_PlatformReferences(t *testing.T) {
+	yaml := `
+deployments:
+  api:
+    image: api:latest
+    environment:
+      VPC_ID: ${{ platform.vpc_ud }}
+`
+
+	// Without the datacenter's exports, platform references aren't checked
+	builder := NewBuilder("test-env", "test-dc")
+	if err := builder.AddComponent("app", loadComponent(t, yaml)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	builder = NewBuilder("test-env", "test-dc")
+	builder.SetPlatformExports([]string{"vpc_id", "region"})
+	err := builder.AddComponent("app", loadComponent(t, yaml))
+	if err == nil {
+		t.Fatal("expected error for a reference to a name the datacenter doesn't export")
+	}
+	if !strings.Contains(err.Error(), `references platform export "vpc_ud", which the datacenter doesn't export`) ||
+		!strings.Contains(err.Error(), `did you mean "vpc_id"`) {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestBuilder_AddComponentWithInstances_ValidatesReferences(t *testing.T) {
+	builder := NewBuilder("test-env", "test-dc")
+
+	comp := loadComponent(t, `
+deployments:
+  api:
+    image: api:latest
+    environment:
+      REDIS_URL: ${{ databases.redis.url }}
+`)
+
+	err := builder.AddComponentWithInstances("app", comp, []InstanceInfo{{Name: "blue", Weight: 100}}, nil)
+	if err == nil || !strings.Contains(err.Error(), `unknown database "redis"`) {
+		t.Fatalf("expected unknown database error, got: %v", err)
+	}
+}
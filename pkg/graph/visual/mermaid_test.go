@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/davidthor/cldctl/pkg/engine/planner"
 	"github.com/davidthor/cldctl/pkg/graph"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -180,3 +181,19 @@ func TestEscapeMermaidLabel(t *testing.T) {
 	assert.Equal(t, `hello #quot;world#quot;`, escapeMermaidLabel(`hello "world"`))
 	assert.Equal(t, "simple", escapeMermaidLabel("simple"))
 }
+
+func TestRenderWavesMermaid(t *testing.T) {
+	g := buildTestGraph()
+	plan, err := planner.NewPlanner().Plan(g, nil)
+	require.NoError(t, err)
+
+	result := RenderWavesMermaid(plan.Waves())
+
+	assert.True(t, strings.HasPrefix(result, "flowchart LR\n"))
+	assert.Contains(t, result, `subgraph wave1 ["Wave 1"]`)
+	assert.Contains(t, result, `my-app--database--main["create my-app/database/main"]`)
+	assert.Contains(t, result, `subgraph wave4 ["Wave 4"]`)
+	// The plan is a single chain, so every link is on the critical path
+	assert.Contains(t, result, "my-app--deployment--api ==> my-app--service--api")
+	assert.NotContains(t, result, "-->")
+}
@@ -0,0 +1,49 @@
+package visual
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+)
+
+// RenderWavesMermaid generates a Mermaid flowchart of a plan's execution
+// waves (see planner.Plan.Waves). Each wave is a subgraph, changes are linked
+// to the changes they wait on, and the links of the critical path are drawn
+// thick.
+func RenderWavesMermaid(s *planner.Schedule) string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	critical := make(map[string]string, len(s.CriticalPath))
+	for i := 1; i < len(s.CriticalPath); i++ {
+		critical[s.CriticalPath[i]] = s.CriticalPath[i-1]
+	}
+
+	for i, wave := range s.Waves {
+		label := fmt.Sprintf("Wave %d", i+1)
+		if wave.Estimate > 0 {
+			label += fmt.Sprintf(" (~%s)", wave.Estimate.Round(time.Second))
+		}
+		b.WriteString(fmt.Sprintf("    subgraph wave%d [\"%s\"]\n", i+1, escapeMermaidLabel(label)))
+		for _, c := range wave.Changes {
+			b.WriteString(fmt.Sprintf("        %s[\"%s %s\"]\n", sanitizeMermaidID(c.Node), c.Action, escapeMermaidLabel(c.Node.ID)))
+		}
+		b.WriteString("    end\n\n")
+	}
+
+	for _, wave := range s.Waves {
+		for _, c := range wave.Changes {
+			for _, dep := range c.After {
+				arrow := "-->"
+				if critical[c.Node.ID] == dep {
+					arrow = "==>"
+				}
+				b.WriteString(fmt.Sprintf("    %s %s %s\n", strings.ReplaceAll(dep, "/", "--"), arrow, sanitizeMermaidID(c.Node)))
+			}
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,334 @@
+// Package ansible implements an IaC plugin that runs Ansible playbooks.
+//
+// Module inputs are passed as extra-vars, the JSON stdout callback is parsed
+// for per-task change detection and failures, and facts set with set_fact
+// become module outputs. This covers datacenters that manage VMs and bare
+// metal where Terraform providers are a poor fit.
+package ansible
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/logging"
+)
+
+// pluginLog is the logger for the commands the plugin runs.
+var pluginLog = logging.Logger(logging.Plugins)
+
+func init() {
+	iac.Register("ansible", func() (iac.Plugin, error) {
+		return NewPlugin()
+	})
+}
+
+// Playbook and inventory file names looked up in the module directory.
+var (
+	applyPlaybooks   = []string{"playbook.yml", "playbook.yaml", "site.yml", "site.yaml"}
+	destroyPlaybooks = []string{"destroy.yml", "destroy.yaml"}
+	inventoryFiles   = []string{"inventory", "inventory.ini", "inventory.yml", "inventory.yaml", "hosts"}
+)
+
+// inventoryInput is the module input that overrides the inventory. It may be
+// a path or a comma-separated host list (e.g. "10.0.0.5,10.0.0.6,").
+const inventoryInput = "inventory"
+
+// runFunc executes ansible-playbook and returns its stdout.
+type runFunc func(ctx context.Context, dir string, args []string, opts iac.RunOptions) ([]byte, error)
+
+// Plugin implements the IaC plugin interface for Ansible.
+type Plugin struct {
+	binaryPath string
+	run        runFunc
+}
+
+// NewPlugin creates a new Ansible plugin instance.
+func NewPlugin() (*Plugin, error) {
+	binaryPath, err := exec.LookPath("ansible-playbook")
+	if err != nil {
+		return nil, fmt.Errorf("ansible-playbook binary not found: %w", err)
+	}
+	p := &Plugin{binaryPath: binaryPath}
+	p.run = p.runPlaybook
+	return p, nil
+}
+
+func (p *Plugin) Name() string {
+	return "ansible"
+}
+
+// State is the persisted state of an Ansible module.
+type State struct {
+	Playbook string                 `json:"playbook"`
+	Inputs   map[string]interface{} `json:"inputs"`
+	Outputs  map[string]interface{} `json:"outputs"`
+	Stats    map[string]HostStats   `json:"stats"`
+}
+
+func (p *Plugin) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResult, error) {
+	dir := moduleDir(opts)
+	playbook, err := findFile(dir, applyPlaybooks)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.OnProgress != nil {
+		opts.OnProgress(iac.PhaseMessage(iac.PhaseConfiguring, fmt.Sprintf("running %s", filepath.Base(playbook))))
+	}
+	report, err := p.execute(ctx, dir, playbook, opts, false)
+	if err != nil {
+		return nil, err
+	}
+
+	facts := report.Facts()
+	outputs := make(map[string]iac.OutputValue, len(facts))
+	for k, v := range facts {
+		outputs[k] = iac.OutputValue{Value: v}
+	}
+
+	state, err := json.Marshal(&State{
+		Playbook: filepath.Base(playbook),
+		Inputs:   opts.Inputs,
+		Outputs:  facts,
+		Stats:    report.Stats,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize state: %w", err)
+	}
+
+	return &iac.ApplyResult{
+		Outputs: outputs,
+		State:   state,
+	}, nil
+}
+
+// Destroy runs destroy.yml when the module provides one. Playbooks have no
+// implicit inverse, so modules without a destroy playbook are a no-op.
+func (p *Plugin) Destroy(ctx context.Context, opts iac.RunOptions) error {
+	dir := moduleDir(opts)
+	playbook, err := findFile(dir, destroyPlaybooks)
+	if err != nil {
+		if opts.Stderr != nil {
+			fmt.Fprintf(opts.Stderr, "warning: no destroy playbook in %s; nothing to tear down\n", dir)
+		}
+		return nil
+	}
+
+	// Prefer the inputs recorded at apply time so teardown targets the same hosts.
+	if state, _ := loadState(opts.StateReader); state != nil && len(opts.Inputs) == 0 {
+		opts.Inputs = state.Inputs
+	}
+
+	if opts.OnProgress != nil {
+		opts.OnProgress(iac.PhaseMessage(iac.PhaseConfiguring, fmt.Sprintf("running %s", filepath.Base(playbook))))
+	}
+	_, err = p.execute(ctx, dir, playbook, opts, false)
+	return err
+}
+
+// Preview runs the playbook in check mode and reports tasks that would change.
+func (p *Plugin) Preview(ctx context.Context, opts iac.RunOptions) (*iac.PreviewResult, error) {
+	dir := moduleDir(opts)
+	playbook, err := findFile(dir, applyPlaybooks)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := p.execute(ctx, dir, playbook, opts, true)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &iac.PreviewResult{}
+	for _, change := range report.ChangedTasks() {
+		result.Changes = append(result.Changes, iac.ResourceChange{
+			ResourceID:   change.Host + ":" + change.Task,
+			ResourceType: change.Action,
+			Action:       iac.ActionUpdate,
+		})
+		result.Summary.Update++
+	}
+	return result, nil
+}
+
+// Refresh runs the playbook in check mode; any task that would change is drift.
+func (p *Plugin) Refresh(ctx context.Context, opts iac.RunOptions) (*iac.RefreshResult, error) {
+	state, err := loadState(opts.StateReader)
+	if err != nil {
+		return nil, err
+	}
+	if state != nil && len(opts.Inputs) == 0 {
+		opts.Inputs = state.Inputs
+	}
+
+	dir := moduleDir(opts)
+	playbook, err := findFile(dir, applyPlaybooks)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := p.execute(ctx, dir, playbook, opts, true)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &iac.RefreshResult{}
+	for _, change := range report.ChangedTasks() {
+		result.Drifts = append(result.Drifts, iac.ResourceDrift{
+			ResourceID:   change.Host + ":" + change.Task,
+			ResourceType: change.Action,
+		})
+	}
+	if state != nil {
+		result.State, _ = json.Marshal(state)
+	}
+	return result, nil
+}
+
+func (p *Plugin) Import(ctx context.Context, opts iac.ImportOptions) (*iac.ImportResult, error) {
+	return nil, fmt.Errorf("the ansible plugin does not support import: playbooks are idempotent, run apply to converge existing hosts")
+}
+
+// execute runs a playbook with inputs as extra-vars and parses the JSON report.
+func (p *Plugin) execute(ctx context.Context, dir, playbook string, opts iac.RunOptions, check bool) (*Report, error) {
+	varsFile, err := writeExtraVars(opts.Inputs)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(varsFile)
+
+	args := []string{filepath.Base(playbook), "--extra-vars", "@" + varsFile}
+	if inventory := resolveInventory(dir, opts.Inputs); inventory != "" {
+		args = append(args, "-i", inventory)
+	}
+	if check {
+		args = append(args, "--check", "--diff")
+	}
+
+	out, runErr := p.run(ctx, dir, args, opts)
+
+	report, parseErr := ParseReport(out)
+	if parseErr != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("ansible-playbook failed: %w", runErr)
+		}
+		return nil, parseErr
+	}
+	if failed := report.Failures(); len(failed) > 0 {
+		return report, fmt.Errorf("ansible-playbook failed:\n  %s", strings.Join(failed, "\n  "))
+	}
+	if runErr != nil {
+		return report, fmt.Errorf("ansible-playbook failed: %w", runErr)
+	}
+	return report, nil
+}
+
+func (p *Plugin) runPlaybook(ctx context.Context, dir string, args []string, opts iac.RunOptions) ([]byte, error) {
+	pluginLog.Debug("running ansible-playbook", "dir", dir, "args", args)
+	cmd := exec.CommandContext(ctx, p.binaryPath, args...)
+	cmd.Dir = dir
+
+	cmd.Env = os.Environ()
+	for k, v := range opts.Environment {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+	}
+	// Emit a single machine-readable JSON document instead of the default
+	// human-oriented output.
+	cmd.Env = append(cmd.Env,
+		"ANSIBLE_STDOUT_CALLBACK=json",
+		"ANSIBLE_LOAD_CALLBACK_PLUGINS=1",
+		"ANSIBLE_NOCOLOR=1",
+		"ANSIBLE_HOST_KEY_CHECKING=False",
+	)
+
+	// Stdout is machine-readable output, so it isn't streamed
+	return iac.RunCommand(cmd, nil, opts.Stderr)
+}
+
+func moduleDir(opts iac.RunOptions) string {
+	if opts.WorkDir != "" {
+		return opts.WorkDir
+	}
+	return opts.ModuleSource
+}
+
+func findFile(dir string, candidates []string) (string, error) {
+	for _, name := range candidates {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no %s found in %s", strings.Join(candidates, " or "), dir)
+}
+
+// resolveInventory returns the inventory argument: the "inventory" input if
+// set, otherwise a conventional inventory file in the module directory.
+func resolveInventory(dir string, inputs map[string]interface{}) string {
+	if v, ok := inputs[inventoryInput].(string); ok && v != "" {
+		return v
+	}
+	if path, err := findFile(dir, inventoryFiles); err == nil {
+		return filepath.Base(path)
+	}
+	return ""
+}
+
+func writeExtraVars(inputs map[string]interface{}) (string, error) {
+	if inputs == nil {
+		inputs = map[string]interface{}{}
+	}
+	data, err := json.Marshal(inputs)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode extra-vars: %w", err)
+	}
+	f, err := os.CreateTemp("", "cldctl-ansible-vars-*.json")
+	if err != nil {
+		return "", fmt.Errorf("failed to write extra-vars: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		return "", fmt.Errorf("failed to write extra-vars: %w", err)
+	}
+	return f.Name(), nil
+}
+
+func loadState(reader io.Reader) (*State, error) {
+	if reader == nil {
+		return nil, nil
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	return &state, nil
+}
+
+// sortedHosts returns the keys of a host-indexed map in a stable order.
+func sortedHosts[V any](m map[string]V) []string {
+	hosts := make([]string, 0, len(m))
+	for h := range m {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// Ensure we implement the Plugin interface
+var _ iac.Plugin = (*Plugin)(nil)
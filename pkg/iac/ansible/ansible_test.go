@@ -0,0 +1,159 @@
+package ansible
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleReport = `[WARNING]: provided hosts list is empty
+{
+  "plays": [{
+    "play": {"name": "configure"},
+    "tasks": [
+      {"task": {"name": "Gathering Facts"}, "hosts": {"web1": {"action": "gather_facts", "ansible_facts": {"ansible_hostname": "web1"}}}},
+      {"task": {"name": "install nginx"}, "hosts": {
+        "web1": {"action": "apt", "changed": true},
+        "web2": {"action": "apt", "changed": false}
+      }},
+      {"task": {"name": "export endpoint"}, "hosts": {
+        "web2": {"action": "set_fact", "ansible_facts": {"endpoint": "http://web2"}},
+        "web1": {"action": "ansible.builtin.set_fact", "ansible_facts": {"endpoint": "http://web1", "port": 80}}
+      }}
+    ]
+  }],
+  "stats": {
+    "web1": {"ok": 3, "changed": 1, "failures": 0, "unreachable": 0},
+    "web2": {"ok": 2, "changed": 0, "failures": 0, "unreachable": 0}
+  }
+}`
+
+const failedReport = `{
+  "plays": [{"play": {"name": "p"}, "tasks": [
+    {"task": {"name": "start service"}, "hosts": {"db1": {"action": "service", "failed": true, "msg": "unit not found"}}}
+  ]}],
+  "stats": {
+    "db1": {"ok": 0, "changed": 0, "failures": 1, "unreachable": 0},
+    "db2": {"ok": 0, "changed": 0, "failures": 0, "unreachable": 1}
+  }
+}`
+
+func TestParseReport(t *testing.T) {
+	report, err := ParseReport([]byte(sampleReport))
+	require.NoError(t, err)
+
+	assert.True(t, report.Changed())
+	assert.Equal(t, []TaskChange{{Host: "web1", Task: "install nginx", Action: "apt"}}, report.ChangedTasks())
+	assert.Empty(t, report.Failures())
+
+	facts := report.Facts()
+	assert.Equal(t, "http://web1", facts["endpoint"], "first host in sorted order wins")
+	assert.Equal(t, float64(80), facts["port"])
+	assert.NotContains(t, facts, "ansible_hostname", "gathered facts are not outputs")
+}
+
+func TestReport_Failures(t *testing.T) {
+	report, err := ParseReport([]byte(failedReport))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"start service [db1]: unit not found",
+		"host db2: 0 failed, 1 unreachable",
+	}, report.Failures())
+}
+
+func TestParseReport_NoJSON(t *testing.T) {
+	_, err := ParseReport([]byte("ERROR! the playbook could not be found"))
+	require.Error(t, err)
+}
+
+type fakeRun struct {
+	args   []string
+	vars   map[string]interface{}
+	output string
+	err    error
+}
+
+func (f *fakeRun) run(ctx context.Context, dir string, args []string, opts iac.RunOptions) ([]byte, error) {
+	f.args = args
+	for i, a := range args {
+		if a == "--extra-vars" {
+			data, _ := os.ReadFile(args[i+1][1:])
+			_ = json.Unmarshal(data, &f.vars)
+		}
+	}
+	return []byte(f.output), f.err
+}
+
+func newModule(t *testing.T, files ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, f := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, f), []byte("- hosts: all\n"), 0644))
+	}
+	return dir
+}
+
+func TestPlugin_Apply(t *testing.T) {
+	dir := newModule(t, "playbook.yml", "inventory.ini")
+	fake := &fakeRun{output: sampleReport}
+	p := &Plugin{run: fake.run}
+
+	result, err := p.Apply(context.Background(), iac.RunOptions{
+		ModuleSource: dir,
+		Inputs:       map[string]interface{}{"version": "1.25"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "playbook.yml", fake.args[0])
+	assert.Contains(t, fake.args, "inventory.ini")
+	assert.NotContains(t, fake.args, "--check")
+	assert.Equal(t, "1.25", fake.vars["version"])
+	assert.Equal(t, "http://web1", result.Outputs["endpoint"].Value)
+
+	var state State
+	require.NoError(t, json.Unmarshal(result.State, &state))
+	assert.Equal(t, 1, state.Stats["web1"].Changed)
+}
+
+func TestPlugin_ApplyFailure(t *testing.T) {
+	dir := newModule(t, "site.yml")
+	p := &Plugin{run: (&fakeRun{output: failedReport, err: fmt.Errorf("exit status 2")}).run}
+
+	_, err := p.Apply(context.Background(), iac.RunOptions{ModuleSource: dir})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unit not found")
+}
+
+func TestPlugin_Preview(t *testing.T) {
+	dir := newModule(t, "playbook.yml")
+	fake := &fakeRun{output: sampleReport}
+	p := &Plugin{run: fake.run}
+
+	preview, err := p.Preview(context.Background(), iac.RunOptions{
+		ModuleSource: dir,
+		Inputs:       map[string]interface{}{"inventory": "10.0.0.5,"},
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, fake.args, "--check")
+	assert.Contains(t, fake.args, "10.0.0.5,")
+	assert.Equal(t, 1, preview.Summary.Update)
+	assert.Equal(t, "web1:install nginx", preview.Changes[0].ResourceID)
+}
+
+func TestPlugin_DestroyWithoutPlaybookIsNoop(t *testing.T) {
+	dir := newModule(t, "playbook.yml")
+	fake := &fakeRun{}
+	p := &Plugin{run: fake.run}
+
+	require.NoError(t, p.Destroy(context.Background(), iac.RunOptions{ModuleSource: dir}))
+	assert.Nil(t, fake.args)
+}
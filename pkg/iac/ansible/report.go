@@ -0,0 +1,162 @@
+package ansible
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Report is the document produced by Ansible's json stdout callback.
+type Report struct {
+	Plays []Play               `json:"plays"`
+	Stats map[string]HostStats `json:"stats"`
+}
+
+// Play is a single play in the report.
+type Play struct {
+	Play  PlayInfo `json:"play"`
+	Tasks []Task   `json:"tasks"`
+}
+
+// PlayInfo identifies a play.
+type PlayInfo struct {
+	Name string `json:"name"`
+}
+
+// Task is a task and its per-host results.
+type Task struct {
+	Task  TaskInfo              `json:"task"`
+	Hosts map[string]TaskResult `json:"hosts"`
+}
+
+// TaskInfo identifies a task.
+type TaskInfo struct {
+	Name string `json:"name"`
+}
+
+// TaskResult is one host's result for a task.
+type TaskResult struct {
+	Action       string                 `json:"action"`
+	Changed      bool                   `json:"changed"`
+	Failed       bool                   `json:"failed"`
+	Unreachable  bool                   `json:"unreachable"`
+	Skipped      bool                   `json:"skipped"`
+	Msg          interface{}            `json:"msg"`
+	AnsibleFacts map[string]interface{} `json:"ansible_facts"`
+}
+
+// HostStats is the play recap for a host.
+type HostStats struct {
+	Ok          int `json:"ok"`
+	Changed     int `json:"changed"`
+	Failures    int `json:"failures"`
+	Unreachable int `json:"unreachable"`
+	Skipped     int `json:"skipped"`
+	Rescued     int `json:"rescued"`
+	Ignored     int `json:"ignored"`
+}
+
+// TaskChange describes a task that changed (or would change) a host.
+type TaskChange struct {
+	Host   string
+	Task   string
+	Action string
+}
+
+// ParseReport parses json callback output. Ansible may print warnings before
+// the JSON document, so parsing starts at the first '{'.
+func ParseReport(out []byte) (*Report, error) {
+	start := bytes.IndexByte(out, '{')
+	if start < 0 {
+		return nil, fmt.Errorf("ansible-playbook produced no JSON report (is the json callback available?)")
+	}
+	var report Report
+	if err := json.Unmarshal(out[start:], &report); err != nil {
+		return nil, fmt.Errorf("failed to parse ansible JSON report: %w", err)
+	}
+	return &report, nil
+}
+
+// Changed reports whether any host recorded a change.
+func (r *Report) Changed() bool {
+	for _, s := range r.Stats {
+		if s.Changed > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangedTasks lists every task/host pair that reported a change, in play order.
+func (r *Report) ChangedTasks() []TaskChange {
+	var changes []TaskChange
+	for _, play := range r.Plays {
+		for _, task := range play.Tasks {
+			for _, host := range sortedHosts(task.Hosts) {
+				result := task.Hosts[host]
+				if result.Changed {
+					changes = append(changes, TaskChange{Host: host, Task: task.Task.Name, Action: result.Action})
+				}
+			}
+		}
+	}
+	return changes
+}
+
+// Failures describes failed or unreachable task results. Hosts that failed
+// without a task-level message (e.g. unreachable before any task ran) are
+// reported from the recap.
+func (r *Report) Failures() []string {
+	var failures []string
+	reported := map[string]bool{}
+	for _, play := range r.Plays {
+		for _, task := range play.Tasks {
+			for _, host := range sortedHosts(task.Hosts) {
+				result := task.Hosts[host]
+				if !result.Failed && !result.Unreachable {
+					continue
+				}
+				reported[host] = true
+				msg := ""
+				if result.Msg != nil {
+					msg = fmt.Sprintf(": %v", result.Msg)
+				}
+				failures = append(failures, fmt.Sprintf("%s [%s]%s", task.Task.Name, host, msg))
+			}
+		}
+	}
+	for _, host := range sortedHosts(r.Stats) {
+		s := r.Stats[host]
+		if (s.Failures > 0 || s.Unreachable > 0) && !reported[host] {
+			failures = append(failures, fmt.Sprintf("host %s: %d failed, %d unreachable", host, s.Failures, s.Unreachable))
+		}
+	}
+	return failures
+}
+
+// Facts collects facts registered with set_fact across all hosts. Gathered
+// host facts (from the setup module) are excluded. When several hosts set the
+// same fact, the first host in sorted order wins.
+func (r *Report) Facts() map[string]interface{} {
+	facts := map[string]interface{}{}
+	for _, play := range r.Plays {
+		for _, task := range play.Tasks {
+			for _, host := range sortedHosts(task.Hosts) {
+				result := task.Hosts[host]
+				if !isSetFact(result.Action) {
+					continue
+				}
+				for k, v := range result.AnsibleFacts {
+					if _, exists := facts[k]; !exists {
+						facts[k] = v
+					}
+				}
+			}
+		}
+	}
+	return facts
+}
+
+func isSetFact(action string) bool {
+	return action == "set_fact" || action == "ansible.builtin.set_fact"
+}
@@ -9,10 +9,13 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/docker/docker/api/types/build"
 	"github.com/docker/docker/client"
+
+	"github.com/davidthor/cldctl/pkg/iac/container/protocol"
 )
 
 // Builder builds container images for IaC modules.
@@ -108,6 +111,7 @@ func (b *Builder) buildImage(ctx context.Context, buildContext io.Reader, tag st
 		Dockerfile: "Dockerfile",
 		Remove:     true,
 		Platform:   "linux/amd64",
+		Labels:     map[string]string{protocol.VersionLabel: strconv.Itoa(protocol.Version)},
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to build image: %w", err)
@@ -280,10 +284,15 @@ ENTRYPOINT ["pulumi"]
 }
 
 // generateOpenTofuDockerfile generates a Dockerfile for an OpenTofu module.
-// The image packages only the tofu binary and module source files. Provider
-// downloads and state backend initialization happen at deploy time via
-// tofu init, since the execution environment needs internet access for state
-// management regardless.
+// The image packages only the tofu binary, the module source files, and the
+// module's dependency lock file. Provider downloads and state backend
+// initialization happen at deploy time via tofu init, since the execution
+// environment needs internet access for state management regardless.
+//
+// The lock file pins the provider versions (and checksums) the module is
+// deployed with. A lock file committed with the module is kept; otherwise
+// one is generated when the image is built, so every deploy of the image
+// installs the same providers.
 func generateOpenTofuDockerfile() (string, error) {
 	return `# Auto-generated Dockerfile for OpenTofu module
 # Uses multi-stage build per OpenTofu 1.10+ requirements
@@ -293,6 +302,15 @@ func generateOpenTofuDockerfile() (string, error) {
 
 FROM ghcr.io/opentofu/opentofu:minimal AS tofu
 
+# Resolve provider versions once, when the image is built, and record them
+# in the dependency lock file. An existing lock file is honored.
+FROM alpine:3.20 AS providers
+COPY --from=tofu /usr/local/bin/tofu /usr/local/bin/tofu
+RUN apk add --no-cache git ca-certificates
+WORKDIR /app
+COPY . .
+RUN tofu init -backend=false -input=false
+
 FROM alpine:3.20
 
 # Install the tofu binary from the minimal image
@@ -306,10 +324,17 @@ WORKDIR /app
 # Copy module files
 COPY . .
 
+# Pin the providers resolved above; deploys fail rather than change them
+COPY --from=providers /app/.terraform.lock.hcl ./.terraform.lock.hcl
+
 ENTRYPOINT ["tofu"]
 `, nil
 }
 
+// tofuLockFile is OpenTofu's dependency lock file, which pins provider
+// versions and checksums.
+const tofuLockFile = ".terraform.lock.hcl"
+
 // createBuildContext creates a tar archive for the Docker build context.
 func createBuildContext(moduleDir string, dockerfile string) (io.Reader, error) {
 	var buf bytes.Buffer
@@ -334,9 +359,10 @@ func createBuildContext(moduleDir string, dockerfile string) (io.Reader, error)
 			return err
 		}
 
-		// Skip hidden files/directories and common excludes
+		// Skip hidden files/directories and common excludes. OpenTofu's
+		// dependency lock file is kept, so its provider pins are honored.
 		name := info.Name()
-		if strings.HasPrefix(name, ".") {
+		if strings.HasPrefix(name, ".") && name != tofuLockFile {
 			if info.IsDir() {
 				return filepath.SkipDir
 			}
@@ -1,8 +1,11 @@
 package container
 
 import (
+	"archive/tar"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 )
@@ -123,8 +126,12 @@ func TestGenerateOpenTofuDockerfile(t *testing.T) {
 	if !strings.Contains(dockerfile, "opentofu/opentofu") {
 		t.Error("Expected opentofu base image reference")
 	}
-	if strings.Contains(dockerfile, "RUN tofu init") {
-		t.Error("RUN tofu init should not be in dockerfile (deferred to deploy time)")
+	finalStage := dockerfile[strings.LastIndex(dockerfile, "\nFROM "):]
+	if strings.Contains(finalStage, "RUN tofu init") {
+		t.Error("RUN tofu init should not be in the final image (deferred to deploy time)")
+	}
+	if !strings.Contains(finalStage, "COPY --from=providers /app/.terraform.lock.hcl") {
+		t.Error("Expected the dependency lock file to be packaged in the image")
 	}
 	if !strings.Contains(dockerfile, "ENTRYPOINT") {
 		t.Error("Expected ENTRYPOINT directive")
@@ -200,3 +207,39 @@ func TestCreateBuildContext(t *testing.T) {
 		t.Fatal("createBuildContext returned nil reader")
 	}
 }
+
+func TestCreateBuildContext_KeepsLockFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	for name, content := range map[string]string{
+		"main.tf":             "resource {}",
+		".terraform.lock.hcl": `provider "registry.opentofu.org/hashicorp/aws" {}`,
+		".gitignore":          "*.bak",
+	} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	reader, err := createBuildContext(tmpDir, "FROM alpine")
+	if err != nil {
+		t.Fatalf("createBuildContext failed: %v", err)
+	}
+
+	var names []string
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read build context: %v", err)
+		}
+		names = append(names, header.Name)
+	}
+	sort.Strings(names)
+	expected := []string{".terraform.lock.hcl", "Dockerfile", "main.tf"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected build context %v, got %v", expected, names)
+	}
+}
@@ -11,91 +11,36 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/iac/container/protocol"
 )
 
 // ModuleRequest represents the input contract for a containerized module.
 // This is passed to the container via a mounted JSON file.
-type ModuleRequest struct {
-	// Action is the operation to perform: "preview", "apply", "destroy", "refresh"
-	Action string `json:"action"`
-
-	// Inputs are the module input values
-	Inputs map[string]interface{} `json:"inputs"`
-
-	// State is the current module state (for updates/destroys)
-	State map[string]interface{} `json:"state,omitempty"`
-
-	// Environment variables to set
-	Environment map[string]string `json:"environment,omitempty"`
-
-	// StackName for Pulumi or workspace name for OpenTofu
-	StackName string `json:"stack_name,omitempty"`
-
-	// Backend configuration for state storage
-	Backend *BackendConfig `json:"backend,omitempty"`
-}
+type ModuleRequest = protocol.Request
 
 // BackendConfig configures state storage for the module.
-type BackendConfig struct {
-	// Type is the backend type (e.g., "s3", "gcs", "azurerm", "local")
-	Type string `json:"type"`
-
-	// Config contains backend-specific configuration
-	Config map[string]string `json:"config"`
-}
+type BackendConfig = protocol.BackendConfig
 
 // ModuleResponse represents the output contract from a containerized module.
 // The container writes this as JSON to a mounted output file.
-type ModuleResponse struct {
-	// Success indicates whether the operation succeeded
-	Success bool `json:"success"`
-
-	// Action that was performed
-	Action string `json:"action"`
-
-	// Outputs from the module (after apply)
-	Outputs map[string]OutputValue `json:"outputs,omitempty"`
-
-	// State to persist (opaque to cldctl)
-	State map[string]interface{} `json:"state,omitempty"`
-
-	// Changes describes what changed (for preview)
-	Changes []ResourceChange `json:"changes,omitempty"`
-
-	// Error message if Success is false
-	Error string `json:"error,omitempty"`
-
-	// Logs from the operation
-	Logs string `json:"logs,omitempty"`
-}
+type ModuleResponse = protocol.Response
 
 // OutputValue represents a module output.
-type OutputValue struct {
-	Value     interface{} `json:"value"`
-	Sensitive bool        `json:"sensitive,omitempty"`
-}
+type OutputValue = protocol.OutputValue
 
 // ResourceChange describes a planned or executed change.
-type ResourceChange struct {
-	// Resource identifier
-	Resource string `json:"resource"`
-
-	// Action: create, update, delete, replace, no-op
-	Action string `json:"action"`
-
-	// Before state (for updates/deletes)
-	Before map[string]interface{} `json:"before,omitempty"`
-
-	// After state (for creates/updates)
-	After map[string]interface{} `json:"after,omitempty"`
-}
+type ResourceChange = protocol.ResourceChange
 
 // Executor runs containerized IaC modules.
 type Executor struct {
@@ -126,11 +71,19 @@ type ExecuteOptions struct {
 	// Credentials for cloud providers
 	Credentials map[string]string
 
+	// Sandbox restricts the container (nil = unrestricted)
+	Sandbox *iac.Sandbox
+
 	// Stdout for streaming output
 	Stdout io.Writer
 
 	// Stderr for streaming errors
 	Stderr io.Writer
+
+	// OnLog is called with each line of the IaC tool's output as the module
+	// writes it, when the request offers protocol.CapabilityLogStream. Images
+	// that don't support it only report their output in the response's Logs.
+	OnLog func(protocol.LogEntry)
 }
 
 // Execute runs a containerized module and returns the response.
@@ -158,6 +111,25 @@ func (e *Executor) Execute(ctx context.Context, opts ExecuteOptions) (*ModuleRes
 	// Prepare output file path
 	outputFile := filepath.Join(opts.WorkDir, "output.json")
 
+	// Follow the streamed log while the module runs. Every entry has been
+	// passed on by the time Execute returns.
+	if opts.OnLog != nil && protocol.HasCapability(opts.Request.Capabilities, protocol.CapabilityLogStream) {
+		logFile := filepath.Join(opts.WorkDir, filepath.Base(protocol.LogPath))
+		if err := os.WriteFile(logFile, nil, 0644); err != nil {
+			return nil, fmt.Errorf("failed to create log file: %w", err)
+		}
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			followLog(logFile, stop, opts.OnLog)
+		}()
+		defer func() {
+			close(stop)
+			<-done
+		}()
+	}
+
 	// Pull image if needed
 	reader, err := e.dockerClient.ImagePull(ctx, opts.Image, image.PullOptions{})
 	if err != nil {
@@ -167,6 +139,12 @@ func (e *Executor) Execute(ctx context.Context, opts ExecuteOptions) (*ModuleRes
 		reader.Close()
 	}
 
+	// The protocol version the image was built with, if it records one
+	imageVersion := 0
+	if inspect, err := e.dockerClient.ImageInspect(ctx, opts.Image); err == nil && inspect.Config != nil {
+		imageVersion, _ = strconv.Atoi(inspect.Config.Labels[protocol.VersionLabel])
+	}
+
 	// Build environment variables
 	env := []string{}
 	for k, v := range opts.Request.Environment {
@@ -180,7 +158,7 @@ func (e *Executor) Execute(ctx context.Context, opts ExecuteOptions) (*ModuleRes
 	containerConfig := &container.Config{
 		Image: opts.Image,
 		Env:   env,
-		Cmd:   []string{"/cldctl-entrypoint", "--input", "/workspace/input.json", "--output", "/workspace/output.json"},
+		Cmd:   []string{"/cldctl-entrypoint", "--input", protocol.InputPath, "--output", protocol.OutputPath},
 	}
 
 	hostConfig := &container.HostConfig{
@@ -193,6 +171,9 @@ func (e *Executor) Execute(ctx context.Context, opts ExecuteOptions) (*ModuleRes
 		},
 		AutoRemove: true,
 	}
+	if err := applySandbox(hostConfig, opts.Sandbox); err != nil {
+		return nil, err
+	}
 
 	resp, err := e.dockerClient.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, "")
 	if err != nil {
@@ -252,9 +233,52 @@ func (e *Executor) Execute(ctx context.Context, opts ExecuteOptions) (*ModuleRes
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
+	// Responses from images built before the protocol was versioned aren't
+	// signed, so a missing signature is only an error for images labelled
+	// with a version, or responses that claim one
+	signature, err := os.ReadFile(protocol.SignaturePath(outputFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read response signature: %w", err)
+	}
+	if err := protocol.CheckResponse(opts.Request, &response, outputData, strings.TrimSpace(string(signature)), imageVersion); err != nil {
+		return nil, fmt.Errorf("invalid response from module %s: %w", opts.Image, err)
+	}
+
 	return &response, nil
 }
 
+// applySandbox restricts a module container's host configuration. The
+// /workspace mount stays writable so the module can write its response, and
+// a read-only root gets a writable /tmp for IaC tool scratch files.
+func applySandbox(hostConfig *container.HostConfig, sandbox *iac.Sandbox) error {
+	if sandbox == nil {
+		return nil
+	}
+	if sandbox.NoNetwork {
+		hostConfig.NetworkMode = network.NetworkNone
+	}
+	if sandbox.ReadOnlyRoot {
+		hostConfig.ReadonlyRootfs = true
+		hostConfig.Tmpfs = map[string]string{"/tmp": "rw,noexec,nosuid"}
+	}
+	hostConfig.Memory = sandbox.Memory
+	hostConfig.NanoCPUs = int64(sandbox.CPUs * 1e9)
+	if sandbox.PidsLimit > 0 {
+		limit := sandbox.PidsLimit
+		hostConfig.PidsLimit = &limit
+	}
+	if sandbox.SeccompProfile != "" {
+		// The Docker API takes the profile itself, not a path
+		profile, err := os.ReadFile(sandbox.SeccompProfile)
+		if err != nil {
+			return fmt.Errorf("failed to read seccomp profile: %w", err)
+		}
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "seccomp="+string(profile))
+	}
+	hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "no-new-privileges")
+	return nil
+}
+
 // Close releases resources.
 func (e *Executor) Close() error {
 	return e.dockerClient.Close()
@@ -5,6 +5,10 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/docker/docker/api/types/container"
+
+	"github.com/davidthor/cldctl/pkg/iac"
 )
 
 func TestModuleRequest_Marshal(t *testing.T) {
@@ -264,3 +268,48 @@ func TestOutputValue(t *testing.T) {
 		})
 	}
 }
+
+func TestApplySandbox(t *testing.T) {
+	profile := filepath.Join(t.TempDir(), "seccomp.json")
+	if err := os.WriteFile(profile, []byte(`{"defaultAction":"SCMP_ACT_ERRNO"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	hostConfig := &container.HostConfig{}
+	err := applySandbox(hostConfig, &iac.Sandbox{
+		NoNetwork:      true,
+		ReadOnlyRoot:   true,
+		Memory:         512 << 20,
+		CPUs:           1.5,
+		PidsLimit:      256,
+		SeccompProfile: profile,
+	})
+	if err != nil {
+		t.Fatalf("applySandbox failed: %v", err)
+	}
+
+	if hostConfig.NetworkMode != "none" {
+		t.Errorf("expected network mode none, got %q", hostConfig.NetworkMode)
+	}
+	if !hostConfig.ReadonlyRootfs || hostConfig.Tmpfs["/tmp"] == "" {
+		t.Error("expected a read-only root with a writable /tmp")
+	}
+	if hostConfig.Memory != 512<<20 || hostConfig.NanoCPUs != 1500000000 {
+		t.Errorf("expected resource limits, got memory=%d nanoCPUs=%d", hostConfig.Memory, hostConfig.NanoCPUs)
+	}
+	if hostConfig.PidsLimit == nil || *hostConfig.PidsLimit != 256 {
+		t.Errorf("expected pids limit 256, got %v", hostConfig.PidsLimit)
+	}
+	if len(hostConfig.SecurityOpt) != 2 || hostConfig.SecurityOpt[0] != `seccomp={"defaultAction":"SCMP_ACT_ERRNO"}` {
+		t.Errorf("expected inline seccomp profile, got %v", hostConfig.SecurityOpt)
+	}
+
+	unrestricted := &container.HostConfig{}
+	if err := applySandbox(unrestricted, nil); err != nil || unrestricted.NetworkMode != "" || len(unrestricted.SecurityOpt) != 0 {
+		t.Errorf("expected no restrictions without a sandbox, got %+v", unrestricted)
+	}
+
+	if err := applySandbox(&container.HostConfig{}, &iac.Sandbox{SeccompProfile: "/does/not/exist.json"}); err == nil {
+		t.Error("expected error for a missing seccomp profile")
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/iac/container/protocol"
+)
+
+// logStream is an io.Writer that writes each line of a tool's output to the
+// streamed log file as a protocol.LogEntry, as soon as the line is complete.
+// Sensitive values are scrubbed from each line first.
+type logStream struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	source  string
+	secrets []string
+	partial []byte
+}
+
+func newLogStream(w io.Writer, source string, secrets []string) *logStream {
+	return &logStream{enc: json.NewEncoder(w), source: source, secrets: secrets}
+}
+
+// Write writes the complete lines in p, keeping any trailing partial line
+// until the rest of it is written.
+func (s *logStream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.partial = append(s.partial, p...)
+	for {
+		i := bytes.IndexByte(s.partial, '\n')
+		if i < 0 {
+			break
+		}
+		s.writeLine(string(s.partial[:i]))
+		s.partial = s.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes a final line that didn't end with a newline. It's a no-op on
+// a nil stream.
+func (s *logStream) Flush() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writeLine(string(s.partial))
+	s.partial = nil
+}
+
+// writeLine writes a line as a log entry. Errors are ignored: the output is
+// still returned in the response if the stream can't be written.
+func (s *logStream) writeLine(line string) {
+	line = redact(strings.TrimRight(line, "\r"), s.secrets)
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	_ = s.enc.Encode(parseLogLine(s.source, line))
+}
+
+// tofuLogLine is the subset of a line of OpenTofu's -json output that's
+// carried over to log entries.
+type tofuLogLine struct {
+	Level     string `json:"@level"`
+	Message   string `json:"@message"`
+	Timestamp string `json:"@timestamp"`
+	Hook      struct {
+		Resource struct {
+			Addr string `json:"addr"`
+		} `json:"resource"`
+	} `json:"hook"`
+}
+
+// parseLogLine turns a line of tool output into a log entry. Lines of
+// OpenTofu's -json output keep their level, message, and resource; other
+// lines are passed through as they are.
+func parseLogLine(source, line string) protocol.LogEntry {
+	entry := protocol.LogEntry{Time: time.Now().UTC(), Source: source, Message: line}
+
+	var tofu tofuLogLine
+	if err := json.Unmarshal([]byte(line), &tofu); err != nil || tofu.Message == "" {
+		return entry
+	}
+	entry.Level = tofu.Level
+	entry.Message = tofu.Message
+	entry.Resource = tofu.Hook.Resource.Addr
+	if t, err := time.Parse(time.RFC3339Nano, tofu.Timestamp); err == nil {
+		entry.Time = t
+	}
+	return entry
+}
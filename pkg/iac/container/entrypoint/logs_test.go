@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/iac/container/protocol"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogStream(t *testing.T) {
+	var file bytes.Buffer
+	stream := newLogStream(&file, "tofu", []string{"hunter22"})
+
+	// Lines can be split across writes
+	tofuLine := `{"@level":"info","@message":"aws_db_instance.main: Creating...","@timestamp":"2024-05-01T10:00:00.000000Z","hook":{"resource":{"addr":"aws_db_instance.main"}},"type":"apply_start"}`
+	for _, chunk := range []string{tofuLine[:40], tofuLine[40:] + "\nplain ", "output hunter22\n\n", "no newline"} {
+		_, err := stream.Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+	stream.Flush()
+
+	var entries []protocol.LogEntry
+	scanner := bufio.NewScanner(&file)
+	for scanner.Scan() {
+		var entry protocol.LogEntry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, "aws_db_instance.main: Creating...", entries[0].Message)
+	assert.Equal(t, "info", entries[0].Level)
+	assert.Equal(t, "aws_db_instance.main", entries[0].Resource)
+	assert.Equal(t, 2024, entries[0].Time.Year())
+
+	assert.Equal(t, "plain output [REDACTED]", entries[1].Message)
+	assert.Equal(t, "tofu", entries[1].Source)
+	assert.Empty(t, entries[1].Level)
+
+	assert.Equal(t, "no newline", entries[2].Message)
+}
+
+func TestLogStream_NilFlush(t *testing.T) {
+	var stream *logStream
+	assert.NotPanics(t, stream.Flush)
+}
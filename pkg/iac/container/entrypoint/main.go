@@ -8,68 +8,44 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+
+	"github.com/davidthor/cldctl/pkg/iac/container/protocol"
 )
 
 // ModuleRequest represents the input from cldctl.
-type ModuleRequest struct {
-	Action      string                 `json:"action"`
-	Inputs      map[string]interface{} `json:"inputs"`
-	State       map[string]interface{} `json:"state,omitempty"`
-	Environment map[string]string      `json:"environment,omitempty"`
-	StackName   string                 `json:"stack_name,omitempty"`
-	Backend     *BackendConfig         `json:"backend,omitempty"`
-}
-
-// BackendConfig for state storage.
-type BackendConfig struct {
-	Type   string            `json:"type"`
-	Config map[string]string `json:"config"`
-}
+type ModuleRequest = protocol.Request
 
 // ModuleResponse represents the output to cldctl.
-type ModuleResponse struct {
-	Success bool                   `json:"success"`
-	Action  string                 `json:"action"`
-	Outputs map[string]OutputValue `json:"outputs,omitempty"`
-	State   map[string]interface{} `json:"state,omitempty"`
-	Changes []ResourceChange       `json:"changes,omitempty"`
-	Error   string                 `json:"error,omitempty"`
-	Logs    string                 `json:"logs,omitempty"`
-}
+type ModuleResponse = protocol.Response
 
 // OutputValue represents a module output.
-type OutputValue struct {
-	Value     interface{} `json:"value"`
-	Sensitive bool        `json:"sensitive,omitempty"`
-}
+type OutputValue = protocol.OutputValue
 
 // ResourceChange describes a change.
-type ResourceChange struct {
-	Resource string                 `json:"resource"`
-	Action   string                 `json:"action"`
-	Before   map[string]interface{} `json:"before,omitempty"`
-	After    map[string]interface{} `json:"after,omitempty"`
-}
+type ResourceChange = protocol.ResourceChange
 
 func main() {
-	inputFile := flag.String("input", "/workspace/input.json", "Input JSON file path")
-	outputFile := flag.String("output", "/workspace/output.json", "Output JSON file path")
+	inputFile := flag.String("input", protocol.InputPath, "Input JSON file path")
+	outputFile := flag.String("output", protocol.OutputPath, "Output JSON file path")
+	logFile := flag.String("log", protocol.LogPath, "Streamed log file path")
 	flag.Parse()
 
 	// Read request
 	data, err := os.ReadFile(*inputFile)
 	if err != nil {
-		writeError(*outputFile, "", fmt.Sprintf("failed to read input: %v", err))
+		writeError(*outputFile, &ModuleRequest{}, fmt.Sprintf("failed to read input: %v", err))
 		os.Exit(1)
 	}
 
 	var request ModuleRequest
 	if err := json.Unmarshal(data, &request); err != nil {
-		writeError(*outputFile, "", fmt.Sprintf("failed to parse input: %v", err))
+		writeError(*outputFile, &ModuleRequest{}, fmt.Sprintf("failed to parse input: %v", err))
 		os.Exit(1)
 	}
 
@@ -81,30 +57,36 @@ func main() {
 	// Detect which IaC tool to use
 	tool := detectTool()
 
+	// Stream the tool's output if cldctl is following it
+	var stream *logStream
+	if _, capabilities := protocol.Negotiate(&request); protocol.HasCapability(capabilities, protocol.CapabilityLogStream) {
+		f, err := os.OpenFile(*logFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			writeError(*outputFile, &request, fmt.Sprintf("failed to open log file: %v", err))
+			os.Exit(1)
+		}
+		defer f.Close()
+		stream = newLogStream(f, tool, sensitiveValues(&request))
+	}
+
 	var response *ModuleResponse
 	switch tool {
 	case "pulumi":
-		response, err = executePulumi(&request)
+		response, err = executePulumi(&request, stream)
 	case "tofu":
-		response, err = executeOpenTofu(&request)
+		response, err = executeOpenTofu(&request, stream)
 	default:
-		writeError(*outputFile, request.Action, fmt.Sprintf("unknown tool: %s", tool))
-		os.Exit(1)
-	}
-
-	if err != nil {
-		writeError(*outputFile, request.Action, err.Error())
+		writeError(*outputFile, &request, fmt.Sprintf("unknown tool: %s", tool))
 		os.Exit(1)
 	}
+	stream.Flush()
 
-	// Write response
-	responseData, err := json.MarshalIndent(response, "", "  ")
 	if err != nil {
-		writeError(*outputFile, request.Action, fmt.Sprintf("failed to marshal response: %v", err))
+		writeError(*outputFile, &request, err.Error())
 		os.Exit(1)
 	}
 
-	if err := os.WriteFile(*outputFile, responseData, 0644); err != nil {
+	if err := writeResponse(*outputFile, &request, response); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to write output: %v\n", err)
 		os.Exit(1)
 	}
@@ -114,14 +96,38 @@ func main() {
 	}
 }
 
-func writeError(outputFile, action, errMsg string) {
+func writeError(outputFile string, request *ModuleRequest, errMsg string) {
 	response := ModuleResponse{
 		Success: false,
-		Action:  action,
+		Action:  request.Action,
 		Error:   errMsg,
 	}
-	data, _ := json.MarshalIndent(response, "", "  ")
-	_ = os.WriteFile(outputFile, data, 0644)
+	_ = writeResponse(outputFile, request, &response)
+}
+
+// writeResponse writes the response in the protocol version negotiated with
+// the request, signing it if that was negotiated too. Sensitive input values
+// are scrubbed from its logs and error.
+func writeResponse(outputFile string, request *ModuleRequest, response *ModuleResponse) error {
+	secrets := sensitiveValues(request)
+	response.Logs = redact(response.Logs, secrets)
+	response.Error = redact(response.Error, secrets)
+	response.ProtocolVersion, response.Capabilities = protocol.Negotiate(request)
+	data, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+	if err := os.WriteFile(outputFile, data, 0644); err != nil {
+		return err
+	}
+	if !protocol.HasCapability(response.Capabilities, protocol.CapabilitySignedResponse) {
+		return nil
+	}
+	signature, err := protocol.Sign(data, request.SigningKey)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(protocol.SignaturePath(outputFile), []byte(signature), 0644)
 }
 
 func detectTool() string {
@@ -141,7 +147,11 @@ func detectTool() string {
 	return "unknown"
 }
 
-func executePulumi(request *ModuleRequest) (*ModuleResponse, error) {
+func executePulumi(request *ModuleRequest, stream *logStream) (*ModuleResponse, error) {
+	if len(request.Plan) > 0 {
+		return nil, fmt.Errorf("saved plans are only supported for OpenTofu modules")
+	}
+
 	// Write inputs as Pulumi config
 	stackName := request.StackName
 	if stackName == "" {
@@ -157,16 +167,18 @@ func executePulumi(request *ModuleRequest) (*ModuleResponse, error) {
 	}
 
 	// Set config from inputs
-	if err := setPulumiConfig("/app", request.Inputs, execCommand); err != nil {
+	if err := setPulumiConfig("/app", request, execCommand); err != nil {
 		return nil, err
 	}
 
 	var logs bytes.Buffer
+	out := commandOutput(&logs, stream)
 	var response *ModuleResponse
 
 	switch request.Action {
 	case "preview":
-		cmd := exec.Command("pulumi", "preview", "--json")
+		args := append([]string{"preview", "--json"}, pulumiTargetArgs(request.Targets)...)
+		cmd := exec.Command("pulumi", args...)
 		cmd.Dir = "/app"
 		cmd.Env = os.Environ()
 		cmd.Stdout = &logs
@@ -190,11 +202,13 @@ func executePulumi(request *ModuleRequest) (*ModuleResponse, error) {
 		}
 
 	case "apply":
-		cmd := exec.Command("pulumi", "up", "--yes", "--json")
+		// The output isn't parsed, so it's left readable for the log stream
+		args := append([]string{"up", "--yes"}, pulumiTargetArgs(request.Targets)...)
+		cmd := exec.Command("pulumi", args...)
 		cmd.Dir = "/app"
 		cmd.Env = os.Environ()
-		cmd.Stdout = &logs
-		cmd.Stderr = &logs
+		cmd.Stdout = out
+		cmd.Stderr = out
 
 		if err := cmd.Run(); err != nil {
 			return &ModuleResponse{
@@ -224,11 +238,12 @@ func executePulumi(request *ModuleRequest) (*ModuleResponse, error) {
 		}
 
 	case "destroy":
-		cmd := exec.Command("pulumi", "destroy", "--yes", "--json")
+		args := append([]string{"destroy", "--yes"}, pulumiTargetArgs(request.Targets)...)
+		cmd := exec.Command("pulumi", args...)
 		cmd.Dir = "/app"
 		cmd.Env = os.Environ()
-		cmd.Stdout = &logs
-		cmd.Stderr = &logs
+		cmd.Stdout = out
+		cmd.Stderr = out
 
 		if err := cmd.Run(); err != nil {
 			return &ModuleResponse{
@@ -252,23 +267,51 @@ func executePulumi(request *ModuleRequest) (*ModuleResponse, error) {
 	return response, nil
 }
 
-// commandRunner executes an external command and returns its combined output.
+// commandOutput returns where a tool command's output goes: the buffer it's
+// returned in, and the log stream if there is one.
+func commandOutput(logs *bytes.Buffer, stream *logStream) io.Writer {
+	if stream == nil {
+		return logs
+	}
+	return io.MultiWriter(logs, stream)
+}
+
+// pulumiTargetArgs returns a --target argument for each resource URN.
+func pulumiTargetArgs(targets []string) []string {
+	args := make([]string, 0, len(targets))
+	for _, t := range targets {
+		args = append(args, "--target", t)
+	}
+	return args
+}
+
+// commandRunner executes an external command, with stdin as its input if
+// it isn't nil, and returns its combined output.
 // This is an abstraction to allow testing without real exec calls.
-type commandRunner func(dir string, name string, args ...string) ([]byte, error)
+type commandRunner func(dir string, stdin io.Reader, name string, args ...string) ([]byte, error)
 
 // execCommand is the default command runner using os/exec.
-func execCommand(dir string, name string, args ...string) ([]byte, error) {
+func execCommand(dir string, stdin io.Reader, name string, args ...string) ([]byte, error) {
 	cmd := exec.Command(name, args...)
 	cmd.Dir = dir
 	cmd.Env = os.Environ()
+	cmd.Stdin = stdin
 	return cmd.CombinedOutput()
 }
 
-// setPulumiConfig sets Pulumi config values from inputs using the provided command runner.
-func setPulumiConfig(dir string, inputs map[string]interface{}, runner commandRunner) error {
-	for key, value := range inputs {
+// setPulumiConfig sets Pulumi config values from the request's inputs using
+// the provided command runner. Sensitive inputs are set as secrets, and their
+// values are passed on stdin so they don't show up in process lists.
+func setPulumiConfig(dir string, request *ModuleRequest, runner commandRunner) error {
+	for key, value := range request.Inputs {
 		valueStr := fmt.Sprintf("%v", value)
-		out, err := runner(dir, "pulumi", "config", "set", key, valueStr)
+		var out []byte
+		var err error
+		if isSensitiveInput(request, key) {
+			out, err = runner(dir, strings.NewReader(valueStr), "pulumi", "config", "set", "--secret", key)
+		} else {
+			out, err = runner(dir, nil, "pulumi", "config", "set", key, valueStr)
+		}
 		if err != nil {
 			return fmt.Errorf("failed to set pulumi config %q: %s", key, string(out))
 		}
@@ -304,37 +347,36 @@ func parsePreviewOutput(output string) []ResourceChange {
 	return nil
 }
 
-func executeOpenTofu(request *ModuleRequest) (*ModuleResponse, error) {
+func executeOpenTofu(request *ModuleRequest, stream *logStream) (*ModuleResponse, error) {
 	// Write inputs as tfvars
 	tfvarsPath := "/app/terraform.tfvars.json"
 	tfvarsData, err := json.MarshalIndent(request.Inputs, "", "  ")
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal inputs: %w", err)
 	}
-	if err := os.WriteFile(tfvarsPath, tfvarsData, 0644); err != nil {
+	// Only readable by the entrypoint, since inputs may hold secrets
+	if err := os.WriteFile(tfvarsPath, tfvarsData, 0600); err != nil {
 		return nil, fmt.Errorf("failed to write tfvars: %w", err)
 	}
 
-	// Initialize if needed
-	if _, err := os.Stat(filepath.Join("/app", ".terraform")); os.IsNotExist(err) {
-		cmd := exec.Command("tofu", "init")
-		cmd.Dir = "/app"
-		cmd.Env = os.Environ()
-		if out, err := cmd.CombinedOutput(); err != nil {
-			return nil, fmt.Errorf("init failed: %s", string(out))
-		}
+	// Initialize the backend and select the stack's workspace
+	_, err = os.Stat(filepath.Join("/app", ".terraform"))
+	if err := prepareTofu("/app", request, err == nil, execCommand); err != nil {
+		return nil, err
 	}
 
 	var logs bytes.Buffer
+	out := commandOutput(&logs, stream)
 	var response *ModuleResponse
 
 	switch request.Action {
 	case "preview":
-		cmd := exec.Command("tofu", "plan", "-json", "-out=/workspace/plan.tfplan")
+		args := append([]string{"plan", "-json", "-out=" + tofuPlanPath}, targetArgs(request.Targets)...)
+		cmd := exec.Command("tofu", args...)
 		cmd.Dir = "/app"
 		cmd.Env = os.Environ()
-		cmd.Stdout = &logs
-		cmd.Stderr = &logs
+		cmd.Stdout = out
+		cmd.Stderr = out
 
 		if err := cmd.Run(); err != nil {
 			return &ModuleResponse{
@@ -345,19 +387,30 @@ func executeOpenTofu(request *ModuleRequest) (*ModuleResponse, error) {
 			}, nil
 		}
 
+		// Return the saved plan, so exactly this plan can be applied later
+		plan, err := os.ReadFile(tofuPlanPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read saved plan: %w", err)
+		}
+
 		response = &ModuleResponse{
 			Success: true,
 			Action:  request.Action,
 			Changes: parseTofuPlanOutput(logs.String()),
+			Plan:    plan,
 			Logs:    logs.String(),
 		}
 
 	case "apply":
-		cmd := exec.Command("tofu", "apply", "-auto-approve", "-json")
+		args, err := tofuApplyArgs(request)
+		if err != nil {
+			return nil, err
+		}
+		cmd := exec.Command("tofu", args...)
 		cmd.Dir = "/app"
 		cmd.Env = os.Environ()
-		cmd.Stdout = &logs
-		cmd.Stderr = &logs
+		cmd.Stdout = out
+		cmd.Stderr = out
 
 		if err := cmd.Run(); err != nil {
 			return &ModuleResponse{
@@ -387,11 +440,12 @@ func executeOpenTofu(request *ModuleRequest) (*ModuleResponse, error) {
 		}
 
 	case "destroy":
-		cmd := exec.Command("tofu", "destroy", "-auto-approve", "-json")
+		args := append([]string{"destroy", "-auto-approve", "-json"}, targetArgs(request.Targets)...)
+		cmd := exec.Command("tofu", args...)
 		cmd.Dir = "/app"
 		cmd.Env = os.Environ()
-		cmd.Stdout = &logs
-		cmd.Stderr = &logs
+		cmd.Stdout = out
+		cmd.Stderr = out
 
 		if err := cmd.Run(); err != nil {
 			return &ModuleResponse{
@@ -415,6 +469,100 @@ func executeOpenTofu(request *ModuleRequest) (*ModuleResponse, error) {
 	return response, nil
 }
 
+// tofuPlanPath is where previews save their plan, and where a saved plan is
+// written to be applied.
+const tofuPlanPath = "/workspace/plan.tfplan"
+
+// tofuBackendOverride is the override file that declares the requested
+// backend type, so modules that don't declare one can still be given a
+// backend. Its settings come from -backend-config.
+const tofuBackendOverride = "cldctl_backend_override.tf"
+
+// tofuLockFile is the dependency lock file that pins the module's provider
+// versions. Images built by cldctl include one.
+const tofuLockFile = ".terraform.lock.hcl"
+
+// prepareTofu initializes the module in dir, if it isn't already or a
+// backend is requested, passing the backend's settings as partial backend
+// configuration. It then selects the workspace named after the stack,
+// creating it if needed; without a stack name, the default workspace is used.
+//
+// When the module has a dependency lock file, init installs exactly the
+// providers it pins, and fails if the module would need different ones.
+func prepareTofu(dir string, request *ModuleRequest, initialized bool, runner commandRunner) error {
+	backend := request.Backend
+	if backend != nil && backend.Type != "" {
+		override := fmt.Sprintf("terraform {\n  backend %q {}\n}\n", backend.Type)
+		if err := os.WriteFile(filepath.Join(dir, tofuBackendOverride), []byte(override), 0644); err != nil {
+			return fmt.Errorf("failed to write backend override: %w", err)
+		}
+	}
+
+	if !initialized || backend != nil {
+		args := []string{"init", "-input=false"}
+		if initialized {
+			args = append(args, "-reconfigure")
+		}
+		locked := false
+		if _, err := os.Stat(filepath.Join(dir, tofuLockFile)); err == nil {
+			locked = true
+			args = append(args, "-lockfile=readonly")
+		}
+		if backend != nil {
+			keys := make([]string, 0, len(backend.Config))
+			for k := range backend.Config {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				args = append(args, fmt.Sprintf("-backend-config=%s=%s", k, backend.Config[k]))
+			}
+		}
+		if out, err := runner(dir, nil, "tofu", args...); err != nil {
+			if locked && isLockFileError(string(out)) {
+				return fmt.Errorf("init failed: the module's providers would change from the versions pinned in %s when the image was built. "+
+					"Rebuild and push the datacenter to update them.\n%s", tofuLockFile, string(out))
+			}
+			return fmt.Errorf("init failed: %s", string(out))
+		}
+	}
+
+	if request.StackName != "" && request.StackName != "default" {
+		if out, err := runner(dir, nil, "tofu", "workspace", "select", "-or-create", request.StackName); err != nil {
+			return fmt.Errorf("failed to select workspace %q: %s", request.StackName, string(out))
+		}
+	}
+	return nil
+}
+
+// isLockFileError reports whether tofu init output says the providers the
+// module needs don't match its dependency lock file.
+func isLockFileError(out string) bool {
+	out = strings.ToLower(out)
+	return strings.Contains(out, "dependency lock file") || strings.Contains(out, "lockfile=readonly")
+}
+
+// tofuApplyArgs returns the arguments of the apply command. A saved plan is
+// written out and applied as it is; it already carries any targets.
+func tofuApplyArgs(request *ModuleRequest) ([]string, error) {
+	if len(request.Plan) == 0 {
+		return append([]string{"apply", "-auto-approve", "-json"}, targetArgs(request.Targets)...), nil
+	}
+	if err := os.WriteFile(tofuPlanPath, request.Plan, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write saved plan: %w", err)
+	}
+	return []string{"apply", "-auto-approve", "-json", tofuPlanPath}, nil
+}
+
+// targetArgs returns a -target argument for each address.
+func targetArgs(targets []string) []string {
+	args := make([]string, 0, len(targets))
+	for _, t := range targets {
+		args = append(args, "-target="+t)
+	}
+	return args
+}
+
 func getTofuOutputs() (map[string]OutputValue, error) {
 	cmd := exec.Command("tofu", "output", "-json")
 	cmd.Dir = "/app"
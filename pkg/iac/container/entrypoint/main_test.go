@@ -1,18 +1,25 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/davidthor/cldctl/pkg/iac/container/protocol"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func mockRunnerSuccess(dir string, name string, args ...string) ([]byte, error) {
+func mockRunnerSuccess(dir string, stdin io.Reader, name string, args ...string) ([]byte, error) {
 	return []byte("ok"), nil
 }
 
-func mockRunnerFailure(dir string, name string, args ...string) ([]byte, error) {
+func mockRunnerFailure(dir string, stdin io.Reader, name string, args ...string) ([]byte, error) {
 	return []byte("error: invalid config key"), fmt.Errorf("exit status 1")
 }
 
@@ -22,7 +29,7 @@ func TestSetPulumiConfig_Success(t *testing.T) {
 		"replicas": 3,
 	}
 
-	err := setPulumiConfig("/app", inputs, mockRunnerSuccess)
+	err := setPulumiConfig("/app", &ModuleRequest{Inputs: inputs}, mockRunnerSuccess)
 	assert.NoError(t, err)
 }
 
@@ -31,7 +38,7 @@ func TestSetPulumiConfig_Failure(t *testing.T) {
 		"badkey": "value",
 	}
 
-	err := setPulumiConfig("/app", inputs, mockRunnerFailure)
+	err := setPulumiConfig("/app", &ModuleRequest{Inputs: inputs}, mockRunnerFailure)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to set pulumi config")
 	assert.Contains(t, err.Error(), "badkey")
@@ -39,12 +46,181 @@ func TestSetPulumiConfig_Failure(t *testing.T) {
 
 func TestSetPulumiConfig_EmptyInputs(t *testing.T) {
 	callCount := 0
-	countingRunner := func(dir string, name string, args ...string) ([]byte, error) {
+	countingRunner := func(dir string, stdin io.Reader, name string, args ...string) ([]byte, error) {
 		callCount++
 		return nil, nil
 	}
 
-	err := setPulumiConfig("/app", map[string]interface{}{}, countingRunner)
+	err := setPulumiConfig("/app", &ModuleRequest{Inputs: map[string]interface{}{}}, countingRunner)
 	assert.NoError(t, err)
 	assert.Equal(t, 0, callCount, "should not run any commands for empty inputs")
 }
+
+func TestWriteResponse_Signed(t *testing.T) {
+	request, err := protocol.NewRequest("apply")
+	require.NoError(t, err)
+	outputFile := filepath.Join(t.TempDir(), "output.json")
+
+	response := &ModuleResponse{Success: true, Action: "apply"}
+	require.NoError(t, writeResponse(outputFile, request, response))
+
+	data, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	signature, err := os.ReadFile(protocol.SignaturePath(outputFile))
+	require.NoError(t, err)
+
+	var decoded ModuleResponse
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, protocol.Version, decoded.ProtocolVersion)
+	assert.NoError(t, protocol.CheckResponse(request, &decoded, data, string(signature), protocol.Version))
+}
+
+func TestWriteResponse_UnversionedRequest(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "output.json")
+
+	response := &ModuleResponse{Success: true, Action: "apply"}
+	require.NoError(t, writeResponse(outputFile, &ModuleRequest{Action: "apply"}, response))
+
+	data, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "protocol_version", "old engines should get the unversioned format")
+	_, err = os.Stat(protocol.SignaturePath(outputFile))
+	assert.True(t, os.IsNotExist(err), "expected no signature for an unversioned request")
+}
+
+func TestPrepareTofu(t *testing.T) {
+	tests := []struct {
+		name        string
+		request     ModuleRequest
+		initialized bool
+		want        []string
+	}{
+		{
+			name: "fresh module in the default workspace",
+			want: []string{"tofu init -input=false"},
+		},
+		{
+			name:        "already initialized",
+			request:     ModuleRequest{StackName: "default"},
+			initialized: true,
+		},
+		{
+			name: "backend and workspace",
+			request: ModuleRequest{
+				StackName: "prod-api-db",
+				Backend: &protocol.BackendConfig{
+					Type:   "s3",
+					Config: map[string]string{"region": "us-east-1", "bucket": "state"},
+				},
+			},
+			initialized: true,
+			want: []string{
+				"tofu init -input=false -reconfigure -backend-config=bucket=state -backend-config=region=us-east-1",
+				"tofu workspace select -or-create prod-api-db",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var calls []string
+			runner := func(_ string, _ io.Reader, name string, args ...string) ([]byte, error) {
+				calls = append(calls, strings.Join(append([]string{name}, args...), " "))
+				return nil, nil
+			}
+
+			require.NoError(t, prepareTofu(dir, &tt.request, tt.initialized, runner))
+			assert.Equal(t, tt.want, calls)
+
+			override, err := os.ReadFile(filepath.Join(dir, tofuBackendOverride))
+			if tt.request.Backend != nil {
+				require.NoError(t, err)
+				assert.Contains(t, string(override), `backend "s3" {}`)
+			} else {
+				assert.True(t, os.IsNotExist(err), "expected no backend override")
+			}
+		})
+	}
+}
+
+func TestPrepareTofu_LockFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, tofuLockFile), []byte(`provider "registry.opentofu.org/hashicorp/aws" {}`), 0644))
+
+	var calls []string
+	runner := func(_ string, _ io.Reader, name string, args ...string) ([]byte, error) {
+		calls = append(calls, strings.Join(append([]string{name}, args...), " "))
+		return nil, nil
+	}
+	require.NoError(t, prepareTofu(dir, &ModuleRequest{}, false, runner))
+	assert.Equal(t, []string{"tofu init -input=false -lockfile=readonly"}, calls)
+
+	runner = func(_ string, _ io.Reader, _ string, _ ...string) ([]byte, error) {
+		return []byte("Error: Provider dependency changes detected\n\nChanges to the required provider dependencies were detected, but the lock file is read-only. " +
+			"To use and record these requirements, run \"tofu init\" without the \"-lockfile=readonly\" flag."), fmt.Errorf("exit status 1")
+	}
+	err := prepareTofu(dir, &ModuleRequest{}, false, runner)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "providers would change from the versions pinned in .terraform.lock.hcl")
+	assert.Contains(t, err.Error(), "Provider dependency changes detected")
+}
+
+func TestPrepareTofu_WorkspaceFailure(t *testing.T) {
+	runner := func(_ string, _ io.Reader, _ string, args ...string) ([]byte, error) {
+		if args[0] == "workspace" {
+			return []byte("invalid workspace name"), fmt.Errorf("exit status 1")
+		}
+		return nil, nil
+	}
+	err := prepareTofu(t.TempDir(), &ModuleRequest{StackName: "bad name"}, true, runner)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid workspace name")
+}
+
+func TestTargetArgs(t *testing.T) {
+	assert.Equal(t, []string{"-target=aws_db_instance.main", "-target=module.vpc"}, targetArgs([]string{"aws_db_instance.main", "module.vpc"}))
+	assert.Empty(t, targetArgs(nil))
+	assert.Equal(t, []string{"--target", "urn:pulumi:prod::app::aws:rds/instance:Instance::db"}, pulumiTargetArgs([]string{"urn:pulumi:prod::app::aws:rds/instance:Instance::db"}))
+}
+
+func TestSetPulumiConfig_Secret(t *testing.T) {
+	var calls []string
+	var stdin string
+	runner := func(dir string, in io.Reader, name string, args ...string) ([]byte, error) {
+		calls = append(calls, strings.Join(args, " "))
+		if in != nil {
+			data, _ := io.ReadAll(in)
+			stdin = string(data)
+		}
+		return nil, nil
+	}
+
+	request := &ModuleRequest{
+		Inputs:          map[string]interface{}{"db_password": "hunter22"},
+		SensitiveInputs: []string{"db_password"},
+	}
+	require.NoError(t, setPulumiConfig("/app", request, runner))
+	assert.Equal(t, []string{"config set --secret db_password"}, calls, "the value shouldn't be on the command line")
+	assert.Equal(t, "hunter22", stdin)
+}
+
+func TestWriteResponse_Redacted(t *testing.T) {
+	request, err := protocol.NewRequest("apply")
+	require.NoError(t, err)
+	request.Inputs = map[string]interface{}{"db_password": "hunter22", "port": 5432, "pin": "123"}
+	request.SensitiveInputs = []string{"db_password", "pin"}
+	outputFile := filepath.Join(t.TempDir(), "output.json")
+
+	response := &ModuleResponse{
+		Action: "apply",
+		Error:  "apply failed: password hunter22 rejected",
+		Logs:   "connecting with hunter22 on 5432, pin 123\n",
+	}
+	require.NoError(t, writeResponse(outputFile, request, response))
+
+	data, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "hunter22")
+	assert.Contains(t, string(data), "password [REDACTED] rejected")
+	assert.Contains(t, string(data), "pin 123", "values too short to be secrets aren't scrubbed")
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// redacted replaces sensitive values in logs and error messages.
+const redacted = "[REDACTED]"
+
+// minRedactedLength is the length below which values aren't scrubbed: short
+// values such as ports or flags would match all through the output, and
+// aren't secrets anyway.
+const minRedactedLength = 4
+
+// sensitiveValues returns the values of the request's sensitive inputs as
+// they could appear in output, longest first so that a value containing
+// another is replaced whole.
+func sensitiveValues(request *ModuleRequest) []string {
+	seen := make(map[string]bool)
+	var values []string
+	for _, name := range request.SensitiveInputs {
+		value, ok := request.Inputs[name]
+		if !ok || value == nil {
+			continue
+		}
+		forms := []string{fmt.Sprintf("%v", value)}
+		if data, err := json.Marshal(value); err == nil {
+			forms = append(forms, string(data))
+		}
+		for _, v := range forms {
+			if len(v) >= minRedactedLength && !seen[v] {
+				seen[v] = true
+				values = append(values, v)
+			}
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	return values
+}
+
+// redact replaces each of values in s.
+func redact(s string, values []string) string {
+	for _, v := range values {
+		s = strings.ReplaceAll(s, v, redacted)
+	}
+	return s
+}
+
+// isSensitiveInput reports whether the request marks an input sensitive.
+func isSensitiveInput(request *ModuleRequest, name string) bool {
+	for _, n := range request.SensitiveInputs {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
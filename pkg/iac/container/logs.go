@@ -0,0 +1,73 @@
+package container
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/iac/container/protocol"
+)
+
+// logPollInterval is how often the streamed log file is checked for new
+// lines while a module runs.
+const logPollInterval = 200 * time.Millisecond
+
+// followLog calls fn with each entry appended to the log file at path, in
+// order, until stop is closed. It then reads whatever is left, including a
+// final line without a newline, and returns.
+func followLog(path string, stop <-chan struct{}, fn func(protocol.LogEntry)) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var partial []byte
+	ticker := time.NewTicker(logPollInterval)
+	defer ticker.Stop()
+
+	for {
+		stopped := false
+		select {
+		case <-stop:
+			stopped = true
+		case <-ticker.C:
+		}
+
+		for {
+			line, err := reader.ReadBytes('\n')
+			partial = append(partial, line...)
+			if err != nil {
+				// Keep the start of a line that's still being written
+				break
+			}
+			emitLogLine(partial, fn)
+			partial = partial[:0]
+		}
+
+		if stopped {
+			emitLogLine(partial, fn)
+			return
+		}
+	}
+}
+
+// emitLogLine decodes a line of the log file. Lines that aren't log entries
+// are passed on as plain messages rather than dropped.
+func emitLogLine(line []byte, fn func(protocol.LogEntry)) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+	var entry protocol.LogEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		entry = protocol.LogEntry{Time: time.Now(), Message: string(line)}
+	}
+	if entry.Message == "" {
+		return
+	}
+	fn(entry)
+}
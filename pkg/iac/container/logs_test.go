@@ -0,0 +1,64 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/iac/container/protocol"
+)
+
+func TestFollowLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	received := make(chan protocol.LogEntry, 10)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		followLog(path, stop, func(entry protocol.LogEntry) { received <- entry })
+	}()
+
+	write := func(s string) {
+		t.Helper()
+		if _, err := f.WriteString(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Entries are passed on while the module is still running
+	write(`{"message":"Creating..."}` + "\n" + `{"message":"Still`)
+	select {
+	case entry := <-received:
+		if entry.Message != "Creating..." {
+			t.Errorf("Message = %q, want %q", entry.Message, "Creating...")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the first entry before the module finished")
+	}
+
+	write(` creating..."}` + "\n" + "not json\n" + `{"message":"Done"}`)
+	close(stop)
+	<-done
+	close(received)
+
+	var messages []string
+	for entry := range received {
+		messages = append(messages, entry.Message)
+	}
+	want := []string{"Still creating...", "not json", "Done"}
+	if len(messages) != len(want) {
+		t.Fatalf("messages = %q, want %q", messages, want)
+	}
+	for i := range want {
+		if messages[i] != want[i] {
+			t.Errorf("messages[%d] = %q, want %q", i, messages[i], want[i])
+		}
+	}
+}
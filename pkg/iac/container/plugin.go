@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/iac/container/protocol"
 )
 
 func init() {
@@ -58,6 +60,11 @@ func (p *Plugin) Name() string {
 	return "container"
 }
 
+// EnforcesSandbox reports that module containers honor RunOptions.Sandbox.
+func (p *Plugin) EnforcesSandbox() bool {
+	return true
+}
+
 // Apply executes a module to create/update resources.
 func (p *Plugin) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResult, error) {
 	response, err := p.executeModule(ctx, "apply", opts)
@@ -300,12 +307,14 @@ func (p *Plugin) executeModule(ctx context.Context, action string, opts iac.RunO
 	defer os.RemoveAll(workDir)
 
 	// Build the request
-	request := &ModuleRequest{
-		Action:      action,
-		Inputs:      opts.Inputs,
-		Environment: opts.Environment,
-		StackName:   generateStackName(opts),
+	request, err := protocol.NewRequest(action)
+	if err != nil {
+		return nil, err
 	}
+	request.Inputs = opts.Inputs
+	request.SensitiveInputs = sensitiveInputs(opts.Inputs)
+	request.Environment = opts.Environment
+	request.StackName = generateStackName(opts)
 
 	// State is passed via StateReader if available
 	// The container handles state internally via its backend configuration
@@ -316,8 +325,10 @@ func (p *Plugin) executeModule(ctx context.Context, action string, opts iac.RunO
 		Request:     request,
 		WorkDir:     workDir,
 		Credentials: extractCredentials(opts.Environment),
+		Sandbox:     opts.Sandbox,
 		Stdout:      opts.Stdout,
 		Stderr:      opts.Stderr,
+		OnLog:       forwardLog(opts.OnProgress),
 	})
 
 	if err != nil {
@@ -327,6 +338,30 @@ func (p *Plugin) executeModule(ctx context.Context, action string, opts iac.RunO
 	return response, nil
 }
 
+// sensitiveInputs returns the sorted names of inputs that look like they
+// hold secrets, for the entrypoint to keep out of logs.
+func sensitiveInputs(inputs map[string]interface{}) []string {
+	var names []string
+	for name := range inputs {
+		if isSensitiveKey(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// forwardLog returns a log handler that reports each line of the module's
+// output as progress, or nil if there's nothing to report it to.
+func forwardLog(onProgress func(string)) func(protocol.LogEntry) {
+	if onProgress == nil {
+		return nil
+	}
+	return func(entry protocol.LogEntry) {
+		onProgress(entry.Message)
+	}
+}
+
 // isContainerImage checks if a string looks like a container image reference.
 func isContainerImage(ref string) bool {
 	// Check for common registry patterns
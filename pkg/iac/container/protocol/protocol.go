@@ -0,0 +1,349 @@
+// Package protocol defines the contract between cldctl and the entrypoint
+// that runs inside containerized IaC modules. cldctl writes a Request to
+// InputPath, runs the module image, and reads the Response the entrypoint
+// writes to OutputPath.
+//
+// The types are shared by both sides, so the package only depends on the
+// standard library and stays small enough to build into module images.
+//
+// # Versions
+//
+// Requests and responses carry a protocol_version. The entrypoint answers in
+// the lower of the version it was asked for and the one it speaks, so old
+// engines keep working with new images. Version 0 is the unversioned protocol
+// that predates the field; images built with it leave every field added since
+// unset, and cldctl accepts their responses as they are.
+//
+// # Capabilities
+//
+// Optional features are negotiated by name within a version: the request
+// lists the capabilities cldctl supports, and the response lists the ones the
+// entrypoint used. A response may only use capabilities it was offered.
+//
+// # Signatures
+//
+// With CapabilitySignedResponse, the request carries a key generated for the
+// run, and the entrypoint writes the HMAC-SHA256 of the response file, keyed
+// with it, next to the response (see SignaturePath). This binds a response to
+// the request it answers, so a response left behind by another run, or
+// written by anything that didn't see the request, is rejected. It doesn't
+// prove which module wrote the response: the module's own code can read the
+// request, key included. Images labelled with VersionLabel must sign their
+// responses when asked to.
+//
+// # Logs
+//
+// With CapabilityLogStream, the entrypoint appends the IaC tool's output to
+// LogPath as it's written, one JSON-encoded LogEntry per line, so cldctl can
+// show progress while the module runs. The response's Logs still holds the
+// whole output.
+package protocol
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Version is the latest protocol version, spoken by both this cldctl and the
+// entrypoint built with it.
+const Version = 1
+
+// VersionLabel is the image label recording the protocol version a module
+// image's entrypoint speaks. cldctl sets it on the images it builds, so that
+// a response from such an image can't pass for one in an older version.
+const VersionLabel = "dev.cldctl.protocol-version"
+
+// Paths of the request, response, and log files inside the module container.
+const (
+	InputPath  = "/workspace/input.json"
+	OutputPath = "/workspace/output.json"
+	LogPath    = "/workspace/log.jsonl"
+)
+
+// Capabilities that can be negotiated.
+const (
+	// CapabilitySignedResponse signs the response with the request's
+	// SigningKey.
+	CapabilitySignedResponse = "signed-response"
+
+	// CapabilityLogStream streams the IaC tool's output while it runs, as
+	// one JSON-encoded LogEntry per line appended to LogPath.
+	CapabilityLogStream = "log-stream"
+
+	// CapabilityTargets limits the action to the resources in the request's
+	// Targets.
+	CapabilityTargets = "targets"
+
+	// CapabilitySavedPlan returns the plan a preview made in the response's
+	// Plan, and applies the request's Plan exactly as it was made. Only
+	// OpenTofu modules support it.
+	CapabilitySavedPlan = "saved-plan"
+)
+
+// Capabilities lists the capabilities this version of the protocol supports,
+// in the order they were added.
+var Capabilities = []string{
+	CapabilitySignedResponse,
+	CapabilityLogStream,
+	CapabilityTargets,
+	CapabilitySavedPlan,
+}
+
+// Request is the input to a containerized module.
+type Request struct {
+	// ProtocolVersion is the latest version the engine speaks
+	ProtocolVersion int `json:"protocol_version,omitempty"`
+
+	// Capabilities the engine supports
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	// SigningKey is the hex-encoded key the response is signed with, when
+	// CapabilitySignedResponse is negotiated
+	SigningKey string `json:"signing_key,omitempty"`
+
+	// Action is the operation to perform: "preview", "apply", "destroy", "refresh"
+	Action string `json:"action"`
+
+	// Inputs are the module input values
+	Inputs map[string]interface{} `json:"inputs"`
+
+	// SensitiveInputs are the names of inputs whose values are secret. The
+	// entrypoint keeps them out of command lines and scrubs them from logs
+	SensitiveInputs []string `json:"sensitive_inputs,omitempty"`
+
+	// State is the current module state (for updates/destroys)
+	State map[string]interface{} `json:"state,omitempty"`
+
+	// Environment variables to set
+	Environment map[string]string `json:"environment,omitempty"`
+
+	// StackName for Pulumi or workspace name for OpenTofu
+	StackName string `json:"stack_name,omitempty"`
+
+	// Backend configuration for state storage. For OpenTofu, Config is
+	// passed as partial backend configuration (-backend-config)
+	Backend *BackendConfig `json:"backend,omitempty"`
+
+	// Targets are addresses of resources within the module to limit the
+	// action to (OpenTofu -target, Pulumi --target)
+	Targets []string `json:"targets,omitempty"`
+
+	// Plan is a plan saved by an earlier preview, to apply exactly as it was
+	// made
+	Plan []byte `json:"plan,omitempty"`
+}
+
+// BackendConfig configures state storage for the module.
+type BackendConfig struct {
+	// Type is the backend type (e.g., "s3", "gcs", "azurerm", "local")
+	Type string `json:"type"`
+
+	// Config contains backend-specific configuration
+	Config map[string]string `json:"config"`
+}
+
+// Response is the output of a containerized module.
+type Response struct {
+	// ProtocolVersion is the version the response is written in
+	ProtocolVersion int `json:"protocol_version,omitempty"`
+
+	// Capabilities the entrypoint used, out of those offered in the request
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	// Success indicates whether the operation succeeded
+	Success bool `json:"success"`
+
+	// Action that was performed
+	Action string `json:"action"`
+
+	// Outputs from the module (after apply)
+	Outputs map[string]OutputValue `json:"outputs,omitempty"`
+
+	// State to persist (opaque to cldctl)
+	State map[string]interface{} `json:"state,omitempty"`
+
+	// Changes describes what changed (for preview)
+	Changes []ResourceChange `json:"changes,omitempty"`
+
+	// Plan is the plan a preview made, which can be passed back in a
+	// request's Plan to apply it
+	Plan []byte `json:"plan,omitempty"`
+
+	// Error message if Success is false
+	Error string `json:"error,omitempty"`
+
+	// Logs from the operation
+	Logs string `json:"logs,omitempty"`
+}
+
+// OutputValue represents a module output.
+type OutputValue struct {
+	Value     interface{} `json:"value"`
+	Sensitive bool        `json:"sensitive,omitempty"`
+}
+
+// LogEntry is a line of output streamed with CapabilityLogStream.
+type LogEntry struct {
+	Time time.Time `json:"time"`
+
+	// Level is the tool's level for the line (e.g. "info", "error"), if it
+	// reports one
+	Level string `json:"level,omitempty"`
+
+	// Source is the tool that wrote the line (e.g. "tofu", "pulumi")
+	Source string `json:"source,omitempty"`
+
+	// Resource is the address of the resource the line is about, if any
+	Resource string `json:"resource,omitempty"`
+
+	Message string `json:"message"`
+}
+
+// ResourceChange describes a planned or executed change.
+type ResourceChange struct {
+	// Resource identifier
+	Resource string `json:"resource"`
+
+	// Action: create, update, delete, replace, no-op
+	Action string `json:"action"`
+
+	// Before state (for updates/deletes)
+	Before map[string]interface{} `json:"before,omitempty"`
+
+	// After state (for creates/updates)
+	After map[string]interface{} `json:"after,omitempty"`
+}
+
+// NewRequest returns a request for action at the latest protocol version,
+// offering every capability, with a new signing key.
+func NewRequest(action string) (*Request, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	return &Request{
+		ProtocolVersion: Version,
+		Capabilities:    append([]string(nil), Capabilities...),
+		SigningKey:      hex.EncodeToString(key),
+		Action:          action,
+	}, nil
+}
+
+// Negotiate returns the version and capabilities to answer a request with:
+// the lower of the requested version and Version, and the offered
+// capabilities this version supports. Version 0 requests get no
+// capabilities.
+func Negotiate(req *Request) (int, []string) {
+	version := req.ProtocolVersion
+	if version > Version {
+		version = Version
+	}
+	if version == 0 {
+		return 0, nil
+	}
+	var capabilities []string
+	for _, c := range req.Capabilities {
+		if HasCapability(Capabilities, c) {
+			capabilities = append(capabilities, c)
+		}
+	}
+	return version, capabilities
+}
+
+// HasCapability reports whether capabilities includes name.
+func HasCapability(capabilities []string, name string) bool {
+	for _, c := range capabilities {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SignaturePath returns the path of the signature written next to the
+// response file at outputPath.
+func SignaturePath(outputPath string) string {
+	return outputPath + ".sig"
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of a response file's contents,
+// keyed with the hex-encoded signing key.
+func Sign(data []byte, key string) (string, error) {
+	k, err := hex.DecodeString(key)
+	if err != nil {
+		return "", fmt.Errorf("invalid signing key: %w", err)
+	}
+	mac := hmac.New(sha256.New, k)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Verify checks a response file's contents against its signature.
+func Verify(data []byte, signature, key string) error {
+	want, err := Sign(data, key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(want), []byte(signature)) {
+		return fmt.Errorf("response signature doesn't match")
+	}
+	return nil
+}
+
+// CheckResponse checks that a response is a valid answer to req: that it's
+// in a version no newer than requested, uses only offered capabilities, and
+// is signed when it says it is. data is the response file's contents, and
+// signature the contents of its signature file, if any.
+//
+// imageVersion is the version the image declares in its VersionLabel, or 0
+// if it doesn't. A labelled image must answer in the version negotiated with
+// it, and sign its response if signing was offered, so that a response can't
+// drop its signature by claiming to be unversioned.
+func CheckResponse(req *Request, resp *Response, data []byte, signature string, imageVersion int) error {
+	if resp.ProtocolVersion > req.ProtocolVersion {
+		return fmt.Errorf("module answered in protocol version %d, but version %d was requested", resp.ProtocolVersion, req.ProtocolVersion)
+	}
+	expected := 0
+	if imageVersion > 0 {
+		expected = min(req.ProtocolVersion, imageVersion, Version)
+	}
+	if resp.ProtocolVersion < expected {
+		return fmt.Errorf("module image speaks protocol version %d, but answered in version %d", imageVersion, resp.ProtocolVersion)
+	}
+	// Older images ignore fields they don't know, so make sure an action
+	// that was meant to be limited wasn't run in full
+	if len(req.Targets) > 0 && !HasCapability(resp.Capabilities, CapabilityTargets) {
+		return fmt.Errorf("module doesn't support targets, so %s ran on every resource", req.Action)
+	}
+	if len(req.Plan) > 0 && !HasCapability(resp.Capabilities, CapabilitySavedPlan) {
+		return fmt.Errorf("module doesn't support saved plans, so %s didn't use the one given", req.Action)
+	}
+	if resp.ProtocolVersion == 0 {
+		if len(resp.Capabilities) > 0 {
+			return fmt.Errorf("module used capabilities without a protocol version")
+		}
+		return nil
+	}
+	for _, c := range resp.Capabilities {
+		if !HasCapability(req.Capabilities, c) {
+			return fmt.Errorf("module used capability %q, which wasn't offered", c)
+		}
+	}
+	// Every entrypoint that speaks a version signs when asked to
+	if expected > 0 && HasCapability(req.Capabilities, CapabilitySignedResponse) && !HasCapability(resp.Capabilities, CapabilitySignedResponse) {
+		return fmt.Errorf("module response isn't signed, but its image supports signing")
+	}
+	if HasCapability(resp.Capabilities, CapabilitySignedResponse) {
+		if signature == "" {
+			return fmt.Errorf("module response is missing its signature")
+		}
+		if err := Verify(data, signature, req.SigningKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package protocol
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name         string
+		req          Request
+		version      int
+		capabilities []string
+	}{
+		{
+			name:    "unversioned request",
+			req:     Request{Capabilities: []string{CapabilitySignedResponse}},
+			version: 0,
+		},
+		{
+			name:         "current version",
+			req:          Request{ProtocolVersion: Version, Capabilities: []string{CapabilitySignedResponse}},
+			version:      Version,
+			capabilities: []string{CapabilitySignedResponse},
+		},
+		{
+			name:         "newer version and unknown capability",
+			req:          Request{ProtocolVersion: Version + 1, Capabilities: []string{"from-the-future", CapabilitySignedResponse}},
+			version:      Version,
+			capabilities: []string{CapabilitySignedResponse},
+		},
+		{
+			name:    "nothing offered",
+			req:     Request{ProtocolVersion: Version},
+			version: Version,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, capabilities := Negotiate(&tt.req)
+			if version != tt.version {
+				t.Errorf("version = %d, want %d", version, tt.version)
+			}
+			if !reflect.DeepEqual(capabilities, tt.capabilities) {
+				t.Errorf("capabilities = %v, want %v", capabilities, tt.capabilities)
+			}
+		})
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	req, err := NewRequest("apply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := func(resp Response) ([]byte, string) {
+		data, err := json.Marshal(resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature, err := Sign(data, req.SigningKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data, signature
+	}
+
+	t.Run("signed", func(t *testing.T) {
+		resp := Response{ProtocolVersion: Version, Capabilities: []string{CapabilitySignedResponse}, Success: true, Action: "apply"}
+		data, signature := signed(resp)
+		if err := CheckResponse(req, &resp, data, signature, Version); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("unversioned", func(t *testing.T) {
+		resp := Response{Success: true, Action: "apply"}
+		if err := CheckResponse(req, &resp, []byte(`{"success":true,"action":"apply"}`), "", 0); err != nil {
+			t.Errorf("expected responses from old images to be accepted: %v", err)
+		}
+	})
+
+	failures := []struct {
+		name    string
+		resp    Response
+		targets []string
+		tamper  func(data []byte, signature string) ([]byte, string)
+		// unlabelled checks the response as coming from an image without
+		// a version label
+		unlabelled bool
+		wantErr    string
+	}{
+		{
+			name:    "newer version",
+			resp:    Response{ProtocolVersion: Version + 1},
+			wantErr: "was requested",
+		},
+		{
+			name:       "targets ignored by an old image",
+			resp:       Response{Success: true},
+			targets:    []string{"aws_db_instance.main"},
+			unlabelled: true,
+			wantErr:    "ran on every resource",
+		},
+		{
+			name: "signature stripped by claiming version 0",
+			resp: Response{Success: true},
+			tamper: func(data []byte, _ string) ([]byte, string) {
+				return data, ""
+			},
+			wantErr: "answered in version 0",
+		},
+		{
+			name: "unsigned response from a signing image",
+			resp: Response{ProtocolVersion: Version, Success: true},
+			tamper: func(data []byte, _ string) ([]byte, string) {
+				return data, ""
+			},
+			wantErr: "isn't signed",
+		},
+		{
+			name:    "capability not offered",
+			resp:    Response{ProtocolVersion: Version, Capabilities: []string{"from-the-future"}},
+			wantErr: "wasn't offered",
+		},
+		{
+			name: "missing signature",
+			resp: Response{ProtocolVersion: Version, Capabilities: []string{CapabilitySignedResponse}},
+			tamper: func(data []byte, _ string) ([]byte, string) {
+				return data, ""
+			},
+			wantErr: "missing its signature",
+		},
+		{
+			name: "modified response",
+			resp: Response{ProtocolVersion: Version, Capabilities: []string{CapabilitySignedResponse}, Success: false},
+			tamper: func(data []byte, signature string) ([]byte, string) {
+				return []byte(strings.Replace(string(data), `"success":false`, `"success":true`, 1)), signature
+			},
+			wantErr: "doesn't match",
+		},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			data, signature := signed(tt.resp)
+			if tt.tamper != nil {
+				data, signature = tt.tamper(data, signature)
+			}
+			req := *req
+			req.Targets = tt.targets
+			imageVersion := Version
+			if tt.unlabelled {
+				imageVersion = 0
+			}
+			err := CheckResponse(&req, &tt.resp, data, signature, imageVersion)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewRequest_KeysDiffer(t *testing.T) {
+	a, err := NewRequest("apply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewRequest("apply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.SigningKey == b.SigningKey {
+		t.Error("expected each request to get its own signing key")
+	}
+	if a.ProtocolVersion != Version {
+		t.Errorf("ProtocolVersion = %d, want %d", a.ProtocolVersion, Version)
+	}
+}
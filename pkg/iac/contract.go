@@ -0,0 +1,317 @@
+package iac
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Module contract files. A module directory may ship JSON Schemas describing
+// the inputs it accepts and the outputs it produces.
+const (
+	InputsSchemaFile  = "inputs.schema.json"
+	OutputsSchemaFile = "outputs.schema.json"
+)
+
+// ModuleContract holds the JSON Schemas a module ships for its inputs and
+// outputs. Either may be nil when the module doesn't declare it.
+type ModuleContract struct {
+	Inputs  *JSONSchema
+	Outputs *JSONSchema
+}
+
+// JSONSchema is the subset of JSON Schema used by module contracts: type,
+// properties, required, additionalProperties (boolean), enum, and items.
+// Other keywords are ignored.
+type JSONSchema struct {
+	Type                 schemaTypes            `json:"type,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+}
+
+// schemaTypes accepts both "type": "string" and "type": ["string", "null"].
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = list
+	return nil
+}
+
+// LoadModuleContract reads the contract files from a local module directory.
+// It returns nil when dir isn't a local directory (e.g., an OCI reference)
+// or ships neither file.
+func LoadModuleContract(dir string) (*ModuleContract, error) {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, nil
+	}
+
+	inputs, err := loadJSONSchema(filepath.Join(dir, InputsSchemaFile))
+	if err != nil {
+		return nil, err
+	}
+	outputs, err := loadJSONSchema(filepath.Join(dir, OutputsSchemaFile))
+	if err != nil {
+		return nil, err
+	}
+	if inputs == nil && outputs == nil {
+		return nil, nil
+	}
+	return &ModuleContract{Inputs: inputs, Outputs: outputs}, nil
+}
+
+func loadJSONSchema(path string) (*JSONSchema, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	var schema JSONSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", filepath.Base(path), err)
+	}
+	return &schema, nil
+}
+
+// ValidateInputs checks inputs against the contract's input schema.
+func (c *ModuleContract) ValidateInputs(module string, inputs map[string]interface{}) error {
+	if c == nil || c.Inputs == nil {
+		return nil
+	}
+	return contractError(c.Inputs.validateObject(inputs, ""), func(v violation) string {
+		switch v.kind {
+		case "missing":
+			return fmt.Sprintf("module %s requires input '%s'", module, v.path)
+		case "unexpected":
+			return fmt.Sprintf("module %s does not accept input '%s'", module, v.path)
+		case "enum":
+			return fmt.Sprintf("module %s expects input '%s' to be one of %s, got %s", module, v.path, v.expected, v.got)
+		default:
+			return fmt.Sprintf("module %s expects input '%s' as %s, got %s", module, v.path, v.expected, v.got)
+		}
+	})
+}
+
+// ValidateOutputs checks outputs against the contract's output schema.
+func (c *ModuleContract) ValidateOutputs(module string, outputs map[string]interface{}) error {
+	if c == nil || c.Outputs == nil {
+		return nil
+	}
+	return contractError(c.Outputs.validateObject(outputs, ""), func(v violation) string {
+		switch v.kind {
+		case "missing":
+			return fmt.Sprintf("module %s did not return required output '%s'", module, v.path)
+		case "unexpected":
+			return fmt.Sprintf("module %s returned undeclared output '%s'", module, v.path)
+		case "enum":
+			return fmt.Sprintf("module %s returned output '%s' as %s, expected one of %s", module, v.path, v.got, v.expected)
+		default:
+			return fmt.Sprintf("module %s returned output '%s' as %s, expected %s", module, v.path, v.got, v.expected)
+		}
+	})
+}
+
+func contractError(violations []violation, describe func(violation) string) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(violations))
+	for i, v := range violations {
+		msgs[i] = describe(v)
+	}
+	if len(msgs) == 1 {
+		return errors.New(msgs[0])
+	}
+	return errors.New("module contract violated:\n  - " + strings.Join(msgs, "\n  - "))
+}
+
+// violation is a single schema mismatch at a dotted path.
+type violation struct {
+	path     string
+	kind     string // "type", "missing", "unexpected", "enum"
+	expected string
+	got      string
+}
+
+func (s *JSONSchema) validate(value interface{}, path string) []violation {
+	if len(s.Type) > 0 {
+		actual := jsonTypeOf(value)
+		if !s.allowsType(value, actual) {
+			return []violation{{path: path, kind: "type", expected: strings.Join(s.Type, " or "), got: actual}}
+		}
+	}
+
+	if len(s.Enum) > 0 {
+		matched := false
+		for _, allowed := range s.Enum {
+			if jsonEqual(allowed, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			allowed, _ := json.Marshal(s.Enum)
+			got, _ := json.Marshal(value)
+			return []violation{{path: path, kind: "enum", expected: string(allowed), got: string(got)}}
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}, map[string]string:
+		return s.validateObject(toInterfaceMap(v), path)
+	case []interface{}:
+		if s.Items == nil {
+			return nil
+		}
+		var violations []violation
+		for i, item := range v {
+			violations = append(violations, s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return violations
+	case []string:
+		if s.Items == nil {
+			return nil
+		}
+		var violations []violation
+		for i, item := range v {
+			violations = append(violations, s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return violations
+	}
+	return nil
+}
+
+func (s *JSONSchema) validateObject(obj map[string]interface{}, path string) []violation {
+	var violations []violation
+
+	for _, name := range s.Required {
+		if val, ok := obj[name]; !ok || val == nil {
+			violations = append(violations, violation{path: joinPath(path, name), kind: "missing"})
+		}
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop, declared := s.Properties[name]
+		if !declared {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				violations = append(violations, violation{path: joinPath(path, name), kind: "unexpected"})
+			}
+			continue
+		}
+		if obj[name] == nil {
+			// Unset optional values are reported by required
+			continue
+		}
+		violations = append(violations, prop.validate(obj[name], joinPath(path, name))...)
+	}
+	return violations
+}
+
+func (s *JSONSchema) allowsType(value interface{}, actual string) bool {
+	for _, t := range s.Type {
+		if t == actual {
+			return true
+		}
+		if t == "integer" && actual == "number" && isWholeNumber(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// jsonTypeOf returns the JSON Schema type name of a Go value.
+func jsonTypeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+		return "number"
+	case map[string]interface{}, map[string]string:
+		return "object"
+	case []interface{}, []string:
+		return "array"
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func isWholeNumber(value interface{}) bool {
+	switch v := value.(type) {
+	case float32:
+		return float64(v) == math.Trunc(float64(v))
+	case float64:
+		return v == math.Trunc(v)
+	case json.Number:
+		_, err := v.Int64()
+		return err == nil
+	}
+	return true
+}
+
+// jsonEqual compares values after a JSON round trip so 5432 and 5432.0 match.
+func jsonEqual(a, b interface{}) bool {
+	aj, errA := json.Marshal(a)
+	bj, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	var av, bv interface{}
+	_ = json.Unmarshal(aj, &av)
+	_ = json.Unmarshal(bj, &bv)
+	return reflect.DeepEqual(av, bv)
+}
+
+func toInterfaceMap(value interface{}) map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v
+	case map[string]string:
+		m := make(map[string]interface{}, len(v))
+		for k, s := range v {
+			m[k] = s
+		}
+		return m
+	}
+	return nil
+}
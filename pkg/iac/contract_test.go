@@ -0,0 +1,162 @@
+package iac
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testInputsSchema = `{
+  "type": "object",
+  "required": ["name", "version"],
+  "additionalProperties": false,
+  "properties": {
+    "name":     { "type": "string" },
+    "version":  { "type": "string", "enum": ["15", "16"] },
+    "port":     { "type": "integer" },
+    "replicas": { "type": ["integer", "null"] },
+    "tags":     { "type": "object", "properties": { "team": { "type": "string" } } },
+    "subnets":  { "type": "array", "items": { "type": "string" } }
+  }
+}`
+
+const testOutputsSchema = `{
+  "type": "object",
+  "required": ["host", "port"],
+  "properties": {
+    "host": { "type": "string" },
+    "port": { "type": "number" }
+  }
+}`
+
+func writeContract(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadModuleContract(t *testing.T) {
+	contract, err := LoadModuleContract(writeContract(t, nil))
+	if err != nil || contract != nil {
+		t.Errorf("expected no contract for a module without schema files, got %v, %v", contract, err)
+	}
+
+	contract, err = LoadModuleContract("ghcr.io/myorg/module:v1")
+	if err != nil || contract != nil {
+		t.Errorf("expected no contract for an image reference, got %v, %v", contract, err)
+	}
+
+	_, err = LoadModuleContract(writeContract(t, map[string]string{InputsSchemaFile: "{"}))
+	if err == nil || !strings.Contains(err.Error(), InputsSchemaFile) {
+		t.Errorf("expected error naming the invalid schema file, got %v", err)
+	}
+
+	contract, err = LoadModuleContract(writeContract(t, map[string]string{OutputsSchemaFile: testOutputsSchema}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if contract.Inputs != nil || contract.Outputs == nil {
+		t.Errorf("expected only an outputs schema, got %+v", contract)
+	}
+}
+
+func TestModuleContract_ValidateInputs(t *testing.T) {
+	contract, err := LoadModuleContract(writeContract(t, map[string]string{InputsSchemaFile: testInputsSchema}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	valid := map[string]interface{}{
+		"name":     "main",
+		"version":  "16",
+		"port":     float64(5432),
+		"replicas": nil,
+		"tags":     map[string]string{"team": "payments"},
+		"subnets":  []interface{}{"a", "b"},
+	}
+	if err := contract.ValidateInputs("postgres", valid); err != nil {
+		t.Errorf("expected valid inputs, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		inputs map[string]interface{}
+		want   string
+	}{
+		{
+			name:   "wrong type",
+			inputs: map[string]interface{}{"name": "main", "version": 16},
+			want:   "module postgres expects input 'version' as string, got number",
+		},
+		{
+			name:   "missing",
+			inputs: map[string]interface{}{"name": "main"},
+			want:   "module postgres requires input 'version'",
+		},
+		{
+			name:   "enum",
+			inputs: map[string]interface{}{"name": "main", "version": "14"},
+			want:   `module postgres expects input 'version' to be one of ["15","16"], got "14"`,
+		},
+		{
+			name:   "fractional integer",
+			inputs: map[string]interface{}{"name": "main", "version": "16", "port": 5432.5},
+			want:   "module postgres expects input 'port' as integer, got number",
+		},
+		{
+			name:   "nested",
+			inputs: map[string]interface{}{"name": "main", "version": "16", "tags": map[string]interface{}{"team": 1}},
+			want:   "module postgres expects input 'tags.team' as string, got number",
+		},
+		{
+			name:   "undeclared",
+			inputs: map[string]interface{}{"name": "main", "version": "16", "subnets": []string{"a"}, "extra": true},
+			want:   "module postgres does not accept input 'extra'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := contract.ValidateInputs("postgres", tt.inputs)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestModuleContract_ValidateOutputs(t *testing.T) {
+	contract, err := LoadModuleContract(writeContract(t, map[string]string{OutputsSchemaFile: testOutputsSchema}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := contract.ValidateOutputs("postgres", map[string]interface{}{"host": "db", "port": 5432, "url": "postgres://db"}); err != nil {
+		t.Errorf("expected valid outputs, got %v", err)
+	}
+
+	err = contract.ValidateOutputs("postgres", map[string]interface{}{"port": "5432"})
+	if err == nil {
+		t.Fatal("expected contract violations")
+	}
+	for _, want := range []string{
+		"module postgres did not return required output 'host'",
+		"module postgres returned output 'port' as string, expected number",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+
+	// A nil contract accepts anything
+	var none *ModuleContract
+	if err := none.ValidateOutputs("postgres", nil); err != nil {
+		t.Errorf("expected nil contract to accept outputs, got %v", err)
+	}
+}
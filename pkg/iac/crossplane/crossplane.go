@@ -0,0 +1,433 @@
+// Package crossplane implements an IaC plugin that provisions infrastructure
+// by creating Crossplane claims (or any Kubernetes custom resource) and
+// waiting for them to report a Ready condition. Status fields and connection
+// secret keys are mapped to module outputs, letting datacenter hooks delegate
+// to XRDs that platform teams already maintain.
+package crossplane
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/logging"
+)
+
+// pluginLog is the logger for the commands the plugin runs.
+var pluginLog = logging.Logger(logging.Plugins)
+
+func init() {
+	iac.Register("crossplane", func() (iac.Plugin, error) {
+		return NewPlugin()
+	})
+}
+
+// runFunc executes kubectl with the given arguments and optional stdin.
+type runFunc func(ctx context.Context, args []string, stdin []byte, opts iac.RunOptions) ([]byte, error)
+
+// Plugin implements the IaC plugin interface on top of kubectl.
+type Plugin struct {
+	binaryPath string
+	run        runFunc
+}
+
+// NewPlugin creates a new crossplane plugin instance.
+func NewPlugin() (*Plugin, error) {
+	binaryPath, err := exec.LookPath("kubectl")
+	if err != nil {
+		return nil, fmt.Errorf("kubectl binary not found: %w", err)
+	}
+	p := &Plugin{binaryPath: binaryPath}
+	p.run = p.runKubectl
+	return p, nil
+}
+
+func (p *Plugin) Name() string {
+	return "crossplane"
+}
+
+// State is the persisted state of a crossplane module.
+type State struct {
+	APIVersion string                 `json:"api_version"`
+	Kind       string                 `json:"kind"`
+	Name       string                 `json:"name"`
+	Namespace  string                 `json:"namespace,omitempty"`
+	Manifest   map[string]interface{} `json:"manifest"`
+	Outputs    map[string]interface{} `json:"outputs"`
+}
+
+// ref returns the kubectl resource reference, e.g. "PostgreSQLInstance.database.example.org/db".
+func (s *State) ref() string {
+	kind := s.Kind
+	if group := strings.SplitN(s.APIVersion, "/", 2); len(group) == 2 {
+		kind = kind + "." + group[0]
+	}
+	return kind + "/" + s.Name
+}
+
+func (s *State) namespaceArgs() []string {
+	if s.Namespace == "" {
+		return nil
+	}
+	return []string{"-n", s.Namespace}
+}
+
+func (p *Plugin) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResult, error) {
+	module, state, err := p.prepare(opts.ModuleSource, opts.Inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := json.Marshal(state.Manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	progress(opts, iac.PhaseMessage(iac.PhaseProvisioning, fmt.Sprintf("applying %s", state.ref())))
+	if _, err := p.run(ctx, []string{"apply", "-f", "-"}, manifest, opts); err != nil {
+		return nil, fmt.Errorf("failed to apply %s: %w", state.ref(), err)
+	}
+
+	if condition := module.Wait.condition(); condition != "" {
+		progress(opts, iac.PhaseMessage(iac.PhaseWaitingHealthy, fmt.Sprintf("waiting for %s condition %s", state.ref(), condition)))
+		args := append([]string{
+			"wait", state.ref(),
+			"--for=condition=" + condition,
+			"--timeout=" + module.Wait.timeout().String(),
+		}, state.namespaceArgs()...)
+		if _, err := p.run(ctx, args, nil, opts); err != nil {
+			return nil, fmt.Errorf("%s did not become %s: %w", state.ref(), condition, err)
+		}
+	}
+
+	outputs, err := p.readOutputs(ctx, module, state, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize state: %w", err)
+	}
+
+	return &iac.ApplyResult{
+		Outputs: outputs,
+		State:   stateBytes,
+	}, nil
+}
+
+func (p *Plugin) Destroy(ctx context.Context, opts iac.RunOptions) error {
+	state, err := p.loadState(opts.StateReader)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		// No state: fall back to the rendered manifest so the object is still
+		// cleaned up if an earlier apply crashed before persisting state.
+		_, state, err = p.prepare(opts.ModuleSource, opts.Inputs)
+		if err != nil {
+			return fmt.Errorf("failed to render manifest for destroy: %w", err)
+		}
+	}
+
+	progress(opts, fmt.Sprintf("deleting %s", state.ref()))
+	args := append([]string{"delete", state.ref(), "--ignore-not-found", "--wait=true"}, state.namespaceArgs()...)
+	if _, err := p.run(ctx, args, nil, opts); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", state.ref(), err)
+	}
+	return nil
+}
+
+func (p *Plugin) Preview(ctx context.Context, opts iac.RunOptions) (*iac.PreviewResult, error) {
+	_, state, err := p.prepare(opts.ModuleSource, opts.Inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &iac.PreviewResult{}
+	change := iac.ResourceChange{
+		ResourceID:   state.ref(),
+		ResourceType: state.Kind,
+		After:        state.Manifest,
+	}
+
+	// A missing object is reported as nil; any other error, such as an
+	// unreachable cluster, says nothing about whether it exists
+	existing, err := p.getObject(ctx, state, opts)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case existing == nil:
+		change.Action = iac.ActionCreate
+		result.Summary.Create++
+	default:
+		manifest, _ := json.Marshal(state.Manifest)
+		if _, diffErr := p.run(ctx, []string{"diff", "-f", "-"}, manifest, opts); diffErr == nil {
+			return result, nil
+		}
+		change.Action = iac.ActionUpdate
+		change.Before = existing
+		result.Summary.Update++
+	}
+
+	result.Changes = append(result.Changes, change)
+	return result, nil
+}
+
+func (p *Plugin) Refresh(ctx context.Context, opts iac.RunOptions) (*iac.RefreshResult, error) {
+	state, err := p.loadState(opts.StateReader)
+	if err != nil || state == nil {
+		return &iac.RefreshResult{}, err
+	}
+
+	obj, err := p.getObject(ctx, state, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &iac.RefreshResult{}
+	if obj == nil {
+		result.Drifts = append(result.Drifts, iac.ResourceDrift{
+			ResourceID:   state.ref(),
+			ResourceType: state.Kind,
+			Diffs:        []iac.PropertyDiff{{Path: "metadata.name", OldValue: state.Name}},
+		})
+	}
+	result.State, _ = json.Marshal(state)
+	return result, nil
+}
+
+// Import adopts an existing object. The first mapping's ID, if set, names the
+// object to adopt (as "name" or "namespace/name"); otherwise the rendered
+// manifest's metadata is used.
+func (p *Plugin) Import(ctx context.Context, opts iac.ImportOptions) (*iac.ImportResult, error) {
+	module, state, err := p.prepare(opts.ModuleSource, opts.Inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	runOpts := iac.RunOptions{
+		ModuleSource: opts.ModuleSource,
+		Inputs:       opts.Inputs,
+		Environment:  opts.Environment,
+		Stdout:       opts.Stdout,
+		Stderr:       opts.Stderr,
+	}
+
+	var imported []string
+	if len(opts.Mappings) > 0 {
+		mapping := opts.Mappings[0]
+		if mapping.ID != "" {
+			if ns, name, ok := strings.Cut(mapping.ID, "/"); ok {
+				state.Namespace, state.Name = ns, name
+			} else {
+				state.Name = mapping.ID
+			}
+		}
+		imported = append(imported, mapping.Address)
+	}
+
+	obj, err := p.getObject(ctx, state, runOpts)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, fmt.Errorf("%s not found", state.ref())
+	}
+
+	outputs, err := p.readOutputs(ctx, module, state, runOpts)
+	if err != nil {
+		return nil, err
+	}
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize state: %w", err)
+	}
+
+	return &iac.ImportResult{
+		Outputs:           outputs,
+		State:             stateBytes,
+		ImportedResources: imported,
+	}, nil
+}
+
+// prepare loads the module and renders its manifest into an initial state.
+func (p *Plugin) prepare(source string, provided map[string]interface{}) (*Module, *State, error) {
+	module, err := LoadModule(source)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load module: %w", err)
+	}
+
+	inputs, err := resolveInputs(module.Inputs, provided)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve inputs: %w", err)
+	}
+
+	rendered, err := render(module.Manifest, inputs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to render manifest: %w", err)
+	}
+	manifest := rendered.(map[string]interface{})
+
+	state := &State{Manifest: manifest, Outputs: map[string]interface{}{}}
+	state.APIVersion, _ = manifest["apiVersion"].(string)
+	state.Kind, _ = manifest["kind"].(string)
+	if meta, ok := manifest["metadata"].(map[string]interface{}); ok {
+		state.Name, _ = meta["name"].(string)
+		state.Namespace, _ = meta["namespace"].(string)
+	}
+	if state.APIVersion == "" || state.Kind == "" || state.Name == "" {
+		return nil, nil, fmt.Errorf("manifest must set apiVersion, kind, and metadata.name")
+	}
+
+	return module, state, nil
+}
+
+// getObject fetches the live object, returning nil if it does not exist.
+func (p *Plugin) getObject(ctx context.Context, state *State, opts iac.RunOptions) (map[string]interface{}, error) {
+	args := append([]string{"get", state.ref(), "-o", "json", "--ignore-not-found"}, state.namespaceArgs()...)
+	out, err := p.run(ctx, args, nil, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", state.ref(), err)
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal(out, &obj); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", state.ref(), err)
+	}
+	return obj, nil
+}
+
+// readOutputs maps object fields and connection secret keys to outputs and
+// records them on the state.
+func (p *Plugin) readOutputs(ctx context.Context, module *Module, state *State, opts iac.RunOptions) (map[string]iac.OutputValue, error) {
+	obj, err := p.getObject(ctx, state, opts)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, fmt.Errorf("%s disappeared after apply", state.ref())
+	}
+
+	var secret map[string]interface{}
+	outputs := make(map[string]iac.OutputValue)
+	for name, def := range module.Outputs {
+		var value interface{}
+		var ok bool
+
+		if key, isSecret := strings.CutPrefix(def.Value, connectionSecretPrefix); isSecret {
+			if secret == nil {
+				secret, err = p.getConnectionSecret(ctx, obj, state, opts)
+				if err != nil {
+					return nil, err
+				}
+			}
+			value, ok = secret[key]
+		} else {
+			value, ok = lookupPath(obj, def.Value)
+		}
+		if !ok {
+			return nil, fmt.Errorf("output %s: field %q not found on %s", name, def.Value, state.ref())
+		}
+
+		state.Outputs[name] = value
+		outputs[name] = iac.OutputValue{Value: value, Sensitive: def.Sensitive}
+	}
+	return outputs, nil
+}
+
+// getConnectionSecret reads and decodes the secret referenced by
+// spec.writeConnectionSecretToRef.
+func (p *Plugin) getConnectionSecret(ctx context.Context, obj map[string]interface{}, state *State, opts iac.RunOptions) (map[string]interface{}, error) {
+	ref, ok := lookupPath(obj, "spec.writeConnectionSecretToRef")
+	refMap, isMap := ref.(map[string]interface{})
+	if !ok || !isMap {
+		return nil, fmt.Errorf("%s has no spec.writeConnectionSecretToRef", state.ref())
+	}
+	name, _ := refMap["name"].(string)
+	namespace, _ := refMap["namespace"].(string)
+	if namespace == "" {
+		namespace = state.Namespace
+	}
+
+	args := []string{"get", "secret", name, "-o", "json"}
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	out, err := p.run(ctx, args, nil, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read connection secret %s: %w", name, err)
+	}
+
+	var secret struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(out, &secret); err != nil {
+		return nil, fmt.Errorf("failed to parse connection secret %s: %w", name, err)
+	}
+
+	decoded := make(map[string]interface{}, len(secret.Data))
+	for k, v := range secret.Data {
+		raw, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode connection secret key %s: %w", k, err)
+		}
+		decoded[k] = string(raw)
+	}
+	return decoded, nil
+}
+
+func (p *Plugin) loadState(reader io.Reader) (*State, error) {
+	if reader == nil {
+		return nil, nil
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	return &state, nil
+}
+
+func (p *Plugin) runKubectl(ctx context.Context, args []string, stdin []byte, opts iac.RunOptions) ([]byte, error) {
+	pluginLog.Debug("running kubectl", "dir", opts.WorkDir, "args", args)
+	cmd := exec.CommandContext(ctx, p.binaryPath, args...)
+	cmd.Dir = opts.WorkDir
+
+	cmd.Env = os.Environ()
+	for k, v := range opts.Environment {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+	}
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+
+	// Stdout is machine-readable output, so it isn't streamed
+	return iac.RunCommand(cmd, nil, opts.Stderr)
+}
+
+func progress(opts iac.RunOptions, message string) {
+	if opts.OnProgress != nil {
+		opts.OnProgress(message)
+	}
+}
+
+// Ensure we implement the Plugin interface
+var _ iac.Plugin = (*Plugin)(nil)
@@ -0,0 +1,214 @@
+package crossplane
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testModule = `plugin: crossplane
+inputs:
+  name:
+    type: string
+    required: true
+  storage:
+    type: number
+    default: 20
+manifest:
+  apiVersion: database.example.org/v1alpha1
+  kind: PostgreSQLInstance
+  metadata:
+    name: "${inputs.name}"
+    namespace: team-a
+  spec:
+    parameters:
+      storageGB: "${inputs.storage}"
+    writeConnectionSecretToRef:
+      name: "${inputs.name}-conn"
+wait:
+  timeout: 2m
+outputs:
+  host:
+    value: status.atProvider.endpoint
+  ready:
+    value: status.conditions[0].status
+  password:
+    value: connectionSecret.password
+    sensitive: true
+`
+
+// fakeKubectl records invocations and serves canned responses.
+type fakeKubectl struct {
+	calls   [][]string
+	applied []byte
+	exists  bool
+	getErr  error // returned by gets of the object
+}
+
+func (f *fakeKubectl) run(ctx context.Context, args []string, stdin []byte, opts iac.RunOptions) ([]byte, error) {
+	f.calls = append(f.calls, args)
+	switch args[0] {
+	case "apply":
+		f.applied = stdin
+		f.exists = true
+		return nil, nil
+	case "wait", "delete":
+		return nil, nil
+	case "diff":
+		return nil, fmt.Errorf("exit status 1")
+	case "get":
+		if args[1] == "secret" {
+			return []byte(`{"data":{"password":"czNjcjN0"}}`), nil
+		}
+		if f.getErr != nil {
+			return nil, f.getErr
+		}
+		if !f.exists {
+			return nil, nil
+		}
+		return []byte(`{
+			"spec": {"writeConnectionSecretToRef": {"name": "db-conn"}},
+			"status": {
+				"atProvider": {"endpoint": "db.internal"},
+				"conditions": [{"type": "Ready", "status": "True"}]
+			}
+		}`), nil
+	}
+	return nil, fmt.Errorf("unexpected kubectl call: %v", args)
+}
+
+func newTestPlugin(t *testing.T) (*Plugin, *fakeKubectl, string) {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "module.yml"), []byte(testModule), 0644))
+	fake := &fakeKubectl{}
+	return &Plugin{run: fake.run}, fake, dir
+}
+
+func TestPlugin_Apply(t *testing.T) {
+	p, fake, dir := newTestPlugin(t)
+
+	result, err := p.Apply(context.Background(), iac.RunOptions{
+		ModuleSource: dir,
+		Inputs:       map[string]interface{}{"name": "db"},
+	})
+	require.NoError(t, err)
+
+	var manifest map[string]interface{}
+	require.NoError(t, json.Unmarshal(fake.applied, &manifest))
+	assert.Equal(t, "db", manifest["metadata"].(map[string]interface{})["name"])
+	params := manifest["spec"].(map[string]interface{})["parameters"].(map[string]interface{})
+	assert.Equal(t, float64(20), params["storageGB"], "whole-string references keep the input type")
+
+	assert.Equal(t, []string{
+		"wait", "PostgreSQLInstance.database.example.org/db",
+		"--for=condition=Ready", "--timeout=2m0s", "-n", "team-a",
+	}, fake.calls[1])
+
+	assert.Equal(t, "db.internal", result.Outputs["host"].Value)
+	assert.Equal(t, "True", result.Outputs["ready"].Value)
+	assert.Equal(t, "s3cr3t", result.Outputs["password"].Value)
+	assert.True(t, result.Outputs["password"].Sensitive)
+
+	var state State
+	require.NoError(t, json.Unmarshal(result.State, &state))
+	assert.Equal(t, "db", state.Name)
+	assert.Equal(t, "team-a", state.Namespace)
+}
+
+func TestPlugin_ApplyMissingRequiredInput(t *testing.T) {
+	p, _, dir := newTestPlugin(t)
+
+	_, err := p.Apply(context.Background(), iac.RunOptions{ModuleSource: dir})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `required input "name"`)
+}
+
+func TestPlugin_DestroyUsesState(t *testing.T) {
+	p, fake, dir := newTestPlugin(t)
+
+	state, _ := json.Marshal(&State{APIVersion: "example.org/v1", Kind: "Bucket", Name: "assets"})
+	err := p.Destroy(context.Background(), iac.RunOptions{
+		ModuleSource: dir,
+		StateReader:  bytes.NewReader(state),
+	})
+	require.NoError(t, err)
+	require.Len(t, fake.calls, 1)
+	assert.Equal(t, "delete", fake.calls[0][0])
+	assert.Equal(t, "Bucket.example.org/assets", fake.calls[0][1])
+}
+
+func TestPlugin_DestroyWithoutStateFailsToRender(t *testing.T) {
+	p, fake, dir := newTestPlugin(t)
+
+	err := p.Destroy(context.Background(), iac.RunOptions{ModuleSource: dir})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to render manifest for destroy")
+	assert.Empty(t, fake.calls)
+}
+
+func TestPlugin_Preview(t *testing.T) {
+	p, fake, dir := newTestPlugin(t)
+	opts := iac.RunOptions{ModuleSource: dir, Inputs: map[string]interface{}{"name": "db"}}
+
+	preview, err := p.Preview(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, 1, preview.Summary.Create)
+
+	fake.exists = true
+	preview, err = p.Preview(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, 1, preview.Summary.Update)
+
+	// Only a missing object is a create
+	fake.getErr = fmt.Errorf("exit status 1: Unable to connect to the server")
+	_, err = p.Preview(context.Background(), opts)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Unable to connect to the server")
+}
+
+func TestLookupPath(t *testing.T) {
+	obj := map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Synced"},
+				map[string]interface{}{"type": "Ready"},
+			},
+		},
+	}
+
+	tests := []struct {
+		path   string
+		want   interface{}
+		wantOK bool
+	}{
+		{"status.conditions[1].type", "Ready", true},
+		{"status.conditions[5].type", nil, false},
+		{"status.missing", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := lookupPath(obj, tt.path)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoadModule_RejectsOtherPlugins(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "module.yml"),
+		[]byte(strings.Replace(testModule, "plugin: crossplane", "plugin: native", 1)), 0644))
+
+	_, err := LoadModule(dir)
+	require.Error(t, err)
+}
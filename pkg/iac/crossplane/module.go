@@ -0,0 +1,253 @@
+package crossplane
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Module is a crossplane module definition (module.yml).
+//
+//	plugin: crossplane
+//	inputs:
+//	  name: { type: string, required: true }
+//	manifest:
+//	  apiVersion: database.example.org/v1alpha1
+//	  kind: PostgreSQLInstance
+//	  metadata:
+//	    name: "${inputs.name}"
+//	  spec:
+//	    writeConnectionSecretToRef:
+//	      name: "${inputs.name}-conn"
+//	wait:
+//	  condition: Ready
+//	  timeout: 15m
+//	outputs:
+//	  host:
+//	    value: status.atProvider.endpoint
+//	  password:
+//	    value: connectionSecret.password
+//	    sensitive: true
+type Module struct {
+	Plugin   string                 `yaml:"plugin"` // Must be "crossplane"
+	Inputs   map[string]InputDef    `yaml:"inputs"`
+	Manifest map[string]interface{} `yaml:"manifest"`
+	Wait     WaitConfig             `yaml:"wait"`
+	Outputs  map[string]OutputDef   `yaml:"outputs"`
+}
+
+// InputDef defines a module input.
+type InputDef struct {
+	Type        string      `yaml:"type"`
+	Required    bool        `yaml:"required"`
+	Default     interface{} `yaml:"default"`
+	Description string      `yaml:"description"`
+	Sensitive   bool        `yaml:"sensitive"`
+}
+
+// WaitConfig controls how the plugin waits for the object to become ready.
+type WaitConfig struct {
+	// Condition is the status condition type to wait for. Defaults to
+	// "Ready"; set to "none" to skip waiting.
+	Condition string `yaml:"condition"`
+
+	// Timeout bounds the wait (Go duration). Defaults to 10m.
+	Timeout string `yaml:"timeout"`
+}
+
+// OutputDef maps a field of the applied object to a module output.
+//
+// Value is a dotted path into the object (e.g. "status.atProvider.endpoint"
+// or "status.conditions[0].reason"). Paths prefixed with "connectionSecret."
+// read a key from the secret referenced by spec.writeConnectionSecretToRef.
+type OutputDef struct {
+	Value       string `yaml:"value"`
+	Description string `yaml:"description"`
+	Sensitive   bool   `yaml:"sensitive"`
+}
+
+const (
+	defaultCondition = "Ready"
+	defaultTimeout   = 10 * time.Minute
+
+	connectionSecretPrefix = "connectionSecret."
+)
+
+// LoadModule loads a crossplane module definition from a directory or file.
+func LoadModule(path string) (*Module, error) {
+	files := []string{
+		filepath.Join(path, "module.yml"),
+		filepath.Join(path, "module.yaml"),
+		path,
+	}
+
+	var data []byte
+	var err error
+	for _, f := range files {
+		data, err = os.ReadFile(f)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read module: %w", err)
+	}
+
+	var module Module
+	if err := yaml.Unmarshal(data, &module); err != nil {
+		return nil, fmt.Errorf("failed to parse module: %w", err)
+	}
+
+	if module.Plugin != "" && module.Plugin != "crossplane" {
+		return nil, fmt.Errorf("invalid plugin type: expected 'crossplane', got '%s'", module.Plugin)
+	}
+	if len(module.Manifest) == 0 {
+		return nil, fmt.Errorf("module has no manifest")
+	}
+
+	return &module, nil
+}
+
+// timeout returns the configured wait timeout.
+func (w WaitConfig) timeout() time.Duration {
+	if w.Timeout == "" {
+		return defaultTimeout
+	}
+	d, err := time.ParseDuration(w.Timeout)
+	if err != nil || d <= 0 {
+		return defaultTimeout
+	}
+	return d
+}
+
+// condition returns the condition to wait for, or "" to skip waiting.
+func (w WaitConfig) condition() string {
+	switch strings.ToLower(w.Condition) {
+	case "":
+		return defaultCondition
+	case "none", "false":
+		return ""
+	default:
+		return w.Condition
+	}
+}
+
+// resolveInputs applies defaults and checks required inputs.
+func resolveInputs(defs map[string]InputDef, provided map[string]interface{}) (map[string]interface{}, error) {
+	resolved := make(map[string]interface{})
+	for name, def := range defs {
+		if v, ok := provided[name]; ok {
+			resolved[name] = v
+		} else if def.Default != nil {
+			resolved[name] = def.Default
+		} else if def.Required {
+			return nil, fmt.Errorf("required input %q not provided", name)
+		}
+	}
+	// Pass through undeclared inputs so simple modules can skip the inputs block.
+	for name, v := range provided {
+		if _, ok := resolved[name]; !ok {
+			resolved[name] = v
+		}
+	}
+	return resolved, nil
+}
+
+var inputRefPattern = regexp.MustCompile(`\$\{\s*inputs\.([A-Za-z0-9_\-]+)\s*\}`)
+
+// render substitutes ${inputs.x} references throughout the manifest. A string
+// consisting of a single reference takes the input's native type so numbers
+// and booleans survive into the object spec.
+func render(value interface{}, inputs map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if m := inputRefPattern.FindStringSubmatch(v); m != nil && m[0] == strings.TrimSpace(v) {
+			val, ok := inputs[m[1]]
+			if !ok {
+				return nil, fmt.Errorf("unknown input %q", m[1])
+			}
+			return val, nil
+		}
+		var missing string
+		out := inputRefPattern.ReplaceAllStringFunc(v, func(ref string) string {
+			name := inputRefPattern.FindStringSubmatch(ref)[1]
+			val, ok := inputs[name]
+			if !ok {
+				missing = name
+				return ref
+			}
+			return fmt.Sprintf("%v", val)
+		})
+		if missing != "" {
+			return nil, fmt.Errorf("unknown input %q", missing)
+		}
+		return out, nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			r, err := render(item, inputs)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			r, err := render(item, inputs)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// lookupPath resolves a dotted path (with optional [n] indexes) in an object.
+func lookupPath(obj interface{}, path string) (interface{}, bool) {
+	current := obj
+	for _, segment := range strings.Split(path, ".") {
+		name := segment
+		var indexes []int
+		for {
+			open := strings.LastIndex(name, "[")
+			if open < 0 || !strings.HasSuffix(name, "]") {
+				break
+			}
+			var idx int
+			if _, err := fmt.Sscanf(name[open+1:len(name)-1], "%d", &idx); err != nil {
+				return nil, false
+			}
+			indexes = append([]int{idx}, indexes...)
+			name = name[:open]
+		}
+
+		if name != "" {
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			current, ok = m[name]
+			if !ok {
+				return nil, false
+			}
+		}
+		for _, idx := range indexes {
+			list, ok := current.([]interface{})
+			if !ok || idx < 0 || idx >= len(list) {
+				return nil, false
+			}
+			current = list[idx]
+		}
+	}
+	return current, true
+}
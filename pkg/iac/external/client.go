@@ -0,0 +1,291 @@
+package external
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	goplugin "github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/iac/external/pluginpb"
+)
+
+// defaultPassEnv lists host environment variables forwarded to every plugin.
+// Everything else must be passed explicitly through RunOptions.Environment.
+var defaultPassEnv = []string{"PATH", "HOME", "TMPDIR", "USER", "LANG"}
+
+// Plugin is the host-side iac.Plugin that delegates to an external binary.
+type Plugin struct {
+	name string
+	path string
+
+	// args are extra arguments passed to the binary (used by tests that
+	// re-exec the test binary as a plugin).
+	args []string
+
+	// PassEnv lists additional host environment variables forwarded to the
+	// plugin process on top of the default allowlist.
+	PassEnv []string
+}
+
+// NewPlugin creates a plugin backed by the executable at path.
+func NewPlugin(name, path string) *Plugin {
+	return &Plugin{name: name, path: path}
+}
+
+func (p *Plugin) Name() string {
+	return p.name
+}
+
+// Path returns the location of the plugin binary.
+func (p *Plugin) Path() string {
+	return p.path
+}
+
+func (p *Plugin) Preview(ctx context.Context, opts iac.RunOptions) (*iac.PreviewResult, error) {
+	req, err := p.runRequest(opts)
+	if err != nil {
+		return nil, err
+	}
+	result, err := p.call(ctx, opts.Environment, opts.OnProgress, opts.Stdout, opts.Stderr,
+		func(ctx context.Context, c pluginpb.IaCPluginClient) (grpc.ServerStreamingClient[pluginpb.Event], error) {
+			return c.Preview(ctx, req)
+		})
+	if err != nil {
+		return nil, err
+	}
+	preview, err := fromPreview(result.Preview)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s returned an invalid result: %w", p.name, err)
+	}
+	return preview, nil
+}
+
+func (p *Plugin) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResult, error) {
+	req, err := p.runRequest(opts)
+	if err != nil {
+		return nil, err
+	}
+	result, err := p.call(ctx, opts.Environment, opts.OnProgress, opts.Stdout, opts.Stderr,
+		func(ctx context.Context, c pluginpb.IaCPluginClient) (grpc.ServerStreamingClient[pluginpb.Event], error) {
+			return c.Apply(ctx, req)
+		})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeState(opts.StateWriter, result.State); err != nil {
+		return nil, err
+	}
+	apply, err := fromApply(result.Apply)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s returned an invalid result: %w", p.name, err)
+	}
+	return apply, nil
+}
+
+func (p *Plugin) Destroy(ctx context.Context, opts iac.RunOptions) error {
+	req, err := p.runRequest(opts)
+	if err != nil {
+		return err
+	}
+	result, err := p.call(ctx, opts.Environment, opts.OnProgress, opts.Stdout, opts.Stderr,
+		func(ctx context.Context, c pluginpb.IaCPluginClient) (grpc.ServerStreamingClient[pluginpb.Event], error) {
+			return c.Destroy(ctx, req)
+		})
+	if err != nil {
+		return err
+	}
+	return writeState(opts.StateWriter, result.State)
+}
+
+func (p *Plugin) Refresh(ctx context.Context, opts iac.RunOptions) (*iac.RefreshResult, error) {
+	req, err := p.runRequest(opts)
+	if err != nil {
+		return nil, err
+	}
+	result, err := p.call(ctx, opts.Environment, opts.OnProgress, opts.Stdout, opts.Stderr,
+		func(ctx context.Context, c pluginpb.IaCPluginClient) (grpc.ServerStreamingClient[pluginpb.Event], error) {
+			return c.Refresh(ctx, req)
+		})
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := fromRefresh(result.Refresh)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s returned an invalid result: %w", p.name, err)
+	}
+	return refresh, nil
+}
+
+func (p *Plugin) Import(ctx context.Context, opts iac.ImportOptions) (*iac.ImportResult, error) {
+	inputs, err := toInputs(opts.Inputs)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", p.name, err)
+	}
+	req := &pluginpb.ImportRequest{
+		ModuleSource: opts.ModuleSource,
+		ModulePath:   opts.ModulePath,
+		Inputs:       inputs,
+		Mappings:     toMappings(opts.Mappings),
+		WorkDir:      opts.WorkDir,
+		Environment:  opts.Environment,
+	}
+	result, err := p.call(ctx, opts.Environment, nil, opts.Stdout, opts.Stderr,
+		func(ctx context.Context, c pluginpb.IaCPluginClient) (grpc.ServerStreamingClient[pluginpb.Event], error) {
+			return c.Import(ctx, req)
+		})
+	if err != nil {
+		return nil, err
+	}
+	imported, err := fromImport(result.Import)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s returned an invalid result: %w", p.name, err)
+	}
+	return imported, nil
+}
+
+// runRequest converts RunOptions to the wire request, draining the state reader.
+func (p *Plugin) runRequest(opts iac.RunOptions) (*pluginpb.RunRequest, error) {
+	var state []byte
+	if opts.StateReader != nil {
+		data, err := io.ReadAll(opts.StateReader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read state for plugin %s: %w", p.name, err)
+		}
+		state = data
+	}
+
+	inputs, err := toInputs(opts.Inputs)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", p.name, err)
+	}
+
+	return &pluginpb.RunRequest{
+		ModuleSource: opts.ModuleSource,
+		ModulePath:   opts.ModulePath,
+		Inputs:       inputs,
+		State:        state,
+		WorkDir:      opts.WorkDir,
+		Environment:  opts.Environment,
+		Volumes:      toVolumes(opts.Volumes),
+	}, nil
+}
+
+// call starts the plugin binary, makes a single streaming RPC and returns its
+// result. Progress events are forwarded to onProgress and log events to
+// stdout as they arrive; the plugin's stderr is forwarded to stderr. The
+// plugin is stopped before call returns.
+func (p *Plugin) call(
+	ctx context.Context,
+	env map[string]string,
+	onProgress func(string),
+	stdout, stderr io.Writer,
+	rpc func(context.Context, pluginpb.IaCPluginClient) (grpc.ServerStreamingClient[pluginpb.Event], error),
+) (*pluginpb.Result, error) {
+	cmd := exec.Command(p.path, p.args...)
+	cmd.Env = p.sandboxEnv(env)
+
+	var stderrBuf bytes.Buffer
+	pluginStderr := io.Writer(&stderrBuf)
+	if stderr != nil {
+		pluginStderr = io.MultiWriter(stderr, &stderrBuf)
+	}
+
+	client := goplugin.NewClient(&goplugin.ClientConfig{
+		HandshakeConfig:  Handshake,
+		Plugins:          goplugin.PluginSet{pluginKey: &grpcPlugin{}},
+		Cmd:              cmd,
+		SkipHostEnv:      true,
+		AllowedProtocols: []goplugin.Protocol{goplugin.ProtocolGRPC},
+		AutoMTLS:         true,
+		Logger:           hclog.NewNullLogger(),
+		Stderr:           pluginStderr,
+		SyncStdout:       stdout,
+		SyncStderr:       stderr,
+	})
+	defer client.Kill()
+
+	conn, err := client.Client()
+	if err != nil {
+		if msg := strings.TrimSpace(stderrBuf.String()); msg != "" {
+			return nil, fmt.Errorf("failed to start plugin %s: %w\n%s", p.name, err, msg)
+		}
+		return nil, fmt.Errorf("failed to start plugin %s: %w", p.name, err)
+	}
+	raw, err := conn.Dispense(pluginKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", p.name, err)
+	}
+
+	stream, err := rpc(ctx, raw.(pluginpb.IaCPluginClient))
+	if err != nil {
+		return nil, p.rpcError(err)
+	}
+	for {
+		event, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("plugin %s returned no result", p.name)
+		}
+		if err != nil {
+			return nil, p.rpcError(err)
+		}
+
+		switch e := event.Event.(type) {
+		case *pluginpb.Event_Progress:
+			if onProgress != nil {
+				onProgress(e.Progress)
+			}
+		case *pluginpb.Event_Log:
+			if stdout != nil {
+				fmt.Fprintln(stdout, e.Log)
+			}
+		case *pluginpb.Event_Result:
+			return e.Result, nil
+		}
+	}
+}
+
+// rpcError reports an error returned by the plugin with its message alone,
+// rather than gRPC's "rpc error: code = ... desc = ..." form.
+func (p *Plugin) rpcError(err error) error {
+	if s, ok := status.FromError(err); ok {
+		return fmt.Errorf("plugin %s: %s", p.name, s.Message())
+	}
+	return fmt.Errorf("plugin %s: %w", p.name, err)
+}
+
+// sandboxEnv builds the plugin process environment. Only the allowlisted host
+// variables and the explicitly passed environment reach the plugin, with the
+// handshake variables go-plugin adds; host credentials are not leaked
+// implicitly.
+func (p *Plugin) sandboxEnv(env map[string]string) []string {
+	var result []string
+	for _, key := range append(append([]string{}, defaultPassEnv...), p.PassEnv...) {
+		if v, ok := os.LookupEnv(key); ok {
+			result = append(result, key+"="+v)
+		}
+	}
+	for k, v := range env {
+		result = append(result, k+"="+v)
+	}
+	return result
+}
+
+func writeState(w io.Writer, state []byte) error {
+	if w == nil || len(state) == 0 {
+		return nil
+	}
+	if _, err := w.Write(state); err != nil {
+		return fmt.Errorf("failed to write plugin state: %w", err)
+	}
+	return nil
+}
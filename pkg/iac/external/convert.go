@@ -0,0 +1,316 @@
+package external
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/iac/external/pluginpb"
+)
+
+// Conversions between the iac types and their wire form. Arbitrary values,
+// such as inputs and outputs, cross as JSON, so numbers arrive as float64 as
+// they would from any JSON-encoded state.
+
+func toDynamic(v interface{}) (*pluginpb.DynamicValue, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.DynamicValue{Json: data}, nil
+}
+
+func fromDynamic(dv *pluginpb.DynamicValue) (interface{}, error) {
+	if dv == nil || len(dv.Json) == 0 {
+		return nil, nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(dv.Json, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func toInputs(inputs map[string]interface{}) (*pluginpb.DynamicValue, error) {
+	if inputs == nil {
+		return nil, nil
+	}
+	dv, err := toDynamic(inputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode inputs: %w", err)
+	}
+	return dv, nil
+}
+
+func fromInputs(dv *pluginpb.DynamicValue) (map[string]interface{}, error) {
+	if dv == nil || len(dv.Json) == 0 {
+		return nil, nil
+	}
+	var inputs map[string]interface{}
+	if err := json.Unmarshal(dv.Json, &inputs); err != nil {
+		return nil, fmt.Errorf("failed to decode inputs: %w", err)
+	}
+	return inputs, nil
+}
+
+func toVolumes(volumes []iac.VolumeMount) []*pluginpb.VolumeMount {
+	var result []*pluginpb.VolumeMount
+	for _, v := range volumes {
+		result = append(result, &pluginpb.VolumeMount{HostPath: v.HostPath, MountPath: v.MountPath, ReadOnly: v.ReadOnly})
+	}
+	return result
+}
+
+func fromVolumes(volumes []*pluginpb.VolumeMount) []iac.VolumeMount {
+	var result []iac.VolumeMount
+	for _, v := range volumes {
+		result = append(result, iac.VolumeMount{HostPath: v.HostPath, MountPath: v.MountPath, ReadOnly: v.ReadOnly})
+	}
+	return result
+}
+
+func toMappings(mappings []iac.ImportMapping) []*pluginpb.ImportMapping {
+	var result []*pluginpb.ImportMapping
+	for _, m := range mappings {
+		result = append(result, &pluginpb.ImportMapping{Address: m.Address, Id: m.ID})
+	}
+	return result
+}
+
+func fromMappings(mappings []*pluginpb.ImportMapping) []iac.ImportMapping {
+	var result []iac.ImportMapping
+	for _, m := range mappings {
+		result = append(result, iac.ImportMapping{Address: m.Address, ID: m.Id})
+	}
+	return result
+}
+
+func toOutputs(outputs map[string]iac.OutputValue) (map[string]*pluginpb.OutputValue, error) {
+	if outputs == nil {
+		return nil, nil
+	}
+	result := make(map[string]*pluginpb.OutputValue, len(outputs))
+	for name, out := range outputs {
+		value, err := toDynamic(out.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode output %s: %w", name, err)
+		}
+		result[name] = &pluginpb.OutputValue{Value: value, Sensitive: out.Sensitive}
+	}
+	return result, nil
+}
+
+func fromOutputs(outputs map[string]*pluginpb.OutputValue) (map[string]iac.OutputValue, error) {
+	if outputs == nil {
+		return nil, nil
+	}
+	result := make(map[string]iac.OutputValue, len(outputs))
+	for name, out := range outputs {
+		value, err := fromDynamic(out.GetValue())
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode output %s: %w", name, err)
+		}
+		result[name] = iac.OutputValue{Value: value, Sensitive: out.GetSensitive()}
+	}
+	return result, nil
+}
+
+func toDiffs(diffs []iac.PropertyDiff) ([]*pluginpb.PropertyDiff, error) {
+	var result []*pluginpb.PropertyDiff
+	for _, d := range diffs {
+		oldValue, err := toDynamic(d.OldValue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", d.Path, err)
+		}
+		newValue, err := toDynamic(d.NewValue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", d.Path, err)
+		}
+		result = append(result, &pluginpb.PropertyDiff{Path: d.Path, OldValue: oldValue, NewValue: newValue, Sensitive: d.Sensitive})
+	}
+	return result, nil
+}
+
+func fromDiffs(diffs []*pluginpb.PropertyDiff) ([]iac.PropertyDiff, error) {
+	var result []iac.PropertyDiff
+	for _, d := range diffs {
+		oldValue, err := fromDynamic(d.OldValue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", d.Path, err)
+		}
+		newValue, err := fromDynamic(d.NewValue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", d.Path, err)
+		}
+		result = append(result, iac.PropertyDiff{Path: d.Path, OldValue: oldValue, NewValue: newValue, Sensitive: d.Sensitive})
+	}
+	return result, nil
+}
+
+func toPreview(result *iac.PreviewResult) (*pluginpb.PreviewResult, error) {
+	pb := &pluginpb.PreviewResult{Summary: &pluginpb.ChangeSummary{
+		Create:  int64(result.Summary.Create),
+		Update:  int64(result.Summary.Update),
+		Delete:  int64(result.Summary.Delete),
+		Replace: int64(result.Summary.Replace),
+	}}
+	for _, c := range result.Changes {
+		before, err := toDynamic(c.Before)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", c.ResourceID, err)
+		}
+		after, err := toDynamic(c.After)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", c.ResourceID, err)
+		}
+		diff, err := toDiffs(c.Diff)
+		if err != nil {
+			return nil, err
+		}
+		pb.Changes = append(pb.Changes, &pluginpb.ResourceChange{
+			ResourceId:   c.ResourceID,
+			ResourceType: c.ResourceType,
+			Action:       string(c.Action),
+			Before:       before,
+			After:        after,
+			Diff:         diff,
+		})
+	}
+	return pb, nil
+}
+
+func fromPreview(pb *pluginpb.PreviewResult) (*iac.PreviewResult, error) {
+	result := &iac.PreviewResult{}
+	if pb == nil {
+		return result, nil
+	}
+	if s := pb.Summary; s != nil {
+		result.Summary = iac.ChangeSummary{
+			Create:  int(s.Create),
+			Update:  int(s.Update),
+			Delete:  int(s.Delete),
+			Replace: int(s.Replace),
+		}
+	}
+	for _, c := range pb.Changes {
+		before, err := fromDynamic(c.Before)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", c.ResourceId, err)
+		}
+		after, err := fromDynamic(c.After)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", c.ResourceId, err)
+		}
+		diff, err := fromDiffs(c.Diff)
+		if err != nil {
+			return nil, err
+		}
+		result.Changes = append(result.Changes, iac.ResourceChange{
+			ResourceID:   c.ResourceId,
+			ResourceType: c.ResourceType,
+			Action:       iac.ChangeAction(c.Action),
+			Before:       before,
+			After:        after,
+			Diff:         diff,
+		})
+	}
+	return result, nil
+}
+
+func toApply(result *iac.ApplyResult) (*pluginpb.ApplyResult, error) {
+	outputs, err := toOutputs(result.Outputs)
+	if err != nil {
+		return nil, err
+	}
+	pb := &pluginpb.ApplyResult{Outputs: outputs, State: result.State}
+	if result.PartialError != nil {
+		pb.PartialError = result.PartialError.Error()
+	}
+	return pb, nil
+}
+
+func fromApply(pb *pluginpb.ApplyResult) (*iac.ApplyResult, error) {
+	result := &iac.ApplyResult{}
+	if pb == nil {
+		return result, nil
+	}
+	outputs, err := fromOutputs(pb.Outputs)
+	if err != nil {
+		return nil, err
+	}
+	result.Outputs = outputs
+	result.State = pb.State
+	if pb.PartialError != "" {
+		result.PartialError = errors.New(pb.PartialError)
+	}
+	return result, nil
+}
+
+func toRefresh(result *iac.RefreshResult) (*pluginpb.RefreshResult, error) {
+	outputs, err := toOutputs(result.Outputs)
+	if err != nil {
+		return nil, err
+	}
+	pb := &pluginpb.RefreshResult{State: result.State, Outputs: outputs, HasOutputs: result.Outputs != nil}
+	for _, d := range result.Drifts {
+		diffs, err := toDiffs(d.Diffs)
+		if err != nil {
+			return nil, err
+		}
+		pb.Drifts = append(pb.Drifts, &pluginpb.ResourceDrift{ResourceId: d.ResourceID, ResourceType: d.ResourceType, Diffs: diffs})
+	}
+	return pb, nil
+}
+
+func fromRefresh(pb *pluginpb.RefreshResult) (*iac.RefreshResult, error) {
+	result := &iac.RefreshResult{}
+	if pb == nil {
+		return result, nil
+	}
+	result.State = pb.State
+	if pb.HasOutputs {
+		outputs, err := fromOutputs(pb.Outputs)
+		if err != nil {
+			return nil, err
+		}
+		if outputs == nil {
+			outputs = map[string]iac.OutputValue{}
+		}
+		result.Outputs = outputs
+	}
+	for _, d := range pb.Drifts {
+		diffs, err := fromDiffs(d.Diffs)
+		if err != nil {
+			return nil, err
+		}
+		result.Drifts = append(result.Drifts, iac.ResourceDrift{ResourceID: d.ResourceId, ResourceType: d.ResourceType, Diffs: diffs})
+	}
+	return result, nil
+}
+
+func toImport(result *iac.ImportResult) (*pluginpb.ImportResult, error) {
+	outputs, err := toOutputs(result.Outputs)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.ImportResult{Outputs: outputs, State: result.State, ImportedResources: result.ImportedResources}, nil
+}
+
+func fromImport(pb *pluginpb.ImportResult) (*iac.ImportResult, error) {
+	result := &iac.ImportResult{}
+	if pb == nil {
+		return result, nil
+	}
+	outputs, err := fromOutputs(pb.Outputs)
+	if err != nil {
+		return nil, err
+	}
+	result.Outputs = outputs
+	result.State = pb.State
+	result.ImportedResources = pb.ImportedResources
+	return result, nil
+}
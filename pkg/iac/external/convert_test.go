@@ -0,0 +1,44 @@
+package external
+
+import (
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefresh_OutputsRoundTrip(t *testing.T) {
+	for name, outputs := range map[string]map[string]iac.OutputValue{
+		"none":  nil,
+		"empty": {},
+		"some":  {"url": {Value: "https://db"}, "password": {Value: "hunter2", Sensitive: true}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pb, err := toRefresh(&iac.RefreshResult{Outputs: outputs})
+			require.NoError(t, err)
+			result, err := fromRefresh(pb)
+			require.NoError(t, err)
+			assert.Equal(t, outputs, result.Outputs)
+		})
+	}
+}
+
+func TestPreview_RoundTrip(t *testing.T) {
+	preview := &iac.PreviewResult{
+		Changes: []iac.ResourceChange{{
+			ResourceID:   "aws_db_instance.main",
+			ResourceType: "aws_db_instance",
+			Action:       iac.ActionUpdate,
+			Before:       map[string]interface{}{"size": "small"},
+			After:        map[string]interface{}{"size": "large"},
+			Diff:         []iac.PropertyDiff{{Path: "size", OldValue: "small", NewValue: "large"}},
+		}},
+		Summary: iac.ChangeSummary{Update: 1},
+	}
+	pb, err := toPreview(preview)
+	require.NoError(t, err)
+	result, err := fromPreview(pb)
+	require.NoError(t, err)
+	assert.Equal(t, preview, result)
+}
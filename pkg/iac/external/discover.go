@@ -0,0 +1,92 @@
+package external
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+)
+
+// PluginDirEnv overrides the directory scanned for plugin binaries.
+const PluginDirEnv = "CLDCTL_PLUGIN_DIR"
+
+// DefaultDir returns the directory scanned for external plugins:
+// $CLDCTL_PLUGIN_DIR if set, otherwise ~/.cldctl/plugins.
+func DefaultDir() (string, error) {
+	if dir := os.Getenv(PluginDirEnv); dir != "" {
+		return dir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".cldctl", "plugins"), nil
+}
+
+// Discover scans dir for plugin binaries named "cldctl-plugin-<name>" and
+// returns them as plugins sorted by name. A missing directory yields no
+// plugins and no error.
+func Discover(dir string) ([]*Plugin, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read plugin directory %s: %w", dir, err)
+	}
+
+	var plugins []*Plugin
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), BinaryPrefix) {
+			continue
+		}
+
+		name := strings.TrimPrefix(entry.Name(), BinaryPrefix)
+		if runtime.GOOS == "windows" {
+			name = strings.TrimSuffix(name, ".exe")
+		}
+		if name == "" {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if runtime.GOOS != "windows" && info.Mode()&0111 == 0 {
+			continue
+		}
+
+		plugins = append(plugins, NewPlugin(name, filepath.Join(dir, entry.Name())))
+	}
+
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].name < plugins[j].name })
+	return plugins, nil
+}
+
+// RegisterDir discovers plugins in dir and registers them with r. Built-in
+// plugins take precedence: an external binary never shadows a compiled-in
+// plugin of the same name. It returns the names that were registered.
+func RegisterDir(r *iac.Registry, dir string) ([]string, error) {
+	plugins, err := Discover(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var registered []string
+	for _, p := range plugins {
+		if r.Has(p.name) {
+			continue
+		}
+		plugin := p
+		r.Register(plugin.name, func() (iac.Plugin, error) {
+			return plugin, nil
+		})
+		registered = append(registered, plugin.name)
+	}
+	return registered, nil
+}
@@ -0,0 +1,175 @@
+package external
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// helperEnv makes the test binary act as a plugin when re-executed.
+const helperEnv = "CLDCTL_EXTERNAL_PLUGIN_HELPER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(helperEnv) == "1" {
+		Serve(&echoPlugin{})
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// echoPlugin echoes its inputs back as outputs and reports its environment.
+type echoPlugin struct{}
+
+func (e *echoPlugin) Name() string { return "echo" }
+
+func (e *echoPlugin) Preview(ctx context.Context, opts iac.RunOptions) (*iac.PreviewResult, error) {
+	return &iac.PreviewResult{Summary: iac.ChangeSummary{Create: len(opts.Inputs)}}, nil
+}
+
+func (e *echoPlugin) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResult, error) {
+	if opts.Inputs["fail"] == true {
+		return nil, fmt.Errorf("apply exploded")
+	}
+	opts.OnProgress("creating resources")
+	fmt.Fprintln(opts.Stdout, "hello from plugin")
+
+	outputs := map[string]iac.OutputValue{}
+	for k, v := range opts.Inputs {
+		outputs[k] = iac.OutputValue{Value: v}
+	}
+	outputs["secret_visible"] = iac.OutputValue{Value: os.Getenv("HOST_SECRET") != ""}
+	outputs["passed"] = iac.OutputValue{Value: os.Getenv("PASSED_VAR")}
+
+	var prior []byte
+	if opts.StateReader != nil {
+		prior = make([]byte, 64)
+		n, _ := opts.StateReader.Read(prior)
+		prior = prior[:n]
+	}
+	return &iac.ApplyResult{Outputs: outputs, State: append(prior, []byte("+applied")...)}, nil
+}
+
+func (e *echoPlugin) Destroy(ctx context.Context, opts iac.RunOptions) error {
+	_, err := opts.StateWriter.Write([]byte("destroyed"))
+	return err
+}
+
+func (e *echoPlugin) Refresh(ctx context.Context, opts iac.RunOptions) (*iac.RefreshResult, error) {
+	return &iac.RefreshResult{State: []byte("refreshed")}, nil
+}
+
+func (e *echoPlugin) Import(ctx context.Context, opts iac.ImportOptions) (*iac.ImportResult, error) {
+	var imported []string
+	for _, m := range opts.Mappings {
+		imported = append(imported, m.Address)
+	}
+	return &iac.ImportResult{ImportedResources: imported}, nil
+}
+
+func newHelperPlugin(t *testing.T) *Plugin {
+	t.Helper()
+	t.Setenv(helperEnv, "1")
+	p := NewPlugin("echo", os.Args[0])
+	p.PassEnv = []string{helperEnv}
+	return p
+}
+
+func TestPlugin_ApplyRoundTrip(t *testing.T) {
+	p := newHelperPlugin(t)
+	t.Setenv("HOST_SECRET", "do-not-leak")
+
+	var progress []string
+	var stdout bytes.Buffer
+	result, err := p.Apply(context.Background(), iac.RunOptions{
+		Inputs:      map[string]interface{}{"name": "db"},
+		StateReader: bytes.NewReader([]byte("prior")),
+		Environment: map[string]string{"PASSED_VAR": "yes"},
+		Stdout:      &stdout,
+		OnProgress:  func(msg string) { progress = append(progress, msg) },
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "db", result.Outputs["name"].Value)
+	assert.Equal(t, false, result.Outputs["secret_visible"].Value, "host env must not leak into the plugin")
+	assert.Equal(t, "yes", result.Outputs["passed"].Value)
+	assert.Equal(t, []byte("prior+applied"), result.State)
+	assert.Equal(t, []string{"creating resources"}, progress)
+	assert.Contains(t, stdout.String(), "hello from plugin")
+}
+
+func TestPlugin_ApplyError(t *testing.T) {
+	p := newHelperPlugin(t)
+
+	_, err := p.Apply(context.Background(), iac.RunOptions{
+		Inputs: map[string]interface{}{"fail": true},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "apply exploded")
+}
+
+func TestPlugin_OtherOperations(t *testing.T) {
+	p := newHelperPlugin(t)
+	ctx := context.Background()
+
+	preview, err := p.Preview(ctx, iac.RunOptions{Inputs: map[string]interface{}{"a": 1, "b": 2}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, preview.Summary.Create)
+
+	var state bytes.Buffer
+	require.NoError(t, p.Destroy(ctx, iac.RunOptions{StateWriter: &state}))
+	assert.Equal(t, "destroyed", state.String())
+
+	refresh, err := p.Refresh(ctx, iac.RunOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("refreshed"), refresh.State)
+
+	imported, err := p.Import(ctx, iac.ImportOptions{Mappings: []iac.ImportMapping{{Address: "aws_s3_bucket.main", ID: "b"}}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aws_s3_bucket.main"}, imported.ImportedResources)
+}
+
+func TestPlugin_HandshakeVersionMismatch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script plugin")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, BinaryPrefix+"old")
+	// A go-plugin handshake line announcing protocol version 99
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho '1|99|unix|/tmp/missing.sock|grpc'\nexec sleep 10\n"), 0755))
+
+	_, err := NewPlugin("old", script).Apply(context.Background(), iac.RunOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Plugin version: 99")
+}
+
+func TestRegisterDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, BinaryPrefix+"cloudformation"), []byte("#!/bin/sh\n"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, BinaryPrefix+"native"), []byte("#!/bin/sh\n"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a plugin"), 0644))
+
+	r := iac.NewRegistry()
+	r.Register("native", func() (iac.Plugin, error) { return nil, nil })
+
+	names, err := RegisterDir(r, dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cloudformation"}, names)
+
+	plugin, err := r.Get("cloudformation")
+	require.NoError(t, err)
+	assert.Equal(t, "cloudformation", plugin.Name())
+}
+
+func TestDiscover_MissingDir(t *testing.T) {
+	plugins, err := Discover(filepath.Join(t.TempDir(), "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, plugins)
+}
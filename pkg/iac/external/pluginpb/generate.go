@@ -0,0 +1,5 @@
+// Package pluginpb holds the gRPC service that external IaC plugins serve,
+// generated from plugin.proto.
+package pluginpb
+
+//go:generate buf generate
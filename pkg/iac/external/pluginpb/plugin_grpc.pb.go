@@ -0,0 +1,295 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: plugin.proto
+
+// The service an external IaC plugin serves to cldctl over go-plugin's gRPC
+// transport. It mirrors the iac.Plugin interface.
+
+package pluginpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	IaCPlugin_Preview_FullMethodName = "/cldctl.iac.v1.IaCPlugin/Preview"
+	IaCPlugin_Apply_FullMethodName   = "/cldctl.iac.v1.IaCPlugin/Apply"
+	IaCPlugin_Destroy_FullMethodName = "/cldctl.iac.v1.IaCPlugin/Destroy"
+	IaCPlugin_Refresh_FullMethodName = "/cldctl.iac.v1.IaCPlugin/Refresh"
+	IaCPlugin_Import_FullMethodName  = "/cldctl.iac.v1.IaCPlugin/Import"
+)
+
+// IaCPluginClient is the client API for IaCPlugin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type IaCPluginClient interface {
+	// Each operation streams progress and log events, then ends with exactly
+	// one result event. A failed operation ends with an error status instead.
+	Preview(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+	Apply(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+	Destroy(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+	Refresh(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+	Import(ctx context.Context, in *ImportRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+}
+
+type iaCPluginClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewIaCPluginClient(cc grpc.ClientConnInterface) IaCPluginClient {
+	return &iaCPluginClient{cc}
+}
+
+func (c *iaCPluginClient) Preview(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &IaCPlugin_ServiceDesc.Streams[0], IaCPlugin_Preview_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RunRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IaCPlugin_PreviewClient = grpc.ServerStreamingClient[Event]
+
+func (c *iaCPluginClient) Apply(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &IaCPlugin_ServiceDesc.Streams[1], IaCPlugin_Apply_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RunRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IaCPlugin_ApplyClient = grpc.ServerStreamingClient[Event]
+
+func (c *iaCPluginClient) Destroy(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &IaCPlugin_ServiceDesc.Streams[2], IaCPlugin_Destroy_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RunRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IaCPlugin_DestroyClient = grpc.ServerStreamingClient[Event]
+
+func (c *iaCPluginClient) Refresh(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &IaCPlugin_ServiceDesc.Streams[3], IaCPlugin_Refresh_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RunRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IaCPlugin_RefreshClient = grpc.ServerStreamingClient[Event]
+
+func (c *iaCPluginClient) Import(ctx context.Context, in *ImportRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &IaCPlugin_ServiceDesc.Streams[4], IaCPlugin_Import_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ImportRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IaCPlugin_ImportClient = grpc.ServerStreamingClient[Event]
+
+// IaCPluginServer is the server API for IaCPlugin service.
+// All implementations must embed UnimplementedIaCPluginServer
+// for forward compatibility.
+type IaCPluginServer interface {
+	// Each operation streams progress and log events, then ends with exactly
+	// one result event. A failed operation ends with an error status instead.
+	Preview(*RunRequest, grpc.ServerStreamingServer[Event]) error
+	Apply(*RunRequest, grpc.ServerStreamingServer[Event]) error
+	Destroy(*RunRequest, grpc.ServerStreamingServer[Event]) error
+	Refresh(*RunRequest, grpc.ServerStreamingServer[Event]) error
+	Import(*ImportRequest, grpc.ServerStreamingServer[Event]) error
+	mustEmbedUnimplementedIaCPluginServer()
+}
+
+// UnimplementedIaCPluginServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedIaCPluginServer struct{}
+
+func (UnimplementedIaCPluginServer) Preview(*RunRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method Preview not implemented")
+}
+func (UnimplementedIaCPluginServer) Apply(*RunRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method Apply not implemented")
+}
+func (UnimplementedIaCPluginServer) Destroy(*RunRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method Destroy not implemented")
+}
+func (UnimplementedIaCPluginServer) Refresh(*RunRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method Refresh not implemented")
+}
+func (UnimplementedIaCPluginServer) Import(*ImportRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method Import not implemented")
+}
+func (UnimplementedIaCPluginServer) mustEmbedUnimplementedIaCPluginServer() {}
+func (UnimplementedIaCPluginServer) testEmbeddedByValue()                   {}
+
+// UnsafeIaCPluginServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to IaCPluginServer will
+// result in compilation errors.
+type UnsafeIaCPluginServer interface {
+	mustEmbedUnimplementedIaCPluginServer()
+}
+
+func RegisterIaCPluginServer(s grpc.ServiceRegistrar, srv IaCPluginServer) {
+	// If the following call pancis, it indicates UnimplementedIaCPluginServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&IaCPlugin_ServiceDesc, srv)
+}
+
+func _IaCPlugin_Preview_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RunRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(IaCPluginServer).Preview(m, &grpc.GenericServerStream[RunRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IaCPlugin_PreviewServer = grpc.ServerStreamingServer[Event]
+
+func _IaCPlugin_Apply_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RunRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(IaCPluginServer).Apply(m, &grpc.GenericServerStream[RunRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IaCPlugin_ApplyServer = grpc.ServerStreamingServer[Event]
+
+func _IaCPlugin_Destroy_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RunRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(IaCPluginServer).Destroy(m, &grpc.GenericServerStream[RunRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IaCPlugin_DestroyServer = grpc.ServerStreamingServer[Event]
+
+func _IaCPlugin_Refresh_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RunRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(IaCPluginServer).Refresh(m, &grpc.GenericServerStream[RunRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IaCPlugin_RefreshServer = grpc.ServerStreamingServer[Event]
+
+func _IaCPlugin_Import_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ImportRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(IaCPluginServer).Import(m, &grpc.GenericServerStream[ImportRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IaCPlugin_ImportServer = grpc.ServerStreamingServer[Event]
+
+// IaCPlugin_ServiceDesc is the grpc.ServiceDesc for IaCPlugin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var IaCPlugin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cldctl.iac.v1.IaCPlugin",
+	HandlerType: (*IaCPluginServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Preview",
+			Handler:       _IaCPlugin_Preview_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Apply",
+			Handler:       _IaCPlugin_Apply_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Destroy",
+			Handler:       _IaCPlugin_Destroy_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Refresh",
+			Handler:       _IaCPlugin_Refresh_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Import",
+			Handler:       _IaCPlugin_Import_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "plugin.proto",
+}
@@ -0,0 +1,75 @@
+// Package external implements out-of-process IaC plugins.
+//
+// External plugins are standalone executables that implement the iac.Plugin
+// interface and serve it over hashicorp/go-plugin's gRPC transport, using the
+// IaCPlugin service defined in pluginpb/plugin.proto. This lets third parties
+// ship plugins (e.g. CloudFormation or Crossplane) as separate binaries, in
+// any language with gRPC support, without forking cldctl.
+//
+// Each operation runs the plugin binary once:
+//
+//  1. cldctl starts the binary with a sandboxed environment containing the
+//     go-plugin handshake variables and only the variables explicitly passed
+//     for the run.
+//  2. The plugin writes go-plugin's handshake line, naming the protocol
+//     version and the address it serves on, to stdout.
+//  3. cldctl calls the RPC for the operation, which streams progress and log
+//     events and ends with the result, then stops the plugin.
+//
+// Plugin authors implement iac.Plugin and call Serve from main().
+package external
+
+import (
+	"context"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/iac/external/pluginpb"
+)
+
+const (
+	// ProtocolVersion is the plugin protocol version spoken by this build.
+	// Bump it whenever the IaCPlugin service changes incompatibly.
+	ProtocolVersion = 2
+
+	// MagicCookieKey is the environment variable used to confirm that a
+	// plugin binary was launched by cldctl rather than run directly.
+	MagicCookieKey = "CLDCTL_PLUGIN_MAGIC_COOKIE"
+
+	// MagicCookieValue is the expected value of MagicCookieKey.
+	MagicCookieValue = "b3c1e4f2-cldctl-iac-plugin"
+
+	// BinaryPrefix is the filename prefix used to discover plugin binaries.
+	// A binary named "cldctl-plugin-cloudformation" registers the
+	// "cloudformation" plugin.
+	BinaryPrefix = "cldctl-plugin-"
+
+	// pluginKey is the name the IaCPlugin service is dispensed under.
+	pluginKey = "iac"
+)
+
+// Handshake is the go-plugin handshake shared by cldctl and its plugins.
+var Handshake = goplugin.HandshakeConfig{
+	ProtocolVersion:  ProtocolVersion,
+	MagicCookieKey:   MagicCookieKey,
+	MagicCookieValue: MagicCookieValue,
+}
+
+// grpcPlugin adapts an iac.Plugin to go-plugin. impl is only set on the
+// plugin side.
+type grpcPlugin struct {
+	goplugin.NetRPCUnsupportedPlugin
+
+	impl iac.Plugin
+}
+
+func (p *grpcPlugin) GRPCServer(broker *goplugin.GRPCBroker, s *grpc.Server) error {
+	pluginpb.RegisterIaCPluginServer(s, &server{impl: p.impl})
+	return nil
+}
+
+func (p *grpcPlugin) GRPCClient(ctx context.Context, broker *goplugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return pluginpb.NewIaCPluginClient(c), nil
+}
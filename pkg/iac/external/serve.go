@@ -0,0 +1,208 @@
+package external
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/iac/external/pluginpb"
+)
+
+// Serve runs impl as an external plugin. It is the entrypoint for plugin
+// binaries and should be called from main():
+//
+//	func main() {
+//	    external.Serve(&MyPlugin{})
+//	}
+//
+// Serve performs the go-plugin handshake, serves the IaCPlugin service over
+// gRPC, and returns once cldctl stops the plugin. A binary run directly,
+// without the magic cookie, prints a message and exits.
+func Serve(impl iac.Plugin) {
+	goplugin.Serve(&goplugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins:         goplugin.PluginSet{pluginKey: &grpcPlugin{impl: impl}},
+		GRPCServer:      goplugin.DefaultGRPCServer,
+	})
+}
+
+// server implements the IaCPlugin service by dispatching to an iac.Plugin.
+type server struct {
+	pluginpb.UnimplementedIaCPluginServer
+
+	impl iac.Plugin
+}
+
+// eventSender sends the events of a single call. gRPC streams are not safe
+// for concurrent sends, and plugins may report progress from goroutines.
+type eventSender struct {
+	mu     sync.Mutex
+	stream grpc.ServerStreamingServer[pluginpb.Event]
+}
+
+func (s *eventSender) send(event *pluginpb.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stream.Send(event)
+}
+
+func (s *eventSender) progress(message string) {
+	_ = s.send(&pluginpb.Event{Event: &pluginpb.Event_Progress{Progress: message}})
+}
+
+// logWriter returns a writer that turns each written line into a log event,
+// so plugins can use RunOptions.Stdout as they would in-process.
+func (s *eventSender) logWriter() io.Writer {
+	return &lineWriter{send: func(line string) {
+		_ = s.send(&pluginpb.Event{Event: &pluginpb.Event_Log{Log: line}})
+	}}
+}
+
+func (s *eventSender) result(result *pluginpb.Result) error {
+	return s.send(&pluginpb.Event{Event: &pluginpb.Event_Result{Result: result}})
+}
+
+// runOptions converts a RunRequest to iac.RunOptions for the call, with
+// state written by the plugin collected in stateOut.
+func (s *eventSender) runOptions(req *pluginpb.RunRequest, stateOut *bytes.Buffer) (iac.RunOptions, error) {
+	inputs, err := fromInputs(req.Inputs)
+	if err != nil {
+		return iac.RunOptions{}, err
+	}
+	opts := iac.RunOptions{
+		ModuleSource: req.ModuleSource,
+		ModulePath:   req.ModulePath,
+		Inputs:       inputs,
+		StateWriter:  stateOut,
+		WorkDir:      req.WorkDir,
+		Environment:  req.Environment,
+		Volumes:      fromVolumes(req.Volumes),
+		Stdout:       s.logWriter(),
+		Stderr:       os.Stderr,
+		OnProgress:   s.progress,
+	}
+	if len(req.State) > 0 {
+		opts.StateReader = bytes.NewReader(req.State)
+	}
+	return opts, nil
+}
+
+func (srv *server) Preview(req *pluginpb.RunRequest, stream pluginpb.IaCPlugin_PreviewServer) error {
+	s := &eventSender{stream: stream}
+	var stateOut bytes.Buffer
+	opts, err := s.runOptions(req, &stateOut)
+	if err != nil {
+		return err
+	}
+	result, err := srv.impl.Preview(stream.Context(), opts)
+	if err != nil {
+		return err
+	}
+	preview, err := toPreview(result)
+	if err != nil {
+		return err
+	}
+	return s.result(&pluginpb.Result{Preview: preview, State: stateOut.Bytes()})
+}
+
+func (srv *server) Apply(req *pluginpb.RunRequest, stream pluginpb.IaCPlugin_ApplyServer) error {
+	s := &eventSender{stream: stream}
+	var stateOut bytes.Buffer
+	opts, err := s.runOptions(req, &stateOut)
+	if err != nil {
+		return err
+	}
+	result, err := srv.impl.Apply(stream.Context(), opts)
+	if err != nil {
+		return err
+	}
+	apply, err := toApply(result)
+	if err != nil {
+		return err
+	}
+	return s.result(&pluginpb.Result{Apply: apply, State: stateOut.Bytes()})
+}
+
+func (srv *server) Destroy(req *pluginpb.RunRequest, stream pluginpb.IaCPlugin_DestroyServer) error {
+	s := &eventSender{stream: stream}
+	var stateOut bytes.Buffer
+	opts, err := s.runOptions(req, &stateOut)
+	if err != nil {
+		return err
+	}
+	if err := srv.impl.Destroy(stream.Context(), opts); err != nil {
+		return err
+	}
+	return s.result(&pluginpb.Result{State: stateOut.Bytes()})
+}
+
+func (srv *server) Refresh(req *pluginpb.RunRequest, stream pluginpb.IaCPlugin_RefreshServer) error {
+	s := &eventSender{stream: stream}
+	var stateOut bytes.Buffer
+	opts, err := s.runOptions(req, &stateOut)
+	if err != nil {
+		return err
+	}
+	result, err := srv.impl.Refresh(stream.Context(), opts)
+	if err != nil {
+		return err
+	}
+	refresh, err := toRefresh(result)
+	if err != nil {
+		return err
+	}
+	return s.result(&pluginpb.Result{Refresh: refresh, State: stateOut.Bytes()})
+}
+
+func (srv *server) Import(req *pluginpb.ImportRequest, stream pluginpb.IaCPlugin_ImportServer) error {
+	s := &eventSender{stream: stream}
+	inputs, err := fromInputs(req.Inputs)
+	if err != nil {
+		return err
+	}
+	result, err := srv.impl.Import(stream.Context(), iac.ImportOptions{
+		ModuleSource: req.ModuleSource,
+		ModulePath:   req.ModulePath,
+		Inputs:       inputs,
+		Mappings:     fromMappings(req.Mappings),
+		WorkDir:      req.WorkDir,
+		Environment:  req.Environment,
+		Stdout:       s.logWriter(),
+		Stderr:       os.Stderr,
+	})
+	if err != nil {
+		return err
+	}
+	imported, err := toImport(result)
+	if err != nil {
+		return err
+	}
+	return s.result(&pluginpb.Result{Import: imported})
+}
+
+type lineWriter struct {
+	mu   sync.Mutex
+	buf  []byte
+	send func(string)
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buf = append(l.buf, p...)
+	for {
+		idx := bytes.IndexByte(l.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		l.send(strings.TrimRight(string(l.buf[:idx]), "\r"))
+		l.buf = l.buf[idx+1:]
+	}
+	return len(p), nil
+}
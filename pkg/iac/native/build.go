@@ -7,10 +7,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/davidthor/cldctl/pkg/logging"
 	"github.com/docker/docker/api/types/build"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/registry"
@@ -68,9 +70,7 @@ type BuildResult struct {
 
 // BuildImage builds a Docker image from a Dockerfile.
 func (d *DockerClient) BuildImage(ctx context.Context, opts BuildOptions) (*BuildResult, error) {
-	if os.Getenv("CLDCTL_DEBUG") != "" {
-		fmt.Fprintf(os.Stderr, "[debug] BuildImage: creating build context for %s\n", opts.Context)
-	}
+	buildLog.Debug("creating build context", "context", opts.Context)
 
 	// Create build context tar
 	contextTar, err := d.createBuildContext(opts.Context, opts.Dockerfile)
@@ -79,9 +79,7 @@ func (d *DockerClient) BuildImage(ctx context.Context, opts BuildOptions) (*Buil
 	}
 	defer contextTar.Close()
 
-	if os.Getenv("CLDCTL_DEBUG") != "" {
-		fmt.Fprintf(os.Stderr, "[debug] BuildImage: build context created, starting Docker build\n")
-	}
+	buildLog.Debug("build context created, starting Docker build", "context", opts.Context)
 
 	// Prepare build options
 	dockerfile := opts.Dockerfile
@@ -226,7 +224,7 @@ func (d *DockerClient) processBuildOutput(reader io.Reader, stdout, stderr io.Wr
 		if strings.HasPrefix(msg.Stream, "Step ") {
 			lastStep = strings.TrimSpace(msg.Stream)
 			// Show step progress in debug mode
-			if stderr != nil && os.Getenv("CLDCTL_DEBUG") != "" {
+			if stderr != nil && logging.Enabled(logging.Build, slog.LevelDebug) {
 				fmt.Fprintf(stderr, "[build] %s", msg.Stream)
 			}
 		}
@@ -2,6 +2,7 @@ package native
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -12,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/davidthor/cldctl/pkg/iac"
 	dockertypes "github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
@@ -19,6 +21,7 @@ import (
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
 )
 
@@ -59,6 +62,8 @@ type ContainerOptions struct {
 	ExtraHosts       []string          // Additional /etc/hosts entries (e.g., "host.docker.internal:host-gateway")
 	ResolveLocalhost bool              // Replace "localhost" in env var values with "host.docker.internal"
 	Wait             bool              // Wait for container to exit before returning (for one-shot tasks)
+	StopTimeout      int               // Seconds to wait after SIGTERM before killing the container (0 uses Docker's default)
+	PostStart        []string          // Command run in the container once it is up; the container is removed if it fails
 	OnProgress       func(string)      // Optional callback for sub-status updates (e.g., "pulling image...", "health check 5/30")
 }
 
@@ -71,9 +76,10 @@ type PortMapping struct {
 
 // VolumeMount defines a volume mount.
 type VolumeMount struct {
-	Name   string
-	Source string
-	Path   string
+	Name     string
+	Source   string
+	Path     string
+	ReadOnly bool
 }
 
 // Healthcheck defines a health check.
@@ -209,7 +215,7 @@ func (d *DockerClient) RunContainer(ctx context.Context, opts ContainerOptions)
 
 	// Only pull if image doesn't exist locally
 	if !imageExists {
-		reportProgress(opts.OnProgress, "pulling image…")
+		reportProgress(opts.OnProgress, iac.PhaseMessage(iac.PhasePulling, "pulling image…"))
 		if err := d.pullImageWithProgress(ctx, opts.Image, opts.OnProgress); err != nil {
 			return "", err
 		}
@@ -249,7 +255,11 @@ func (d *DockerClient) RunContainer(ctx context.Context, opts ContainerOptions)
 		if source == "" {
 			source = vm.Name
 		}
-		binds = append(binds, fmt.Sprintf("%s:%s", source, vm.Path))
+		bind := fmt.Sprintf("%s:%s", source, vm.Path)
+		if vm.ReadOnly {
+			bind += ":ro"
+		}
+		binds = append(binds, bind)
 	}
 
 	// Create container config
@@ -260,6 +270,10 @@ func (d *DockerClient) RunContainer(ctx context.Context, opts ContainerOptions)
 		Entrypoint:   opts.Entrypoint,
 		ExposedPorts: exposedPorts,
 	}
+	if opts.StopTimeout > 0 {
+		stopTimeout := opts.StopTimeout
+		config.StopTimeout = &stopTimeout
+	}
 
 	// For wait-mode containers (tasks), enable attach so we can stream
 	// stdout/stderr lines as progress regardless of the log driver.
@@ -333,7 +347,7 @@ func (d *DockerClient) RunContainer(ctx context.Context, opts ContainerOptions)
 	}
 
 	// Create container
-	reportProgress(opts.OnProgress, "starting container…")
+	reportProgress(opts.OnProgress, iac.PhaseMessage(iac.PhaseProvisioning, "starting container…"))
 	resp, err := d.client.ContainerCreate(ctx, config, hostConfig, networkConfig, nil, opts.Name)
 	if err != nil {
 		return "", fmt.Errorf("failed to create container: %w", err)
@@ -362,7 +376,7 @@ func (d *DockerClient) RunContainer(ctx context.Context, opts ContainerOptions)
 		if attachOk {
 			attachResp.Close()
 		}
-		_ = d.client.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+		d.removeFailedContainer(ctx, resp.ID)
 		return "", fmt.Errorf("failed to start container: %w", err)
 	}
 
@@ -374,11 +388,21 @@ func (d *DockerClient) RunContainer(ctx context.Context, opts ContainerOptions)
 			if attachOk {
 				attachResp.Close()
 			}
-			_ = d.client.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+			d.removeFailedContainer(ctx, resp.ID)
 			return "", fmt.Errorf("container failed health check: %w", err)
 		}
 	}
 
+	// Run the post-start hook once the container is up (and healthy, when a
+	// healthcheck is configured). A failing hook fails the container.
+	if len(opts.PostStart) > 0 && !opts.Wait {
+		reportProgress(opts.OnProgress, "running post-start hook…")
+		if _, err := d.ExecInContainer(ctx, resp.ID, opts.PostStart, nil); err != nil {
+			d.removeFailedContainer(ctx, resp.ID)
+			return "", fmt.Errorf("post-start hook failed: %w", err)
+		}
+	}
+
 	// If Wait is true, block until the container exits (for one-shot tasks like migrations)
 	if opts.Wait {
 		statusCh, errCh := d.client.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
@@ -433,7 +457,7 @@ func (d *DockerClient) waitForHealthy(ctx context.Context, containerID string, h
 		}
 	}
 
-	reportProgress(onProgress, fmt.Sprintf("health check 0/%d", retries))
+	reportProgress(onProgress, iac.PhaseMessage(iac.PhaseWaitingHealthy, fmt.Sprintf("health check 0/%d", retries)))
 
 	select {
 	case <-time.After(startDelay):
@@ -514,6 +538,15 @@ func (d *DockerClient) waitForHealthy(ctx context.Context, containerID string, h
 	return fmt.Errorf("%s", msg)
 }
 
+// removeFailedContainer removes a container that was created but never came
+// up. It uses a cleanup context so the container is still removed when ctx
+// was cancelled while waiting for it.
+func (d *DockerClient) removeFailedContainer(ctx context.Context, containerID string) {
+	cleanupCtx, cancel := iac.CleanupContext(ctx)
+	defer cancel()
+	_ = d.client.ContainerRemove(cleanupCtx, containerID, container.RemoveOptions{Force: true})
+}
+
 // tailContainerLogs fetches the last N lines of a container's stdout/stderr.
 // It returns an empty string (and no error) if logs cannot be retrieved.
 func (d *DockerClient) tailContainerLogs(ctx context.Context, containerID string, lines string) string {
@@ -633,6 +666,19 @@ func (d *DockerClient) IsContainerRunning(ctx context.Context, containerID strin
 	return info.State.Running, nil
 }
 
+// ContainerStatus reports whether a container exists and, if it does,
+// whether it's running.
+func (d *DockerClient) ContainerStatus(ctx context.Context, containerID string) (exists, running bool, err error) {
+	info, err := d.client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		if strings.Contains(err.Error(), "No such container") {
+			return false, false, nil
+		}
+		return false, false, err
+	}
+	return true, info.State.Running, nil
+}
+
 // GetContainerByName finds a container by name and returns its ID.
 // Returns empty string if not found.
 func (d *DockerClient) GetContainerByName(ctx context.Context, name string) (string, error) {
@@ -702,6 +748,25 @@ func (d *DockerClient) ContainerMatchesConfig(ctx context.Context, containerID s
 		}
 	}
 
+	// Check host path mounts, such as generated files, whose paths change
+	// with their content
+	binds := make(map[string]bool, len(info.HostConfig.Binds))
+	for _, b := range info.HostConfig.Binds {
+		binds[b] = true
+	}
+	for _, vm := range opts.Volumes {
+		if vm.Source == "" {
+			continue
+		}
+		bind := fmt.Sprintf("%s:%s", vm.Source, vm.Path)
+		if vm.ReadOnly {
+			bind += ":ro"
+		}
+		if !binds[bind] {
+			return false
+		}
+	}
+
 	// Note: We don't check ports here because dynamically-assigned host ports would always differ.
 	// The image and env check is usually sufficient for local development.
 
@@ -716,6 +781,70 @@ func (d *DockerClient) RemoveContainer(ctx context.Context, containerID string)
 	})
 }
 
+// StopContainer stops a container without removing it.
+func (d *DockerClient) StopContainer(ctx context.Context, containerID string) error {
+	return d.client.ContainerStop(ctx, containerID, container.StopOptions{})
+}
+
+// StopContainerWithTimeout sends a container SIGTERM and kills it if it
+// hasn't exited after the timeout.
+func (d *DockerClient) StopContainerWithTimeout(ctx context.Context, containerID string, timeout time.Duration) error {
+	seconds := int(timeout.Round(time.Second) / time.Second)
+	return d.client.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &seconds})
+}
+
+// StartContainer starts a stopped container.
+func (d *DockerClient) StartContainer(ctx context.Context, containerID string) error {
+	return d.client.ContainerStart(ctx, containerID, container.StartOptions{})
+}
+
+// RenameContainer renames a container.
+func (d *DockerClient) RenameContainer(ctx context.Context, containerID, newName string) error {
+	return d.client.ContainerRename(ctx, containerID, newName)
+}
+
+// ExecInContainer runs a command inside a running container, optionally
+// feeding it stdin, and returns its stdout. A non-zero exit code is an error
+// that includes the command's stderr.
+func (d *DockerClient) ExecInContainer(ctx context.Context, containerID string, command []string, stdin []byte) ([]byte, error) {
+	created, err := d.client.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd:          command,
+		AttachStdin:  stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	attach, err := d.client.ContainerExecAttach(ctx, created.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach to exec: %w", err)
+	}
+	defer attach.Close()
+
+	if stdin != nil {
+		go func() {
+			_, _ = attach.Conn.Write(stdin)
+			_ = attach.CloseWrite()
+		}()
+	}
+
+	var stdout, stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, attach.Reader); err != nil {
+		return nil, fmt.Errorf("failed to read exec output: %w", err)
+	}
+
+	inspect, err := d.client.ContainerExecInspect(ctx, created.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect exec: %w", err)
+	}
+	if inspect.ExitCode != 0 {
+		return stdout.Bytes(), fmt.Errorf("%s exited with code %d: %s", command[0], inspect.ExitCode, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
 // CreateNetwork creates a Docker network.
 func (d *DockerClient) CreateNetwork(ctx context.Context, name string) (string, error) {
 	// Check if network already exists
@@ -838,7 +967,8 @@ type RunOneShotOptions struct {
 	Environment      map[string]string
 	Network          string
 	WorkDir          string
-	ResolveLocalhost bool // Replace "localhost" in env var values with "host.docker.internal"
+	Volumes          []VolumeMount // Named volumes or host paths to mount
+	ResolveLocalhost bool          // Replace "localhost" in env var values with "host.docker.internal"
 }
 
 // RunOneShot runs a command in a temporary Docker container and returns the output.
@@ -876,6 +1006,13 @@ func (d *DockerClient) RunOneShot(ctx context.Context, opts RunOneShotOptions) (
 
 	// Create host config
 	hostConfig := &container.HostConfig{}
+	for _, vm := range opts.Volumes {
+		source := vm.Source
+		if source == "" {
+			source = vm.Name
+		}
+		hostConfig.Binds = append(hostConfig.Binds, fmt.Sprintf("%s:%s", source, vm.Path))
+	}
 	if opts.ResolveLocalhost {
 		hostConfig.ExtraHosts = []string{"host.docker.internal:host-gateway"}
 	}
@@ -0,0 +1,119 @@
+package native
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+)
+
+// fileMountsDir is where the files mounted into containers are written, in
+// a directory per container.
+var fileMountsDir = "~/.cldctl/files"
+
+// defaultFileMode is the mode of a mounted file that doesn't set one.
+const defaultFileMode os.FileMode = 0644
+
+// FileMount is an entry of the "files" property of a docker:container
+// resource: a file cldctl writes on the host and bind-mounts read-only into
+// the container. It takes a deployment's files input as-is:
+//
+//	files:
+//	  - mount_path: /etc/nginx/conf.d/default.conf
+//	    content: "server { listen 80; }"
+//	    mode: "0644"   # octal, default 0644
+type FileMount struct {
+	MountPath string
+	Content   string
+	Mode      os.FileMode
+}
+
+func getFileMounts(props map[string]interface{}, key string) []FileMount {
+	arr, ok := props[key].([]interface{})
+	if !ok {
+		return nil
+	}
+	var result []FileMount
+	for _, item := range arr {
+		m, ok := item.(map[string]interface{})
+		if !ok || getString(m, "mount_path") == "" {
+			continue
+		}
+		f := FileMount{
+			MountPath: getString(m, "mount_path"),
+			Content:   getString(m, "content"),
+			Mode:      defaultFileMode,
+		}
+		if mode, err := strconv.ParseUint(getString(m, "mode"), 8, 32); err == nil {
+			f.Mode = os.FileMode(mode)
+		}
+		result = append(result, f)
+	}
+	return result
+}
+
+// hostPath returns where the file is written for a container. The path
+// includes a hash of the file, so a change to it changes the container's
+// bind mounts and the container is replaced.
+func (f FileMount) hostPath(dir string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%o\x00%s", f.MountPath, f.Mode, f.Content)))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])[:12], path.Base(f.MountPath))
+}
+
+// fileMountDir returns the directory holding a container's files.
+func fileMountDir(containerName string) string {
+	return filepath.Join(expandHome(fileMountsDir), containerName)
+}
+
+// writeFileMounts writes a container's files to the host, removes the ones
+// it no longer mounts, and returns the bind mounts for them.
+func writeFileMounts(containerName string, files []FileMount) ([]VolumeMount, error) {
+	dir := fileMountDir(containerName)
+	if len(files) == 0 {
+		_ = os.RemoveAll(dir)
+		return nil, nil
+	}
+
+	keep := make(map[string]bool, len(files))
+	mounts := make([]VolumeMount, 0, len(files))
+	for _, f := range files {
+		hostPath := f.hostPath(dir)
+		// A file that exists already has this content, and may be mounted
+		// into the running container
+		if _, err := os.Stat(hostPath); os.IsNotExist(err) {
+			if err := writeFileMount(hostPath, f); err != nil {
+				return nil, err
+			}
+		}
+		keep[filepath.Base(filepath.Dir(hostPath))] = true
+		mounts = append(mounts, VolumeMount{Source: hostPath, Path: f.MountPath, ReadOnly: true})
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if !keep[entry.Name()] {
+			_ = os.RemoveAll(filepath.Join(dir, entry.Name()))
+		}
+	}
+	return mounts, nil
+}
+
+func writeFileMount(hostPath string, f FileMount) error {
+	if err := os.MkdirAll(filepath.Dir(hostPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", f.MountPath, err)
+	}
+	if err := os.WriteFile(hostPath, []byte(f.Content), f.Mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", f.MountPath, err)
+	}
+	// WriteFile applies the umask to the mode
+	if err := os.Chmod(hostPath, f.Mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", f.MountPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package native
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetFileMounts(t *testing.T) {
+	assert.Nil(t, getFileMounts(map[string]interface{}{}, "files"))
+
+	files := getFileMounts(map[string]interface{}{
+		"files": []interface{}{
+			map[string]interface{}{"mount_path": "/etc/app/config.json", "content": "{}"},
+			map[string]interface{}{"mount_path": "/etc/app/secret", "content": "s3cr3t", "mode": "0600"},
+			map[string]interface{}{"content": "no mount path"},
+		},
+	}, "files")
+	require.Len(t, files, 2)
+	assert.Equal(t, FileMount{MountPath: "/etc/app/config.json", Content: "{}", Mode: 0644}, files[0])
+	assert.Equal(t, os.FileMode(0600), files[1].Mode)
+}
+
+func TestWriteFileMounts(t *testing.T) {
+	fileMountsDir = t.TempDir()
+	defer func() { fileMountsDir = "~/.cldctl/files" }()
+
+	files := []FileMount{
+		{MountPath: "/etc/app/config.json", Content: `{"debug": true}`, Mode: 0644},
+		{MountPath: "/etc/app/secret", Content: "s3cr3t", Mode: 0600},
+	}
+	mounts, err := writeFileMounts("dev-app-api", files)
+	require.NoError(t, err)
+	require.Len(t, mounts, 2)
+
+	assert.Equal(t, "/etc/app/config.json", mounts[0].Path)
+	assert.True(t, mounts[0].ReadOnly)
+	assert.Equal(t, "config.json", filepath.Base(mounts[0].Source))
+	data, err := os.ReadFile(mounts[0].Source)
+	require.NoError(t, err)
+	assert.Equal(t, `{"debug": true}`, string(data))
+
+	info, err := os.Stat(mounts[1].Source)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Changing a file moves it, so the container's mounts change, and the
+	// old version is removed
+	files[0].Content = `{"debug": false}`
+	updated, err := writeFileMounts("dev-app-api", files)
+	require.NoError(t, err)
+	assert.NotEqual(t, mounts[0].Source, updated[0].Source)
+	assert.Equal(t, mounts[1].Source, updated[1].Source)
+	assert.NoFileExists(t, mounts[0].Source)
+
+	// A container without files has nothing left behind
+	_, err = writeFileMounts("dev-app-api", nil)
+	require.NoError(t, err)
+	assert.NoDirExists(t, fileMountDir("dev-app-api"))
+}
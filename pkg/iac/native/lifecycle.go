@@ -0,0 +1,89 @@
+package native
+
+import (
+	"context"
+	"time"
+)
+
+// defaultGracePeriod is how long a container is given to exit after SIGTERM
+// when its lifecycle doesn't set a grace period. It matches Docker's default.
+const defaultGracePeriod = 10 * time.Second
+
+// LifecycleConfig is the "lifecycle" property of a docker:container resource.
+// It takes a deployment's lifecycle input as-is:
+//
+//	lifecycle:
+//	  post_start: ["/app/warm-cache"]       # run once the container has started
+//	  pre_stop: ["nginx", "-s", "quit"]     # run before the container is sent SIGTERM
+//	  termination_grace_period_seconds: 30  # time between SIGTERM and kill
+type LifecycleConfig struct {
+	PostStart   []string
+	PreStop     []string
+	GracePeriod time.Duration
+}
+
+func getLifecycleConfig(props map[string]interface{}, key string) *LifecycleConfig {
+	raw, ok := props[key].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	cfg := &LifecycleConfig{
+		PostStart:   getStringSlice(raw, "post_start"),
+		PreStop:     getStringSlice(raw, "pre_stop"),
+		GracePeriod: time.Duration(toInt(raw["termination_grace_period_seconds"])) * time.Second,
+	}
+	if cfg.GracePeriod <= 0 {
+		cfg.GracePeriod = defaultGracePeriod
+	}
+	return cfg
+}
+
+// applyTo sets the container options that carry out the lifecycle once the
+// container is running: the post-start hook, and the stop timeout Docker
+// uses whenever the container is stopped.
+func (l *LifecycleConfig) applyTo(opts *ContainerOptions) {
+	if l == nil {
+		return
+	}
+	opts.PostStart = l.PostStart
+	opts.StopTimeout = int(l.GracePeriod / time.Second)
+}
+
+// gracePeriod returns how long the container is given to shut down.
+func (l *LifecycleConfig) gracePeriod() time.Duration {
+	if l == nil {
+		return defaultGracePeriod
+	}
+	return l.GracePeriod
+}
+
+// stopContainer shuts a container down gracefully and removes it. The
+// pre-stop hook runs first, then the container is sent SIGTERM and killed if
+// it hasn't exited by the end of the grace period. Like in Kubernetes, the
+// pre-stop hook counts against the grace period.
+func (p *Plugin) stopContainer(ctx context.Context, containerID string, lifecycle *LifecycleConfig) error {
+	deadline := time.Now().Add(lifecycle.gracePeriod())
+	p.runPreStop(ctx, containerID, lifecycle)
+
+	// Give the process at least a second to handle SIGTERM, even when the
+	// pre-stop hook used up the grace period.
+	remaining := time.Until(deadline)
+	if remaining < time.Second {
+		remaining = time.Second
+	}
+	// Stopping a container that has already exited is a no-op, and a
+	// container that can't be stopped is still force-removed below.
+	_ = p.docker.StopContainerWithTimeout(ctx, containerID, remaining)
+	return p.docker.RemoveContainer(ctx, containerID)
+}
+
+// runPreStop runs the container's pre-stop hook, if it has one, for up to the
+// grace period. A failing hook doesn't keep the container from stopping.
+func (p *Plugin) runPreStop(ctx context.Context, containerID string, lifecycle *LifecycleConfig) {
+	if lifecycle == nil || len(lifecycle.PreStop) == 0 {
+		return
+	}
+	hookCtx, cancel := context.WithTimeout(ctx, lifecycle.GracePeriod)
+	defer cancel()
+	_, _ = p.docker.ExecInContainer(hookCtx, containerID, lifecycle.PreStop, nil)
+}
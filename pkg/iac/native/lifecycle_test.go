@@ -0,0 +1,35 @@
+package native
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetLifecycleConfig(t *testing.T) {
+	assert.Nil(t, getLifecycleConfig(map[string]interface{}{}, "lifecycle"))
+	assert.Equal(t, defaultGracePeriod, getLifecycleConfig(nil, "lifecycle").gracePeriod())
+
+	cfg := getLifecycleConfig(map[string]interface{}{
+		"lifecycle": map[string]interface{}{
+			// JSON round-tripped state
+			"post_start":                       []interface{}{"/app/warm-cache"},
+			"pre_stop":                         []interface{}{"nginx", "-s", "quit"},
+			"termination_grace_period_seconds": float64(30),
+		},
+	}, "lifecycle")
+	require.NotNil(t, cfg)
+	assert.Equal(t, []string{"/app/warm-cache"}, cfg.PostStart)
+	assert.Equal(t, []string{"nginx", "-s", "quit"}, cfg.PreStop)
+	assert.Equal(t, 30*time.Second, cfg.GracePeriod)
+
+	opts := ContainerOptions{}
+	cfg.applyTo(&opts)
+	assert.Equal(t, 30, opts.StopTimeout)
+	assert.Equal(t, []string{"/app/warm-cache"}, opts.PostStart)
+
+	defaults := getLifecycleConfig(map[string]interface{}{"lifecycle": map[string]interface{}{}}, "lifecycle")
+	assert.Equal(t, defaultGracePeriod, defaults.GracePeriod)
+}
@@ -7,14 +7,19 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/logging"
 )
 
+// buildLog is the logger for image builds.
+var buildLog = logging.Logger(logging.Build)
+
 func init() {
 	iac.Register("native", func() (iac.Plugin, error) {
 		return NewPlugin()
@@ -148,16 +153,14 @@ func (p *Plugin) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResu
 
 		// Check for context cancellation before each resource
 		if ctx.Err() != nil {
-			p.rollback(ctx, state)
-			return nil, ctx.Err()
+			return nil, p.failApply(ctx, state, ctx.Err())
 		}
 
 		// Evaluate when condition — skip resource if condition is false
 		if resource.When != "" {
 			condResult, err := evaluateExpression(resource.When, evalCtx)
 			if err != nil {
-				p.rollback(ctx, state)
-				return nil, fmt.Errorf("failed to evaluate when condition for resource %s: %w", name, err)
+				return nil, p.failApply(ctx, state, fmt.Errorf("failed to evaluate when condition for resource %s: %w", name, err))
 			}
 
 			if !isTruthy(condResult) {
@@ -168,8 +171,7 @@ func (p *Plugin) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResu
 		resourceState, err := p.applyResource(ctx, name, resource, evalCtx, existingState, opts.Stdout, opts.Stderr, opts.OnProgress)
 		if err != nil {
 			// Rollback on failure
-			p.rollback(ctx, state)
-			return nil, fmt.Errorf("failed to apply resource %s: %w", name, err)
+			return nil, p.failApply(ctx, state, fmt.Errorf("failed to apply resource %s: %w", name, err))
 		}
 		state.Resources[name] = resourceState
 		evalCtx.Resources = state.Resources
@@ -180,7 +182,7 @@ func (p *Plugin) Apply(ctx context.Context, opts iac.RunOptions) (*iac.ApplyResu
 	for name, outputDef := range module.Outputs {
 		value, err := evaluateExpression(outputDef.Value, evalCtx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to evaluate output %s: %w", name, err)
+			return nil, p.failApply(ctx, state, fmt.Errorf("failed to evaluate output %s: %w", name, err))
 		}
 		state.Outputs[name] = value
 		outputs[name] = iac.OutputValue{
@@ -232,9 +234,116 @@ func (p *Plugin) Preview(ctx context.Context, opts iac.RunOptions) (*iac.Preview
 	}, nil
 }
 
+// RefreshesState reports that Refresh checks the Docker resources recorded
+// in a module's state.
+func (p *Plugin) RefreshesState() bool {
+	return true
+}
+
+// Refresh checks that the Docker containers, networks, and volumes recorded
+// in the state still exist, and that containers are still running. Other
+// resources are trusted as recorded: processes only live as long as the
+// cldctl process that started them, and builds, keys, and one-off commands
+// leave nothing running to check.
 func (p *Plugin) Refresh(ctx context.Context, opts iac.RunOptions) (*iac.RefreshResult, error) {
-	// Native plugin trusts stored state, no refresh needed
-	return &iac.RefreshResult{}, nil
+	if opts.StateReader == nil {
+		return &iac.RefreshResult{}, nil
+	}
+	state, err := p.loadState(opts.StateReader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	names := make([]string, 0, len(state.Resources))
+	for name := range state.Resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var drifts []iac.ResourceDrift
+	for _, name := range names {
+		rs := state.Resources[name]
+		diffs, err := p.refreshResource(ctx, rs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to refresh resource %s: %w", name, err)
+		}
+		if len(diffs) > 0 {
+			drifts = append(drifts, iac.ResourceDrift{ResourceID: name, ResourceType: rs.Type, Diffs: diffs})
+		}
+	}
+
+	sensitive := make(map[string]bool)
+	if module, err := LoadModule(state.ModulePath); err == nil {
+		for name, def := range module.Outputs {
+			sensitive[name] = def.Sensitive
+		}
+	}
+	outputs := make(map[string]iac.OutputValue, len(state.Outputs))
+	for name, value := range state.Outputs {
+		outputs[name] = iac.OutputValue{Value: value, Sensitive: sensitive[name]}
+	}
+
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize state: %w", err)
+	}
+	return &iac.RefreshResult{
+		State:   stateBytes,
+		Drifts:  drifts,
+		Outputs: outputs,
+	}, nil
+}
+
+// refreshProcess reports a process that isn't running, or that's waiting to
+// be restarted after crashing, as drift. Processes started by an earlier run
+// of cldctl are checked by their PID.
+func (p *Plugin) refreshProcess(name string, rs *ResourceState) []iac.PropertyDiff {
+	if status, ok := p.process.Status(name); ok {
+		if status.State == ProcessRunning {
+			return nil
+		}
+		return []iac.PropertyDiff{{Path: "status", OldValue: ProcessRunning, NewValue: status.State}}
+	}
+
+	pidFile, _ := rs.Outputs["pid_file"].(string)
+	if pid := recordedPID(pidFile, toInt(rs.Outputs["pid"])); pid > 0 && groupAlive(pid) {
+		return nil
+	}
+	return []iac.PropertyDiff{{Path: "running", OldValue: true, NewValue: false}}
+}
+
+// refreshResource returns how a resource differs from its recorded state.
+func (p *Plugin) refreshResource(ctx context.Context, rs *ResourceState) ([]iac.PropertyDiff, error) {
+	id, ok := rs.ID.(string)
+	if !ok || id == "" {
+		return nil, nil
+	}
+
+	var exists bool
+	var err error
+	switch rs.Type {
+	case "docker:container":
+		var running bool
+		exists, running, err = p.docker.ContainerStatus(ctx, id)
+		if err == nil && exists && !running {
+			return []iac.PropertyDiff{{Path: "running", OldValue: true, NewValue: false}}, nil
+		}
+	case "docker:network":
+		exists, err = p.docker.NetworkExists(ctx, id)
+	case "docker:volume":
+		exists, err = p.docker.VolumeExists(ctx, id)
+	case "process":
+		return p.refreshProcess(id, rs), nil
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return []iac.PropertyDiff{{Path: iac.DriftPathExists, OldValue: true, NewValue: false}}, nil
+	}
+	return nil, nil
 }
 
 func (p *Plugin) Import(ctx context.Context, opts iac.ImportOptions) (*iac.ImportResult, error) {
@@ -432,11 +541,17 @@ func (p *Plugin) applyResource(ctx context.Context, name string, resource Resour
 	switch resource.Type {
 	case "docker:container":
 		rs, err = p.applyDockerContainer(ctx, name, props, existing, onProgress)
+		if err == nil {
+			err = p.containerReady(ctx, rs, props, onProgress)
+		}
 	case "docker:network":
 		rs, err = p.applyDockerNetwork(ctx, name, props, existing)
 	case "docker:volume":
 		rs, err = p.applyDockerVolume(ctx, name, props, existing)
 	case "docker:build":
+		if onProgress != nil {
+			onProgress(iac.PhaseMessage(iac.PhaseBuilding, "building image…"))
+		}
 		rs, err = p.applyDockerBuild(ctx, name, props, existing, stdout, stderr)
 	case "process":
 		rs, err = p.applyProcess(ctx, name, props, existing, stdout, stderr)
@@ -481,7 +596,18 @@ func (p *Plugin) destroyResource(ctx context.Context, name string, rs *ResourceS
 	switch rs.Type {
 	case "docker:container":
 		if id, ok := rs.ID.(string); ok {
-			return p.docker.RemoveContainer(ctx, id)
+			if err := p.stopContainer(ctx, id, getLifecycleConfig(rs.Properties, "lifecycle")); err != nil {
+				return err
+			}
+		}
+		if name := getString(rs.Properties, "name"); name != "" {
+			_ = os.RemoveAll(fileMountDir(name))
+		}
+		// Volumes created by a postgres major upgrade belong to the container.
+		if managed, _ := rs.Outputs["managed_volume"].(bool); managed {
+			if vol, ok := rs.Outputs["data_volume"].(string); ok && vol != "" {
+				return p.docker.RemoveVolume(ctx, vol)
+			}
 		}
 	case "docker:network":
 		if id, ok := rs.ID.(string); ok {
@@ -499,7 +625,12 @@ func (p *Plugin) destroyResource(ctx context.Context, name string, rs *ResourceS
 		return nil
 	case "process":
 		if processName, ok := rs.ID.(string); ok {
-			return p.process.StopProcess(processName, 10*time.Second)
+			if p.process.Manages(processName) {
+				return p.process.StopProcess(processName, 10*time.Second)
+			}
+			// Started by an earlier run of cldctl
+			pidFile, _ := rs.Outputs["pid_file"].(string)
+			return stopProcessByPID(pidFile, toInt(rs.Outputs["pid"]), 10*time.Second)
 		}
 	case "exec":
 		return nil // One-time execution, nothing to destroy (unless destroy cmd handled above)
@@ -525,10 +656,42 @@ func (p *Plugin) executeDestroyCommand(ctx context.Context, cmd *ResolvedDestroy
 	return err
 }
 
-func (p *Plugin) rollback(ctx context.Context, state *State) {
+// failApply rolls back the resources created so far by Apply and returns
+// err, wrapped in an iac.PartialApplyError if some could not be removed.
+func (p *Plugin) failApply(ctx context.Context, state *State, err error) error {
+	leftover := p.rollback(ctx, state)
+	if leftover == nil {
+		return err
+	}
+	data, marshalErr := json.Marshal(leftover)
+	if marshalErr != nil {
+		return err
+	}
+	return &iac.PartialApplyError{Err: err, State: data}
+}
+
+// rollback destroys the resources in state. It runs on a cleanup context so
+// that an apply interrupted by cancellation still cleans up after itself.
+// Resources that could not be destroyed are returned, or nil if none remain.
+func (p *Plugin) rollback(ctx context.Context, state *State) *State {
+	cleanupCtx, cancel := iac.CleanupContext(ctx)
+	defer cancel()
+
+	var leftover *State
 	for name, rs := range state.Resources {
-		_ = p.destroyResource(ctx, name, rs)
+		if err := p.destroyResource(cleanupCtx, name, rs); err != nil {
+			if leftover == nil {
+				leftover = &State{
+					ModulePath: state.ModulePath,
+					Inputs:     state.Inputs,
+					Resources:  make(map[string]*ResourceState),
+					Outputs:    make(map[string]interface{}),
+				}
+			}
+			leftover.Resources[name] = rs
+		}
 	}
+	return leftover
 }
 
 func (p *Plugin) applyDockerContainer(ctx context.Context, name string, props map[string]interface{}, existing *State, onProgress func(string)) (*ResourceState, error) {
@@ -555,25 +718,66 @@ func (p *Plugin) applyDockerContainer(ctx context.Context, name string, props ma
 		OnProgress:       onProgress,
 	}
 
-	// Check if container already exists and is running (from state)
+	var prior *ResourceState
 	if existing != nil {
-		if rs, ok := existing.Resources[name]; ok {
-			if containerID, ok := rs.ID.(string); ok {
-				running, err := p.docker.IsContainerRunning(ctx, containerID)
-				if err == nil && running {
-					// Check if container config matches what we want
-					if p.docker.ContainerMatchesConfig(ctx, containerID, opts) {
-						// Register port mappings from existing container for resolve_to_localhost
-						if ports, ok := rs.Outputs["ports"].([]interface{}); ok {
-							p.registerContainerPorts(containerName, ports)
-						}
-						// Container still running with same config, reuse it
-						return rs, nil
+		prior = existing.Resources[name]
+	}
+
+	// Data-preserving upgrades: keep generated credentials stable and mount
+	// the volume that currently holds the data.
+	upgrade := getUpgradeConfig(props, "upgrade")
+	rollout := getRolloutConfig(props, "rollout")
+	lifecycle := getLifecycleConfig(props, "lifecycle")
+	lifecycle.applyTo(&opts)
+	fileMounts, err := writeFileMounts(containerName, getFileMounts(props, "files"))
+	if err != nil {
+		return nil, err
+	}
+	opts.Volumes = append(opts.Volumes, fileMounts...)
+	applyPreservedEnv(&opts, prior, upgrade)
+	p.resolveDataVolume(ctx, &opts, prior, upgrade)
+
+	// Check if container already exists and is running (from state)
+	if prior != nil {
+		rs := prior
+		// The running container is shut down with the lifecycle it was
+		// started with
+		previous := getLifecycleConfig(rs.Properties, "lifecycle")
+		if containerID, ok := rs.ID.(string); ok {
+			running, err := p.docker.IsContainerRunning(ctx, containerID)
+			if err == nil && running {
+				// Check if container config matches what we want
+				if p.docker.ContainerMatchesConfig(ctx, containerID, opts) {
+					// Register port mappings from existing container for resolve_to_localhost
+					if ports, ok := rs.Outputs["ports"].([]interface{}); ok {
+						p.registerContainerPorts(containerName, ports)
 					}
+					// Container still running with same config, reuse it
+					return rs, nil
+				}
+
+				// Config changed on a running container with a managed
+				// upgrade strategy: switch over without losing data.
+				if upgrade.managed() {
+					newID, extra, err := p.upgradeContainer(ctx, containerID, rs, opts, upgrade)
+					if err != nil {
+						return nil, err
+					}
+					return p.containerState(ctx, newID, props, opts, extra)
+				}
+
+				// Config changed with a rolling rollout: keep the old
+				// container serving until the new one is ready.
+				if rollout.rolling() {
+					newID, err := p.rollingReplace(ctx, containerID, opts, rollout, previous)
+					if err != nil {
+						return nil, err
+					}
+					return p.containerState(ctx, newID, props, opts, nil)
 				}
-				// Container stopped, missing, or config changed - remove it
-				_ = p.docker.RemoveContainer(ctx, containerID)
 			}
+			// Container stopped, missing, or config changed - remove it
+			_ = p.stopContainer(ctx, containerID, previous)
 		}
 	}
 
@@ -600,7 +804,7 @@ func (p *Plugin) applyDockerContainer(ctx context.Context, name string, props ma
 				}
 			}
 			// Config changed or container not running, remove it
-			_ = p.docker.RemoveContainer(ctx, existingID)
+			_ = p.stopContainer(ctx, existingID, lifecycle)
 		}
 	}
 
@@ -610,7 +814,18 @@ func (p *Plugin) applyDockerContainer(ctx context.Context, name string, props ma
 		return nil, err
 	}
 
-	// Get container info
+	var extra map[string]interface{}
+	if upgrade.managed() {
+		if idx := dataMountIndex(opts.Volumes, upgrade); idx >= 0 {
+			extra = map[string]interface{}{"data_volume": opts.Volumes[idx].Name}
+		}
+	}
+	return p.containerState(ctx, containerID, props, opts, extra)
+}
+
+// containerState inspects a freshly started container and builds its
+// resource state. Extra outputs (e.g. upgrade bookkeeping) are merged in.
+func (p *Plugin) containerState(ctx context.Context, containerID string, props map[string]interface{}, opts ContainerOptions, extra map[string]interface{}) (*ResourceState, error) {
 	info, err := p.docker.InspectContainer(ctx, containerID)
 	if err != nil {
 		_ = p.docker.RemoveContainer(ctx, containerID)
@@ -618,18 +833,23 @@ func (p *Plugin) applyDockerContainer(ctx context.Context, name string, props ma
 	}
 
 	portsArray := buildPortsArray(opts.Ports, info.Ports)
-	p.registerContainerPorts(containerName, portsArray)
+	p.registerContainerPorts(opts.Name, portsArray)
+
+	outputs := map[string]interface{}{
+		"container_id": containerID,
+		"ports":        portsArray,
+		"environment":  opts.Environment, // Include environment for dependent resources
+		"name":         opts.Name,
+	}
+	for k, v := range extra {
+		outputs[k] = v
+	}
 
 	return &ResourceState{
 		Type:       "docker:container",
 		ID:         containerID,
 		Properties: props,
-		Outputs: map[string]interface{}{
-			"container_id": containerID,
-			"ports":        portsArray,
-			"environment":  opts.Environment, // Include environment for dependent resources
-			"name":         containerName,
-		},
+		Outputs:    outputs,
 	}, nil
 }
 
@@ -782,10 +1002,13 @@ func (p *Plugin) applyDockerBuild(ctx context.Context, name string, props map[st
 	opts.Stdout = stdout
 	opts.Stderr = stderr
 
-	// Stream build output in debug mode
-	if os.Getenv("CLDCTL_DEBUG") != "" {
-		fmt.Fprintf(os.Stderr, "[debug] Building image: context=%s, dockerfile=%s, tags=%v\n", buildContext, dockerfile, tags)
-		opts.Stderr = os.Stderr
+	// Stream build output to the logs in debug mode
+	buildLog.Debug("building image", "context", buildContext, "dockerfile", dockerfile, "tags", tags)
+	if w := logging.Writer(logging.Build); w != nil {
+		if stderr != nil {
+			w = io.MultiWriter(stderr, w)
+		}
+		opts.Stderr = w
 	}
 
 	// Add timeout for builds (10 minutes default, configurable via env)
@@ -804,15 +1027,10 @@ func (p *Plugin) applyDockerBuild(ctx context.Context, name string, props map[st
 		if buildCtx.Err() == context.DeadlineExceeded {
 			return nil, fmt.Errorf("build timed out after %v", buildTimeout)
 		}
-		if os.Getenv("CLDCTL_DEBUG") != "" {
-			fmt.Fprintf(os.Stderr, "[debug] Build failed: %v\n", err)
-		}
+		buildLog.Debug("build failed", "context", buildContext, "error", err)
 		return nil, err
 	}
-
-	if os.Getenv("CLDCTL_DEBUG") != "" {
-		fmt.Fprintf(os.Stderr, "[debug] Build succeeded: imageID=%s\n", buildResult.ImageID)
-	}
+	buildLog.Debug("build succeeded", "context", buildContext, "image_id", buildResult.ImageID)
 
 	// Determine the primary tag for output
 	primaryTag := ""
@@ -879,9 +1097,17 @@ func (p *Plugin) applyProcess(ctx context.Context, name string, props map[string
 	processName := getString(props, "name")
 	if existing != nil {
 		if rs, ok := existing.Resources[name]; ok {
-			if pName, ok := rs.ID.(string); ok && p.process.IsProcessRunning(pName) {
-				// Process still running, reuse it
-				return rs, nil
+			if pName, ok := rs.ID.(string); ok {
+				if p.process.IsProcessRunning(pName) {
+					// Process still running, reuse it
+					return rs, nil
+				}
+				// A process left running by an earlier run of cldctl would
+				// hold on to its ports, so it's replaced
+				if !p.process.Manages(pName) {
+					pidFile, _ := rs.Outputs["pid_file"].(string)
+					_ = stopProcessByPID(pidFile, toInt(rs.Outputs["pid"]), 10*time.Second)
+				}
 			}
 		}
 	}
@@ -920,6 +1146,8 @@ func (p *Plugin) applyProcess(ctx context.Context, name string, props map[string
 		Command:     getStringSlice(props, "command"),
 		Environment: env,
 		Readiness:   readiness,
+		Restart:     getRestartPolicy(props, "restart"),
+		Logs:        getLogOptions(props, "logs"),
 		Stdout:      stdout,
 		Stderr:      stderr,
 	}
@@ -929,14 +1157,19 @@ func (p *Plugin) applyProcess(ctx context.Context, name string, props map[string
 		return nil, err
 	}
 
+	outputs := map[string]interface{}{
+		"pid":         info.PID,
+		"environment": info.Environment,
+	}
+	if info.LogFile != "" {
+		outputs["log_file"] = info.LogFile
+		outputs["pid_file"] = info.PIDFile
+	}
 	return &ResourceState{
 		Type:       "process",
 		ID:         processName,
 		Properties: props,
-		Outputs: map[string]interface{}{
-			"pid":         info.PID,
-			"environment": info.Environment,
-		},
+		Outputs:    outputs,
 	}, nil
 }
 
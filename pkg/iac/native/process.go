@@ -24,6 +24,10 @@ type ProcessOptions struct {
 	Environment map[string]string
 	// Readiness check configuration
 	Readiness *ReadinessCheck
+	// Restart policy once the process is ready (nil never restarts it)
+	Restart *RestartPolicy
+	// Log files for the process's output and PID (nil for no files)
+	Logs *LogOptions
 	// Graceful stop configuration
 	GracefulStop *GracefulStop
 	// Stdout receives process stdout. If nil, output is discarded.
@@ -53,6 +57,10 @@ type ProcessInfo struct {
 	Command     []string
 	Environment map[string]string
 	WorkingDir  string
+	// LogFile and PIDFile are set when the process's output is logged to
+	// files (see LogOptions)
+	LogFile string
+	PIDFile string
 }
 
 // ProcessManager manages local processes.
@@ -61,10 +69,28 @@ type ProcessManager struct {
 	mu        sync.RWMutex
 }
 
+// managedProcess is a process started by a ProcessManager, along with its
+// supervisor's bookkeeping. A restarted process is a new run with its own
+// command and done channel.
 type managedProcess struct {
-	cmd  *exec.Cmd
-	info *ProcessInfo
-	done chan error
+	opts   ProcessOptions
+	ctx    context.Context
+	logs   *rotatingFile
+	stdout io.Writer
+	stderr io.Writer
+
+	mu        sync.Mutex // guards the fields below
+	cmd       *exec.Cmd
+	info      *ProcessInfo
+	done      chan error // receives the current run's exit
+	startedAt time.Time
+	status    ProcessStatus
+	ready     bool // passed its readiness check, so crashes are restarted
+
+	stop     chan struct{} // closed when the process is being stopped
+	stopOnce sync.Once
+	exited   chan struct{} // closed when the supervisor is done
+	exitCh   chan error    // receives the exit that ended supervision
 }
 
 // NewProcessManager creates a new process manager.
@@ -74,19 +100,17 @@ func NewProcessManager() *ProcessManager {
 	}
 }
 
-// StartProcess starts a new process.
+// StartProcess starts a new process and supervises it: it's restarted under
+// its restart policy once it has passed its readiness check.
 func (pm *ProcessManager) StartProcess(ctx context.Context, opts ProcessOptions) (*ProcessInfo, error) {
 	pm.mu.Lock()
 
 	// Check if process already running
 	if mp, exists := pm.processes[opts.Name]; exists {
-		if mp.cmd.Process != nil {
-			// Check if still running
-			if err := mp.cmd.Process.Signal(syscall.Signal(0)); err == nil {
-				info := mp.info
-				pm.mu.Unlock()
-				return info, nil
-			}
+		if mp.alive() {
+			info := mp.processInfo()
+			pm.mu.Unlock()
+			return info, nil
 		}
 		delete(pm.processes, opts.Name)
 	}
@@ -96,6 +120,82 @@ func (pm *ProcessManager) StartProcess(ctx context.Context, opts ProcessOptions)
 		return nil, fmt.Errorf("command is required")
 	}
 
+	// Stream output to configured writers (or discard if nil), and to the
+	// process's log file if it has one
+	mp := &managedProcess{
+		opts:   opts,
+		ctx:    ctx,
+		stdout: opts.Stdout,
+		stderr: opts.Stderr,
+		info: &ProcessInfo{
+			Name:        opts.Name,
+			Command:     opts.Command,
+			Environment: opts.Environment,
+			WorkingDir:  opts.WorkingDir,
+		},
+		// Processes without a readiness check are restarted right away
+		ready:  opts.Readiness == nil,
+		stop:   make(chan struct{}),
+		exited: make(chan struct{}),
+		exitCh: make(chan error, 1),
+	}
+	if mp.stdout == nil {
+		mp.stdout = io.Discard
+	}
+	if mp.stderr == nil {
+		mp.stderr = io.Discard
+	}
+	if opts.Logs != nil {
+		logs, err := openRotatingFile(opts.Logs.LogFile(opts.Name), opts.Logs.MaxSize, opts.Logs.MaxFiles)
+		if err != nil {
+			pm.mu.Unlock()
+			return nil, err
+		}
+		mp.logs = logs
+		mp.stdout = io.MultiWriter(mp.stdout, logs)
+		mp.stderr = io.MultiWriter(mp.stderr, logs)
+		mp.info.LogFile = opts.Logs.LogFile(opts.Name)
+		mp.info.PIDFile = opts.Logs.PIDFile(opts.Name)
+		mp.status.LogFile = mp.info.LogFile
+	}
+
+	mp.mu.Lock()
+	err := mp.spawn()
+	mp.mu.Unlock()
+	if err != nil {
+		mp.closeLogs()
+		pm.mu.Unlock()
+		return nil, err
+	}
+	go mp.supervise()
+
+	pm.processes[opts.Name] = mp
+
+	// Release the lock before the potentially long-running readiness check so
+	// that other processes can start concurrently. The process is already
+	// registered in the map, so concurrent callers will see it.
+	pm.mu.Unlock()
+
+	// Wait for readiness if configured
+	if opts.Readiness != nil {
+		if err := pm.waitForReady(ctx, opts.Readiness, mp.exitCh); err != nil {
+			// Re-acquire lock for cleanup
+			_ = pm.StopProcess(opts.Name, 5*time.Second)
+			return nil, fmt.Errorf("process failed readiness check: %w", err)
+		}
+	}
+
+	mp.mu.Lock()
+	mp.ready = true
+	mp.mu.Unlock()
+
+	return mp.processInfo(), nil
+}
+
+// spawn starts a run of the process. Caller must hold mp.mu.
+func (mp *managedProcess) spawn() error {
+	opts := mp.opts
+
 	// Prepare environment
 	env := os.Environ()
 	for k, v := range opts.Environment {
@@ -103,7 +203,7 @@ func (pm *ProcessManager) StartProcess(ctx context.Context, opts ProcessOptions)
 	}
 
 	// Create command
-	cmd := exec.CommandContext(ctx, opts.Command[0], opts.Command[1:]...)
+	cmd := exec.CommandContext(mp.ctx, opts.Command[0], opts.Command[1:]...)
 	cmd.Dir = opts.WorkingDir
 	cmd.Env = env
 	// Put the process in its own process group so we can kill the entire
@@ -113,32 +213,20 @@ func (pm *ProcessManager) StartProcess(ctx context.Context, opts ProcessOptions)
 	// Set up output capture
 	stdoutPipe, err := cmd.StdoutPipe()
 	if err != nil {
-		pm.mu.Unlock()
-		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+		return fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
 	stderrPipe, err := cmd.StderrPipe()
 	if err != nil {
-		pm.mu.Unlock()
-		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+		return fmt.Errorf("failed to create stderr pipe: %w", err)
 	}
 
 	// Start the process
 	if err := cmd.Start(); err != nil {
-		pm.mu.Unlock()
-		return nil, fmt.Errorf("failed to start process: %w", err)
+		return fmt.Errorf("failed to start process: %w", err)
 	}
 
-	// Stream output to configured writers (or discard if nil)
-	stdoutWriter := opts.Stdout
-	if stdoutWriter == nil {
-		stdoutWriter = io.Discard
-	}
-	stderrWriter := opts.Stderr
-	if stderrWriter == nil {
-		stderrWriter = io.Discard
-	}
-	go streamOutput(stdoutPipe, fmt.Sprintf("[%s] ", opts.Name), stdoutWriter)
-	go streamOutput(stderrPipe, fmt.Sprintf("[%s] [ERROR] ", opts.Name), stderrWriter)
+	go streamOutput(stdoutPipe, fmt.Sprintf("[%s] ", opts.Name), mp.stdout)
+	go streamOutput(stderrPipe, fmt.Sprintf("[%s] [ERROR] ", opts.Name), mp.stderr)
 
 	// Track completion
 	done := make(chan error, 1)
@@ -146,35 +234,37 @@ func (pm *ProcessManager) StartProcess(ctx context.Context, opts ProcessOptions)
 		done <- cmd.Wait()
 	}()
 
-	info := &ProcessInfo{
-		PID:         cmd.Process.Pid,
-		Name:        opts.Name,
-		Command:     opts.Command,
-		Environment: opts.Environment,
-		WorkingDir:  opts.WorkingDir,
-	}
+	mp.cmd = cmd
+	mp.done = done
+	mp.startedAt = time.Now()
+	mp.info.PID = cmd.Process.Pid
+	mp.status.State = ProcessRunning
+	mp.status.PID = cmd.Process.Pid
 
-	pm.processes[opts.Name] = &managedProcess{
-		cmd:  cmd,
-		info: info,
-		done: done,
+	// Record the PID so that the process can be stopped by a later run of
+	// cldctl, which won't have started it
+	if mp.info.PIDFile != "" {
+		_ = os.WriteFile(mp.info.PIDFile, []byte(fmt.Sprintf("%d\n", cmd.Process.Pid)), 0644)
 	}
+	return nil
+}
 
-	// Release the lock before the potentially long-running readiness check so
-	// that other processes can start concurrently. The process is already
-	// registered in the map, so concurrent callers will see it.
-	pm.mu.Unlock()
-
-	// Wait for readiness if configured
-	if opts.Readiness != nil {
-		if err := pm.waitForReady(ctx, opts.Readiness, done); err != nil {
-			// Re-acquire lock for cleanup
-			_ = pm.StopProcess(opts.Name, 5*time.Second)
-			return nil, fmt.Errorf("process failed readiness check: %w", err)
-		}
+// alive reports whether the process is running or waiting to be restarted.
+func (mp *managedProcess) alive() bool {
+	select {
+	case <-mp.exited:
+		return false
+	default:
+		return true
 	}
+}
 
-	return info, nil
+// processInfo returns a copy of the process's info for its current run.
+func (mp *managedProcess) processInfo() *ProcessInfo {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	info := *mp.info
+	return &info
 }
 
 // StopProcess stops a running process.
@@ -191,19 +281,24 @@ func (pm *ProcessManager) stopProcessLocked(name string, timeout time.Duration)
 	if !exists {
 		return nil // Already stopped
 	}
+	delete(pm.processes, name)
 
-	if mp.cmd.Process == nil {
-		delete(pm.processes, name)
+	// Keep the supervisor from restarting the process, then signal the run
+	// that's current once it can no longer change
+	mp.stopOnce.Do(func() { close(mp.stop) })
+	mp.mu.Lock()
+	cmd := mp.cmd
+	mp.mu.Unlock()
+	if cmd == nil || cmd.Process == nil {
 		return nil
 	}
 
-	pgid := mp.cmd.Process.Pid
+	pgid := cmd.Process.Pid
 
 	// Try graceful shutdown — signal the entire process group so child
 	// processes (e.g. node spawned by sh -c) also receive SIGTERM.
 	if err := syscall.Kill(-pgid, syscall.SIGTERM); err != nil {
 		// Process group might already be dead
-		delete(pm.processes, name)
 		return nil
 	}
 
@@ -212,13 +307,11 @@ func (pm *ProcessManager) stopProcessLocked(name string, timeout time.Duration)
 	defer timer.Stop()
 
 	select {
-	case <-mp.done:
-		delete(pm.processes, name)
+	case <-mp.exited:
 		return nil
 	case <-timer.C:
 		// Force kill the entire process group
 		_ = syscall.Kill(-pgid, syscall.SIGKILL)
-		delete(pm.processes, name)
 		return nil
 	}
 }
@@ -233,26 +326,26 @@ func (pm *ProcessManager) GetProcessInfo(name string) (*ProcessInfo, error) {
 		return nil, fmt.Errorf("process not found: %s", name)
 	}
 
-	return mp.info, nil
+	return mp.processInfo(), nil
 }
 
-// IsProcessRunning checks if a process is running.
+// IsProcessRunning checks if a process is running, or is waiting out its
+// backoff to be restarted.
 func (pm *ProcessManager) IsProcessRunning(name string) bool {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
 
 	mp, exists := pm.processes[name]
-	if !exists {
-		return false
-	}
+	return exists && mp.alive()
+}
 
-	if mp.cmd.Process == nil {
-		return false
-	}
+// Manages reports whether a process was started by this manager.
+func (pm *ProcessManager) Manages(name string) bool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
 
-	// Check if process is still alive
-	err := mp.cmd.Process.Signal(syscall.Signal(0))
-	return err == nil
+	_, exists := pm.processes[name]
+	return exists
 }
 
 // StopAllWithPrefix stops all processes whose names start with the given prefix.
@@ -0,0 +1,274 @@
+package native
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/iac"
+)
+
+// Container readiness probe types.
+const (
+	ProbePostgres = "postgres"
+	ProbeRedis    = "redis"
+	ProbeHTTP     = "http"
+	ProbeTCP      = "tcp"
+)
+
+// ContainerReadiness is the "readiness" property of a docker:container
+// resource: a protocol-level probe cldctl runs against the container through
+// its published host port before the resource reports its outputs. Unlike a
+// healthcheck it doesn't depend on tools inside the image, and it's also run
+// when a running container is reused, so dependents never race a server
+// that's still starting.
+//
+//	readiness:
+//	  type: postgres              # postgres, redis, http, or tcp
+//	  port: 5432                  # container port, which must be published
+//	  user: postgres              # postgres only
+//	  database: app               # postgres only
+//	  path: /minio/health/ready   # http only
+//	  interval: 1s                # time between probes
+//	  timeout: 60s                # how long to wait for the probe to pass
+type ContainerReadiness struct {
+	Type     string
+	Port     int
+	User     string
+	Database string
+	Path     string
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+func getContainerReadiness(props map[string]interface{}, key string) *ContainerReadiness {
+	raw, ok := props[key].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return &ContainerReadiness{
+		Type:     getString(raw, "type"),
+		Port:     toInt(raw["port"]),
+		User:     getString(raw, "user"),
+		Database: getString(raw, "database"),
+		Path:     getString(raw, "path"),
+		Interval: parseDuration(getString(raw, "interval"), time.Second),
+		Timeout:  parseDuration(getString(raw, "timeout"), 60*time.Second),
+	}
+}
+
+// probe runs the readiness probe once against addr (host:port), returning
+// why the server isn't ready yet.
+func (r *ContainerReadiness) probe(ctx context.Context, addr string) error {
+	probeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	switch r.Type {
+	case ProbePostgres:
+		return probePostgres(probeCtx, addr, r.User, r.Database)
+	case ProbeRedis:
+		return probeRedis(probeCtx, addr)
+	case ProbeHTTP:
+		return probeHTTP(probeCtx, "http://"+addr+r.Path)
+	case ProbeTCP:
+		conn, err := dialProbe(probeCtx, addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	default:
+		return fmt.Errorf("unsupported readiness probe type: %q (supported: postgres, redis, http, tcp)", r.Type)
+	}
+}
+
+// containerReady waits for a started or reused container to pass its
+// readiness probe, if it has one.
+func (p *Plugin) containerReady(ctx context.Context, rs *ResourceState, props map[string]interface{}, onProgress func(string)) error {
+	r := getContainerReadiness(props, "readiness")
+	if r == nil {
+		return nil
+	}
+	containerID, _ := rs.ID.(string)
+	ports, _ := rs.Outputs["ports"].([]interface{})
+	return p.waitForContainerReady(ctx, containerID, ports, r, onProgress)
+}
+
+// waitForContainerReady probes a container until it's ready, it exits, or
+// the readiness timeout passes. ports are the container's resource outputs,
+// which map its ports to their host ports.
+func (p *Plugin) waitForContainerReady(ctx context.Context, containerID string, ports []interface{}, r *ContainerReadiness, onProgress func(string)) error {
+	hostPort := 0
+	for _, entry := range ports {
+		if m, ok := entry.(map[string]interface{}); ok && toInt(m["container"]) == r.Port {
+			hostPort = toInt(m["host"])
+		}
+	}
+	if hostPort == 0 {
+		return fmt.Errorf("readiness probe port %d is not published", r.Port)
+	}
+	addr := net.JoinHostPort("127.0.0.1", fmt.Sprintf("%d", hostPort))
+
+	reportProgress(onProgress, iac.PhaseMessage(iac.PhaseWaitingHealthy, fmt.Sprintf("waiting for %s to accept connections…", r.Type)))
+
+	deadline := time.Now().Add(r.Timeout)
+	var lastErr error
+	for {
+		if lastErr = r.probe(ctx, addr); lastErr == nil {
+			return nil
+		}
+		if running, err := p.docker.IsContainerRunning(ctx, containerID); err == nil && !running {
+			msg := fmt.Sprintf("container exited while waiting for %s readiness", r.Type)
+			if logs := p.docker.tailContainerLogs(ctx, containerID, "50"); logs != "" {
+				msg += ":\n" + logs
+			}
+			return errors.New(msg)
+		}
+		if time.Now().Add(r.Interval).After(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(r.Interval):
+		}
+	}
+
+	msg := fmt.Sprintf("%s did not become ready within %v: %v", r.Type, r.Timeout, lastErr)
+	if logs := p.docker.tailContainerLogs(ctx, containerID, "50"); logs != "" {
+		msg += "\n\nContainer logs:\n" + logs
+	}
+	return errors.New(msg)
+}
+
+func dialProbe(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	return conn, nil
+}
+
+// pgCannotConnectNow is the SQLSTATE a PostgreSQL server returns while it's
+// starting up, shutting down, or recovering.
+const pgCannotConnectNow = "57P03"
+
+// probePostgres checks that a PostgreSQL server accepts connections, the way
+// pg_isready does: it sends a startup message and treats any reply other
+// than "cannot connect now" as ready, including authentication requests and
+// errors. The image's entrypoint initializes the database on a server that
+// only listens on a Unix socket, so probing over TCP also waits for that to
+// finish.
+func probePostgres(ctx context.Context, addr, user, database string) error {
+	conn, err := dialProbe(ctx, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if user == "" {
+		user = "postgres"
+	}
+	var params bytes.Buffer
+	_ = binary.Write(&params, binary.BigEndian, int32(196608)) // protocol 3.0
+	for _, kv := range [][2]string{{"user", user}, {"database", database}} {
+		if kv[1] == "" {
+			continue
+		}
+		params.WriteString(kv[0] + "\x00" + kv[1] + "\x00")
+	}
+	params.WriteByte(0)
+
+	msg := make([]byte, 4, 4+params.Len())
+	binary.BigEndian.PutUint32(msg, uint32(4+params.Len()))
+	msg = append(msg, params.Bytes()...)
+	if _, err := conn.Write(msg); err != nil {
+		return err
+	}
+
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return fmt.Errorf("no response to startup message: %w", err)
+	}
+	if header[0] != 'E' {
+		return nil
+	}
+
+	// An ErrorResponse is a list of typed, null-terminated fields
+	length := int(binary.BigEndian.Uint32(header[1:])) - 4
+	if length <= 0 || length > 1<<16 {
+		return fmt.Errorf("invalid error response")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(conn, body); err != nil {
+		return fmt.Errorf("failed to read error response: %w", err)
+	}
+	var code, message string
+	for _, field := range bytes.Split(body, []byte{0}) {
+		if len(field) < 2 {
+			continue
+		}
+		switch field[0] {
+		case 'C':
+			code = string(field[1:])
+		case 'M':
+			message = string(field[1:])
+		}
+	}
+	if code == pgCannotConnectNow {
+		return fmt.Errorf("server is not accepting connections: %s", message)
+	}
+	return nil
+}
+
+// probeRedis checks that a Redis server answers PING. A server that requires
+// authentication is up, so NOAUTH counts as ready, but one that's still
+// loading its dataset is not.
+func probeRedis(ctx context.Context, addr string) error {
+	conn, err := dialProbe(ctx, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("PING\r\n")); err != nil {
+		return err
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("no reply to PING: %w", err)
+	}
+	reply = strings.TrimSpace(reply)
+	if reply == "+PONG" || strings.HasPrefix(reply, "-NOAUTH") {
+		return nil
+	}
+	return fmt.Errorf("unexpected reply to PING: %s", reply)
+}
+
+// probeHTTP checks that an HTTP endpoint responds with a 2xx status.
+func probeHTTP(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
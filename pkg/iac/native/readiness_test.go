@@ -0,0 +1,136 @@
+package native
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveOnce accepts connections on a local listener and answers each with
+// the next reply, after reading the request with read.
+func serveOnce(t *testing.T, read func(net.Conn), replies ...[]byte) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for _, reply := range replies {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			read(conn)
+			_, _ = conn.Write(reply)
+			conn.Close()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func readStartupMessage(conn net.Conn) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	_, _ = io.ReadFull(conn, make([]byte, binary.BigEndian.Uint32(header)-4))
+}
+
+func pgMessage(kind byte, body string) []byte {
+	msg := []byte{kind, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(msg[1:], uint32(4+len(body)))
+	return append(msg, body...)
+}
+
+func TestGetContainerReadiness(t *testing.T) {
+	assert.Nil(t, getContainerReadiness(map[string]interface{}{}, "readiness"))
+
+	r := getContainerReadiness(map[string]interface{}{
+		"readiness": map[string]interface{}{
+			"type":     "postgres",
+			"port":     5432,
+			"database": "app",
+			"timeout":  "2m",
+		},
+	}, "readiness")
+	require.NotNil(t, r)
+	assert.Equal(t, ProbePostgres, r.Type)
+	assert.Equal(t, 5432, r.Port)
+	assert.Equal(t, "app", r.Database)
+	assert.Equal(t, 2*time.Minute, r.Timeout)
+	assert.Equal(t, time.Second, r.Interval)
+}
+
+func TestProbePostgres(t *testing.T) {
+	addr := serveOnce(t, readStartupMessage,
+		pgMessage('E', "SFATAL\x00C57P03\x00Mthe database system is starting up\x00\x00"),
+		pgMessage('R', "\x00\x00\x00\x03"),
+		pgMessage('E', "SFATAL\x00C28P01\x00Mpassword authentication failed\x00\x00"),
+	)
+
+	err := probePostgres(context.Background(), addr, "", "app")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "starting up")
+
+	assert.NoError(t, probePostgres(context.Background(), addr, "", "app"), "an authentication request means the server is up")
+	assert.NoError(t, probePostgres(context.Background(), addr, "", "app"), "authentication errors mean the server is up")
+}
+
+func TestProbePostgres_ConnectionClosed(t *testing.T) {
+	// Docker's port proxy accepts connections before the server listens,
+	// then closes them
+	addr := serveOnce(t, func(net.Conn) {}, nil)
+	assert.Error(t, probePostgres(context.Background(), addr, "", ""))
+}
+
+func TestProbeRedis(t *testing.T) {
+	readLine := func(conn net.Conn) { _, _ = conn.Read(make([]byte, 64)) }
+	addr := serveOnce(t, readLine,
+		[]byte("-LOADING Redis is loading the dataset in memory\r\n"),
+		[]byte("+PONG\r\n"),
+		[]byte("-NOAUTH Authentication required.\r\n"),
+	)
+
+	err := probeRedis(context.Background(), addr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "LOADING")
+
+	assert.NoError(t, probeRedis(context.Background(), addr))
+	assert.NoError(t, probeRedis(context.Background(), addr))
+}
+
+func TestProbeHTTP(t *testing.T) {
+	ready := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/minio/health/ready" || !ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	r := &ContainerReadiness{Type: ProbeHTTP, Path: "/minio/health/ready"}
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	err := r.probe(context.Background(), addr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+
+	ready = true
+	assert.NoError(t, r.probe(context.Background(), addr))
+}
+
+func TestContainerReadinessProbe_UnsupportedType(t *testing.T) {
+	r := &ContainerReadiness{Type: "mongodb"}
+	err := r.probe(context.Background(), "127.0.0.1:1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported readiness probe type")
+}
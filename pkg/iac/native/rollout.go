@@ -0,0 +1,134 @@
+package native
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Rollout strategies for docker:container resources.
+const (
+	// RolloutRecreate removes the old container before creating the new one.
+	// This is the default.
+	RolloutRecreate = "recreate"
+
+	// RolloutRolling starts the new container next to the old one and only
+	// removes the old container once the new one is ready, so the workload
+	// stays up while it is replaced.
+	RolloutRolling = "rolling"
+)
+
+// RolloutConfig is the "rollout" property of a docker:container resource. It
+// takes a deployment's strategy input as-is:
+//
+//	rollout:
+//	  type: rolling          # recreate (default) or rolling
+//	  max_surge: 1           # 0 replaces the container without running both
+//	  min_ready_seconds: 5   # how long the new container must stay up
+type RolloutConfig struct {
+	Type     string
+	Surge    bool
+	MinReady time.Duration
+}
+
+func getRolloutConfig(props map[string]interface{}, key string) *RolloutConfig {
+	raw, ok := props[key].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	cfg := &RolloutConfig{
+		Type:     getString(raw, "type"),
+		Surge:    true,
+		MinReady: time.Duration(toInt(raw["min_ready_seconds"])) * time.Second,
+	}
+	if cfg.Type == "" {
+		cfg.Type = RolloutRecreate
+	}
+	// A single local container can't surge by a percentage, so only an
+	// explicit zero disables it
+	if surge, ok := raw["max_surge"]; ok && surge != nil && fmt.Sprintf("%v", surge) == "0" {
+		cfg.Surge = false
+	}
+	return cfg
+}
+
+// rolling reports whether the container is replaced without downtime.
+func (r *RolloutConfig) rolling() bool {
+	return r != nil && r.Type == RolloutRolling
+}
+
+// rollingReplace replaces a running container following a rolling rollout.
+// When both containers can run at once the new one is started under a
+// temporary name, and the old one is only removed once the new one is ready;
+// if it never becomes ready the old container keeps serving. Containers that
+// publish fixed host ports can't run side by side, so they (and rollouts
+// with max_surge 0) switch over instead: the old container is stopped, and
+// restored if the new one fails. The old container is shut down following
+// its previous lifecycle.
+func (p *Plugin) rollingReplace(ctx context.Context, oldID string, opts ContainerOptions, rollout *RolloutConfig, previous *LifecycleConfig) (string, error) {
+	if !rollout.Surge || publishesHostPorts(opts) || opts.Name == "" {
+		p.runPreStop(ctx, oldID, previous)
+		return p.switchover(ctx, oldID, opts, nil, func(newID string) error {
+			return p.waitMinReady(ctx, newID, rollout)
+		})
+	}
+
+	nextOpts := opts
+	nextOpts.Name = opts.Name + "-next"
+	if leftover, _ := p.docker.GetContainerByName(ctx, nextOpts.Name); leftover != "" {
+		_ = p.docker.RemoveContainer(ctx, leftover)
+	}
+
+	reportProgress(opts.OnProgress, "starting new container alongside the current one…")
+	// RunContainer gates on the healthcheck and removes the container itself
+	// if it never becomes healthy.
+	newID, err := p.docker.RunContainer(ctx, nextOpts)
+	if err != nil {
+		return "", fmt.Errorf("rollout failed, previous container still running: %w", err)
+	}
+	if err := p.waitMinReady(ctx, newID, rollout); err != nil {
+		_ = p.docker.RemoveContainer(ctx, newID)
+		return "", fmt.Errorf("rollout failed, previous container still running: %w", err)
+	}
+
+	if err := p.stopContainer(ctx, oldID, previous); err != nil {
+		_ = p.docker.RemoveContainer(ctx, newID)
+		return "", fmt.Errorf("failed to remove previous container: %w", err)
+	}
+	if err := p.docker.RenameContainer(ctx, newID, opts.Name); err != nil {
+		return "", fmt.Errorf("failed to rename new container: %w", err)
+	}
+	return newID, nil
+}
+
+// waitMinReady waits for the rollout's minimum ready time and checks that the
+// container is still running afterwards.
+func (p *Plugin) waitMinReady(ctx context.Context, containerID string, rollout *RolloutConfig) error {
+	if rollout.MinReady <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(rollout.MinReady):
+	}
+	running, err := p.docker.IsContainerRunning(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect new container: %w", err)
+	}
+	if !running {
+		return fmt.Errorf("new container exited within min_ready_seconds (%s)", rollout.MinReady)
+	}
+	return nil
+}
+
+// publishesHostPorts reports whether the container binds fixed host ports,
+// which a second copy of it could not bind at the same time.
+func publishesHostPorts(opts ContainerOptions) bool {
+	for _, p := range opts.Ports {
+		if p.HostPort != 0 {
+			return true
+		}
+	}
+	return false
+}
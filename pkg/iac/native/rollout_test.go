@@ -0,0 +1,41 @@
+package native
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRolloutConfig(t *testing.T) {
+	assert.Nil(t, getRolloutConfig(map[string]interface{}{}, "rollout"))
+	assert.False(t, getRolloutConfig(map[string]interface{}{}, "rollout").rolling())
+
+	cfg := getRolloutConfig(map[string]interface{}{
+		"rollout": map[string]interface{}{
+			"type":              "rolling",
+			"max_surge":         "25%",
+			"min_ready_seconds": 5,
+		},
+	}, "rollout")
+	require.NotNil(t, cfg)
+	assert.True(t, cfg.rolling())
+	assert.True(t, cfg.Surge, "percentages still allow a second container")
+	assert.Equal(t, 5*time.Second, cfg.MinReady)
+
+	noSurge := getRolloutConfig(map[string]interface{}{
+		"rollout": map[string]interface{}{"type": "rolling", "max_surge": 0},
+	}, "rollout")
+	assert.False(t, noSurge.Surge)
+
+	recreate := getRolloutConfig(map[string]interface{}{"rollout": map[string]interface{}{}}, "rollout")
+	assert.Equal(t, RolloutRecreate, recreate.Type)
+	assert.False(t, recreate.rolling())
+}
+
+func TestPublishesHostPorts(t *testing.T) {
+	assert.False(t, publishesHostPorts(ContainerOptions{}))
+	assert.False(t, publishesHostPorts(ContainerOptions{Ports: []PortMapping{{ContainerPort: 8080}}}))
+	assert.True(t, publishesHostPorts(ContainerOptions{Ports: []PortMapping{{ContainerPort: 8080, HostPort: 8080}}}))
+}
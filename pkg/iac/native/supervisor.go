@@ -0,0 +1,408 @@
+package native
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Restart policies for process resources.
+const (
+	// RestartNever leaves a process that exits stopped. This is the default.
+	RestartNever = "never"
+
+	// RestartOnFailure restarts a process that exits with an error or is
+	// killed by a signal.
+	RestartOnFailure = "on-failure"
+
+	// RestartAlways restarts a process whenever it exits.
+	RestartAlways = "always"
+)
+
+// Process states reported by ProcessManager.Status.
+const (
+	ProcessRunning    = "running"
+	ProcessRestarting = "restarting"
+	ProcessExited     = "exited"
+)
+
+// restartResetAfter is how long a process has to stay up for its restart
+// backoff and consecutive restart count to reset. It matches Docker's.
+const restartResetAfter = 10 * time.Second
+
+// RestartPolicy is the "restart" property of a process resource:
+//
+//	restart:
+//	  policy: on-failure   # never (default), on-failure, or always
+//	  max_restarts: 10     # consecutive restarts before giving up (0 = no limit)
+//	  backoff: 1s          # delay before the first restart, doubled each time
+//	  max_backoff: 30s     # longest delay between restarts
+type RestartPolicy struct {
+	Policy      string
+	MaxRestarts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+func getRestartPolicy(props map[string]interface{}, key string) *RestartPolicy {
+	raw, ok := props[key].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	policy := &RestartPolicy{
+		Policy:      getString(raw, "policy"),
+		MaxRestarts: toInt(raw["max_restarts"]),
+		Backoff:     parseDuration(getString(raw, "backoff"), time.Second),
+		MaxBackoff:  parseDuration(getString(raw, "max_backoff"), 30*time.Second),
+	}
+	if policy.Policy == "" {
+		policy.Policy = RestartNever
+	}
+	return policy
+}
+
+// shouldRestart reports whether a process that exited with exitErr after
+// the given number of consecutive restarts is restarted.
+func (r *RestartPolicy) shouldRestart(exitErr error, restarts int) bool {
+	if r == nil || (r.MaxRestarts > 0 && restarts >= r.MaxRestarts) {
+		return false
+	}
+	switch r.Policy {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return exitErr != nil
+	default:
+		return false
+	}
+}
+
+// delay returns how long to wait before the nth consecutive restart.
+func (r *RestartPolicy) delay(restarts int) time.Duration {
+	d := r.Backoff
+	for i := 0; i < restarts && d < r.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.MaxBackoff {
+		d = r.MaxBackoff
+	}
+	return d
+}
+
+// LogOptions is the "logs" property of a process resource. The process's
+// output is written to <dir>/<name>.log, rotated when it reaches max_size_mb
+// and keeping max_files old files, and its PID to <dir>/<name>.pid:
+//
+//	logs:
+//	  dir: ~/.cldctl/environments/dev/logs
+//	  max_size_mb: 10   # default 10
+//	  max_files: 3      # default 3
+type LogOptions struct {
+	Dir      string
+	MaxSize  int64
+	MaxFiles int
+}
+
+func getLogOptions(props map[string]interface{}, key string) *LogOptions {
+	raw, ok := props[key].(map[string]interface{})
+	if !ok || getString(raw, "dir") == "" {
+		return nil
+	}
+	opts := &LogOptions{
+		Dir:      expandHome(getString(raw, "dir")),
+		MaxSize:  int64(toInt(raw["max_size_mb"])) << 20,
+		MaxFiles: toInt(raw["max_files"]),
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = 10 << 20
+	}
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = 3
+	}
+	return opts
+}
+
+// LogFile returns the path of a process's log file.
+func (l *LogOptions) LogFile(name string) string {
+	return filepath.Join(l.Dir, name+".log")
+}
+
+// PIDFile returns the path of the file holding a process's PID.
+func (l *LogOptions) PIDFile(name string) string {
+	return filepath.Join(l.Dir, name+".pid")
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// rotatingFile is a log file that's rotated once it reaches maxSize: name.log
+// is renamed to name.log.1, name.log.1 to name.log.2, and so on, keeping
+// maxFiles old files. It's safe for concurrent use.
+type rotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+func openRotatingFile(path string, maxSize int64, maxFiles int) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	r := &rotatingFile{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	r.file, r.size = f, info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate shifts the old files along and starts a new one. Caller must hold
+// r.mu.
+func (r *rotatingFile) rotate() error {
+	r.file.Close()
+	r.file = nil
+	_ = os.Remove(fmt.Sprintf("%s.%d", r.path, r.maxFiles))
+	for i := r.maxFiles - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// ProcessStatus reports how a supervised process is doing.
+type ProcessStatus struct {
+	// State is running, restarting (waiting out the backoff after a crash),
+	// or exited (stopped, and not restarted under its policy)
+	State    string
+	PID      int
+	Restarts int
+	// LastExit describes how the process last exited, if it has
+	LastExit string
+	LogFile  string
+}
+
+// Status returns the status of a process started by this manager.
+func (pm *ProcessManager) Status(name string) (*ProcessStatus, bool) {
+	pm.mu.RLock()
+	mp, ok := pm.processes[name]
+	pm.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	status := mp.status
+	return &status, true
+}
+
+// supervise waits for each run of a process to exit and restarts it under
+// its restart policy, until it's stopped or the policy gives up. It's the
+// only reader of the runs' exit results.
+func (mp *managedProcess) supervise() {
+	defer close(mp.exited)
+	defer mp.closeLogs()
+
+	consecutive := 0
+	for {
+		mp.mu.Lock()
+		done, startedAt := mp.done, mp.startedAt
+		mp.mu.Unlock()
+
+		exitErr := <-done
+
+		select {
+		case <-mp.stop:
+			mp.setExited(exitErr)
+			return
+		default:
+		}
+
+		if time.Since(startedAt) >= restartResetAfter {
+			consecutive = 0
+		}
+
+		mp.mu.Lock()
+		restart := mp.ready && mp.opts.Restart.shouldRestart(exitErr, consecutive)
+		mp.mu.Unlock()
+		if !restart {
+			mp.setExited(exitErr)
+			return
+		}
+
+		delay := mp.opts.Restart.delay(consecutive)
+		consecutive++
+		mp.mu.Lock()
+		mp.status.State = ProcessRestarting
+		mp.status.LastExit = describeExit(exitErr)
+		mp.mu.Unlock()
+		fmt.Fprintf(mp.stderr, "[%s] [ERROR] process %s; restarting in %v (restart %d)\n", mp.opts.Name, describeExit(exitErr), delay, consecutive)
+
+		select {
+		case <-mp.stop:
+			mp.setExited(exitErr)
+			return
+		case <-mp.ctx.Done():
+			mp.setExited(exitErr)
+			return
+		case <-time.After(delay):
+		}
+
+		mp.mu.Lock()
+		select {
+		case <-mp.stop:
+			mp.mu.Unlock()
+			mp.setExited(exitErr)
+			return
+		default:
+		}
+		err := mp.spawn()
+		if err == nil {
+			mp.status.Restarts++
+		}
+		mp.mu.Unlock()
+		if err != nil {
+			fmt.Fprintf(mp.stderr, "[%s] [ERROR] failed to restart process: %v\n", mp.opts.Name, err)
+			mp.setExited(err)
+			return
+		}
+	}
+}
+
+// setExited records that the process has stopped for good. Readiness checks
+// learn of the exit through exitCh.
+func (mp *managedProcess) setExited(exitErr error) {
+	mp.exitCh <- exitErr
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.status.State = ProcessExited
+	mp.status.LastExit = describeExit(exitErr)
+	// The PID file may already belong to a new process of the same name
+	if mp.info.PIDFile != "" {
+		if data, err := os.ReadFile(mp.info.PIDFile); err == nil && strings.TrimSpace(string(data)) == strconv.Itoa(mp.info.PID) {
+			_ = os.Remove(mp.info.PIDFile)
+		}
+	}
+}
+
+func (mp *managedProcess) closeLogs() {
+	if mp.logs != nil {
+		_ = mp.logs.Close()
+	}
+}
+
+func describeExit(err error) string {
+	if err == nil {
+		return "exited with code 0"
+	}
+	return "exited: " + err.Error()
+}
+
+// stopProcessByPID stops a process this manager didn't start, such as one
+// started by an earlier cldctl run, by its process group. The PID is read
+// from pidFile when it exists, since a supervised process's PID changes as
+// it's restarted, and falls back to pid. Processes are started as the leader
+// of their own process group, so a PID without a group of the same ID has
+// exited (and possibly been reused) and is left alone.
+func stopProcessByPID(pidFile string, pid int, timeout time.Duration) error {
+	pid = recordedPID(pidFile, pid)
+	if pidFile != "" {
+		defer os.Remove(pidFile)
+	}
+	if pid <= 0 || !groupAlive(pid) {
+		return nil
+	}
+
+	if err := syscall.Kill(-pid, syscall.SIGTERM); err != nil {
+		return nil
+	}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if !groupAlive(pid) {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	_ = syscall.Kill(-pid, syscall.SIGKILL)
+	return nil
+}
+
+// recordedPID returns the PID in pidFile if it can be read, or pid.
+func recordedPID(pidFile string, pid int) int {
+	if pidFile == "" {
+		return pid
+	}
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		return pid
+	}
+	if filePID, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+		return filePID
+	}
+	return pid
+}
+
+// groupAlive reports whether any process is left in a process group.
+func groupAlive(pgid int) bool {
+	return syscall.Kill(-pgid, syscall.Signal(0)) == nil
+}
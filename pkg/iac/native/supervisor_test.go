@@ -0,0 +1,130 @@
+package native
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestartPolicy(t *testing.T) {
+	assert.Nil(t, getRestartPolicy(map[string]interface{}{}, "restart"))
+
+	var never *RestartPolicy
+	assert.False(t, never.shouldRestart(errors.New("exit status 1"), 0))
+
+	policy := getRestartPolicy(map[string]interface{}{
+		"restart": map[string]interface{}{
+			"policy":       "on-failure",
+			"max_restarts": 3,
+			"backoff":      "1s",
+			"max_backoff":  "5s",
+		},
+	}, "restart")
+	require.NotNil(t, policy)
+	assert.True(t, policy.shouldRestart(errors.New("exit status 1"), 0))
+	assert.False(t, policy.shouldRestart(nil, 0), "on-failure doesn't restart clean exits")
+	assert.False(t, policy.shouldRestart(errors.New("exit status 1"), 3), "gives up after max_restarts")
+
+	assert.Equal(t, time.Second, policy.delay(0))
+	assert.Equal(t, 2*time.Second, policy.delay(1))
+	assert.Equal(t, 4*time.Second, policy.delay(2))
+	assert.Equal(t, 5*time.Second, policy.delay(3))
+
+	always := &RestartPolicy{Policy: RestartAlways}
+	assert.True(t, always.shouldRestart(nil, 10))
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "api.log")
+	f, err := openRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.Close())
+
+	read := func(p string) string {
+		data, _ := os.ReadFile(p)
+		return string(data)
+	}
+	assert.Equal(t, "fourth\n", read(path))
+	assert.Equal(t, "third\n", read(path+".1"))
+	assert.Equal(t, "second\n", read(path+".2"))
+	assert.NoFileExists(t, path+".3", "only max_files old files are kept")
+}
+
+func TestStartProcess_RestartsOnFailure(t *testing.T) {
+	pm := NewProcessManager()
+	_, err := pm.StartProcess(context.Background(), ProcessOptions{
+		Name:    "crashy",
+		Command: []string{"sh", "-c", "exit 1"},
+		Restart: &RestartPolicy{Policy: RestartOnFailure, MaxRestarts: 2, Backoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return !pm.IsProcessRunning("crashy") }, 5*time.Second, 10*time.Millisecond)
+	status, ok := pm.Status("crashy")
+	require.True(t, ok)
+	assert.Equal(t, ProcessExited, status.State)
+	assert.Equal(t, 2, status.Restarts)
+	assert.Contains(t, status.LastExit, "exit status 1")
+}
+
+func TestStartProcess_LogsAndPIDFile(t *testing.T) {
+	logs := &LogOptions{Dir: t.TempDir(), MaxSize: 1 << 20, MaxFiles: 3}
+	pm := NewProcessManager()
+	info, err := pm.StartProcess(context.Background(), ProcessOptions{
+		Name:    "web",
+		Command: []string{"sh", "-c", "echo hello; sleep 30"},
+		Logs:    logs,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, logs.LogFile("web"), info.LogFile)
+
+	data, err := os.ReadFile(info.PIDFile)
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(info.PID), strings.TrimSpace(string(data)))
+
+	require.Eventually(t, func() bool {
+		data, _ := os.ReadFile(info.LogFile)
+		return strings.Contains(string(data), "[web] hello")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, pm.StopProcess("web", 5*time.Second))
+	assert.NoFileExists(t, info.PIDFile)
+	assert.False(t, pm.IsProcessRunning("web"))
+}
+
+func TestStopProcessByPID(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	require.NoError(t, cmd.Start())
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+
+	pidFile := filepath.Join(t.TempDir(), "sleep.pid")
+	require.NoError(t, os.WriteFile(pidFile, []byte(strconv.Itoa(cmd.Process.Pid)), 0644))
+
+	require.NoError(t, stopProcessByPID(pidFile, 0, 5*time.Second))
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the process to be stopped")
+	}
+	assert.NoFileExists(t, pidFile)
+}
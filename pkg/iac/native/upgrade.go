@@ -0,0 +1,369 @@
+package native
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Upgrade strategies for docker:container resources.
+const (
+	// UpgradeRecreate removes the old container and creates a new one. This
+	// is the default and matches the behaviour of containers without an
+	// upgrade block.
+	UpgradeRecreate = "recreate"
+
+	// UpgradeInPlace starts the new container on the same data volume and
+	// only removes the old container once the new one passes its healthcheck.
+	// A failed healthcheck restores the old container.
+	UpgradeInPlace = "in_place"
+
+	// UpgradePostgres behaves like in_place for minor version changes and
+	// migrates data into a new, version-suffixed volume for major version
+	// changes (PostgreSQL data directories are not forward compatible).
+	UpgradePostgres = "postgres"
+)
+
+// Postgres major-version migration methods.
+const (
+	// PostgresMethodDump streams pg_dumpall from the old server into the new
+	// one. Works with any extension the new image ships (e.g. pgvector).
+	PostgresMethodDump = "dump"
+
+	// PostgresMethodPgUpgrade runs pg_upgrade in a helper image that contains
+	// both server versions.
+	PostgresMethodPgUpgrade = "pg_upgrade"
+
+	defaultPgUpgradeImage = "tianon/postgres-upgrade:{from}-to-{to}"
+)
+
+// UpgradeConfig is the "upgrade" property of a docker:container resource.
+//
+//	upgrade:
+//	  strategy: postgres          # recreate (default), in_place, postgres
+//	  volume: "${inputs.name}-data"
+//	  data_path: /var/lib/postgresql/data
+//	  method: dump                # postgres only: dump (default) or pg_upgrade
+//	  preserve_env: [MINIO_ROOT_USER, MINIO_ROOT_PASSWORD]
+type UpgradeConfig struct {
+	Strategy string
+	Volume   string
+	DataPath string
+	Method   string
+	Image    string
+
+	// PreserveEnv lists environment variables whose previously applied
+	// values are kept, so generated credentials don't change (and lock out
+	// existing data) on every apply.
+	PreserveEnv []string
+}
+
+func getUpgradeConfig(props map[string]interface{}, key string) *UpgradeConfig {
+	raw, ok := props[key].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	cfg := &UpgradeConfig{
+		Strategy:    getString(raw, "strategy"),
+		Volume:      getString(raw, "volume"),
+		DataPath:    getString(raw, "data_path"),
+		Method:      getString(raw, "method"),
+		Image:       getString(raw, "image"),
+		PreserveEnv: getStringSlice(raw, "preserve_env"),
+	}
+	if cfg.Strategy == "" {
+		cfg.Strategy = UpgradeRecreate
+	}
+	if cfg.Method == "" {
+		cfg.Method = PostgresMethodDump
+	}
+	if cfg.Image == "" {
+		cfg.Image = defaultPgUpgradeImage
+	}
+	return cfg
+}
+
+// managed reports whether the strategy replaces containers via switchover.
+func (u *UpgradeConfig) managed() bool {
+	return u != nil && (u.Strategy == UpgradeInPlace || u.Strategy == UpgradePostgres)
+}
+
+// applyPreservedEnv copies preserved environment values from the prior state
+// into opts.
+func applyPreservedEnv(opts *ContainerOptions, prior *ResourceState, upgrade *UpgradeConfig) {
+	if upgrade == nil || prior == nil || len(upgrade.PreserveEnv) == 0 {
+		return
+	}
+	previous := stringMapFrom(prior.Outputs["environment"])
+	for _, key := range upgrade.PreserveEnv {
+		if v, ok := previous[key]; ok && v != "" {
+			if opts.Environment == nil {
+				opts.Environment = map[string]string{}
+			}
+			opts.Environment[key] = v
+		}
+	}
+}
+
+// resolveDataVolume points the data mount at the volume currently holding the
+// data. After a postgres major upgrade that is a version-suffixed volume
+// rather than the one declared in the module.
+func (p *Plugin) resolveDataVolume(ctx context.Context, opts *ContainerOptions, prior *ResourceState, upgrade *UpgradeConfig) {
+	if !upgrade.managed() {
+		return
+	}
+	idx := dataMountIndex(opts.Volumes, upgrade)
+	if idx < 0 {
+		return
+	}
+
+	if prior != nil {
+		if vol, ok := prior.Outputs["data_volume"].(string); ok && vol != "" {
+			opts.Volumes[idx].Name = vol
+			return
+		}
+	}
+
+	// State lost: look for a volume left behind by an earlier upgrade.
+	if upgrade.Strategy == UpgradePostgres {
+		if major := postgresMajor(opts.Image); major > 0 {
+			candidate := versionedVolume(opts.Volumes[idx].Name, major)
+			if exists, _ := p.docker.VolumeExists(ctx, candidate); exists {
+				opts.Volumes[idx].Name = candidate
+			}
+		}
+	}
+}
+
+// upgradeContainer replaces a running container whose configuration changed
+// according to the upgrade strategy. It returns the new container ID and any
+// extra outputs to record.
+func (p *Plugin) upgradeContainer(ctx context.Context, oldID string, prior *ResourceState, opts ContainerOptions, upgrade *UpgradeConfig) (string, map[string]interface{}, error) {
+	extra := map[string]interface{}{}
+	if idx := dataMountIndex(opts.Volumes, upgrade); idx >= 0 {
+		extra["data_volume"] = opts.Volumes[idx].Name
+	}
+	if managed, ok := prior.Outputs["managed_volume"].(bool); ok && managed {
+		extra["managed_volume"] = true
+	}
+
+	if upgrade.Strategy == UpgradePostgres {
+		oldImage := getString(prior.Properties, "image")
+		from, to := postgresMajor(oldImage), postgresMajor(opts.Image)
+		if from > 0 && to > 0 && to < from {
+			return "", nil, fmt.Errorf("downgrading PostgreSQL from %d to %d is not supported: the data volume was written by version %d", from, to, from)
+		}
+		if from > 0 && to > from {
+			newID, volume, err := p.upgradePostgresMajor(ctx, oldID, opts, upgrade, from, to)
+			if err != nil {
+				return "", nil, err
+			}
+			extra["data_volume"] = volume
+			extra["managed_volume"] = true
+			extra["upgraded_from"] = oldImage
+			return newID, extra, nil
+		}
+	}
+
+	reportProgress(opts.OnProgress, "switching over to new container…")
+	newID, err := p.switchover(ctx, oldID, opts, nil, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	return newID, extra, nil
+}
+
+// upgradePostgresMajor migrates data to a new volume for a major version
+// change. The old volume is left untouched so a failed upgrade can roll back
+// and a successful one keeps a copy of the previous data.
+func (p *Plugin) upgradePostgresMajor(ctx context.Context, oldID string, opts ContainerOptions, upgrade *UpgradeConfig, from, to int) (string, string, error) {
+	idx := dataMountIndex(opts.Volumes, upgrade)
+	if idx < 0 {
+		return "", "", fmt.Errorf("postgres upgrade requires a data volume mounted at %q", upgrade.DataPath)
+	}
+	oldVolume := opts.Volumes[idx].Name
+	newVolume := versionedVolume(oldVolume, to)
+
+	newOpts := opts
+	newOpts.Volumes = append([]VolumeMount(nil), opts.Volumes...)
+	newOpts.Volumes[idx].Name = newVolume
+
+	user := opts.Environment["POSTGRES_USER"]
+	if user == "" {
+		user = "postgres"
+	}
+
+	if _, err := p.docker.CreateVolume(ctx, newVolume); err != nil {
+		return "", "", fmt.Errorf("failed to create volume for PostgreSQL %d: %w", to, err)
+	}
+	cleanupVolume := func() { _ = p.docker.RemoveVolume(ctx, newVolume) }
+
+	var beforeStart func() error
+	var afterStart func(string) error
+
+	switch upgrade.Method {
+	case PostgresMethodPgUpgrade:
+		image := strings.NewReplacer("{from}", strconv.Itoa(from), "{to}", strconv.Itoa(to)).Replace(upgrade.Image)
+		beforeStart = func() error {
+			reportProgress(opts.OnProgress, fmt.Sprintf("running pg_upgrade %d → %d…", from, to))
+			oldData := fmt.Sprintf("/var/lib/postgresql/%d/data", from)
+			newData := fmt.Sprintf("/var/lib/postgresql/%d/data", to)
+			if _, err := p.docker.RunOneShot(ctx, RunOneShotOptions{
+				Image:       image,
+				Command:     []string{"pg_upgrade"},
+				Environment: map[string]string{"PGUSER": user, "POSTGRES_INITDB_ARGS": "-U " + user},
+				Volumes: []VolumeMount{
+					{Name: oldVolume, Path: oldData},
+					{Name: newVolume, Path: newData},
+				},
+			}); err != nil {
+				return fmt.Errorf("pg_upgrade failed: %w", err)
+			}
+			// pg_upgrade does not carry over client authentication rules.
+			_, err := p.docker.RunOneShot(ctx, RunOneShotOptions{
+				Image:   opts.Image,
+				Command: []string{"cp", "/old/pg_hba.conf", "/new/pg_hba.conf"},
+				Volumes: []VolumeMount{
+					{Name: oldVolume, Path: "/old"},
+					{Name: newVolume, Path: "/new"},
+				},
+			})
+			return err
+		}
+	default:
+		reportProgress(opts.OnProgress, fmt.Sprintf("dumping PostgreSQL %d data…", from))
+		dump, err := p.docker.ExecInContainer(ctx, oldID, []string{"pg_dumpall", "-U", user}, nil)
+		if err != nil {
+			cleanupVolume()
+			return "", "", fmt.Errorf("failed to dump PostgreSQL %d data: %w", from, err)
+		}
+		afterStart = func(newID string) error {
+			reportProgress(opts.OnProgress, fmt.Sprintf("restoring data into PostgreSQL %d…", to))
+			_, err := p.docker.ExecInContainer(ctx, newID, []string{"psql", "-U", user, "-d", "postgres", "-q"}, dump)
+			if err != nil {
+				return fmt.Errorf("failed to restore data into PostgreSQL %d: %w", to, err)
+			}
+			return nil
+		}
+	}
+
+	newID, err := p.switchover(ctx, oldID, newOpts, beforeStart, afterStart)
+	if err != nil {
+		cleanupVolume()
+		return "", "", err
+	}
+	return newID, newVolume, nil
+}
+
+// switchover stops the old container, starts the new one, and only removes
+// the old container once the new one is healthy and any migration succeeded.
+// On failure the old container is renamed back and restarted.
+func (p *Plugin) switchover(ctx context.Context, oldID string, opts ContainerOptions, beforeStart func() error, afterStart func(string) error) (string, error) {
+	previousName := opts.Name + "-previous"
+	if leftover, _ := p.docker.GetContainerByName(ctx, previousName); leftover != "" && leftover != oldID {
+		_ = p.docker.RemoveContainer(ctx, leftover)
+	}
+
+	if err := p.docker.StopContainer(ctx, oldID); err != nil {
+		return "", fmt.Errorf("failed to stop previous container: %w", err)
+	}
+	if opts.Name != "" {
+		if err := p.docker.RenameContainer(ctx, oldID, previousName); err != nil {
+			_ = p.docker.StartContainer(ctx, oldID)
+			return "", fmt.Errorf("failed to rename previous container: %w", err)
+		}
+	}
+
+	rollback := func(cause error) error {
+		if opts.Name != "" {
+			_ = p.docker.RenameContainer(ctx, oldID, opts.Name)
+		}
+		if err := p.docker.StartContainer(ctx, oldID); err != nil {
+			return fmt.Errorf("upgrade failed: %v; restoring previous container also failed: %w", cause, err)
+		}
+		return fmt.Errorf("upgrade failed, previous container restored: %w", cause)
+	}
+
+	if beforeStart != nil {
+		if err := beforeStart(); err != nil {
+			return "", rollback(err)
+		}
+	}
+
+	// RunContainer gates on the healthcheck and removes the container itself
+	// if it never becomes healthy.
+	newID, err := p.docker.RunContainer(ctx, opts)
+	if err != nil {
+		return "", rollback(err)
+	}
+
+	if afterStart != nil {
+		if err := afterStart(newID); err != nil {
+			_ = p.docker.RemoveContainer(ctx, newID)
+			return "", rollback(err)
+		}
+	}
+
+	_ = p.docker.RemoveContainer(ctx, oldID)
+	return newID, nil
+}
+
+func dataMountIndex(volumes []VolumeMount, upgrade *UpgradeConfig) int {
+	for i, v := range volumes {
+		if upgrade.DataPath != "" && v.Path == upgrade.DataPath {
+			return i
+		}
+		if upgrade.DataPath == "" && upgrade.Volume != "" && v.Name == upgrade.Volume {
+			return i
+		}
+	}
+	return -1
+}
+
+// versionedVolume returns the volume name holding data for a PostgreSQL
+// major version, e.g. "db-data" → "db-data-pg17".
+func versionedVolume(base string, major int) string {
+	base = pgVolumeSuffix.ReplaceAllString(base, "")
+	return fmt.Sprintf("%s-pg%d", base, major)
+}
+
+var (
+	pgVolumeSuffix = regexp.MustCompile(`-pg\d+$`)
+	firstNumber    = regexp.MustCompile(`\d+`)
+)
+
+// postgresMajor extracts the major version from a PostgreSQL image reference
+// such as "postgres:16-alpine", "postgres:16.2", or "pgvector/pgvector:pg17".
+// It returns 0 when no version can be determined (e.g. "postgres:latest").
+func postgresMajor(image string) int {
+	idx := strings.LastIndex(image, ":")
+	if idx < 0 || strings.Contains(image[idx:], "/") {
+		return 0
+	}
+	match := firstNumber.FindString(image[idx+1:])
+	if match == "" {
+		return 0
+	}
+	major, _ := strconv.Atoi(match)
+	return major
+}
+
+// stringMapFrom converts a map that may have round-tripped through JSON.
+func stringMapFrom(v interface{}) map[string]string {
+	result := map[string]string{}
+	switch m := v.(type) {
+	case map[string]string:
+		for k, val := range m {
+			result[k] = val
+		}
+	case map[string]interface{}:
+		for k, val := range m {
+			if s, ok := val.(string); ok {
+				result[k] = s
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,90 @@
+package native
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresMajor(t *testing.T) {
+	tests := []struct {
+		image string
+		want  int
+	}{
+		{"postgres:16", 16},
+		{"postgres:16.2-alpine", 16},
+		{"pgvector/pgvector:pg17", 17},
+		{"registry.local:5000/postgres:15", 15},
+		{"registry.local:5000/postgres", 0},
+		{"postgres:latest", 0},
+		{"postgres", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			assert.Equal(t, tt.want, postgresMajor(tt.image))
+		})
+	}
+}
+
+func TestVersionedVolume(t *testing.T) {
+	assert.Equal(t, "db-data-pg17", versionedVolume("db-data", 17))
+	assert.Equal(t, "db-data-pg18", versionedVolume("db-data-pg17", 18), "suffixes are replaced, not stacked")
+}
+
+func TestGetUpgradeConfig(t *testing.T) {
+	assert.Nil(t, getUpgradeConfig(map[string]interface{}{}, "upgrade"))
+
+	cfg := getUpgradeConfig(map[string]interface{}{
+		"upgrade": map[string]interface{}{
+			"strategy":     "postgres",
+			"data_path":    "/var/lib/postgresql/data",
+			"preserve_env": []interface{}{"POSTGRES_PASSWORD"},
+		},
+	}, "upgrade")
+	require.NotNil(t, cfg)
+	assert.Equal(t, UpgradePostgres, cfg.Strategy)
+	assert.Equal(t, PostgresMethodDump, cfg.Method)
+	assert.Equal(t, defaultPgUpgradeImage, cfg.Image)
+	assert.Equal(t, []string{"POSTGRES_PASSWORD"}, cfg.PreserveEnv)
+	assert.True(t, cfg.managed())
+
+	recreate := getUpgradeConfig(map[string]interface{}{"upgrade": map[string]interface{}{}}, "upgrade")
+	assert.Equal(t, UpgradeRecreate, recreate.Strategy)
+	assert.False(t, recreate.managed())
+}
+
+func TestApplyPreservedEnv(t *testing.T) {
+	prior := &ResourceState{
+		Outputs: map[string]interface{}{
+			// JSON round-tripped state
+			"environment": map[string]interface{}{
+				"MINIO_ROOT_USER":     "olduser",
+				"MINIO_ROOT_PASSWORD": "oldpass",
+				"OTHER":               "old",
+			},
+		},
+	}
+	opts := &ContainerOptions{Environment: map[string]string{
+		"MINIO_ROOT_USER":     "newuser",
+		"MINIO_ROOT_PASSWORD": "newpass",
+		"OTHER":               "new",
+	}}
+	upgrade := &UpgradeConfig{PreserveEnv: []string{"MINIO_ROOT_USER", "MINIO_ROOT_PASSWORD"}}
+
+	applyPreservedEnv(opts, prior, upgrade)
+
+	assert.Equal(t, "olduser", opts.Environment["MINIO_ROOT_USER"])
+	assert.Equal(t, "oldpass", opts.Environment["MINIO_ROOT_PASSWORD"])
+	assert.Equal(t, "new", opts.Environment["OTHER"], "only listed variables are preserved")
+}
+
+func TestDataMountIndex(t *testing.T) {
+	volumes := []VolumeMount{
+		{Name: "config", Path: "/etc/app"},
+		{Name: "db-data", Path: "/var/lib/postgresql/data"},
+	}
+	assert.Equal(t, 1, dataMountIndex(volumes, &UpgradeConfig{DataPath: "/var/lib/postgresql/data"}))
+	assert.Equal(t, 1, dataMountIndex(volumes, &UpgradeConfig{Volume: "db-data"}))
+	assert.Equal(t, -1, dataMountIndex(volumes, &UpgradeConfig{DataPath: "/data"}))
+}
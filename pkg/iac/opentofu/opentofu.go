@@ -2,27 +2,33 @@
 package opentofu
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/logging"
 )
 
+// pluginLog is the logger for the commands the plugin runs.
+var pluginLog = logging.Logger(logging.Plugins)
+
 func init() {
-	// Register both opentofu and terraform names
+	// Register the opentofu, terraform, and tofu names
 	iac.Register("opentofu", func() (iac.Plugin, error) {
 		return NewPlugin("tofu")
 	})
 	iac.Register("terraform", func() (iac.Plugin, error) {
 		return NewPlugin("terraform")
 	})
+	iac.Register("tofu", func() (iac.Plugin, error) {
+		return NewPlugin("tofu")
+	})
 }
 
 // Plugin implements the IaC plugin interface for OpenTofu/Terraform.
@@ -65,6 +71,12 @@ func (p *Plugin) Name() string {
 	return "opentofu"
 }
 
+// RefreshesState reports that Refresh reads back the module's resources
+// with `tofu refresh`.
+func (p *Plugin) RefreshesState() bool {
+	return true
+}
+
 // TFState represents Terraform/OpenTofu state.
 type TFState struct {
 	Version          int          `json:"version"`
@@ -234,11 +246,19 @@ func (p *Plugin) Refresh(ctx context.Context, opts iac.RunOptions) (*iac.Refresh
 		workDir = opts.ModuleSource
 	}
 
+	// Write tfvars file from inputs
+	if err := p.writeTFVars(workDir, opts.Inputs); err != nil {
+		return nil, fmt.Errorf("failed to write tfvars: %w", err)
+	}
+
 	// Initialize if needed
 	if err := p.init(ctx, workDir, opts); err != nil {
 		return nil, fmt.Errorf("init failed: %w", err)
 	}
 
+	// Keep the state as recorded to find what drifted
+	recorded, _ := p.readState(workDir)
+
 	// Run refresh
 	args := []string{
 		"refresh",
@@ -246,6 +266,12 @@ func (p *Plugin) Refresh(ctx context.Context, opts iac.RunOptions) (*iac.Refresh
 		"-input=false",
 	}
 
+	// Add var file if exists
+	varFile := filepath.Join(workDir, "terraform.tfvars.json")
+	if _, err := os.Stat(varFile); err == nil {
+		args = append(args, "-var-file=terraform.tfvars.json")
+	}
+
 	_, err := p.runTF(ctx, workDir, args, opts)
 	if err != nil {
 		return nil, fmt.Errorf("refresh failed: %w", err)
@@ -256,10 +282,15 @@ func (p *Plugin) Refresh(ctx context.Context, opts iac.RunOptions) (*iac.Refresh
 	if err != nil {
 		return nil, fmt.Errorf("failed to read state: %w", err)
 	}
+	outputs, err := p.OutputsFromState(stateBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outputs: %w", err)
+	}
 
 	return &iac.RefreshResult{
-		State:  stateBytes,
-		Drifts: []iac.ResourceDrift{}, // Would parse from refresh output
+		State:   stateBytes,
+		Drifts:  stateDrifts(recorded, stateBytes),
+		Outputs: outputs,
 	}, nil
 }
 
@@ -313,6 +344,74 @@ func (p *Plugin) getOutputs(ctx context.Context, workDir string, opts iac.RunOpt
 	return outputs, nil
 }
 
+// OutputsFromState reads the outputs recorded in a Terraform/OpenTofu state
+// file.
+func (p *Plugin) OutputsFromState(state []byte) (map[string]iac.OutputValue, error) {
+	var tfState TFState
+	if err := json.Unmarshal(state, &tfState); err != nil {
+		return nil, fmt.Errorf("invalid state file: %w", err)
+	}
+	if tfState.Version == 0 {
+		return nil, fmt.Errorf("invalid state file: missing version")
+	}
+
+	outputs := make(map[string]iac.OutputValue, len(tfState.Outputs))
+	for k, v := range tfState.Outputs {
+		outputs[k] = iac.OutputValue{
+			Value:     v.Value,
+			Sensitive: v.Sensitive,
+		}
+	}
+	return outputs, nil
+}
+
+// stateDrifts compares the managed resources of a state before and after a
+// refresh. Instances are matched by address and position. Nothing is
+// reported if either state can't be read.
+func stateDrifts(recorded, refreshed []byte) []iac.ResourceDrift {
+	var before, after TFState
+	if json.Unmarshal(recorded, &before) != nil || json.Unmarshal(refreshed, &after) != nil {
+		return nil
+	}
+
+	type instance struct {
+		resourceType string
+		attributes   map[string]interface{}
+	}
+	instances := func(state TFState) map[string]instance {
+		m := make(map[string]instance)
+		for _, r := range state.Resources {
+			if r.Mode != "" && r.Mode != "managed" {
+				continue
+			}
+			for i, inst := range r.Instances {
+				address := r.Type + "." + r.Name
+				if len(r.Instances) > 1 {
+					address = fmt.Sprintf("%s[%d]", address, i)
+				}
+				m[address] = instance{resourceType: r.Type, attributes: inst.Attributes}
+			}
+		}
+		return m
+	}
+
+	actual := instances(after)
+	var drifts []iac.ResourceDrift
+	for address, inst := range instances(before) {
+		drift := iac.ResourceDrift{ResourceID: address, ResourceType: inst.resourceType}
+		if now, ok := actual[address]; ok {
+			drift.Diffs = iac.DiffProperties(inst.attributes, now.attributes)
+		} else {
+			drift.Diffs = []iac.PropertyDiff{{Path: iac.DriftPathExists, OldValue: true, NewValue: false}}
+		}
+		if len(drift.Diffs) > 0 {
+			drifts = append(drifts, drift)
+		}
+	}
+	sort.Slice(drifts, func(i, j int) bool { return drifts[i].ResourceID < drifts[j].ResourceID })
+	return drifts
+}
+
 func (p *Plugin) readState(workDir string) ([]byte, error) {
 	stateFile := filepath.Join(workDir, "terraform.tfstate")
 	return os.ReadFile(stateFile)
@@ -483,6 +582,7 @@ func (p *Plugin) Import(ctx context.Context, opts iac.ImportOptions) (*iac.Impor
 }
 
 func (p *Plugin) runTF(ctx context.Context, workDir string, args []string, opts iac.RunOptions) (string, error) {
+	pluginLog.Debug("running tofu", "dir", workDir, "args", args)
 	cmd := exec.CommandContext(ctx, p.binaryPath, args...)
 	cmd.Dir = workDir
 
@@ -496,24 +596,8 @@ func (p *Plugin) runTF(ctx context.Context, workDir string, args []string, opts
 	cmd.Env = append(cmd.Env, "TF_INPUT=0")
 	cmd.Env = append(cmd.Env, "TF_IN_AUTOMATION=1")
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	// Also stream to provided writers if available
-	if opts.Stdout != nil {
-		cmd.Stdout = io.MultiWriter(&stdout, opts.Stdout)
-	}
-	if opts.Stderr != nil {
-		cmd.Stderr = io.MultiWriter(&stderr, opts.Stderr)
-	}
-
-	err := cmd.Run()
-	if err != nil {
-		return stdout.String(), fmt.Errorf("%w: %s", err, stderr.String())
-	}
-
-	return stdout.String(), nil
+	stdout, err := iac.RunCommand(cmd, opts.Stdout, opts.Stderr)
+	return string(stdout), err
 }
 
 // Ensure we implement the Plugin interface
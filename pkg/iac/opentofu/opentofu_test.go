@@ -280,6 +280,71 @@ func TestPlugin_ReadState_NotFound(t *testing.T) {
 	}
 }
 
+func TestPlugin_OutputsFromState(t *testing.T) {
+	p := &Plugin{}
+
+	outputs, err := p.OutputsFromState([]byte(`{
+		"version": 4,
+		"terraform_version": "1.5.0",
+		"outputs": {
+			"vpc_id": {"value": "vpc-0abc123", "type": "string"},
+			"db_password": {"value": "s3cret", "type": "string", "sensitive": true}
+		},
+		"resources": []
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if outputs["vpc_id"].Value != "vpc-0abc123" || outputs["vpc_id"].Sensitive {
+		t.Errorf("unexpected vpc_id output: %+v", outputs["vpc_id"])
+	}
+	if !outputs["db_password"].Sensitive {
+		t.Error("expected db_password to be sensitive")
+	}
+
+	if _, err := p.OutputsFromState([]byte(`{"outputs": {}}`)); err == nil {
+		t.Error("expected error for state without a version")
+	}
+	if _, err := p.OutputsFromState([]byte("not json")); err == nil {
+		t.Error("expected error for invalid state")
+	}
+}
+
+func TestStateDrifts(t *testing.T) {
+	recorded := []byte(`{
+		"version": 4,
+		"resources": [
+			{"mode": "managed", "type": "aws_db_instance", "name": "main", "instances": [{"attributes": {"id": "db-1", "allocated_storage": 20}}]},
+			{"mode": "managed", "type": "aws_s3_bucket", "name": "logs", "instances": [{"attributes": {"id": "logs"}}]},
+			{"mode": "managed", "type": "aws_iam_role", "name": "app", "instances": [{"attributes": {"id": "app"}}]},
+			{"mode": "data", "type": "aws_caller_identity", "name": "current", "instances": [{"attributes": {"account_id": "1"}}]}
+		]
+	}`)
+	refreshed := []byte(`{
+		"version": 4,
+		"resources": [
+			{"mode": "managed", "type": "aws_db_instance", "name": "main", "instances": [{"attributes": {"id": "db-1", "allocated_storage": 50}}]},
+			{"mode": "managed", "type": "aws_iam_role", "name": "app", "instances": [{"attributes": {"id": "app"}}]},
+			{"mode": "data", "type": "aws_caller_identity", "name": "current", "instances": [{"attributes": {"account_id": "2"}}]}
+		]
+	}`)
+
+	drifts := stateDrifts(recorded, refreshed)
+	if len(drifts) != 2 {
+		t.Fatalf("expected 2 drifts, got %+v", drifts)
+	}
+	if drifts[0].ResourceID != "aws_db_instance.main" || drifts[0].Diffs[0].Path != "allocated_storage" {
+		t.Errorf("unexpected drift: %+v", drifts[0])
+	}
+	if drifts[1].ResourceID != "aws_s3_bucket.logs" || drifts[1].Diffs[0].Path != iac.DriftPathExists {
+		t.Errorf("expected the removed bucket to be reported, got %+v", drifts[1])
+	}
+
+	if drifts := stateDrifts(nil, refreshed); drifts != nil {
+		t.Errorf("expected no drifts without a recorded state, got %+v", drifts)
+	}
+}
+
 func TestPlugin_ParsePlanOutput(t *testing.T) {
 	p := &Plugin{}
 
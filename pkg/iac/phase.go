@@ -0,0 +1,51 @@
+package iac
+
+import "strings"
+
+// Phase is a standardized stage of applying a resource, reported through
+// RunOptions.OnProgress so that a resource that has been running for a long
+// time can be told apart: still pulling its image, or waiting on a health
+// check that will never pass.
+type Phase string
+
+const (
+	PhasePulling        Phase = "pulling"
+	PhaseBuilding       Phase = "building"
+	PhaseProvisioning   Phase = "provisioning"
+	PhaseWaitingHealthy Phase = "waiting-healthy"
+	PhaseConfiguring    Phase = "configuring"
+)
+
+// Phases lists the standardized phases in the order a resource usually
+// moves through them.
+var Phases = []Phase{PhasePulling, PhaseBuilding, PhaseProvisioning, PhaseWaitingHealthy, PhaseConfiguring}
+
+// PhaseMessage formats a progress message that moves the resource into a
+// phase, e.g. PhaseMessage(PhasePulling, "pulling image…") reports
+// "[pulling] pulling image…". Later messages without a phase leave the
+// resource in it.
+func PhaseMessage(phase Phase, message string) string {
+	if message == "" {
+		return "[" + string(phase) + "]"
+	}
+	return "[" + string(phase) + "] " + message
+}
+
+// ParseProgress splits a progress message into the phase it reports, if it
+// starts with one of the standardized phases, and the rest of the message.
+func ParseProgress(message string) (Phase, string) {
+	rest, ok := strings.CutPrefix(message, "[")
+	if !ok {
+		return "", message
+	}
+	name, rest, ok := strings.Cut(rest, "]")
+	if !ok {
+		return "", message
+	}
+	for _, phase := range Phases {
+		if string(phase) == name {
+			return phase, strings.TrimPrefix(rest, " ")
+		}
+	}
+	return "", message
+}
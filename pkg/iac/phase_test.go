@@ -0,0 +1,24 @@
+package iac
+
+import "testing"
+
+func TestParseProgress(t *testing.T) {
+	tests := []struct {
+		message   string
+		wantPhase Phase
+		wantRest  string
+	}{
+		{PhaseMessage(PhasePulling, "pulling image…"), PhasePulling, "pulling image…"},
+		{PhaseMessage(PhaseWaitingHealthy, ""), PhaseWaitingHealthy, ""},
+		{"[configuring] running site.yml", PhaseConfiguring, "running site.yml"},
+		{"health check 5/30", "", "health check 5/30"},
+		{"[info] not a phase", "", "[info] not a phase"},
+		{"[pulling", "", "[pulling"},
+	}
+	for _, tt := range tests {
+		phase, rest := ParseProgress(tt.message)
+		if phase != tt.wantPhase || rest != tt.wantRest {
+			t.Errorf("ParseProgress(%q) = %q, %q; want %q, %q", tt.message, phase, rest, tt.wantPhase, tt.wantRest)
+		}
+	}
+}
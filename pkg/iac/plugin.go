@@ -4,6 +4,9 @@ package iac
 import (
 	"context"
 	"io"
+	"reflect"
+	"sort"
+	"time"
 )
 
 // Plugin defines the interface for IaC framework plugins.
@@ -14,13 +17,21 @@ type Plugin interface {
 	// Preview generates a preview of changes without applying
 	Preview(ctx context.Context, opts RunOptions) (*PreviewResult, error)
 
-	// Apply applies the module and returns outputs
+	// Apply applies the module and returns outputs.
+	//
+	// When Apply fails or ctx is cancelled part way through, it should make a
+	// best-effort attempt to roll back the resources it created, using
+	// CleanupContext so the rollback itself is not cancelled. Resources that
+	// could not be rolled back must be reported with a *PartialApplyError so
+	// the caller can record them for later cleanup instead of losing track.
 	Apply(ctx context.Context, opts RunOptions) (*ApplyResult, error)
 
 	// Destroy destroys resources created by the module
 	Destroy(ctx context.Context, opts RunOptions) error
 
-	// Refresh refreshes state without applying changes
+	// Refresh reads back the infrastructure described by the state in
+	// opts.StateReader without applying changes, reporting what drifted from
+	// it and the module's current outputs
 	Refresh(ctx context.Context, opts RunOptions) (*RefreshResult, error)
 
 	// Import adopts existing cloud resources into the module's state.
@@ -102,12 +113,17 @@ type RunOptions struct {
 	// Volumes are volume mounts needed by the module (e.g., Docker socket)
 	Volumes []VolumeMount
 
+	// Sandbox restricts how the module runs (nil = unrestricted). Only
+	// plugins implementing SandboxEnforcer may be given one.
+	Sandbox *Sandbox
+
 	// Stdout/Stderr for command output
 	Stdout io.Writer
 	Stderr io.Writer
 
 	// OnProgress reports sub-status updates during long-running operations
-	// (e.g., "pulling image...", "health check 5/30"). May be nil.
+	// (e.g., "pulling image...", "health check 5/30"). Messages formatted with
+	// PhaseMessage also move the resource into a standardized phase. May be nil.
 	OnProgress func(message string)
 }
 
@@ -118,6 +134,69 @@ type VolumeMount struct {
 	ReadOnly  bool
 }
 
+// Sandbox restricts a module's execution so untrusted modules can't reach
+// the network or exhaust the host.
+type Sandbox struct {
+	NoNetwork      bool
+	ReadOnlyRoot   bool
+	Memory         int64   // Bytes (0 = unlimited)
+	CPUs           float64 // 0 = unlimited
+	PidsLimit      int64   // 0 = unlimited
+	SeccompProfile string  // Absolute path to a seccomp profile (JSON)
+}
+
+// SandboxEnforcer is implemented by plugins that can enforce
+// RunOptions.Sandbox.
+type SandboxEnforcer interface {
+	EnforcesSandbox() bool
+}
+
+// EnforcesSandbox reports whether p can enforce RunOptions.Sandbox.
+func EnforcesSandbox(p Plugin) bool {
+	s, ok := p.(SandboxEnforcer)
+	return ok && s.EnforcesSandbox()
+}
+
+// StateRefresher is implemented by plugins whose Refresh reads back the
+// infrastructure a module's state describes, rather than trusting the state
+// as recorded. Only these are used to probe resources a crashed deploy left
+// in flight.
+type StateRefresher interface {
+	RefreshesState() bool
+}
+
+// RefreshesState reports whether p's Refresh reads back real infrastructure.
+func RefreshesState(p Plugin) bool {
+	r, ok := p.(StateRefresher)
+	return ok && r.RefreshesState()
+}
+
+// CredentialChecker is implemented by plugins that can cheaply check that
+// the cloud credentials a module would run with are valid, so that expired
+// credentials are reported before a deploy starts rather than partway
+// through it.
+type CredentialChecker interface {
+	// CheckCredentials checks the credentials of a module run with env
+	// layered over the CLI's environment (see RunOptions.Environment).
+	CheckCredentials(ctx context.Context, env map[string]string) error
+}
+
+// CheckCredentials checks the credentials of a module run by p with env, if
+// p can check them. Plugins that can't are assumed to have valid ones.
+func CheckCredentials(ctx context.Context, p Plugin, env map[string]string) error {
+	if c, ok := p.(CredentialChecker); ok {
+		return c.CheckCredentials(ctx, env)
+	}
+	return nil
+}
+
+// StateOutputReader is implemented by plugins that can read a module's
+// outputs directly from its serialized state, so modules applied outside
+// cldctl can be adopted without re-running them.
+type StateOutputReader interface {
+	OutputsFromState(state []byte) (map[string]OutputValue, error)
+}
+
 // PreviewResult contains the result of a preview operation.
 type PreviewResult struct {
 	Changes []ResourceChange
@@ -176,15 +255,86 @@ type OutputValue struct {
 	Sensitive bool
 }
 
+// SensitiveOutputs returns the names of the sensitive outputs, sorted.
+func SensitiveOutputs(outputs map[string]OutputValue) []string {
+	var names []string
+	for name, out := range outputs {
+		if out.Sensitive {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 // RefreshResult contains the result of a refresh operation.
 type RefreshResult struct {
+	// State is the refreshed state; nil if the plugin doesn't manage state
+	// this way
 	State  []byte
 	Drifts []ResourceDrift
+
+	// Outputs are the module's outputs as read back; nil if the plugin
+	// can't read them
+	Outputs map[string]OutputValue
 }
 
 // ResourceDrift describes drift between state and actual infrastructure.
+// A resource that no longer exists has a single diff with the path
+// DriftPathExists.
 type ResourceDrift struct {
 	ResourceID   string
 	ResourceType string
 	Diffs        []PropertyDiff
 }
+
+// DriftPathExists is the PropertyDiff path reporting that a resource
+// recorded in state no longer exists (true → false).
+const DriftPathExists = "exists"
+
+// DiffProperties returns the differences between the properties of a
+// resource as recorded and as read back, sorted by path.
+func DiffProperties(recorded, actual map[string]interface{}) []PropertyDiff {
+	var diffs []PropertyDiff
+	for k, old := range recorded {
+		if v, ok := actual[k]; !ok || !reflect.DeepEqual(old, v) {
+			diffs = append(diffs, PropertyDiff{Path: k, OldValue: old, NewValue: actual[k]})
+		}
+	}
+	for k, v := range actual {
+		if _, ok := recorded[k]; !ok {
+			diffs = append(diffs, PropertyDiff{Path: k, NewValue: v})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs
+}
+
+// CleanupTimeout bounds how long a plugin spends rolling back a failed or
+// cancelled apply.
+const CleanupTimeout = 2 * time.Minute
+
+// CleanupContext returns a context for rolling back after a failed or
+// cancelled apply. It keeps ctx's values but not its cancellation, so cleanup
+// can still run after the user interrupts a deploy, and is bounded by
+// CleanupTimeout.
+func CleanupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), CleanupTimeout)
+}
+
+// PartialApplyError is returned by Apply when it failed after creating
+// resources that it could not roll back. State is the serialized plugin state
+// of the leftover resources, in the same format as ApplyResult.State, so they
+// can be destroyed later.
+type PartialApplyError struct {
+	Err   error
+	State []byte
+}
+
+func (e *PartialApplyError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PartialApplyError) Unwrap() error {
+	return e.Err
+}
@@ -2,7 +2,11 @@ package iac
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"reflect"
 	"testing"
 )
 
@@ -322,6 +326,20 @@ func TestOutputValue(t *testing.T) {
 	}
 }
 
+func TestSensitiveOutputs(t *testing.T) {
+	names := SensitiveOutputs(map[string]OutputValue{
+		"host":       {Value: "db.internal"},
+		"password":   {Value: "secret", Sensitive: true},
+		"access_key": {Value: "key", Sensitive: true},
+	})
+	if !reflect.DeepEqual(names, []string{"access_key", "password"}) {
+		t.Errorf("expected the sensitive outputs, sorted, got %v", names)
+	}
+	if names := SensitiveOutputs(nil); names != nil {
+		t.Errorf("expected no sensitive outputs, got %v", names)
+	}
+}
+
 func TestRefreshResult(t *testing.T) {
 	result := RefreshResult{
 		State: []byte(`{"refreshed": true}`),
@@ -356,6 +374,21 @@ func TestRefreshResult(t *testing.T) {
 	}
 }
 
+func TestDiffProperties(t *testing.T) {
+	diffs := DiffProperties(
+		map[string]interface{}{"size": 20, "tags": map[string]interface{}{"team": "a"}, "name": "db", "zone": "a"},
+		map[string]interface{}{"size": 50, "tags": map[string]interface{}{"team": "a"}, "name": "db", "endpoint": "db.internal"},
+	)
+	got := fmt.Sprint(diffs)
+	want := "[{endpoint <nil> db.internal false} {size 20 50 false} {zone a <nil> false}]"
+	if got != want {
+		t.Errorf("diffs = %s, want %s", got, want)
+	}
+	if diffs := DiffProperties(map[string]interface{}{"a": 1}, map[string]interface{}{"a": 1}); len(diffs) != 0 {
+		t.Errorf("expected no diffs for equal properties, got %v", diffs)
+	}
+}
+
 func TestResourceDrift(t *testing.T) {
 	drift := ResourceDrift{
 		ResourceID:   "resource-id",
@@ -381,3 +414,33 @@ func TestPlugin_Interface(t *testing.T) {
 	// Verify the mock plugin implements the Plugin interface
 	var _ Plugin = &mockPlugin{}
 }
+
+func TestCleanupContext_IgnoresParentCancellation(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ctx, cleanupCancel := CleanupContext(parent)
+	defer cleanupCancel()
+
+	if ctx.Err() != nil {
+		t.Errorf("cleanup context should not be cancelled, got %v", ctx.Err())
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		t.Error("cleanup context should have a deadline")
+	}
+}
+
+func TestPartialApplyError_Unwrap(t *testing.T) {
+	err := fmt.Errorf("module failed: %w", &PartialApplyError{Err: context.Canceled, State: []byte("{}")})
+
+	var partial *PartialApplyError
+	if !errors.As(err, &partial) {
+		t.Fatal("expected errors.As to find PartialApplyError")
+	}
+	if string(partial.State) != "{}" {
+		t.Errorf("State = %q", partial.State)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Error("expected PartialApplyError to unwrap to its cause")
+	}
+}
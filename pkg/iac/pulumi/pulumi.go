@@ -2,20 +2,22 @@
 package pulumi
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/davidthor/cldctl/pkg/iac"
+	"github.com/davidthor/cldctl/pkg/logging"
 	"gopkg.in/yaml.v3"
 )
 
+// pluginLog is the logger for the commands the plugin runs.
+var pluginLog = logging.Logger(logging.Plugins)
+
 func init() {
 	iac.Register("pulumi", func() (iac.Plugin, error) {
 		return NewPlugin()
@@ -45,6 +47,12 @@ func (p *Plugin) Name() string {
 	return "pulumi"
 }
 
+// RefreshesState reports that Refresh reads back the stack's resources with
+// `pulumi refresh`.
+func (p *Plugin) RefreshesState() bool {
+	return true
+}
+
 // State represents Pulumi stack state.
 type State struct {
 	StackName   string                 `json:"stack_name"`
@@ -187,6 +195,9 @@ func (p *Plugin) Refresh(ctx context.Context, opts iac.RunOptions) (*iac.Refresh
 
 	stackName := getStackName(opts.Environment)
 
+	// Keep the state as recorded to find what drifted
+	recorded, _ := p.exportState(ctx, workDir, stackName, opts)
+
 	// Run pulumi refresh
 	args := []string{
 		"refresh",
@@ -206,13 +217,63 @@ func (p *Plugin) Refresh(ctx context.Context, opts iac.RunOptions) (*iac.Refresh
 	if err != nil {
 		return nil, fmt.Errorf("failed to export state: %w", err)
 	}
+	outputs, err := p.getOutputs(ctx, workDir, stackName, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outputs: %w", err)
+	}
 
 	return &iac.RefreshResult{
-		State:  stateBytes,
-		Drifts: []iac.ResourceDrift{}, // Would parse from refresh output
+		State:   stateBytes,
+		Drifts:  stackDrifts(recorded, stateBytes),
+		Outputs: outputs,
 	}, nil
 }
 
+// stackExport is the part of `pulumi stack export` output drift is found
+// from.
+type stackExport struct {
+	Deployment struct {
+		Resources []struct {
+			URN     string                 `json:"urn"`
+			Type    string                 `json:"type"`
+			Outputs map[string]interface{} `json:"outputs"`
+		} `json:"resources"`
+	} `json:"deployment"`
+}
+
+// stackDrifts compares the resources of a stack export before and after a
+// refresh, matched by URN. Nothing is reported if either export can't be
+// read.
+func stackDrifts(recorded, refreshed []byte) []iac.ResourceDrift {
+	var before, after stackExport
+	if json.Unmarshal(recorded, &before) != nil || json.Unmarshal(refreshed, &after) != nil {
+		return nil
+	}
+
+	actual := make(map[string]map[string]interface{}, len(after.Deployment.Resources))
+	for _, r := range after.Deployment.Resources {
+		actual[r.URN] = r.Outputs
+	}
+
+	var drifts []iac.ResourceDrift
+	for _, r := range before.Deployment.Resources {
+		// The stack and its providers aren't infrastructure that drifts
+		if strings.HasPrefix(r.Type, "pulumi:") {
+			continue
+		}
+		drift := iac.ResourceDrift{ResourceID: r.URN, ResourceType: r.Type}
+		if outputs, ok := actual[r.URN]; ok {
+			drift.Diffs = iac.DiffProperties(r.Outputs, outputs)
+		} else {
+			drift.Diffs = []iac.PropertyDiff{{Path: iac.DriftPathExists, OldValue: true, NewValue: false}}
+		}
+		if len(drift.Diffs) > 0 {
+			drifts = append(drifts, drift)
+		}
+	}
+	return drifts
+}
+
 func (p *Plugin) Import(ctx context.Context, opts iac.ImportOptions) (*iac.ImportResult, error) {
 	workDir := opts.WorkDir
 	if workDir == "" {
@@ -471,6 +532,7 @@ func mapPulumiAction(op string) iac.ChangeAction {
 }
 
 func (p *Plugin) runPulumi(ctx context.Context, workDir string, args []string, opts iac.RunOptions) (string, error) {
+	pluginLog.Debug("running pulumi", "dir", workDir, "args", args)
 	cmd := exec.CommandContext(ctx, p.pulumiPath, args...)
 	cmd.Dir = workDir
 
@@ -485,24 +547,8 @@ func (p *Plugin) runPulumi(ctx context.Context, workDir string, args []string, o
 		cmd.Env = append(cmd.Env, "PULUMI_BACKEND_URL=file://~/.pulumi")
 	}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	// Also stream to provided writers if available
-	if opts.Stdout != nil {
-		cmd.Stdout = io.MultiWriter(&stdout, opts.Stdout)
-	}
-	if opts.Stderr != nil {
-		cmd.Stderr = io.MultiWriter(&stderr, opts.Stderr)
-	}
-
-	err := cmd.Run()
-	if err != nil {
-		return stdout.String(), fmt.Errorf("%w: %s", err, stderr.String())
-	}
-
-	return stdout.String(), nil
+	stdout, err := iac.RunCommand(cmd, opts.Stdout, opts.Stderr)
+	return string(stdout), err
 }
 
 func getStackName(env map[string]string) string {
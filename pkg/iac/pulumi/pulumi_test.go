@@ -262,6 +262,31 @@ func TestPlugin_WriteConfig_NilInputs(t *testing.T) {
 	}
 }
 
+func TestStackDrifts(t *testing.T) {
+	recorded := []byte(`{"version": 3, "deployment": {"resources": [
+		{"urn": "urn:pulumi:dev::app::pulumi:pulumi:Stack::app-dev", "type": "pulumi:pulumi:Stack", "outputs": {"url": "a"}},
+		{"urn": "urn:pulumi:dev::app::aws:s3/bucket:Bucket::logs", "type": "aws:s3/bucket:Bucket", "outputs": {"versioning": true}},
+		{"urn": "urn:pulumi:dev::app::aws:sqs/queue:Queue::jobs", "type": "aws:sqs/queue:Queue", "outputs": {"name": "jobs"}},
+		{"urn": "urn:pulumi:dev::app::aws:iam/role:Role::app", "type": "aws:iam/role:Role", "outputs": {"name": "app"}}
+	]}}`)
+	refreshed := []byte(`{"version": 3, "deployment": {"resources": [
+		{"urn": "urn:pulumi:dev::app::pulumi:pulumi:Stack::app-dev", "type": "pulumi:pulumi:Stack", "outputs": {"url": "b"}},
+		{"urn": "urn:pulumi:dev::app::aws:s3/bucket:Bucket::logs", "type": "aws:s3/bucket:Bucket", "outputs": {"versioning": false}},
+		{"urn": "urn:pulumi:dev::app::aws:iam/role:Role::app", "type": "aws:iam/role:Role", "outputs": {"name": "app"}}
+	]}}`)
+
+	drifts := stackDrifts(recorded, refreshed)
+	if len(drifts) != 2 {
+		t.Fatalf("expected 2 drifts, got %+v", drifts)
+	}
+	if drifts[0].ResourceType != "aws:s3/bucket:Bucket" || drifts[0].Diffs[0].Path != "versioning" {
+		t.Errorf("unexpected drift: %+v", drifts[0])
+	}
+	if drifts[1].ResourceType != "aws:sqs/queue:Queue" || drifts[1].Diffs[0].Path != iac.DriftPathExists {
+		t.Errorf("expected the removed queue to be reported, got %+v", drifts[1])
+	}
+}
+
 func TestPlugin_ParsePreviewOutput(t *testing.T) {
 	p := &Plugin{}
 
@@ -15,8 +15,13 @@ type Registry struct {
 }
 
 // DefaultRegistry is the global plugin registry.
-var DefaultRegistry = &Registry{
-	factories: make(map[string]Factory),
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry creates an empty plugin registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: make(map[string]Factory),
+	}
 }
 
 // Register adds a plugin factory to the registry.
@@ -39,6 +44,14 @@ func (r *Registry) Get(name string) (Plugin, error) {
 	return factory()
 }
 
+// Has reports whether a plugin with the given name is registered.
+func (r *Registry) Has(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.factories[name]
+	return ok
+}
+
 // List returns all registered plugin names.
 func (r *Registry) List() []string {
 	r.mu.RLock()
@@ -0,0 +1,107 @@
+package iac
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// MaxErrorOutput is the amount of trailing stderr plugins keep for error
+// messages.
+const MaxErrorOutput = 64 << 10
+
+// TailBuffer is an io.Writer that keeps only the last Max bytes written to
+// it. Plugins use it in place of bytes.Buffer to capture stderr for error
+// messages, so long-running commands with verbose output don't hold their
+// entire output in memory. It is safe for concurrent use.
+type TailBuffer struct {
+	// Max is the number of trailing bytes to retain.
+	Max int
+
+	mu        sync.Mutex
+	buf       []byte
+	truncated bool
+}
+
+// NewTailBuffer creates a TailBuffer that retains the last max bytes.
+func NewTailBuffer(max int) *TailBuffer {
+	return &TailBuffer{Max: max}
+}
+
+// Write appends p, discarding the oldest bytes once Max is exceeded.
+func (b *TailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(p) >= b.Max {
+		b.truncated = b.truncated || len(b.buf) > 0 || len(p) > b.Max
+		b.buf = append(b.buf[:0], p[len(p)-b.Max:]...)
+		return len(p), nil
+	}
+
+	b.buf = append(b.buf, p...)
+	// Compact only once the buffer has grown to twice its limit so the
+	// copy cost is amortized across writes.
+	if len(b.buf) > 2*b.Max {
+		n := copy(b.buf, b.buf[len(b.buf)-b.Max:])
+		b.buf = b.buf[:n]
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+// Bytes returns a copy of the retained output.
+func (b *TailBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data := b.buf
+	if len(data) > b.Max {
+		data = data[len(data)-b.Max:]
+	}
+	return append([]byte(nil), data...)
+}
+
+// Truncated reports whether earlier output has been discarded.
+func (b *TailBuffer) Truncated() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.truncated || len(b.buf) > b.Max
+}
+
+// String returns the retained output. When earlier output was discarded the
+// leading partial line is dropped so the result starts on a line boundary.
+func (b *TailBuffer) String() string {
+	data := b.Bytes()
+	if b.Truncated() {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	return string(data)
+}
+
+// RunCommand runs a plugin's tool and returns its stdout. Stdout is parsed
+// by callers and kept in full; stderr is only used for error messages, so
+// just its tail is retained, and included in the error if the command fails.
+// Output is also streamed to stdout and stderr when they're set.
+func RunCommand(cmd *exec.Cmd, stdout, stderr io.Writer) ([]byte, error) {
+	var out bytes.Buffer
+	errTail := NewTailBuffer(MaxErrorOutput)
+	cmd.Stdout = &out
+	cmd.Stderr = errTail
+	if stdout != nil {
+		cmd.Stdout = io.MultiWriter(&out, stdout)
+	}
+	if stderr != nil {
+		cmd.Stderr = io.MultiWriter(errTail, stderr)
+	}
+
+	if err := cmd.Run(); err != nil {
+		return out.Bytes(), fmt.Errorf("%w: %s", err, strings.TrimSpace(errTail.String()))
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,67 @@
+package iac
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestTailBuffer_KeepsEverythingUnderLimit(t *testing.T) {
+	b := NewTailBuffer(64)
+	_, _ = b.Write([]byte("line one\n"))
+	_, _ = b.Write([]byte("line two\n"))
+
+	if got := b.String(); got != "line one\nline two\n" {
+		t.Errorf("String() = %q", got)
+	}
+	if b.Truncated() {
+		t.Error("buffer under its limit should not be truncated")
+	}
+}
+
+func TestTailBuffer_KeepsOnlyTail(t *testing.T) {
+	b := NewTailBuffer(32)
+	for i := 0; i < 100; i++ {
+		_, _ = b.Write([]byte("0123456789\n"))
+	}
+	_, _ = b.Write([]byte("last line\n"))
+
+	if n := len(b.Bytes()); n != 32 {
+		t.Errorf("retained %d bytes, want 32", n)
+	}
+	if !b.Truncated() {
+		t.Error("expected buffer to be truncated")
+	}
+	got := b.String()
+	if !strings.HasSuffix(got, "last line\n") {
+		t.Errorf("String() = %q, want suffix %q", got, "last line\n")
+	}
+	if !strings.HasPrefix(got, "0123456789\n") {
+		t.Errorf("String() = %q, want it to start on a line boundary", got)
+	}
+}
+
+func TestTailBuffer_LargeWrite(t *testing.T) {
+	b := NewTailBuffer(8)
+	_, _ = b.Write([]byte(strings.Repeat("x", 100) + "tail"))
+
+	if got := string(b.Bytes()); got != "xxxxtail" {
+		t.Errorf("Bytes() = %q, want %q", got, "xxxxtail")
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	var streamed strings.Builder
+	out, err := RunCommand(exec.Command("sh", "-c", "echo out; echo err >&2"), &streamed, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(out) != "out\n" || streamed.String() != "out\n" {
+		t.Errorf("stdout = %q, streamed %q", out, streamed.String())
+	}
+
+	_, err = RunCommand(exec.Command("sh", "-c", "echo failed >&2; exit 3"), nil, nil)
+	if err == nil || !strings.HasSuffix(err.Error(), ": failed") {
+		t.Errorf("expected the error to end with stderr, got %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+// Package labels handles the key/value metadata attached to environments and
+// components, and the selectors used to pick them out for list and bulk
+// commands.
+//
+// Selectors follow the Kubernetes syntax: a comma-separated list of
+// requirements that must all hold, e.g.
+//
+//	team=payments,tier!=batch,region in (us-east-1,eu-west-1),!legacy
+package labels
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxKeyLength bounds label keys so they fit cloud provider tag limits.
+const maxKeyLength = 63
+
+// Parse parses key=value pairs, as passed to repeatable --label flags.
+func Parse(pairs []string) (map[string]string, error) {
+	labels := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid label %q: expected key=value", pair)
+		}
+		if err := ValidateKey(key); err != nil {
+			return nil, err
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// ValidateKey checks that a label key is 1-63 characters of letters, digits,
+// '-', '_', '.' and '/', starting with a letter or digit.
+func ValidateKey(key string) error {
+	if key == "" || len(key) > maxKeyLength {
+		return fmt.Errorf("invalid label key %q: must be 1-%d characters", key, maxKeyLength)
+	}
+	for i, r := range key {
+		alnum := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+		if i == 0 && !alnum {
+			return fmt.Errorf("invalid label key %q: must start with a letter or digit", key)
+		}
+		if !alnum && r != '-' && r != '_' && r != '.' && r != '/' {
+			return fmt.Errorf("invalid label key %q: unexpected character %q", key, r)
+		}
+	}
+	return nil
+}
+
+// Merge returns base overlaid with override, or nil when both are empty.
+// Component labels are merged over their environment's this way.
+func Merge(base, override map[string]string) map[string]string {
+	if len(base) == 0 && len(override) == 0 {
+		return nil
+	}
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
+
+// Format renders labels as sorted key=value pairs separated by commas.
+func Format(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Operator is the comparison a selector requirement makes.
+type Operator string
+
+const (
+	Equals       Operator = "="
+	NotEquals    Operator = "!="
+	In           Operator = "in"
+	NotIn        Operator = "notin"
+	Exists       Operator = "exists"
+	DoesNotExist Operator = "!"
+)
+
+// Requirement is a single condition of a selector.
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string
+}
+
+// Matches reports whether labels satisfy the requirement. As in Kubernetes,
+// != and notin also match labels that don't have the key at all.
+func (r Requirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	switch r.Operator {
+	case Exists:
+		return ok
+	case DoesNotExist:
+		return !ok
+	case Equals, In:
+		return ok && contains(r.Values, value)
+	case NotEquals, NotIn:
+		return !ok || !contains(r.Values, value)
+	}
+	return false
+}
+
+// Selector matches labels that satisfy all of its requirements. An empty
+// selector matches everything.
+type Selector []Requirement
+
+// Matches reports whether labels satisfy every requirement.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseSelector parses a selector expression. Supported requirements are
+// key=value (or key==value), key!=value, key in (a,b), key notin (a,b), key
+// (the label is set) and !key (the label isn't set).
+func ParseSelector(s string) (Selector, error) {
+	terms, err := splitTerms(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var selector Selector
+	for _, term := range terms {
+		r, err := parseRequirement(term)
+		if err != nil {
+			return nil, err
+		}
+		selector = append(selector, r)
+	}
+	return selector, nil
+}
+
+// splitTerms splits a selector on the commas that aren't inside a value set.
+func splitTerms(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	var terms []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("invalid selector %q: unbalanced parentheses", s)
+			}
+		case ',':
+			if depth == 0 {
+				terms = append(terms, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("invalid selector %q: unbalanced parentheses", s)
+	}
+	return append(terms, s[start:]), nil
+}
+
+func parseRequirement(term string) (Requirement, error) {
+	term = strings.TrimSpace(term)
+	invalid := func(reason string) (Requirement, error) {
+		return Requirement{}, fmt.Errorf("invalid selector requirement %q: %s", term, reason)
+	}
+	if term == "" {
+		return invalid("empty requirement")
+	}
+
+	var r Requirement
+	switch {
+	case strings.HasPrefix(term, "!") && !strings.Contains(term, "="):
+		r = Requirement{Key: strings.TrimSpace(term[1:]), Operator: DoesNotExist}
+	case strings.Contains(term, "!="):
+		key, value, _ := strings.Cut(term, "!=")
+		r = Requirement{Key: strings.TrimSpace(key), Operator: NotEquals, Values: []string{strings.TrimSpace(value)}}
+	case strings.Contains(term, "="):
+		key, value, _ := strings.Cut(term, "=")
+		value = strings.TrimPrefix(value, "=")
+		r = Requirement{Key: strings.TrimSpace(key), Operator: Equals, Values: []string{strings.TrimSpace(value)}}
+	case strings.Contains(term, "("):
+		fields := strings.Fields(term[:strings.Index(term, "(")])
+		if len(fields) != 2 || (fields[1] != string(In) && fields[1] != string(NotIn)) {
+			return invalid("expected key in (...) or key notin (...)")
+		}
+		if !strings.HasSuffix(term, ")") {
+			return invalid("expected a closing parenthesis")
+		}
+		r = Requirement{Key: fields[0], Operator: Operator(fields[1])}
+		for _, value := range strings.Split(term[strings.Index(term, "(")+1:len(term)-1], ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				r.Values = append(r.Values, value)
+			}
+		}
+		if len(r.Values) == 0 {
+			return invalid("expected at least one value")
+		}
+	default:
+		r = Requirement{Key: term, Operator: Exists}
+	}
+
+	if err := ValidateKey(r.Key); err != nil {
+		return invalid(err.Error())
+	}
+	return r, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
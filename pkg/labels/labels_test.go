@@ -0,0 +1,80 @@
+package labels
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	labels, err := Parse([]string{"team=payments", "tier=", "cost-center/id=42"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(labels) != 3 || labels["team"] != "payments" || labels["tier"] != "" || labels["cost-center/id"] != "42" {
+		t.Errorf("unexpected labels: %v", labels)
+	}
+
+	for _, invalid := range []string{"team", "=payments", "-team=x", "te am=x"} {
+		if _, err := Parse([]string{invalid}); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	merged := Merge(map[string]string{"team": "payments", "tier": "web"}, map[string]string{"tier": "worker"})
+	if Format(merged) != "team=payments,tier=worker" {
+		t.Errorf("unexpected merge: %v", merged)
+	}
+	if Merge(nil, map[string]string{}) != nil {
+		t.Error("expected nil when merging empty labels")
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	labels := map[string]string{"team": "payments", "region": "us-east-1", "tier": "web"}
+
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"team=payments", true},
+		{"team==payments", true},
+		{"team=search", false},
+		{"team!=search", true},
+		{"owner!=alice", true},
+		{"region in (us-east-1, eu-west-1)", true},
+		{"region notin (us-east-1,eu-west-1)", false},
+		{"team=payments,region in (eu-west-1)", false},
+		{"tier", true},
+		{"owner", false},
+		{"!owner", true},
+		{"!tier", false},
+		{" team = payments , !legacy ", true},
+	}
+	for _, tt := range tests {
+		sel, err := ParseSelector(tt.selector)
+		if err != nil {
+			t.Errorf("ParseSelector(%q): unexpected error: %v", tt.selector, err)
+			continue
+		}
+		if got := sel.Matches(labels); got != tt.want {
+			t.Errorf("ParseSelector(%q).Matches = %v, want %v", tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestParseSelector_Invalid(t *testing.T) {
+	for _, selector := range []string{
+		"team=payments,",
+		"=payments",
+		"region in (a,b",
+		"region in ()",
+		"region within (a)",
+		"region in (a))",
+	} {
+		if _, err := ParseSelector(selector); err == nil {
+			t.Errorf("expected error for selector %q", selector)
+		}
+	}
+}
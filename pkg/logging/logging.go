@@ -0,0 +1,207 @@
+// Package logging is cldctl's diagnostic logger. Engine, executor, and
+// plugin code logs through the logger of its subsystem, and the CLI decides
+// which levels and subsystems are written, and where.
+//
+// Nothing below warning level is written by default. Logs go to stderr
+// unless an output file is configured, which keeps them from interleaving
+// with progress output.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Subsystems that log.
+const (
+	Engine      = "engine"
+	Executor    = "executor"
+	Expressions = "expressions"
+	Plugins     = "plugins"
+	Build       = "build"
+)
+
+// All selects every subsystem in Options.Debug.
+const All = "all"
+
+// Subsystems are the subsystems that can be selected for debug logging.
+var Subsystems = []string{Build, Engine, Executor, Expressions, Plugins}
+
+// Options configures logging.
+type Options struct {
+	// Level is the lowest level logged by every subsystem. Defaults to
+	// slog.LevelWarn.
+	Level *slog.Level
+
+	// Debug lists subsystems logged at debug level whatever Level is, or
+	// All for every subsystem
+	Debug []string
+
+	// Output receives the logs. Defaults to stderr.
+	Output io.Writer
+}
+
+type config struct {
+	level   slog.Level
+	debug   map[string]bool
+	out     io.Writer
+	handler slog.Handler
+}
+
+var (
+	mu  sync.RWMutex
+	cfg = newConfig(slog.LevelWarn, nil, os.Stderr)
+)
+
+func newConfig(level slog.Level, debug map[string]bool, out io.Writer) *config {
+	return &config{
+		level: level,
+		debug: debug,
+		out:   out,
+		handler: slog.NewTextHandler(out, &slog.HandlerOptions{
+			// Levels are filtered per subsystem by subsystemHandler
+			Level: slog.LevelDebug,
+		}),
+	}
+}
+
+// Configure sets the levels, subsystems, and output of logging.
+func Configure(opts Options) error {
+	level := slog.LevelWarn
+	if opts.Level != nil {
+		level = *opts.Level
+	}
+
+	debug := make(map[string]bool)
+	for _, name := range opts.Debug {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if name == All {
+			for _, s := range Subsystems {
+				debug[s] = true
+			}
+			continue
+		}
+		if !isSubsystem(name) {
+			return fmt.Errorf("unknown log subsystem %q (valid: %s, %s)", name, strings.Join(Subsystems, ", "), All)
+		}
+		debug[name] = true
+	}
+
+	out := opts.Output
+	if out == nil {
+		out = os.Stderr
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	cfg = newConfig(level, debug, out)
+	return nil
+}
+
+// ParseLevel parses a level name: debug, info, warn, or error.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q (valid: debug, info, warn, error)", s)
+	}
+	return level, nil
+}
+
+// Logger returns the logger of a subsystem. It can be created before
+// logging is configured: each record is checked against the configuration
+// current when it's logged.
+func Logger(subsystem string) *slog.Logger {
+	return slog.New(&subsystemHandler{subsystem: subsystem})
+}
+
+// Enabled reports whether a subsystem logs at a level, for callers that
+// would otherwise do work to build a message nobody sees.
+func Enabled(subsystem string, level slog.Level) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return cfg.enabled(subsystem, level)
+}
+
+// WritesToStderr reports whether logs below warning level are written to
+// stderr, where they'd interleave with redrawn progress output.
+func WritesToStderr() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	if cfg.out != os.Stderr {
+		return false
+	}
+	return cfg.level < slog.LevelWarn || len(cfg.debug) > 0
+}
+
+// Writer returns a writer for a subsystem's raw debug output, such as build
+// logs, or nil if the subsystem doesn't log at debug level.
+func Writer(subsystem string) io.Writer {
+	mu.RLock()
+	defer mu.RUnlock()
+	if !cfg.enabled(subsystem, slog.LevelDebug) {
+		return nil
+	}
+	return cfg.out
+}
+
+func (c *config) enabled(subsystem string, level slog.Level) bool {
+	if c.debug[subsystem] {
+		return true
+	}
+	return level >= c.level
+}
+
+func isSubsystem(name string) bool {
+	i := sort.SearchStrings(Subsystems, name)
+	return i < len(Subsystems) && Subsystems[i] == name
+}
+
+// subsystemHandler filters a subsystem's records by the current
+// configuration and writes them with its handler.
+type subsystemHandler struct {
+	subsystem string
+
+	// with holds the WithAttrs and WithGroup calls made on the handler, in
+	// order, to apply to the configured handler
+	with []func(slog.Handler) slog.Handler
+}
+
+func (h *subsystemHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return Enabled(h.subsystem, level)
+}
+
+func (h *subsystemHandler) Handle(ctx context.Context, r slog.Record) error {
+	mu.RLock()
+	handler := cfg.handler
+	mu.RUnlock()
+
+	handler = handler.WithAttrs([]slog.Attr{slog.String("subsystem", h.subsystem)})
+	for _, with := range h.with {
+		handler = with(handler)
+	}
+	return handler.Handle(ctx, r)
+}
+
+func (h *subsystemHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.extend(func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) })
+}
+
+func (h *subsystemHandler) WithGroup(name string) slog.Handler {
+	return h.extend(func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) })
+}
+
+func (h *subsystemHandler) extend(with func(slog.Handler) slog.Handler) slog.Handler {
+	return &subsystemHandler{
+		subsystem: h.subsystem,
+		with:      append(append([]func(slog.Handler) slog.Handler(nil), h.with...), with),
+	}
+}
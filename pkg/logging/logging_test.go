@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestConfigure_SubsystemFilter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Configure(Options{Debug: []string{Executor}, Output: &buf}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = Configure(Options{}) }()
+
+	Logger(Executor).Debug("launching node", "node", "app/deployment/api")
+	Logger(Expressions).Debug("unresolved expression")
+	Logger(Expressions).Warn("slow resolution")
+
+	out := buf.String()
+	if !strings.Contains(out, "subsystem=executor") || !strings.Contains(out, "node=app/deployment/api") {
+		t.Errorf("expected the executor's debug record, got %q", out)
+	}
+	if strings.Contains(out, "unresolved expression") {
+		t.Errorf("expected other subsystems' debug records to be left out, got %q", out)
+	}
+	if !strings.Contains(out, "slow resolution") {
+		t.Errorf("expected warnings from every subsystem, got %q", out)
+	}
+	if Writer(Executor) != &buf || Writer(Plugins) != nil {
+		t.Error("expected a raw writer only for subsystems logging at debug level")
+	}
+}
+
+func TestConfigure_Level(t *testing.T) {
+	var buf bytes.Buffer
+	level := slog.LevelInfo
+	if err := Configure(Options{Level: &level, Output: &buf}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = Configure(Options{}) }()
+
+	Logger(Engine).Info("executing plan")
+	Logger(Engine).Debug("details")
+	if out := buf.String(); !strings.Contains(out, "executing plan") || strings.Contains(out, "details") {
+		t.Errorf("expected only info records, got %q", out)
+	}
+	if WritesToStderr() {
+		t.Error("logs written to a file shouldn't count as stderr output")
+	}
+}
+
+func TestConfigure_Stderr(t *testing.T) {
+	defer func() { _ = Configure(Options{}) }()
+
+	if err := Configure(Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if WritesToStderr() {
+		t.Error("expected only warnings by default")
+	}
+	if err := Configure(Options{Debug: []string{All}, Output: os.Stderr}); err != nil {
+		t.Fatal(err)
+	}
+	if !WritesToStderr() || !Enabled(Plugins, slog.LevelDebug) {
+		t.Error("expected debug logs of every subsystem on stderr")
+	}
+}
+
+func TestConfigure_UnknownSubsystem(t *testing.T) {
+	if err := Configure(Options{Debug: []string{"executr"}}); err == nil {
+		t.Error("expected an error for an unknown subsystem")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	if level, err := ParseLevel("debug"); err != nil || level != slog.LevelDebug {
+		t.Errorf("ParseLevel(debug) = %v, %v", level, err)
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
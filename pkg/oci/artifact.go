@@ -12,6 +12,7 @@ const (
 	ArtifactTypeComponent  ArtifactType = "component"
 	ArtifactTypeDatacenter ArtifactType = "datacenter"
 	ArtifactTypeModule     ArtifactType = "module"
+	ArtifactTypeCatalog    ArtifactType = "catalog"
 )
 
 // MediaTypes for cldctl artifacts.
@@ -22,6 +23,8 @@ const (
 	MediaTypeDatacenterLayer  = "application/vnd.architect.datacenter.layer.v1.tar+gzip"
 	MediaTypeModuleConfig     = "application/vnd.architect.module.config.v1+json"
 	MediaTypeModuleLayer      = "application/vnd.architect.module.layer.v1.tar+gzip"
+	MediaTypeCatalogConfig    = "application/vnd.architect.catalog.config.v1+json"
+	MediaTypeCatalogLayer     = "application/vnd.architect.catalog.layer.v1.tar+gzip"
 )
 
 // Artifact represents an OCI artifact.
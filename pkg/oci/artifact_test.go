@@ -219,6 +219,9 @@ func TestArtifactTypes(t *testing.T) {
 	if ArtifactTypeModule != "module" {
 		t.Errorf("ArtifactTypeModule: got %q, want %q", ArtifactTypeModule, "module")
 	}
+	if ArtifactTypeCatalog != "catalog" {
+		t.Errorf("ArtifactTypeCatalog: got %q, want %q", ArtifactTypeCatalog, "catalog")
+	}
 }
 
 func TestMediaTypes(t *testing.T) {
@@ -235,6 +238,8 @@ func TestMediaTypes(t *testing.T) {
 		{"DatacenterLayer", MediaTypeDatacenterLayer},
 		{"ModuleConfig", MediaTypeModuleConfig},
 		{"ModuleLayer", MediaTypeModuleLayer},
+		{"CatalogConfig", MediaTypeCatalogConfig},
+		{"CatalogLayer", MediaTypeCatalogLayer},
 	}
 
 	for _, mt := range mediaTypes {
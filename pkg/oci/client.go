@@ -52,6 +52,8 @@ func (c *Client) Push(ctx context.Context, artifact *Artifact) error {
 		layerMediaType = MediaTypeDatacenterLayer
 	case ArtifactTypeModule:
 		layerMediaType = MediaTypeModuleLayer
+	case ArtifactTypeCatalog:
+		layerMediaType = MediaTypeCatalogLayer
 	}
 
 	// Add layers
@@ -128,6 +130,21 @@ func (c *Client) PullConfig(ctx context.Context, reference string) ([]byte, erro
 	return json.Marshal(configFile)
 }
 
+// Digest returns the digest of the manifest a reference currently points to,
+// without pulling the artifact.
+func (c *Client) Digest(ctx context.Context, reference string) (string, error) {
+	ref, err := name.ParseReference(reference)
+	if err != nil {
+		return "", fmt.Errorf("invalid reference: %w", err)
+	}
+
+	desc, err := remote.Head(ref, remote.WithAuthFromKeychain(c.auth), remote.WithContext(ctx))
+	if err != nil {
+		return "", registryError(reference, err)
+	}
+	return desc.Digest.String(), nil
+}
+
 // Exists checks if an artifact exists in the registry.
 func (c *Client) Exists(ctx context.Context, reference string) (bool, error) {
 	ref, err := name.ParseReference(reference)
@@ -19,6 +19,7 @@ type Component interface {
 	Buckets() []Bucket
 	EncryptionKeys() []EncryptionKey
 	SMTP() []SMTPConnection
+	External() []ExternalService
 	Ports() []Port
 	Deployments() []Deployment
 	Functions() []Function
@@ -26,14 +27,30 @@ type Component interface {
 	Routes() []Route
 	Cronjobs() []Cronjob
 
+	// Smoke tests run by `cldctl test component`
+	Tests() []Test
+
+	// Webhooks called when the component is deployed or destroyed
+	Webhooks() []Webhook
+
 	// Observability
 	Observability() Observability
 
+	// Datacenter environment injection opt-outs
+	Injection() Injection
+
+	// Datacenter capabilities the component needs (e.g., "postgres", "cronjobs")
+	Requires() []string
+
 	// Configuration
 	Variables() []Variable
 	Dependencies() []Dependency
 	Outputs() []Output
 
+	// Default instances (progressive delivery)
+	Instances() []Instance
+	Distinct() []string
+
 	// Version information
 	SchemaVersion() string
 
@@ -64,6 +81,7 @@ type Database interface {
 	Type() string
 	Version() string
 	Migrations() Migrations
+	Readiness() Readiness // nil when the database doesn't declare one
 }
 
 // Migrations represents database migration configuration.
@@ -108,6 +126,14 @@ type SMTPConnection interface {
 	Description() string
 }
 
+// ExternalService represents a third-party service the component calls.
+type ExternalService interface {
+	Name() string
+	URL() string
+	Description() string
+	Credentials() map[string]string // Credential name to ${{ variables.<name> }} expression
+}
+
 // Port represents a dynamic port allocation request.
 // Ports are allocated by the engine or a datacenter hook and can be referenced
 // in environment variables and service ports via ${{ ports.<name>.port }}.
@@ -126,15 +152,48 @@ type Deployment interface {
 	Command() []string
 	Entrypoint() []string
 	Environment() map[string]string
+	EnvFrom() []EnvFile
 	WorkingDirectory() string
 	CPU() string
 	Memory() string
 	Replicas() int
+	Strategy() Strategy   // nil when the deployment doesn't declare one
+	Lifecycle() Lifecycle // nil when the deployment doesn't declare one
 	Volumes() []Volume
+	Files() []File
+	Readiness() Readiness // nil when the deployment doesn't declare one
 	LivenessProbe() Probe
 	ReadinessProbe() Probe
 }
 
+// EnvFile is a .env file, bundled with the component, that a workload takes
+// environment variables from. Its values may contain expressions. Variables
+// set in the workload's environment block take precedence over the file.
+type EnvFile interface {
+	Path() string // Relative to the component directory
+	// Environment returns the variables defined in the file. It is empty
+	// when the component was loaded from bytes rather than from disk.
+	Environment() map[string]string
+}
+
+// Strategy describes how a deployment's replicas are replaced when it changes.
+type Strategy interface {
+	Type() string           // rolling or recreate
+	MaxSurge() string       // Count ("1") or percentage ("25%"); empty when unset
+	MaxUnavailable() string // Count ("0") or percentage ("25%"); empty when unset
+	MinReadySeconds() int
+}
+
+// Lifecycle configures the hooks run as a deployment's containers start and
+// stop, and how long they are given to shut down.
+type Lifecycle interface {
+	PostStart() []string // Run in the container once it has started
+	PreStop() []string   // Run in the container before it is sent SIGTERM
+	// TerminationGracePeriodSeconds is the time between SIGTERM and kill. It
+	// is 0 when the datacenter's default applies.
+	TerminationGracePeriodSeconds() int
+}
+
 // Runtime describes the runtime environment for a deployment.
 // When present without an image, the datacenter can provision a VM or managed runtime.
 type Runtime interface {
@@ -159,9 +218,11 @@ type Function interface {
 	// or an expression ("${{ ports.web.port }}"). Returns "" if not set.
 	Port() string
 	Environment() map[string]string
+	EnvFrom() []EnvFile
 	CPU() string
 	Memory() string
 	Timeout() int
+	Readiness() Readiness // nil when the function doesn't declare one
 
 	// IsSourceBased returns true if this is a source-based function
 	IsSourceBased() bool
@@ -201,6 +262,19 @@ type Service interface {
 	URL() string
 	Port() string
 	Protocol() string
+	Readiness() Readiness // nil when the service doesn't declare one
+}
+
+// Readiness is a readiness gate: a check run once a resource has been
+// deployed, which must pass before the resources that depend on it are
+// deployed. Exactly one of HTTP, TCP, and Command is set, and they may
+// contain expressions.
+type Readiness interface {
+	HTTP() string      // URL that must respond with a 2xx status
+	TCP() string       // host:port that must accept connections
+	Command() []string // Command that must exit successfully
+	Interval() string  // Time between checks; empty for the default
+	Timeout() string   // How long the check has to pass; empty for the default
 }
 
 // Route represents external traffic routing.
@@ -211,6 +285,18 @@ type Route interface {
 	Rules() []RouteRule
 	Service() string
 	Function() string
+
+	// Auth returns the route's authentication requirement, or nil when the
+	// route is public.
+	Auth() RouteAuth
+}
+
+// RouteAuth represents authentication required on a route. The datacenter's
+// routeAuth hook provisions a proxy that authenticates requests and forwards
+// the caller's identity to the upstream.
+type RouteAuth interface {
+	Provider() string
+	Config() map[string]interface{}
 }
 
 // RouteRule represents a routing rule.
@@ -282,6 +368,13 @@ type Observability interface {
 	Attributes() map[string]string // Custom OTel resource attributes
 }
 
+// Injection describes which datacenter environment injections a component
+// opts out of. Returns nil if the component does not configure injection.
+type Injection interface {
+	Disabled() bool    // Opt out of all injections
+	Exclude() []string // Names of injections to skip
+}
+
 // Cronjob represents a scheduled task.
 type Cronjob interface {
 	Name() string
@@ -294,13 +387,40 @@ type Cronjob interface {
 	Memory() string
 }
 
+// Test represents a smoke test run against a deployed component.
+type Test interface {
+	Name() string
+	Command() []string
+	Environment() map[string]string
+	Timeout() string
+}
+
+// Webhook represents a webhook called on component lifecycle events. URL
+// and Secret are unresolved expressions.
+type Webhook interface {
+	Name() string
+	URL() string
+	Events() []string
+	Secret() string
+	Timeout() string
+}
+
 // Variable represents a configurable input.
 type Variable interface {
 	Name() string
+	Type() string
 	Description() string
 	Default() interface{}
 	Required() bool
 	Sensitive() bool
+	Enum() []interface{}
+	Pattern() string
+}
+
+// Instance is a default weighted instance declared by the component.
+type Instance interface {
+	Name() string
+	Weight() int
 }
 
 // Dependency represents a dependency on another component.
@@ -330,6 +450,16 @@ type Volume interface {
 	ReadOnly() bool
 }
 
+// File is a file mounted into a deployment's containers, such as a config
+// file the application reads at startup. Its content may contain
+// expressions.
+type File interface {
+	MountPath() string
+	Content() string
+	Source() string // The bundled file the content was read from, if any
+	Mode() string   // Octal permissions, e.g. "0600"; empty for the default
+}
+
 // Probe represents a health check probe.
 // Port() and TCPPort() return interface{} to support both integer literals
 // and expression strings (${{ ports.*.port }}).
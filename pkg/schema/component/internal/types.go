@@ -16,6 +16,7 @@ type InternalComponent struct {
 	Buckets        []InternalBucket
 	EncryptionKeys []InternalEncryptionKey
 	SMTP           []InternalSMTP
+	External       []InternalExternal
 	Ports          []InternalPort
 	Deployments    []InternalDeployment
 	Functions      []InternalFunction
@@ -23,14 +24,31 @@ type InternalComponent struct {
 	Routes         []InternalRoute
 	Cronjobs       []InternalCronjob
 
+	// Smoke tests run by `cldctl test component`
+	Tests []InternalTest
+
+	// Webhooks called when the component is deployed or destroyed
+	Webhooks []InternalWebhook
+
 	// Observability
 	Observability *InternalObservability
 
+	// Datacenter environment injection opt-outs
+	Injection *InternalInjection
+
+	// Datacenter capabilities the component needs (e.g., "postgres", "cronjobs")
+	Requires []string
+
 	// Configuration
 	Variables    []InternalVariable
 	Dependencies []InternalDependency
 	Outputs      []InternalOutput
 
+	// Default weighted instances, used when the environment does not
+	// configure instances for the component
+	Instances []InternalInstance
+	Distinct  []string
+
 	// Source information
 	SourceVersion string // Which schema version this came from
 	SourcePath    string // Original file path
@@ -46,6 +64,13 @@ type InternalObservability struct {
 	Attributes map[string]string // Custom OTel resource attributes
 }
 
+// InternalInjection records which datacenter environment injections a
+// component opts out of. A nil value means every matching injection applies.
+type InternalInjection struct {
+	Disabled bool     // Opt out of all injections
+	Exclude  []string // Names of injections to skip
+}
+
 // InternalComponentBuild represents a top-level named Docker build configuration.
 // Deployments reference the built image via ${{ builds.<name>.image }}.
 type InternalComponentBuild struct {
@@ -62,6 +87,7 @@ type InternalDatabase struct {
 	Type       string              // e.g., "postgres"
 	Version    string              // e.g., "^15" (semver constraint)
 	Migrations *InternalMigrations // Optional
+	Readiness  *InternalReadiness  // Optional
 }
 
 // InternalMigrations represents database migration configuration.
@@ -112,6 +138,16 @@ type InternalSMTP struct {
 	Description string // Optional description
 }
 
+// InternalExternal represents a third-party service the component calls.
+// Workloads reference it via ${{ external.<name>.<output> }}, where outputs
+// are url, host, port, protocol, and each credential name.
+type InternalExternal struct {
+	Name        string
+	URL         string            // Base URL of the service
+	Description string            // Optional description
+	Credentials map[string]string // Credential name to ${{ variables.<name> }} expression
+}
+
 // InternalPort represents a dynamic port allocation request.
 // The engine (or datacenter hook) allocates a port number and exposes it
 // via ${{ ports.<name>.port }} expressions.
@@ -136,6 +172,7 @@ type InternalDeployment struct {
 	Command     []string
 	Entrypoint  []string
 	Environment map[string]Expression // Values may contain expressions
+	EnvFrom     []InternalEnvFile     // Templated .env files, merged under Environment
 
 	// Process configuration
 	WorkingDirectory string // Working directory for process-based execution (defaults to component dir)
@@ -144,14 +181,45 @@ type InternalDeployment struct {
 	CPU      string
 	Memory   string
 	Replicas int
+	Strategy *InternalStrategy
+
+	// Startup and shutdown
+	Lifecycle *InternalLifecycle
 
 	// Advanced configuration
 	Volumes        []InternalVolume
+	Files          []InternalFile
 	LivenessProbe  *InternalProbe
 	ReadinessProbe *InternalProbe
+	Readiness      *InternalReadiness
 	Labels         map[string]string
 }
 
+// InternalEnvFile is a .env file a workload takes environment variables
+// from. Environment is filled in by the loader, which reads the file relative
+// to the component; it is nil when the component was loaded from bytes.
+type InternalEnvFile struct {
+	Path        string
+	Environment map[string]Expression
+}
+
+// InternalStrategy describes how a deployment's replicas are replaced when it
+// changes.
+type InternalStrategy struct {
+	Type            string // rolling or recreate
+	MaxSurge        string // Count ("1") or percentage ("25%"); empty when unset
+	MaxUnavailable  string // Count ("0") or percentage ("25%"); empty when unset
+	MinReadySeconds int
+}
+
+// InternalLifecycle configures the hooks run as a deployment's containers
+// start and stop, and how long they are given to shut down.
+type InternalLifecycle struct {
+	PostStart                     []string // Run in the container once it has started
+	PreStop                       []string // Run in the container before it is sent SIGTERM
+	TerminationGracePeriodSeconds int      // Time between SIGTERM and kill; 0 uses the datacenter's default
+}
+
 // InternalRuntime describes the runtime environment for a deployment.
 // When present without an image, the datacenter can provision a VM or managed runtime.
 type InternalRuntime struct {
@@ -174,9 +242,11 @@ type InternalFunction struct {
 	// Common configuration
 	Port        Expression // Port number or expression (e.g., "3000" or "${{ ports.web.port }}")
 	Environment map[string]Expression
+	EnvFrom     []InternalEnvFile // Templated .env files, merged under Environment
 	CPU         string
 	Memory      string
 	Timeout     int // seconds
+	Readiness   *InternalReadiness
 }
 
 // InternalFunctionSource represents a source-based function.
@@ -211,8 +281,9 @@ type InternalService struct {
 	URL        string // External URL (virtual service)
 
 	// Configuration
-	Port     Expression // Port number or expression (e.g., "8080" or "${{ ports.api.port }}")
-	Protocol string     // http, https, tcp, grpc
+	Port      Expression // Port number or expression (e.g., "8080" or "${{ ports.api.port }}")
+	Protocol  string     // http, https, tcp, grpc
+	Readiness *InternalReadiness
 }
 
 // InternalRoute represents external traffic routing configuration.
@@ -227,6 +298,15 @@ type InternalRoute struct {
 	// Simplified form (alternative to Rules)
 	Service  string // Direct service reference
 	Function string // Direct function reference
+
+	// Auth is set when requests must be authenticated before reaching the upstream
+	Auth *InternalRouteAuth
+}
+
+// InternalRouteAuth represents authentication required on a route.
+type InternalRouteAuth struct {
+	Provider string                 // Identity provider hint (optional)
+	Config   map[string]interface{} // Provider-specific settings (optional)
 }
 
 // InternalRouteRule represents a routing rule.
@@ -358,13 +438,47 @@ type InternalCronjob struct {
 	Memory string
 }
 
+// InternalTest represents a smoke test run against a deployed component.
+type InternalTest struct {
+	Name string
+
+	// Command runs on the machine running cldctl
+	Command     []string
+	Environment map[string]Expression
+
+	// Timeout is a Go duration string; empty for the default
+	Timeout string
+}
+
+// InternalWebhook represents a webhook called on component lifecycle events.
+type InternalWebhook struct {
+	Name   string
+	URL    Expression
+	Events []string // deploy, destroy
+
+	// Secret signs the payload; empty to send it unsigned
+	Secret Expression
+
+	// Timeout is a Go duration string; empty for the default
+	Timeout string
+}
+
 // InternalVariable represents a configurable input.
 type InternalVariable struct {
 	Name        string
+	Type        string // string, number, bool, list, or object; empty when untyped
 	Description string
 	Default     interface{}
 	Required    bool
 	Sensitive   bool
+	Enum        []interface{} // Allowed values; empty allows any
+	Pattern     string        // Regular expression string values must match
+}
+
+// InternalInstance represents a default weighted instance of a component.
+type InternalInstance struct {
+	Name   string
+	Weight int
 }
 
 // InternalDependency represents a dependency on another component.
@@ -385,6 +499,17 @@ type InternalOutput struct {
 	Sensitive   bool
 }
 
+// InternalReadiness is a check run once a resource has been deployed, which
+// must pass before the resources that depend on it are deployed. Exactly one
+// of HTTP, TCP, and Command is set.
+type InternalReadiness struct {
+	HTTP     string   // URL that must respond with a 2xx status
+	TCP      string   // host:port that must accept connections
+	Command  []string // Command that must exit successfully
+	Interval string   // Time between checks; empty for the default
+	Timeout  string   // How long the check has to pass; empty for the default
+}
+
 // InternalVolume represents a volume mount.
 type InternalVolume struct {
 	MountPath string
@@ -393,6 +518,16 @@ type InternalVolume struct {
 	ReadOnly  bool
 }
 
+// InternalFile is a file mounted into a deployment's containers. When it
+// has a Source, Content is filled in by the loader, which reads the file
+// relative to the component.
+type InternalFile struct {
+	MountPath string
+	Content   Expression
+	Source    string
+	Mode      string
+}
+
 // InternalProbe represents a health check probe.
 // Port and TCPPort are interface{} to support both integer literals and
 // expression strings (${{ ports.*.port }}).
@@ -6,9 +6,11 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/davidthor/cldctl/pkg/envfile"
 	"github.com/davidthor/cldctl/pkg/errors"
 	"github.com/davidthor/cldctl/pkg/schema/component/internal"
 	"github.com/davidthor/cldctl/pkg/schema/component/v1"
+	"github.com/davidthor/cldctl/pkg/schema/component/v2"
 	"gopkg.in/yaml.v3"
 )
 
@@ -23,25 +25,25 @@ type Transformer interface {
 }
 
 // versionDetectingLoader implements the Loader interface with automatic version detection.
+// v1 documents are parsed and validated as v1, then upgraded to v2 in memory,
+// so every version is transformed by the v2 transformer.
 type versionDetectingLoader struct {
-	parsers        map[string]*v1.Parser
-	validators     map[string]*v1.Validator
-	transformers   map[string]*v1.Transformer
+	v1Parser       *v1.Parser
+	v1Validator    *v1.Validator
+	v2Parser       *v2.Parser
+	v2Validator    *v2.Validator
+	transformer    *v2.Transformer
 	defaultVersion string
 }
 
 // NewLoader creates a new component loader that auto-detects schema version.
 func NewLoader() Loader {
 	return &versionDetectingLoader{
-		parsers: map[string]*v1.Parser{
-			"v1": v1.NewParser(),
-		},
-		validators: map[string]*v1.Validator{
-			"v1": v1.NewValidator(),
-		},
-		transformers: map[string]*v1.Transformer{
-			"v1": v1.NewTransformer(),
-		},
+		v1Parser:       v1.NewParser(),
+		v1Validator:    v1.NewValidator(),
+		v2Parser:       v2.NewParser(),
+		v2Validator:    v2.NewValidator(),
+		transformer:    v2.NewTransformer(),
 		defaultVersion: "v1",
 	}
 }
@@ -71,8 +73,18 @@ func (l *versionDetectingLoader) Load(path string) (Component, error) {
 	// Set source path on internal component
 	comp.Internal().SourcePath = path
 
-	// Try to load README from the same directory
+	// Read the env files workloads take variables from
 	dir := filepath.Dir(path)
+	if err := l.loadEnvFiles(comp.Internal(), dir); err != nil {
+		return nil, err
+	}
+
+	// Read the content of files deployments mount from the component
+	if err := l.loadFileSources(comp.Internal(), dir); err != nil {
+		return nil, err
+	}
+
+	// Try to load README from the same directory
 	readme := l.loadReadme(dir)
 	if readme != "" {
 		comp.Internal().Readme = readme
@@ -81,6 +93,68 @@ func (l *versionDetectingLoader) Load(path string) (Component, error) {
 	return comp, nil
 }
 
+// loadEnvFiles reads the envFrom files of every deployment and function,
+// relative to the component directory.
+func (l *versionDetectingLoader) loadEnvFiles(ic *internal.InternalComponent, dir string) error {
+	cache := make(map[string]map[string]internal.Expression)
+	load := func(workload string, files []internal.InternalEnvFile) error {
+		for i := range files {
+			f := &files[i]
+			if env, ok := cache[f.Path]; ok {
+				f.Environment = env
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir, f.Path))
+			if err != nil {
+				return errors.Wrap(errors.ErrCodeParse, fmt.Sprintf("%s: failed to read env file %s", workload, f.Path), err)
+			}
+			vars, err := envfile.Parse(data)
+			if err != nil {
+				return errors.Wrap(errors.ErrCodeParse, fmt.Sprintf("%s: failed to parse env file %s", workload, f.Path), err)
+			}
+			env := make(map[string]internal.Expression, len(vars))
+			for k, v := range vars {
+				env[k] = internal.NewExpression(v)
+			}
+			cache[f.Path] = env
+			f.Environment = env
+		}
+		return nil
+	}
+
+	for i := range ic.Deployments {
+		if err := load("deployments."+ic.Deployments[i].Name, ic.Deployments[i].EnvFrom); err != nil {
+			return err
+		}
+	}
+	for i := range ic.Functions {
+		if err := load("functions."+ic.Functions[i].Name, ic.Functions[i].EnvFrom); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadFileSources reads the content of deployment files that have a source,
+// relative to the component directory.
+func (l *versionDetectingLoader) loadFileSources(ic *internal.InternalComponent, dir string) error {
+	for i := range ic.Deployments {
+		dep := &ic.Deployments[i]
+		for j := range dep.Files {
+			f := &dep.Files[j]
+			if f.Source == "" {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir, f.Source))
+			if err != nil {
+				return errors.Wrap(errors.ErrCodeParse, fmt.Sprintf("deployments.%s: failed to read file %s", dep.Name, f.Source), err)
+			}
+			f.Content = internal.NewExpression(string(data))
+		}
+	}
+	return nil
+}
+
 // loadReadme attempts to load a README file from the given directory.
 // It checks for README.md, README.MD, readme.md, and README (in that order).
 func (l *versionDetectingLoader) loadReadme(dir string) string {
@@ -97,55 +171,18 @@ func (l *versionDetectingLoader) loadReadme(dir string) string {
 
 // LoadFromBytes parses a component from raw bytes.
 func (l *versionDetectingLoader) LoadFromBytes(data []byte, sourcePath string) (Component, error) {
-	// Detect version
-	version, err := l.detectVersion(data)
-	if err != nil {
-		return nil, errors.Wrap(errors.ErrCodeParse, "failed to detect schema version", err)
-	}
-
-	// Get parser for version
-	parser, ok := l.parsers[version]
-	if !ok {
-		return nil, errors.New(errors.ErrCodeParse, fmt.Sprintf("unsupported schema version: %s", version))
-	}
-
-	// Parse schema
-	schema, err := parser.ParseBytes(data)
+	schema, version, err := l.parse(data, sourcePath)
 	if err != nil {
-		return nil, errors.ParseError(sourcePath, err)
-	}
-
-	// Validate schema
-	validator, ok := l.validators[version]
-	if !ok {
-		return nil, errors.New(errors.ErrCodeParse, fmt.Sprintf("no validator for version: %s", version))
-	}
-
-	validationErrors := validator.Validate(schema)
-	if len(validationErrors) > 0 {
-		errMsgs := make([]string, len(validationErrors))
-		for i, e := range validationErrors {
-			errMsgs[i] = e.Error()
-		}
-		return nil, errors.ValidationError(
-			"component validation failed",
-			map[string]interface{}{
-				"errors": errMsgs,
-			},
-		)
+		return nil, err
 	}
 
 	// Transform to internal representation
-	transformer, ok := l.transformers[version]
-	if !ok {
-		return nil, errors.New(errors.ErrCodeParse, fmt.Sprintf("no transformer for version: %s", version))
-	}
-
-	internal, err := transformer.Transform(schema)
+	internal, err := l.transformer.Transform(schema)
 	if err != nil {
 		return nil, errors.Wrap(errors.ErrCodeParse, "failed to transform schema", err)
 	}
 
+	internal.SourceVersion = version
 	internal.SourcePath = sourcePath
 
 	return newComponentWrapper(internal), nil
@@ -158,37 +195,49 @@ func (l *versionDetectingLoader) Validate(path string) error {
 		return errors.Wrap(errors.ErrCodeParse, fmt.Sprintf("failed to read %s", path), err)
 	}
 
+	_, _, err = l.parse(data, path)
+	return err
+}
+
+// parse detects the schema version, then parses and validates the document
+// with that version's rules. v1 documents are upgraded to v2 afterwards. It
+// returns the detected version alongside the schema.
+func (l *versionDetectingLoader) parse(data []byte, sourcePath string) (*v2.SchemaV2, string, error) {
 	// Detect version
 	version, err := l.detectVersion(data)
 	if err != nil {
-		return errors.Wrap(errors.ErrCodeParse, "failed to detect schema version", err)
-	}
-
-	// Get parser for version
-	parser, ok := l.parsers[version]
-	if !ok {
-		return errors.New(errors.ErrCodeParse, fmt.Sprintf("unsupported schema version: %s", version))
-	}
-
-	// Parse schema
-	schema, err := parser.ParseBytes(data)
-	if err != nil {
-		return errors.ParseError(path, err)
-	}
-
-	// Validate schema
-	validator, ok := l.validators[version]
-	if !ok {
-		return errors.New(errors.ErrCodeParse, fmt.Sprintf("no validator for version: %s", version))
+		return nil, "", errors.Wrap(errors.ErrCodeParse, "failed to detect schema version", err)
+	}
+
+	var (
+		schema           *v2.SchemaV2
+		validationErrors []v1.ValidationError
+	)
+	switch version {
+	case "v1":
+		s, err := l.v1Parser.ParseBytes(data)
+		if err != nil {
+			return nil, "", errors.ParseError(sourcePath, err)
+		}
+		validationErrors = append(v1.ApplyCommon(s.Common, s.Deployments, s.Functions, s.Cronjobs), l.v1Validator.Validate(s)...)
+		schema = v2.Upgrade(s)
+	case "v2":
+		s, err := l.v2Parser.ParseBytes(data)
+		if err != nil {
+			return nil, "", errors.ParseError(sourcePath, err)
+		}
+		validationErrors = append(s.ApplyCommon(), l.v2Validator.Validate(s)...)
+		schema = s
+	default:
+		return nil, "", errors.New(errors.ErrCodeParse, fmt.Sprintf("unsupported schema version: %s", version))
 	}
 
-	validationErrors := validator.Validate(schema)
 	if len(validationErrors) > 0 {
 		errMsgs := make([]string, len(validationErrors))
 		for i, e := range validationErrors {
 			errMsgs[i] = e.Error()
 		}
-		return errors.ValidationError(
+		return nil, "", errors.ValidationError(
 			"component validation failed",
 			map[string]interface{}{
 				"errors": errMsgs,
@@ -196,7 +245,7 @@ func (l *versionDetectingLoader) Validate(path string) error {
 		)
 	}
 
-	return nil
+	return schema, version, nil
 }
 
 // resolveExtends resolves the extends chain for a component file.
@@ -0,0 +1,148 @@
+package component
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_EnvFrom(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "config"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config", "shared.env"), []byte(`
+# Shared by every workload
+DATABASE_URL=${{ databases.main.url }}
+LOG_LEVEL=info
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cld.yml"), []byte(`
+databases:
+  main:
+    type: postgres:^16
+deployments:
+  api:
+    image: api:latest
+    envFrom:
+      - config/shared.env
+    environment:
+      LOG_LEVEL: debug
+functions:
+  web:
+    container:
+      image: web:latest
+    envFrom:
+      - config/shared.env
+`), 0644))
+
+	comp, err := NewLoader().Load(filepath.Join(dir, "cld.yml"))
+	require.NoError(t, err)
+
+	require.Len(t, comp.Deployments(), 1)
+	files := comp.Deployments()[0].EnvFrom()
+	require.Len(t, files, 1)
+	assert.Equal(t, "config/shared.env", files[0].Path())
+	assert.Equal(t, map[string]string{
+		"DATABASE_URL": "${{ databases.main.url }}",
+		"LOG_LEVEL":    "info",
+	}, files[0].Environment())
+
+	// The environment block is left as written; it is merged over the file
+	// when the graph is built
+	assert.Equal(t, map[string]string{"LOG_LEVEL": "debug"}, comp.Deployments()[0].Environment())
+
+	require.Len(t, comp.Functions(), 1)
+	require.Len(t, comp.Functions()[0].EnvFrom(), 1)
+	assert.Equal(t, "info", comp.Functions()[0].EnvFrom()[0].Environment()["LOG_LEVEL"])
+}
+
+func TestLoad_EnvFromMissingFile(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cld.yml"), []byte(`
+deployments:
+  api:
+    image: api:latest
+    envFrom:
+      - missing.env
+`), 0644))
+
+	_, err := NewLoader().Load(filepath.Join(dir, "cld.yml"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "deployments.api: failed to read env file missing.env")
+}
+
+func TestLoad_FileSource(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "config"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config", "app.toml"), []byte(`database = "${{ databases.main.url }}"
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cld.yml"), []byte(`
+databases:
+  main:
+    type: postgres:^16
+deployments:
+  api:
+    image: api:latest
+    files:
+      - mount_path: /etc/app/app.toml
+        source: config/app.toml
+      - mount_path: /etc/app/motd
+        content: hello
+        mode: "0600"
+`), 0644))
+
+	comp, err := NewLoader().Load(filepath.Join(dir, "cld.yml"))
+	require.NoError(t, err)
+
+	files := comp.Deployments()[0].Files()
+	require.Len(t, files, 2)
+	assert.Equal(t, "/etc/app/app.toml", files[0].MountPath())
+	assert.Equal(t, "config/app.toml", files[0].Source())
+	assert.Equal(t, "database = \"${{ databases.main.url }}\"\n", files[0].Content())
+	assert.Equal(t, "hello", files[1].Content())
+	assert.Equal(t, "0600", files[1].Mode())
+}
+
+func TestLoadFromBytes_CommonBlocksV2(t *testing.T) {
+	comp, err := NewLoader().LoadFromBytes([]byte(`
+version: v2
+common:
+  service-defaults:
+    environment:
+      LOG_FORMAT: json
+    cpu: "0.5"
+    readiness_probe:
+      path: /ready
+      port: 8080
+workloads:
+  deployments:
+    api:
+      extends: service-defaults
+      image: api:latest
+`), "/tmp/app/cld.yml")
+	require.NoError(t, err)
+
+	require.Len(t, comp.Deployments(), 1)
+	api := comp.Deployments()[0]
+	assert.Equal(t, "json", api.Environment()["LOG_FORMAT"])
+	assert.Equal(t, "0.5", api.CPU())
+	require.NotNil(t, api.ReadinessProbe())
+	assert.Equal(t, "/ready", api.ReadinessProbe().Path())
+
+	_, err = NewLoader().LoadFromBytes([]byte(`
+version: v2
+workloads:
+  deployments:
+    api:
+      extends: missing
+      image: api:latest
+`), "/tmp/app/cld.yml")
+	var cldErr *errors.Error
+	require.ErrorAs(t, err, &cldErr)
+	assert.Contains(t, cldErr.Details["errors"], `workloads.deployments.api.extends: unknown common block "missing"`)
+}
@@ -0,0 +1,177 @@
+package v1
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CommonV1 is a named block of workload configuration declared under the
+// top-level common section. Deployments, functions, and cronjobs merge it in
+// by listing its name in extends.
+type CommonV1 struct {
+	Environment    map[string]string `yaml:"environment,omitempty" json:"environment,omitempty"`
+	EnvFrom        []string          `yaml:"envFrom,omitempty" json:"envFrom,omitempty"`
+	CPU            string            `yaml:"cpu,omitempty" json:"cpu,omitempty"`
+	Memory         string            `yaml:"memory,omitempty" json:"memory,omitempty"`
+	LivenessProbe  *ProbeV1          `yaml:"liveness_probe,omitempty" json:"liveness_probe,omitempty"`
+	ReadinessProbe *ProbeV1          `yaml:"readiness_probe,omitempty" json:"readiness_probe,omitempty"`
+
+	// Unknown collects keys a common block may not set so the validator can
+	// reject them.
+	Unknown map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// ExtendsV1 lists the common blocks a workload merges in. It accepts a
+// single name or a list of names.
+type ExtendsV1 []string
+
+// UnmarshalYAML supports both a single name and a list of names.
+func (e *ExtendsV1) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		*e = ExtendsV1{s}
+		return nil
+	}
+
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return fmt.Errorf("extends must be a name or a list of names of common blocks: %w", err)
+	}
+	*e = list
+	return nil
+}
+
+// ApplyCommon merges the common blocks each workload extends into the
+// workload, in place, and clears its extends list. Blocks are applied in the
+// order they are listed, so a later block overrides an earlier one, and the
+// workload's own settings override them all. Environment variables are
+// merged key by key and env files are concatenated. Settings a workload kind
+// doesn't have (probes on functions and cronjobs, env files on cronjobs) are
+// left out, so one block can be shared by every kind of workload.
+//
+// It returns an error for each reference to an undeclared block.
+func ApplyCommon(common map[string]CommonV1, deployments map[string]DeploymentV1, functions map[string]FunctionV1, cronjobs map[string]CronjobV1) []ValidationError {
+	var errs []ValidationError
+
+	// resolve returns the blocks a workload extends, merged in order
+	resolve := func(field string, extends ExtendsV1) (CommonV1, bool) {
+		var merged CommonV1
+		ok := true
+		for _, name := range extends {
+			block, exists := common[name]
+			if !exists {
+				errs = append(errs, ValidationError{
+					Field:   field + ".extends",
+					Message: fmt.Sprintf("unknown common block %q", name),
+				})
+				ok = false
+				continue
+			}
+			merged = mergeCommon(merged, block)
+		}
+		return merged, ok
+	}
+
+	for _, name := range sortedKeys(deployments) {
+		dep := deployments[name]
+		if len(dep.Extends) == 0 {
+			continue
+		}
+		field := "deployments." + name
+		if block, ok := resolve(field, dep.Extends); ok {
+			dep.Environment = mergeEnvironment(block.Environment, dep.Environment)
+			dep.EnvFrom = append(append([]string(nil), block.EnvFrom...), dep.EnvFrom...)
+			dep.CPU = firstNonEmpty(dep.CPU, block.CPU)
+			dep.Memory = firstNonEmpty(dep.Memory, block.Memory)
+			if dep.LivenessProbe == nil {
+				dep.LivenessProbe = block.LivenessProbe
+			}
+			if dep.ReadinessProbe == nil {
+				dep.ReadinessProbe = block.ReadinessProbe
+			}
+		}
+		dep.Extends = nil
+		deployments[name] = dep
+	}
+
+	for _, name := range sortedKeys(functions) {
+		fn := functions[name]
+		if len(fn.Extends) == 0 {
+			continue
+		}
+		field := "functions." + name
+		if block, ok := resolve(field, fn.Extends); ok {
+			fn.Environment = mergeEnvironment(block.Environment, fn.Environment)
+			fn.EnvFrom = append(append([]string(nil), block.EnvFrom...), fn.EnvFrom...)
+			fn.CPU = firstNonEmpty(fn.CPU, block.CPU)
+			fn.Memory = firstNonEmpty(fn.Memory, block.Memory)
+		}
+		fn.Extends = nil
+		functions[name] = fn
+	}
+
+	for _, name := range sortedKeys(cronjobs) {
+		cj := cronjobs[name]
+		if len(cj.Extends) == 0 {
+			continue
+		}
+		field := "cronjobs." + name
+		if block, ok := resolve(field, cj.Extends); ok {
+			cj.Environment = mergeEnvironment(block.Environment, cj.Environment)
+			cj.CPU = firstNonEmpty(cj.CPU, block.CPU)
+			cj.Memory = firstNonEmpty(cj.Memory, block.Memory)
+		}
+		cj.Extends = nil
+		cronjobs[name] = cj
+	}
+
+	return errs
+}
+
+// mergeCommon applies override on top of base.
+func mergeCommon(base, override CommonV1) CommonV1 {
+	base.Environment = mergeEnvironment(base.Environment, override.Environment)
+	base.EnvFrom = append(append([]string(nil), base.EnvFrom...), override.EnvFrom...)
+	base.CPU = firstNonEmpty(override.CPU, base.CPU)
+	base.Memory = firstNonEmpty(override.Memory, base.Memory)
+	if override.LivenessProbe != nil {
+		base.LivenessProbe = override.LivenessProbe
+	}
+	if override.ReadinessProbe != nil {
+		base.ReadinessProbe = override.ReadinessProbe
+	}
+	return base
+}
+
+// mergeEnvironment returns base with the keys of override applied on top.
+func mergeEnvironment(base, override map[string]string) map[string]string {
+	if len(base) == 0 {
+		return override
+	}
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
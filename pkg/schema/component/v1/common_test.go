@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyCommon_MergesBlocksInOrder(t *testing.T) {
+	parser := &Parser{}
+
+	yaml := `
+common:
+  base:
+    environment:
+      LOG_FORMAT: json
+      LOG_LEVEL: info
+    cpu: "0.5"
+    memory: 512Mi
+    liveness_probe:
+      path: /healthz
+      port: 8080
+  verbose:
+    environment:
+      LOG_LEVEL: debug
+    envFrom:
+      - shared.env
+
+deployments:
+  api:
+    extends: [base, verbose]
+    image: api:latest
+    memory: 1Gi
+    environment:
+      LOG_FORMAT: text
+    envFrom:
+      - api.env
+  worker:
+    extends: base
+    image: worker:latest
+
+cronjobs:
+  cleanup:
+    extends: base
+    image: cleanup:latest
+    schedule: "0 * * * *"
+`
+
+	schema, err := parser.ParseBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if errs := ApplyCommon(schema.Common, schema.Deployments, schema.Functions, schema.Cronjobs); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	api := schema.Deployments["api"]
+	if api.Extends != nil {
+		t.Errorf("expected extends to be cleared, got %v", api.Extends)
+	}
+	wantEnv := map[string]string{
+		"LOG_FORMAT": "text",  // the deployment overrides every block
+		"LOG_LEVEL":  "debug", // verbose overrides base
+	}
+	for k, v := range wantEnv {
+		if api.Environment[k] != v {
+			t.Errorf("api %s = %q, want %q", k, api.Environment[k], v)
+		}
+	}
+	if strings.Join(api.EnvFrom, ",") != "shared.env,api.env" {
+		t.Errorf("expected env files from the block before the deployment's own, got %v", api.EnvFrom)
+	}
+	if api.CPU != "0.5" || api.Memory != "1Gi" {
+		t.Errorf("expected cpu 0.5 from base and memory 1Gi from the deployment, got %q and %q", api.CPU, api.Memory)
+	}
+	if api.LivenessProbe == nil || api.LivenessProbe.Path != "/healthz" {
+		t.Errorf("expected liveness probe from base, got %+v", api.LivenessProbe)
+	}
+
+	if schema.Deployments["worker"].Environment["LOG_LEVEL"] != "info" {
+		t.Errorf("expected worker to get LOG_LEVEL from base, got %v", schema.Deployments["worker"].Environment)
+	}
+	if schema.Cronjobs["cleanup"].Memory != "512Mi" {
+		t.Errorf("expected cronjob to get memory from base, got %q", schema.Cronjobs["cleanup"].Memory)
+	}
+}
+
+func TestApplyCommon_UnknownBlock(t *testing.T) {
+	common := map[string]CommonV1{
+		"probes": {LivenessProbe: &ProbeV1{Path: "/healthz"}},
+	}
+	deployments := map[string]DeploymentV1{
+		"api": {Image: "api:latest", Extends: ExtendsV1{"missing"}},
+	}
+	functions := map[string]FunctionV1{
+		"web": {Container: &FunctionContainerV1{Image: "web:latest"}, Extends: ExtendsV1{"probes"}},
+	}
+
+	errs := ApplyCommon(common, deployments, functions, nil)
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(errs), errs)
+	}
+	if errs[0].Field != "deployments.api.extends" || !strings.Contains(errs[0].Message, `unknown common block "missing"`) {
+		t.Errorf("unexpected error: %v", errs[0])
+	}
+	if functions["web"].Extends != nil {
+		t.Errorf("expected the function to extend the probe-only block without error")
+	}
+}
+
+func TestValidator_CommonUnknownKey(t *testing.T) {
+	parser := &Parser{}
+
+	schema, err := parser.ParseBytes([]byte(`
+common:
+  base:
+    image: api:latest
+    cpu: "0.5"
+`))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	errs := (&Validator{}).Validate(schema)
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(errs), errs)
+	}
+	if errs[0].Field != "common.base.image" {
+		t.Errorf("unexpected field %q", errs[0].Field)
+	}
+}
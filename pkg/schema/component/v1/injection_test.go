@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"testing"
+)
+
+func TestParser_ParseBytes_InjectionBoolFalse(t *testing.T) {
+	parser := &Parser{}
+
+	yaml := `
+deployments:
+  api:
+    image: nginx:latest
+
+injection: false
+`
+
+	schema, err := parser.ParseBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	if schema.Injection == nil {
+		t.Fatal("expected injection to be set")
+	}
+	if schema.Injection.Enabled {
+		t.Error("expected injection to be disabled")
+	}
+
+	ic, err := NewTransformer().Transform(schema)
+	if err != nil {
+		t.Fatalf("unexpected transform error: %v", err)
+	}
+	if ic.Injection == nil || !ic.Injection.Disabled {
+		t.Error("expected transformed injection to be disabled")
+	}
+}
+
+func TestParser_ParseBytes_InjectionExclude(t *testing.T) {
+	parser := &Parser{}
+
+	yaml := `
+deployments:
+  api:
+    image: nginx:latest
+
+injection:
+  exclude:
+    - proxy
+    - region
+`
+
+	schema, err := parser.ParseBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	ic, err := NewTransformer().Transform(schema)
+	if err != nil {
+		t.Fatalf("unexpected transform error: %v", err)
+	}
+	if ic.Injection == nil {
+		t.Fatal("expected injection to be set")
+	}
+	if ic.Injection.Disabled {
+		t.Error("expected injection to stay enabled")
+	}
+	if len(ic.Injection.Exclude) != 2 || ic.Injection.Exclude[0] != "proxy" || ic.Injection.Exclude[1] != "region" {
+		t.Errorf("expected exclude [proxy region], got %v", ic.Injection.Exclude)
+	}
+}
+
+func TestParser_ParseBytes_InjectionOmitted(t *testing.T) {
+	parser := &Parser{}
+
+	yaml := `
+deployments:
+  api:
+    image: nginx:latest
+`
+
+	schema, err := parser.ParseBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	ic, err := NewTransformer().Transform(schema)
+	if err != nil {
+		t.Fatalf("unexpected transform error: %v", err)
+	}
+	if ic.Injection != nil {
+		t.Error("expected injection to be nil when omitted")
+	}
+}
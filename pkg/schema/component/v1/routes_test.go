@@ -0,0 +1,88 @@
+package v1
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParser_ParseBytes_RouteAuthShorthand(t *testing.T) {
+	parser := &Parser{}
+
+	yaml := `
+routes:
+  admin:
+    type: http
+    service: admin
+    auth: required
+  public:
+    type: http
+    service: web
+`
+
+	schema, err := parser.ParseBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	if schema.Routes["admin"].Auth == nil {
+		t.Fatal("expected auth to be set on admin route")
+	}
+	if schema.Routes["admin"].Auth.Provider != "" {
+		t.Errorf("expected no provider for shorthand, got %q", schema.Routes["admin"].Auth.Provider)
+	}
+	if schema.Routes["public"].Auth != nil {
+		t.Error("expected public route to have no auth")
+	}
+}
+
+func TestParser_ParseBytes_RouteAuthFullObject(t *testing.T) {
+	parser := &Parser{}
+
+	yaml := `
+routes:
+  admin:
+    type: http
+    service: admin
+    auth:
+      provider: github
+      config:
+        org: my-org
+        teams: [platform]
+`
+
+	schema, err := parser.ParseBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	auth := schema.Routes["admin"].Auth
+	if auth == nil {
+		t.Fatal("expected auth to be set")
+	}
+	if auth.Provider != "github" {
+		t.Errorf("expected provider 'github', got %q", auth.Provider)
+	}
+	if auth.Config["org"] != "my-org" {
+		t.Errorf("expected config org 'my-org', got %v", auth.Config["org"])
+	}
+}
+
+func TestParser_ParseBytes_RouteAuthInvalidShorthand(t *testing.T) {
+	parser := &Parser{}
+
+	yaml := `
+routes:
+  admin:
+    type: http
+    service: admin
+    auth: optional
+`
+
+	_, err := parser.ParseBytes([]byte(yaml))
+	if err == nil {
+		t.Fatal("expected error for unknown auth shorthand")
+	}
+	if !strings.Contains(err.Error(), `auth must be "required"`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -54,6 +54,16 @@ func (t *Transformer) Transform(v1 *SchemaV1) (*internal.InternalComponent, erro
 		ic.SMTP = append(ic.SMTP, is)
 	}
 
+	// Transform external services
+	for name, ext := range v1.External {
+		ic.External = append(ic.External, internal.InternalExternal{
+			Name:        name,
+			URL:         ext.URL,
+			Description: ext.Description,
+			Credentials: ext.Credentials,
+		})
+	}
+
 	// Transform ports
 	for name, p := range v1.Ports {
 		ip := t.transformPort(name, p)
@@ -102,11 +112,46 @@ func (t *Transformer) Transform(v1 *SchemaV1) (*internal.InternalComponent, erro
 		ic.Cronjobs = append(ic.Cronjobs, icj)
 	}
 
+	// Transform tests
+	for name, test := range v1.Tests {
+		it := internal.InternalTest{
+			Name:        name,
+			Command:     test.Command,
+			Environment: make(map[string]internal.Expression),
+			Timeout:     test.Timeout,
+		}
+		for k, v := range test.Environment {
+			it.Environment[k] = internal.NewExpression(v)
+		}
+		ic.Tests = append(ic.Tests, it)
+	}
+
+	// Transform webhooks
+	for name, webhook := range v1.Webhooks {
+		ic.Webhooks = append(ic.Webhooks, internal.InternalWebhook{
+			Name:    name,
+			URL:     internal.NewExpression(webhook.URL),
+			Events:  webhook.Events,
+			Secret:  internal.NewExpression(webhook.Secret),
+			Timeout: webhook.Timeout,
+		})
+	}
+
 	// Transform observability
 	if v1.Observability != nil {
 		ic.Observability = t.transformObservability(v1.Observability)
 	}
 
+	// Transform environment injection opt-outs
+	if v1.Injection != nil {
+		ic.Injection = &internal.InternalInjection{
+			Disabled: !v1.Injection.Enabled,
+			Exclude:  v1.Injection.Exclude,
+		}
+	}
+
+	ic.Requires = v1.Requires
+
 	// Transform variables
 	for name, v := range v1.Variables {
 		iv := t.transformVariable(name, v)
@@ -149,6 +194,7 @@ func (t *Transformer) transformDatabase(name string, db DatabaseV1) (internal.In
 			idb.Migrations.Runtime = t.transformRuntime(db.Migrations.Runtime)
 		}
 	}
+	idb.Readiness = transformReadiness(db.Readiness)
 
 	return idb, nil
 }
@@ -222,11 +268,33 @@ func (t *Transformer) transformDeployment(name string, dep DeploymentV1) (intern
 		idep.Runtime = t.transformRuntime(dep.Runtime)
 	}
 
+	// Transform rollout strategy
+	if dep.Strategy != nil {
+		idep.Strategy = &internal.InternalStrategy{
+			Type:            defaultString(dep.Strategy.Type, StrategyRolling),
+			MaxSurge:        interfaceToString(dep.Strategy.MaxSurge),
+			MaxUnavailable:  interfaceToString(dep.Strategy.MaxUnavailable),
+			MinReadySeconds: dep.Strategy.MinReadySeconds,
+		}
+	}
+
+	// Transform lifecycle hooks
+	if dep.Lifecycle != nil {
+		idep.Lifecycle = &internal.InternalLifecycle{
+			PostStart:                     dep.Lifecycle.PostStart,
+			PreStop:                       dep.Lifecycle.PreStop,
+			TerminationGracePeriodSeconds: dep.Lifecycle.TerminationGracePeriodSeconds,
+		}
+	}
+
 	// Transform environment with expression detection
 	idep.Environment = make(map[string]internal.Expression)
 	for k, v := range dep.Environment {
 		idep.Environment[k] = internal.NewExpression(v)
 	}
+	for _, path := range dep.EnvFrom {
+		idep.EnvFrom = append(idep.EnvFrom, internal.InternalEnvFile{Path: path})
+	}
 
 	// Transform volumes
 	for _, vol := range dep.Volumes {
@@ -238,6 +306,18 @@ func (t *Transformer) transformDeployment(name string, dep DeploymentV1) (intern
 		})
 	}
 
+	idep.Readiness = transformReadiness(dep.Readiness)
+
+	// Transform files
+	for _, f := range dep.Files {
+		idep.Files = append(idep.Files, internal.InternalFile{
+			MountPath: f.MountPath,
+			Content:   internal.NewExpression(f.Content),
+			Source:    f.Source,
+			Mode:      f.Mode,
+		})
+	}
+
 	// Transform probes
 	if dep.LivenessProbe != nil {
 		idep.LivenessProbe = t.transformProbe(dep.LivenessProbe)
@@ -251,11 +331,12 @@ func (t *Transformer) transformDeployment(name string, dep DeploymentV1) (intern
 
 func (t *Transformer) transformFunction(name string, fn FunctionV1) (internal.InternalFunction, error) {
 	ifn := internal.InternalFunction{
-		Name:    name,
-		Port:    internal.NewExpression(fn.PortAsString()),
-		CPU:     fn.CPU,
-		Memory:  fn.Memory,
-		Timeout: fn.Timeout,
+		Name:      name,
+		Port:      internal.NewExpression(fn.PortAsString()),
+		CPU:       fn.CPU,
+		Memory:    fn.Memory,
+		Timeout:   fn.Timeout,
+		Readiness: transformReadiness(fn.Readiness),
 	}
 
 	// Transform discriminated union
@@ -288,6 +369,9 @@ func (t *Transformer) transformFunction(name string, fn FunctionV1) (internal.In
 	for k, v := range fn.Environment {
 		ifn.Environment[k] = internal.NewExpression(v)
 	}
+	for _, path := range fn.EnvFrom {
+		ifn.EnvFrom = append(ifn.EnvFrom, internal.InternalEnvFile{Path: path})
+	}
 
 	return ifn, nil
 }
@@ -299,6 +383,20 @@ func (t *Transformer) transformService(name string, svc ServiceV1) internal.Inte
 		URL:        svc.URL,
 		Port:       internal.NewExpression(svc.PortAsString()),
 		Protocol:   defaultString(svc.Protocol, "http"),
+		Readiness:  transformReadiness(svc.Readiness),
+	}
+}
+
+func transformReadiness(r *ReadinessV1) *internal.InternalReadiness {
+	if r == nil {
+		return nil
+	}
+	return &internal.InternalReadiness{
+		HTTP:     r.HTTP,
+		TCP:      r.TCP,
+		Command:  r.Command,
+		Interval: r.Interval,
+		Timeout:  r.Timeout,
 	}
 }
 
@@ -311,6 +409,13 @@ func (t *Transformer) transformRoute(name string, rt RouteV1) (internal.Internal
 		Function: rt.Function,
 	}
 
+	if rt.Auth != nil {
+		irt.Auth = &internal.InternalRouteAuth{
+			Provider: rt.Auth.Provider,
+			Config:   rt.Auth.Config,
+		}
+	}
+
 	// Transform rules
 	for _, rule := range rt.Rules {
 		irule, err := t.transformRouteRule(rule)
@@ -28,11 +28,16 @@ type SchemaV1 struct {
 	Version string `yaml:"version,omitempty" json:"version,omitempty"`
 	Extends string `yaml:"extends,omitempty" json:"extends,omitempty"`
 
+	// Requires lists datacenter capabilities the component needs (e.g.,
+	// postgres, smtp, cronjobs), checked before anything is deployed
+	Requires []string `yaml:"requires,omitempty" json:"requires,omitempty"`
+
 	Builds         map[string]BuildV1         `yaml:"builds,omitempty" json:"builds,omitempty"`
 	Databases      map[string]DatabaseV1      `yaml:"databases,omitempty" json:"databases,omitempty"`
 	Buckets        map[string]BucketV1        `yaml:"buckets,omitempty" json:"buckets,omitempty"`
 	EncryptionKeys map[string]EncryptionKeyV1 `yaml:"encryptionKeys,omitempty" json:"encryptionKeys,omitempty"`
 	SMTP           map[string]SMTPV1          `yaml:"smtp,omitempty" json:"smtp,omitempty"`
+	External       map[string]ExternalV1      `yaml:"external,omitempty" json:"external,omitempty"`
 	Ports          map[string]PortV1          `yaml:"ports,omitempty" json:"ports,omitempty"`
 	Deployments    map[string]DeploymentV1    `yaml:"deployments,omitempty" json:"deployments,omitempty"`
 	Functions      map[string]FunctionV1      `yaml:"functions,omitempty" json:"functions,omitempty"`
@@ -40,7 +45,19 @@ type SchemaV1 struct {
 	Routes         map[string]RouteV1         `yaml:"routes,omitempty" json:"routes,omitempty"`
 	Cronjobs       map[string]CronjobV1       `yaml:"cronjobs,omitempty" json:"cronjobs,omitempty"`
 
+	// Common declares named blocks of workload configuration that
+	// workloads merge in with extends
+	Common map[string]CommonV1 `yaml:"common,omitempty" json:"common,omitempty"`
+
+	// Tests are smoke tests that `cldctl test component` runs once the
+	// component is deployed
+	Tests map[string]TestV1 `yaml:"tests,omitempty" json:"tests,omitempty"`
+
+	// Webhooks are called when the component is deployed or destroyed
+	Webhooks map[string]WebhookV1 `yaml:"webhooks,omitempty" json:"webhooks,omitempty"`
+
 	Observability *ObservabilityV1 `yaml:"observability,omitempty" json:"observability,omitempty"`
+	Injection     *InjectionV1     `yaml:"injection,omitempty" json:"injection,omitempty"`
 
 	Variables    map[string]VariableV1   `yaml:"variables,omitempty" json:"variables,omitempty"`
 	Dependencies map[string]DependencyV1 `yaml:"dependencies,omitempty" json:"dependencies,omitempty"`
@@ -79,10 +96,37 @@ func (o *ObservabilityV1) UnmarshalYAML(unmarshal func(interface{}) error) error
 	return nil
 }
 
+// InjectionV1 controls which datacenter environment injections apply to the
+// component's workloads. Supports boolean shorthand (false opts out of every
+// injection) and an object form that excludes injections by name.
+type InjectionV1 struct {
+	Enabled bool     `yaml:"-" json:"-"`                                 // Internal: false when the component opts out entirely
+	Exclude []string `yaml:"exclude,omitempty" json:"exclude,omitempty"` // Names of datacenter inject blocks to skip
+}
+
+// UnmarshalYAML supports both boolean shorthand (true/false) and full object form.
+func (i *InjectionV1) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var b bool
+	if err := unmarshal(&b); err == nil {
+		i.Enabled = b
+		return nil
+	}
+
+	type rawInjection InjectionV1
+	var raw rawInjection
+	if err := unmarshal(&raw); err != nil {
+		return fmt.Errorf("injection must be a boolean or an object: %w", err)
+	}
+	*i = InjectionV1(raw)
+	i.Enabled = true
+	return nil
+}
+
 // DatabaseV1 represents a database in the v1 schema.
 type DatabaseV1 struct {
 	Type       string        `yaml:"type" json:"type"`
 	Migrations *MigrationsV1 `yaml:"migrations,omitempty" json:"migrations,omitempty"`
+	Readiness  *ReadinessV1  `yaml:"readiness,omitempty" json:"readiness,omitempty"`
 }
 
 // MigrationsV1 represents migrations in the v1 schema.
@@ -126,6 +170,17 @@ type SMTPV1 struct {
 	Description string `yaml:"description,omitempty" json:"description,omitempty"` // Optional description
 }
 
+// ExternalV1 represents a third-party service the component calls (e.g., the
+// Stripe API). Declaring it makes the outbound dependency visible to the
+// datacenter, which can generate egress network policy for it. Credentials
+// must be supplied through component variables so secrets never live in the
+// component file.
+type ExternalV1 struct {
+	URL         string            `yaml:"url" json:"url"`                                     // Base URL of the service
+	Description string            `yaml:"description,omitempty" json:"description,omitempty"` // Optional description
+	Credentials map[string]string `yaml:"credentials,omitempty" json:"credentials,omitempty"` // Credential name to ${{ variables.<name> }} expression
+}
+
 // PortV1 represents a dynamic port allocation in the v1 schema.
 // Ports are allocated by the engine (or a datacenter hook) and can be referenced
 // in environment variables and service ports via ${{ ports.<name>.port }}.
@@ -160,16 +215,22 @@ func (p *PortV1) UnmarshalYAML(unmarshal func(interface{}) error) error {
 // Image and runtime are optional. When neither is set, the datacenter decides
 // how to execute the workload (e.g., as a host process for local development).
 type DeploymentV1 struct {
+	Extends          ExtendsV1         `yaml:"extends,omitempty" json:"extends,omitempty"` // Common blocks to merge in
 	Image            string            `yaml:"image,omitempty" json:"image,omitempty"`
 	Runtime          *RuntimeV1        `yaml:"runtime,omitempty" json:"runtime,omitempty"`
 	Command          []string          `yaml:"command,omitempty" json:"command,omitempty"`
 	Entrypoint       []string          `yaml:"entrypoint,omitempty" json:"entrypoint,omitempty"`
 	Environment      map[string]string `yaml:"environment,omitempty" json:"environment,omitempty"`
+	EnvFrom          []string          `yaml:"envFrom,omitempty" json:"envFrom,omitempty"` // .env files, relative to the component, whose values may contain expressions
 	WorkingDirectory string            `yaml:"workingDirectory,omitempty" json:"workingDirectory,omitempty"`
 	CPU              string            `yaml:"cpu,omitempty" json:"cpu,omitempty"`
 	Memory           string            `yaml:"memory,omitempty" json:"memory,omitempty"`
 	Replicas         int               `yaml:"replicas,omitempty" json:"replicas,omitempty"`
+	Strategy         *StrategyV1       `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+	Lifecycle        *LifecycleV1      `yaml:"lifecycle,omitempty" json:"lifecycle,omitempty"`
 	Volumes          []VolumeV1        `yaml:"volumes,omitempty" json:"volumes,omitempty"`
+	Files            []FileV1          `yaml:"files,omitempty" json:"files,omitempty"`
+	Readiness        *ReadinessV1      `yaml:"readiness,omitempty" json:"readiness,omitempty"`
 	LivenessProbe    *ProbeV1          `yaml:"liveness_probe,omitempty" json:"liveness_probe,omitempty"`
 	ReadinessProbe   *ProbeV1          `yaml:"readiness_probe,omitempty" json:"readiness_probe,omitempty"`
 	Labels           map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
@@ -212,13 +273,18 @@ type FunctionV1 struct {
 	Src       *FunctionSourceV1    `yaml:"src,omitempty" json:"src,omitempty"`
 	Container *FunctionContainerV1 `yaml:"container,omitempty" json:"container,omitempty"`
 
+	// Extends lists the common blocks to merge in
+	Extends ExtendsV1 `yaml:"extends,omitempty" json:"extends,omitempty"`
+
 	// Common fields (valid for both src and container)
 	// Port supports both integer literals (3000) and expression strings (${{ ports.web.port }}).
 	Port        interface{}       `yaml:"port,omitempty" json:"port,omitempty"`
 	Environment map[string]string `yaml:"environment,omitempty" json:"environment,omitempty"`
+	EnvFrom     []string          `yaml:"envFrom,omitempty" json:"envFrom,omitempty"` // .env files, relative to the component, whose values may contain expressions
 	CPU         string            `yaml:"cpu,omitempty" json:"cpu,omitempty"`
 	Memory      string            `yaml:"memory,omitempty" json:"memory,omitempty"`
 	Timeout     int               `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	Readiness   *ReadinessV1      `yaml:"readiness,omitempty" json:"readiness,omitempty"`
 }
 
 // FunctionSourceV1 represents a source-based function configuration.
@@ -248,20 +314,22 @@ type FunctionContainerV1 struct {
 // Note: Functions don't need services - routes can point directly to functions.
 // Port supports both integer literals (8080) and expression strings (${{ ports.api.port }}).
 type ServiceV1 struct {
-	Deployment string      `yaml:"deployment" json:"deployment"`
-	URL        string      `yaml:"url,omitempty" json:"url,omitempty"`
-	Port       interface{} `yaml:"-" json:"-"`                           // int or string; handled by custom UnmarshalYAML
-	PortRaw    interface{} `yaml:"port,omitempty" json:"port,omitempty"` // raw YAML value
-	Protocol   string      `yaml:"protocol,omitempty" json:"protocol,omitempty"`
+	Deployment string       `yaml:"deployment" json:"deployment"`
+	URL        string       `yaml:"url,omitempty" json:"url,omitempty"`
+	Port       interface{}  `yaml:"-" json:"-"`                           // int or string; handled by custom UnmarshalYAML
+	PortRaw    interface{}  `yaml:"port,omitempty" json:"port,omitempty"` // raw YAML value
+	Protocol   string       `yaml:"protocol,omitempty" json:"protocol,omitempty"`
+	Readiness  *ReadinessV1 `yaml:"readiness,omitempty" json:"readiness,omitempty"`
 }
 
 // UnmarshalYAML handles port being either an int or a string expression.
 func (s *ServiceV1) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	type rawService struct {
-		Deployment string      `yaml:"deployment"`
-		URL        string      `yaml:"url,omitempty"`
-		Port       interface{} `yaml:"port,omitempty"`
-		Protocol   string      `yaml:"protocol,omitempty"`
+		Deployment string       `yaml:"deployment"`
+		URL        string       `yaml:"url,omitempty"`
+		Port       interface{}  `yaml:"port,omitempty"`
+		Protocol   string       `yaml:"protocol,omitempty"`
+		Readiness  *ReadinessV1 `yaml:"readiness,omitempty"`
 	}
 	var raw rawService
 	if err := unmarshal(&raw); err != nil {
@@ -270,6 +338,7 @@ func (s *ServiceV1) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	s.Deployment = raw.Deployment
 	s.URL = raw.URL
 	s.Protocol = raw.Protocol
+	s.Readiness = raw.Readiness
 	s.PortRaw = raw.Port
 	s.Port = raw.Port
 	return nil
@@ -318,6 +387,41 @@ type RouteV1 struct {
 	// Simplified form
 	Service  string `yaml:"service,omitempty" json:"service,omitempty"`
 	Function string `yaml:"function,omitempty" json:"function,omitempty"`
+
+	// Auth requires requests to be authenticated before they reach the
+	// upstream. Nil when the route is public.
+	Auth *RouteAuthV1 `yaml:"auth,omitempty" json:"auth,omitempty"`
+}
+
+// RouteAuthV1 represents route authentication in the v1 schema.
+// Supports both string shorthand ("required") and full object form.
+// The datacenter's routeAuth hook places an authenticating proxy in front of
+// the route's upstream and forwards the caller's identity in request headers.
+type RouteAuthV1 struct {
+	Provider string                 `yaml:"provider,omitempty" json:"provider,omitempty"` // Identity provider hint for the datacenter (e.g., "github", "google")
+	Config   map[string]interface{} `yaml:"config,omitempty" json:"config,omitempty"`     // Provider-specific settings passed to the hook
+}
+
+// UnmarshalYAML supports both string shorthand ("required") and full object form.
+func (a *RouteAuthV1) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Try string shorthand first
+	var s string
+	if err := unmarshal(&s); err == nil {
+		if s != "required" {
+			return fmt.Errorf("auth must be \"required\" or an object, got %q", s)
+		}
+		*a = RouteAuthV1{}
+		return nil
+	}
+
+	// Fall back to full object form
+	type rawRouteAuth RouteAuthV1
+	var raw rawRouteAuth
+	if err := unmarshal(&raw); err != nil {
+		return fmt.Errorf("auth must be \"required\" or an object with provider and config fields: %w", err)
+	}
+	*a = RouteAuthV1(raw)
+	return nil
 }
 
 // RouteRuleV1 represents a route rule in the v1 schema.
@@ -430,6 +534,7 @@ type TimeoutsV1 struct {
 
 // CronjobV1 represents a cronjob in the v1 schema.
 type CronjobV1 struct {
+	Extends     ExtendsV1         `yaml:"extends,omitempty" json:"extends,omitempty"` // Common blocks to merge in
 	Image       string            `yaml:"image,omitempty" json:"image,omitempty"`
 	Build       *BuildV1          `yaml:"build,omitempty" json:"build,omitempty"`
 	Schedule    string            `yaml:"schedule" json:"schedule"`
@@ -439,6 +544,27 @@ type CronjobV1 struct {
 	Memory      string            `yaml:"memory,omitempty" json:"memory,omitempty"`
 }
 
+// TestV1 represents a smoke test in the v1 schema. The command runs on the
+// machine running cldctl, with environment values resolved against the
+// deployed component (e.g. ${{ routes.main.url }}).
+type TestV1 struct {
+	Command     []string          `yaml:"command" json:"command"`
+	Environment map[string]string `yaml:"environment,omitempty" json:"environment,omitempty"`
+	Timeout     string            `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// WebhookV1 represents a lifecycle webhook in the v1 schema. The engine
+// POSTs a JSON payload describing the environment and the component's
+// resolved outputs to the URL on each of the listed events ("deploy", after
+// a successful deploy, and "destroy", before the component is destroyed).
+// When a secret is set, the payload is signed with it (HMAC-SHA256).
+type WebhookV1 struct {
+	URL     string   `yaml:"url" json:"url"`
+	Events  []string `yaml:"events" json:"events"`
+	Secret  string   `yaml:"secret,omitempty" json:"secret,omitempty"`
+	Timeout string   `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
 // VariableV1 represents a variable in the v1 schema.
 type VariableV1 struct {
 	Description string      `yaml:"description,omitempty" json:"description,omitempty"`
@@ -483,6 +609,42 @@ func (d *DependencyV1) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return nil
 }
 
+// Deployment strategy types.
+const (
+	StrategyRolling  = "rolling"
+	StrategyRecreate = "recreate"
+)
+
+// StrategyV1 describes how a deployment's replicas are replaced when it
+// changes. MaxSurge and MaxUnavailable accept a count (1) or a percentage of
+// replicas ("25%").
+type StrategyV1 struct {
+	Type            string      `yaml:"type,omitempty" json:"type,omitempty"` // rolling (default) or recreate
+	MaxSurge        interface{} `yaml:"maxSurge,omitempty" json:"maxSurge,omitempty"`
+	MaxUnavailable  interface{} `yaml:"maxUnavailable,omitempty" json:"maxUnavailable,omitempty"`
+	MinReadySeconds int         `yaml:"minReadySeconds,omitempty" json:"minReadySeconds,omitempty"`
+}
+
+// LifecycleV1 configures the hooks run as a deployment's containers start
+// and stop, and how long they are given to shut down.
+type LifecycleV1 struct {
+	PostStart                     []string `yaml:"postStart,omitempty" json:"postStart,omitempty"`                                         // Run in the container once it has started
+	PreStop                       []string `yaml:"preStop,omitempty" json:"preStop,omitempty"`                                             // Run in the container before it is sent SIGTERM
+	TerminationGracePeriodSeconds int      `yaml:"terminationGracePeriodSeconds,omitempty" json:"terminationGracePeriodSeconds,omitempty"` // Time between SIGTERM and kill
+}
+
+// ReadinessV1 is a readiness gate: a check cldctl runs once a resource has
+// been deployed, which must pass before the resources that depend on it are
+// deployed. Exactly one of HTTP, TCP, and Command is set, and they may
+// contain expressions, including ones that read the resource's own outputs.
+type ReadinessV1 struct {
+	HTTP     string   `yaml:"http,omitempty" json:"http,omitempty"`         // URL that must respond with a 2xx status
+	TCP      string   `yaml:"tcp,omitempty" json:"tcp,omitempty"`           // host:port that must accept connections
+	Command  []string `yaml:"command,omitempty" json:"command,omitempty"`   // Command that must exit successfully
+	Interval string   `yaml:"interval,omitempty" json:"interval,omitempty"` // Time between checks (default 2s)
+	Timeout  string   `yaml:"timeout,omitempty" json:"timeout,omitempty"`   // How long the check has to pass (default 60s)
+}
+
 // VolumeV1 represents a volume in the v1 schema.
 type VolumeV1 struct {
 	MountPath string `yaml:"mount_path" json:"mount_path"`
@@ -491,6 +653,16 @@ type VolumeV1 struct {
 	ReadOnly  bool   `yaml:"read_only,omitempty" json:"read_only,omitempty"`
 }
 
+// FileV1 is a file mounted into a deployment's containers. Its content is
+// either inline or read from a file bundled with the component, and may
+// contain expressions either way.
+type FileV1 struct {
+	MountPath string `yaml:"mount_path" json:"mount_path"`
+	Content   string `yaml:"content,omitempty" json:"content,omitempty"`
+	Source    string `yaml:"source,omitempty" json:"source,omitempty"` // Relative to the component
+	Mode      string `yaml:"mode,omitempty" json:"mode,omitempty"`     // Octal permissions, e.g. "0600"
+}
+
 // ProbeV1 represents a probe in the v1 schema.
 // Port and TCPPort support both integer literals and expression strings (${{ ports.*.port }}).
 type ProbeV1 struct {
@@ -2,9 +2,23 @@ package v1
 
 import (
 	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// credentialVariablePattern matches a credential value that is exactly one
+// variable reference, e.g. ${{ variables.stripe_api_key }}.
+var credentialVariablePattern = regexp.MustCompile(`^\$\{\{\s*variables\.([A-Za-z_][A-Za-z0-9_-]*)\s*\}\}$`)
+
+// externalOutputs are the outputs every external service provides. Credential
+// names may not shadow them.
+var externalOutputs = []string{"url", "host", "port", "protocol"}
+
 // ValidationError represents a validation error.
 type ValidationError struct {
 	Field   string
@@ -42,6 +56,9 @@ func (v *Validator) Validate(schema *SchemaV1) []ValidationError {
 	// Validate SMTP connections
 	errs = append(errs, v.validateSMTP(schema.SMTP)...)
 
+	// Validate external services
+	errs = append(errs, v.validateExternal(schema.External, schema.Variables)...)
+
 	// Validate deployments
 	errs = append(errs, v.validateDeployments(schema.Deployments)...)
 
@@ -57,6 +74,15 @@ func (v *Validator) Validate(schema *SchemaV1) []ValidationError {
 	// Validate cronjobs
 	errs = append(errs, v.validateCronjobs(schema.Cronjobs)...)
 
+	// Validate common blocks
+	errs = append(errs, v.validateCommon(schema.Common)...)
+
+	// Validate tests
+	errs = append(errs, v.validateTests(schema.Tests)...)
+
+	// Validate webhooks
+	errs = append(errs, v.validateWebhooks(schema.Webhooks)...)
+
 	// Validate observability
 	errs = append(errs, v.validateObservability(schema.Observability)...)
 
@@ -66,6 +92,9 @@ func (v *Validator) Validate(schema *SchemaV1) []ValidationError {
 	// Validate dependencies
 	errs = append(errs, v.validateDependencies(schema.Dependencies)...)
 
+	// Validate required datacenter capabilities
+	errs = append(errs, v.validateRequires(schema.Requires)...)
+
 	return errs
 }
 
@@ -107,6 +136,7 @@ func (v *Validator) validateDatabases(databases map[string]DatabaseV1) []Validat
 				})
 			}
 		}
+		errs = append(errs, validateReadiness(fmt.Sprintf("databases.%s.readiness", name), db.Readiness)...)
 	}
 
 	return errs
@@ -231,6 +261,58 @@ func (v *Validator) validateSMTP(smtp map[string]SMTPV1) []ValidationError {
 	return nil
 }
 
+func (v *Validator) validateExternal(external map[string]ExternalV1, variables map[string]VariableV1) []ValidationError {
+	var errs []ValidationError
+
+	for name, ext := range external {
+		if ext.URL == "" {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("external.%s.url", name),
+				Message: "url is required",
+			})
+		} else if u, err := url.Parse(ext.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			// Egress policy is generated from the URL, so it must be a
+			// literal address rather than an expression
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("external.%s.url", name),
+				Message: fmt.Sprintf("url %q must be an absolute URL with a scheme and host (e.g., https://api.stripe.com)", ext.URL),
+			})
+		}
+
+		for credName, value := range ext.Credentials {
+			field := fmt.Sprintf("external.%s.credentials.%s", name, credName)
+			if strings.Contains(credName, ".") {
+				errs = append(errs, ValidationError{
+					Field:   field,
+					Message: "credential names cannot contain dots",
+				})
+			}
+			if contains(externalOutputs, credName) {
+				errs = append(errs, ValidationError{
+					Field:   field,
+					Message: fmt.Sprintf("credential name %q is reserved for the external service's %s output", credName, credName),
+				})
+			}
+			match := credentialVariablePattern.FindStringSubmatch(strings.TrimSpace(value))
+			if match == nil {
+				errs = append(errs, ValidationError{
+					Field:   field,
+					Message: "credentials must be supplied through a variable (e.g., ${{ variables.stripe_api_key }})",
+				})
+				continue
+			}
+			if _, ok := variables[match[1]]; !ok {
+				errs = append(errs, ValidationError{
+					Field:   field,
+					Message: fmt.Sprintf("references undeclared variable %q", match[1]),
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
 func (v *Validator) validateBuilds(builds map[string]BuildV1) []ValidationError {
 	var errs []ValidationError
 
@@ -261,6 +343,18 @@ func (v *Validator) validateDeployments(deployments map[string]DeploymentV1) []V
 				Message: "replicas must be non-negative",
 			})
 		}
+		errs = append(errs, validateEnvFrom(fmt.Sprintf("deployments.%s.envFrom", name), dep.EnvFrom)...)
+		errs = append(errs, validateFiles(fmt.Sprintf("deployments.%s.files", name), dep.Files)...)
+		errs = append(errs, validateReadiness(fmt.Sprintf("deployments.%s.readiness", name), dep.Readiness)...)
+		if dep.Strategy != nil {
+			errs = append(errs, validateStrategy(fmt.Sprintf("deployments.%s.strategy", name), dep.Strategy)...)
+		}
+		if dep.Lifecycle != nil && dep.Lifecycle.TerminationGracePeriodSeconds < 0 {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("deployments.%s.lifecycle.terminationGracePeriodSeconds", name),
+				Message: "terminationGracePeriodSeconds must be non-negative",
+			})
+		}
 
 		// Validate runtime
 		if dep.Runtime != nil {
@@ -288,10 +382,205 @@ func (v *Validator) validateDeployments(deployments map[string]DeploymentV1) []V
 	return errs
 }
 
+// rolloutAmountPattern matches a maxSurge or maxUnavailable value: a count or
+// a percentage of replicas.
+var rolloutAmountPattern = regexp.MustCompile(`^\d+%?$`)
+
+func validateStrategy(field string, s *StrategyV1) []ValidationError {
+	var errs []ValidationError
+
+	switch s.Type {
+	case "", StrategyRolling:
+		surge, unavailable := interfaceToString(s.MaxSurge), interfaceToString(s.MaxUnavailable)
+		for _, amount := range []struct{ key, value string }{{"maxSurge", surge}, {"maxUnavailable", unavailable}} {
+			if value := amount.value; value != "" && !rolloutAmountPattern.MatchString(value) {
+				errs = append(errs, ValidationError{
+					Field:   field + "." + amount.key,
+					Message: fmt.Sprintf("invalid value %q, must be a non-negative count or a percentage (e.g. 1 or \"25%%\")", value),
+				})
+			}
+		}
+		if isZeroAmount(surge) && isZeroAmount(unavailable) {
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: "maxSurge and maxUnavailable cannot both be 0",
+			})
+		}
+	case StrategyRecreate:
+		if s.MaxSurge != nil || s.MaxUnavailable != nil {
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: "maxSurge and maxUnavailable only apply to the rolling strategy",
+			})
+		}
+	default:
+		errs = append(errs, ValidationError{
+			Field:   field + ".type",
+			Message: fmt.Sprintf("invalid strategy %q, must be one of: %v", s.Type, []string{StrategyRolling, StrategyRecreate}),
+		})
+	}
+
+	if s.MinReadySeconds < 0 {
+		errs = append(errs, ValidationError{
+			Field:   field + ".minReadySeconds",
+			Message: "minReadySeconds must be non-negative",
+		})
+	}
+
+	return errs
+}
+
+func isZeroAmount(v string) bool {
+	return v == "0" || v == "0%"
+}
+
+// validateEnvFrom checks that env files are paths inside the component
+// directory, which is what gets bundled into the component artifact.
+func validateEnvFrom(field string, paths []string) []ValidationError {
+	var errs []ValidationError
+
+	for i, path := range paths {
+		if msg := componentPathError(path); msg != "" {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s[%d]", field, i),
+				Message: msg,
+			})
+		}
+	}
+
+	return errs
+}
+
+// componentPathError describes what's wrong with a path that must point to
+// a file inside the component directory, or returns "" if it's valid.
+func componentPathError(path string) string {
+	clean := filepath.ToSlash(filepath.Clean(path))
+	switch {
+	case path == "":
+		return "path is required"
+	case filepath.IsAbs(path) || strings.HasPrefix(path, "/"):
+		return fmt.Sprintf("path %q must be relative to the component", path)
+	case clean == ".." || strings.HasPrefix(clean, "../"):
+		return fmt.Sprintf("path %q must be inside the component directory", path)
+	}
+	return ""
+}
+
+// validateReadiness checks that a readiness gate runs exactly one kind of
+// check, and that its interval and timeout are positive durations.
+func validateReadiness(field string, r *ReadinessV1) []ValidationError {
+	if r == nil {
+		return nil
+	}
+	var errs []ValidationError
+
+	checks := 0
+	for _, set := range []bool{r.HTTP != "", r.TCP != "", len(r.Command) > 0} {
+		if set {
+			checks++
+		}
+	}
+	if checks != 1 {
+		errs = append(errs, ValidationError{
+			Field:   field,
+			Message: "exactly one of http, tcp, or command is required",
+		})
+	}
+
+	for _, d := range []struct{ name, value string }{{"interval", r.Interval}, {"timeout", r.Timeout}} {
+		if d.value == "" {
+			continue
+		}
+		if parsed, err := time.ParseDuration(d.value); err != nil || parsed <= 0 {
+			errs = append(errs, ValidationError{
+				Field:   field + "." + d.name,
+				Message: fmt.Sprintf("invalid %s %q: expected a positive duration (e.g. 30s)", d.name, d.value),
+			})
+		}
+	}
+
+	return errs
+}
+
+// validateFiles checks that each file a deployment mounts has a unique
+// absolute mount path, takes its content from exactly one place, and has a
+// valid mode.
+func validateFiles(field string, files []FileV1) []ValidationError {
+	var errs []ValidationError
+
+	seen := make(map[string]bool)
+	for i, f := range files {
+		prefix := fmt.Sprintf("%s[%d]", field, i)
+		switch {
+		case f.MountPath == "":
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".mount_path",
+				Message: "mount_path is required",
+			})
+		case !strings.HasPrefix(f.MountPath, "/"):
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".mount_path",
+				Message: fmt.Sprintf("mount_path %q must be absolute", f.MountPath),
+			})
+		case seen[path.Clean(f.MountPath)]:
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".mount_path",
+				Message: fmt.Sprintf("mount_path %q is used by another file", f.MountPath),
+			})
+		}
+		seen[path.Clean(f.MountPath)] = true
+
+		switch {
+		case f.Content != "" && f.Source != "":
+			errs = append(errs, ValidationError{
+				Field:   prefix,
+				Message: "content and source cannot both be set",
+			})
+		case f.Source != "":
+			if msg := componentPathError(f.Source); msg != "" {
+				errs = append(errs, ValidationError{
+					Field:   prefix + ".source",
+					Message: msg,
+				})
+			}
+		}
+
+		if f.Mode != "" {
+			if mode, err := strconv.ParseUint(f.Mode, 8, 32); err != nil || mode > 0777 {
+				errs = append(errs, ValidationError{
+					Field:   prefix + ".mode",
+					Message: fmt.Sprintf("invalid mode %q, must be octal permissions such as \"0644\"", f.Mode),
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+func (v *Validator) validateCommon(common map[string]CommonV1) []ValidationError {
+	var errs []ValidationError
+
+	for _, name := range sortedKeys(common) {
+		block := common[name]
+		for _, key := range sortedKeys(block.Unknown) {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("common.%s.%s", name, key),
+				Message: "common blocks can only set environment, envFrom, cpu, memory, liveness_probe, and readiness_probe",
+			})
+		}
+		errs = append(errs, validateEnvFrom(fmt.Sprintf("common.%s.envFrom", name), block.EnvFrom)...)
+	}
+
+	return errs
+}
+
 func (v *Validator) validateFunctions(functions map[string]FunctionV1) []ValidationError {
 	var errs []ValidationError
 
 	for name, fn := range functions {
+		errs = append(errs, validateReadiness(fmt.Sprintf("functions.%s.readiness", name), fn.Readiness)...)
+
 		// Validate discriminated union: exactly one of src or container must be set
 		hasSrc := fn.Src != nil
 		hasContainer := fn.Container != nil
@@ -352,6 +641,7 @@ func (v *Validator) validateFunctions(functions map[string]FunctionV1) []Validat
 				Message: "timeout must be non-negative",
 			})
 		}
+		errs = append(errs, validateEnvFrom(fmt.Sprintf("functions.%s.envFrom", name), fn.EnvFrom)...)
 	}
 
 	return errs
@@ -392,6 +682,7 @@ func (v *Validator) validateServices(services map[string]ServiceV1, deployments
 				})
 			}
 		}
+		errs = append(errs, validateReadiness(fmt.Sprintf("services.%s.readiness", name), svc.Readiness)...)
 	}
 
 	return errs
@@ -510,6 +801,66 @@ func (v *Validator) validateCronjobs(cronjobs map[string]CronjobV1) []Validation
 	return errs
 }
 
+func (v *Validator) validateTests(tests map[string]TestV1) []ValidationError {
+	var errs []ValidationError
+
+	for name, test := range tests {
+		if len(test.Command) == 0 {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("tests.%s.command", name),
+				Message: "command is required",
+			})
+		}
+		if test.Timeout != "" {
+			if d, err := time.ParseDuration(test.Timeout); err != nil || d <= 0 {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("tests.%s.timeout", name),
+					Message: fmt.Sprintf("invalid timeout %q: expected a positive duration (e.g. 2m)", test.Timeout),
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+func (v *Validator) validateWebhooks(webhooks map[string]WebhookV1) []ValidationError {
+	var errs []ValidationError
+
+	for name, webhook := range webhooks {
+		if webhook.URL == "" {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("webhooks.%s.url", name),
+				Message: "url is required",
+			})
+		}
+		if len(webhook.Events) == 0 {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("webhooks.%s.events", name),
+				Message: "at least one event is required (deploy, destroy)",
+			})
+		}
+		for _, event := range webhook.Events {
+			if event != "deploy" && event != "destroy" {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("webhooks.%s.events", name),
+					Message: fmt.Sprintf("unknown event %q: expected deploy or destroy", event),
+				})
+			}
+		}
+		if webhook.Timeout != "" {
+			if d, err := time.ParseDuration(webhook.Timeout); err != nil || d <= 0 {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("webhooks.%s.timeout", name),
+					Message: fmt.Sprintf("invalid timeout %q: expected a positive duration (e.g. 10s)", webhook.Timeout),
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
 func (v *Validator) validateObservability(obs *ObservabilityV1) []ValidationError {
 	// Observability is optional and has no required fields.
 	// When set to false (Enabled=false), it's a valid no-op.
@@ -556,6 +907,31 @@ func (v *Validator) validateDependencies(dependencies map[string]DependencyV1) [
 	return errs
 }
 
+// validCapabilities are the datacenter capabilities a component can require:
+// resource types, plus database engines for components that need a specific
+// one.
+var validCapabilities = []string{
+	"builds", "databases", "buckets", "encryptionKeys", "smtp", "deployments",
+	"functions", "services", "routes", "cronjobs", "tasks", "observability",
+	"networkPolicies", "routeAuth",
+	"postgres", "mysql", "mongodb", "redis", "mariadb", "cockroachdb", "clickhouse",
+}
+
+func (v *Validator) validateRequires(requires []string) []ValidationError {
+	var errs []ValidationError
+
+	for i, name := range requires {
+		if !contains(validCapabilities, name) {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("requires[%d]", i),
+				Message: fmt.Sprintf("unknown capability %q, must be one of: %v", name, validCapabilities),
+			})
+		}
+	}
+
+	return errs
+}
+
 // isFilePath checks if a reference looks like a local file path.
 func isFilePath(ref string) bool {
 	// Check for path prefixes
@@ -53,6 +53,105 @@ func TestValidator_Validate(t *testing.T) {
 			},
 			wantErrors: 0,
 		},
+		{
+			name: "valid deployment envFrom",
+			schema: &SchemaV1{
+				Deployments: map[string]DeploymentV1{
+					"api": {Image: "nginx:latest", EnvFrom: []string{".env.shared", "config/api.env"}},
+				},
+			},
+			wantErrors: 0,
+		},
+		{
+			name: "envFrom outside the component",
+			schema: &SchemaV1{
+				Deployments: map[string]DeploymentV1{
+					"api": {Image: "nginx:latest", EnvFrom: []string{"../shared.env", "/etc/app.env", ""}},
+				},
+			},
+			wantErrors: 3,
+		},
+		{
+			name: "valid deployment strategy",
+			schema: &SchemaV1{
+				Deployments: map[string]DeploymentV1{
+					"api": {Image: "nginx:latest", Strategy: &StrategyV1{Type: StrategyRolling, MaxSurge: "25%", MaxUnavailable: 0, MinReadySeconds: 10}},
+				},
+			},
+			wantErrors: 0,
+		},
+		{
+			name: "invalid deployment strategy",
+			schema: &SchemaV1{
+				Deployments: map[string]DeploymentV1{
+					"api":    {Image: "nginx:latest", Strategy: &StrategyV1{Type: "blue-green", MinReadySeconds: -1}},
+					"web":    {Image: "nginx:latest", Strategy: &StrategyV1{MaxSurge: "lots"}},
+					"worker": {Image: "nginx:latest", Strategy: &StrategyV1{Type: StrategyRolling, MaxSurge: 0, MaxUnavailable: "0%"}},
+					"batch":  {Image: "nginx:latest", Strategy: &StrategyV1{Type: StrategyRecreate, MaxSurge: 1}},
+				},
+			},
+			wantErrors: 5,
+		},
+		{
+			name: "negative termination grace period",
+			schema: &SchemaV1{
+				Deployments: map[string]DeploymentV1{
+					"api": {Image: "nginx:latest", Lifecycle: &LifecycleV1{PreStop: []string{"sleep", "5"}, TerminationGracePeriodSeconds: -1}},
+				},
+			},
+			wantErrors: 1,
+		},
+		{
+			name: "valid deployment files",
+			schema: &SchemaV1{
+				Deployments: map[string]DeploymentV1{
+					"api": {Image: "nginx:latest", Files: []FileV1{
+						{MountPath: "/etc/nginx/conf.d/default.conf", Source: "config/nginx.conf"},
+						{MountPath: "/app/config.json", Content: `{"db": "${{ databases.main.url }}"}`, Mode: "0600"},
+					}},
+				},
+			},
+			wantErrors: 0,
+		},
+		{
+			name: "invalid deployment files",
+			schema: &SchemaV1{
+				Deployments: map[string]DeploymentV1{
+					"api": {Image: "nginx:latest", Files: []FileV1{
+						{MountPath: "config.json", Content: "{}"},
+						{MountPath: "/app/a.conf", Content: "a", Source: "a.conf"},
+						{MountPath: "/app/a.conf/", Source: "../shared/a.conf"},
+						{MountPath: "/app/b.conf", Content: "b", Mode: "rw"},
+					}},
+				},
+			},
+			wantErrors: 5,
+		},
+		{
+			name: "valid readiness gates",
+			schema: &SchemaV1{
+				Databases: map[string]DatabaseV1{
+					"main": {Type: "postgres:^16", Readiness: &ReadinessV1{Command: []string{"pg_isready", "-d", "${{ databases.main.url }}"}}},
+				},
+				Deployments: map[string]DeploymentV1{
+					"api": {Image: "api:latest", Readiness: &ReadinessV1{TCP: "localhost:${{ ports.api.port }}", Timeout: "2m"}},
+				},
+				Services: map[string]ServiceV1{
+					"api": {Deployment: "api", Port: 8080, Readiness: &ReadinessV1{HTTP: "${{ services.api.url }}/healthz", Interval: "5s"}},
+				},
+			},
+			wantErrors: 0,
+		},
+		{
+			name: "invalid readiness gates",
+			schema: &SchemaV1{
+				Deployments: map[string]DeploymentV1{
+					"api":    {Image: "api:latest", Readiness: &ReadinessV1{Timeout: "soon"}},
+					"worker": {Image: "worker:latest", Readiness: &ReadinessV1{HTTP: "http://localhost:8080", TCP: "localhost:8080", Interval: "-1s"}},
+				},
+			},
+			wantErrors: 4,
+		},
 		{
 			name: "valid deployment without image or build (process-based)",
 			schema: &SchemaV1{
@@ -157,6 +256,111 @@ func TestValidator_Validate(t *testing.T) {
 			},
 			wantErrors: 1,
 		},
+		{
+			name: "valid test",
+			schema: &SchemaV1{
+				Tests: map[string]TestV1{
+					"health": {
+						Command:     []string{"curl", "-f", "$API_URL/health"},
+						Environment: map[string]string{"API_URL": "${{ routes.main.url }}"},
+						Timeout:     "2m",
+					},
+				},
+			},
+			wantErrors: 0,
+		},
+		{
+			name: "test without command and with invalid timeout",
+			schema: &SchemaV1{
+				Tests: map[string]TestV1{
+					"health": {Timeout: "soon"},
+				},
+			},
+			wantErrors: 2,
+		},
+		{
+			name: "valid webhook",
+			schema: &SchemaV1{
+				Webhooks: map[string]WebhookV1{
+					"license": {
+						URL:     "https://license.example.com/environments/${{ environment.name }}",
+						Events:  []string{"deploy", "destroy"},
+						Secret:  "${{ variables.license_secret }}",
+						Timeout: "5s",
+					},
+				},
+			},
+			wantErrors: 0,
+		},
+		{
+			name: "webhook without url and with unknown event",
+			schema: &SchemaV1{
+				Webhooks: map[string]WebhookV1{
+					"license": {Events: []string{"deploy", "rollback"}},
+				},
+			},
+			wantErrors: 2,
+		},
+		{
+			name: "valid external service",
+			schema: &SchemaV1{
+				Variables: map[string]VariableV1{"stripe_key": {Sensitive: true}},
+				External: map[string]ExternalV1{
+					"stripe": {
+						URL:         "https://api.stripe.com",
+						Credentials: map[string]string{"api_key": "${{ variables.stripe_key }}"},
+					},
+				},
+			},
+			wantErrors: 0,
+		},
+		{
+			name: "external service without absolute url",
+			schema: &SchemaV1{
+				External: map[string]ExternalV1{
+					"stripe": {URL: "api.stripe.com"},
+				},
+			},
+			wantErrors: 1,
+		},
+		{
+			name: "external credential not from a variable",
+			schema: &SchemaV1{
+				External: map[string]ExternalV1{
+					"twilio": {
+						URL:         "https://api.twilio.com",
+						Credentials: map[string]string{"auth_token": "hardcoded-token"},
+					},
+				},
+			},
+			wantErrors: 1,
+		},
+		{
+			name: "external credential with undeclared variable and reserved name",
+			schema: &SchemaV1{
+				External: map[string]ExternalV1{
+					"twilio": {
+						URL:         "https://api.twilio.com",
+						Credentials: map[string]string{"host": "${{ variables.twilio_host }}"},
+					},
+				},
+			},
+			wantErrors: 2,
+		},
+		{
+			name: "valid requires",
+			schema: &SchemaV1{
+				Requires: []string{"postgres", "smtp", "cronjobs"},
+			},
+			wantErrors: 0,
+		},
+		{
+			name: "unknown required capability",
+			schema: &SchemaV1{
+				Requires: []string{"postgres", "cronjob"},
+			},
+			wantErrors: 1,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,145 @@
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Constraint names reported by CheckValue.
+const (
+	ConstraintType    = "type"
+	ConstraintEnum    = "enum"
+	ConstraintPattern = "pattern"
+)
+
+// ConstraintError describes a value that violates one of a variable's
+// constraints. Messages never include the value itself, so they are safe to
+// print for sensitive variables.
+type ConstraintError struct {
+	Constraint string
+	Message    string
+}
+
+func (e *ConstraintError) Error() string {
+	return e.Message
+}
+
+// CheckValue checks a value against a variable's type, enum, and pattern.
+// CLI flags and variable files deliver strings, so a string is accepted for
+// a number, bool, list, or object variable when it parses as that type
+// (lists and objects as JSON). An empty type skips the type check.
+func CheckValue(varType string, enum []interface{}, pattern string, value interface{}) *ConstraintError {
+	typed, err := coerce(varType, value)
+	if err != nil {
+		return &ConstraintError{Constraint: ConstraintType, Message: err.Error()}
+	}
+
+	if len(enum) > 0 {
+		allowed := false
+		for _, e := range enum {
+			if sameValue(e, typed) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			options := make([]string, len(enum))
+			for i, e := range enum {
+				options[i] = fmt.Sprint(e)
+			}
+			return &ConstraintError{
+				Constraint: ConstraintEnum,
+				Message:    fmt.Sprintf("must be one of: %s", strings.Join(options, ", ")),
+			}
+		}
+	}
+
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return &ConstraintError{Constraint: ConstraintPattern, Message: fmt.Sprintf("invalid pattern %q: %v", pattern, err)}
+		}
+		if !re.MatchString(fmt.Sprint(typed)) {
+			return &ConstraintError{
+				Constraint: ConstraintPattern,
+				Message:    fmt.Sprintf("must match pattern %s", pattern),
+			}
+		}
+	}
+
+	return nil
+}
+
+// coerce converts a value to the Go representation of varType.
+func coerce(varType string, value interface{}) (interface{}, error) {
+	s, isString := value.(string)
+
+	switch varType {
+	case "":
+		return value, nil
+	case VariableTypeString:
+		switch value.(type) {
+		case string, bool, int, int64, uint64, float64:
+			return fmt.Sprint(value), nil
+		}
+		return nil, fmt.Errorf("must be a string")
+	case VariableTypeNumber:
+		if isString {
+			n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return nil, fmt.Errorf("must be a number")
+			}
+			return n, nil
+		}
+		if !MatchesType(varType, value) {
+			return nil, fmt.Errorf("must be a number")
+		}
+		return value, nil
+	case VariableTypeBool:
+		if isString {
+			b, err := strconv.ParseBool(strings.TrimSpace(s))
+			if err != nil {
+				return nil, fmt.Errorf("must be a bool (true or false)")
+			}
+			return b, nil
+		}
+		if !MatchesType(varType, value) {
+			return nil, fmt.Errorf("must be a bool (true or false)")
+		}
+		return value, nil
+	case VariableTypeList:
+		if isString {
+			var list []interface{}
+			if err := json.Unmarshal([]byte(s), &list); err != nil {
+				return nil, fmt.Errorf("must be a list (JSON array when given as a string)")
+			}
+			return list, nil
+		}
+		if !MatchesType(varType, value) {
+			return nil, fmt.Errorf("must be a list")
+		}
+		return value, nil
+	case VariableTypeObject:
+		if isString {
+			var obj map[string]interface{}
+			if err := json.Unmarshal([]byte(s), &obj); err != nil {
+				return nil, fmt.Errorf("must be an object (JSON object when given as a string)")
+			}
+			return obj, nil
+		}
+		if !MatchesType(varType, value) {
+			return nil, fmt.Errorf("must be an object")
+		}
+		return value, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", varType)
+}
+
+// sameValue compares two decoded values, treating numbers of different Go
+// types (e.g., int from YAML and float64 from JSON) as equal.
+func sameValue(a, b interface{}) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
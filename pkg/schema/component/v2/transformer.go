@@ -0,0 +1,44 @@
+package v2
+
+import (
+	"github.com/davidthor/cldctl/pkg/schema/component/internal"
+	"github.com/davidthor/cldctl/pkg/schema/component/v1"
+)
+
+// Transformer converts v2 schema to internal representation.
+type Transformer struct {
+	v1 *v1.Transformer
+}
+
+// NewTransformer creates a new v2 transformer.
+func NewTransformer() *Transformer {
+	return &Transformer{v1: v1.NewTransformer()}
+}
+
+// Transform converts a v2 schema to the internal representation.
+func (t *Transformer) Transform(v2 *SchemaV2) (*internal.InternalComponent, error) {
+	ic, err := t.v1.Transform(v2.toV1())
+	if err != nil {
+		return nil, err
+	}
+	ic.SourceVersion = "v2"
+
+	// Carry variable types and constraints, which v1 does not have
+	for i := range ic.Variables {
+		v := v2.Variables[ic.Variables[i].Name]
+		ic.Variables[i].Type = v.Type
+		ic.Variables[i].Enum = v.Enum
+		ic.Variables[i].Pattern = v.Pattern
+	}
+
+	// Transform default instances
+	for _, inst := range v2.Instances {
+		ic.Instances = append(ic.Instances, internal.InternalInstance{
+			Name:   inst.Name,
+			Weight: inst.Weight,
+		})
+	}
+	ic.Distinct = v2.Distinct
+
+	return ic, nil
+}
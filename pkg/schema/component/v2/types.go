@@ -0,0 +1,144 @@
+// Package v2 implements the v2 component schema.
+//
+// v2 reorganizes v1 without changing what a component can describe:
+// deployments, functions, and cronjobs are grouped under a single workloads
+// block, variables declare their type, and components can declare the
+// weighted instances they deploy with by default. Builds, resources, and
+// routing keep their v1 shapes, so their types are shared with package v1.
+package v2
+
+import (
+	"github.com/davidthor/cldctl/pkg/schema/component/v1"
+)
+
+// Variable types supported by the v2 schema.
+const (
+	VariableTypeString = "string"
+	VariableTypeNumber = "number"
+	VariableTypeBool   = "bool"
+	VariableTypeList   = "list"
+	VariableTypeObject = "object"
+)
+
+// SchemaV2 represents the v2 component schema.
+type SchemaV2 struct {
+	Version string `yaml:"version" json:"version"`
+	Extends string `yaml:"extends,omitempty" json:"extends,omitempty"`
+
+	Requires []string `yaml:"requires,omitempty" json:"requires,omitempty"`
+
+	Variables map[string]VariableV2 `yaml:"variables,omitempty" json:"variables,omitempty"`
+
+	Builds         map[string]v1.BuildV1         `yaml:"builds,omitempty" json:"builds,omitempty"`
+	Databases      map[string]v1.DatabaseV1      `yaml:"databases,omitempty" json:"databases,omitempty"`
+	Buckets        map[string]v1.BucketV1        `yaml:"buckets,omitempty" json:"buckets,omitempty"`
+	EncryptionKeys map[string]v1.EncryptionKeyV1 `yaml:"encryptionKeys,omitempty" json:"encryptionKeys,omitempty"`
+	SMTP           map[string]v1.SMTPV1          `yaml:"smtp,omitempty" json:"smtp,omitempty"`
+	External       map[string]v1.ExternalV1      `yaml:"external,omitempty" json:"external,omitempty"`
+	Ports          map[string]v1.PortV1          `yaml:"ports,omitempty" json:"ports,omitempty"`
+
+	Workloads WorkloadsV2 `yaml:"workloads,omitempty" json:"workloads,omitempty"`
+
+	// Common declares named blocks of workload configuration that
+	// workloads merge in with extends
+	Common map[string]v1.CommonV1 `yaml:"common,omitempty" json:"common,omitempty"`
+
+	Services map[string]v1.ServiceV1 `yaml:"services,omitempty" json:"services,omitempty"`
+	Routes   map[string]v1.RouteV1   `yaml:"routes,omitempty" json:"routes,omitempty"`
+
+	Tests    map[string]v1.TestV1    `yaml:"tests,omitempty" json:"tests,omitempty"`
+	Webhooks map[string]v1.WebhookV1 `yaml:"webhooks,omitempty" json:"webhooks,omitempty"`
+
+	Observability *v1.ObservabilityV1 `yaml:"observability,omitempty" json:"observability,omitempty"`
+	Injection     *v1.InjectionV1     `yaml:"injection,omitempty" json:"injection,omitempty"`
+
+	// Instances are the weighted instances the component deploys with when
+	// the environment does not configure its own.
+	Instances []InstanceV2 `yaml:"instances,omitempty" json:"instances,omitempty"`
+
+	// Distinct lists shared resources ("<type>.<name>") that should be
+	// created per instance.
+	Distinct []string `yaml:"distinct,omitempty" json:"distinct,omitempty"`
+
+	Dependencies map[string]v1.DependencyV1 `yaml:"dependencies,omitempty" json:"dependencies,omitempty"`
+	Outputs      map[string]v1.OutputV1     `yaml:"outputs,omitempty" json:"outputs,omitempty"`
+
+	// Unknown collects top-level keys the schema does not define so the
+	// validator can reject them (most often v1 workload keys).
+	Unknown map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// WorkloadsV2 groups everything that runs code.
+type WorkloadsV2 struct {
+	Deployments map[string]v1.DeploymentV1 `yaml:"deployments,omitempty" json:"deployments,omitempty"`
+	Functions   map[string]v1.FunctionV1   `yaml:"functions,omitempty" json:"functions,omitempty"`
+	Cronjobs    map[string]v1.CronjobV1    `yaml:"cronjobs,omitempty" json:"cronjobs,omitempty"`
+}
+
+// VariableV2 represents a typed input variable. Provided values are checked
+// against the type and constraints when the component is planned.
+type VariableV2 struct {
+	Type        string        `yaml:"type,omitempty" json:"type,omitempty"` // string, number, bool, list, object; unchecked when omitted
+	Description string        `yaml:"description,omitempty" json:"description,omitempty"`
+	Default     interface{}   `yaml:"default,omitempty" json:"default,omitempty"`
+	Required    bool          `yaml:"required,omitempty" json:"required,omitempty"`
+	Sensitive   bool          `yaml:"sensitive,omitempty" json:"sensitive,omitempty"`
+	Enum        []interface{} `yaml:"enum,omitempty" json:"enum,omitempty"`       // Allowed values
+	Pattern     string        `yaml:"pattern,omitempty" json:"pattern,omitempty"` // Regular expression string values must match
+}
+
+// InstanceV2 is a default weighted instance of the component.
+type InstanceV2 struct {
+	Name   string `yaml:"name" json:"name"`
+	Weight int    `yaml:"weight" json:"weight"`
+}
+
+// ApplyCommon merges the common blocks each workload extends into the
+// workload, reporting errors at their v2 location. See v1.ApplyCommon.
+func (s *SchemaV2) ApplyCommon() []ValidationError {
+	errs := v1.ApplyCommon(s.Common, s.Workloads.Deployments, s.Workloads.Functions, s.Workloads.Cronjobs)
+	for i := range errs {
+		errs[i].Field = "workloads." + errs[i].Field
+	}
+	return errs
+}
+
+// toV1 flattens the schema into its v1 equivalent so that validation and
+// transformation of the shared types can be delegated to package v1.
+func (s *SchemaV2) toV1() *v1.SchemaV1 {
+	variables := make(map[string]v1.VariableV1, len(s.Variables))
+	for name, v := range s.Variables {
+		variables[name] = v1.VariableV1{
+			Description: v.Description,
+			Default:     v.Default,
+			Required:    v.Required,
+			Sensitive:   v.Sensitive,
+		}
+	}
+
+	return &v1.SchemaV1{
+		Version:        s.Version,
+		Extends:        s.Extends,
+		Requires:       s.Requires,
+		Builds:         s.Builds,
+		Databases:      s.Databases,
+		Buckets:        s.Buckets,
+		EncryptionKeys: s.EncryptionKeys,
+		SMTP:           s.SMTP,
+		External:       s.External,
+		Ports:          s.Ports,
+		Deployments:    s.Workloads.Deployments,
+		Functions:      s.Workloads.Functions,
+		Services:       s.Services,
+		Routes:         s.Routes,
+		Cronjobs:       s.Workloads.Cronjobs,
+		Common:         s.Common,
+		Tests:          s.Tests,
+		Webhooks:       s.Webhooks,
+		Observability:  s.Observability,
+		Injection:      s.Injection,
+		Variables:      variables,
+		Dependencies:   s.Dependencies,
+		Outputs:        s.Outputs,
+	}
+}
@@ -0,0 +1,215 @@
+package v2
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/schema/component/v1"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrAlreadyV2 is returned by Migrate for documents that already use v2.
+var ErrAlreadyV2 = errors.New("component already uses schema v2")
+
+// workloadKeys are the v1 top-level keys that move under workloads in v2.
+var workloadKeys = []string{"deployments", "functions", "cronjobs"}
+
+// Upgrade converts a parsed v1 schema to v2 in memory. v1 variables stay
+// untyped so values that were accepted before keep working, and the v1
+// secret alias becomes sensitive.
+func Upgrade(s *v1.SchemaV1) *SchemaV2 {
+	var variables map[string]VariableV2
+	if len(s.Variables) > 0 {
+		variables = make(map[string]VariableV2, len(s.Variables))
+		for name, v := range s.Variables {
+			variables[name] = VariableV2{
+				Description: v.Description,
+				Default:     v.Default,
+				Required:    v.Required,
+				Sensitive:   v.Sensitive || v.Secret,
+			}
+		}
+	}
+
+	return &SchemaV2{
+		Version:        "v2",
+		Extends:        s.Extends,
+		Requires:       s.Requires,
+		Variables:      variables,
+		Builds:         s.Builds,
+		Databases:      s.Databases,
+		Buckets:        s.Buckets,
+		EncryptionKeys: s.EncryptionKeys,
+		SMTP:           s.SMTP,
+		External:       s.External,
+		Ports:          s.Ports,
+		Workloads: WorkloadsV2{
+			Deployments: s.Deployments,
+			Functions:   s.Functions,
+			Cronjobs:    s.Cronjobs,
+		},
+		Common:        s.Common,
+		Services:      s.Services,
+		Routes:        s.Routes,
+		Tests:         s.Tests,
+		Webhooks:      s.Webhooks,
+		Observability: s.Observability,
+		Injection:     s.Injection,
+		Dependencies:  s.Dependencies,
+		Outputs:       s.Outputs,
+	}
+}
+
+// Migrate rewrites a v1 component document as v2. It edits the YAML tree
+// rather than re-encoding the parsed schema, so comments, key order, and
+// expression strings are preserved:
+//
+//   - version is set to v2
+//   - deployments, functions, and cronjobs move under workloads
+//   - every variable gets a type inferred from its default
+//   - the secret alias on variables becomes sensitive
+func Migrate(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("component must be a YAML mapping")
+	}
+	root := doc.Content[0]
+
+	// Set the version
+	if _, version := mappingEntry(root, "version"); version != nil {
+		v := strings.ToLower(version.Value)
+		if !strings.HasPrefix(v, "v") {
+			v = "v" + v
+		}
+		switch v {
+		case "v1":
+		case "v2":
+			return nil, ErrAlreadyV2
+		default:
+			return nil, fmt.Errorf("unsupported schema version: %s", version.Value)
+		}
+		version.Value = "v2"
+		version.Tag = "!!str"
+		version.Style = 0
+	} else {
+		// Keep a leading file comment above the new version key
+		key := scalarNode("version")
+		if len(root.Content) > 0 {
+			key.HeadComment, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+		}
+		root.Content = append([]*yaml.Node{key, scalarNode("v2")}, root.Content...)
+	}
+
+	migrateWorkloads(root)
+
+	if _, variables := mappingEntry(root, "variables"); variables != nil && variables.Kind == yaml.MappingNode {
+		for i := 1; i < len(variables.Content); i += 2 {
+			variables.Content[i] = migrateVariable(variables.Content[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// migrateWorkloads moves the v1 workload sections into a workloads block
+// placed where the first of them appeared.
+func migrateWorkloads(root *yaml.Node) {
+	workloads := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	insertAt := -1
+
+	var kept []*yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if isWorkloadKey(key.Value) {
+			if insertAt < 0 {
+				insertAt = len(kept)
+			}
+			workloads.Content = append(workloads.Content, key, value)
+			continue
+		}
+		kept = append(kept, key, value)
+	}
+	if insertAt < 0 {
+		return
+	}
+
+	content := make([]*yaml.Node, 0, len(kept)+2)
+	content = append(content, kept[:insertAt]...)
+	content = append(content, scalarNode("workloads"), workloads)
+	content = append(content, kept[insertAt:]...)
+	root.Content = content
+}
+
+// migrateVariable adds a type to a variable definition and renames secret
+// to sensitive. Empty definitions (e.g., "api_key:") become typed mappings.
+func migrateVariable(node *yaml.Node) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+			return &yaml.Node{
+				Kind:    yaml.MappingNode,
+				Tag:     "!!map",
+				Content: []*yaml.Node{scalarNode("type"), scalarNode(VariableTypeString)},
+			}
+		}
+		return node
+	}
+
+	if secretIdx, secret := mappingEntry(node, "secret"); secret != nil {
+		if _, sensitive := mappingEntry(node, "sensitive"); sensitive == nil {
+			node.Content[secretIdx].Value = "sensitive"
+		} else {
+			if secret.Value == "true" {
+				sensitive.Value = "true"
+			}
+			node.Content = append(node.Content[:secretIdx], node.Content[secretIdx+2:]...)
+		}
+	}
+
+	if _, typ := mappingEntry(node, "type"); typ == nil {
+		var def interface{}
+		if _, defNode := mappingEntry(node, "default"); defNode != nil {
+			_ = defNode.Decode(&def)
+		}
+		node.Content = append([]*yaml.Node{scalarNode("type"), scalarNode(InferType(def))}, node.Content...)
+	}
+
+	return node
+}
+
+// mappingEntry returns the index of key within a mapping node's content and
+// the node of its value.
+func mappingEntry(node *yaml.Node, key string) (int, *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i, node.Content[i+1]
+		}
+	}
+	return -1, nil
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func isWorkloadKey(key string) bool {
+	for _, k := range workloadKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
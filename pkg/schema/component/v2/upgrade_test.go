@@ -0,0 +1,148 @@
+package v2
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/schema/component/v1"
+)
+
+func TestMigrate(t *testing.T) {
+	input := `# Component header
+builds:
+  api:
+    context: .
+variables:
+  api_key:
+    secret: true
+  replicas:
+    default: 2
+  debug:
+deployments:
+  api:
+    image: ${{ builds.api.image }} # built image
+services:
+  api:
+    deployment: api
+    port: 8080
+cronjobs:
+  cleanup:
+    image: alpine
+    schedule: "0 * * * *"
+`
+	out, err := Migrate([]byte(input))
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	got := string(out)
+
+	for _, want := range []string{
+		"# Component header\nversion: v2\n",
+		"  api_key:\n    type: string\n    sensitive: true\n",
+		"  replicas:\n    type: number\n    default: 2\n",
+		"  debug:\n    type: string\n",
+		"workloads:\n  deployments:\n    api:\n      image: ${{ builds.api.image }} # built image\n",
+		"  cronjobs:\n    cleanup:\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Index(got, "workloads:") > strings.Index(got, "services:") {
+		t.Errorf("expected workloads to replace deployments in place, got:\n%s", got)
+	}
+
+	// The result parses and validates as v2
+	schema, err := NewParser().ParseBytes(out)
+	if err != nil {
+		t.Fatalf("failed to parse migrated document: %v", err)
+	}
+	if errs := NewValidator().Validate(schema); len(errs) > 0 {
+		t.Errorf("migrated document is invalid: %v", errs)
+	}
+	if len(schema.Workloads.Deployments) != 1 || len(schema.Workloads.Cronjobs) != 1 {
+		t.Errorf("expected workloads to be parsed, got %+v", schema.Workloads)
+	}
+}
+
+func TestMigrate_AlreadyV2(t *testing.T) {
+	_, err := Migrate([]byte("version: v2\n"))
+	if !errors.Is(err, ErrAlreadyV2) {
+		t.Errorf("expected ErrAlreadyV2, got %v", err)
+	}
+
+	if _, err := Migrate([]byte("version: v3\n")); err == nil {
+		t.Error("expected error for unknown version")
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	s := &v1.SchemaV1{
+		Deployments: map[string]v1.DeploymentV1{"api": {Image: "nginx"}},
+		Variables: map[string]v1.VariableV1{
+			"token":   {Secret: true},
+			"enabled": {Default: true},
+			"labels":  {Default: map[string]interface{}{"team": "core"}},
+		},
+	}
+
+	upgraded := Upgrade(s)
+	if upgraded.Version != "v2" {
+		t.Errorf("expected version v2, got %q", upgraded.Version)
+	}
+	if _, ok := upgraded.Workloads.Deployments["api"]; !ok {
+		t.Error("expected deployment to move under workloads")
+	}
+
+	for name, v := range upgraded.Variables {
+		if v.Type != "" {
+			t.Errorf("variable %s: expected v1 variable to stay untyped, got %s", name, v.Type)
+		}
+	}
+	if !upgraded.Variables["token"].Sensitive {
+		t.Error("expected secret alias to become sensitive")
+	}
+}
+
+func TestTransform(t *testing.T) {
+	schema, err := NewParser().ParseBytes([]byte(`version: v2
+variables:
+  replicas:
+    type: number
+    default: 2
+workloads:
+  deployments:
+    api:
+      image: nginx
+instances:
+  - name: stable
+    weight: 90
+  - name: canary
+    weight: 10
+distinct: [database.main]
+`))
+	if err != nil {
+		t.Fatalf("ParseBytes failed: %v", err)
+	}
+
+	ic, err := NewTransformer().Transform(schema)
+	if err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+	if ic.SourceVersion != "v2" {
+		t.Errorf("expected source version v2, got %q", ic.SourceVersion)
+	}
+	if len(ic.Deployments) != 1 || ic.Deployments[0].Name != "api" {
+		t.Errorf("expected deployment api, got %+v", ic.Deployments)
+	}
+	if len(ic.Variables) != 1 || ic.Variables[0].Type != VariableTypeNumber {
+		t.Errorf("expected typed variable, got %+v", ic.Variables)
+	}
+	if len(ic.Instances) != 2 || ic.Instances[1].Name != "canary" || ic.Instances[1].Weight != 10 {
+		t.Errorf("expected instances to be transformed, got %+v", ic.Instances)
+	}
+	if len(ic.Distinct) != 1 || ic.Distinct[0] != "database.main" {
+		t.Errorf("expected distinct to be transformed, got %v", ic.Distinct)
+	}
+}
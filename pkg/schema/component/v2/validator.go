@@ -0,0 +1,227 @@
+package v2
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/schema/component/v1"
+)
+
+// ValidationError represents a validation error.
+type ValidationError = v1.ValidationError
+
+// Validator validates v2 component schemas.
+type Validator struct {
+	v1 *v1.Validator
+}
+
+// NewValidator creates a new v2 validator.
+func NewValidator() *Validator {
+	return &Validator{v1: v1.NewValidator()}
+}
+
+// Validate validates a v2 schema and returns all validation errors.
+func (v *Validator) Validate(schema *SchemaV2) []ValidationError {
+	var errs []ValidationError
+
+	// Reject keys that are not part of the v2 schema
+	errs = append(errs, v.validateUnknownKeys(schema.Unknown)...)
+
+	// Validate the sections shared with v1, reporting workload errors at
+	// their v2 location
+	for _, e := range v.v1.Validate(schema.toV1()) {
+		for _, section := range []string{"deployments.", "functions.", "cronjobs."} {
+			if strings.HasPrefix(e.Field, section) {
+				e.Field = "workloads." + e.Field
+				break
+			}
+		}
+		errs = append(errs, e)
+	}
+
+	// Validate variable types
+	errs = append(errs, v.validateVariables(schema.Variables)...)
+
+	// Validate instances
+	errs = append(errs, v.validateInstances(schema.Instances, schema.Distinct)...)
+
+	return errs
+}
+
+func (v *Validator) validateUnknownKeys(unknown map[string]interface{}) []ValidationError {
+	var errs []ValidationError
+
+	keys := make([]string, 0, len(unknown))
+	for key := range unknown {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		msg := "unknown field"
+		switch key {
+		case "deployments", "functions", "cronjobs":
+			msg = fmt.Sprintf("%s must be declared under workloads in v2 (run 'cldctl migrate component' to convert v1 files)", key)
+		}
+		errs = append(errs, ValidationError{Field: key, Message: msg})
+	}
+
+	return errs
+}
+
+func (v *Validator) validateVariables(variables map[string]VariableV2) []ValidationError {
+	var errs []ValidationError
+
+	for name, variable := range variables {
+		field := fmt.Sprintf("variables.%s", name)
+
+		if variable.Type != "" && !isVariableType(variable.Type) {
+			errs = append(errs, ValidationError{
+				Field:   field + ".type",
+				Message: fmt.Sprintf("unknown type %q (must be string, number, bool, list, or object)", variable.Type),
+			})
+			continue
+		}
+
+		if variable.Pattern != "" {
+			if variable.Type != "" && variable.Type != VariableTypeString {
+				errs = append(errs, ValidationError{
+					Field:   field + ".pattern",
+					Message: "pattern is only supported for string variables",
+				})
+				continue
+			}
+			if _, err := regexp.Compile(variable.Pattern); err != nil {
+				errs = append(errs, ValidationError{
+					Field:   field + ".pattern",
+					Message: fmt.Sprintf("invalid regular expression: %v", err),
+				})
+				continue
+			}
+		}
+
+		enumValid := true
+		for i, e := range variable.Enum {
+			if variable.Type != "" && !MatchesType(variable.Type, e) {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("%s.enum[%d]", field, i),
+					Message: fmt.Sprintf("value does not match type %s", variable.Type),
+				})
+				enumValid = false
+			}
+		}
+
+		if variable.Default != nil && enumValid {
+			if variable.Type != "" && !MatchesType(variable.Type, variable.Default) {
+				errs = append(errs, ValidationError{
+					Field:   field + ".default",
+					Message: fmt.Sprintf("default value does not match type %s", variable.Type),
+				})
+			} else if cerr := CheckValue(variable.Type, variable.Enum, variable.Pattern, variable.Default); cerr != nil {
+				errs = append(errs, ValidationError{
+					Field:   field + ".default",
+					Message: fmt.Sprintf("default value %s", cerr.Message),
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+func (v *Validator) validateInstances(instances []InstanceV2, distinct []string) []ValidationError {
+	var errs []ValidationError
+
+	seen := make(map[string]bool)
+	totalWeight := 0
+	for i, inst := range instances {
+		field := fmt.Sprintf("instances[%d]", i)
+		if inst.Name == "" {
+			errs = append(errs, ValidationError{Field: field + ".name", Message: "name is required"})
+		} else if seen[inst.Name] {
+			errs = append(errs, ValidationError{Field: field + ".name", Message: fmt.Sprintf("duplicate instance name %q", inst.Name)})
+		}
+		seen[inst.Name] = true
+
+		if inst.Weight < 0 || inst.Weight > 100 {
+			errs = append(errs, ValidationError{Field: field + ".weight", Message: "weight must be between 0 and 100"})
+		}
+		totalWeight += inst.Weight
+	}
+
+	if totalWeight > 100 {
+		errs = append(errs, ValidationError{
+			Field:   "instances",
+			Message: fmt.Sprintf("total instance weights (%d) exceed 100", totalWeight),
+		})
+	}
+
+	if len(distinct) > 0 && len(instances) == 0 {
+		errs = append(errs, ValidationError{Field: "distinct", Message: "distinct requires instances"})
+	}
+	for i, d := range distinct {
+		if typ, name, ok := strings.Cut(d, "."); !ok || typ == "" || name == "" {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("distinct[%d]", i),
+				Message: fmt.Sprintf("%q must be in the form <type>.<name> (e.g., database.main)", d),
+			})
+		}
+	}
+
+	return errs
+}
+
+func isVariableType(t string) bool {
+	switch t {
+	case VariableTypeString, VariableTypeNumber, VariableTypeBool, VariableTypeList, VariableTypeObject:
+		return true
+	}
+	return false
+}
+
+// MatchesType reports whether a decoded YAML or JSON value is of the given
+// variable type.
+func MatchesType(varType string, value interface{}) bool {
+	switch varType {
+	case VariableTypeString:
+		_, ok := value.(string)
+		return ok
+	case VariableTypeNumber:
+		switch value.(type) {
+		case int, int64, uint64, float64:
+			return true
+		}
+		return false
+	case VariableTypeBool:
+		_, ok := value.(bool)
+		return ok
+	case VariableTypeList:
+		_, ok := value.([]interface{})
+		return ok
+	case VariableTypeObject:
+		switch value.(type) {
+		case map[string]interface{}, map[interface{}]interface{}:
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+// InferType returns the variable type of a default value. Variables without
+// a default are strings.
+func InferType(value interface{}) string {
+	switch value.(type) {
+	case bool:
+		return VariableTypeBool
+	case int, int64, uint64, float64:
+		return VariableTypeNumber
+	case []interface{}:
+		return VariableTypeList
+	case map[string]interface{}, map[interface{}]interface{}:
+		return VariableTypeObject
+	}
+	return VariableTypeString
+}
@@ -0,0 +1,142 @@
+package v2
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/schema/component/v1"
+)
+
+func TestValidator_Validate(t *testing.T) {
+	validator := NewValidator()
+
+	tests := []struct {
+		name      string
+		schema    *SchemaV2
+		wantField string
+	}{
+		{
+			name:   "valid empty schema",
+			schema: &SchemaV2{Version: "v2"},
+		},
+		{
+			name: "v1 workload key",
+			schema: &SchemaV2{
+				Unknown: map[string]interface{}{"deployments": map[string]interface{}{}},
+			},
+			wantField: "deployments",
+		},
+		{
+			name: "workload errors use v2 path",
+			schema: &SchemaV2{
+				Workloads: WorkloadsV2{
+					Deployments: map[string]v1.DeploymentV1{"api": {Replicas: -1}},
+				},
+			},
+			wantField: "workloads.deployments.api",
+		},
+		{
+			name: "unknown variable type",
+			schema: &SchemaV2{
+				Variables: map[string]VariableV2{"x": {Type: "integer"}},
+			},
+			wantField: "variables.x.type",
+		},
+		{
+			name: "default does not match type",
+			schema: &SchemaV2{
+				Variables: map[string]VariableV2{"x": {Type: VariableTypeNumber, Default: "two"}},
+			},
+			wantField: "variables.x.default",
+		},
+		{
+			name: "valid typed variables",
+			schema: &SchemaV2{
+				Variables: map[string]VariableV2{
+					"n":    {Type: VariableTypeNumber, Default: 2},
+					"b":    {Type: VariableTypeBool, Default: false},
+					"l":    {Type: VariableTypeList, Default: []interface{}{"a"}},
+					"o":    {Type: VariableTypeObject, Default: map[string]interface{}{"a": 1}},
+					"name": {Default: "api"},
+				},
+			},
+		},
+		{
+			name: "invalid pattern",
+			schema: &SchemaV2{
+				Variables: map[string]VariableV2{"x": {Type: VariableTypeString, Pattern: "(["}},
+			},
+			wantField: "variables.x.pattern",
+		},
+		{
+			name: "pattern on non-string variable",
+			schema: &SchemaV2{
+				Variables: map[string]VariableV2{"x": {Type: VariableTypeNumber, Pattern: "^1"}},
+			},
+			wantField: "variables.x.pattern",
+		},
+		{
+			name: "enum value does not match type",
+			schema: &SchemaV2{
+				Variables: map[string]VariableV2{"x": {Type: VariableTypeNumber, Enum: []interface{}{1, "two"}}},
+			},
+			wantField: "variables.x.enum[1]",
+		},
+		{
+			name: "default not in enum",
+			schema: &SchemaV2{
+				Variables: map[string]VariableV2{"x": {Type: VariableTypeString, Enum: []interface{}{"a", "b"}, Default: "c"}},
+			},
+			wantField: "variables.x.default",
+		},
+		{
+			name: "valid constraints",
+			schema: &SchemaV2{
+				Variables: map[string]VariableV2{
+					"size": {Type: VariableTypeNumber, Enum: []interface{}{1, 2, 4}, Default: 2},
+					"slug": {Type: VariableTypeString, Pattern: "^[a-z]+$", Default: "api"},
+				},
+			},
+		},
+		{
+			name: "instance weights exceed 100",
+			schema: &SchemaV2{
+				Instances: []InstanceV2{{Name: "a", Weight: 60}, {Name: "b", Weight: 60}},
+			},
+			wantField: "instances",
+		},
+		{
+			name: "duplicate instance name",
+			schema: &SchemaV2{
+				Instances: []InstanceV2{{Name: "a", Weight: 50}, {Name: "a", Weight: 50}},
+			},
+			wantField: "instances[1].name",
+		},
+		{
+			name: "malformed distinct",
+			schema: &SchemaV2{
+				Instances: []InstanceV2{{Name: "a", Weight: 100}},
+				Distinct:  []string{"main"},
+			},
+			wantField: "distinct[0]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validator.Validate(tt.schema)
+			if tt.wantField == "" {
+				if len(errs) > 0 {
+					t.Errorf("expected no errors, got %v", errs)
+				}
+				return
+			}
+			for _, e := range errs {
+				if strings.HasPrefix(e.Field, tt.wantField) {
+					return
+				}
+			}
+			t.Errorf("expected an error for %s, got %v", tt.wantField, errs)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package component
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/schema/component/v2"
+)
+
+// InvalidVariable describes a provided value that violates a variable's
+// declaration.
+type InvalidVariable struct {
+	Variable   string
+	Constraint string // type, enum, or pattern
+	Message    string
+}
+
+// VariableValidationError is returned when provided variable values violate
+// the component's variable declarations.
+type VariableValidationError struct {
+	Invalid []InvalidVariable
+}
+
+func (e *VariableValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("invalid variable values:")
+	for _, iv := range e.Invalid {
+		fmt.Fprintf(&b, "\n  - %s: %s (%s constraint)", iv.Variable, iv.Message, iv.Constraint)
+	}
+	return b.String()
+}
+
+// ValidateVariableValues checks provided values against the type, enum, and
+// pattern of the component's variables. Values that are still unresolved
+// expressions are skipped, as are values for undeclared variables. Values of
+// sensitive variables are never included in the error.
+func ValidateVariableValues(comp Component, values map[string]interface{}) error {
+	var invalid []InvalidVariable
+
+	for _, v := range comp.Variables() {
+		value, ok := values[v.Name()]
+		if !ok || value == nil {
+			continue
+		}
+		if s, isString := value.(string); isString && strings.Contains(s, "${{") {
+			continue
+		}
+
+		cerr := v2.CheckValue(v.Type(), v.Enum(), v.Pattern(), value)
+		if cerr == nil {
+			continue
+		}
+
+		msg := cerr.Message
+		if !v.Sensitive() {
+			msg += fmt.Sprintf(" (got %s)", formatVariableValue(value))
+		}
+		invalid = append(invalid, InvalidVariable{
+			Variable:   v.Name(),
+			Constraint: cerr.Constraint,
+			Message:    msg,
+		})
+	}
+
+	if len(invalid) == 0 {
+		return nil
+	}
+	sort.Slice(invalid, func(i, j int) bool { return invalid[i].Variable < invalid[j].Variable })
+	return &VariableValidationError{Invalid: invalid}
+}
+
+func formatVariableValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,97 @@
+package component
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const typedVariablesComponent = `version: v2
+variables:
+  replicas:
+    type: number
+    default: 2
+  tier:
+    type: string
+    enum: [free, pro]
+  slug:
+    type: string
+    pattern: ^[a-z0-9-]+$
+  token:
+    type: string
+    sensitive: true
+    pattern: ^tok_
+  flags:
+    type: list
+  legacy: {}
+workloads:
+  deployments:
+    api:
+      image: nginx
+`
+
+func TestValidateVariableValues(t *testing.T) {
+	comp, err := NewLoader().LoadFromBytes([]byte(typedVariablesComponent), "cld.yml")
+	require.NoError(t, err)
+
+	t.Run("valid values", func(t *testing.T) {
+		err := ValidateVariableValues(comp, map[string]interface{}{
+			"replicas": "3",
+			"tier":     "pro",
+			"slug":     "my-app",
+			"token":    "tok_123",
+			"flags":    `["a","b"]`,
+			"legacy":   []interface{}{"anything"},
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("unresolved expressions are skipped", func(t *testing.T) {
+		err := ValidateVariableValues(comp, map[string]interface{}{
+			"replicas": "${{ variables.count }}",
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("reports every failed constraint", func(t *testing.T) {
+		err := ValidateVariableValues(comp, map[string]interface{}{
+			"replicas": "many",
+			"tier":     "enterprise",
+			"slug":     "My App",
+			"token":    "hunter2",
+		})
+		require.Error(t, err)
+
+		var verr *VariableValidationError
+		require.True(t, errors.As(err, &verr))
+		require.Len(t, verr.Invalid, 4)
+
+		byName := map[string]InvalidVariable{}
+		for _, iv := range verr.Invalid {
+			byName[iv.Variable] = iv
+		}
+		assert.Equal(t, "type", byName["replicas"].Constraint)
+		assert.Equal(t, "enum", byName["tier"].Constraint)
+		assert.Contains(t, byName["tier"].Message, "free, pro")
+		assert.Equal(t, "pattern", byName["slug"].Constraint)
+		assert.Equal(t, "pattern", byName["token"].Constraint)
+
+		// Sensitive values are not echoed
+		assert.False(t, strings.Contains(err.Error(), "hunter2"))
+		assert.Contains(t, err.Error(), `"My App"`)
+	})
+}
+
+func TestLoader_RejectsInvalidVariableDefaults(t *testing.T) {
+	_, err := NewLoader().LoadFromBytes([]byte(`version: v2
+variables:
+  tier:
+    type: string
+    enum: [free, pro]
+    default: trial
+`), "cld.yml")
+	require.Error(t, err)
+}
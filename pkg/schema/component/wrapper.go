@@ -61,6 +61,14 @@ func (c *componentWrapper) SMTP() []SMTPConnection {
 	return result
 }
 
+func (c *componentWrapper) External() []ExternalService {
+	result := make([]ExternalService, len(c.ic.External))
+	for i := range c.ic.External {
+		result[i] = &externalWrapper{e: &c.ic.External[i]}
+	}
+	return result
+}
+
 func (c *componentWrapper) Ports() []Port {
 	result := make([]Port, len(c.ic.Ports))
 	for i := range c.ic.Ports {
@@ -109,6 +117,22 @@ func (c *componentWrapper) Cronjobs() []Cronjob {
 	return result
 }
 
+func (c *componentWrapper) Tests() []Test {
+	result := make([]Test, len(c.ic.Tests))
+	for i := range c.ic.Tests {
+		result[i] = &testWrapper{t: &c.ic.Tests[i]}
+	}
+	return result
+}
+
+func (c *componentWrapper) Webhooks() []Webhook {
+	result := make([]Webhook, len(c.ic.Webhooks))
+	for i := range c.ic.Webhooks {
+		result[i] = &webhookWrapper{w: &c.ic.Webhooks[i]}
+	}
+	return result
+}
+
 func (c *componentWrapper) Observability() Observability {
 	if c.ic.Observability == nil {
 		return nil
@@ -116,6 +140,17 @@ func (c *componentWrapper) Observability() Observability {
 	return &observabilityWrapper{obs: c.ic.Observability}
 }
 
+func (c *componentWrapper) Injection() Injection {
+	if c.ic.Injection == nil {
+		return nil
+	}
+	return &injectionWrapper{inj: c.ic.Injection}
+}
+
+func (c *componentWrapper) Requires() []string {
+	return c.ic.Requires
+}
+
 func (c *componentWrapper) Variables() []Variable {
 	result := make([]Variable, len(c.ic.Variables))
 	for i := range c.ic.Variables {
@@ -124,6 +159,16 @@ func (c *componentWrapper) Variables() []Variable {
 	return result
 }
 
+func (c *componentWrapper) Instances() []Instance {
+	result := make([]Instance, len(c.ic.Instances))
+	for i := range c.ic.Instances {
+		result[i] = &instanceWrapper{inst: &c.ic.Instances[i]}
+	}
+	return result
+}
+
+func (c *componentWrapper) Distinct() []string { return c.ic.Distinct }
+
 func (c *componentWrapper) Dependencies() []Dependency {
 	result := make([]Dependency, len(c.ic.Dependencies))
 	for i := range c.ic.Dependencies {
@@ -157,6 +202,8 @@ func (d *databaseWrapper) Name() string    { return d.db.Name }
 func (d *databaseWrapper) Type() string    { return d.db.Type }
 func (d *databaseWrapper) Version() string { return d.db.Version }
 
+func (d *databaseWrapper) Readiness() Readiness { return wrapReadiness(d.db.Readiness) }
+
 func (d *databaseWrapper) Migrations() Migrations {
 	if d.db.Migrations == nil {
 		return nil
@@ -231,6 +278,16 @@ type smtpWrapper struct {
 func (s *smtpWrapper) Name() string        { return s.s.Name }
 func (s *smtpWrapper) Description() string { return s.s.Description }
 
+// External service wrapper
+type externalWrapper struct {
+	e *internal.InternalExternal
+}
+
+func (e *externalWrapper) Name() string                   { return e.e.Name }
+func (e *externalWrapper) URL() string                    { return e.e.URL }
+func (e *externalWrapper) Description() string            { return e.e.Description }
+func (e *externalWrapper) Credentials() map[string]string { return e.e.Credentials }
+
 // Port wrapper
 type portWrapper struct {
 	p *internal.InternalPort
@@ -260,6 +317,41 @@ func (d *deploymentWrapper) Runtime() Runtime {
 	return &runtimeWrapper{rt: d.dep.Runtime}
 }
 
+func (d *deploymentWrapper) Strategy() Strategy {
+	if d.dep.Strategy == nil {
+		return nil
+	}
+	return &strategyWrapper{s: d.dep.Strategy}
+}
+
+// Strategy wrapper
+type strategyWrapper struct {
+	s *internal.InternalStrategy
+}
+
+func (s *strategyWrapper) Type() string           { return s.s.Type }
+func (s *strategyWrapper) MaxSurge() string       { return s.s.MaxSurge }
+func (s *strategyWrapper) MaxUnavailable() string { return s.s.MaxUnavailable }
+func (s *strategyWrapper) MinReadySeconds() int   { return s.s.MinReadySeconds }
+
+func (d *deploymentWrapper) Lifecycle() Lifecycle {
+	if d.dep.Lifecycle == nil {
+		return nil
+	}
+	return &lifecycleWrapper{l: d.dep.Lifecycle}
+}
+
+// Lifecycle wrapper
+type lifecycleWrapper struct {
+	l *internal.InternalLifecycle
+}
+
+func (l *lifecycleWrapper) PostStart() []string { return l.l.PostStart }
+func (l *lifecycleWrapper) PreStop() []string   { return l.l.PreStop }
+func (l *lifecycleWrapper) TerminationGracePeriodSeconds() int {
+	return l.l.TerminationGracePeriodSeconds
+}
+
 // Runtime wrapper
 type runtimeWrapper struct {
 	rt *internal.InternalRuntime
@@ -279,6 +371,10 @@ func (d *deploymentWrapper) Environment() map[string]string {
 	return result
 }
 
+func (d *deploymentWrapper) EnvFrom() []EnvFile {
+	return wrapEnvFiles(d.dep.EnvFrom)
+}
+
 func (d *deploymentWrapper) Volumes() []Volume {
 	result := make([]Volume, len(d.dep.Volumes))
 	for i := range d.dep.Volumes {
@@ -287,6 +383,16 @@ func (d *deploymentWrapper) Volumes() []Volume {
 	return result
 }
 
+func (d *deploymentWrapper) Readiness() Readiness { return wrapReadiness(d.dep.Readiness) }
+
+func (d *deploymentWrapper) Files() []File {
+	result := make([]File, len(d.dep.Files))
+	for i := range d.dep.Files {
+		result[i] = &fileWrapper{f: &d.dep.Files[i]}
+	}
+	return result
+}
+
 func (d *deploymentWrapper) LivenessProbe() Probe {
 	if d.dep.LivenessProbe == nil {
 		return nil
@@ -312,6 +418,8 @@ func (f *functionWrapper) CPU() string    { return f.fn.CPU }
 func (f *functionWrapper) Memory() string { return f.fn.Memory }
 func (f *functionWrapper) Timeout() int   { return f.fn.Timeout }
 
+func (f *functionWrapper) Readiness() Readiness { return wrapReadiness(f.fn.Readiness) }
+
 func (f *functionWrapper) Src() FunctionSource {
 	if f.fn.Src == nil {
 		return nil
@@ -337,6 +445,33 @@ func (f *functionWrapper) Environment() map[string]string {
 	return result
 }
 
+func (f *functionWrapper) EnvFrom() []EnvFile {
+	return wrapEnvFiles(f.fn.EnvFrom)
+}
+
+// EnvFile wrapper
+type envFileWrapper struct {
+	f *internal.InternalEnvFile
+}
+
+func wrapEnvFiles(files []internal.InternalEnvFile) []EnvFile {
+	result := make([]EnvFile, len(files))
+	for i := range files {
+		result[i] = &envFileWrapper{f: &files[i]}
+	}
+	return result
+}
+
+func (e *envFileWrapper) Path() string { return e.f.Path }
+
+func (e *envFileWrapper) Environment() map[string]string {
+	result := make(map[string]string)
+	for k, v := range e.f.Environment {
+		result[k] = v.Raw
+	}
+	return result
+}
+
 // FunctionSource wrapper
 type functionSourceWrapper struct {
 	src *internal.InternalFunctionSource
@@ -378,6 +513,26 @@ func (s *serviceWrapper) URL() string        { return s.svc.URL }
 func (s *serviceWrapper) Port() string       { return s.svc.Port.Raw }
 func (s *serviceWrapper) Protocol() string   { return s.svc.Protocol }
 
+func (s *serviceWrapper) Readiness() Readiness { return wrapReadiness(s.svc.Readiness) }
+
+// Readiness wrapper
+type readinessWrapper struct {
+	r *internal.InternalReadiness
+}
+
+func wrapReadiness(r *internal.InternalReadiness) Readiness {
+	if r == nil {
+		return nil
+	}
+	return &readinessWrapper{r: r}
+}
+
+func (r *readinessWrapper) HTTP() string      { return r.r.HTTP }
+func (r *readinessWrapper) TCP() string       { return r.r.TCP }
+func (r *readinessWrapper) Command() []string { return r.r.Command }
+func (r *readinessWrapper) Interval() string  { return r.r.Interval }
+func (r *readinessWrapper) Timeout() string   { return r.r.Timeout }
+
 // Route wrapper
 type routeWrapper struct {
 	rt *internal.InternalRoute
@@ -389,6 +544,21 @@ func (r *routeWrapper) Internal() bool   { return r.rt.Internal }
 func (r *routeWrapper) Service() string  { return r.rt.Service }
 func (r *routeWrapper) Function() string { return r.rt.Function }
 
+func (r *routeWrapper) Auth() RouteAuth {
+	if r.rt.Auth == nil {
+		return nil
+	}
+	return &routeAuthWrapper{auth: r.rt.Auth}
+}
+
+// RouteAuth wrapper
+type routeAuthWrapper struct {
+	auth *internal.InternalRouteAuth
+}
+
+func (a *routeAuthWrapper) Provider() string               { return a.auth.Provider }
+func (a *routeAuthWrapper) Config() map[string]interface{} { return a.auth.Config }
+
 func (r *routeWrapper) Rules() []RouteRule {
 	result := make([]RouteRule, len(r.rt.Rules))
 	for i := range r.rt.Rules {
@@ -557,6 +727,34 @@ func (c *cronjobWrapper) Environment() map[string]string {
 	return result
 }
 
+// Test wrapper
+type testWrapper struct {
+	t *internal.InternalTest
+}
+
+func (t *testWrapper) Name() string      { return t.t.Name }
+func (t *testWrapper) Command() []string { return t.t.Command }
+func (t *testWrapper) Timeout() string   { return t.t.Timeout }
+
+func (t *testWrapper) Environment() map[string]string {
+	result := make(map[string]string)
+	for k, v := range t.t.Environment {
+		result[k] = v.Raw
+	}
+	return result
+}
+
+// Webhook wrapper
+type webhookWrapper struct {
+	w *internal.InternalWebhook
+}
+
+func (w *webhookWrapper) Name() string     { return w.w.Name }
+func (w *webhookWrapper) URL() string      { return w.w.URL.Raw }
+func (w *webhookWrapper) Events() []string { return w.w.Events }
+func (w *webhookWrapper) Secret() string   { return w.w.Secret.Raw }
+func (w *webhookWrapper) Timeout() string  { return w.w.Timeout }
+
 // Observability wrapper
 type observabilityWrapper struct {
 	obs *internal.InternalObservability
@@ -565,16 +763,35 @@ type observabilityWrapper struct {
 func (o *observabilityWrapper) Inject() bool                  { return o.obs.Inject }
 func (o *observabilityWrapper) Attributes() map[string]string { return o.obs.Attributes }
 
+// Injection wrapper
+type injectionWrapper struct {
+	inj *internal.InternalInjection
+}
+
+func (i *injectionWrapper) Disabled() bool    { return i.inj.Disabled }
+func (i *injectionWrapper) Exclude() []string { return i.inj.Exclude }
+
 // Variable wrapper
 type variableWrapper struct {
 	v *internal.InternalVariable
 }
 
 func (v *variableWrapper) Name() string         { return v.v.Name }
+func (v *variableWrapper) Type() string         { return v.v.Type }
 func (v *variableWrapper) Description() string  { return v.v.Description }
 func (v *variableWrapper) Default() interface{} { return v.v.Default }
 func (v *variableWrapper) Required() bool       { return v.v.Required }
 func (v *variableWrapper) Sensitive() bool      { return v.v.Sensitive }
+func (v *variableWrapper) Enum() []interface{}  { return v.v.Enum }
+func (v *variableWrapper) Pattern() string      { return v.v.Pattern }
+
+// Instance wrapper
+type instanceWrapper struct {
+	inst *internal.InternalInstance
+}
+
+func (i *instanceWrapper) Name() string { return i.inst.Name }
+func (i *instanceWrapper) Weight() int  { return i.inst.Weight }
 
 // Dependency wrapper
 type dependencyWrapper struct {
@@ -605,6 +822,16 @@ func (v *volumeWrapper) HostPath() string  { return v.v.HostPath }
 func (v *volumeWrapper) Name() string      { return v.v.Name }
 func (v *volumeWrapper) ReadOnly() bool    { return v.v.ReadOnly }
 
+// File wrapper
+type fileWrapper struct {
+	f *internal.InternalFile
+}
+
+func (f *fileWrapper) MountPath() string { return f.f.MountPath }
+func (f *fileWrapper) Content() string   { return f.f.Content.Raw }
+func (f *fileWrapper) Source() string    { return f.f.Source }
+func (f *fileWrapper) Mode() string      { return f.f.Mode }
+
 // Probe wrapper
 type probeWrapper struct {
 	p *internal.InternalProbe
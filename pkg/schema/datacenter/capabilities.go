@@ -0,0 +1,137 @@
+package datacenter
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+
+	v1 "github.com/davidthor/cldctl/pkg/schema/datacenter/v1"
+)
+
+// capability describes how a datacenter provides something a component can
+// require. probe holds the node inputs each hook's when clause is evaluated
+// against, so a database hook scoped to postgres provides "postgres" but not
+// "mysql".
+type capability struct {
+	noun     string
+	nodeType string
+	hooks    func(Hooks) []Hook
+	probe    map[string]interface{}
+}
+
+var capabilities = map[string]capability{
+	"builds":          {noun: "build", nodeType: "dockerBuild", hooks: Hooks.DockerBuild},
+	"databases":       {noun: "database", nodeType: "database", hooks: Hooks.Database},
+	"buckets":         {noun: "bucket", nodeType: "bucket", hooks: Hooks.Bucket},
+	"encryptionKeys":  {noun: "encryption key", nodeType: "encryptionKey", hooks: Hooks.EncryptionKey},
+	"smtp":            {noun: "smtp", nodeType: "smtp", hooks: Hooks.SMTP},
+	"deployments":     {noun: "deployment", nodeType: "deployment", hooks: Hooks.Deployment},
+	"functions":       {noun: "function", nodeType: "function", hooks: Hooks.Function},
+	"services":        {noun: "service", nodeType: "service", hooks: Hooks.Service},
+	"routes":          {noun: "route", nodeType: "route", hooks: Hooks.Route},
+	"cronjobs":        {noun: "cronjob", nodeType: "cronjob", hooks: Hooks.Cronjob},
+	"tasks":           {noun: "task", nodeType: "task", hooks: Hooks.Task},
+	"observability":   {noun: "observability", nodeType: "observability", hooks: Hooks.Observability},
+	"networkPolicies": {noun: "network policy", nodeType: "networkPolicy", hooks: Hooks.NetworkPolicy},
+	"routeAuth":       {noun: "route authentication", nodeType: "routeAuth", hooks: Hooks.RouteAuth},
+}
+
+// databaseEngines are the database types a component can require by name.
+var databaseEngines = []string{"postgres", "mysql", "mongodb", "redis", "mariadb", "cockroachdb", "clickhouse"}
+
+func init() {
+	for _, engine := range databaseEngines {
+		capabilities[engine] = capability{
+			noun:     engine,
+			nodeType: "database",
+			hooks:    Hooks.Database,
+			probe:    map[string]interface{}{"type": engine},
+		}
+	}
+}
+
+// IsCapability reports whether name is a capability components can require.
+func IsCapability(name string) bool {
+	_, ok := capabilities[name]
+	return ok
+}
+
+// Capabilities returns the names of the capabilities dc provides, sorted.
+func Capabilities(dc Datacenter) []string {
+	var names []string
+	for name := range capabilities {
+		if providesCapability(dc, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MissingCapabilities returns a description of each required capability dc
+// does not provide (e.g., "cronjob", "postgres"), in the order required.
+// Unknown capability names are reported as missing.
+func MissingCapabilities(dc Datacenter, required []string) []string {
+	var missing []string
+	for _, name := range required {
+		if providesCapability(dc, name) {
+			continue
+		}
+		if c, ok := capabilities[name]; ok {
+			missing = append(missing, c.noun)
+		} else {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// providesCapability reports whether the first of dc's hooks to match the
+// capability's probe inputs provisions the resource. A hook whose when clause
+// can't be evaluated statically (for example, one that reads inputs the probe
+// doesn't set) is assumed to match; a hook with an error block rejects the
+// resource. Hooks that fall through never provision a resource on their own.
+func providesCapability(dc Datacenter, name string) bool {
+	c, ok := capabilities[name]
+	if !ok || dc == nil || dc.Environment() == nil || dc.Environment().Hooks() == nil {
+		return false
+	}
+	for _, hook := range c.hooks(dc.Environment().Hooks()) {
+		if hook.Fallthrough() {
+			continue
+		}
+		matched, known := probeWhen(hook.When(), c.nodeType, c.probe)
+		if !known {
+			if hook.Error() == "" {
+				return true
+			}
+			continue
+		}
+		if matched {
+			return hook.Error() == ""
+		}
+	}
+	return false
+}
+
+// probeWhen evaluates a hook's when clause against probe inputs. known is
+// false when the clause can't be evaluated with them.
+func probeWhen(when, nodeType string, inputs map[string]interface{}) (matched, known bool) {
+	if strings.TrimSpace(when) == "" {
+		return true, true
+	}
+	expr, diags := hclsyntax.ParseExpression([]byte(when), "when.hcl", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return false, false
+	}
+
+	eval := v1.NewEvaluator()
+	eval.SetNodeContext(nodeType, "", "", inputs)
+	result, err := eval.EvaluateWhen(expr)
+	if err != nil {
+		return false, false
+	}
+	return result, true
+}
@@ -0,0 +1,73 @@
+package datacenter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const capabilitiesTestDatacenter = `
+environment {
+  database {
+    when = element(split(":", node.inputs.type), 0) == "postgres"
+    module "postgres" {
+      build = "./modules/postgres"
+    }
+    outputs = {
+      host = module.postgres.host
+      port = module.postgres.port
+      url  = module.postgres.url
+    }
+  }
+
+  database {
+    when  = element(split(":", node.inputs.type), 0) == "mysql"
+    error = "MySQL is not supported in this datacenter"
+  }
+
+  deployment {
+    module "deployment" {
+      build = "./modules/deployment"
+    }
+    outputs = {
+      id = module.deployment.id
+    }
+  }
+
+  cronjob {
+    when = variable.enable_cron
+    module "cronjob" {
+      build = "./modules/cronjob"
+    }
+    outputs = {
+      id = module.cronjob.id
+    }
+  }
+}
+`
+
+func loadCapabilitiesTestDatacenter(t *testing.T) Datacenter {
+	t.Helper()
+	dc, err := NewLoader().LoadFromBytes([]byte(capabilitiesTestDatacenter), "/tmp/dc/datacenter.dc")
+	require.NoError(t, err)
+	return dc
+}
+
+func TestCapabilities(t *testing.T) {
+	dc := loadCapabilitiesTestDatacenter(t)
+
+	// Hooks whose when clause can't be evaluated statically (cronjob reads a
+	// variable) are assumed to apply; error hooks reject the engine
+	assert.Equal(t, []string{"cronjobs", "databases", "deployments", "postgres"}, Capabilities(dc))
+}
+
+func TestMissingCapabilities(t *testing.T) {
+	dc := loadCapabilitiesTestDatacenter(t)
+
+	assert.Empty(t, MissingCapabilities(dc, []string{"postgres", "deployments", "cronjobs"}))
+	assert.Equal(t,
+		[]string{"mysql", "smtp", "function", "unknown"},
+		MissingCapabilities(dc, []string{"mysql", "smtp", "postgres", "functions", "unknown"}))
+	assert.Equal(t, []string{"cronjob"}, MissingCapabilities(nil, []string{"cronjobs"}))
+}
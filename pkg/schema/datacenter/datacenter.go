@@ -19,6 +19,13 @@ type Datacenter interface {
 	// Components (datacenter-level component declarations)
 	Components() []DatacenterComponent
 
+	// Sandbox applied to container-based modules, or nil if unrestricted
+	Sandbox() Sandbox
+
+	// EnvironmentDefaults returns the per-environment variable defaults, in
+	// declaration order
+	EnvironmentDefaults() []EnvironmentDefaults
+
 	// Environment configuration
 	Environment() Environment
 
@@ -38,6 +45,14 @@ type Extends struct {
 	Path  string // Local path (build-time resolution)
 }
 
+// EnvironmentDefaults sets variable defaults for the environments whose names
+// match a pattern, e.g. larger instances for "prod-*".
+type EnvironmentDefaults interface {
+	// Pattern is a path.Match pattern matched against environment names
+	Pattern() string
+	Variables() map[string]interface{}
+}
+
 // DatacenterComponent represents a component declared at the datacenter level.
 // These components are deployed into environments on-demand when needed as
 // dependencies by other components.
@@ -70,6 +85,43 @@ type Module interface {
 	Environment() map[string]string
 	When() string
 	Volumes() []VolumeMount
+	Credentials() Credentials
+
+	// Cacheable reports whether the executor may reuse the module's prior
+	// outputs instead of applying it again.
+	Cacheable() bool
+	// CacheKey is the expression whose value identifies a cached result.
+	// Empty means the key is derived from the module's inputs.
+	CacheKey() string
+}
+
+// Sandbox restricts how container-based modules run so untrusted modules
+// can't reach the network or exhaust the host.
+type Sandbox interface {
+	NoNetwork() bool
+	ReadOnlyRoot() bool
+	// Memory is the memory limit in bytes (0 = unlimited).
+	Memory() int64
+	// CPUs is the CPU limit (0 = unlimited).
+	CPUs() float64
+	// PidsLimit is the maximum number of processes (0 = unlimited).
+	PidsLimit() int64
+	// SeccompProfile is the path to a seccomp profile, relative to the
+	// datacenter file.
+	SeccompProfile() string
+}
+
+// Credentials scopes the cloud credentials a module runs with. Values may
+// be expressions resolved at deploy time. A nil Credentials means the
+// module runs with the CLI's own credentials.
+type Credentials interface {
+	// RoleARN is the AWS IAM role to assume.
+	RoleARN() string
+	// ServiceAccount is the GCP service account to impersonate.
+	ServiceAccount() string
+	// EnvSet names a set of CLDCTL_CREDENTIALS_<SET>_* process environment
+	// variables passed to the module with the prefix removed.
+	EnvSet() string
 }
 
 // VolumeMount represents a volume mount.
@@ -83,6 +135,24 @@ type VolumeMount interface {
 type Environment interface {
 	Modules() []Module
 	Hooks() Hooks
+	Injections() []Injection
+	// OnDeploy returns the modules run once after all of an environment's
+	// component resources are deployed.
+	OnDeploy() []Module
+	// OnDestroy returns the modules run once before an environment is
+	// destroyed.
+	OnDestroy() []Module
+	// Exports maps the names components may read as ${{ platform.<name> }}
+	// to value expressions, evaluated at deploy time.
+	Exports() map[string]string
+}
+
+// Injection is a named set of environment variables injected into every
+// workload its when condition matches.
+type Injection interface {
+	Name() string
+	When() string
+	Environment() map[string]string
 }
 
 // Hooks provides access to resource hooks.
@@ -103,15 +173,47 @@ type Hooks interface {
 	Observability() []Hook
 	Port() []Hook
 	NetworkPolicy() []Hook
+	RouteAuth() []Hook
 }
 
 // Hook represents a resource hook.
 type Hook interface {
 	When() string
 	Modules() []Module
+	// Validate returns modules that are previewed, never applied, before a
+	// deploy executes, to check cheaply that the hook's modules can run
+	// (e.g. that their cloud credentials haven't expired).
+	Validate() []Module
 	Outputs() map[string]string
 	NestedOutputs() map[string]map[string]string
+	Guarantees() []string
+	// ReplaceOn lists the node inputs whose changes can't be applied in
+	// place: when one of them changes, the resource's modules are destroyed
+	// and applied again instead of updated. "*" matches every input.
+	ReplaceOn() []string
 	Error() string
+	// ErrorCode, ErrorDocsURL, and ErrorAlternatives add remediation to an
+	// error hook's message: a machine-readable code, a link to documentation,
+	// and the supported alternatives to suggest. Each may be empty.
+	ErrorCode() string
+	ErrorDocsURL() string
+	ErrorAlternatives() []string
+	Credentials() Credentials
+	// Sandbox replaces the datacenter sandbox for the hook's modules, or is
+	// nil to use it.
+	Sandbox() Sandbox
+	// Priority orders the hooks of a type: higher priorities are tried
+	// first, and hooks of equal priority are tried in declaration order.
+	Priority() int
+	// Fallthrough reports whether matching continues after this hook, so
+	// that its modules run alongside those of the next matching hook.
+	Fallthrough() bool
+	// Aggregate reports whether the hook's modules run once for all of the
+	// resources it matches in an environment, rather than once per resource.
+	Aggregate() bool
+	// Shared reports whether the hook's modules are provisioned once for the
+	// datacenter and reused by every environment whose resources it matches.
+	Shared() bool
 }
 
 // Loader loads and parses datacenter configurations.
@@ -17,6 +17,12 @@ type InternalDatacenter struct {
 	// as dependencies by other components.
 	Components []InternalDatacenterComponent
 
+	// Sandbox applied to container-based modules (nil if unrestricted)
+	Sandbox *InternalSandbox
+
+	// Per-environment variable defaults, in declaration order
+	EnvironmentDefaults []InternalEnvironmentDefaults
+
 	// Environment configuration
 	Environment InternalEnvironment
 
@@ -40,6 +46,13 @@ type InternalDatacenterComponent struct {
 	Variables map[string]string // HCL expression strings (evaluated at runtime with datacenter variables)
 }
 
+// InternalEnvironmentDefaults sets variable defaults for the environments
+// whose names match Pattern (a path.Match pattern).
+type InternalEnvironmentDefaults struct {
+	Pattern   string
+	Variables map[string]interface{}
+}
+
 // InternalVariable represents a datacenter variable.
 type InternalVariable struct {
 	Name        string
@@ -59,13 +72,40 @@ type InternalModule struct {
 	Source string // OCI reference for compiled form
 
 	// Configuration
-	Plugin      string            // "pulumi", "opentofu", "native"
+	Plugin      string            // "pulumi", "opentofu", "native", "crossplane", "ansible"
 	Inputs      map[string]string // Input values (may be HCL expressions)
 	Environment map[string]string // Environment variables
 	When        string            // Conditional expression
 
 	// Volume mounts
 	Volumes []InternalVolumeMount
+
+	// Credentials overrides the hook's credentials field by field
+	Credentials *InternalCredentials
+
+	// Caching: a cacheable module's prior outputs are reused when its cache
+	// key matches. An empty CacheKey means the key is derived from inputs.
+	Cacheable bool
+	CacheKey  string
+}
+
+// InternalSandbox restricts how container-based modules run.
+type InternalSandbox struct {
+	NoNetwork      bool    // Run without network access
+	ReadOnlyRoot   bool    // Mount the container root filesystem read-only
+	Memory         int64   // Memory limit in bytes (0 = unlimited)
+	CPUs           float64 // CPU limit (0 = unlimited)
+	PidsLimit      int64   // Maximum number of processes (0 = unlimited)
+	SeccompProfile string  // Path to a seccomp profile, relative to the datacenter
+}
+
+// InternalCredentials scopes the cloud credentials a module runs with.
+// Values may be HCL expressions (e.g., variable.db_role_arn) resolved at
+// deploy time.
+type InternalCredentials struct {
+	RoleARN        string // AWS IAM role to assume
+	ServiceAccount string // GCP service account to impersonate
+	EnvSet         string // Named set of CLDCTL_CREDENTIALS_<SET>_* process environment variables
 }
 
 // InternalVolumeMount represents a volume mount for module execution.
@@ -77,8 +117,20 @@ type InternalVolumeMount struct {
 
 // InternalEnvironment represents environment-level configuration.
 type InternalEnvironment struct {
-	Modules []InternalModule
-	Hooks   InternalHooks
+	Modules    []InternalModule
+	Hooks      InternalHooks
+	Injections []InternalInjection
+	OnDeploy   []InternalModule  // Run once after every component resource succeeds
+	OnDestroy  []InternalModule  // Run once before the environment is torn down
+	Exports    map[string]string // Export name to value expression, read by components as platform.<name>
+}
+
+// InternalInjection is a named set of environment variables the engine adds
+// to every workload matching When. Components can opt out by name.
+type InternalInjection struct {
+	Name        string
+	When        string            // Condition evaluated against the workload node (empty matches all)
+	Environment map[string]string // Variable name to value expression, evaluated at deploy time
 }
 
 // InternalHooks contains resource hooks.
@@ -99,13 +151,26 @@ type InternalHooks struct {
 	Observability []InternalHook
 	Port          []InternalHook
 	NetworkPolicy []InternalHook
+	RouteAuth     []InternalHook
 }
 
 // InternalHook represents a resource hook.
 type InternalHook struct {
-	When          string                       // Conditional expression
-	Modules       []InternalModule             // Modules to execute
-	Outputs       map[string]string            // Output mappings (HCL expressions)
-	NestedOutputs map[string]map[string]string // Nested output objects (e.g., read/write sub-objects for database hooks)
-	Error         string                       // Human-readable error message (mutually exclusive with Modules/Outputs)
+	When              string                       // Conditional expression
+	Modules           []InternalModule             // Modules to execute
+	Validate          []InternalModule             // Modules previewed before a deploy to check that the hook can run
+	Outputs           map[string]string            // Output mappings (HCL expressions)
+	NestedOutputs     map[string]map[string]string // Nested output objects (e.g., read/write sub-objects for database hooks)
+	Guarantees        []string                     // Extra outputs the hook promises beyond the built-in contract (dot paths for nested outputs)
+	ReplaceOn         []string                     // Node inputs whose changes replace the resource instead of updating it ("*" for any)
+	Error             string                       // Human-readable error message (mutually exclusive with Modules/Outputs)
+	ErrorCode         string                       // Machine-readable code reported with Error
+	ErrorDocsURL      string                       // Documentation explaining Error and how to resolve it
+	ErrorAlternatives []string                     // Supported alternatives suggested with Error
+	Credentials       *InternalCredentials         // Credentials the hook's modules run with
+	Sandbox           *InternalSandbox             // Replaces the datacenter sandbox for the hook's modules
+	Priority          int                          // Hooks with a higher priority are tried first
+	Fallthrough       bool                         // Matching continues after this hook; its modules run alongside the next match
+	Aggregate         bool                         // Modules run once for all matching resources in the environment
+	Shared            bool                         // Modules are provisioned once per datacenter and reused by every environment
 }
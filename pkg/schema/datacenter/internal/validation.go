@@ -2,6 +2,7 @@ package internal
 
 import (
 	"fmt"
+	"regexp"
 	"sort"
 	"strings"
 )
@@ -29,18 +30,42 @@ var RequiredHookOutputs = map[string][]string{
 	"task":          {"id", "status"},
 	"observability": {"endpoint", "protocol"},
 	"databaseUser":  {"url"},
+	"routeAuth":     {"url", "host", "port"},
 }
 
-// ValidateHookOutputs checks that every non-error hook for the given type
-// declares all required output keys. It inspects both the flat Outputs map
-// and the NestedOutputs map (which covers nested objects like read/write).
-// Returns nil if all hooks are valid.
-func ValidateHookOutputs(hookType string, hooks []InternalHook) []error {
-	required, ok := RequiredHookOutputs[hookType]
-	if !ok {
-		return nil // No required outputs defined for this type
+// HookRequiredOutputs returns the outputs the given hook must produce: the
+// built-in contract for its type followed by any extra outputs the hook
+// guarantees. Nested outputs use dot paths (e.g., "read.host").
+func HookRequiredOutputs(hookType string, hook InternalHook) []string {
+	required := append([]string(nil), RequiredHookOutputs[hookType]...)
+	for _, key := range hook.Guarantees {
+		if !containsString(required, key) {
+			required = append(required, key)
+		}
 	}
+	return required
+}
 
+// DeclaresOutput reports whether the hook's outputs block declares key.
+// A dot path such as "read.host" refers to a key inside a nested output
+// object; a plain name matches either a flat or a nested output.
+func DeclaresOutput(hook InternalHook, key string) bool {
+	if parent, child, ok := strings.Cut(key, "."); ok {
+		_, exists := hook.NestedOutputs[parent][child]
+		return exists
+	}
+	if _, exists := hook.Outputs[key]; exists {
+		return true
+	}
+	_, exists := hook.NestedOutputs[key]
+	return exists
+}
+
+// ValidateHookOutputs checks that every non-error hook for the given type
+// declares all required output keys, including the outputs it guarantees.
+// It inspects both the flat Outputs map and the NestedOutputs map (which
+// covers nested objects like read/write). Returns nil if all hooks are valid.
+func ValidateHookOutputs(hookType string, hooks []InternalHook) []error {
 	var errs []error
 	for i, hook := range hooks {
 		// Error hooks don't produce outputs — skip them.
@@ -53,25 +78,26 @@ func ValidateHookOutputs(hookType string, hooks []InternalHook) []error {
 			continue
 		}
 
-		// Collect all declared output keys (flat + nested top-level keys).
-		declared := make(map[string]bool)
-		for k := range hook.Outputs {
-			declared[k] = true
-		}
-		for k := range hook.NestedOutputs {
-			declared[k] = true
+		// Fallthrough hooks add outputs to those of the hook they fall
+		// through to, which provides the contract.
+		if hook.Fallthrough {
+			continue
 		}
 
 		var missing []string
-		for _, key := range required {
-			if !declared[key] {
+		for _, key := range HookRequiredOutputs(hookType, hook) {
+			if !DeclaresOutput(hook, key) {
 				missing = append(missing, key)
 			}
 		}
 
 		if len(missing) > 0 {
-			have := make([]string, 0, len(declared))
-			for k := range declared {
+			// Collect all declared output keys (flat + nested top-level keys).
+			have := make([]string, 0, len(hook.Outputs)+len(hook.NestedOutputs))
+			for k := range hook.Outputs {
+				have = append(have, k)
+			}
+			for k := range hook.NestedOutputs {
 				have = append(have, k)
 			}
 			sort.Strings(have)
@@ -99,3 +125,110 @@ func ValidateHookOutputs(hookType string, hooks []InternalHook) []error {
 
 	return errs
 }
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// environmentModuleRefPattern matches environment.module.<name> references
+// to environment-scoped modules.
+var environmentModuleRefPattern = regexp.MustCompile(`environment\.module\.([A-Za-z0-9_-]+)`)
+
+// EnvironmentModuleRefs returns the names of the environment-scoped modules
+// expr references through environment.module.<name>.<output>, in order of
+// first use.
+func EnvironmentModuleRefs(expr string) []string {
+	var names []string
+	for _, m := range environmentModuleRefPattern.FindAllStringSubmatch(expr, -1) {
+		if !containsString(names, m[1]) {
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// ValidateEnvironmentModuleRefs checks that hooks only reference environment
+// modules the environment block declares, and that an environment module
+// only references modules declared before it, since environment modules are
+// provisioned in order. hooks maps each hook type to its hooks.
+func ValidateEnvironmentModuleRefs(env *InternalEnvironment, hooks map[string][]InternalHook) []error {
+	var errs []error
+
+	declared := make(map[string]bool, len(env.Modules))
+	for _, mod := range env.Modules {
+		for _, name := range moduleEnvironmentRefs(mod) {
+			if name == mod.Name {
+				errs = append(errs, fmt.Errorf("environment module %s references its own outputs", mod.Name))
+			} else if !declared[name] {
+				errs = append(errs, fmt.Errorf("environment module %s references environment module %s, which must be declared before it", mod.Name, name))
+			}
+		}
+		declared[mod.Name] = true
+	}
+
+	hookTypes := make([]string, 0, len(hooks))
+	for hookType := range hooks {
+		hookTypes = append(hookTypes, hookType)
+	}
+	sort.Strings(hookTypes)
+
+	for _, hookType := range hookTypes {
+		for _, hook := range hooks[hookType] {
+			var refs []string
+			for _, mod := range hook.Modules {
+				refs = append(refs, moduleEnvironmentRefs(mod)...)
+			}
+			for _, expr := range hook.Outputs {
+				refs = append(refs, EnvironmentModuleRefs(expr)...)
+			}
+			for _, nested := range hook.NestedOutputs {
+				for _, expr := range nested {
+					refs = append(refs, EnvironmentModuleRefs(expr)...)
+				}
+			}
+
+			var unknown []string
+			for _, name := range refs {
+				if !declared[name] && !containsString(unknown, name) {
+					unknown = append(unknown, name)
+				}
+			}
+			for _, name := range unknown {
+				errs = append(errs, fmt.Errorf("%s hook references undeclared environment module %s", hookType, name))
+			}
+		}
+	}
+
+	return errs
+}
+
+// moduleEnvironmentRefs returns the environment modules a module's inputs,
+// when clause and cache key reference.
+func moduleEnvironmentRefs(mod InternalModule) []string {
+	keys := make([]string, 0, len(mod.Inputs))
+	for k := range mod.Inputs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	exprs := make([]string, 0, len(keys)+2)
+	for _, k := range keys {
+		exprs = append(exprs, mod.Inputs[k])
+	}
+	exprs = append(exprs, mod.When, mod.CacheKey)
+
+	var names []string
+	for _, expr := range exprs {
+		for _, name := range EnvironmentModuleRefs(expr) {
+			if !containsString(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
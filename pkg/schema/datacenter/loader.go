@@ -3,6 +3,7 @@ package datacenter
 import (
 	"fmt"
 	"os"
+	"sort"
 
 	"github.com/davidthor/cldctl/pkg/errors"
 	"github.com/davidthor/cldctl/pkg/schema/datacenter/internal"
@@ -138,6 +139,41 @@ func (d *datacenterWrapper) Environment() Environment {
 	return &environmentWrapper{e: &d.dc.Environment}
 }
 
+func (d *datacenterWrapper) Sandbox() Sandbox {
+	if d.dc.Sandbox == nil {
+		return nil
+	}
+	return &sandboxWrapper{s: d.dc.Sandbox}
+}
+
+func (d *datacenterWrapper) EnvironmentDefaults() []EnvironmentDefaults {
+	result := make([]EnvironmentDefaults, len(d.dc.EnvironmentDefaults))
+	for i := range d.dc.EnvironmentDefaults {
+		result[i] = &environmentDefaultsWrapper{d: &d.dc.EnvironmentDefaults[i]}
+	}
+	return result
+}
+
+// environmentDefaultsWrapper implements EnvironmentDefaults interface.
+type environmentDefaultsWrapper struct {
+	d *internal.InternalEnvironmentDefaults
+}
+
+func (w *environmentDefaultsWrapper) Pattern() string                   { return w.d.Pattern }
+func (w *environmentDefaultsWrapper) Variables() map[string]interface{} { return w.d.Variables }
+
+// sandboxWrapper implements Sandbox interface.
+type sandboxWrapper struct {
+	s *internal.InternalSandbox
+}
+
+func (s *sandboxWrapper) NoNetwork() bool        { return s.s.NoNetwork }
+func (s *sandboxWrapper) ReadOnlyRoot() bool     { return s.s.ReadOnlyRoot }
+func (s *sandboxWrapper) Memory() int64          { return s.s.Memory }
+func (s *sandboxWrapper) CPUs() float64          { return s.s.CPUs }
+func (s *sandboxWrapper) PidsLimit() int64       { return s.s.PidsLimit }
+func (s *sandboxWrapper) SeccompProfile() string { return s.s.SeccompProfile }
+
 func (d *datacenterWrapper) SchemaVersion() string {
 	return d.dc.SourceVersion
 }
@@ -183,6 +219,24 @@ func (m *moduleWrapper) Plugin() string                 { return m.m.Plugin }
 func (m *moduleWrapper) Inputs() map[string]string      { return m.m.Inputs }
 func (m *moduleWrapper) Environment() map[string]string { return m.m.Environment }
 func (m *moduleWrapper) When() string                   { return m.m.When }
+func (m *moduleWrapper) Cacheable() bool                { return m.m.Cacheable }
+func (m *moduleWrapper) CacheKey() string               { return m.m.CacheKey }
+
+func (m *moduleWrapper) Credentials() Credentials {
+	if m.m.Credentials == nil {
+		return nil
+	}
+	return &credentialsWrapper{c: m.m.Credentials}
+}
+
+// credentialsWrapper implements Credentials interface.
+type credentialsWrapper struct {
+	c *internal.InternalCredentials
+}
+
+func (c *credentialsWrapper) RoleARN() string        { return c.c.RoleARN }
+func (c *credentialsWrapper) ServiceAccount() string { return c.c.ServiceAccount }
+func (c *credentialsWrapper) EnvSet() string         { return c.c.EnvSet }
 
 func (m *moduleWrapper) Volumes() []VolumeMount {
 	result := make([]VolumeMount, len(m.m.Volumes))
@@ -214,10 +268,45 @@ func (e *environmentWrapper) Modules() []Module {
 	return result
 }
 
+func (e *environmentWrapper) OnDeploy() []Module {
+	result := make([]Module, len(e.e.OnDeploy))
+	for i := range e.e.OnDeploy {
+		result[i] = &moduleWrapper{m: &e.e.OnDeploy[i]}
+	}
+	return result
+}
+
+func (e *environmentWrapper) OnDestroy() []Module {
+	result := make([]Module, len(e.e.OnDestroy))
+	for i := range e.e.OnDestroy {
+		result[i] = &moduleWrapper{m: &e.e.OnDestroy[i]}
+	}
+	return result
+}
+
+func (e *environmentWrapper) Exports() map[string]string { return e.e.Exports }
+
 func (e *environmentWrapper) Hooks() Hooks {
 	return &hooksWrapper{h: &e.e.Hooks}
 }
 
+func (e *environmentWrapper) Injections() []Injection {
+	result := make([]Injection, len(e.e.Injections))
+	for i := range e.e.Injections {
+		result[i] = &injectionWrapper{inj: &e.e.Injections[i]}
+	}
+	return result
+}
+
+// injectionWrapper implements Injection interface.
+type injectionWrapper struct {
+	inj *internal.InternalInjection
+}
+
+func (i *injectionWrapper) Name() string                   { return i.inj.Name }
+func (i *injectionWrapper) When() string                   { return i.inj.When }
+func (i *injectionWrapper) Environment() map[string]string { return i.inj.Environment }
+
 // hooksWrapper implements Hooks interface.
 type hooksWrapper struct {
 	h *internal.InternalHooks
@@ -239,12 +328,18 @@ func (h *hooksWrapper) DockerBuild() []Hook   { return wrapHooks(h.h.DockerBuild
 func (h *hooksWrapper) Observability() []Hook { return wrapHooks(h.h.Observability) }
 func (h *hooksWrapper) Port() []Hook          { return wrapHooks(h.h.Port) }
 func (h *hooksWrapper) NetworkPolicy() []Hook { return wrapHooks(h.h.NetworkPolicy) }
+func (h *hooksWrapper) RouteAuth() []Hook     { return wrapHooks(h.h.RouteAuth) }
 
+// wrapHooks returns hooks in the order they are tried: by descending
+// priority, then in declaration order.
 func wrapHooks(hooks []internal.InternalHook) []Hook {
 	result := make([]Hook, len(hooks))
 	for i := range hooks {
 		result[i] = &hookWrapper{h: &hooks[i]}
 	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Priority() > result[j].Priority()
+	})
 	return result
 }
 
@@ -263,8 +358,48 @@ func (h *hookWrapper) Modules() []Module {
 	return result
 }
 
+func (h *hookWrapper) Validate() []Module {
+	result := make([]Module, len(h.h.Validate))
+	for i := range h.h.Validate {
+		result[i] = &moduleWrapper{m: &h.h.Validate[i]}
+	}
+	return result
+}
+
 func (h *hookWrapper) Outputs() map[string]string { return h.h.Outputs }
 
 func (h *hookWrapper) NestedOutputs() map[string]map[string]string { return h.h.NestedOutputs }
 
+func (h *hookWrapper) Guarantees() []string { return h.h.Guarantees }
+
+func (h *hookWrapper) ReplaceOn() []string { return h.h.ReplaceOn }
+
 func (h *hookWrapper) Error() string { return h.h.Error }
+
+func (h *hookWrapper) ErrorCode() string { return h.h.ErrorCode }
+
+func (h *hookWrapper) ErrorDocsURL() string { return h.h.ErrorDocsURL }
+
+func (h *hookWrapper) ErrorAlternatives() []string { return h.h.ErrorAlternatives }
+
+func (h *hookWrapper) Priority() int { return h.h.Priority }
+
+func (h *hookWrapper) Fallthrough() bool { return h.h.Fallthrough }
+
+func (h *hookWrapper) Aggregate() bool { return h.h.Aggregate }
+
+func (h *hookWrapper) Shared() bool { return h.h.Shared }
+
+func (h *hookWrapper) Sandbox() Sandbox {
+	if h.h.Sandbox == nil {
+		return nil
+	}
+	return &sandboxWrapper{s: h.h.Sandbox}
+}
+
+func (h *hookWrapper) Credentials() Credentials {
+	if h.h.Credentials == nil {
+		return nil
+	}
+	return &credentialsWrapper{c: h.h.Credentials}
+}
@@ -0,0 +1,185 @@
+package datacenter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// hookTypes lists each hook type with its hooks, for checks that cover every
+// type.
+var hookTypes = []struct {
+	name  string
+	hooks func(Hooks) []Hook
+}{
+	{"database", Hooks.Database},
+	{"task", Hooks.Task},
+	{"bucket", Hooks.Bucket},
+	{"encryptionKey", Hooks.EncryptionKey},
+	{"smtp", Hooks.SMTP},
+	{"databaseUser", Hooks.DatabaseUser},
+	{"deployment", Hooks.Deployment},
+	{"function", Hooks.Function},
+	{"service", Hooks.Service},
+	{"route", Hooks.Route},
+	{"cronjob", Hooks.Cronjob},
+	{"secret", Hooks.Secret},
+	{"dockerBuild", Hooks.DockerBuild},
+	{"observability", Hooks.Observability},
+	{"port", Hooks.Port},
+	{"networkPolicy", Hooks.NetworkPolicy},
+	{"routeAuth", Hooks.RouteAuth},
+}
+
+// MatchHook returns the hook that handles a resource, trying hooks in order
+// (as returned by Hooks) until one matches. Matching hooks that fall through
+// don't end the search: their modules run alongside those of the hook that
+// does, so the result combines them, and is nil if no hook ends the search.
+// index is the position of the hook that ends the search, or -1.
+func MatchHook(hooks []Hook, matches func(Hook) bool) (hook Hook, index int) {
+	var chain []Hook
+	for i, h := range hooks {
+		if !matches(h) {
+			continue
+		}
+		chain = append(chain, h)
+		if h.Fallthrough() {
+			continue
+		}
+		if len(chain) == 1 {
+			return h, i
+		}
+		return &hookChain{hooks: chain}, i
+	}
+	return nil, -1
+}
+
+// ModuleHook returns the hook that declares module, one of the modules of
+// hook. It differs from hook when hook combines fallthrough hooks, whose
+// modules run with their own hook's credentials and sandbox.
+func ModuleHook(hook Hook, module Module) Hook {
+	if m, ok := module.(*chainedModule); ok {
+		return m.hook
+	}
+	return hook
+}
+
+// hookChain combines the fallthrough hooks that matched a resource with the
+// hook that ended the search. Modules run in order; outputs are merged, with
+// later hooks overriding earlier ones.
+type hookChain struct {
+	hooks []Hook
+}
+
+func (c *hookChain) last() Hook { return c.hooks[len(c.hooks)-1] }
+
+func (c *hookChain) When() string { return c.last().When() }
+
+func (c *hookChain) Modules() []Module {
+	var result []Module
+	for _, h := range c.hooks {
+		for _, m := range h.Modules() {
+			result = append(result, &chainedModule{Module: m, hook: h})
+		}
+	}
+	return result
+}
+
+func (c *hookChain) Validate() []Module {
+	var result []Module
+	for _, h := range c.hooks {
+		for _, m := range h.Validate() {
+			result = append(result, &chainedModule{Module: m, hook: h})
+		}
+	}
+	return result
+}
+
+func (c *hookChain) Outputs() map[string]string {
+	result := make(map[string]string)
+	for _, h := range c.hooks {
+		for k, v := range h.Outputs() {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+func (c *hookChain) NestedOutputs() map[string]map[string]string {
+	result := make(map[string]map[string]string)
+	for _, h := range c.hooks {
+		for k, v := range h.NestedOutputs() {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+func (c *hookChain) Guarantees() []string {
+	var result []string
+	for _, h := range c.hooks {
+		result = append(result, h.Guarantees()...)
+	}
+	return result
+}
+
+func (c *hookChain) ReplaceOn() []string {
+	var result []string
+	for _, h := range c.hooks {
+		result = append(result, h.ReplaceOn()...)
+	}
+	return result
+}
+
+func (c *hookChain) Error() string               { return c.last().Error() }
+func (c *hookChain) ErrorCode() string           { return c.last().ErrorCode() }
+func (c *hookChain) ErrorDocsURL() string        { return c.last().ErrorDocsURL() }
+func (c *hookChain) ErrorAlternatives() []string { return c.last().ErrorAlternatives() }
+func (c *hookChain) Credentials() Credentials    { return c.last().Credentials() }
+func (c *hookChain) Sandbox() Sandbox            { return c.last().Sandbox() }
+func (c *hookChain) Priority() int               { return c.last().Priority() }
+func (c *hookChain) Fallthrough() bool           { return false }
+func (c *hookChain) Aggregate() bool             { return false }
+func (c *hookChain) Shared() bool                { return false }
+
+// chainedModule is a module of a hookChain, along with the hook that
+// declares it.
+type chainedModule struct {
+	Module
+	hook Hook
+}
+
+// LintHooks returns a warning for each hook that can never be selected
+// because a catch-all (a hook without a when condition, or with when = true)
+// is tried before it. The parser rejects this within a file, but it is easy
+// to miss with when = true, or when the catch-all comes from a child
+// datacenter through extends.
+func LintHooks(dc Datacenter) []string {
+	if dc == nil || dc.Environment() == nil || dc.Environment().Hooks() == nil {
+		return nil
+	}
+
+	var warnings []string
+	for _, t := range hookTypes {
+		hooks := t.hooks(dc.Environment().Hooks())
+		catchAll := -1
+		for i, h := range hooks {
+			if catchAll >= 0 {
+				warnings = append(warnings, fmt.Sprintf("%s hook%s is never used: the catch-all %s hook%s is tried first and matches every resource. Give it a higher priority than the catch-all, or give the catch-all a lower one (e.g. priority = -1).",
+					t.name, hookLabel(h, i), t.name, hookLabel(hooks[catchAll], catchAll)))
+				continue
+			}
+			if when := strings.TrimSpace(h.When()); (when == "" || when == "true") && !h.Fallthrough() {
+				catchAll = i
+			}
+		}
+	}
+	return warnings
+}
+
+// hookLabel identifies a hook in a warning.
+func hookLabel(h Hook, index int) string {
+	if when := strings.TrimSpace(h.When()); when != "" {
+		return fmt.Sprintf(" (when %s)", when)
+	}
+	return fmt.Sprintf("[%d]", index)
+}
@@ -0,0 +1,157 @@
+package datacenter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const matchingTestDatacenter = `
+environment {
+  database {
+    priority    = 10
+    fallthrough = true
+    module "backup" {
+      build = "./modules/backup"
+    }
+    outputs = {
+      backup_bucket = module.backup.bucket
+    }
+  }
+
+  database {
+    priority = -1
+    module "default" {
+      build = "./modules/default"
+    }
+    outputs = {
+      host = module.default.host
+      port = module.default.port
+      url  = module.default.url
+    }
+  }
+
+  database {
+    when = node.inputs.type == "postgres"
+    credentials {
+      env_set = "postgres"
+    }
+    module "postgres" {
+      build = "./modules/postgres"
+    }
+    outputs = {
+      host = module.postgres.host
+      port = module.postgres.port
+      url  = module.postgres.url
+    }
+  }
+}
+`
+
+func TestHooks_OrderedByPriority(t *testing.T) {
+	dc, err := NewLoader().LoadFromBytes([]byte(matchingTestDatacenter), "/tmp/dc/datacenter.dc")
+	require.NoError(t, err)
+
+	hooks := dc.Environment().Hooks().Database()
+	require.Len(t, hooks, 3)
+	assert.Equal(t, 10, hooks[0].Priority())
+	assert.Equal(t, `node.inputs.type == "postgres"`, hooks[1].When())
+	assert.Equal(t, -1, hooks[2].Priority())
+}
+
+func TestMatchHook(t *testing.T) {
+	dc, err := NewLoader().LoadFromBytes([]byte(matchingTestDatacenter), "/tmp/dc/datacenter.dc")
+	require.NoError(t, err)
+	hooks := dc.Environment().Hooks().Database()
+
+	// Only catch-alls match: the fallthrough hook runs with the default
+	hook, index := MatchHook(hooks, func(h Hook) bool { return h.When() == "" })
+	require.NotNil(t, hook)
+	assert.Equal(t, 2, index)
+	assert.False(t, hook.Fallthrough())
+
+	var names []string
+	for _, m := range hook.Modules() {
+		names = append(names, m.Name())
+	}
+	assert.Equal(t, []string{"backup", "default"}, names)
+	assert.Equal(t, map[string]string{
+		"backup_bucket": "module.backup.bucket",
+		"host":          "module.default.host",
+		"port":          "module.default.port",
+		"url":           "module.default.url",
+	}, hook.Outputs())
+
+	// Modules keep their own hook's credentials
+	hook, index = MatchHook(hooks, func(Hook) bool { return true })
+	require.NotNil(t, hook)
+	assert.Equal(t, 1, index)
+	modules := hook.Modules()
+	require.Len(t, modules, 2)
+	assert.Nil(t, ModuleHook(hook, modules[0]).Credentials())
+	require.NotNil(t, ModuleHook(hook, modules[1]).Credentials())
+	assert.Equal(t, "postgres", ModuleHook(hook, modules[1]).Credentials().EnvSet())
+
+	// A single matching hook is returned as-is
+	hook, index = MatchHook(hooks[1:], func(Hook) bool { return true })
+	assert.Same(t, hooks[1], hook)
+	assert.Equal(t, 0, index)
+	modules = hook.Modules()
+	assert.Same(t, hook, ModuleHook(hook, modules[0]))
+
+	// Fallthrough hooks alone don't handle a resource
+	hook, index = MatchHook(hooks[:1], func(Hook) bool { return true })
+	assert.Nil(t, hook)
+	assert.Equal(t, -1, index)
+}
+
+func TestLintHooks(t *testing.T) {
+	dc, err := NewLoader().LoadFromBytes([]byte(`
+environment {
+  database {
+    when  = true
+    error = "Databases are not supported"
+  }
+
+  database {
+    when  = node.inputs.type == "postgres"
+    error = "Postgres is not supported"
+  }
+
+  deployment {
+    when = node.inputs.runtime != null
+    module "process" {
+      build = "./modules/process"
+    }
+    outputs = {
+      id = module.process.id
+    }
+  }
+
+  deployment {
+    module "container" {
+      build = "./modules/container"
+    }
+    outputs = {
+      id = module.container.id
+    }
+  }
+}
+`), "/tmp/dc/datacenter.dc")
+	require.NoError(t, err)
+
+	warnings := LintHooks(dc)
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], `database hook (when node.inputs.type == "postgres") is never used`)
+	assert.Contains(t, warnings[0], "catch-all database hook (when true)")
+}
+
+func TestLintHooks_NoWarnings(t *testing.T) {
+	dc := loadCapabilitiesTestDatacenter(t)
+	assert.Empty(t, LintHooks(dc))
+
+	dc, err := NewLoader().LoadFromBytes([]byte(matchingTestDatacenter), "/tmp/dc/datacenter.dc")
+	require.NoError(t, err)
+	assert.Empty(t, LintHooks(dc))
+}
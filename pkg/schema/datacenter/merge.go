@@ -12,8 +12,13 @@ import (
 //   - Components: Union; child wins on name collision
 //   - Environment modules: Union; child wins on name collision
 //   - Hooks (per type): Prepend child hooks before parent hooks. If both have
-//     catch-alls (hook without a 'when' condition), only the child's catch-all
-//     is kept (it shadows the parent's).
+//     catch-alls (hook without a 'when' condition that doesn't fall through),
+//     only the child's catch-all is kept (it shadows the parent's). Priorities
+//     reorder the merged hooks when they are loaded.
+//   - Environment injections: Union; child wins on name collision
+//   - Sandbox: child replaces parent
+//   - Environment variable defaults: parent's, then child's, so the child's
+//     win where both match an environment
 //
 // The merged result has Extends set to nil (fully resolved).
 func MergeDatacenters(child, parent *internal.InternalDatacenter) *internal.InternalDatacenter {
@@ -32,6 +37,14 @@ func MergeDatacenters(child, parent *internal.InternalDatacenter) *internal.Inte
 	// Merge components: start with parent, overlay child (child wins on name collision)
 	merged.Components = mergeComponents(child.Components, parent.Components)
 
+	// Sandbox: child replaces parent
+	merged.Sandbox = parent.Sandbox
+	if child.Sandbox != nil {
+		merged.Sandbox = child.Sandbox
+	}
+
+	merged.EnvironmentDefaults = append(append([]internal.InternalEnvironmentDefaults(nil), parent.EnvironmentDefaults...), child.EnvironmentDefaults...)
+
 	// Merge environment
 	merged.Environment = mergeEnvironment(child.Environment, parent.Environment)
 
@@ -108,9 +121,46 @@ func mergeEnvironment(child, parent internal.InternalEnvironment) internal.Inter
 	// Merge hooks per type
 	merged.Hooks = mergeHooks(child.Hooks, parent.Hooks)
 
+	// Merge environment injections
+	merged.Injections = mergeInjections(child.Injections, parent.Injections)
+
+	// Merge environment lifecycle hooks
+	merged.OnDeploy = mergeModules(child.OnDeploy, parent.OnDeploy)
+	merged.OnDestroy = mergeModules(child.OnDestroy, parent.OnDestroy)
+
+	// Merge platform exports. Child wins on name collision.
+	if len(child.Exports) > 0 || len(parent.Exports) > 0 {
+		merged.Exports = make(map[string]string, len(child.Exports)+len(parent.Exports))
+		for name, expr := range parent.Exports {
+			merged.Exports[name] = expr
+		}
+		for name, expr := range child.Exports {
+			merged.Exports[name] = expr
+		}
+	}
+
 	return merged
 }
 
+// mergeInjections merges child and parent environment injections. Child wins on name collision.
+func mergeInjections(child, parent []internal.InternalInjection) []internal.InternalInjection {
+	childIndex := make(map[string]bool, len(child))
+	for _, inj := range child {
+		childIndex[inj.Name] = true
+	}
+
+	result := make([]internal.InternalInjection, len(child))
+	copy(result, child)
+
+	for _, inj := range parent {
+		if !childIndex[inj.Name] {
+			result = append(result, inj)
+		}
+	}
+
+	return result
+}
+
 // mergeHooks merges all hook types between child and parent.
 func mergeHooks(child, parent internal.InternalHooks) internal.InternalHooks {
 	return internal.InternalHooks{
@@ -130,13 +180,14 @@ func mergeHooks(child, parent internal.InternalHooks) internal.InternalHooks {
 		Observability: mergeHookSlice(child.Observability, parent.Observability),
 		Port:          mergeHookSlice(child.Port, parent.Port),
 		NetworkPolicy: mergeHookSlice(child.NetworkPolicy, parent.NetworkPolicy),
+		RouteAuth:     mergeHookSlice(child.RouteAuth, parent.RouteAuth),
 	}
 }
 
 // mergeHookSlice merges child and parent hooks for a single hook type.
 // Child hooks are prepended before parent hooks (child hooks are higher priority
-// in the waterfall evaluation). If both have catch-alls (no 'when' condition),
-// only the child's catch-all is kept.
+// in the waterfall evaluation). If both have catch-alls (no 'when' condition
+// and no fallthrough), only the child's catch-all is kept.
 func mergeHookSlice(child, parent []internal.InternalHook) []internal.InternalHook {
 	if len(child) == 0 {
 		return parent
@@ -148,7 +199,7 @@ func mergeHookSlice(child, parent []internal.InternalHook) []internal.InternalHo
 	// Check if child has a catch-all (hook without 'when')
 	childHasCatchAll := false
 	for _, h := range child {
-		if h.When == "" {
+		if h.When == "" && !h.Fallthrough {
 			childHasCatchAll = true
 			break
 		}
@@ -160,7 +211,7 @@ func mergeHookSlice(child, parent []internal.InternalHook) []internal.InternalHo
 
 	// Add parent hooks, but drop parent's catch-all if child has one
 	for _, h := range parent {
-		if childHasCatchAll && h.When == "" {
+		if childHasCatchAll && h.When == "" && !h.Fallthrough {
 			// Skip parent catch-all -- child's catch-all shadows it
 			continue
 		}
@@ -324,6 +324,32 @@ func TestMergeDatacenters_EnvironmentModules(t *testing.T) {
 	assert.Equal(t, "monitoring", mods[1].Name)
 }
 
+func TestMergeDatacenters_EnvironmentInjections(t *testing.T) {
+	child := &internal.InternalDatacenter{
+		SourceVersion: "v1",
+		Environment: internal.InternalEnvironment{
+			Injections: []internal.InternalInjection{
+				{Name: "proxy", Environment: map[string]string{"HTTPS_PROXY": "http://child:3128"}},
+			},
+		},
+	}
+	parent := &internal.InternalDatacenter{
+		Environment: internal.InternalEnvironment{
+			Injections: []internal.InternalInjection{
+				{Name: "proxy", Environment: map[string]string{"HTTPS_PROXY": "http://parent:3128"}},
+				{Name: "region", Environment: map[string]string{"AWS_REGION": "us-east-1"}},
+			},
+		},
+	}
+
+	merged := MergeDatacenters(child, parent)
+	injections := merged.Environment.Injections
+	require.Len(t, injections, 2)
+	assert.Equal(t, "proxy", injections[0].Name)
+	assert.Equal(t, "http://child:3128", injections[0].Environment["HTTPS_PROXY"]) // child wins
+	assert.Equal(t, "region", injections[1].Name)
+}
+
 func TestMergeDatacenters_AllHookTypes(t *testing.T) {
 	// Verify that all hook types are properly merged
 	childHook := internal.InternalHook{When: "child", Outputs: map[string]string{"x": "y"}}
@@ -0,0 +1,39 @@
+package datacenter
+
+import (
+	"github.com/davidthor/cldctl/pkg/schema/datacenter/internal"
+)
+
+// RequiredHookOutputs returns the outputs a hook of the given type must
+// produce: the built-in contract for the type (e.g., host, port and url for
+// database hooks) followed by any extra outputs the hook guarantees. Nested
+// outputs are named with dot paths such as "read.host".
+func RequiredHookOutputs(hookType string, hook Hook) []string {
+	if hook == nil {
+		return append([]string(nil), internal.RequiredHookOutputs[hookType]...)
+	}
+	return internal.HookRequiredOutputs(hookType, toInternalHook(hook))
+}
+
+// HookDeclaresOutput reports whether the hook's outputs block declares key.
+// A dot path such as "read.host" refers to a key inside a nested output
+// object.
+func HookDeclaresOutput(hook Hook, key string) bool {
+	return internal.DeclaresOutput(toInternalHook(hook), key)
+}
+
+func toInternalHook(hook Hook) internal.InternalHook {
+	if w, ok := hook.(*hookWrapper); ok {
+		return *w.h
+	}
+	return internal.InternalHook{
+		When:              hook.When(),
+		Outputs:           hook.Outputs(),
+		NestedOutputs:     hook.NestedOutputs(),
+		Guarantees:        hook.Guarantees(),
+		Error:             hook.Error(),
+		ErrorCode:         hook.ErrorCode(),
+		ErrorDocsURL:      hook.ErrorDocsURL(),
+		ErrorAlternatives: hook.ErrorAlternatives(),
+	}
+}
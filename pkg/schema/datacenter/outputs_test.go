@@ -0,0 +1,65 @@
+package datacenter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoader_HookGuaranteesMustBeDeclared(t *testing.T) {
+	_, err := NewLoader().LoadFromBytes([]byte(`
+environment {
+  database {
+    guarantees = ["poolUrl", "read.host"]
+
+    module "postgres" {
+      build = "./modules/postgres"
+    }
+
+    outputs = {
+      host = module.postgres.host
+      port = module.postgres.port
+      url  = module.postgres.url
+    }
+  }
+}
+`), "/tmp/dc/datacenter.dc")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing required outputs: poolUrl, read.host")
+}
+
+func TestRequiredHookOutputs(t *testing.T) {
+	dc, err := NewLoader().LoadFromBytes([]byte(`
+environment {
+  database {
+    guarantees = ["poolUrl", "read.host", "url"]
+
+    module "postgres" {
+      build = "./modules/postgres"
+    }
+
+    outputs = {
+      host    = module.postgres.host
+      port    = module.postgres.port
+      url     = module.postgres.url
+      poolUrl = module.postgres.pool_url
+      read = {
+        host = module.postgres.replica_host
+      }
+    }
+  }
+}
+`), "/tmp/dc/datacenter.dc")
+	require.NoError(t, err)
+
+	hook := dc.Environment().Hooks().Database()[0]
+	assert.Equal(t, []string{"host", "port", "url", "poolUrl", "read.host"}, RequiredHookOutputs("database", hook))
+	assert.Equal(t, []string{"id"}, RequiredHookOutputs("deployment", nil))
+
+	assert.True(t, HookDeclaresOutput(hook, "poolUrl"))
+	assert.True(t, HookDeclaresOutput(hook, "read"))
+	assert.True(t, HookDeclaresOutput(hook, "read.host"))
+	assert.False(t, HookDeclaresOutput(hook, "read.url"))
+	assert.False(t, HookDeclaresOutput(hook, "password"))
+}
@@ -0,0 +1,41 @@
+package datacenter
+
+import (
+	"sort"
+
+	"github.com/davidthor/cldctl/pkg/schema/datacenter/internal"
+)
+
+// HookEnvironmentModules returns the names of the environment-scoped modules
+// a hook's module inputs and outputs reference, sorted. These modules must be
+// provisioned before the hook runs.
+func HookEnvironmentModules(hook Hook) []string {
+	var exprs []string
+	for _, mod := range hook.Modules() {
+		for _, expr := range mod.Inputs() {
+			exprs = append(exprs, expr)
+		}
+		exprs = append(exprs, mod.When(), mod.CacheKey())
+	}
+	for _, expr := range hook.Outputs() {
+		exprs = append(exprs, expr)
+	}
+	for _, nested := range hook.NestedOutputs() {
+		for _, expr := range nested {
+			exprs = append(exprs, expr)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, expr := range exprs {
+		for _, name := range internal.EnvironmentModuleRefs(expr) {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
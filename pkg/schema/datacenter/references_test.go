@@ -0,0 +1,107 @@
+package datacenter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHookEnvironmentModules(t *testing.T) {
+	dc, err := NewLoader().LoadFromBytes([]byte(`
+environment {
+  module "ingress" {
+    build = "./modules/ingress"
+  }
+
+  module "otel" {
+    build = "./modules/otel"
+    inputs = {
+      namespace = environment.module.ingress.namespace
+    }
+  }
+
+  deployment {
+    module "deployment" {
+      build = "./modules/deployment"
+      inputs = {
+        collector = "${environment.module.otel.endpoint}"
+      }
+    }
+    outputs = {
+      id = module.deployment.id
+    }
+  }
+
+  route {
+    module "route" {
+      build = "./modules/route"
+    }
+    outputs = {
+      url  = "https://${environment.module.ingress.host}"
+      host = environment.module.ingress.host
+      port = 443
+    }
+  }
+}
+`), "/tmp/dc/datacenter.dc")
+	require.NoError(t, err)
+
+	hooks := dc.Environment().Hooks()
+	assert.Equal(t, []string{"otel"}, HookEnvironmentModules(hooks.Deployment()[0]))
+	assert.Equal(t, []string{"ingress"}, HookEnvironmentModules(hooks.Route()[0]))
+}
+
+func TestEnvironmentModuleRefValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "undeclared module in hook",
+			src: `
+environment {
+  deployment {
+    module "deployment" {
+      build = "./modules/deployment"
+      inputs = {
+        collector = environment.module.otel.endpoint
+      }
+    }
+    outputs = {
+      id = module.deployment.id
+    }
+  }
+}
+`,
+			want: "deployment hook references undeclared environment module otel",
+		},
+		{
+			name: "module declared later",
+			src: `
+environment {
+  module "otel" {
+    build = "./modules/otel"
+    inputs = {
+      namespace = environment.module.ingress.namespace
+    }
+  }
+
+  module "ingress" {
+    build = "./modules/ingress"
+  }
+}
+`,
+			want: "environment module otel references environment module ingress, which must be declared before it",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewLoader().LoadFromBytes([]byte(tt.src), "/tmp/dc/datacenter.dc")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
@@ -29,6 +29,7 @@ type EnvironmentContext struct {
 	Datacenter string
 	Account    string
 	Region     string
+	Labels     map[string]string
 }
 
 // NodeContext holds the current resource node being processed.
@@ -103,6 +104,12 @@ func (ctx *EvalContext) ToHCLContext() *hcl.EvalContext {
 		if ctx.Environment.Region != "" {
 			envValues["region"] = cty.StringVal(ctx.Environment.Region)
 		}
+		// Labels are always set so that hooks can use lookup() on them
+		labels := make(map[string]cty.Value, len(ctx.Environment.Labels))
+		for k, v := range ctx.Environment.Labels {
+			labels[k] = cty.StringVal(v)
+		}
+		envValues["labels"] = cty.ObjectVal(labels)
 		vars["environment"] = cty.ObjectVal(envValues)
 	}
 
@@ -118,6 +125,12 @@ func (ctx *EvalContext) ToHCLContext() *hcl.EvalContext {
 		} else {
 			nodeValues["inputs"] = cty.EmptyObjectVal
 		}
+		// node.labels is shorthand for the resource's labels input
+		if labels, ok := ctx.Node.Inputs["labels"]; ok && !labels.IsNull() && (labels.Type().IsObjectType() || labels.Type().IsMapType()) {
+			nodeValues["labels"] = labels
+		} else {
+			nodeValues["labels"] = cty.EmptyObjectVal
+		}
 		vars["node"] = cty.ObjectVal(nodeValues)
 	}
 
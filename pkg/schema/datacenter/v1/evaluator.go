@@ -314,6 +314,15 @@ func (e *Evaluator) SetEnvironmentContext(name, datacenter, account, region stri
 	}
 }
 
+// SetEnvironmentLabels sets the environment's labels, read as
+// environment.labels.<key>.
+func (e *Evaluator) SetEnvironmentLabels(labels map[string]string) {
+	if e.ctx.Environment == nil {
+		e.ctx.Environment = &EnvironmentContext{}
+	}
+	e.ctx.Environment.Labels = labels
+}
+
 // SetVariables sets datacenter variables.
 func (e *Evaluator) SetVariables(vars map[string]interface{}) {
 	for k, v := range vars {
@@ -2,10 +2,15 @@ package v1
 
 import (
 	"fmt"
+	"math/big"
 	"os"
+	"path"
+	"strconv"
+	"strings"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/zclconf/go-cty/cty"
 )
 
@@ -75,11 +80,16 @@ func (p *Parser) ParseBytes(data []byte, filename string) (*SchemaV1, hcl.Diagno
 			{Type: "variable", LabelNames: []string{"name"}},
 			{Type: "module", LabelNames: []string{"name"}},
 			{Type: "component", LabelNames: []string{"name"}},
+			{Type: "sandbox"},
 			{Type: "environment"},
 		},
 	}
 
-	content, moreDiags := file.Body.Content(bodySchema)
+	// Environment blocks labeled with a name pattern only set variable
+	// defaults, so they're split off from the environment block
+	body, defaultsBlocks := splitEnvironmentDefaults(file.Body)
+
+	content, moreDiags := body.Content(bodySchema)
 	diags = append(diags, moreDiags...)
 
 	// Get HCL evaluation context
@@ -112,6 +122,19 @@ func (p *Parser) ParseBytes(data []byte, filename string) (*SchemaV1, hcl.Diagno
 		}
 	}
 
+	// Parse per-environment variable defaults
+	declared := make(map[string]bool, len(schema.Variables))
+	for _, v := range schema.Variables {
+		declared[v.Name] = true
+	}
+	for _, block := range defaultsBlocks {
+		defaults, blockDiags := p.parseEnvironmentDefaults(block, declared, schema.Extends != nil)
+		diags = append(diags, blockDiags...)
+		if defaults != nil {
+			schema.EnvironmentDefaults = append(schema.EnvironmentDefaults, *defaults)
+		}
+	}
+
 	// Parse top-level modules
 	for _, block := range content.Blocks.OfType("module") {
 		module, blockDiags := p.parseModule(block)
@@ -130,6 +153,11 @@ func (p *Parser) ParseBytes(data []byte, filename string) (*SchemaV1, hcl.Diagno
 		}
 	}
 
+	// Parse datacenter-wide sandbox
+	sandbox, sandboxDiags := p.parseSandboxBlocks(content.Blocks.OfType("sandbox"))
+	diags = append(diags, sandboxDiags...)
+	schema.Sandbox = sandbox
+
 	// Parse environment block
 	for _, block := range content.Blocks.OfType("environment") {
 		env, blockDiags := p.parseEnvironment(block)
@@ -141,6 +169,92 @@ func (p *Parser) ParseBytes(data []byte, filename string) (*SchemaV1, hcl.Diagno
 	return schema, diags, nil
 }
 
+// splitEnvironmentDefaults separates the environment blocks labeled with a
+// name pattern from the rest of a datacenter's body.
+func splitEnvironmentDefaults(body hcl.Body) (hcl.Body, []*hcl.Block) {
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return body, nil
+	}
+
+	rest := *syntaxBody
+	rest.Blocks = nil
+	var defaults []*hcl.Block
+	for _, block := range syntaxBody.Blocks {
+		if block.Type == "environment" && len(block.Labels) == 1 {
+			defaults = append(defaults, block.AsHCLBlock())
+			continue
+		}
+		rest.Blocks = append(rest.Blocks, block)
+	}
+	return &rest, defaults
+}
+
+// parseEnvironmentDefaults parses an environment block that sets variable
+// defaults for the environments whose names match its pattern. Unless the
+// datacenter extends another, which may declare them, the variables must be
+// declared.
+func (p *Parser) parseEnvironmentDefaults(block *hcl.Block, declared map[string]bool, extends bool) (*EnvironmentDefaultsBlockV1, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+
+	pattern := block.Labels[0]
+	if _, err := path.Match(pattern, ""); err != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid environment pattern",
+			Detail:   fmt.Sprintf("%q is not a valid environment name pattern: %v.", pattern, err),
+			Subject:  block.LabelRanges[0].Ptr(),
+		})
+		return nil, diags
+	}
+
+	content, moreDiags := block.Body.Content(&hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "variables", Required: true},
+		},
+	})
+	diags = append(diags, moreDiags...)
+	attr, ok := content.Attributes["variables"]
+	if !ok {
+		return nil, diags
+	}
+
+	val, valDiags := attr.Expr.Value(p.getHCLContext())
+	diags = append(diags, valDiags...)
+	if valDiags.HasErrors() {
+		return nil, diags
+	}
+	if !val.Type().IsObjectType() && !val.Type().IsMapType() {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid environment variables",
+			Detail:   "The 'variables' attribute must be an object of variable values.",
+			Subject:  attr.Expr.Range().Ptr(),
+		})
+		return nil, diags
+	}
+
+	defaults := &EnvironmentDefaultsBlockV1{
+		Pattern:   pattern,
+		Variables: make(map[string]cty.Value),
+	}
+	for it := val.ElementIterator(); it.Next(); {
+		k, v := it.Element()
+		name := k.AsString()
+		if !extends && !declared[name] {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Undeclared variable",
+				Detail:   fmt.Sprintf("Environment %q sets a default for variable %q, which isn't declared.", pattern, name),
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+			continue
+		}
+		defaults.Variables[name] = v
+	}
+	return defaults, diags
+}
+
 func (p *Parser) parseExtends(attr *hcl.Attribute) (*ExtendsBlockV1, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
 	hclCtx := p.getHCLContext()
@@ -275,10 +389,13 @@ func (p *Parser) parseModule(block *hcl.Block) (*ModuleBlockV1, hcl.Diagnostics)
 			{Name: "when"},
 			{Name: "environment"},
 			{Name: "inputs"},
+			{Name: "cacheable"},
+			{Name: "cache_key"},
 		},
 		Blocks: []hcl.BlockHeaderSchema{
 			{Type: "inputs"},
 			{Type: "volume"},
+			{Type: "credentials"},
 		},
 	}
 
@@ -369,9 +486,256 @@ func (p *Parser) parseModule(block *hcl.Block) (*ModuleBlockV1, hcl.Diagnostics)
 		}
 	}
 
+	creds, credDiags := p.parseCredentialsBlocks(content.Blocks.OfType("credentials"))
+	diags = append(diags, credDiags...)
+	module.Credentials = creds
+
+	if attr, ok := content.Attributes["cacheable"]; ok {
+		val, valDiags := attr.Expr.Value(hclCtx)
+		diags = append(diags, valDiags...)
+		if !valDiags.HasErrors() {
+			if val.Type() != cty.Bool || val.IsNull() {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid cacheable",
+					Detail:   "cacheable must be true or false.",
+					Subject:  attr.Expr.Range().Ptr(),
+				})
+			} else {
+				module.Cacheable = val.True()
+			}
+		}
+	}
+
+	if attr, ok := content.Attributes["cache_key"]; ok {
+		// Cache keys usually reference node inputs or other modules' outputs,
+		// so they're evaluated at runtime
+		module.CacheKeyExpr = attr.Expr
+		if !module.Cacheable {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid cache_key",
+				Detail:   "cache_key only applies to modules with cacheable = true.",
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+		}
+	}
+
 	return module, diags
 }
 
+// parseCredentialsBlocks parses the optional credentials block of a hook or
+// module. At most one block is allowed.
+func (p *Parser) parseCredentialsBlocks(blocks hcl.Blocks) (*CredentialsBlockV1, hcl.Diagnostics) {
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+	var diags hcl.Diagnostics
+	for _, dup := range blocks[1:] {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Duplicate credentials block",
+			Detail:   "Only one credentials block is allowed.",
+			Subject:  dup.DefRange.Ptr(),
+		})
+	}
+	creds, credDiags := p.parseCredentials(blocks[0])
+	diags = append(diags, credDiags...)
+	return creds, diags
+}
+
+// parseSandboxBlocks parses the optional sandbox block of a datacenter or
+// hook. At most one block is allowed.
+func (p *Parser) parseSandboxBlocks(blocks hcl.Blocks) (*SandboxBlockV1, hcl.Diagnostics) {
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+	var diags hcl.Diagnostics
+	for _, dup := range blocks[1:] {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Duplicate sandbox block",
+			Detail:   "Only one sandbox block is allowed.",
+			Subject:  dup.DefRange.Ptr(),
+		})
+	}
+	sandbox, sandboxDiags := p.parseSandbox(blocks[0])
+	diags = append(diags, sandboxDiags...)
+	return sandbox, diags
+}
+
+func (p *Parser) parseSandbox(block *hcl.Block) (*SandboxBlockV1, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	hclCtx := p.getHCLContext()
+
+	sandboxSchema := &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "network"},
+			{Name: "read_only_root"},
+			{Name: "memory"},
+			{Name: "cpus"},
+			{Name: "pids_limit"},
+			{Name: "seccomp_profile"},
+		},
+	}
+
+	content, moreDiags := block.Body.Content(sandboxSchema)
+	diags = append(diags, moreDiags...)
+	if moreDiags.HasErrors() {
+		return nil, diags
+	}
+
+	sandbox := &SandboxBlockV1{
+		Remain: block.Body,
+	}
+
+	invalid := func(attr *hcl.Attribute, detail string) {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid sandbox " + attr.Name,
+			Detail:   detail,
+			Subject:  attr.Expr.Range().Ptr(),
+		})
+	}
+	value := func(name string, ty cty.Type) (*hcl.Attribute, cty.Value, bool) {
+		attr, ok := content.Attributes[name]
+		if !ok {
+			return nil, cty.NilVal, false
+		}
+		val, valDiags := attr.Expr.Value(hclCtx)
+		diags = append(diags, valDiags...)
+		if valDiags.HasErrors() {
+			return nil, cty.NilVal, false
+		}
+		if ty != cty.DynamicPseudoType && (val.IsNull() || val.Type() != ty) {
+			invalid(attr, fmt.Sprintf("%s must be a %s.", name, ty.FriendlyName()))
+			return nil, cty.NilVal, false
+		}
+		return attr, val, true
+	}
+
+	if _, val, ok := value("network", cty.Bool); ok {
+		sandbox.NoNetwork = val.False()
+	}
+	if _, val, ok := value("read_only_root", cty.Bool); ok {
+		sandbox.ReadOnlyRoot = val.True()
+	}
+	if attr, val, ok := value("memory", cty.DynamicPseudoType); ok {
+		memory, err := parseByteSize(val)
+		if err != nil {
+			invalid(attr, err.Error())
+		} else {
+			sandbox.Memory = memory
+		}
+	}
+	if attr, val, ok := value("cpus", cty.Number); ok {
+		cpus, _ := val.AsBigFloat().Float64()
+		if cpus <= 0 {
+			invalid(attr, "cpus must be greater than zero.")
+		} else {
+			sandbox.CPUs = cpus
+		}
+	}
+	if attr, val, ok := value("pids_limit", cty.Number); ok {
+		limit, acc := val.AsBigFloat().Int64()
+		if acc != big.Exact || limit <= 0 {
+			invalid(attr, "pids_limit must be a positive whole number.")
+		} else {
+			sandbox.PidsLimit = limit
+		}
+	}
+	if _, val, ok := value("seccomp_profile", cty.String); ok {
+		sandbox.SeccompProfile = val.AsString()
+	}
+
+	return sandbox, diags
+}
+
+// parseByteSize parses a memory size given either as a number of bytes or
+// as a string with a binary unit suffix (e.g., "512m", "2Gi").
+func parseByteSize(val cty.Value) (int64, error) {
+	if val.IsNull() {
+		return 0, fmt.Errorf("memory must be a size such as \"512m\" or \"2g\".")
+	}
+	if val.Type() == cty.Number {
+		n, acc := val.AsBigFloat().Int64()
+		if acc != big.Exact || n <= 0 {
+			return 0, fmt.Errorf("memory must be a positive number of bytes.")
+		}
+		return n, nil
+	}
+	if val.Type() != cty.String {
+		return 0, fmt.Errorf("memory must be a size such as \"512m\" or \"2g\".")
+	}
+
+	s := strings.ToLower(strings.TrimSpace(val.AsString()))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "b"), "i")
+	multiplier := int64(1)
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'k':
+			multiplier = 1 << 10
+		case 'm':
+			multiplier = 1 << 20
+		case 'g':
+			multiplier = 1 << 30
+		}
+		if multiplier > 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("memory must be a size such as \"512m\" or \"2g\", got %q.", val.AsString())
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+func (p *Parser) parseCredentials(block *hcl.Block) (*CredentialsBlockV1, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+
+	credSchema := &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "role_arn"},
+			{Name: "service_account"},
+			{Name: "env_set"},
+		},
+	}
+
+	content, moreDiags := block.Body.Content(credSchema)
+	diags = append(diags, moreDiags...)
+	if moreDiags.HasErrors() {
+		return nil, diags
+	}
+
+	// Values are kept as expressions: they usually reference datacenter
+	// variables that are only known at deploy time
+	creds := &CredentialsBlockV1{
+		Remain: block.Body,
+	}
+	if attr, ok := content.Attributes["role_arn"]; ok {
+		creds.RoleARNExpr = attr.Expr
+	}
+	if attr, ok := content.Attributes["service_account"]; ok {
+		creds.ServiceAccountExpr = attr.Expr
+	}
+	if attr, ok := content.Attributes["env_set"]; ok {
+		creds.EnvSetExpr = attr.Expr
+	}
+
+	if creds.RoleARNExpr == nil && creds.ServiceAccountExpr == nil && creds.EnvSetExpr == nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Empty credentials block",
+			Detail:   "credentials must set at least one of role_arn, service_account, or env_set.",
+			Subject:  block.DefRange.Ptr(),
+		})
+		return nil, diags
+	}
+
+	return creds, diags
+}
+
 func (p *Parser) parseVolume(block *hcl.Block) (*VolumeBlockV1, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
 	hclCtx := p.getHCLContext()
@@ -438,6 +802,11 @@ func (p *Parser) parseEnvironment(block *hcl.Block) (*EnvironmentBlockV1, hcl.Di
 			{Type: "observability"},
 			{Type: "port"},
 			{Type: "networkPolicy"},
+			{Type: "routeAuth"},
+			{Type: "inject", LabelNames: []string{"name"}},
+			{Type: "exports"},
+			{Type: "on_deploy"},
+			{Type: "on_destroy"},
 		},
 	}
 
@@ -457,6 +826,64 @@ func (p *Parser) parseEnvironment(block *hcl.Block) (*EnvironmentBlockV1, hcl.Di
 		}
 	}
 
+	// Parse environment injections
+	seenInjections := make(map[string]bool)
+	for _, injectBlock := range content.Blocks.OfType("inject") {
+		inject, injectDiags := p.parseInject(injectBlock)
+		diags = append(diags, injectDiags...)
+		if inject == nil {
+			continue
+		}
+		if seenInjections[inject.Name] {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate inject block",
+				Detail:   fmt.Sprintf("An inject block named %q is already defined. Inject block names must be unique so components can exclude them by name.", inject.Name),
+				Subject:  injectBlock.DefRange.Ptr(),
+			})
+			continue
+		}
+		seenInjections[inject.Name] = true
+		env.Injections = append(env.Injections, *inject)
+	}
+
+	// Parse platform exports
+	for _, exportsBlock := range content.Blocks.OfType("exports") {
+		if env.Exports != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate exports block",
+				Detail:   "An environment can have only one exports block. Declare all of its exports in the same block.",
+				Subject:  exportsBlock.DefRange.Ptr(),
+			})
+			continue
+		}
+		exports, exportsDiags := p.parseExports(exportsBlock)
+		diags = append(diags, exportsDiags...)
+		env.Exports = exports
+	}
+
+	// Parse environment lifecycle hooks
+	for blockType, target := range map[string]**EnvironmentHookBlockV1{
+		"on_deploy":  &env.OnDeploy,
+		"on_destroy": &env.OnDestroy,
+	} {
+		for _, hookBlock := range content.Blocks.OfType(blockType) {
+			if *target != nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  fmt.Sprintf("Duplicate %s block", blockType),
+					Detail:   fmt.Sprintf("An environment can have only one %s block. Declare all of its modules in the same block.", blockType),
+					Subject:  hookBlock.DefRange.Ptr(),
+				})
+				continue
+			}
+			hook, hookDiags := p.parseEnvironmentHook(hookBlock)
+			diags = append(diags, hookDiags...)
+			*target = hook
+		}
+	}
+
 	// Parse hooks
 	hookTypes := map[string]*[]HookBlockV1{
 		"database":      &env.DatabaseHooks,
@@ -475,6 +902,7 @@ func (p *Parser) parseEnvironment(block *hcl.Block) (*EnvironmentBlockV1, hcl.Di
 		"observability": &env.ObservabilityHooks,
 		"port":          &env.PortHooks,
 		"networkPolicy": &env.NetworkPolicyHooks,
+		"routeAuth":     &env.RouteAuthHooks,
 	}
 
 	for hookType, hooks := range hookTypes {
@@ -487,14 +915,23 @@ func (p *Parser) parseEnvironment(block *hcl.Block) (*EnvironmentBlockV1, hcl.Di
 		}
 
 		// Validate reachability: a hook without a 'when' condition (catch-all)
-		// must be the last hook of its type. Any hooks after it are unreachable.
+		// must be the last hook of its type to be tried. Any hooks tried after
+		// it are unreachable, unless it falls through.
 		for i, hook := range *hooks {
-			if hook.When == "" && hook.WhenExpr == nil && i < len(*hooks)-1 {
-				remaining := len(*hooks) - i - 1
+			if hook.When != "" || hook.WhenExpr != nil || hook.Fallthrough {
+				continue
+			}
+			remaining := 0
+			for j, other := range *hooks {
+				if other.Priority < hook.Priority || (other.Priority == hook.Priority && j > i) {
+					remaining++
+				}
+			}
+			if remaining > 0 {
 				diags = append(diags, &hcl.Diagnostic{
 					Severity: hcl.DiagError,
 					Summary:  fmt.Sprintf("Unreachable %s hook(s)", hookType),
-					Detail:   fmt.Sprintf("A %s hook without a 'when' condition matches all resources and must be the last hook of its type, but %d more %s hook(s) follow. Move the catch-all hook to the end or add a 'when' condition.", hookType, remaining, hookType),
+					Detail:   fmt.Sprintf("A %s hook without a 'when' condition matches all resources and must be the last hook of its type, but %d more %s hook(s) follow. Move the catch-all hook to the end, give it a lower priority, or add a 'when' condition.", hookType, remaining, hookType),
 				})
 				break
 			}
@@ -504,6 +941,46 @@ func (p *Parser) parseEnvironment(block *hcl.Block) (*EnvironmentBlockV1, hcl.Di
 	return env, diags
 }
 
+// parseEnvironmentHook parses an on_deploy or on_destroy block.
+func (p *Parser) parseEnvironmentHook(block *hcl.Block) (*EnvironmentHookBlockV1, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+
+	hookSchema := &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "module", LabelNames: []string{"name"}},
+		},
+	}
+
+	content, moreDiags := block.Body.Content(hookSchema)
+	diags = append(diags, moreDiags...)
+
+	hook := &EnvironmentHookBlockV1{
+		Remain: block.Body,
+	}
+
+	seen := make(map[string]bool)
+	for _, modBlock := range content.Blocks.OfType("module") {
+		module, modDiags := p.parseModule(modBlock)
+		diags = append(diags, modDiags...)
+		if module == nil {
+			continue
+		}
+		if seen[module.Name] {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate module",
+				Detail:   fmt.Sprintf("A module named %q is already defined in this %s block.", module.Name, block.Type),
+				Subject:  modBlock.DefRange.Ptr(),
+			})
+			continue
+		}
+		seen[module.Name] = true
+		hook.Modules = append(hook.Modules, *module)
+	}
+
+	return hook, diags
+}
+
 func (p *Parser) parseComponent(block *hcl.Block) (*ComponentBlockV1, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
 	hclCtx := p.getHCLContext()
@@ -540,6 +1017,88 @@ func (p *Parser) parseComponent(block *hcl.Block) (*ComponentBlockV1, hcl.Diagno
 	return comp, diags
 }
 
+// parseExports parses an environment's exports block. Exports are shared by
+// every component in the environment, so they can only refer to the
+// environment and datacenter variables, not to a resource being provisioned.
+func (p *Parser) parseExports(block *hcl.Block) (hcl.Attributes, hcl.Diagnostics) {
+	attrs, diags := block.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	for name, attr := range attrs {
+		for _, traversal := range attr.Expr.Variables() {
+			switch root := traversal.RootName(); root {
+			case "environment", "variable":
+			default:
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid export",
+					Detail:   fmt.Sprintf("Export %q refers to %q. Exports can only refer to environment (e.g. environment.module.<name>.<output>) and variable.", name, root),
+					Subject:  traversal.SourceRange().Ptr(),
+				})
+			}
+		}
+	}
+	return attrs, diags
+}
+
+func (p *Parser) parseInject(block *hcl.Block) (*InjectBlockV1, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	hclCtx := p.getHCLContext()
+
+	injectSchema := &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "when"},
+			{Name: "environment", Required: true},
+		},
+	}
+
+	content, moreDiags := block.Body.Content(injectSchema)
+	diags = append(diags, moreDiags...)
+	if moreDiags.HasErrors() {
+		return nil, diags
+	}
+
+	inject := &InjectBlockV1{
+		Name:   block.Labels[0],
+		Remain: block.Body,
+	}
+
+	if attr, ok := content.Attributes["when"]; ok {
+		// Store raw expression for runtime evaluation; conditions usually
+		// reference node.type or node.component, which are only known at deploy
+		inject.WhenExpr = attr.Expr
+		val, valDiags := attr.Expr.Value(hclCtx)
+		if !valDiags.HasErrors() {
+			switch val.Type() {
+			case cty.Bool:
+				if val.True() {
+					inject.When = "true"
+				} else {
+					inject.When = "false"
+				}
+			case cty.String:
+				inject.When = val.AsString()
+			}
+		}
+	}
+
+	attr := content.Attributes["environment"]
+	if _, ok := attr.Expr.(*hclsyntax.ObjectConsExpr); !ok {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid inject environment",
+			Detail:   "environment must be an object mapping variable names to values.",
+			Subject:  attr.Expr.Range().Ptr(),
+		})
+		return nil, diags
+	}
+	inject.EnvironmentExpr = attr.Expr
+
+	return inject, diags
+}
+
 func (p *Parser) parseHook(block *hcl.Block) (*HookBlockV1, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
 	hclCtx := p.getHCLContext()
@@ -548,11 +1107,23 @@ func (p *Parser) parseHook(block *hcl.Block) (*HookBlockV1, hcl.Diagnostics) {
 		Attributes: []hcl.AttributeSchema{
 			{Name: "when"},
 			{Name: "outputs"},
+			{Name: "guarantees"},
+			{Name: "replace_on"},
 			{Name: "error"},
+			{Name: "error_code"},
+			{Name: "error_docs_url"},
+			{Name: "error_alternatives"},
+			{Name: "priority"},
+			{Name: "fallthrough"},
+			{Name: "aggregate"},
+			{Name: "shared"},
 		},
 		Blocks: []hcl.BlockHeaderSchema{
 			{Type: "module", LabelNames: []string{"name"}},
+			{Type: "validate", LabelNames: []string{"name"}},
 			{Type: "outputs"},
+			{Type: "credentials"},
+			{Type: "sandbox"},
 		},
 	}
 
@@ -583,6 +1154,138 @@ func (p *Parser) parseHook(block *hcl.Block) (*HookBlockV1, hcl.Diagnostics) {
 		}
 	}
 
+	// Parse guarantees: extra outputs this hook promises on top of the
+	// built-in contract for its type
+	if attr, ok := content.Attributes["guarantees"]; ok {
+		val, valDiags := attr.Expr.Value(hclCtx)
+		diags = append(diags, valDiags...)
+		if !valDiags.HasErrors() {
+			if !val.Type().IsTupleType() && !val.Type().IsListType() {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid guarantees",
+					Detail:   "guarantees must be a list of output names.",
+					Subject:  attr.Expr.Range().Ptr(),
+				})
+			} else {
+				for _, v := range val.AsValueSlice() {
+					if v.IsNull() || v.Type() != cty.String {
+						diags = append(diags, &hcl.Diagnostic{
+							Severity: hcl.DiagError,
+							Summary:  "Invalid guarantees",
+							Detail:   "guarantees must be a list of output names.",
+							Subject:  attr.Expr.Range().Ptr(),
+						})
+						break
+					}
+					hook.Guarantees = append(hook.Guarantees, v.AsString())
+				}
+			}
+		}
+	}
+
+	// Parse replace_on: the node inputs whose changes force the resource to
+	// be replaced rather than updated in place
+	if attr, ok := content.Attributes["replace_on"]; ok {
+		val, valDiags := attr.Expr.Value(hclCtx)
+		diags = append(diags, valDiags...)
+		if !valDiags.HasErrors() {
+			invalid := !val.Type().IsTupleType() && !val.Type().IsListType()
+			if !invalid {
+				for _, v := range val.AsValueSlice() {
+					if v.IsNull() || v.Type() != cty.String {
+						invalid = true
+						break
+					}
+					hook.ReplaceOn = append(hook.ReplaceOn, v.AsString())
+				}
+			}
+			if invalid {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid replace_on",
+					Detail:   `replace_on must be a list of node input names, or ["*"] to replace the resource on any change.`,
+					Subject:  attr.Expr.Range().Ptr(),
+				})
+			}
+		}
+	}
+
+	// Parse priority: hooks of a type are tried from the highest priority to
+	// the lowest, and in declaration order within a priority
+	if attr, ok := content.Attributes["priority"]; ok {
+		val, valDiags := attr.Expr.Value(hclCtx)
+		diags = append(diags, valDiags...)
+		if !valDiags.HasErrors() {
+			var priority int64
+			acc := big.Below
+			if !val.IsNull() && val.Type() == cty.Number {
+				priority, acc = val.AsBigFloat().Int64()
+			}
+			if acc != big.Exact {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid priority",
+					Detail:   "priority must be a whole number. Hooks with a higher priority are tried first.",
+					Subject:  attr.Expr.Range().Ptr(),
+				})
+			} else {
+				hook.Priority = int(priority)
+			}
+		}
+	}
+
+	if attr, ok := content.Attributes["fallthrough"]; ok {
+		val, valDiags := attr.Expr.Value(hclCtx)
+		diags = append(diags, valDiags...)
+		if !valDiags.HasErrors() {
+			if val.IsNull() || val.Type() != cty.Bool {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid fallthrough",
+					Detail:   "fallthrough must be true or false.",
+					Subject:  attr.Expr.Range().Ptr(),
+				})
+			} else {
+				hook.Fallthrough = val.True()
+			}
+		}
+	}
+
+	if attr, ok := content.Attributes["aggregate"]; ok {
+		val, valDiags := attr.Expr.Value(hclCtx)
+		diags = append(diags, valDiags...)
+		if !valDiags.HasErrors() {
+			if val.IsNull() || val.Type() != cty.Bool {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid aggregate",
+					Detail:   "aggregate must be true or false.",
+					Subject:  attr.Expr.Range().Ptr(),
+				})
+			} else {
+				hook.Aggregate = val.True()
+			}
+		}
+	}
+
+	if attr, ok := content.Attributes["shared"]; ok {
+		val, valDiags := attr.Expr.Value(hclCtx)
+		diags = append(diags, valDiags...)
+		if !valDiags.HasErrors() {
+			if val.IsNull() || val.Type() != cty.Bool {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid shared",
+					Detail:   "shared must be true or false.",
+					Subject:  attr.Expr.Range().Ptr(),
+				})
+			} else {
+				hook.Shared = val.True()
+			}
+		}
+	}
+
 	// Parse error attribute
 	if attr, ok := content.Attributes["error"]; ok {
 		hook.ErrorExpr = attr.Expr
@@ -607,6 +1310,58 @@ func (p *Parser) parseHook(block *hcl.Block) (*HookBlockV1, hcl.Diagnostics) {
 		}
 	}
 
+	// Parse the error's remediation: a code, a docs link, and alternatives
+	for _, field := range []struct {
+		name   string
+		target *string
+	}{{"error_code", &hook.ErrorCode}, {"error_docs_url", &hook.ErrorDocsURL}} {
+		name := field.name
+		attr, ok := content.Attributes[name]
+		if !ok {
+			continue
+		}
+		val, valDiags := attr.Expr.Value(hclCtx)
+		diags = append(diags, valDiags...)
+		if valDiags.HasErrors() {
+			continue
+		}
+		if val.IsNull() || val.Type() != cty.String {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Invalid %s", name),
+				Detail:   fmt.Sprintf("%s must be a string.", name),
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+			continue
+		}
+		*field.target = val.AsString()
+	}
+	if attr, ok := content.Attributes["error_alternatives"]; ok {
+		val, valDiags := attr.Expr.Value(hclCtx)
+		diags = append(diags, valDiags...)
+		if !valDiags.HasErrors() {
+			invalid := val.IsNull() || !(val.Type().IsListType() || val.Type().IsTupleType())
+			if !invalid {
+				for _, v := range val.AsValueSlice() {
+					if v.IsNull() || v.Type() != cty.String {
+						invalid = true
+						break
+					}
+					hook.ErrorAlternatives = append(hook.ErrorAlternatives, v.AsString())
+				}
+			}
+			if invalid {
+				hook.ErrorAlternatives = nil
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid error_alternatives",
+					Detail:   "error_alternatives must be a list of strings.",
+					Subject:  attr.Expr.Range().Ptr(),
+				})
+			}
+		}
+	}
+
 	// Parse modules
 	for _, modBlock := range content.Blocks.OfType("module") {
 		module, modDiags := p.parseModule(modBlock)
@@ -616,6 +1371,24 @@ func (p *Parser) parseHook(block *hcl.Block) (*HookBlockV1, hcl.Diagnostics) {
 		}
 	}
 
+	// Parse validate modules, which are parsed like modules but only
+	// previewed, before a deploy, to check that the hook can run
+	for _, validateBlock := range content.Blocks.OfType("validate") {
+		module, modDiags := p.parseModule(validateBlock)
+		diags = append(diags, modDiags...)
+		if module != nil {
+			hook.Validate = append(hook.Validate, *module)
+		}
+	}
+
+	creds, credDiags := p.parseCredentialsBlocks(content.Blocks.OfType("credentials"))
+	diags = append(diags, credDiags...)
+	hook.Credentials = creds
+
+	sandbox, sandboxDiags := p.parseSandboxBlocks(content.Blocks.OfType("sandbox"))
+	diags = append(diags, sandboxDiags...)
+	hook.Sandbox = sandbox
+
 	// Parse outputs - can be either an attribute (outputs = {...}) or a block (outputs {...})
 	if attr, ok := content.Attributes["outputs"]; ok {
 		// Attribute syntax: outputs = { ... }
@@ -652,6 +1425,17 @@ func (p *Parser) parseHook(block *hcl.Block) (*HookBlockV1, hcl.Diagnostics) {
 	hasModules := len(hook.Modules) > 0
 	hasOutputs := hook.OutputsExpr != nil || hook.OutputsAttrs != nil
 
+	for _, name := range []string{"error_code", "error_docs_url", "error_alternatives"} {
+		if attr, ok := content.Attributes[name]; ok && !hasError {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Invalid hook: '%s' requires 'error'", name),
+				Detail:   fmt.Sprintf("%s adds remediation to the message of an error hook, so it can only be set alongside 'error'.", name),
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+		}
+	}
+
 	if hasError && hasModules {
 		diags = append(diags, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
@@ -670,5 +1454,77 @@ func (p *Parser) parseHook(block *hcl.Block) (*HookBlockV1, hcl.Diagnostics) {
 		})
 	}
 
+	if hasError && hook.Fallthrough {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid hook: 'error' and 'fallthrough' are mutually exclusive",
+			Detail:   "A hook with an 'error' attribute rejects the resource, so matching can't fall through to the next hook.",
+			Subject:  block.DefRange.Ptr(),
+		})
+	}
+
+	if hasError && hook.Aggregate {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid hook: 'error' and 'aggregate' are mutually exclusive",
+			Detail:   "A hook with an 'error' attribute rejects the resource and runs no modules, so there is nothing to aggregate.",
+			Subject:  block.DefRange.Ptr(),
+		})
+	}
+
+	if hook.Aggregate && hook.Fallthrough {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid hook: 'aggregate' and 'fallthrough' are mutually exclusive",
+			Detail:   "An aggregate hook runs its modules once for all of the resources it matches, so they can't run alongside the modules of another hook.",
+			Subject:  block.DefRange.Ptr(),
+		})
+	}
+
+	if hook.Shared {
+		var conflict string
+		switch {
+		case hasError:
+			conflict = "error"
+		case hook.Fallthrough:
+			conflict = "fallthrough"
+		case hook.Aggregate:
+			conflict = "aggregate"
+		}
+		if conflict != "" {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Invalid hook: 'shared' and '%s' are mutually exclusive", conflict),
+				Detail:   "A shared hook's modules are provisioned once for the datacenter and reused by every environment, so they can't be combined with another hook's modules or run per environment.",
+				Subject:  block.DefRange.Ptr(),
+			})
+		}
+	}
+
+	if len(hook.ReplaceOn) > 0 && (hasError || hook.Aggregate || hook.Shared) {
+		conflict := "error"
+		switch {
+		case hook.Aggregate:
+			conflict = "aggregate"
+		case hook.Shared:
+			conflict = "shared"
+		}
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  fmt.Sprintf("Invalid hook: '%s' and 'replace_on' are mutually exclusive", conflict),
+			Detail:   "Only hooks whose modules provision each resource on its own can replace a resource.",
+			Subject:  block.DefRange.Ptr(),
+		})
+	}
+
+	if hasError && len(hook.Guarantees) > 0 {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid hook: 'error' and 'guarantees' are mutually exclusive",
+			Detail:   "A hook with an 'error' attribute rejects the resource and produces no outputs, so it cannot guarantee any.",
+			Subject:  block.DefRange.Ptr(),
+		})
+	}
+
 	return hook, diags
 }
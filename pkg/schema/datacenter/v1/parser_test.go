@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -242,6 +243,72 @@ environment {
 	}
 }
 
+func TestParser_HookErrorRemediation(t *testing.T) {
+	parser := NewParser()
+
+	hcl := `
+environment {
+  database {
+    when               = true
+    error              = "mysql is not supported."
+    error_code         = "MYSQL_UNSUPPORTED"
+    error_docs_url     = "https://docs.example.com/databases"
+    error_alternatives = ["postgres", "mariadb"]
+  }
+}
+`
+
+	schema, diags, err := parser.ParseBytes([]byte(hcl), "test.hcl")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+
+	hook := schema.Environment.DatabaseHooks[0]
+	if hook.ErrorCode != "MYSQL_UNSUPPORTED" {
+		t.Errorf("expected error code, got %q", hook.ErrorCode)
+	}
+	if hook.ErrorDocsURL != "https://docs.example.com/databases" {
+		t.Errorf("expected docs URL, got %q", hook.ErrorDocsURL)
+	}
+	if len(hook.ErrorAlternatives) != 2 || hook.ErrorAlternatives[0] != "postgres" || hook.ErrorAlternatives[1] != "mariadb" {
+		t.Errorf("expected alternatives [postgres mariadb], got %v", hook.ErrorAlternatives)
+	}
+}
+
+func TestParser_HookErrorRemediationRequiresError(t *testing.T) {
+	parser := NewParser()
+
+	hcl := `
+environment {
+  database {
+    error_code = "MYSQL_UNSUPPORTED"
+
+    module "pg" {
+      plugin = "native"
+      build  = "./modules/pg"
+    }
+  }
+}
+`
+
+	_, diags, _ := parser.ParseBytes([]byte(hcl), "test.hcl")
+
+	foundError := false
+	for _, d := range diags {
+		if d.Summary == "Invalid hook: 'error_code' requires 'error'" {
+			foundError = true
+			break
+		}
+	}
+
+	if !foundError {
+		t.Error("expected diagnostic error for error_code without error")
+	}
+}
+
 func TestParser_HookErrorMutualExclusivity_ErrorAndModule(t *testing.T) {
 	parser := NewParser()
 
@@ -410,99 +477,275 @@ environment {
 	}
 }
 
-func TestParser_ExtendsImage(t *testing.T) {
+func TestParser_HookReachability_CatchAllLowerPriority(t *testing.T) {
 	parser := NewParser()
 
 	hcl := `
-extends = {
-  image = "ghcr.io/myorg/my-dc:v1"
-}
-
 environment {
   database {
-    when = true
+    priority = -1
+
+    module "default" {
+      plugin = "native"
+      build  = "./modules/default-db"
+    }
+  }
+
+  database {
+    when = node.inputs.type == "postgres"
+
     module "pg" {
       plugin = "native"
       build  = "./modules/pg"
     }
-    outputs {
-      url = "test"
-    }
   }
 }
 `
 
-	schema, diags, err := parser.ParseBytes([]byte(hcl), "test.hcl")
+	dc, diags, err := parser.ParseBytes([]byte(hcl), "test.hcl")
 	if err != nil {
 		t.Fatalf("failed to parse: %v", err)
 	}
 
 	for _, d := range diags {
-		if d.Severity == 0 {
-			t.Fatalf("unexpected error diagnostic: %s - %s", d.Summary, d.Detail)
+		if d.Summary == "Unreachable database hook(s)" {
+			t.Error("catch-all with the lowest priority should not produce reachability error")
 		}
 	}
-
-	if schema.Extends == nil {
-		t.Fatal("expected extends block")
+	if got := dc.Environment.DatabaseHooks[0].Priority; got != -1 {
+		t.Errorf("expected priority -1, got %d", got)
 	}
+}
 
-	if schema.Extends.Image != "ghcr.io/myorg/my-dc:v1" {
-		t.Errorf("expected image 'ghcr.io/myorg/my-dc:v1', got %q", schema.Extends.Image)
-	}
+func TestParser_HookFallthrough(t *testing.T) {
+	parser := NewParser()
 
-	if schema.Extends.Path != "" {
-		t.Errorf("expected empty path, got %q", schema.Extends.Path)
+	hcl := `
+environment {
+  database {
+    fallthrough = true
+
+    module "monitoring" {
+      plugin = "native"
+      build  = "./modules/monitoring"
+    }
+  }
+
+  database {
+    module "pg" {
+      plugin = "native"
+      build  = "./modules/pg"
+    }
+  }
+}
+`
+
+	dc, diags, err := parser.ParseBytes([]byte(hcl), "test.hcl")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if diags.HasErrors() {
+		t.Fatalf("a catch-all that falls through should not shadow later hooks: %s", diags.Error())
+	}
+	if !dc.Environment.DatabaseHooks[0].Fallthrough {
+		t.Error("expected first hook to fall through")
 	}
 }
 
-func TestParser_ExtendsPath(t *testing.T) {
+func TestParser_HookFallthroughWithError(t *testing.T) {
 	parser := NewParser()
 
 	hcl := `
-extends = {
-  path = "../base-datacenter"
+environment {
+  database {
+    when        = node.inputs.type == "mongodb"
+    fallthrough = true
+    error       = "MongoDB is not supported."
+  }
+}
+`
+
+	_, diags, _ := parser.ParseBytes([]byte(hcl), "test.hcl")
+
+	foundError := false
+	for _, d := range diags {
+		if d.Summary == "Invalid hook: 'error' and 'fallthrough' are mutually exclusive" {
+			foundError = true
+			break
+		}
+	}
+	if !foundError {
+		t.Error("expected diagnostic error for error + fallthrough")
+	}
 }
 
+func TestParser_HookAggregate(t *testing.T) {
+	parser := NewParser()
+
+	hcl := `
 environment {
-  database {
-    when = true
-    error = "not supported"
+  route {
+    aggregate = true
+    module "proxy" {
+      build = "./modules/proxy"
+    }
+    outputs = {
+      url = module.proxy.url
+    }
   }
 }
 `
 
 	schema, diags, err := parser.ParseBytes([]byte(hcl), "test.hcl")
-	if err != nil {
-		t.Fatalf("failed to parse: %v", err)
+	if err != nil || diags.HasErrors() {
+		t.Fatalf("unexpected errors: %v %v", err, diags)
 	}
+	if !schema.Environment.RouteHooks[0].Aggregate {
+		t.Error("expected the route hook to be an aggregate")
+	}
+}
+
+func TestParser_HookAggregateWithFallthrough(t *testing.T) {
+	parser := NewParser()
+
+	hcl := `
+environment {
+  route {
+    aggregate   = true
+    fallthrough = true
+    module "proxy" {
+      build = "./modules/proxy"
+    }
+  }
+}
+`
+
+	_, diags, _ := parser.ParseBytes([]byte(hcl), "test.hcl")
 
+	foundError := false
 	for _, d := range diags {
-		if d.Severity == 0 {
-			t.Fatalf("unexpected error diagnostic: %s - %s", d.Summary, d.Detail)
+		if d.Summary == "Invalid hook: 'aggregate' and 'fallthrough' are mutually exclusive" {
+			foundError = true
+			break
 		}
 	}
+	if !foundError {
+		t.Error("expected diagnostic error for aggregate + fallthrough")
+	}
+}
 
-	if schema.Extends == nil {
-		t.Fatal("expected extends block")
+func TestParser_EnvironmentLifecycleHooks(t *testing.T) {
+	parser := NewParser()
+
+	hcl := `
+environment {
+  on_deploy {
+    module "warm" {
+      build = "./modules/warm"
+      inputs = {
+        url = environment.components.api.routes.main.url
+      }
+    }
+    module "notify" {
+      build = "./modules/notify"
+    }
+  }
+
+  on_destroy {
+    module "dns" {
+      build = "./modules/dns"
+    }
+  }
+}
+`
+
+	schema, diags, err := parser.ParseBytes([]byte(hcl), "test.hcl")
+	if err != nil || diags.HasErrors() {
+		t.Fatalf("unexpected errors: %v %v", err, diags)
 	}
+	env := schema.Environment
+	if env.OnDeploy == nil || len(env.OnDeploy.Modules) != 2 || env.OnDeploy.Modules[1].Name != "notify" {
+		t.Fatalf("expected 2 on_deploy modules, got %+v", env.OnDeploy)
+	}
+	if env.OnDestroy == nil || len(env.OnDestroy.Modules) != 1 || env.OnDestroy.Modules[0].Name != "dns" {
+		t.Fatalf("expected 1 on_destroy module, got %+v", env.OnDestroy)
+	}
+}
 
-	if schema.Extends.Path != "../base-datacenter" {
-		t.Errorf("expected path '../base-datacenter', got %q", schema.Extends.Path)
+func TestParser_DuplicateEnvironmentLifecycleHook(t *testing.T) {
+	parser := NewParser()
+
+	hcl := `
+environment {
+  on_deploy {
+    module "warm" {
+      build = "./modules/warm"
+    }
+  }
+
+  on_deploy {
+    module "notify" {
+      build = "./modules/notify"
+    }
+  }
+}
+`
+
+	_, diags, _ := parser.ParseBytes([]byte(hcl), "test.hcl")
+
+	foundError := false
+	for _, d := range diags {
+		if d.Summary == "Duplicate on_deploy block" {
+			foundError = true
+			break
+		}
 	}
+	if !foundError {
+		t.Error("expected diagnostic error for duplicate on_deploy blocks")
+	}
+}
 
-	if schema.Extends.Image != "" {
-		t.Errorf("expected empty image, got %q", schema.Extends.Image)
+func TestParser_HookShared(t *testing.T) {
+	parser := NewParser()
+
+	hcl := `
+environment {
+  smtp {
+    shared = true
+    module "mailer" {
+      build = "./modules/mailer"
+    }
+    outputs = {
+      host     = module.mailer.host
+      port     = module.mailer.port
+      username = module.mailer.username
+      password = module.mailer.password
+    }
+  }
+}
+`
+
+	schema, diags, err := parser.ParseBytes([]byte(hcl), "test.hcl")
+	if err != nil || diags.HasErrors() {
+		t.Fatalf("unexpected errors: %v %v", err, diags)
+	}
+	if !schema.Environment.SMTPHooks[0].Shared {
+		t.Error("expected the smtp hook to be shared")
 	}
 }
 
-func TestParser_ExtendsMutualExclusivity(t *testing.T) {
+func TestParser_HookSharedWithAggregate(t *testing.T) {
 	parser := NewParser()
 
 	hcl := `
-extends = {
-  image = "ghcr.io/myorg/my-dc:v1"
-  path  = "../base-datacenter"
+environment {
+  route {
+    shared    = true
+    aggregate = true
+    module "proxy" {
+      build = "./modules/proxy"
+    }
+  }
 }
 `
 
@@ -510,59 +753,786 @@ extends = {
 
 	foundError := false
 	for _, d := range diags {
-		if d.Summary == "Invalid extends: 'image' and 'path' are mutually exclusive" {
+		if d.Summary == "Invalid hook: 'shared' and 'aggregate' are mutually exclusive" {
 			foundError = true
 			break
 		}
 	}
-
 	if !foundError {
-		t.Error("expected diagnostic error for extends with both image and path")
+		t.Error("expected diagnostic error for shared + aggregate")
 	}
 }
 
-func TestParser_ExtendsEmpty(t *testing.T) {
+func TestParser_EnvironmentExports(t *testing.T) {
 	parser := NewParser()
 
 	hcl := `
-extends = {}
+environment {
+  module "network" {
+    build = "./modules/network"
+  }
+  exports {
+    vpc_id = environment.module.network.vpc_id
+    region = variable.region
+  }
+}
+`
+
+	schema, diags, err := parser.ParseBytes([]byte(hcl), "test.hcl")
+	if err != nil || diags.HasErrors() {
+		t.Fatalf("unexpected errors: %v %v", err, diags)
+	}
+	if len(schema.Environment.Exports) != 2 || schema.Environment.Exports["vpc_id"] == nil {
+		t.Errorf("expected 2 exports, got %v", schema.Environment.Exports)
+	}
+}
+
+func TestParser_EnvironmentExportsInvalidReference(t *testing.T) {
+	parser := NewParser()
+
+	hcl := `
+environment {
+  exports {
+    db_url = node.inputs.url
+  }
+}
 `
 
 	_, diags, _ := parser.ParseBytes([]byte(hcl), "test.hcl")
 
 	foundError := false
 	for _, d := range diags {
-		if d.Summary == "Invalid extends: missing 'image' or 'path'" {
+		if d.Summary == "Invalid export" {
 			foundError = true
 			break
 		}
 	}
-
 	if !foundError {
-		t.Error("expected diagnostic error for empty extends")
+		t.Error("expected diagnostic error for an export that refers to a resource")
 	}
 }
 
-func TestParser_NoExtends(t *testing.T) {
+func TestParser_HookInvalidPriority(t *testing.T) {
 	parser := NewParser()
 
 	hcl := `
 environment {
-  deployment {
-    module "deploy" {
+  database {
+    priority = 1.5
+
+    module "pg" {
       plugin = "native"
-      build  = "./modules/deploy"
+      build  = "./modules/pg"
     }
   }
 }
 `
 
-	schema, _, err := parser.ParseBytes([]byte(hcl), "test.hcl")
-	if err != nil {
-		t.Fatalf("failed to parse: %v", err)
-	}
+	_, diags, _ := parser.ParseBytes([]byte(hcl), "test.hcl")
 
-	if schema.Extends != nil {
-		t.Error("expected no extends block")
+	foundError := false
+	for _, d := range diags {
+		if d.Summary == "Invalid priority" {
+			foundError = true
+			break
+		}
+	}
+	if !foundError {
+		t.Error("expected diagnostic error for a fractional priority")
+	}
+}
+
+func TestParser_ExtendsImage(t *testing.T) {
+	parser := NewParser()
+
+	hcl := `
+extends = {
+  image = "ghcr.io/myorg/my-dc:v1"
+}
+
+environment {
+  database {
+    when = true
+    module "pg" {
+      plugin = "native"
+      build  = "./modules/pg"
+    }
+    outputs {
+      url = "test"
+    }
+  }
+}
+`
+
+	schema, diags, err := parser.ParseBytes([]byte(hcl), "test.hcl")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	for _, d := range diags {
+		if d.Severity == 0 {
+			t.Fatalf("unexpected error diagnostic: %s - %s", d.Summary, d.Detail)
+		}
+	}
+
+	if schema.Extends == nil {
+		t.Fatal("expected extends block")
+	}
+
+	if schema.Extends.Image != "ghcr.io/myorg/my-dc:v1" {
+		t.Errorf("expected image 'ghcr.io/myorg/my-dc:v1', got %q", schema.Extends.Image)
+	}
+
+	if schema.Extends.Path != "" {
+		t.Errorf("expected empty path, got %q", schema.Extends.Path)
+	}
+}
+
+func TestParser_ExtendsPath(t *testing.T) {
+	parser := NewParser()
+
+	hcl := `
+extends = {
+  path = "../base-datacenter"
+}
+
+environment {
+  database {
+    when = true
+    error = "not supported"
+  }
+}
+`
+
+	schema, diags, err := parser.ParseBytes([]byte(hcl), "test.hcl")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	for _, d := range diags {
+		if d.Severity == 0 {
+			t.Fatalf("unexpected error diagnostic: %s - %s", d.Summary, d.Detail)
+		}
+	}
+
+	if schema.Extends == nil {
+		t.Fatal("expected extends block")
+	}
+
+	if schema.Extends.Path != "../base-datacenter" {
+		t.Errorf("expected path '../base-datacenter', got %q", schema.Extends.Path)
+	}
+
+	if schema.Extends.Image != "" {
+		t.Errorf("expected empty image, got %q", schema.Extends.Image)
+	}
+}
+
+func TestParser_ExtendsMutualExclusivity(t *testing.T) {
+	parser := NewParser()
+
+	hcl := `
+extends = {
+  image = "ghcr.io/myorg/my-dc:v1"
+  path  = "../base-datacenter"
+}
+`
+
+	_, diags, _ := parser.ParseBytes([]byte(hcl), "test.hcl")
+
+	foundError := false
+	for _, d := range diags {
+		if d.Summary == "Invalid extends: 'image' and 'path' are mutually exclusive" {
+			foundError = true
+			break
+		}
+	}
+
+	if !foundError {
+		t.Error("expected diagnostic error for extends with both image and path")
+	}
+}
+
+func TestParser_EnvironmentDefaultsUndeclaredVariable(t *testing.T) {
+	parser := NewParser()
+
+	hcl := `
+variable "instance_class" {
+  default = "small"
+}
+
+environment "prod-*" {
+  variables = {
+    instance_class = "large"
+    instance_size  = "xl"
+  }
+}
+`
+
+	schema, diags, _ := parser.ParseBytes([]byte(hcl), "test.hcl")
+
+	foundError := false
+	for _, d := range diags {
+		if d.Summary == "Undeclared variable" && strings.Contains(d.Detail, `"instance_size"`) {
+			foundError = true
+			break
+		}
+	}
+	if !foundError {
+		t.Errorf("expected an undeclared variable diagnostic, got %s", diags.Error())
+	}
+	if len(schema.EnvironmentDefaults) != 1 || schema.EnvironmentDefaults[0].Pattern != "prod-*" {
+		t.Errorf("expected the environment defaults to be parsed, got %+v", schema.EnvironmentDefaults)
+	}
+}
+
+func TestParser_ExtendsEmpty(t *testing.T) {
+	parser := NewParser()
+
+	hcl := `
+extends = {}
+`
+
+	_, diags, _ := parser.ParseBytes([]byte(hcl), "test.hcl")
+
+	foundError := false
+	for _, d := range diags {
+		if d.Summary == "Invalid extends: missing 'image' or 'path'" {
+			foundError = true
+			break
+		}
+	}
+
+	if !foundError {
+		t.Error("expected diagnostic error for empty extends")
+	}
+}
+
+func TestParser_NoExtends(t *testing.T) {
+	parser := NewParser()
+
+	hcl := `
+environment {
+  deployment {
+    module "deploy" {
+      plugin = "native"
+      build  = "./modules/deploy"
+    }
+  }
+}
+`
+
+	schema, _, err := parser.ParseBytes([]byte(hcl), "test.hcl")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	if schema.Extends != nil {
+		t.Error("expected no extends block")
+	}
+}
+
+func TestParser_HookGuarantees(t *testing.T) {
+	parser := NewParser()
+
+	hcl := `
+environment {
+  database {
+    guarantees = ["poolUrl", "read.host"]
+
+    module "postgres" {
+      build = "./modules/postgres"
+    }
+
+    outputs {
+      host    = module.postgres.host
+      port    = module.postgres.port
+      url     = module.postgres.url
+      poolUrl = module.postgres.pool_url
+    }
+  }
+}
+`
+
+	schema, diags, err := parser.ParseBytes([]byte(hcl), "test.hcl")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+
+	hook := schema.Environment.DatabaseHooks[0]
+	if len(hook.Guarantees) != 2 || hook.Guarantees[0] != "poolUrl" || hook.Guarantees[1] != "read.host" {
+		t.Errorf("expected guarantees [poolUrl read.host], got %v", hook.Guarantees)
+	}
+}
+
+func TestParser_HookReplaceOn(t *testing.T) {
+	parser := NewParser()
+
+	hcl := `
+environment {
+  database {
+    replace_on = ["type", "region"]
+
+    module "postgres" {
+      build = "./modules/postgres"
+    }
+
+    outputs {
+      host = module.postgres.host
+      port = module.postgres.port
+      url  = module.postgres.url
+    }
+  }
+
+  bucket {
+    replace_on = "name"
+    module "bucket" {
+      build = "./modules/bucket"
+    }
+  }
+
+  smtp {
+    aggregate  = true
+    replace_on = ["*"]
+    module "smtp" {
+      build = "./modules/smtp"
+    }
+  }
+}
+`
+
+	schema, diags, err := parser.ParseBytes([]byte(hcl), "test.hcl")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	hook := schema.Environment.DatabaseHooks[0]
+	if len(hook.ReplaceOn) != 2 || hook.ReplaceOn[0] != "type" || hook.ReplaceOn[1] != "region" {
+		t.Errorf("expected replace_on [type region], got %v", hook.ReplaceOn)
+	}
+
+	var summaries []string
+	for _, d := range diags {
+		summaries = append(summaries, d.Summary)
+	}
+	for _, want := range []string{"Invalid replace_on", "Invalid hook: 'aggregate' and 'replace_on' are mutually exclusive"} {
+		if !strings.Contains(strings.Join(summaries, "\n"), want) {
+			t.Errorf("expected diagnostic %q, got %v", want, summaries)
+		}
+	}
+}
+
+func TestParser_HookErrorMutualExclusivity_ErrorAndGuarantees(t *testing.T) {
+	parser := NewParser()
+
+	hcl := `
+environment {
+  database {
+    error      = "Not supported"
+    guarantees = ["poolUrl"]
+  }
+}
+`
+
+	_, diags, _ := parser.ParseBytes([]byte(hcl), "test.hcl")
+
+	foundError := false
+	for _, d := range diags {
+		if d.Summary == "Invalid hook: 'error' and 'guarantees' are mutually exclusive" {
+			foundError = true
+			break
+		}
+	}
+
+	if !foundError {
+		t.Error("expected diagnostic error for error + guarantees mutual exclusivity")
+	}
+}
+
+func TestParser_InjectBlocks(t *testing.T) {
+	parser := NewParser()
+
+	src := `
+environment {
+  inject "proxy" {
+    when = node.type == "deployment"
+    environment = {
+      HTTPS_PROXY = "http://proxy.internal:3128"
+      NO_PROXY    = "localhost,${node.component}"
+    }
+  }
+
+  inject "region" {
+    environment = {
+      AWS_REGION = variable.region
+    }
+  }
+}
+`
+
+	schema, diags, err := parser.ParseBytes([]byte(src), "test.hcl")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+
+	injections := schema.Environment.Injections
+	if len(injections) != 2 {
+		t.Fatalf("expected 2 inject blocks, got %d", len(injections))
+	}
+	if injections[0].Name != "proxy" || injections[0].WhenExpr == nil {
+		t.Errorf("expected proxy injection with a when expression, got %+v", injections[0])
+	}
+
+	dc, err := NewTransformer().WithSourceBytes([]byte(src)).Transform(schema)
+	if err != nil {
+		t.Fatalf("failed to transform: %v", err)
+	}
+
+	proxy := dc.Environment.Injections[0]
+	if proxy.When != `node.type == "deployment"` {
+		t.Errorf("expected raw when expression, got %q", proxy.When)
+	}
+	if proxy.Environment["HTTPS_PROXY"] != "http://proxy.internal:3128" {
+		t.Errorf("expected literal HTTPS_PROXY, got %q", proxy.Environment["HTTPS_PROXY"])
+	}
+	if proxy.Environment["NO_PROXY"] != "localhost,${node.component}" {
+		t.Errorf("expected NO_PROXY template source, got %q", proxy.Environment["NO_PROXY"])
+	}
+
+	region := dc.Environment.Injections[1]
+	if region.When != "" {
+		t.Errorf("expected no when condition, got %q", region.When)
+	}
+	if region.Environment["AWS_REGION"] != "variable.region" {
+		t.Errorf("expected AWS_REGION expression, got %q", region.Environment["AWS_REGION"])
+	}
+}
+
+func TestParser_InjectBlockDuplicateName(t *testing.T) {
+	parser := NewParser()
+
+	src := `
+environment {
+  inject "proxy" {
+    environment = { HTTPS_PROXY = "http://a:3128" }
+  }
+  inject "proxy" {
+    environment = { HTTPS_PROXY = "http://b:3128" }
+  }
+}
+`
+
+	_, diags, _ := parser.ParseBytes([]byte(src), "test.hcl")
+
+	foundError := false
+	for _, d := range diags {
+		if d.Summary == "Duplicate inject block" {
+			foundError = true
+			break
+		}
+	}
+	if !foundError {
+		t.Error("expected diagnostic error for duplicate inject block names")
+	}
+}
+
+func TestParser_InjectBlockRequiresEnvironment(t *testing.T) {
+	parser := NewParser()
+
+	src := `
+environment {
+  inject "proxy" {
+    when = true
+  }
+}
+`
+
+	_, diags, _ := parser.ParseBytes([]byte(src), "test.hcl")
+	if !diags.HasErrors() {
+		t.Error("expected an error for an inject block without environment")
+	}
+}
+
+func TestParser_HookCredentials(t *testing.T) {
+	parser := NewParser()
+
+	src := `
+environment {
+  database {
+    credentials {
+      role_arn = variable.database_role_arn
+      env_set  = "aws-prod"
+    }
+
+    module "postgres" {
+      build = "./modules/postgres"
+
+      credentials {
+        role_arn = "arn:aws:iam::123456789012:role/rds-admin"
+      }
+    }
+
+    module "dns" {
+      build = "./modules/dns"
+    }
+
+    outputs = {
+      host = module.postgres.host
+      port = module.postgres.port
+      url  = module.postgres.url
+    }
+  }
+}
+`
+
+	schema, diags, err := parser.ParseBytes([]byte(src), "test.hcl")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+
+	dc, err := NewTransformer().WithSourceBytes([]byte(src)).Transform(schema)
+	if err != nil {
+		t.Fatalf("failed to transform: %v", err)
+	}
+
+	hook := dc.Environment.Hooks.Database[0]
+	if hook.Credentials == nil {
+		t.Fatal("expected hook credentials")
+	}
+	if hook.Credentials.RoleARN != "variable.database_role_arn" {
+		t.Errorf("expected role_arn expression, got %q", hook.Credentials.RoleARN)
+	}
+	if hook.Credentials.EnvSet != "aws-prod" {
+		t.Errorf("expected env_set aws-prod, got %q", hook.Credentials.EnvSet)
+	}
+
+	postgres := hook.Modules[0]
+	if postgres.Credentials == nil || postgres.Credentials.RoleARN != "arn:aws:iam::123456789012:role/rds-admin" {
+		t.Errorf("expected module role_arn override, got %+v", postgres.Credentials)
+	}
+	if hook.Modules[1].Credentials != nil {
+		t.Errorf("expected no module credentials, got %+v", hook.Modules[1].Credentials)
+	}
+}
+
+func TestParser_CredentialsBlockErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		summary string
+	}{
+		{
+			name: "empty",
+			src: `
+environment {
+  database {
+    credentials {}
+    module "postgres" { build = "./modules/postgres" }
+  }
+}
+`,
+			summary: "Empty credentials block",
+		},
+		{
+			name: "duplicate",
+			src: `
+environment {
+  database {
+    credentials { env_set = "a" }
+    credentials { env_set = "b" }
+    module "postgres" { build = "./modules/postgres" }
+  }
+}
+`,
+			summary: "Duplicate credentials block",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, diags, _ := NewParser().ParseBytes([]byte(tt.src), "test.hcl")
+
+			foundError := false
+			for _, d := range diags {
+				if d.Summary == tt.summary {
+					foundError = true
+					break
+				}
+			}
+			if !foundError {
+				t.Errorf("expected %q diagnostic, got %v", tt.summary, diags)
+			}
+		})
+	}
+}
+
+func TestParser_Sandbox(t *testing.T) {
+	parser := NewParser()
+
+	src := `
+sandbox {
+  network         = false
+  read_only_root  = true
+  memory          = "512m"
+  cpus            = 1.5
+  pids_limit      = 256
+  seccomp_profile = "./seccomp.json"
+}
+
+environment {
+  database {
+    sandbox {
+      memory = "2Gi"
+    }
+
+    module "postgres" {
+      build = "./modules/postgres"
+    }
+
+    outputs = {
+      host = module.postgres.host
+      port = module.postgres.port
+      url  = module.postgres.url
+    }
+  }
+}
+`
+
+	schema, diags, err := parser.ParseBytes([]byte(src), "test.hcl")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+
+	dc, err := NewTransformer().WithSourceBytes([]byte(src)).Transform(schema)
+	if err != nil {
+		t.Fatalf("failed to transform: %v", err)
+	}
+
+	sandbox := dc.Sandbox
+	if sandbox == nil {
+		t.Fatal("expected datacenter sandbox")
+	}
+	if !sandbox.NoNetwork || !sandbox.ReadOnlyRoot {
+		t.Errorf("expected no network and a read-only root, got %+v", sandbox)
+	}
+	if sandbox.Memory != 512<<20 || sandbox.CPUs != 1.5 || sandbox.PidsLimit != 256 {
+		t.Errorf("expected resource limits, got %+v", sandbox)
+	}
+	if sandbox.SeccompProfile != "./seccomp.json" {
+		t.Errorf("expected seccomp profile path, got %q", sandbox.SeccompProfile)
+	}
+
+	hookSandbox := dc.Environment.Hooks.Database[0].Sandbox
+	if hookSandbox == nil || hookSandbox.Memory != 2<<30 || hookSandbox.NoNetwork {
+		t.Errorf("expected hook sandbox with only a memory limit, got %+v", hookSandbox)
+	}
+}
+
+func TestParser_SandboxInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		summary string
+	}{
+		{"memory", `memory = "lots"`, "Invalid sandbox memory"},
+		{"cpus", `cpus = 0`, "Invalid sandbox cpus"},
+		{"pids_limit", `pids_limit = 1.5`, "Invalid sandbox pids_limit"},
+		{"network", `network = "off"`, "Invalid sandbox network"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "sandbox {\n  " + tt.body + "\n}\n"
+			_, diags, _ := NewParser().ParseBytes([]byte(src), "test.hcl")
+
+			foundError := false
+			for _, d := range diags {
+				if d.Summary == tt.summary {
+					foundError = true
+					break
+				}
+			}
+			if !foundError {
+				t.Errorf("expected %q diagnostic, got %v", tt.summary, diags)
+			}
+		})
+	}
+}
+
+func TestParser_CacheableModule(t *testing.T) {
+	parser := NewParser()
+
+	src := `
+environment {
+  dockerBuild {
+    module "build" {
+      build     = "./modules/build"
+      cacheable = true
+      cache_key = "${node.inputs.context}-${node.inputs.dockerfile}"
+    }
+
+    module "lookup" {
+      build     = "./modules/lookup"
+      cacheable = true
+    }
+
+    outputs = {
+      image = module.build.image
+    }
+  }
+}
+`
+
+	schema, diags, err := parser.ParseBytes([]byte(src), "test.hcl")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+
+	dc, err := NewTransformer().WithSourceBytes([]byte(src)).Transform(schema)
+	if err != nil {
+		t.Fatalf("failed to transform: %v", err)
+	}
+
+	modules := dc.Environment.Hooks.DockerBuild[0].Modules
+	if !modules[0].Cacheable || modules[0].CacheKey != "${node.inputs.context}-${node.inputs.dockerfile}" {
+		t.Errorf("expected cacheable module with cache key expression, got cacheable=%v key=%q", modules[0].Cacheable, modules[0].CacheKey)
+	}
+	if !modules[1].Cacheable || modules[1].CacheKey != "" {
+		t.Errorf("expected cacheable module without cache key, got cacheable=%v key=%q", modules[1].Cacheable, modules[1].CacheKey)
+	}
+}
+
+func TestParser_CacheKeyRequiresCacheable(t *testing.T) {
+	src := `
+environment {
+  dockerBuild {
+    module "build" {
+      build     = "./modules/build"
+      cache_key = node.inputs.context
+    }
+  }
+}
+`
+	_, diags, _ := NewParser().ParseBytes([]byte(src), "test.hcl")
+	for _, d := range diags {
+		if d.Summary == "Invalid cache_key" {
+			return
+		}
 	}
+	t.Errorf("expected Invalid cache_key diagnostic, got %v", diags)
 }
@@ -63,6 +63,20 @@ func (t *Transformer) Transform(v1 *SchemaV1) (*internal.InternalDatacenter, err
 		dc.Components = append(dc.Components, ic)
 	}
 
+	dc.Sandbox = transformSandbox(v1.Sandbox)
+
+	// Transform per-environment variable defaults
+	for _, d := range v1.EnvironmentDefaults {
+		ed := internal.InternalEnvironmentDefaults{
+			Pattern:   d.Pattern,
+			Variables: make(map[string]interface{}, len(d.Variables)),
+		}
+		for name, val := range d.Variables {
+			ed.Variables[name] = ctyValueToGo(val)
+		}
+		dc.EnvironmentDefaults = append(dc.EnvironmentDefaults, ed)
+	}
+
 	// Transform environment
 	if v1.Environment != nil {
 		dc.Environment = t.transformEnvironment(v1.Environment)
@@ -79,6 +93,18 @@ func (t *Transformer) Transform(v1 *SchemaV1) (*internal.InternalDatacenter, err
 		return nil, fmt.Errorf("datacenter hook output validation failed:\n  - %s", strings.Join(msgs, "\n  - "))
 	}
 
+	// Check environment module references. A datacenter that extends another
+	// may reference modules its parent declares, so it is checked at deploy time
+	if v1.Extends == nil {
+		if errs := internal.ValidateEnvironmentModuleRefs(&dc.Environment, hooksByType(&dc.Environment.Hooks)); len(errs) > 0 {
+			msgs := make([]string, len(errs))
+			for i, e := range errs {
+				msgs[i] = e.Error()
+			}
+			return nil, fmt.Errorf("datacenter environment module validation failed:\n  - %s", strings.Join(msgs, "\n  - "))
+		}
+	}
+
 	return dc, nil
 }
 
@@ -193,9 +219,48 @@ func (t *Transformer) transformModule(m ModuleBlockV1) internal.InternalModule {
 		})
 	}
 
+	im.Credentials = t.transformCredentials(m.Credentials)
+	im.Cacheable = m.Cacheable
+	if m.CacheKeyExpr != nil {
+		im.CacheKey = exprToString(m.CacheKeyExpr, t.sourceBytes)
+	}
+
 	return im
 }
 
+func transformSandbox(s *SandboxBlockV1) *internal.InternalSandbox {
+	if s == nil {
+		return nil
+	}
+	return &internal.InternalSandbox{
+		NoNetwork:      s.NoNetwork,
+		ReadOnlyRoot:   s.ReadOnlyRoot,
+		Memory:         s.Memory,
+		CPUs:           s.CPUs,
+		PidsLimit:      s.PidsLimit,
+		SeccompProfile: s.SeccompProfile,
+	}
+}
+
+// transformCredentials stores credential values as expression strings so
+// variable references can be resolved at deploy time.
+func (t *Transformer) transformCredentials(c *CredentialsBlockV1) *internal.InternalCredentials {
+	if c == nil {
+		return nil
+	}
+	ic := &internal.InternalCredentials{}
+	if c.RoleARNExpr != nil {
+		ic.RoleARN = exprToString(c.RoleARNExpr, t.sourceBytes)
+	}
+	if c.ServiceAccountExpr != nil {
+		ic.ServiceAccount = exprToString(c.ServiceAccountExpr, t.sourceBytes)
+	}
+	if c.EnvSetExpr != nil {
+		ic.EnvSet = exprToString(c.EnvSetExpr, t.sourceBytes)
+	}
+	return ic
+}
+
 func (t *Transformer) transformEnvironment(env *EnvironmentBlockV1) internal.InternalEnvironment {
 	ie := internal.InternalEnvironment{}
 
@@ -221,10 +286,65 @@ func (t *Transformer) transformEnvironment(env *EnvironmentBlockV1) internal.Int
 	ie.Hooks.Observability = t.transformHooks(env.ObservabilityHooks)
 	ie.Hooks.Port = t.transformHooks(env.PortHooks)
 	ie.Hooks.NetworkPolicy = t.transformHooks(env.NetworkPolicyHooks)
+	ie.Hooks.RouteAuth = t.transformHooks(env.RouteAuthHooks)
+
+	// Transform environment injections
+	for _, inj := range env.Injections {
+		ie.Injections = append(ie.Injections, t.transformInjection(inj))
+	}
+
+	// Store exports as expression strings, resolved at deploy time once the
+	// environment's modules are provisioned
+	if len(env.Exports) > 0 {
+		ie.Exports = make(map[string]string, len(env.Exports))
+		for name, attr := range env.Exports {
+			ie.Exports[name] = exprToString(attr.Expr, t.sourceBytes)
+		}
+	}
+
+	// Transform environment lifecycle hooks
+	if env.OnDeploy != nil {
+		for _, m := range env.OnDeploy.Modules {
+			ie.OnDeploy = append(ie.OnDeploy, t.transformModule(m))
+		}
+	}
+	if env.OnDestroy != nil {
+		for _, m := range env.OnDestroy.Modules {
+			ie.OnDestroy = append(ie.OnDestroy, t.transformModule(m))
+		}
+	}
 
 	return ie
 }
 
+func (t *Transformer) transformInjection(inj InjectBlockV1) internal.InternalInjection {
+	when := inj.When
+	// Conditions referencing node context can only be evaluated at deploy time
+	if when == "" && inj.WhenExpr != nil {
+		when = exprToString(inj.WhenExpr, t.sourceBytes)
+	}
+
+	ii := internal.InternalInjection{
+		Name:        inj.Name,
+		When:        when,
+		Environment: make(map[string]string),
+	}
+
+	// Store values as expression strings so the executor can resolve node
+	// context, variables, and observability outputs for each workload.
+	if objExpr, ok := inj.EnvironmentExpr.(*hclsyntax.ObjectConsExpr); ok {
+		for _, item := range objExpr.Items {
+			key, keyDiags := item.KeyExpr.Value(nil)
+			if keyDiags.HasErrors() || key.Type() != cty.String {
+				continue
+			}
+			ii.Environment[key.AsString()] = exprToString(item.ValueExpr, t.sourceBytes)
+		}
+	}
+
+	return ii
+}
+
 func (t *Transformer) transformHooks(hooks []HookBlockV1) []internal.InternalHook {
 	var result []internal.InternalHook
 
@@ -238,16 +358,30 @@ func (t *Transformer) transformHooks(hooks []HookBlockV1) []internal.InternalHoo
 		}
 
 		ih := internal.InternalHook{
-			When:          when,
-			Error:         h.Error,
-			Outputs:       make(map[string]string),
-			NestedOutputs: make(map[string]map[string]string),
+			When:              when,
+			Error:             h.Error,
+			ErrorCode:         h.ErrorCode,
+			ErrorDocsURL:      h.ErrorDocsURL,
+			ErrorAlternatives: h.ErrorAlternatives,
+			Outputs:           make(map[string]string),
+			NestedOutputs:     make(map[string]map[string]string),
+			Guarantees:        h.Guarantees,
+			ReplaceOn:         h.ReplaceOn,
+			Credentials:       t.transformCredentials(h.Credentials),
+			Sandbox:           transformSandbox(h.Sandbox),
+			Priority:          h.Priority,
+			Fallthrough:       h.Fallthrough,
+			Aggregate:         h.Aggregate,
+			Shared:            h.Shared,
 		}
 
 		// Transform modules
 		for _, m := range h.Modules {
 			ih.Modules = append(ih.Modules, t.transformModule(m))
 		}
+		for _, m := range h.Validate {
+			ih.Validate = append(ih.Validate, t.transformModule(m))
+		}
 
 		// Transform outputs - can be from expression or attributes
 		if h.OutputsExpr != nil {
@@ -445,7 +579,18 @@ func ctyValueToGo(val cty.Value) interface{} {
 func (t *Transformer) validateHookOutputs(hooks *internal.InternalHooks) []error {
 	var errs []error
 
-	hookMap := map[string][]internal.InternalHook{
+	for hookType, hookList := range hooksByType(hooks) {
+		if hookErrs := internal.ValidateHookOutputs(hookType, hookList); len(hookErrs) > 0 {
+			errs = append(errs, hookErrs...)
+		}
+	}
+
+	return errs
+}
+
+// hooksByType maps each hook type name to its hooks.
+func hooksByType(hooks *internal.InternalHooks) map[string][]internal.InternalHook {
+	return map[string][]internal.InternalHook{
 		"database":      hooks.Database,
 		"task":          hooks.Task,
 		"bucket":        hooks.Bucket,
@@ -462,13 +607,6 @@ func (t *Transformer) validateHookOutputs(hooks *internal.InternalHooks) []error
 		"port":          hooks.Port,
 		"databaseUser":  hooks.DatabaseUser,
 		"networkPolicy": hooks.NetworkPolicy,
+		"routeAuth":     hooks.RouteAuth,
 	}
-
-	for hookType, hookList := range hookMap {
-		if hookErrs := internal.ValidateHookOutputs(hookType, hookList); len(hookErrs) > 0 {
-			errs = append(errs, hookErrs...)
-		}
-	}
-
-	return errs
 }
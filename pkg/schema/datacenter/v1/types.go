@@ -13,7 +13,19 @@ type SchemaV1 struct {
 	Variables   []VariableBlockV1   `hcl:"variable,block"`
 	Modules     []ModuleBlockV1     `hcl:"module,block"`
 	Components  []ComponentBlockV1  `hcl:"-"` // Parsed manually from HCL
+	Sandbox     *SandboxBlockV1     `hcl:"sandbox,block"`
 	Environment *EnvironmentBlockV1 `hcl:"environment,block"`
+
+	// EnvironmentDefaults are the environment blocks labeled with a name
+	// pattern. Parsed manually from HCL.
+	EnvironmentDefaults []EnvironmentDefaultsBlockV1 `hcl:"-"`
+}
+
+// EnvironmentDefaultsBlockV1 sets variable defaults for the environments
+// whose names match a pattern.
+type EnvironmentDefaultsBlockV1 struct {
+	Pattern   string               `hcl:"pattern,label"`
+	Variables map[string]cty.Value `hcl:"-"`
 }
 
 // ExtendsBlockV1 represents the extends attribute for datacenter inheritance.
@@ -54,6 +66,9 @@ type ModuleBlockV1 struct {
 	When            string               `hcl:"when,optional"`
 	WhenExpr        hcl.Expression       `hcl:"-"` // Raw when expression for runtime evaluation
 	Volumes         []VolumeBlockV1      `hcl:"volume,block"`
+	Credentials     *CredentialsBlockV1  `hcl:"credentials,block"`
+	Cacheable       bool                 `hcl:"cacheable,optional"`
+	CacheKeyExpr    hcl.Expression       `hcl:"-"` // Raw cache_key expression for runtime evaluation
 	Remain          hcl.Body             `hcl:",remain"`
 }
 
@@ -64,6 +79,26 @@ type VolumeBlockV1 struct {
 	ReadOnly  bool   `hcl:"read_only,optional"`
 }
 
+// SandboxBlockV1 restricts how container-based modules run.
+type SandboxBlockV1 struct {
+	NoNetwork      bool     `hcl:"-"`                        // Set by network = false
+	ReadOnlyRoot   bool     `hcl:"read_only_root,optional"`  // Mount the container root filesystem read-only
+	Memory         int64    `hcl:"memory,optional"`          // Memory limit in bytes (parsed from "512m", "2g", ...)
+	CPUs           float64  `hcl:"cpus,optional"`            // CPU limit (e.g., 1.5)
+	PidsLimit      int64    `hcl:"pids_limit,optional"`      // Maximum number of processes
+	SeccompProfile string   `hcl:"seccomp_profile,optional"` // Path to a seccomp profile (JSON)
+	Remain         hcl.Body `hcl:",remain"`
+}
+
+// CredentialsBlockV1 scopes the cloud credentials modules run with. Values
+// are kept as expressions so they can reference datacenter variables.
+type CredentialsBlockV1 struct {
+	RoleARNExpr        hcl.Expression `hcl:"-"` // AWS IAM role to assume
+	ServiceAccountExpr hcl.Expression `hcl:"-"` // GCP service account to impersonate
+	EnvSetExpr         hcl.Expression `hcl:"-"` // Named set of CLDCTL_CREDENTIALS_<SET>_* variables
+	Remain             hcl.Body       `hcl:",remain"`
+}
+
 // EnvironmentBlockV1 represents the environment block.
 type EnvironmentBlockV1 struct {
 	Modules            []ModuleBlockV1 `hcl:"module,block"`
@@ -83,7 +118,34 @@ type EnvironmentBlockV1 struct {
 	ObservabilityHooks []HookBlockV1   `hcl:"observability,block"`
 	PortHooks          []HookBlockV1   `hcl:"port,block"`
 	NetworkPolicyHooks []HookBlockV1   `hcl:"networkPolicy,block"`
+	RouteAuthHooks     []HookBlockV1   `hcl:"routeAuth,block"`
+	Injections         []InjectBlockV1 `hcl:"inject,block"`
 	Remain             hcl.Body        `hcl:",remain"`
+
+	// Exports are the values components may read as ${{ platform.<name> }},
+	// usually outputs of environment modules
+	Exports hcl.Attributes `hcl:"-"`
+
+	// Environment lifecycle hooks
+	OnDeploy  *EnvironmentHookBlockV1 `hcl:"on_deploy,block"`
+	OnDestroy *EnvironmentHookBlockV1 `hcl:"on_destroy,block"`
+}
+
+// EnvironmentHookBlockV1 represents an on_deploy or on_destroy block, whose
+// modules run once per environment rather than per resource.
+type EnvironmentHookBlockV1 struct {
+	Modules []ModuleBlockV1 `hcl:"module,block"`
+	Remain  hcl.Body        `hcl:",remain"`
+}
+
+// InjectBlockV1 represents a named set of environment variables injected
+// into every workload the when condition matches.
+type InjectBlockV1 struct {
+	Name            string         `hcl:"name,label"`
+	When            string         `hcl:"when,optional"`
+	WhenExpr        hcl.Expression `hcl:"-"` // Raw when expression for runtime evaluation
+	EnvironmentExpr hcl.Expression `hcl:"-"` // Raw environment map for runtime evaluation
+	Remain          hcl.Body       `hcl:",remain"`
 }
 
 // HookBlockV1 represents a resource hook block.
@@ -91,12 +153,34 @@ type HookBlockV1 struct {
 	When              string                    `hcl:"when,optional"`
 	WhenExpr          hcl.Expression            `hcl:"-"` // Raw when expression for runtime evaluation
 	Modules           []ModuleBlockV1           `hcl:"module,block"`
-	OutputsExpr       hcl.Expression            `hcl:"-"`              // Raw outputs expression for runtime evaluation (attribute syntax)
-	OutputsAttrs      hcl.Attributes            `hcl:"-"`              // Raw outputs attributes for runtime evaluation (block syntax)
-	NestedOutputExprs map[string]hcl.Expression `hcl:"-"`              // Nested output objects (e.g., read = {...}, write = {...})
-	Error             string                    `hcl:"error,optional"` // Human-readable error message (mutually exclusive with modules/outputs)
-	ErrorExpr         hcl.Expression            `hcl:"-"`              // Raw error expression for runtime interpolation
+	Validate          []ModuleBlockV1           `hcl:"validate,block"`              // Modules previewed before a deploy to check that the hook can run
+	OutputsExpr       hcl.Expression            `hcl:"-"`                           // Raw outputs expression for runtime evaluation (attribute syntax)
+	OutputsAttrs      hcl.Attributes            `hcl:"-"`                           // Raw outputs attributes for runtime evaluation (block syntax)
+	NestedOutputExprs map[string]hcl.Expression `hcl:"-"`                           // Nested output objects (e.g., read = {...}, write = {...})
+	Guarantees        []string                  `hcl:"guarantees,optional"`         // Extra outputs this hook promises to produce (dot paths for nested outputs, e.g. "read.host")
+	ReplaceOn         []string                  `hcl:"replace_on,optional"`         // Node inputs whose changes replace the resource instead of updating it ("*" for any)
+	Error             string                    `hcl:"error,optional"`              // Human-readable error message (mutually exclusive with modules/outputs)
+	ErrorExpr         hcl.Expression            `hcl:"-"`                           // Raw error expression for runtime interpolation
+	ErrorCode         string                    `hcl:"error_code,optional"`         // Machine-readable code reported with the error
+	ErrorDocsURL      string                    `hcl:"error_docs_url,optional"`     // Documentation explaining the error and how to resolve it
+	ErrorAlternatives []string                  `hcl:"error_alternatives,optional"` // Supported alternatives suggested with the error
+	Credentials       *CredentialsBlockV1       `hcl:"credentials,block"`           // Credentials for the hook's modules (modules can override)
+	Sandbox           *SandboxBlockV1           `hcl:"sandbox,block"`               // Replaces the datacenter sandbox for the hook's modules
 	Remain            hcl.Body                  `hcl:",remain"`
+
+	// Priority orders the hooks of a type: higher priorities are tried first
+	// (default 0). Fallthrough keeps matching after this hook, running its
+	// modules alongside those of the next matching hook.
+	Priority    int  `hcl:"priority,optional"`
+	Fallthrough bool `hcl:"fallthrough,optional"`
+
+	// Aggregate runs the hook's modules once for every resource it matches
+	// in the environment, instead of once per resource.
+	Aggregate bool `hcl:"aggregate,optional"`
+
+	// Shared provisions the hook's modules once for the whole datacenter, and
+	// every environment whose resources the hook matches reuses them.
+	Shared bool `hcl:"shared,optional"`
 }
 
 // OutputsBlockV1 represents the outputs block in a hook.
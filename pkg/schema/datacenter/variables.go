@@ -0,0 +1,39 @@
+package datacenter
+
+import "path"
+
+// ResolveVariables returns the values of a datacenter's variables for an
+// environment. Values set when the datacenter was deployed take precedence,
+// then the defaults of environment blocks whose pattern matches envName (later
+// blocks win), then the variables' own defaults. An empty envName resolves
+// the variables outside of any environment.
+func ResolveVariables(dc Datacenter, values map[string]string, envName string) map[string]interface{} {
+	vars := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		vars[k] = v
+	}
+
+	if envName != "" {
+		envDefaults := make(map[string]interface{})
+		for _, d := range dc.EnvironmentDefaults() {
+			if ok, err := path.Match(d.Pattern(), envName); err != nil || !ok {
+				continue
+			}
+			for k, v := range d.Variables() {
+				envDefaults[k] = v
+			}
+		}
+		for k, v := range envDefaults {
+			if _, ok := vars[k]; !ok {
+				vars[k] = v
+			}
+		}
+	}
+
+	for _, v := range dc.Variables() {
+		if _, ok := vars[v.Name()]; !ok && v.Default() != nil {
+			vars[v.Name()] = v.Default()
+		}
+	}
+	return vars
+}
@@ -0,0 +1,58 @@
+package datacenter
+
+import "testing"
+
+func TestResolveVariables(t *testing.T) {
+	dc, err := NewLoader().LoadFromBytes([]byte(`
+variable "instance_class" {
+  default = "small"
+}
+
+variable "replicas" {
+  default = 1
+}
+
+variable "region" {}
+
+environment "prod-*" {
+  variables = {
+    instance_class = "large"
+    replicas       = 3
+  }
+}
+
+environment "prod-eu" {
+  variables = {
+    replicas = 5
+  }
+}
+
+environment {}
+`), "/tmp/dc/datacenter.dc")
+	if err != nil {
+		t.Fatalf("failed to load datacenter: %v", err)
+	}
+
+	tests := []struct {
+		env    string
+		values map[string]string
+		want   map[string]interface{}
+	}{
+		{"staging", map[string]string{"region": "us-east-1"}, map[string]interface{}{"region": "us-east-1", "instance_class": "small", "replicas": int64(1)}},
+		{"prod-us", nil, map[string]interface{}{"instance_class": "large", "replicas": int64(3)}},
+		// Later blocks win
+		{"prod-eu", nil, map[string]interface{}{"instance_class": "large", "replicas": int64(5)}},
+		// Values set on the datacenter win over environment defaults
+		{"prod-us", map[string]string{"instance_class": "medium"}, map[string]interface{}{"instance_class": "medium", "replicas": int64(3)}},
+		// Outside of an environment
+		{"", nil, map[string]interface{}{"instance_class": "small", "replicas": int64(1)}},
+	}
+	for _, tt := range tests {
+		got := ResolveVariables(dc, tt.values, tt.env)
+		for k, want := range tt.want {
+			if got[k] != want {
+				t.Errorf("env %q: %s = %v (%T), want %v", tt.env, k, got[k], got[k], want)
+			}
+		}
+	}
+}
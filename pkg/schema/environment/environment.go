@@ -20,6 +20,13 @@ type Environment interface {
 	// Components
 	Components() map[string]ComponentConfig
 
+	// Outputs returns environment-level output declarations
+	Outputs() map[string]Output
+
+	// Bootstrap returns the environment's bootstrap tasks, in the order
+	// they run
+	Bootstrap() []BootstrapTask
+
 	// Version information
 	SchemaVersion() string
 
@@ -51,6 +58,41 @@ type EnvironmentVariable interface {
 	Env() string
 }
 
+// Output represents an environment-level output, resolved after each deploy
+// so pipelines can read values like a preview environment's URL.
+type Output interface {
+	Name() string
+	Description() string
+
+	// Value returns the output expression, e.g.
+	// "${{ components.api.routes.main.url }}"
+	Value() string
+
+	Sensitive() bool
+}
+
+// BootstrapTask is a command cldctl runs once for an environment, after its
+// first successful deploy, such as creating an admin user.
+type BootstrapTask interface {
+	Name() string
+
+	// Command returns the command and its arguments, which may contain
+	// component output and route URL expressions
+	Command() []string
+
+	// Environment returns variables set for the command, whose values may
+	// contain the same expressions
+	Environment() map[string]string
+
+	// WorkingDirectory returns the directory to run in, relative to the
+	// environment file, or empty string for the file's directory
+	WorkingDirectory() string
+
+	// Timeout returns how long the command may run, or empty string for
+	// the default
+	Timeout() string
+}
+
 // ComponentConfig represents a component's configuration within an environment.
 // Exactly one of Path or Image will be set (in single-instance mode).
 type ComponentConfig interface {
@@ -63,6 +105,10 @@ type ComponentConfig interface {
 	// Variable values
 	Variables() map[string]interface{}
 
+	// Replicas returns the replica count for every deployment, or 0 if unset.
+	// Per-deployment scaling takes precedence.
+	Replicas() int
+
 	// Port overrides (maps port name to specific port number)
 	Ports() map[string]int
 
@@ -131,8 +177,16 @@ type RouteConfig interface {
 
 // Hostname represents a hostname configuration.
 type Hostname interface {
+	// Subdomain returns the subdomain of the environment domain. A leading
+	// "*." label (or "*" alone) matches any subdomain.
 	Subdomain() string
+
+	// Host returns an explicit hostname, which may start with "*.".
 	Host() string
+
+	// PathPrefix returns the path prefix matched on this hostname, or empty
+	// string to use the route's path prefix.
+	PathPrefix() string
 }
 
 // TLSConfig represents TLS configuration.
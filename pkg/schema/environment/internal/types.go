@@ -15,6 +15,12 @@ type InternalEnvironment struct {
 	// Component configurations
 	Components map[string]InternalComponentConfig
 
+	// Environment-level outputs
+	Outputs map[string]InternalOutput
+
+	// Bootstrap tasks, in the order they run
+	Bootstrap []InternalBootstrapTask
+
 	// Source information
 	SourceVersion string
 	SourcePath    string
@@ -31,6 +37,26 @@ type InternalEnvironmentVariable struct {
 	Env         string // Explicit OS env var name override (defaults to UPPER_SNAKE_CASE of Name)
 }
 
+// InternalOutput represents an environment-level output. Value is an
+// expression string resolved against the deployed environment.
+type InternalOutput struct {
+	Name        string
+	Description string
+	Value       string
+	Sensitive   bool
+}
+
+// InternalBootstrapTask represents a command run once for an environment.
+// Command arguments and environment values are expression strings resolved
+// against the deployed environment.
+type InternalBootstrapTask struct {
+	Name             string
+	Command          []string
+	Environment      map[string]string
+	WorkingDirectory string
+	Timeout          string
+}
+
 // InternalComponentConfig represents the configuration for a component in an environment.
 // Exactly one of Path or Image must be set (at the top level or within instances).
 type InternalComponentConfig struct {
@@ -45,6 +71,10 @@ type InternalComponentConfig struct {
 	// Variable values for the component
 	Variables map[string]interface{}
 
+	// Replicas for every deployment in the component (0 = unset).
+	// Per-deployment Scaling takes precedence.
+	Replicas int
+
 	// Port overrides (maps port name to specific port number)
 	Ports map[string]int
 
@@ -121,6 +151,10 @@ type InternalHostname struct {
 	// One of these is set
 	Subdomain string // Results in subdomain.<env-domain>
 	Host      string // Explicit full hostname
+
+	// PathPrefix matched together with the hostname. When empty, the
+	// route's PathPrefix applies.
+	PathPrefix string
 }
 
 // InternalTLSConfig represents TLS configuration.
@@ -32,7 +32,10 @@ type ResolveOptions struct {
 //  5. Error if required and no value found
 //
 // After resolving variables, it substitutes ${{ variables.* }} and ${{ locals.* }}
-// expressions in all component variable values.
+// expressions in all component variable values, environment outputs, and
+// bootstrap tasks. References to other components'
+// outputs (${{ components.<name>.outputs.<key> }}) are left in place for the
+// engine to resolve once the referenced component is deployed.
 func ResolveVariables(env *internal.InternalEnvironment, opts ResolveOptions) error {
 	// Step 1: Resolve declared variable values
 	resolved, err := resolveVariableValues(env.Variables, opts)
@@ -41,7 +44,17 @@ func ResolveVariables(env *internal.InternalEnvironment, opts ResolveOptions) er
 	}
 
 	// Step 2: Substitute expressions in component configs
-	return resolveComponentExpressions(env, resolved)
+	if err := resolveComponentExpressions(env, resolved); err != nil {
+		return err
+	}
+
+	// Step 3: Substitute expressions in environment outputs
+	if err := resolveOutputExpressions(env, resolved); err != nil {
+		return err
+	}
+
+	// Step 4: Substitute expressions in bootstrap tasks
+	return resolveBootstrapExpressions(env, resolved)
 }
 
 // resolveVariableValues resolves each declared variable to a concrete value.
@@ -115,7 +128,7 @@ func resolveComponentExpressions(env *internal.InternalEnvironment, resolved map
 
 		resolvedVars := make(map[string]interface{}, len(comp.Variables))
 		for key, val := range comp.Variables {
-			resolvedVal, err := resolveValue(val, resolved, env.Locals, compName, key)
+			resolvedVal, err := resolveValue(val, resolved, env.Locals, fmt.Sprintf("components.%s.variables.%s", compName, key))
 			if err != nil {
 				return err
 			}
@@ -127,9 +140,56 @@ func resolveComponentExpressions(env *internal.InternalEnvironment, resolved map
 	return nil
 }
 
+// resolveOutputExpressions substitutes ${{ variables.* }} and ${{ locals.* }}
+// expressions in environment output values. References to components are left
+// for the engine to resolve after deploy.
+func resolveOutputExpressions(env *internal.InternalEnvironment, resolved map[string]interface{}) error {
+	for name, output := range env.Outputs {
+		val, err := resolveValue(output.Value, resolved, env.Locals, fmt.Sprintf("outputs.%s", name))
+		if err != nil {
+			return err
+		}
+		output.Value = fmt.Sprintf("%v", val)
+		env.Outputs[name] = output
+	}
+	return nil
+}
+
+// resolveBootstrapExpressions substitutes ${{ variables.* }} and
+// ${{ locals.* }} expressions in bootstrap task commands and environment
+// values. References to components are left for the engine to resolve when
+// the task runs.
+func resolveBootstrapExpressions(env *internal.InternalEnvironment, resolved map[string]interface{}) error {
+	for i, task := range env.Bootstrap {
+		command := make([]string, len(task.Command))
+		for j, arg := range task.Command {
+			val, err := resolveValue(arg, resolved, env.Locals, fmt.Sprintf("bootstrap.%s.command", task.Name))
+			if err != nil {
+				return err
+			}
+			command[j] = fmt.Sprintf("%v", val)
+		}
+		task.Command = command
+
+		if task.Environment != nil {
+			environment := make(map[string]string, len(task.Environment))
+			for key, value := range task.Environment {
+				val, err := resolveValue(value, resolved, env.Locals, fmt.Sprintf("bootstrap.%s.environment.%s", task.Name, key))
+				if err != nil {
+					return err
+				}
+				environment[key] = fmt.Sprintf("%v", val)
+			}
+			task.Environment = environment
+		}
+		env.Bootstrap[i] = task
+	}
+	return nil
+}
+
 // resolveValue resolves ${{ }} expressions in a single value.
 // Supports string values containing expressions, and passes through non-string values.
-func resolveValue(val interface{}, variables map[string]interface{}, locals map[string]interface{}, compName, key string) (interface{}, error) {
+func resolveValue(val interface{}, variables map[string]interface{}, locals map[string]interface{}, field string) (interface{}, error) {
 	str, ok := val.(string)
 	if !ok {
 		return val, nil
@@ -137,7 +197,7 @@ func resolveValue(val interface{}, variables map[string]interface{}, locals map[
 
 	// Check if the entire value is a single expression (return typed value)
 	if match := expressionPattern.FindStringSubmatch(str); match != nil && match[0] == str {
-		return resolveExpression(strings.TrimSpace(match[1]), variables, locals, compName, key)
+		return resolveExpression(strings.TrimSpace(match[1]), variables, locals, field)
 	}
 
 	// Otherwise do string interpolation (multiple expressions or mixed content)
@@ -146,7 +206,7 @@ func resolveValue(val interface{}, variables map[string]interface{}, locals map[
 		if match == nil {
 			return expr
 		}
-		resolved, err := resolveExpression(strings.TrimSpace(match[1]), variables, locals, compName, key)
+		resolved, err := resolveExpression(strings.TrimSpace(match[1]), variables, locals, field)
 		if err != nil {
 			return expr // Leave unresolved on error (will be caught by validation)
 		}
@@ -157,10 +217,10 @@ func resolveValue(val interface{}, variables map[string]interface{}, locals map[
 }
 
 // resolveExpression resolves a single expression path like "variables.foo" or "locals.bar".
-func resolveExpression(expr string, variables map[string]interface{}, locals map[string]interface{}, compName, key string) (interface{}, error) {
+func resolveExpression(expr string, variables map[string]interface{}, locals map[string]interface{}, field string) (interface{}, error) {
 	parts := strings.SplitN(expr, ".", 2)
 	if len(parts) != 2 {
-		return nil, fmt.Errorf("components.%s.variables.%s: invalid expression ${{ %s }}", compName, key, expr)
+		return nil, fmt.Errorf("%s: invalid expression ${{ %s }}", field, expr)
 	}
 
 	namespace := parts[0]
@@ -171,18 +231,22 @@ func resolveExpression(expr string, variables map[string]interface{}, locals map
 		if val, ok := variables[name]; ok {
 			return val, nil
 		}
-		return nil, fmt.Errorf("components.%s.variables.%s: undefined variable ${{ variables.%s }}", compName, key, name)
+		return nil, fmt.Errorf("%s: undefined variable ${{ variables.%s }}", field, name)
 
 	case "locals":
 		if locals == nil {
-			return nil, fmt.Errorf("components.%s.variables.%s: no locals defined, cannot resolve ${{ locals.%s }}", compName, key, name)
+			return nil, fmt.Errorf("%s: no locals defined, cannot resolve ${{ locals.%s }}", field, name)
 		}
 		if val, ok := locals[name]; ok {
 			return val, nil
 		}
-		return nil, fmt.Errorf("components.%s.variables.%s: undefined local ${{ locals.%s }}", compName, key, name)
+		return nil, fmt.Errorf("%s: undefined local ${{ locals.%s }}", field, name)
+
+	case "components":
+		// Resolved by the engine at deploy time
+		return fmt.Sprintf("${{ %s }}", expr), nil
 
 	default:
-		return nil, fmt.Errorf("components.%s.variables.%s: unsupported expression namespace %q in ${{ %s }}", compName, key, namespace, expr)
+		return nil, fmt.Errorf("%s: unsupported expression namespace %q in ${{ %s }}", field, namespace, expr)
 	}
 }
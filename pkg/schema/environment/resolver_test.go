@@ -276,3 +276,66 @@ func TestResolveVariables_NoVariablesBlock(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "just a string", env.Components["app"].Variables["static"])
 }
+
+func TestResolveVariables_ComponentOutputReferencesPassThrough(t *testing.T) {
+	env := &internal.InternalEnvironment{
+		Variables: map[string]internal.InternalEnvironmentVariable{
+			"region": {Name: "region", Default: "us-east-1"},
+		},
+		Components: map[string]internal.InternalComponentConfig{
+			"api": {
+				Variables: map[string]interface{}{
+					"issuer": "${{ components.auth.outputs.issuer }}",
+					"mixed":  "${{ variables.region }}:${{ components.auth.outputs.domain }}",
+				},
+			},
+		},
+	}
+
+	err := ResolveVariables(env, ResolveOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "${{ components.auth.outputs.issuer }}", env.Components["api"].Variables["issuer"])
+	assert.Equal(t, "us-east-1:${{ components.auth.outputs.domain }}", env.Components["api"].Variables["mixed"])
+}
+
+func TestResolveVariables_Outputs(t *testing.T) {
+	env := &internal.InternalEnvironment{
+		Variables: map[string]internal.InternalEnvironmentVariable{
+			"region": {Name: "region", Default: "us-east-1"},
+		},
+		Outputs: map[string]internal.InternalOutput{
+			"api_url": {Name: "api_url", Value: "${{ components.api.routes.main.url }}"},
+			"region":  {Name: "region", Value: "${{ variables.region }}"},
+		},
+	}
+
+	err := ResolveVariables(env, ResolveOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "${{ components.api.routes.main.url }}", env.Outputs["api_url"].Value)
+	assert.Equal(t, "us-east-1", env.Outputs["region"].Value)
+
+	env.Outputs["bad"] = internal.InternalOutput{Name: "bad", Value: "${{ variables.missing }}"}
+	err = ResolveVariables(env, ResolveOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "outputs.bad: undefined variable")
+}
+
+func TestResolveVariables_Bootstrap(t *testing.T) {
+	env := &internal.InternalEnvironment{
+		Variables: map[string]internal.InternalEnvironmentVariable{
+			"admin_email": {Name: "admin_email", Default: "admin@example.com"},
+		},
+		Bootstrap: []internal.InternalBootstrapTask{{
+			Name:    "create-admin",
+			Command: []string{"create-admin", "--email=${{ variables.admin_email }}"},
+			Environment: map[string]string{
+				"API_URL": "${{ components.api.routes.main.url }}",
+			},
+		}},
+	}
+
+	err := ResolveVariables(env, ResolveOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"create-admin", "--email=admin@example.com"}, env.Bootstrap[0].Command)
+	assert.Equal(t, "${{ components.api.routes.main.url }}", env.Bootstrap[0].Environment["API_URL"])
+}
@@ -128,3 +128,36 @@ components:
 		t.Error("expected error for invalid YAML")
 	}
 }
+
+func TestParser_ParseBytes_ComponentReplicas(t *testing.T) {
+	schema, err := NewParser().ParseBytes([]byte(`
+components:
+  api:
+    path: ./api
+    replicas: 3
+    scaling:
+      worker:
+        replicas: 5
+        cpu: "500m"
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	env, err := NewTransformer().Transform(schema)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	comp := env.Components["api"]
+	if comp.Replicas != 3 {
+		t.Errorf("expected replicas 3, got %d", comp.Replicas)
+	}
+	if comp.Scaling["worker"].Replicas != 5 || comp.Scaling["worker"].CPU != "500m" {
+		t.Errorf("unexpected scaling: %+v", comp.Scaling["worker"])
+	}
+
+	schema.Components["api"] = ComponentConfigV1{Path: "./api", Replicas: -1}
+	if errs := NewValidator().Validate(schema); len(errs) == 0 {
+		t.Error("expected error for negative replicas")
+	}
+}
@@ -0,0 +1,147 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidator_Validate_ComponentOutputReferences(t *testing.T) {
+	validator := NewValidator()
+
+	schema := &SchemaV1{
+		Components: map[string]ComponentConfigV1{
+			"auth": {Image: "ghcr.io/org/auth:v1"},
+			"api": {
+				Image: "ghcr.io/org/api:v1",
+				Variables: map[string]interface{}{
+					"issuer":   "${{ components.auth.outputs.issuer }}",
+					"audience": "https://${{ components.auth.outputs.domain }}/api",
+				},
+			},
+		},
+	}
+
+	errors := validator.Validate(schema)
+	assert.Empty(t, errors)
+}
+
+func TestValidator_Validate_ComponentOutputReferenceErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantMsg string
+	}{
+		{"undefined component", "${{ components.billing.outputs.url }}", `references undefined component "billing"`},
+		{"missing outputs segment", "${{ components.auth.issuer }}", "invalid component reference"},
+		{"self reference", "${{ components.api.outputs.url }}", "cannot reference its own outputs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := &SchemaV1{
+				Components: map[string]ComponentConfigV1{
+					"auth": {Image: "ghcr.io/org/auth:v1"},
+					"api": {
+						Image:     "ghcr.io/org/api:v1",
+						Variables: map[string]interface{}{"value": tt.value},
+					},
+				},
+			}
+
+			errors := NewValidator().Validate(schema)
+			require.Len(t, errors, 1)
+			assert.Equal(t, "components.api.variables.value", errors[0].Field)
+			assert.Contains(t, errors[0].Message, tt.wantMsg)
+		})
+	}
+}
+
+func TestValidator_Validate_ComponentOutputReferenceCycle(t *testing.T) {
+	schema := &SchemaV1{
+		Components: map[string]ComponentConfigV1{
+			"a": {
+				Image:     "ghcr.io/org/a:v1",
+				Variables: map[string]interface{}{"b_url": "${{ components.b.outputs.url }}"},
+			},
+			"b": {
+				Image:     "ghcr.io/org/b:v1",
+				Variables: map[string]interface{}{"a_url": "${{ components.a.outputs.url }}"},
+			},
+		},
+	}
+
+	errors := NewValidator().Validate(schema)
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Message, "a -> b -> a")
+}
+
+func TestValidator_Validate_Outputs(t *testing.T) {
+	schema := &SchemaV1{
+		Variables: map[string]EnvironmentVariableV1{"region": {Default: "us-east-1"}},
+		Components: map[string]ComponentConfigV1{
+			"api": {Image: "ghcr.io/org/api:v1"},
+		},
+		Outputs: map[string]OutputV1{
+			"api_url":  {Value: "${{ components.api.routes.main.url }}"},
+			"issuer":   {Value: "${{ components.api.outputs.issuer }}", Sensitive: true},
+			"endpoint": {Value: "${{ components.api.routes.main.url }}/v1 (${{ variables.region }})"},
+		},
+	}
+	assert.Empty(t, NewValidator().Validate(schema))
+
+	tests := []struct {
+		name    string
+		value   string
+		wantMsg string
+	}{
+		{"empty value", "", "value is required"},
+		{"undefined component", "${{ components.web.routes.main.url }}", `references undefined component "web"`},
+		{"route without url", "${{ components.api.routes.main }}", "invalid component reference"},
+		{"undefined variable", "${{ variables.zone }}", `references undefined variable "zone"`},
+		{"unsupported namespace", "${{ dependencies.api.outputs.url }}", `unsupported expression namespace "dependencies"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema.Outputs = map[string]OutputV1{"value": {Value: tt.value}}
+			errors := NewValidator().Validate(schema)
+			require.Len(t, errors, 1)
+			assert.Equal(t, "outputs.value", errors[0].Field)
+			assert.Contains(t, errors[0].Message, tt.wantMsg)
+		})
+	}
+}
+
+func TestValidator_Validate_Bootstrap(t *testing.T) {
+	schema := &SchemaV1{
+		Variables: map[string]EnvironmentVariableV1{"admin_email": {Required: true}},
+		Components: map[string]ComponentConfigV1{
+			"api": {Image: "ghcr.io/org/api:v1"},
+		},
+		Bootstrap: []BootstrapTaskV1{
+			{
+				Name:    "create-admin",
+				Command: []string{"./scripts/create-admin.sh", "${{ variables.admin_email }}"},
+				Environment: map[string]string{
+					"API_URL": "${{ components.api.routes.main.url }}",
+				},
+				Timeout: "5m",
+			},
+		},
+	}
+	assert.Empty(t, NewValidator().Validate(schema))
+
+	schema.Bootstrap = append(schema.Bootstrap,
+		BootstrapTaskV1{Name: "create-admin", Command: []string{"true"}},
+		BootstrapTaskV1{Name: "register", Environment: map[string]string{"URL": "${{ components.billing.outputs.url }}"}, Timeout: "soon"},
+	)
+	errors := NewValidator().Validate(schema)
+	require.Len(t, errors, 4)
+	assert.Equal(t, "bootstrap[1].name", errors[0].Field)
+	assert.Contains(t, errors[0].Message, "duplicate task name")
+	assert.Equal(t, "bootstrap.register.command", errors[1].Field)
+	assert.Equal(t, "bootstrap.register.timeout", errors[2].Field)
+	assert.Equal(t, "bootstrap.register.environment.URL", errors[3].Field)
+	assert.Contains(t, errors[3].Message, `references undefined component "billing"`)
+}
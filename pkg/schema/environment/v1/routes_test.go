@@ -0,0 +1,81 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_ParseBytes_RouteHostnames(t *testing.T) {
+	parser := NewParser()
+
+	yaml := `
+components:
+  web:
+    image: ghcr.io/org/web:v1
+    routes:
+      main:
+        pathPrefix: /app
+        hostnames:
+          - subdomain: "*.preview"
+          - host: "*.example.com"
+            pathPrefix: /docs
+`
+
+	schema, err := parser.ParseBytes([]byte(yaml))
+	require.NoError(t, err)
+
+	hostnames := schema.Components["web"].Routes["main"].Hostnames
+	require.Len(t, hostnames, 2)
+	assert.Equal(t, "*.preview", hostnames[0].Subdomain)
+	assert.Equal(t, "*.example.com", hostnames[1].Host)
+	assert.Equal(t, "/docs", hostnames[1].PathPrefix)
+
+	assert.Empty(t, NewValidator().Validate(schema))
+}
+
+func TestValidator_Validate_RouteHostnames(t *testing.T) {
+	tests := []struct {
+		name      string
+		hostnames []HostnameV1
+		wantField string
+		wantMsg   string
+	}{
+		{"wildcard subdomain", []HostnameV1{{Subdomain: "*"}, {Subdomain: "*.preview"}}, "", ""},
+		{"multi-label subdomain", []HostnameV1{{Subdomain: "api.internal"}}, "", ""},
+		{"wildcard host", []HostnameV1{{Host: "*.example.com"}}, "", ""},
+		{"expression subdomain", []HostnameV1{{Subdomain: "preview-${{ variables.pr }}"}}, "", ""},
+		{"same host on different paths", []HostnameV1{{Host: "example.com", PathPrefix: "/a"}, {Host: "example.com", PathPrefix: "/b"}}, "", ""},
+		{"wildcard in middle", []HostnameV1{{Subdomain: "api.*"}}, "hostnames[0].subdomain", "subdomain must be"},
+		{"uppercase subdomain", []HostnameV1{{Subdomain: "App"}}, "hostnames[0].subdomain", "subdomain must be"},
+		{"bare wildcard host", []HostnameV1{{Host: "*"}}, "hostnames[0].host", "host must be"},
+		{"single-label host", []HostnameV1{{Host: "localhost"}}, "hostnames[0].host", "host must be"},
+		{"wildcard with one label host", []HostnameV1{{Host: "*.com"}}, "hostnames[0].host", "host must be"},
+		{"relative path prefix", []HostnameV1{{Host: "example.com", PathPrefix: "api"}}, "hostnames[0].pathPrefix", "must start with /"},
+		{"duplicate host and path", []HostnameV1{{Host: "example.com", PathPrefix: "/api"}, {Host: "example.com", PathPrefix: "/api/"}}, "hostnames[1]", "duplicate of hostnames[0]"},
+		{"duplicate inherits route path", []HostnameV1{{Subdomain: "app"}, {Subdomain: "app", PathPrefix: "/"}}, "hostnames[1]", "duplicate of hostnames[0]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := &SchemaV1{
+				Components: map[string]ComponentConfigV1{
+					"web": {
+						Image:  "ghcr.io/org/web:v1",
+						Routes: map[string]RouteConfigV1{"main": {Hostnames: tt.hostnames}},
+					},
+				},
+			}
+
+			errors := NewValidator().Validate(schema)
+			if tt.wantField == "" {
+				assert.Empty(t, errors)
+				return
+			}
+			require.Len(t, errors, 1)
+			assert.Equal(t, "components.web.routes.main."+tt.wantField, errors[0].Field)
+			assert.Contains(t, errors[0].Message, tt.wantMsg)
+		})
+	}
+}
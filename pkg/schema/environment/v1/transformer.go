@@ -19,6 +19,7 @@ func (t *Transformer) Transform(v1 *SchemaV1) (*internal.InternalEnvironment, er
 		Variables:     make(map[string]internal.InternalEnvironmentVariable),
 		Locals:        v1.Locals,
 		Components:    make(map[string]internal.InternalComponentConfig),
+		Outputs:       make(map[string]internal.InternalOutput),
 		SourceVersion: "v1",
 	}
 
@@ -32,6 +33,26 @@ func (t *Transformer) Transform(v1 *SchemaV1) (*internal.InternalEnvironment, er
 		env.Components[name] = t.transformComponent(comp)
 	}
 
+	// Transform outputs
+	for name, output := range v1.Outputs {
+		env.Outputs[name] = internal.InternalOutput{
+			Name:        name,
+			Description: output.Description,
+			Value:       output.Value,
+			Sensitive:   output.Sensitive,
+		}
+	}
+
+	for _, task := range v1.Bootstrap {
+		env.Bootstrap = append(env.Bootstrap, internal.InternalBootstrapTask{
+			Name:             task.Name,
+			Command:          task.Command,
+			Environment:      task.Environment,
+			WorkingDirectory: task.WorkingDirectory,
+			Timeout:          task.Timeout,
+		})
+	}
+
 	return env, nil
 }
 
@@ -51,6 +72,7 @@ func (t *Transformer) transformComponent(v1 ComponentConfigV1) internal.Internal
 		Path:        v1.Path,
 		Image:       v1.Image,
 		Variables:   v1.Variables,
+		Replicas:    v1.Replicas,
 		Ports:       v1.Ports,
 		Scaling:     make(map[string]internal.InternalScalingConfig),
 		Functions:   make(map[string]internal.InternalFunctionConfig),
@@ -118,8 +140,9 @@ func (t *Transformer) transformRoute(v1 RouteConfigV1) internal.InternalRouteCon
 
 	for i, hostname := range v1.Hostnames {
 		route.Hostnames[i] = internal.InternalHostname{
-			Subdomain: hostname.Subdomain,
-			Host:      hostname.Host,
+			Subdomain:  hostname.Subdomain,
+			Host:       hostname.Host,
+			PathPrefix: hostname.PathPrefix,
 		}
 	}
 
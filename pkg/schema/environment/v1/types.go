@@ -17,6 +17,42 @@ type SchemaV1 struct {
 
 	// Component configurations
 	Components map[string]ComponentConfigV1 `yaml:"components,omitempty" json:"components,omitempty"`
+
+	// Environment-level outputs, resolved after each deploy
+	Outputs map[string]OutputV1 `yaml:"outputs,omitempty" json:"outputs,omitempty"`
+
+	// Tasks run once, in order, after the environment's first successful
+	// deploy
+	Bootstrap []BootstrapTaskV1 `yaml:"bootstrap,omitempty" json:"bootstrap,omitempty"`
+}
+
+// BootstrapTaskV1 represents a bootstrap task in the v1 schema: a command
+// cldctl runs once for the environment, such as creating an admin user.
+// Arguments and environment values may reference variables, locals,
+// component outputs, and route URLs, like outputs.
+type BootstrapTaskV1 struct {
+	Name    string   `yaml:"name" json:"name"`
+	Command []string `yaml:"command" json:"command"`
+
+	// Environment variables set for the command, on top of cldctl's own
+	Environment map[string]string `yaml:"environment,omitempty" json:"environment,omitempty"`
+
+	// Directory the command runs in, relative to the environment file.
+	// Defaults to the environment file's directory.
+	WorkingDirectory string `yaml:"workingDirectory,omitempty" json:"workingDirectory,omitempty"`
+
+	// How long the command may run (default 10m)
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// OutputV1 represents an environment output in the v1 schema. Values may
+// reference variables, locals, component outputs
+// (${{ components.<name>.outputs.<key> }}), and route URLs
+// (${{ components.<name>.routes.<route>.url }}).
+type OutputV1 struct {
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	Value       string `yaml:"value" json:"value"`
+	Sensitive   bool   `yaml:"sensitive,omitempty" json:"sensitive,omitempty"`
 }
 
 // EnvironmentVariableV1 represents a variable declaration in the v1 environment schema.
@@ -54,6 +90,10 @@ type ComponentConfigV1 struct {
 	// Variable values
 	Variables map[string]interface{} `yaml:"variables,omitempty" json:"variables,omitempty"`
 
+	// Replicas sets the replica count of every deployment in the component.
+	// Per-deployment scaling takes precedence.
+	Replicas int `yaml:"replicas,omitempty" json:"replicas,omitempty"`
+
 	// Port overrides per port name (maps port name to specific port number)
 	Ports map[string]int `yaml:"ports,omitempty" json:"ports,omitempty"`
 
@@ -123,10 +163,15 @@ type RouteConfigV1 struct {
 	TLS       *TLSConfigV1 `yaml:"tls,omitempty" json:"tls,omitempty"`
 }
 
-// HostnameV1 represents a hostname in v1 schema.
+// HostnameV1 represents a hostname in v1 schema. Either Subdomain or Host
+// is set; both may start with a "*." wildcard label.
 type HostnameV1 struct {
 	Subdomain string `yaml:"subdomain,omitempty" json:"subdomain,omitempty"`
 	Host      string `yaml:"host,omitempty" json:"host,omitempty"`
+
+	// PathPrefix matched together with the hostname. Defaults to the
+	// route's pathPrefix when not set.
+	PathPrefix string `yaml:"pathPrefix,omitempty" json:"pathPrefix,omitempty"`
 }
 
 // TLSConfigV1 represents TLS configuration in v1 schema.
@@ -3,7 +3,9 @@ package v1
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 )
 
 // expressionPattern matches ${{ ... }} expressions.
@@ -43,10 +45,21 @@ func (v *Validator) Validate(schema *SchemaV1) []ValidationError {
 		errors = append(errors, compErrors...)
 
 		// Validate that ${{ variables.* }} references point to declared variables
-		refErrors := v.validateVariableReferences(name, comp, schema.Variables, schema.Locals)
+		refErrors := v.validateVariableReferences(name, comp, schema.Variables, schema.Locals, schema.Components)
 		errors = append(errors, refErrors...)
 	}
 
+	// Validate environment outputs
+	for name, output := range schema.Outputs {
+		errors = append(errors, v.validateOutput(name, output, schema)...)
+	}
+
+	// Validate bootstrap tasks
+	errors = append(errors, v.validateBootstrap(schema)...)
+
+	// Validate that component output references do not form a cycle
+	errors = append(errors, v.validateComponentReferenceCycles(schema.Components)...)
+
 	// Validate locals don't contain reserved keys
 	for key := range schema.Locals {
 		if isReservedLocalKey(key) {
@@ -75,9 +88,10 @@ func (v *Validator) validateVariable(name string, variable EnvironmentVariableV1
 	return errors
 }
 
-// validateVariableReferences checks that ${{ variables.* }} and ${{ locals.* }}
-// expressions in component variable values reference declared names.
-func (v *Validator) validateVariableReferences(compName string, comp ComponentConfigV1, vars map[string]EnvironmentVariableV1, locals map[string]interface{}) []ValidationError {
+// validateVariableReferences checks that ${{ variables.* }}, ${{ locals.* }},
+// and ${{ components.<name>.outputs.<key> }} expressions in component variable
+// values reference declared names.
+func (v *Validator) validateVariableReferences(compName string, comp ComponentConfigV1, vars map[string]EnvironmentVariableV1, locals map[string]interface{}, components map[string]ComponentConfigV1) []ValidationError {
 	var errors []ValidationError
 
 	for key, val := range comp.Variables {
@@ -112,10 +126,224 @@ func (v *Validator) validateVariableReferences(compName string, comp ComponentCo
 						Message: fmt.Sprintf("references undefined local %q", refName),
 					})
 				}
+			case "components":
+				target, ok := componentOutputReference(expr)
+				switch {
+				case !ok:
+					errors = append(errors, ValidationError{
+						Field:   fmt.Sprintf("components.%s.variables.%s", compName, key),
+						Message: fmt.Sprintf("invalid component reference ${{ %s }} (expected components.<name>.outputs.<key>)", expr),
+					})
+				case target == compName:
+					errors = append(errors, ValidationError{
+						Field:   fmt.Sprintf("components.%s.variables.%s", compName, key),
+						Message: "a component cannot reference its own outputs",
+					})
+				default:
+					if _, ok := components[target]; !ok {
+						errors = append(errors, ValidationError{
+							Field:   fmt.Sprintf("components.%s.variables.%s", compName, key),
+							Message: fmt.Sprintf("references undefined component %q", target),
+						})
+					}
+				}
+			}
+		}
+	}
+
+	return errors
+}
+
+// validateOutput checks that an environment output has a value and that its
+// expressions reference declared variables, locals, and components.
+func (v *Validator) validateOutput(name string, output OutputV1, schema *SchemaV1) []ValidationError {
+	field := fmt.Sprintf("outputs.%s", name)
+
+	if strings.TrimSpace(output.Value) == "" {
+		return []ValidationError{{Field: field, Message: "value is required"}}
+	}
+	return v.validateEnvironmentExpressions(field, output.Value, schema)
+}
+
+// validateBootstrap checks that bootstrap tasks have unique names and a
+// command, and that their expressions reference declared variables, locals,
+// and components.
+func (v *Validator) validateBootstrap(schema *SchemaV1) []ValidationError {
+	var errors []ValidationError
+	seen := make(map[string]bool, len(schema.Bootstrap))
+
+	for i, task := range schema.Bootstrap {
+		field := fmt.Sprintf("bootstrap[%d]", i)
+		if task.Name == "" {
+			errors = append(errors, ValidationError{Field: field + ".name", Message: "task name is required"})
+		} else {
+			if seen[task.Name] {
+				errors = append(errors, ValidationError{Field: field + ".name", Message: fmt.Sprintf("duplicate task name %q", task.Name)})
+			}
+			seen[task.Name] = true
+			field = "bootstrap." + task.Name
+		}
+
+		if len(task.Command) == 0 || strings.TrimSpace(task.Command[0]) == "" {
+			errors = append(errors, ValidationError{Field: field + ".command", Message: "command is required"})
+		}
+		if task.Timeout != "" {
+			if d, err := time.ParseDuration(task.Timeout); err != nil || d <= 0 {
+				errors = append(errors, ValidationError{
+					Field:   field + ".timeout",
+					Message: fmt.Sprintf("invalid duration %q (e.g. 5m)", task.Timeout),
+				})
+			}
+		}
+
+		for _, arg := range task.Command {
+			errors = append(errors, v.validateEnvironmentExpressions(field+".command", arg, schema)...)
+		}
+		keys := make([]string, 0, len(task.Environment))
+		for key := range task.Environment {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			errors = append(errors, v.validateEnvironmentExpressions(field+".environment."+key, task.Environment[key], schema)...)
+		}
+	}
+
+	return errors
+}
+
+// validateEnvironmentExpressions checks that the expressions in a value
+// evaluated against the deployed environment, such as an output, reference
+// declared variables, locals, and components.
+func (v *Validator) validateEnvironmentExpressions(field, value string, schema *SchemaV1) []ValidationError {
+	var errors []ValidationError
+	for _, match := range validatorExprPattern.FindAllStringSubmatch(value, -1) {
+		expr := strings.TrimSpace(match[1])
+		parts := strings.SplitN(expr, ".", 2)
+		if len(parts) != 2 {
+			errors = append(errors, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("invalid expression ${{ %s }}", expr),
+			})
+			continue
+		}
+
+		switch parts[0] {
+		case "variables":
+			if _, ok := schema.Variables[parts[1]]; !ok {
+				errors = append(errors, ValidationError{
+					Field:   field,
+					Message: fmt.Sprintf("references undefined variable %q", parts[1]),
+				})
+			}
+		case "locals":
+			if _, ok := schema.Locals[parts[1]]; !ok {
+				errors = append(errors, ValidationError{
+					Field:   field,
+					Message: fmt.Sprintf("references undefined local %q", parts[1]),
+				})
+			}
+		case "components":
+			target, ok := componentOutputReference(expr)
+			if !ok {
+				target, ok = componentRouteReference(expr)
+			}
+			if !ok {
+				errors = append(errors, ValidationError{
+					Field:   field,
+					Message: fmt.Sprintf("invalid component reference ${{ %s }} (expected components.<name>.outputs.<key> or components.<name>.routes.<route>.url)", expr),
+				})
+			} else if _, ok := schema.Components[target]; !ok {
+				errors = append(errors, ValidationError{
+					Field:   field,
+					Message: fmt.Sprintf("references undefined component %q", target),
+				})
+			}
+		default:
+			errors = append(errors, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("unsupported expression namespace %q in ${{ %s }}", parts[0], expr),
+			})
+		}
+	}
+
+	return errors
+}
+
+// componentRouteReference parses "components.<name>.routes.<route>.url" and
+// returns the component name.
+func componentRouteReference(expr string) (string, bool) {
+	parts := strings.Split(expr, ".")
+	if len(parts) != 5 || parts[0] != "components" || parts[2] != "routes" || parts[4] != "url" || parts[1] == "" || parts[3] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// componentOutputReference parses "components.<name>.outputs.<key>" and
+// returns the component name.
+func componentOutputReference(expr string) (string, bool) {
+	parts := strings.Split(expr, ".")
+	if len(parts) != 4 || parts[0] != "components" || parts[2] != "outputs" || parts[1] == "" || parts[3] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// validateComponentReferenceCycles reports components whose variables
+// reference each other's outputs, directly or transitively, since neither
+// could be deployed first.
+func (v *Validator) validateComponentReferenceCycles(components map[string]ComponentConfigV1) []ValidationError {
+	refs := make(map[string][]string, len(components))
+	for name, comp := range components {
+		for _, val := range comp.Variables {
+			str, ok := val.(string)
+			if !ok {
+				continue
+			}
+			for _, match := range validatorExprPattern.FindAllStringSubmatch(str, -1) {
+				if target, ok := componentOutputReference(strings.TrimSpace(match[1])); ok && target != name {
+					refs[name] = append(refs[name], target)
+				}
 			}
 		}
 	}
 
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var errors []ValidationError
+	var visit func(name string, path []string)
+	visit = func(name string, path []string) {
+		switch state[name] {
+		case visiting:
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("components.%s", name),
+				Message: fmt.Sprintf("component output references form a cycle: %s", strings.Join(append(path, name), " -> ")),
+			})
+			return
+		case done:
+			return
+		}
+		state[name] = visiting
+		for _, target := range refs[name] {
+			visit(target, append(path, name))
+		}
+		state[name] = done
+	}
+	for _, name := range names {
+		visit(name, nil)
+	}
+
 	return errors
 }
 
@@ -143,6 +371,13 @@ func (v *Validator) validateComponent(name string, comp ComponentConfigV1) []Val
 		}
 	}
 
+	if comp.Replicas < 0 {
+		errors = append(errors, ValidationError{
+			Field:   prefix + ".replicas",
+			Message: "replicas must be non-negative",
+		})
+	}
+
 	// Validate scaling configs
 	for deployName, scaling := range comp.Scaling {
 		scalingErrors := v.validateScaling(fmt.Sprintf("%s.scaling.%s", prefix, deployName), scaling)
@@ -312,24 +547,89 @@ func (v *Validator) validateRoute(prefix string, routeConfig RouteConfigV1) []Va
 	}
 
 	// Each hostname must have either subdomain or host, but not both
+	seen := make(map[string]int)
 	for i, hostname := range routeConfig.Hostnames {
+		field := fmt.Sprintf("%s.hostnames[%d]", prefix, i)
 		if hostname.Subdomain == "" && hostname.Host == "" {
 			errors = append(errors, ValidationError{
-				Field:   fmt.Sprintf("%s.hostnames[%d]", prefix, i),
+				Field:   field,
 				Message: "hostname must have either subdomain or host",
 			})
 		}
 		if hostname.Subdomain != "" && hostname.Host != "" {
 			errors = append(errors, ValidationError{
-				Field:   fmt.Sprintf("%s.hostnames[%d]", prefix, i),
+				Field:   field,
 				Message: "hostname cannot have both subdomain and host",
 			})
 		}
+		if hostname.Subdomain != "" && !isValidHostnamePattern(hostname.Subdomain, false) {
+			errors = append(errors, ValidationError{
+				Field:   field + ".subdomain",
+				Message: "subdomain must be dot-separated lowercase labels, optionally starting with a \"*\" wildcard label",
+			})
+		}
+		if hostname.Host != "" && !isValidHostnamePattern(hostname.Host, true) {
+			errors = append(errors, ValidationError{
+				Field:   field + ".host",
+				Message: "host must be a fully-qualified lowercase hostname, optionally starting with a \"*.\" wildcard label",
+			})
+		}
+		if hostname.PathPrefix != "" && !strings.HasPrefix(hostname.PathPrefix, "/") {
+			errors = append(errors, ValidationError{
+				Field:   field + ".pathPrefix",
+				Message: "pathPrefix must start with /",
+			})
+		}
+
+		// The same host and path cannot be matched twice by one route
+		pathPrefix := hostname.PathPrefix
+		if pathPrefix == "" {
+			pathPrefix = routeConfig.PathPrefix
+		}
+		key := "subdomain:" + hostname.Subdomain
+		if hostname.Host != "" {
+			key = "host:" + hostname.Host
+		}
+		key += "/" + strings.Trim(pathPrefix, "/")
+		if first, ok := seen[key]; ok {
+			errors = append(errors, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("duplicate of hostnames[%d]", first),
+			})
+		} else {
+			seen[key] = i
+		}
 	}
 
 	return errors
 }
 
+// isValidHostnamePattern reports whether value is a dot-separated list of DNS
+// labels where only the first label may be the "*" wildcard. Fully-qualified
+// hosts need at least two labels besides the wildcard. Values containing
+// expressions are resolved later and are not checked here.
+func isValidHostnamePattern(value string, fullyQualified bool) bool {
+	if strings.Contains(value, "${{") {
+		return true
+	}
+	labels := strings.Split(value, ".")
+	if labels[0] == "*" {
+		labels = labels[1:]
+		if len(labels) == 0 {
+			return !fullyQualified
+		}
+	}
+	if fullyQualified && len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if !subdomainPattern.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
+
 // isReservedLocalKey checks if a key is reserved.
 func isReservedLocalKey(key string) bool {
 	reserved := []string{"environment", "datacenter", "component", "node"}
@@ -34,6 +34,27 @@ func (e *environmentWrapper) Components() map[string]ComponentConfig {
 	return result
 }
 
+func (e *environmentWrapper) Outputs() map[string]Output {
+	result := make(map[string]Output, len(e.env.Outputs))
+	for name := range e.env.Outputs {
+		o := e.env.Outputs[name]
+		result[name] = &outputWrapper{o: &o}
+	}
+	return result
+}
+
+func (e *environmentWrapper) Bootstrap() []BootstrapTask {
+	if len(e.env.Bootstrap) == 0 {
+		return nil
+	}
+	result := make([]BootstrapTask, len(e.env.Bootstrap))
+	for i := range e.env.Bootstrap {
+		task := e.env.Bootstrap[i]
+		result[i] = &bootstrapTaskWrapper{t: &task}
+	}
+	return result
+}
+
 func (e *environmentWrapper) Name() string                            { return e.env.Name }
 func (e *environmentWrapper) SchemaVersion() string                   { return e.env.SourceVersion }
 func (e *environmentWrapper) SourcePath() string                      { return e.env.SourcePath }
@@ -47,6 +68,7 @@ type componentConfigWrapper struct {
 func (c *componentConfigWrapper) Path() string                              { return c.c.Path }
 func (c *componentConfigWrapper) Image() string                             { return c.c.Image }
 func (c *componentConfigWrapper) Variables() map[string]interface{}         { return c.c.Variables }
+func (c *componentConfigWrapper) Replicas() int                             { return c.c.Replicas }
 func (c *componentConfigWrapper) Ports() map[string]int                     { return c.c.Ports }
 func (c *componentConfigWrapper) Environment() map[string]map[string]string { return c.c.Environment }
 
@@ -140,8 +162,9 @@ type hostnameWrapper struct {
 	h *internal.InternalHostname
 }
 
-func (h *hostnameWrapper) Subdomain() string { return h.h.Subdomain }
-func (h *hostnameWrapper) Host() string      { return h.h.Host }
+func (h *hostnameWrapper) Subdomain() string  { return h.h.Subdomain }
+func (h *hostnameWrapper) Host() string       { return h.h.Host }
+func (h *hostnameWrapper) PathPrefix() string { return h.h.PathPrefix }
 
 // tlsConfigWrapper wraps an InternalTLSConfig.
 type tlsConfigWrapper struct {
@@ -172,3 +195,24 @@ func (i *instanceConfigWrapper) Name() string                      { return i.i.
 func (i *instanceConfigWrapper) Source() string                    { return i.i.Source }
 func (i *instanceConfigWrapper) Weight() int                       { return i.i.Weight }
 func (i *instanceConfigWrapper) Variables() map[string]interface{} { return i.i.Variables }
+
+// outputWrapper wraps an InternalOutput.
+type outputWrapper struct {
+	o *internal.InternalOutput
+}
+
+func (o *outputWrapper) Name() string        { return o.o.Name }
+func (o *outputWrapper) Description() string { return o.o.Description }
+func (o *outputWrapper) Value() string       { return o.o.Value }
+func (o *outputWrapper) Sensitive() bool     { return o.o.Sensitive }
+
+// bootstrapTaskWrapper wraps an InternalBootstrapTask.
+type bootstrapTaskWrapper struct {
+	t *internal.InternalBootstrapTask
+}
+
+func (t *bootstrapTaskWrapper) Name() string                   { return t.t.Name }
+func (t *bootstrapTaskWrapper) Command() []string              { return t.t.Command }
+func (t *bootstrapTaskWrapper) Environment() map[string]string { return t.t.Environment }
+func (t *bootstrapTaskWrapper) WorkingDirectory() string       { return t.t.WorkingDirectory }
+func (t *bootstrapTaskWrapper) Timeout() string                { return t.t.Timeout }
@@ -0,0 +1,56 @@
+package selfupdate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Install atomically replaces the executable at path with binary. The new
+// binary is written next to the target and renamed over it, so a failed
+// install leaves the current executable untouched. The previous executable is
+// moved aside first because Windows cannot overwrite a running binary.
+func Install(path string, binary []byte) error {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", resolved, err)
+	}
+
+	dir := filepath.Dir(resolved)
+	tmp, err := os.CreateTemp(dir, ".cldctl-upgrade-*")
+	if err != nil {
+		return fmt.Errorf("cannot write to %s (try running with elevated permissions): %w", dir, err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write new binary: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write new binary: %w", err)
+	}
+	if err := os.Chmod(tmpPath, info.Mode().Perm()|0o111); err != nil {
+		return fmt.Errorf("failed to make new binary executable: %w", err)
+	}
+
+	old := resolved + ".old"
+	_ = os.Remove(old)
+	if err := os.Rename(resolved, old); err != nil {
+		return fmt.Errorf("failed to move current binary aside: %w", err)
+	}
+	if err := os.Rename(tmpPath, resolved); err != nil {
+		// Put the original back so the CLI keeps working.
+		_ = os.Rename(old, resolved)
+		return fmt.Errorf("failed to install new binary: %w", err)
+	}
+	// Best effort: Windows keeps the running executable locked.
+	_ = os.Remove(old)
+	return nil
+}
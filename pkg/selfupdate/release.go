@@ -0,0 +1,226 @@
+// Package selfupdate finds, verifies, and installs cldctl releases.
+//
+// Releases are published on GitHub with one binary per platform
+// (cldctl-<os>-<arch>[.exe]), a checksums.txt file listing their SHA-256
+// digests, and checksums.txt.sig, an ed25519 signature over checksums.txt.
+// A downloaded binary is trusted only if checksums.txt verifies against the
+// release public key and the binary's digest matches its checksums entry.
+package selfupdate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultRepository is the GitHub repository cldctl releases are published to.
+const DefaultRepository = "davidthor/cldctl"
+
+// Release asset names.
+const (
+	ChecksumsAsset = "checksums.txt"
+	SignatureAsset = "checksums.txt.sig"
+)
+
+// Channel selects which releases are eligible for an upgrade.
+type Channel string
+
+const (
+	// ChannelStable only considers full releases.
+	ChannelStable Channel = "stable"
+
+	// ChannelEdge also considers pre-releases.
+	ChannelEdge Channel = "edge"
+)
+
+// ParseChannel validates a channel name. An empty name selects stable.
+func ParseChannel(name string) (Channel, error) {
+	switch Channel(name) {
+	case "", ChannelStable:
+		return ChannelStable, nil
+	case ChannelEdge:
+		return ChannelEdge, nil
+	default:
+		return "", fmt.Errorf("unknown channel %q (use 'stable' or 'edge')", name)
+	}
+}
+
+// Release is a published cldctl release.
+type Release struct {
+	Version     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Body        string    `json:"body"`
+	Prerelease  bool      `json:"prerelease"`
+	Draft       bool      `json:"draft"`
+	PublishedAt time.Time `json:"published_at"`
+	Assets      []Asset   `json:"assets"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+	Size int64  `json:"size"`
+}
+
+// Asset returns the named asset, or nil if the release does not have it.
+func (r *Release) Asset(name string) *Asset {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// DeployFixes returns the entries listed under the "Deploy Fixes" heading of
+// the release notes. Maintainers use this section to call out fixes for bugs
+// that could cause failed or incorrect deploys.
+func (r *Release) DeployFixes() []string {
+	var fixes []string
+	inSection := false
+	for _, line := range strings.Split(r.Body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			heading := strings.ToLower(strings.TrimSpace(strings.TrimLeft(trimmed, "#")))
+			inSection = heading == "deploy fixes"
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if item, ok := strings.CutPrefix(trimmed, "- "); ok {
+			fixes = append(fixes, strings.TrimSpace(item))
+		} else if item, ok := strings.CutPrefix(trimmed, "* "); ok {
+			fixes = append(fixes, strings.TrimSpace(item))
+		}
+	}
+	return fixes
+}
+
+// BinaryAssetName returns the release asset name for a platform.
+func BinaryAssetName(goos, goarch string) string {
+	name := fmt.Sprintf("cldctl-%s-%s", goos, goarch)
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// CurrentBinaryAssetName returns the release asset name for this platform.
+func CurrentBinaryAssetName() string {
+	return BinaryAssetName(runtime.GOOS, runtime.GOARCH)
+}
+
+// Client queries the release feed.
+type Client struct {
+	// BaseURL is the GitHub API base URL.
+	BaseURL string
+
+	// Repository is the "owner/name" repository to read releases from.
+	Repository string
+
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client for the official cldctl releases.
+func NewClient() *Client {
+	return &Client{
+		BaseURL:    "https://api.github.com",
+		Repository: DefaultRepository,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Releases returns the published releases on a channel, newest first.
+func (c *Client) Releases(ctx context.Context, channel Channel) ([]Release, error) {
+	url := fmt.Sprintf("%s/repos/%s/releases?per_page=100", strings.TrimRight(c.BaseURL, "/"), c.Repository)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch releases: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("release feed returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var all []Release
+	if err := json.NewDecoder(resp.Body).Decode(&all); err != nil {
+		return nil, fmt.Errorf("failed to decode releases: %w", err)
+	}
+
+	releases := make([]Release, 0, len(all))
+	for _, r := range all {
+		if r.Draft || (r.Prerelease && channel != ChannelEdge) {
+			continue
+		}
+		if _, ok := parseVersion(r.Version); !ok {
+			continue
+		}
+		releases = append(releases, r)
+	}
+	sort.SliceStable(releases, func(i, j int) bool {
+		return CompareVersions(releases[i].Version, releases[j].Version) > 0
+	})
+	return releases, nil
+}
+
+// Latest returns the newest release on a channel.
+func (c *Client) Latest(ctx context.Context, channel Channel) (*Release, error) {
+	releases, err := c.Releases(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+	if len(releases) == 0 {
+		return nil, fmt.Errorf("no releases found on the %s channel", channel)
+	}
+	return &releases[0], nil
+}
+
+// Find returns the release with the given version, on any channel.
+func (c *Client) Find(ctx context.Context, tag string) (*Release, error) {
+	releases, err := c.Releases(ctx, ChannelEdge)
+	if err != nil {
+		return nil, err
+	}
+	for i := range releases {
+		if normalize(releases[i].Version) == normalize(tag) {
+			return &releases[i], nil
+		}
+	}
+	return nil, fmt.Errorf("release %s not found", tag)
+}
+
+// Newer returns the releases on a channel that are newer than current,
+// newest first.
+func (c *Client) Newer(ctx context.Context, current string, channel Channel) ([]Release, error) {
+	releases, err := c.Releases(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+	var newer []Release
+	for _, r := range releases {
+		if CompareVersions(r.Version, current) > 0 {
+			newer = append(newer, r)
+		}
+	}
+	return newer, nil
+}
+
+func normalize(version string) string {
+	return strings.TrimPrefix(version, "v")
+}
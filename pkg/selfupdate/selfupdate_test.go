@@ -0,0 +1,237 @@
+package selfupdate
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "v1.2.3", 0},
+		{"v1.3.0", "v1.2.9", 1},
+		{"1.2.0", "v1.10.0", -1},
+		{"v1.2", "v1.2.1", -1},
+		{"v2.0.0-rc.1", "v2.0.0", -1},
+		{"v2.0.0-rc.2", "v2.0.0-rc.1", 1},
+		{"v1.2.0-rc.10", "v1.2.0-rc.9", 1},
+		{"v1.2.0-rc.9", "v1.2.0-rc.10", -1},
+		{"v1.0.0-alpha", "v1.0.0-alpha.1", -1},
+		{"v1.0.0-alpha.1", "v1.0.0-alpha.beta", -1},
+		{"v1.0.0-alpha.beta", "v1.0.0-beta", -1},
+		{"v1.0.0-beta.11", "v1.0.0-beta.2", 1},
+		{"v1.0.0-rc.1", "v1.0.0-rc.1", 0},
+		{"v1.0.0+build.5", "v1.0.0", 0},
+		{"dev", "v1.0.0", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"_"+tt.b, func(t *testing.T) {
+			assert.Equal(t, tt.want, CompareVersions(tt.a, tt.b))
+		})
+	}
+}
+
+func TestParseChannel(t *testing.T) {
+	ch, err := ParseChannel("")
+	require.NoError(t, err)
+	assert.Equal(t, ChannelStable, ch)
+
+	ch, err = ParseChannel("edge")
+	require.NoError(t, err)
+	assert.Equal(t, ChannelEdge, ch)
+
+	_, err = ParseChannel("nightly")
+	assert.Error(t, err)
+}
+
+func TestRelease_DeployFixes(t *testing.T) {
+	r := Release{Body: "## Features\n- new thing\n\n## Deploy Fixes\n- Fix port collisions between environments (#412)\n* Fix postgres upgrade losing data\n\n## Other\n- docs"}
+	assert.Equal(t, []string{
+		"Fix port collisions between environments (#412)",
+		"Fix postgres upgrade losing data",
+	}, r.DeployFixes())
+
+	assert.Empty(t, (&Release{Body: "- nothing special"}).DeployFixes())
+}
+
+func TestBinaryAssetName(t *testing.T) {
+	assert.Equal(t, "cldctl-linux-amd64", BinaryAssetName("linux", "amd64"))
+	assert.Equal(t, "cldctl-windows-amd64.exe", BinaryAssetName("windows", "amd64"))
+}
+
+// releaseServer serves a fake release feed with signed assets.
+type releaseServer struct {
+	*httptest.Server
+	assets map[string][]byte
+}
+
+func newReleaseServer(t *testing.T, releases []Release, assets map[string][]byte) *releaseServer {
+	t.Helper()
+	rs := &releaseServer{assets: assets}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/davidthor/cldctl/releases", func(w http.ResponseWriter, r *http.Request) {
+		for i := range releases {
+			for j := range releases[i].Assets {
+				releases[i].Assets[j].URL = rs.URL + "/download/" + releases[i].Assets[j].Name
+			}
+		}
+		_ = json.NewEncoder(w).Encode(releases)
+	})
+	mux.HandleFunc("/download/", func(w http.ResponseWriter, r *http.Request) {
+		data, ok := rs.assets[filepath.Base(r.URL.Path)]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	})
+	rs.Server = httptest.NewServer(mux)
+	t.Cleanup(rs.Close)
+	return rs
+}
+
+func (rs *releaseServer) client() *Client {
+	return &Client{BaseURL: rs.URL, Repository: DefaultRepository, HTTPClient: rs.Client()}
+}
+
+func assetsNamed(names ...string) []Asset {
+	assets := make([]Asset, len(names))
+	for i, n := range names {
+		assets[i] = Asset{Name: n}
+	}
+	return assets
+}
+
+func TestClient_ReleasesByChannel(t *testing.T) {
+	rs := newReleaseServer(t, []Release{
+		{Version: "v1.1.0"},
+		{Version: "v1.3.0-rc.1", Prerelease: true},
+		{Version: "v1.2.0"},
+		{Version: "v2.0.0", Draft: true},
+		{Version: "nightly"},
+	}, nil)
+	c := rs.client()
+
+	latest, err := c.Latest(context.Background(), ChannelStable)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.2.0", latest.Version)
+
+	edge, err := c.Latest(context.Background(), ChannelEdge)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.3.0-rc.1", edge.Version)
+
+	newer, err := c.Newer(context.Background(), "v1.1.0", ChannelStable)
+	require.NoError(t, err)
+	require.Len(t, newer, 1)
+	assert.Equal(t, "v1.2.0", newer[0].Version)
+
+	found, err := c.Find(context.Background(), "1.1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1.0", found.Version)
+}
+
+func signedRelease(t *testing.T, binary []byte) (Release, map[string][]byte, string, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	name := BinaryAssetName("linux", "amd64")
+	sum := sha256.Sum256(binary)
+	checksums := []byte(fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), name))
+
+	assets := map[string][]byte{
+		name:           binary,
+		ChecksumsAsset: checksums,
+		SignatureAsset: ed25519.Sign(priv, checksums),
+	}
+	release := Release{Version: "v1.2.0", Assets: assetsNamed(name, ChecksumsAsset, SignatureAsset)}
+	return release, assets, base64.StdEncoding.EncodeToString(pub), priv
+}
+
+func TestClient_FetchBinary(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho cldctl\n")
+	release, assets, pubKey, _ := signedRelease(t, binary)
+	rs := newReleaseServer(t, []Release{release}, assets)
+	c := rs.client()
+
+	r, err := c.Latest(context.Background(), ChannelStable)
+	require.NoError(t, err)
+
+	got, err := c.FetchBinary(context.Background(), r, FetchOptions{AssetName: "cldctl-linux-amd64", PublicKey: pubKey})
+	require.NoError(t, err)
+	assert.Equal(t, binary, got)
+}
+
+func TestClient_FetchBinary_RejectsTampering(t *testing.T) {
+	binary := []byte("original")
+	release, assets, pubKey, _ := signedRelease(t, binary)
+	rs := newReleaseServer(t, []Release{release}, assets)
+	c := rs.client()
+	opts := FetchOptions{AssetName: "cldctl-linux-amd64", PublicKey: pubKey}
+
+	r, err := c.Latest(context.Background(), ChannelStable)
+	require.NoError(t, err)
+
+	// Binary swapped without updating checksums
+	rs.assets["cldctl-linux-amd64"] = []byte("malicious")
+	_, err = c.FetchBinary(context.Background(), r, opts)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum mismatch")
+
+	// Checksums rewritten to match, but not re-signed
+	sum := sha256.Sum256([]byte("malicious"))
+	rs.assets[ChecksumsAsset] = []byte(hex.EncodeToString(sum[:]) + "  cldctl-linux-amd64\n")
+	_, err = c.FetchBinary(context.Background(), r, opts)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "signature verification failed")
+
+	// Development builds have no key to verify with
+	_, err = c.FetchBinary(context.Background(), r, FetchOptions{AssetName: "cldctl-linux-amd64"})
+	assert.ErrorIs(t, err, ErrNoPublicKey)
+}
+
+func TestVerifySignature_Base64(t *testing.T) {
+	data := []byte("checksums")
+	_, _, pubKey, priv := signedRelease(t, nil)
+
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, data))
+	assert.NoError(t, VerifySignature(data, []byte(sig+"\n"), pubKey))
+}
+
+func TestInstall(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "cldctl")
+	require.NoError(t, os.WriteFile(exe, []byte("old"), 0o755))
+
+	link := filepath.Join(dir, "cldctl-link")
+	require.NoError(t, os.Symlink(exe, link))
+
+	require.NoError(t, Install(link, []byte("new")))
+
+	data, err := os.ReadFile(exe)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+
+	info, err := os.Stat(exe)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode().Perm()&0o100, "installed binary is executable")
+
+	_, err = os.Stat(exe + ".old")
+	assert.True(t, os.IsNotExist(err), "previous binary is cleaned up")
+}
@@ -0,0 +1,165 @@
+package selfupdate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxAssetSize bounds how much a single release asset download may read.
+const maxAssetSize = 512 << 20
+
+// ErrNoPublicKey is returned when a signature must be verified but the build
+// has no release public key (e.g. a development build).
+var ErrNoPublicKey = errors.New("this build has no release signing key; cannot verify release signatures")
+
+// Download fetches a release asset.
+func (c *Client) Download(ctx context.Context, asset *Asset) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/octet-stream")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", asset.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: server returned %d", asset.Name, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAssetSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", asset.Name, err)
+	}
+	if len(data) > maxAssetSize {
+		return nil, fmt.Errorf("failed to download %s: asset exceeds %d bytes", asset.Name, maxAssetSize)
+	}
+	return data, nil
+}
+
+// VerifySignature checks an ed25519 signature over data. The public key and
+// signature may be raw bytes or base64 encoded.
+func VerifySignature(data, signature []byte, publicKey string) error {
+	if publicKey == "" {
+		return ErrNoPublicKey
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid release public key")
+	}
+
+	sig := signature
+	if len(sig) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+		if err != nil || len(decoded) != ed25519.SignatureSize {
+			return fmt.Errorf("malformed signature")
+		}
+		sig = decoded
+	}
+
+	if !ed25519.Verify(ed25519.PublicKey(key), data, sig) {
+		return fmt.Errorf("signature verification failed")
+	}
+	return nil
+}
+
+// ParseChecksums parses a sha256sum-style checksums file into a map from file
+// name to hex digest.
+func ParseChecksums(data []byte) map[string]string {
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		// sha256sum marks binary-mode entries with a leading '*'
+		sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	return sums
+}
+
+// VerifyChecksum checks that data matches the digest recorded for name.
+func VerifyChecksum(checksums []byte, name string, data []byte) error {
+	want, ok := ParseChecksums(checksums)[name]
+	if !ok {
+		return fmt.Errorf("%s is not listed in %s", name, ChecksumsAsset)
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, want, got)
+	}
+	return nil
+}
+
+// FetchOptions configures FetchBinary.
+type FetchOptions struct {
+	// AssetName is the binary to download. Defaults to the current platform's.
+	AssetName string
+
+	// PublicKey is the base64 ed25519 key release checksums are signed with.
+	PublicKey string
+
+	// SkipSignature downloads without verifying the checksums signature. The
+	// binary's checksum is still verified.
+	SkipSignature bool
+}
+
+// FetchBinary downloads a release's binary for this platform and verifies it
+// against the signed checksums file.
+func (c *Client) FetchBinary(ctx context.Context, release *Release, opts FetchOptions) ([]byte, error) {
+	name := opts.AssetName
+	if name == "" {
+		name = CurrentBinaryAssetName()
+	}
+
+	binaryAsset := release.Asset(name)
+	if binaryAsset == nil {
+		return nil, fmt.Errorf("release %s has no binary for this platform (%s)", release.Version, name)
+	}
+	checksumsAsset := release.Asset(ChecksumsAsset)
+	if checksumsAsset == nil {
+		return nil, fmt.Errorf("release %s has no %s", release.Version, ChecksumsAsset)
+	}
+
+	checksums, err := c.Download(ctx, checksumsAsset)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.SkipSignature {
+		sigAsset := release.Asset(SignatureAsset)
+		if sigAsset == nil {
+			return nil, fmt.Errorf("release %s is not signed (no %s)", release.Version, SignatureAsset)
+		}
+		sig, err := c.Download(ctx, sigAsset)
+		if err != nil {
+			return nil, err
+		}
+		if err := VerifySignature(checksums, sig, opts.PublicKey); err != nil {
+			return nil, fmt.Errorf("release %s: %w", release.Version, err)
+		}
+	}
+
+	binary, err := c.Download(ctx, binaryAsset)
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifyChecksum(checksums, name, binary); err != nil {
+		return nil, err
+	}
+	return binary, nil
+}
@@ -0,0 +1,111 @@
+package selfupdate
+
+import (
+	"strconv"
+	"strings"
+)
+
+// version is a parsed semantic version.
+type version struct {
+	core       [3]int
+	prerelease string
+}
+
+// CompareVersions compares two semantic versions ("v1.2.3", "1.2.3",
+// "v1.3.0-rc.1"). It returns 1 if a is newer, -1 if b is newer, and 0 if they
+// are equal. A pre-release sorts before its release, and build metadata is
+// ignored. Unparseable versions (such as "dev") compare equal to anything, so
+// development builds are never reported as outdated.
+func CompareVersions(a, b string) int {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+	if !okA || !okB {
+		return 0
+	}
+	for i := range va.core {
+		if va.core[i] != vb.core[i] {
+			if va.core[i] > vb.core[i] {
+				return 1
+			}
+			return -1
+		}
+	}
+	switch {
+	case va.prerelease == vb.prerelease:
+		return 0
+	case va.prerelease == "":
+		return 1
+	case vb.prerelease == "":
+		return -1
+	default:
+		return comparePrerelease(va.prerelease, vb.prerelease)
+	}
+}
+
+// comparePrerelease compares pre-release versions by their dot-separated
+// identifiers (semver §11): numeric identifiers compare as numbers and sort
+// before alphanumeric ones, and a shorter list of otherwise equal
+// identifiers sorts first, so rc.9 < rc.10 and alpha < alpha.1.
+func comparePrerelease(a, b string) int {
+	idsA, idsB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(idsA) && i < len(idsB); i++ {
+		x, y := idsA[i], idsB[i]
+		nx, errX := strconv.ParseUint(x, 10, 64)
+		ny, errY := strconv.ParseUint(y, 10, 64)
+		switch {
+		case errX == nil && errY == nil:
+			if nx != ny {
+				if nx > ny {
+					return 1
+				}
+				return -1
+			}
+		case errX == nil:
+			return -1
+		case errY == nil:
+			return 1
+		case x != y:
+			if x > y {
+				return 1
+			}
+			return -1
+		}
+	}
+	switch {
+	case len(idsA) > len(idsB):
+		return 1
+	case len(idsA) < len(idsB):
+		return -1
+	}
+	return 0
+}
+
+// IsVersion reports whether v is a parseable release version.
+func IsVersion(v string) bool {
+	_, ok := parseVersion(v)
+	return ok
+}
+
+func parseVersion(v string) (version, bool) {
+	var parsed version
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		v = v[:i]
+	}
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		parsed.prerelease = v[i+1:]
+		v = v[:i]
+	}
+	fields := strings.Split(v, ".")
+	if len(fields) > 3 {
+		return parsed, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return parsed, false
+		}
+		parsed.core[i] = n
+	}
+	return parsed, true
+}
@@ -0,0 +1,84 @@
+package snapshot
+
+import (
+	"sort"
+
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+)
+
+// Kinds of node change reported by Diff.
+const (
+	NodeAdded   = "added"
+	NodeRemoved = "removed"
+	NodeChanged = "changed"
+)
+
+// NodeDiff is a node that was added, removed, or changed between two
+// snapshots.
+type NodeDiff struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Change string `json:"change"`
+	// Properties lists the inputs that changed, for changed nodes. A change
+	// to the node's dependencies is reported as the "depends_on" property.
+	Properties []PropertyDiff `json:"properties,omitempty"`
+}
+
+// PropertyDiff is a single changed value. Old is nil for values that were
+// added, and New is nil for values that were removed.
+type PropertyDiff struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// Diff compares the nodes of two snapshots, sorted by node ID.
+func Diff(before, after *Snapshot) []NodeDiff {
+	oldNodes := make(map[string]Node, len(before.Nodes))
+	for _, n := range before.Nodes {
+		oldNodes[n.ID] = n
+	}
+	newIDs := make(map[string]bool, len(after.Nodes))
+
+	diffs := []NodeDiff{}
+	p := planner.NewPlanner()
+	for _, n := range after.Nodes {
+		newIDs[n.ID] = true
+		prev, ok := oldNodes[n.ID]
+		if !ok {
+			diffs = append(diffs, NodeDiff{ID: n.ID, Type: n.Type, Change: NodeAdded})
+			continue
+		}
+
+		var props []PropertyDiff
+		for _, c := range p.CompareInputs(n.Inputs, prev.Inputs) {
+			props = append(props, PropertyDiff{Path: c.Path, Old: c.OldValue, New: c.NewValue})
+		}
+		if !equalStrings(prev.DependsOn, n.DependsOn) {
+			props = append(props, PropertyDiff{Path: "depends_on", Old: prev.DependsOn, New: n.DependsOn})
+		}
+		if len(props) > 0 {
+			diffs = append(diffs, NodeDiff{ID: n.ID, Type: n.Type, Change: NodeChanged, Properties: props})
+		}
+	}
+	for _, n := range before.Nodes {
+		if !newIDs[n.ID] {
+			diffs = append(diffs, NodeDiff{ID: n.ID, Type: n.Type, Change: NodeRemoved})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].ID < diffs[j].ID })
+	return diffs
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package snapshot
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	before := &Snapshot{Nodes: []Node{
+		{ID: "app/bucket/uploads", Type: "bucket"},
+		{ID: "app/database/main", Type: "database", Inputs: map[string]interface{}{"type": "postgres:^16"}},
+		{ID: "app/deployment/api", Type: "deployment", Inputs: map[string]interface{}{"image": "api:1"}, DependsOn: []string{"app/database/main"}},
+		{ID: "app/service/api", Type: "service", Inputs: map[string]interface{}{"port": "8080"}},
+	}}
+	after := &Snapshot{Nodes: []Node{
+		{ID: "app/database/cache", Type: "database", Inputs: map[string]interface{}{"type": "redis"}},
+		{ID: "app/database/main", Type: "database", Inputs: map[string]interface{}{"type": "postgres:^17"}},
+		{ID: "app/deployment/api", Type: "deployment", Inputs: map[string]interface{}{"image": "api:1"}, DependsOn: []string{"app/database/cache", "app/database/main"}},
+		{ID: "app/service/api", Type: "service", Inputs: map[string]interface{}{"port": "8080"}},
+	}}
+
+	diffs := Diff(before, after)
+
+	want := []string{
+		"app/bucket/uploads removed []",
+		"app/database/cache added []",
+		"app/database/main changed [{type postgres:^16 postgres:^17}]",
+		"app/deployment/api changed [{depends_on [app/database/main] [app/database/cache app/database/main]}]",
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("expected %d diffs, got %+v", len(want), diffs)
+	}
+	for i, d := range diffs {
+		if got := fmt.Sprintf("%s %s %v", d.ID, d.Change, d.Properties); got != want[i] {
+			t.Errorf("diff %d: expected %q, got %q", i, want[i], got)
+		}
+	}
+
+	if diffs := Diff(after, after); len(diffs) != 0 {
+		t.Errorf("expected no diffs between identical snapshots, got %+v", diffs)
+	}
+}
@@ -0,0 +1,214 @@
+// Package snapshot serializes dependency graphs and plans deterministically
+// so that they can be compared against golden files. Component and
+// datacenter repositories use it to add regression tests asserting that a
+// change does not alter the infrastructure graph.
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/graph"
+)
+
+// UpdateEnv is the environment variable that, when set to a non-empty
+// value, makes AssertGolden rewrite golden files instead of comparing them.
+const UpdateEnv = "CLDCTL_UPDATE_SNAPSHOTS"
+
+// Snapshot is the serialized form of a graph and the plan to create it.
+// Nodes are sorted by ID, dependency lists are sorted, and maps are encoded
+// with sorted keys, so the same graph always produces the same bytes.
+type Snapshot struct {
+	Environment string        `json:"environment"`
+	Datacenter  string        `json:"datacenter"`
+	Nodes       []Node        `json:"nodes"`
+	Plan        *planner.Plan `json:"plan,omitempty"`
+}
+
+// Node is the serialized form of a graph node. Outputs and execution state
+// are left out: they are only known once the graph is applied.
+type Node struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	Component string                 `json:"component"`
+	Name      string                 `json:"name"`
+	Instance  string                 `json:"instance,omitempty"`
+	Inputs    map[string]interface{} `json:"inputs,omitempty"`
+	DependsOn []string               `json:"depends_on,omitempty"`
+}
+
+// Options configures how a snapshot is taken.
+type Options struct {
+	// ComponentDirs maps component names to the directories they were
+	// loaded from. Paths under a component's directory in its node inputs
+	// (e.g. build contexts) are written relative to it, so snapshots taken
+	// from different checkouts match.
+	ComponentDirs map[string]string
+}
+
+// New takes a snapshot of g and, if not nil, the plan to apply it.
+func New(g *graph.Graph, plan *planner.Plan, opts Options) *Snapshot {
+	s := &Snapshot{
+		Environment: g.Environment,
+		Datacenter:  g.Datacenter,
+		Nodes:       make([]Node, 0, len(g.Nodes)),
+		Plan:        plan,
+	}
+
+	for _, n := range g.Nodes {
+		node := Node{
+			ID:        n.ID,
+			Type:      string(n.Type),
+			Component: n.Component,
+			Name:      n.Name,
+		}
+		if n.Instance != nil {
+			node.Instance = n.Instance.Name
+		}
+		if len(n.Inputs) > 0 {
+			dir := opts.ComponentDirs[n.Component]
+			node.Inputs = make(map[string]interface{}, len(n.Inputs))
+			for k, v := range n.Inputs {
+				node.Inputs[k] = relativize(v, dir)
+			}
+		}
+		if len(n.DependsOn) > 0 {
+			node.DependsOn = append([]string(nil), n.DependsOn...)
+			sort.Strings(node.DependsOn)
+		}
+		s.Nodes = append(s.Nodes, node)
+	}
+	sort.Slice(s.Nodes, func(i, j int) bool { return s.Nodes[i].ID < s.Nodes[j].ID })
+
+	return s
+}
+
+// Marshal encodes the snapshot as indented JSON with a trailing newline.
+func (s *Snapshot) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// relativize rewrites paths under dir in v as "./"-prefixed relative paths.
+func relativize(v interface{}, dir string) interface{} {
+	if dir == "" {
+		return v
+	}
+	switch val := v.(type) {
+	case string:
+		if val == dir {
+			return "."
+		}
+		if rel, ok := strings.CutPrefix(val, dir+string(filepath.Separator)); ok {
+			return "./" + filepath.ToSlash(rel)
+		}
+		return val
+	case []string:
+		out := make([]string, len(val))
+		for i, s := range val {
+			out[i], _ = relativize(s, dir).(string)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = relativize(item, dir)
+		}
+		return out
+	case map[string]string:
+		out := make(map[string]string, len(val))
+		for k, s := range val {
+			out[k], _ = relativize(s, dir).(string)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = relativize(item, dir)
+		}
+		return out
+	}
+	return v
+}
+
+// MismatchError is returned by Compare when a snapshot differs from its
+// golden file.
+type MismatchError struct {
+	Golden string
+	// Line is the first line (1-based) that differs.
+	Line int
+	Want string
+	Got  string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("snapshot does not match %s at line %d:\n  - %s\n  + %s", e.Golden, e.Line, e.Want, e.Got)
+}
+
+// Compare checks got against the golden file, returning a *MismatchError
+// that points at the first differing line if they differ.
+func Compare(golden string, got []byte) error {
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		return fmt.Errorf("failed to read golden file: %w", err)
+	}
+	if bytes.Equal(want, got) {
+		return nil
+	}
+
+	wantLines := strings.Split(string(want), "\n")
+	gotLines := strings.Split(string(got), "\n")
+	line := func(lines []string, i int) string {
+		if i < len(lines) {
+			return lines[i]
+		}
+		return "(end of file)"
+	}
+	i := 0
+	for i < len(wantLines) && i < len(gotLines) && wantLines[i] == gotLines[i] {
+		i++
+	}
+	return &MismatchError{Golden: golden, Line: i + 1, Want: line(wantLines, i), Got: line(gotLines, i)}
+}
+
+// Write saves got as the golden file, creating its directory if needed.
+func Write(golden string, got []byte) error {
+	if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+		return fmt.Errorf("failed to create golden file directory: %w", err)
+	}
+	if err := os.WriteFile(golden, got, 0644); err != nil {
+		return fmt.Errorf("failed to write golden file: %w", err)
+	}
+	return nil
+}
+
+// TB is the subset of testing.TB used by AssertGolden.
+type TB interface {
+	Helper()
+	Fatalf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// AssertGolden fails the test if got differs from the golden file. When
+// UpdateEnv is set, the golden file is rewritten instead.
+func AssertGolden(t TB, golden string, got []byte) {
+	t.Helper()
+	if os.Getenv(UpdateEnv) != "" {
+		if err := Write(golden, got); err != nil {
+			t.Fatalf("%v", err)
+		}
+		return
+	}
+	if err := Compare(golden, got); err != nil {
+		t.Errorf("%v (set %s=1 to accept changes)", err, UpdateEnv)
+	}
+}
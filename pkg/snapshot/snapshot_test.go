@@ -0,0 +1,92 @@
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/engine"
+	"github.com/davidthor/cldctl/pkg/engine/planner"
+	"github.com/davidthor/cldctl/pkg/schema/component"
+)
+
+func loadSnapshot(t *testing.T) []byte {
+	t.Helper()
+	comp, err := component.NewLoader().LoadFromBytes([]byte(`
+builds:
+  api:
+    context: ./api
+databases:
+  main:
+    type: postgres:^16
+deployments:
+  api:
+    image: ${{ builds.api.image }}
+    environment:
+      DATABASE_URL: ${{ databases.main.url }}
+services:
+  api:
+    deployment: api
+    port: 8080
+routes:
+  main:
+    type: http
+    service: api
+`), "/src/app/cld.yml")
+	if err != nil {
+		t.Fatalf("failed to load component: %v", err)
+	}
+
+	g, err := engine.BuildGraph("staging", "local", nil, map[string]component.Component{"app": comp})
+	if err != nil {
+		t.Fatalf("BuildGraph() error: %v", err)
+	}
+	plan, err := planner.NewPlanner().Plan(g, nil)
+	if err != nil {
+		t.Fatalf("Plan() error: %v", err)
+	}
+
+	data, err := New(g, plan, Options{ComponentDirs: map[string]string{"app": "/src/app"}}).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	return data
+}
+
+func TestSnapshot_Golden(t *testing.T) {
+	got := loadSnapshot(t)
+	if bytes.Contains(got, []byte("/src/app")) {
+		t.Errorf("expected component paths to be relative, got:\n%s", got)
+	}
+	AssertGolden(t, filepath.Join("testdata", "app.golden.json"), got)
+}
+
+func TestSnapshot_StableAcrossRuns(t *testing.T) {
+	first := loadSnapshot(t)
+	for i := 0; i < 20; i++ {
+		if next := loadSnapshot(t); !bytes.Equal(first, next) {
+			t.Fatalf("snapshot changed between runs:\n%s\n%s", first, next)
+		}
+	}
+}
+
+func TestCompare_Mismatch(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "golden.json")
+	if err := Write(golden, []byte("{\n  \"a\": 1\n}\n")); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+
+	if err := Compare(golden, []byte("{\n  \"a\": 1\n}\n")); err != nil {
+		t.Errorf("expected identical snapshot to match, got %v", err)
+	}
+
+	err := Compare(golden, []byte("{\n  \"a\": 2\n}\n"))
+	var mismatch *MismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected MismatchError, got %v", err)
+	}
+	if mismatch.Line != 2 || mismatch.Want != `  "a": 1` || mismatch.Got != `  "a": 2` {
+		t.Errorf("unexpected mismatch: %+v", mismatch)
+	}
+}
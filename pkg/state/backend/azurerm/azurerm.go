@@ -17,6 +17,7 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/lease"
 	"github.com/davidthor/cldctl/pkg/state/backend"
 	"github.com/google/uuid"
 )
@@ -145,6 +146,14 @@ func (b *Backend) Delete(ctx context.Context, statePath string) error {
 	blobPath := b.fullPath(statePath)
 
 	_, err := b.client.DeleteBlob(ctx, b.containerName, blobPath, nil)
+	if bloberror.HasCode(err, bloberror.LeaseIDMissing) {
+		// Only lock blobs are leased. Deleting one breaks the lock, as
+		// force-unlock does, so the lease is broken first.
+		err = b.breakLease(ctx, blobPath)
+		if err == nil {
+			_, err = b.client.DeleteBlob(ctx, b.containerName, blobPath, nil)
+		}
+	}
 	if err != nil {
 		// Ignore not found errors for idempotency
 		if bloberror.HasCode(err, bloberror.BlobNotFound) {
@@ -156,6 +165,17 @@ func (b *Backend) Delete(ctx context.Context, statePath string) error {
 	return nil
 }
 
+// breakLease ends the lease on a blob immediately.
+func (b *Backend) breakLease(ctx context.Context, blobPath string) error {
+	blobClient := b.client.ServiceClient().NewContainerClient(b.containerName).NewBlobClient(blobPath)
+	leaseClient, err := lease.NewBlobClient(blobClient, nil)
+	if err != nil {
+		return err
+	}
+	_, err = leaseClient.BreakLease(ctx, &lease.BlobBreakOptions{BreakPeriod: toPtr(int32(0))})
+	return err
+}
+
 func (b *Backend) List(ctx context.Context, prefix string) ([]string, error) {
 	fullPrefix := b.fullPath(prefix)
 
@@ -203,37 +223,78 @@ func (b *Backend) Exists(ctx context.Context, statePath string) (bool, error) {
 	return true, nil
 }
 
-func (b *Backend) Lock(ctx context.Context, statePath string, info backend.LockInfo) (backend.Lock, error) {
-	lockPath := b.fullPath(statePath + ".lock")
+// leaseDuration is the duration, in seconds, of the lease that holds a lock.
+// It is the longest Azure allows short of an infinite lease, which would
+// never expire if its holder died. KeepAlive renews it well within that.
+const leaseDuration = 60
 
-	// Check for existing lock
-	existingLock, err := b.readLock(ctx, lockPath)
-	if err == nil {
-		// Check if lock is stale (older than 1 hour)
-		if time.Since(existingLock.Created) < time.Hour {
-			return nil, &backend.LockError{
-				Info: existingLock,
-				Err:  backend.ErrLocked,
-			}
-		}
-	}
+// Lock holds the lock as a lease on the lock blob: Azure grants a blob's
+// lease to only one client at a time, so of two processes taking the lock at
+// once only one succeeds. The lease expires if its holder stops renewing it,
+// which is what makes a lock stale here.
+func (b *Backend) Lock(ctx context.Context, statePath string, info backend.LockInfo) (backend.Lock, error) {
+	lockPath := b.fullPath(backend.LockFile(statePath))
 
-	// Create lock
+	// Create lock. The ID doubles as the lease ID, which must be a GUID.
 	info.ID = uuid.New().String()
 	info.Path = statePath
 	info.Created = time.Now()
+	info.Expires = info.Created.Add(backend.LockTTL)
 
 	lockData, err := json.Marshal(info)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal lock info: %w", err)
 	}
 
+	blobClient := b.client.ServiceClient().NewContainerClient(b.containerName).NewBlobClient(lockPath)
+	leaseClient, err := lease.NewBlobClient(blobClient, &lease.BlobClientOptions{LeaseID: &info.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lease client: %w", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		// The lease needs a blob to be taken on; a blob left behind by
+		// another holder is reused.
+		_, err = b.client.UploadBuffer(ctx, b.containerName, lockPath, lockData, &azblob.UploadBufferOptions{
+			HTTPHeaders: &blob.HTTPHeaders{
+				BlobContentType: toPtr("application/json"),
+			},
+			AccessConditions: &blob.AccessConditions{
+				ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfNoneMatch: toPtr(azcore.ETagAny)},
+			},
+		})
+		if err != nil && !bloberror.HasCode(err, bloberror.BlobAlreadyExists, bloberror.ConditionNotMet, bloberror.LeaseIDMissing) {
+			return nil, fmt.Errorf("failed to create lock: %w", err)
+		}
+
+		_, err = leaseClient.AcquireLease(ctx, leaseDuration, nil)
+		if err == nil {
+			break
+		}
+		if bloberror.HasCode(err, bloberror.BlobNotFound) && attempt == 0 {
+			continue // released in the meantime
+		}
+		if bloberror.HasCode(err, bloberror.LeaseAlreadyPresent, bloberror.LeaseIsBreakingAndCannotBeAcquired) {
+			existing, _ := b.readLock(ctx, lockPath)
+			return nil, &backend.LockError{
+				Info: existing,
+				Err:  backend.ErrLocked,
+			}
+		}
+		return nil, fmt.Errorf("failed to acquire lock lease: %w", err)
+	}
+
+	// Record this holder in the blob, which may still describe a previous one.
 	_, err = b.client.UploadBuffer(ctx, b.containerName, lockPath, lockData, &azblob.UploadBufferOptions{
 		HTTPHeaders: &blob.HTTPHeaders{
 			BlobContentType: toPtr("application/json"),
 		},
+		AccessConditions: &blob.AccessConditions{
+			LeaseAccessConditions: &blob.LeaseAccessConditions{LeaseID: &info.ID},
+		},
 	})
 	if err != nil {
+		_, _ = leaseClient.ReleaseLease(ctx, nil)
 		return nil, fmt.Errorf("failed to create lock: %w", err)
 	}
 
@@ -241,6 +302,7 @@ func (b *Backend) Lock(ctx context.Context, statePath string, info backend.LockI
 		backend: b,
 		path:    lockPath,
 		info:    info,
+		lease:   leaseClient,
 	}, nil
 }
 
@@ -271,20 +333,38 @@ type azureLock struct {
 	backend *Backend
 	path    string
 	info    backend.LockInfo
+	lease   *lease.BlobClient
 }
 
 func (l *azureLock) ID() string {
 	return l.info.ID
 }
 
+// Unlock deletes the lock blob, which ends the lease, unless the lease has
+// since expired and been taken by another process.
 func (l *azureLock) Unlock(ctx context.Context) error {
-	_, err := l.backend.client.DeleteBlob(ctx, l.backend.containerName, l.path, nil)
-	if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+	_, err := l.backend.client.DeleteBlob(ctx, l.backend.containerName, l.path, &blob.DeleteOptions{
+		AccessConditions: &blob.AccessConditions{
+			LeaseAccessConditions: &blob.LeaseAccessConditions{LeaseID: &l.info.ID},
+		},
+	})
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.LeaseIDMismatchWithBlobOperation, bloberror.LeaseNotPresentWithBlobOperation, bloberror.LeaseLost) {
 		return fmt.Errorf("failed to release lock: %w", err)
 	}
 	return nil
 }
 
+func (l *azureLock) Renew(ctx context.Context) error {
+	_, err := l.lease.RenewLease(ctx, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.LeaseIDMismatchWithLeaseOperation, bloberror.LeaseNotPresentWithLeaseOperation, bloberror.LeaseIsBrokenAndCannotBeRenewed, bloberror.LeaseLost) {
+		return backend.ErrLockLost
+	}
+	if err != nil {
+		return fmt.Errorf("failed to renew lock lease: %w", err)
+	}
+	return nil
+}
+
 func (l *azureLock) Info() backend.LockInfo {
 	return l.info
 }
@@ -14,6 +14,20 @@ var ErrNotFound = errors.New("state not found")
 // ErrLocked is returned when state is already locked.
 var ErrLocked = errors.New("state is locked")
 
+// ErrLockLost is returned when renewing a lock that has since been broken.
+var ErrLockLost = errors.New("lock is no longer held")
+
+// LockTTL is how long a lock lasts unless its holder renews it. A lock that
+// has expired, because its holder stopped renewing it, is stale, and the
+// next Lock takes it over.
+const LockTTL = 2 * time.Minute
+
+// LockRenewInterval is how often KeepAlive renews a lock.
+const LockRenewInterval = 30 * time.Second
+
+// legacyLockTTL is how long a lock recorded without an expiry lasts.
+const legacyLockTTL = time.Hour
+
 // Backend defines the interface for state storage backends.
 type Backend interface {
 	// Type returns the backend type identifier (e.g., "s3", "local", "gcs")
@@ -38,8 +52,9 @@ type Backend interface {
 	// Exists checks if a state file exists.
 	Exists(ctx context.Context, path string) (bool, error)
 
-	// Lock acquires a lock for the given path.
-	// Blocks until lock is acquired or context is cancelled.
+	// Lock acquires a lock for the given path, or returns a *LockError if
+	// another process holds it. Taking the lock is atomic, so two processes
+	// can never both hold it; a stale lock is taken over.
 	Lock(ctx context.Context, path string, info LockInfo) (Lock, error)
 }
 
@@ -51,6 +66,10 @@ type Lock interface {
 	// Unlock releases the lock.
 	Unlock(ctx context.Context) error
 
+	// Renew extends the lock's expiry by LockTTL. It returns ErrLockLost if
+	// the lock has been broken since it was taken.
+	Renew(ctx context.Context) error
+
 	// Info returns lock metadata.
 	Info() LockInfo
 }
@@ -59,12 +78,58 @@ type Lock interface {
 type LockInfo struct {
 	ID        string    `json:"id"`
 	Path      string    `json:"path"`
-	Who       string    `json:"who"`       // User or CI job identity
-	Operation string    `json:"operation"` // What operation holds the lock
+	Who       string    `json:"who"`            // User or CI job identity
+	Operation string    `json:"operation"`      // What operation holds the lock
+	Host      string    `json:"host,omitempty"` // Host of the process holding the lock
+	PID       int       `json:"pid,omitempty"`  // ID of the process holding the lock
 	Created   time.Time `json:"created"`
 	Expires   time.Time `json:"expires,omitempty"` // Optional expiration
 }
 
+// Stale reports whether a lock has expired, so that it may be taken over.
+// Locks recorded without an expiry go stale an hour after they were taken.
+func (i LockInfo) Stale(now time.Time) bool {
+	if i.Expires.IsZero() {
+		return now.Sub(i.Created) >= legacyLockTTL
+	}
+	return now.After(i.Expires)
+}
+
+// KeepAlive renews lock every LockRenewInterval until the returned function
+// is called, so that the lock doesn't expire while its holder is still
+// working. A renewal that fails is retried at the next interval, unless the
+// lock was lost.
+func KeepAlive(lock Lock) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(LockRenewInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := lock.Renew(ctx); errors.Is(err, ErrLockLost) {
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// LockFile returns the path of the file that records the lock on path.
+// Backends store it next to the state it guards, so a lock can be inspected
+// or broken with Read and Delete.
+func LockFile(path string) string {
+	return path + ".lock"
+}
+
 // LockError is returned when locking fails because state is already locked.
 type LockError struct {
 	Info LockInfo
@@ -8,13 +8,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/davidthor/cldctl/pkg/state/backend"
 	"github.com/google/uuid"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
@@ -168,63 +171,89 @@ func (b *Backend) Exists(ctx context.Context, statePath string) (bool, error) {
 	return true, nil
 }
 
+// Lock creates the lock object with a DoesNotExist precondition, so that of
+// two processes taking the lock at once only one succeeds. A stale lock is
+// taken over with a precondition on its generation, which fails if it
+// changed since it was read.
 func (b *Backend) Lock(ctx context.Context, statePath string, info backend.LockInfo) (backend.Lock, error) {
-	lockPath := b.fullPath(statePath + ".lock")
-
-	// Check for existing lock
-	existingLock, err := b.readLock(ctx, lockPath)
-	if err == nil {
-		// Check if lock is stale (older than 1 hour)
-		if time.Since(existingLock.Created) < time.Hour {
-			return nil, &backend.LockError{
-				Info: existingLock,
-				Err:  backend.ErrLocked,
-			}
-		}
-	}
+	lockPath := b.fullPath(backend.LockFile(statePath))
 
 	// Create lock
 	info.ID = uuid.New().String()
 	info.Path = statePath
 	info.Created = time.Now()
+	info.Expires = info.Created.Add(backend.LockTTL)
 
 	lockData, err := json.Marshal(info)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal lock info: %w", err)
 	}
 
-	writer := b.client.Bucket(b.bucket).Object(lockPath).NewWriter(ctx)
+	generation, err := b.writeLock(ctx, lockPath, lockData, storage.Conditions{DoesNotExist: true})
+	if preconditionFailed(err) {
+		existing, existingGeneration, readErr := b.readLock(ctx, lockPath)
+		if readErr != nil || !existing.Stale(time.Now()) {
+			return nil, &backend.LockError{
+				Info: existing,
+				Err:  backend.ErrLocked,
+			}
+		}
+		generation, err = b.writeLock(ctx, lockPath, lockData, storage.Conditions{GenerationMatch: existingGeneration})
+		if preconditionFailed(err) {
+			existing, _, _ = b.readLock(ctx, lockPath)
+			return nil, &backend.LockError{
+				Info: existing,
+				Err:  backend.ErrLocked,
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lock: %w", err)
+	}
+
+	return &gcsLock{
+		backend:    b,
+		path:       lockPath,
+		info:       info,
+		generation: generation,
+	}, nil
+}
+
+// writeLock writes a lock object under conds, returning its generation.
+func (b *Backend) writeLock(ctx context.Context, lockPath string, data []byte, conds storage.Conditions) (int64, error) {
+	writer := b.client.Bucket(b.bucket).Object(lockPath).If(conds).NewWriter(ctx)
 	writer.ContentType = "application/json"
 
-	if _, err := writer.Write(lockData); err != nil {
+	if _, err := writer.Write(data); err != nil {
 		writer.Close()
-		return nil, fmt.Errorf("failed to create lock: %w", err)
+		return 0, err
 	}
-
 	if err := writer.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close lock writer: %w", err)
+		return 0, err
 	}
-
-	return &gcsLock{
-		backend: b,
-		path:    lockPath,
-		info:    info,
-	}, nil
+	return writer.Attrs().Generation, nil
 }
 
-func (b *Backend) readLock(ctx context.Context, lockPath string) (backend.LockInfo, error) {
+func (b *Backend) readLock(ctx context.Context, lockPath string) (backend.LockInfo, int64, error) {
 	reader, err := b.client.Bucket(b.bucket).Object(lockPath).NewReader(ctx)
 	if err != nil {
-		return backend.LockInfo{}, err
+		return backend.LockInfo{}, 0, err
 	}
 	defer reader.Close()
 
 	var info backend.LockInfo
 	if err := json.NewDecoder(reader).Decode(&info); err != nil {
-		return backend.LockInfo{}, err
+		return backend.LockInfo{}, 0, err
 	}
 
-	return info, nil
+	return info, reader.Attrs.Generation, nil
+}
+
+// preconditionFailed reports whether a conditional request failed because
+// the object exists, or has changed.
+func preconditionFailed(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed
 }
 
 func (b *Backend) fullPath(statePath string) string {
@@ -243,22 +272,55 @@ func (b *Backend) Close() error {
 type gcsLock struct {
 	backend *Backend
 	path    string
-	info    backend.LockInfo
+
+	mu         sync.Mutex
+	info       backend.LockInfo
+	generation int64 // of the lock object as this process last wrote it
 }
 
 func (l *gcsLock) ID() string {
 	return l.info.ID
 }
 
+// Unlock deletes the lock object, unless the lock has since been broken and
+// taken by another process.
 func (l *gcsLock) Unlock(ctx context.Context) error {
-	err := l.backend.client.Bucket(l.backend.bucket).Object(l.path).Delete(ctx)
-	if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err := l.backend.client.Bucket(l.backend.bucket).Object(l.path).
+		If(storage.Conditions{GenerationMatch: l.generation}).Delete(ctx)
+	if err != nil && !errors.Is(err, storage.ErrObjectNotExist) && !preconditionFailed(err) {
 		return fmt.Errorf("failed to release lock: %w", err)
 	}
 	return nil
 }
 
+func (l *gcsLock) Renew(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	info := l.info
+	info.Expires = time.Now().Add(backend.LockTTL)
+	data, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lock info: %w", err)
+	}
+	generation, err := l.backend.writeLock(ctx, l.path, data, storage.Conditions{GenerationMatch: l.generation})
+	if preconditionFailed(err) {
+		return backend.ErrLockLost
+	}
+	if err != nil {
+		return fmt.Errorf("failed to renew lock: %w", err)
+	}
+	l.info = info
+	l.generation = generation
+	return nil
+}
+
 func (l *gcsLock) Info() backend.LockInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	return l.info
 }
 
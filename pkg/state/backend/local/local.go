@@ -2,10 +2,13 @@
 package local
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sync"
@@ -155,11 +158,14 @@ func (b *Backend) Exists(ctx context.Context, path string) (bool, error) {
 	return true, nil
 }
 
+// Lock creates the lock file with O_EXCL, so that of two processes taking
+// the lock at once only one succeeds. A stale lock file is first moved aside,
+// and put back if it turns out to have been replaced by a live lock.
 func (b *Backend) Lock(ctx context.Context, path string, info backend.LockInfo) (backend.Lock, error) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	lockPath := path + ".lock"
+	lockPath := backend.LockFile(path)
 
 	// Check if already locked
 	if existing, ok := b.locks[lockPath]; ok {
@@ -169,39 +175,48 @@ func (b *Backend) Lock(ctx context.Context, path string, info backend.LockInfo)
 		}
 	}
 
-	// Check lock file on disk
-	lockFilePath := b.fullPath(lockPath)
-	if data, err := os.ReadFile(lockFilePath); err == nil {
-		var existingInfo backend.LockInfo
-		if err := json.Unmarshal(data, &existingInfo); err == nil {
-			// Check if lock is stale (older than 1 hour)
-			if time.Since(existingInfo.Created) < time.Hour {
-				return nil, &backend.LockError{
-					Info: existingInfo,
-					Err:  backend.ErrLocked,
-				}
-			}
-		}
-	}
-
 	// Create lock
 	info.ID = uuid.New().String()
 	info.Path = path
 	info.Created = time.Now()
+	info.Expires = info.Created.Add(backend.LockTTL)
 
-	// Write lock file
 	lockData, err := json.Marshal(info)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal lock info: %w", err)
 	}
 
+	lockFilePath := b.fullPath(lockPath)
 	dir := filepath.Dir(lockFilePath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create lock directory: %w", err)
 	}
 
-	if err := os.WriteFile(lockFilePath, lockData, 0644); err != nil {
-		return nil, fmt.Errorf("failed to write lock file: %w", err)
+	for tookOver := false; ; tookOver = true {
+		err := createLockFile(lockFilePath, lockData)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to write lock file: %w", err)
+		}
+
+		existing, err := readLockFile(lockFilePath)
+		if errors.Is(err, fs.ErrNotExist) && !tookOver {
+			continue // released in the meantime
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lock file: %w", err)
+		}
+		if tookOver || !existing.Stale(time.Now()) {
+			return nil, &backend.LockError{
+				Info: existing,
+				Err:  backend.ErrLocked,
+			}
+		}
+		if err := breakStaleLock(lockFilePath, existing); err != nil {
+			return nil, err
+		}
 	}
 
 	lock := &localLock{
@@ -215,6 +230,65 @@ func (b *Backend) Lock(ctx context.Context, path string, info backend.LockInfo)
 	return lock, nil
 }
 
+// createLockFile creates the lock file, failing with fs.ErrExist if it
+// already exists.
+func createLockFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// readLockFile reads the lock recorded in a lock file. A lock file whose
+// holder hasn't finished writing it yet, or died before doing so, has no
+// valid contents; it is dated by its modification time.
+func readLockFile(path string) (backend.LockInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return backend.LockInfo{}, err
+	}
+	var info backend.LockInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		stat, statErr := os.Stat(path)
+		if statErr != nil {
+			return backend.LockInfo{}, statErr
+		}
+		info = backend.LockInfo{Created: stat.ModTime(), Expires: stat.ModTime().Add(backend.LockTTL)}
+	}
+	return info, nil
+}
+
+// breakStaleLock removes the stale lock file recording stale. The file is
+// renamed first, which only one process can do, and checked: if another
+// process has meanwhile replaced the stale lock with its own, it is put back.
+func breakStaleLock(path string, stale backend.LockInfo) error {
+	aside := filepath.Join(filepath.Dir(path), ".cldctl-stale-lock-"+uuid.New().String())
+	if err := os.Rename(path, aside); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to break stale lock: %w", err)
+	}
+	defer os.Remove(aside)
+
+	moved, err := readLockFile(aside)
+	if err == nil && moved.ID != stale.ID {
+		// Link fails if yet another lock file has been created since, in
+		// which case that one stands.
+		_ = os.Link(aside, path)
+	}
+	return nil
+}
+
 func (b *Backend) fullPath(path string) string {
 	return filepath.Join(b.basePath, path)
 }
@@ -231,12 +305,18 @@ func (l *localLock) ID() string {
 	return l.info.ID
 }
 
+// Unlock removes the lock file, unless the lock has since been broken and
+// taken by another process.
 func (l *localLock) Unlock(ctx context.Context) error {
 	l.backend.mu.Lock()
 	defer l.backend.mu.Unlock()
 
 	delete(l.backend.locks, l.path)
 
+	current, err := readLockFile(l.filePath)
+	if errors.Is(err, fs.ErrNotExist) || err == nil && current.ID != l.info.ID {
+		return nil
+	}
 	if err := os.Remove(l.filePath); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to remove lock file: %w", err)
 	}
@@ -244,6 +324,33 @@ func (l *localLock) Unlock(ctx context.Context) error {
 	return nil
 }
 
+func (l *localLock) Renew(ctx context.Context) error {
+	l.backend.mu.Lock()
+	defer l.backend.mu.Unlock()
+
+	current, err := readLockFile(l.filePath)
+	if errors.Is(err, fs.ErrNotExist) || err == nil && current.ID != l.info.ID {
+		return backend.ErrLockLost
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read lock file: %w", err)
+	}
+
+	info := l.info
+	info.Expires = time.Now().Add(backend.LockTTL)
+	data, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lock info: %w", err)
+	}
+	if err := l.backend.Write(ctx, l.path, bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("failed to renew lock: %w", err)
+	}
+	l.info = info
+	return nil
+}
+
 func (l *localLock) Info() backend.LockInfo {
+	l.backend.mu.RLock()
+	defer l.backend.mu.RUnlock()
 	return l.info
 }
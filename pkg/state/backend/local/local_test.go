@@ -3,10 +3,14 @@ package local
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/davidthor/cldctl/pkg/state/backend"
 )
@@ -226,6 +230,85 @@ func TestBackend_LockConflict(t *testing.T) {
 	}
 }
 
+func TestBackend_LockRace(t *testing.T) {
+	tmpDir := t.TempDir()
+	ctx := context.Background()
+
+	// Separate backends on one directory stand in for separate processes,
+	// which don't share the in-process lock table.
+	const racers = 8
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		held   []backend.Lock
+		failed int
+	)
+	for i := 0; i < racers; i++ {
+		b, _ := NewBackend(map[string]string{"path": tmpDir})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lock, err := b.Lock(ctx, "test/state", backend.LockInfo{Who: "test-user"})
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if !errors.Is(err, backend.ErrLocked) {
+					t.Errorf("unexpected error: %v", err)
+				}
+				failed++
+				return
+			}
+			held = append(held, lock)
+		}()
+	}
+	wg.Wait()
+
+	if len(held) != 1 || failed != racers-1 {
+		t.Fatalf("expected exactly one lock to be taken, got %d (%d refused)", len(held), failed)
+	}
+}
+
+func TestBackend_LockStaleTakeOver(t *testing.T) {
+	tmpDir := t.TempDir()
+	ctx := context.Background()
+	testPath := "test/state"
+
+	b1, _ := NewBackend(map[string]string{"path": tmpDir})
+	b2, _ := NewBackend(map[string]string{"path": tmpDir})
+
+	first, err := b1.Lock(ctx, testPath, backend.LockInfo{Who: "first"})
+	if err != nil {
+		t.Fatalf("first lock failed: %v", err)
+	}
+
+	if _, err := b2.Lock(ctx, testPath, backend.LockInfo{Who: "second"}); !errors.Is(err, backend.ErrLocked) {
+		t.Fatalf("expected a live lock to be refused, got %v", err)
+	}
+
+	// Let the first lock lapse without renewal.
+	info := first.Info()
+	info.Expires = time.Now().Add(-time.Second)
+	data, _ := json.Marshal(info)
+	if err := os.WriteFile(filepath.Join(tmpDir, testPath+".lock"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := b2.Lock(ctx, testPath, backend.LockInfo{Who: "second"})
+	if err != nil {
+		t.Fatalf("expected a stale lock to be taken over, got %v", err)
+	}
+
+	if err := first.Renew(ctx); !errors.Is(err, backend.ErrLockLost) {
+		t.Errorf("expected renewing a broken lock to fail with ErrLockLost, got %v", err)
+	}
+	if err := first.Unlock(ctx); err != nil {
+		t.Fatalf("unlock failed: %v", err)
+	}
+	if err := second.Renew(ctx); err != nil {
+		t.Errorf("expected the new lock to survive the old holder's unlock, got %v", err)
+	}
+}
+
 func TestBackend_AtomicWrite(t *testing.T) {
 	tmpDir := t.TempDir()
 	b, _ := NewBackend(map[string]string{"path": tmpDir})
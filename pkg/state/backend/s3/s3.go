@@ -8,11 +8,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -194,37 +197,42 @@ func (b *Backend) Exists(ctx context.Context, statePath string) (bool, error) {
 	return true, nil
 }
 
+// Lock creates the lock object with If-None-Match: *, so that of two
+// processes taking the lock at once only one succeeds. A stale lock is taken
+// over with If-Match on its ETag, which fails if it changed since it was
+// read.
 func (b *Backend) Lock(ctx context.Context, statePath string, info backend.LockInfo) (backend.Lock, error) {
-	lockKey := b.fullPath(statePath + ".lock")
-
-	// Check for existing lock
-	existingLock, err := b.readLock(ctx, lockKey)
-	if err == nil {
-		// Check if lock is stale (older than 1 hour)
-		if time.Since(existingLock.Created) < time.Hour {
-			return nil, &backend.LockError{
-				Info: existingLock,
-				Err:  backend.ErrLocked,
-			}
-		}
-	}
+	lockKey := b.fullPath(backend.LockFile(statePath))
 
 	// Create lock
 	info.ID = uuid.New().String()
 	info.Path = statePath
 	info.Created = time.Now()
+	info.Expires = info.Created.Add(backend.LockTTL)
 
 	lockData, err := json.Marshal(info)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal lock info: %w", err)
 	}
 
-	_, err = b.client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket:      &b.bucket,
-		Key:         &lockKey,
-		Body:        bytes.NewReader(lockData),
-		ContentType: aws.String("application/json"),
-	})
+	etag, err := b.putLock(ctx, lockKey, lockData, &s3.PutObjectInput{IfNoneMatch: aws.String("*")})
+	if preconditionFailed(err) {
+		existing, existingETag, readErr := b.readLock(ctx, lockKey)
+		if readErr != nil || !existing.Stale(time.Now()) {
+			return nil, &backend.LockError{
+				Info: existing,
+				Err:  backend.ErrLocked,
+			}
+		}
+		etag, err = b.putLock(ctx, lockKey, lockData, &s3.PutObjectInput{IfMatch: &existingETag})
+		if preconditionFailed(err) {
+			existing, _, _ = b.readLock(ctx, lockKey)
+			return nil, &backend.LockError{
+				Info: existing,
+				Err:  backend.ErrLocked,
+			}
+		}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create lock: %w", err)
 	}
@@ -233,25 +241,51 @@ func (b *Backend) Lock(ctx context.Context, statePath string, info backend.LockI
 		backend: b,
 		key:     lockKey,
 		info:    info,
+		etag:    etag,
 	}, nil
 }
 
-func (b *Backend) readLock(ctx context.Context, key string) (backend.LockInfo, error) {
+// putLock writes a lock object under the conditions set in input, returning
+// its ETag.
+func (b *Backend) putLock(ctx context.Context, key string, data []byte, input *s3.PutObjectInput) (string, error) {
+	input.Bucket = &b.bucket
+	input.Key = &key
+	input.Body = bytes.NewReader(data)
+	input.ContentType = aws.String("application/json")
+	output, err := b.client.PutObject(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(output.ETag), nil
+}
+
+func (b *Backend) readLock(ctx context.Context, key string) (backend.LockInfo, string, error) {
 	output, err := b.client.GetObject(ctx, &s3.GetObjectInput{
 		Bucket: &b.bucket,
 		Key:    &key,
 	})
 	if err != nil {
-		return backend.LockInfo{}, err
+		return backend.LockInfo{}, "", err
 	}
 	defer output.Body.Close()
 
 	var info backend.LockInfo
 	if err := json.NewDecoder(output.Body).Decode(&info); err != nil {
-		return backend.LockInfo{}, err
+		return backend.LockInfo{}, "", err
 	}
 
-	return info, nil
+	return info, aws.ToString(output.ETag), nil
+}
+
+// preconditionFailed reports whether a conditional request failed because
+// the object exists, or has changed.
+func preconditionFailed(err error) bool {
+	var respErr *awshttp.ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+	// 409 is returned when a conflicting conditional write is in progress.
+	return respErr.HTTPStatusCode() == http.StatusPreconditionFailed || respErr.HTTPStatusCode() == http.StatusConflict
 }
 
 func (b *Backend) fullPath(statePath string) string {
@@ -265,24 +299,57 @@ func (b *Backend) fullPath(statePath string) string {
 type s3Lock struct {
 	backend *Backend
 	key     string
-	info    backend.LockInfo
+
+	mu   sync.Mutex
+	info backend.LockInfo
+	etag string // of the lock object as this process last wrote it
 }
 
 func (l *s3Lock) ID() string {
 	return l.info.ID
 }
 
+// Unlock deletes the lock object, unless the lock has since been broken and
+// taken by another process.
 func (l *s3Lock) Unlock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	_, err := l.backend.client.DeleteObject(ctx, &s3.DeleteObjectInput{
-		Bucket: &l.backend.bucket,
-		Key:    &l.key,
+		Bucket:  &l.backend.bucket,
+		Key:     &l.key,
+		IfMatch: &l.etag,
 	})
-	if err != nil {
+	if err != nil && !preconditionFailed(err) {
 		return fmt.Errorf("failed to release lock: %w", err)
 	}
 	return nil
 }
 
+func (l *s3Lock) Renew(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	info := l.info
+	info.Expires = time.Now().Add(backend.LockTTL)
+	data, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lock info: %w", err)
+	}
+	etag, err := l.backend.putLock(ctx, l.key, data, &s3.PutObjectInput{IfMatch: &l.etag})
+	if preconditionFailed(err) {
+		return backend.ErrLockLost
+	}
+	if err != nil {
+		return fmt.Errorf("failed to renew lock: %w", err)
+	}
+	l.info = info
+	l.etag = etag
+	return nil
+}
+
 func (l *s3Lock) Info() backend.LockInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	return l.info
 }
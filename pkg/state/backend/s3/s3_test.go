@@ -2,7 +2,10 @@ package s3
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -14,18 +17,36 @@ import (
 	"github.com/davidthor/cldctl/pkg/state/backend"
 )
 
-// mockS3Server simulates AWS S3 API for testing.
+// mockS3Server simulates AWS S3 API for testing, including conditional
+// writes and deletes.
 type mockS3Server struct {
 	mu      sync.RWMutex
 	objects map[string][]byte
+	etags   map[string]string
+	version int
 }
 
 func newMockS3Server() *mockS3Server {
 	return &mockS3Server{
 		objects: make(map[string][]byte),
+		etags:   make(map[string]string),
 	}
 }
 
+// preconditionFailed checks a request's If-Match and If-None-Match headers
+// against the object at key, writing a 412 response if they don't hold.
+func (m *mockS3Server) preconditionFailed(w http.ResponseWriter, r *http.Request, key string) bool {
+	etag, exists := m.etags[key]
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	ifMatch := r.Header.Get("If-Match")
+	if ifNoneMatch == "*" && exists || ifMatch != "" && ifMatch != etag {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>PreconditionFailed</Code></Error>`))
+		return true
+	}
+	return false
+}
+
 func (m *mockS3Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -59,7 +80,7 @@ func (m *mockS3Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	case http.MethodPut:
 		m.handlePut(w, r, fullKey)
 	case http.MethodDelete:
-		m.handleDelete(w, fullKey)
+		m.handleDelete(w, r, fullKey)
 	case http.MethodHead:
 		m.handleHead(w, fullKey)
 	default:
@@ -75,6 +96,7 @@ func (m *mockS3Server) handleGet(w http.ResponseWriter, key string) {
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", m.etags[key])
 	_, _ = w.Write(data)
 }
 
@@ -84,12 +106,22 @@ func (m *mockS3Server) handlePut(w http.ResponseWriter, r *http.Request, key str
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if m.preconditionFailed(w, r, key) {
+		return
+	}
+	m.version++
 	m.objects[key] = data
+	m.etags[key] = fmt.Sprintf(`"%d"`, m.version)
+	w.Header().Set("ETag", m.etags[key])
 	w.WriteHeader(http.StatusOK)
 }
 
-func (m *mockS3Server) handleDelete(w http.ResponseWriter, key string) {
+func (m *mockS3Server) handleDelete(w http.ResponseWriter, r *http.Request, key string) {
+	if m.preconditionFailed(w, r, key) {
+		return
+	}
 	delete(m.objects, key)
+	delete(m.etags, key)
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -610,3 +642,98 @@ func TestLockInfo_WithExpiration(t *testing.T) {
 		t.Error("expected Expires to be set")
 	}
 }
+
+func newMockBackend(t *testing.T) (*Backend, *mockS3Server) {
+	t.Helper()
+	mock := newMockS3Server()
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+
+	b, err := NewBackend(map[string]string{
+		"bucket":           "test-bucket",
+		"region":           "us-east-1",
+		"endpoint":         server.URL,
+		"access_key":       "test-key",
+		"secret_key":       "test-secret",
+		"force_path_style": "true",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return b.(*Backend), mock
+}
+
+func TestBackend_LockRace(t *testing.T) {
+	b, _ := newMockBackend(t)
+	ctx := context.Background()
+
+	const n = 8
+	var wg sync.WaitGroup
+	locks := make(chan backend.Lock, n)
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lock, err := b.Lock(ctx, "envs/dev", backend.LockInfo{Who: "test"})
+			if err != nil {
+				errs <- err
+				return
+			}
+			locks <- lock
+		}()
+	}
+	wg.Wait()
+	close(locks)
+	close(errs)
+
+	if len(locks) != 1 {
+		t.Fatalf("expected exactly one lock to be taken, got %d", len(locks))
+	}
+	held := <-locks
+	recorded, _, err := b.readLock(ctx, "envs/dev.lock")
+	if err != nil {
+		t.Fatalf("failed to read the lock: %v", err)
+	}
+	if recorded.ID != held.ID() {
+		t.Errorf("expected the lock object to record %s, got %s", held.ID(), recorded.ID)
+	}
+	for err := range errs {
+		if !errors.Is(err, backend.ErrLocked) {
+			t.Errorf("expected ErrLocked, got %v", err)
+		}
+	}
+}
+
+func TestBackend_LockStaleTakeOver(t *testing.T) {
+	b, mock := newMockBackend(t)
+	ctx := context.Background()
+
+	first, err := b.Lock(ctx, "envs/dev", backend.LockInfo{Who: "crashed"})
+	if err != nil {
+		t.Fatalf("lock failed: %v", err)
+	}
+
+	// Expire the first lock, as if its holder stopped renewing it.
+	stale := first.Info()
+	stale.Expires = time.Now().Add(-time.Second)
+	data, _ := json.Marshal(stale)
+	mock.mu.Lock()
+	mock.objects["test-bucket/envs/dev.lock"] = data
+	mock.mu.Unlock()
+
+	second, err := b.Lock(ctx, "envs/dev", backend.LockInfo{Who: "next"})
+	if err != nil {
+		t.Fatalf("expected the stale lock to be taken over: %v", err)
+	}
+
+	if err := first.Renew(ctx); !errors.Is(err, backend.ErrLockLost) {
+		t.Errorf("expected the broken lock's renewal to fail with ErrLockLost, got %v", err)
+	}
+	if err := first.Unlock(ctx); err != nil {
+		t.Fatalf("unlock failed: %v", err)
+	}
+	if err := second.Renew(ctx); err != nil {
+		t.Errorf("the broken lock's Unlock must not release the new one: %v", err)
+	}
+}
@@ -0,0 +1,384 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// EncryptionKeyConfig is the backend configuration key holding the key that
+// sensitive outputs are encrypted with. It's read by NewManagerFromConfig
+// for every backend type.
+const EncryptionKeyConfig = "encryption_key"
+
+// encryptedPrefix marks an encrypted value in state.
+const encryptedPrefix = "cldctl:encrypted:v1:"
+
+// ErrNoEncryptionKey is returned when reading state with encrypted values
+// without an encryption key.
+var ErrNoEncryptionKey = errors.New("state has encrypted values, but no state encryption key is configured")
+
+// encryptionManager encrypts the values of sensitive outputs (see
+// types.ResourceState.SensitiveOutputs) and the IaC plugins' state as state
+// is saved, and decrypts them as it's read. The rest of the state is stored
+// as is, so it stays readable.
+//
+// Values are encrypted with AES-256-GCM under a nonce derived from the value.
+// Encrypting a value twice gives the same result, so saving unchanged state
+// doesn't change what's stored; only whether two values are equal is
+// revealed.
+type encryptionManager struct {
+	Manager
+	aead     cipher.AEAD // nil without a key
+	nonceKey []byte
+
+	// warnings receives the warning that sensitive values are saved
+	// unencrypted, written once
+	warnings io.Writer
+	warnOnce sync.Once
+}
+
+// WithEncryption returns a Manager that encrypts sensitive outputs, the
+// inputs that carry copies of them, and IaC state, with a key derived from
+// key. With an empty key, nothing is encrypted, a warning is written to
+// stderr the first time sensitive values are saved, and state with encrypted
+// values can't be read (ErrNoEncryptionKey).
+func WithEncryption(m Manager, key string) (Manager, error) {
+	em := &encryptionManager{Manager: m, warnings: os.Stderr}
+	if key == "" {
+		return em, nil
+	}
+
+	aesKey, err := hkdf.Key(sha256.New, []byte(key), nil, "cldctl state encryption", 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive state encryption key: %w", err)
+	}
+	nonceKey, err := hkdf.Key(sha256.New, []byte(key), nil, "cldctl state encryption nonce", 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive state encryption key: %w", err)
+	}
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create state cipher: %w", err)
+	}
+	em.aead, err = cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create state cipher: %w", err)
+	}
+	em.nonceKey = nonceKey
+	return em, nil
+}
+
+// encrypt encrypts a value, along with its type. Encrypted values are left
+// as they are.
+func (m *encryptionManager) encrypt(value interface{}) (interface{}, error) {
+	if s, ok := value.(string); ok && strings.HasPrefix(s, encryptedPrefix) {
+		return value, nil
+	}
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode value: %w", err)
+	}
+	return m.seal(plaintext), nil
+}
+
+// seal encrypts plaintext, returning it in its encrypted form.
+func (m *encryptionManager) seal(plaintext []byte) string {
+	mac := hmac.New(sha256.New, m.nonceKey)
+	mac.Write(plaintext)
+	nonce := mac.Sum(nil)[:m.aead.NonceSize()]
+	sealed := m.aead.Seal(nonce, nonce, plaintext, nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed)
+}
+
+// decrypt decrypts an encrypted value. Other values are returned as they
+// are, so state saved before it was encrypted can still be read.
+func (m *encryptionManager) decrypt(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(s, encryptedPrefix) {
+		return value, nil
+	}
+	plaintext, err := m.open(s)
+	if err != nil {
+		return nil, err
+	}
+	var decrypted interface{}
+	if err := json.Unmarshal(plaintext, &decrypted); err != nil {
+		return nil, fmt.Errorf("failed to decode value: %w", err)
+	}
+	return decrypted, nil
+}
+
+// open decrypts the encrypted form of a value returned by seal.
+func (m *encryptionManager) open(s string) ([]byte, error) {
+	if m.aead == nil {
+		return nil, ErrNoEncryptionKey
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, encryptedPrefix))
+	if err != nil || len(sealed) < m.aead.NonceSize() {
+		return nil, fmt.Errorf("malformed encrypted value")
+	}
+	nonce, ciphertext := sealed[:m.aead.NonceSize()], sealed[m.aead.NonceSize():]
+	plaintext, err := m.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value; is the state encryption key the one it was saved with?")
+	}
+	return plaintext, nil
+}
+
+// iacStates returns the IaC plugins' state blobs (IaCState) of the resources
+// and modules in v, which is one of the state types the manager saves.
+// Plugins keep copies of their inputs and outputs in these blobs, secrets
+// included, so with a key they're encrypted whole.
+func iacStates(v interface{}) []*[]byte {
+	var blobs []*[]byte
+	modules := func(modules map[string]*types.ModuleState) {
+		for _, ms := range modules {
+			if ms != nil && len(ms.IaCState) > 0 {
+				blobs = append(blobs, &ms.IaCState)
+			}
+		}
+	}
+	resources := func(resources map[string]*types.ResourceState) {
+		for _, rs := range resources {
+			if rs == nil {
+				continue
+			}
+			if len(rs.IaCState) > 0 {
+				blobs = append(blobs, &rs.IaCState)
+			}
+			modules(rs.ModuleStates)
+		}
+	}
+	component := func(cs *types.ComponentState) {
+		resources(cs.Resources)
+		for _, inst := range cs.Instances {
+			if inst != nil {
+				resources(inst.Resources)
+			}
+		}
+	}
+
+	switch s := v.(type) {
+	case *types.EnvironmentState:
+		for _, cs := range s.Components {
+			if cs != nil {
+				component(cs)
+			}
+		}
+		modules(s.Modules)
+		modules(s.OnDeploy)
+		for _, agg := range s.Aggregates {
+			if agg != nil {
+				modules(agg.ModuleStates)
+			}
+		}
+	case *types.ComponentState:
+		component(s)
+	case *types.ResourceState:
+		resources(map[string]*types.ResourceState{"": s})
+	case *types.DatacenterState:
+		modules(s.Modules)
+	case *types.SharedResourceState:
+		modules(s.ModuleStates)
+	}
+	return blobs
+}
+
+// decrypted decrypts v's sensitive values and IaC state in place.
+func decrypted[T any](m *encryptionManager, v *T, walk func(*T, sensitiveFunc) error) error {
+	if v == nil {
+		return nil
+	}
+	if err := walk(v, m.decrypt); err != nil {
+		return err
+	}
+	for _, blob := range iacStates(v) {
+		if !bytes.HasPrefix(*blob, []byte(encryptedPrefix)) {
+			continue
+		}
+		plaintext, err := m.open(string(*blob))
+		if err != nil {
+			return fmt.Errorf("IaC state: %w", err)
+		}
+		*blob = plaintext
+	}
+	return nil
+}
+
+// encrypted returns a copy of v with its sensitive outputs encrypted, along
+// with the inputs of an environment's resources and modules that carry
+// copies of them (see markSensitiveInputs) and the IaC state of every
+// resource and module. v is left as it is, since callers go on using the
+// state they save, and is returned itself when there's nothing to encrypt.
+func encrypted[T any](m *encryptionManager, v *T, walk func(*T, sensitiveFunc) error) (*T, error) {
+	if v == nil {
+		return v, nil
+	}
+	found := false
+	_ = walk(v, func(value interface{}) (interface{}, error) {
+		found = true
+		return value, nil
+	})
+	if m.aead == nil {
+		if found {
+			m.warnUnencrypted()
+		}
+		return v, nil
+	}
+	if !found && len(iacStates(v)) == 0 {
+		return v, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	var c T
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	if env, ok := any(&c).(*types.EnvironmentState); ok {
+		markSensitiveInputs(env)
+	}
+	if err := walk(&c, m.encrypt); err != nil {
+		return nil, err
+	}
+	for _, blob := range iacStates(&c) {
+		if !bytes.HasPrefix(*blob, []byte(encryptedPrefix)) {
+			*blob = []byte(m.seal(*blob))
+		}
+	}
+	return &c, nil
+}
+
+// warnUnencrypted warns, once, that sensitive values are being saved without
+// an encryption key.
+func (m *encryptionManager) warnUnencrypted() {
+	m.warnOnce.Do(func() {
+		fmt.Fprintf(m.warnings, "Warning: saving sensitive outputs, such as passwords, to state unencrypted, since no state encryption key is configured.\n"+
+			"Anyone who can read the state can read them. Set CLDCTL_STATE_ENCRYPTION_KEY, or the backend's %s, to encrypt them.\n", EncryptionKeyConfig)
+	})
+}
+
+func (m *encryptionManager) GetDatacenter(ctx context.Context, name string) (*types.DatacenterState, error) {
+	dc, err := m.Manager.GetDatacenter(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := decrypted(m, dc, walkDatacenter); err != nil {
+		return nil, fmt.Errorf("datacenter %s: %w", name, err)
+	}
+	return dc, nil
+}
+
+func (m *encryptionManager) SaveDatacenter(ctx context.Context, state *types.DatacenterState) error {
+	sealed, err := encrypted(m, state, walkDatacenter)
+	if err != nil {
+		return fmt.Errorf("datacenter %s: %w", state.Name, err)
+	}
+	return m.Manager.SaveDatacenter(ctx, sealed)
+}
+
+func (m *encryptionManager) GetSharedResource(ctx context.Context, dc, key string) (*types.SharedResourceState, error) {
+	shared, err := m.Manager.GetSharedResource(ctx, dc, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := decrypted(m, shared, walkShared); err != nil {
+		return nil, fmt.Errorf("shared resource %s: %w", key, err)
+	}
+	return shared, nil
+}
+
+func (m *encryptionManager) SaveSharedResource(ctx context.Context, dc string, state *types.SharedResourceState) error {
+	sealed, err := encrypted(m, state, walkShared)
+	if err != nil {
+		return fmt.Errorf("shared resource %s: %w", state.Key, err)
+	}
+	return m.Manager.SaveSharedResource(ctx, dc, sealed)
+}
+
+func (m *encryptionManager) ListSharedResources(ctx context.Context, dc string) ([]*types.SharedResourceState, error) {
+	shared, err := m.Manager.ListSharedResources(ctx, dc)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range shared {
+		if err := decrypted(m, s, walkShared); err != nil {
+			return nil, fmt.Errorf("shared resource %s: %w", s.Key, err)
+		}
+	}
+	return shared, nil
+}
+
+func (m *encryptionManager) GetEnvironment(ctx context.Context, datacenter, name string) (*types.EnvironmentState, error) {
+	env, err := m.Manager.GetEnvironment(ctx, datacenter, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := decrypted(m, env, walkEnvironment); err != nil {
+		return nil, fmt.Errorf("environment %s: %w", name, err)
+	}
+	return env, nil
+}
+
+func (m *encryptionManager) SaveEnvironment(ctx context.Context, datacenter string, state *types.EnvironmentState) error {
+	sealed, err := encrypted(m, state, walkEnvironment)
+	if err != nil {
+		return fmt.Errorf("environment %s: %w", state.Name, err)
+	}
+	return m.Manager.SaveEnvironment(ctx, datacenter, sealed)
+}
+
+func (m *encryptionManager) GetComponent(ctx context.Context, dc, env, component string) (*types.ComponentState, error) {
+	cs, err := m.Manager.GetComponent(ctx, dc, env, component)
+	if err != nil {
+		return nil, err
+	}
+	if err := decrypted(m, cs, walkComponent); err != nil {
+		return nil, fmt.Errorf("component %s: %w", component, err)
+	}
+	return cs, nil
+}
+
+func (m *encryptionManager) SaveComponent(ctx context.Context, dc, env string, state *types.ComponentState) error {
+	sealed, err := encrypted(m, state, walkComponent)
+	if err != nil {
+		return fmt.Errorf("component %s: %w", state.Name, err)
+	}
+	return m.Manager.SaveComponent(ctx, dc, env, sealed)
+}
+
+func (m *encryptionManager) GetResource(ctx context.Context, dc, env, component, resource string) (*types.ResourceState, error) {
+	rs, err := m.Manager.GetResource(ctx, dc, env, component, resource)
+	if err != nil {
+		return nil, err
+	}
+	if err := decrypted(m, rs, walkResource); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+func (m *encryptionManager) SaveResource(ctx context.Context, dc, env, component string, state *types.ResourceState) error {
+	sealed, err := encrypted(m, state, walkResource)
+	if err != nil {
+		return err
+	}
+	return m.Manager.SaveResource(ctx, dc, env, component, sealed)
+}
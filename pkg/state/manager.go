@@ -7,7 +7,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"path"
+	"sync"
 
 	"github.com/davidthor/cldctl/pkg/state/backend"
 	"github.com/davidthor/cldctl/pkg/state/types"
@@ -27,6 +29,18 @@ type Manager interface {
 	DeleteDatacenterComponent(ctx context.Context, dc, component string) error
 	ListDatacenterComponents(ctx context.Context, dc string) ([]*types.DatacenterComponentConfig, error)
 
+	// Tenant operations (teams sharing a datacenter)
+	GetTenant(ctx context.Context, dc, name string) (*types.TenantConfig, error)
+	SaveTenant(ctx context.Context, dc string, tenant *types.TenantConfig) error
+	DeleteTenant(ctx context.Context, dc, name string) error
+	ListTenants(ctx context.Context, dc string) ([]*types.TenantConfig, error)
+
+	// Shared resource operations (hook modules shared by environments)
+	GetSharedResource(ctx context.Context, dc, key string) (*types.SharedResourceState, error)
+	SaveSharedResource(ctx context.Context, dc string, state *types.SharedResourceState) error
+	DeleteSharedResource(ctx context.Context, dc, key string) error
+	ListSharedResources(ctx context.Context, dc string) ([]*types.SharedResourceState, error)
+
 	// Environment operations (datacenter-scoped)
 	ListEnvironments(ctx context.Context, datacenter string) ([]types.EnvironmentRef, error)
 	GetEnvironment(ctx context.Context, datacenter, name string) (*types.EnvironmentState, error)
@@ -46,6 +60,11 @@ type Manager interface {
 	// Locking
 	Lock(ctx context.Context, scope LockScope) (backend.Lock, error)
 
+	// ForceUnlock breaks the lock on scope, whoever holds it, and returns
+	// the broken lock's metadata. It returns backend.ErrNotFound if scope
+	// isn't locked.
+	ForceUnlock(ctx context.Context, scope LockScope) (*backend.LockInfo, error)
+
 	// Backend info
 	Backend() backend.Backend
 }
@@ -61,21 +80,46 @@ type LockScope struct {
 
 // manager implements the Manager interface.
 type manager struct {
-	backend backend.Backend
+	backend   backend.Backend
+	stampOnce sync.Once
+}
+
+// ManagerWrapper is implemented by backends that refine the manager built
+// on top of them, e.g. to save environments transactionally. NewManager
+// applies it.
+type ManagerWrapper interface {
+	WrapManager(m Manager) Manager
 }
 
 // NewManager creates a new state manager with the given backend.
 func NewManager(b backend.Backend) Manager {
-	return &manager{backend: b}
+	m := &manager{backend: b}
+	if w, ok := b.(ManagerWrapper); ok {
+		return w.WrapManager(m)
+	}
+	return m
 }
 
 // NewManagerFromConfig creates a new state manager from backend configuration.
+// Sensitive outputs are encrypted with the configuration's EncryptionKeyConfig,
+// which isn't passed on to the backend.
 func NewManagerFromConfig(config backend.Config) (Manager, error) {
+	key := config.Config[EncryptionKeyConfig]
+	if _, ok := config.Config[EncryptionKeyConfig]; ok {
+		cfg := make(map[string]string, len(config.Config))
+		for k, v := range config.Config {
+			if k != EncryptionKeyConfig {
+				cfg[k] = v
+			}
+		}
+		config.Config = cfg
+	}
+
 	b, err := backend.Create(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create backend: %w", err)
 	}
-	return NewManager(b), nil
+	return WithEncryption(NewManager(b), key)
 }
 
 func (m *manager) Backend() backend.Backend {
@@ -90,6 +134,7 @@ func (m *manager) GetDatacenter(ctx context.Context, name string) (*types.Datace
 }
 
 func (m *manager) SaveDatacenter(ctx context.Context, state *types.DatacenterState) error {
+	m.stampMetadata(ctx)
 	p := datacenterPath(state.Name)
 	return writeJSON(ctx, m.backend, p, state)
 }
@@ -170,6 +215,80 @@ func (m *manager) ListDatacenterComponents(ctx context.Context, dc string) ([]*t
 	return components, nil
 }
 
+// Tenant operations
+
+func (m *manager) GetTenant(ctx context.Context, dc, name string) (*types.TenantConfig, error) {
+	p := tenantPath(dc, name)
+	return readJSON[types.TenantConfig](ctx, m.backend, p)
+}
+
+func (m *manager) SaveTenant(ctx context.Context, dc string, tenant *types.TenantConfig) error {
+	m.stampMetadata(ctx)
+	p := tenantPath(dc, tenant.Name)
+	return writeJSON(ctx, m.backend, p, tenant)
+}
+
+func (m *manager) DeleteTenant(ctx context.Context, dc, name string) error {
+	p := tenantPath(dc, name)
+	return m.backend.Delete(ctx, p)
+}
+
+func (m *manager) ListTenants(ctx context.Context, dc string) ([]*types.TenantConfig, error) {
+	prefix := path.Join("datacenters", dc, "tenants") + "/"
+	paths, err := m.backend.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var tenants []*types.TenantConfig
+	for _, p := range paths {
+		tenant, err := readJSON[types.TenantConfig](ctx, m.backend, p)
+		if err != nil {
+			continue // Skip files that can't be read
+		}
+		tenants = append(tenants, tenant)
+	}
+
+	return tenants, nil
+}
+
+// Shared resource operations
+
+func (m *manager) GetSharedResource(ctx context.Context, dc, key string) (*types.SharedResourceState, error) {
+	p := sharedResourcePath(dc, key)
+	return readJSON[types.SharedResourceState](ctx, m.backend, p)
+}
+
+func (m *manager) SaveSharedResource(ctx context.Context, dc string, state *types.SharedResourceState) error {
+	m.stampMetadata(ctx)
+	p := sharedResourcePath(dc, state.Key)
+	return writeJSON(ctx, m.backend, p, state)
+}
+
+func (m *manager) DeleteSharedResource(ctx context.Context, dc, key string) error {
+	p := sharedResourcePath(dc, key)
+	return m.backend.Delete(ctx, p)
+}
+
+func (m *manager) ListSharedResources(ctx context.Context, dc string) ([]*types.SharedResourceState, error) {
+	prefix := path.Join("datacenters", dc, "shared") + "/"
+	paths, err := m.backend.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared []*types.SharedResourceState
+	for _, p := range paths {
+		s, err := readJSON[types.SharedResourceState](ctx, m.backend, p)
+		if err != nil {
+			continue // Skip files that can't be read
+		}
+		shared = append(shared, s)
+	}
+
+	return shared, nil
+}
+
 // Environment operations
 
 func (m *manager) ListEnvironments(ctx context.Context, datacenter string) ([]types.EnvironmentRef, error) {
@@ -212,6 +331,7 @@ func (m *manager) GetEnvironment(ctx context.Context, datacenter, name string) (
 }
 
 func (m *manager) SaveEnvironment(ctx context.Context, datacenter string, state *types.EnvironmentState) error {
+	m.stampMetadata(ctx)
 	p := environmentPath(datacenter, state.Name)
 	return writeJSON(ctx, m.backend, p, state)
 }
@@ -282,17 +402,35 @@ func (m *manager) DeleteResource(ctx context.Context, dc, env, component, resour
 // Locking
 
 func (m *manager) Lock(ctx context.Context, scope LockScope) (backend.Lock, error) {
-	lockPath := path.Join("datacenters", scope.Datacenter, "environments", scope.Environment)
-	if scope.Component != "" {
-		lockPath = path.Join(lockPath, scope.Component)
-	}
-
+	host, _ := os.Hostname()
 	info := backend.LockInfo{
 		Who:       scope.Who,
 		Operation: scope.Operation,
+		Host:      host,
+		PID:       os.Getpid(),
 	}
 
-	return m.backend.Lock(ctx, lockPath, info)
+	return m.backend.Lock(ctx, lockPath(scope), info)
+}
+
+func (m *manager) ForceUnlock(ctx context.Context, scope LockScope) (*backend.LockInfo, error) {
+	p := backend.LockFile(lockPath(scope))
+	info, err := readJSON[backend.LockInfo](ctx, m.backend, p)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.backend.Delete(ctx, p); err != nil {
+		return nil, fmt.Errorf("failed to remove lock: %w", err)
+	}
+	return info, nil
+}
+
+func lockPath(scope LockScope) string {
+	p := path.Join("datacenters", scope.Datacenter, "environments", scope.Environment)
+	if scope.Component != "" {
+		p = path.Join(p, scope.Component)
+	}
+	return p
 }
 
 // Path helpers
@@ -305,6 +443,19 @@ func datacenterComponentPath(dc, component string) string {
 	return path.Join("datacenters", dc, "components", component+".state.json")
 }
 
+func tenantPath(dc, name string) string {
+	return path.Join("datacenters", dc, "tenants", name+".state.json")
+}
+
+func sharedResourcePath(dc, key string) string {
+	return path.Join("datacenters", dc, "shared", key+".state.json")
+}
+
+// EnvironmentPath returns the backend path of an environment's state.
+func EnvironmentPath(dc, name string) string {
+	return environmentPath(dc, name)
+}
+
 func environmentPath(dc, name string) string {
 	return path.Join("datacenters", dc, "environments", name, "environment.state.json")
 }
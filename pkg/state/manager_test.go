@@ -2,6 +2,7 @@ package state
 
 import (
 	"context"
+	"errors"
 	"os"
 	"testing"
 	"time"
@@ -33,6 +34,35 @@ func TestNewManager(t *testing.T) {
 	}
 }
 
+// wrappingBackend is a backend that wraps the managers built on it.
+type wrappingBackend struct {
+	backend.Backend
+}
+
+type wrappedManager struct {
+	Manager
+}
+
+func (b *wrappingBackend) WrapManager(m Manager) Manager {
+	return &wrappedManager{Manager: m}
+}
+
+func TestNewManager_Wrapper(t *testing.T) {
+	b, err := local.NewBackend(map[string]string{"path": t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+
+	m := NewManager(&wrappingBackend{Backend: b})
+	wrapped, ok := m.(*wrappedManager)
+	if !ok {
+		t.Fatalf("expected the backend to wrap the manager, got %T", m)
+	}
+	if _, ok := wrapped.Manager.(*manager); !ok {
+		t.Errorf("expected the default manager to be wrapped, got %T", wrapped.Manager)
+	}
+}
+
 func TestNewManagerFromConfig(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "state-manager-config-test-*")
 	if err != nil {
@@ -474,6 +504,16 @@ func TestPathHelpers(t *testing.T) {
 			fn:       func() string { return datacenterPath("aws-east") },
 			expected: "datacenters/aws-east/datacenter.state.json",
 		},
+		{
+			name:     "tenantPath",
+			fn:       func() string { return tenantPath("aws-east", "payments") },
+			expected: "datacenters/aws-east/tenants/payments.state.json",
+		},
+		{
+			name:     "sharedResourcePath",
+			fn:       func() string { return sharedResourcePath("aws-east", "smtp-mailer") },
+			expected: "datacenters/aws-east/shared/smtp-mailer.state.json",
+		},
 		{
 			name:     "environmentPath",
 			fn:       func() string { return environmentPath("aws-east", "production") },
@@ -562,6 +602,46 @@ func TestLocking(t *testing.T) {
 	}
 }
 
+func TestLocking_ForceUnlock(t *testing.T) {
+	m, cleanup := createTestManager(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	scope := LockScope{
+		Datacenter:  "test-dc",
+		Environment: "test-env",
+		Operation:   "deploy",
+		Who:         "test-user",
+	}
+
+	if _, err := m.ForceUnlock(ctx, scope); !errors.Is(err, backend.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for an unlocked scope, got %v", err)
+	}
+
+	lock, err := m.Lock(ctx, scope)
+	if err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	if info := lock.Info(); info.PID != os.Getpid() || info.Who != "test-user" {
+		t.Errorf("expected the lock to record its holder, got %+v", info)
+	}
+
+	if _, err := m.Lock(ctx, scope); !errors.Is(err, backend.ErrLocked) {
+		t.Fatalf("expected the scope to be locked, got %v", err)
+	}
+
+	info, err := m.ForceUnlock(ctx, scope)
+	if err != nil {
+		t.Fatalf("ForceUnlock failed: %v", err)
+	}
+	if info.ID != lock.ID() {
+		t.Errorf("expected the broken lock's metadata, got %+v", info)
+	}
+	if _, err := m.ForceUnlock(ctx, scope); !errors.Is(err, backend.ErrNotFound) {
+		t.Errorf("expected the lock to be gone, got %v", err)
+	}
+}
+
 func TestLocking_EnvironmentOnly(t *testing.T) {
 	m, cleanup := createTestManager(t)
 	defer cleanup()
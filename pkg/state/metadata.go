@@ -0,0 +1,58 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/state/backend"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// SchemaVersion is the version of the state layout this build reads and
+// writes. Bump it whenever a change to the state types would be misread by
+// older CLI versions.
+const SchemaVersion = 1
+
+// WriterVersion identifies the CLI build that writes state. The CLI sets it
+// from its build version at startup; it is recorded in the state metadata so
+// version skew between CLI installations can be detected.
+var WriterVersion = "dev"
+
+// metadataPath is the backend path of the state metadata document.
+const metadataPath = "cldctl.state.json"
+
+// ReadMetadata returns the state metadata stored in a backend, or nil if the
+// backend has never been written by a CLI that records metadata.
+func ReadMetadata(ctx context.Context, b backend.Backend) (*types.StateMetadata, error) {
+	meta, err := readJSON[types.StateMetadata](ctx, b, metadataPath)
+	if errors.Is(err, backend.ErrNotFound) {
+		return nil, nil
+	}
+	return meta, err
+}
+
+// WriteMetadata records that the backend was written by this CLI. The stored
+// schema version never moves backwards, so an older CLI writing to state
+// upgraded by a newer one does not hide the skew.
+func WriteMetadata(ctx context.Context, b backend.Backend) error {
+	meta := &types.StateMetadata{
+		SchemaVersion: SchemaVersion,
+		CLIVersion:    WriterVersion,
+		UpdatedAt:     time.Now(),
+	}
+	if existing, err := ReadMetadata(ctx, b); err == nil && existing != nil && existing.SchemaVersion > meta.SchemaVersion {
+		meta.SchemaVersion = existing.SchemaVersion
+		meta.CLIVersion = existing.CLIVersion
+	}
+	return writeJSON(ctx, b, metadataPath, meta)
+}
+
+// stampMetadata writes the state metadata once per manager, on the first
+// datacenter or environment save. Failures are ignored: metadata is advisory
+// and must never block a deploy.
+func (m *manager) stampMetadata(ctx context.Context) {
+	m.stampOnce.Do(func() {
+		_ = WriteMetadata(ctx, m.backend)
+	})
+}
@@ -0,0 +1,69 @@
+package state
+
+import (
+	"context"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/state/backend/local"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+func TestMetadata_StampedOnSave(t *testing.T) {
+	ctx := context.Background()
+	b, err := local.NewBackend(map[string]string{"path": t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+
+	meta, err := ReadMetadata(ctx, b)
+	if err != nil {
+		t.Fatalf("ReadMetadata failed: %v", err)
+	}
+	if meta != nil {
+		t.Fatalf("expected no metadata on a fresh backend, got %+v", meta)
+	}
+
+	origVersion := WriterVersion
+	WriterVersion = "v1.4.0"
+	defer func() { WriterVersion = origVersion }()
+
+	m := NewManager(b)
+	if err := m.SaveDatacenter(ctx, &types.DatacenterState{Name: "local"}); err != nil {
+		t.Fatalf("SaveDatacenter failed: %v", err)
+	}
+
+	meta, err = ReadMetadata(ctx, b)
+	if err != nil {
+		t.Fatalf("ReadMetadata failed: %v", err)
+	}
+	if meta == nil || meta.SchemaVersion != SchemaVersion || meta.CLIVersion != "v1.4.0" {
+		t.Errorf("unexpected metadata: %+v", meta)
+	}
+}
+
+func TestWriteMetadata_NeverDowngradesSchema(t *testing.T) {
+	ctx := context.Background()
+	b, err := local.NewBackend(map[string]string{"path": t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+
+	if err := writeJSON(ctx, b, metadataPath, &types.StateMetadata{
+		SchemaVersion: SchemaVersion + 1,
+		CLIVersion:    "v9.0.0",
+	}); err != nil {
+		t.Fatalf("writeJSON failed: %v", err)
+	}
+
+	if err := WriteMetadata(ctx, b); err != nil {
+		t.Fatalf("WriteMetadata failed: %v", err)
+	}
+
+	meta, err := ReadMetadata(ctx, b)
+	if err != nil {
+		t.Fatalf("ReadMetadata failed: %v", err)
+	}
+	if meta.SchemaVersion != SchemaVersion+1 || meta.CLIVersion != "v9.0.0" {
+		t.Errorf("expected newer schema to be preserved, got %+v", meta)
+	}
+}
@@ -0,0 +1,182 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/davidthor/cldctl/pkg/state"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// Manager is a state manager whose environment saves are transactional.
+//
+// The executor saves the whole environment after every resource it applies,
+// from whatever it read when the deploy started. Saved naively, a process
+// deploying one component would overwrite the progress of another process
+// deploying a different component to the same environment. SaveEnvironment
+// instead locks the environment's row, and keeps the saved version of every
+// component this process hasn't changed since it last read or saved the
+// environment.
+type Manager struct {
+	state.Manager
+	backend *Backend
+
+	mu sync.Mutex
+	// bases holds, per environment path, the encoded components as this
+	// process last read or saved them.
+	bases     map[string]map[string][]byte
+	stampOnce sync.Once
+}
+
+// WrapManager implements state.ManagerWrapper, so managers created for a
+// postgres backend are *Manager.
+func (b *Backend) WrapManager(m state.Manager) state.Manager {
+	return &Manager{
+		Manager: m,
+		backend: b,
+		bases:   make(map[string]map[string][]byte),
+	}
+}
+
+func (m *Manager) GetEnvironment(ctx context.Context, datacenter, name string) (*types.EnvironmentState, error) {
+	env, err := m.Manager.GetEnvironment(ctx, datacenter, name)
+	if err != nil {
+		return nil, err
+	}
+	components, err := encodeComponents(env)
+	if err != nil {
+		return nil, err
+	}
+	m.setBase(state.EnvironmentPath(datacenter, name), components)
+	return env, nil
+}
+
+func (m *Manager) SaveEnvironment(ctx context.Context, datacenter string, env *types.EnvironmentState) error {
+	m.stampOnce.Do(func() {
+		_ = state.WriteMetadata(ctx, m.backend)
+	})
+
+	p := state.EnvironmentPath(datacenter, env.Name)
+	ours, err := encodeComponents(env)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	base := m.bases[p]
+	m.mu.Unlock()
+
+	tx, err := m.backend.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Insert an empty row for a new environment, so there is a row to lock.
+	// A concurrent insert of the same path waits for this transaction.
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+		"INSERT INTO %s (path, data) VALUES ($1, ''::bytea) ON CONFLICT (path) DO NOTHING", m.backend.table), p); err != nil {
+		return fmt.Errorf("failed to save environment: %w", err)
+	}
+	var data []byte
+	if err := tx.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT data FROM %s WHERE path = $1 FOR UPDATE", m.backend.table), p).Scan(&data); err != nil {
+		return fmt.Errorf("failed to lock environment: %w", err)
+	}
+	var current *types.EnvironmentState
+	if len(data) > 0 {
+		current = &types.EnvironmentState{}
+		if err := json.Unmarshal(data, current); err != nil {
+			return fmt.Errorf("failed to decode JSON: %w", err)
+		}
+	}
+
+	content, err := json.MarshalIndent(mergeEnvironment(env, ours, base, current), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	if err := upsert(ctx, tx, m.backend.table, p, content); err != nil {
+		return fmt.Errorf("failed to save environment: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save environment: %w", err)
+	}
+
+	m.setBase(p, ours)
+	return nil
+}
+
+func (m *Manager) DeleteEnvironment(ctx context.Context, datacenter, name string) error {
+	if err := m.Manager.DeleteEnvironment(ctx, datacenter, name); err != nil {
+		return err
+	}
+	m.setBase(state.EnvironmentPath(datacenter, name), nil)
+	return nil
+}
+
+func (m *Manager) setBase(p string, components map[string][]byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if components == nil {
+		delete(m.bases, p)
+		return
+	}
+	m.bases[p] = components
+}
+
+// encodeComponents returns the JSON encoding of each of env's components.
+func encodeComponents(env *types.EnvironmentState) (map[string][]byte, error) {
+	components := make(map[string][]byte, len(env.Components))
+	for name, cs := range env.Components {
+		data, err := json.Marshal(cs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode component %s: %w", name, err)
+		}
+		components[name] = data
+	}
+	return components, nil
+}
+
+// mergeEnvironment returns the environment to save over current, the
+// environment as saved by any process. ours is env's components encoded,
+// and base the components as this process last read or saved them.
+//
+// The environment-level fields are env's. A component env added, changed
+// or removed since base is saved as env has it; every other component is
+// kept as current has it, since another process may have changed or
+// removed it.
+func mergeEnvironment(env *types.EnvironmentState, ours, base map[string][]byte, current *types.EnvironmentState) *types.EnvironmentState {
+	if current == nil {
+		return env
+	}
+
+	merged := *env
+	merged.Components = make(map[string]*types.ComponentState, len(env.Components))
+	for name, cs := range env.Components {
+		merged.Components[name] = cs
+	}
+	for name, cs := range current.Components {
+		encoded, inOurs := ours[name]
+		baseEncoded, inBase := base[name]
+		switch {
+		case inOurs && inBase && bytes.Equal(encoded, baseEncoded):
+			// Unchanged here: keep the saved version.
+			merged.Components[name] = cs
+		case !inOurs && !inBase:
+			// Added by another process.
+			merged.Components[name] = cs
+		}
+	}
+	for name := range env.Components {
+		if _, inCurrent := current.Components[name]; inCurrent {
+			continue
+		}
+		if baseEncoded, inBase := base[name]; inBase && bytes.Equal(ours[name], baseEncoded) {
+			// Unchanged here and removed by another process.
+			delete(merged.Components, name)
+		}
+	}
+	return &merged
+}
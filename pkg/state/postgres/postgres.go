@@ -0,0 +1,284 @@
+// Package postgres implements a PostgreSQL state backend.
+//
+// State documents are stored as rows of a single table, keyed by their
+// backend path, so the layout matches the other backends and state can be
+// migrated to and from them. Locks are rows too, which makes acquiring one
+// a single atomic statement.
+//
+// On top of the backend, the package provides a state manager whose
+// environment saves run in a transaction holding a row-level lock on the
+// environment, so processes saving the same environment concurrently can't
+// overwrite each other's components.
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidthor/cldctl/pkg/state/backend"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the "pgx" database/sql driver
+)
+
+func init() {
+	backend.Register("postgres", NewBackend)
+}
+
+// staleLockAge is how long a lock must go unrenewed before another process
+// may take it over, matching the other backends.
+const staleLockAge = backend.LockTTL
+
+// Backend implements the state backend interface for PostgreSQL.
+type Backend struct {
+	db     *sql.DB
+	schema string
+	table  string // quoted, schema-qualified table name
+}
+
+// NewBackend creates a new PostgreSQL backend, creating its schema and table
+// if they don't exist.
+func NewBackend(cfg map[string]string) (backend.Backend, error) {
+	connStr := cfg["conn_str"]
+	if connStr == "" {
+		return nil, fmt.Errorf("postgres backend requires 'conn_str' configuration")
+	}
+
+	schema := cfg["schema"]
+	if schema == "" {
+		schema = "cldctl"
+	}
+
+	db, err := sql.Open("pgx", connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	b := &Backend{
+		db:     db,
+		schema: schema,
+		table:  pgx.Identifier{schema, "states"}.Sanitize(),
+	}
+	if err := b.migrate(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// migrate creates the backend's schema and table.
+func (b *Backend) migrate(ctx context.Context) error {
+	statements := []string{
+		fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", pgx.Identifier{b.schema}.Sanitize()),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	path       TEXT PRIMARY KEY,
+	data       BYTEA NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`, b.table),
+	}
+	for _, stmt := range statements {
+		if _, err := b.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to create state table: %w", err)
+		}
+	}
+	return nil
+}
+
+func (b *Backend) Type() string {
+	return "postgres"
+}
+
+func (b *Backend) Read(ctx context.Context, path string) (io.ReadCloser, error) {
+	var data []byte
+	err := b.db.QueryRowContext(ctx, fmt.Sprintf("SELECT data FROM %s WHERE path = $1", b.table), path).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, backend.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (b *Backend) Write(ctx context.Context, path string, data io.Reader) error {
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return fmt.Errorf("failed to read data: %w", err)
+	}
+	if err := upsert(ctx, b.db, b.table, path, content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+func (b *Backend) Delete(ctx context.Context, path string) error {
+	if _, err := b.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE path = $1", b.table), path); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", path, err)
+	}
+	return nil
+}
+
+// List returns the paths under prefix. Like the local backend, a prefix
+// without a trailing slash names a directory or a single path, so listing
+// "datacenters/dev" doesn't return the state of "datacenters/dev2".
+func (b *Backend) List(ctx context.Context, prefix string) ([]string, error) {
+	query := fmt.Sprintf("SELECT path FROM %s WHERE left(path, char_length($1)) = $1 ORDER BY path", b.table)
+	args := []interface{}{prefix}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		query = fmt.Sprintf("SELECT path FROM %s WHERE path = $1 OR left(path, char_length($2)) = $2 ORDER BY path", b.table)
+		args = []interface{}{prefix, prefix + "/"}
+	}
+
+	rows, err := b.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+		}
+		paths = append(paths, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+	}
+	return paths, nil
+}
+
+func (b *Backend) Exists(ctx context.Context, path string) (bool, error) {
+	var exists bool
+	err := b.db.QueryRowContext(ctx, fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE path = $1)", b.table), path).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check %s: %w", path, err)
+	}
+	return exists, nil
+}
+
+// Lock records the lock as a row at backend.LockFile(path). The row is
+// inserted, or a stale lock's row taken over, in a single statement, so two
+// processes can never both hold the lock.
+func (b *Backend) Lock(ctx context.Context, path string, info backend.LockInfo) (backend.Lock, error) {
+	lockPath := backend.LockFile(path)
+
+	info.ID = uuid.New().String()
+	info.Path = path
+	info.Created = time.Now()
+	info.Expires = info.Created.Add(backend.LockTTL)
+	lockData, err := json.Marshal(info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal lock info: %w", err)
+	}
+
+	result, err := b.db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s AS s (path, data, updated_at) VALUES ($1, $2, now())
+ON CONFLICT (path) DO UPDATE SET data = EXCLUDED.data, updated_at = EXCLUDED.updated_at
+WHERE s.updated_at < now() - make_interval(secs => $3)`, b.table),
+		lockPath, lockData, staleLockAge.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	} else if n == 0 {
+		var existing backend.LockInfo
+		if reader, err := b.Read(ctx, lockPath); err == nil {
+			_ = json.NewDecoder(reader).Decode(&existing)
+			reader.Close()
+		}
+		return nil, &backend.LockError{
+			Info: existing,
+			Err:  backend.ErrLocked,
+		}
+	}
+
+	return &postgresLock{
+		backend: b,
+		path:    lockPath,
+		info:    info,
+	}, nil
+}
+
+// Close closes the backend's database connections.
+func (b *Backend) Close() error {
+	return b.db.Close()
+}
+
+// execer is the part of *sql.DB and *sql.Tx that upsert needs.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func upsert(ctx context.Context, db execer, table, path string, data []byte) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (path, data, updated_at) VALUES ($1, $2, now())
+ON CONFLICT (path) DO UPDATE SET data = EXCLUDED.data, updated_at = EXCLUDED.updated_at`, table), path, data)
+	return err
+}
+
+// postgresLock implements the Lock interface for PostgreSQL.
+type postgresLock struct {
+	backend *Backend
+	path    string
+
+	mu   sync.Mutex
+	info backend.LockInfo
+}
+
+func (l *postgresLock) ID() string {
+	return l.info.ID
+}
+
+// Unlock removes the lock's row, unless the lock has since been broken and
+// taken by another process.
+func (l *postgresLock) Unlock(ctx context.Context) error {
+	_, err := l.backend.db.ExecContext(ctx, fmt.Sprintf(
+		"DELETE FROM %s WHERE path = $1 AND convert_from(data, 'UTF8')::jsonb->>'id' = $2", l.backend.table),
+		l.path, l.info.ID)
+	if err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// Renew refreshes the row's updated_at, which is what staleness is measured
+// from, unless the lock has since been broken and taken by another process.
+func (l *postgresLock) Renew(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	info := l.info
+	info.Expires = time.Now().Add(backend.LockTTL)
+	data, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lock info: %w", err)
+	}
+	result, err := l.backend.db.ExecContext(ctx, fmt.Sprintf(
+		"UPDATE %s SET data = $3, updated_at = now() WHERE path = $1 AND convert_from(data, 'UTF8')::jsonb->>'id' = $2", l.backend.table),
+		l.path, l.info.ID, data)
+	if err != nil {
+		return fmt.Errorf("failed to renew lock: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to renew lock: %w", err)
+	} else if n == 0 {
+		return backend.ErrLockLost
+	}
+	l.info = info
+	return nil
+}
+
+func (l *postgresLock) Info() backend.LockInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.info
+}
@@ -0,0 +1,322 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// RedactedValue is shown in place of a sensitive value.
+const RedactedValue = "(sensitive)"
+
+// sensitiveFunc transforms the value of a sensitive output.
+type sensitiveFunc func(value interface{}) (interface{}, error)
+
+// transformValues applies fn to the outputs or inputs at the given dot
+// paths, e.g. "write.password". Paths without a value are skipped.
+func transformValues(kind string, values map[string]interface{}, paths []string, fn sensitiveFunc) error {
+	for _, p := range paths {
+		parts := strings.Split(p, ".")
+		m := values
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := m[part].(map[string]interface{})
+			if !ok {
+				m = nil
+				break
+			}
+			m = next
+		}
+		value, ok := m[parts[len(parts)-1]]
+		if !ok {
+			continue
+		}
+		transformed, err := fn(value)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", kind, p, err)
+		}
+		m[parts[len(parts)-1]] = transformed
+	}
+	return nil
+}
+
+func walkModules(modules map[string]*types.ModuleState, fn sensitiveFunc) error {
+	for name, ms := range modules {
+		if ms == nil {
+			continue
+		}
+		if err := transformValues("output", ms.Outputs, ms.SensitiveOutputs, fn); err != nil {
+			return fmt.Errorf("module %s: %w", name, err)
+		}
+		if err := transformValues("input", ms.Inputs, ms.SensitiveInputs, fn); err != nil {
+			return fmt.Errorf("module %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func walkResources(resources map[string]*types.ResourceState, fn sensitiveFunc) error {
+	for key, rs := range resources {
+		if rs == nil {
+			continue
+		}
+		if err := transformValues("output", rs.Outputs, rs.SensitiveOutputs, fn); err != nil {
+			return fmt.Errorf("resource %s: %w", key, err)
+		}
+		if err := transformValues("input", rs.Inputs, rs.SensitiveInputs, fn); err != nil {
+			return fmt.Errorf("resource %s: %w", key, err)
+		}
+		if err := walkModules(rs.ModuleStates, fn); err != nil {
+			return fmt.Errorf("resource %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func walkResource(rs *types.ResourceState, fn sensitiveFunc) error {
+	return walkResources(map[string]*types.ResourceState{rs.Type + "." + rs.Name: rs}, fn)
+}
+
+func walkComponent(cs *types.ComponentState, fn sensitiveFunc) error {
+	if err := walkResources(cs.Resources, fn); err != nil {
+		return err
+	}
+	for name, inst := range cs.Instances {
+		if inst == nil {
+			continue
+		}
+		if err := walkResources(inst.Resources, fn); err != nil {
+			return fmt.Errorf("instance %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// walkEnvironment applies fn to every sensitive value in env: the sensitive
+// outputs and inputs of its resources and modules, and its sensitive
+// environment outputs.
+func walkEnvironment(env *types.EnvironmentState, fn sensitiveFunc) error {
+	for name, cs := range env.Components {
+		if cs == nil {
+			continue
+		}
+		if err := walkComponent(cs, fn); err != nil {
+			return fmt.Errorf("component %s: %w", name, err)
+		}
+	}
+	if err := walkModules(env.Modules, fn); err != nil {
+		return err
+	}
+	if err := walkModules(env.OnDeploy, fn); err != nil {
+		return err
+	}
+	for key, agg := range env.Aggregates {
+		if agg == nil {
+			continue
+		}
+		if err := walkModules(agg.ModuleStates, fn); err != nil {
+			return fmt.Errorf("aggregate %s: %w", key, err)
+		}
+	}
+	for name, out := range env.Outputs {
+		if out == nil || !out.Sensitive {
+			continue
+		}
+		value, err := fn(out.Value)
+		if err != nil {
+			return fmt.Errorf("output %s: %w", name, err)
+		}
+		out.Value = value
+	}
+	return nil
+}
+
+func walkDatacenter(dc *types.DatacenterState, fn sensitiveFunc) error {
+	return walkModules(dc.Modules, fn)
+}
+
+func walkShared(s *types.SharedResourceState, fn sensitiveFunc) error {
+	return walkModules(s.ModuleStates, fn)
+}
+
+// sensitiveStrings returns the values of env's sensitive outputs as
+// strings, longest first, so that a secret containing another is replaced
+// first.
+func sensitiveStrings(env *types.EnvironmentState) []string {
+	var secrets []string
+	_ = walkEnvironment(env, func(value interface{}) (interface{}, error) {
+		// Short values would match unrelated parts of inputs
+		if s := fmt.Sprintf("%v", value); len(s) >= 4 {
+			secrets = append(secrets, s)
+		}
+		return value, nil
+	})
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	return secrets
+}
+
+// eachInputs calls fn with the inputs of every resource and module in env,
+// and with the list of their sensitive input paths.
+func eachInputs(env *types.EnvironmentState, fn func(inputs *map[string]interface{}, sensitive *[]string)) {
+	modules := func(modules map[string]*types.ModuleState) {
+		for _, ms := range modules {
+			if ms != nil {
+				fn(&ms.Inputs, &ms.SensitiveInputs)
+			}
+		}
+	}
+	resources := func(resources map[string]*types.ResourceState) {
+		for _, rs := range resources {
+			if rs == nil {
+				continue
+			}
+			fn(&rs.Inputs, &rs.SensitiveInputs)
+			modules(rs.ModuleStates)
+		}
+	}
+	for _, cs := range env.Components {
+		if cs == nil {
+			continue
+		}
+		resources(cs.Resources)
+		for _, inst := range cs.Instances {
+			if inst != nil {
+				resources(inst.Resources)
+			}
+		}
+	}
+	modules(env.Modules)
+	modules(env.OnDeploy)
+	for _, agg := range env.Aggregates {
+		if agg != nil {
+			modules(agg.ModuleStates)
+		}
+	}
+}
+
+// markSensitiveInputs records in SensitiveInputs the inputs of env's
+// resources and modules that carry a copy of a sensitive output, such as a
+// database password a deployment receives in a connection string, so that
+// they're encrypted along with the output.
+func markSensitiveInputs(env *types.EnvironmentState) {
+	eachInputs(env, func(_ *map[string]interface{}, sensitive *[]string) { *sensitive = nil })
+	secrets := sensitiveStrings(env)
+	if len(secrets) == 0 {
+		return
+	}
+	eachInputs(env, func(inputs *map[string]interface{}, sensitive *[]string) {
+		*sensitive = sensitivePaths(*inputs, "", secrets)
+	})
+}
+
+// sensitivePaths returns the dot paths of the values in inputs that contain
+// one of the secrets. Maps are descended into unless one of their keys has a
+// dot, which a path can't express.
+func sensitivePaths(inputs map[string]interface{}, prefix string, secrets []string) []string {
+	var paths []string
+	for key, value := range inputs {
+		if nested, ok := value.(map[string]interface{}); ok && !hasDottedKey(nested) {
+			paths = append(paths, sensitivePaths(nested, prefix+key+".", secrets)...)
+			continue
+		}
+		if containsSecret(value, secrets) {
+			paths = append(paths, prefix+key)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func hasDottedKey(m map[string]interface{}) bool {
+	for key := range m {
+		if strings.Contains(key, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// containsSecret reports whether any string in v contains one of the
+// secrets.
+func containsSecret(v interface{}, secrets []string) bool {
+	switch val := v.(type) {
+	case string:
+		for _, s := range secrets {
+			if strings.Contains(val, s) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for _, item := range val {
+			if containsSecret(item, secrets) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range val {
+			if containsSecret(item, secrets) {
+				return true
+			}
+		}
+	case map[string]string:
+		for _, item := range val {
+			if containsSecret(item, secrets) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RedactEnvironment replaces the values of env's sensitive outputs and
+// inputs with RedactedValue, along with any other copies of the outputs in
+// the inputs of its resources and modules, such as a database password a
+// deployment receives in a connection string. It modifies env in place.
+func RedactEnvironment(env *types.EnvironmentState) {
+	secrets := sensitiveStrings(env)
+	_ = walkEnvironment(env, func(interface{}) (interface{}, error) {
+		return RedactedValue, nil
+	})
+	if len(secrets) == 0 {
+		return
+	}
+	eachInputs(env, func(inputs *map[string]interface{}, _ *[]string) {
+		*inputs = redactStrings(*inputs, secrets).(map[string]interface{})
+	})
+}
+
+// redactStrings returns v with every occurrence of the secrets in its
+// strings replaced with RedactedValue.
+func redactStrings(v interface{}, secrets []string) interface{} {
+	switch val := v.(type) {
+	case string:
+		for _, s := range secrets {
+			val = strings.ReplaceAll(val, s, RedactedValue)
+		}
+		return val
+	case map[string]interface{}:
+		if val == nil {
+			return val
+		}
+		redacted := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			redacted[k] = redactStrings(item, secrets)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(val))
+		for i, item := range val {
+			redacted[i] = redactStrings(item, secrets)
+		}
+		return redacted
+	case map[string]string:
+		redacted := make(map[string]string, len(val))
+		for k, item := range val {
+			redacted[k] = redactStrings(item, secrets).(string)
+		}
+		return redacted
+	default:
+		return v
+	}
+}
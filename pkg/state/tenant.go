@@ -0,0 +1,316 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/davidthor/cldctl/pkg/state/backend"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// ErrTenantDenied is returned when a tenant-scoped manager refuses an
+// operation outside what the tenant is allowed to do.
+var ErrTenantDenied = errors.New("denied by tenant")
+
+// tenantManager scopes a Manager to one tenant of each datacenter it's used
+// with. Environments outside the tenant's prefix are hidden from listings and
+// can't be read or changed, changes need the deployer role, quotas are
+// checked as environments are saved, and datacenter-level state is read-only.
+//
+// It's enforced by cldctl, not the backend: anyone with the backend's
+// credentials can still read and write all of its state.
+type tenantManager struct {
+	Manager
+	tenant   string
+	identity string
+
+	mu      sync.Mutex
+	configs map[string]*types.TenantConfig
+}
+
+// WithTenant returns a Manager that acts as identity within the named
+// tenant. The tenant must be defined in each datacenter the manager is used
+// with (see Manager.SaveTenant).
+func WithTenant(m Manager, tenant, identity string) Manager {
+	return &tenantManager{
+		Manager:  m,
+		tenant:   tenant,
+		identity: identity,
+		configs:  map[string]*types.TenantConfig{},
+	}
+}
+
+// config returns the tenant's definition in a datacenter, checking that the
+// identity is a member.
+func (m *tenantManager) config(ctx context.Context, dc string) (*types.TenantConfig, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.configs[dc]
+	if !ok {
+		var err error
+		t, err = m.Manager.GetTenant(ctx, dc, m.tenant)
+		if errors.Is(err, backend.ErrNotFound) {
+			return nil, fmt.Errorf("tenant %q is not defined in datacenter %q", m.tenant, dc)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tenant %q: %w", m.tenant, err)
+		}
+		m.configs[dc] = t
+	}
+	if t.Role(m.identity) == "" {
+		return nil, fmt.Errorf("%w: %q is not a member of tenant %q", ErrTenantDenied, m.identity, m.tenant)
+	}
+	return t, nil
+}
+
+// readable checks that an environment belongs to the tenant.
+func (m *tenantManager) readable(ctx context.Context, dc, env string) (*types.TenantConfig, error) {
+	t, err := m.config(ctx, dc)
+	if err != nil {
+		return nil, err
+	}
+	if !t.Owns(env) {
+		return nil, fmt.Errorf("%w: environment %q is outside tenant %q (environment names must start with %q)", ErrTenantDenied, env, m.tenant, t.Prefix)
+	}
+	return t, nil
+}
+
+// writable checks that an environment belongs to the tenant and that the
+// identity may change it.
+func (m *tenantManager) writable(ctx context.Context, dc, env string) (*types.TenantConfig, error) {
+	t, err := m.readable(ctx, dc, env)
+	if err != nil {
+		return nil, err
+	}
+	return t, m.checkDeployer(t)
+}
+
+func (m *tenantManager) checkDeployer(t *types.TenantConfig) error {
+	if t.Role(m.identity) != types.TenantRoleDeployer {
+		return fmt.Errorf("%w: %q is a %s of tenant %q and can't change environments", ErrTenantDenied, m.identity, t.Role(m.identity), m.tenant)
+	}
+	return nil
+}
+
+func (m *tenantManager) datacenterReadOnly(dc string) error {
+	return fmt.Errorf("%w: tenant %q can't change datacenter %q", ErrTenantDenied, m.tenant, dc)
+}
+
+// Datacenter state is shared by every tenant, so it's read-only
+
+func (m *tenantManager) SaveDatacenter(ctx context.Context, state *types.DatacenterState) error {
+	return m.datacenterReadOnly(state.Name)
+}
+
+func (m *tenantManager) DeleteDatacenter(ctx context.Context, name string) error {
+	return m.datacenterReadOnly(name)
+}
+
+func (m *tenantManager) SaveDatacenterComponent(ctx context.Context, dc string, state *types.DatacenterComponentConfig) error {
+	return m.datacenterReadOnly(dc)
+}
+
+func (m *tenantManager) DeleteDatacenterComponent(ctx context.Context, dc, component string) error {
+	return m.datacenterReadOnly(dc)
+}
+
+// Tenant operations
+
+func (m *tenantManager) GetTenant(ctx context.Context, dc, name string) (*types.TenantConfig, error) {
+	if name != m.tenant {
+		return nil, fmt.Errorf("%w: tenant %q can't read tenant %q", ErrTenantDenied, m.tenant, name)
+	}
+	return m.config(ctx, dc)
+}
+
+func (m *tenantManager) SaveTenant(ctx context.Context, dc string, tenant *types.TenantConfig) error {
+	return m.datacenterReadOnly(dc)
+}
+
+func (m *tenantManager) DeleteTenant(ctx context.Context, dc, name string) error {
+	return m.datacenterReadOnly(dc)
+}
+
+func (m *tenantManager) ListTenants(ctx context.Context, dc string) ([]*types.TenantConfig, error) {
+	t, err := m.config(ctx, dc)
+	if err != nil {
+		return nil, err
+	}
+	return []*types.TenantConfig{t}, nil
+}
+
+// Shared resources are used by the environments of every tenant, so
+// deployers can add and remove their environments' references to them
+
+func (m *tenantManager) SaveSharedResource(ctx context.Context, dc string, state *types.SharedResourceState) error {
+	t, err := m.config(ctx, dc)
+	if err != nil {
+		return err
+	}
+	if err := m.checkDeployer(t); err != nil {
+		return err
+	}
+	return m.Manager.SaveSharedResource(ctx, dc, state)
+}
+
+func (m *tenantManager) DeleteSharedResource(ctx context.Context, dc, key string) error {
+	t, err := m.config(ctx, dc)
+	if err != nil {
+		return err
+	}
+	if err := m.checkDeployer(t); err != nil {
+		return err
+	}
+	return m.Manager.DeleteSharedResource(ctx, dc, key)
+}
+
+// Environment operations
+
+func (m *tenantManager) ListEnvironments(ctx context.Context, datacenter string) ([]types.EnvironmentRef, error) {
+	t, err := m.config(ctx, datacenter)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := m.Manager.ListEnvironments(ctx, datacenter)
+	if err != nil {
+		return nil, err
+	}
+	var owned []types.EnvironmentRef
+	for _, ref := range refs {
+		if t.Owns(ref.Name) {
+			owned = append(owned, ref)
+		}
+	}
+	return owned, nil
+}
+
+func (m *tenantManager) GetEnvironment(ctx context.Context, datacenter, name string) (*types.EnvironmentState, error) {
+	if _, err := m.readable(ctx, datacenter, name); err != nil {
+		return nil, err
+	}
+	return m.Manager.GetEnvironment(ctx, datacenter, name)
+}
+
+func (m *tenantManager) SaveEnvironment(ctx context.Context, datacenter string, state *types.EnvironmentState) error {
+	t, err := m.writable(ctx, datacenter, state.Name)
+	if err != nil {
+		return err
+	}
+	if err := m.checkQuota(ctx, datacenter, t, state); err != nil {
+		return err
+	}
+	return m.Manager.SaveEnvironment(ctx, datacenter, state)
+}
+
+// checkQuota checks that saving an environment keeps the tenant within its
+// quota. Only growth is refused, so lowering a quota doesn't lock a tenant
+// out of environments that are already over it.
+func (m *tenantManager) checkQuota(ctx context.Context, datacenter string, t *types.TenantConfig, state *types.EnvironmentState) error {
+	if t.Quota.MaxEnvironments == 0 && t.Quota.MaxComponents == 0 {
+		return nil
+	}
+
+	existing, err := m.Manager.GetEnvironment(ctx, datacenter, state.Name)
+	if err != nil && !errors.Is(err, backend.ErrNotFound) {
+		return err
+	}
+
+	if existing == nil && t.Quota.MaxEnvironments > 0 {
+		refs, err := m.ListEnvironments(ctx, datacenter)
+		if err != nil {
+			return err
+		}
+		if len(refs) >= t.Quota.MaxEnvironments {
+			return fmt.Errorf("%w: tenant %q is at its quota of %d environments", ErrTenantDenied, m.tenant, t.Quota.MaxEnvironments)
+		}
+	}
+
+	if max := t.Quota.MaxComponents; max > 0 && len(state.Components) > max {
+		before := 0
+		if existing != nil {
+			before = len(existing.Components)
+		}
+		if len(state.Components) > before {
+			return fmt.Errorf("%w: tenant %q allows at most %d components per environment, and %q would have %d", ErrTenantDenied, m.tenant, max, state.Name, len(state.Components))
+		}
+	}
+	return nil
+}
+
+func (m *tenantManager) DeleteEnvironment(ctx context.Context, datacenter, name string) error {
+	if _, err := m.writable(ctx, datacenter, name); err != nil {
+		return err
+	}
+	return m.Manager.DeleteEnvironment(ctx, datacenter, name)
+}
+
+// Component operations
+
+func (m *tenantManager) GetComponent(ctx context.Context, dc, env, component string) (*types.ComponentState, error) {
+	if _, err := m.readable(ctx, dc, env); err != nil {
+		return nil, err
+	}
+	return m.Manager.GetComponent(ctx, dc, env, component)
+}
+
+func (m *tenantManager) SaveComponent(ctx context.Context, dc, env string, state *types.ComponentState) error {
+	if _, err := m.writable(ctx, dc, env); err != nil {
+		return err
+	}
+	return m.Manager.SaveComponent(ctx, dc, env, state)
+}
+
+func (m *tenantManager) DeleteComponent(ctx context.Context, dc, env, component string) error {
+	if _, err := m.writable(ctx, dc, env); err != nil {
+		return err
+	}
+	return m.Manager.DeleteComponent(ctx, dc, env, component)
+}
+
+// Resource operations
+
+func (m *tenantManager) GetResource(ctx context.Context, dc, env, component, resource string) (*types.ResourceState, error) {
+	if _, err := m.readable(ctx, dc, env); err != nil {
+		return nil, err
+	}
+	return m.Manager.GetResource(ctx, dc, env, component, resource)
+}
+
+func (m *tenantManager) SaveResource(ctx context.Context, dc, env, component string, state *types.ResourceState) error {
+	if _, err := m.writable(ctx, dc, env); err != nil {
+		return err
+	}
+	return m.Manager.SaveResource(ctx, dc, env, component, state)
+}
+
+func (m *tenantManager) DeleteResource(ctx context.Context, dc, env, component, resource string) error {
+	if _, err := m.writable(ctx, dc, env); err != nil {
+		return err
+	}
+	return m.Manager.DeleteResource(ctx, dc, env, component, resource)
+}
+
+// Locking
+
+func (m *tenantManager) Lock(ctx context.Context, scope LockScope) (backend.Lock, error) {
+	if scope.Environment == "" {
+		return nil, m.datacenterReadOnly(scope.Datacenter)
+	}
+	if _, err := m.writable(ctx, scope.Datacenter, scope.Environment); err != nil {
+		return nil, err
+	}
+	return m.Manager.Lock(ctx, scope)
+}
+
+func (m *tenantManager) ForceUnlock(ctx context.Context, scope LockScope) (*backend.LockInfo, error) {
+	if scope.Environment == "" {
+		return nil, m.datacenterReadOnly(scope.Datacenter)
+	}
+	if _, err := m.writable(ctx, scope.Datacenter, scope.Environment); err != nil {
+		return nil, err
+	}
+	return m.Manager.ForceUnlock(ctx, scope)
+}
@@ -0,0 +1,133 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/davidthor/cldctl/pkg/state/backend/local"
+	"github.com/davidthor/cldctl/pkg/state/types"
+)
+
+// newTenantTestManager returns an unscoped manager with a datacenter holding
+// a "payments" tenant and environments of two teams.
+func newTenantTestManager(t *testing.T, tenant *types.TenantConfig) Manager {
+	t.Helper()
+	b, err := local.NewBackend(map[string]string{"path": t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+	m := NewManager(b)
+	ctx := context.Background()
+
+	if err := m.SaveDatacenter(ctx, &types.DatacenterState{Name: "shared"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SaveTenant(ctx, "shared", tenant); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"pay-staging", "pay-prod", "search-prod"} {
+		env := &types.EnvironmentState{Name: name, Datacenter: "shared", Components: map[string]*types.ComponentState{"api": {Name: "api"}}}
+		if err := m.SaveEnvironment(ctx, "shared", env); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func TestWithTenant_Isolation(t *testing.T) {
+	ctx := context.Background()
+	base := newTenantTestManager(t, &types.TenantConfig{Name: "payments", Prefix: "pay-"})
+	m := WithTenant(base, "payments", "alice")
+
+	refs, err := m.ListEnvironments(ctx, "shared")
+	if err != nil {
+		t.Fatalf("ListEnvironments failed: %v", err)
+	}
+	if len(refs) != 2 {
+		t.Errorf("expected the tenant's 2 environments, got %v", refs)
+	}
+	for _, ref := range refs {
+		if ref.Name == "search-prod" {
+			t.Error("another tenant's environment was listed")
+		}
+	}
+
+	if _, err := m.GetEnvironment(ctx, "shared", "pay-prod"); err != nil {
+		t.Errorf("GetEnvironment of an owned environment failed: %v", err)
+	}
+	if _, err := m.GetEnvironment(ctx, "shared", "search-prod"); !errors.Is(err, ErrTenantDenied) {
+		t.Errorf("expected reading another tenant's environment to be denied, got %v", err)
+	}
+	if err := m.SaveEnvironment(ctx, "shared", &types.EnvironmentState{Name: "preview"}); !errors.Is(err, ErrTenantDenied) {
+		t.Errorf("expected an environment without the prefix to be denied, got %v", err)
+	}
+	if err := m.DeleteEnvironment(ctx, "shared", "search-prod"); !errors.Is(err, ErrTenantDenied) {
+		t.Errorf("expected deleting another tenant's environment to be denied, got %v", err)
+	}
+	if err := m.SaveDatacenter(ctx, &types.DatacenterState{Name: "shared"}); !errors.Is(err, ErrTenantDenied) {
+		t.Errorf("expected datacenter changes to be denied, got %v", err)
+	}
+	if err := m.SaveTenant(ctx, "shared", &types.TenantConfig{Name: "payments", Prefix: ""}); !errors.Is(err, ErrTenantDenied) {
+		t.Errorf("expected tenants to be unable to change themselves, got %v", err)
+	}
+
+	if _, err := WithTenant(base, "unknown", "alice").ListEnvironments(ctx, "shared"); err == nil {
+		t.Error("expected an undefined tenant to be an error")
+	}
+}
+
+func TestWithTenant_Roles(t *testing.T) {
+	ctx := context.Background()
+	base := newTenantTestManager(t, &types.TenantConfig{
+		Name:    "payments",
+		Prefix:  "pay-",
+		Members: map[string]types.TenantRole{"alice": types.TenantRoleDeployer, "bob": types.TenantRoleViewer},
+	})
+	env := &types.EnvironmentState{Name: "pay-staging", Datacenter: "shared"}
+
+	if err := WithTenant(base, "payments", "alice").SaveEnvironment(ctx, "shared", env); err != nil {
+		t.Errorf("expected a deployer to save, got %v", err)
+	}
+
+	viewer := WithTenant(base, "payments", "bob")
+	if _, err := viewer.GetEnvironment(ctx, "shared", "pay-staging"); err != nil {
+		t.Errorf("expected a viewer to read, got %v", err)
+	}
+	if err := viewer.SaveEnvironment(ctx, "shared", env); !errors.Is(err, ErrTenantDenied) {
+		t.Errorf("expected a viewer's save to be denied, got %v", err)
+	}
+
+	if _, err := WithTenant(base, "payments", "mallory").ListEnvironments(ctx, "shared"); !errors.Is(err, ErrTenantDenied) {
+		t.Errorf("expected a non-member to be denied, got %v", err)
+	}
+}
+
+func TestWithTenant_Quota(t *testing.T) {
+	ctx := context.Background()
+	base := newTenantTestManager(t, &types.TenantConfig{
+		Name:   "payments",
+		Prefix: "pay-",
+		Quota:  types.TenantQuota{MaxEnvironments: 2, MaxComponents: 1},
+	})
+	m := WithTenant(base, "payments", "alice")
+
+	err := m.SaveEnvironment(ctx, "shared", &types.EnvironmentState{Name: "pay-dev"})
+	if !errors.Is(err, ErrTenantDenied) {
+		t.Errorf("expected a third environment to exceed the quota, got %v", err)
+	}
+
+	env, err := m.GetEnvironment(ctx, "shared", "pay-prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Status = types.EnvironmentStatusReady
+	if err := m.SaveEnvironment(ctx, "shared", env); err != nil {
+		t.Errorf("expected updating an environment within quota to succeed, got %v", err)
+	}
+
+	env.Components["worker"] = &types.ComponentState{Name: "worker"}
+	if err := m.SaveEnvironment(ctx, "shared", env); !errors.Is(err, ErrTenantDenied) {
+		t.Errorf("expected a second component to exceed the quota, got %v", err)
+	}
+}
@@ -2,9 +2,18 @@
 package types
 
 import (
+	"strings"
 	"time"
 )
 
+// StateMetadata describes the CLI that last wrote a state backend. It is
+// stored at the backend root and used to detect version skew.
+type StateMetadata struct {
+	SchemaVersion int       `json:"schema_version"`
+	CLIVersion    string    `json:"cli_version"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
 // DatacenterState represents the state of a datacenter.
 type DatacenterState struct {
 	// Metadata
@@ -31,6 +40,58 @@ type DatacenterComponentConfig struct {
 	Variables map[string]string `json:"variables,omitempty"` // HCL expression strings (evaluated at runtime)
 }
 
+// TenantConfig is a team sharing a datacenter. Each tenant is stored as its
+// own state file under datacenters/<dc>/tenants/. A tenant only sees and
+// changes environments whose names start with its Prefix.
+type TenantConfig struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Prefix is the name prefix of the tenant's environments (e.g., "payments-")
+	Prefix string `json:"prefix"`
+
+	// Quota limits what the tenant can deploy
+	Quota TenantQuota `json:"quota,omitempty"`
+
+	// Members maps identities to their role in the tenant. When empty,
+	// anyone selecting the tenant is a deployer.
+	Members map[string]TenantRole `json:"members,omitempty"`
+}
+
+// TenantQuota limits a tenant's usage of a datacenter. Zero means unlimited.
+type TenantQuota struct {
+	// MaxEnvironments is the most environments the tenant can have
+	MaxEnvironments int `json:"max_environments,omitempty"`
+
+	// MaxComponents is the most components each of its environments can have
+	MaxComponents int `json:"max_components,omitempty"`
+}
+
+// TenantRole is what a member of a tenant can do.
+type TenantRole string
+
+const (
+	// TenantRoleViewer can read the tenant's environments.
+	TenantRoleViewer TenantRole = "viewer"
+
+	// TenantRoleDeployer can also create, deploy to, and destroy them.
+	TenantRoleDeployer TenantRole = "deployer"
+)
+
+// Owns reports whether an environment belongs to the tenant.
+func (t *TenantConfig) Owns(envName string) bool {
+	return strings.HasPrefix(envName, t.Prefix)
+}
+
+// Role returns an identity's role in the tenant, or "" if it isn't a member.
+func (t *TenantConfig) Role(identity string) TenantRole {
+	if len(t.Members) == 0 {
+		return TenantRoleDeployer
+	}
+	return t.Members[identity]
+}
+
 // EnvironmentState represents the state of an environment.
 type EnvironmentState struct {
 	// Metadata
@@ -46,11 +107,73 @@ type EnvironmentState struct {
 	// Configuration from environment file
 	Variables map[string]string `json:"variables,omitempty"`
 
+	// Labels are arbitrary key/value metadata used to select environments
+	// for bulk operations
+	Labels map[string]string `json:"labels,omitempty"`
+
 	// Deployed components
 	Components map[string]*ComponentState `json:"components,omitempty"`
 
 	// Environment-level module states
 	Modules map[string]*ModuleState `json:"modules,omitempty"`
+
+	// Aggregates records the modules of aggregate hooks, which run once for
+	// all of the resources they match, keyed by the aggregate's key (see
+	// ResourceState.Aggregate)
+	Aggregates map[string]*AggregateState `json:"aggregates,omitempty"`
+
+	// OnDeploy records the modules of the datacenter's on_deploy block, which
+	// run after each deploy to the environment, so they can be destroyed
+	// with it
+	OnDeploy map[string]*ModuleState `json:"on_deploy,omitempty"`
+
+	// Outputs are the environment file's outputs, resolved after the last
+	// deploy that applied it
+	Outputs map[string]*EnvironmentOutput `json:"outputs,omitempty"`
+
+	// Bootstrap records the environment file's bootstrap tasks that have
+	// run, keyed by task name, so later deploys skip them
+	Bootstrap map[string]*BootstrapTaskState `json:"bootstrap,omitempty"`
+
+	// DatacenterVersion pins the environment to a datacenter version (the
+	// artifact reference recorded in DatacenterState.Version). Pinned
+	// environments keep that version until explicitly upgraded. Empty means
+	// the environment follows the deployed datacenter version.
+	DatacenterVersion string `json:"datacenter_version,omitempty"`
+
+	// Ports records host ports allocated to resources in this environment,
+	// keyed by graph node ID. Allocations are sticky across deploys and are
+	// released when the owning resource is destroyed.
+	Ports map[string]int `json:"ports,omitempty"`
+
+	// Heartbeat is set while a deploy is executing against the environment.
+	// A heartbeat that stops being refreshed means the process running the
+	// deploy died partway through.
+	Heartbeat *DeployHeartbeat `json:"heartbeat,omitempty"`
+}
+
+// DeployHeartbeat records the process executing a deploy.
+type DeployHeartbeat struct {
+	// Owner identifies the process as host:pid.
+	Owner string `json:"owner"`
+
+	// StartedAt records when execution started.
+	StartedAt time.Time `json:"started_at"`
+
+	// UpdatedAt is refreshed periodically while execution runs.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// EnvironmentOutput is a resolved environment-level output.
+type EnvironmentOutput struct {
+	Value       interface{} `json:"value"`
+	Description string      `json:"description,omitempty"`
+	Sensitive   bool        `json:"sensitive,omitempty"`
+}
+
+// BootstrapTaskState records a bootstrap task that has run.
+type BootstrapTaskState struct {
+	CompletedAt time.Time `json:"completed_at"`
 }
 
 // EnvironmentStatus represents the status of an environment.
@@ -73,6 +196,11 @@ type ComponentState struct {
 	DeployedAt time.Time `json:"deployed_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
 
+	// Digest is the manifest digest of the artifact the component was last
+	// deployed from, recorded by `cldctl refresh` so it can tell when the
+	// component's tag has moved.
+	Digest string `json:"digest,omitempty"`
+
 	// Status
 	Status       ResourceStatus `json:"status"`
 	StatusReason string         `json:"status_reason,omitempty"`
@@ -80,6 +208,11 @@ type ComponentState struct {
 	// Variables used for this deployment
 	Variables map[string]string `json:"variables,omitempty"`
 
+	// Labels are arbitrary key/value metadata set when the component was
+	// deployed. Hooks receive them, merged over the environment's labels, as
+	// node.inputs.labels.
+	Labels map[string]string `json:"labels,omitempty"`
+
 	// Dependencies lists the names of other components this component depends on.
 	// Populated at deploy time from the component schema's dependency declarations.
 	Dependencies []string `json:"dependencies,omitempty"`
@@ -96,6 +229,51 @@ type ComponentState struct {
 	// When nil, the component is in single-instance mode.
 	// Per-instance resources live under InstanceState.Resources.
 	Instances map[string]*InstanceState `json:"instances,omitempty"`
+
+	// Routes maps route names to the address each route was published at.
+	// Used to detect routes that collide with other components.
+	Routes map[string]*RouteState `json:"routes,omitempty"`
+
+	// Webhooks are the component's lifecycle webhooks as of its last deploy,
+	// recorded so the destroy webhooks can be called without the component
+	// file
+	Webhooks map[string]*WebhookState `json:"webhooks,omitempty"`
+}
+
+// WebhookState records a component lifecycle webhook. URL and Secret are
+// kept as unresolved expressions, so secret values aren't written to state;
+// they are resolved against the component's variables when called.
+type WebhookState struct {
+	URL     string   `json:"url"`
+	Events  []string `json:"events"`
+	Secret  string   `json:"secret,omitempty"`
+	Timeout string   `json:"timeout,omitempty"`
+}
+
+// RouteState records where a route was published.
+type RouteState struct {
+	// Subdomain passed to the datacenter route hook.
+	Subdomain string `json:"subdomain"`
+
+	// PathPrefix passed to the datacenter route hook.
+	PathPrefix string `json:"path_prefix"`
+
+	// Generated is true when the subdomain was generated rather than set by
+	// the environment. Generated subdomains are reused on later deploys so
+	// published URLs stay stable.
+	Generated bool `json:"generated,omitempty"`
+
+	// Hostnames are the additional host and path combinations the route
+	// was published at, from the environment's route hostnames.
+	Hostnames []RouteHostname `json:"hostnames,omitempty"`
+}
+
+// RouteHostname records one host and path combination a route matches.
+// Exactly one of Subdomain and Host is set.
+type RouteHostname struct {
+	Subdomain  string `json:"subdomain,omitempty"`
+	Host       string `json:"host,omitempty"`
+	PathPrefix string `json:"path_prefix"`
 }
 
 // InstanceState represents the state of a single weighted component instance.
@@ -132,12 +310,32 @@ type ResourceState struct {
 	Hook   string `json:"hook,omitempty"`   // Hook type that created this resource
 	Module string `json:"module,omitempty"` // Module name within hook
 
+	// Aggregate is the key of the aggregate hook that created this resource,
+	// if any. The hook's module state is then recorded once for all of its
+	// resources, in EnvironmentState.Aggregates.
+	Aggregate string `json:"aggregate,omitempty"`
+
+	// Shared is the key of the shared hook whose modules this resource uses,
+	// if any. The modules' state is recorded once for the datacenter, in a
+	// SharedResourceState.
+	Shared string `json:"shared,omitempty"`
+
 	// Resource inputs (normalized from component)
 	Inputs map[string]interface{} `json:"inputs,omitempty"`
 
 	// Resource outputs (from hook execution)
 	Outputs map[string]interface{} `json:"outputs,omitempty"`
 
+	// SensitiveOutputs are the dot paths of outputs derived from sensitive
+	// module outputs (e.g. "password", "write.password"). Their values are
+	// encrypted at rest when the state manager has an encryption key.
+	SensitiveOutputs []string `json:"sensitive_outputs,omitempty"`
+
+	// SensitiveInputs are the dot paths of inputs that carry a copy of a
+	// sensitive value, such as a connection string with a database password.
+	// The state manager records and encrypts them along with the outputs.
+	SensitiveInputs []string `json:"sensitive_inputs,omitempty"`
+
 	// IaC state (serialized state from the plugin) - used for single-module hooks
 	IaCState []byte `json:"iac_state,omitempty"`
 
@@ -150,6 +348,82 @@ type ResourceState struct {
 	// Status
 	Status       ResourceStatus `json:"status"`
 	StatusReason string         `json:"status_reason,omitempty"`
+
+	// RootCause is the node ID of the failed resource that kept this one
+	// from being applied, when its status reason is
+	// StatusReasonUpstreamFailure
+	RootCause string `json:"root_cause,omitempty"`
+
+	// Timing records the resource's last successful apply, for tracking
+	// deploy times
+	Timing *ResourceTiming `json:"timing,omitempty"`
+
+	// Paused resources are left alone by deploys until they're unpaused, so
+	// that they can be changed by hand without being reverted
+	Paused      bool   `json:"paused,omitempty"`
+	PauseReason string `json:"pause_reason,omitempty"`
+}
+
+// AggregateState is the state of an aggregate hook: the resources it was
+// last applied with, and the state of its modules.
+type AggregateState struct {
+	// Members are the resources the hook's modules were last applied with,
+	// keyed by graph node ID. Resources that aren't part of a deploy are
+	// included again with their recorded inputs.
+	Members map[string]*AggregateMember `json:"members,omitempty"`
+
+	// ModuleStates maps module name to its state
+	ModuleStates map[string]*ModuleState `json:"module_states,omitempty"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AggregateMember is a resource an aggregate hook was applied with.
+type AggregateMember struct {
+	Component string                 `json:"component"`
+	Type      string                 `json:"type"`
+	Name      string                 `json:"name"`
+	Inputs    map[string]interface{} `json:"inputs,omitempty"`
+}
+
+// SharedResourceState is the state of a shared hook's modules, which are
+// provisioned once for a datacenter and reused by every environment whose
+// resources the hook matches. Each is stored as its own state file under
+// datacenters/<dc>/shared/.
+type SharedResourceState struct {
+	// Key identifies the shared hook (see ResourceState.Shared)
+	Key string `json:"key"`
+
+	// ModuleStates maps module name to its state
+	ModuleStates map[string]*ModuleState `json:"module_states,omitempty"`
+
+	// References are the resources using the modules, keyed by
+	// "<environment>/<node ID>". The modules are destroyed when the last
+	// reference is removed.
+	References map[string]*SharedReference `json:"references,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SharedReference is a resource using a shared hook's modules.
+type SharedReference struct {
+	Environment string    `json:"environment"`
+	Component   string    `json:"component"`
+	Type        string    `json:"type"`
+	Name        string    `json:"name"`
+	AddedAt     time.Time `json:"added_at"`
+}
+
+// ResourceTiming records how long an apply of a resource took.
+type ResourceTiming struct {
+	// Action is the planned action that was applied (create, update, or
+	// replace).
+	Action string `json:"action"`
+
+	StartedAt  time.Time `json:"started_at"`
+	EndedAt    time.Time `json:"ended_at"`
+	DurationMS int64     `json:"duration_ms"`
 }
 
 // ResourceStatus represents the status of a resource.
@@ -162,8 +436,18 @@ const (
 	ResourceStatusFailed       ResourceStatus = "failed"
 	ResourceStatusDeleting     ResourceStatus = "deleting"
 	ResourceStatusDeleted      ResourceStatus = "deleted"
+
+	// ResourceStatusUnknown marks a resource whose apply was cancelled or
+	// could not be fully rolled back, so infrastructure may have been left
+	// behind. Its IaC state is kept so a later destroy can clean it up.
+	ResourceStatusUnknown ResourceStatus = "unknown"
 )
 
+// StatusReasonUpstreamFailure is the status reason of a failed resource that
+// wasn't applied because a resource it depends on failed. Its RootCause
+// names that resource.
+const StatusReasonUpstreamFailure = "skipped: upstream failure"
+
 // ModuleState represents the state of an IaC module execution.
 type ModuleState struct {
 	// Metadata
@@ -179,14 +463,40 @@ type ModuleState struct {
 	// Outputs from the module
 	Outputs map[string]interface{} `json:"outputs,omitempty"`
 
+	// SensitiveOutputs names the outputs the module marked sensitive
+	SensitiveOutputs []string `json:"sensitive_outputs,omitempty"`
+
+	// SensitiveInputs are the dot paths of inputs that carry a copy of a
+	// sensitive value (see ResourceState.SensitiveInputs)
+	SensitiveInputs []string `json:"sensitive_inputs,omitempty"`
+
 	// IaC state (serialized state from the plugin)
 	IaCState []byte `json:"iac_state,omitempty"`
 
+	// Credentials the module ran with, so destroy can run with the same
+	// scope. Only identifiers are recorded, never secret values.
+	Credentials *ModuleCredentials `json:"credentials,omitempty"`
+
+	// Adopted is set for root modules applied outside cldctl and registered
+	// from their existing state rather than declared in the datacenter.
+	Adopted bool `json:"adopted,omitempty"`
+
+	// CacheKey identifies the result of a cacheable module. A later apply
+	// whose key matches reuses Outputs instead of running the module.
+	CacheKey string `json:"cache_key,omitempty"`
+
 	// Status
 	Status       ModuleStatus `json:"status"`
 	StatusReason string       `json:"status_reason,omitempty"`
 }
 
+// ModuleCredentials records the resolved credential scope of a module.
+type ModuleCredentials struct {
+	RoleARN        string `json:"role_arn,omitempty"`
+	ServiceAccount string `json:"service_account,omitempty"`
+	EnvSet         string `json:"env_set,omitempty"`
+}
+
 // ModuleStatus represents the status of a module.
 type ModuleStatus string
 